@@ -0,0 +1,80 @@
+// Package apiclient is a typed client of the gRPC API of Xray, for tools managing a running
+// instance without vendoring its proto files.
+//
+// The API is enabled by the "api" object of the config, with the services the client calls listed
+// in it: "StatsService", "HandlerService" and "RoutingService". Adding "ReflectionService" also
+// enables gRPC server reflection, for generic tools like grpcurl.
+package apiclient
+
+import (
+	"context"
+	"time"
+
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	routerService "github.com/xtls/xray-core/app/router/command"
+	statsService "github.com/xtls/xray-core/app/stats/command"
+	"github.com/xtls/xray-core/common/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// DefaultTimeout bounds each call, unless set otherwise with WithTimeout.
+const DefaultTimeout = 3 * time.Second
+
+// Client calls the API of an Xray instance. It is safe for concurrent use.
+type Client struct {
+	conn    *grpc.ClientConn
+	timeout time.Duration
+
+	stats   statsService.StatsServiceClient
+	handler handlerService.HandlerServiceClient
+	routing routerService.RoutingServiceClient
+}
+
+// Option changes the settings of a Client.
+type Option func(*Client)
+
+// WithTimeout sets the time each call may take, including dialing. Calls given a context with an
+// earlier deadline end at that deadline instead.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// Dial connects to the API listening on address, like "127.0.0.1:8080", waiting until the
+// connection is established.
+func Dial(ctx context.Context, address string, opts ...Option) (*Client, error) {
+	c := &Client{timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(c)
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, address, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	if err != nil {
+		return nil, errors.New("failed to dial API server ", address).Base(err)
+	}
+	c.conn = conn
+	c.stats = statsService.NewStatsServiceClient(conn)
+	c.handler = handlerService.NewHandlerServiceClient(conn)
+	c.routing = routerService.NewRoutingServiceClient(conn)
+	return c, nil
+}
+
+// Conn returns the connection to the API, for services and calls the client doesn't wrap.
+func (c *Client) Conn() *grpc.ClientConn {
+	return c.conn
+}
+
+// Close closes the connection to the API.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
@@ -0,0 +1,111 @@
+package apiclient_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/app/commander"
+	. "github.com/xtls/xray-core/app/commander/apiclient"
+	"github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/app/proxyman"
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	_ "github.com/xtls/xray-core/app/proxyman/inbound"
+	_ "github.com/xtls/xray-core/app/proxyman/outbound"
+	"github.com/xtls/xray-core/app/router"
+	routerService "github.com/xtls/xray-core/app/router/command"
+	"github.com/xtls/xray-core/app/stats"
+	statsService "github.com/xtls/xray-core/app/stats/command"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+	feature_stats "github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/proxy/blackhole"
+	"github.com/xtls/xray-core/proxy/freedom"
+	"github.com/xtls/xray-core/testing/servers/tcp"
+)
+
+func TestClient(t *testing.T) {
+	apiPort := tcp.PickPort()
+	config := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&commander.Config{
+				Tag:    "api",
+				Listen: fmt.Sprintf("127.0.0.1:%d", apiPort),
+				Service: []*serial.TypedMessage{
+					serial.ToTypedMessage(&statsService.Config{}),
+					serial.ToTypedMessage(&handlerService.Config{}),
+					serial.ToTypedMessage(&routerService.Config{}),
+				},
+			}),
+			serial.ToTypedMessage(&stats.Config{}),
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+			serial.ToTypedMessage(&router.Config{}),
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				Tag:           "direct",
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+	server, err := core.New(config)
+	common.Must(err)
+	common.Must(server.Start())
+	defer server.Close()
+
+	counter, err := feature_stats.GetOrRegisterCounter(server.GetFeature(feature_stats.ManagerType()).(feature_stats.Manager), "outbound>>>direct>>>traffic>>>uplink")
+	common.Must(err)
+	counter.Add(42)
+
+	ctx := context.Background()
+	client, err := Dial(ctx, fmt.Sprintf("127.0.0.1:%d", apiPort), WithTimeout(time.Second*5))
+	common.Must(err)
+	defer client.Close()
+
+	if value, err := client.Stats(ctx, "outbound>>>direct>>>traffic>>>uplink", true); err != nil || value != 42 {
+		t.Error("expected counter of 42, but got ", value, " ", err)
+	}
+	if values, err := client.QueryStats(ctx, "direct", false); err != nil || len(values) != 1 || values["outbound>>>direct>>>traffic>>>uplink"] != 0 {
+		t.Error("expected counter reset to 0, but got ", values, " ", err)
+	}
+
+	common.Must(client.AddOutbound(ctx, &core.OutboundHandlerConfig{
+		Tag:           "block",
+		ProxySettings: serial.ToTypedMessage(&blackhole.Config{}),
+	}))
+	common.Must(client.AddRules(ctx, &router.Config{
+		Rule: []*router.RoutingRule{
+			{
+				RuleTag:   "to-block",
+				TargetTag: &router.RoutingRule_Tag{Tag: "block"},
+				PortList:  &net.PortList{Range: []*net.PortRange{net.SinglePortRange(25)}},
+			},
+		},
+	}, true))
+	tag, err := client.TestRoute(ctx, &routerService.RoutingContext{
+		Network:      net.Network_TCP,
+		TargetDomain: "example.com",
+		TargetPort:   25,
+	})
+	if err != nil || tag != "block" {
+		t.Error("expected route to block, but got ", tag, " ", err)
+	}
+	common.Must(client.RemoveRule(ctx, "to-block"))
+	common.Must(client.RemoveOutbound(ctx, "block", 0))
+
+	if err := client.RemoveInbound(ctx, "missing"); err == nil {
+		t.Error("expected error removing a missing inbound")
+	}
+
+	shortCtx, cancel := context.WithTimeout(ctx, time.Nanosecond)
+	defer cancel()
+	time.Sleep(time.Millisecond)
+	if _, err := client.SysStats(shortCtx); err == nil {
+		t.Error("expected the deadline of the context to be kept")
+	}
+}
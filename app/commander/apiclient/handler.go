@@ -0,0 +1,118 @@
+package apiclient
+
+import (
+	"context"
+	"time"
+
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+)
+
+// AddInbound adds and starts the inbound built from config.
+func (c *Client) AddInbound(ctx context.Context, config *core.InboundHandlerConfig) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err := c.handler.AddInbound(ctx, &handlerService.AddInboundRequest{Inbound: config})
+	return err
+}
+
+// RemoveInbound closes and removes the inbound with tag.
+func (c *Client) RemoveInbound(ctx context.Context, tag string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err := c.handler.RemoveInbound(ctx, &handlerService.RemoveInboundRequest{Tag: tag})
+	return err
+}
+
+// AddOutbound adds the outbound built from config.
+func (c *Client) AddOutbound(ctx context.Context, config *core.OutboundHandlerConfig) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err := c.handler.AddOutbound(ctx, &handlerService.AddOutboundRequest{Outbound: config})
+	return err
+}
+
+// RemoveOutbound removes the outbound with tag. With a drain timeout, the outbound is no longer
+// used for new connections, and closed once those in progress finish, or at the timeout.
+func (c *Client) RemoveOutbound(ctx context.Context, tag string, drain time.Duration) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err := c.handler.RemoveOutbound(ctx, &handlerService.RemoveOutboundRequest{
+		Tag:          tag,
+		DrainTimeout: uint32(drain / time.Second),
+	})
+	return err
+}
+
+// AddUser adds user to the inbound with tag.
+func (c *Client) AddUser(ctx context.Context, tag string, user *protocol.User) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err := c.handler.AlterInbound(ctx, &handlerService.AlterInboundRequest{
+		Tag:       tag,
+		Operation: serial.ToTypedMessage(&handlerService.AddUserOperation{User: user}),
+	})
+	return err
+}
+
+// RemoveUser removes the user with email from the inbound with tag.
+func (c *Client) RemoveUser(ctx context.Context, tag string, email string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err := c.handler.AlterInbound(ctx, &handlerService.AlterInboundRequest{
+		Tag:       tag,
+		Operation: serial.ToTypedMessage(&handlerService.RemoveUserOperation{Email: email}),
+	})
+	return err
+}
+
+// InboundUsers returns the users of the inbound with tag, or only the one with email if set.
+func (c *Client) InboundUsers(ctx context.Context, tag string, email string) ([]*protocol.User, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.handler.GetInboundUsers(ctx, &handlerService.GetInboundUserRequest{Tag: tag, Email: email})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Users, nil
+}
+
+// FailedInbounds returns the inbounds which failed to start.
+func (c *Client) FailedInbounds(ctx context.Context) ([]*handlerService.FailedInbound, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.handler.ListFailedInbounds(ctx, &handlerService.ListFailedInboundsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Inbounds, nil
+}
+
+// InboundAddresses returns the addresses the inbound with tag listens on, or those of all inbounds
+// if tag is empty.
+func (c *Client) InboundAddresses(ctx context.Context, tag string) ([]*handlerService.InboundAddress, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.handler.ListInboundAddresses(ctx, &handlerService.ListInboundAddressesRequest{Tag: tag})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Addresses, nil
+}
+
+// IdleConnections returns the connections of the inbound with tag, or of all inbounds if tag is
+// empty, idle for at least idle.
+func (c *Client) IdleConnections(ctx context.Context, tag string, idle time.Duration) ([]*handlerService.IdleConnection, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.handler.ListIdleConnections(ctx, &handlerService.ListIdleConnectionsRequest{
+		Tag:     tag,
+		MinIdle: int64(idle / time.Second),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Connections, nil
+}
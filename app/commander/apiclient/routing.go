@@ -0,0 +1,82 @@
+package apiclient
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/app/router"
+	routerService "github.com/xtls/xray-core/app/router/command"
+	"github.com/xtls/xray-core/common/serial"
+)
+
+// AddRules adds the rules and balancers of config, after the existing ones if shouldAppend is
+// true, or replacing them otherwise.
+func (c *Client) AddRules(ctx context.Context, config *router.Config, shouldAppend bool) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err := c.routing.AddRule(ctx, &routerService.AddRuleRequest{
+		Config:       serial.ToTypedMessage(config),
+		ShouldAppend: shouldAppend,
+	})
+	return err
+}
+
+// RemoveRule removes the rule with ruleTag.
+func (c *Client) RemoveRule(ctx context.Context, ruleTag string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err := c.routing.RemoveRule(ctx, &routerService.RemoveRuleRequest{RuleTag: ruleTag})
+	return err
+}
+
+// SetRuleGroup enables or disables the rules of group.
+func (c *Client) SetRuleGroup(ctx context.Context, group string, enabled bool) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err := c.routing.SetRuleGroup(ctx, &routerService.SetRuleGroupRequest{Group: group, Enabled: enabled})
+	return err
+}
+
+// RuleGroups returns the rule groups, with whether each is enabled.
+func (c *Client) RuleGroups(ctx context.Context) ([]*routerService.RuleGroupInfo, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.routing.ListRuleGroups(ctx, &routerService.ListRuleGroupsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Groups, nil
+}
+
+// TestRoute returns the outbound the router picks for the connection described by routingContext.
+func (c *Client) TestRoute(ctx context.Context, routingContext *routerService.RoutingContext) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.routing.TestRoute(ctx, &routerService.TestRouteRequest{RoutingContext: routingContext})
+	if err != nil {
+		return "", err
+	}
+	return resp.OutboundTag, nil
+}
+
+// BalancerInfo returns the targets of the balancer with tag, and its override if any.
+func (c *Client) BalancerInfo(ctx context.Context, tag string) (*routerService.BalancerMsg, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.routing.GetBalancerInfo(ctx, &routerService.GetBalancerInfoRequest{Tag: tag})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Balancer, nil
+}
+
+// OverrideBalancer makes the balancer with balancerTag pick target, or clears the override if
+// target is empty.
+func (c *Client) OverrideBalancer(ctx context.Context, balancerTag string, target string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err := c.routing.OverrideBalancerTarget(ctx, &routerService.OverrideBalancerTargetRequest{
+		BalancerTag: balancerTag,
+		Target:      target,
+	})
+	return err
+}
@@ -0,0 +1,54 @@
+package apiclient
+
+import (
+	"context"
+
+	statsService "github.com/xtls/xray-core/app/stats/command"
+)
+
+// Stats returns the value of the counter name, like "user>>>love@example.com>>>traffic>>>uplink",
+// resetting it to 0 if reset is true.
+func (c *Client) Stats(ctx context.Context, name string, reset bool) (int64, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.stats.GetStats(ctx, &statsService.GetStatsRequest{Name: name, Reset_: reset})
+	if err != nil {
+		return 0, err
+	}
+	return resp.GetStat().GetValue(), nil
+}
+
+// QueryStats returns the values of the counters whose names match pattern, by name, resetting
+// them to 0 if reset is true. An empty pattern matches all counters.
+func (c *Client) QueryStats(ctx context.Context, pattern string, reset bool) (map[string]int64, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.stats.QueryStats(ctx, &statsService.QueryStatsRequest{Pattern: pattern, Reset_: reset})
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]int64, len(resp.Stat))
+	for _, stat := range resp.Stat {
+		values[stat.Name] = stat.Value
+	}
+	return values, nil
+}
+
+// OnlineIPs returns the IPs the user with email is online from, with the Unix time each was last
+// seen at.
+func (c *Client) OnlineIPs(ctx context.Context, email string) (map[string]int64, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.stats.GetStatsOnlineIpList(ctx, &statsService.GetStatsRequest{Name: "user>>>" + email + ">>>online"})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Ips, nil
+}
+
+// SysStats returns the runtime statistics of the instance.
+func (c *Client) SysStats(ctx context.Context) (*statsService.SysStatsResponse, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.stats.GetSysStats(ctx, &statsService.SysStatsRequest{})
+}
@@ -7,18 +7,26 @@ import (
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/listen"
 	"github.com/xtls/xray-core/common/signal/done"
 	core "github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/features/outbound"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
+// ErrorCodeKey is the key of the trailer telling the code of the Diagnostic of a failed call,
+// such as bind_failed for an inbound whose port is taken.
+const ErrorCodeKey = "xray-error-code"
+
 // Commander is a Xray feature that provides gRPC methods to external clients.
 type Commander struct {
 	sync.Mutex
 	server   *grpc.Server
 	services []Service
 	ohm      outbound.Manager
+	dns      dns.Client
 	tag      string
 	listen   string
 }
@@ -30,8 +38,9 @@ func NewCommander(ctx context.Context, config *Config) (*Commander, error) {
 		listen: config.Listen,
 	}
 
-	common.Must(core.RequireFeatures(ctx, func(om outbound.Manager) {
+	common.Must(core.RequireFeatures(ctx, func(om outbound.Manager, d dns.Client) {
 		c.ohm = om
+		c.dns = d
 	}))
 
 	for _, rawConfig := range config.Service {
@@ -53,33 +62,46 @@ func NewCommander(ctx context.Context, config *Config) (*Commander, error) {
 	return c, nil
 }
 
+// maxRecvMsgSize lets geo data files be uploaded through the routing API, as they're larger than
+// the default of gRPC.
+const maxRecvMsgSize = 256 * 1024 * 1024
+
 // Type implements common.HasType.
 func (c *Commander) Type() interface{} {
 	return (*Commander)(nil)
 }
 
+// diagnose tells the code of the Diagnostic of the error of a failed call in its trailer.
+func diagnose(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if diag := errors.DiagnosticOf(err); diag != nil {
+		grpc.SetTrailer(ctx, metadata.Pairs(ErrorCodeKey, diag.Code))
+	}
+	return resp, err
+}
+
 // Start implements common.Runnable.
 func (c *Commander) Start() error {
 	c.Lock()
-	c.server = grpc.NewServer()
+	c.server = grpc.NewServer(grpc.MaxRecvMsgSize(maxRecvMsgSize), grpc.ChainUnaryInterceptor(diagnose))
 	for _, service := range c.services {
 		service.Register(c.server)
 	}
 	c.Unlock()
 
-	var listen = func(listener net.Listener) {
+	var serve = func(listener net.Listener) {
 		if err := c.server.Serve(listener); err != nil {
 			errors.LogErrorInner(context.Background(), err, "failed to start grpc server")
 		}
 	}
 
 	if len(c.listen) > 0 {
-		if l, err := net.Listen("tcp", c.listen); err != nil {
+		if l, err := listen.TCP(context.Background(), c.listen, c.dns); err != nil {
 			errors.LogErrorInner(context.Background(), err, "API server failed to listen on ", c.listen)
-			return err
+			return errors.Diagnose(errors.CodeAPIPortBusy, err)
 		} else {
 			errors.LogInfo(context.Background(), "API server listening on ", l.Addr())
-			go listen(l)
+			go serve(l)
 		}
 		return nil
 	}
@@ -89,7 +111,7 @@ func (c *Commander) Start() error {
 		done:   done.New(),
 	}
 
-	go listen(listener)
+	go serve(listener)
 
 	if err := c.ohm.RemoveHandler(context.Background(), c.tag); err != nil {
 		errors.LogInfoInner(context.Background(), err, "failed to remove existing handler")
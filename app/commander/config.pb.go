@@ -29,7 +29,9 @@ type Config struct {
 
 	// Tag of the outbound handler that handles grpc connections.
 	Tag string `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
-	// Network address of commander grpc service.
+	// Network address of commander grpc service. The host may be a hostname,
+	// resolved through the DNS of Xray, or "iface:name" for the addresses of a
+	// network interface.
 	Listen string `protobuf:"bytes,3,opt,name=listen,proto3" json:"listen,omitempty"`
 	// Services that supported by this server. All services must implement Service
 	// interface.
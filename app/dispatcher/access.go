@@ -0,0 +1,83 @@
+package dispatcher
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/transport"
+)
+
+// trafficCounter is a stats.Counter of the bytes of a single connection.
+type trafficCounter struct {
+	value atomic.Int64
+}
+
+func (c *trafficCounter) Value() int64 {
+	return c.value.Load()
+}
+
+func (c *trafficCounter) Set(v int64) int64 {
+	return c.value.Swap(v)
+}
+
+func (c *trafficCounter) Add(v int64) int64 {
+	return c.value.Add(v) - v
+}
+
+// closedAccess records the access of a connection once more when the connection closes, with the
+// bytes it carried and how long it lasted.
+type closedAccess struct {
+	msg   log.AccessMessage
+	start time.Time
+	up    trafficCounter
+	down  trafficCounter
+	once  sync.Once
+}
+
+func (a *closedAccess) record() {
+	a.once.Do(func() {
+		msg := a.msg
+		msg.Status = log.AccessClosed
+		msg.Reason = nil
+		msg.Up = a.up.Value()
+		msg.Down = a.down.Value()
+		msg.Duration = time.Since(a.start)
+		log.Record(&msg)
+	})
+}
+
+// closingWriter calls the record of its access when the outbound is done with the connection.
+type closingWriter struct {
+	buf.Writer
+	access *closedAccess
+}
+
+func (w *closingWriter) Close() error {
+	w.access.record()
+	return common.Close(w.Writer)
+}
+
+func (w *closingWriter) Interrupt() {
+	w.access.record()
+	common.Interrupt(w.Writer)
+}
+
+// Unwrap returns the writer w writes to, so that spliced traffic is counted through it.
+func (w *closingWriter) Unwrap() buf.Writer {
+	return w.Writer
+}
+
+// recordClosedAccess counts the traffic of link, and records msg once more with it when link
+// is closed.
+func recordClosedAccess(msg *log.AccessMessage, link *transport.Link) {
+	access := &closedAccess{msg: *msg, start: time.Now()}
+	link.Reader = &SizeStatReader{Counter: &access.up, Reader: link.Reader}
+	link.Writer = &closingWriter{
+		Writer: &SizeStatWriter{Counter: &access.down, Writer: link.Writer},
+		access: access,
+	}
+}
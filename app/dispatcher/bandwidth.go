@@ -0,0 +1,161 @@
+package dispatcher
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/transport"
+)
+
+// bandwidthLimiters are the limiters of the two directions of a bandwidth class.
+type bandwidthLimiters struct {
+	uplink   rateLimiter
+	downlink rateLimiter
+}
+
+// limitBandwidth makes the traffic of link count against the limits of class, waiting as long
+// as the connections of the class go beyond them.
+func (d *DefaultDispatcher) limitBandwidth(ctx context.Context, class *routing.BandwidthClass, link *transport.Link) {
+	markShaped(ctx)
+	v, _ := d.bandwidth.LoadOrStore(class, new(bandwidthLimiters))
+	limiters := v.(*bandwidthLimiters)
+	link.Reader = &limitedReader{Reader: link.Reader, ctx: ctx, class: class, limiter: &limiters.uplink}
+	link.Writer = &limitedWriter{Writer: link.Writer, ctx: ctx, class: class, limiter: &limiters.downlink}
+}
+
+// shape limits the traffic of link by the rates of shaping, and counts it against its quotas.
+func (d *DefaultDispatcher) shape(ctx context.Context, shaping *routing.Shaping, link *transport.Link) {
+	markShaped(ctx)
+	for _, c := range shaping.Counters {
+		link.Reader = &SizeStatReader{Counter: c, Reader: link.Reader}
+		link.Writer = &SizeStatWriter{Counter: c, Writer: link.Writer}
+	}
+	for _, class := range shaping.Classes {
+		d.limitBandwidth(ctx, class, link)
+	}
+}
+
+// markShaped keeps the traffic of the connection on the link, rather than spliced past it.
+func markShaped(ctx context.Context) {
+	if outbounds := session.OutboundsFromContext(ctx); len(outbounds) > 0 {
+		outbounds[len(outbounds)-1].Shaped = true
+	}
+}
+
+// rateLimiter is a token bucket holding up to a second of traffic. Tokens taken beyond those
+// available are owed, so that the connections sharing it wait in turn.
+type rateLimiter struct {
+	access sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes n tokens at rate per second, and returns how long to wait before using them.
+func (l *rateLimiter) reserve(n int32, rate int64) time.Duration {
+	if rate <= 0 || n <= 0 {
+		return 0
+	}
+	l.access.Lock()
+	defer l.access.Unlock()
+
+	now := time.Now()
+	if l.last.IsZero() {
+		l.tokens = float64(rate)
+	} else {
+		l.tokens += now.Sub(l.last).Seconds() * float64(rate)
+	}
+	l.last = now
+	if l.tokens > float64(rate) {
+		l.tokens = float64(rate)
+	}
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / float64(rate) * float64(time.Second))
+}
+
+// wait blocks until n bytes may pass under the current rate of class.
+func (l *rateLimiter) wait(ctx context.Context, class *routing.BandwidthClass, n int32) error {
+	delay := l.reserve(n, class.Rate())
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// limitedReader limits the uplink of a connection.
+type limitedReader struct {
+	buf.Reader
+	ctx     context.Context
+	class   *routing.BandwidthClass
+	limiter *rateLimiter
+}
+
+// ReadMultiBuffer implements buf.Reader.
+func (r *limitedReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	mb, err := r.Reader.ReadMultiBuffer()
+	if werr := r.limiter.wait(r.ctx, r.class, mb.Len()); werr != nil && err == nil {
+		buf.ReleaseMulti(mb)
+		return nil, werr
+	}
+	return mb, err
+}
+
+// ReadMultiBufferTimeout implements buf.TimeoutReader.
+func (r *limitedReader) ReadMultiBufferTimeout(timeout time.Duration) (buf.MultiBuffer, error) {
+	tr, ok := r.Reader.(buf.TimeoutReader)
+	if !ok {
+		return nil, buf.ErrNotTimeoutReader
+	}
+	mb, err := tr.ReadMultiBufferTimeout(timeout)
+	if werr := r.limiter.wait(r.ctx, r.class, mb.Len()); werr != nil && err == nil {
+		buf.ReleaseMulti(mb)
+		return nil, werr
+	}
+	return mb, err
+}
+
+// Interrupt implements common.Interruptible.
+func (r *limitedReader) Interrupt() {
+	common.Interrupt(r.Reader)
+}
+
+// limitedWriter limits the downlink of a connection.
+type limitedWriter struct {
+	buf.Writer
+	ctx     context.Context
+	class   *routing.BandwidthClass
+	limiter *rateLimiter
+}
+
+// WriteMultiBuffer implements buf.Writer.
+func (w *limitedWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	if err := w.limiter.wait(w.ctx, w.class, mb.Len()); err != nil {
+		buf.ReleaseMulti(mb)
+		return err
+	}
+	return w.Writer.WriteMultiBuffer(mb)
+}
+
+// Close implements common.Closable.
+func (w *limitedWriter) Close() error {
+	return common.Close(w.Writer)
+}
+
+// Interrupt implements common.Interruptible.
+func (w *limitedWriter) Interrupt() {
+	common.Interrupt(w.Writer)
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/crash"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/common/net"
@@ -27,14 +28,19 @@ import (
 
 var errSniffingTimeout = errors.New("timeout on sniffing")
 
+// defaultSniffTimeout is the longest wait for content to sniff, unless set by the inbound.
+const defaultSniffTimeout = 200 * time.Millisecond
+
 type cachedReader struct {
 	sync.Mutex
 	reader *pipe.Reader
 	cache  buf.MultiBuffer
 }
 
-func (r *cachedReader) Cache(b *buf.Buffer) {
-	mb, _ := r.reader.ReadMultiBufferTimeout(time.Millisecond * 100)
+// Cache waits up to timeout for more content, and copies all the content so far into b. It returns
+// the error of reading the content, buf.ErrReadTimeout if none came.
+func (r *cachedReader) Cache(b *buf.Buffer, timeout time.Duration) error {
+	mb, err := r.reader.ReadMultiBufferTimeout(timeout)
 	r.Lock()
 	if !mb.IsEmpty() {
 		r.cache, _ = buf.MergeMulti(r.cache, mb)
@@ -50,6 +56,7 @@ func (r *cachedReader) Cache(b *buf.Buffer) {
 	n := r.cache.Copy(rawBytes)
 	b.Resize(0, int32(n))
 	r.Unlock()
+	return err
 }
 
 func (r *cachedReader) readInternal() buf.MultiBuffer {
@@ -94,12 +101,19 @@ func (r *cachedReader) Interrupt() {
 
 // DefaultDispatcher is a default implementation of Dispatcher.
 type DefaultDispatcher struct {
-	ohm    outbound.Manager
-	router routing.Router
-	policy policy.Manager
-	stats  stats.Manager
-	dns    dns.Client
-	fdns   dns.FakeDNSEngine
+	ohm     outbound.Manager
+	router  routing.Router
+	policy  policy.Manager
+	stats   stats.Manager
+	dns     dns.Client
+	fdns    dns.FakeDNSEngine
+	tracker routing.ConnectionTracker
+	shaper  routing.TrafficShaper
+	domains *domainStats
+	// ruleStats counts connections and traffic against the rules routing them.
+	ruleStats bool
+
+	bandwidth sync.Map // *routing.BandwidthClass -> *bandwidthLimiters
 }
 
 func init() {
@@ -109,6 +123,12 @@ func init() {
 			core.OptionalFeatures(ctx, func(fdns dns.FakeDNSEngine) {
 				d.fdns = fdns
 			})
+			core.OptionalFeatures(ctx, func(tracker routing.ConnectionTracker) {
+				d.tracker = tracker
+			})
+			core.OptionalFeatures(ctx, func(shaper routing.TrafficShaper) {
+				d.shaper = shaper
+			})
 			return d.Init(config.(*Config), om, router, pm, sm, dc)
 		}); err != nil {
 			return nil, err
@@ -124,6 +144,10 @@ func (d *DefaultDispatcher) Init(config *Config, om outbound.Manager, router rou
 	d.policy = pm
 	d.stats = sm
 	d.dns = dns
+	if s := pm.ForSystem().Stats; s.DomainUplink || s.DomainDownlink {
+		d.domains = newDomainStats(sm, s)
+	}
+	d.ruleStats = pm.ForSystem().Stats.Rule
 	return nil
 }
 
@@ -203,6 +227,13 @@ func (d *DefaultDispatcher) shouldOverride(ctx context.Context, result SniffResu
 	if domain == "" {
 		return false
 	}
+	protocolString := result.Protocol()
+	if resComp, ok := result.(SnifferResultComposite); ok {
+		protocolString = resComp.ProtocolForDomainResult()
+	}
+	if request.DomainExcluder != nil && request.DomainExcluder.Excluded(protocolString, domain) {
+		return false
+	}
 	for _, d := range request.ExcludeForDomain {
 		if strings.HasPrefix(d, "regexp:") {
 			pattern := d[7:]
@@ -220,10 +251,6 @@ func (d *DefaultDispatcher) shouldOverride(ctx context.Context, result SniffResu
 			}
 		}
 	}
-	protocolString := result.Protocol()
-	if resComp, ok := result.(SnifferResultComposite); ok {
-		protocolString = resComp.ProtocolForDomainResult()
-	}
 	for _, p := range request.OverrideDestinationForProtocol {
 		if strings.HasPrefix(protocolString, p) || strings.HasPrefix(p, protocolString) {
 			return true
@@ -243,6 +270,16 @@ func (d *DefaultDispatcher) shouldOverride(ctx context.Context, result SniffResu
 	return false
 }
 
+// withTimeouts bounds the stages of handling the connection, such as dialing, by the policy of
+// its user.
+func (d *DefaultDispatcher) withTimeouts(ctx context.Context) context.Context {
+	var level uint32
+	if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.User != nil {
+		level = inbound.User.Level
+	}
+	return policy.ContextWithTimeouts(ctx, d.policy.ForLevel(level).Timeouts)
+}
+
 // Dispatch implements routing.Dispatcher.
 func (d *DefaultDispatcher) Dispatch(ctx context.Context, destination net.Destination) (*transport.Link, error) {
 	if !destination.IsValid() {
@@ -261,18 +298,29 @@ func (d *DefaultDispatcher) Dispatch(ctx context.Context, destination net.Destin
 		content = new(session.Content)
 		ctx = session.ContextWithContent(ctx, content)
 	}
+	ctx = d.withTimeouts(ctx)
+
+	if bp := policy.BufferPolicyFromContext(ctx); bp.Auto && bp.PerConnection >= 0 {
+		// The outbound handler resizes the buffers once it's known.
+		ctx = pipe.ContextWithLimit(ctx, pipe.NewLimit(bp.PerConnection))
+	}
 
 	sniffingRequest := content.SniffingRequest
+	if content.OwnTransport && sniffingRequest.Enabled {
+		errors.LogDebug(ctx, "skipped sniffing the packets of our own transport")
+		sniffingRequest.Enabled = false
+	}
 	inbound, outbound := d.getLink(ctx)
 	if !sniffingRequest.Enabled {
 		go d.routedDispatch(ctx, outbound, destination)
 	} else {
 		go func() {
+			defer recoverLink(ctx, outbound)
 			cReader := &cachedReader{
 				reader: outbound.Reader.(*pipe.Reader),
 			}
 			outbound.Reader = cReader
-			result, err := sniffer(ctx, cReader, sniffingRequest.MetadataOnly, destination.Network)
+			result, err := sniffer(ctx, cReader, &sniffingRequest, destination.Network)
 			if err == nil {
 				content.Protocol = result.Protocol()
 			}
@@ -318,7 +366,12 @@ func (d *DefaultDispatcher) DispatchLink(ctx context.Context, destination net.De
 		content = new(session.Content)
 		ctx = session.ContextWithContent(ctx, content)
 	}
+	ctx = d.withTimeouts(ctx)
 	sniffingRequest := content.SniffingRequest
+	if content.OwnTransport && sniffingRequest.Enabled {
+		errors.LogDebug(ctx, "skipped sniffing the packets of our own transport")
+		sniffingRequest.Enabled = false
+	}
 	if !sniffingRequest.Enabled {
 		d.routedDispatch(ctx, outbound, destination)
 	} else {
@@ -326,7 +379,7 @@ func (d *DefaultDispatcher) DispatchLink(ctx context.Context, destination net.De
 			reader: outbound.Reader.(*pipe.Reader),
 		}
 		outbound.Reader = cReader
-		result, err := sniffer(ctx, cReader, sniffingRequest.MetadataOnly, destination.Network)
+		result, err := sniffer(ctx, cReader, &sniffingRequest, destination.Network)
 		if err == nil {
 			content.Protocol = result.Protocol()
 		}
@@ -354,7 +407,9 @@ func (d *DefaultDispatcher) DispatchLink(ctx context.Context, destination net.De
 	return nil
 }
 
-func sniffer(ctx context.Context, cReader *cachedReader, metadataOnly bool, network net.Network) (SniffResult, error) {
+// sniffer sniffs the content of cReader for request, waiting for more of it until the protocol is
+// identified or ruled out by all sniffers, up to the timeout and byte limit of request.
+func sniffer(ctx context.Context, cReader *cachedReader, request *session.SniffingRequest, network net.Network) (SniffResult, error) {
 	payload := buf.New()
 	defer payload.Release()
 
@@ -362,33 +417,45 @@ func sniffer(ctx context.Context, cReader *cachedReader, metadataOnly bool, netw
 
 	metaresult, metadataErr := sniffer.SniffMetadata(ctx)
 
-	if metadataOnly {
+	if request.MetadataOnly {
 		return metaresult, metadataErr
 	}
 
+	timeout := request.Timeout
+	if timeout <= 0 {
+		timeout = defaultSniffTimeout
+	}
+	maxBytes := request.MaxBytes
+	if maxBytes <= 0 || maxBytes > buf.Size {
+		maxBytes = buf.Size
+	}
+	deadline := time.Now().Add(timeout)
 	contentResult, contentErr := func() (SniffResult, error) {
-		totalAttempt := 0
 		for {
 			select {
 			case <-ctx.Done():
 				return nil, ctx.Err()
 			default:
-				totalAttempt++
-				if totalAttempt > 2 {
-					return nil, errSniffingTimeout
-				}
+			}
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return nil, errSniffingTimeout
+			}
 
-				cReader.Cache(payload)
-				if !payload.IsEmpty() {
-					result, err := sniffer.Sniff(ctx, payload.Bytes(), network)
-					if err != common.ErrNoClue {
-						return result, err
-					}
-				}
-				if payload.IsFull() {
-					return nil, errUnknownContent
+			err := cReader.Cache(payload, remaining)
+			if !payload.IsEmpty() {
+				result, err := sniffer.Sniff(ctx, payload.Bytes(), network)
+				if err != common.ErrNoClue {
+					return result, err
 				}
 			}
+			if payload.Len() >= maxBytes {
+				return nil, errUnknownContent
+			}
+			if err != nil && err != buf.ErrReadTimeout {
+				// No more content is coming.
+				return nil, errUnknownContent
+			}
 		}
 	}()
 	if contentErr != nil && metadataErr == nil {
@@ -399,7 +466,35 @@ func sniffer(ctx context.Context, cReader *cachedReader, metadataOnly bool, netw
 	}
 	return contentResult, contentErr
 }
+
+// trackedOutbound returns the outbound of the connection which announced destination, if any.
+func (d *DefaultDispatcher) trackedOutbound(ctx context.Context, destination net.Destination) string {
+	if d.tracker == nil {
+		return ""
+	}
+	return d.tracker.OutboundFor(ctx, destination)
+}
+
+// recoverLink contains a panic of the goroutine serving link, and interrupts link so that
+// the inbound side of the connection ends as well. It must be deferred directly.
+func recoverLink(ctx context.Context, link *transport.Link) {
+	if v := recover(); v != nil {
+		crash.Handle(ctx, "outbound", v)
+		common.Interrupt(link.Reader)
+		common.Interrupt(link.Writer)
+	}
+}
+
+// errNotConnected is reported to the inbound waiting for a request to be connected when it's
+// served without reaching an outbound which connects it.
+var errNotConnected = errors.New("request not connected by any outbound")
+
 func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.Link, destination net.Destination) {
+	defer recoverLink(ctx, link)
+	if connect := session.ConnectFromContext(ctx); connect != nil {
+		// Outbounds report first, unless the request is rejected before reaching one.
+		defer connect.Report(errNotConnected)
+	}
 	outbounds := session.OutboundsFromContext(ctx)
 	ob := outbounds[len(outbounds)-1]
 	if hosts, ok := d.dns.(dns.HostsLookup); ok && destination.Address.Family().IsDomain() {
@@ -414,12 +509,19 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 			}
 		}
 	}
+	if bypassesLocal(ctx, destination) {
+		d.bypassLocal(ctx, link, destination)
+		return
+	}
 
 	var handler outbound.Handler
 
 	routingLink := routing_session.AsRoutingContext(ctx)
 	inTag := routingLink.GetInboundTag()
 	isPickRoute := 0
+	var mirror *routing.Mirror
+	var class *routing.BandwidthClass
+	var ruleTag string
 	if forcedOutboundTag := session.GetForcedOutboundTagFromContext(ctx); forcedOutboundTag != "" {
 		ctx = session.SetForcedOutboundTagToContext(ctx, "")
 		if h := d.ohm.GetHandler(forcedOutboundTag); h != nil {
@@ -432,11 +534,30 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 			common.Interrupt(link.Reader)
 			return
 		}
+	} else if trackedTag := d.trackedOutbound(ctx, destination); trackedTag != "" {
+		if h := d.ohm.GetHandler(trackedTag); h != nil {
+			isPickRoute = 2
+			errors.LogInfo(ctx, "taking detour [", trackedTag, "] of the related connection for [", destination, "]")
+			handler = h
+		}
 	} else if d.router != nil {
 		if route, err := d.router.PickRoute(routingLink); err == nil {
+			if mr, ok := route.(routing.MirroredRoute); ok {
+				mirror = mr.GetMirror()
+			}
+			if cr, ok := route.(routing.ClassifiedRoute); ok {
+				class = cr.GetBandwidthClass()
+			}
 			outTag := route.GetOutboundTag()
+			if sm, ok := d.ohm.(outbound.StandbyManager); ok {
+				if active := sm.ActiveFor(outTag); active != outTag {
+					errors.LogInfo(ctx, "outbound [", outTag, "] failed over to standby [", active, "]")
+					outTag = active
+				}
+			}
 			if h := d.ohm.GetHandler(outTag); h != nil {
 				isPickRoute = 2
+				ruleTag = route.GetRuleTag()
 				if route.GetRuleTag() == "" {
 					errors.LogInfo(ctx, "taking detour [", outTag, "] for [", destination, "]")
 				} else {
@@ -462,7 +583,36 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 		return
 	}
 
+	var shaping *routing.Shaping
+	if d.shaper != nil {
+		var err error
+		if shaping, err = d.shaper.Shape(ctx, handler.Tag()); err != nil {
+			errors.LogInfoInner(ctx, err, "rejected connection to ", destination)
+			common.Close(link.Writer)
+			common.Interrupt(link.Reader)
+			return
+		}
+	}
+
 	ob.Tag = handler.Tag()
+	if d.tracker != nil {
+		link.Writer = d.tracker.Track(ctx, destination, handler.Tag(), link.Writer)
+	}
+	if mirror != nil {
+		d.mirror(ctx, mirror, destination, link)
+	}
+	if class != nil {
+		d.limitBandwidth(ctx, class, link)
+	}
+	if shaping != nil {
+		d.shape(ctx, shaping, link)
+	}
+	if d.domains != nil && destination.Address.Family().IsDomain() {
+		d.domains.track(destination.Address.Domain(), link)
+	}
+	if d.ruleStats && ruleTag != "" {
+		d.countRule(ruleTag, link)
+	}
 	if accessMessage := log.AccessMessageFromContext(ctx); accessMessage != nil {
 		if tag := handler.Tag(); tag != "" {
 			if inTag == "" {
@@ -475,7 +625,12 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 				accessMessage.Detour = inTag + " >> " + tag
 			}
 		}
+		accessMessage.Inbound = inTag
+		accessMessage.Outbound = handler.Tag()
 		log.Record(accessMessage)
+		if log.ClosedAccessesRecorded() {
+			recordClosedAccess(accessMessage, link)
+		}
 	}
 
 	handler.Dispatch(ctx, link)
@@ -0,0 +1,92 @@
+package dispatcher
+
+import (
+	"strings"
+
+	"github.com/xtls/xray-core/common/cache"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/transport"
+	"golang.org/x/net/publicsuffix"
+)
+
+// defaultDomainLimit is the most domains counted at a time, if the policy doesn't tell.
+const defaultDomainLimit = 1000
+
+// domainCounters are the counters of a domain.
+type domainCounters struct {
+	uplink   stats.Counter
+	downlink stats.Counter
+}
+
+// domainStats counts the traffic of connections by the registered domain of their destinations,
+// as the counters "domain>>>[domain]>>>traffic>>>uplink" and "...>>>downlink". Only the counters
+// of the most recently seen domains are kept, so that their number stays bounded.
+type domainStats struct {
+	stats    stats.Manager
+	policy   policy.SystemStats
+	counters cache.Lru
+}
+
+func newDomainStats(sm stats.Manager, p policy.SystemStats) *domainStats {
+	s := &domainStats{
+		stats:  sm,
+		policy: p,
+	}
+	limit := p.DomainLimit
+	if limit <= 0 {
+		limit = defaultDomainLimit
+	}
+	s.counters = cache.NewLruWithEvict(limit, func(key, _ interface{}) {
+		domain := key.(string)
+		s.stats.UnregisterCounter(domainCounterName(domain, "uplink"))
+		s.stats.UnregisterCounter(domainCounterName(domain, "downlink"))
+	})
+	return s
+}
+
+func domainCounterName(domain, direction string) string {
+	return "domain>>>" + domain + ">>>traffic>>>" + direction
+}
+
+// registeredDomain returns the eTLD+1 of domain, such as "example.co.uk" for "www.example.co.uk",
+// or domain itself if it has none.
+func registeredDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if etld1, err := publicsuffix.EffectiveTLDPlusOne(domain); err == nil {
+		return etld1
+	}
+	return domain
+}
+
+func (s *domainStats) get(domain string) *domainCounters {
+	if v, found := s.counters.Get(domain); found {
+		return v.(*domainCounters)
+	}
+	c := new(domainCounters)
+	if s.policy.DomainUplink {
+		c.uplink, _ = stats.GetOrRegisterCounter(s.stats, domainCounterName(domain, "uplink"))
+	}
+	if s.policy.DomainDownlink {
+		c.downlink, _ = stats.GetOrRegisterCounter(s.stats, domainCounterName(domain, "downlink"))
+	}
+	s.counters.Put(domain, c)
+	return c
+}
+
+// track counts the traffic of link against the registered domain of domain.
+func (s *domainStats) track(domain string, link *transport.Link) {
+	c := s.get(registeredDomain(domain))
+	if c.uplink != nil {
+		link.Reader = &SizeStatReader{
+			Counter: c.uplink,
+			Reader:  link.Reader,
+		}
+	}
+	if c.downlink != nil {
+		link.Writer = &SizeStatWriter{
+			Counter: c.downlink,
+			Writer:  link.Writer,
+		}
+	}
+}
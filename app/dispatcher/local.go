@@ -0,0 +1,59 @@
+package dispatcher
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/internet"
+)
+
+// bypassesLocal tells whether the connection of ctx to destination goes to a service of this
+// host directly. Only TCP is bypassed, and only for inbounds which ask for it, as proxying to the
+// localhost of a remote server is legitimate otherwise.
+func bypassesLocal(ctx context.Context, destination net.Destination) bool {
+	return destination.Network == net.Network_TCP && session.LocalBypassFromContext(ctx) &&
+		internet.IsLocalService(destination)
+}
+
+// bypassLocal serves link by dialing destination, a service of this host, without routing and
+// outbounds, which would add their latency or send the connection away and back again.
+func (d *DefaultDispatcher) bypassLocal(ctx context.Context, link *transport.Link, destination net.Destination) {
+	errors.LogInfo(ctx, "bypassing outbounds for local ", destination)
+	conn, err := internet.DialSystem(ctx, destination, nil)
+	if connect := session.ConnectFromContext(ctx); connect != nil {
+		connect.Report(err)
+	}
+	if err != nil {
+		errors.LogInfoInner(ctx, err, "failed to dial local ", destination)
+		common.Close(link.Writer)
+		common.Interrupt(link.Reader)
+		return
+	}
+	defer conn.Close()
+
+	timeouts := policy.TimeoutsFromContext(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, timeouts.ConnectionIdle)
+
+	requestDone := func() error {
+		defer timer.SetTimeout(timeouts.DownlinkOnly)
+		return buf.Copy(link.Reader, buf.NewWriter(conn), buf.UpdateActivity(timer))
+	}
+	responseDone := func() error {
+		defer timer.SetTimeout(timeouts.UplinkOnly)
+		return buf.Copy(buf.NewReader(conn), link.Writer, buf.UpdateActivity(timer))
+	}
+	if err := task.Run(ctx, requestDone, task.OnSuccess(responseDone, task.Close(link.Writer))); err != nil {
+		errors.LogInfoInner(ctx, err, "local connection ends")
+		common.Interrupt(link.Reader)
+		common.Interrupt(link.Writer)
+	}
+}
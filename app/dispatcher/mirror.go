@@ -0,0 +1,211 @@
+package dispatcher
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	c "github.com/xtls/xray-core/common/ctx"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+// mirrorBufferSize is the most data waiting to be copied to a mirror. More is dropped, so that
+// a slow mirror doesn't hold back the mirrored connection.
+const mirrorBufferSize = 512 * 1024
+
+// mirror copies the traffic of link to m, if the connection is sampled. The traffic of a mirrored
+// connection is kept on the link, as splicing it would bypass the mirror.
+func (d *DefaultDispatcher) mirror(ctx context.Context, m *routing.Mirror, destination net.Destination, link *transport.Link) {
+	if m.SampleRate > 0 && m.SampleRate < 1 && rand.Float32() >= m.SampleRate {
+		return
+	}
+
+	if w := d.openMirror(ctx, m, destination); w != nil {
+		markShaped(ctx)
+		link.Reader = &mirrorReader{Reader: link.Reader, mirror: newMirrorCopier(w, m.MaxBytes)}
+	}
+	if m.Downlink {
+		if w := d.openMirror(ctx, m, destination); w != nil {
+			markShaped(ctx)
+			link.Writer = &mirrorWriter{Writer: link.Writer, mirror: newMirrorCopier(w, m.MaxBytes)}
+		}
+	}
+}
+
+// openMirror starts a stream to m, and returns the writer of its content.
+func (d *DefaultDispatcher) openMirror(ctx context.Context, m *routing.Mirror, destination net.Destination) buf.Writer {
+	reader, writer := pipe.New(pipe.WithSizeLimit(mirrorBufferSize), pipe.DiscardOverflow())
+
+	if m.OutboundTag != "" {
+		handler := d.ohm.GetHandler(m.OutboundTag)
+		if handler == nil {
+			errors.LogWarning(ctx, "non existing mirror outbound: ", m.OutboundTag)
+			return nil
+		}
+		mctx := c.ContextWithID(core.ToBackgroundDetachedContext(ctx), c.IDFromContext(ctx))
+		mctx = session.ContextWithOutbounds(mctx, []*session.Outbound{{
+			Target:         destination,
+			OriginalTarget: destination,
+			Tag:            m.OutboundTag,
+		}})
+		errors.LogInfo(ctx, "mirroring to [", m.OutboundTag, "]")
+		go handler.Dispatch(mctx, &transport.Link{Reader: reader, Writer: buf.Discard})
+		return writer
+	}
+
+	network := "tcp"
+	if strings.HasPrefix(m.Address, "/") || strings.HasPrefix(m.Address, "@") {
+		network = "unix"
+	}
+	errors.LogInfo(ctx, "mirroring to ", m.Address)
+	go func() {
+		defer common.Interrupt(reader)
+		dialer := net.Dialer{Timeout: policy.TimeoutsFromContext(ctx).Dial}
+		conn, err := dialer.Dial(network, m.Address)
+		if err != nil {
+			errors.LogWarningInner(ctx, err, "failed to connect to mirror ", m.Address)
+			return
+		}
+		defer conn.Close()
+		if err := buf.Copy(reader, buf.NewWriter(conn)); err != nil {
+			errors.LogDebugInner(ctx, err, "mirror ", m.Address, " ends")
+		}
+	}()
+	return writer
+}
+
+// mirrorCopier copies data to a mirror, up to a number of bytes.
+type mirrorCopier struct {
+	sync.Mutex
+	writer    buf.Writer
+	remaining int64
+	limited   bool
+}
+
+func newMirrorCopier(writer buf.Writer, maxBytes int64) *mirrorCopier {
+	return &mirrorCopier{
+		writer:    writer,
+		remaining: maxBytes,
+		limited:   maxBytes > 0,
+	}
+}
+
+func (m *mirrorCopier) copy(mb buf.MultiBuffer) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.writer == nil || mb.IsEmpty() {
+		return
+	}
+	var copied buf.MultiBuffer
+	for _, b := range mb {
+		data := b.Bytes()
+		if m.limited && int64(len(data)) > m.remaining {
+			data = data[:m.remaining]
+		}
+		if len(data) == 0 {
+			continue
+		}
+		nb := buf.New()
+		nb.Write(data)
+		nb.UDP = b.UDP
+		copied = append(copied, nb)
+		if m.limited {
+			m.remaining -= int64(len(data))
+			if m.remaining == 0 {
+				break
+			}
+		}
+	}
+	if !copied.IsEmpty() {
+		m.writer.WriteMultiBuffer(copied)
+	}
+	if m.limited && m.remaining == 0 {
+		m.closeLocked()
+	}
+}
+
+func (m *mirrorCopier) close() {
+	m.Lock()
+	defer m.Unlock()
+
+	m.closeLocked()
+}
+
+func (m *mirrorCopier) closeLocked() {
+	if m.writer != nil {
+		common.Close(m.writer)
+		m.writer = nil
+	}
+}
+
+// mirrorReader copies the uplink of a connection to a mirror.
+type mirrorReader struct {
+	buf.Reader
+	mirror *mirrorCopier
+}
+
+// ReadMultiBuffer implements buf.Reader.
+func (r *mirrorReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	mb, err := r.Reader.ReadMultiBuffer()
+	r.mirror.copy(mb)
+	if err != nil {
+		r.mirror.close()
+	}
+	return mb, err
+}
+
+// ReadMultiBufferTimeout implements buf.TimeoutReader.
+func (r *mirrorReader) ReadMultiBufferTimeout(timeout time.Duration) (buf.MultiBuffer, error) {
+	tr, ok := r.Reader.(buf.TimeoutReader)
+	if !ok {
+		return nil, buf.ErrNotTimeoutReader
+	}
+	mb, err := tr.ReadMultiBufferTimeout(timeout)
+	r.mirror.copy(mb)
+	if err != nil && err != buf.ErrReadTimeout {
+		r.mirror.close()
+	}
+	return mb, err
+}
+
+// Interrupt implements common.Interruptible.
+func (r *mirrorReader) Interrupt() {
+	r.mirror.close()
+	common.Interrupt(r.Reader)
+}
+
+// mirrorWriter copies the downlink of a connection to a mirror.
+type mirrorWriter struct {
+	buf.Writer
+	mirror *mirrorCopier
+}
+
+// WriteMultiBuffer implements buf.Writer.
+func (w *mirrorWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	w.mirror.copy(mb)
+	return w.Writer.WriteMultiBuffer(mb)
+}
+
+// Close implements common.Closable.
+func (w *mirrorWriter) Close() error {
+	w.mirror.close()
+	return common.Close(w.Writer)
+}
+
+// Interrupt implements common.Interruptible.
+func (w *mirrorWriter) Interrupt() {
+	w.mirror.close()
+	common.Interrupt(w.Writer)
+}
@@ -0,0 +1,28 @@
+package dispatcher
+
+import (
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/transport"
+)
+
+// countRule counts the connection of link and its traffic against the rule tagged tag, which
+// routed it, if the router registered the counters of the rule.
+func (d *DefaultDispatcher) countRule(tag string, link *transport.Link) {
+	connections := d.stats.GetCounter(routing.RuleCounterName(tag, "connections"))
+	if connections == nil {
+		return
+	}
+	connections.Add(1)
+	if c := d.stats.GetCounter(routing.RuleCounterName(tag, "traffic>>>uplink")); c != nil {
+		link.Reader = &SizeStatReader{
+			Counter: c,
+			Reader:  link.Reader,
+		}
+	}
+	if c := d.stats.GetCounter(routing.RuleCounterName(tag, "traffic>>>downlink")); c != nil {
+		link.Writer = &SizeStatWriter{
+			Counter: c,
+			Writer:  link.Writer,
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package dispatcher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+const xrayKey core.XrayKey = 1
+
+// tlsPrefix returns n bytes starting with the header of a TLS record too long to complete,
+// which keeps the TLS sniffer waiting for more.
+func tlsPrefix(n int) []byte {
+	b := make([]byte, n)
+	copy(b, []byte{0x16, 0x03, 0x01, 0xff, 0xff})
+	return b
+}
+
+func TestSnifferEarlyExit(t *testing.T) {
+	v, err := core.New(&core.Config{})
+	common.Must(err)
+	ctx := context.WithValue(context.Background(), xrayKey, v)
+
+	tests := []struct {
+		name    string
+		content []byte
+		request *session.SniffingRequest
+		want    error
+	}{
+		{
+			name:    "timeout",
+			content: tlsPrefix(16),
+			request: &session.SniffingRequest{Timeout: 50 * time.Millisecond},
+			want:    errSniffingTimeout,
+		},
+		{
+			name:    "byte limit",
+			content: tlsPrefix(64),
+			request: &session.SniffingRequest{Timeout: 10 * time.Second, MaxBytes: 32},
+			want:    errUnknownContent,
+		},
+		{
+			name:    "byte limit above buffer size",
+			content: tlsPrefix(buf.Size + 1),
+			request: &session.SniffingRequest{Timeout: 10 * time.Second, MaxBytes: 1 << 20},
+			want:    errUnknownContent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, writer := pipe.New()
+			defer writer.Close()
+			common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, tt.content)))
+
+			start := time.Now()
+			_, err := sniffer(ctx, &cachedReader{reader: reader}, tt.request, net.Network_TCP)
+			if err != tt.want {
+				t.Fatalf("sniffer() = %v, want %v", err, tt.want)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("sniffer() took %v", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package dns
+
+import (
+	"net/netip"
+
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/strmatcher"
+)
+
+var errBogusAnswer = errors.New("answer with bogus IP")
+
+// bogonPrefixes are the reserved and private networks no public domain resolves to.
+var bogonPrefixes = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/3",
+	"::/128",
+	"::1/128",
+	"100::/64",
+	"2001:db8::/32",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+}
+
+// bogusFilter finds the IPs in answers which poisoned answers to plain DNS queries often have,
+// so that such answers are discarded in favor of those of the next name server.
+type bogusFilter struct {
+	ips    []*router.GeoIPMatcher
+	bogons *router.GeoIPMatcher
+	// local matches the domains which resolve to private IPs legitimately.
+	local *strmatcher.MatcherGroup
+}
+
+// newBogusFilter returns the filter of config, or nil if it has none.
+func newBogusFilter(config *Config, container *router.GeoIPMatcherContainer) (*bogusFilter, error) {
+	if len(config.BogusIp) == 0 && !config.FilterBogons {
+		return nil, nil
+	}
+	f := new(bogusFilter)
+	for _, geoip := range config.BogusIp {
+		matcher, err := container.Add(geoip)
+		if err != nil {
+			return nil, errors.New("failed to create bogus ip matcher").Base(err)
+		}
+		f.ips = append(f.ips, matcher)
+	}
+	if config.FilterBogons {
+		cidrs := make([]*router.CIDR, 0, len(bogonPrefixes))
+		for _, p := range bogonPrefixes {
+			prefix := netip.MustParsePrefix(p)
+			cidrs = append(cidrs, &router.CIDR{
+				Ip:     prefix.Addr().AsSlice(),
+				Prefix: uint32(prefix.Bits()),
+			})
+		}
+		f.bogons = new(router.GeoIPMatcher)
+		if err := f.bogons.Init(cidrs); err != nil {
+			return nil, err
+		}
+		f.local = new(strmatcher.MatcherGroup)
+		for _, domain := range localTLDsAndDotlessDomains {
+			matcher, err := toStrMatcher(domain.Type, domain.Domain)
+			if err != nil {
+				return nil, err
+			}
+			f.local.Add(matcher)
+		}
+	}
+	return f, nil
+}
+
+// find returns the first bogus IP of the answer for domain, or nil if there's none.
+func (f *bogusFilter) find(domain string, ips []net.IP) net.IP {
+	bogons := f.bogons != nil && len(f.local.Match(domain)) == 0
+	for _, ip := range ips {
+		if bogons && f.bogons.Match(ip) {
+			return ip
+		}
+		for _, matcher := range f.ips {
+			if matcher.Match(ip) {
+				return ip
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package dns
+
+import (
+	"context"
+	"strings"
+
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/strmatcher"
+	"github.com/xtls/xray-core/features/dns"
+)
+
+// bootstrap is the name server queried for a few domains, such as those of the proxy servers,
+// when all name servers fail for them. With name servers reached through the proxy, this lets
+// the tunnel be established again when it's down, as after network changes.
+type bootstrap struct {
+	client  *Client
+	domains *strmatcher.MatcherGroup
+	// excluded matches the exceptions to domains, if there are any.
+	excluded *strmatcher.MatcherGroup
+}
+
+// newBootstrap creates the bootstrap name server of ns, whose prioritized domains are those it's
+// queried for.
+func newBootstrap(ctx context.Context, ns *NameServer, clientIP net.IP) (*bootstrap, error) {
+	b := &bootstrap{domains: new(strmatcher.MatcherGroup)}
+	for _, domain := range ns.PrioritizedDomain {
+		matcher, err := toStrMatcher(domain.Type, domain.Domain)
+		if err != nil {
+			return nil, errors.New("failed to create bootstrap domain").Base(err)
+		}
+		b.domains.Add(matcher)
+	}
+	for _, domain := range ns.ExcludedDomain {
+		matcher, err := toStrMatcher(domain.Type, domain.Domain)
+		if err != nil {
+			return nil, errors.New("failed to create excluded bootstrap domain").Base(err)
+		}
+		if b.excluded == nil {
+			b.excluded = new(strmatcher.MatcherGroup)
+		}
+		b.excluded.Add(matcher)
+	}
+
+	// The domains are matched above, rather than by the domain matcher of the name servers.
+	server := &NameServer{
+		Address:       ns.Address,
+		ClientIp:      ns.ClientIp,
+		Geoip:         ns.Geoip,
+		QueryStrategy: ns.QueryStrategy,
+		Tag:           ns.Tag,
+		OutboundTag:   ns.OutboundTag,
+	}
+	switch len(ns.ClientIp) {
+	case net.IPv4len, net.IPv6len:
+		clientIP = net.IP(ns.ClientIp)
+	}
+	var matcherInfos []*DomainMatcherInfo
+	client, err := NewClient(ctx, server, clientIP, &router.GeoIPMatcherContainer{}, &matcherInfos,
+		func(strmatcher.Matcher, int, []*DomainMatcherInfo) error { return nil })
+	if err != nil {
+		return nil, errors.New("failed to create bootstrap client").Base(err)
+	}
+	b.client = client
+	return b, nil
+}
+
+// serves tells whether the bootstrap name server may be queried for domain.
+func (b *bootstrap) serves(domain string) bool {
+	if len(b.domains.Match(domain)) == 0 {
+		return false
+	}
+	return b.excluded == nil || len(b.excluded.Match(domain)) == 0
+}
+
+// lookupBootstrap queries the bootstrap name server for domain, which all name servers failed
+// to answer with err.
+func (s *DNS) lookupBootstrap(ctx context.Context, domain string, option dns.IPOption, err error) ([]net.IP, error) {
+	errors.LogWarningInner(ctx, err, "all name servers failed for domain ", domain, ", querying bootstrap server ", s.bootstrap.client.Name())
+	ips, berr := s.bootstrap.client.QueryIP(ctx, domain, option, s.disableCache)
+	if len(ips) > 0 {
+		return ips, nil
+	}
+	if berr == nil {
+		berr = dns.ErrEmptyResponse
+	}
+	return nil, errors.New("bootstrap server ", s.bootstrap.client.Name(), " failed for domain ", domain).Base(berr)
+}
+
+// hostBootstrap resolves the domain of a name server, by static IPs or another name server, so
+// that it's dialed by IP rather than resolving the domain by the system or outbounds, which may
+// end up querying the name server itself.
+type hostBootstrap struct {
+	domain string
+	ips    []net.IP
+	// client resolves domain if there are no ips.
+	client *Client
+}
+
+// resolve returns dest with the domain of the name server replaced by its IP.
+func (b *hostBootstrap) resolve(ctx context.Context, dest net.Destination) (net.Destination, error) {
+	if b == nil || !dest.Address.Family().IsDomain() || !strings.EqualFold(dest.Address.Domain(), b.domain) {
+		return dest, nil
+	}
+	ips := b.ips
+	if len(ips) == 0 {
+		var err error
+		ips, err = b.client.QueryIP(ctx, b.domain, dns.IPOption{IPv4Enable: true, IPv6Enable: true}, false)
+		if err == nil && len(ips) == 0 {
+			err = dns.ErrEmptyResponse
+		}
+		if err != nil {
+			return dest, errors.New("failed to resolve ", b.domain, " by bootstrap server ", b.client.Name()).Base(err)
+		}
+	}
+	dest.Address = net.IPAddress(ips[0])
+	return dest, nil
+}
+
+// serverBootstrap is embedded in name servers dialing a domain, to resolve it by the bootstrap
+// of their config.
+type serverBootstrap struct {
+	bootstrap *hostBootstrap
+}
+
+func (s *serverBootstrap) setBootstrap(b *hostBootstrap) {
+	s.bootstrap = b
+}
+
+func (s *serverBootstrap) getBootstrap() *hostBootstrap {
+	return s.bootstrap
+}
+
+// resolveServer returns dest with the domain of the name server resolved by its bootstrap, if it
+// has one.
+func (s *serverBootstrap) resolveServer(ctx context.Context, dest net.Destination) (net.Destination, error) {
+	return s.bootstrap.resolve(ctx, dest)
+}
+
+// setHostBootstraps sets the bootstraps of the name servers of clients, which are created of
+// nameServers in order.
+func setHostBootstraps(nameServers []*NameServer, clients []*Client) error {
+	for i, ns := range nameServers {
+		if len(ns.BootstrapIp) == 0 && ns.BootstrapTag == "" {
+			continue
+		}
+		server, ok := clients[i].server.(interface{ setBootstrap(*hostBootstrap) })
+		host := nameServerHost(ns)
+		if !ok || host == "" {
+			return errors.New("name server ", clients[i].Name(), " has no domain to bootstrap")
+		}
+		b := &hostBootstrap{domain: host}
+		for _, ip := range ns.BootstrapIp {
+			switch len(ip) {
+			case net.IPv4len, net.IPv6len:
+				b.ips = append(b.ips, net.IP(ip))
+			default:
+				return errors.New("unexpected bootstrap IP length ", len(ip), " of name server ", clients[i].Name())
+			}
+		}
+		if len(b.ips) == 0 {
+			for j, client := range clients {
+				if j == i || client.tag != ns.BootstrapTag {
+					continue
+				}
+				// Servers bootstrapping each other would wait on each other.
+				if other := nameServers[j]; other.BootstrapTag != "" && len(other.BootstrapIp) == 0 {
+					return errors.New("bootstrap server ", ns.BootstrapTag, " of name server ", clients[i].Name(), " is bootstrapped by a name server itself")
+				}
+				b.client = client
+				break
+			}
+			if b.client == nil {
+				return errors.New("bootstrap server ", ns.BootstrapTag, " of name server ", clients[i].Name(), " not found")
+			}
+		}
+		server.setBootstrap(b)
+	}
+	return nil
+}
@@ -4,16 +4,21 @@ package dns
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/xtls/xray-core/app/router"
 	"github.com/xtls/xray-core/common"
+	c "github.com/xtls/xray-core/common/ctx"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/strmatcher"
+	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/routing"
 )
 
 // DNS is a DNS rely server.
@@ -23,12 +28,22 @@ type DNS struct {
 	disableCache           bool
 	disableFallback        bool
 	disableFallbackIfMatch bool
+	disableFailover        bool
+	bogusFilter            *bogusFilter
 	ipOption               *dns.IPOption
 	hosts                  *StaticHosts
 	clients                []*Client
 	ctx                    context.Context
 	domainMatcher          strmatcher.IndexMatcher
 	matcherInfos           []*DomainMatcherInfo
+	resolveInternal        bool
+	// serverHosts are the domains of the name servers without bootstraps, which are left to the
+	// system resolver even with resolveInternal, as resolving them would query the name servers
+	// themselves.
+	serverHosts map[string]bool
+	bootstrap   *bootstrap
+	// partitionCache answers the lookups of outbounds for their destinations apart from the others.
+	partitionCache bool
 }
 
 // DomainMatcherInfo contains information attached to index returned by Server.domainMatcher
@@ -83,14 +98,18 @@ func New(ctx context.Context, config *Config) (*DNS, error) {
 
 	clients := []*Client{}
 	domainRuleCount := 0
+	serverHosts := make(map[string]bool)
 	for _, ns := range config.NameServer {
 		domainRuleCount += len(ns.PrioritizedDomain)
+		if host := nameServerHost(ns); host != "" && len(ns.BootstrapIp) == 0 && ns.BootstrapTag == "" {
+			serverHosts[host] = true
+		}
 	}
 
 	// MatcherInfos is ensured to cover the maximum index domainMatcher could return, where matcher's index starts from 1
 	matcherInfos := make([]*DomainMatcherInfo, domainRuleCount+1)
 	domainMatcher := &strmatcher.MatcherGroup{}
-	geoipContainer := router.GeoIPMatcherContainer{}
+	geoipContainer := &router.GeoIPMatcherContainer{}
 
 	for _, ns := range config.NameServer {
 		clientIdx := len(clients)
@@ -114,12 +133,33 @@ func New(ctx context.Context, config *Config) (*DNS, error) {
 		}
 		clients = append(clients, client)
 	}
+	// Name servers are created along with the dispatcher.
+	if err := core.RequireFeatures(ctx, func(routing.Dispatcher) error {
+		return setHostBootstraps(config.NameServer, clients)
+	}); err != nil {
+		return nil, err
+	}
+
+	filter, err := newBogusFilter(config, geoipContainer)
+	if err != nil {
+		return nil, err
+	}
 
 	// If there is no DNS client in config, add a `localhost` DNS client
 	if len(clients) == 0 {
 		clients = append(clients, NewLocalDNSClient())
 	}
 
+	var b *bootstrap
+	if config.Bootstrap != nil {
+		if b, err = newBootstrap(ctx, config.Bootstrap, clientIP); err != nil {
+			return nil, err
+		}
+		if host := nameServerHost(config.Bootstrap); host != "" {
+			serverHosts[host] = true
+		}
+	}
+
 	return &DNS{
 		tag:                    tag,
 		hosts:                  hosts,
@@ -131,6 +171,12 @@ func New(ctx context.Context, config *Config) (*DNS, error) {
 		disableCache:           config.DisableCache,
 		disableFallback:        config.DisableFallback,
 		disableFallbackIfMatch: config.DisableFallbackIfMatch,
+		disableFailover:        config.DisableFailover,
+		bogusFilter:            filter,
+		resolveInternal:        config.ResolveInternal,
+		serverHosts:            serverHosts,
+		bootstrap:              b,
+		partitionCache:         config.PartitionCache,
 	}, nil
 }
 
@@ -157,15 +203,43 @@ func (s *DNS) IsOwnLink(ctx context.Context) bool {
 
 // LookupIP implements dns.Client.
 func (s *DNS) LookupIP(domain string, option dns.IPOption) ([]net.IP, error) {
+	ips, _, err := s.lookupIP(s.ctx, domain, option)
+	return ips, err
+}
+
+// LookupIPForSession implements dns.SessionLookup.
+func (s *DNS) LookupIPForSession(ctx context.Context, domain string, option dns.IPOption) ([]net.IP, error) {
+	ips, _, err := s.lookupIP(s.sessionContext(ctx), domain, option)
+	return ips, err
+}
+
+// LookupIPWithTTL implements dns.TTLLookup.
+func (s *DNS) LookupIPWithTTL(ctx context.Context, domain string, option dns.IPOption) ([]net.IP, uint32, error) {
+	return s.lookupIP(s.sessionContext(ctx), domain, option)
+}
+
+// sessionContext returns the context of a lookup on behalf of the session of ctx, in the partition
+// of ctx if the cache is partitioned.
+func (s *DNS) sessionContext(ctx context.Context) context.Context {
+	lookupCtx := c.ContextWithID(s.ctx, c.IDFromContext(ctx))
+	if tag := dns.PartitionFromContext(ctx); tag != "" && s.partitionCache {
+		lookupCtx = dns.ContextWithPartition(lookupCtx, tag)
+	}
+	return lookupCtx
+}
+
+// lookupIP looks up domain, logging in ctx. It also returns the seconds the cached answer of the
+// name server remains valid for, 0 if unknown, as for static hosts.
+func (s *DNS) lookupIP(ctx context.Context, domain string, option dns.IPOption) ([]net.IP, uint32, error) {
 	if domain == "" {
-		return nil, errors.New("empty domain name")
+		return nil, 0, errors.New("empty domain name")
 	}
 
 	option.IPv4Enable = option.IPv4Enable && s.ipOption.IPv4Enable
 	option.IPv6Enable = option.IPv6Enable && s.ipOption.IPv6Enable
 
 	if !option.IPv4Enable && !option.IPv6Enable {
-		return nil, dns.ErrEmptyResponse
+		return nil, 0, dns.ErrEmptyResponse
 	}
 
 	// Normalize the FQDN form query
@@ -176,37 +250,97 @@ func (s *DNS) LookupIP(domain string, option dns.IPOption) ([]net.IP, error) {
 	case addrs == nil: // Domain not recorded in static host
 		break
 	case len(addrs) == 0: // Domain recorded, but no valid IP returned (e.g. IPv4 address with only IPv6 enabled)
-		return nil, dns.ErrEmptyResponse
+		return nil, 0, dns.ErrEmptyResponse
 	case len(addrs) == 1 && addrs[0].Family().IsDomain(): // Domain replacement
-		errors.LogInfo(s.ctx, "domain replaced: ", domain, " -> ", addrs[0].Domain())
+		errors.LogInfo(ctx, "domain replaced: ", domain, " -> ", addrs[0].Domain())
 		domain = addrs[0].Domain()
 	default: // Successfully found ip records in static host
-		errors.LogInfo(s.ctx, "returning ", len(addrs), " IP(s) for domain ", domain, " -> ", addrs)
-		return toNetIP(addrs)
+		errors.LogInfo(ctx, "returning ", len(addrs), " IP(s) for domain ", domain, " -> ", addrs)
+		ips, err := toNetIP(addrs)
+		return ips, 0, err
 	}
 
 	// Name servers lookup
+	ctx = session.ContextWithInbound(ctx, &session.Inbound{Tag: s.tag})
+	ips, ttl, unavailable, err := s.queryNameServers(ctx, domain, option)
+	if unavailable && s.bootstrap != nil && s.bootstrap.serves(domain) {
+		ips, err := s.lookupBootstrap(ctx, domain, option, err)
+		return ips, 0, err
+	}
+	return ips, ttl, err
+}
+
+// queryNameServers queries the name servers for domain in turn, until one answers it, and returns
+// the seconds its answer remains cached for as well. It also tells whether none could be reached,
+// rather than answered without IPs.
+func (s *DNS) queryNameServers(ctx context.Context, domain string, option dns.IPOption) ([]net.IP, uint32, bool, error) {
 	errs := []error{}
-	ctx := session.ContextWithInbound(s.ctx, &session.Inbound{Tag: s.tag})
+	// Whether all the name servers queried failed, as by timeouts or network errors.
+	queried, unavailable := false, true
 	for _, client := range s.sortClients(domain) {
 		if !option.FakeEnable && strings.EqualFold(client.Name(), "FakeDNS") {
-			errors.LogDebug(s.ctx, "skip DNS resolution for domain ", domain, " at server ", client.Name())
+			errors.LogDebug(ctx, "skip DNS resolution for domain ", domain, " at server ", client.Name())
 			continue
 		}
 		ips, err := client.QueryIP(ctx, domain, option, s.disableCache)
+		if len(ips) > 0 && s.bogusFilter != nil && !strings.EqualFold(client.Name(), "FakeDNS") {
+			if ip := s.bogusFilter.find(domain, ips); ip != nil {
+				errors.LogWarning(ctx, "discarding answer of server ", client.Name(), " for domain ", domain, " with bogus IP ", ip)
+				ips, err = nil, errBogusAnswer
+			}
+		}
 		if len(ips) > 0 {
-			return ips, nil
+			return ips, client.cachedTTL(ctx, domain, option), false, nil
 		}
 		if err != nil {
-			errors.LogInfoInner(s.ctx, err, "failed to lookup ip for domain ", domain, " at server ", client.Name())
+			errors.LogInfoInner(ctx, err, "failed to lookup ip for domain ", domain, " at server ", client.Name())
 			errs = append(errs, err)
 		}
+		queried = true
+		unavailable = unavailable && isFailure(err) && err != errExpectedIPNonMatch && err != errBogusAnswer
 		// 5 for RcodeRefused in miekg/dns, hardcode to reduce binary size
-		if err != context.Canceled && err != context.DeadlineExceeded && err != errExpectedIPNonMatch && err != dns.ErrEmptyResponse && dns.RCodeFromError(err) != 5 {
-			return nil, err
+		if err != context.Canceled && err != context.DeadlineExceeded && err != errExpectedIPNonMatch && err != errBogusAnswer && err != dns.ErrEmptyResponse && dns.RCodeFromError(err) != 5 {
+			return nil, 0, unavailable, err
 		}
 	}
 
+	return nil, 0, queried && unavailable, errors.New("returning nil for domain ", domain).Base(errors.Combine(errs...))
+}
+
+// LookupIPWithTag implements dns.TaggedLookup.
+func (s *DNS) LookupIPWithTag(domain string, tag string, option dns.IPOption) ([]net.IP, error) {
+	if domain == "" {
+		return nil, errors.New("empty domain name")
+	}
+
+	option.IPv4Enable = option.IPv4Enable && s.ipOption.IPv4Enable
+	option.IPv6Enable = option.IPv6Enable && s.ipOption.IPv6Enable
+
+	if !option.IPv4Enable && !option.IPv6Enable {
+		return nil, dns.ErrEmptyResponse
+	}
+
+	domain = strings.TrimSuffix(domain, ".")
+
+	errs := []error{}
+	ctx := session.ContextWithInbound(s.ctx, &session.Inbound{Tag: s.tag})
+	for _, client := range s.clients {
+		if client.tag != tag {
+			continue
+		}
+		ips, err := client.QueryIP(ctx, domain, option, true)
+		if len(ips) > 0 {
+			return ips, nil
+		}
+		if err != nil {
+			errors.LogInfoInner(s.ctx, err, "failed to lookup ip for domain ", domain, " at server ", client.Name())
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no name server tagged ", tag, " answered for domain ", domain)
+	}
+
 	return nil, errors.New("returning nil for domain ", domain).Base(errors.Combine(errs...))
 }
 
@@ -242,6 +376,58 @@ func (s *DNS) SetFakeDNSOption(isFakeEnable bool) {
 	s.ipOption.FakeEnable = isFakeEnable
 }
 
+// FlushCache implements dns.CacheFlusher.
+func (s *DNS) FlushCache() {
+	for _, client := range s.clients {
+		if f, ok := client.server.(dns.CacheFlusher); ok {
+			f.FlushCache()
+		}
+		for _, server := range client.partitionServers() {
+			if f, ok := server.(dns.CacheFlusher); ok {
+				f.FlushCache()
+			}
+		}
+	}
+}
+
+// Reclaim implements features.Reclaimer, dropping the cached answers of all name servers.
+func (s *DNS) Reclaim() {
+	s.FlushCache()
+}
+
+// GetNameServers implements dns.NameServerLister.
+func (s *DNS) GetNameServers() []string {
+	names := make([]string, 0, len(s.clients))
+	for _, client := range s.clients {
+		names = append(names, client.Name())
+	}
+	return names
+}
+
+// GetCacheStats implements dns.CacheStatsReporter.
+func (s *DNS) GetCacheStats() []dns.CacheStats {
+	var stats []dns.CacheStats
+	for _, client := range s.clients {
+		if c, ok := client.server.(interface{ cacheCounts() (int64, int64) }); ok {
+			hits, misses := c.cacheCounts()
+			stats = append(stats, dns.CacheStats{Server: client.Name(), Hits: hits, Misses: misses})
+		}
+		partitions := client.partitionServers()
+		tags := make([]string, 0, len(partitions))
+		for tag := range partitions {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			if c, ok := partitions[tag].(interface{ cacheCounts() (int64, int64) }); ok {
+				hits, misses := c.cacheCounts()
+				stats = append(stats, dns.CacheStats{Server: client.Name(), Partition: tag, Hits: hits, Misses: misses})
+			}
+		}
+	}
+	return stats
+}
+
 func (s *DNS) sortClients(domain string) []*Client {
 	clients := make([]*Client, 0, len(s.clients))
 	clientUsed := make([]bool, len(s.clients))
@@ -253,6 +439,9 @@ func (s *DNS) sortClients(domain string) []*Client {
 	for _, match := range s.domainMatcher.Match(domain) {
 		info := s.matcherInfos[match]
 		client := s.clients[info.clientIdx]
+		if client.excludes(domain) {
+			continue
+		}
 		domainRule := client.domains[info.domainRuleIdx]
 		domainRules = append(domainRules, fmt.Sprintf("%s(DNS idx:%d)", domainRule, info.clientIdx))
 		if clientUsed[info.clientIdx] {
@@ -263,6 +452,7 @@ func (s *DNS) sortClients(domain string) []*Client {
 		clientNames = append(clientNames, client.Name())
 		hasMatch = true
 	}
+	matched := len(clients)
 
 	if !(s.disableFallback || s.disableFallbackIfMatch && hasMatch) {
 		// Default round-robin query
@@ -276,6 +466,14 @@ func (s *DNS) sortClients(domain string) []*Client {
 		}
 	}
 
+	if !s.disableFailover {
+		// Failing servers are tried last, among those matching the domain and among the others.
+		clients = append(prioritizeHealthy(clients[:matched]), prioritizeHealthy(clients[matched:])...)
+		for i, client := range clients {
+			clientNames[i] = client.Name()
+		}
+	}
+
 	if len(domainRules) > 0 {
 		errors.LogDebug(s.ctx, "domain ", domain, " matches following rules: ", domainRules)
 	}
@@ -294,6 +492,17 @@ func (s *DNS) sortClients(domain string) []*Client {
 
 func init() {
 	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
-		return New(ctx, config.(*Config))
+		d, err := New(ctx, config.(*Config))
+		if err != nil {
+			return nil, err
+		}
+		// Queries of the DNS module are bounded by the resolve timeout of the default level.
+		if err := core.RequireFeatures(ctx, func(pm policy.Manager) error {
+			d.ctx = policy.ContextWithTimeouts(d.ctx, pm.ForLevel(0).Timeouts)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		return d, nil
 	}))
 }
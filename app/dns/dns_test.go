@@ -18,6 +18,7 @@ import (
 	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/core"
 	feature_dns "github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/proxy/blackhole"
 	"github.com/xtls/xray-core/proxy/freedom"
 	"github.com/xtls/xray-core/testing/servers/udp"
 )
@@ -100,6 +101,10 @@ func (*staticHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 			rr, _ := dns.NewRR("localhost-b. IN A 127.0.0.4")
 			ans.Answer = append(ans.Answer, rr)
 
+		case q.Name == "dns.bootstrap.test." && q.Qtype == dns.TypeA:
+			rr, _ := dns.NewRR("dns.bootstrap.test. IN A 127.0.0.1")
+			ans.Answer = append(ans.Answer, rr)
+
 		case q.Name == "Mijia\\ Cloud." && q.Qtype == dns.TypeA:
 			rr, _ := dns.NewRR("Mijia\\ Cloud. IN A 127.0.0.1")
 			ans.Answer = append(ans.Answer, rr)
@@ -1061,3 +1066,246 @@ func TestMultiMatchPrioritizedDomain(t *testing.T) {
 		t.Error("DNS query doesn't finish in 2 seconds.")
 	}
 }
+
+func TestNameServerOutboundTag(t *testing.T) {
+	port := udp.PickPort()
+
+	dnsServer := dns.Server{
+		Addr:    "127.0.0.1:" + port.String(),
+		Net:     "udp",
+		Handler: &staticHandler{},
+		UDPSize: 1200,
+	}
+
+	go dnsServer.ListenAndServe()
+	time.Sleep(time.Second)
+
+	config := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&Config{
+				NameServer: []*NameServer{
+					{
+						Address: &net.Endpoint{
+							Network: net.Network_UDP,
+							Address: &net.IPOrDomain{
+								Address: &net.IPOrDomain_Ip{
+									Ip: []byte{127, 0, 0, 1},
+								},
+							},
+							Port: uint32(port),
+						},
+						OutboundTag: "direct",
+					},
+				},
+			}),
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+			serial.ToTypedMessage(&policy.Config{}),
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				// Queries routed by default would be dropped.
+				Tag:           "block",
+				ProxySettings: serial.ToTypedMessage(&blackhole.Config{}),
+			},
+			{
+				Tag:           "direct",
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	v, err := core.New(config)
+	common.Must(err)
+
+	client := v.GetFeature(feature_dns.ClientType()).(feature_dns.Client)
+
+	ips, err := client.LookupIP("google.com", feature_dns.IPOption{
+		IPv4Enable: true,
+		IPv6Enable: true,
+		FakeEnable: false,
+	})
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	if r := cmp.Diff(ips, []net.IP{{8, 8, 8, 8}}); r != "" {
+		t.Fatal(r)
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	port := udp.PickPort()
+
+	dnsServer := dns.Server{
+		Addr:    "127.0.0.1:" + port.String(),
+		Net:     "udp",
+		Handler: &staticHandler{},
+		UDPSize: 1200,
+	}
+
+	go dnsServer.ListenAndServe()
+	time.Sleep(time.Second)
+
+	endpoint := &net.Endpoint{
+		Network: net.Network_UDP,
+		Address: &net.IPOrDomain{
+			Address: &net.IPOrDomain_Ip{
+				Ip: []byte{127, 0, 0, 1},
+			},
+		},
+		Port: uint32(port),
+	}
+	config := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&Config{
+				NameServer: []*NameServer{
+					{
+						// The tunnel is down.
+						Address:     endpoint,
+						OutboundTag: "block",
+					},
+				},
+				Bootstrap: &NameServer{
+					Address:     endpoint,
+					OutboundTag: "direct",
+					PrioritizedDomain: []*NameServer_PriorityDomain{
+						{Type: DomainMatchingType_Full, Domain: "google.com"},
+					},
+				},
+			}),
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+			serial.ToTypedMessage(&policy.Config{}),
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				Tag:           "block",
+				ProxySettings: serial.ToTypedMessage(&blackhole.Config{}),
+			},
+			{
+				Tag:           "direct",
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	v, err := core.New(config)
+	common.Must(err)
+
+	client := v.GetFeature(feature_dns.ClientType()).(feature_dns.Client)
+
+	ips, err := client.LookupIP("google.com", feature_dns.IPOption{
+		IPv4Enable: true,
+		IPv6Enable: true,
+		FakeEnable: false,
+	})
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if r := cmp.Diff(ips, []net.IP{{8, 8, 8, 8}}); r != "" {
+		t.Fatal(r)
+	}
+
+	// Other domains are left to the name servers.
+	if _, err := client.LookupIP("facebook.com", feature_dns.IPOption{
+		IPv4Enable: true,
+		IPv6Enable: true,
+		FakeEnable: false,
+	}); err == nil {
+		t.Fatal("expected error for domain out of the bootstrap domains")
+	}
+}
+
+func TestNameServerBootstrap(t *testing.T) {
+	port := udp.PickPort()
+
+	dnsServer := dns.Server{
+		Addr:    "127.0.0.1:" + port.String(),
+		Net:     "udp",
+		Handler: &staticHandler{},
+		UDPSize: 1200,
+	}
+
+	go dnsServer.ListenAndServe()
+	time.Sleep(time.Second)
+
+	// The domain of the name server resolves nowhere but by its bootstrap.
+	bootstrapped := func(ns *NameServer) *NameServer {
+		ns.Address = &net.Endpoint{
+			Network: net.Network_UDP,
+			Address: net.NewIPOrDomain(net.DomainAddress("dns.bootstrap.test")),
+			Port:    uint32(port),
+		}
+		ns.OutboundTag = "direct"
+		return ns
+	}
+	local := &NameServer{
+		Address: &net.Endpoint{
+			Network: net.Network_UDP,
+			Address: net.NewIPOrDomain(net.LocalHostIP),
+			Port:    uint32(port),
+		},
+		OutboundTag:  "direct",
+		Tag:          "local",
+		SkipFallback: true,
+	}
+
+	for name, nameServers := range map[string][]*NameServer{
+		"ip":  {bootstrapped(&NameServer{BootstrapIp: [][]byte{{127, 0, 0, 1}}})},
+		"tag": {bootstrapped(&NameServer{BootstrapTag: "local"}), local},
+	} {
+		config := &core.Config{
+			App: []*serial.TypedMessage{
+				serial.ToTypedMessage(&Config{NameServer: nameServers}),
+				serial.ToTypedMessage(&dispatcher.Config{}),
+				serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+				serial.ToTypedMessage(&policy.Config{}),
+			},
+			Outbound: []*core.OutboundHandlerConfig{
+				{
+					Tag:           "direct",
+					ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+				},
+			},
+		}
+
+		v, err := core.New(config)
+		common.Must(err)
+
+		client := v.GetFeature(feature_dns.ClientType()).(feature_dns.Client)
+		ips, err := client.LookupIP("google.com", feature_dns.IPOption{
+			IPv4Enable: true,
+			IPv6Enable: true,
+			FakeEnable: false,
+		})
+		if err != nil {
+			t.Fatal(name, ": unexpected error: ", err)
+		}
+		if r := cmp.Diff(ips, []net.IP{{8, 8, 8, 8}}); r != "" {
+			t.Fatal(name, ": ", r)
+		}
+	}
+
+	for name, nameServers := range map[string][]*NameServer{
+		"no domain":   {{Address: local.Address, BootstrapIp: [][]byte{{127, 0, 0, 1}}}},
+		"unknown tag": {bootstrapped(&NameServer{BootstrapTag: "other"}), local},
+		"own tag":     {bootstrapped(&NameServer{Tag: "self", BootstrapTag: "self"})},
+		"mutual": {
+			bootstrapped(&NameServer{Tag: "a", BootstrapTag: "b"}),
+			bootstrapped(&NameServer{Tag: "b", BootstrapTag: "a"}),
+		},
+	} {
+		config := &core.Config{
+			App: []*serial.TypedMessage{
+				serial.ToTypedMessage(&Config{NameServer: nameServers}),
+				serial.ToTypedMessage(&dispatcher.Config{}),
+				serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+				serial.ToTypedMessage(&policy.Config{}),
+			},
+		}
+		if _, err := core.New(config); err == nil {
+			t.Error(name, ": expected error")
+		}
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/binary"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/xtls/xray-core/common"
+	c "github.com/xtls/xray-core/common/ctx"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/common/net"
@@ -24,6 +26,25 @@ func Fqdn(domain string) string {
 	return domain + "."
 }
 
+// cacheStats counts the queries of a name server answered from its cache, and those missing it.
+type cacheStats struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func (c *cacheStats) hit() {
+	c.hits.Add(1)
+}
+
+func (c *cacheStats) miss() {
+	c.misses.Add(1)
+}
+
+// cacheCounts returns the numbers of cache hits and misses.
+func (c *cacheStats) cacheCounts() (int64, int64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
 type record struct {
 	A    *IPRecord
 	AAAA *IPRecord
@@ -47,6 +68,31 @@ func (r *IPRecord) getIPs() ([]net.Address, error) {
 	return r.IP, nil
 }
 
+// ttl returns the seconds the shortest lived of the records enabled by option remains valid, 0 if
+// none is cached.
+func (r *record) ttl(option dns_feature.IPOption) uint32 {
+	if r == nil {
+		return 0
+	}
+	var records []*IPRecord
+	if option.IPv4Enable {
+		records = append(records, r.A)
+	}
+	if option.IPv6Enable {
+		records = append(records, r.AAAA)
+	}
+	var expire time.Time
+	for _, rec := range records {
+		if rec != nil && len(rec.IP) > 0 && (expire.IsZero() || rec.Expire.Before(expire)) {
+			expire = rec.Expire
+		}
+	}
+	if left := time.Until(expire); left > 0 {
+		return uint32(left / time.Second)
+	}
+	return 0
+}
+
 func isNewer(baseRec *IPRecord, newRec *IPRecord) bool {
 	if newRec == nil {
 		return false
@@ -231,6 +277,11 @@ L:
 }
 
 // toDnsContext create a new background context with parent inbound, session and dns log
+// sessionID returns the ID of the session a query is made for, for the logs of the query.
+func sessionID(ctx context.Context) uint32 {
+	return uint32(c.IDFromContext(ctx))
+}
+
 func toDnsContext(ctx context.Context, addr string) context.Context {
 	dnsCtx := core.ToBackgroundDetachedContext(ctx)
 	if inbound := session.InboundFromContext(ctx); inbound != nil {
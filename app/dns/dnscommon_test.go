@@ -181,3 +181,31 @@ func TestFqdn(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordTTL(t *testing.T) {
+	now := time.Now()
+	r := &record{
+		A:    &IPRecord{IP: []net.Address{net.ParseAddress("1.1.1.1")}, Expire: now.Add(300*time.Second + time.Second/2)},
+		AAAA: &IPRecord{IP: []net.Address{net.ParseAddress("::1")}, Expire: now.Add(60*time.Second + time.Second/2)},
+	}
+	for _, tc := range []struct {
+		option dns_feature.IPOption
+		ttl    uint32
+	}{
+		{dns_feature.IPOption{IPv4Enable: true}, 300},
+		{dns_feature.IPOption{IPv6Enable: true}, 60},
+		{dns_feature.IPOption{IPv4Enable: true, IPv6Enable: true}, 60},
+		{dns_feature.IPOption{}, 0},
+	} {
+		if ttl := r.ttl(tc.option); ttl != tc.ttl {
+			t.Error("unexpected TTL ", ttl, " of ", tc.option, ", expected ", tc.ttl)
+		}
+	}
+	if ttl := (*record)(nil).ttl(dns_feature.IPOption{IPv4Enable: true}); ttl != 0 {
+		t.Error("unexpected TTL of no record ", ttl)
+	}
+	r.A.Expire = now.Add(-time.Second)
+	if ttl := r.ttl(dns_feature.IPOption{IPv4Enable: true}); ttl != 0 {
+		t.Error("unexpected TTL of expired record ", ttl)
+	}
+}
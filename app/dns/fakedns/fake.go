@@ -2,6 +2,7 @@ package fakedns
 
 import (
 	"context"
+	"encoding/json"
 	"math"
 	"math/big"
 	gonet "net"
@@ -12,6 +13,7 @@ import (
 	"github.com/xtls/xray-core/common/cache"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/storage"
 	"github.com/xtls/xray-core/features/dns"
 )
 
@@ -50,6 +52,9 @@ func (fkdns *Holder) Start() error {
 }
 
 func (fkdns *Holder) Close() error {
+	if fkdns.config != nil && fkdns.domainToIP != nil {
+		fkdns.save()
+	}
 	fkdns.domainToIP = nil
 	fkdns.ipRange = nil
 	fkdns.mu = nil
@@ -75,7 +80,57 @@ func NewFakeDNSHolderConfigOnly(conf *FakeDnsPool) (*Holder, error) {
 }
 
 func (fkdns *Holder) initializeFromConfig() error {
-	return fkdns.initialize(fkdns.config.IpPool, int(fkdns.config.LruSize))
+	if err := fkdns.initialize(fkdns.config.IpPool, int(fkdns.config.LruSize)); err != nil {
+		return err
+	}
+	fkdns.load()
+	return nil
+}
+
+// mappingsEntry returns where the fake IPs of the pool are kept across restarts, so that the
+// ones still cached by clients keep resolving to their domains.
+func (fkdns *Holder) mappingsEntry() storage.Entry {
+	return storage.Entry{Bucket: "fakedns", Key: fkdns.config.IpPool}
+}
+
+// load restores the fake IPs saved before the last restart, in the order they were used.
+func (fkdns *Holder) load() {
+	b, err := fkdns.mappingsEntry().Load()
+	if err != nil {
+		if err != storage.ErrNotFound {
+			errors.LogWarningInner(context.Background(), err, "failed to load fake IPs of ", fkdns.config.IpPool)
+		}
+		return
+	}
+	var mappings [][2]string
+	if err := json.Unmarshal(b, &mappings); err != nil {
+		errors.LogWarningInner(context.Background(), err, "failed to parse fake IPs of ", fkdns.config.IpPool)
+		return
+	}
+	for _, m := range mappings {
+		ip := gonet.ParseIP(m[1])
+		if ip == nil || !fkdns.ipRange.Contains(ip) {
+			continue
+		}
+		fkdns.domainToIP.Put(m[0], net.IPAddress(ip))
+	}
+}
+
+// save keeps the fake IPs, from the least to the most recently used, if there is a state
+// directory.
+func (fkdns *Holder) save() {
+	var mappings [][2]string
+	fkdns.domainToIP.Range(func(key, value interface{}) bool {
+		mappings = append(mappings, [2]string{key.(string), value.(net.Address).String()})
+		return true
+	})
+	b, err := json.Marshal(mappings)
+	if err == nil {
+		err = fkdns.mappingsEntry().Save(b)
+	}
+	if err != nil {
+		errors.LogWarningInner(context.Background(), err, "failed to save fake IPs of ", fkdns.config.IpPool)
+	}
 }
 
 func (fkdns *Holder) initialize(ipPoolCidr string, lruSize int) error {
@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/platform"
 	"github.com/xtls/xray-core/common/uuid"
 	"github.com/xtls/xray-core/features/dns"
 	"golang.org/x/sync/errgroup"
@@ -92,6 +93,25 @@ func testGetFakeIP(index int, addr [][]net.Address, fkdns *Holder) func() error
 	}
 }
 
+func TestFakeDnsHolderRestoreMapping(t *testing.T) {
+	t.Setenv(platform.StateLocation, t.TempDir())
+	config := &FakeDnsPool{
+		IpPool:  dns.FakeIPv4Pool,
+		LruSize: 256,
+	}
+	fkdns, err := NewFakeDNSHolderConfigOnly(config)
+	common.Must(err)
+	common.Must(fkdns.Start())
+	addr := fkdns.GetFakeIPForDomain("fakednstest.example.com")
+	common.Must(fkdns.Close())
+
+	fkdns, err = NewFakeDNSHolderConfigOnly(config)
+	common.Must(err)
+	common.Must(fkdns.Start())
+	assert.Equal(t, "fakednstest.example.com", fkdns.GetDomainFromFakeDNS(addr[0]))
+	assert.Equal(t, addr, fkdns.GetFakeIPForDomain("fakednstest.example.com"))
+}
+
 func TestFakeDnsHolderCreateMappingAndRollOver(t *testing.T) {
 	fkdns, err := NewFakeDNSHolderConfigOnly(&FakeDnsPool{
 		IpPool:  dns.FakeIPv4Pool,
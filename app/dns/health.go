@@ -0,0 +1,115 @@
+package dns
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/features/dns"
+)
+
+const (
+	// healthFailures is the number of consecutive failures after which a server is deprioritized.
+	healthFailures = 3
+	// healthMinBackoff and healthMaxBackoff bound the time before a deprioritized server is
+	// probed again, which doubles with each failed probe.
+	healthMinBackoff = 5 * time.Second
+	healthMaxBackoff = 5 * time.Minute
+	// healthWeight is the weight of a new sample in the moving averages.
+	healthWeight = 0.2
+)
+
+// serverHealth scores a name server by its recent queries. Servers failing repeatedly, by
+// timeouts or network errors, are deprioritized, until a query probing them succeeds.
+type serverHealth struct {
+	sync.Mutex
+	failures  int           // consecutive failures
+	errorRate float64       // moving average of failures
+	latency   time.Duration // moving average of the time of successful queries
+	retryAt   time.Time     // when a deprioritized server may be probed again
+}
+
+// isFailure tells whether err of a query shows the server is unavailable, rather than that it
+// answered without IPs.
+func isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	// 3 for RcodeNameError and 5 for RcodeRefused in miekg/dns, hardcode to reduce binary size
+	if err == dns.ErrEmptyResponse || dns.RCodeFromError(err) == 3 || dns.RCodeFromError(err) == 5 {
+		return false
+	}
+	return true
+}
+
+// healthy tells whether the server should be queried in its configured order. Once the backoff
+// of a deprioritized server passes, a single query is let through to probe it.
+func (h *serverHealth) healthy() bool {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.failures < healthFailures {
+		return true
+	}
+	now := time.Now()
+	if now.Before(h.retryAt) {
+		return false
+	}
+	// Until the probe is recorded, or in case it's never sent.
+	h.retryAt = now.Add(healthMinBackoff)
+	return true
+}
+
+// record updates the score of the server by the result of a query, which took d.
+func (h *serverHealth) record(ctx context.Context, name string, d time.Duration, err error) {
+	if err != nil && ctx.Err() == context.Canceled {
+		return
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	if !isFailure(err) {
+		h.errorRate *= 1 - healthWeight
+		if h.latency == 0 {
+			h.latency = d
+		} else {
+			h.latency = time.Duration(float64(h.latency)*(1-healthWeight) + float64(d)*healthWeight)
+		}
+		if h.failures >= healthFailures {
+			errors.LogInfo(ctx, "DNS server ", name, " recovered, latency ", h.latency)
+		}
+		h.failures = 0
+		return
+	}
+
+	h.errorRate = h.errorRate*(1-healthWeight) + healthWeight
+	h.failures++
+	if h.failures < healthFailures {
+		return
+	}
+	backoff := healthMinBackoff << min(h.failures-healthFailures, 6)
+	if backoff > healthMaxBackoff {
+		backoff = healthMaxBackoff
+	}
+	h.retryAt = time.Now().Add(backoff)
+	if h.failures == healthFailures {
+		errors.LogWarningInner(ctx, err, "DNS server ", name, " deprioritized after ", h.failures, " failures, error rate ", int(h.errorRate*100), "%")
+	}
+}
+
+// prioritizeHealthy moves the clients which aren't healthy behind the others, keeping the
+// configured order otherwise.
+func prioritizeHealthy(clients []*Client) []*Client {
+	sorted := make([]*Client, 0, len(clients))
+	var unhealthy []*Client
+	for _, client := range clients {
+		if client.health.healthy() {
+			sorted = append(sorted, client)
+		} else {
+			unhealthy = append(unhealthy, client)
+		}
+	}
+	return append(sorted, unhealthy...)
+}
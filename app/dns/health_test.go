@@ -0,0 +1,54 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/features/dns"
+)
+
+func TestServerHealth(t *testing.T) {
+	ctx := context.Background()
+	errTimeout := errors.New("timeout")
+	var h serverHealth
+
+	for i := 0; i < healthFailures-1; i++ {
+		h.record(ctx, "test", time.Second, errTimeout)
+	}
+	h.record(ctx, "test", 10*time.Millisecond, dns.ErrEmptyResponse)
+	if !h.healthy() {
+		t.Error("expected healthy after an answer")
+	}
+
+	for i := 0; i < healthFailures; i++ {
+		h.record(ctx, "test", time.Second, errTimeout)
+	}
+	if h.healthy() {
+		t.Error("expected unhealthy after failures")
+	}
+
+	h.retryAt = time.Now().Add(-time.Second)
+	if !h.healthy() {
+		t.Error("expected a probe after the backoff")
+	}
+	if h.healthy() {
+		t.Error("expected a single probe")
+	}
+	h.record(ctx, "test", 10*time.Millisecond, nil)
+	if !h.healthy() {
+		t.Error("expected healthy after a successful probe")
+	}
+}
+
+func TestPrioritizeHealthy(t *testing.T) {
+	clients := []*Client{{}, {}, {}}
+	for i := 0; i < healthFailures; i++ {
+		clients[0].health.record(context.Background(), "test", time.Second, errors.New("timeout"))
+	}
+	sorted := prioritizeHealthy(clients)
+	if sorted[0] != clients[1] || sorted[1] != clients[2] || sorted[2] != clients[0] {
+		t.Error("expected the failing client last")
+	}
+}
@@ -0,0 +1,33 @@
+package dns
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/xtls/xray-core/common/net"
+)
+
+// ResolvesInternal implements dns.InternalResolver.
+func (s *DNS) ResolvesInternal(domain string) bool {
+	if !s.resolveInternal {
+		return false
+	}
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	return !s.serverHosts[domain] || s.hosts.Lookup(domain, *s.ipOption) != nil
+}
+
+// nameServerHost returns the domain of the server of ns, or "" if it is an IP or has no server.
+func nameServerHost(ns *NameServer) string {
+	address := ns.GetAddress().AsDestination().Address
+	if !address.Family().IsDomain() {
+		return ""
+	}
+	host := address.Domain()
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		host = u.Hostname()
+	}
+	if net.ParseAddress(host).Family().IsIP() || strings.EqualFold(host, "localhost") || strings.EqualFold(host, "fakedns") {
+		return ""
+	}
+	return strings.ToLower(host)
+}
@@ -0,0 +1,83 @@
+package dns
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/features/dns"
+)
+
+func TestResolvesInternal(t *testing.T) {
+	serverHosts := make(map[string]bool)
+	for _, address := range []string{"https://dns.example.com/dns-query", "tcp://1.1.1.1:53", "udp.example.com", "localhost", "8.8.8.8", "https+local://Hosted.example.com/dns-query"} {
+		ns := &NameServer{Address: &net.Endpoint{Address: net.NewIPOrDomain(net.ParseAddress(address)), Port: 53}}
+		if host := nameServerHost(ns); host != "" {
+			serverHosts[host] = true
+		}
+	}
+	if len(serverHosts) != 3 || !serverHosts["dns.example.com"] || !serverHosts["udp.example.com"] || !serverHosts["hosted.example.com"] {
+		t.Fatalf("unexpected name server hosts %v", serverHosts)
+	}
+
+	hosts, err := NewStaticHosts([]*Config_HostMapping{
+		{Type: DomainMatchingType_Full, Domain: "hosted.example.com", Ip: [][]byte{{1, 2, 3, 4}}},
+	})
+	common.Must(err)
+	s := &DNS{
+		hosts:           hosts,
+		ipOption:        &dns.IPOption{IPv4Enable: true, IPv6Enable: true},
+		resolveInternal: true,
+		serverHosts:     serverHosts,
+	}
+	for domain, want := range map[string]bool{
+		"server.example.com":  true,
+		"dns.example.com":     false,
+		"DNS.example.com.":    false,
+		"hosted.example.com":  true,
+		"udp.example.com":     false,
+		"other.example.com.":  true,
+		"hosted.example.com.": true,
+	} {
+		if got := s.ResolvesInternal(domain); got != want {
+			t.Errorf("ResolvesInternal(%q): got %v, want %v", domain, got, want)
+		}
+	}
+
+	s.resolveInternal = false
+	if s.ResolvesInternal("server.example.com") {
+		t.Error("expected the system resolver without resolveInternal")
+	}
+}
+
+func TestClientPartitions(t *testing.T) {
+	shared := NewLocalNameServer(QueryStrategy_USE_IP)
+	created := 0
+	client := &Client{
+		server: shared,
+		newPartition: func(tag string) (Server, error) {
+			created++
+			return NewLocalNameServer(QueryStrategy_USE_IP), nil
+		},
+	}
+	ctx := context.Background()
+	if client.serverFor(ctx) != shared {
+		t.Error("expected the shared server without a partition")
+	}
+	direct := client.serverFor(dns.ContextWithPartition(ctx, "direct"))
+	if direct == shared || client.serverFor(dns.ContextWithPartition(ctx, "direct")) != direct {
+		t.Error("expected a server of its own for the partition")
+	}
+	if proxy := client.serverFor(dns.ContextWithPartition(ctx, "proxy")); proxy == direct || proxy == shared {
+		t.Error("expected a server for each partition")
+	}
+	if created != 2 || len(client.partitionServers()) != 2 {
+		t.Errorf("unexpected partitions %v", client.partitionServers())
+	}
+
+	client.newPartition = nil
+	if client.serverFor(dns.ContextWithPartition(ctx, "other")) != shared {
+		t.Error("expected the shared server of a name server without partitions")
+	}
+}
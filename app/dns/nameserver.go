@@ -4,15 +4,19 @@ import (
 	"context"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/xtls/xray-core/app/router"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/strmatcher"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/transport"
 )
 
 // Server is the interface for Name Server.
@@ -23,13 +27,29 @@ type Server interface {
 	QueryIP(ctx context.Context, domain string, clientIP net.IP, option dns.IPOption, disableCache bool) ([]net.IP, error)
 }
 
+// cachingServer is a Server which caches its answers until their TTLs run out.
+type cachingServer interface {
+	// cachedTTL returns the seconds the cached answer for domain remains valid, 0 if there is none.
+	cachedTTL(domain string, option dns.IPOption) uint32
+}
+
 // Client is the interface for DNS client.
 type Client struct {
 	server       Server
 	clientIP     net.IP
 	skipFallback bool
+	tag          string
 	domains      []string
 	expectIPs    []*router.GeoIPMatcher
+	// excludedDomains matches the exceptions to the domains of the client, if it has any.
+	excludedDomains *strmatcher.MatcherGroup
+	health          serverHealth
+	// newPartition creates the name server of the partition of the outbound tagged tag, which
+	// queries through the outbound and caches apart. It's nil for name servers which can't be
+	// partitioned, local ones or those with an outbound of their own.
+	newPartition    func(tag string) (Server, error)
+	partitionAccess sync.Mutex
+	partitions      map[string]Server
 }
 
 var errExpectedIPNonMatch = errors.New("expectIPs not match")
@@ -73,23 +93,59 @@ func NewServer(ctx context.Context, dest net.Destination, dispatcher routing.Dis
 	return nil, errors.New("No available name server could be created from ", dest).AtWarning()
 }
 
+// outboundDispatcher sends the queries of a name server through the outbound tagged tag.
+type outboundDispatcher struct {
+	routing.Dispatcher
+	tag string
+}
+
+// withOutbound returns ctx with a content of its own forcing the outbound, as the content of
+// ctx may be shared with the connection the query was made for.
+func (d *outboundDispatcher) withOutbound(ctx context.Context) context.Context {
+	content := new(session.Content)
+	if c := session.ContentFromContext(ctx); c != nil {
+		content.Protocol = c.Protocol
+		content.SkipDNSResolve = c.SkipDNSResolve
+	}
+	return session.SetForcedOutboundTagToContext(session.ContextWithContent(ctx, content), d.tag)
+}
+
+// Dispatch implements routing.Dispatcher.
+func (d *outboundDispatcher) Dispatch(ctx context.Context, dest net.Destination) (*transport.Link, error) {
+	return d.Dispatcher.Dispatch(d.withOutbound(ctx), dest)
+}
+
+// DispatchLink implements routing.Dispatcher.
+func (d *outboundDispatcher) DispatchLink(ctx context.Context, dest net.Destination, link *transport.Link) error {
+	return d.Dispatcher.DispatchLink(d.withOutbound(ctx), dest, link)
+}
+
 // NewClient creates a DNS client managing a name server with client IP, domain rules and expected IPs.
 func NewClient(
 	ctx context.Context,
 	ns *NameServer,
 	clientIP net.IP,
-	container router.GeoIPMatcherContainer,
+	container *router.GeoIPMatcherContainer,
 	matcherInfos *[]*DomainMatcherInfo,
 	updateDomainRule func(strmatcher.Matcher, int, []*DomainMatcherInfo) error,
 ) (*Client, error) {
 	client := &Client{}
 
 	err := core.RequireFeatures(ctx, func(dispatcher routing.Dispatcher) error {
+		if ns.OutboundTag != "" {
+			dispatcher = &outboundDispatcher{Dispatcher: dispatcher, tag: ns.OutboundTag}
+		}
 		// Create a new server for each client for now
 		server, err := NewServer(ctx, ns.Address.AsDestination(), dispatcher, ns.GetQueryStrategy())
 		if err != nil {
 			return errors.New("failed to create nameserver").Base(err).AtWarning()
 		}
+		if ns.OutboundTag == "" && isDispatched(server) {
+			client.newPartition = func(tag string) (Server, error) {
+				d := &outboundDispatcher{Dispatcher: dispatcher, tag: tag}
+				return NewServer(ctx, ns.Address.AsDestination(), d, ns.GetQueryStrategy())
+			}
+		}
 
 		// Prioritize local domains with specific TLDs or those without any dot for the local DNS
 		if _, isLocalDNS := server.(*LocalNameServer); isLocalDNS {
@@ -140,6 +196,18 @@ func NewClient(
 			}
 		}
 
+		var excludedDomains *strmatcher.MatcherGroup
+		for _, domain := range ns.ExcludedDomain {
+			domainRule, err := toStrMatcher(domain.Type, domain.Domain)
+			if err != nil {
+				return errors.New("failed to create excluded domain").Base(err).AtWarning()
+			}
+			if excludedDomains == nil {
+				excludedDomains = new(strmatcher.MatcherGroup)
+			}
+			excludedDomains.Add(domainRule)
+		}
+
 		// Establish expected IPs
 		var matchers []*router.GeoIPMatcher
 		for _, geoip := range ns.Geoip {
@@ -162,8 +230,10 @@ func NewClient(
 		client.server = server
 		client.clientIP = clientIP
 		client.skipFallback = ns.SkipFallback
+		client.tag = ns.Tag
 		client.domains = rules
 		client.expectIPs = matchers
+		client.excludedDomains = excludedDomains
 		return nil
 	})
 	return client, err
@@ -174,11 +244,67 @@ func (c *Client) Name() string {
 	return c.server.Name()
 }
 
+// excludes tells whether domain is an exception to the domains of the client.
+func (c *Client) excludes(domain string) bool {
+	return c.excludedDomains != nil && len(c.excludedDomains.Match(domain)) > 0
+}
+
+// isDispatched tells whether the queries of server are dispatched, so that they may be sent through
+// any outbound.
+func isDispatched(server Server) bool {
+	switch server.(type) {
+	case *ClassicNameServer, *TCPNameServer, *DoHNameServer:
+		return true
+	}
+	return false
+}
+
+// serverFor returns the name server for the lookup of ctx: that of the partition of the outbound
+// it's for, if the lookup is partitioned, or the shared one.
+func (c *Client) serverFor(ctx context.Context) Server {
+	tag := dns.PartitionFromContext(ctx)
+	if tag == "" || c.newPartition == nil {
+		return c.server
+	}
+	c.partitionAccess.Lock()
+	defer c.partitionAccess.Unlock()
+	if server, found := c.partitions[tag]; found {
+		return server
+	}
+	server, err := c.newPartition(tag)
+	if err != nil {
+		errors.LogWarningInner(ctx, err, "failed to create partition ", tag, " of name server ", c.Name())
+		return c.server
+	}
+	if b, ok := c.server.(interface{ getBootstrap() *hostBootstrap }); ok {
+		server.(interface{ setBootstrap(*hostBootstrap) }).setBootstrap(b.getBootstrap())
+	}
+	if c.partitions == nil {
+		c.partitions = make(map[string]Server)
+	}
+	c.partitions[tag] = server
+	return server
+}
+
+// partitionServers returns the name servers of the partitions of the client, by the tags of their
+// outbounds.
+func (c *Client) partitionServers() map[string]Server {
+	c.partitionAccess.Lock()
+	defer c.partitionAccess.Unlock()
+	servers := make(map[string]Server, len(c.partitions))
+	for tag, server := range c.partitions {
+		servers[tag] = server
+	}
+	return servers
+}
+
 // QueryIP sends DNS query to the name server with the client's IP.
 func (c *Client) QueryIP(ctx context.Context, domain string, option dns.IPOption, disableCache bool) ([]net.IP, error) {
-	ctx, cancel := context.WithTimeout(ctx, 4*time.Second)
-	ips, err := c.server.QueryIP(ctx, domain, c.clientIP, option, disableCache)
-	cancel()
+	ctx, done := policy.WithStageTimeout(ctx, policy.StageResolve)
+	start := time.Now()
+	ips, err := c.serverFor(ctx).QueryIP(ctx, domain, c.clientIP, option, disableCache)
+	c.health.record(ctx, c.Name(), time.Since(start), err)
+	done()
 
 	if err != nil {
 		return ips, err
@@ -186,6 +312,29 @@ func (c *Client) QueryIP(ctx context.Context, domain string, option dns.IPOption
 	return c.MatchExpectedIPs(domain, ips)
 }
 
+// queryDeadline returns when the requests of a query for ctx are given up: the deadline of ctx,
+// which the resolve stage of QueryIP sets, or else the resolve timeout from now. Requests are
+// always bounded, by the default resolve timeout if the policy has none.
+func queryDeadline(ctx context.Context) time.Time {
+	if d, ok := ctx.Deadline(); ok {
+		return d
+	}
+	timeout := policy.TimeoutsFromContext(ctx).Resolve
+	if timeout <= 0 {
+		timeout = policy.SessionDefault().Timeouts.Resolve
+	}
+	return time.Now().Add(timeout)
+}
+
+// cachedTTL returns the seconds the answer of the name server for the lookup of ctx for domain
+// remains cached, 0 if it doesn't cache answers.
+func (c *Client) cachedTTL(ctx context.Context, domain string, option dns.IPOption) uint32 {
+	if cs, ok := c.serverFor(ctx).(cachingServer); ok {
+		return cs.cachedTTL(domain, option)
+	}
+	return 0
+}
+
 // MatchExpectedIPs matches queried domain IPs with expected IPs and returns matched ones.
 func (c *Client) MatchExpectedIPs(domain string, ips []net.IP) ([]net.IP, error) {
 	if len(c.expectIPs) == 0 {
@@ -31,6 +31,8 @@ import (
 // which is compatible with traditional dns over udp(RFC1035),
 // thus most of the DOH implementation is copied from udpns.go
 type DoHNameServer struct {
+	cacheStats
+	serverBootstrap
 	dispatcher routing.Dispatcher
 	sync.RWMutex
 	ips           map[string]*record
@@ -54,6 +56,9 @@ func NewDoHNameServer(url *url.URL, dispatcher routing.Dispatcher, queryStrategy
 		if err != nil {
 			return nil, err
 		}
+		if dest, err = s.resolveServer(ctx, dest); err != nil {
+			return nil, err
+		}
 		dnsCtx := toDnsContext(ctx, s.dohURL)
 		if h2c {
 			dnsCtx = session.ContextWithMitmAlpn11(dnsCtx, false) // for insurance
@@ -118,6 +123,9 @@ func NewDoHLocalNameServer(url *url.URL, queryStrategy QueryStrategy) *DoHNameSe
 			if err != nil {
 				return nil, err
 			}
+			if dest, err = s.resolveServer(ctx, dest); err != nil {
+				return nil, err
+			}
 			conn, err := internet.DialSystem(ctx, dest, nil)
 			log.Record(&log.AccessMessage{
 				From:   "DNS",
@@ -159,6 +167,14 @@ func (s *DoHNameServer) Name() string {
 	return s.name
 }
 
+// FlushCache implements dns.CacheFlusher.
+func (s *DoHNameServer) FlushCache() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.ips = make(map[string]*record)
+}
+
 // Cleanup clears expired items from cache
 func (s *DoHNameServer) Cleanup() error {
 	now := time.Now()
@@ -250,12 +266,7 @@ func (s *DoHNameServer) sendQuery(ctx context.Context, domain string, clientIP n
 
 	reqs := buildReqMsgs(domain, option, s.newReqID, genEDNS0Options(clientIP))
 
-	var deadline time.Time
-	if d, ok := ctx.Deadline(); ok {
-		deadline = d
-	} else {
-		deadline = time.Now().Add(time.Second * 5)
-	}
+	deadline := queryDeadline(ctx)
 
 	for _, req := range reqs {
 		go func(r *dnsRequest) {
@@ -368,6 +379,13 @@ func (s *DoHNameServer) findIPsForDomain(domain string, option dns_feature.IPOpt
 	return nil, errRecordNotFound
 }
 
+// cachedTTL implements cachingServer.
+func (s *DoHNameServer) cachedTTL(domain string, option dns_feature.IPOption) uint32 {
+	s.RLock()
+	defer s.RUnlock()
+	return s.ips[Fqdn(domain)].ttl(option)
+}
+
 // QueryIP implements Server.
 func (s *DoHNameServer) QueryIP(ctx context.Context, domain string, clientIP net.IP, option dns_feature.IPOption, disableCache bool) ([]net.IP, error) { // nolint: dupl
 	fqdn := Fqdn(domain)
@@ -382,9 +400,11 @@ func (s *DoHNameServer) QueryIP(ctx context.Context, domain string, clientIP net
 		ips, err := s.findIPsForDomain(fqdn, option)
 		if err == nil || err == dns_feature.ErrEmptyResponse {
 			errors.LogDebugInner(ctx, err, s.name, " cache HIT ", domain, " -> ", ips)
-			log.Record(&log.DNSLog{Server: s.name, Domain: domain, Result: ips, Status: log.DNSCacheHit, Elapsed: 0, Error: err})
+			log.Record(&log.DNSLog{Server: s.name, Domain: domain, Result: ips, Status: log.DNSCacheHit, Elapsed: 0, Error: err, SessionID: sessionID(ctx)})
+			s.cacheStats.hit()
 			return ips, err
 		}
+		s.cacheStats.miss()
 	}
 
 	// ipv4 and ipv6 belong to different subscription groups
@@ -419,7 +439,7 @@ func (s *DoHNameServer) QueryIP(ctx context.Context, domain string, clientIP net
 	for {
 		ips, err := s.findIPsForDomain(fqdn, option)
 		if err != errRecordNotFound {
-			log.Record(&log.DNSLog{Server: s.name, Domain: domain, Result: ips, Status: log.DNSQueried, Elapsed: time.Since(start), Error: err})
+			log.Record(&log.DNSLog{Server: s.name, Domain: domain, Result: ips, Status: log.DNSQueried, Elapsed: time.Since(start), Error: err, SessionID: sessionID(ctx)})
 			return ips, err
 		}
 
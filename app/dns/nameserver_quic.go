@@ -32,6 +32,8 @@ const handshakeTimeout = time.Second * 8
 
 // QUICNameServer implemented DNS over QUIC
 type QUICNameServer struct {
+	cacheStats
+	serverBootstrap
 	sync.RWMutex
 	ips           map[string]*record
 	pub           *pubsub.Service
@@ -76,6 +78,14 @@ func (s *QUICNameServer) Name() string {
 	return s.name
 }
 
+// FlushCache implements dns.CacheFlusher.
+func (s *QUICNameServer) FlushCache() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.ips = make(map[string]*record)
+}
+
 // Cleanup clears expired items from cache
 func (s *QUICNameServer) Cleanup() error {
 	now := time.Now()
@@ -162,12 +172,7 @@ func (s *QUICNameServer) sendQuery(ctx context.Context, domain string, clientIP
 
 	reqs := buildReqMsgs(domain, option, s.newReqID, genEDNS0Options(clientIP))
 
-	var deadline time.Time
-	if d, ok := ctx.Deadline(); ok {
-		deadline = d
-	} else {
-		deadline = time.Now().Add(time.Second * 5)
-	}
+	deadline := queryDeadline(ctx)
 
 	for _, req := range reqs {
 		go func(r *dnsRequest) {
@@ -287,6 +292,13 @@ func (s *QUICNameServer) findIPsForDomain(domain string, option dns_feature.IPOp
 }
 
 // QueryIP is called from dns.Server->queryIPTimeout
+// cachedTTL implements cachingServer.
+func (s *QUICNameServer) cachedTTL(domain string, option dns_feature.IPOption) uint32 {
+	s.RLock()
+	defer s.RUnlock()
+	return s.ips[Fqdn(domain)].ttl(option)
+}
+
 func (s *QUICNameServer) QueryIP(ctx context.Context, domain string, clientIP net.IP, option dns_feature.IPOption, disableCache bool) ([]net.IP, error) {
 	fqdn := Fqdn(domain)
 	option = ResolveIpOptionOverride(s.queryStrategy, option)
@@ -300,9 +312,11 @@ func (s *QUICNameServer) QueryIP(ctx context.Context, domain string, clientIP ne
 		ips, err := s.findIPsForDomain(fqdn, option)
 		if err == nil || err == dns_feature.ErrEmptyResponse {
 			errors.LogDebugInner(ctx, err, s.name, " cache HIT ", domain, " -> ", ips)
-			log.Record(&log.DNSLog{Server: s.name, Domain: domain, Result: ips, Status: log.DNSCacheHit, Elapsed: 0, Error: err})
+			log.Record(&log.DNSLog{Server: s.name, Domain: domain, Result: ips, Status: log.DNSCacheHit, Elapsed: 0, Error: err, SessionID: sessionID(ctx)})
+			s.cacheStats.hit()
 			return ips, err
 		}
+		s.cacheStats.miss()
 	}
 
 	// ipv4 and ipv6 belong to different subscription groups
@@ -337,7 +351,7 @@ func (s *QUICNameServer) QueryIP(ctx context.Context, domain string, clientIP ne
 	for {
 		ips, err := s.findIPsForDomain(fqdn, option)
 		if err != errRecordNotFound {
-			log.Record(&log.DNSLog{Server: s.name, Domain: domain, Result: ips, Status: log.DNSQueried, Elapsed: time.Since(start), Error: err})
+			log.Record(&log.DNSLog{Server: s.name, Domain: domain, Result: ips, Status: log.DNSQueried, Elapsed: time.Since(start), Error: err, SessionID: sessionID(ctx)})
 			return ips, err
 		}
 
@@ -398,7 +412,13 @@ func (s *QUICNameServer) openConnection() (quic.Connection, error) {
 		HandshakeIdleTimeout: handshakeTimeout,
 	}
 	tlsConfig.ServerName = s.destination.Address.String()
-	conn, err := quic.DialAddr(context.Background(), s.destination.NetAddr(), tlsConfig.GetTLSConfig(tls.WithNextProto("http/1.1", http2.NextProtoTLS, NextProtoDQ)), quicConfig)
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	dest, err := s.resolveServer(ctx, *s.destination)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := quic.DialAddr(context.Background(), dest.NetAddr(), tlsConfig.GetTLSConfig(tls.WithNextProto("http/1.1", http2.NextProtoTLS, NextProtoDQ)), quicConfig)
 	log.Record(&log.AccessMessage{
 		From:   "DNS",
 		To:     s.destination,
@@ -27,6 +27,8 @@ import (
 
 // TCPNameServer implemented DNS over TCP (RFC7766).
 type TCPNameServer struct {
+	cacheStats
+	serverBootstrap
 	sync.RWMutex
 	name          string
 	destination   *net.Destination
@@ -50,7 +52,11 @@ func NewTCPNameServer(
 	}
 
 	s.dial = func(ctx context.Context) (net.Conn, error) {
-		link, err := dispatcher.Dispatch(toDnsContext(ctx, s.destination.String()), *s.destination)
+		dest, err := s.resolveServer(ctx, *s.destination)
+		if err != nil {
+			return nil, err
+		}
+		link, err := dispatcher.Dispatch(toDnsContext(ctx, s.destination.String()), dest)
 		if err != nil {
 			return nil, err
 		}
@@ -72,7 +78,11 @@ func NewTCPLocalNameServer(url *url.URL, queryStrategy QueryStrategy) (*TCPNameS
 	}
 
 	s.dial = func(ctx context.Context) (net.Conn, error) {
-		return internet.DialSystem(ctx, *s.destination, nil)
+		dest, err := s.resolveServer(ctx, *s.destination)
+		if err != nil {
+			return nil, err
+		}
+		return internet.DialSystem(ctx, dest, nil)
 	}
 
 	return s, nil
@@ -108,6 +118,14 @@ func (s *TCPNameServer) Name() string {
 	return s.name
 }
 
+// FlushCache implements dns.CacheFlusher.
+func (s *TCPNameServer) FlushCache() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.ips = make(map[string]*record)
+}
+
 // Cleanup clears expired items from cache
 func (s *TCPNameServer) Cleanup() error {
 	now := time.Now()
@@ -194,12 +212,7 @@ func (s *TCPNameServer) sendQuery(ctx context.Context, domain string, clientIP n
 
 	reqs := buildReqMsgs(domain, option, s.newReqID, genEDNS0Options(clientIP))
 
-	var deadline time.Time
-	if d, ok := ctx.Deadline(); ok {
-		deadline = d
-	} else {
-		deadline = time.Now().Add(time.Second * 5)
-	}
+	deadline := queryDeadline(ctx)
 
 	for _, req := range reqs {
 		go func(r *dnsRequest) {
@@ -311,6 +324,13 @@ func (s *TCPNameServer) findIPsForDomain(domain string, option dns_feature.IPOpt
 	return nil, dns_feature.ErrEmptyResponse
 }
 
+// cachedTTL implements cachingServer.
+func (s *TCPNameServer) cachedTTL(domain string, option dns_feature.IPOption) uint32 {
+	s.RLock()
+	defer s.RUnlock()
+	return s.ips[Fqdn(domain)].ttl(option)
+}
+
 // QueryIP implements Server.
 func (s *TCPNameServer) QueryIP(ctx context.Context, domain string, clientIP net.IP, option dns_feature.IPOption, disableCache bool) ([]net.IP, error) {
 	fqdn := Fqdn(domain)
@@ -325,9 +345,11 @@ func (s *TCPNameServer) QueryIP(ctx context.Context, domain string, clientIP net
 		ips, err := s.findIPsForDomain(fqdn, option)
 		if err == nil || err == dns_feature.ErrEmptyResponse {
 			errors.LogDebugInner(ctx, err, s.name, " cache HIT ", domain, " -> ", ips)
-			log.Record(&log.DNSLog{Server: s.name, Domain: domain, Result: ips, Status: log.DNSCacheHit, Elapsed: 0, Error: err})
+			log.Record(&log.DNSLog{Server: s.name, Domain: domain, Result: ips, Status: log.DNSCacheHit, Elapsed: 0, Error: err, SessionID: sessionID(ctx)})
+			s.cacheStats.hit()
 			return ips, err
 		}
+		s.cacheStats.miss()
 	}
 
 	// ipv4 and ipv6 belong to different subscription groups
@@ -362,7 +384,7 @@ func (s *TCPNameServer) QueryIP(ctx context.Context, domain string, clientIP net
 	for {
 		ips, err := s.findIPsForDomain(fqdn, option)
 		if err != errRecordNotFound {
-			log.Record(&log.DNSLog{Server: s.name, Domain: domain, Result: ips, Status: log.DNSQueried, Elapsed: time.Since(start), Error: err})
+			log.Record(&log.DNSLog{Server: s.name, Domain: domain, Result: ips, Status: log.DNSQueried, Elapsed: time.Since(start), Error: err, SessionID: sessionID(ctx)})
 			return ips, err
 		}
 
@@ -0,0 +1,38 @@
+package dns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/features/policy"
+)
+
+func TestQueryDeadline(t *testing.T) {
+	deadline := time.Now().Add(time.Hour)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		timeout time.Duration
+	}{
+		{"default", context.Background(), policy.SessionDefault().Timeouts.Resolve},
+		{"policy", policy.ContextWithTimeouts(context.Background(), policy.Timeout{Resolve: time.Minute}), time.Minute},
+		{"unbounded policy", policy.ContextWithTimeouts(context.Background(), policy.Timeout{}), policy.SessionDefault().Timeouts.Resolve},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now()
+			got := queryDeadline(tt.ctx)
+			if got.Before(before.Add(tt.timeout)) || got.After(time.Now().Add(tt.timeout)) {
+				t.Errorf("queryDeadline() = %v, want %v from now", got, tt.timeout)
+			}
+		})
+	}
+
+	if got := queryDeadline(policy.ContextWithTimeouts(ctx, policy.Timeout{Resolve: time.Minute})); !got.Equal(deadline) {
+		t.Errorf("queryDeadline() = %v, want the deadline of the context %v", got, deadline)
+	}
+}
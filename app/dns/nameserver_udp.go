@@ -23,6 +23,8 @@ import (
 
 // ClassicNameServer implemented traditional UDP DNS.
 type ClassicNameServer struct {
+	cacheStats
+	serverBootstrap
 	sync.RWMutex
 	name          string
 	address       *net.Destination
@@ -64,6 +66,14 @@ func (s *ClassicNameServer) Name() string {
 	return s.name
 }
 
+// FlushCache implements dns.CacheFlusher.
+func (s *ClassicNameServer) FlushCache() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.ips = make(map[string]*record)
+}
+
 // Cleanup clears expired items from cache
 func (s *ClassicNameServer) Cleanup() error {
 	now := time.Now()
@@ -191,12 +201,17 @@ func (s *ClassicNameServer) addPendingRequest(req *dnsRequest) {
 func (s *ClassicNameServer) sendQuery(ctx context.Context, domain string, clientIP net.IP, option dns_feature.IPOption) {
 	errors.LogDebug(ctx, s.name, " querying DNS for: ", domain)
 
+	dest, err := s.resolveServer(ctx, *s.address)
+	if err != nil {
+		errors.LogWarningInner(ctx, err, s.name, " failed to query DNS for: ", domain)
+		return
+	}
 	reqs := buildReqMsgs(domain, option, s.newReqID, genEDNS0Options(clientIP))
 
 	for _, req := range reqs {
 		s.addPendingRequest(req)
 		b, _ := dns.PackMessage(req.msg)
-		s.udpServer.Dispatch(toDnsContext(ctx, s.address.String()), *s.address, b)
+		s.udpServer.Dispatch(toDnsContext(ctx, s.address.String()), dest, b)
 	}
 }
 
@@ -238,6 +253,13 @@ func (s *ClassicNameServer) findIPsForDomain(domain string, option dns_feature.I
 	return nil, dns_feature.ErrEmptyResponse
 }
 
+// cachedTTL implements cachingServer.
+func (s *ClassicNameServer) cachedTTL(domain string, option dns_feature.IPOption) uint32 {
+	s.RLock()
+	defer s.RUnlock()
+	return s.ips[Fqdn(domain)].ttl(option)
+}
+
 // QueryIP implements Server.
 func (s *ClassicNameServer) QueryIP(ctx context.Context, domain string, clientIP net.IP, option dns_feature.IPOption, disableCache bool) ([]net.IP, error) {
 	fqdn := Fqdn(domain)
@@ -252,9 +274,11 @@ func (s *ClassicNameServer) QueryIP(ctx context.Context, domain string, clientIP
 		ips, err := s.findIPsForDomain(fqdn, option)
 		if err == nil || err == dns_feature.ErrEmptyResponse {
 			errors.LogDebugInner(ctx, err, s.name, " cache HIT ", domain, " -> ", ips)
-			log.Record(&log.DNSLog{Server: s.name, Domain: domain, Result: ips, Status: log.DNSCacheHit, Elapsed: 0, Error: err})
+			log.Record(&log.DNSLog{Server: s.name, Domain: domain, Result: ips, Status: log.DNSCacheHit, Elapsed: 0, Error: err, SessionID: sessionID(ctx)})
+			s.cacheStats.hit()
 			return ips, err
 		}
+		s.cacheStats.miss()
 	}
 
 	// ipv4 and ipv6 belong to different subscription groups
@@ -289,7 +313,7 @@ func (s *ClassicNameServer) QueryIP(ctx context.Context, domain string, clientIP
 	for {
 		ips, err := s.findIPsForDomain(fqdn, option)
 		if err != errRecordNotFound {
-			log.Record(&log.DNSLog{Server: s.name, Domain: domain, Result: ips, Status: log.DNSQueried, Elapsed: time.Since(start), Error: err})
+			log.Record(&log.DNSLog{Server: s.name, Domain: domain, Result: ips, Status: log.DNSQueried, Elapsed: time.Since(start), Error: err, SessionID: sessionID(ctx)})
 			return ips, err
 		}
 
@@ -0,0 +1,140 @@
+// Package ftp follows FTP control connections, so that the data connections they announce
+// take the same outbound, which port based routing rules can't express.
+package ftp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/routing"
+)
+
+const defaultTimeout = time.Minute
+
+type expectation struct {
+	tag    string
+	expire time.Time
+}
+
+// Helper is a routing.ConnectionTracker for FTP.
+type Helper struct {
+	ports   map[net.Port]bool
+	rewrite bool
+	timeout time.Duration
+
+	access   sync.Mutex
+	expected map[string]expectation
+}
+
+// New creates a new Helper.
+func New(ctx context.Context, config *Config) (*Helper, error) {
+	h := &Helper{
+		ports:    make(map[net.Port]bool),
+		rewrite:  config.RewritePassive,
+		timeout:  time.Duration(config.Timeout) * time.Second,
+		expected: make(map[string]expectation),
+	}
+	if h.timeout == 0 {
+		h.timeout = defaultTimeout
+	}
+	for _, port := range config.Ports {
+		if port == 0 || port > 65535 {
+			return nil, errors.New("invalid FTP port: ", port)
+		}
+		h.ports[net.Port(port)] = true
+	}
+	if len(h.ports) == 0 {
+		h.ports[21] = true
+	}
+	return h, nil
+}
+
+// Type implements common.HasType.
+func (*Helper) Type() interface{} {
+	return routing.ConnectionTrackerType()
+}
+
+// Start implements common.Runnable.
+func (*Helper) Start() error {
+	return nil
+}
+
+// Close implements common.Closable.
+func (*Helper) Close() error {
+	return nil
+}
+
+func sourceOf(ctx context.Context) string {
+	if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.Source.IsValid() {
+		return inbound.Source.Address.String()
+	}
+	return ""
+}
+
+func expectationKey(source string, destination net.Destination) string {
+	return source + ">" + destination.NetAddr()
+}
+
+// expect records that source will connect to destination, on behalf of a control connection
+// routed to tag.
+func (h *Helper) expect(source string, destination net.Destination, tag string) {
+	now := time.Now()
+	h.access.Lock()
+	defer h.access.Unlock()
+	for k, e := range h.expected {
+		if now.After(e.expire) {
+			delete(h.expected, k)
+		}
+	}
+	h.expected[expectationKey(source, destination)] = expectation{
+		tag:    tag,
+		expire: now.Add(h.timeout),
+	}
+}
+
+// Track implements routing.ConnectionTracker.
+func (h *Helper) Track(ctx context.Context, destination net.Destination, outboundTag string, writer buf.Writer) buf.Writer {
+	if destination.Network != net.Network_TCP || !h.ports[destination.Port] {
+		return writer
+	}
+	return &replyWriter{
+		Writer: writer,
+		helper: h,
+		ctx:    ctx,
+		source: sourceOf(ctx),
+		server: destination,
+		tag:    outboundTag,
+	}
+}
+
+// OutboundFor implements routing.ConnectionTracker. Each announced data connection is
+// matched once.
+func (h *Helper) OutboundFor(ctx context.Context, destination net.Destination) string {
+	if destination.Network != net.Network_TCP {
+		return ""
+	}
+	key := expectationKey(sourceOf(ctx), destination)
+	h.access.Lock()
+	defer h.access.Unlock()
+	e, found := h.expected[key]
+	if !found {
+		return ""
+	}
+	delete(h.expected, key)
+	if time.Now().After(e.expire) {
+		return ""
+	}
+	return e.tag
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		return New(ctx, config.(*Config))
+	}))
+}
@@ -0,0 +1,161 @@
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strconv"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+// maxLineLength bounds the part of a reply held back while waiting for its line end. Longer
+// lines mean the connection doesn't carry plain FTP replies.
+const maxLineLength = 4096
+
+var (
+	passiveReply         = regexp.MustCompile(`(\d{1,3}),(\d{1,3}),(\d{1,3}),(\d{1,3}),(\d{1,3}),(\d{1,3})`)
+	extendedPassiveReply = regexp.MustCompile(`\(\|\|\|(\d{1,5})\|\)`)
+)
+
+// parsePassive parses the address of a "227 Entering Passive Mode" reply, and returns where
+// it is located in the line.
+func parsePassive(line []byte) (ip net.IP, port net.Port, loc []int) {
+	m := passiveReply.FindSubmatchIndex(line)
+	if m == nil {
+		return nil, 0, nil
+	}
+	var n [6]byte
+	for i := range n {
+		v, err := strconv.Atoi(string(line[m[2+2*i]:m[3+2*i]]))
+		if err != nil || v > 255 {
+			return nil, 0, nil
+		}
+		n[i] = byte(v)
+	}
+	return net.IP{n[0], n[1], n[2], n[3]}, net.PortFromBytes(n[4:]), m[:2]
+}
+
+// parseExtendedPassive parses the port of a "229 Entering Extended Passive Mode" reply.
+func parseExtendedPassive(line []byte) net.Port {
+	m := extendedPassiveReply.FindSubmatch(line)
+	if m == nil {
+		return 0
+	}
+	port, err := strconv.Atoi(string(m[1]))
+	if err != nil || port == 0 || port > 65535 {
+		return 0
+	}
+	return net.Port(port)
+}
+
+func isInternal(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast()
+}
+
+// replyWriter inspects the replies of an FTP server, line by line.
+type replyWriter struct {
+	buf.Writer
+	helper *Helper
+	ctx    context.Context
+	source string
+	server net.Destination
+	tag    string
+
+	line []byte // incomplete line
+	done bool
+}
+
+// WriteMultiBuffer implements buf.Writer.
+func (w *replyWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	if w.done {
+		return w.Writer.WriteMultiBuffer(mb)
+	}
+	data := w.line
+	for _, b := range mb {
+		data = append(data, b.Bytes()...)
+	}
+	buf.ReleaseMulti(mb)
+
+	var out []byte
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		out = append(out, w.handleLine(data[:i+1])...)
+		data = data[i+1:]
+		if w.done {
+			out = append(out, data...)
+			data = nil
+			break
+		}
+	}
+	if len(data) > maxLineLength {
+		out = append(out, data...)
+		data = nil
+		w.done = true
+	}
+	w.line = append([]byte(nil), data...)
+
+	if len(out) == 0 {
+		return nil
+	}
+	return w.Writer.WriteMultiBuffer(buf.MergeBytes(nil, out))
+}
+
+// handleLine records the data connection announced in line, and returns the line to send.
+func (w *replyWriter) handleLine(line []byte) []byte {
+	switch {
+	case bytes.HasPrefix(line, []byte("227 ")):
+		ip, port, loc := parsePassive(line)
+		if ip == nil {
+			return line
+		}
+		if w.helper.rewrite && isInternal(ip) && w.server.Address.Family().IsIPv4() && !isInternal(w.server.Address.IP()) {
+			server := w.server.Address.IP().To4()
+			errors.LogInfo(w.ctx, "rewriting FTP passive address ", ip, " to ", server)
+			ip = server
+			rewritten := append([]byte(nil), line[:loc[0]]...)
+			for _, b := range server {
+				rewritten = strconv.AppendInt(rewritten, int64(b), 10)
+				rewritten = append(rewritten, ',')
+			}
+			rewritten = strconv.AppendInt(rewritten, int64(port>>8), 10)
+			rewritten = append(rewritten, ',')
+			rewritten = strconv.AppendInt(rewritten, int64(port&0xff), 10)
+			line = append(rewritten, line[loc[1]:]...)
+		}
+		w.expect(net.TCPDestination(net.IPAddress(ip), port))
+	case bytes.HasPrefix(line, []byte("229 ")):
+		if port := parseExtendedPassive(line); port != 0 {
+			w.expect(net.TCPDestination(w.server.Address, port))
+		}
+	case bytes.HasPrefix(line, []byte("234 ")):
+		// AUTH TLS accepted, the rest is encrypted.
+		w.done = true
+	}
+	return line
+}
+
+func (w *replyWriter) expect(destination net.Destination) {
+	errors.LogInfo(w.ctx, "expecting FTP data connection to ", destination, " through [", w.tag, "]")
+	w.helper.expect(w.source, destination, w.tag)
+}
+
+// Close implements common.Closable.
+func (w *replyWriter) Close() error {
+	if len(w.line) > 0 {
+		w.Writer.WriteMultiBuffer(buf.MergeBytes(nil, w.line))
+		w.line = nil
+	}
+	return common.Close(w.Writer)
+}
+
+// Interrupt implements common.Interruptible.
+func (w *replyWriter) Interrupt() {
+	common.Interrupt(w.Writer)
+}
@@ -0,0 +1,63 @@
+package ftp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+)
+
+type collectWriter struct {
+	data []byte
+}
+
+func (w *collectWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	for _, b := range mb {
+		w.data = append(w.data, b.Bytes()...)
+	}
+	buf.ReleaseMulti(mb)
+	return nil
+}
+
+func TestPassiveRewrite(t *testing.T) {
+	h, err := New(context.Background(), &Config{RewritePassive: true})
+	common.Must(err)
+
+	server := net.TCPDestination(net.ParseAddress("8.8.4.4"), 21)
+	out := new(collectWriter)
+	w := h.Track(context.Background(), server, "proxy", out)
+
+	// A reply split across writes.
+	common.Must(w.WriteMultiBuffer(buf.MergeBytes(nil, []byte("220 ready\r\n227 Entering Passive Mode (192,168,1,"))))
+	if string(out.data) != "220 ready\r\n" {
+		t.Error("unexpected output: ", string(out.data))
+	}
+	common.Must(w.WriteMultiBuffer(buf.MergeBytes(nil, []byte("2,78,52).\r\n"))))
+	if string(out.data) != "220 ready\r\n227 Entering Passive Mode (8,8,4,4,78,52).\r\n" {
+		t.Error("unexpected output: ", string(out.data))
+	}
+
+	data := net.TCPDestination(net.ParseAddress("8.8.4.4"), 78*256+52)
+	if tag := h.OutboundFor(context.Background(), data); tag != "proxy" {
+		t.Error("unexpected tag: ", tag)
+	}
+	if tag := h.OutboundFor(context.Background(), data); tag != "" {
+		t.Error("expectation matched twice")
+	}
+}
+
+func TestExtendedPassive(t *testing.T) {
+	h, err := New(context.Background(), &Config{})
+	common.Must(err)
+
+	server := net.TCPDestination(net.ParseAddress("ftp.example.com"), 21)
+	w := h.Track(context.Background(), server, "proxy", new(collectWriter))
+	common.Must(w.WriteMultiBuffer(buf.MergeBytes(nil, []byte("229 Entering Extended Passive Mode (|||50000|)\r\n"))))
+
+	data := net.TCPDestination(net.ParseAddress("ftp.example.com"), 50000)
+	if tag := h.OutboundFor(context.Background(), data); tag != "proxy" {
+		t.Error("unexpected tag: ", tag)
+	}
+}
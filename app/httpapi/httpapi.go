@@ -0,0 +1,209 @@
+// Package httpapi serves a REST and WebSocket API for external GUIs, following the API of Clash
+// where its concepts match those of Xray.
+package httpapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/listen"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/status"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+// DefaultListen is the address the API listens on if none is set. It's only reachable locally.
+const DefaultListen = "127.0.0.1:9090"
+
+// Server is the HTTP API.
+type Server struct {
+	config   *Config
+	instance *core.Instance
+	ohm      outbound.Manager
+	router   routing.Router
+	stats    stats.Manager
+	dns      dns.Client
+	server   *http.Server
+	listener net.Listener
+
+	access  sync.Mutex
+	history map[string][]delayResult
+	// replacing serializes replacements of outbounds, so that one doesn't see the outbound of
+	// another missing halfway.
+	replacing sync.Mutex
+}
+
+// NewServer creates a new Server based on the given config.
+func NewServer(ctx context.Context, config *Config) (*Server, error) {
+	s := &Server{
+		config:   config,
+		instance: core.MustFromContext(ctx),
+		history:  map[string][]delayResult{},
+	}
+	common.Must(core.RequireFeatures(ctx, func(om outbound.Manager, r routing.Router, sm stats.Manager, d dns.Client) {
+		s.ohm = om
+		s.router = r
+		s.stats = sm
+		s.dns = d
+	}))
+	return s, nil
+}
+
+// Type implements common.HasType.
+func (*Server) Type() interface{} {
+	return (*Server)(nil)
+}
+
+// Start implements common.Runnable.
+func (s *Server) Start() error {
+	address := s.config.Listen
+	if address == "" {
+		address = DefaultListen
+	}
+	listener, err := listen.TCP(context.Background(), address, s.dns)
+	if err != nil {
+		return errors.New("failed to listen on ", address).Base(errors.Diagnose(errors.CodeAPIPortBusy, err))
+	}
+	s.listener = listener
+	s.server = &http.Server{
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errors.LogInfo(context.Background(), "HTTP API listening on ", listener.Addr())
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			errors.LogErrorInner(context.Background(), err, "failed to serve HTTP API")
+		}
+	}()
+	return nil
+}
+
+// Close implements common.Closable.
+func (s *Server) Close() error {
+	if s.server != nil {
+		return s.server.Close()
+	}
+	return nil
+}
+
+// Addr returns the address the API listens on, or nil before Start.
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"hello": "xray"})
+	})
+	mux.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"version": core.Version()})
+	})
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, status.Collect(s.instance))
+	})
+	mux.HandleFunc("GET /proxies", s.listProxies)
+	mux.HandleFunc("GET /proxies/{name}", s.getProxy)
+	mux.HandleFunc("PUT /proxies/{name}", s.selectProxy)
+	mux.HandleFunc("GET /proxies/{name}/delay", s.testDelay)
+	mux.HandleFunc("POST /outbounds", s.addOutbound)
+	mux.HandleFunc("PUT /outbounds/{tag}", s.replaceOutbound)
+	mux.HandleFunc("DELETE /outbounds/{tag}", s.removeOutbound)
+	mux.HandleFunc("GET /stats", s.queryStats)
+	mux.HandleFunc("GET /traffic", s.streamTraffic)
+	mux.HandleFunc("GET /logs", s.streamLogs)
+	mux.HandleFunc("GET /sysproxy", getSystemProxy)
+	mux.HandleFunc("PUT /sysproxy", setSystemProxy)
+	mux.HandleFunc("GET /pause", s.getPause)
+	mux.HandleFunc("PUT /pause", s.setPause)
+	return s.cors(s.authenticate(mux))
+}
+
+// allowedOrigin returns whether web pages of origin may call the API.
+func (s *Server) allowedOrigin(origin string) bool {
+	for _, allowed := range s.config.AllowOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// cors answers preflight requests, and lets browsers read the responses to requests from the
+// allowed origins.
+func (s *Server) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && s.allowedOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+			w.Header().Add("Vary", "Origin")
+		}
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate rejects requests without the secret as bearer token. Browsers can't set headers
+// on WebSocket requests, so those may pass it as the token query parameter instead.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	secret := []byte(s.config.Secret)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(secret) > 0 {
+			token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found && isWebSocket(r) {
+				token = r.URL.Query().Get("token")
+			}
+			if subtle.ConstantTimeCompare([]byte(token), secret) != 1 {
+				writeError(w, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes message the way Clash reports errors.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
+
+// writeErrorOf writes err after prefix the way writeError does, along with the code of its
+// Diagnostic, if any, for automation.
+func writeErrorOf(w http.ResponseWriter, status int, prefix string, err error) {
+	body := map[string]string{"message": prefix + err.Error()}
+	if diag := errors.DiagnosticOf(err); diag != nil {
+		body["code"] = diag.Code
+	}
+	writeJSON(w, status, body)
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, cfg interface{}) (interface{}, error) {
+		return NewServer(ctx, cfg.(*Config))
+	}))
+}
@@ -0,0 +1,234 @@
+package httpapi_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/xtls/xray-core/app/httpapi"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/status"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/infra/conf/serial"
+	_ "github.com/xtls/xray-core/main/distro/all"
+)
+
+const testConfig = `{
+	"httpApi": {"listen": "127.0.0.1:0", "secret": "secret"},
+	"stats": {},
+	"policy": {"system": {"statsOutboundUplink": true, "statsOutboundDownlink": true}},
+	"outbounds": [
+		{"tag": "direct", "protocol": "freedom"},
+		{"tag": "block", "protocol": "blackhole"}
+	],
+	"routing": {
+		"balancers": [{"tag": "auto", "selector": ["direct", "block"]}]
+	}
+}`
+
+func startServer(t *testing.T) (*core.Instance, string) {
+	t.Helper()
+	config, err := serial.LoadJSONConfig(strings.NewReader(testConfig))
+	common.Must(err)
+	instance, err := core.New(config)
+	common.Must(err)
+	common.Must(instance.Start())
+	t.Cleanup(func() { instance.Close() })
+	server := instance.GetFeature((*httpapi.Server)(nil)).(*httpapi.Server)
+	return instance, "http://" + server.Addr().String()
+}
+
+func request(t *testing.T, method, url, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	common.Must(err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	common.Must(err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func decode(t *testing.T, resp *http.Response, v interface{}) {
+	t.Helper()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatal("unexpected status ", resp.Status, ": ", string(body))
+	}
+	common.Must(json.NewDecoder(resp.Body).Decode(v))
+}
+
+type proxy struct {
+	Name string   `json:"name"`
+	Type string   `json:"type"`
+	All  []string `json:"all"`
+	Now  string   `json:"now"`
+}
+
+func TestAuthentication(t *testing.T) {
+	_, base := startServer(t)
+
+	resp, err := http.Get(base + "/proxies")
+	common.Must(err)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Error("expected unauthorized, got ", resp.Status)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, base+"/proxies", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	resp, err = http.DefaultClient.Do(req)
+	common.Must(err)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Error("expected unauthorized, got ", resp.Status)
+	}
+
+	var hello map[string]string
+	decode(t, request(t, http.MethodGet, base+"/", ""), &hello)
+	if hello["hello"] != "xray" {
+		t.Error("unexpected response: ", hello)
+	}
+}
+
+func TestProxies(t *testing.T) {
+	_, base := startServer(t)
+
+	var list struct {
+		Proxies map[string]proxy `json:"proxies"`
+	}
+	decode(t, request(t, http.MethodGet, base+"/proxies", ""), &list)
+	if list.Proxies["direct"].Type != "Direct" || list.Proxies["block"].Type != "Reject" {
+		t.Error("unexpected outbounds: ", list.Proxies)
+	}
+	if auto := list.Proxies["auto"]; auto.Type != "Selector" || len(auto.All) != 2 {
+		t.Error("unexpected balancer: ", auto)
+	}
+
+	if resp := request(t, http.MethodPut, base+"/proxies/auto", `{"name": "block"}`); resp.StatusCode != http.StatusNoContent {
+		t.Fatal("failed to select outbound: ", resp.Status)
+	}
+	var auto proxy
+	decode(t, request(t, http.MethodGet, base+"/proxies/auto", ""), &auto)
+	if auto.Now != "block" {
+		t.Error("expected block selected, got ", auto.Now)
+	}
+	if resp := request(t, http.MethodPut, base+"/proxies/auto", `{"name": "missing"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Error("expected missing outbound rejected, got ", resp.Status)
+	}
+	if resp := request(t, http.MethodGet, base+"/proxies/missing", ""); resp.StatusCode != http.StatusNotFound {
+		t.Error("expected not found, got ", resp.Status)
+	}
+}
+
+func TestOutbounds(t *testing.T) {
+	_, base := startServer(t)
+
+	if resp := request(t, http.MethodPost, base+"/outbounds", `{"tag": "added", "protocol": "blackhole"}`); resp.StatusCode != http.StatusNoContent {
+		t.Fatal("failed to add outbound: ", resp.Status)
+	}
+	if resp := request(t, http.MethodPost, base+"/outbounds", `{"tag": "added", "protocol": "blackhole"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Error("expected existing tag rejected, got ", resp.Status)
+	}
+	if resp := request(t, http.MethodPut, base+"/outbounds/added", `{"protocol": "freedom"}`); resp.StatusCode != http.StatusNoContent {
+		t.Fatal("failed to replace outbound: ", resp.Status)
+	}
+	var added proxy
+	decode(t, request(t, http.MethodGet, base+"/proxies/added", ""), &added)
+	if added.Type != "Direct" {
+		t.Error("expected replaced outbound, got ", added.Type)
+	}
+	if resp := request(t, http.MethodDelete, base+"/outbounds/added", ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatal("failed to remove outbound: ", resp.Status)
+	}
+	if resp := request(t, http.MethodGet, base+"/proxies/added", ""); resp.StatusCode != http.StatusNotFound {
+		t.Error("expected removed outbound, got ", resp.Status)
+	}
+}
+
+func TestTraffic(t *testing.T) {
+	_, base := startServer(t)
+
+	resp := request(t, http.MethodGet, base+"/traffic", "")
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	common.Must(err)
+	var traffic map[string]int64
+	common.Must(json.Unmarshal([]byte(line), &traffic))
+	if _, found := traffic["up"]; !found {
+		t.Error("unexpected traffic: ", line)
+	}
+
+	var stats struct {
+		Counters map[string]int64 `json:"counters"`
+	}
+	decode(t, request(t, http.MethodGet, base+"/stats?pattern=^outbound>>>direct>>>", ""), &stats)
+	if len(stats.Counters) != 2 {
+		t.Error("unexpected counters: ", stats.Counters)
+	}
+}
+
+type systemProxy struct {
+	enabled bool
+}
+
+func (p *systemProxy) Enabled() bool {
+	return p.enabled
+}
+
+func (p *systemProxy) SetEnabled(enabled bool) error {
+	p.enabled = enabled
+	return nil
+}
+
+func TestSystemProxy(t *testing.T) {
+	_, base := startServer(t)
+
+	if resp := request(t, http.MethodGet, base+"/sysproxy", ""); resp.StatusCode != http.StatusNotImplemented {
+		t.Error("expected system proxy not available, got ", resp.Status)
+	}
+	p := &systemProxy{enabled: true}
+	httpapi.RegisterSystemProxy(p)
+	defer httpapi.RegisterSystemProxy(nil)
+
+	if resp := request(t, http.MethodPut, base+"/sysproxy", `{"enabled": false}`); resp.StatusCode != http.StatusNoContent {
+		t.Fatal("failed to disable system proxy: ", resp.Status)
+	}
+	var state map[string]bool
+	decode(t, request(t, http.MethodGet, base+"/sysproxy", ""), &state)
+	if p.enabled || state["enabled"] {
+		t.Error("expected system proxy disabled")
+	}
+}
+
+func TestPause(t *testing.T) {
+	instance, base := startServer(t)
+
+	if resp := request(t, http.MethodPut, base+"/pause", `{"paused": true}`); resp.StatusCode != http.StatusNoContent {
+		t.Fatal("failed to pause: ", resp.Status)
+	}
+	var state map[string]bool
+	decode(t, request(t, http.MethodGet, base+"/pause", ""), &state)
+	if !state["paused"] || !instance.GetFeature(inbound.ManagerType()).(inbound.Pauser).Paused() {
+		t.Error("expected inbounds paused")
+	}
+	if resp := request(t, http.MethodPut, base+"/pause", `{}`); resp.StatusCode != http.StatusBadRequest {
+		t.Error("expected bad request, got ", resp.Status)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	_, base := startServer(t)
+
+	var summary status.Summary
+	decode(t, request(t, http.MethodGet, base+"/status", ""), &summary)
+	if len(summary.Outbounds) != 2 || summary.Outbounds[0] != (status.Outbound{Tag: "block", Protocol: "blackhole"}) {
+		t.Error("unexpected outbounds: ", summary.Outbounds)
+	}
+	if len(summary.Balancers) != 1 || summary.Balancers[0] != "auto" {
+		t.Error("unexpected balancers: ", summary.Balancers)
+	}
+}
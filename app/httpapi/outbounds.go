@@ -0,0 +1,94 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/xtls/xray-core/core"
+)
+
+// maxOutboundSize caps the size of outbound configs posted to the API.
+const maxOutboundSize = 1 << 20
+
+// readOutbound builds the outbound of the Xray JSON config in the body of r. The config is loaded
+// through the JSON loader of core, as this package can't depend on infra/conf.
+func readOutbound(r *http.Request) (*core.OutboundHandlerConfig, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxOutboundSize))
+	if err != nil {
+		return nil, err
+	}
+	var outbound json.RawMessage
+	if err := json.Unmarshal(body, &outbound); err != nil {
+		return nil, err
+	}
+	wrapped, err := json.Marshal(map[string][]json.RawMessage{"outbounds": {outbound}})
+	if err != nil {
+		return nil, err
+	}
+	config, err := core.LoadConfig("json", bytes.NewReader(wrapped))
+	if err != nil {
+		return nil, err
+	}
+	return config.Outbound[0], nil
+}
+
+// addOutbound adds the outbound in the body, which must have a tag not in use.
+func (s *Server) addOutbound(w http.ResponseWriter, r *http.Request) {
+	config, err := readOutbound(r)
+	if err != nil {
+		writeErrorOf(w, http.StatusBadRequest, "Body invalid: ", err)
+		return
+	}
+	if config.Tag == "" {
+		writeError(w, http.StatusBadRequest, "Body invalid: outbound has no tag")
+		return
+	}
+	if err := core.AddOutboundHandler(s.instance, config); err != nil {
+		writeErrorOf(w, http.StatusBadRequest, "", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// replaceOutbound replaces the outbound with the given tag with the one in the body, which takes
+// the tag.
+func (s *Server) replaceOutbound(w http.ResponseWriter, r *http.Request) {
+	tag := r.PathValue("tag")
+	config, err := readOutbound(r)
+	if err != nil {
+		writeErrorOf(w, http.StatusBadRequest, "Body invalid: ", err)
+		return
+	}
+	config.Tag = tag
+
+	s.replacing.Lock()
+	defer s.replacing.Unlock()
+	if s.ohm.GetHandler(tag) == nil {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	if err := s.ohm.RemoveHandler(r.Context(), tag); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := core.AddOutboundHandler(s.instance, config); err != nil {
+		writeErrorOf(w, http.StatusBadRequest, "", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) removeOutbound(w http.ResponseWriter, r *http.Request) {
+	tag := r.PathValue("tag")
+	if s.ohm.GetHandler(tag) == nil {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	if err := s.ohm.RemoveHandler(r.Context(), tag); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
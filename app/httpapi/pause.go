@@ -0,0 +1,84 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/xtls/xray-core/features/inbound"
+)
+
+// Pause pauses and resumes the proxy, with the inbounds refusing new connections while paused. It's
+// provided by the program embedding Xray, which moves the system proxy along. Without one, the API
+// pauses the inbounds alone.
+type Pause interface {
+	Paused() bool
+	SetPaused(paused bool) error
+}
+
+var (
+	pauseAccess sync.Mutex
+	pause       Pause
+)
+
+// RegisterPause makes the API pause and resume the proxy through p.
+func RegisterPause(p Pause) {
+	pauseAccess.Lock()
+	defer pauseAccess.Unlock()
+	pause = p
+}
+
+// inboundPause pauses the inbounds of an instance.
+type inboundPause struct {
+	pauser inbound.Pauser
+}
+
+func (p inboundPause) Paused() bool {
+	return p.pauser.Paused()
+}
+
+func (p inboundPause) SetPaused(paused bool) error {
+	p.pauser.SetPaused(paused)
+	return nil
+}
+
+func (s *Server) registeredPause() Pause {
+	pauseAccess.Lock()
+	defer pauseAccess.Unlock()
+	if pause != nil {
+		return pause
+	}
+	if pauser, ok := s.instance.GetFeature(inbound.ManagerType()).(inbound.Pauser); ok {
+		return inboundPause{pauser}
+	}
+	return nil
+}
+
+func (s *Server) getPause(w http.ResponseWriter, r *http.Request) {
+	p := s.registeredPause()
+	if p == nil {
+		writeError(w, http.StatusNotImplemented, "Pausing is not available")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": p.Paused()})
+}
+
+func (s *Server) setPause(w http.ResponseWriter, r *http.Request) {
+	p := s.registeredPause()
+	if p == nil {
+		writeError(w, http.StatusNotImplemented, "Pausing is not available")
+		return
+	}
+	var body struct {
+		Paused *bool `json:"paused"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Paused == nil {
+		writeError(w, http.StatusBadRequest, "Body invalid")
+		return
+	}
+	if err := p.SetPaused(*body.Paused); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
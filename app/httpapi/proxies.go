@@ -0,0 +1,264 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/status"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/routing"
+)
+
+const (
+	// defaultDelayURL is requested through outbounds to test their delay, if the client doesn't
+	// tell one.
+	defaultDelayURL = "https://www.gstatic.com/generate_204"
+	// defaultDelayTimeout is the time given to delay tests, if the client doesn't tell one.
+	defaultDelayTimeout = 5 * time.Second
+	// maxHistory is the number of delay results kept for each outbound.
+	maxHistory = 10
+)
+
+// clashTypes maps the packages of the outbound protocols to the names Clash gives them.
+var clashTypes = map[string]string{
+	"freedom":     "Direct",
+	"blackhole":   "Reject",
+	"dns":         "Dns",
+	"http":        "Http",
+	"socks":       "Socks5",
+	"shadowsocks": "Shadowsocks",
+	"vmess":       "Vmess",
+	"vless":       "Vless",
+	"trojan":      "Trojan",
+	"wireguard":   "WireGuard",
+	"hysteria":    "Hysteria2",
+}
+
+type delayResult struct {
+	Time  time.Time `json:"time"`
+	Delay int64     `json:"delay"`
+}
+
+// proxyInfo is an outbound, or a balancer as a Selector group, as Clash describes its proxies.
+type proxyInfo struct {
+	Name    string        `json:"name"`
+	Type    string        `json:"type"`
+	History []delayResult `json:"history"`
+	All     []string      `json:"all,omitempty"`
+	Now     string        `json:"now,omitempty"`
+}
+
+// outboundType returns the Clash name of the protocol of handler.
+func outboundType(handler outbound.Handler) string {
+	protocol := status.OutboundProtocol(handler)
+	if name, found := clashTypes[protocol]; found {
+		return name
+	}
+	return protocol
+}
+
+func (s *Server) historyOf(name string) []delayResult {
+	s.access.Lock()
+	defer s.access.Unlock()
+	return append([]delayResult{}, s.history[name]...)
+}
+
+func (s *Server) addHistory(name string, delay time.Duration) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	history := append(s.history[name], delayResult{Time: time.Now(), Delay: delay.Milliseconds()})
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+	s.history[name] = history
+}
+
+// isBalancer returns whether name is the tag of a balancer rather than an outbound.
+func (s *Server) isBalancer(name string) bool {
+	if lister, ok := s.router.(routing.BalancerLister); ok {
+		for _, tag := range lister.ListBalancers() {
+			if tag == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// selected returns the outbound the balancer with the given tag uses: the one chosen through the
+// API if any, or else the one its strategy prefers.
+func (s *Server) selected(tag string) string {
+	if overrider, ok := s.router.(routing.BalancerOverrider); ok {
+		if target, err := overrider.GetOverrideTarget(tag); err == nil && target != "" {
+			return target
+		}
+	}
+	if principle, ok := s.router.(routing.BalancerPrincipleTarget); ok {
+		if targets, err := principle.GetPrincipleTarget(tag); err == nil && len(targets) > 0 {
+			return targets[0]
+		}
+	}
+	return ""
+}
+
+// proxy returns the outbound or balancer with the given name, or nil if there's none.
+func (s *Server) proxy(name string) *proxyInfo {
+	if s.isBalancer(name) {
+		info := &proxyInfo{Name: name, Type: "Selector", History: s.historyOf(name), Now: s.selected(name)}
+		if candidates, ok := s.router.(routing.BalancerCandidates); ok {
+			info.All, _ = candidates.GetCandidates(name)
+		}
+		return info
+	}
+	if name == "" {
+		return nil
+	}
+	handler := s.ohm.GetHandler(name)
+	if handler == nil {
+		return nil
+	}
+	return &proxyInfo{Name: name, Type: outboundType(handler), History: s.historyOf(name)}
+}
+
+func (s *Server) listProxies(w http.ResponseWriter, r *http.Request) {
+	var names []string
+	if selector, ok := s.ohm.(outbound.HandlerSelector); ok {
+		names = selector.Select([]string{""})
+	}
+	if lister, ok := s.router.(routing.BalancerLister); ok {
+		names = append(names, lister.ListBalancers()...)
+	}
+	proxies := map[string]*proxyInfo{}
+	for _, name := range names {
+		if info := s.proxy(name); info != nil {
+			proxies[name] = info
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"proxies": proxies})
+}
+
+func (s *Server) getProxy(w http.ResponseWriter, r *http.Request) {
+	info := s.proxy(r.PathValue("name"))
+	if info == nil {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// selectProxy makes a balancer use the outbound in the body, or its strategy again if the
+// outbound is empty.
+func (s *Server) selectProxy(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Body invalid")
+		return
+	}
+	overrider, ok := s.router.(routing.BalancerOverrider)
+	if !ok || !s.isBalancer(name) {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	if body.Name != "" {
+		if candidates, ok := s.router.(routing.BalancerCandidates); ok {
+			all, _ := candidates.GetCandidates(name)
+			found := false
+			for _, tag := range all {
+				found = found || tag == body.Name
+			}
+			if !found {
+				writeError(w, http.StatusBadRequest, "Selector update error: proxy not exist")
+				return
+			}
+		}
+	}
+	if err := overrider.SetOverrideTarget(name, body.Name); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	errors.LogInfo(r.Context(), "balancer [", name, "] switched to [", body.Name, "] through HTTP API")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// testDelay requests the url in the query through the outbound, or the outbound a balancer
+// uses, and answers with the time it took in milliseconds.
+func (s *Server) testDelay(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if s.proxy(name) == nil {
+		writeError(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+	tag := name
+	if s.isBalancer(name) {
+		if tag = s.selected(name); tag == "" {
+			writeError(w, http.StatusServiceUnavailable, "No outbound selected")
+			return
+		}
+	}
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		url = defaultDelayURL
+	}
+	timeout := defaultDelayTimeout
+	if value := r.URL.Query().Get("timeout"); value != "" {
+		ms, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || ms == 0 {
+			writeError(w, http.StatusBadRequest, "Format error")
+			return
+		}
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+	delay, err := s.headThrough(r.Context(), tag, url, timeout)
+	if err != nil {
+		s.addHistory(name, 0)
+		if timeout, ok := err.(interface{ Timeout() bool }); ok && timeout.Timeout() {
+			writeError(w, http.StatusGatewayTimeout, "Timeout")
+		} else {
+			writeError(w, http.StatusServiceUnavailable, "An error occurred in the delay test: "+err.Error())
+		}
+		return
+	}
+	s.addHistory(name, delay)
+	writeJSON(w, http.StatusOK, map[string]int64{"delay": delay.Milliseconds()})
+}
+
+// headThrough makes a HEAD request to url through the outbound with the given tag.
+func (s *Server) headThrough(ctx context.Context, tag string, url string, timeout time.Duration) (time.Duration, error) {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dest, err := net.ParseDestination(network + ":" + addr)
+				if err != nil {
+					return nil, err
+				}
+				return core.Dial(session.SetForcedOutboundTagToContext(ctx, tag), s.instance, dest)
+			},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout: timeout,
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	response, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	response.Body.Close()
+	return time.Since(start), nil
+}
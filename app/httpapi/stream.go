@@ -0,0 +1,252 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/xtls/xray-core/app/log"
+	"github.com/xtls/xray-core/app/stats"
+	clog "github.com/xtls/xray-core/common/log"
+	feature_stats "github.com/xtls/xray-core/features/stats"
+)
+
+// logBuffer is the number of log entries kept for a client slow to receive them. Entries beyond
+// it are dropped.
+const logBuffer = 256
+
+// stream sends JSON values to a client, as WebSocket messages if it asked for an upgrade, or
+// else as lines of a chunked response, the way Clash streams traffic and logs.
+type stream struct {
+	ws      *websocket.Conn
+	w       http.ResponseWriter
+	flusher http.Flusher
+	encoder *json.Encoder
+	// done is closed once the client goes away.
+	done <-chan struct{}
+}
+
+func (s *Server) newStream(w http.ResponseWriter, r *http.Request) (*stream, error) {
+	if isWebSocket(r) {
+		upgrader := websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				return origin == "" || s.allowedOrigin(origin)
+			},
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return nil, err
+		}
+		// Reads make the connection notice the client closing it.
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+		return &stream{ws: conn, done: done}, nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	return &stream{w: w, flusher: flusher, encoder: json.NewEncoder(w), done: r.Context().Done()}, nil
+}
+
+func (s *stream) send(v interface{}) error {
+	if s.ws != nil {
+		s.ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return s.ws.WriteJSON(v)
+	}
+	if err := s.encoder.Encode(v); err != nil {
+		return err
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	return nil
+}
+
+func (s *stream) close() {
+	if s.ws != nil {
+		s.ws.Close()
+	}
+}
+
+func isWebSocket(r *http.Request) bool {
+	return websocket.IsWebSocketUpgrade(r)
+}
+
+// traffic returns the bytes sent and received through all outbounds, as counted by the stats
+// of outbounds.
+func (s *Server) traffic() (up int64, down int64) {
+	manager, ok := s.stats.(*stats.Manager)
+	if !ok {
+		return 0, 0
+	}
+	manager.VisitCounters(func(name string, counter feature_stats.Counter) bool {
+		// outbound>>>tag>>>traffic>>>direction
+		parts := strings.Split(name, ">>>")
+		if len(parts) != 4 || parts[0] != "outbound" || parts[2] != "traffic" {
+			return true
+		}
+		switch parts[3] {
+		case "uplink":
+			up += counter.Value()
+		case "downlink":
+			down += counter.Value()
+		}
+		return true
+	})
+	return up, down
+}
+
+// streamTraffic sends the bytes per second sent and received through all outbounds, every
+// second.
+func (s *Server) streamTraffic(w http.ResponseWriter, r *http.Request) {
+	st, err := s.newStream(w, r)
+	if err != nil {
+		return
+	}
+	defer st.close()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	lastUp, lastDown := s.traffic()
+	for {
+		select {
+		case <-ticker.C:
+		case <-st.done:
+			return
+		}
+		up, down := s.traffic()
+		if err := st.send(map[string]int64{"up": up - lastUp, "down": down - lastDown}); err != nil {
+			return
+		}
+		lastUp, lastDown = up, down
+	}
+}
+
+type logEntry struct {
+	Type    string `json:"type"`
+	Payload string `json:"payload"`
+}
+
+// logLevels maps the levels of Clash to the severities they show.
+var logLevels = map[string]clog.Severity{
+	"error":   clog.Severity_Error,
+	"warning": clog.Severity_Warning,
+	"info":    clog.Severity_Info,
+	"debug":   clog.Severity_Debug,
+}
+
+// newLogEntry returns the entry of msg, or nil if it's more verbose than level. Access and DNS
+// logs show at the info level.
+func newLogEntry(msg clog.Message, level clog.Severity) *logEntry {
+	inner := msg
+	if masked, ok := msg.(*log.MaskedMsgWrapper); ok {
+		inner = masked.Message
+	}
+	severity := clog.Severity_Info
+	switch inner := inner.(type) {
+	case *clog.AccessMessage, *clog.DNSLog:
+	case *clog.GeneralMessage:
+		severity = inner.Severity
+	default:
+		return nil
+	}
+	if severity > level {
+		return nil
+	}
+	entry := &logEntry{Type: "info", Payload: msg.String()}
+	for name, s := range logLevels {
+		if s == severity {
+			entry.Type = name
+		}
+	}
+	return entry
+}
+
+// streamLogs sends the log messages at the level in the query, info by default, as they're
+// written.
+func (s *Server) streamLogs(w http.ResponseWriter, r *http.Request) {
+	level := clog.Severity_Info
+	if name := r.URL.Query().Get("level"); name != "" {
+		var found bool
+		if level, found = logLevels[name]; !found {
+			writeError(w, http.StatusBadRequest, "Level error")
+			return
+		}
+	}
+	logger, ok := s.instance.GetFeature((*log.Instance)(nil)).(*log.Instance)
+	if !ok {
+		writeError(w, http.StatusServiceUnavailable, "Logs are not available")
+		return
+	}
+	st, err := s.newStream(w, r)
+	if err != nil {
+		return
+	}
+	defer st.close()
+
+	entries := make(chan *logEntry, logBuffer)
+	var dropped atomic.Uint32
+	follower := func(msg clog.Message) {
+		entry := newLogEntry(msg, level)
+		if entry == nil {
+			return
+		}
+		select {
+		case entries <- entry:
+		default:
+			dropped.Add(1)
+		}
+	}
+	logger.AddFollower(&follower)
+	defer logger.RemoveFollower(&follower)
+
+	for {
+		select {
+		case entry := <-entries:
+			if n := dropped.Swap(0); n > 0 {
+				st.send(&logEntry{Type: "warning", Payload: fmt.Sprint("dropped ", n, " log messages the client was too slow for")})
+			}
+			if err := st.send(entry); err != nil {
+				return
+			}
+		case <-st.done:
+			return
+		}
+	}
+}
+
+// queryStats answers with the values of the counters matching the regular expression in the
+// pattern query parameter, or of all of them.
+func (s *Server) queryStats(w http.ResponseWriter, r *http.Request) {
+	manager, ok := s.stats.(*stats.Manager)
+	if !ok {
+		writeError(w, http.StatusServiceUnavailable, "Stats are not enabled")
+		return
+	}
+	pattern, err := regexp.Compile(r.URL.Query().Get("pattern"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Pattern error: "+err.Error())
+		return
+	}
+	counters := map[string]int64{}
+	manager.VisitCounters(func(name string, counter feature_stats.Counter) bool {
+		if pattern.MatchString(name) {
+			counters[name] = counter.Value()
+		}
+		return true
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"counters": counters})
+}
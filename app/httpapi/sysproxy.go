@@ -0,0 +1,61 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// SystemProxy turns the system proxy pointing to Xray on and off. It's provided by the program
+// embedding Xray, which knows the inbound the system proxy points to.
+type SystemProxy interface {
+	Enabled() bool
+	SetEnabled(enabled bool) error
+}
+
+var (
+	systemProxyAccess sync.Mutex
+	systemProxy       SystemProxy
+)
+
+// RegisterSystemProxy makes the API control the system proxy through p.
+func RegisterSystemProxy(p SystemProxy) {
+	systemProxyAccess.Lock()
+	defer systemProxyAccess.Unlock()
+	systemProxy = p
+}
+
+func registeredSystemProxy() SystemProxy {
+	systemProxyAccess.Lock()
+	defer systemProxyAccess.Unlock()
+	return systemProxy
+}
+
+func getSystemProxy(w http.ResponseWriter, r *http.Request) {
+	p := registeredSystemProxy()
+	if p == nil {
+		writeError(w, http.StatusNotImplemented, "System proxy is not available")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"enabled": p.Enabled()})
+}
+
+func setSystemProxy(w http.ResponseWriter, r *http.Request) {
+	p := registeredSystemProxy()
+	if p == nil {
+		writeError(w, http.StatusNotImplemented, "System proxy is not available")
+		return
+	}
+	var body struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Enabled == nil {
+		writeError(w, http.StatusBadRequest, "Body invalid")
+		return
+	}
+	if err := p.SetEnabled(*body.Enabled); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
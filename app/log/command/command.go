@@ -2,10 +2,14 @@ package command
 
 import (
 	"context"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/xtls/xray-core/app/log"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
+	clog "github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/core"
 	grpc "google.golang.org/grpc"
 )
@@ -29,6 +33,114 @@ func (s *LoggerServer) RestartLogger(ctx context.Context, request *RestartLogger
 	return &RestartLoggerResponse{}, nil
 }
 
+// followLogBuffer is the number of entries kept for a client of FollowLog that's slow to
+// receive them. Entries beyond it are dropped.
+const followLogBuffer = 256
+
+// FollowLog implements LoggerService.
+func (s *LoggerServer) FollowLog(request *FollowLogRequest, stream LoggerService_FollowLogServer) error {
+	logger, ok := s.V.GetFeature((*log.Instance)(nil)).(*log.Instance)
+	if !ok {
+		return errors.New("unable to get logger instance")
+	}
+
+	entries := make(chan *LogEntry, followLogBuffer)
+	var dropped atomic.Uint32
+	follower := func(msg clog.Message) {
+		entry := newLogEntry(request, msg, time.Now())
+		if entry == nil {
+			return
+		}
+		entry.Dropped = dropped.Swap(0)
+		select {
+		case entries <- entry:
+		default:
+			dropped.Add(entry.Dropped + 1)
+		}
+	}
+	logger.AddFollower(&follower)
+	defer logger.RemoveFollower(&follower)
+
+	for {
+		select {
+		case entry := <-entries:
+			if err := stream.Send(entry); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// DumpLog implements LoggerService.
+func (s *LoggerServer) DumpLog(ctx context.Context, request *DumpLogRequest) (*DumpLogResponse, error) {
+	logger, ok := s.V.GetFeature((*log.Instance)(nil)).(*log.Instance)
+	if !ok {
+		return nil, errors.New("unable to get logger instance")
+	}
+	kept := logger.MemoryEntries()
+	if kept == nil {
+		return nil, errors.New("no log is of the memory type")
+	}
+
+	response := new(DumpLogResponse)
+	for _, e := range kept {
+		if entry := newLogEntry(request, e.Message, e.Time); entry != nil {
+			response.Entries = append(response.Entries, entry)
+		}
+	}
+	if limit := int(request.Limit); limit > 0 && len(response.Entries) > limit {
+		response.Entries = response.Entries[len(response.Entries)-limit:]
+	}
+	return response, nil
+}
+
+// entryFilter is what a request filters entries by.
+type entryFilter interface {
+	GetLevel() clog.Severity
+	GetType() []LogType
+	GetFilter() string
+}
+
+// newLogEntry returns the entry of msg logged at t, or nil if the request filters it out.
+func newLogEntry(request entryFilter, msg clog.Message, t time.Time) *LogEntry {
+	inner := msg
+	if masked, ok := msg.(*log.MaskedMsgWrapper); ok {
+		inner = masked.Message
+	}
+	entry := new(LogEntry)
+	switch inner := inner.(type) {
+	case *clog.AccessMessage:
+		entry.Type = LogType_Access
+	case *clog.DNSLog:
+		entry.Type = LogType_DNS
+	case *clog.GeneralMessage:
+		entry.Type = LogType_Error
+		entry.Level = inner.Severity
+		if level := request.GetLevel(); level != clog.Severity_Unknown && entry.Level > level {
+			return nil
+		}
+	default:
+		return nil
+	}
+	if types := request.GetType(); len(types) > 0 {
+		wanted := false
+		for _, t := range types {
+			wanted = wanted || t == entry.Type
+		}
+		if !wanted {
+			return nil
+		}
+	}
+	entry.Message = msg.String()
+	if !strings.Contains(entry.Message, request.GetFilter()) {
+		return nil
+	}
+	entry.Time = t.UnixMilli()
+	return entry
+}
+
 func (s *LoggerServer) mustEmbedUnimplementedLoggerServiceServer() {}
 
 type service struct {
@@ -3,6 +3,7 @@ package command_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/xtls/xray-core/app/dispatcher"
 	"github.com/xtls/xray-core/app/log"
@@ -11,8 +12,10 @@ import (
 	_ "github.com/xtls/xray-core/app/proxyman/inbound"
 	_ "github.com/xtls/xray-core/app/proxyman/outbound"
 	"github.com/xtls/xray-core/common"
+	clog "github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/core"
+	"google.golang.org/grpc"
 )
 
 func TestLoggerRestart(t *testing.T) {
@@ -32,3 +35,114 @@ func TestLoggerRestart(t *testing.T) {
 	}
 	common.Must2(server.RestartLogger(context.Background(), &RestartLoggerRequest{}))
 }
+
+type logStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	entries chan *LogEntry
+}
+
+func (s *logStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *logStream) Send(entry *LogEntry) error {
+	s.entries <- entry
+	return nil
+}
+
+func TestFollowLog(t *testing.T) {
+	v, err := core.New(&core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&log.Config{}),
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+		},
+	})
+	common.Must(err)
+	common.Must(v.Start())
+	defer v.Close()
+
+	server := &LoggerServer{
+		V: v,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &logStream{ctx: ctx, entries: make(chan *LogEntry, 16)}
+	done := make(chan error)
+	go func() {
+		done <- server.FollowLog(&FollowLogRequest{
+			Level:  clog.Severity_Warning,
+			Filter: "followed",
+		}, stream)
+	}()
+
+	// Log until the follower is added.
+	probe := &clog.GeneralMessage{Severity: clog.Severity_Warning, Content: "probe followed"}
+	for received := false; !received; {
+		clog.Record(probe)
+		select {
+		case <-stream.entries:
+			received = true
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	clog.Record(&clog.GeneralMessage{Severity: clog.Severity_Info, Content: "too verbose followed"})
+	clog.Record(&clog.GeneralMessage{Severity: clog.Severity_Error, Content: "not matching"})
+	clog.Record(&clog.GeneralMessage{Severity: clog.Severity_Error, Content: "error followed"})
+
+	for {
+		var entry *LogEntry
+		select {
+		case entry = <-stream.entries:
+		case <-time.After(time.Second):
+			t.Fatal("no entry followed")
+		}
+		if entry.Message == probe.String() {
+			continue
+		}
+		if entry.Type != LogType_Error || entry.Level != clog.Severity_Error || entry.Message != "[Error] error followed" {
+			t.Error("unexpected entry: ", entry)
+		}
+		break
+	}
+
+	cancel()
+	common.Must(<-done)
+}
+
+func TestDumpLog(t *testing.T) {
+	v, err := core.New(&core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&log.Config{
+				ErrorLogType:  log.LogType_Memory,
+				ErrorLogLevel: clog.Severity_Info,
+				MemorySize:    3,
+			}),
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+		},
+	})
+	common.Must(err)
+	common.Must(v.Start())
+	defer v.Close()
+
+	for _, content := range []string{"first dumped", "second dumped", "third dumped", "fourth dumped"} {
+		clog.Record(&clog.GeneralMessage{Severity: clog.Severity_Warning, Content: content})
+	}
+	clog.Record(&clog.GeneralMessage{Severity: clog.Severity_Debug, Content: "too verbose dumped"})
+
+	server := &LoggerServer{
+		V: v,
+	}
+	response, err := server.DumpLog(context.Background(), &DumpLogRequest{Filter: "dumped", Limit: 2})
+	common.Must(err)
+	if len(response.Entries) != 2 || response.Entries[0].Message != "[Warning] third dumped" || response.Entries[1].Message != "[Warning] fourth dumped" {
+		t.Error("unexpected entries: ", response.Entries)
+	}
+	if response.Entries[0].Time == 0 || response.Entries[0].Type != LogType_Error {
+		t.Error("unexpected entry: ", response.Entries[0])
+	}
+}
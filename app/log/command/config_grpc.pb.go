@@ -20,6 +20,8 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	LoggerService_RestartLogger_FullMethodName = "/xray.app.log.command.LoggerService/RestartLogger"
+	LoggerService_FollowLog_FullMethodName     = "/xray.app.log.command.LoggerService/FollowLog"
+	LoggerService_DumpLog_FullMethodName       = "/xray.app.log.command.LoggerService/DumpLog"
 )
 
 // LoggerServiceClient is the client API for LoggerService service.
@@ -27,6 +29,11 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type LoggerServiceClient interface {
 	RestartLogger(ctx context.Context, in *RestartLoggerRequest, opts ...grpc.CallOption) (*RestartLoggerResponse, error)
+	// Streams the entries logged from now on, whether the logger writes them or
+	// not.
+	FollowLog(ctx context.Context, in *FollowLogRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogEntry], error)
+	// Returns the entries kept by the logs of the memory type.
+	DumpLog(ctx context.Context, in *DumpLogRequest, opts ...grpc.CallOption) (*DumpLogResponse, error)
 }
 
 type loggerServiceClient struct {
@@ -47,11 +54,45 @@ func (c *loggerServiceClient) RestartLogger(ctx context.Context, in *RestartLogg
 	return out, nil
 }
 
+func (c *loggerServiceClient) FollowLog(ctx context.Context, in *FollowLogRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogEntry], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &LoggerService_ServiceDesc.Streams[0], LoggerService_FollowLog_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[FollowLogRequest, LogEntry]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LoggerService_FollowLogClient = grpc.ServerStreamingClient[LogEntry]
+
+func (c *loggerServiceClient) DumpLog(ctx context.Context, in *DumpLogRequest, opts ...grpc.CallOption) (*DumpLogResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DumpLogResponse)
+	err := c.cc.Invoke(ctx, LoggerService_DumpLog_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // LoggerServiceServer is the server API for LoggerService service.
 // All implementations must embed UnimplementedLoggerServiceServer
 // for forward compatibility.
 type LoggerServiceServer interface {
 	RestartLogger(context.Context, *RestartLoggerRequest) (*RestartLoggerResponse, error)
+	// Streams the entries logged from now on, whether the logger writes them or
+	// not.
+	FollowLog(*FollowLogRequest, grpc.ServerStreamingServer[LogEntry]) error
+	// Returns the entries kept by the logs of the memory type.
+	DumpLog(context.Context, *DumpLogRequest) (*DumpLogResponse, error)
 	mustEmbedUnimplementedLoggerServiceServer()
 }
 
@@ -65,6 +106,12 @@ type UnimplementedLoggerServiceServer struct{}
 func (UnimplementedLoggerServiceServer) RestartLogger(context.Context, *RestartLoggerRequest) (*RestartLoggerResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RestartLogger not implemented")
 }
+func (UnimplementedLoggerServiceServer) FollowLog(*FollowLogRequest, grpc.ServerStreamingServer[LogEntry]) error {
+	return status.Errorf(codes.Unimplemented, "method FollowLog not implemented")
+}
+func (UnimplementedLoggerServiceServer) DumpLog(context.Context, *DumpLogRequest) (*DumpLogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DumpLog not implemented")
+}
 func (UnimplementedLoggerServiceServer) mustEmbedUnimplementedLoggerServiceServer() {}
 func (UnimplementedLoggerServiceServer) testEmbeddedByValue()                       {}
 
@@ -104,6 +151,35 @@ func _LoggerService_RestartLogger_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _LoggerService_FollowLog_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FollowLogRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LoggerServiceServer).FollowLog(m, &grpc.GenericServerStream[FollowLogRequest, LogEntry]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LoggerService_FollowLogServer = grpc.ServerStreamingServer[LogEntry]
+
+func _LoggerService_DumpLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DumpLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LoggerServiceServer).DumpLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LoggerService_DumpLog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LoggerServiceServer).DumpLog(ctx, req.(*DumpLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // LoggerService_ServiceDesc is the grpc.ServiceDesc for LoggerService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -115,7 +191,17 @@ var LoggerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RestartLogger",
 			Handler:    _LoggerService_RestartLogger_Handler,
 		},
+		{
+			MethodName: "DumpLog",
+			Handler:    _LoggerService_DumpLog_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "FollowLog",
+			Handler:       _LoggerService_FollowLog_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "app/log/command/config.proto",
 }
@@ -0,0 +1,78 @@
+package log
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/xtls/xray-core/common/log"
+)
+
+type hasCaller interface {
+	Caller() string
+}
+
+// moduleLevels finds the severity of the error log for the package a message comes from.
+type moduleLevels struct {
+	fallback log.Severity
+	modules  map[string]log.Severity
+	cache    sync.Map // caller -> log.Severity
+}
+
+func newModuleLevels(fallback log.Severity, modules map[string]log.Severity) *moduleLevels {
+	m := &moduleLevels{
+		fallback: fallback,
+		modules:  make(map[string]log.Severity, len(modules)),
+	}
+	for module, severity := range modules {
+		m.modules[strings.Trim(module, "/")] = severity
+	}
+	return m
+}
+
+// levelOf returns the severity for messages from caller. The module matching the deepest
+// package of caller wins, so "transport/internet/grpc" takes precedence over "transport".
+func (m *moduleLevels) levelOf(caller string) log.Severity {
+	if caller == "" {
+		return m.fallback
+	}
+	if v, found := m.cache.Load(caller); found {
+		return v.(log.Severity)
+	}
+
+	level := m.fallback
+	segments := strings.Split(caller, "/")
+	bestEnd, bestLen := -1, 0
+	for module, severity := range m.modules {
+		parts := strings.Split(module, "/")
+		for start := 0; start+len(parts) <= len(segments); start++ {
+			if !equalSegments(segments[start:start+len(parts)], parts) {
+				continue
+			}
+			end := start + len(parts)
+			if end > bestEnd || (end == bestEnd && len(parts) > bestLen) {
+				bestEnd, bestLen = end, len(parts)
+				level = severity
+			}
+		}
+	}
+	m.cache.Store(caller, level)
+	return level
+}
+
+func equalSegments(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// allows returns true if msg is to be written to the error log.
+func (m *moduleLevels) allows(msg *log.GeneralMessage) bool {
+	level := m.fallback
+	if c, ok := msg.Content.(hasCaller); ok {
+		level = m.levelOf(c.Caller())
+	}
+	return msg.Severity <= level
+}
@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
@@ -20,6 +21,10 @@ type Instance struct {
 	errorLogger  log.Handler
 	active       bool
 	dns          bool
+	levels       *moduleLevels
+	followers    map[*func(log.Message)]struct{}
+	// memory keeps the entries of the logs of the Memory type, across restarts of the logger.
+	memory *memoryLog
 }
 
 // New creates a new log.Instance based on the given config.
@@ -28,6 +33,10 @@ func New(ctx context.Context, config *Config) (*Instance, error) {
 		config: config,
 		active: false,
 		dns:    config.EnableDnsLog,
+		levels: newModuleLevels(config.ErrorLogLevel, config.ModuleLevels),
+	}
+	if config.AccessLogType == LogType_Memory || config.ErrorLogType == LogType_Memory {
+		g.memory = newMemoryLog(config.MemorySize)
 	}
 	log.RegisterHandler(g)
 
@@ -41,10 +50,21 @@ func New(ctx context.Context, config *Config) (*Instance, error) {
 	return g, nil
 }
 
+// handlerOptions returns the options of the handler writing to path.
+func (g *Instance) handlerOptions(path string) HandlerCreatorOptions {
+	return HandlerCreatorOptions{
+		Path:  path,
+		Plain: g.config.Format == LogFormat_JSON,
+		Rotation: log.FileOptions{
+			MaxSize:    int64(g.config.MaxSize) * 1024 * 1024,
+			MaxAge:     time.Duration(g.config.RotateInterval) * time.Second,
+			MaxBackups: int(g.config.MaxBackups),
+		},
+	}
+}
+
 func (g *Instance) initAccessLogger() error {
-	handler, err := createHandler(g.config.AccessLogType, HandlerCreatorOptions{
-		Path: g.config.AccessLogPath,
-	})
+	handler, err := createHandler(g.config.AccessLogType, g.handlerOptions(g.config.AccessLogPath))
 	if err != nil {
 		return err
 	}
@@ -53,9 +73,7 @@ func (g *Instance) initAccessLogger() error {
 }
 
 func (g *Instance) initErrorLogger() error {
-	handler, err := createHandler(g.config.ErrorLogType, HandlerCreatorOptions{
-		Path: g.config.ErrorLogPath,
-	})
+	handler, err := createHandler(g.config.ErrorLogType, g.handlerOptions(g.config.ErrorLogPath))
 	if err != nil {
 		return err
 	}
@@ -77,6 +95,8 @@ func (g *Instance) startInternal() error {
 	}
 
 	g.active = true
+	// Closed connections are only recorded in JSON, which has fields for their traffic.
+	log.RecordClosedAccesses(g.config.Format == LogFormat_JSON && g.config.AccessLogType != LogType_None)
 
 	if err := g.initAccessLogger(); err != nil {
 		return errors.New("failed to initialize access logger").Base(err).AtWarning()
@@ -109,24 +129,76 @@ func (g *Instance) Handle(msg log.Message) {
 		Msg = msg
 	}
 
+	for f := range g.followers {
+		(*f)(Msg)
+	}
+	// The memory log keeps messages as they are, to be formatted when read.
+	kept := Msg
+
+	if g.config.Format == LogFormat_JSON {
+		var mask func(string) string
+		if g.config.MaskAddress != "" {
+			mask = (&MaskedMsgWrapper{config: g.config}).mask
+		}
+		Msg = jsonMessage(log.FormatJSON(msg, time.Now(), mask))
+	}
+
 	switch msg := msg.(type) {
 	case *log.AccessMessage:
 		if g.accessLogger != nil {
 			g.accessLogger.Handle(Msg)
 		}
+		if g.config.AccessLogType == LogType_Memory {
+			g.memory.add(kept)
+		}
 	case *log.DNSLog:
 		if g.dns && g.accessLogger != nil {
 			g.accessLogger.Handle(Msg)
 		}
+		if g.dns && g.config.AccessLogType == LogType_Memory {
+			g.memory.add(kept)
+		}
 	case *log.GeneralMessage:
-		if g.errorLogger != nil && msg.Severity <= g.config.ErrorLogLevel {
+		if g.errorLogger != nil && g.levels.allows(msg) {
 			g.errorLogger.Handle(Msg)
 		}
+		if g.config.ErrorLogType == LogType_Memory && g.levels.allows(msg) {
+			g.memory.add(kept)
+		}
 	default:
 		// Swallow
 	}
 }
 
+// AddFollower makes f receive every message the instance handles, whether it's logged or not,
+// until it's removed. f is called with the instance locked, so it must neither block nor log.
+func (g *Instance) AddFollower(f *func(log.Message)) {
+	g.Lock()
+	defer g.Unlock()
+
+	if g.followers == nil {
+		g.followers = make(map[*func(log.Message)]struct{})
+	}
+	g.followers[f] = struct{}{}
+}
+
+// RemoveFollower stops f from receiving messages.
+func (g *Instance) RemoveFollower(f *func(log.Message)) {
+	g.Lock()
+	defer g.Unlock()
+
+	delete(g.followers, f)
+}
+
+// MemoryEntries returns the entries the logs of the Memory type kept, oldest first, or nil if
+// there are none of them.
+func (g *Instance) MemoryEntries() []MemoryEntry {
+	if g.memory == nil {
+		return nil
+	}
+	return g.memory.list()
+}
+
 // Close implements common.Closable.Close().
 func (g *Instance) Close() error {
 	errors.LogDebug(context.Background(), "Logger closing")
@@ -139,6 +211,7 @@ func (g *Instance) Close() error {
 	}
 
 	g.active = false
+	log.RecordClosedAccesses(false)
 
 	common.Close(g.accessLogger)
 	g.accessLogger = nil
@@ -156,8 +229,11 @@ type MaskedMsgWrapper struct {
 }
 
 func (m *MaskedMsgWrapper) String() string {
-	str := m.Message.String()
+	return m.mask(m.Message.String())
+}
 
+// mask hides the IP addresses in str as the config tells.
+func (m *MaskedMsgWrapper) mask(str string) string {
 	ipv4Regex := regexp.MustCompile(`(\d{1,3}\.){3}\d{1,3}`)
 	ipv6Regex := regexp.MustCompile(`((?:[\da-fA-F]{0,4}:[\da-fA-F]{0,4}){2,7})(?:[\/\\%](\d{1,3}))?`)
 
@@ -199,6 +275,13 @@ func (m *MaskedMsgWrapper) String() string {
 	return maskedMsg
 }
 
+// jsonMessage is a message formatted as a line of JSON.
+type jsonMessage string
+
+func (m jsonMessage) String() string {
+	return string(m)
+}
+
 func init() {
 	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
 		return New(ctx, config.(*Config))
@@ -10,6 +10,10 @@ import (
 
 type HandlerCreatorOptions struct {
 	Path string
+	// Plain writes lines as they are, without timestamps, for formats carrying their own.
+	Plain bool
+	// Rotation is when log files are rotated, and how many rotated files are kept.
+	Rotation log.FileOptions
 }
 
 type HandlerCreator func(LogType, HandlerCreatorOptions) (log.Handler, error)
@@ -43,11 +47,16 @@ func createHandler(logType LogType, options HandlerCreatorOptions) (log.Handler,
 
 func init() {
 	common.Must(RegisterHandlerCreator(LogType_Console, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
+		if options.Plain {
+			return log.NewLogger(log.CreatePlainStdoutLogWriter()), nil
+		}
 		return log.NewLogger(log.CreateStdoutLogWriter()), nil
 	}))
 
 	common.Must(RegisterHandlerCreator(LogType_File, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
-		creator, err := log.CreateFileLogWriter(options.Path)
+		fileOptions := options.Rotation
+		fileOptions.Plain = options.Plain
+		creator, err := log.CreateRotatingFileLogWriter(options.Path, fileOptions)
 		if err != nil {
 			return nil, err
 		}
@@ -57,4 +66,9 @@ func init() {
 	common.Must(RegisterHandlerCreator(LogType_None, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
 		return nil, nil
 	}))
+
+	// Entries of the Memory type are kept by the Instance.
+	common.Must(RegisterHandlerCreator(LogType_Memory, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
+		return nil, nil
+	}))
 }
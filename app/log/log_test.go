@@ -2,11 +2,13 @@ package log_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/xtls/xray-core/app/log"
 	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
 	clog "github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/testing/mocks"
 )
@@ -50,3 +52,43 @@ func TestCustomLogHandler(t *testing.T) {
 
 	common.Must(logger.Close())
 }
+
+func TestModuleLevels(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	var loggedValue []string
+
+	mockHandler := mocks.NewLogHandler(mockCtl)
+	mockHandler.EXPECT().Handle(gomock.Any()).AnyTimes().DoAndReturn(func(msg clog.Message) {
+		loggedValue = append(loggedValue, msg.String())
+	})
+
+	log.RegisterHandlerCreator(log.LogType_Console, func(lt log.LogType, options log.HandlerCreatorOptions) (clog.Handler, error) {
+		return mockHandler, nil
+	})
+
+	logger, err := log.New(context.Background(), &log.Config{
+		ErrorLogLevel: clog.Severity_Warning,
+		ErrorLogType:  log.LogType_Console,
+		AccessLogType: log.LogType_None,
+		ModuleLevels: map[string]clog.Severity{
+			"app":          clog.Severity_Error,
+			"app/log_test": clog.Severity_Debug,
+		},
+	})
+	common.Must(err)
+	common.Must(logger.Start())
+
+	errors.LogDebug(context.Background(), "module debug")
+	clog.Record(&clog.GeneralMessage{
+		Severity: clog.Severity_Debug,
+		Content:  "global debug",
+	})
+
+	if len(loggedValue) != 1 || !strings.HasSuffix(loggedValue[0], "module debug") {
+		t.Fatal("unexpected log messages: ", loggedValue)
+	}
+
+	common.Must(logger.Close())
+}
@@ -0,0 +1,59 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/log"
+)
+
+// defaultMemorySize is the number of entries the memory log keeps by default.
+const defaultMemorySize = 1000
+
+// MemoryEntry is an entry of the memory log.
+type MemoryEntry struct {
+	Message log.Message
+	Time    time.Time
+}
+
+// memoryLog keeps the last entries logged in a ring buffer, for the logs of the Memory type, so that
+// they can be read through the API without writing to disk.
+type memoryLog struct {
+	sync.Mutex
+	entries []MemoryEntry
+	// next is where the next entry is kept, overwriting the oldest one once full.
+	next int
+	full bool
+}
+
+func newMemoryLog(size uint32) *memoryLog {
+	if size == 0 {
+		size = defaultMemorySize
+	}
+	return &memoryLog{entries: make([]MemoryEntry, size)}
+}
+
+func (m *memoryLog) add(msg log.Message) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.entries[m.next] = MemoryEntry{Message: msg, Time: time.Now()}
+	m.next++
+	if m.next == len(m.entries) {
+		m.next = 0
+		m.full = true
+	}
+}
+
+// list returns the entries kept, oldest first.
+func (m *memoryLog) list() []MemoryEntry {
+	m.Lock()
+	defer m.Unlock()
+
+	if !m.full {
+		return append([]MemoryEntry(nil), m.entries[:m.next]...)
+	}
+	entries := make([]MemoryEntry, 0, len(m.entries))
+	entries = append(entries, m.entries[m.next:]...)
+	return append(entries, m.entries[:m.next]...)
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	gonet "net"
+	"net/http"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+)
+
+// accessControl gates the metrics, expvar and pprof endpoints by the address of the clients, the
+// inbound they come through by the tag, and HTTP basic authentication.
+type accessControl struct {
+	sources  []*net.IPNet
+	inbounds map[string]bool
+	username []byte
+	password []byte
+}
+
+func newAccessControl(config *Config) (*accessControl, error) {
+	a := &accessControl{
+		username: []byte(config.Username),
+		password: []byte(config.Password),
+	}
+	for _, s := range config.AllowSources {
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, errors.New("invalid source of metrics: ", s)
+			}
+			bits := 8 * len(ip)
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			a.sources = append(a.sources, &net.IPNet{IP: ip, Mask: gonet.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := gonet.ParseCIDR(s)
+		if err != nil {
+			return nil, errors.New("invalid source of metrics: ", s).Base(err)
+		}
+		a.sources = append(a.sources, ipNet)
+	}
+	if len(config.InboundTags) > 0 {
+		a.inbounds = make(map[string]bool, len(config.InboundTags))
+		for _, tag := range config.InboundTags {
+			a.inbounds[tag] = true
+		}
+	}
+	return a, nil
+}
+
+// allowSource tells whether clients from ip are allowed. Without sources set, all are.
+func (a *accessControl) allowSource(ip net.IP) bool {
+	if len(a.sources) == 0 {
+		return true
+	}
+	for _, s := range a.sources {
+		if ip != nil && s.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowInbound tells whether the connection of ctx, dispatched to the metrics by the tag, is
+// allowed by its inbound and its source.
+func (a *accessControl) allowInbound(ctx context.Context) bool {
+	in := session.InboundFromContext(ctx)
+	if a.inbounds != nil && (in == nil || !a.inbounds[in.Tag]) {
+		return false
+	}
+	if len(a.sources) == 0 {
+		return true
+	}
+	if in == nil || !in.Source.IsValid() || !in.Source.Address.Family().IsIP() {
+		return false
+	}
+	return a.allowSource(in.Source.Address.IP())
+}
+
+// authenticate rejects requests without the username and password, if set.
+func (a *accessControl) authenticate(next http.Handler) http.Handler {
+	if len(a.username) == 0 && len(a.password) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		if subtle.ConstantTimeCompare([]byte(username), a.username)&subtle.ConstantTimeCompare([]byte(password), a.password) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="xray metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sourceListener closes the connections of the clients not allowed as they're accepted.
+type sourceListener struct {
+	net.Listener
+	access *accessControl
+}
+
+// Accept implements net.Listener.
+func (l *sourceListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		// Unix sockets are guarded by the permissions of the file.
+		addr, ok := conn.RemoteAddr().(*net.TCPAddr)
+		if !ok || l.access.allowSource(addr.IP) {
+			return conn, nil
+		}
+		errors.LogInfo(context.Background(), "rejected metrics client ", conn.RemoteAddr())
+		conn.Close()
+	}
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+)
+
+func TestAccessControl(t *testing.T) {
+	a, err := newAccessControl(&Config{
+		AllowSources: []string{"127.0.0.1", "10.0.0.0/8", "::1"},
+		Username:     "admin",
+		Password:     "secret",
+		InboundTags:  []string{"tunnel"},
+	})
+	common.Must(err)
+
+	for ip, allowed := range map[string]bool{
+		"127.0.0.1":   true,
+		"10.1.2.3":    true,
+		"::1":         true,
+		"192.168.1.1": false,
+		"127.0.0.2":   false,
+	} {
+		if a.allowSource(net.ParseIP(ip)) != allowed {
+			t.Error("source ", ip, " allowed: ", !allowed)
+		}
+	}
+
+	inbound := func(tag, source string) context.Context {
+		return session.ContextWithInbound(context.Background(), &session.Inbound{
+			Tag:    tag,
+			Source: net.TCPDestination(net.ParseAddress(source), 1234),
+		})
+	}
+	if !a.allowInbound(inbound("tunnel", "10.0.0.1")) {
+		t.Error("client through the tunnel rejected")
+	}
+	if a.allowInbound(inbound("socks", "10.0.0.1")) {
+		t.Error("client through another inbound allowed")
+	}
+	if a.allowInbound(inbound("tunnel", "8.8.8.8")) {
+		t.Error("client from another source allowed")
+	}
+	if a.allowInbound(context.Background()) {
+		t.Error("client without inbound allowed")
+	}
+
+	handler := a.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, c := range []struct {
+		username, password string
+		status             int
+	}{
+		{"admin", "secret", http.StatusOK},
+		{"admin", "wrong", http.StatusUnauthorized},
+		{"", "", http.StatusUnauthorized},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+		if c.username != "" {
+			r.SetBasicAuth(c.username, c.password)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Error("status ", w.Code, " for ", c.username, ":", c.password)
+		}
+	}
+
+	if _, err := newAccessControl(&Config{AllowSources: []string{"example.com"}}); err == nil {
+		t.Error("expected an error for an invalid source")
+	}
+}
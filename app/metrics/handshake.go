@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"time"
+
+	feature_stats "github.com/xtls/xray-core/features/stats"
+)
+
+// stageTiming is the histogram of a handshake stage, as published in expvar.
+type stageTiming struct {
+	Count int64 `json:"count"`
+	// Sum of all durations, in milliseconds.
+	SumMs float64 `json:"sumMs"`
+	// Number of durations up to each bound, like "4ms", with "+Inf" for all.
+	Buckets map[string]int64 `json:"buckets"`
+}
+
+func newStageTiming(s feature_stats.HistogramSnapshot) *stageTiming {
+	t := &stageTiming{
+		Count:   s.Count,
+		SumMs:   float64(s.Sum) / float64(time.Millisecond),
+		Buckets: make(map[string]int64, len(s.Counts)),
+	}
+	var cumulative int64
+	for i, count := range s.Counts {
+		cumulative += count
+		if i < len(s.Bounds) {
+			t.Buckets[s.Bounds[i].String()] = cumulative
+		} else {
+			t.Buckets["+Inf"] = cumulative
+		}
+	}
+	return t
+}
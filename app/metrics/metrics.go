@@ -11,33 +11,50 @@ import (
 	"github.com/xtls/xray-core/app/stats"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/listen"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/signal/done"
 	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/features/extension"
+	"github.com/xtls/xray-core/features/inbound"
 	"github.com/xtls/xray-core/features/outbound"
 	feature_stats "github.com/xtls/xray-core/features/stats"
 )
 
 type MetricsHandler struct {
+	ihm          inbound.Manager
 	ohm          outbound.Manager
 	statsManager feature_stats.Manager
 	observatory  extension.Observatory
+	dns          dns.Client
 	tag          string
 	listen       string
 	tcpListener  net.Listener
+	access       *accessControl
 }
 
 // NewMetricsHandler creates a new MetricsHandler based on the given config.
 func NewMetricsHandler(ctx context.Context, config *Config) (*MetricsHandler, error) {
+	access, err := newAccessControl(config)
+	if err != nil {
+		return nil, err
+	}
 	c := &MetricsHandler{
 		tag:    config.Tag,
 		listen: config.Listen,
+		access: access,
 	}
-	common.Must(core.RequireFeatures(ctx, func(om outbound.Manager, sm feature_stats.Manager) {
+	common.Must(core.RequireFeatures(ctx, func(im inbound.Manager, om outbound.Manager, sm feature_stats.Manager, d dns.Client) {
 		c.statsManager = sm
+		c.ihm = im
 		c.ohm = om
+		c.dns = d
 	}))
+	prometheusHandler.Store(c)
+	registerPrometheus.Do(func() {
+		http.HandleFunc(prometheusPath, servePrometheus)
+	})
 	expvar.Publish("stats", expvar.Func(func() interface{} {
 		manager, ok := c.statsManager.(*stats.Manager)
 		if !ok {
@@ -62,6 +79,27 @@ func NewMetricsHandler(ctx context.Context, config *Config) (*MetricsHandler, er
 		})
 		return resp
 	}))
+	expvar.Publish("handshake", expvar.Func(func() interface{} {
+		manager, ok := c.statsManager.(*stats.Manager)
+		if !ok {
+			return nil
+		}
+		// outbound>>>tag>>>handshake>>>stage
+		resp := map[string]map[string]*stageTiming{}
+		manager.VisitHistograms(func(name string, histogram feature_stats.Histogram) bool {
+			nameSplit := strings.Split(name, ">>>")
+			if len(nameSplit) != 4 || nameSplit[0] != "outbound" || nameSplit[2] != "handshake" {
+				return true
+			}
+			tag, stage := nameSplit[1], nameSplit[3]
+			if resp[tag] == nil {
+				resp[tag] = map[string]*stageTiming{}
+			}
+			resp[tag][stage] = newStageTiming(histogram.Snapshot())
+			return true
+		})
+		return resp
+	}))
 	expvar.Publish("observatory", expvar.Func(func() interface{} {
 		if c.observatory == nil {
 			common.Must(core.RequireFeatures(ctx, func(observatory extension.Observatory) error {
@@ -82,6 +120,17 @@ func NewMetricsHandler(ctx context.Context, config *Config) (*MetricsHandler, er
 		}
 		return resp
 	}))
+	expvar.Publish("failedInbounds", expvar.Func(func() interface{} {
+		reporter, ok := c.ihm.(inbound.FailureReporter)
+		if !ok {
+			return nil
+		}
+		resp := map[string]string{}
+		for _, f := range reporter.GetFailedHandlers() {
+			resp[f.Tag] = f.Err.Error()
+		}
+		return resp
+	}))
 	return c, nil
 }
 
@@ -90,18 +139,19 @@ func (p *MetricsHandler) Type() interface{} {
 }
 
 func (p *MetricsHandler) Start() error {
+	handler := p.access.authenticate(http.DefaultServeMux)
 
 	// direct listen a port if listen is set
 	if p.listen != "" {
-		TCPlistener, err := net.Listen("tcp", p.listen)
+		TCPlistener, err := listen.TCP(context.Background(), p.listen, p.dns)
 		if err != nil {
-			return err
+			return errors.Diagnose(errors.CodeAPIPortBusy, err)
 		}
-		p.tcpListener = TCPlistener
+		p.tcpListener = &sourceListener{Listener: TCPlistener, access: p.access}
 		errors.LogInfo(context.Background(), "Metrics server listening on ", p.listen)
 
 		go func() {
-			if err := http.Serve(TCPlistener, http.DefaultServeMux); err != nil {
+			if err := http.Serve(p.tcpListener, handler); err != nil {
 				errors.LogErrorInner(context.Background(), err, "failed to start metrics server")
 			}
 		}()
@@ -113,7 +163,7 @@ func (p *MetricsHandler) Start() error {
 	}
 
 	go func() {
-		if err := http.Serve(listener, http.DefaultServeMux); err != nil {
+		if err := http.Serve(listener, handler); err != nil {
 			errors.LogErrorInner(context.Background(), err, "failed to start metrics server")
 		}
 	}()
@@ -125,6 +175,7 @@ func (p *MetricsHandler) Start() error {
 	return p.ohm.AddHandler(context.Background(), &Outbound{
 		tag:      p.tag,
 		listener: listener,
+		control:  p.access,
 	})
 }
 
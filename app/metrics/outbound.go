@@ -67,10 +67,19 @@ type Outbound struct {
 	listener *OutboundListener
 	access   sync.RWMutex
 	closed   bool
+	// control is the access control of the connections dispatched, none if nil.
+	control *accessControl
 }
 
 // Dispatch implements outbound.Handler.
 func (co *Outbound) Dispatch(ctx context.Context, link *transport.Link) {
+	if co.control != nil && !co.control.allowInbound(ctx) {
+		errors.LogInfo(ctx, "rejected metrics client through outbound [", co.tag, "]")
+		common.Interrupt(link.Reader)
+		common.Interrupt(link.Writer)
+		return
+	}
+
 	co.access.RLock()
 
 	if co.closed {
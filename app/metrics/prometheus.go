@@ -0,0 +1,242 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/outbound"
+	feature_stats "github.com/xtls/xray-core/features/stats"
+)
+
+// prometheusPath is the path the metrics are served at in the Prometheus text format.
+const prometheusPath = "/metrics"
+
+var (
+	// prometheusHandler is the MetricsHandler serving prometheusPath, the latest created. The
+	// path can only be registered to http.DefaultServeMux once.
+	prometheusHandler  atomic.Pointer[MetricsHandler]
+	registerPrometheus sync.Once
+)
+
+func servePrometheus(w http.ResponseWriter, r *http.Request) {
+	p := prometheusHandler.Load()
+	if p == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	b := bufio.NewWriter(w)
+	p.writePrometheus(b)
+	b.Flush()
+}
+
+// prometheusSample is a sample of a metric family, with its labels formatted.
+type prometheusSample struct {
+	suffix string
+	labels string
+	// series is labels without the bucket bound, ordering samples.
+	series string
+	value  string
+}
+
+// prometheusMetrics are the samples of metric families by name. Families are written in order of
+// name, and samples in order of labels, the buckets of a histogram in the order added.
+type prometheusMetrics struct {
+	types   map[string]string
+	helps   map[string]string
+	samples map[string][]prometheusSample
+}
+
+func newPrometheusMetrics() *prometheusMetrics {
+	return &prometheusMetrics{
+		types:   map[string]string{},
+		helps:   map[string]string{},
+		samples: map[string][]prometheusSample{},
+	}
+}
+
+// declare sets the type and help text of the metric family name.
+func (m *prometheusMetrics) declare(name, typ, help string) {
+	m.types[name] = typ
+	m.helps[name] = help
+}
+
+// add adds a sample of the metric family name, like "_bucket" with suffix, with the given label
+// pairs.
+func (m *prometheusMetrics) add(name, suffix, value string, labels ...string) {
+	series := labels
+	if len(labels) >= 2 && labels[len(labels)-2] == "le" {
+		series = labels[:len(labels)-2]
+	}
+	m.samples[name] = append(m.samples[name], prometheusSample{
+		suffix: suffix,
+		labels: formatLabels(labels),
+		series: formatLabels(series),
+		value:  value,
+	})
+}
+
+func formatLabels(labels []string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(labels[i+1]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+func (m *prometheusMetrics) write(w *bufio.Writer) {
+	names := make([]string, 0, len(m.types))
+	for name := range m.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, m.helps[name], name, m.types[name])
+		samples := m.samples[name]
+		sort.SliceStable(samples, func(i, j int) bool {
+			return samples[i].series < samples[j].series
+		})
+		for _, s := range samples {
+			if s.labels == "" {
+				fmt.Fprintf(w, "%s%s %s\n", name, s.suffix, s.value)
+			} else {
+				fmt.Fprintf(w, "%s%s{%s} %s\n", name, s.suffix, s.labels, s.value)
+			}
+		}
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatInt(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// writePrometheus writes the traffic counters, active connections, handshake latencies and DNS
+// cache statistics in the Prometheus text format.
+func (p *MetricsHandler) writePrometheus(w *bufio.Writer) {
+	m := newPrometheusMetrics()
+	if manager, ok := p.statsManager.(*stats.Manager); ok {
+		p.collectTraffic(m, manager)
+		p.collectHandshakes(m, manager)
+	}
+	p.collectConnections(m)
+	p.collectDNSCache(m)
+	m.write(w)
+}
+
+// collectTraffic adds the counters named like "inbound>>>tag>>>traffic>>>uplink", and those of
+// the connections of routing rules.
+func (p *MetricsHandler) collectTraffic(m *prometheusMetrics, manager *stats.Manager) {
+	labels := map[string]string{"inbound": "tag", "outbound": "tag", "user": "user", "rule": "rule"}
+	helps := map[string]string{
+		"inbound":  "Bytes transferred through each inbound.",
+		"outbound": "Bytes transferred through each outbound.",
+		"user":     "Bytes transferred by each user.",
+		"rule":     "Bytes transferred by the connections each routing rule routed.",
+	}
+	manager.VisitCounters(func(name string, counter feature_stats.Counter) bool {
+		nameSplit := strings.Split(name, ">>>")
+		if len(nameSplit) == 3 && nameSplit[0] == "rule" && nameSplit[2] == "connections" {
+			const metric = "xray_rule_connections_total"
+			m.declare(metric, "counter", "Connections each routing rule routed.")
+			m.add(metric, "", formatInt(counter.Value()), "rule", nameSplit[1])
+			return true
+		}
+		if len(nameSplit) != 4 || nameSplit[2] != "traffic" {
+			return true
+		}
+		label, found := labels[nameSplit[0]]
+		if !found {
+			return true
+		}
+		metric := "xray_" + nameSplit[0] + "_traffic_bytes_total"
+		m.declare(metric, "counter", helps[nameSplit[0]])
+		m.add(metric, "", formatInt(counter.Value()), label, nameSplit[1], "direction", nameSplit[3])
+		return true
+	})
+}
+
+// collectHandshakes adds the histograms named like "outbound>>>tag>>>handshake>>>stage".
+func (p *MetricsHandler) collectHandshakes(m *prometheusMetrics, manager *stats.Manager) {
+	const metric = "xray_outbound_handshake_seconds"
+	manager.VisitHistograms(func(name string, histogram feature_stats.Histogram) bool {
+		nameSplit := strings.Split(name, ">>>")
+		if len(nameSplit) != 4 || nameSplit[0] != "outbound" || nameSplit[2] != "handshake" {
+			return true
+		}
+		tag, stage := nameSplit[1], nameSplit[3]
+		s := histogram.Snapshot()
+		m.declare(metric, "histogram", "Duration of each stage of outbound handshakes.")
+		var cumulative int64
+		for i, count := range s.Counts {
+			cumulative += count
+			le := "+Inf"
+			if i < len(s.Bounds) {
+				le = formatFloat(s.Bounds[i].Seconds())
+			}
+			m.add(metric, "_bucket", formatInt(cumulative), "tag", tag, "stage", stage, "le", le)
+		}
+		m.add(metric, "_sum", formatFloat(s.Sum.Seconds()), "tag", tag, "stage", stage)
+		m.add(metric, "_count", formatInt(s.Count), "tag", tag, "stage", stage)
+		return true
+	})
+}
+
+func (p *MetricsHandler) collectConnections(m *prometheusMetrics) {
+	if reporter, ok := p.ihm.(inbound.ConnectionReporter); ok {
+		const metric = "xray_inbound_active_connections"
+		m.declare(metric, "gauge", "Connections in progress through each inbound, UDP sessions included.")
+		for _, c := range reporter.GetActiveConnections() {
+			m.add(metric, "", strconv.Itoa(c.Connections), "tag", c.Tag)
+		}
+	}
+	if reporter, ok := p.ohm.(outbound.ConnectionReporter); ok {
+		const metric = "xray_outbound_active_connections"
+		m.declare(metric, "gauge", "Connections in progress through each outbound.")
+		for _, c := range reporter.GetActiveConnections() {
+			m.add(metric, "", strconv.Itoa(c.Connections), "tag", c.Tag)
+		}
+	}
+}
+
+func (p *MetricsHandler) collectDNSCache(m *prometheusMetrics) {
+	reporter, ok := p.dns.(dns.CacheStatsReporter)
+	if !ok {
+		return
+	}
+	const hits, misses = "xray_dns_cache_hits_total", "xray_dns_cache_misses_total"
+	m.declare(hits, "counter", "DNS queries answered from the cache of each name server.")
+	m.declare(misses, "counter", "DNS queries missing the cache of each name server.")
+	for _, s := range reporter.GetCacheStats() {
+		labels := []string{"server", s.Server}
+		if s.Partition != "" {
+			labels = append(labels, "partition", s.Partition)
+		}
+		m.add(hits, "", formatInt(s.Hits), labels...)
+		m.add(misses, "", formatInt(s.Misses), labels...)
+	}
+}
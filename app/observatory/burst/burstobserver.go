@@ -25,11 +25,22 @@ type Observer struct {
 
 	finished *done.Instance
 
-	ohm outbound.Manager
+	ohm        outbound.Manager
+	congestion *observatory.CongestionTracker
 }
 
 func (o *Observer) GetObservation(ctx context.Context) (proto.Message, error) {
-	return &observatory.ObservationResult{Status: o.createResult()}, nil
+	return &observatory.ObservationResult{Status: observatory.ApplyMaintenance(o.ohm, o.congestion.Apply(o.createResult()))}, nil
+}
+
+// ObservesCongestion implements extension.CongestionObserver.
+func (o *Observer) ObservesCongestion(outbound string) bool {
+	return o.congestion.ObservesCongestion(outbound)
+}
+
+// ObserveCongestion implements extension.CongestionObserver.
+func (o *Observer) ObserveCongestion(outbound string, kind string, value float64) {
+	o.congestion.ObserveCongestion(outbound, kind, value)
 }
 
 func (o *Observer) createResult() []*observatory.OutboundStatus {
@@ -99,10 +110,11 @@ func New(ctx context.Context, config *Config) (*Observer, error) {
 	}
 	hp := NewHealthPing(ctx, dispatcher, config.PingConfig)
 	return &Observer{
-		config: config,
-		ctx:    ctx,
-		ohm:    outboundManager,
-		hp:     hp,
+		config:     config,
+		ctx:        ctx,
+		ohm:        outboundManager,
+		hp:         hp,
+		congestion: observatory.NewCongestionTracker(config.Congestion, config.SubjectSelector),
 	}, nil
 }
 
@@ -9,6 +9,7 @@ import (
 
 	"github.com/xtls/xray-core/common/dice"
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/power"
 	"github.com/xtls/xray-core/features/routing"
 )
 
@@ -28,6 +29,7 @@ type HealthPing struct {
 	access      sync.Mutex
 	ticker      *time.Ticker
 	tickerClose chan struct{}
+	unsubscribe func()
 
 	Settings *HealthPingSettings
 	Results  map[string]*HealthPingRTTS
@@ -83,6 +85,20 @@ func (h *HealthPing) StartScheduler(selector func() ([]string, error)) {
 	tickerClose := make(chan struct{})
 	h.ticker = ticker
 	h.tickerClose = tickerClose
+	// Outbounds are checked again right after the system wakes up.
+	h.unsubscribe = power.Subscribe(func(e power.Event) {
+		if e.Type != power.Wake {
+			return
+		}
+		go func() {
+			tags, err := selector()
+			if err != nil {
+				errors.LogWarning(h.ctx, "error select outbounds for health check on wake: ", err)
+				return
+			}
+			h.Check(tags)
+		}()
+	})
 	go func() {
 		tags, err := selector()
 		if err != nil {
@@ -120,6 +136,8 @@ func (h *HealthPing) StopScheduler() {
 	}
 	h.ticker.Stop()
 	h.ticker = nil
+	h.unsubscribe()
+	h.unsubscribe = nil
 	close(h.tickerClose)
 	h.tickerClose = nil
 }
@@ -163,6 +181,14 @@ func (h *HealthPing) doCheck(tags []string, duration time.Duration, rounds int)
 				delay = time.Duration(dice.RollInt63n(int64(duration)))
 			}
 			time.AfterFunc(delay, func() {
+				// No pings are sent while the system is going to sleep.
+				if power.Asleep() {
+					ch <- &rtt{
+						handler: handler,
+						value:   0,
+					}
+					return
+				}
 				errors.LogDebug(h.ctx, "checking ", handler)
 				delay, err := client.MeasureDelay()
 				if err == nil {
@@ -172,7 +198,7 @@ func (h *HealthPing) doCheck(tags []string, duration time.Duration, rounds int)
 					}
 					return
 				}
-				if !h.checkConnectivity() {
+				if power.Asleep() || !h.checkConnectivity() {
 					errors.LogWarning(h.ctx, "network is down")
 					ch <- &rtt{
 						handler: handler,
@@ -0,0 +1,125 @@
+package observatory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/features/extension"
+	"google.golang.org/protobuf/proto"
+)
+
+const defaultCongestionHold = time.Minute
+
+// CongestionTracker keeps the outbounds whose connections reported congestion signals past the
+// thresholds of a CongestionConfig, and marks them as not alive in observations until the
+// signals are older than the hold time.
+type CongestionTracker struct {
+	config   *CongestionConfig
+	selector []string
+	hold     time.Duration
+
+	access    sync.Mutex
+	congested map[string]congestion
+}
+
+type congestion struct {
+	reason string
+	until  time.Time
+}
+
+// NewCongestionTracker creates a CongestionTracker for the outbounds matching selector. config
+// may be nil, in which case no signal is observed.
+func NewCongestionTracker(config *CongestionConfig, selector []string) *CongestionTracker {
+	t := &CongestionTracker{
+		config:    config,
+		selector:  selector,
+		hold:      defaultCongestionHold,
+		congested: make(map[string]congestion),
+	}
+	if config.GetHold() > 0 {
+		t.hold = time.Duration(config.Hold)
+	}
+	return t
+}
+
+// ObservesCongestion implements extension.CongestionObserver.
+func (t *CongestionTracker) ObservesCongestion(outbound string) bool {
+	if t == nil || t.config == nil {
+		return false
+	}
+	for _, s := range t.selector {
+		if strings.HasPrefix(outbound, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ObserveCongestion implements extension.CongestionObserver.
+func (t *CongestionTracker) ObserveCongestion(outbound string, kind string, value float64) {
+	if !t.ObservesCongestion(outbound) {
+		return
+	}
+	var reason string
+	switch kind {
+	case extension.CongestionRetransmission:
+		if limit := t.config.MaxRetransmission; limit > 0 && value > float64(limit) {
+			reason = fmt.Sprintf("retransmission %.3f above %.3f", value, limit)
+		}
+	case extension.CongestionRTTInflation:
+		if limit := t.config.MaxRttInflation; limit > 0 && value > float64(limit) {
+			reason = fmt.Sprintf("rtt inflation %.2f above %.2f", value, limit)
+		}
+	case extension.CongestionThroughput:
+		if limit := t.config.MinThroughput; limit > 0 && value < float64(limit) {
+			reason = fmt.Sprintf("throughput %.2f below %.2f", value, limit)
+		}
+	}
+	if reason == "" {
+		return
+	}
+
+	now := time.Now()
+	t.access.Lock()
+	previous, found := t.congested[outbound]
+	t.congested[outbound] = congestion{reason: reason, until: now.Add(t.hold)}
+	t.access.Unlock()
+	if !found || now.After(previous.until) {
+		errors.LogWarning(context.Background(), "outbound ", outbound, " is congested: ", reason)
+	}
+}
+
+// Apply returns status with the congested outbounds marked as not alive. Changed statuses are
+// copies, so the records of observers are left intact.
+func (t *CongestionTracker) Apply(status []*OutboundStatus) []*OutboundStatus {
+	if t == nil || t.config == nil {
+		return status
+	}
+	now := time.Now()
+	t.access.Lock()
+	defer t.access.Unlock()
+	for tag, c := range t.congested {
+		if now.After(c.until) {
+			delete(t.congested, tag)
+		}
+	}
+	if len(t.congested) == 0 {
+		return status
+	}
+
+	result := make([]*OutboundStatus, 0, len(status))
+	for _, s := range status {
+		if c, found := t.congested[s.OutboundTag]; found && s.Alive {
+			s = proto.Clone(s).(*OutboundStatus)
+			s.Alive = false
+			s.Delay = 99999999
+			s.LastErrorReason = "congestion: " + c.reason
+		}
+		result = append(result, s)
+	}
+	return result
+}
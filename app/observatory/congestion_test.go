@@ -0,0 +1,74 @@
+package observatory_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xtls/xray-core/app/observatory"
+	"github.com/xtls/xray-core/features/extension"
+)
+
+func TestCongestionTracker(t *testing.T) {
+	tracker := NewCongestionTracker(&CongestionConfig{
+		MaxRetransmission: 0.05,
+		MaxRttInflation:   3,
+		MinThroughput:     0.2,
+		Hold:              int64(100 * time.Millisecond),
+	}, []string{"proxy"})
+	status := []*OutboundStatus{
+		{OutboundTag: "proxy-a", Alive: true, Delay: 100},
+		{OutboundTag: "proxy-b", Alive: true, Delay: 200},
+		{OutboundTag: "direct", Alive: true, Delay: 10},
+	}
+
+	if !tracker.ObservesCongestion("proxy-a") {
+		t.Error("expect proxy-a to be observed")
+	}
+	if tracker.ObservesCongestion("direct") {
+		t.Error("expect direct not to be observed")
+	}
+
+	tracker.ObserveCongestion("proxy-a", extension.CongestionRetransmission, 0.01)
+	tracker.ObserveCongestion("proxy-a", extension.CongestionRTTInflation, 2)
+	tracker.ObserveCongestion("proxy-a", extension.CongestionThroughput, 0.5)
+	tracker.ObserveCongestion("direct", extension.CongestionRetransmission, 0.5)
+	for _, s := range tracker.Apply(status) {
+		if !s.Alive {
+			t.Error("expect ", s.OutboundTag, " to be alive")
+		}
+	}
+
+	tracker.ObserveCongestion("proxy-b", extension.CongestionThroughput, 0.1)
+	result := tracker.Apply(status)
+	if len(result) != 3 {
+		t.Fatal("expect 3 outbounds, but got ", len(result))
+	}
+	if result[1].Alive || result[1].LastErrorReason == "" {
+		t.Error("expect proxy-b to be congested, but got ", result[1])
+	}
+	if !status[1].Alive {
+		t.Error("expect the status of the observer to be left intact")
+	}
+	if !result[0].Alive || !result[2].Alive {
+		t.Error("expect other outbounds to be alive")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	for _, s := range tracker.Apply(status) {
+		if !s.Alive {
+			t.Error("expect ", s.OutboundTag, " to be alive after the hold")
+		}
+	}
+}
+
+func TestCongestionTrackerDisabled(t *testing.T) {
+	tracker := NewCongestionTracker(nil, []string{"proxy"})
+	if tracker.ObservesCongestion("proxy") {
+		t.Error("expect no outbound to be observed")
+	}
+	tracker.ObserveCongestion("proxy", extension.CongestionRetransmission, 1)
+	status := []*OutboundStatus{{OutboundTag: "proxy", Alive: true}}
+	if result := tracker.Apply(status); !result[0].Alive {
+		t.Error("expect proxy to be alive")
+	}
+}
@@ -0,0 +1,130 @@
+package observatory
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+const (
+	defaultEgressURL      = "https://api.ipify.org"
+	defaultEgressInterval = 10 * time.Minute
+)
+
+// egressChecker checks that outbounds leave from the expected networks, so that providers
+// silently rerouting traffic through other countries or networks are noticed.
+type egressChecker struct {
+	url      string
+	interval time.Duration
+	expected []*net.IPNet
+
+	access sync.Mutex
+	last   map[string]*egressResult
+}
+
+type egressResult struct {
+	time   time.Time
+	ip     string
+	reason string // why the egress IP is unexpected, if it is
+}
+
+func newEgressChecker(config *EgressCheck) (*egressChecker, error) {
+	c := &egressChecker{
+		url:      config.Url,
+		interval: time.Duration(config.Interval),
+		last:     make(map[string]*egressResult),
+	}
+	if c.url == "" {
+		c.url = defaultEgressURL
+	}
+	if c.interval <= 0 {
+		c.interval = defaultEgressInterval
+	}
+	for _, n := range config.Expected {
+		bits := len(n.Ip) * 8
+		if (bits != 32 && bits != 128) || int(n.Prefix) > bits {
+			return nil, errors.New("invalid expected network of egress check")
+		}
+		c.expected = append(c.expected, &net.IPNet{IP: n.Ip, Mask: net.CIDRMask(int(n.Prefix), bits)})
+	}
+	if len(c.expected) == 0 {
+		return nil, errors.New("no expected network for egress check")
+	}
+	return c, nil
+}
+
+func (c *egressChecker) expects(ip net.IP) bool {
+	for _, n := range c.expected {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns the egress IP of the outbound, and why it's unexpected if it is. The IP is
+// fetched through client once the interval passed since the last time, and the last result
+// stands if it can't be fetched.
+func (c *egressChecker) check(ctx context.Context, outbound string, client *http.Client) (string, string) {
+	c.access.Lock()
+	last := c.last[outbound]
+	c.access.Unlock()
+	if last != nil && time.Since(last.time) < c.interval {
+		return last.ip, last.reason
+	}
+
+	ip, err := fetchEgressIP(client, c.url)
+	if err != nil {
+		errors.LogInfoInner(ctx, err, "failed to fetch the egress IP of ", outbound)
+		if last != nil {
+			return last.ip, last.reason
+		}
+		return "", ""
+	}
+	result := &egressResult{
+		time: time.Now(),
+		ip:   ip.String(),
+	}
+	if !c.expects(ip) {
+		result.reason = "egress IP " + result.ip + " is not in the expected networks"
+	}
+	switch {
+	case result.reason != "" && (last == nil || last.reason == ""):
+		errors.LogWarning(ctx, "the outbound ", outbound, " is unhealthy: ", result.reason)
+	case result.reason == "" && last != nil && last.reason != "":
+		errors.LogWarning(ctx, "the outbound ", outbound, " is back to the expected egress IP ", result.ip)
+	case last != nil && last.ip != result.ip:
+		errors.LogInfo(ctx, "the egress IP of the outbound ", outbound, " changed from ", last.ip, " to ", result.ip)
+	}
+
+	c.access.Lock()
+	c.last[outbound] = result
+	c.access.Unlock()
+	return result.ip, result.reason
+}
+
+func fetchEgressIP(client *http.Client, url string) (net.IP, error) {
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected status ", response.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, 64))
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		return nil, errors.New("not an IP: ", string(body))
+	}
+	return ip, nil
+}
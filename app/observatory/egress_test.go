@@ -0,0 +1,79 @@
+package observatory
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEgressCheck(t *testing.T) {
+	var egress atomic.Value
+	egress.Store("203.0.113.5\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := egress.Load().(string)
+		if ip == "" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(ip))
+	}))
+	defer server.Close()
+
+	c, err := newEgressChecker(&EgressCheck{
+		Url:      server.URL,
+		Interval: int64(time.Nanosecond),
+		Expected: []*EgressCheck_Network{{Ip: net.ParseIP("203.0.113.0").To4(), Prefix: 24}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(want string, unexpected bool) {
+		t.Helper()
+		ip, reason := c.check(context.Background(), "proxy", server.Client())
+		if ip != want || (reason != "") != unexpected {
+			t.Errorf("check() = %q, %q, want %q, unexpected %v", ip, reason, want, unexpected)
+		}
+	}
+
+	check("203.0.113.5", false)
+	egress.Store("198.51.100.7")
+	check("198.51.100.7", true)
+	// The last result stands while the egress IP can't be fetched.
+	egress.Store("")
+	check("198.51.100.7", true)
+	egress.Store("203.0.113.9")
+	check("203.0.113.9", false)
+
+	c.interval = time.Hour
+	egress.Store("198.51.100.7")
+	check("203.0.113.9", false)
+}
+
+func TestNewEgressChecker(t *testing.T) {
+	c, err := newEgressChecker(&EgressCheck{
+		Expected: []*EgressCheck_Network{{Ip: net.ParseIP("2001:db8::"), Prefix: 32}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.url != defaultEgressURL || c.interval != defaultEgressInterval {
+		t.Error("unexpected defaults: ", c.url, " ", c.interval)
+	}
+	if !c.expects(net.ParseIP("2001:db8:1::1")) || c.expects(net.ParseIP("2001:db9::1")) {
+		t.Error("unexpected matching of the expected network")
+	}
+
+	for _, config := range []*EgressCheck{
+		{},
+		{Expected: []*EgressCheck_Network{{Ip: net.ParseIP("203.0.113.0").To4(), Prefix: 33}}},
+		{Expected: []*EgressCheck_Network{{Ip: []byte{1, 2, 3}, Prefix: 8}}},
+	} {
+		if _, err := newEgressChecker(config); err == nil {
+			t.Error("expected an error for ", config)
+		}
+	}
+}
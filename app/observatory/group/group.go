@@ -0,0 +1,139 @@
+// Package group runs several observatories, each probing its own outbounds with its own
+// settings, so that balancers can be bound to the observatory fitting their outbounds.
+package group
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/app/observatory"
+	"github.com/xtls/xray-core/app/observatory/burst"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/features/extension"
+	"google.golang.org/protobuf/proto"
+)
+
+// Group is an extension.ObservatoryGroup. As an extension.Observatory, it reports the
+// observations of all its members, the first member observing an outbound winning.
+type Group struct {
+	members []extension.Observatory
+	tagged  map[string]extension.Observatory
+}
+
+// New creates a Group with the observatories in config.
+func New(ctx context.Context, config *Config) (*Group, error) {
+	g := &Group{
+		tagged: make(map[string]extension.Observatory),
+	}
+	for _, c := range config.Observatory {
+		o, err := observatory.New(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		if err := g.add(c.Tag, o); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range config.BurstObservatory {
+		o, err := burst.New(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		if err := g.add(c.Tag, o); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func (g *Group) add(tag string, o extension.Observatory) error {
+	if tag != "" {
+		if _, found := g.tagged[tag]; found {
+			return errors.New("duplicate observatory tag ", tag)
+		}
+		g.tagged[tag] = o
+	}
+	g.members = append(g.members, o)
+	return nil
+}
+
+// Type implements common.HasType.
+func (g *Group) Type() interface{} {
+	return extension.ObservatoryType()
+}
+
+// Start implements common.Runnable.
+func (g *Group) Start() error {
+	for _, o := range g.members {
+		if err := o.Start(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements common.Closable.
+func (g *Group) Close() error {
+	var errs []error
+	for _, o := range g.members {
+		if err := o.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Combine(errs...)
+}
+
+// GetObservatory implements extension.ObservatoryGroup.
+func (g *Group) GetObservatory(tag string) extension.Observatory {
+	return g.tagged[tag]
+}
+
+// GetObservation implements extension.Observatory.
+func (g *Group) GetObservation(ctx context.Context) (proto.Message, error) {
+	result := &observatory.ObservationResult{}
+	seen := make(map[string]bool)
+	for _, o := range g.members {
+		m, err := o.GetObservation(ctx)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := m.(*observatory.ObservationResult)
+		if !ok {
+			continue
+		}
+		for _, s := range r.Status {
+			if seen[s.OutboundTag] {
+				continue
+			}
+			seen[s.OutboundTag] = true
+			result.Status = append(result.Status, s)
+		}
+	}
+	return result, nil
+}
+
+// ObservesCongestion implements extension.CongestionObserver.
+func (g *Group) ObservesCongestion(outbound string) bool {
+	for _, o := range g.members {
+		if c, ok := o.(extension.CongestionObserver); ok && c.ObservesCongestion(outbound) {
+			return true
+		}
+	}
+	return false
+}
+
+// ObserveCongestion implements extension.CongestionObserver. The signal is fed to every member
+// observing the outbound.
+func (g *Group) ObserveCongestion(outbound string, kind string, value float64) {
+	for _, o := range g.members {
+		if c, ok := o.(extension.CongestionObserver); ok && c.ObservesCongestion(outbound) {
+			c.ObserveCongestion(outbound, kind, value)
+		}
+	}
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		return New(ctx, config.(*Config))
+	}))
+}
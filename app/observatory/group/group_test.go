@@ -0,0 +1,61 @@
+package group
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/app/observatory"
+	"github.com/xtls/xray-core/features/extension"
+	"google.golang.org/protobuf/proto"
+)
+
+type fakeObservatory struct {
+	extension.Observatory
+	status []*observatory.OutboundStatus
+}
+
+func (o *fakeObservatory) GetObservation(ctx context.Context) (proto.Message, error) {
+	return &observatory.ObservationResult{Status: o.status}, nil
+}
+
+func TestGroup(t *testing.T) {
+	fast := &fakeObservatory{status: []*observatory.OutboundStatus{
+		{OutboundTag: "a", Alive: true, Delay: 10},
+		{OutboundTag: "b", Alive: true, Delay: 20},
+	}}
+	bulk := &fakeObservatory{status: []*observatory.OutboundStatus{
+		{OutboundTag: "b", Alive: false},
+		{OutboundTag: "c", Alive: true, Delay: 300},
+	}}
+	g := &Group{tagged: make(map[string]extension.Observatory)}
+	if err := g.add("fast", fast); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.add("bulk", bulk); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.add("bulk", bulk); err == nil {
+		t.Error("expect error on duplicate tag")
+	}
+
+	if o := g.GetObservatory("bulk"); o != bulk {
+		t.Error("expect bulk observatory, but got ", o)
+	}
+	if o := g.GetObservatory("none"); o != nil {
+		t.Error("expect no observatory, but got ", o)
+	}
+
+	m, err := g.GetObservation(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := m.(*observatory.ObservationResult).Status
+	if len(status) != 3 {
+		t.Fatal("expect 3 outbounds, but got ", len(status))
+	}
+	for _, s := range status {
+		if s.OutboundTag == "b" && !s.Alive {
+			t.Error("expect status of b from the first observatory")
+		}
+	}
+}
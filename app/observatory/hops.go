@@ -0,0 +1,82 @@
+package observatory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+// hopTimer times the hops of a chained outbound in a probe, by when the transport of each of
+// them is up: the last of its connect and TLS stages is over. The handshakes of the proxy
+// protocols finish with the first data through the whole chain, so they don't tell the hops
+// apart. Hops over mux record no stages, and are counted into the hop after them.
+type hopTimer struct {
+	start time.Time
+
+	access sync.Mutex
+	ready  map[string]time.Duration
+}
+
+func newHopTimer() *hopTimer {
+	return &hopTimer{start: time.Now(), ready: make(map[string]time.Duration)}
+}
+
+// record implements stats.HopRecorder.
+func (t *hopTimer) record(tag string, stage stats.Stage) {
+	if stage != stats.StageConnect && stage != stats.StageTLS {
+		return
+	}
+	d := time.Since(t.start)
+	t.access.Lock()
+	defer t.access.Unlock()
+	if d > t.ready[tag] {
+		t.ready[tag] = d
+	}
+}
+
+// delays returns the latency each of hops adds, in milliseconds: how long after the hop before
+// it its transport is up.
+func (t *hopTimer) delays(hops []string) []int64 {
+	t.access.Lock()
+	defer t.access.Unlock()
+	return hopDelays(hops, t.ready)
+}
+
+func hopDelays(hops []string, ready map[string]time.Duration) []int64 {
+	delays := make([]int64, len(hops))
+	var last time.Duration
+	for i, tag := range hops {
+		r, ok := ready[tag]
+		if !ok || r < last {
+			continue
+		}
+		delays[i] = (r - last).Milliseconds()
+		last = r
+	}
+	return delays
+}
+
+// chainOf returns the hops of the outbound with tag, the nearest first, or nil if it isn't
+// chained to another one by proxySettings or dialerProxy.
+func chainOf(ohm outbound.Manager, tag string) []string {
+	var hops []string
+	seen := make(map[string]bool)
+	for tag != "" && !seen[tag] {
+		seen[tag] = true
+		hops = append(hops, tag)
+		h, ok := ohm.GetHandler(tag).(outbound.ChainedHandler)
+		if !ok {
+			break
+		}
+		tag = h.NextHop()
+	}
+	if len(hops) < 2 {
+		return nil
+	}
+	for i, j := 0, len(hops)-1; i < j; i, j = i+1, j-1 {
+		hops[i], hops[j] = hops[j], hops[i]
+	}
+	return hops
+}
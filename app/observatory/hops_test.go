@@ -0,0 +1,19 @@
+package observatory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHopDelays(t *testing.T) {
+	hops := []string{"first", "mux", "last"}
+	ready := map[string]time.Duration{
+		"first": 40 * time.Millisecond,
+		"last":  130 * time.Millisecond,
+	}
+	if r := cmp.Diff(hopDelays(hops, ready), []int64{40, 0, 90}); r != "" {
+		t.Error(r)
+	}
+}
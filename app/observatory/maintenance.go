@@ -0,0 +1,46 @@
+package observatory
+
+import (
+	"github.com/xtls/xray-core/features/outbound"
+	"google.golang.org/protobuf/proto"
+)
+
+// ApplyMaintenance returns status with the outbounds in maintenance marked as not alive. Such
+// outbounds which are not observed are listed as well. Changed statuses are copies, so the
+// records of observers are left intact.
+func ApplyMaintenance(ohm outbound.Manager, status []*OutboundStatus) []*OutboundStatus {
+	mm, ok := ohm.(outbound.MaintenanceManager)
+	if !ok {
+		return status
+	}
+	tags := mm.GetMaintenance()
+	if len(tags) == 0 {
+		return status
+	}
+	down := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		down[tag] = true
+	}
+
+	result := make([]*OutboundStatus, 0, len(status)+len(tags))
+	for _, s := range status {
+		if down[s.OutboundTag] {
+			s = proto.Clone(s).(*OutboundStatus)
+			s.Alive = false
+			s.Maintenance = true
+			s.LastErrorReason = "maintenance"
+			delete(down, s.OutboundTag)
+		}
+		result = append(result, s)
+	}
+	for _, tag := range tags {
+		if down[tag] {
+			result = append(result, &OutboundStatus{
+				OutboundTag:     tag,
+				Maintenance:     true,
+				LastErrorReason: "maintenance",
+			})
+		}
+	}
+	return result
+}
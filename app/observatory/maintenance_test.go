@@ -0,0 +1,54 @@
+package observatory_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xtls/xray-core/app/observatory"
+	"github.com/xtls/xray-core/features/outbound"
+)
+
+// maintenanceManager has the outbounds tagged down in maintenance.
+type maintenanceManager struct {
+	outbound.Manager
+	down []string
+}
+
+func (m *maintenanceManager) SetMaintenance(tag string, maintenance bool) error { return nil }
+func (m *maintenanceManager) InMaintenance(tag string) bool                     { return false }
+func (m *maintenanceManager) GetMaintenance() []string                          { return m.down }
+func (m *maintenanceManager) Suspend(tag string, duration time.Duration)        {}
+
+func TestApplyMaintenance(t *testing.T) {
+	status := []*OutboundStatus{
+		{OutboundTag: "proxy-a", Alive: true, Delay: 100},
+		{OutboundTag: "proxy-b", Alive: true, Delay: 200},
+	}
+	result := ApplyMaintenance(&maintenanceManager{down: []string{"proxy-a", "proxy-c"}}, status)
+
+	got := make(map[string]*OutboundStatus)
+	for _, s := range result {
+		got[s.OutboundTag] = s
+	}
+	if len(result) != 3 {
+		t.Fatal("unexpected statuses: ", result)
+	}
+	for _, tag := range []string{"proxy-a", "proxy-c"} {
+		if s := got[tag]; s.Alive || !s.Maintenance || s.LastErrorReason != "maintenance" {
+			t.Error("expect ", tag, " to be down for maintenance, got ", s)
+		}
+	}
+	if s := got["proxy-b"]; !s.Alive || s.Maintenance || s.Delay != 200 {
+		t.Error("expect proxy-b to be left alive, got ", s)
+	}
+	if !status[0].Alive || status[0].Maintenance {
+		t.Error("expect the observed status to be left intact, got ", status[0])
+	}
+}
+
+func TestApplyMaintenanceNone(t *testing.T) {
+	status := []*OutboundStatus{{OutboundTag: "proxy-a", Alive: true}}
+	if result := ApplyMaintenance(&maintenanceManager{}, status); len(result) != 1 || result[0] != status[0] {
+		t.Error("expect the statuses unchanged without maintenance, got ", result)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
 	v2net "github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/power"
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/signal/done"
 	"github.com/xtls/xray-core/common/task"
@@ -19,6 +20,7 @@ import (
 	"github.com/xtls/xray-core/features/extension"
 	"github.com/xtls/xray-core/features/outbound"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/transport/internet/tagged"
 	"google.golang.org/protobuf/proto"
 )
@@ -31,13 +33,28 @@ type Observer struct {
 	status     []*OutboundStatus
 
 	finished *done.Instance
+	// woken is signaled when the system wakes up, to probe right away.
+	woken       chan struct{}
+	unsubscribe func()
 
 	ohm        outbound.Manager
 	dispatcher routing.Dispatcher
+	egress     *egressChecker
+	congestion *CongestionTracker
 }
 
 func (o *Observer) GetObservation(ctx context.Context) (proto.Message, error) {
-	return &ObservationResult{Status: o.status}, nil
+	return &ObservationResult{Status: ApplyMaintenance(o.ohm, o.congestion.Apply(o.status))}, nil
+}
+
+// ObservesCongestion implements extension.CongestionObserver.
+func (o *Observer) ObservesCongestion(outbound string) bool {
+	return o.congestion.ObservesCongestion(outbound)
+}
+
+// ObserveCongestion implements extension.CongestionObserver.
+func (o *Observer) ObserveCongestion(outbound string, kind string, value float64) {
+	o.congestion.ObserveCongestion(outbound, kind, value)
 }
 
 func (o *Observer) Type() interface{} {
@@ -47,12 +64,17 @@ func (o *Observer) Type() interface{} {
 func (o *Observer) Start() error {
 	if o.config != nil && len(o.config.SubjectSelector) != 0 {
 		o.finished = done.New()
+		o.woken = make(chan struct{}, 1)
+		o.unsubscribe = power.Subscribe(o.onPower)
 		go o.background()
 	}
 	return nil
 }
 
 func (o *Observer) Close() error {
+	if o.unsubscribe != nil {
+		o.unsubscribe()
+	}
 	if o.finished != nil {
 		return o.finished.Close()
 	}
@@ -80,11 +102,11 @@ func (o *Observer) background() {
 			sort.Strings(outbounds)
 			for _, v := range outbounds {
 				result := o.probe(v)
-				o.updateStatusForResult(v, &result)
+				o.updateStatusForResult(v, result)
 				if o.finished.Done() {
 					return
 				}
-				time.Sleep(sleepTime)
+				o.wait(sleepTime)
 			}
 			continue
 		}
@@ -94,7 +116,7 @@ func (o *Observer) background() {
 		for _, v := range outbounds {
 			go func(v string) {
 				result := o.probe(v)
-				o.updateStatusForResult(v, &result)
+				o.updateStatusForResult(v, result)
 				ch <- struct{}{}
 			}(v)
 		}
@@ -106,7 +128,38 @@ func (o *Observer) background() {
 				return
 			}
 		}
-		time.Sleep(sleepTime)
+		o.wait(sleepTime)
+	}
+}
+
+func (o *Observer) onPower(e power.Event) {
+	if e.Type != power.Wake {
+		return
+	}
+	select {
+	case o.woken <- struct{}{}:
+	default:
+	}
+}
+
+// wait waits for d, or until the system wakes up, so that outbounds are probed right away. No
+// probes are sent while the system is going to sleep.
+func (o *Observer) wait(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	expired := timer.C
+	for {
+		select {
+		case <-expired:
+			if !power.Asleep() {
+				return
+			}
+			expired = nil
+		case <-o.woken:
+			return
+		case <-o.finished.Wait():
+			return
+		}
 	}
 }
 
@@ -117,8 +170,15 @@ func (o *Observer) updateStatus(outbounds []string) {
 	_ = outbounds
 }
 
-func (o *Observer) probe(outbound string) ProbeResult {
+func (o *Observer) probe(outbound string) *ProbeResult {
 	errorCollectorForRequest := newErrorCollector()
+	var hops []string
+	var timer *hopTimer
+	if o.config.ProbeHops {
+		if hops = chainOf(o.ohm, outbound); hops != nil {
+			timer = newHopTimer()
+		}
+	}
 
 	httpTransport := http.Transport{
 		Proxy: func(*http.Request) (*url.URL, error) {
@@ -133,6 +193,9 @@ func (o *Observer) probe(outbound string) ProbeResult {
 					return errors.New("cannot understand address").Base(err)
 				}
 				trackedCtx := session.TrackedConnectionError(o.ctx, errorCollectorForRequest)
+				if timer != nil {
+					trackedCtx = stats.ContextWithHopRecorder(trackedCtx, timer.record)
+				}
 				conn, err := tagged.Dialer(trackedCtx, o.dispatcher, dest, outbound)
 				if err != nil {
 					return errors.New("cannot dial remote address ", dest).Base(err)
@@ -162,7 +225,15 @@ func (o *Observer) probe(outbound string) ProbeResult {
 		if o.config.ProbeUrl != "" {
 			probeURL = o.config.ProbeUrl
 		}
-		response, err := httpClient.Get(probeURL)
+		method := http.MethodGet
+		if o.config.ProbeMethod != "" {
+			method = o.config.ProbeMethod
+		}
+		request, err := http.NewRequest(method, probeURL, nil)
+		if err != nil {
+			return errors.New("invalid probe request").Base(err)
+		}
+		response, err := httpClient.Do(request)
 		if err != nil {
 			return errors.New("outbound failed to relay connection").Base(err)
 		}
@@ -174,15 +245,32 @@ func (o *Observer) probe(outbound string) ProbeResult {
 		return nil
 	})
 	if err != nil {
-		var errorMessage = "the outbound " + outbound + " is dead: GET request failed:" + err.Error() + "with outbound handler report underlying connection failed"
+		var errorMessage = "the outbound " + outbound + " is dead: probe request failed:" + err.Error() + "with outbound handler report underlying connection failed"
 		errors.LogInfoInner(o.ctx, errorCollectorForRequest.UnderlyingError(), errorMessage)
-		return ProbeResult{Alive: false, LastErrorReason: errorMessage}
+		return &ProbeResult{Alive: false, LastErrorReason: errorMessage}
 	}
 	errors.LogInfo(o.ctx, "the outbound ", outbound, " is alive:", GETTime.Seconds())
-	return ProbeResult{Alive: true, Delay: GETTime.Milliseconds()}
+	result := &ProbeResult{Alive: true, Delay: GETTime.Milliseconds()}
+	if timer != nil {
+		result.HopTags = hops
+		result.HopDelays = timer.delays(hops)
+	}
+	if o.egress != nil {
+		ip, reason := o.egress.check(o.ctx, outbound, httpClient)
+		result.EgressIp = ip
+		if reason != "" {
+			result.Alive = false
+			result.LastErrorReason = reason
+		}
+	}
+	return result
 }
 
 func (o *Observer) updateStatusForResult(outbound string, result *ProbeResult) {
+	// Probes failing as the system goes to sleep don't mark outbounds dead.
+	if power.Asleep() {
+		return
+	}
 	o.statusLock.Lock()
 	defer o.statusLock.Unlock()
 	var status *OutboundStatus
@@ -196,8 +284,13 @@ func (o *Observer) updateStatusForResult(outbound string, result *ProbeResult) {
 	status.LastTryTime = time.Now().Unix()
 	status.OutboundTag = outbound
 	status.Alive = result.Alive
+	if result.EgressIp != "" {
+		status.EgressIp = result.EgressIp
+	}
 	if result.Alive {
 		status.Delay = result.Delay
+		status.HopTags = result.HopTags
+		status.HopDelays = result.HopDelays
 		status.LastSeenTime = status.LastTryTime
 		status.LastErrorReason = ""
 	} else {
@@ -225,12 +318,19 @@ func New(ctx context.Context, config *Config) (*Observer, error) {
 	if err != nil {
 		return nil, errors.New("Cannot get depended features").Base(err)
 	}
-	return &Observer{
+	o := &Observer{
 		config:     config,
 		ctx:        ctx,
 		ohm:        outboundManager,
 		dispatcher: dispatcher,
-	}, nil
+		congestion: NewCongestionTracker(config.Congestion, config.SubjectSelector),
+	}
+	if config.EgressCheck != nil {
+		if o.egress, err = newEgressChecker(config.EgressCheck); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
 }
 
 func init() {
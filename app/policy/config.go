@@ -23,6 +23,8 @@ func defaultPolicy() *Policy {
 			ConnectionIdle: &Second{Value: uint32(p.Timeouts.ConnectionIdle / time.Second)},
 			UplinkOnly:     &Second{Value: uint32(p.Timeouts.UplinkOnly / time.Second)},
 			DownlinkOnly:   &Second{Value: uint32(p.Timeouts.DownlinkOnly / time.Second)},
+			Dial:           &Second{Value: uint32(p.Timeouts.Dial / time.Second)},
+			Resolve:        &Second{Value: uint32(p.Timeouts.Resolve / time.Second)},
 		},
 		Buffer: &Policy_Buffer{
 			Connection: p.Buffer.PerConnection,
@@ -43,6 +45,12 @@ func (p *Policy_Timeout) overrideWith(another *Policy_Timeout) {
 	if another.DownlinkOnly != nil {
 		p.DownlinkOnly = &Second{Value: another.DownlinkOnly.Value}
 	}
+	if another.Dial != nil {
+		p.Dial = &Second{Value: another.Dial.Value}
+	}
+	if another.Resolve != nil {
+		p.Resolve = &Second{Value: another.Resolve.Value}
+	}
 }
 
 func (p *Policy) overrideWith(another *Policy) {
@@ -56,6 +64,7 @@ func (p *Policy) overrideWith(another *Policy) {
 	if another.Buffer != nil {
 		p.Buffer = &Policy_Buffer{
 			Connection: another.Buffer.Connection,
+			Auto:       another.Buffer.Auto,
 		}
 	}
 }
@@ -69,6 +78,12 @@ func (p *Policy) ToCorePolicy() policy.Session {
 		cp.Timeouts.Handshake = p.Timeout.Handshake.Duration()
 		cp.Timeouts.DownlinkOnly = p.Timeout.DownlinkOnly.Duration()
 		cp.Timeouts.UplinkOnly = p.Timeout.UplinkOnly.Duration()
+		if p.Timeout.Dial != nil {
+			cp.Timeouts.Dial = p.Timeout.Dial.Duration()
+		}
+		if p.Timeout.Resolve != nil {
+			cp.Timeouts.Resolve = p.Timeout.Resolve.Duration()
+		}
 	}
 	if p.Stats != nil {
 		cp.Stats.UserUplink = p.Stats.UserUplink
@@ -77,6 +92,7 @@ func (p *Policy) ToCorePolicy() policy.Session {
 	}
 	if p.Buffer != nil {
 		cp.Buffer.PerConnection = p.Buffer.Connection
+		cp.Buffer.Auto = p.Buffer.Auto
 	}
 	return cp
 }
@@ -85,10 +101,15 @@ func (p *Policy) ToCorePolicy() policy.Session {
 func (p *SystemPolicy) ToCorePolicy() policy.System {
 	return policy.System{
 		Stats: policy.SystemStats{
-			InboundUplink:    p.Stats.InboundUplink,
-			InboundDownlink:  p.Stats.InboundDownlink,
-			OutboundUplink:   p.Stats.OutboundUplink,
-			OutboundDownlink: p.Stats.OutboundDownlink,
+			InboundUplink:     p.Stats.InboundUplink,
+			InboundDownlink:   p.Stats.InboundDownlink,
+			OutboundUplink:    p.Stats.OutboundUplink,
+			OutboundDownlink:  p.Stats.OutboundDownlink,
+			OutboundHandshake: p.Stats.OutboundHandshake,
+			DomainUplink:      p.Stats.DomainUplink,
+			DomainDownlink:    p.Stats.DomainDownlink,
+			DomainLimit:       int(p.Stats.DomainLimit),
+			Rule:              p.Stats.Rule,
 		},
 	}
 }
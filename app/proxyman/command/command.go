@@ -2,9 +2,11 @@ package command
 
 import (
 	"context"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/inbound"
@@ -139,6 +141,105 @@ func (s *handlerServer) GetInboundUsersCount(ctx context.Context, request *GetIn
 	return &GetInboundUsersCountResponse{Count: um.GetUsersCount(ctx)}, nil
 }
 
+func (s *handlerServer) ListFailedInbounds(ctx context.Context, request *ListFailedInboundsRequest) (*ListFailedInboundsResponse, error) {
+	reporter, ok := s.ihm.(inbound.FailureReporter)
+	if !ok {
+		return nil, errors.New("inbound manager doesn't report failures")
+	}
+	response := &ListFailedInboundsResponse{}
+	for _, f := range reporter.GetFailedHandlers() {
+		response.Inbounds = append(response.Inbounds, &FailedInbound{
+			Tag:   f.Tag,
+			Error: f.Err.Error(),
+		})
+	}
+	return response, nil
+}
+
+func (s *handlerServer) ListUDPSessions(ctx context.Context, request *ListUDPSessionsRequest) (*ListUDPSessionsResponse, error) {
+	table, ok := s.ihm.(inbound.UDPSessionTable)
+	if !ok {
+		return nil, errors.New("inbound manager doesn't track UDP sessions")
+	}
+	response := &ListUDPSessionsResponse{}
+	for _, session := range table.GetUDPSessions(request.Tag) {
+		entry := &UDPSession{
+			Tag:      session.Tag,
+			Source:   session.Source.NetAddr(),
+			Uplink:   session.Uplink,
+			Downlink: session.Downlink,
+			Ttl:      int64(session.TTL / time.Second),
+		}
+		if session.Destination.IsValid() {
+			entry.Destination = session.Destination.NetAddr()
+		}
+		response.Sessions = append(response.Sessions, entry)
+	}
+	return response, nil
+}
+
+func (s *handlerServer) FlushUDPSessions(ctx context.Context, request *FlushUDPSessionsRequest) (*FlushUDPSessionsResponse, error) {
+	table, ok := s.ihm.(inbound.UDPSessionTable)
+	if !ok {
+		return nil, errors.New("inbound manager doesn't track UDP sessions")
+	}
+	var source net.Address
+	if request.Source != "" {
+		source = net.ParseAddress(request.Source)
+	}
+	count := table.FlushUDPSessions(request.Tag, source)
+	return &FlushUDPSessionsResponse{Count: uint32(count)}, nil
+}
+
+func (s *handlerServer) ListIdleConnections(ctx context.Context, request *ListIdleConnectionsRequest) (*ListIdleConnectionsResponse, error) {
+	lister, ok := s.ihm.(inbound.IdleConnectionLister)
+	if !ok {
+		return nil, errors.New("inbound manager doesn't track idle connections")
+	}
+	response := &ListIdleConnectionsResponse{}
+	for _, c := range lister.GetIdleConnections(request.Tag, time.Duration(request.MinIdle)*time.Second) {
+		entry := &IdleConnection{
+			Tag:     c.Tag,
+			Source:  c.Source.NetAddr(),
+			User:    c.User,
+			Age:     int64(c.Age / time.Second),
+			Idle:    int64(c.Idle / time.Second),
+			Limit:   int64(c.Limit / time.Second),
+			Session: uint32(c.Session),
+		}
+		if c.Destination.IsValid() {
+			entry.Destination = c.Destination.NetAddr()
+		}
+		response.Connections = append(response.Connections, entry)
+	}
+	return response, nil
+}
+
+func (s *handlerServer) ListFeatures(ctx context.Context, request *ListFeaturesRequest) (*ListFeaturesResponse, error) {
+	response := &ListFeaturesResponse{Compiled: CompiledFeatures()}
+	if config := s.s.Config(); config != nil {
+		response.Configured = configuredFeatures(config)
+	}
+	return response, nil
+}
+
+func (s *handlerServer) ListInboundAddresses(ctx context.Context, request *ListInboundAddressesRequest) (*ListInboundAddressesResponse, error) {
+	reporter, ok := s.ihm.(inbound.AddressReporter)
+	if !ok {
+		return nil, errors.New("inbound manager doesn't report addresses")
+	}
+	response := &ListInboundAddressesResponse{}
+	for _, a := range reporter.GetListenAddresses(request.Tag) {
+		response.Addresses = append(response.Addresses, &InboundAddress{
+			Tag:     a.Tag,
+			Network: a.Address.Network.SystemString(),
+			Address: a.Address.Address.String(),
+			Port:    uint32(a.Address.Port),
+		})
+	}
+	return response, nil
+}
+
 func (s *handlerServer) AddOutbound(ctx context.Context, request *AddOutboundRequest) (*AddOutboundResponse, error) {
 	if err := core.AddOutboundHandler(s.s, request.Outbound); err != nil {
 		return nil, err
@@ -147,6 +248,13 @@ func (s *handlerServer) AddOutbound(ctx context.Context, request *AddOutboundReq
 }
 
 func (s *handlerServer) RemoveOutbound(ctx context.Context, request *RemoveOutboundRequest) (*RemoveOutboundResponse, error) {
+	if request.DrainTimeout > 0 {
+		dm, ok := s.ohm.(outbound.DrainManager)
+		if !ok {
+			return nil, errors.New("outbound manager doesn't support draining")
+		}
+		return &RemoveOutboundResponse{}, dm.DrainHandler(ctx, request.Tag, time.Duration(request.DrainTimeout)*time.Second)
+	}
 	return &RemoveOutboundResponse{}, s.ohm.RemoveHandler(ctx, request.Tag)
 }
 
@@ -164,6 +272,71 @@ func (s *handlerServer) AlterOutbound(ctx context.Context, request *AlterOutboun
 	return &AlterOutboundResponse{}, operation.ApplyOutbound(ctx, handler)
 }
 
+func (s *handlerServer) SetOutboundMaintenance(ctx context.Context, request *SetOutboundMaintenanceRequest) (*SetOutboundMaintenanceResponse, error) {
+	mm, ok := s.ohm.(outbound.MaintenanceManager)
+	if !ok {
+		return nil, errors.New("outbound manager doesn't support maintenance")
+	}
+	return &SetOutboundMaintenanceResponse{}, mm.SetMaintenance(request.Tag, request.Maintenance)
+}
+
+func (s *handlerServer) ListOutboundMaintenance(ctx context.Context, request *ListOutboundMaintenanceRequest) (*ListOutboundMaintenanceResponse, error) {
+	mm, ok := s.ohm.(outbound.MaintenanceManager)
+	if !ok {
+		return nil, errors.New("outbound manager doesn't support maintenance")
+	}
+	return &ListOutboundMaintenanceResponse{Tags: mm.GetMaintenance()}, nil
+}
+
+func (s *handlerServer) GetOutboundChain(ctx context.Context, request *GetOutboundChainRequest) (*GetOutboundChainResponse, error) {
+	if s.ohm.GetHandler(request.Tag) == nil {
+		return nil, errors.New("outbound [", request.Tag, "] not found")
+	}
+	response := &GetOutboundChainResponse{}
+	seen := make(map[string]bool)
+	for tag := request.Tag; tag != "" && !seen[tag]; {
+		seen[tag] = true
+		hop := &OutboundHop{Tag: tag}
+		response.Hops = append(response.Hops, hop)
+		handler, ok := s.ohm.GetHandler(tag).(outbound.ChainedHandler)
+		if !ok {
+			hop.Missing = s.ohm.GetHandler(tag) == nil
+			break
+		}
+		status := handler.HopStatus()
+		hop.Successes = status.Successes
+		hop.Failures = status.Failures
+		hop.LastError = status.LastError
+		hop.LastErrorTime = unixTime(status.LastErrorTime)
+		hop.LastSuccessTime = unixTime(status.LastSuccessTime)
+		tag = handler.NextHop()
+	}
+	return response, nil
+}
+
+func (s *handlerServer) ListDrainingOutbounds(ctx context.Context, request *ListDrainingOutboundsRequest) (*ListDrainingOutboundsResponse, error) {
+	dm, ok := s.ohm.(outbound.DrainManager)
+	if !ok {
+		return nil, errors.New("outbound manager doesn't support draining")
+	}
+	response := &ListDrainingOutboundsResponse{}
+	for _, d := range dm.GetDrainingHandlers() {
+		response.Outbounds = append(response.Outbounds, &DrainingOutbound{
+			Tag:         d.Tag,
+			Connections: uint32(d.Connections),
+			Deadline:    unixTime(d.Deadline),
+		})
+	}
+	return response, nil
+}
+
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
 func (s *handlerServer) mustEmbedUnimplementedHandlerServiceServer() {}
 
 type service struct {
@@ -19,14 +19,24 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	HandlerService_AddInbound_FullMethodName           = "/xray.app.proxyman.command.HandlerService/AddInbound"
-	HandlerService_RemoveInbound_FullMethodName        = "/xray.app.proxyman.command.HandlerService/RemoveInbound"
-	HandlerService_AlterInbound_FullMethodName         = "/xray.app.proxyman.command.HandlerService/AlterInbound"
-	HandlerService_GetInboundUsers_FullMethodName      = "/xray.app.proxyman.command.HandlerService/GetInboundUsers"
-	HandlerService_GetInboundUsersCount_FullMethodName = "/xray.app.proxyman.command.HandlerService/GetInboundUsersCount"
-	HandlerService_AddOutbound_FullMethodName          = "/xray.app.proxyman.command.HandlerService/AddOutbound"
-	HandlerService_RemoveOutbound_FullMethodName       = "/xray.app.proxyman.command.HandlerService/RemoveOutbound"
-	HandlerService_AlterOutbound_FullMethodName        = "/xray.app.proxyman.command.HandlerService/AlterOutbound"
+	HandlerService_AddInbound_FullMethodName              = "/xray.app.proxyman.command.HandlerService/AddInbound"
+	HandlerService_RemoveInbound_FullMethodName           = "/xray.app.proxyman.command.HandlerService/RemoveInbound"
+	HandlerService_AlterInbound_FullMethodName            = "/xray.app.proxyman.command.HandlerService/AlterInbound"
+	HandlerService_GetInboundUsers_FullMethodName         = "/xray.app.proxyman.command.HandlerService/GetInboundUsers"
+	HandlerService_GetInboundUsersCount_FullMethodName    = "/xray.app.proxyman.command.HandlerService/GetInboundUsersCount"
+	HandlerService_ListFailedInbounds_FullMethodName      = "/xray.app.proxyman.command.HandlerService/ListFailedInbounds"
+	HandlerService_ListUDPSessions_FullMethodName         = "/xray.app.proxyman.command.HandlerService/ListUDPSessions"
+	HandlerService_FlushUDPSessions_FullMethodName        = "/xray.app.proxyman.command.HandlerService/FlushUDPSessions"
+	HandlerService_ListInboundAddresses_FullMethodName    = "/xray.app.proxyman.command.HandlerService/ListInboundAddresses"
+	HandlerService_AddOutbound_FullMethodName             = "/xray.app.proxyman.command.HandlerService/AddOutbound"
+	HandlerService_RemoveOutbound_FullMethodName          = "/xray.app.proxyman.command.HandlerService/RemoveOutbound"
+	HandlerService_AlterOutbound_FullMethodName           = "/xray.app.proxyman.command.HandlerService/AlterOutbound"
+	HandlerService_SetOutboundMaintenance_FullMethodName  = "/xray.app.proxyman.command.HandlerService/SetOutboundMaintenance"
+	HandlerService_ListOutboundMaintenance_FullMethodName = "/xray.app.proxyman.command.HandlerService/ListOutboundMaintenance"
+	HandlerService_GetOutboundChain_FullMethodName        = "/xray.app.proxyman.command.HandlerService/GetOutboundChain"
+	HandlerService_ListDrainingOutbounds_FullMethodName   = "/xray.app.proxyman.command.HandlerService/ListDrainingOutbounds"
+	HandlerService_ListIdleConnections_FullMethodName     = "/xray.app.proxyman.command.HandlerService/ListIdleConnections"
+	HandlerService_ListFeatures_FullMethodName            = "/xray.app.proxyman.command.HandlerService/ListFeatures"
 )
 
 // HandlerServiceClient is the client API for HandlerService service.
@@ -38,9 +48,19 @@ type HandlerServiceClient interface {
 	AlterInbound(ctx context.Context, in *AlterInboundRequest, opts ...grpc.CallOption) (*AlterInboundResponse, error)
 	GetInboundUsers(ctx context.Context, in *GetInboundUserRequest, opts ...grpc.CallOption) (*GetInboundUserResponse, error)
 	GetInboundUsersCount(ctx context.Context, in *GetInboundUserRequest, opts ...grpc.CallOption) (*GetInboundUsersCountResponse, error)
+	ListFailedInbounds(ctx context.Context, in *ListFailedInboundsRequest, opts ...grpc.CallOption) (*ListFailedInboundsResponse, error)
+	ListUDPSessions(ctx context.Context, in *ListUDPSessionsRequest, opts ...grpc.CallOption) (*ListUDPSessionsResponse, error)
+	FlushUDPSessions(ctx context.Context, in *FlushUDPSessionsRequest, opts ...grpc.CallOption) (*FlushUDPSessionsResponse, error)
+	ListInboundAddresses(ctx context.Context, in *ListInboundAddressesRequest, opts ...grpc.CallOption) (*ListInboundAddressesResponse, error)
 	AddOutbound(ctx context.Context, in *AddOutboundRequest, opts ...grpc.CallOption) (*AddOutboundResponse, error)
 	RemoveOutbound(ctx context.Context, in *RemoveOutboundRequest, opts ...grpc.CallOption) (*RemoveOutboundResponse, error)
 	AlterOutbound(ctx context.Context, in *AlterOutboundRequest, opts ...grpc.CallOption) (*AlterOutboundResponse, error)
+	SetOutboundMaintenance(ctx context.Context, in *SetOutboundMaintenanceRequest, opts ...grpc.CallOption) (*SetOutboundMaintenanceResponse, error)
+	ListOutboundMaintenance(ctx context.Context, in *ListOutboundMaintenanceRequest, opts ...grpc.CallOption) (*ListOutboundMaintenanceResponse, error)
+	GetOutboundChain(ctx context.Context, in *GetOutboundChainRequest, opts ...grpc.CallOption) (*GetOutboundChainResponse, error)
+	ListDrainingOutbounds(ctx context.Context, in *ListDrainingOutboundsRequest, opts ...grpc.CallOption) (*ListDrainingOutboundsResponse, error)
+	ListIdleConnections(ctx context.Context, in *ListIdleConnectionsRequest, opts ...grpc.CallOption) (*ListIdleConnectionsResponse, error)
+	ListFeatures(ctx context.Context, in *ListFeaturesRequest, opts ...grpc.CallOption) (*ListFeaturesResponse, error)
 }
 
 type handlerServiceClient struct {
@@ -101,6 +121,46 @@ func (c *handlerServiceClient) GetInboundUsersCount(ctx context.Context, in *Get
 	return out, nil
 }
 
+func (c *handlerServiceClient) ListFailedInbounds(ctx context.Context, in *ListFailedInboundsRequest, opts ...grpc.CallOption) (*ListFailedInboundsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFailedInboundsResponse)
+	err := c.cc.Invoke(ctx, HandlerService_ListFailedInbounds_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handlerServiceClient) ListUDPSessions(ctx context.Context, in *ListUDPSessionsRequest, opts ...grpc.CallOption) (*ListUDPSessionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListUDPSessionsResponse)
+	err := c.cc.Invoke(ctx, HandlerService_ListUDPSessions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handlerServiceClient) FlushUDPSessions(ctx context.Context, in *FlushUDPSessionsRequest, opts ...grpc.CallOption) (*FlushUDPSessionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FlushUDPSessionsResponse)
+	err := c.cc.Invoke(ctx, HandlerService_FlushUDPSessions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handlerServiceClient) ListInboundAddresses(ctx context.Context, in *ListInboundAddressesRequest, opts ...grpc.CallOption) (*ListInboundAddressesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListInboundAddressesResponse)
+	err := c.cc.Invoke(ctx, HandlerService_ListInboundAddresses_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *handlerServiceClient) AddOutbound(ctx context.Context, in *AddOutboundRequest, opts ...grpc.CallOption) (*AddOutboundResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(AddOutboundResponse)
@@ -131,6 +191,66 @@ func (c *handlerServiceClient) AlterOutbound(ctx context.Context, in *AlterOutbo
 	return out, nil
 }
 
+func (c *handlerServiceClient) SetOutboundMaintenance(ctx context.Context, in *SetOutboundMaintenanceRequest, opts ...grpc.CallOption) (*SetOutboundMaintenanceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetOutboundMaintenanceResponse)
+	err := c.cc.Invoke(ctx, HandlerService_SetOutboundMaintenance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handlerServiceClient) ListOutboundMaintenance(ctx context.Context, in *ListOutboundMaintenanceRequest, opts ...grpc.CallOption) (*ListOutboundMaintenanceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListOutboundMaintenanceResponse)
+	err := c.cc.Invoke(ctx, HandlerService_ListOutboundMaintenance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handlerServiceClient) GetOutboundChain(ctx context.Context, in *GetOutboundChainRequest, opts ...grpc.CallOption) (*GetOutboundChainResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetOutboundChainResponse)
+	err := c.cc.Invoke(ctx, HandlerService_GetOutboundChain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handlerServiceClient) ListDrainingOutbounds(ctx context.Context, in *ListDrainingOutboundsRequest, opts ...grpc.CallOption) (*ListDrainingOutboundsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDrainingOutboundsResponse)
+	err := c.cc.Invoke(ctx, HandlerService_ListDrainingOutbounds_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handlerServiceClient) ListIdleConnections(ctx context.Context, in *ListIdleConnectionsRequest, opts ...grpc.CallOption) (*ListIdleConnectionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListIdleConnectionsResponse)
+	err := c.cc.Invoke(ctx, HandlerService_ListIdleConnections_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handlerServiceClient) ListFeatures(ctx context.Context, in *ListFeaturesRequest, opts ...grpc.CallOption) (*ListFeaturesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFeaturesResponse)
+	err := c.cc.Invoke(ctx, HandlerService_ListFeatures_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // HandlerServiceServer is the server API for HandlerService service.
 // All implementations must embed UnimplementedHandlerServiceServer
 // for forward compatibility.
@@ -140,9 +260,19 @@ type HandlerServiceServer interface {
 	AlterInbound(context.Context, *AlterInboundRequest) (*AlterInboundResponse, error)
 	GetInboundUsers(context.Context, *GetInboundUserRequest) (*GetInboundUserResponse, error)
 	GetInboundUsersCount(context.Context, *GetInboundUserRequest) (*GetInboundUsersCountResponse, error)
+	ListFailedInbounds(context.Context, *ListFailedInboundsRequest) (*ListFailedInboundsResponse, error)
+	ListUDPSessions(context.Context, *ListUDPSessionsRequest) (*ListUDPSessionsResponse, error)
+	FlushUDPSessions(context.Context, *FlushUDPSessionsRequest) (*FlushUDPSessionsResponse, error)
+	ListInboundAddresses(context.Context, *ListInboundAddressesRequest) (*ListInboundAddressesResponse, error)
 	AddOutbound(context.Context, *AddOutboundRequest) (*AddOutboundResponse, error)
 	RemoveOutbound(context.Context, *RemoveOutboundRequest) (*RemoveOutboundResponse, error)
 	AlterOutbound(context.Context, *AlterOutboundRequest) (*AlterOutboundResponse, error)
+	SetOutboundMaintenance(context.Context, *SetOutboundMaintenanceRequest) (*SetOutboundMaintenanceResponse, error)
+	ListOutboundMaintenance(context.Context, *ListOutboundMaintenanceRequest) (*ListOutboundMaintenanceResponse, error)
+	GetOutboundChain(context.Context, *GetOutboundChainRequest) (*GetOutboundChainResponse, error)
+	ListDrainingOutbounds(context.Context, *ListDrainingOutboundsRequest) (*ListDrainingOutboundsResponse, error)
+	ListIdleConnections(context.Context, *ListIdleConnectionsRequest) (*ListIdleConnectionsResponse, error)
+	ListFeatures(context.Context, *ListFeaturesRequest) (*ListFeaturesResponse, error)
 	mustEmbedUnimplementedHandlerServiceServer()
 }
 
@@ -168,6 +298,18 @@ func (UnimplementedHandlerServiceServer) GetInboundUsers(context.Context, *GetIn
 func (UnimplementedHandlerServiceServer) GetInboundUsersCount(context.Context, *GetInboundUserRequest) (*GetInboundUsersCountResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetInboundUsersCount not implemented")
 }
+func (UnimplementedHandlerServiceServer) ListFailedInbounds(context.Context, *ListFailedInboundsRequest) (*ListFailedInboundsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFailedInbounds not implemented")
+}
+func (UnimplementedHandlerServiceServer) ListUDPSessions(context.Context, *ListUDPSessionsRequest) (*ListUDPSessionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUDPSessions not implemented")
+}
+func (UnimplementedHandlerServiceServer) FlushUDPSessions(context.Context, *FlushUDPSessionsRequest) (*FlushUDPSessionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FlushUDPSessions not implemented")
+}
+func (UnimplementedHandlerServiceServer) ListInboundAddresses(context.Context, *ListInboundAddressesRequest) (*ListInboundAddressesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListInboundAddresses not implemented")
+}
 func (UnimplementedHandlerServiceServer) AddOutbound(context.Context, *AddOutboundRequest) (*AddOutboundResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AddOutbound not implemented")
 }
@@ -177,6 +319,24 @@ func (UnimplementedHandlerServiceServer) RemoveOutbound(context.Context, *Remove
 func (UnimplementedHandlerServiceServer) AlterOutbound(context.Context, *AlterOutboundRequest) (*AlterOutboundResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AlterOutbound not implemented")
 }
+func (UnimplementedHandlerServiceServer) SetOutboundMaintenance(context.Context, *SetOutboundMaintenanceRequest) (*SetOutboundMaintenanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetOutboundMaintenance not implemented")
+}
+func (UnimplementedHandlerServiceServer) ListOutboundMaintenance(context.Context, *ListOutboundMaintenanceRequest) (*ListOutboundMaintenanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOutboundMaintenance not implemented")
+}
+func (UnimplementedHandlerServiceServer) GetOutboundChain(context.Context, *GetOutboundChainRequest) (*GetOutboundChainResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOutboundChain not implemented")
+}
+func (UnimplementedHandlerServiceServer) ListDrainingOutbounds(context.Context, *ListDrainingOutboundsRequest) (*ListDrainingOutboundsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDrainingOutbounds not implemented")
+}
+func (UnimplementedHandlerServiceServer) ListIdleConnections(context.Context, *ListIdleConnectionsRequest) (*ListIdleConnectionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListIdleConnections not implemented")
+}
+func (UnimplementedHandlerServiceServer) ListFeatures(context.Context, *ListFeaturesRequest) (*ListFeaturesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFeatures not implemented")
+}
 func (UnimplementedHandlerServiceServer) mustEmbedUnimplementedHandlerServiceServer() {}
 func (UnimplementedHandlerServiceServer) testEmbeddedByValue()                        {}
 
@@ -288,6 +448,78 @@ func _HandlerService_GetInboundUsersCount_Handler(srv interface{}, ctx context.C
 	return interceptor(ctx, in, info, handler)
 }
 
+func _HandlerService_ListFailedInbounds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFailedInboundsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).ListFailedInbounds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_ListFailedInbounds_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).ListFailedInbounds(ctx, req.(*ListFailedInboundsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HandlerService_ListUDPSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUDPSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).ListUDPSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_ListUDPSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).ListUDPSessions(ctx, req.(*ListUDPSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HandlerService_FlushUDPSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FlushUDPSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).FlushUDPSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_FlushUDPSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).FlushUDPSessions(ctx, req.(*FlushUDPSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HandlerService_ListInboundAddresses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListInboundAddressesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).ListInboundAddresses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_ListInboundAddresses_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).ListInboundAddresses(ctx, req.(*ListInboundAddressesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _HandlerService_AddOutbound_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(AddOutboundRequest)
 	if err := dec(in); err != nil {
@@ -342,6 +574,114 @@ func _HandlerService_AlterOutbound_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _HandlerService_SetOutboundMaintenance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetOutboundMaintenanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).SetOutboundMaintenance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_SetOutboundMaintenance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).SetOutboundMaintenance(ctx, req.(*SetOutboundMaintenanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HandlerService_ListOutboundMaintenance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOutboundMaintenanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).ListOutboundMaintenance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_ListOutboundMaintenance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).ListOutboundMaintenance(ctx, req.(*ListOutboundMaintenanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HandlerService_GetOutboundChain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOutboundChainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).GetOutboundChain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_GetOutboundChain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).GetOutboundChain(ctx, req.(*GetOutboundChainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HandlerService_ListDrainingOutbounds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDrainingOutboundsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).ListDrainingOutbounds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_ListDrainingOutbounds_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).ListDrainingOutbounds(ctx, req.(*ListDrainingOutboundsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HandlerService_ListIdleConnections_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListIdleConnectionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).ListIdleConnections(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_ListIdleConnections_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).ListIdleConnections(ctx, req.(*ListIdleConnectionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HandlerService_ListFeatures_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFeaturesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).ListFeatures(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_ListFeatures_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).ListFeatures(ctx, req.(*ListFeaturesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // HandlerService_ServiceDesc is the grpc.ServiceDesc for HandlerService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -369,6 +709,22 @@ var HandlerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetInboundUsersCount",
 			Handler:    _HandlerService_GetInboundUsersCount_Handler,
 		},
+		{
+			MethodName: "ListFailedInbounds",
+			Handler:    _HandlerService_ListFailedInbounds_Handler,
+		},
+		{
+			MethodName: "ListUDPSessions",
+			Handler:    _HandlerService_ListUDPSessions_Handler,
+		},
+		{
+			MethodName: "FlushUDPSessions",
+			Handler:    _HandlerService_FlushUDPSessions_Handler,
+		},
+		{
+			MethodName: "ListInboundAddresses",
+			Handler:    _HandlerService_ListInboundAddresses_Handler,
+		},
 		{
 			MethodName: "AddOutbound",
 			Handler:    _HandlerService_AddOutbound_Handler,
@@ -381,6 +737,30 @@ var HandlerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "AlterOutbound",
 			Handler:    _HandlerService_AlterOutbound_Handler,
 		},
+		{
+			MethodName: "SetOutboundMaintenance",
+			Handler:    _HandlerService_SetOutboundMaintenance_Handler,
+		},
+		{
+			MethodName: "ListOutboundMaintenance",
+			Handler:    _HandlerService_ListOutboundMaintenance_Handler,
+		},
+		{
+			MethodName: "GetOutboundChain",
+			Handler:    _HandlerService_GetOutboundChain_Handler,
+		},
+		{
+			MethodName: "ListDrainingOutbounds",
+			Handler:    _HandlerService_ListDrainingOutbounds_Handler,
+		},
+		{
+			MethodName: "ListIdleConnections",
+			Handler:    _HandlerService_ListIdleConnections_Handler,
+		},
+		{
+			MethodName: "ListFeatures",
+			Handler:    _HandlerService_ListFeatures_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "app/proxyman/command/command.proto",
@@ -0,0 +1,143 @@
+package command
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/transport/internet"
+)
+
+// chainedHandler is a hop of a chain, going through next.
+type chainedHandler struct {
+	outbound.Handler
+	next   string
+	status outbound.HopStatus
+}
+
+func (h *chainedHandler) NextHop() string {
+	return h.next
+}
+
+func (h *chainedHandler) HopStatus() outbound.HopStatus {
+	return h.status
+}
+
+type chainManager struct {
+	outbound.Manager
+	handlers map[string]outbound.Handler
+}
+
+func (m *chainManager) GetHandler(tag string) outbound.Handler {
+	if h, found := m.handlers[tag]; found {
+		return h
+	}
+	return nil
+}
+
+func TestGetOutboundChain(t *testing.T) {
+	s := &handlerServer{ohm: &chainManager{handlers: map[string]outbound.Handler{
+		"entry":  &chainedHandler{next: "middle", status: outbound.HopStatus{Successes: 3}},
+		"middle": &chainedHandler{next: "exit", status: outbound.HopStatus{Successes: 3, Failures: 2, LastError: "connection refused"}},
+		"exit":   &chainedHandler{next: "gone"},
+		"loop-a": &chainedHandler{next: "loop-b"},
+		"loop-b": &chainedHandler{next: "loop-a"},
+	}}}
+
+	tests := []struct {
+		tag  string
+		want []*OutboundHop
+	}{
+		{"entry", []*OutboundHop{
+			{Tag: "entry", Successes: 3},
+			{Tag: "middle", Successes: 3, Failures: 2, LastError: "connection refused"},
+			{Tag: "exit"},
+			{Tag: "gone", Missing: true},
+		}},
+		{"loop-a", []*OutboundHop{{Tag: "loop-a"}, {Tag: "loop-b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			response, err := s.GetOutboundChain(context.Background(), &GetOutboundChainRequest{Tag: tt.tag})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(response.Hops) != len(tt.want) {
+				t.Fatal("unexpected hops: ", response.Hops)
+			}
+			for i, hop := range response.Hops {
+				got := []interface{}{hop.Tag, hop.Successes, hop.Failures, hop.LastError, hop.Missing}
+				want := []interface{}{tt.want[i].Tag, tt.want[i].Successes, tt.want[i].Failures, tt.want[i].LastError, tt.want[i].Missing}
+				if r := cmp.Diff(got, want); r != "" {
+					t.Error("hop ", i, ": ", r)
+				}
+			}
+		})
+	}
+
+	if _, err := s.GetOutboundChain(context.Background(), &GetOutboundChainRequest{Tag: "gone"}); err == nil {
+		t.Error("expected the chain of a missing outbound to fail")
+	}
+}
+
+func TestConfiguredFeatures(t *testing.T) {
+	f := configuredFeatures(&core.Config{
+		App: []*serial.TypedMessage{
+			{Type: "xray.app.log.Config"},
+			{Type: "xray.app.dispatcher.Config"},
+			{Type: "xray.app.log.Config"},
+		},
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					StreamSettings: &internet.StreamConfig{
+						ProtocolName: "websocket",
+						SecurityType: "xray.transport.internet.tls.Config",
+					},
+				}),
+				ProxySettings: &serial.TypedMessage{Type: "xray.proxy.vless.inbound.Config"},
+			},
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{}),
+				ProxySettings:    &serial.TypedMessage{Type: "xray.proxy.socks.ServerConfig"},
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+					StreamSettings: &internet.StreamConfig{
+						ProtocolName: "grpc",
+						SecurityType: "xray.transport.internet.custom.Config",
+					},
+				}),
+				ProxySettings: &serial.TypedMessage{Type: "xray.proxy.vless.outbound.Config"},
+			},
+			{
+				ProxySettings: &serial.TypedMessage{Type: "xray.proxy.freedom.Config"},
+			},
+		},
+	})
+
+	for _, tt := range []struct {
+		name      string
+		got, want []string
+	}{
+		{"protocols", f.Protocols, []string{
+			"xray.proxy.freedom.Config",
+			"xray.proxy.socks.ServerConfig",
+			"xray.proxy.vless.inbound.Config",
+			"xray.proxy.vless.outbound.Config",
+		}},
+		{"transports", f.Transports, []string{"grpc", "tcp", "websocket"}},
+		{"security", f.Security, []string{"tls", "xray.transport.internet.custom.Config"}},
+		{"apps", f.Apps, []string{"xray.app.dispatcher.Config", "xray.app.log.Config"}},
+	} {
+		if r := cmp.Diff(tt.got, tt.want); r != "" {
+			t.Error(tt.name, ": ", r)
+		}
+	}
+}
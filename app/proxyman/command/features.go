@@ -0,0 +1,100 @@
+package command
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/transport/internet"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// securityTypes are the security types of streams, by the types of their configs.
+var securityTypes = map[string]string{
+	"xray.transport.internet.tls.Config":     "tls",
+	"xray.transport.internet.reality.Config": "reality",
+}
+
+// CompiledFeatures returns the features compiled into the binary.
+func CompiledFeatures() *Features {
+	f := &Features{Transports: internet.RegisteredProtocols()}
+	for _, t := range common.RegisteredConfigs() {
+		if m, ok := reflect.Zero(t).Interface().(proto.Message); ok {
+			f.addConfig(serial.GetMessageType(m))
+		}
+	}
+	for name, security := range securityTypes {
+		if _, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(name)); err == nil {
+			f.Security = append(f.Security, security)
+		}
+	}
+	f.normalize()
+	return f
+}
+
+// configuredFeatures returns the features config instantiates.
+func configuredFeatures(config *core.Config) *Features {
+	f := &Features{}
+	for _, app := range config.GetApp() {
+		f.addConfig(app.Type)
+	}
+	for _, ib := range config.GetInbound() {
+		f.addConfig(ib.ProxySettings.GetType())
+		if ib.ReceiverSettings == nil {
+			f.addStream(nil)
+		} else if rs, err := ib.ReceiverSettings.GetInstance(); err == nil {
+			if rc, ok := rs.(*proxyman.ReceiverConfig); ok {
+				f.addStream(rc.StreamSettings)
+			}
+		}
+	}
+	for _, ob := range config.GetOutbound() {
+		f.addConfig(ob.ProxySettings.GetType())
+		// Outbounds added without sender settings, such as blocked direct ones, dial over TCP.
+		if ob.SenderSettings == nil {
+			f.addStream(nil)
+		} else if ss, err := ob.SenderSettings.GetInstance(); err == nil {
+			if sc, ok := ss.(*proxyman.SenderConfig); ok {
+				f.addStream(sc.StreamSettings)
+			}
+		}
+	}
+	f.normalize()
+	return f
+}
+
+// addConfig adds the feature of the config of type t, if it's a protocol or an app.
+func (f *Features) addConfig(t string) {
+	switch {
+	case strings.HasPrefix(t, "xray.proxy."):
+		f.Protocols = append(f.Protocols, t)
+	case strings.HasPrefix(t, "xray.app."):
+		f.Apps = append(f.Apps, t)
+	}
+}
+
+// addStream adds the transport and security type of s.
+func (f *Features) addStream(s *internet.StreamConfig) {
+	f.Transports = append(f.Transports, s.GetEffectiveProtocol())
+	if s != nil && s.HasSecuritySettings() {
+		security, found := securityTypes[s.SecurityType]
+		if !found {
+			security = s.SecurityType
+		}
+		f.Security = append(f.Security, security)
+	}
+}
+
+// normalize sorts the lists of f, without duplicates.
+func (f *Features) normalize() {
+	for _, list := range []*[]string{&f.Protocols, &f.Transports, &f.Security, &f.Apps} {
+		slices.Sort(*list)
+		*list = slices.Compact(*list)
+	}
+}
@@ -2,6 +2,7 @@ package inbound
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/xtls/xray-core/app/proxyman"
 	"github.com/xtls/xray-core/common"
@@ -9,6 +10,7 @@ import (
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/mux"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/stats"
@@ -41,11 +43,27 @@ func getStatCounter(v *core.Instance, tag string) (stats.Counter, stats.Counter)
 	return uplinkCounter, downlinkCounter
 }
 
+// withTimeouts bounds the stages of handling the connections of an inbound, from their accept on,
+// by the policy of the default level, until the dispatcher bounds them by the level of the user.
+func withTimeouts(ctx context.Context, v *core.Instance) context.Context {
+	pm := v.GetFeature(policy.ManagerType()).(policy.Manager)
+	return policy.ContextWithTimeouts(ctx, pm.ForLevel(0).Timeouts)
+}
+
+func getHandshakeStats(v *core.Instance) *proxy.HandshakeStats {
+	statsManager, _ := v.GetFeature(stats.ManagerType()).(stats.Manager)
+	return proxy.NewHandshakeStats(statsManager)
+}
+
 type AlwaysOnInboundHandler struct {
 	proxy   proxy.Inbound
 	workers []worker
 	mux     *mux.Server
 	tag     string
+	conns   connCounter
+	paused  atomic.Bool
+	// receiver is kept to tell whether a replacing handler listens the same way.
+	receiver *proxyman.ReceiverConfig
 }
 
 func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *proxyman.ReceiverConfig, proxyConfig interface{}) (*AlwaysOnInboundHandler, error) {
@@ -59,12 +77,14 @@ func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *
 	}
 
 	h := &AlwaysOnInboundHandler{
-		proxy: p,
-		mux:   mux.NewServer(ctx),
-		tag:   tag,
+		proxy:    p,
+		mux:      mux.NewServer(ctx),
+		tag:      tag,
+		receiver: receiverConfig,
 	}
 
 	uplinkCounter, downlinkCounter := getStatCounter(core.MustFromContext(ctx), tag)
+	handshakes := getHandshakeStats(core.MustFromContext(ctx))
 
 	nl := p.Network()
 	pl := receiverConfig.PortList
@@ -78,6 +98,11 @@ func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *
 		return nil, errors.New("failed to parse stream config").Base(err).AtWarning()
 	}
 
+	sniffingRequest, err := newSniffingRequest(receiverConfig.GetEffectiveSniffingSettings())
+	if err != nil {
+		return nil, errors.New("failed to parse sniffing config").Base(err).AtWarning()
+	}
+
 	if receiverConfig.ReceiveOriginalDestination {
 		if mss.SocketSettings == nil {
 			mss.SocketSettings = &internet.SocketConfig{}
@@ -87,6 +112,26 @@ func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *
 		}
 		mss.SocketSettings.ReceiveOriginalDestAddress = true
 	}
+	if receiverConfig.LocalBypass {
+		ctx = session.ContextWithLocalBypass(ctx, true)
+	}
+	ctx = withTimeouts(ctx, core.MustFromContext(ctx))
+	if acceptor, ok := p.(proxy.Acceptor); ok {
+		errors.LogDebug(ctx, "creating accepting worker for inbound ", tag)
+
+		worker := &acceptWorker{
+			proxy:           acceptor,
+			tag:             tag,
+			dispatcher:      h.mux,
+			sniffingRequest: sniffingRequest,
+			uplinkCounter:   uplinkCounter,
+			downlinkCounter: downlinkCounter,
+			conns:           &h.conns,
+			drainFlag:       drainFlag{paused: &h.paused},
+			ctx:             ctx,
+		}
+		h.workers = append(h.workers, worker)
+	}
 	if pl == nil {
 		if net.HasNetwork(nl, net.Network_UNIX) {
 			errors.LogDebug(ctx, "creating unix domain socket worker on ", address)
@@ -97,9 +142,11 @@ func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *
 				stream:          mss,
 				tag:             tag,
 				dispatcher:      h.mux,
-				sniffingConfig:  receiverConfig.GetEffectiveSniffingSettings(),
+				sniffingRequest: sniffingRequest,
 				uplinkCounter:   uplinkCounter,
 				downlinkCounter: downlinkCounter,
+				conns:           &h.conns,
+				drainFlag:       drainFlag{paused: &h.paused},
 				ctx:             ctx,
 			}
 			h.workers = append(h.workers, worker)
@@ -107,6 +154,21 @@ func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *
 	}
 	if pl != nil {
 		for _, pr := range pl.Range {
+			if pr.From == 0 && pr.To == 0 {
+				var networks []net.Network
+				if net.HasNetwork(nl, net.Network_TCP) && mss.ProtocolName != "mkcp" {
+					networks = append(networks, net.Network_TCP)
+				}
+				if net.HasNetwork(nl, net.Network_UDP) || (net.HasNetwork(nl, net.Network_TCP) && mss.ProtocolName == "mkcp") {
+					networks = append(networks, net.Network_UDP)
+				}
+				port, err := allocatePort(address, networks)
+				if err != nil {
+					return nil, err
+				}
+				errors.LogInfo(ctx, "inbound [", tag, "] allocated port ", port, " on ", address)
+				pr = &net.PortRange{From: uint32(port), To: uint32(port)}
+			}
 			for port := pr.From; port <= pr.To; port++ {
 				if net.HasNetwork(nl, net.Network_TCP) {
 					errors.LogDebug(ctx, "creating stream worker on ", address, ":", port)
@@ -119,9 +181,12 @@ func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *
 						recvOrigDest:    receiverConfig.ReceiveOriginalDestination,
 						tag:             tag,
 						dispatcher:      h.mux,
-						sniffingConfig:  receiverConfig.GetEffectiveSniffingSettings(),
+						sniffingRequest: sniffingRequest,
 						uplinkCounter:   uplinkCounter,
 						downlinkCounter: downlinkCounter,
+						handshakes:      handshakes,
+						conns:           &h.conns,
+						drainFlag:       drainFlag{paused: &h.paused},
 						ctx:             ctx,
 					}
 					h.workers = append(h.workers, worker)
@@ -134,9 +199,11 @@ func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *
 						address:         address,
 						port:            net.Port(port),
 						dispatcher:      h.mux,
-						sniffingConfig:  receiverConfig.GetEffectiveSniffingSettings(),
+						sniffingRequest: sniffingRequest,
 						uplinkCounter:   uplinkCounter,
 						downlinkCounter: downlinkCounter,
+						conns:           &h.conns,
+						drainFlag:       drainFlag{paused: &h.paused},
 						stream:          mss,
 						ctx:             ctx,
 					}
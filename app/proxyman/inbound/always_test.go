@@ -0,0 +1,34 @@
+package inbound
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	app_policy "github.com/xtls/xray-core/app/policy"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/policy"
+)
+
+func TestWithTimeouts(t *testing.T) {
+	v, err := core.New(&core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&app_policy.Config{
+				Level: map[uint32]*app_policy.Policy{
+					0: {Timeout: &app_policy.Policy_Timeout{
+						Dial:    &app_policy.Second{Value: 3},
+						Resolve: &app_policy.Second{Value: 7},
+					}},
+				},
+			}),
+		},
+	})
+	common.Must(err)
+
+	timeouts := policy.TimeoutsFromContext(withTimeouts(context.Background(), v))
+	if timeouts.Dial != 3*time.Second || timeouts.Resolve != 7*time.Second {
+		t.Error("unexpected timeouts: ", timeouts)
+	}
+}
@@ -0,0 +1,107 @@
+package inbound
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtls/xray-core/features/inbound"
+)
+
+// connCounter counts the connections in progress through the workers of a handler, and keeps
+// those of stream workers so that idle ones can be listed and swept.
+type connCounter struct {
+	n      atomic.Int64
+	access sync.Mutex
+	conns  map[*trackedConn]struct{}
+}
+
+// trackedConn is a stream connection in progress, with the context it is processed in.
+type trackedConn struct {
+	ctx    context.Context
+	conn   io.Closer
+	cancel context.CancelFunc
+	start  time.Time
+}
+
+func (c *connCounter) inc() {
+	if c != nil {
+		c.n.Add(1)
+	}
+}
+
+func (c *connCounter) dec() {
+	if c != nil {
+		c.n.Add(-1)
+	}
+}
+
+// track keeps conn, processed in ctx carrying its session.Inbound, until untracked.
+func (c *connCounter) track(ctx context.Context, conn io.Closer, cancel context.CancelFunc) *trackedConn {
+	if c == nil {
+		return nil
+	}
+	t := &trackedConn{ctx: ctx, conn: conn, cancel: cancel, start: time.Now()}
+	c.access.Lock()
+	if c.conns == nil {
+		c.conns = make(map[*trackedConn]struct{})
+	}
+	c.conns[t] = struct{}{}
+	c.access.Unlock()
+	return t
+}
+
+func (c *connCounter) untrack(t *trackedConn) {
+	if c == nil || t == nil {
+		return
+	}
+	c.access.Lock()
+	delete(c.conns, t)
+	c.access.Unlock()
+}
+
+func (c *connCounter) tracked() []*trackedConn {
+	c.access.Lock()
+	defer c.access.Unlock()
+	conns := make([]*trackedConn, 0, len(c.conns))
+	for t := range c.conns {
+		conns = append(conns, t)
+	}
+	return conns
+}
+
+// connectionHolder is implemented by handlers counting their connections.
+type connectionHolder interface {
+	connections() *connCounter
+}
+
+func (h *AlwaysOnInboundHandler) connections() *connCounter {
+	return &h.conns
+}
+
+func (h *DynamicInboundHandler) connections() *connCounter {
+	return &h.conns
+}
+
+// GetActiveConnections implements inbound.ConnectionReporter.
+func (m *Manager) GetActiveConnections() []inbound.ConnectionCount {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	counts := make([]inbound.ConnectionCount, 0, len(m.taggedHandlers))
+	for tag, handler := range m.taggedHandlers {
+		if _, ok := handler.(connectionHolder); !ok {
+			continue
+		}
+		n := 0
+		for _, h := range append(m.handlersFor(tag), m.retiringFor(tag)...) {
+			if holder, ok := h.(connectionHolder); ok {
+				n += int(holder.connections().n.Load())
+			}
+		}
+		counts = append(counts, inbound.ConnectionCount{Tag: tag, Connections: n})
+	}
+	return counts
+}
@@ -0,0 +1,58 @@
+package inbound
+
+import (
+	"sync/atomic"
+)
+
+// drainFlag is set on workers which stop taking new connections, leaving those in progress. paused
+// is the flag of their handler, set while the inbounds are paused.
+type drainFlag struct {
+	draining atomic.Bool
+	paused   *atomic.Bool
+}
+
+func (f *drainFlag) stopAccepting() {
+	f.draining.Store(true)
+}
+
+// refusing tells whether the worker refuses new connections.
+func (f *drainFlag) refusing() bool {
+	return f.draining.Load() || (f.paused != nil && f.paused.Load())
+}
+
+// acceptStopper is implemented by workers and handlers which can stop taking new connections.
+type acceptStopper interface {
+	stopAccepting()
+}
+
+func stopWorkers(workers []worker) {
+	for _, w := range workers {
+		if s, ok := w.(acceptStopper); ok {
+			s.stopAccepting()
+		}
+	}
+}
+
+func (h *AlwaysOnInboundHandler) stopAccepting() {
+	stopWorkers(h.workers)
+}
+
+func (h *DynamicInboundHandler) stopAccepting() {
+	// Refreshing would replace the workers with accepting ones.
+	h.task.Close()
+	h.workerMutex.RLock()
+	defer h.workerMutex.RUnlock()
+	stopWorkers(h.worker)
+}
+
+// StopAccepting implements inbound.Drainer.
+func (m *Manager) StopAccepting() {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	for _, handler := range m.handlersFor("") {
+		if s, ok := handler.(acceptStopper); ok {
+			s.stopAccepting()
+		}
+	}
+}
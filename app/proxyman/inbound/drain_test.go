@@ -0,0 +1,93 @@
+package inbound_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/app/proxyman"
+	_ "github.com/xtls/xray-core/app/proxyman/inbound"
+	_ "github.com/xtls/xray-core/app/proxyman/outbound"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/proxy/dokodemo"
+	"github.com/xtls/xray-core/proxy/freedom"
+	"github.com/xtls/xray-core/testing/servers/tcp"
+)
+
+// echoes tells whether a message sent on conn comes back.
+func echoes(conn net.Conn) bool {
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		return false
+	}
+	b := make([]byte, 4)
+	_, err := io.ReadFull(conn, b)
+	return err == nil && string(b) == "ping"
+}
+
+func TestStopAccepting(t *testing.T) {
+	echo := tcp.Server{MsgProcessor: func(b []byte) []byte { return b }}
+	dest, err := echo.Start()
+	common.Must(err)
+	defer echo.Close()
+
+	port := tcp.PickPort()
+	server, err := core.New(&core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+		},
+		Inbound: []*core.InboundHandlerConfig{{
+			Tag: "in",
+			ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+				PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(port)}},
+				Listen:   net.NewIPOrDomain(net.LocalHostIP),
+			}),
+			ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+				Address:  net.NewIPOrDomain(dest.Address),
+				Port:     uint32(dest.Port),
+				Networks: []net.Network{net.Network_TCP},
+			}),
+		}},
+		Outbound: []*core.OutboundHandlerConfig{{
+			ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+		}},
+	})
+	common.Must(err)
+	common.Must(server.Start())
+	defer server.Close()
+
+	addr := net.TCPDestination(net.LocalHostIP, port).NetAddr()
+	inFlight, err := net.Dial("tcp", addr)
+	common.Must(err)
+	defer inFlight.Close()
+	if !echoes(inFlight) {
+		t.Fatal("the inbound doesn't relay")
+	}
+
+	im := server.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	im.(inbound.Drainer).StopAccepting()
+
+	if conn, err := net.Dial("tcp", addr); err == nil {
+		defer conn.Close()
+		if echoes(conn) {
+			t.Error("a new connection was taken after StopAccepting")
+		}
+	}
+	if !echoes(inFlight) {
+		t.Error("the connection in progress was broken by StopAccepting")
+	}
+	n := 0
+	for _, c := range im.(inbound.ConnectionReporter).GetActiveConnections() {
+		n += c.Connections
+	}
+	if n != 1 {
+		t.Error(n, " connections in progress, want 1")
+	}
+}
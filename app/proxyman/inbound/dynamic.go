@@ -3,6 +3,7 @@ package inbound
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/xtls/xray-core/app/proxyman"
@@ -10,6 +11,7 @@ import (
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/mux"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/task"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/proxy"
@@ -17,24 +19,31 @@ import (
 )
 
 type DynamicInboundHandler struct {
-	tag            string
-	v              *core.Instance
-	proxyConfig    interface{}
-	receiverConfig *proxyman.ReceiverConfig
-	streamSettings *internet.MemoryStreamConfig
-	portMutex      sync.Mutex
-	portsInUse     map[net.Port]struct{}
-	workerMutex    sync.RWMutex
-	worker         []worker
-	lastRefresh    time.Time
-	mux            *mux.Server
-	task           *task.Periodic
+	tag             string
+	v               *core.Instance
+	proxyConfig     interface{}
+	receiverConfig  *proxyman.ReceiverConfig
+	streamSettings  *internet.MemoryStreamConfig
+	sniffingRequest *session.SniffingRequest
+	portMutex       sync.Mutex
+	portsInUse      map[net.Port]struct{}
+	workerMutex     sync.RWMutex
+	worker          []worker
+	lastRefresh     time.Time
+	mux             *mux.Server
+	task            *task.Periodic
+	conns           connCounter
+	paused          atomic.Bool
 
 	ctx context.Context
 }
 
 func NewDynamicInboundHandler(ctx context.Context, tag string, receiverConfig *proxyman.ReceiverConfig, proxyConfig interface{}) (*DynamicInboundHandler, error) {
 	v := core.MustFromContext(ctx)
+	if receiverConfig.LocalBypass {
+		ctx = session.ContextWithLocalBypass(ctx, true)
+	}
+	ctx = withTimeouts(ctx, v)
 	h := &DynamicInboundHandler{
 		tag:            tag,
 		proxyConfig:    proxyConfig,
@@ -49,6 +58,10 @@ func NewDynamicInboundHandler(ctx context.Context, tag string, receiverConfig *p
 	if err != nil {
 		return nil, errors.New("failed to parse stream settings").Base(err).AtWarning()
 	}
+	h.sniffingRequest, err = newSniffingRequest(receiverConfig.GetEffectiveSniffingSettings())
+	if err != nil {
+		return nil, errors.New("failed to parse sniffing config").Base(err).AtWarning()
+	}
 	if receiverConfig.ReceiveOriginalDestination {
 		if mss.SocketSettings == nil {
 			mss.SocketSettings = &internet.SocketConfig{}
@@ -119,6 +132,7 @@ func (h *DynamicInboundHandler) refresh() error {
 	}
 
 	uplinkCounter, downlinkCounter := getStatCounter(h.v, h.tag)
+	handshakes := getHandshakeStats(h.v)
 
 	for i := uint32(0); i < concurrency; i++ {
 		port := h.allocatePort()
@@ -138,9 +152,12 @@ func (h *DynamicInboundHandler) refresh() error {
 				stream:          h.streamSettings,
 				recvOrigDest:    h.receiverConfig.ReceiveOriginalDestination,
 				dispatcher:      h.mux,
-				sniffingConfig:  h.receiverConfig.GetEffectiveSniffingSettings(),
+				sniffingRequest: h.sniffingRequest,
 				uplinkCounter:   uplinkCounter,
 				downlinkCounter: downlinkCounter,
+				handshakes:      handshakes,
+				conns:           &h.conns,
+				drainFlag:       drainFlag{paused: &h.paused},
 				ctx:             h.ctx,
 			}
 			if err := worker.Start(); err != nil {
@@ -157,9 +174,11 @@ func (h *DynamicInboundHandler) refresh() error {
 				address:         address,
 				port:            port,
 				dispatcher:      h.mux,
-				sniffingConfig:  h.receiverConfig.GetEffectiveSniffingSettings(),
+				sniffingRequest: h.sniffingRequest,
 				uplinkCounter:   uplinkCounter,
 				downlinkCounter: downlinkCounter,
+				conns:           &h.conns,
+				drainFlag:       drainFlag{paused: &h.paused},
 				stream:          h.streamSettings,
 				ctx:             h.ctx,
 			}
@@ -0,0 +1,73 @@
+package inbound
+
+import (
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/features/inbound"
+)
+
+// allocateAttempts is how many ports are tried for one free on all the networks of a handler.
+const allocateAttempts = 10
+
+// allocatePort picks a port for the workers of a handler configured with port 0, which is free on
+// address for all the networks they listen on, so that they share it.
+func allocatePort(address net.Address, networks []net.Network) (net.Port, error) {
+	if address.Family().IsDomain() && address.Domain() == "localhost" {
+		address = net.LocalHostIP
+	}
+	if len(networks) == 0 || !address.Family().IsIP() {
+		return 0, errors.New("can't allocate a port on ", address)
+	}
+	var lastErr error
+	for i := 0; i < allocateAttempts; i++ {
+		port, err := probePort(address.IP(), networks[0], 0)
+		if err != nil {
+			return 0, errors.New("failed to allocate a port on ", address).Base(err)
+		}
+		free := true
+		for _, network := range networks[1:] {
+			if _, err := probePort(address.IP(), network, port); err != nil {
+				free, lastErr = false, err
+				break
+			}
+		}
+		if free {
+			return port, nil
+		}
+	}
+	return 0, errors.New("failed to allocate a port free for all of ", networks, " on ", address).Base(lastErr)
+}
+
+// probePort listens on ip and port briefly, and returns the port listened on.
+func probePort(ip net.IP, network net.Network, port net.Port) (net.Port, error) {
+	if network == net.Network_UDP {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: ip, Port: int(port)})
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+		return net.Port(conn.LocalAddr().(*net.UDPAddr).Port), nil
+	}
+	l, err := net.Listen("tcp", (&net.TCPAddr{IP: ip, Port: int(port)}).String())
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return net.Port(l.Addr().(*net.TCPAddr).Port), nil
+}
+
+// GetListenAddresses implements inbound.AddressReporter.
+func (m *Manager) GetListenAddresses(tag string) []inbound.ListenAddress {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	var addresses []inbound.ListenAddress
+	for _, handler := range m.handlersFor(tag) {
+		if binder, ok := handler.(portBinder); ok {
+			for _, b := range binder.portBindings() {
+				addresses = append(addresses, inbound.ListenAddress{Tag: b.tag, Address: b.dest})
+			}
+		}
+	}
+	return addresses
+}
@@ -0,0 +1,110 @@
+package inbound
+
+import (
+	"time"
+
+	c "github.com/xtls/xray-core/common/ctx"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/inbound"
+)
+
+// idleSweepInterval is how often connections idle beyond policy are looked for and closed.
+const idleSweepInterval = time.Minute
+
+// idleLimit returns how long the connection of in may stay idle: the idle timeout of the policy of
+// its user, or the current timeout of its activity timer if longer, as while splicing.
+func (m *Manager) idleLimit(in *session.Inbound) time.Duration {
+	var limit time.Duration
+	if m.policyManager != nil {
+		var level uint32
+		if in.User != nil {
+			level = in.User.Level
+		}
+		limit = m.policyManager.ForLevel(level).Timeouts.ConnectionIdle
+	}
+	if timeout := in.Timer.Timeout(); timeout > limit {
+		limit = timeout
+	}
+	return limit
+}
+
+// visitIdleConnections calls visit with the tracked connections of the handlers with the given tag,
+// or of all handlers if tag is empty, idle for at least idle. Connections through inbounds which
+// keep no activity timer are left out, as their idle time is unknown. Caller must hold m.access.
+func (m *Manager) visitIdleConnections(tag string, idle time.Duration, visit func(*trackedConn, inbound.IdleConnection)) {
+	now := time.Now()
+	for _, handler := range append(m.handlersFor(tag), m.retiringFor(tag)...) {
+		holder, ok := handler.(connectionHolder)
+		if !ok {
+			continue
+		}
+		for _, t := range holder.connections().tracked() {
+			in := session.InboundFromContext(t.ctx)
+			if in == nil || in.Timer == nil {
+				continue
+			}
+			id := c.IDFromContext(t.ctx)
+			c := inbound.IdleConnection{
+				Tag:     in.Tag,
+				Source:  in.Source,
+				Age:     now.Sub(t.start),
+				Idle:    in.Timer.Idle(),
+				Limit:   m.idleLimit(in),
+				Session: id,
+			}
+			if c.Idle < idle {
+				continue
+			}
+			if in.User != nil {
+				c.User = in.User.Email
+			}
+			if outbounds := session.OutboundsFromContext(t.ctx); len(outbounds) > 0 {
+				c.Destination = outbounds[len(outbounds)-1].Target
+			}
+			visit(t, c)
+		}
+	}
+}
+
+// GetIdleConnections implements inbound.IdleConnectionLister.
+func (m *Manager) GetIdleConnections(tag string, idle time.Duration) []inbound.IdleConnection {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	var conns []inbound.IdleConnection
+	m.visitIdleConnections(tag, idle, func(_ *trackedConn, c inbound.IdleConnection) {
+		conns = append(conns, c)
+	})
+	return conns
+}
+
+// sweepIdleConnections closes the connections idle beyond their limits, logging each in its
+// session so that clients leaking connections can be told apart.
+func (m *Manager) sweepIdleConnections() error {
+	type sweep struct {
+		conn *trackedConn
+		info inbound.IdleConnection
+	}
+	var sweeps []sweep
+	m.access.RLock()
+	m.visitIdleConnections("", 0, func(t *trackedConn, c inbound.IdleConnection) {
+		if c.Limit > 0 && c.Idle > c.Limit {
+			sweeps = append(sweeps, sweep{conn: t, info: c})
+		}
+	})
+	m.access.RUnlock()
+
+	for _, s := range sweeps {
+		c := s.info
+		errors.LogWarning(s.conn.ctx, "closing connection idle for ", c.Idle.Round(time.Second), " beyond ", c.Limit,
+			" through inbound [", c.Tag, "] from ", c.Source, " to ", c.Destination, " of user [", c.User, "], open for ", c.Age.Round(time.Second))
+		s.conn.cancel()
+		s.conn.conn.Close()
+	}
+
+	m.access.Lock()
+	m.closeRetired()
+	m.access.Unlock()
+	return nil
+}
@@ -10,8 +10,10 @@ import (
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/task"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/policy"
 )
 
 // Manager manages all inbound handlers.
@@ -20,12 +22,30 @@ type Manager struct {
 	untaggedHandler []inbound.Handler
 	taggedHandlers  map[string]inbound.Handler
 	running         bool
+	tolerateErrors  bool
+	failedHandlers  []inbound.HandlerFailure
+	policyManager   policy.Manager
+	idleSweeper     *task.Periodic
+	// retiring are the replaced handlers whose listeners were taken over, until their connections end.
+	retiring []inbound.Handler
+	// paused tells whether the handlers refuse new connections.
+	paused bool
 }
 
 // New returns a new Manager for inbound handlers.
 func New(ctx context.Context, config *proxyman.InboundConfig) (*Manager, error) {
 	m := &Manager{
 		taggedHandlers: make(map[string]inbound.Handler),
+		tolerateErrors: config.TolerateErrors,
+	}
+	m.idleSweeper = &task.Periodic{
+		Interval: idleSweepInterval,
+		Execute:  m.sweepIdleConnections,
+	}
+	if err := core.RequireFeatures(ctx, func(pm policy.Manager) {
+		m.policyManager = pm
+	}); err != nil {
+		return nil, err
 	}
 	return m, nil
 }
@@ -49,6 +69,7 @@ func (m *Manager) AddHandler(ctx context.Context, handler inbound.Handler) error
 	} else {
 		m.untaggedHandler = append(m.untaggedHandler, handler)
 	}
+	m.pauseIfPaused(handler)
 
 	if m.running {
 		return handler.Start()
@@ -91,27 +112,74 @@ func (m *Manager) RemoveHandler(ctx context.Context, tag string) error {
 
 // Start implements common.Runnable.
 func (m *Manager) Start() error {
+	if err := m.startHandlers(); err != nil {
+		return err
+	}
+	return m.idleSweeper.Start()
+}
+
+func (m *Manager) startHandlers() error {
 	m.access.Lock()
 	defer m.access.Unlock()
 
 	m.running = true
 
-	for _, handler := range m.taggedHandlers {
+	if err := m.precheckPorts(); err != nil {
+		return err
+	}
+
+	for tag, handler := range m.taggedHandlers {
 		if err := handler.Start(); err != nil {
-			return err
+			if !m.tolerateErrors {
+				return err
+			}
+			m.markFailed(tag, handler, err)
+			delete(m.taggedHandlers, tag)
 		}
 	}
 
+	started := m.untaggedHandler[:0]
 	for _, handler := range m.untaggedHandler {
 		if err := handler.Start(); err != nil {
-			return err
+			if !m.tolerateErrors {
+				return err
+			}
+			m.markFailed("", handler, err)
+			continue
 		}
+		started = append(started, handler)
+	}
+	m.untaggedHandler = started
+
+	if len(m.failedHandlers) > 0 {
+		errors.LogWarning(context.Background(), len(m.failedHandlers), " inbound handler(s) failed to start, the others keep running")
 	}
 	return nil
 }
 
+// markFailed takes a handler that failed to start out of service. The caller must hold m.access.
+func (m *Manager) markFailed(tag string, handler inbound.Handler, err error) {
+	errors.LogErrorInner(context.Background(), err, "failed to start inbound handler [", tag, "]")
+	if err := handler.Close(); err != nil {
+		errors.LogDebugInner(context.Background(), err, "failed to close inbound handler [", tag, "]")
+	}
+	m.failedHandlers = append(m.failedHandlers, inbound.HandlerFailure{Tag: tag, Err: err})
+}
+
+// GetFailedHandlers implements inbound.FailureReporter.
+func (m *Manager) GetFailedHandlers() []inbound.HandlerFailure {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	failures := make([]inbound.HandlerFailure, len(m.failedHandlers))
+	copy(failures, m.failedHandlers)
+	return failures
+}
+
 // Close implements common.Closable.
 func (m *Manager) Close() error {
+	m.idleSweeper.Close()
+
 	m.access.Lock()
 	defer m.access.Unlock()
 
@@ -128,6 +196,12 @@ func (m *Manager) Close() error {
 			errs = append(errs, err)
 		}
 	}
+	for _, handler := range m.retiring {
+		if err := handler.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	m.retiring = nil
 
 	if len(errs) > 0 {
 		return errors.New("failed to close all handlers").Base(errors.New(serial.Concat(errs...)))
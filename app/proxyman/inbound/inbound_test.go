@@ -0,0 +1,111 @@
+package inbound
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/features/inbound"
+)
+
+type fakeHandler struct {
+	tag      string
+	startErr error
+	started  bool
+	closed   bool
+	bindings []*portBinding
+}
+
+func (h *fakeHandler) Start() error {
+	h.started = true
+	return h.startErr
+}
+
+func (h *fakeHandler) Close() error {
+	h.closed = true
+	return nil
+}
+
+func (h *fakeHandler) Tag() string {
+	return h.tag
+}
+
+func (h *fakeHandler) portBindings() []*portBinding {
+	return h.bindings
+}
+
+func (h *fakeHandler) GetRandomInboundProxy() (interface{}, net.Port, int) {
+	return nil, 0, 0
+}
+
+func newTestManager(tolerateErrors bool, handlers ...*fakeHandler) *Manager {
+	m := &Manager{
+		taggedHandlers: make(map[string]inbound.Handler),
+		tolerateErrors: tolerateErrors,
+		idleSweeper: &task.Periodic{
+			Interval: time.Hour,
+			Execute:  func() error { return nil },
+		},
+	}
+	for _, h := range handlers {
+		if h.tag == "" {
+			m.untaggedHandler = append(m.untaggedHandler, h)
+		} else {
+			m.taggedHandlers[h.tag] = h
+		}
+	}
+	return m
+}
+
+func TestManagerToleratesFailedHandler(t *testing.T) {
+	errInUse := errors.New("address already in use")
+	errDenied := errors.New("permission denied")
+	good := &fakeHandler{tag: "good"}
+	bad := &fakeHandler{tag: "bad", startErr: errInUse}
+	untagged := &fakeHandler{startErr: errDenied}
+	m := newTestManager(true, good, bad, untagged)
+	defer m.Close()
+
+	if err := m.Start(); err != nil {
+		t.Fatal("expected failed handlers to be skipped, got ", err)
+	}
+	if !good.started || good.closed {
+		t.Error("expected the good handler to keep running")
+	}
+	if !bad.closed || !untagged.closed {
+		t.Error("expected the failed handlers to be closed")
+	}
+	if _, found := m.taggedHandlers["bad"]; found {
+		t.Error("expected the failed handler to be removed")
+	}
+	if len(m.untaggedHandler) != 0 {
+		t.Error("expected the failed untagged handler to be removed")
+	}
+
+	failures := m.GetFailedHandlers()
+	if len(failures) != 2 {
+		t.Fatal("expected 2 failures, got ", failures)
+	}
+	tags := map[string]error{}
+	for _, f := range failures {
+		tags[f.Tag] = f.Err
+	}
+	if tags["bad"] != errInUse || tags[""] != errDenied {
+		t.Error("unexpected failures: ", failures)
+	}
+}
+
+func TestManagerFailsWithoutToleration(t *testing.T) {
+	bad := &fakeHandler{tag: "bad", startErr: errors.New("address already in use")}
+	m := newTestManager(false, bad)
+	defer m.Close()
+
+	if err := m.Start(); err == nil {
+		t.Fatal("expected the failed handler to fail the manager")
+	}
+	if len(m.GetFailedHandlers()) != 0 {
+		t.Error("expected no failures to be reported")
+	}
+}
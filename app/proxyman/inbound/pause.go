@@ -0,0 +1,47 @@
+package inbound
+
+import (
+	"github.com/xtls/xray-core/features/inbound"
+)
+
+// pauser is implemented by handlers whose workers can refuse new connections for a while.
+type pauser interface {
+	setPaused(paused bool)
+}
+
+func (h *AlwaysOnInboundHandler) setPaused(paused bool) {
+	h.paused.Store(paused)
+}
+
+func (h *DynamicInboundHandler) setPaused(paused bool) {
+	// Workers created by refreshing share the flag too.
+	h.paused.Store(paused)
+}
+
+// pauseIfPaused pauses handler, a new one, if the handlers are paused. The caller must hold
+// m.access.
+func (m *Manager) pauseIfPaused(handler inbound.Handler) {
+	if p, ok := handler.(pauser); ok && m.paused {
+		p.setPaused(true)
+	}
+}
+
+// SetPaused implements inbound.Pauser.
+func (m *Manager) SetPaused(paused bool) {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	m.paused = paused
+	for _, handler := range m.handlersFor("") {
+		if p, ok := handler.(pauser); ok {
+			p.setPaused(paused)
+		}
+	}
+}
+
+// Paused implements inbound.Pauser.
+func (m *Manager) Paused() bool {
+	m.access.RLock()
+	defer m.access.RUnlock()
+	return m.paused
+}
@@ -0,0 +1,130 @@
+package inbound
+
+import (
+	"context"
+	goerrors "errors"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/features/inbound"
+)
+
+// portBinding is an address and port that an inbound handler is going to listen on.
+type portBinding struct {
+	tag  string
+	dest net.Destination
+}
+
+// overlaps returns true if both bindings can't be listened at the same time.
+func (b *portBinding) overlaps(o *portBinding) bool {
+	if b.dest.Network != o.dest.Network || b.dest.Port != o.dest.Port {
+		return false
+	}
+	if isAnyIP(b.dest.Address) || isAnyIP(o.dest.Address) {
+		return true
+	}
+	return b.dest.Address.String() == o.dest.Address.String()
+}
+
+func isAnyIP(address net.Address) bool {
+	return address == net.AnyIP || address == net.AnyIPv6
+}
+
+// portBinder is implemented by inbound handlers that know their listening ports in advance.
+type portBinder interface {
+	portBindings() []*portBinding
+}
+
+func (h *AlwaysOnInboundHandler) portBindings() []*portBinding {
+	var bindings []*portBinding
+	for _, w := range h.workers {
+		switch w := w.(type) {
+		case *tcpWorker:
+			dest := net.TCPDestination(w.address, w.port)
+			if w.stream != nil && w.stream.ProtocolName == "mkcp" {
+				dest.Network = net.Network_UDP
+			}
+			bindings = append(bindings, &portBinding{tag: h.tag, dest: dest})
+		case *udpWorker:
+			bindings = append(bindings, &portBinding{tag: h.tag, dest: net.UDPDestination(w.address, w.port)})
+		}
+	}
+	return bindings
+}
+
+// probeBinding checks whether the binding is already taken by another process.
+// Other errors, like lack of permission, are left for the handler to report.
+func probeBinding(b *portBinding) bool {
+	var err error
+	if b.dest.Network == net.Network_UDP {
+		var conn *net.UDPConn
+		conn, err = net.ListenUDP("udp", &net.UDPAddr{IP: b.dest.Address.IP(), Port: int(b.dest.Port)})
+		if err == nil {
+			conn.Close()
+		}
+	} else {
+		var l net.Listener
+		l, err = net.Listen("tcp", b.dest.NetAddr())
+		if err == nil {
+			l.Close()
+		}
+	}
+	return err != nil && goerrors.Is(err, syscall.EADDRINUSE)
+}
+
+// checkPortConflicts finds every binding that conflicts with another inbound or with a running process,
+// and returns a single error describing all of them.
+func checkPortConflicts(handlers []inbound.Handler) error {
+	var bindings []*portBinding
+	for _, handler := range handlers {
+		if binder, ok := handler.(portBinder); ok {
+			bindings = append(bindings, binder.portBindings()...)
+		}
+	}
+
+	var conflicts []string
+	conflicting := make(map[*portBinding]bool)
+	for i, b := range bindings {
+		for _, o := range bindings[i+1:] {
+			if b.overlaps(o) {
+				conflicts = append(conflicts, b.dest.String()+" of inbound ["+b.tag+"] conflicts with "+o.dest.String()+" of inbound ["+o.tag+"]")
+				conflicting[b] = true
+				conflicting[o] = true
+			}
+		}
+	}
+	for _, b := range bindings {
+		if conflicting[b] || !probeBinding(b) {
+			continue
+		}
+		msg := b.dest.String() + " of inbound [" + b.tag + "] is already in use"
+		if owner := findPortOwner(b.dest.Network, b.dest.Port); owner != "" {
+			msg += " by " + owner
+		}
+		conflicts = append(conflicts, msg)
+	}
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+	sort.Strings(conflicts)
+	return errors.New("port conflicts found:\n\t" + strings.Join(conflicts, "\n\t"))
+}
+
+// precheckPorts runs checkPortConflicts on all handlers. The caller must hold m.access.
+func (m *Manager) precheckPorts() error {
+	handlers := make([]inbound.Handler, 0, len(m.taggedHandlers)+len(m.untaggedHandler))
+	for _, handler := range m.taggedHandlers {
+		handlers = append(handlers, handler)
+	}
+	handlers = append(handlers, m.untaggedHandler...)
+	err := checkPortConflicts(handlers)
+	if err != nil && m.tolerateErrors {
+		errors.LogError(context.Background(), err.Error())
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+package inbound
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/features/inbound"
+)
+
+func TestPortBindingOverlaps(t *testing.T) {
+	local := net.ParseAddress("127.0.0.1")
+	other := net.ParseAddress("127.0.0.2")
+	cases := []struct {
+		a, b     net.Destination
+		overlaps bool
+	}{
+		{net.TCPDestination(local, 1080), net.TCPDestination(local, 1080), true},
+		{net.TCPDestination(local, 1080), net.TCPDestination(local, 1081), false},
+		{net.TCPDestination(local, 1080), net.UDPDestination(local, 1080), false},
+		{net.TCPDestination(local, 1080), net.TCPDestination(other, 1080), false},
+		{net.TCPDestination(net.AnyIP, 1080), net.TCPDestination(local, 1080), true},
+		{net.TCPDestination(local, 1080), net.TCPDestination(net.AnyIPv6, 1080), true},
+		{net.UDPDestination(net.AnyIP, 1080), net.UDPDestination(net.AnyIPv6, 1080), true},
+		{net.TCPDestination(net.AnyIP, 1080), net.UDPDestination(net.AnyIP, 1080), false},
+	}
+	for _, c := range cases {
+		a, b := &portBinding{dest: c.a}, &portBinding{dest: c.b}
+		if a.overlaps(b) != c.overlaps || b.overlaps(a) != c.overlaps {
+			t.Error(c.a, " and ", c.b, ": expected overlaps ", c.overlaps)
+		}
+	}
+}
+
+// rangeHandler returns a handler listening on the ports from-to of address.
+func rangeHandler(tag string, network net.Network, address string, from, to net.Port) *fakeHandler {
+	h := &fakeHandler{tag: tag}
+	for port := from; port <= to; port++ {
+		dest := net.Destination{Network: network, Address: net.ParseAddress(address), Port: port}
+		h.bindings = append(h.bindings, &portBinding{tag: tag, dest: dest})
+	}
+	return h
+}
+
+func TestCheckPortConflicts(t *testing.T) {
+	cases := []struct {
+		name      string
+		handlers  []*fakeHandler
+		conflicts []string
+	}{
+		{
+			name: "overlapping ranges",
+			handlers: []*fakeHandler{
+				rangeHandler("a", net.Network_TCP, "127.0.0.1", 41000, 41005),
+				rangeHandler("b", net.Network_TCP, "127.0.0.1", 41004, 41010),
+			},
+			conflicts: []string{
+				"tcp:127.0.0.1:41004 of inbound [a] conflicts with tcp:127.0.0.1:41004 of inbound [b]",
+				"tcp:127.0.0.1:41005 of inbound [a] conflicts with tcp:127.0.0.1:41005 of inbound [b]",
+			},
+		},
+		{
+			name: "adjacent ranges",
+			handlers: []*fakeHandler{
+				rangeHandler("a", net.Network_TCP, "127.0.0.1", 41000, 41005),
+				rangeHandler("b", net.Network_TCP, "127.0.0.1", 41006, 41010),
+			},
+		},
+		{
+			name: "disjoint ranges",
+			handlers: []*fakeHandler{
+				rangeHandler("a", net.Network_TCP, "127.0.0.1", 41000, 41005),
+				rangeHandler("b", net.Network_TCP, "127.0.0.1", 41100, 41105),
+			},
+		},
+		{
+			name: "different addresses",
+			handlers: []*fakeHandler{
+				rangeHandler("a", net.Network_TCP, "127.0.0.1", 41000, 41005),
+				rangeHandler("b", net.Network_TCP, "127.0.0.2", 41000, 41005),
+			},
+		},
+		{
+			name: "any address",
+			handlers: []*fakeHandler{
+				rangeHandler("a", net.Network_TCP, "0.0.0.0", 41000, 41000),
+				rangeHandler("b", net.Network_TCP, "127.0.0.1", 41000, 41000),
+			},
+			conflicts: []string{
+				"tcp:0.0.0.0:41000 of inbound [a] conflicts with tcp:127.0.0.1:41000 of inbound [b]",
+			},
+		},
+		{
+			name: "different networks",
+			handlers: []*fakeHandler{
+				rangeHandler("a", net.Network_TCP, "127.0.0.1", 41000, 41005),
+				rangeHandler("b", net.Network_UDP, "127.0.0.1", 41000, 41005),
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var handlers []inbound.Handler
+			for _, h := range c.handlers {
+				handlers = append(handlers, h)
+			}
+			err := checkPortConflicts(handlers)
+			if len(c.conflicts) == 0 {
+				if err != nil {
+					t.Error("expected no conflicts, got ", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected conflicts")
+			}
+			for _, conflict := range c.conflicts {
+				if !strings.Contains(err.Error(), conflict) {
+					t.Error("expected ", conflict, " in ", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckPortConflictsInUse(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	port := net.Port(l.Addr().(*net.TCPAddr).Port)
+
+	h := rangeHandler("a", net.Network_TCP, "127.0.0.1", port, port)
+	err = checkPortConflicts([]inbound.Handler{h})
+	if err == nil || !strings.Contains(err.Error(), "of inbound [a] is already in use") {
+		t.Fatal("expected the port to be in use, got ", err)
+	}
+	// The listener is found in procfs, owned by the test itself.
+	if runtime.GOOS == "linux" && !strings.Contains(err.Error(), "by this Xray process") {
+		t.Error("expected the owner of the port, got ", err)
+	}
+}
@@ -0,0 +1,38 @@
+//go:build darwin
+// +build darwin
+
+package inbound
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/xtls/xray-core/common/net"
+)
+
+// findPortOwner looks up the process listening on the port with lsof.
+func findPortOwner(network net.Network, port net.Port) string {
+	args := []string{"-nP", "-Fpc"}
+	if network == net.Network_UDP {
+		args = append(args, "-iUDP:"+port.String())
+	} else {
+		args = append(args, "-iTCP:"+port.String(), "-sTCP:LISTEN")
+	}
+	out, err := exec.Command("lsof", args...).Output()
+	if err != nil {
+		return ""
+	}
+	var pid, name string
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.HasPrefix(line, "p") && pid == "":
+			pid = line[1:]
+		case strings.HasPrefix(line, "c") && name == "":
+			name = line[1:]
+		}
+	}
+	if pid == "" {
+		return ""
+	}
+	return "process " + pid + " (" + name + ")"
+}
@@ -0,0 +1,63 @@
+//go:build linux
+// +build linux
+
+package inbound
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xtls/xray-core/common/net"
+)
+
+// findPortOwner looks up the process listening on the port through procfs.
+func findPortOwner(network net.Network, port net.Port) string {
+	tables := []string{"/proc/net/tcp", "/proc/net/tcp6"}
+	listenState := "0A"
+	if network == net.Network_UDP {
+		tables = []string{"/proc/net/udp", "/proc/net/udp6"}
+		listenState = "07"
+	}
+
+	inodes := make(map[string]bool)
+	for _, table := range tables {
+		f, err := os.Open(table)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Scan() // header
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 10 || fields[3] != listenState {
+				continue
+			}
+			idx := strings.LastIndexByte(fields[1], ':')
+			if p, err := strconv.ParseUint(fields[1][idx+1:], 16, 16); err == nil && net.Port(p) == port {
+				inodes["socket:["+fields[9]+"]"] = true
+			}
+		}
+		f.Close()
+	}
+	if len(inodes) == 0 {
+		return ""
+	}
+
+	fds, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	for _, fd := range fds {
+		link, err := os.Readlink(fd)
+		if err != nil || !inodes[link] {
+			continue
+		}
+		pid := strings.Split(fd, "/")[2]
+		if pid == strconv.Itoa(os.Getpid()) {
+			return "this Xray process"
+		}
+		comm, _ := os.ReadFile(filepath.Join("/proc", pid, "comm"))
+		return "process " + pid + " (" + strings.TrimSpace(string(comm)) + ")"
+	}
+	return "another process"
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package inbound
+
+import (
+	"github.com/xtls/xray-core/common/net"
+)
+
+// findPortOwner is not supported on this platform.
+func findPortOwner(network net.Network, port net.Port) string {
+	return ""
+}
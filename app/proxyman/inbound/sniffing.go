@@ -0,0 +1,101 @@
+package inbound
+
+import (
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/strmatcher"
+)
+
+type protocolExclusion struct {
+	protocol string
+	matcher  *strmatcher.MatcherGroup
+}
+
+// domainExcluder matches sniffed domains against the exclusion lists of an inbound.
+// Unlike SniffingRequest.ExcludeForDomain it is compiled once per inbound.
+type domainExcluder struct {
+	all       *strmatcher.MatcherGroup
+	protocols []protocolExclusion
+}
+
+// Excluded implements session.DomainExcluder.
+func (e *domainExcluder) Excluded(protocol string, domain string) bool {
+	domain = strings.ToLower(domain)
+	if e.all != nil && len(e.all.Match(domain)) > 0 {
+		return true
+	}
+	for _, p := range e.protocols {
+		if strings.HasPrefix(protocol, p.protocol) && len(p.matcher.Match(domain)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// newDomainMatcher compiles exclusion entries. Entries may carry a "full:", "domain:",
+// "keyword:" or "regexp:" prefix, entries without one must match exactly.
+func newDomainMatcher(domains []string) (*strmatcher.MatcherGroup, error) {
+	g := new(strmatcher.MatcherGroup)
+	for _, d := range domains {
+		t := strmatcher.Full
+		switch {
+		case strings.HasPrefix(d, "full:"):
+			d = d[5:]
+		case strings.HasPrefix(d, "domain:"):
+			t, d = strmatcher.Domain, d[7:]
+		case strings.HasPrefix(d, "keyword:"):
+			t, d = strmatcher.Substr, d[8:]
+		case strings.HasPrefix(d, "regexp:"):
+			t, d = strmatcher.Regex, d[7:]
+		}
+		m, err := t.New(d)
+		if err != nil {
+			return nil, errors.New("invalid excluded domain: ", d).Base(err)
+		}
+		g.Add(m)
+	}
+	return g, nil
+}
+
+// newSniffingRequest builds the sniffing request shared by all connections of an inbound.
+func newSniffingRequest(config *proxyman.SniffingConfig) (*session.SniffingRequest, error) {
+	if config == nil {
+		return nil, nil
+	}
+	request := &session.SniffingRequest{
+		Enabled:                        config.Enabled,
+		OverrideDestinationForProtocol: config.DestinationOverride,
+		MetadataOnly:                   config.MetadataOnly,
+		RouteOnly:                      config.RouteOnly,
+		Timeout:                        time.Duration(config.Timeout),
+		MaxBytes:                       int32(config.MaxBytes),
+	}
+	if len(config.DomainsExcluded) == 0 && len(config.ProtocolDomainsExcluded) == 0 {
+		return request, nil
+	}
+
+	excluder := new(domainExcluder)
+	if len(config.DomainsExcluded) > 0 {
+		m, err := newDomainMatcher(config.DomainsExcluded)
+		if err != nil {
+			return nil, err
+		}
+		excluder.all = m
+	}
+	for _, e := range config.ProtocolDomainsExcluded {
+		m, err := newDomainMatcher(e.Domains)
+		if err != nil {
+			return nil, errors.New("failed to build exclusions for ", e.Protocol).Base(err)
+		}
+		excluder.protocols = append(excluder.protocols, protocolExclusion{
+			protocol: e.Protocol,
+			matcher:  m,
+		})
+	}
+	request.DomainExcluder = excluder
+	return request, nil
+}
@@ -0,0 +1,49 @@
+package inbound
+
+import (
+	"testing"
+
+	"github.com/xtls/xray-core/app/proxyman"
+)
+
+func TestSniffingDomainExclusions(t *testing.T) {
+	request, err := newSniffingRequest(&proxyman.SniffingConfig{
+		Enabled:         true,
+		DomainsExcluded: []string{"exact.example.org", "domain:example.com", "keyword:game", `regexp:^cdn\d+\.`},
+		ProtocolDomainsExcluded: []*proxyman.SniffingExclusion{
+			{Protocol: "tls", Domains: []string{"full:pinned.example.net"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		protocol string
+		domain   string
+		excluded bool
+	}{
+		{"http", "exact.example.org", true},
+		{"http", "sub.exact.example.org", false},
+		{"tls", "WWW.Example.COM", true},
+		{"quic", "mygame.net", true},
+		{"http", "cdn12.example.net", true},
+		{"tls", "pinned.example.net", true},
+		{"http", "pinned.example.net", false},
+		{"quic", "pinned.example.net", false},
+		{"tls", "sub.pinned.example.net", false},
+		{"tls", "example.net", false},
+	}
+	for _, tt := range tests {
+		if got := request.DomainExcluder.Excluded(tt.protocol, tt.domain); got != tt.excluded {
+			t.Errorf("Excluded(%q, %q) = %v, want %v", tt.protocol, tt.domain, got, tt.excluded)
+		}
+	}
+
+	if request, err := newSniffingRequest(&proxyman.SniffingConfig{Enabled: true}); err != nil || request.DomainExcluder != nil {
+		t.Error("expected no excluder without exclusions, got ", request, err)
+	}
+	if _, err := newSniffingRequest(&proxyman.SniffingConfig{DomainsExcluded: []string{"regexp:("}}); err == nil {
+		t.Error("expected an invalid regexp to fail")
+	}
+}
@@ -0,0 +1,113 @@
+package inbound
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/features/inbound"
+	"google.golang.org/protobuf/proto"
+)
+
+// listenerTaker is implemented by handlers which can take over the listeners of the handler they
+// replace.
+type listenerTaker interface {
+	// takeOver takes the listeners of old if both listen the same way, and returns whether it did.
+	takeOver(old inbound.Handler) bool
+}
+
+// listensLike returns whether h listens on the same addresses with the same transport as o, and
+// only with stream workers, whose listeners can be handed over.
+func (h *AlwaysOnInboundHandler) listensLike(o *AlwaysOnInboundHandler) bool {
+	a, b := h.receiver, o.receiver
+	if a == nil || b == nil || a.AllocationStrategy != nil || b.AllocationStrategy != nil {
+		return false
+	}
+	if !proto.Equal(a.Listen, b.Listen) || !proto.Equal(a.PortList, b.PortList) ||
+		!proto.Equal(a.StreamSettings, b.StreamSettings) ||
+		a.ReceiveOriginalDestination != b.ReceiveOriginalDestination {
+		return false
+	}
+	if len(h.workers) == 0 || len(h.workers) != len(o.workers) {
+		return false
+	}
+	for i := range h.workers {
+		w, ok := h.workers[i].(*tcpWorker)
+		if !ok {
+			return false
+		}
+		ow, ok := o.workers[i].(*tcpWorker)
+		if !ok || ow.hub == nil || w.port != ow.port || w.address.String() != ow.address.String() {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *AlwaysOnInboundHandler) takeOver(old inbound.Handler) bool {
+	o, ok := old.(*AlwaysOnInboundHandler)
+	if !ok || !h.listensLike(o) {
+		return false
+	}
+	for i := range o.workers {
+		o.workers[i].(*tcpWorker).handOver(h.workers[i].(*tcpWorker))
+	}
+	return true
+}
+
+// ReplaceHandler implements inbound.Replacer. A handler whose listeners were taken over is retired:
+// its connections in progress are still listed and swept under its tag, and it's closed once the
+// last of them ends.
+func (m *Manager) ReplaceHandler(ctx context.Context, handler inbound.Handler) (bool, error) {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	tag := handler.Tag()
+	old, found := m.taggedHandlers[tag]
+	if !found {
+		return false, errors.New("handler not found: ", tag)
+	}
+	takenOver := false
+	if taker, ok := handler.(listenerTaker); ok && m.running {
+		takenOver = taker.takeOver(old)
+	}
+	if takenOver {
+		m.retiring = append(m.retiring, old)
+	} else if err := old.Close(); err != nil {
+		errors.LogWarningInner(ctx, err, "failed to close handler ", tag)
+	}
+	m.taggedHandlers[tag] = handler
+	m.pauseIfPaused(handler)
+
+	if m.running {
+		return takenOver, handler.Start()
+	}
+	return takenOver, nil
+}
+
+// retiringFor returns the retiring handlers with the given tag, or all of them if tag is empty.
+// The caller must hold m.access.
+func (m *Manager) retiringFor(tag string) []inbound.Handler {
+	var handlers []inbound.Handler
+	for _, handler := range m.retiring {
+		if tag == "" || handler.Tag() == tag {
+			handlers = append(handlers, handler)
+		}
+	}
+	return handlers
+}
+
+// closeRetired closes the retired handlers whose connections have all ended. The caller must hold
+// m.access.
+func (m *Manager) closeRetired() {
+	retiring := m.retiring[:0]
+	for _, handler := range m.retiring {
+		if holder, ok := handler.(connectionHolder); ok && holder.connections().n.Load() > 0 {
+			retiring = append(retiring, handler)
+			continue
+		}
+		if err := handler.Close(); err != nil {
+			errors.LogDebugInner(context.Background(), err, "failed to close retired handler ", handler.Tag())
+		}
+	}
+	m.retiring = retiring
+}
@@ -0,0 +1,96 @@
+package inbound
+
+import (
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/features/inbound"
+)
+
+// udpSessionHolder is implemented by handlers that run UDP workers.
+type udpSessionHolder interface {
+	udpSessions() []inbound.UDPSession
+	flushUDPSessions(source net.Address) int
+}
+
+func collectUDPSessions(workers []worker) []inbound.UDPSession {
+	var sessions []inbound.UDPSession
+	for _, w := range workers {
+		if uw, ok := w.(*udpWorker); ok {
+			sessions = append(sessions, uw.sessions()...)
+		}
+	}
+	return sessions
+}
+
+func flushUDPSessions(workers []worker, source net.Address) int {
+	count := 0
+	for _, w := range workers {
+		if uw, ok := w.(*udpWorker); ok {
+			count += uw.flush(source)
+		}
+	}
+	return count
+}
+
+func (h *AlwaysOnInboundHandler) udpSessions() []inbound.UDPSession {
+	return collectUDPSessions(h.workers)
+}
+
+func (h *AlwaysOnInboundHandler) flushUDPSessions(source net.Address) int {
+	return flushUDPSessions(h.workers, source)
+}
+
+func (h *DynamicInboundHandler) udpSessions() []inbound.UDPSession {
+	h.workerMutex.RLock()
+	defer h.workerMutex.RUnlock()
+	return collectUDPSessions(h.worker)
+}
+
+func (h *DynamicInboundHandler) flushUDPSessions(source net.Address) int {
+	h.workerMutex.RLock()
+	defer h.workerMutex.RUnlock()
+	return flushUDPSessions(h.worker, source)
+}
+
+// handlersFor returns the handlers with the given tag, or all handlers if tag is empty.
+// Caller must hold m.access.
+func (m *Manager) handlersFor(tag string) []inbound.Handler {
+	if tag != "" {
+		if handler, found := m.taggedHandlers[tag]; found {
+			return []inbound.Handler{handler}
+		}
+		return nil
+	}
+	handlers := append([]inbound.Handler(nil), m.untaggedHandler...)
+	for _, handler := range m.taggedHandlers {
+		handlers = append(handlers, handler)
+	}
+	return handlers
+}
+
+// GetUDPSessions implements inbound.UDPSessionTable.
+func (m *Manager) GetUDPSessions(tag string) []inbound.UDPSession {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	var sessions []inbound.UDPSession
+	for _, handler := range m.handlersFor(tag) {
+		if holder, ok := handler.(udpSessionHolder); ok {
+			sessions = append(sessions, holder.udpSessions()...)
+		}
+	}
+	return sessions
+}
+
+// FlushUDPSessions implements inbound.UDPSessionTable.
+func (m *Manager) FlushUDPSessions(tag string, source net.Address) int {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	count := 0
+	for _, handler := range m.handlersFor(tag) {
+		if holder, ok := handler.(udpSessionHolder); ok {
+			count += holder.flushUDPSessions(source)
+		}
+	}
+	return count
+}
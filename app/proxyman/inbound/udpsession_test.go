@@ -0,0 +1,63 @@
+package inbound
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common/net"
+)
+
+func TestUDPSessionTable(t *testing.T) {
+	w := &udpWorker{tag: "udp", address: net.LocalHostIP, port: 5353, activeConn: make(map[connID]*udpConn)}
+	open := func(source string, port net.Port, idle time.Duration) *udpConn {
+		conn, _ := w.getConnection(connID{src: net.UDPDestination(net.ParseAddress(source), port)})
+		atomic.StoreInt64(&conn.lastActivityTime, time.Now().Add(-idle).Unix())
+		return conn
+	}
+	game := open("192.0.2.1", 5000, 0)
+	atomic.StoreInt64(&game.uplinkBytes, 100)
+	atomic.StoreInt64(&game.downlinkBytes, 2000)
+	voip := open("192.0.2.1", 5001, 30*time.Second)
+	other := open("192.0.2.2", 5000, 0)
+
+	m := newTestManager(false)
+	m.taggedHandlers["udp"] = &AlwaysOnInboundHandler{tag: "udp", workers: []worker{w}}
+
+	sessions := m.GetUDPSessions("udp")
+	if len(sessions) != 3 || len(m.GetUDPSessions("")) != 3 || len(m.GetUDPSessions("missing")) != 0 {
+		t.Fatal("unexpected sessions: ", sessions)
+	}
+	for _, s := range sessions {
+		switch s.Source.Port {
+		case 5000:
+			if s.Source.Address.String() == "192.0.2.1" && (s.Uplink != 100 || s.Downlink != 2000) {
+				t.Error("unexpected bytes of session: ", s)
+			}
+			if s.TTL < udpSessionTimeout-time.Second {
+				t.Error("unexpected TTL of active session: ", s.TTL)
+			}
+		case 5001:
+			if ttl := udpSessionTimeout - 30*time.Second; s.TTL > ttl || s.TTL < ttl-time.Second {
+				t.Error("TTL of session idle for 30s is ", s.TTL, ", want ", ttl)
+			}
+		}
+	}
+
+	if n := m.FlushUDPSessions("udp", net.ParseAddress("192.0.2.1")); n != 2 {
+		t.Error("flushed ", n, " sessions of 192.0.2.1, want 2")
+	}
+	if !game.done.Done() || !voip.done.Done() || other.done.Done() {
+		t.Error("expected only the sessions of 192.0.2.1 closed")
+	}
+	if sessions := m.GetUDPSessions("udp"); len(sessions) != 1 || sessions[0].Source.Address.String() != "192.0.2.2" {
+		t.Error("unexpected sessions after flushing: ", sessions)
+	}
+
+	if n := m.FlushUDPSessions("", nil); n != 1 || !other.done.Done() {
+		t.Error("expected flushing all sessions to close the one left, flushed ", n)
+	}
+	if sessions := m.GetUDPSessions(""); len(sessions) != 0 {
+		t.Error("unexpected sessions after flushing all: ", sessions)
+	}
+}
@@ -6,9 +6,9 @@ import (
 	"sync/atomic"
 	"time"
 
-	"github.com/xtls/xray-core/app/proxyman"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/crash"
 	c "github.com/xtls/xray-core/common/ctx"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
@@ -16,12 +16,14 @@ import (
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/signal/done"
 	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/features/inbound"
 	"github.com/xtls/xray-core/features/routing"
 	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/tcp"
+	"github.com/xtls/xray-core/transport/internet/tls"
 	"github.com/xtls/xray-core/transport/internet/udp"
 	"github.com/xtls/xray-core/transport/pipe"
 )
@@ -41,11 +43,18 @@ type tcpWorker struct {
 	recvOrigDest    bool
 	tag             string
 	dispatcher      routing.Dispatcher
-	sniffingConfig  *proxyman.SniffingConfig
+	sniffingRequest *session.SniffingRequest
 	uplinkCounter   stats.Counter
 	downlinkCounter stats.Counter
+	handshakes      *proxy.HandshakeStats
+	conns           *connCounter
+	drainFlag
 
-	hub internet.Listener
+	hub     internet.Listener
+	release func()
+	// current is the worker the connections accepted by hub go to, which is another one once a
+	// replacing handler takes hub over.
+	current *atomic.Pointer[tcpWorker]
 
 	ctx context.Context
 }
@@ -58,9 +67,16 @@ func getTProxyType(s *internet.MemoryStreamConfig) internet.SocketConfig_TProxyM
 }
 
 func (w *tcpWorker) callback(conn stat.Connection) {
+	if w.refusing() {
+		conn.Close()
+		return
+	}
 	ctx, cancel := context.WithCancel(w.ctx)
 	sid := session.NewID()
 	ctx = c.ContextWithID(ctx, sid)
+	defer crash.Recover(ctx, "inbound")
+	w.conns.inc()
+	defer w.conns.dec()
 
 	outbounds := []*session.Outbound{{}}
 	if w.recvOrigDest {
@@ -81,7 +97,9 @@ func (w *tcpWorker) callback(conn stat.Connection) {
 		}
 	}
 	ctx = session.ContextWithOutbounds(ctx, outbounds)
+	ctx = internet.ContextWithLoopSource(ctx, net.DestinationFromAddr(conn.RemoteAddr()))
 
+	rawConn := conn
 	if w.uplinkCounter != nil || w.downlinkCounter != nil {
 		conn = &stat.CounterConnection{
 			Connection:   conn,
@@ -97,20 +115,23 @@ func (w *tcpWorker) callback(conn stat.Connection) {
 	})
 
 	content := new(session.Content)
-	if w.sniffingConfig != nil {
-		content.SniffingRequest.Enabled = w.sniffingConfig.Enabled
-		content.SniffingRequest.OverrideDestinationForProtocol = w.sniffingConfig.DestinationOverride
-		content.SniffingRequest.ExcludeForDomain = w.sniffingConfig.DomainsExcluded
-		content.SniffingRequest.MetadataOnly = w.sniffingConfig.MetadataOnly
-		content.SniffingRequest.RouteOnly = w.sniffingConfig.RouteOnly
+	if w.sniffingRequest != nil {
+		content.SniffingRequest = *w.sniffingRequest
 	}
 	ctx = session.ContextWithContent(ctx, content)
+	defer w.conns.untrack(w.conns.track(ctx, conn, cancel))
 
+	defer func() {
+		cancel()
+		conn.Close()
+	}()
 	if err := w.proxy.Process(ctx, net.Network_TCP, conn, w.dispatcher); err != nil {
 		errors.LogInfoInner(ctx, err, "connection ends")
+		// Without a handshake guard, the TLS handshake is left to the first read of the proxy.
+		if tlsConn, ok := rawConn.(*tls.Conn); ok && !tlsConn.ConnectionState().HandshakeComplete {
+			w.handshakes.Count(w.tag, proxy.HandshakeTLS)
+		}
 	}
-	cancel()
-	conn.Close()
 }
 
 func (w *tcpWorker) Proxy() proxy.Inbound {
@@ -118,23 +139,50 @@ func (w *tcpWorker) Proxy() proxy.Inbound {
 }
 
 func (w *tcpWorker) Start() error {
-	ctx := context.Background()
+	if w.current != nil {
+		// The listener was taken over from the worker of a replaced handler.
+		w.current.Store(w)
+		return nil
+	}
+	current := new(atomic.Pointer[tcpWorker])
+	current.Store(w)
+	ctx := internet.ContextWithHandshakeFailure(context.Background(), func(net.Conn, error) {
+		w := current.Load()
+		w.handshakes.Count(w.tag, proxy.HandshakeTLS)
+	})
 	hub, err := internet.ListenTCP(ctx, w.address, w.port, w.stream, func(conn stat.Connection) {
-		go w.callback(conn)
+		go current.Load().callback(conn)
 	})
 	if err != nil {
-		return errors.New("failed to listen TCP on ", w.port).AtWarning().Base(err)
+		return errors.New("failed to listen TCP on ", w.port).AtWarning().Base(errors.Diagnose(errors.CodeBindFailed, err))
 	}
 	w.hub = hub
+	w.current = current
+	dest := net.TCPDestination(w.address, w.port)
+	if w.stream != nil && w.stream.ProtocolName == "mkcp" {
+		dest.Network = net.Network_UDP
+	}
+	release := internet.ReserveLocalAddress(dest)
+	unregister := internet.RegisterInboundAddress(w.tag, dest)
+	w.release = func() {
+		release()
+		unregister()
+	}
 	return nil
 }
 
 func (w *tcpWorker) Close() error {
 	var errs []interface{}
+	if w.release != nil {
+		w.release()
+	}
 	if w.hub != nil {
 		if err := common.Close(w.hub); err != nil {
 			errs = append(errs, err)
 		}
+	}
+	// A worker which handed its listener over has been started as well.
+	if w.current != nil {
 		if err := common.Close(w.proxy); err != nil {
 			errs = append(errs, err)
 		}
@@ -146,12 +194,24 @@ func (w *tcpWorker) Close() error {
 	return nil
 }
 
+// handOver gives the listener of w to next, which is not started yet. Connections accepted from
+// then on go to next once it starts, while those in progress stay with w.
+func (w *tcpWorker) handOver(next *tcpWorker) {
+	next.hub, next.release, next.current = w.hub, w.release, w.current
+	w.hub, w.release = nil, nil
+}
+
 func (w *tcpWorker) Port() net.Port {
 	return w.port
 }
 
+// udpSessionTimeout is how long an idle UDP session is kept in the table.
+const udpSessionTimeout = 2 * time.Minute
+
 type udpConn struct {
 	lastActivityTime int64 // in seconds
+	uplinkBytes      int64
+	downlinkBytes    int64
 	reader           buf.Reader
 	writer           buf.Writer
 	output           func([]byte) (int, error)
@@ -178,6 +238,7 @@ func (c *udpConn) ReadMultiBuffer() (buf.MultiBuffer, error) {
 		return nil, err
 	}
 	c.updateActivity()
+	atomic.AddInt64(&c.uplinkBytes, int64(mb.Len()))
 
 	if c.uplink != nil {
 		c.uplink.Add(int64(mb.Len()))
@@ -193,6 +254,7 @@ func (c *udpConn) Read(buf []byte) (int, error) {
 // Write implements io.Writer.
 func (c *udpConn) Write(buf []byte) (int, error) {
 	n, err := c.output(buf)
+	atomic.AddInt64(&c.downlinkBytes, int64(n))
 	if c.downlink != nil {
 		c.downlink.Add(int64(n))
 	}
@@ -243,12 +305,15 @@ type udpWorker struct {
 	tag             string
 	stream          *internet.MemoryStreamConfig
 	dispatcher      routing.Dispatcher
-	sniffingConfig  *proxyman.SniffingConfig
+	sniffingRequest *session.SniffingRequest
 	uplinkCounter   stats.Counter
 	downlinkCounter stats.Counter
+	conns           *connCounter
+	drainFlag
 
 	checker    *task.Periodic
 	activeConn map[connID]*udpConn
+	release    func()
 
 	ctx  context.Context
 	cone bool
@@ -287,6 +352,14 @@ func (w *udpWorker) getConnection(id connID) (*udpConn, bool) {
 	return conn, false
 }
 
+// hasConnection returns true if the session id is in progress.
+func (w *udpWorker) hasConnection(id connID) bool {
+	w.RLock()
+	defer w.RUnlock()
+	conn, found := w.activeConn[id]
+	return found && !conn.done.Done()
+}
+
 func (w *udpWorker) callback(b *buf.Buffer, source net.Destination, originalDest net.Destination) {
 	id := connID{
 		src: source,
@@ -297,6 +370,10 @@ func (w *udpWorker) callback(b *buf.Buffer, source net.Destination, originalDest
 		}
 		b.UDP = &originalDest
 	}
+	if w.refusing() && !w.hasConnection(id) {
+		b.Release()
+		return
+	}
 	conn, existing := w.getConnection(id)
 
 	// payload will be discarded in pipe is full.
@@ -315,28 +392,32 @@ func (w *udpWorker) callback(b *buf.Buffer, source net.Destination, originalDest
 				outbounds[0].Target = originalDest
 			}
 			ctx = session.ContextWithOutbounds(ctx, outbounds)
+			ctx = internet.ContextWithLoopSource(ctx, source)
 			ctx = session.ContextWithInbound(ctx, &session.Inbound{
 				Source:  source,
 				Gateway: net.UDPDestination(w.address, w.port),
 				Tag:     w.tag,
 			})
 			content := new(session.Content)
-			if w.sniffingConfig != nil {
-				content.SniffingRequest.Enabled = w.sniffingConfig.Enabled
-				content.SniffingRequest.OverrideDestinationForProtocol = w.sniffingConfig.DestinationOverride
-				content.SniffingRequest.MetadataOnly = w.sniffingConfig.MetadataOnly
-				content.SniffingRequest.RouteOnly = w.sniffingConfig.RouteOnly
+			if w.sniffingRequest != nil {
+				content.SniffingRequest = *w.sniffingRequest
 			}
+			content.OwnTransport = internet.IsOwnTransport(ctx)
 			ctx = session.ContextWithContent(ctx, content)
+			defer crash.Recover(ctx, "inbound")
+			w.conns.inc()
+			defer w.conns.dec()
+			defer func() {
+				conn.Close()
+				// conn not removed by checker TODO may be lock worker here is better
+				if !conn.inactive {
+					conn.setInactive()
+					w.removeConn(id)
+				}
+			}()
 			if err := w.proxy.Process(ctx, net.Network_UDP, conn, w.dispatcher); err != nil {
 				errors.LogInfoInner(ctx, err, "connection ends")
 			}
-			conn.Close()
-			// conn not removed by checker TODO may be lock worker here is better
-			if !conn.inactive {
-				conn.setInactive()
-				w.removeConn(id)
-			}
 		}()
 	}
 }
@@ -364,7 +445,7 @@ func (w *udpWorker) clean() error {
 	}
 
 	for addr, conn := range w.activeConn {
-		if nowSec-atomic.LoadInt64(&conn.lastActivityTime) > 2*60 {
+		if nowSec-atomic.LoadInt64(&conn.lastActivityTime) > int64(udpSessionTimeout/time.Second) {
 			if !conn.inactive {
 				conn.setInactive()
 				delete(w.activeConn, addr)
@@ -380,12 +461,60 @@ func (w *udpWorker) clean() error {
 	return nil
 }
 
+// sessions returns a snapshot of the active sessions of the worker.
+func (w *udpWorker) sessions() []inbound.UDPSession {
+	now := time.Now()
+	w.RLock()
+	defer w.RUnlock()
+
+	sessions := make([]inbound.UDPSession, 0, len(w.activeConn))
+	for id, conn := range w.activeConn {
+		if conn.done.Done() {
+			continue
+		}
+		idle := now.Sub(time.Unix(atomic.LoadInt64(&conn.lastActivityTime), 0))
+		ttl := udpSessionTimeout - idle
+		if ttl < 0 {
+			ttl = 0
+		}
+		sessions = append(sessions, inbound.UDPSession{
+			Tag:         w.tag,
+			Source:      id.src,
+			Destination: id.dest,
+			Uplink:      atomic.LoadInt64(&conn.uplinkBytes),
+			Downlink:    atomic.LoadInt64(&conn.downlinkBytes),
+			TTL:         ttl,
+		})
+	}
+	return sessions
+}
+
+// flush closes the sessions from source, or all sessions if source is nil.
+func (w *udpWorker) flush(source net.Address) int {
+	w.Lock()
+	defer w.Unlock()
+
+	count := 0
+	for id, conn := range w.activeConn {
+		if source != nil && id.src.Address != source {
+			continue
+		}
+		if !conn.inactive {
+			conn.setInactive()
+			delete(w.activeConn, id)
+		}
+		conn.Close()
+		count++
+	}
+	return count
+}
+
 func (w *udpWorker) Start() error {
 	w.activeConn = make(map[connID]*udpConn, 16)
 	ctx := context.Background()
 	h, err := udp.ListenUDP(ctx, w.address, w.port, w.stream, udp.HubCapacity(256))
 	if err != nil {
-		return err
+		return errors.Diagnose(errors.CodeBindFailed, err)
 	}
 
 	w.cone = w.ctx.Value("cone").(bool)
@@ -396,6 +525,13 @@ func (w *udpWorker) Start() error {
 	}
 
 	w.hub = h
+	dest := net.UDPDestination(w.address, w.port)
+	release := internet.ReserveLocalAddress(dest)
+	unregister := internet.RegisterInboundAddress(w.tag, dest)
+	w.release = func() {
+		release()
+		unregister()
+	}
 	go w.handlePackets()
 	return nil
 }
@@ -406,6 +542,9 @@ func (w *udpWorker) Close() error {
 
 	var errs []interface{}
 
+	if w.release != nil {
+		w.release()
+	}
 	if w.hub != nil {
 		if err := w.hub.Close(); err != nil {
 			errs = append(errs, err)
@@ -442,9 +581,11 @@ type dsWorker struct {
 	stream          *internet.MemoryStreamConfig
 	tag             string
 	dispatcher      routing.Dispatcher
-	sniffingConfig  *proxyman.SniffingConfig
+	sniffingRequest *session.SniffingRequest
 	uplinkCounter   stats.Counter
 	downlinkCounter stats.Counter
+	conns           *connCounter
+	drainFlag
 
 	hub internet.Listener
 
@@ -452,9 +593,16 @@ type dsWorker struct {
 }
 
 func (w *dsWorker) callback(conn stat.Connection) {
+	if w.refusing() {
+		conn.Close()
+		return
+	}
 	ctx, cancel := context.WithCancel(w.ctx)
 	sid := session.NewID()
 	ctx = c.ContextWithID(ctx, sid)
+	defer crash.Recover(ctx, "inbound")
+	w.conns.inc()
+	defer w.conns.dec()
 
 	if w.uplinkCounter != nil || w.downlinkCounter != nil {
 		conn = &stat.CounterConnection{
@@ -471,22 +619,21 @@ func (w *dsWorker) callback(conn stat.Connection) {
 	})
 
 	content := new(session.Content)
-	if w.sniffingConfig != nil {
-		content.SniffingRequest.Enabled = w.sniffingConfig.Enabled
-		content.SniffingRequest.OverrideDestinationForProtocol = w.sniffingConfig.DestinationOverride
-		content.SniffingRequest.ExcludeForDomain = w.sniffingConfig.DomainsExcluded
-		content.SniffingRequest.MetadataOnly = w.sniffingConfig.MetadataOnly
-		content.SniffingRequest.RouteOnly = w.sniffingConfig.RouteOnly
+	if w.sniffingRequest != nil {
+		content.SniffingRequest = *w.sniffingRequest
 	}
 	ctx = session.ContextWithContent(ctx, content)
+	defer w.conns.untrack(w.conns.track(ctx, conn, cancel))
 
+	defer func() {
+		cancel()
+		if err := conn.Close(); err != nil {
+			errors.LogInfoInner(ctx, err, "failed to close connection")
+		}
+	}()
 	if err := w.proxy.Process(ctx, net.Network_UNIX, conn, w.dispatcher); err != nil {
 		errors.LogInfoInner(ctx, err, "connection ends")
 	}
-	cancel()
-	if err := conn.Close(); err != nil {
-		errors.LogInfoInner(ctx, err, "failed to close connection")
-	}
 }
 
 func (w *dsWorker) Proxy() proxy.Inbound {
@@ -503,7 +650,7 @@ func (w *dsWorker) Start() error {
 		go w.callback(conn)
 	})
 	if err != nil {
-		return errors.New("failed to listen Unix Domain Socket on ", w.address).AtWarning().Base(err)
+		return errors.New("failed to listen Unix Domain Socket on ", w.address).AtWarning().Base(errors.Diagnose(errors.CodeBindFailed, err))
 	}
 	w.hub = hub
 	return nil
@@ -525,3 +672,84 @@ func (w *dsWorker) Close() error {
 
 	return nil
 }
+
+// acceptWorker processes the connections an Acceptor accepts by itself, such as those of a TUN
+// device.
+type acceptWorker struct {
+	proxy           proxy.Acceptor
+	tag             string
+	dispatcher      routing.Dispatcher
+	sniffingRequest *session.SniffingRequest
+	uplinkCounter   stats.Counter
+	downlinkCounter stats.Counter
+	conns           *connCounter
+	drainFlag
+
+	ctx context.Context
+}
+
+func (w *acceptWorker) callback(conn stat.Connection, dest net.Destination) {
+	if w.refusing() {
+		conn.Close()
+		return
+	}
+	ctx, cancel := context.WithCancel(w.ctx)
+	sid := session.NewID()
+	ctx = c.ContextWithID(ctx, sid)
+	defer crash.Recover(ctx, "inbound")
+	w.conns.inc()
+	defer w.conns.dec()
+
+	ctx = session.ContextWithOutbounds(ctx, []*session.Outbound{{Target: dest}})
+
+	if w.uplinkCounter != nil || w.downlinkCounter != nil {
+		conn = &stat.CounterConnection{
+			Connection:   conn,
+			ReadCounter:  w.uplinkCounter,
+			WriteCounter: w.downlinkCounter,
+		}
+	}
+	ctx = session.ContextWithInbound(ctx, &session.Inbound{
+		Source: net.DestinationFromAddr(conn.RemoteAddr()),
+		Tag:    w.tag,
+		Conn:   conn,
+	})
+
+	content := new(session.Content)
+	if w.sniffingRequest != nil {
+		content.SniffingRequest = *w.sniffingRequest
+	}
+	ctx = session.ContextWithContent(ctx, content)
+	defer w.conns.untrack(w.conns.track(ctx, conn, cancel))
+
+	defer func() {
+		cancel()
+		if err := conn.Close(); err != nil {
+			errors.LogInfoInner(ctx, err, "failed to close connection")
+		}
+	}()
+	if err := w.proxy.Process(ctx, dest.Network, conn, w.dispatcher); err != nil {
+		errors.LogInfoInner(ctx, err, "connection ends")
+	}
+}
+
+func (w *acceptWorker) Proxy() proxy.Inbound {
+	return w.proxy
+}
+
+func (w *acceptWorker) Port() net.Port {
+	return net.Port(0)
+}
+
+func (w *acceptWorker) Start() error {
+	if err := w.proxy.Accept(func(conn stat.Connection, dest net.Destination) {
+		go w.callback(conn, dest)
+	}); err != nil {
+		return errors.New("failed to accept connections for inbound ", w.tag).AtWarning().Base(err)
+	}
+	return nil
+}
+
+func (w *acceptWorker) Close() error {
+	return w.proxy.Close()
+}
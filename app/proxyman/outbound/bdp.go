@@ -0,0 +1,186 @@
+package outbound
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+const (
+	// maxAutoBufferSize caps the buffers sized by bandwidth-delay product.
+	maxAutoBufferSize = 16 * 1024 * 1024
+	// minTransferSample is the least data a connection has to carry for its throughput to
+	// be taken into account. Shorter transfers end before the congestion window opens up.
+	minTransferSample = 256 * 1024
+	// windowHintThreshold is the bandwidth-delay product from which TCP buffers of outgoing
+	// connections are set. Setting them turns off the autotuning of the system, which
+	// handles smaller products well enough.
+	windowHintThreshold = 4 * 1024 * 1024
+)
+
+// bdpEstimator estimates the bandwidth-delay product of the path behind an outbound, from the
+// connections it has made.
+type bdpEstimator struct {
+	sync.Mutex
+	rtt        time.Duration // smoothed time to dial
+	throughput float64       // smoothed downlink throughput, in bytes per second
+}
+
+// observeRTT records the time a dial took. Handshakes of the stream settings make it a few
+// round trips, which errs on the side of larger buffers.
+func (e *bdpEstimator) observeRTT(d time.Duration) {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.rtt == 0 {
+		e.rtt = d
+	} else {
+		e.rtt += (d - e.rtt) / 8
+	}
+}
+
+// observeTransfer records that size bytes arrived over d. It returns the ratio of the throughput
+// of the transfer to the smoothed one before it, or 0 if either is unknown.
+func (e *bdpEstimator) observeTransfer(size int64, d time.Duration) float64 {
+	if size < minTransferSample || d <= 0 {
+		return 0
+	}
+	sample := float64(size) / d.Seconds()
+
+	e.Lock()
+	defer e.Unlock()
+
+	if e.throughput == 0 {
+		e.throughput = sample
+		return 0
+	}
+	ratio := sample / e.throughput
+	e.throughput += (sample - e.throughput) / 8
+	return ratio
+}
+
+// product returns the estimated bandwidth-delay product in bytes, or 0 if nothing was measured yet.
+func (e *bdpEstimator) product() int64 {
+	e.Lock()
+	defer e.Unlock()
+
+	bdp := e.throughput * e.rtt.Seconds()
+	if bdp > maxAutoBufferSize {
+		return maxAutoBufferSize
+	}
+	return int64(bdp)
+}
+
+// bufferSize returns the size of buffers keeping the path busy, and at least min. Twice the
+// product leaves room for the throughput to grow.
+func (e *bdpEstimator) bufferSize(min int32) int32 {
+	size := 2 * e.product()
+	if size > maxAutoBufferSize {
+		size = maxAutoBufferSize
+	}
+	if size < int64(min) {
+		return min
+	}
+	return int32(size)
+}
+
+// dialOnce dials dest within the dial timeout, and records the time it took.
+func (h *Handler) dialOnce(ctx context.Context, dest net.Destination) (stat.Connection, error) {
+	ctx, done := policy.WithStageTimeout(ctx, policy.StageDial)
+	defer done()
+
+	start := time.Now()
+	conn, err := internet.Dial(ctx, dest, h.streamSettings)
+	if err == nil {
+		h.bdp.observeRTT(time.Since(start))
+	}
+	return conn, err
+}
+
+// resizeBuffers sizes the buffers of the connection in ctx by the estimated bandwidth-delay
+// product, if they are sized automatically. It returns whether they are.
+func (h *Handler) resizeBuffers(ctx context.Context) bool {
+	l := pipe.LimitFromContext(ctx)
+	if l == nil {
+		return false
+	}
+	min := l.Get()
+	if size := h.bdp.bufferSize(min); size != min {
+		errors.LogDebug(ctx, "buffer size of [", h.tag, "] set to ", size)
+		l.Set(size)
+	}
+	return true
+}
+
+// setWindowHint sets the TCP buffers of conn to the estimated bandwidth-delay product, on
+// paths where the system may not open the window far enough by itself.
+func (h *Handler) setWindowHint(ctx context.Context, conn stat.Connection) {
+	size := 2 * h.bdp.product()
+	if size < windowHintThreshold {
+		return
+	}
+	if size > maxAutoBufferSize {
+		size = maxAutoBufferSize
+	}
+	raw, _, _ := proxy.UnwrapRawConn(conn)
+	tc, ok := raw.(*net.TCPConn)
+	if !ok {
+		return
+	}
+	if err := tc.SetReadBuffer(int(size)); err != nil {
+		errors.LogDebugInner(ctx, err, "failed to set TCP read buffer")
+	}
+	if err := tc.SetWriteBuffer(int(size)); err != nil {
+		errors.LogDebugInner(ctx, err, "failed to set TCP write buffer")
+	}
+}
+
+// transferWriter measures the throughput of the downlink of a connection.
+type transferWriter struct {
+	buf.Writer
+	size  int64
+	first time.Time
+	last  time.Time
+}
+
+// WriteMultiBuffer implements buf.Writer.
+func (w *transferWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	now := time.Now()
+	if w.first.IsZero() {
+		w.first = now
+	} else {
+		// The first chunk arrives after a round trip, so it doesn't count towards throughput.
+		w.size += int64(mb.Len())
+	}
+	w.last = now
+	return w.Writer.WriteMultiBuffer(mb)
+}
+
+// Unwrap returns the writer w writes to.
+func (w *transferWriter) Unwrap() buf.Writer {
+	return w.Writer
+}
+
+// Close implements common.Closable.
+func (w *transferWriter) Close() error {
+	return common.Close(w.Writer)
+}
+
+// Interrupt implements common.Interruptible.
+func (w *transferWriter) Interrupt() {
+	common.Interrupt(w.Writer)
+}
+
+func (w *transferWriter) duration() time.Duration {
+	return w.last.Sub(w.first)
+}
@@ -0,0 +1,49 @@
+package outbound
+
+import (
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/features/outbound"
+)
+
+// hopTracker keeps the health of a handler as a hop of a chain.
+type hopTracker struct {
+	sync.Mutex
+	status outbound.HopStatus
+}
+
+func (t *hopTracker) record(err error) {
+	t.Lock()
+	defer t.Unlock()
+
+	if err != nil {
+		t.status.Failures++
+		t.status.LastError = err.Error()
+		t.status.LastErrorTime = time.Now()
+		return
+	}
+	t.status.Successes++
+	t.status.LastSuccessTime = time.Now()
+}
+
+// NextHop implements outbound.ChainedHandler.
+func (h *Handler) NextHop() string {
+	if h.senderSettings == nil {
+		return ""
+	}
+	if h.senderSettings.ProxySettings.HasTag() {
+		return h.senderSettings.ProxySettings.Tag
+	}
+	if h.streamSettings != nil && h.streamSettings.SocketSettings != nil {
+		return h.streamSettings.SocketSettings.DialerProxy
+	}
+	return ""
+}
+
+// HopStatus implements outbound.ChainedHandler.
+func (h *Handler) HopStatus() outbound.HopStatus {
+	h.hops.Lock()
+	defer h.hops.Unlock()
+	return h.hops.status
+}
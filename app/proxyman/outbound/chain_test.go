@@ -0,0 +1,84 @@
+package outbound
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+func TestNextHop(t *testing.T) {
+	tests := []struct {
+		name   string
+		sender *proxyman.SenderConfig
+		want   string
+	}{
+		{"direct", &proxyman.SenderConfig{}, ""},
+		{"proxySettings", &proxyman.SenderConfig{ProxySettings: &internet.ProxyConfig{Tag: "next"}}, "next"},
+		{"dialerProxy", &proxyman.SenderConfig{StreamSettings: &internet.StreamConfig{
+			SocketSettings: &internet.SocketConfig{DialerProxy: "dialer"},
+		}}, "dialer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if hop := newInitTestHandler(t, tt.sender).NextHop(); hop != tt.want {
+				t.Errorf("NextHop() = %q, want %q", hop, tt.want)
+			}
+		})
+	}
+}
+
+func TestHopStatus(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	closed.Close()
+
+	h := newInitTestHandler(t, &proxyman.SenderConfig{})
+	dispatch := func(addr net.Addr) {
+		dest := net.DestinationFromAddr(addr)
+		ctx := session.ContextWithOutbounds(h.ctx, []*session.Outbound{{Target: dest, OriginalTarget: dest}})
+		uplinkReader, uplinkWriter := pipe.New()
+		_, downlinkWriter := pipe.New()
+		uplinkWriter.Close()
+		done := make(chan struct{})
+		go func() {
+			h.Dispatch(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter})
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("dispatch to ", dest, " didn't end")
+		}
+	}
+
+	dispatch(l.Addr())
+	status := h.HopStatus()
+	if status.Successes != 1 || status.Failures != 0 || status.LastSuccessTime.IsZero() {
+		t.Error("unexpected status after a connection: ", status)
+	}
+
+	dispatch(closed.Addr())
+	status = h.HopStatus()
+	if status.Successes != 1 || status.Failures != 1 || status.LastError == "" || status.LastErrorTime.IsZero() {
+		t.Error("unexpected status after a failed dial: ", status)
+	}
+}
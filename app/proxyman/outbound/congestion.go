@@ -0,0 +1,136 @@
+package outbound
+
+import (
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/extension"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+const (
+	// congestionInterval is how often the TCP connections of an outbound are sampled.
+	congestionInterval = 5 * time.Second
+	// minCongestionSegments is the least segments a connection has to send between samples for
+	// its retransmission rate to count.
+	minCongestionSegments = 100
+)
+
+// tcpSample is the state of a TCP connection when it was last sampled.
+type tcpSample struct {
+	retransmitted uint32
+	sent          uint32
+}
+
+// congestionSampler feeds the congestion signals of the connections of an outbound to the
+// observatory, so that balancers move off the outbound when its connections degrade.
+type congestionSampler struct {
+	once     sync.Once
+	observer extension.CongestionObserver
+
+	access  sync.Mutex
+	conns   map[*net.TCPConn]*tcpSample
+	running bool
+}
+
+// congestionObserver returns the observatory taking the congestion signals of h, or nil if
+// there is none.
+func (h *Handler) congestionObserver() extension.CongestionObserver {
+	s := &h.congestion
+	s.once.Do(func() {
+		v := core.FromContext(h.ctx)
+		if v == nil {
+			return
+		}
+		if o, ok := v.GetFeature(extension.ObservatoryType()).(extension.CongestionObserver); ok && o.ObservesCongestion(h.tag) {
+			s.observer = o
+		}
+	})
+	return s.observer
+}
+
+// trackCongestion samples the TCP connection under conn until it's closed.
+func (h *Handler) trackCongestion(conn stat.Connection) {
+	if !tcpInfoSupported || h.congestionObserver() == nil {
+		return
+	}
+	raw, _, _ := proxy.UnwrapRawConn(conn)
+	tc, ok := raw.(*net.TCPConn)
+	if !ok {
+		return
+	}
+
+	s := &h.congestion
+	s.access.Lock()
+	defer s.access.Unlock()
+	if s.conns == nil {
+		s.conns = make(map[*net.TCPConn]*tcpSample)
+	}
+	s.conns[tc] = &tcpSample{}
+	if !s.running {
+		s.running = true
+		go h.sampleCongestion()
+	}
+}
+
+// sampleCongestion reports the worst signals of the connections of h every interval, until they
+// are all closed.
+func (h *Handler) sampleCongestion() {
+	s := &h.congestion
+	for {
+		time.Sleep(congestionInterval)
+
+		s.access.Lock()
+		if len(s.conns) == 0 || h.ctx.Err() != nil {
+			s.conns = nil
+			s.running = false
+			s.access.Unlock()
+			return
+		}
+		conns := make(map[*net.TCPConn]*tcpSample, len(s.conns))
+		for tc, sample := range s.conns {
+			conns[tc] = sample
+		}
+		s.access.Unlock()
+
+		var retransmission, inflation float64
+		for tc, sample := range conns {
+			info, err := getTCPInfo(tc)
+			if err != nil {
+				// The connection is closed.
+				s.access.Lock()
+				delete(s.conns, tc)
+				s.access.Unlock()
+				continue
+			}
+			if sent := info.sent - sample.sent; sent >= minCongestionSegments {
+				if r := float64(info.retransmitted-sample.retransmitted) / float64(sent); r > retransmission {
+					retransmission = r
+				}
+				*sample = tcpSample{retransmitted: info.retransmitted, sent: info.sent}
+			}
+			if info.minRTT > 0 {
+				if i := float64(info.rtt) / float64(info.minRTT); i > inflation {
+					inflation = i
+				}
+			}
+		}
+		if retransmission > 0 {
+			s.observer.ObserveCongestion(h.tag, extension.CongestionRetransmission, retransmission)
+		}
+		if inflation > 0 {
+			s.observer.ObserveCongestion(h.tag, extension.CongestionRTTInflation, inflation)
+		}
+	}
+}
+
+// observeThroughput reports the ratio of the throughput of a finished transfer to the usual one
+// of h, if known.
+func (h *Handler) observeThroughput(ratio float64) {
+	if ratio > 0 && h.congestionObserver() != nil {
+		h.congestion.observer.ObserveCongestion(h.tag, extension.CongestionThroughput, ratio)
+	}
+}
@@ -0,0 +1,39 @@
+package outbound
+
+import (
+	"github.com/xtls/xray-core/common/net"
+	"golang.org/x/sys/unix"
+)
+
+const tcpInfoSupported = true
+
+// tcpInfo is the part of the TCP_INFO of a connection used as congestion signals.
+type tcpInfo struct {
+	retransmitted uint32
+	sent          uint32
+	rtt           uint32 // in microseconds
+	minRTT        uint32 // in microseconds
+}
+
+func getTCPInfo(conn *net.TCPConn) (*tcpInfo, error) {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var info *unix.TCPInfo
+	var sockErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		info, sockErr = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+	}); err != nil {
+		return nil, err
+	}
+	if sockErr != nil {
+		return nil, sockErr
+	}
+	return &tcpInfo{
+		retransmitted: info.Total_retrans,
+		sent:          info.Segs_out,
+		rtt:           info.Rtt,
+		minRTT:        info.Min_rtt,
+	}, nil
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+// +build !linux
+
+package outbound
+
+import (
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+const tcpInfoSupported = false
+
+type tcpInfo struct {
+	retransmitted uint32
+	sent          uint32
+	rtt           uint32
+	minRTT        uint32
+}
+
+func getTCPInfo(conn *net.TCPConn) (*tcpInfo, error) {
+	return nil, errors.New("TCP_INFO is not supported on this platform")
+}
@@ -0,0 +1,197 @@
+package outbound
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/transport"
+)
+
+// drainCheckInterval is how often a draining handler is checked for connections left.
+const drainCheckInterval = time.Second
+
+// connTracker keeps the connections going through a Handler, so that draining it can wait for
+// them, and interrupt those outliving the drain timeout.
+type connTracker struct {
+	access sync.Mutex
+	links  map[*trackedWriter]*transport.Link
+}
+
+// track registers link until its downlink is closed or interrupted, and returns the link to use
+// instead. Mux connections are tracked until their sessions end, after Dispatch returns.
+func (t *connTracker) track(link *transport.Link) *transport.Link {
+	w := &trackedWriter{Writer: link.Writer, tracker: t}
+	t.access.Lock()
+	if t.links == nil {
+		t.links = make(map[*trackedWriter]*transport.Link)
+	}
+	t.links[w] = link
+	t.access.Unlock()
+	return &transport.Link{Reader: link.Reader, Writer: w}
+}
+
+func (t *connTracker) untrack(w *trackedWriter) {
+	t.access.Lock()
+	delete(t.links, w)
+	t.access.Unlock()
+}
+
+func (t *connTracker) count() int {
+	t.access.Lock()
+	defer t.access.Unlock()
+	return len(t.links)
+}
+
+// interrupt breaks the connections left.
+func (t *connTracker) interrupt() {
+	t.access.Lock()
+	links := make([]*transport.Link, 0, len(t.links))
+	for _, link := range t.links {
+		links = append(links, link)
+	}
+	t.links = nil
+	t.access.Unlock()
+	for _, link := range links {
+		common.Interrupt(link.Writer)
+		common.Interrupt(link.Reader)
+	}
+}
+
+// trackedWriter is the downlink of a tracked connection.
+type trackedWriter struct {
+	buf.Writer
+	tracker *connTracker
+}
+
+// Unwrap returns the writer w writes to.
+func (w *trackedWriter) Unwrap() buf.Writer {
+	return w.Writer
+}
+
+// Close implements common.Closable.
+func (w *trackedWriter) Close() error {
+	w.tracker.untrack(w)
+	return common.Close(w.Writer)
+}
+
+// Interrupt implements common.Interruptible.
+func (w *trackedWriter) Interrupt() {
+	w.tracker.untrack(w)
+	common.Interrupt(w.Writer)
+}
+
+// drainingHandler is a handler removed from the Manager, which is closed once its connections
+// finish, or at its deadline.
+type drainingHandler struct {
+	outbound.Handler
+	deadline time.Time
+	done     chan struct{}
+	once     sync.Once
+}
+
+func (d *drainingHandler) connections() int {
+	if h, ok := d.Handler.(*Handler); ok {
+		return h.conns.count()
+	}
+	return 0
+}
+
+func (d *drainingHandler) close() error {
+	var err error
+	d.once.Do(func() {
+		close(d.done)
+		if h, ok := d.Handler.(*Handler); ok {
+			h.conns.interrupt()
+		}
+		err = d.Handler.Close()
+	})
+	return err
+}
+
+// DrainHandler implements outbound.DrainManager.
+func (m *Manager) DrainHandler(ctx context.Context, tag string, timeout time.Duration) error {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	handler, found := m.taggedHandler[tag]
+	if !found {
+		return errors.New("outbound not found: ", tag)
+	}
+	m.removeHandler(tag)
+
+	d := &drainingHandler{
+		Handler:  handler,
+		deadline: time.Now().Add(timeout),
+		done:     make(chan struct{}),
+	}
+	m.draining = append(m.draining, d)
+	go m.drain(d)
+	errors.LogInfo(ctx, "draining outbound [", tag, "] for up to ", timeout)
+	return nil
+}
+
+func (m *Manager) drain(d *drainingHandler) {
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+	for d.connections() > 0 && time.Now().Before(d.deadline) {
+		select {
+		case <-ticker.C:
+		case <-d.done:
+			return
+		}
+	}
+	left := d.connections()
+	if err := d.close(); err != nil {
+		errors.LogWarningInner(m.ctx, err, "failed to close drained outbound [", d.Tag(), "]")
+	}
+
+	m.access.Lock()
+	for i, other := range m.draining {
+		if other == d {
+			m.draining = append(m.draining[:i], m.draining[i+1:]...)
+			break
+		}
+	}
+	m.access.Unlock()
+
+	if left > 0 {
+		errors.LogInfo(m.ctx, "outbound [", d.Tag(), "] drained, ", left, " connections interrupted")
+	} else {
+		errors.LogInfo(m.ctx, "outbound [", d.Tag(), "] drained")
+	}
+}
+
+// GetDrainingHandlers implements outbound.DrainManager.
+func (m *Manager) GetDrainingHandlers() []outbound.DrainingHandler {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	handlers := make([]outbound.DrainingHandler, 0, len(m.draining))
+	for _, d := range m.draining {
+		handlers = append(handlers, outbound.DrainingHandler{
+			Tag:         d.Tag(),
+			Connections: d.connections(),
+			Deadline:    d.deadline,
+		})
+	}
+	return handlers
+}
+
+// GetActiveConnections implements outbound.ConnectionReporter.
+func (m *Manager) GetActiveConnections() []outbound.ConnectionCount {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	counts := make([]outbound.ConnectionCount, 0, len(m.taggedHandler))
+	for tag, handler := range m.taggedHandler {
+		if h, ok := handler.(*Handler); ok {
+			counts = append(counts, outbound.ConnectionCount{Tag: tag, Connections: h.conns.count()})
+		}
+	}
+	return counts
+}
@@ -0,0 +1,57 @@
+package outbound
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+func TestDrainHandler(t *testing.T) {
+	m := &Manager{
+		taggedHandler: map[string]outbound.Handler{"finishing": &Handler{tag: "finishing"}, "stuck": &Handler{tag: "stuck"}},
+		tagsCache:     &sync.Map{},
+		maintenance:   make(map[string]bool),
+		suspended:     make(map[string]time.Time),
+		ctx:           context.Background(),
+	}
+	track := func(tag string) *transport.Link {
+		reader, writer := pipe.New()
+		return m.GetHandler(tag).(*Handler).conns.track(&transport.Link{Reader: reader, Writer: writer})
+	}
+	finishing := track("finishing")
+	stuck := track("stuck")
+
+	ctx := context.Background()
+	common.Must(m.DrainHandler(ctx, "finishing", time.Minute))
+	common.Must(m.DrainHandler(ctx, "stuck", 100*time.Millisecond))
+	if m.GetHandler("finishing") != nil || len(m.Select([]string{"finishing", "stuck"})) != 0 {
+		t.Fatal("draining outbounds are still selected")
+	}
+	if err := m.DrainHandler(ctx, "finishing", time.Minute); err == nil {
+		t.Error("expected draining an outbound twice to fail")
+	}
+	draining := m.GetDrainingHandlers()
+	if len(draining) != 2 || draining[0].Tag != "finishing" || draining[0].Connections != 1 {
+		t.Fatal("unexpected draining outbounds: ", draining)
+	}
+
+	common.Close(finishing.Writer)
+	for deadline := time.Now().Add(5 * time.Second); len(m.GetDrainingHandlers()) > 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("outbounds not drained: ", m.GetDrainingHandlers())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	b := buf.New()
+	b.WriteString("late")
+	if err := stuck.Writer.WriteMultiBuffer(buf.MultiBuffer{b}); err == nil {
+		t.Error("expected the connection outliving the timeout to be interrupted")
+	}
+}
@@ -8,18 +8,26 @@ import (
 	"math/big"
 	gonet "net"
 	"os"
+	"time"
 
 	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/app/router"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/mux"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/net/cnc"
+	"github.com/xtls/xray-core/common/ntp"
+	"github.com/xtls/xray-core/common/retry"
 	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/xudp"
 	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/features/outbound"
 	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/routing"
+	routing_session "github.com/xtls/xray-core/features/routing/session"
 	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport"
@@ -29,6 +37,10 @@ import (
 	"github.com/xtls/xray-core/transport/pipe"
 )
 
+// migrationSuspension is how long an outbound is skipped by balancers after its Mux
+// connection failed with streams to migrate.
+const migrationSuspension = 30 * time.Second
+
 func getStatCounter(v *core.Instance, tag string) (stats.Counter, stats.Counter) {
 	var uplinkCounter stats.Counter
 	var downlinkCounter stats.Counter
@@ -54,8 +66,26 @@ func getStatCounter(v *core.Instance, tag string) (stats.Counter, stats.Counter)
 	return uplinkCounter, downlinkCounter
 }
 
+func getStageHistograms(v *core.Instance, tag string) map[stats.Stage]stats.Histogram {
+	policy := v.GetFeature(policy.ManagerType()).(policy.Manager)
+	if len(tag) == 0 || !policy.ForSystem().Stats.OutboundHandshake {
+		return nil
+	}
+	statsManager := v.GetFeature(stats.ManagerType()).(stats.Manager)
+	histograms := make(map[stats.Stage]stats.Histogram)
+	for _, stage := range stats.Stages {
+		name := "outbound>>>" + tag + ">>>handshake>>>" + string(stage)
+		if h, _ := stats.GetOrRegisterHistogram(statsManager, name); h != nil {
+			histograms[stage] = h
+		}
+	}
+	return histograms
+}
+
 // Handler implements outbound.Handler.
 type Handler struct {
+	ctx             context.Context
+	cancel          context.CancelFunc
 	tag             string
 	senderSettings  *proxyman.SenderConfig
 	streamSettings  *internet.MemoryStreamConfig
@@ -63,9 +93,19 @@ type Handler struct {
 	outboundManager outbound.Manager
 	mux             *mux.ClientManager
 	xudp            *mux.ClientManager
+	muxExclude      []router.Condition
 	udp443          string
+	xudpKey         []byte
 	uplinkCounter   stats.Counter
 	downlinkCounter stats.Counter
+	stages          map[stats.Stage]stats.Histogram
+	bdp             bdpEstimator
+	hops            hopTracker
+	conns           connTracker
+	congestion      congestionSampler
+	tracer          *internet.DialTracer
+	// clock is the clock of the handler's own NTP server, if any.
+	clock *ntp.Clock
 }
 
 // NewHandler creates a new Handler based on the given configuration.
@@ -77,6 +117,7 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 		outboundManager: v.GetFeature(outbound.ManagerType()).(outbound.Manager),
 		uplinkCounter:   uplinkCounter,
 		downlinkCounter: downlinkCounter,
+		stages:          getStageHistograms(v, config.Tag),
 	}
 
 	if config.SenderSettings != nil {
@@ -92,6 +133,9 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 				return nil, errors.New("failed to parse stream settings").Base(err).AtWarning()
 			}
 			h.streamSettings = mss
+			if s.Trace {
+				h.tracer = internet.NewDialTracer(config.Tag)
+			}
 		default:
 			return nil, errors.New("settings is not SenderConfig")
 		}
@@ -102,6 +146,16 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 		return nil, err
 	}
 
+	// Time-sensitive protocols take the time of the handler's NTP server, or the global one.
+	if h.senderSettings != nil && h.senderSettings.NtpServer != "" {
+		h.clock = ntp.NewClock(h.senderSettings.NtpServer, time.Duration(h.senderSettings.NtpInterval)*time.Second)
+	}
+	if h.clock != nil {
+		ctx = ntp.ContextWithClock(ctx, h.clock)
+	} else if m, ok := h.outboundManager.(*Manager); ok && m.clock != nil {
+		ctx = ntp.ContextWithClock(ctx, m.clock)
+	}
+
 	rawProxyHandler, err := common.CreateObject(ctx, proxyConfig)
 	if err != nil {
 		return nil, err
@@ -113,7 +167,13 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 	}
 
 	if h.senderSettings != nil && h.senderSettings.MultiplexSettings != nil {
+		// XUDP also goes without Mux, in the packet encoding of VLESS and VMess.
+		h.xudpKey = h.senderSettings.MultiplexSettings.XudpKey
 		if config := h.senderSettings.MultiplexSettings; config.Enabled {
+			var migrate mux.MigrateFunc
+			if config.Migrate {
+				migrate = h.migrate
+			}
 			if config.Concurrency < 0 {
 				h.mux = &mux.ClientManager{Enabled: false}
 			}
@@ -130,6 +190,7 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 							Strategy: mux.ClientStrategy{
 								MaxConcurrency: uint32(config.Concurrency),
 								MaxConnection:  128,
+								Migrate:        migrate,
 							},
 						},
 					},
@@ -157,10 +218,18 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 				}
 			}
 			h.udp443 = config.XudpProxyUDP443
+			for _, rule := range config.Exclude {
+				cond, err := rule.BuildCondition()
+				if err != nil {
+					return nil, errors.New("failed to build Mux exclusion").Base(err)
+				}
+				h.muxExclude = append(h.muxExclude, cond)
+			}
 		}
 	}
 
 	h.proxy = proxyHandler
+	h.ctx, h.cancel = context.WithCancel(ctx)
 	return h, nil
 }
 
@@ -171,14 +240,36 @@ func (h *Handler) Tag() string {
 
 // Dispatch implements proxy.Outbound.Dispatch.
 func (h *Handler) Dispatch(ctx context.Context, link *transport.Link) {
+	link = h.conns.track(link)
 	outbounds := session.OutboundsFromContext(ctx)
 	ob := outbounds[len(outbounds)-1]
+	autoBuffer := h.resizeBuffers(ctx)
+	ctx, link = h.timeStages(ctx, link)
+	if h.xudpKey != nil {
+		ctx = xudp.ContextWithBaseKey(ctx, h.xudpKey)
+	}
 	if ob.Target.Network == net.Network_UDP && ob.OriginalTarget.Address != nil && ob.OriginalTarget.Address != ob.Target.Address {
 		link.Reader = &buf.EndpointOverrideReader{Reader: link.Reader, Dest: ob.Target.Address, OriginalDest: ob.OriginalTarget.Address}
 		link.Writer = &buf.EndpointOverrideWriter{Writer: link.Writer, Dest: ob.Target.Address, OriginalDest: ob.OriginalTarget.Address}
 	}
+	if session.BindFromContext(ctx) != nil {
+		if binder, ok := h.proxy.(proxy.Binder); !ok || !binder.SupportsBind() {
+			err := errors.New("outbound [", h.tag, "] doesn't support BIND").AtWarning()
+			session.SubmitOutboundErrorToOriginator(ctx, err)
+			errors.LogInfo(ctx, err.Error())
+			common.Interrupt(link.Writer)
+			return
+		}
+		// BIND requests accept a connection instead of dialing, which mux can't carry.
+		goto out
+	}
+	if h.mux != nil && h.excludedFromMux(ctx) {
+		goto out
+	}
 	if h.mux != nil {
 		test := func(err error) {
+			reportConnect(ctx, err)
+			h.hops.record(err)
 			if err != nil {
 				err := errors.New("failed to process mux outbound traffic").Base(err)
 				session.SubmitOutboundErrorToOriginator(ctx, err)
@@ -208,12 +299,26 @@ func (h *Handler) Dispatch(ctx context.Context, link *transport.Link) {
 		}
 	}
 out:
+	var transfer *transferWriter
+	if autoBuffer || h.congestionObserver() != nil {
+		transfer = &transferWriter{Writer: link.Writer}
+		link.Writer = transfer
+	}
+	if connector, ok := h.proxy.(proxy.Connector); !ok || !connector.ConnectsFirst() {
+		// Nothing to wait for before relaying.
+		reportConnect(ctx, nil)
+	}
 	err := h.proxy.Process(ctx, link, h)
+	if transfer != nil {
+		h.observeThroughput(h.bdp.observeTransfer(transfer.size, transfer.duration()))
+	}
 	if err != nil {
 		if goerrors.Is(err, io.EOF) || goerrors.Is(err, io.ErrClosedPipe) || goerrors.Is(err, context.Canceled) {
 			err = nil
 		}
 	}
+	reportConnect(ctx, err)
+	h.hops.record(err)
 	if err != nil {
 		// Ensure outbound ray is properly closed.
 		err := errors.New("failed to process outbound traffic").Base(err)
@@ -226,6 +331,54 @@ out:
 	common.Interrupt(link.Reader)
 }
 
+// reportConnect reports err to the inbound waiting for the request of ctx to be connected, if
+// any. Only the first report counts.
+func reportConnect(ctx context.Context, err error) {
+	if connect := session.ConnectFromContext(ctx); connect != nil {
+		connect.Report(err)
+	}
+}
+
+// excludedFromMux returns whether the traffic of ctx matches the Mux exclusions, to go without it.
+func (h *Handler) excludedFromMux(ctx context.Context) bool {
+	if len(h.muxExclude) == 0 {
+		return false
+	}
+	routingCtx := routing_session.AsRoutingContext(ctx)
+	for _, cond := range h.muxExclude {
+		if cond.Apply(routingCtx) {
+			return true
+		}
+	}
+	return false
+}
+
+// migrate re-dispatches a stream whose Mux connection failed before it got any response.
+// This outbound is suspended for a while, so that balancers pick another one.
+func (h *Handler) migrate(ctx context.Context, link *transport.Link) {
+	if mm, ok := h.outboundManager.(outbound.MaintenanceManager); ok {
+		mm.Suspend(h.tag, migrationSuspension)
+	}
+
+	outbounds := session.OutboundsFromContext(ctx)
+	ob := outbounds[len(outbounds)-1]
+	if content := session.ContentFromContext(ctx); content != nil {
+		// The stream was sniffed already, and its reader can't be sniffed again.
+		ctx = session.ContextWithContent(ctx, &session.Content{
+			Protocol:       content.Protocol,
+			Attributes:     content.Attributes,
+			SkipDNSResolve: content.SkipDNSResolve,
+		})
+	}
+
+	dispatcher := core.MustFromContext(h.ctx).GetFeature(routing.DispatcherType()).(routing.Dispatcher)
+	if err := dispatcher.DispatchLink(ctx, ob.Target, link); err != nil {
+		errors.LogInfoInner(ctx, err, "failed to migrate stream from [", h.tag, "]")
+		common.Interrupt(link.Writer)
+		common.Interrupt(link.Reader)
+	}
+}
+
 // Address implements internet.Dialer.
 func (h *Handler) Address() net.Address {
 	if h.senderSettings == nil || h.senderSettings.Via == nil {
@@ -284,7 +437,20 @@ func (h *Handler) Dial(ctx context.Context, dest net.Destination) (stat.Connecti
 		return conn, err
 	}
 
-	conn, err := internet.Dial(ctx, dest, h.streamSettings)
+	ctx = h.tracer.Start(ctx, dest)
+	conn, err := h.dial(ctx, dest)
+	if err != nil {
+		internet.TraceDial(ctx, "failed: ", err)
+	} else {
+		internet.TraceDial(ctx, "connected ", conn.LocalAddr(), " -> ", conn.RemoteAddr())
+	}
+	if err == nil && pipe.LimitFromContext(ctx) != nil {
+		h.setWindowHint(ctx, conn)
+	}
+	if err == nil {
+		h.trackCongestion(conn)
+		reportConnect(ctx, nil)
+	}
 	conn = h.getStatCouterConnection(conn)
 	outbounds := session.OutboundsFromContext(ctx)
 	ob := outbounds[len(outbounds)-1]
@@ -310,12 +476,66 @@ func (h *Handler) GetOutbound() proxy.Outbound {
 
 // Start implements common.Runnable.
 func (h *Handler) Start() error {
+	if h.clock != nil {
+		common.Must(h.clock.Start())
+	}
+	if initializer, ok := h.proxy.(proxy.Initializer); ok {
+		go func() {
+			err := h.initProxy(initializer, retry.ExponentialBackoff(10, 1000))
+			switch {
+			case h.ctx.Err() != nil:
+			case err != nil:
+				errors.LogWarningInner(h.ctx, err, "failed to initialize outbound [", h.tag, "] in background")
+			default:
+				errors.LogInfo(h.ctx, "outbound [", h.tag, "] initialized")
+			}
+		}()
+	}
+	return nil
+}
+
+// initProxy runs the expensive setup of the proxy in background, so that a slow or unreachable
+// server doesn't hold back the startup of other handlers. Connections arriving before it
+// finishes are handled by the proxy's own lazy initialization. It gives up once the handler
+// is closed.
+func (h *Handler) initProxy(initializer proxy.Initializer, strategy retry.Strategy) error {
+	ctx := session.ContextWithOutbounds(h.ctx, []*session.Outbound{{Tag: h.tag}})
+	return strategy.OnContext(h.ctx, func() error {
+		return initializer.Init(ctx, h)
+	})
+}
+
+// Prepare implements proxy.Preparer. It resolves the domain of dest with the DNS by the domain
+// strategy of the handler, and loads the TLS certificates of its transport.
+func (h *Handler) Prepare(ctx context.Context, dest net.Destination) error {
+	if dest.Address.Family().IsDomain() {
+		if client, ok := core.MustFromContext(h.ctx).GetFeature(dns.ClientType()).(dns.Client); ok {
+			var strategy internet.DomainStrategy
+			if h.streamSettings != nil {
+				strategy = h.streamSettings.SocketSettings.GetDomainStrategy()
+			}
+			if _, err := internet.LookupIPWithStrategy(ctx, client, dest.Address.Domain(), strategy); err != nil {
+				return errors.New("failed to resolve ", dest.Address).Base(err)
+			}
+		}
+	}
+	if config := tls.ConfigFromStreamSettings(h.streamSettings); config != nil {
+		if err := config.LoadCertificates(); err != nil {
+			return errors.New("failed to load TLS certificates").Base(err)
+		}
+	}
 	return nil
 }
 
 // Close implements common.Closable.
 func (h *Handler) Close() error {
+	if h.cancel != nil {
+		h.cancel()
+	}
 	common.Close(h.mux)
+	if h.clock != nil {
+		h.clock.Close()
+	}
 	return nil
 }
 
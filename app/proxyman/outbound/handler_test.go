@@ -24,6 +24,7 @@ import (
 func TestInterfaces(t *testing.T) {
 	_ = (outbound.Handler)(new(Handler))
 	_ = (outbound.Manager)(new(Manager))
+	_ = (outbound.StandbyManager)(new(Manager))
 }
 
 const xrayKey core.XrayKey = 1
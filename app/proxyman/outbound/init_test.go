@@ -0,0 +1,181 @@
+package outbound
+
+import (
+	"context"
+	gonet "net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/retry"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/proxy/freedom"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/internet"
+	_ "github.com/xtls/xray-core/transport/internet/tcp"
+	"github.com/xtls/xray-core/transport/internet/tls"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+const xrayKey core.XrayKey = 1
+
+var errInit = errors.New("server unreachable")
+
+type fakeInitializer struct {
+	proxy.Outbound
+	err   error
+	calls atomic.Int32
+	init  chan struct{}
+}
+
+func (f *fakeInitializer) Init(ctx context.Context, dialer internet.Dialer) error {
+	f.calls.Add(1)
+	select {
+	case f.init <- struct{}{}:
+	default:
+	}
+	return f.err
+}
+
+func newInitTestHandler(t *testing.T, sender *proxyman.SenderConfig) *Handler {
+	v, err := core.New(&core.Config{})
+	common.Must(err)
+	v.AddFeature((outbound.Manager)(new(Manager)))
+	ctx := context.WithValue(context.Background(), xrayKey, v)
+	h, err := NewHandler(ctx, &core.OutboundHandlerConfig{
+		Tag:            "tag",
+		SenderSettings: serial.ToTypedMessage(sender),
+		ProxySettings:  serial.ToTypedMessage(&freedom.Config{}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.(*Handler).Close() })
+	return h.(*Handler)
+}
+
+func TestHandlerStartInitsProxy(t *testing.T) {
+	h := newInitTestHandler(t, &proxyman.SenderConfig{})
+	initializer := &fakeInitializer{init: make(chan struct{}, 1)}
+	h.proxy = initializer
+
+	common.Must(h.Start())
+	select {
+	case <-initializer.init:
+	case <-time.After(time.Second):
+		t.Fatal("Init was not called")
+	}
+}
+
+func TestHandlerInitReportsErrors(t *testing.T) {
+	h := newInitTestHandler(t, &proxyman.SenderConfig{})
+	initializer := &fakeInitializer{err: errInit, init: make(chan struct{}, 1)}
+
+	err := h.initProxy(initializer, retry.Timed(3, 0))
+	if errors.Cause(err) != retry.ErrRetryFailed || !strings.Contains(err.Error(), "server unreachable") {
+		t.Error("unexpected error: ", err)
+	}
+	if calls := initializer.calls.Load(); calls != 3 {
+		t.Error("Init called ", calls, " times, want 3")
+	}
+}
+
+func TestHandlerInitStopsOnClose(t *testing.T) {
+	h := newInitTestHandler(t, &proxyman.SenderConfig{})
+	initializer := &fakeInitializer{err: errInit, init: make(chan struct{}, 1)}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.initProxy(initializer, retry.Timed(10, 100000))
+	}()
+	<-initializer.init
+	common.Must(h.Close())
+
+	select {
+	case err := <-done:
+		if errors.Cause(err) != context.Canceled {
+			t.Error("unexpected error: ", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Init kept retrying after Close")
+	}
+	if calls := initializer.calls.Load(); calls != 1 {
+		t.Error("Init called ", calls, " times, want 1")
+	}
+}
+
+func TestHandlerPrepare(t *testing.T) {
+	streamSettings := func(config *tls.Config) *internet.StreamConfig {
+		return &internet.StreamConfig{
+			SecurityType:     serial.GetMessageType(config),
+			SecuritySettings: []*serial.TypedMessage{serial.ToTypedMessage(config)},
+		}
+	}
+	tests := []struct {
+		name    string
+		stream  *internet.StreamConfig
+		wantErr bool
+	}{
+		{"no TLS", nil, false},
+		{"TLS", streamSettings(&tls.Config{DisableSystemRoot: true}), false},
+		{"invalid certificate", streamSettings(&tls.Config{
+			DisableSystemRoot: true,
+			Certificate:       []*tls.Certificate{{Certificate: []byte("invalid"), Key: []byte("invalid")}},
+		}), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newInitTestHandler(t, &proxyman.SenderConfig{StreamSettings: tt.stream})
+			dest := net.TCPDestination(net.ParseAddress("192.0.2.1"), 443)
+			if err := h.Prepare(h.ctx, dest); (err != nil) != tt.wantErr {
+				t.Errorf("Prepare() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandlerReportsConnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer l.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	closed.Close()
+
+	tests := []struct {
+		name    string
+		addr    gonet.Addr
+		wantErr bool
+	}{
+		{"connected", l.Addr(), false},
+		{"refused", closed.Addr(), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newInitTestHandler(t, &proxyman.SenderConfig{})
+			dest := net.DestinationFromAddr(tt.addr)
+			connect := session.NewConnect()
+			ctx := session.ContextWithConnect(h.ctx, connect)
+			ctx = session.ContextWithOutbounds(ctx, []*session.Outbound{{Target: dest, OriginalTarget: dest}})
+			uplinkReader, uplinkWriter := pipe.New()
+			_, downlinkWriter := pipe.New()
+			defer uplinkWriter.Close()
+			go h.Dispatch(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter})
+
+			timeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := connect.Wait(timeout); (err != nil) != tt.wantErr || err == context.DeadlineExceeded {
+				t.Errorf("reported %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package outbound
+
+import (
+	"sort"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// SetMaintenance implements outbound.MaintenanceManager.
+func (m *Manager) SetMaintenance(tag string, maintenance bool) error {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	if _, found := m.taggedHandler[tag]; !found {
+		return errors.New("outbound not found: ", tag)
+	}
+	if maintenance {
+		m.maintenance[tag] = true
+	} else {
+		delete(m.maintenance, tag)
+		delete(m.suspended, tag)
+	}
+	return nil
+}
+
+// InMaintenance implements outbound.MaintenanceManager.
+func (m *Manager) InMaintenance(tag string) bool {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	if m.maintenance[tag] {
+		return true
+	}
+	until, found := m.suspended[tag]
+	return found && time.Now().Before(until)
+}
+
+// GetMaintenance implements outbound.MaintenanceManager.
+func (m *Manager) GetMaintenance() []string {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	tags := make([]string, 0, len(m.maintenance))
+	for tag := range m.maintenance {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Suspend implements outbound.MaintenanceManager.
+func (m *Manager) Suspend(tag string, duration time.Duration) {
+	now := time.Now()
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	if _, found := m.taggedHandler[tag]; !found {
+		return
+	}
+	for t, until := range m.suspended {
+		if now.After(until) {
+			delete(m.suspended, t)
+		}
+	}
+	m.suspended[tag] = now.Add(duration)
+}
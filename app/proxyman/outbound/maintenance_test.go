@@ -0,0 +1,52 @@
+package outbound
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/xtls/xray-core/features/outbound"
+)
+
+func TestMaintenance(t *testing.T) {
+	m := &Manager{
+		taggedHandler: map[string]outbound.Handler{"a": &Handler{tag: "a"}, "b": &Handler{tag: "b"}},
+		tagsCache:     &sync.Map{},
+		maintenance:   make(map[string]bool),
+		suspended:     make(map[string]time.Time),
+		ctx:           context.Background(),
+	}
+	if err := m.SetMaintenance("missing", true); err == nil {
+		t.Error("expected putting a missing outbound in maintenance to fail")
+	}
+	if err := m.SetMaintenance("a", true); err != nil {
+		t.Fatal(err)
+	}
+	if !m.InMaintenance("a") || m.InMaintenance("b") {
+		t.Error("expected only a in maintenance")
+	}
+	if r := cmp.Diff(m.GetMaintenance(), []string{"a"}); r != "" {
+		t.Error(r)
+	}
+
+	m.Suspend("b", 100*time.Millisecond)
+	if !m.InMaintenance("b") {
+		t.Error("expected the suspended outbound in maintenance")
+	}
+	if r := cmp.Diff(m.GetMaintenance(), []string{"a"}); r != "" {
+		t.Error("suspended outbounds are listed as in maintenance: ", r)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if m.InMaintenance("b") {
+		t.Error("expected the suspension to be over")
+	}
+
+	if err := m.SetMaintenance("a", false); err != nil {
+		t.Fatal(err)
+	}
+	if m.InMaintenance("a") || len(m.GetMaintenance()) != 0 {
+		t.Error("expected a out of maintenance")
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/xtls/xray-core/app/proxyman"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/ntp"
+	"github.com/xtls/xray-core/common/power"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/routing"
 )
 
 // Manager is to manage all outbound handlers.
@@ -21,6 +25,16 @@ type Manager struct {
 	untaggedHandlers []outbound.Handler
 	running          bool
 	tagsCache        *sync.Map
+	maintenance      map[string]bool
+	suspended        map[string]time.Time
+	standby          map[string]*standbyGroup
+	draining         []*drainingHandler
+	dispatcher       routing.Dispatcher
+	ctx              context.Context
+	cancel           context.CancelFunc
+	// clock is the clock of the global NTP server, if any, for the handlers without their own.
+	clock       *ntp.Clock
+	unsubscribe func()
 }
 
 // New creates a new Manager.
@@ -28,6 +42,20 @@ func New(ctx context.Context, config *proxyman.OutboundConfig) (*Manager, error)
 	m := &Manager{
 		taggedHandler: make(map[string]outbound.Handler),
 		tagsCache:     &sync.Map{},
+		maintenance:   make(map[string]bool),
+		suspended:     make(map[string]time.Time),
+		standby:       newStandbyGroups(config.Standby),
+		ctx:           ctx,
+	}
+	if config.NtpServer != "" {
+		m.clock = ntp.NewClock(config.NtpServer, time.Duration(config.NtpInterval)*time.Second)
+	}
+	if len(m.standby) > 0 {
+		if err := core.RequireFeatures(ctx, func(d routing.Dispatcher) {
+			m.dispatcher = d
+		}); err != nil {
+			return nil, err
+		}
 	}
 	return m, nil
 }
@@ -44,6 +72,11 @@ func (m *Manager) Start() error {
 
 	m.running = true
 
+	if m.clock != nil {
+		common.Must(m.clock.Start())
+	}
+	m.unsubscribe = power.Subscribe(m.onPower)
+
 	for _, h := range m.taggedHandler {
 		if err := h.Start(); err != nil {
 			return err
@@ -56,6 +89,14 @@ func (m *Manager) Start() error {
 		}
 	}
 
+	if len(m.standby) > 0 && m.dispatcher != nil {
+		var ctx context.Context
+		ctx, m.cancel = context.WithCancel(m.ctx)
+		for _, g := range m.standby {
+			go g.run(ctx, m.dispatcher)
+		}
+	}
+
 	return nil
 }
 
@@ -65,6 +106,15 @@ func (m *Manager) Close() error {
 	defer m.access.Unlock()
 
 	m.running = false
+	if m.cancel != nil {
+		m.cancel()
+	}
+	if m.clock != nil {
+		m.clock.Close()
+	}
+	if m.unsubscribe != nil {
+		m.unsubscribe()
+	}
 
 	var errs []error
 	for _, h := range m.taggedHandler {
@@ -75,6 +125,11 @@ func (m *Manager) Close() error {
 		errs = append(errs, h.Close())
 	}
 
+	for _, d := range m.draining {
+		errs = append(errs, d.close())
+	}
+	m.draining = nil
+
 	return errors.Combine(errs...)
 }
 
@@ -135,14 +190,27 @@ func (m *Manager) RemoveHandler(ctx context.Context, tag string) error {
 	m.access.Lock()
 	defer m.access.Unlock()
 
+	m.removeHandler(tag)
+	return nil
+}
+
+func (m *Manager) removeHandler(tag string) {
 	m.tagsCache = &sync.Map{}
 
 	delete(m.taggedHandler, tag)
+	delete(m.maintenance, tag)
+	delete(m.suspended, tag)
 	if m.defaultHandler != nil && m.defaultHandler.Tag() == tag {
 		m.defaultHandler = nil
 	}
+}
 
-	return nil
+// Reclaim implements features.Reclaimer, dropping the cached results of Select.
+func (m *Manager) Reclaim() {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	m.tagsCache = &sync.Map{}
 }
 
 // Select implements outbound.HandlerSelector.
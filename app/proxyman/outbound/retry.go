@@ -0,0 +1,129 @@
+package outbound
+
+import (
+	"context"
+	goerrors "errors"
+	"syscall"
+	"time"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common/dice"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+// dial dials dest, retrying failed attempts as configured in the sender settings.
+func (h *Handler) dial(ctx context.Context, dest net.Destination) (stat.Connection, error) {
+	config := h.senderSettings.GetRetrySettings()
+	if config.GetAttempts() <= 1 {
+		return h.dialOnce(ctx, dest)
+	}
+
+	var addresses []net.Address
+	if config.RotateAddress && dest.Address.Family().IsDomain() {
+		addresses = h.resolveAll(ctx, dest.Address.Domain())
+		internet.TraceDial(ctx, "rotating over ", addresses)
+	}
+
+	var timer *time.Timer
+	var lastErr error
+	for attempt := uint32(0); attempt < config.Attempts; attempt++ {
+		if attempt > 0 {
+			delay := backoff(config, attempt)
+			if timer == nil {
+				timer = time.NewTimer(delay)
+				defer timer.Stop()
+			} else {
+				timer.Reset(delay)
+			}
+			select {
+			case <-ctx.Done():
+				return nil, errors.New("dial canceled").Base(lastErr)
+			case <-timer.C:
+			}
+		}
+
+		target := rotateAddress(dest, addresses, attempt)
+		conn, err := h.dialOnce(ctx, target)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		if !isRetryable(err, config) {
+			break
+		}
+		errors.LogInfoInner(ctx, err, "failed to dial ", target, " (attempt ", attempt+1, "/", config.Attempts, ")")
+		internet.TraceDial(ctx, "attempt ", attempt+1, "/", config.Attempts, " to ", target, " failed: ", err)
+	}
+	return nil, lastErr
+}
+
+// backoff returns the wait before the given attempt, which doubles from the configured backoff
+// on every attempt after the first.
+func backoff(config *proxyman.RetryConfig, attempt uint32) time.Duration {
+	if attempt == 0 {
+		return 0
+	}
+	return time.Duration(config.Backoff) * time.Millisecond << (attempt - 1)
+}
+
+// rotateAddress returns dest with the address to dial at the given attempt, going round
+// addresses. dest is dialed as is if there are none.
+func rotateAddress(dest net.Destination, addresses []net.Address, attempt uint32) net.Destination {
+	if len(addresses) > 0 {
+		dest.Address = addresses[int(attempt)%len(addresses)]
+	}
+	return dest
+}
+
+// resolveAll returns all addresses of domain by the domain strategy of the handler, starting at
+// a random one. It returns nil if the domain can't be resolved, so that the domain itself is dialed.
+func (h *Handler) resolveAll(ctx context.Context, domain string) []net.Address {
+	client, ok := core.MustFromContext(h.ctx).GetFeature(dns.ClientType()).(dns.Client)
+	if !ok {
+		return nil
+	}
+	var strategy internet.DomainStrategy
+	if h.streamSettings != nil {
+		strategy = h.streamSettings.SocketSettings.GetDomainStrategy()
+	}
+	ips, err := internet.LookupIPWithStrategy(ctx, client, domain, strategy)
+	if err != nil || len(ips) == 0 {
+		errors.LogInfoInner(ctx, err, "failed to resolve ", domain, " for address rotation")
+		return nil
+	}
+	start := dice.Roll(len(ips))
+	addresses := make([]net.Address, 0, len(ips))
+	for i := range ips {
+		addresses = append(addresses, net.IPAddress(ips[(start+i)%len(ips)]))
+	}
+	return addresses
+}
+
+func isRetryable(err error, config *proxyman.RetryConfig) bool {
+	if len(config.RetryOn) == 0 {
+		return true
+	}
+	for _, kind := range config.RetryOn {
+		switch kind {
+		case "timeout":
+			var netErr net.Error
+			if goerrors.Is(err, context.DeadlineExceeded) || (goerrors.As(err, &netErr) && netErr.Timeout()) {
+				return true
+			}
+		case "reset":
+			if goerrors.Is(err, syscall.ECONNRESET) {
+				return true
+			}
+		case "refused":
+			if goerrors.Is(err, syscall.ECONNREFUSED) {
+				return true
+			}
+		}
+	}
+	return false
+}
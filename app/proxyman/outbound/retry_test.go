@@ -0,0 +1,99 @@
+package outbound
+
+import (
+	"context"
+	gonet "net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+func dialError(err error) error {
+	return errors.New("failed to dial").Base(&gonet.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", err)})
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		retryOn []string
+		want    bool
+	}{
+		{"any error by default", errors.New("boom"), nil, true},
+		{"deadline", errors.New("timed out").Base(context.DeadlineExceeded), []string{"timeout"}, true},
+		{"net timeout", dialError(syscall.ETIMEDOUT), []string{"timeout"}, true},
+		{"reset", dialError(syscall.ECONNRESET), []string{"reset"}, true},
+		{"refused", dialError(syscall.ECONNREFUSED), []string{"refused"}, true},
+		{"refused among others", dialError(syscall.ECONNREFUSED), []string{"timeout", "refused"}, true},
+		{"refused but reset wanted", dialError(syscall.ECONNREFUSED), []string{"reset"}, false},
+		{"other error", errors.New("boom"), []string{"timeout", "reset", "refused"}, false},
+		{"unknown kind", dialError(syscall.ECONNRESET), []string{"unknown"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &proxyman.RetryConfig{Attempts: 3, RetryOn: tt.retryOn}
+			if got := isRetryable(tt.err, config); got != tt.want {
+				t.Errorf("isRetryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRotateAddress(t *testing.T) {
+	dest := net.TCPDestination(net.DomainAddress("example.com"), 443)
+	addresses := []net.Address{
+		net.ParseAddress("192.0.2.1"),
+		net.ParseAddress("192.0.2.2"),
+		net.ParseAddress("2001:db8::1"),
+	}
+	tests := []struct {
+		name      string
+		addresses []net.Address
+		attempts  uint32
+		want      []string
+	}{
+		{"no addresses", nil, 2, []string{"tcp:example.com:443", "tcp:example.com:443"}},
+		{"one address", addresses[:1], 2, []string{"tcp:192.0.2.1:443", "tcp:192.0.2.1:443"}},
+		{"round", addresses, 4, []string{"tcp:192.0.2.1:443", "tcp:192.0.2.2:443", "tcp:[2001:db8::1]:443", "tcp:192.0.2.1:443"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for attempt := uint32(0); attempt < tt.attempts; attempt++ {
+				got = append(got, rotateAddress(dest, tt.addresses, attempt).String())
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff uint32
+		want    []time.Duration
+	}{
+		{"none", 0, []time.Duration{0, 0, 0, 0}},
+		{"doubling", 100, []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &proxyman.RetryConfig{Attempts: uint32(len(tt.want)), Backoff: tt.backoff}
+			var got []time.Duration
+			for attempt := uint32(0); attempt < config.Attempts; attempt++ {
+				got = append(got, backoff(config, attempt))
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
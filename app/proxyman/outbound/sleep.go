@@ -0,0 +1,53 @@
+package outbound
+
+import (
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/mux"
+	"github.com/xtls/xray-core/common/power"
+	"github.com/xtls/xray-core/features/outbound"
+)
+
+// staleAfterSleep is the least sleep of the system after which the connections of outbounds are
+// taken for dead, as NATs and servers have dropped them by then.
+const staleAfterSleep = time.Minute
+
+// onPower closes the connections of the handlers on wake, so that programs reconnect right away
+// instead of waiting on dead connections until they time out.
+func (m *Manager) onPower(e power.Event) {
+	if e.Type != power.Wake || e.Slept < staleAfterSleep {
+		return
+	}
+	m.access.RLock()
+	handlers := make([]outbound.Handler, 0, len(m.taggedHandler)+len(m.untaggedHandlers))
+	for _, h := range m.taggedHandler {
+		handlers = append(handlers, h)
+	}
+	handlers = append(handlers, m.untaggedHandlers...)
+	m.access.RUnlock()
+
+	closed := 0
+	for _, h := range handlers {
+		if h, ok := h.(*Handler); ok {
+			closed += h.closeConnections()
+		}
+	}
+	errors.LogInfo(m.ctx, "woke up after sleeping for ", e.Slept.Round(time.Second), ", closed ", closed, " connections of outbounds")
+}
+
+// closeConnections interrupts the connections in progress, and closes the mux connections so that
+// new streams dial new ones. It returns the number of connections interrupted.
+func (h *Handler) closeConnections() int {
+	n := h.conns.count()
+	h.conns.interrupt()
+	for _, c := range []*mux.ClientManager{h.mux, h.xudp} {
+		if c == nil {
+			continue
+		}
+		if p, ok := c.Picker.(*mux.IncrementalWorkerPicker); ok {
+			p.CloseAll()
+		}
+	}
+	return n
+}
@@ -0,0 +1,71 @@
+package outbound
+
+import (
+	"context"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/transport"
+)
+
+// timeStages makes the stages of the connection in ctx recorded in the histograms of the
+// handler, if there are any, and by the HopRecorder of ctx as those of this hop. The first byte
+// of the response is timed from now.
+func (h *Handler) timeStages(ctx context.Context, link *transport.Link) (context.Context, *transport.Link) {
+	hops := stats.HopRecorderFromContext(ctx)
+	if len(h.stages) == 0 && hops == nil {
+		return ctx, link
+	}
+	record := h.recordStage
+	if hops != nil {
+		record = func(stage stats.Stage, d time.Duration) {
+			h.recordStage(stage, d)
+			hops(h.tag, stage)
+		}
+	}
+	ctx = stats.ContextWithStageRecorder(ctx, record)
+	return ctx, &transport.Link{
+		Reader: link.Reader,
+		Writer: &firstByteWriter{Writer: link.Writer, ctx: ctx, start: time.Now()},
+	}
+}
+
+func (h *Handler) recordStage(stage stats.Stage, d time.Duration) {
+	if histogram := h.stages[stage]; histogram != nil {
+		histogram.Observe(d)
+	}
+}
+
+// firstByteWriter records the time until the first response of a connection.
+type firstByteWriter struct {
+	buf.Writer
+	ctx      context.Context
+	start    time.Time
+	recorded bool
+}
+
+// WriteMultiBuffer implements buf.Writer.
+func (w *firstByteWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	if !w.recorded && !mb.IsEmpty() {
+		w.recorded = true
+		stats.RecordStage(w.ctx, stats.StageFirstByte, w.start)
+	}
+	return w.Writer.WriteMultiBuffer(mb)
+}
+
+// Unwrap returns the writer w writes to.
+func (w *firstByteWriter) Unwrap() buf.Writer {
+	return w.Writer
+}
+
+// Close implements common.Closable.
+func (w *firstByteWriter) Close() error {
+	return common.Close(w.Writer)
+}
+
+// Interrupt implements common.Interruptible.
+func (w *firstByteWriter) Interrupt() {
+	common.Interrupt(w.Writer)
+}
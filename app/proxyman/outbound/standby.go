@@ -0,0 +1,197 @@
+package outbound
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/transport/internet/tagged"
+)
+
+const (
+	defaultStandbyProbeURL      = "https://www.google.com/generate_204"
+	defaultStandbyProbeInterval = 10 * time.Second
+	defaultStandbyProbeTimeout  = 5 * time.Second
+	// standbyRecovery is how many probes in a row a failed primary must pass to take its traffic back,
+	// so that a flapping primary doesn't move streaming sessions back and forth.
+	standbyRecovery = 3
+)
+
+// standbyGroup probes a primary outbound and its warm standbys, and tracks which of them serves
+// the traffic routed to the primary. Probes keep their connections alive between intervals, so
+// that the transports of standbys are connected already when they take over.
+type standbyGroup struct {
+	primary  string
+	standbys []string
+	probeURL string
+	interval time.Duration
+	timeout  time.Duration
+	clients  map[string]*http.Client
+
+	access    sync.RWMutex
+	active    string
+	recovered int
+}
+
+// newStandbyGroups groups standby configs by their primary, keeping the order of standbys.
+// Probe settings of a group are taken from its first standby.
+func newStandbyGroups(configs []*proxyman.StandbyConfig) map[string]*standbyGroup {
+	groups := make(map[string]*standbyGroup)
+	for _, c := range configs {
+		g, found := groups[c.Primary]
+		if !found {
+			g = &standbyGroup{
+				primary:  c.Primary,
+				active:   c.Primary,
+				probeURL: c.ProbeUrl,
+				interval: time.Duration(c.ProbeInterval),
+				timeout:  time.Duration(c.ProbeTimeout),
+			}
+			if g.probeURL == "" {
+				g.probeURL = defaultStandbyProbeURL
+			}
+			if g.interval <= 0 {
+				g.interval = defaultStandbyProbeInterval
+			}
+			if g.timeout <= 0 {
+				g.timeout = defaultStandbyProbeTimeout
+			}
+			groups[c.Primary] = g
+		}
+		g.standbys = append(g.standbys, c.Tag)
+	}
+	return groups
+}
+
+// activeTag returns the tag of the outbound serving the traffic of the primary.
+func (g *standbyGroup) activeTag() string {
+	g.access.RLock()
+	defer g.access.RUnlock()
+	return g.active
+}
+
+// run probes the group every interval until ctx is done.
+func (g *standbyGroup) run(ctx context.Context, dispatcher routing.Dispatcher) {
+	g.clients = make(map[string]*http.Client, len(g.standbys)+1)
+	for _, tag := range append([]string{g.primary}, g.standbys...) {
+		g.clients[tag] = g.newClient(ctx, dispatcher, tag)
+	}
+	defer func() {
+		for _, client := range g.clients {
+			client.CloseIdleConnections()
+		}
+	}()
+
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		g.update(ctx, g.probe(ctx))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *standbyGroup) newClient(ctx context.Context, dispatcher routing.Dispatcher, tag string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(_ context.Context, network, addr string) (net.Conn, error) {
+				if tagged.Dialer == nil {
+					return nil, errors.New("tagged dialer is not available")
+				}
+				dest, err := net.ParseDestination(network + ":" + addr)
+				if err != nil {
+					return nil, err
+				}
+				return tagged.Dialer(ctx, dispatcher, dest, tag)
+			},
+			MaxIdleConnsPerHost: 1,
+			IdleConnTimeout:     2 * g.interval,
+		},
+		Timeout: g.timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// probe requests the probe URL through all outbounds of the group at once, and returns which
+// of them passed.
+func (g *standbyGroup) probe(ctx context.Context) map[string]bool {
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	healthy := make(map[string]bool, len(g.clients))
+	for tag, client := range g.clients {
+		wg.Add(1)
+		go func(tag string, client *http.Client) {
+			defer wg.Done()
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, g.probeURL, nil)
+			if err != nil {
+				return
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				errors.LogDebugInner(ctx, err, "standby probe of outbound [", tag, "] failed")
+				// Don't reuse a connection which may be broken.
+				client.CloseIdleConnections()
+				return
+			}
+			resp.Body.Close()
+			lock.Lock()
+			healthy[tag] = true
+			lock.Unlock()
+		}(tag, client)
+	}
+	wg.Wait()
+	return healthy
+}
+
+// update moves the traffic of the primary to the first healthy standby when the primary
+// fails, and back after it passes standbyRecovery probes in a row.
+func (g *standbyGroup) update(ctx context.Context, healthy map[string]bool) {
+	if ctx.Err() != nil {
+		return
+	}
+	g.access.Lock()
+	defer g.access.Unlock()
+
+	if healthy[g.primary] {
+		if g.active == g.primary {
+			return
+		}
+		g.recovered++
+		if g.recovered >= standbyRecovery || !healthy[g.active] {
+			errors.LogWarning(ctx, "outbound [", g.primary, "] recovered, taking traffic back from standby [", g.active, "]")
+			g.active = g.primary
+			g.recovered = 0
+		}
+		return
+	}
+	g.recovered = 0
+	if g.active != g.primary && healthy[g.active] {
+		return
+	}
+	for _, tag := range g.standbys {
+		if healthy[tag] {
+			errors.LogWarning(ctx, "outbound [", g.active, "] failed, switching traffic of [", g.primary, "] to standby [", tag, "]")
+			g.active = tag
+			return
+		}
+	}
+	// Without a healthy standby, the traffic stays where it is.
+}
+
+// ActiveFor implements outbound.StandbyManager.
+func (m *Manager) ActiveFor(tag string) string {
+	if g, found := m.standby[tag]; found {
+		return g.activeTag()
+	}
+	return tag
+}
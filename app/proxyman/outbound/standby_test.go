@@ -0,0 +1,47 @@
+package outbound
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/app/proxyman"
+)
+
+func TestStandbyFailover(t *testing.T) {
+	groups := newStandbyGroups([]*proxyman.StandbyConfig{
+		{Tag: "b", Primary: "a"},
+		{Tag: "c", Primary: "a"},
+	})
+	g := groups["a"]
+	if g == nil || len(g.standbys) != 2 || g.interval != defaultStandbyProbeInterval {
+		t.Fatal("unexpected groups: ", groups)
+	}
+	m := &Manager{standby: groups}
+	ctx := context.Background()
+
+	steps := []struct {
+		healthy map[string]bool
+		active  string
+	}{
+		{map[string]bool{"a": true, "b": true, "c": true}, "a"},
+		{map[string]bool{"b": true, "c": true}, "b"},
+		{map[string]bool{"c": true}, "c"},
+		// Nothing healthy: traffic stays.
+		{map[string]bool{}, "c"},
+		{map[string]bool{"a": true, "c": true}, "c"},
+		{map[string]bool{"a": true, "c": true}, "c"},
+		{map[string]bool{"a": true, "c": true}, "a"},
+		{map[string]bool{"b": true}, "b"},
+		// The standby failed while the primary recovers.
+		{map[string]bool{"a": true}, "a"},
+	}
+	for i, step := range steps {
+		g.update(ctx, step.healthy)
+		if active := m.ActiveFor("a"); active != step.active {
+			t.Error("step ", i, ": expected ", step.active, ", actual ", active)
+		}
+	}
+	if active := m.ActiveFor("b"); active != "b" {
+		t.Error("expected outbound without standby to serve itself, actual ", active)
+	}
+}
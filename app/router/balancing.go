@@ -2,6 +2,7 @@ package router
 
 import (
 	"context"
+	"sort"
 	sync "sync"
 
 	"github.com/xtls/xray-core/app/observatory"
@@ -20,8 +21,15 @@ type BalancingPrincipleTarget interface {
 	GetPrincipleTarget([]string) []string
 }
 
+// BalancingRuleStrategy is a BalancingStrategy which picks outbounds depending on the routing rule
+// of the traffic as well.
+type BalancingRuleStrategy interface {
+	PickOutboundForRule(candidates []string, ruleTag string) string
+}
+
 type RoundRobinStrategy struct {
-	FallbackTag string
+	FallbackTag    string
+	ObservatoryTag string
 
 	ctx         context.Context
 	observatory extension.Observatory
@@ -33,12 +41,27 @@ func (s *RoundRobinStrategy) InjectContext(ctx context.Context) {
 	s.ctx = ctx
 	if len(s.FallbackTag) > 0 {
 		common.Must(core.RequireFeatures(s.ctx, func(observatory extension.Observatory) error {
-			s.observatory = observatory
+			s.observatory = selectObservatory(ctx, observatory, s.ObservatoryTag)
 			return nil
 		}))
 	}
 }
 
+// selectObservatory returns the member tagged tag of o if o is a group of observatories, or o
+// itself if tag is empty or not found.
+func selectObservatory(ctx context.Context, o extension.Observatory, tag string) extension.Observatory {
+	if tag == "" {
+		return o
+	}
+	if g, ok := o.(extension.ObservatoryGroup); ok {
+		if member := g.GetObservatory(tag); member != nil {
+			return member
+		}
+	}
+	errors.LogWarning(ctx, "observatory ", tag, " not found, using observations of all observatories")
+	return o
+}
+
 func (s *RoundRobinStrategy) GetPrincipleTarget(strings []string) []string {
 	return strings
 }
@@ -93,6 +116,11 @@ type Balancer struct {
 
 // PickOutbound picks the tag of a outbound
 func (b *Balancer) PickOutbound() (string, error) {
+	return b.PickOutboundForRule("")
+}
+
+// PickOutboundForRule picks the tag of a outbound for the traffic of the routing rule tagged ruleTag.
+func (b *Balancer) PickOutboundForRule(ruleTag string) (string, error) {
 	candidates, err := b.SelectOutbounds()
 	if err != nil {
 		if b.fallbackTag != "" {
@@ -101,9 +129,21 @@ func (b *Balancer) PickOutbound() (string, error) {
 		}
 		return "", err
 	}
+	if mm, ok := b.ohm.(outbound.MaintenanceManager); ok {
+		candidates = excludeMaintenance(mm, candidates)
+		if len(candidates) == 0 {
+			if b.fallbackTag != "" {
+				errors.LogInfo(context.Background(), "fallback to [", b.fallbackTag, "], as all outbounds are in maintenance")
+				return b.fallbackTag, nil
+			}
+			return "", errors.New("all outbounds are in maintenance")
+		}
+	}
 	var tag string
 	if o := b.override.Get(); o != "" {
 		tag = o
+	} else if s, ok := b.strategy.(BalancingRuleStrategy); ok {
+		tag = s.PickOutboundForRule(candidates, ruleTag)
 	} else {
 		tag = b.strategy.PickOutbound(candidates)
 	}
@@ -118,6 +158,17 @@ func (b *Balancer) PickOutbound() (string, error) {
 	return tag, nil
 }
 
+// excludeMaintenance returns the tags which are not in maintenance.
+func excludeMaintenance(mm outbound.MaintenanceManager, tags []string) []string {
+	var available []string
+	for _, tag := range tags {
+		if !mm.InMaintenance(tag) {
+			available = append(available, tag)
+		}
+	}
+	return available
+}
+
 func (b *Balancer) InjectContext(ctx context.Context) {
 	if contextReceiver, ok := b.strategy.(extension.ContextReceiver); ok {
 		contextReceiver.InjectContext(ctx)
@@ -149,10 +200,29 @@ func (r *Router) GetPrincipleTarget(tag string) ([]string, error) {
 	return nil, errors.New("cannot find tag")
 }
 
+// GetCandidates implements routing.BalancerCandidates
+func (r *Router) GetCandidates(tag string) ([]string, error) {
+	if b, ok := r.balancers[tag]; ok {
+		return b.SelectOutbounds()
+	}
+	return nil, errors.New("cannot find tag")
+}
+
+// ListBalancers implements routing.BalancerLister
+func (r *Router) ListBalancers() []string {
+	tags := make([]string, 0, len(r.balancers))
+	for tag := range r.balancers {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
 // SetOverrideTarget implements routing.BalancerOverrider
 func (r *Router) SetOverrideTarget(tag, target string) error {
 	if b, ok := r.balancers[tag]; ok {
 		b.override.Put(target)
+		saveOverride(tag, target)
 		return nil
 	}
 	return errors.New("cannot find tag")
@@ -1,9 +1,11 @@
 package router
 
 import (
+	"context"
 	sync "sync"
 
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/storage"
 )
 
 func (r *Router) OverrideBalancer(balancer string, target string) error {
@@ -18,9 +20,37 @@ func (r *Router) OverrideBalancer(balancer string, target string) error {
 		return errors.New("balancer '", balancer, "' not found")
 	}
 	b.override.Put(target)
+	saveOverride(balancer, target)
 	return nil
 }
 
+// overrideEntry returns where the override of the balancer tagged tag is kept across restarts.
+func overrideEntry(tag string) storage.Entry {
+	return storage.Entry{Bucket: "balancer", Key: tag}
+}
+
+// saveOverride keeps the override of the balancer tagged tag across restarts, if there is a
+// state directory.
+func saveOverride(tag, target string) {
+	entry := overrideEntry(tag)
+	var err error
+	if target == "" {
+		err = entry.Delete()
+	} else {
+		err = entry.Save([]byte(target))
+	}
+	if err != nil {
+		errors.LogWarningInner(context.Background(), err, "failed to save the override of balancer ", tag)
+	}
+}
+
+// restoreOverride sets the override of b, tagged tag, saved before the last restart, if any.
+func restoreOverride(tag string, b *Balancer) {
+	if target, err := overrideEntry(tag).Load(); err == nil {
+		b.override.Put(string(target))
+	}
+}
+
 type overrideSettings struct {
 	target string
 }
@@ -0,0 +1,121 @@
+package router_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/outbound"
+	routing_session "github.com/xtls/xray-core/features/routing/session"
+)
+
+const xrayKey core.XrayKey = 1
+
+// maintenanceManager selects the outbounds tagged tags, those in down being in maintenance.
+type maintenanceManager struct {
+	outbound.Manager
+	tags []string
+	down map[string]bool
+}
+
+func (m *maintenanceManager) Select([]string) []string {
+	return m.tags
+}
+
+func (m *maintenanceManager) SetMaintenance(tag string, maintenance bool) error {
+	m.down[tag] = maintenance
+	return nil
+}
+
+func (m *maintenanceManager) InMaintenance(tag string) bool {
+	return m.down[tag]
+}
+
+func (m *maintenanceManager) GetMaintenance() []string {
+	var tags []string
+	for tag, down := range m.down {
+		if down {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func (m *maintenanceManager) Suspend(tag string, duration time.Duration) {
+	m.down[tag] = true
+}
+
+func newMaintenanceRouter(t *testing.T, ohm *maintenanceManager) *Router {
+	t.Helper()
+	config := &Config{
+		Rule: []*RoutingRule{
+			{
+				TargetTag: &RoutingRule_BalancingTag{
+					BalancingTag: "balance",
+				},
+				Networks: []net.Network{net.Network_TCP},
+			},
+		},
+		BalancingRule: []*BalancingRule{
+			{
+				Tag:              "balance",
+				OutboundSelector: []string{"test-"},
+				FallbackTag:      "fallback",
+			},
+		},
+	}
+	// The fallback of the balancer has it look for an observatory.
+	v, err := core.New(&core.Config{})
+	common.Must(err)
+	r := new(Router)
+	common.Must(r.Init(context.WithValue(context.Background(), xrayKey, v), config, nil, ohm, nil))
+	return r
+}
+
+func pickTag(t *testing.T, r *Router) string {
+	t.Helper()
+	ctx := session.ContextWithOutbounds(context.Background(), []*session.Outbound{{
+		Target: net.TCPDestination(net.DomainAddress("example.com"), 80),
+	}})
+	route, err := r.PickRoute(routing_session.AsRoutingContext(ctx))
+	common.Must(err)
+	return route.GetOutboundTag()
+}
+
+func TestBalancerSkipsMaintenance(t *testing.T) {
+	ohm := &maintenanceManager{
+		tags: []string{"test-a", "test-b", "test-c"},
+		down: map[string]bool{"test-a": true, "test-c": true},
+	}
+	r := newMaintenanceRouter(t, ohm)
+	for i := 0; i < 20; i++ {
+		if tag := pickTag(t, r); tag != "test-b" {
+			t.Fatal("picked ", tag, ", want the outbound not in maintenance")
+		}
+	}
+
+	common.Must(ohm.SetMaintenance("test-a", false))
+	picked := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		picked[pickTag(t, r)] = true
+	}
+	if !picked["test-a"] || picked["test-c"] {
+		t.Error("picked ", picked, ", want test-a back and test-c still skipped")
+	}
+}
+
+func TestBalancerFallsBackInMaintenance(t *testing.T) {
+	ohm := &maintenanceManager{
+		tags: []string{"test-a", "test-b"},
+		down: map[string]bool{"test-a": true, "test-b": true},
+	}
+	r := newMaintenanceRouter(t, ohm)
+	if tag := pickTag(t, r); tag != "fallback" {
+		t.Error("picked ", tag, ", want the fallback with all outbounds in maintenance")
+	}
+}
@@ -0,0 +1,252 @@
+package router
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/platform"
+	"github.com/xtls/xray-core/features/routing"
+	"go4.org/netipx"
+)
+
+const (
+	defaultBlocklistRefresh = 24 * time.Hour
+	blocklistTimeout        = time.Minute
+	// maxBlocklistSize caps the size of downloaded blocklists.
+	maxBlocklistSize = 64 * 1024 * 1024
+)
+
+// ipBlocklist is a list of IP networks downloaded from a URL, like Spamhaus DROP or the FireHOL
+// lists, and downloaded again periodically. The last copy is kept in the cache directory, so
+// that the list is in effect from the start, before it's downloaded.
+type ipBlocklist struct {
+	name    string
+	url     string
+	refresh time.Duration
+	set     atomic.Pointer[netipx.IPSet]
+
+	access sync.Mutex
+	cancel context.CancelFunc
+}
+
+var (
+	blocklistAccess sync.Mutex
+	blocklists      = make(map[string]*ipBlocklist) // by name
+)
+
+// registerBlocklist makes the blocklist of config known to rules by its name.
+func registerBlocklist(config *Blocklist) (*ipBlocklist, error) {
+	if config.Name == "" || strings.ContainsAny(config.Name, `/\:`) {
+		return nil, errors.New("invalid blocklist name: ", config.Name)
+	}
+	if !strings.HasPrefix(config.Url, "http://") && !strings.HasPrefix(config.Url, "https://") {
+		return nil, errors.New("blocklist ", config.Name, " has no HTTP URL")
+	}
+
+	blocklistAccess.Lock()
+	defer blocklistAccess.Unlock()
+
+	if b := blocklists[config.Name]; b != nil && b.url == config.Url {
+		return b, nil
+	}
+	b := &ipBlocklist{
+		name:    config.Name,
+		url:     config.Url,
+		refresh: time.Duration(config.Refresh) * time.Second,
+	}
+	if b.refresh == 0 {
+		b.refresh = defaultBlocklistRefresh
+	}
+	if path := b.cachePath(); path != "" {
+		if f, err := os.Open(path); err == nil {
+			if set, n, err := parseBlocklist(f); err == nil {
+				b.set.Store(set)
+				errors.LogInfo(context.Background(), "loaded ", n, " networks of blocklist ", b.name, " from cache")
+			}
+			f.Close()
+		}
+	}
+	blocklists[config.Name] = b
+	return b, nil
+}
+
+func findBlocklist(name string) *ipBlocklist {
+	blocklistAccess.Lock()
+	defer blocklistAccess.Unlock()
+
+	return blocklists[name]
+}
+
+func (b *ipBlocklist) cachePath() string {
+	dir := platform.GetCacheDirectory()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "blocklist-"+b.name+".txt")
+}
+
+// Match returns whether ip is in the list.
+func (b *ipBlocklist) Match(ip net.IP) bool {
+	set := b.set.Load()
+	if set == nil {
+		return false
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	return ok && set.Contains(addr.Unmap())
+}
+
+// start downloads the list now and on every refresh, until it's closed.
+func (b *ipBlocklist) start(ctx context.Context) {
+	b.access.Lock()
+	defer b.access.Unlock()
+
+	if b.cancel != nil {
+		return
+	}
+	ctx, b.cancel = context.WithCancel(ctx)
+	go func() {
+		for {
+			if err := b.update(ctx); err != nil {
+				errors.LogWarningInner(ctx, err, "failed to update blocklist ", b.name)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(b.refresh):
+			}
+		}
+	}()
+}
+
+func (b *ipBlocklist) close() {
+	b.access.Lock()
+	defer b.access.Unlock()
+
+	if b.cancel != nil {
+		b.cancel()
+		b.cancel = nil
+	}
+}
+
+func (b *ipBlocklist) update(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, blocklistTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("unexpected status ", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBlocklistSize))
+	if err != nil {
+		return err
+	}
+	set, n, err := parseBlocklist(strings.NewReader(string(data)))
+	if err != nil {
+		return err
+	}
+	b.set.Store(set)
+	errors.LogInfo(ctx, "updated blocklist ", b.name, " with ", n, " networks")
+
+	if path := b.cachePath(); path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+			tmp := path + ".tmp"
+			if err := os.WriteFile(tmp, data, 0o644); err == nil {
+				os.Rename(tmp, path)
+			}
+		}
+	}
+	return nil
+}
+
+// parseBlocklist parses a list of an IP or a network per line. Comments start with "#" or ";",
+// as in the lists of FireHOL and Spamhaus, and invalid lines are skipped. It returns the set and
+// the number of entries in it.
+func parseBlocklist(r io.Reader) (*netipx.IPSet, int, error) {
+	var builder netipx.IPSetBuilder
+	n := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexAny(line, "#;"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(fields[0]); err == nil {
+			builder.AddPrefix(prefix.Masked())
+			n++
+		} else if addr, err := netip.ParseAddr(fields[0]); err == nil {
+			builder.Add(addr.Unmap())
+			n++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	if n == 0 {
+		return nil, 0, errors.New("no network in blocklist")
+	}
+	set, err := builder.IPSet()
+	if err != nil {
+		return nil, 0, err
+	}
+	return set, n, nil
+}
+
+// BlocklistMatcher matches the target or source IPs against blocklists.
+type BlocklistMatcher struct {
+	lists    []*ipBlocklist
+	onSource bool
+}
+
+// NewBlocklistMatcher creates a matcher of the blocklists with the names, which must be known.
+func NewBlocklistMatcher(names []string, onSource bool) (*BlocklistMatcher, error) {
+	m := &BlocklistMatcher{onSource: onSource}
+	for _, name := range names {
+		b := findBlocklist(name)
+		if b == nil {
+			return nil, errors.New("unknown blocklist: ", name)
+		}
+		m.lists = append(m.lists, b)
+	}
+	return m, nil
+}
+
+// Apply implements Condition.
+func (m *BlocklistMatcher) Apply(ctx routing.Context) bool {
+	var ips []net.IP
+	if m.onSource {
+		ips = ctx.GetSourceIPs()
+	} else {
+		ips = ctx.GetTargetIPs()
+	}
+	for _, ip := range ips {
+		for _, b := range m.lists {
+			if b.Match(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package router
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestParseBlocklist(t *testing.T) {
+	list := `; Spamhaus DROP List
+1.10.16.0/20 ; SBL256894
+# FireHOL
+192.0.2.1
+2001:db8::/32	comment
+not an ip
+`
+	set, n, err := parseBlocklist(strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Error("expected 3 networks, got ", n)
+	}
+	for ip, expected := range map[string]bool{
+		"1.10.20.1":   true,
+		"1.10.32.1":   false,
+		"192.0.2.1":   true,
+		"192.0.2.2":   false,
+		"2001:db8::1": true,
+		"2001:db9::1": false,
+	} {
+		if set.Contains(netip.MustParseAddr(ip)) != expected {
+			t.Error("unexpected match of ", ip)
+		}
+	}
+
+	if _, _, err := parseBlocklist(strings.NewReader("# empty\n")); err == nil {
+		t.Error("expected an error for an empty list")
+	}
+}
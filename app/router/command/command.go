@@ -2,20 +2,30 @@ package command
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
 	"time"
 
+	"github.com/xtls/xray-core/app/router"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/geodata"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/routing"
 	"github.com/xtls/xray-core/features/stats"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 )
 
 // routingServer is an implementation of RoutingService.
 type routingServer struct {
 	router       routing.Router
 	routingStats stats.Channel
+	// source reloads the config after geo data is replaced, nil if it can't be.
+	source interface {
+		ReloadFromSource() (*core.ReloadResult, error)
+	}
 }
 
 func (s *routingServer) GetBalancerInfo(ctx context.Context, request *GetBalancerInfoRequest) (*GetBalancerInfoResponse, error) {
@@ -67,6 +77,72 @@ func (s *routingServer) RemoveRule(ctx context.Context, request *RemoveRuleReque
 	return nil, errors.New("unsupported router implementation")
 }
 
+func (s *routingServer) SetRuleGroup(ctx context.Context, request *SetRuleGroupRequest) (*SetRuleGroupResponse, error) {
+	if gm, ok := s.router.(routing.RuleGroupManager); ok {
+		return &SetRuleGroupResponse{}, gm.SetRuleGroupEnabled(request.Group, request.Enabled)
+	}
+	return nil, errors.New("unsupported router implementation")
+}
+
+func (s *routingServer) ListRuleGroups(ctx context.Context, request *ListRuleGroupsRequest) (*ListRuleGroupsResponse, error) {
+	gm, ok := s.router.(routing.RuleGroupManager)
+	if !ok {
+		return nil, errors.New("unsupported router implementation")
+	}
+	response := &ListRuleGroupsResponse{}
+	for _, group := range gm.GetRuleGroups() {
+		response.Groups = append(response.Groups, &RuleGroupInfo{
+			Name:    group.Name,
+			Enabled: group.Enabled,
+			Rules:   int32(group.Rules),
+		})
+	}
+	return response, nil
+}
+
+func (s *routingServer) UpdateGeodata(ctx context.Context, request *UpdateGeodataRequest) (*UpdateGeodataResponse, error) {
+	if s.source == nil {
+		return nil, errors.New("unsupported server implementation")
+	}
+	data := request.Data
+	if request.Url != "" {
+		if len(data) > 0 {
+			return nil, errors.New("both data and url are set")
+		}
+		var err error
+		if data, err = geodata.Download(ctx, nil, request.Url); err != nil {
+			return nil, errors.New("failed to download ", request.Url).Base(err)
+		}
+	}
+	sum := sha256.Sum256(data)
+	response := &UpdateGeodataResponse{Sha256: hex.EncodeToString(sum[:])}
+	if request.Sha256 != "" && !strings.EqualFold(request.Sha256, response.Sha256) {
+		return nil, errors.New("checksum mismatch, got ", response.Sha256)
+	}
+	// geoip.dat and geosite.dat files share the layout of GeoSiteList on the wire.
+	list := new(router.GeoSiteList)
+	if err := proto.Unmarshal(data, list); err != nil || len(list.Entry) == 0 {
+		return nil, errors.New("invalid geo data for ", request.File).Base(err)
+	}
+
+	changed, err := geodata.Install(request.File, data)
+	if err != nil {
+		return nil, errors.New("failed to replace ", request.File).Base(err)
+	}
+	response.Changed = changed
+	if !changed {
+		return response, nil
+	}
+	errors.LogInfo(ctx, "replaced ", request.File, " through API, reloading config")
+	result, err := s.source.ReloadFromSource()
+	if err != nil {
+		return nil, errors.New(request.File, " replaced, but failed to reload config").Base(err)
+	}
+	response.RoutingChanged = result.RoutingChanged
+	response.RestartRequired = result.RestartRequired
+	return response, nil
+}
+
 // NewRoutingServer creates a statistics service with statistics manager.
 func NewRoutingServer(router routing.Router, routingStats stats.Channel) RoutingServiceServer {
 	return &routingServer{
@@ -128,7 +204,7 @@ type service struct {
 
 func (s *service) Register(server *grpc.Server) {
 	common.Must(s.v.RequireFeatures(func(router routing.Router, stats stats.Manager) {
-		rs := NewRoutingServer(router, nil)
+		rs := &routingServer{router: router, source: s.v}
 		RegisterRoutingServiceServer(server, rs)
 
 		// For compatibility purposes
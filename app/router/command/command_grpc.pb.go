@@ -25,6 +25,9 @@ const (
 	RoutingService_OverrideBalancerTarget_FullMethodName = "/xray.app.router.command.RoutingService/OverrideBalancerTarget"
 	RoutingService_AddRule_FullMethodName                = "/xray.app.router.command.RoutingService/AddRule"
 	RoutingService_RemoveRule_FullMethodName             = "/xray.app.router.command.RoutingService/RemoveRule"
+	RoutingService_SetRuleGroup_FullMethodName           = "/xray.app.router.command.RoutingService/SetRuleGroup"
+	RoutingService_ListRuleGroups_FullMethodName         = "/xray.app.router.command.RoutingService/ListRuleGroups"
+	RoutingService_UpdateGeodata_FullMethodName          = "/xray.app.router.command.RoutingService/UpdateGeodata"
 )
 
 // RoutingServiceClient is the client API for RoutingService service.
@@ -37,6 +40,9 @@ type RoutingServiceClient interface {
 	OverrideBalancerTarget(ctx context.Context, in *OverrideBalancerTargetRequest, opts ...grpc.CallOption) (*OverrideBalancerTargetResponse, error)
 	AddRule(ctx context.Context, in *AddRuleRequest, opts ...grpc.CallOption) (*AddRuleResponse, error)
 	RemoveRule(ctx context.Context, in *RemoveRuleRequest, opts ...grpc.CallOption) (*RemoveRuleResponse, error)
+	SetRuleGroup(ctx context.Context, in *SetRuleGroupRequest, opts ...grpc.CallOption) (*SetRuleGroupResponse, error)
+	ListRuleGroups(ctx context.Context, in *ListRuleGroupsRequest, opts ...grpc.CallOption) (*ListRuleGroupsResponse, error)
+	UpdateGeodata(ctx context.Context, in *UpdateGeodataRequest, opts ...grpc.CallOption) (*UpdateGeodataResponse, error)
 }
 
 type routingServiceClient struct {
@@ -116,6 +122,36 @@ func (c *routingServiceClient) RemoveRule(ctx context.Context, in *RemoveRuleReq
 	return out, nil
 }
 
+func (c *routingServiceClient) SetRuleGroup(ctx context.Context, in *SetRuleGroupRequest, opts ...grpc.CallOption) (*SetRuleGroupResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetRuleGroupResponse)
+	err := c.cc.Invoke(ctx, RoutingService_SetRuleGroup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routingServiceClient) ListRuleGroups(ctx context.Context, in *ListRuleGroupsRequest, opts ...grpc.CallOption) (*ListRuleGroupsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRuleGroupsResponse)
+	err := c.cc.Invoke(ctx, RoutingService_ListRuleGroups_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routingServiceClient) UpdateGeodata(ctx context.Context, in *UpdateGeodataRequest, opts ...grpc.CallOption) (*UpdateGeodataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateGeodataResponse)
+	err := c.cc.Invoke(ctx, RoutingService_UpdateGeodata_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RoutingServiceServer is the server API for RoutingService service.
 // All implementations must embed UnimplementedRoutingServiceServer
 // for forward compatibility.
@@ -126,6 +162,9 @@ type RoutingServiceServer interface {
 	OverrideBalancerTarget(context.Context, *OverrideBalancerTargetRequest) (*OverrideBalancerTargetResponse, error)
 	AddRule(context.Context, *AddRuleRequest) (*AddRuleResponse, error)
 	RemoveRule(context.Context, *RemoveRuleRequest) (*RemoveRuleResponse, error)
+	SetRuleGroup(context.Context, *SetRuleGroupRequest) (*SetRuleGroupResponse, error)
+	ListRuleGroups(context.Context, *ListRuleGroupsRequest) (*ListRuleGroupsResponse, error)
+	UpdateGeodata(context.Context, *UpdateGeodataRequest) (*UpdateGeodataResponse, error)
 	mustEmbedUnimplementedRoutingServiceServer()
 }
 
@@ -154,6 +193,15 @@ func (UnimplementedRoutingServiceServer) AddRule(context.Context, *AddRuleReques
 func (UnimplementedRoutingServiceServer) RemoveRule(context.Context, *RemoveRuleRequest) (*RemoveRuleResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RemoveRule not implemented")
 }
+func (UnimplementedRoutingServiceServer) SetRuleGroup(context.Context, *SetRuleGroupRequest) (*SetRuleGroupResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetRuleGroup not implemented")
+}
+func (UnimplementedRoutingServiceServer) ListRuleGroups(context.Context, *ListRuleGroupsRequest) (*ListRuleGroupsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRuleGroups not implemented")
+}
+func (UnimplementedRoutingServiceServer) UpdateGeodata(context.Context, *UpdateGeodataRequest) (*UpdateGeodataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateGeodata not implemented")
+}
 func (UnimplementedRoutingServiceServer) mustEmbedUnimplementedRoutingServiceServer() {}
 func (UnimplementedRoutingServiceServer) testEmbeddedByValue()                        {}
 
@@ -276,6 +324,60 @@ func _RoutingService_RemoveRule_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RoutingService_SetRuleGroup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRuleGroupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RoutingServiceServer).SetRuleGroup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RoutingService_SetRuleGroup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RoutingServiceServer).SetRuleGroup(ctx, req.(*SetRuleGroupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RoutingService_ListRuleGroups_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRuleGroupsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RoutingServiceServer).ListRuleGroups(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RoutingService_ListRuleGroups_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RoutingServiceServer).ListRuleGroups(ctx, req.(*ListRuleGroupsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RoutingService_UpdateGeodata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateGeodataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RoutingServiceServer).UpdateGeodata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RoutingService_UpdateGeodata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RoutingServiceServer).UpdateGeodata(ctx, req.(*UpdateGeodataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // RoutingService_ServiceDesc is the grpc.ServiceDesc for RoutingService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -303,6 +405,18 @@ var RoutingService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RemoveRule",
 			Handler:    _RoutingService_RemoveRule_Handler,
 		},
+		{
+			MethodName: "SetRuleGroup",
+			Handler:    _RoutingService_SetRuleGroup_Handler,
+		},
+		{
+			MethodName: "ListRuleGroups",
+			Handler:    _RoutingService_ListRuleGroups_Handler,
+		},
+		{
+			MethodName: "UpdateGeodata",
+			Handler:    _RoutingService_UpdateGeodata_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -0,0 +1,66 @@
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/core"
+	"google.golang.org/protobuf/proto"
+)
+
+type fakeSource struct {
+	reloads int
+}
+
+func (s *fakeSource) ReloadFromSource() (*core.ReloadResult, error) {
+	s.reloads++
+	return &core.ReloadResult{RoutingChanged: true}, nil
+}
+
+func TestUpdateGeodata(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("xray.location.asset", dir)
+	source := new(fakeSource)
+	s := &routingServer{source: source}
+
+	data, err := proto.Marshal(&router.GeoSiteList{Entry: []*router.GeoSite{{
+		CountryCode: "TEST",
+		Domain:      []*router.Domain{{Type: router.Domain_Domain, Value: "example.com"}},
+	}}})
+	common.Must(err)
+	request := &UpdateGeodataRequest{File: "geosite.dat", Data: data}
+	response, err := s.UpdateGeodata(context.Background(), request)
+	common.Must(err)
+	if !response.Changed || !response.RoutingChanged || source.reloads != 1 {
+		t.Error("first update: ", response, source.reloads)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "geosite.dat")); !proto.Equal(mustUnmarshal(b), mustUnmarshal(data)) {
+		t.Error("file not replaced")
+	}
+
+	request.Sha256 = response.Sha256
+	if response, err := s.UpdateGeodata(context.Background(), request); err != nil || response.Changed || source.reloads != 1 {
+		t.Error("same data reloaded: ", response, err)
+	}
+
+	for _, r := range []*UpdateGeodataRequest{
+		{File: "geosite.dat", Data: data, Sha256: "00"},
+		{File: "geosite.dat", Data: []byte("not geo data")},
+		{File: "../geosite.dat", Data: data},
+		{File: "geosite.dat"},
+	} {
+		if _, err := s.UpdateGeodata(context.Background(), r); err == nil {
+			t.Error("expected an error for ", r)
+		}
+	}
+}
+
+func mustUnmarshal(b []byte) *router.GeoSiteList {
+	list := new(router.GeoSiteList)
+	common.Must(proto.Unmarshal(b, list))
+	return list
+}
@@ -14,6 +14,17 @@ type Condition interface {
 	Apply(ctx routing.Context) bool
 }
 
+// memorySizer is implemented by Conditions which hold large lists, to tell the memory they use.
+type memorySizer interface {
+	MemorySize() int64
+}
+
+// releaser is implemented by Conditions sharing data with other rules, to give it up once
+// their rule is removed.
+type releaser interface {
+	release()
+}
+
 type ConditionChan []Condition
 
 func NewConditionChan() *ConditionChan {
@@ -40,6 +51,25 @@ func (v *ConditionChan) Len() int {
 	return len(*v)
 }
 
+// MemorySize implements memorySizer.
+func (v *ConditionChan) MemorySize() int64 {
+	var size int64
+	for _, cond := range *v {
+		if s, ok := cond.(memorySizer); ok {
+			size += s.MemorySize()
+		}
+	}
+	return size
+}
+
+func (v *ConditionChan) release() {
+	for _, cond := range *v {
+		if r, ok := cond.(releaser); ok {
+			r.release()
+		}
+	}
+}
+
 var matcherTypeMap = map[Domain_Type]strmatcher.Type{
 	Domain_Plain:  strmatcher.Substr,
 	Domain_Regex:  strmatcher.Regex,
@@ -63,6 +93,9 @@ func domainToMatcher(domain *Domain) (strmatcher.Matcher, error) {
 
 type DomainMatcher struct {
 	matchers strmatcher.IndexMatcher
+	size     int64
+	// exclude matches the exceptions among the domains, if there are any.
+	exclude *DomainMatcher
 }
 
 func NewMphMatcherGroup(domains []*Domain) (*DomainMatcher, error) {
@@ -80,26 +113,48 @@ func NewMphMatcherGroup(domains []*Domain) (*DomainMatcher, error) {
 	g.Build()
 	return &DomainMatcher{
 		matchers: g,
+		size:     int64(g.MemorySize()),
 	}, nil
 }
 
 func NewDomainMatcher(domains []*Domain) (*DomainMatcher, error) {
 	g := new(strmatcher.MatcherGroup)
+	var size int64
 	for _, d := range domains {
 		m, err := domainToMatcher(d)
 		if err != nil {
 			return nil, err
 		}
 		g.Add(m)
+		// The pattern, and its entry in a map or list.
+		size += int64(len(d.Value)) + 48
 	}
 
 	return &DomainMatcher{
 		matchers: g,
+		size:     size,
 	}, nil
 }
 
 func (m *DomainMatcher) ApplyDomain(domain string) bool {
-	return len(m.matchers.Match(strings.ToLower(domain))) > 0
+	domain = strings.ToLower(domain)
+	if len(m.matchers.Match(domain)) == 0 {
+		return false
+	}
+	return m.exclude == nil || len(m.exclude.matchers.Match(domain)) == 0
+}
+
+// Exclude makes m reject the domains matched by exclude, even if they match m.
+func (m *DomainMatcher) Exclude(exclude *DomainMatcher) {
+	m.exclude = exclude
+}
+
+// MemorySize implements memorySizer.
+func (m *DomainMatcher) MemorySize() int64 {
+	if m.exclude != nil {
+		return m.size + m.exclude.size
+	}
+	return m.size
 }
 
 // Apply implements Condition.
@@ -114,6 +169,7 @@ func (m *DomainMatcher) Apply(ctx routing.Context) bool {
 type MultiGeoIPMatcher struct {
 	matchers []*GeoIPMatcher
 	onSource bool
+	onLocal  bool
 }
 
 func NewMultiGeoIPMatcher(geoips []*GeoIP, onSource bool) (*MultiGeoIPMatcher, error) {
@@ -121,6 +177,9 @@ func NewMultiGeoIPMatcher(geoips []*GeoIP, onSource bool) (*MultiGeoIPMatcher, e
 	for _, geoip := range geoips {
 		matcher, err := globalGeoIPContainer.Add(geoip)
 		if err != nil {
+			for _, m := range matchers {
+				globalGeoIPContainer.Release(m)
+			}
 			return nil, err
 		}
 		matchers = append(matchers, matcher)
@@ -134,12 +193,30 @@ func NewMultiGeoIPMatcher(geoips []*GeoIP, onSource bool) (*MultiGeoIPMatcher, e
 	return matcher, nil
 }
 
+// MemorySize implements memorySizer.
+func (m *MultiGeoIPMatcher) MemorySize() int64 {
+	var size int64
+	for _, matcher := range m.matchers {
+		size += matcher.size
+	}
+	return size
+}
+
+func (m *MultiGeoIPMatcher) release() {
+	for _, matcher := range m.matchers {
+		globalGeoIPContainer.Release(matcher)
+	}
+}
+
 // Apply implements Condition.
 func (m *MultiGeoIPMatcher) Apply(ctx routing.Context) bool {
 	var ips []net.IP
-	if m.onSource {
+	switch {
+	case m.onLocal:
+		ips = ctx.GetLocalIPs()
+	case m.onSource:
 		ips = ctx.GetSourceIPs()
-	} else {
+	default:
 		ips = ctx.GetTargetIPs()
 	}
 	for _, ip := range ips {
@@ -155,6 +232,7 @@ func (m *MultiGeoIPMatcher) Apply(ctx routing.Context) bool {
 type PortMatcher struct {
 	port     net.MemoryPortList
 	onSource bool
+	onLocal  bool
 }
 
 // NewPortMatcher create a new port matcher that can match source or destination port
@@ -165,8 +243,19 @@ func NewPortMatcher(list *net.PortList, onSource bool) *PortMatcher {
 	}
 }
 
+// NewLocalPortMatcher creates a new port matcher that matches the local port the inbound accepted the connection on.
+func NewLocalPortMatcher(list *net.PortList) *PortMatcher {
+	return &PortMatcher{
+		port:    net.PortListFromProto(list),
+		onLocal: true,
+	}
+}
+
 // Apply implements Condition.
 func (v *PortMatcher) Apply(ctx routing.Context) bool {
+	if v.onLocal {
+		return v.port.Contains(ctx.GetLocalPort())
+	}
 	if v.onSource {
 		return v.port.Contains(ctx.GetSourcePort())
 	} else {
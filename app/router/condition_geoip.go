@@ -3,6 +3,7 @@ package router
 import (
 	"net/netip"
 	"strconv"
+	"sync"
 
 	"github.com/xtls/xray-core/common/net"
 	"go4.org/netipx"
@@ -13,6 +14,8 @@ type GeoIPMatcher struct {
 	reverseMatch bool
 	ip4          *netipx.IPSet
 	ip6          *netipx.IPSet
+	size         int64 // estimated memory in bytes
+	refs         int   // rules using it, while in a GeoIPMatcherContainer
 }
 
 func (m *GeoIPMatcher) Init(cidrs []*CIDR) error {
@@ -46,6 +49,10 @@ func (m *GeoIPMatcher) Init(cidrs []*CIDR) error {
 		m.ip6 = ip6
 	}
 
+	// An IPSet holds ranges of two netip.Addr each.
+	const rangeSize = 2 * 24
+	m.size = rangeSize * int64(len(m.ip4.Ranges())+len(m.ip6.Ranges()))
+
 	return nil
 }
 
@@ -88,18 +95,16 @@ func (m *GeoIPMatcher) Match(ip net.IP) bool {
 
 // GeoIPMatcherContainer is a container for GeoIPMatchers. It keeps unique copies of GeoIPMatcher by country code.
 type GeoIPMatcherContainer struct {
+	access   sync.Mutex
 	matchers []*GeoIPMatcher
 }
 
 // Add adds a new GeoIP set into the container.
 // If the country code of GeoIP is not empty, GeoIPMatcherContainer will try to find an existing one, instead of adding a new one.
+// It's safe to call from several goroutines, which build distinct sets in parallel.
 func (c *GeoIPMatcherContainer) Add(geoip *GeoIP) (*GeoIPMatcher, error) {
-	if len(geoip.CountryCode) > 0 {
-		for _, m := range c.matchers {
-			if m.countryCode == geoip.CountryCode && m.reverseMatch == geoip.ReverseMatch {
-				return m, nil
-			}
-		}
+	if m := c.acquire(geoip); m != nil {
+		return m, nil
 	}
 
 	m := &GeoIPMatcher{
@@ -110,9 +115,56 @@ func (c *GeoIPMatcherContainer) Add(geoip *GeoIP) (*GeoIPMatcher, error) {
 		return nil, err
 	}
 	if len(geoip.CountryCode) > 0 {
+		c.access.Lock()
+		defer c.access.Unlock()
+		if existing := c.findLocked(geoip); existing != nil {
+			existing.refs++
+			return existing, nil
+		}
+		m.refs = 1
 		c.matchers = append(c.matchers, m)
 	}
 	return m, nil
 }
 
+// Release gives up a GeoIPMatcher returned by Add. It's removed from the container once no
+// one uses it, so that the memory of GeoIP sets no longer in use is freed.
+func (c *GeoIPMatcherContainer) Release(m *GeoIPMatcher) {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	for i, existing := range c.matchers {
+		if existing == m {
+			m.refs--
+			if m.refs <= 0 {
+				c.matchers = append(c.matchers[:i], c.matchers[i+1:]...)
+			}
+			return
+		}
+	}
+}
+
+func (c *GeoIPMatcherContainer) acquire(geoip *GeoIP) *GeoIPMatcher {
+	if len(geoip.CountryCode) == 0 {
+		return nil
+	}
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	m := c.findLocked(geoip)
+	if m != nil {
+		m.refs++
+	}
+	return m
+}
+
+func (c *GeoIPMatcherContainer) findLocked(geoip *GeoIP) *GeoIPMatcher {
+	for _, m := range c.matchers {
+		if m.countryCode == geoip.CountryCode && m.reverseMatch == geoip.ReverseMatch {
+			return m
+		}
+	}
+	return nil
+}
+
 var globalGeoIPContainer GeoIPMatcherContainer
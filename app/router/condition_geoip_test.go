@@ -62,6 +62,30 @@ func TestGeoIPMatcherContainer(t *testing.T) {
 	}
 }
 
+func TestGeoIPMatcherContainerRelease(t *testing.T) {
+	container := &router.GeoIPMatcherContainer{}
+
+	m1, err := container.Add(&router.GeoIP{CountryCode: "CN"})
+	common.Must(err)
+	m2, err := container.Add(&router.GeoIP{CountryCode: "CN"})
+	common.Must(err)
+
+	container.Release(m1)
+	m3, err := container.Add(&router.GeoIP{CountryCode: "CN"})
+	common.Must(err)
+	if m3 != m2 {
+		t.Error("expect the matcher to be kept while in use, but not")
+	}
+
+	container.Release(m2)
+	container.Release(m3)
+	m4, err := container.Add(&router.GeoIP{CountryCode: "CN"})
+	common.Must(err)
+	if m4 == m1 {
+		t.Error("expect the matcher to be freed once released, but not")
+	}
+}
+
 func TestGeoIPMatcher(t *testing.T) {
 	cidrList := []*router.CIDR{
 		{Ip: []byte{0, 0, 0, 0}, Prefix: 8},
@@ -0,0 +1,69 @@
+package router
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/process"
+	"github.com/xtls/xray-core/features/routing"
+)
+
+// ProcessMatcher matches connections by the local process they come from, which is looked up
+// through the source address, so that applications on the same machine can be routed apart.
+type ProcessMatcher struct {
+	names []string
+	paths []string
+}
+
+// NewProcessMatcher returns a matcher of processes, given by the name of their executable, like
+// "firefox" or "firefox.exe", or its full path. Names and paths are matched regardless of case.
+func NewProcessMatcher(processes []string) *ProcessMatcher {
+	m := &ProcessMatcher{}
+	for _, p := range processes {
+		switch {
+		case p == "":
+		case strings.ContainsAny(p, `/\`):
+			m.paths = append(m.paths, filepath.Clean(p))
+		default:
+			if ext := filepath.Ext(p); strings.EqualFold(ext, ".exe") {
+				p = p[:len(p)-len(ext)]
+			}
+			m.names = append(m.names, p)
+		}
+	}
+	return m
+}
+
+// Apply implements Condition.
+func (m *ProcessMatcher) Apply(ctx routing.Context) bool {
+	ips := ctx.GetSourceIPs()
+	if len(ips) == 0 {
+		return false
+	}
+	network := ctx.GetNetwork()
+	if network != net.Network_UDP {
+		network = net.Network_TCP
+	}
+	info, err := process.FindOwner(network, net.Destination{
+		Network: network,
+		Address: net.IPAddress(ips[0]),
+		Port:    ctx.GetSourcePort(),
+	})
+	if err != nil {
+		return false
+	}
+	for _, name := range m.names {
+		if strings.EqualFold(name, info.Name) {
+			return true
+		}
+	}
+	if info.Path != "" {
+		for _, path := range m.paths {
+			if strings.EqualFold(path, info.Path) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,7 +1,10 @@
 package router_test
 
 import (
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 
 	. "github.com/xtls/xray-core/app/router"
@@ -9,6 +12,7 @@ import (
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/platform/filesystem"
+	"github.com/xtls/xray-core/common/process"
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/protocol/http"
 	"github.com/xtls/xray-core/common/session"
@@ -91,6 +95,36 @@ func TestRoutingRule(t *testing.T) {
 				},
 			},
 		},
+		{
+			rule: &RoutingRule{
+				Domain: []*Domain{
+					{
+						Value: "example.com",
+						Type:  Domain_Domain,
+					},
+				},
+				ExcludedDomain: []*Domain{
+					{
+						Value: "ads.example.com",
+						Type:  Domain_Full,
+					},
+				},
+			},
+			test: []ruleTest{
+				{
+					input:  withOutbound(&session.Outbound{Target: net.TCPDestination(net.DomainAddress("www.example.com"), 80)}),
+					output: true,
+				},
+				{
+					input:  withOutbound(&session.Outbound{Target: net.TCPDestination(net.DomainAddress("ads.example.com"), 80)}),
+					output: false,
+				},
+				{
+					input:  withOutbound(&session.Outbound{Target: net.TCPDestination(net.DomainAddress("x.ads.example.com"), 80)}),
+					output: true,
+				},
+			},
+		},
 		{
 			rule: &RoutingRule{
 				Geoip: []*GeoIP{
@@ -258,6 +292,32 @@ func TestRoutingRule(t *testing.T) {
 				},
 			},
 		},
+		{
+			rule: &RoutingRule{
+				LocalPortList: &net.PortList{
+					Range: []*net.PortRange{{From: 8443, To: 8443}},
+				},
+				LocalGeoip: []*GeoIP{
+					{
+						Cidr: []*CIDR{{Ip: []byte{10, 0, 0, 1}, Prefix: 32}},
+					},
+				},
+			},
+			test: []ruleTest{
+				{
+					input:  withInbound(&session.Inbound{Gateway: net.TCPDestination(net.ParseAddress("10.0.0.1"), 8443)}),
+					output: true,
+				},
+				{
+					input:  withInbound(&session.Inbound{Gateway: net.TCPDestination(net.ParseAddress("10.0.0.1"), 8444)}),
+					output: false,
+				},
+				{
+					input:  withInbound(&session.Inbound{Gateway: net.TCPDestination(net.ParseAddress("10.0.0.2"), 8443)}),
+					output: false,
+				},
+			},
+		},
 		{
 			rule: &RoutingRule{
 				Protocol: []string{"http"},
@@ -506,3 +566,40 @@ func BenchmarkMultiGeoIPMatcher(b *testing.B) {
 		_ = matcher.Apply(ctx)
 	}
 }
+
+func TestProcessRule(t *testing.T) {
+	executable, err := os.Executable()
+	common.Must(err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx := &routing_session.Context{
+		Inbound:  &session.Inbound{Source: net.DestinationFromAddr(conn.LocalAddr())},
+		Outbound: &session.Outbound{Target: net.TCPDestination(net.DomainAddress("example.com"), 80)},
+	}
+	if _, err := process.FindOwner(net.Network_TCP, ctx.Inbound.Source); err != nil {
+		t.Skip("finding processes is not supported: ", err)
+	}
+
+	for _, test := range []struct {
+		process []string
+		output  bool
+	}{
+		{process: []string{filepath.Base(executable)}, output: true},
+		{process: []string{strings.ToUpper(filepath.Base(executable))}, output: true},
+		{process: []string{"firefox", executable}, output: true},
+		{process: []string{"firefox", "/usr/bin/slack"}, output: false},
+	} {
+		cond, err := (&RoutingRule{Process: test.process}).BuildCondition()
+		common.Must(err)
+		if actual := cond.Apply(ctx); actual != test.output {
+			t.Error("process ", test.process, ": expected ", test.output, ", but got ", actual)
+		}
+	}
+}
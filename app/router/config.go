@@ -4,8 +4,10 @@ import (
 	"context"
 	"regexp"
 	"strings"
+	"sync/atomic"
 
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/task"
 	"github.com/xtls/xray-core/features/outbound"
 	"github.com/xtls/xray-core/features/routing"
 )
@@ -13,43 +15,92 @@ import (
 type Rule struct {
 	Tag       string
 	RuleTag   string
+	Priority  int32
 	Balancer  *Balancer
 	Condition Condition
+	Mirror    *routing.Mirror
+
+	BandwidthClass *routing.BandwidthClass
+
+	group   *ruleGroup
+	latency *latencyBudget
+}
+
+// ruleGroup is a named set of rules which can be disabled as a whole.
+type ruleGroup struct {
+	disabled atomic.Bool
 }
 
 func (r *Rule) GetTag() (string, error) {
+	if r.latency != nil && !r.latency.met(r.candidates()) {
+		errors.LogDebug(context.Background(), "latency budget of rule ", r.RuleTag, " not met, fallback to [", r.latency.fallbackTag, "]")
+		return r.latency.pickFallback(r.RuleTag)
+	}
 	if r.Balancer != nil {
-		return r.Balancer.PickOutbound()
+		return r.Balancer.PickOutboundForRule(r.RuleTag)
 	}
 	return r.Tag, nil
 }
 
 // Apply checks rule matching of current routing context.
 func (r *Rule) Apply(ctx routing.Context) bool {
+	if r.group != nil && r.group.disabled.Load() {
+		return false
+	}
 	return r.Condition.Apply(ctx)
 }
 
+// Build creates the routing.Mirror of the config. It returns nil for a nil config.
+func (c *MirrorConfig) Build() *routing.Mirror {
+	if c == nil {
+		return nil
+	}
+	return &routing.Mirror{
+		OutboundTag: c.OutboundTag,
+		Address:     c.Address,
+		SampleRate:  c.SampleRate,
+		MaxBytes:    int64(c.MaxBytes),
+		Downlink:    c.Downlink,
+	}
+}
+
+// buildDomainMatcher builds a matcher of domains, of the kind the rule asks for.
+func (rr *RoutingRule) buildDomainMatcher(domains []*Domain) (*DomainMatcher, error) {
+	switch rr.DomainMatcher {
+	case "linear":
+		matcher, err := NewDomainMatcher(domains)
+		if err != nil {
+			return nil, errors.New("failed to build domain condition").Base(err)
+		}
+		return matcher, nil
+	case "mph", "hybrid":
+		fallthrough
+	default:
+		matcher, err := NewMphMatcherGroup(domains)
+		if err != nil {
+			return nil, errors.New("failed to build domain condition with MphDomainMatcher").Base(err)
+		}
+		errors.LogDebug(context.Background(), "MphDomainMatcher is enabled for ", len(domains), " domain rule(s)")
+		return matcher, nil
+	}
+}
+
 func (rr *RoutingRule) BuildCondition() (Condition, error) {
 	conds := NewConditionChan()
 
 	if len(rr.Domain) > 0 {
-		switch rr.DomainMatcher {
-		case "linear":
-			matcher, err := NewDomainMatcher(rr.Domain)
-			if err != nil {
-				return nil, errors.New("failed to build domain condition").Base(err)
-			}
-			conds.Add(matcher)
-		case "mph", "hybrid":
-			fallthrough
-		default:
-			matcher, err := NewMphMatcherGroup(rr.Domain)
+		matcher, err := rr.buildDomainMatcher(rr.Domain)
+		if err != nil {
+			return nil, err
+		}
+		if len(rr.ExcludedDomain) > 0 {
+			exclude, err := rr.buildDomainMatcher(rr.ExcludedDomain)
 			if err != nil {
-				return nil, errors.New("failed to build domain condition with MphDomainMatcher").Base(err)
+				return nil, errors.New("failed to build excluded domains").Base(err)
 			}
-			errors.LogDebug(context.Background(), "MphDomainMatcher is enabled for ", len(rr.Domain), " domain rule(s)")
-			conds.Add(matcher)
+			matcher.Exclude(exclude)
 		}
+		conds.Add(matcher)
 	}
 
 	if len(rr.UserEmail) > 0 {
@@ -68,6 +119,10 @@ func (rr *RoutingRule) BuildCondition() (Condition, error) {
 		conds.Add(NewPortMatcher(rr.SourcePortList, true))
 	}
 
+	if rr.LocalPortList != nil {
+		conds.Add(NewLocalPortMatcher(rr.LocalPortList))
+	}
+
 	if len(rr.Networks) > 0 {
 		conds.Add(NewNetworkMatcher(rr.Networks))
 	}
@@ -88,6 +143,31 @@ func (rr *RoutingRule) BuildCondition() (Condition, error) {
 		conds.Add(cond)
 	}
 
+	if len(rr.LocalGeoip) > 0 {
+		cond, err := NewMultiGeoIPMatcher(rr.LocalGeoip, false)
+		if err != nil {
+			return nil, err
+		}
+		cond.onLocal = true
+		conds.Add(cond)
+	}
+
+	if len(rr.Blocklist) > 0 {
+		cond, err := NewBlocklistMatcher(rr.Blocklist, false)
+		if err != nil {
+			return nil, err
+		}
+		conds.Add(cond)
+	}
+
+	if len(rr.SourceBlocklist) > 0 {
+		cond, err := NewBlocklistMatcher(rr.SourceBlocklist, true)
+		if err != nil {
+			return nil, err
+		}
+		conds.Add(cond)
+	}
+
 	if len(rr.Protocol) > 0 {
 		conds.Add(NewProtocolMatcher(rr.Protocol))
 	}
@@ -100,6 +180,11 @@ func (rr *RoutingRule) BuildCondition() (Condition, error) {
 		conds.Add(&AttributeMatcher{configuredKeys})
 	}
 
+	// Looking up the process of a connection is the most costly, so it's done last.
+	if len(rr.Process) > 0 {
+		conds.Add(NewProcessMatcher(rr.Process))
+	}
+
 	if conds.Len() == 0 {
 		return nil, errors.New("this rule has no effective fields").AtWarning()
 	}
@@ -107,20 +192,36 @@ func (rr *RoutingRule) BuildCondition() (Condition, error) {
 	return conds, nil
 }
 
+// buildConditions builds the conditions of rules in parallel, as the matchers of large domain
+// and IP lists take a while each.
+func buildConditions(rules []*RoutingRule) ([]Condition, error) {
+	conds := make([]Condition, len(rules))
+	err := task.ForEach(len(rules), func(i int) error {
+		cond, err := rules[i].BuildCondition()
+		conds[i] = cond
+		return err
+	})
+	if err != nil {
+		releaseConditions(conds)
+		return nil, err
+	}
+	return conds, nil
+}
+
 // Build builds the balancing rule
 func (br *BalancingRule) Build(ohm outbound.Manager, dispatcher routing.Dispatcher) (*Balancer, error) {
 	switch strings.ToLower(br.Strategy) {
 	case "leastping":
 		return &Balancer{
 			selectors:   br.OutboundSelector,
-			strategy:    &LeastPingStrategy{},
+			strategy:    &LeastPingStrategy{ObservatoryTag: br.ObservatoryTag},
 			fallbackTag: br.FallbackTag,
 			ohm:         ohm,
 		}, nil
 	case "roundrobin":
 		return &Balancer{
 			selectors:   br.OutboundSelector,
-			strategy:    &RoundRobinStrategy{FallbackTag: br.FallbackTag},
+			strategy:    &RoundRobinStrategy{FallbackTag: br.FallbackTag, ObservatoryTag: br.ObservatoryTag},
 			fallbackTag: br.FallbackTag,
 			ohm:         ohm,
 		}, nil
@@ -134,12 +235,48 @@ func (br *BalancingRule) Build(ohm outbound.Manager, dispatcher routing.Dispatch
 			return nil, errors.New("not a StrategyLeastLoadConfig").AtError()
 		}
 		leastLoadStrategy := NewLeastLoadStrategy(s)
+		leastLoadStrategy.ObservatoryTag = br.ObservatoryTag
 		return &Balancer{
 			selectors:   br.OutboundSelector,
 			ohm:         ohm,
 			fallbackTag: br.FallbackTag,
 			strategy:    leastLoadStrategy,
 		}, nil
+	case "selector":
+		i, err := br.StrategySettings.GetInstance()
+		if err != nil {
+			return nil, err
+		}
+		s, ok := i.(*StrategySelectorConfig)
+		if !ok {
+			return nil, errors.New("not a StrategySelectorConfig").AtError()
+		}
+		selectorStrategy := NewSelectorStrategy(s)
+		selectorStrategy.ObservatoryTag = br.ObservatoryTag
+		return &Balancer{
+			selectors:   br.OutboundSelector,
+			ohm:         ohm,
+			fallbackTag: br.FallbackTag,
+			strategy:    selectorStrategy,
+		}, nil
+	case "quota":
+		i, err := br.StrategySettings.GetInstance()
+		if err != nil {
+			return nil, err
+		}
+		s, ok := i.(*StrategyQuotaConfig)
+		if !ok {
+			return nil, errors.New("not a StrategyQuotaConfig").AtError()
+		}
+		quotaStrategy := NewQuotaStrategy(s)
+		quotaStrategy.ObservatoryTag = br.ObservatoryTag
+		quotaStrategy.BalancerTag = br.Tag
+		return &Balancer{
+			selectors:   br.OutboundSelector,
+			ohm:         ohm,
+			fallbackTag: br.FallbackTag,
+			strategy:    quotaStrategy,
+		}, nil
 	case "random":
 		fallthrough
 	case "":
@@ -147,7 +284,7 @@ func (br *BalancingRule) Build(ohm outbound.Manager, dispatcher routing.Dispatch
 			selectors:   br.OutboundSelector,
 			ohm:         ohm,
 			fallbackTag: br.FallbackTag,
-			strategy:    &RandomStrategy{FallbackTag: br.FallbackTag},
+			strategy:    &RandomStrategy{FallbackTag: br.FallbackTag, ObservatoryTag: br.ObservatoryTag},
 		}, nil
 	default:
 		return nil, errors.New("unrecognized balancer type")
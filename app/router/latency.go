@@ -0,0 +1,97 @@
+package router
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/app/observatory"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/extension"
+	"github.com/xtls/xray-core/features/outbound"
+)
+
+// latencyBudget sends the connections of a rule to a fallback, unless the best alive outbound the
+// rule goes to is observed below a round trip time. Outbounds not observed yet don't meet it.
+type latencyBudget struct {
+	// budget is in milliseconds, as the delays of the observatory.
+	budget      int64
+	fallbackTag string
+	// fallback is the balancer tagged fallbackTag, if any, or fallbackTag is of an outbound.
+	fallback *Balancer
+
+	ctx         context.Context
+	observatory extension.Observatory
+}
+
+// buildLatencyBudget builds the latency budget of rule, nil if it has none.
+func (r *Router) buildLatencyBudget(rule *RoutingRule) (*latencyBudget, error) {
+	if rule.GetLatencyBudget() == 0 {
+		return nil, nil
+	}
+	tag := rule.GetLatencyFallbackTag()
+	if tag == "" {
+		return nil, errors.New("no fallback of the latency budget")
+	}
+	l := &latencyBudget{
+		budget:      int64(rule.GetLatencyBudget()),
+		fallbackTag: tag,
+		fallback:    r.balancers[tag],
+		ctx:         r.ctx,
+	}
+	if err := core.RequireFeatures(r.ctx, func(observatory extension.Observatory) error {
+		l.observatory = observatory
+		return nil
+	}); err != nil {
+		return nil, errors.New("the latency budget needs an observatory").Base(err)
+	}
+	return l, nil
+}
+
+// met returns whether the best alive one of candidates is observed below the budget.
+func (l *latencyBudget) met(candidates []string) bool {
+	if l.observatory == nil {
+		return false
+	}
+	report, err := l.observatory.GetObservation(l.ctx)
+	if err != nil {
+		errors.LogInfoInner(l.ctx, err, "cannot get observer report")
+		return false
+	}
+	result, ok := report.(*observatory.ObservationResult)
+	if !ok {
+		return false
+	}
+	return withinLatencyBudget(outboundList(candidates), result.Status, l.budget)
+}
+
+func withinLatencyBudget(candidates outboundList, status []*observatory.OutboundStatus, budget int64) bool {
+	for _, s := range status {
+		if s.Alive && s.Delay < budget && candidates.contains(s.OutboundTag) {
+			return true
+		}
+	}
+	return false
+}
+
+// pickFallback returns the tag of the fallback outbound for the traffic of the rule tagged ruleTag.
+func (l *latencyBudget) pickFallback(ruleTag string) (string, error) {
+	if l.fallback != nil {
+		return l.fallback.PickOutboundForRule(ruleTag)
+	}
+	return l.fallbackTag, nil
+}
+
+// candidates returns the tags of the outbounds the rule r may go to.
+func (r *Rule) candidates() []string {
+	if r.Balancer == nil {
+		return []string{r.Tag}
+	}
+	candidates, err := r.Balancer.SelectOutbounds()
+	if err != nil {
+		return nil
+	}
+	if mm, ok := r.Balancer.ohm.(outbound.MaintenanceManager); ok {
+		candidates = excludeMaintenance(mm, candidates)
+	}
+	return candidates
+}
@@ -0,0 +1,80 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/app/observatory"
+	"github.com/xtls/xray-core/features/extension"
+	"github.com/xtls/xray-core/features/outbound"
+	"google.golang.org/protobuf/proto"
+)
+
+type fakeObservatory struct {
+	extension.Observatory
+	status []*observatory.OutboundStatus
+}
+
+func (o *fakeObservatory) GetObservation(ctx context.Context) (proto.Message, error) {
+	return &observatory.ObservationResult{Status: o.status}, nil
+}
+
+type fakeSelector struct {
+	outbound.Manager
+	tags []string
+}
+
+func (s *fakeSelector) Select([]string) []string {
+	return s.tags
+}
+
+func TestLatencyBudget(t *testing.T) {
+	o := &fakeObservatory{}
+	latency := func(fallback *Balancer) *latencyBudget {
+		return &latencyBudget{budget: 150, fallbackTag: "any", fallback: fallback, ctx: context.Background(), observatory: o}
+	}
+	fast := &Balancer{
+		selectors: []string{"fast-"},
+		strategy:  &LeastPingStrategy{ctx: context.Background(), observatory: o},
+		ohm:       &fakeSelector{tags: []string{"fast-a", "fast-b"}},
+	}
+	direct := &Rule{Tag: "direct", latency: latency(nil)}
+	games := &Rule{Balancer: fast, latency: latency(&Balancer{
+		strategy: &RandomStrategy{},
+		ohm:      &fakeSelector{tags: []string{"any-a"}},
+	})}
+
+	steps := []struct {
+		status      []*observatory.OutboundStatus
+		games, want string
+	}{
+		// Nothing is observed yet.
+		{nil, "any-a", "any"},
+		{
+			[]*observatory.OutboundStatus{
+				{OutboundTag: "fast-a", Alive: true, Delay: 200},
+				{OutboundTag: "fast-b", Alive: true, Delay: 100},
+				{OutboundTag: "direct", Alive: true, Delay: 20},
+			},
+			"fast-b", "direct",
+		},
+		// The best node is dead, and the rest are too slow.
+		{
+			[]*observatory.OutboundStatus{
+				{OutboundTag: "fast-a", Alive: true, Delay: 150},
+				{OutboundTag: "fast-b", Alive: false, Delay: 100},
+				{OutboundTag: "direct", Alive: true, Delay: 300},
+			},
+			"any-a", "any",
+		},
+	}
+	for i, step := range steps {
+		o.status = step.status
+		if tag, err := games.GetTag(); err != nil || tag != step.games {
+			t.Errorf("step %d: balancer rule got %q (%v), want %q", i, tag, err, step.games)
+		}
+		if tag, err := direct.GetTag(); err != nil || tag != step.want {
+			t.Errorf("step %d: outbound rule got %q (%v), want %q", i, tag, err, step.want)
+		}
+	}
+}
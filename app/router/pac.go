@@ -0,0 +1,219 @@
+package router
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// pacDomains are the domains of a rule, by matching type.
+type pacDomains struct {
+	Full   map[string]int `json:"full,omitempty"`
+	Domain map[string]int `json:"domain,omitempty"`
+	Plain  []string       `json:"plain,omitempty"`
+	Regex  []string       `json:"regex,omitempty"`
+}
+
+// pacRule is a rule as evaluated by the PAC script.
+type pacRule struct {
+	Direct   bool        `json:"direct"`
+	Domains  *pacDomains `json:"domains,omitempty"`
+	Excluded *pacDomains `json:"excluded,omitempty"`
+	// CIDRs are IPv4 networks, as pairs of address and mask.
+	CIDRs   [][2]uint32 `json:"cidrs,omitempty"`
+	Reverse bool        `json:"reverse,omitempty"`
+}
+
+func newPACDomains(domains []*Domain) *pacDomains {
+	if len(domains) == 0 {
+		return nil
+	}
+	d := &pacDomains{}
+	for _, domain := range domains {
+		value := strings.ToLower(domain.GetValue())
+		switch domain.GetType() {
+		case Domain_Full:
+			if d.Full == nil {
+				d.Full = make(map[string]int)
+			}
+			d.Full[value] = 1
+		case Domain_Domain:
+			if d.Domain == nil {
+				d.Domain = make(map[string]int)
+			}
+			d.Domain[value] = 1
+		case Domain_Plain:
+			d.Plain = append(d.Plain, value)
+		case Domain_Regex:
+			d.Regex = append(d.Regex, domain.GetValue())
+		}
+	}
+	return d
+}
+
+// pacRules returns the rules of c a PAC script can evaluate, in the order the router does: those
+// matching target domains only, or target IPs only. Rules with other conditions are left out, as a
+// PAC script only knows the host.
+func (c *Config) pacRules(directTags []string) []pacRule {
+	direct := make(map[string]bool, len(directTags))
+	for _, tag := range directTags {
+		direct[tag] = true
+	}
+	rules := append([]*RoutingRule(nil), c.GetRule()...)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].GetPriority() > rules[j].GetPriority()
+	})
+
+	var result []pacRule
+	for _, rule := range rules {
+		if len(rule.PortList.GetRange()) > 0 || len(rule.Networks) > 0 || len(rule.SourceGeoip) > 0 ||
+			len(rule.SourcePortList.GetRange()) > 0 || len(rule.UserEmail) > 0 || len(rule.InboundTag) > 0 ||
+			len(rule.Protocol) > 0 || len(rule.Attributes) > 0 || len(rule.LocalPortList.GetRange()) > 0 ||
+			len(rule.LocalGeoip) > 0 || len(rule.Blocklist) > 0 || len(rule.SourceBlocklist) > 0 {
+			continue
+		}
+		if (len(rule.Domain) > 0) == (len(rule.Geoip) > 0) {
+			continue
+		}
+		r := pacRule{
+			Direct:   rule.GetTag() != "" && direct[rule.GetTag()],
+			Domains:  newPACDomains(rule.Domain),
+			Excluded: newPACDomains(rule.ExcludedDomain),
+		}
+		if len(rule.Geoip) > 0 {
+			// A reversed GeoIP among others isn't supported.
+			if len(rule.Geoip) > 1 && anyReversed(rule.Geoip) {
+				continue
+			}
+			r.Reverse = rule.Geoip[0].GetReverseMatch()
+			for _, geoip := range rule.Geoip {
+				for _, cidr := range geoip.Cidr {
+					if len(cidr.Ip) != 4 || cidr.Prefix > 32 {
+						continue
+					}
+					mask := ^uint32(0) << (32 - cidr.Prefix)
+					if cidr.Prefix == 0 {
+						mask = 0
+					}
+					r.CIDRs = append(r.CIDRs, [2]uint32{binary.BigEndian.Uint32(cidr.Ip) & mask, mask})
+				}
+			}
+			if len(r.CIDRs) == 0 && !r.Reverse {
+				continue
+			}
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+func anyReversed(geoips []*GeoIP) bool {
+	for _, geoip := range geoips {
+		if geoip.GetReverseMatch() {
+			return true
+		}
+	}
+	return false
+}
+
+// PAC returns a proxy auto-config script routing hosts as the rules of c do, as far as a script
+// given the host only can tell. IP rules only apply to IPv4 hosts, without resolving domains.
+// Hosts routed to the outbounds tagged directTags are connected directly, and others through
+// proxy, e.g. "SOCKS5 127.0.0.1:1080". Hosts matching no rule are connected directly if
+// defaultDirect.
+func (c *Config) PAC(proxy string, directTags []string, defaultDirect bool) ([]byte, error) {
+	rules, err := json.Marshal(c.pacRules(directTags))
+	if err != nil {
+		return nil, errors.New("failed to encode PAC rules").Base(err)
+	}
+	p, err := json.Marshal(proxy)
+	if err != nil {
+		return nil, errors.New("failed to encode PAC proxy").Base(err)
+	}
+	return []byte(fmt.Sprintf(pacTemplate, p, defaultDirect, rules)), nil
+}
+
+const pacTemplate = `// Generated by Xray from its routing rules.
+var proxy = %s;
+var defaultDirect = %t;
+var rules = %s;
+
+function compileRegex(d) {
+  if (!d || !d.regex) return;
+  var compiled = [];
+  for (var i = 0; i < d.regex.length; i++) {
+    try {
+      compiled.push(new RegExp(d.regex[i]));
+    } catch (e) {}
+  }
+  d.regex = compiled;
+}
+
+for (var r = 0; r < rules.length; r++) {
+  compileRegex(rules[r].domains);
+  compileRegex(rules[r].excluded);
+}
+
+function matchDomains(d, host) {
+  if (!d) return false;
+  if (d.full && d.full.hasOwnProperty(host)) return true;
+  if (d.domain) {
+    for (var h = host; ; ) {
+      if (d.domain.hasOwnProperty(h)) return true;
+      var dot = h.indexOf(".");
+      if (dot < 0) break;
+      h = h.substring(dot + 1);
+    }
+  }
+  var i;
+  if (d.plain) {
+    for (i = 0; i < d.plain.length; i++) {
+      if (host.indexOf(d.plain[i]) >= 0) return true;
+    }
+  }
+  if (d.regex) {
+    for (i = 0; i < d.regex.length; i++) {
+      if (d.regex[i].test(host)) return true;
+    }
+  }
+  return false;
+}
+
+function parseIPv4(host) {
+  var m = /^(\d+)\.(\d+)\.(\d+)\.(\d+)$/.exec(host);
+  if (!m) return -1;
+  return ((m[1] << 24) | (m[2] << 16) | (m[3] << 8) | m[4]) >>> 0;
+}
+
+function matchCIDRs(rule, ip) {
+  var cidrs = rule.cidrs || [];
+  var matched = false;
+  for (var i = 0; i < cidrs.length; i++) {
+    if (((ip & cidrs[i][1]) >>> 0) == cidrs[i][0]) {
+      matched = true;
+      break;
+    }
+  }
+  return matched != !!rule.reverse;
+}
+
+function FindProxyForURL(url, host) {
+  host = host.toLowerCase().replace(/\.$/, "");
+  var ip = parseIPv4(host);
+  for (var i = 0; i < rules.length; i++) {
+    var rule = rules[i];
+    var matched;
+    if (rule.domains) {
+      matched = matchDomains(rule.domains, host) && !matchDomains(rule.excluded, host);
+    } else {
+      matched = ip >= 0 && matchCIDRs(rule, ip);
+    }
+    if (matched) return rule.direct ? "DIRECT" : proxy;
+  }
+  return defaultDirect ? "DIRECT" : proxy;
+}
+`
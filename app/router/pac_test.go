@@ -0,0 +1,66 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/xtls/xray-core/common/net"
+)
+
+func TestPACRules(t *testing.T) {
+	config := &Config{
+		Rule: []*RoutingRule{
+			{
+				TargetTag: &RoutingRule_Tag{Tag: "direct"},
+				Domain: []*Domain{
+					{Type: Domain_Domain, Value: "Example.com"},
+					{Type: Domain_Full, Value: "www.example.org"},
+					{Type: Domain_Plain, Value: "local"},
+					{Type: Domain_Regex, Value: `^a\d+\.example\.net$`},
+				},
+				ExcludedDomain: []*Domain{{Type: Domain_Full, Value: "proxied.example.com"}},
+			},
+			{
+				TargetTag: &RoutingRule_Tag{Tag: "direct"},
+				Geoip: []*GeoIP{{Cidr: []*CIDR{
+					{Ip: []byte{192, 168, 0, 0}, Prefix: 16},
+					{Ip: net.ParseAddress("fc00::").IP(), Prefix: 7},
+				}}},
+			},
+			// Rules with conditions the script can't evaluate are left out.
+			{
+				TargetTag:  &RoutingRule_Tag{Tag: "direct"},
+				Domain:     []*Domain{{Type: Domain_Domain, Value: "example.net"}},
+				InboundTag: []string{"socks"},
+			},
+			{
+				TargetTag: &RoutingRule_BalancingTag{BalancingTag: "proxies"},
+				Domain:    []*Domain{{Type: Domain_Domain, Value: "example.io"}},
+				Priority:  1,
+			},
+		},
+	}
+
+	want := []pacRule{
+		{
+			Domains: &pacDomains{Domain: map[string]int{"example.io": 1}},
+		},
+		{
+			Direct: true,
+			Domains: &pacDomains{
+				Full:   map[string]int{"www.example.org": 1},
+				Domain: map[string]int{"example.com": 1},
+				Plain:  []string{"local"},
+				Regex:  []string{`^a\d+\.example\.net$`},
+			},
+			Excluded: &pacDomains{Full: map[string]int{"proxied.example.com": 1}},
+		},
+		{
+			Direct: true,
+			CIDRs:  [][2]uint32{{0xc0a80000, 0xffff0000}},
+		},
+	}
+	if r := cmp.Diff(config.pacRules([]string{"direct"}), want); r != "" {
+		t.Error(r)
+	}
+}
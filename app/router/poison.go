@@ -0,0 +1,78 @@
+package router
+
+import (
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/features/routing"
+	routing_dns "github.com/xtls/xray-core/features/routing/dns"
+)
+
+// poisonCheck re-resolves domains whose locally resolved IPs route them directly, while a rule
+// matching the domain itself routes it elsewhere, which is typical of poisoned answers.
+type poisonCheck struct {
+	dnsTag string
+	direct map[string]bool
+	lookup dns.TaggedLookup
+}
+
+func newPoisonCheck(config *PoisonCheck, d dns.Client) (*poisonCheck, error) {
+	lookup, ok := d.(dns.TaggedLookup)
+	if !ok {
+		return nil, errors.New("poison check requires the DNS module")
+	}
+	direct := make(map[string]bool, len(config.DirectOutbound))
+	for _, tag := range config.DirectOutbound {
+		direct[tag] = true
+	}
+	return &poisonCheck{
+		dnsTag: config.DnsTag,
+		direct: direct,
+		lookup: lookup,
+	}, nil
+}
+
+// checkPoison returns the route of rule matched by the resolved context, or the route by the
+// answer of the tagged name servers if rule is direct only because of the locally resolved IPs.
+func (r *Router) checkPoison(rule *Rule, plain routing.Context, resolved routing.Context) (*Rule, routing.Context, error) {
+	check := r.poisonCheck
+	if _, isResolved := resolved.(*routing_dns.ResolvableContext); check == nil || !isResolved || rule.Balancer != nil || !check.direct[rule.Tag] || rule.Apply(plain) {
+		return rule, resolved, nil
+	}
+	var proxied *Rule
+	for _, rr := range r.rules {
+		if (rr.Balancer != nil || !check.direct[rr.Tag]) && rr.Apply(plain) {
+			proxied = rr
+			break
+		}
+	}
+	if proxied == nil {
+		return rule, resolved, nil
+	}
+
+	domain := plain.GetTargetDomain()
+	ips, err := check.lookup.LookupIPWithTag(domain, check.dnsTag, dns.IPOption{
+		IPv4Enable: true,
+		IPv6Enable: true,
+	})
+	if err != nil {
+		errors.LogInfoInner(r.ctx, err, "failed to re-resolve ", domain, " at name servers ", check.dnsTag)
+		return rule, resolved, nil
+	}
+
+	remote := routing_dns.ContextWithResolvedIPs(plain, ips)
+	var picked *Rule
+	for _, rr := range r.rules {
+		if rr.Apply(remote) {
+			picked = rr
+			break
+		}
+	}
+	if picked != rule {
+		errors.LogWarning(r.ctx, "likely poisoned answer for ", domain, ": ", resolved.GetTargetIPs(), " routes it to ", rule.Tag, ", but ", ips, " from name servers ", check.dnsTag, " does not")
+	}
+	if picked == nil {
+		return nil, remote, common.ErrNoClue
+	}
+	return picked, remote, nil
+}
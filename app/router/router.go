@@ -2,6 +2,7 @@ package router
 
 import (
 	"context"
+	"sort"
 	sync "sync"
 
 	"github.com/xtls/xray-core/common"
@@ -10,8 +11,10 @@ import (
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
 	routing_dns "github.com/xtls/xray-core/features/routing/dns"
+	"github.com/xtls/xray-core/features/stats"
 )
 
 // Router is an implementation of routing.Router.
@@ -19,12 +22,19 @@ type Router struct {
 	domainStrategy Config_DomainStrategy
 	rules          []*Rule
 	balancers      map[string]*Balancer
+	groups         map[string]*ruleGroup
+	classes        map[string]*routing.BandwidthClass
+	blocklists     []*ipBlocklist
+	poisonCheck    *poisonCheck
 	dns            dns.Client
 
 	ctx        context.Context
 	ohm        outbound.Manager
 	dispatcher routing.Dispatcher
-	mu         sync.Mutex
+	stats      stats.Manager
+	// ruleStats counts the connections and traffic of the rules with tags.
+	ruleStats bool
+	mu        sync.Mutex
 }
 
 // Route is an implementation of routing.Route.
@@ -33,6 +43,8 @@ type Route struct {
 	outboundGroupTags []string
 	outboundTag       string
 	ruleTag           string
+	mirror            *routing.Mirror
+	bandwidthClass    *routing.BandwidthClass
 }
 
 // Init initializes the Router.
@@ -50,19 +62,45 @@ func (r *Router) Init(ctx context.Context, config *Config, d dns.Client, ohm out
 			return err
 		}
 		balancer.InjectContext(ctx)
+		restoreOverride(rule.Tag, balancer)
 		r.balancers[rule.Tag] = balancer
 	}
 
-	r.rules = make([]*Rule, 0, len(config.Rule))
-	for _, rule := range config.Rule {
-		cond, err := rule.BuildCondition()
+	r.groups = make(map[string]*ruleGroup)
+	r.applyRuleGroups(config.RuleGroup)
+
+	r.classes = make(map[string]*routing.BandwidthClass)
+	r.applyBandwidthClasses(config.BandwidthClass)
+
+	for _, bl := range config.Blocklist {
+		b, err := registerBlocklist(bl)
+		if err != nil {
+			return err
+		}
+		r.blocklists = append(r.blocklists, b)
+	}
+
+	if config.PoisonCheck != nil {
+		check, err := newPoisonCheck(config.PoisonCheck, d)
 		if err != nil {
 			return err
 		}
+		r.poisonCheck = check
+	}
+
+	r.rules = make([]*Rule, 0, len(config.Rule))
+	conds, err := buildConditions(config.Rule)
+	if err != nil {
+		return err
+	}
+	for i, rule := range config.Rule {
 		rr := &Rule{
-			Condition: cond,
+			Condition: conds[i],
 			Tag:       rule.GetTag(),
 			RuleTag:   rule.GetRuleTag(),
+			Priority:  rule.GetPriority(),
+			Mirror:    rule.GetMirror().Build(),
+			group:     r.getRuleGroup(rule.GetGroup()),
 		}
 		btag := rule.GetBalancingTag()
 		if len(btag) > 0 {
@@ -72,8 +110,19 @@ func (r *Router) Init(ctx context.Context, config *Config, d dns.Client, ohm out
 			}
 			rr.Balancer = brule
 		}
+		if class := rule.GetBandwidthClass(); class != "" {
+			rr.BandwidthClass = r.classes[class]
+			if rr.BandwidthClass == nil {
+				return errors.New("bandwidth class ", class, " not found")
+			}
+		}
+		if rr.latency, err = r.buildLatencyBudget(rule); err != nil {
+			return err
+		}
 		r.rules = append(r.rules, rr)
+		r.trackRule(rr)
 	}
+	r.sortRules()
 
 	return nil
 }
@@ -88,7 +137,7 @@ func (r *Router) PickRoute(ctx routing.Context) (routing.Route, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Route{Context: ctx, outboundTag: tag, ruleTag: rule.RuleTag}, nil
+	return &Route{Context: ctx, outboundTag: tag, ruleTag: rule.RuleTag, mirror: rule.Mirror, bandwidthClass: rule.BandwidthClass}, nil
 }
 
 // AddRule implements routing.Router.
@@ -105,53 +154,219 @@ func (r *Router) AddRule(config *serial.TypedMessage, shouldAppend bool) error {
 }
 
 func (r *Router) ReloadRules(config *Config, shouldAppend bool) error {
+	// The matchers are built before the lock, so that routing goes on with the current rules
+	// meanwhile, and keeps them if any fails to build.
+	conds, err := buildConditions(config.Rule)
+	if err != nil {
+		return err
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if !shouldAppend {
+		for _, rule := range r.rules {
+			r.releaseRule(rule)
+		}
+		for _, b := range r.balancers {
+			common.Close(b.strategy)
+		}
 		r.balancers = make(map[string]*Balancer, len(config.BalancingRule))
 		r.rules = make([]*Rule, 0, len(config.Rule))
 	}
+	r.applyRuleGroups(config.RuleGroup)
+	r.applyBandwidthClasses(config.BandwidthClass)
 	for _, rule := range config.BalancingRule {
 		_, found := r.balancers[rule.Tag]
 		if found {
+			releaseConditions(conds)
 			return errors.New("duplicate balancer tag")
 		}
 		balancer, err := rule.Build(r.ohm, r.dispatcher)
 		if err != nil {
+			releaseConditions(conds)
 			return err
 		}
 		balancer.InjectContext(r.ctx)
+		restoreOverride(rule.Tag, balancer)
 		r.balancers[rule.Tag] = balancer
 	}
 
-	for _, rule := range config.Rule {
+	for i, rule := range config.Rule {
 		if r.RuleExists(rule.GetRuleTag()) {
+			releaseConditions(conds[i:])
 			return errors.New("duplicate ruleTag ", rule.GetRuleTag())
 		}
-		cond, err := rule.BuildCondition()
-		if err != nil {
-			return err
-		}
 		rr := &Rule{
-			Condition: cond,
+			Condition: conds[i],
 			Tag:       rule.GetTag(),
 			RuleTag:   rule.GetRuleTag(),
+			Priority:  rule.GetPriority(),
+			Mirror:    rule.GetMirror().Build(),
+			group:     r.getRuleGroup(rule.GetGroup()),
 		}
 		btag := rule.GetBalancingTag()
 		if len(btag) > 0 {
 			brule, found := r.balancers[btag]
 			if !found {
+				releaseConditions(conds[i:])
 				return errors.New("balancer ", btag, " not found")
 			}
 			rr.Balancer = brule
 		}
+		if class := rule.GetBandwidthClass(); class != "" {
+			rr.BandwidthClass = r.classes[class]
+			if rr.BandwidthClass == nil {
+				releaseConditions(conds[i:])
+				return errors.New("bandwidth class ", class, " not found")
+			}
+		}
+		if rr.latency, err = r.buildLatencyBudget(rule); err != nil {
+			releaseConditions(conds[i:])
+			return err
+		}
 		r.rules = append(r.rules, rr)
+		r.trackRule(rr)
+	}
+	r.sortRules()
+
+	return nil
+}
+
+// ruleStatsCounters are the counters of the connections of a rule and their traffic, which the
+// dispatcher counts.
+var ruleStatsCounters = []string{"connections", "traffic>>>uplink", "traffic>>>downlink"}
+
+// trackRule publishes the memory used by the matchers of rule as the counter
+// "rule>>>[ruleTag]>>>memory", if the rule has a tag. With rule stats, it also registers the
+// counters of its connections and traffic, so that rules never matched show as well.
+func (r *Router) trackRule(rule *Rule) {
+	if r.stats == nil || rule.RuleTag == "" {
+		return
+	}
+	if s, ok := rule.Condition.(memorySizer); ok {
+		if c, _ := stats.GetOrRegisterCounter(r.stats, routing.RuleCounterName(rule.RuleTag, "memory")); c != nil {
+			c.Set(s.MemorySize())
+		}
+	}
+	if r.ruleStats {
+		for _, name := range ruleStatsCounters {
+			stats.GetOrRegisterCounter(r.stats, routing.RuleCounterName(rule.RuleTag, name))
+		}
+	}
+}
+
+// releaseRule frees what a removed rule holds.
+func (r *Router) releaseRule(rule *Rule) {
+	releaseConditions([]Condition{rule.Condition})
+	if r.stats != nil && rule.RuleTag != "" {
+		r.stats.UnregisterCounter(routing.RuleCounterName(rule.RuleTag, "memory"))
+		if r.ruleStats {
+			for _, name := range ruleStatsCounters {
+				r.stats.UnregisterCounter(routing.RuleCounterName(rule.RuleTag, name))
+			}
+		}
+	}
+}
+
+func releaseConditions(conds []Condition) {
+	for _, cond := range conds {
+		if rl, ok := cond.(releaser); ok {
+			rl.release()
+		}
+	}
+}
+
+// getRuleGroup returns the group with the given name, creating it if needed.
+// Rules without a group name belong to no group.
+func (r *Router) getRuleGroup(name string) *ruleGroup {
+	if name == "" {
+		return nil
 	}
+	group, found := r.groups[name]
+	if !found {
+		group = new(ruleGroup)
+		r.groups[name] = group
+	}
+	return group
+}
 
+func (r *Router) applyRuleGroups(groups []*RuleGroup) {
+	for _, g := range groups {
+		if g.GetName() == "" {
+			continue
+		}
+		r.getRuleGroup(g.GetName()).disabled.Store(g.GetDisabled())
+	}
+}
+
+// applyBandwidthClasses defines the classes, or changes the limits of those already defined,
+// which rules and open connections keep referring to.
+func (r *Router) applyBandwidthClasses(classes []*BandwidthClass) {
+	for _, c := range classes {
+		if class, found := r.classes[c.GetName()]; found {
+			class.SetRate(int64(c.GetRate()))
+			continue
+		}
+		r.classes[c.GetName()] = routing.NewBandwidthClass(c.GetName(), int64(c.GetRate()))
+	}
+}
+
+// sortRules orders rules by descending priority. The slice is replaced
+// rather than sorted in place, as it may be read concurrently.
+func (r *Router) sortRules() {
+	rules := make([]*Rule, len(r.rules))
+	copy(rules, r.rules)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority > rules[j].Priority
+	})
+	r.rules = rules
+}
+
+// SetRuleGroupEnabled implements routing.RuleGroupManager.
+func (r *Router) SetRuleGroupEnabled(name string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	group, found := r.groups[name]
+	if !found {
+		return errors.New("rule group not found: ", name)
+	}
+	group.disabled.Store(!enabled)
 	return nil
 }
 
+// GetRuleGroups implements routing.RuleGroupManager.
+func (r *Router) GetRuleGroups() []routing.RuleGroupInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := make(map[*ruleGroup]int)
+	for _, rule := range r.rules {
+		if rule.group != nil {
+			count[rule.group]++
+		}
+	}
+	infos := make([]routing.RuleGroupInfo, 0, len(r.groups))
+	for name, group := range r.groups {
+		infos = append(infos, routing.RuleGroupInfo{
+			Name:    name,
+			Enabled: !group.disabled.Load(),
+			Rules:   count[group],
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// RuleCount implements routing.RuleCounter.
+func (r *Router) RuleCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.rules)
+}
+
 func (r *Router) RuleExists(tag string) bool {
 	if tag != "" {
 		for _, rule := range r.rules {
@@ -173,6 +388,8 @@ func (r *Router) RemoveRule(tag string) error {
 		for _, rule := range r.rules {
 			if rule.RuleTag != tag {
 				newRules = append(newRules, rule)
+			} else {
+				r.releaseRule(rule)
 			}
 		}
 		r.rules = newRules
@@ -186,6 +403,7 @@ func (r *Router) pickRouteInternal(ctx routing.Context) (*Rule, routing.Context,
 	// the DOH remote server maybe a domain name,
 	// this prevents cycle resolving dead loop
 	skipDNSResolve := ctx.GetSkipDNSResolve()
+	plain := ctx
 
 	if r.domainStrategy == Config_IpOnDemand && !skipDNSResolve {
 		ctx = routing_dns.ContextWithDNSClient(ctx, r.dns)
@@ -193,7 +411,7 @@ func (r *Router) pickRouteInternal(ctx routing.Context) (*Rule, routing.Context,
 
 	for _, rule := range r.rules {
 		if rule.Apply(ctx) {
-			return rule, ctx, nil
+			return r.checkPoison(rule, plain, ctx)
 		}
 	}
 
@@ -206,7 +424,7 @@ func (r *Router) pickRouteInternal(ctx routing.Context) (*Rule, routing.Context,
 	// Try applying rules again if we have IPs.
 	for _, rule := range r.rules {
 		if rule.Apply(ctx) {
-			return rule, ctx, nil
+			return r.checkPoison(rule, plain, ctx)
 		}
 	}
 
@@ -215,11 +433,20 @@ func (r *Router) pickRouteInternal(ctx routing.Context) (*Rule, routing.Context,
 
 // Start implements common.Runnable.
 func (r *Router) Start() error {
+	for _, b := range r.blocklists {
+		b.start(r.ctx)
+	}
 	return nil
 }
 
 // Close implements common.Closable.
 func (r *Router) Close() error {
+	for _, b := range r.blocklists {
+		b.close()
+	}
+	for _, b := range r.balancers {
+		common.Close(b.strategy)
+	}
 	return nil
 }
 
@@ -242,10 +469,22 @@ func (r *Route) GetRuleTag() string {
 	return r.ruleTag
 }
 
+// GetMirror implements routing.MirroredRoute.
+func (r *Route) GetMirror() *routing.Mirror {
+	return r.mirror
+}
+
+// GetBandwidthClass implements routing.ClassifiedRoute.
+func (r *Route) GetBandwidthClass() *routing.BandwidthClass {
+	return r.bandwidthClass
+}
+
 func init() {
 	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
 		r := new(Router)
-		if err := core.RequireFeatures(ctx, func(d dns.Client, ohm outbound.Manager, dispatcher routing.Dispatcher) error {
+		if err := core.RequireFeatures(ctx, func(d dns.Client, ohm outbound.Manager, dispatcher routing.Dispatcher, sm stats.Manager, pm policy.Manager) error {
+			r.stats = sm
+			r.ruleStats = pm.ForSystem().Stats.Rule
 			return r.Init(ctx, config.(*Config), d, ohm, dispatcher)
 		}); err != nil {
 			return nil, err
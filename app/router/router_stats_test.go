@@ -0,0 +1,45 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/features/routing"
+	feature_stats "github.com/xtls/xray-core/features/stats"
+)
+
+func TestRuleStatsCounters(t *testing.T) {
+	manager, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+	r := &Router{stats: manager, ruleStats: true}
+	common.Must(r.Init(context.Background(), &Config{
+		Rule: []*RoutingRule{
+			{TargetTag: &RoutingRule_Tag{Tag: "direct"}, RuleTag: "lan", Networks: []net.Network{net.Network_TCP}},
+			{TargetTag: &RoutingRule_Tag{Tag: "proxy"}, Networks: []net.Network{net.Network_UDP}},
+		},
+	}, nil, nil, nil))
+
+	names := []string{"connections", "traffic>>>uplink", "traffic>>>downlink"}
+	for _, name := range names {
+		if manager.GetCounter(routing.RuleCounterName("lan", name)) == nil {
+			t.Error("expected counter ", name, " of the tagged rule")
+		}
+	}
+	manager.VisitCounters(func(name string, _ feature_stats.Counter) bool {
+		if strings.HasPrefix(name, "rule>>>") && !strings.HasPrefix(name, "rule>>>lan>>>") {
+			t.Error("unexpected counter of a rule without tag: ", name)
+		}
+		return true
+	})
+
+	common.Must(r.RemoveRule("lan"))
+	for _, name := range names {
+		if manager.GetCounter(routing.RuleCounterName("lan", name)) != nil {
+			t.Error("expected counter ", name, " of the removed rule unregistered")
+		}
+	}
+}
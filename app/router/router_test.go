@@ -55,6 +55,56 @@ func TestSimpleRouter(t *testing.T) {
 	}
 }
 
+func TestRulePriorityAndGroup(t *testing.T) {
+	config := &Config{
+		Rule: []*RoutingRule{
+			{
+				TargetTag: &RoutingRule_Tag{
+					Tag: "low",
+				},
+				Networks: []net.Network{net.Network_TCP},
+			},
+			{
+				TargetTag: &RoutingRule_Tag{
+					Tag: "high",
+				},
+				Networks: []net.Network{net.Network_TCP},
+				Priority: 10,
+				Group:    "test",
+			},
+		},
+	}
+
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockDNS := mocks.NewDNSClient(mockCtl)
+	mockOhm := mocks.NewOutboundManager(mockCtl)
+	mockHs := mocks.NewOutboundHandlerSelector(mockCtl)
+
+	r := new(Router)
+	common.Must(r.Init(context.TODO(), config, mockDNS, &mockOutboundManager{
+		Manager:         mockOhm,
+		HandlerSelector: mockHs,
+	}, nil))
+
+	ctx := session.ContextWithOutbounds(context.Background(), []*session.Outbound{{
+		Target: net.TCPDestination(net.DomainAddress("example.com"), 80),
+	}})
+	route, err := r.PickRoute(routing_session.AsRoutingContext(ctx))
+	common.Must(err)
+	if tag := route.GetOutboundTag(); tag != "high" {
+		t.Error("expect tag 'high', bug actually ", tag)
+	}
+
+	common.Must(r.SetRuleGroupEnabled("test", false))
+	route, err = r.PickRoute(routing_session.AsRoutingContext(ctx))
+	common.Must(err)
+	if tag := route.GetOutboundTag(); tag != "low" {
+		t.Error("expect tag 'low', bug actually ", tag)
+	}
+}
+
 func TestSimpleBalancer(t *testing.T) {
 	config := &Config{
 		Rule: []*RoutingRule{
@@ -276,3 +326,85 @@ func TestIPIfNonMatchIP(t *testing.T) {
 		t.Error("expect tag 'test', bug actually ", tag)
 	}
 }
+
+type taggedDNS struct {
+	dns.Client
+	local  []net.IP
+	remote map[string][]net.IP
+}
+
+func (d *taggedDNS) LookupIP(domain string, option dns.IPOption) ([]net.IP, error) {
+	return d.local, nil
+}
+
+func (d *taggedDNS) LookupIPWithTag(domain string, tag string, option dns.IPOption) ([]net.IP, error) {
+	return d.remote[tag], nil
+}
+
+func TestPoisonCheck(t *testing.T) {
+	config := &Config{
+		DomainStrategy: Config_IpOnDemand,
+		Rule: []*RoutingRule{
+			{
+				TargetTag: &RoutingRule_Tag{
+					Tag: "direct",
+				},
+				Geoip: []*GeoIP{
+					{
+						Cidr: []*CIDR{
+							{
+								Ip:     []byte{10, 0, 0, 0},
+								Prefix: 8,
+							},
+						},
+					},
+				},
+			},
+			{
+				TargetTag: &RoutingRule_Tag{
+					Tag: "proxy",
+				},
+				Domain: []*Domain{
+					{
+						Type:  Domain_Domain,
+						Value: "example.com",
+					},
+				},
+			},
+		},
+		PoisonCheck: &PoisonCheck{
+			DnsTag:         "remote",
+			DirectOutbound: []string{"direct"},
+		},
+	}
+
+	d := &taggedDNS{
+		local: []net.IP{{10, 0, 0, 1}},
+		remote: map[string][]net.IP{
+			"remote": {{93, 184, 216, 34}},
+		},
+	}
+	r := new(Router)
+	common.Must(r.Init(context.TODO(), config, d, nil, nil))
+
+	pick := func(domain string) string {
+		ctx := session.ContextWithOutbounds(context.Background(), []*session.Outbound{{
+			Target: net.TCPDestination(net.DomainAddress(domain), 80),
+		}})
+		route, err := r.PickRoute(routing_session.AsRoutingContext(ctx))
+		common.Must(err)
+		return route.GetOutboundTag()
+	}
+
+	if tag := pick("www.example.com"); tag != "proxy" {
+		t.Error("expect tag 'proxy', but actually ", tag)
+	}
+	if tag := pick("example.org"); tag != "direct" {
+		t.Error("expect tag 'direct', but actually ", tag)
+	}
+
+	d.remote["remote"] = []net.IP{{10, 0, 0, 2}}
+	if tag := pick("www.example.com"); tag != "direct" {
+		t.Error("expect tag 'direct', but actually ", tag)
+	}
+}
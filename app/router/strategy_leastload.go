@@ -16,6 +16,8 @@ import (
 
 // LeastLoadStrategy represents a least load balancing strategy
 type LeastLoadStrategy struct {
+	ObservatoryTag string
+
 	settings *StrategyLeastLoadConfig
 	costs    *WeightManager
 
@@ -50,18 +52,19 @@ func NewLeastLoadStrategy(settings *StrategyLeastLoadConfig) *LeastLoadStrategy
 // we don't use HealthCheckResult directly because
 // it may change by health checker during routing
 type node struct {
-	Tag              string
-	CountAll         int
-	CountFail        int
-	RTTAverage       time.Duration
-	RTTDeviation     time.Duration
+	Tag          string
+	CountAll     int
+	CountFail    int
+	RTTAverage   time.Duration
+	RTTDeviation time.Duration
+	// RTTDeviationCost is the cost the nodes are sorted and compared to baselines by.
 	RTTDeviationCost time.Duration
 }
 
 func (s *LeastLoadStrategy) InjectContext(ctx context.Context) {
 	s.ctx = ctx
 	common.Must(core.RequireFeatures(s.ctx, func(observatory extension.Observatory) error {
-		s.observer = observatory
+		s.observer = selectObservatory(ctx, observatory, s.ObservatoryTag)
 		return nil
 	}))
 }
@@ -158,22 +161,24 @@ func (s *LeastLoadStrategy) getNodes(candidates []string, maxRTT time.Duration)
 	for _, v := range results.Status {
 		if v.Alive && (v.Delay < maxRTT.Milliseconds() || maxRTT == 0) && outboundlist.contains(v.OutboundTag) {
 			record := &node{
-				Tag:              v.OutboundTag,
-				CountAll:         1,
-				CountFail:        1,
-				RTTAverage:       time.Duration(v.Delay) * time.Millisecond,
-				RTTDeviation:     time.Duration(v.Delay) * time.Millisecond,
-				RTTDeviationCost: time.Duration(s.costs.Apply(v.OutboundTag, float64(time.Duration(v.Delay)*time.Millisecond))),
+				Tag:          v.OutboundTag,
+				CountAll:     1,
+				CountFail:    0,
+				RTTAverage:   time.Duration(v.Delay) * time.Millisecond,
+				RTTDeviation: time.Duration(v.Delay) * time.Millisecond,
 			}
 
 			if v.HealthPing != nil {
 				record.RTTAverage = time.Duration(v.HealthPing.Average)
 				record.RTTDeviation = time.Duration(v.HealthPing.Deviation)
-				record.RTTDeviationCost = time.Duration(s.costs.Apply(v.OutboundTag, float64(v.HealthPing.Deviation)))
 				record.CountAll = int(v.HealthPing.All)
 				record.CountFail = int(v.HealthPing.Fail)
-
 			}
+			if !s.tolerated(record) {
+				errors.LogDebug(s.ctx, "least load: ", record.Tag, " failed ", record.CountFail, " of ", record.CountAll, " probes, over tolerance")
+				continue
+			}
+			record.RTTDeviationCost = s.cost(record)
 			ret = append(ret, record)
 		}
 	}
@@ -182,6 +187,31 @@ func (s *LeastLoadStrategy) getNodes(candidates []string, maxRTT time.Duration)
 	return ret
 }
 
+// tolerated returns false if the failure rate of n is over the tolerance. A zero tolerance
+// accepts any failure rate.
+func (s *LeastLoadStrategy) tolerated(n *node) bool {
+	if s.settings.Tolerance <= 0 || n.CountAll <= 0 {
+		return true
+	}
+	return float64(n.CountFail)/float64(n.CountAll) <= float64(s.settings.Tolerance)
+}
+
+// cost combines the RTT average, RTT deviation and failure rate of n, weighted by the settings,
+// and applies the cost configured for the outbound. Without RTT and jitter weights, only the
+// deviation counts.
+func (s *LeastLoadStrategy) cost(n *node) time.Duration {
+	rttWeight := float64(s.settings.RttWeight)
+	jitterWeight := float64(s.settings.JitterWeight)
+	if rttWeight <= 0 && jitterWeight <= 0 {
+		jitterWeight = 1
+	}
+	value := rttWeight*float64(n.RTTAverage) + jitterWeight*float64(n.RTTDeviation)
+	if n.CountAll > 0 && s.settings.FailureWeight > 0 {
+		value *= 1 + float64(s.settings.FailureWeight)*float64(n.CountFail)/float64(n.CountAll)
+	}
+	return time.Duration(s.costs.Apply(n.Tag, value))
+}
+
 func leastloadSort(nodes []*node) {
 	sort.Slice(nodes, func(i, j int) bool {
 		left := nodes[i]
@@ -2,6 +2,7 @@ package router
 
 import (
 	"testing"
+	"time"
 )
 
 /*
@@ -177,3 +178,46 @@ func TestSelectLeastLoadBaselinesNoQualified(t *testing.T) {
 		t.Errorf("expected: %v, actual: %v", expected, len(ns))
 	}
 }
+func TestLeastLoadCost(t *testing.T) {
+	strategy := NewLeastLoadStrategy(&StrategyLeastLoadConfig{
+		RttWeight:     1,
+		JitterWeight:  2,
+		FailureWeight: 1,
+		Tolerance:     0.5,
+	})
+	nodes := []*node{
+		{Tag: "a", CountAll: 10, CountFail: 0, RTTAverage: 100, RTTDeviation: 50},
+		{Tag: "b", CountAll: 10, CountFail: 5, RTTAverage: 100, RTTDeviation: 10},
+		{Tag: "c", CountAll: 10, CountFail: 6, RTTAverage: 10, RTTDeviation: 10},
+	}
+	expected := []struct {
+		tolerated bool
+		cost      time.Duration
+	}{
+		{true, 200},
+		{true, 180},
+		{false, 0},
+	}
+	for i, n := range nodes {
+		if tolerated := strategy.tolerated(n); tolerated != expected[i].tolerated {
+			t.Errorf("%s tolerated expected: %v, actual: %v", n.Tag, expected[i].tolerated, tolerated)
+		}
+		if !expected[i].tolerated {
+			continue
+		}
+		if cost := strategy.cost(n); cost != expected[i].cost {
+			t.Errorf("%s cost expected: %v, actual: %v", n.Tag, expected[i].cost, cost)
+		}
+	}
+}
+
+func TestLeastLoadCostDefault(t *testing.T) {
+	strategy := NewLeastLoadStrategy(&StrategyLeastLoadConfig{})
+	n := &node{Tag: "a", CountAll: 10, CountFail: 9, RTTAverage: 100, RTTDeviation: 50}
+	if !strategy.tolerated(n) {
+		t.Error("expected any failure rate tolerated without tolerance")
+	}
+	if cost := strategy.cost(n); cost != 50 {
+		t.Errorf("expected: %v, actual: %v", 50, cost)
+	}
+}
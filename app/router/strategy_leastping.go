@@ -11,6 +11,8 @@ import (
 )
 
 type LeastPingStrategy struct {
+	ObservatoryTag string
+
 	ctx         context.Context
 	observatory extension.Observatory
 }
@@ -22,7 +24,7 @@ func (l *LeastPingStrategy) GetPrincipleTarget(strings []string) []string {
 func (l *LeastPingStrategy) InjectContext(ctx context.Context) {
 	l.ctx = ctx
 	common.Must(core.RequireFeatures(l.ctx, func(observatory extension.Observatory) error {
-		l.observatory = observatory
+		l.observatory = selectObservatory(ctx, observatory, l.ObservatoryTag)
 		return nil
 	}))
 }
@@ -0,0 +1,299 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/app/observatory"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/storage"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/extension"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+const (
+	defaultQuotaReserve = 0.1
+	// quotaSaveInterval is how often the usage is saved.
+	quotaSaveInterval = time.Minute
+)
+
+// QuotaStrategy routes through the fastest outbound with data left in its monthly cap. Bulk
+// traffic, that of the routing rules tagged in BulkRuleTags, is moreover steered away from nearly
+// exhausted outbounds, while latency-sensitive traffic stays on the fastest one.
+//
+// The usage of an outbound is read from its uplink and downlink counters, which are registered
+// when statsOutboundUplink and statsOutboundDownlink are enabled in the system policy. It is added
+// to the usage of the month kept in the usage file, or in the state.
+type QuotaStrategy struct {
+	ObservatoryTag string
+	// BalancerTag keys the usage in the state, without a usage file.
+	BalancerTag string
+
+	ctx         context.Context
+	observatory extension.Observatory
+	stats       stats.Manager
+
+	caps      map[string]int64
+	usageFile string
+	resetDay  int
+	reserve   float64
+	bulkRules map[string]bool
+
+	access sync.Mutex
+	// period is the start of the month the usage is of.
+	period time.Time
+	usage  map[string]int64
+	// counted are the values of the counters of each outbound already added to usage.
+	counted map[string]int64
+	saved   time.Time
+	warned  bool
+}
+
+// quotaUsage is the usage as it is kept. The counted values are kept so that a strategy
+// replaced when the routing rules are reloaded doesn't count the same traffic again; they are
+// above the counters after a restart, and then ignored.
+type quotaUsage struct {
+	Period  time.Time        `json:"period"`
+	Usage   map[string]int64 `json:"usage"`
+	Counted map[string]int64 `json:"counted,omitempty"`
+}
+
+// NewQuotaStrategy creates a QuotaStrategy with settings.
+func NewQuotaStrategy(settings *StrategyQuotaConfig) *QuotaStrategy {
+	s := &QuotaStrategy{
+		caps:      make(map[string]int64),
+		usageFile: settings.GetUsageFile(),
+		resetDay:  int(settings.GetResetDay()),
+		reserve:   float64(settings.GetReserve()),
+		bulkRules: make(map[string]bool),
+		usage:     make(map[string]int64),
+		counted:   make(map[string]int64),
+	}
+	for _, q := range settings.GetQuotas() {
+		s.caps[q.GetOutboundTag()] = int64(q.GetMonthlyCap())
+	}
+	for _, tag := range settings.GetBulkRuleTags() {
+		s.bulkRules[tag] = true
+	}
+	if s.resetDay < 1 || s.resetDay > 28 {
+		s.resetDay = 1
+	}
+	if s.reserve <= 0 || s.reserve >= 1 {
+		s.reserve = defaultQuotaReserve
+	}
+	return s
+}
+
+func (s *QuotaStrategy) InjectContext(ctx context.Context) {
+	s.ctx = ctx
+	s.load()
+	common.Must(core.RequireFeatures(s.ctx, func(sm stats.Manager) error {
+		s.stats = sm
+		return nil
+	}))
+	common.Must(core.OptionalFeatures(s.ctx, func(observatory extension.Observatory) error {
+		s.observatory = selectObservatory(ctx, observatory, s.ObservatoryTag)
+		return nil
+	}))
+}
+
+func (s *QuotaStrategy) GetPrincipleTarget(strings []string) []string {
+	return []string{s.PickOutbound(strings)}
+}
+
+// PickOutbound picks an outbound for latency-sensitive traffic.
+func (s *QuotaStrategy) PickOutbound(candidates []string) string {
+	return s.PickOutboundForRule(candidates, "")
+}
+
+// PickOutboundForRule implements BalancingRuleStrategy.
+func (s *QuotaStrategy) PickOutboundForRule(candidates []string, ruleTag string) string {
+	return s.pick(candidates, s.observe(), s.bulkRules[ruleTag], time.Now())
+}
+
+// observe returns the status of the outbounds, or nil without an observatory.
+func (s *QuotaStrategy) observe() []*observatory.OutboundStatus {
+	if s.observatory == nil {
+		return nil
+	}
+	observeReport, err := s.observatory.GetObservation(s.ctx)
+	if err != nil {
+		errors.LogInfoInner(s.ctx, err, "cannot get observer report")
+		return nil
+	}
+	if result, ok := observeReport.(*observatory.ObservationResult); ok {
+		return result.Status
+	}
+	return nil
+}
+
+// pick returns the fastest alive candidate which isn't exhausted, or, for bulk traffic, the fastest
+// which isn't nearly exhausted either, unless all are. Candidates without status are taken as
+// alive, and slower than any measured one.
+func (s *QuotaStrategy) pick(candidates []string, status []*observatory.OutboundStatus, bulk bool, now time.Time) string {
+	s.access.Lock()
+	defer s.access.Unlock()
+	s.refresh(now)
+
+	statusMap := make(map[string]*observatory.OutboundStatus, len(status))
+	for _, v := range status {
+		statusMap[v.OutboundTag] = v
+	}
+	var ranked []string
+	for _, tag := range candidates {
+		if v, found := statusMap[tag]; found && !v.Alive {
+			continue
+		}
+		if s.remaining(tag) <= 0 {
+			continue
+		}
+		ranked = append(ranked, tag)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, foundA := statusMap[ranked[i]]
+		b, foundB := statusMap[ranked[j]]
+		if foundA != foundB {
+			return foundA
+		}
+		return foundA && a.Delay < b.Delay
+	})
+	if len(ranked) == 0 {
+		return ""
+	}
+	if !bulk {
+		return ranked[0]
+	}
+	most := ranked[0]
+	for _, tag := range ranked {
+		if s.remaining(tag) >= s.reserve {
+			return tag
+		}
+		if s.remaining(tag) > s.remaining(most) {
+			most = tag
+		}
+	}
+	return most
+}
+
+// remaining returns the fraction of the cap of the outbound tagged tag left, or 1 without a cap.
+func (s *QuotaStrategy) remaining(tag string) float64 {
+	limit, found := s.caps[tag]
+	if !found || limit <= 0 {
+		return 1
+	}
+	return float64(limit-s.usage[tag]) / float64(limit)
+}
+
+// refresh adds the traffic counted since the last refresh to the usage, which is reset when a new
+// month starts, and saves it every quotaSaveInterval.
+func (s *QuotaStrategy) refresh(now time.Time) {
+	if period := quotaPeriod(now, s.resetDay); !period.Equal(s.period) {
+		if !s.period.IsZero() {
+			errors.LogInfo(s.ctx, "quota usage reset for the month from ", period.Format(time.DateOnly))
+		}
+		s.period = period
+		s.usage = make(map[string]int64)
+	}
+	for tag := range s.caps {
+		value, found := s.counterValue(tag)
+		if !found {
+			if !s.warned {
+				errors.LogWarning(s.ctx, "no traffic counter of outbound ", tag, ", enable statsOutboundUplink and statsOutboundDownlink to count its quota")
+				s.warned = true
+			}
+			continue
+		}
+		delta := value - s.counted[tag]
+		if delta < 0 {
+			// The counters were reset.
+			delta = value
+		}
+		s.counted[tag] = value
+		s.usage[tag] += delta
+	}
+	if now.Sub(s.saved) >= quotaSaveInterval {
+		s.save()
+		s.saved = now
+	}
+}
+
+// counterValue returns the sum of the uplink and downlink counters of the outbound tagged tag.
+func (s *QuotaStrategy) counterValue(tag string) (int64, bool) {
+	if s.stats == nil {
+		return 0, false
+	}
+	var value int64
+	found := false
+	for _, direction := range []string{"uplink", "downlink"} {
+		if c := s.stats.GetCounter("outbound>>>" + tag + ">>>traffic>>>" + direction); c != nil {
+			value += c.Value()
+			found = true
+		}
+	}
+	return value, found
+}
+
+// quotaPeriod returns the start of the month, reset on day, now is in.
+func quotaPeriod(now time.Time, day int) time.Time {
+	year, month, today := now.Date()
+	if today < day {
+		month--
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+}
+
+// usageEntry returns where the usage is kept: in the usage file if set, or in the state.
+func (s *QuotaStrategy) usageEntry() storage.Entry {
+	return storage.Entry{Path: s.usageFile, Bucket: "quota", Key: s.BalancerTag}
+}
+
+// load reads the usage kept, if any.
+func (s *QuotaStrategy) load() {
+	b, err := s.usageEntry().Load()
+	if err != nil {
+		if err != storage.ErrNotFound {
+			errors.LogWarningInner(s.ctx, err, "failed to read quota usage")
+		}
+		return
+	}
+	var u quotaUsage
+	if err := json.Unmarshal(b, &u); err != nil {
+		errors.LogWarningInner(s.ctx, err, "failed to parse quota usage")
+		return
+	}
+	s.access.Lock()
+	defer s.access.Unlock()
+	s.period = u.Period
+	for tag, value := range u.Usage {
+		s.usage[tag] = value
+	}
+	for tag, value := range u.Counted {
+		s.counted[tag] = value
+	}
+}
+
+// save keeps the usage, if there is somewhere to.
+func (s *QuotaStrategy) save() {
+	b, err := json.Marshal(&quotaUsage{Period: s.period, Usage: s.usage, Counted: s.counted})
+	if err != nil {
+		errors.LogWarningInner(s.ctx, err, "failed to encode quota usage")
+		return
+	}
+	if err := s.usageEntry().Save(b); err != nil {
+		errors.LogWarningInner(s.ctx, err, "failed to save quota usage")
+	}
+}
+
+// Close implements common.Closable. It saves the usage counted since the last save.
+func (s *QuotaStrategy) Close() error {
+	s.access.Lock()
+	defer s.access.Unlock()
+	s.saved = time.Time{}
+	s.refresh(time.Now())
+	return nil
+}
@@ -0,0 +1,98 @@
+package router
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/app/observatory"
+	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+)
+
+func TestQuotaStrategy(t *testing.T) {
+	usageFile := filepath.Join(t.TempDir(), "usage.json")
+	settings := &StrategyQuotaConfig{
+		Quotas: []*OutboundQuota{
+			{OutboundTag: "fast", MonthlyCap: 1000},
+			{OutboundTag: "slow", MonthlyCap: 1000},
+		},
+		UsageFile:    usageFile,
+		Reserve:      0.2,
+		BulkRuleTags: []string{"downloads"},
+	}
+	manager, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+	fast, _ := manager.RegisterCounter("outbound>>>fast>>>traffic>>>uplink")
+	slow, _ := manager.RegisterCounter("outbound>>>slow>>>traffic>>>downlink")
+
+	s := NewQuotaStrategy(settings)
+	s.ctx = context.Background()
+	s.stats = manager
+	candidates := []string{"slow", "fast", "uncapped"}
+	status := []*observatory.OutboundStatus{
+		{OutboundTag: "fast", Alive: true, Delay: 50},
+		{OutboundTag: "slow", Alive: true, Delay: 200},
+		{OutboundTag: "uncapped", Alive: true, Delay: 300},
+	}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		fast, slow int64
+		after      time.Duration
+		wantBulk   string
+		want       string
+	}{
+		// The fastest is picked for all traffic.
+		{0, 0, 0, "fast", "fast"},
+		// Nearly exhausted, it is kept for latency-sensitive traffic only.
+		{850, 0, time.Second, "slow", "fast"},
+		// Exhausted, it is picked for none.
+		{1000, 500, 2 * time.Second, "slow", "slow"},
+		// Bulk traffic goes to a slower outbound above the reserve.
+		{1000, 900, 3 * time.Second, "uncapped", "slow"},
+		// The usage is reset with the month.
+		{1000, 900, 16 * 24 * time.Hour, "fast", "fast"},
+	}
+	for i, step := range steps {
+		fast.Set(step.fast)
+		slow.Set(step.slow)
+		at := now.Add(step.after)
+		if got := s.pick(candidates, status, true, at); got != step.wantBulk {
+			t.Errorf("step %d: bulk got %q, want %q", i, got, step.wantBulk)
+		}
+		if got := s.pick(candidates, status, false, at); got != step.want {
+			t.Errorf("step %d: got %q, want %q", i, got, step.want)
+		}
+	}
+
+	// The usage is restored from the usage file, without counting the counters twice.
+	fast.Set(1100)
+	s.saved = time.Time{}
+	s.refresh(now.Add(16 * 24 * time.Hour))
+	restored := NewQuotaStrategy(settings)
+	restored.ctx = context.Background()
+	restored.stats = manager
+	restored.load()
+	restored.refresh(now.Add(16 * 24 * time.Hour))
+	if got := restored.usage["fast"]; got != 100 {
+		t.Errorf("restored usage of fast: got %d, want 100", got)
+	}
+}
+
+func TestQuotaPeriod(t *testing.T) {
+	for _, c := range []struct {
+		now  time.Time
+		day  int
+		want time.Time
+	}{
+		{time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), 1, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), 20, time.Date(2026, 9, 20, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), 10, time.Date(2025, 12, 10, 0, 0, 0, 0, time.UTC)},
+	} {
+		if got := quotaPeriod(c.now, c.day); !got.Equal(c.want) {
+			t.Errorf("quotaPeriod(%v, %d): got %v, want %v", c.now, c.day, got, c.want)
+		}
+	}
+}
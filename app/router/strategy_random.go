@@ -12,7 +12,8 @@ import (
 
 // RandomStrategy represents a random balancing strategy
 type RandomStrategy struct {
-	FallbackTag string
+	FallbackTag    string
+	ObservatoryTag string
 
 	ctx         context.Context
 	observatory extension.Observatory
@@ -22,7 +23,7 @@ func (s *RandomStrategy) InjectContext(ctx context.Context) {
 	s.ctx = ctx
 	if len(s.FallbackTag) > 0 {
 		common.Must(core.RequireFeatures(s.ctx, func(observatory extension.Observatory) error {
-			s.observatory = observatory
+			s.observatory = selectObservatory(ctx, observatory, s.ObservatoryTag)
 			return nil
 		}))
 	}
@@ -0,0 +1,119 @@
+package router
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/app/observatory"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/extension"
+)
+
+const (
+	defaultSelectorTolerance = 50 * time.Millisecond
+	defaultSelectorHoldDown  = time.Minute
+)
+
+// SelectorStrategy routes through the fastest healthy outbound, and keeps it until it fails or
+// another one is faster by more than the tolerance, so that traffic doesn't flap between outbounds
+// of close delays. An outbound that failed is demoted: it isn't selected for the hold-down time
+// after it is alive again.
+type SelectorStrategy struct {
+	ObservatoryTag string
+
+	ctx         context.Context
+	observatory extension.Observatory
+	tolerance   time.Duration
+	holdDown    time.Duration
+
+	access   sync.Mutex
+	selected string
+	// demoted are the outbounds that failed, with the time they may be selected again.
+	demoted map[string]time.Time
+}
+
+// NewSelectorStrategy creates a SelectorStrategy with settings.
+func NewSelectorStrategy(settings *StrategySelectorConfig) *SelectorStrategy {
+	s := &SelectorStrategy{
+		tolerance: time.Duration(settings.GetTolerance()),
+		holdDown:  time.Duration(settings.GetHoldDown()),
+		demoted:   make(map[string]time.Time),
+	}
+	if s.tolerance <= 0 {
+		s.tolerance = defaultSelectorTolerance
+	}
+	if s.holdDown <= 0 {
+		s.holdDown = defaultSelectorHoldDown
+	}
+	return s
+}
+
+func (s *SelectorStrategy) InjectContext(ctx context.Context) {
+	s.ctx = ctx
+	common.Must(core.RequireFeatures(s.ctx, func(observatory extension.Observatory) error {
+		s.observatory = selectObservatory(ctx, observatory, s.ObservatoryTag)
+		return nil
+	}))
+}
+
+func (s *SelectorStrategy) GetPrincipleTarget(strings []string) []string {
+	return []string{s.PickOutbound(strings)}
+}
+
+func (s *SelectorStrategy) PickOutbound(strings []string) string {
+	if s.observatory == nil {
+		errors.LogError(s.ctx, "observer is nil")
+		return ""
+	}
+	observeReport, err := s.observatory.GetObservation(s.ctx)
+	if err != nil {
+		errors.LogInfoInner(s.ctx, err, "cannot get observer report")
+		return ""
+	}
+	result, ok := observeReport.(*observatory.ObservationResult)
+	if !ok {
+		return ""
+	}
+	return s.pick(outboundList(strings), result.Status, time.Now())
+}
+
+func (s *SelectorStrategy) pick(candidates outboundList, status []*observatory.OutboundStatus, now time.Time) string {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	delays := make(map[string]time.Duration)
+	for _, v := range status {
+		if !candidates.contains(v.OutboundTag) {
+			continue
+		}
+		if !v.Alive {
+			s.demoted[v.OutboundTag] = now.Add(s.holdDown)
+			continue
+		}
+		if until, found := s.demoted[v.OutboundTag]; found {
+			if now.Before(until) {
+				continue
+			}
+			delete(s.demoted, v.OutboundTag)
+		}
+		delays[v.OutboundTag] = time.Duration(v.Delay) * time.Millisecond
+	}
+
+	fastest := ""
+	for _, tag := range candidates {
+		if delay, found := delays[tag]; found && (fastest == "" || delay < delays[fastest]) {
+			fastest = tag
+		}
+	}
+	if delay, found := delays[s.selected]; found && delay <= delays[fastest]+s.tolerance {
+		return s.selected
+	}
+	if fastest != s.selected {
+		errors.LogInfo(s.ctx, "selector switched from [", s.selected, "] to [", fastest, "]")
+		s.selected = fastest
+	}
+	return fastest
+}
@@ -0,0 +1,51 @@
+package router
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/app/observatory"
+)
+
+func TestSelectorStrategy(t *testing.T) {
+	s := NewSelectorStrategy(&StrategySelectorConfig{
+		Tolerance: int64(20 * time.Millisecond),
+		HoldDown:  int64(time.Minute),
+	})
+	s.ctx = context.Background()
+	candidates := outboundList{"a", "b", "c"}
+	status := func(delays map[string]int64) []*observatory.OutboundStatus {
+		var result []*observatory.OutboundStatus
+		for tag, delay := range delays {
+			result = append(result, &observatory.OutboundStatus{OutboundTag: tag, Alive: delay > 0, Delay: delay})
+		}
+		return result
+	}
+	now := time.Now()
+
+	steps := []struct {
+		delays map[string]int64
+		after  time.Duration
+		want   string
+	}{
+		// The fastest is selected.
+		{map[string]int64{"a": 100, "b": 50, "c": 80}, 0, "b"},
+		// Another one faster within the tolerance doesn't take over.
+		{map[string]int64{"a": 100, "b": 50, "c": 40}, time.Second, "b"},
+		// Beyond the tolerance it does.
+		{map[string]int64{"a": 100, "b": 50, "c": 20}, 2 * time.Second, "c"},
+		// A failed outbound is replaced.
+		{map[string]int64{"a": 100, "b": 50, "c": 0}, 3 * time.Second, "b"},
+		// and is kept out for the hold-down time.
+		{map[string]int64{"a": 100, "b": 50, "c": 10}, 30 * time.Second, "b"},
+		{map[string]int64{"a": 100, "b": 50, "c": 10}, 2 * time.Minute, "c"},
+		// Without a healthy outbound, none is selected.
+		{map[string]int64{"a": 0, "b": 0, "c": 0}, 3 * time.Minute, ""},
+	}
+	for i, step := range steps {
+		if got := s.pick(candidates, status(step.delays), now.Add(step.after)); got != step.want {
+			t.Errorf("step %d: got %q, want %q", i, got, step.want)
+		}
+	}
+}
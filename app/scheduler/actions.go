@@ -0,0 +1,277 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/platform"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/features/routing"
+	feature_stats "github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/transport/internet/tagged"
+)
+
+const (
+	// maxGeodataSize caps the size of downloaded dat files.
+	maxGeodataSize         = 256 * 1024 * 1024
+	downloadTimeout        = 10 * time.Minute
+	defaultCertificateDays = 14
+	defaultHookTimeout     = time.Minute
+	// maxHookOutput caps the output of hooks kept for logs.
+	maxHookOutput = 4096
+)
+
+func (s *Scheduler) newGeodataUpdate(config *GeodataUpdate) (action, error) {
+	if len(config.File) == 0 {
+		return nil, errors.New("no file to update")
+	}
+	for _, f := range config.File {
+		if !strings.HasPrefix(f.Url, "http://") && !strings.HasPrefix(f.Url, "https://") {
+			return nil, errors.New("not an HTTP URL: ", f.Url)
+		}
+		if f.Name == "" || filepath.Base(f.Name) != f.Name {
+			return nil, errors.New("invalid file name: ", f.Name)
+		}
+	}
+
+	client := &http.Client{Timeout: downloadTimeout}
+	if config.OutboundTag != "" {
+		if err := core.RequireFeatures(s.ctx, func(d routing.Dispatcher) {
+			client.Transport = &http.Transport{
+				Proxy: func(*http.Request) (*url.URL, error) {
+					return nil, nil
+				},
+				DialContext: func(_ context.Context, network, addr string) (net.Conn, error) {
+					dest, err := net.ParseDestination(network + ":" + addr)
+					if err != nil {
+						return nil, err
+					}
+					return tagged.Dialer(s.ctx, d, dest, config.OutboundTag)
+				},
+			}
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(ctx context.Context) error {
+		for _, f := range config.File {
+			if err := download(ctx, client, f.Url, platform.GetAssetLocation(f.Name)); err != nil {
+				return errors.New("failed to update ", f.Name).Base(err)
+			}
+			errors.LogInfo(ctx, "updated ", f.Name, ", which takes effect when rules are loaded again")
+		}
+		return nil
+	}, nil
+}
+
+// download replaces the file at path with what's at source, once it's complete.
+func download(ctx context.Context, client *http.Client, source, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("unexpected status ", resp.Status)
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxGeodataSize+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	switch {
+	case err == nil && n == 0:
+		err = errors.New("empty file")
+	case err == nil && n > maxGeodataSize:
+		err = errors.New("file larger than ", maxGeodataSize, " bytes")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *Scheduler) newStatsSnapshot(config *StatsSnapshot) (action, error) {
+	if config.Path == "" {
+		return nil, errors.New("no path to write stats to")
+	}
+	var manager *stats.Manager
+	if err := core.RequireFeatures(s.ctx, func(sm feature_stats.Manager) error {
+		m, ok := sm.(*stats.Manager)
+		if !ok {
+			return errors.New("stats are not enabled")
+		}
+		manager = m
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) error {
+		snapshot := struct {
+			Time     time.Time        `json:"time"`
+			Counters map[string]int64 `json:"counters"`
+		}{
+			Time:     time.Now(),
+			Counters: make(map[string]int64),
+		}
+		manager.VisitCounters(func(name string, c feature_stats.Counter) bool {
+			if config.Reset_ {
+				snapshot.Counters[name] = c.Set(0)
+			} else {
+				snapshot.Counters[name] = c.Value()
+			}
+			return true
+		})
+		line, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		f, err := os.OpenFile(config.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(append(line, '\n'))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
+
+func newCertificateCheck(config *CertificateCheck) (action, error) {
+	if len(config.File) == 0 {
+		return nil, errors.New("no certificate to check")
+	}
+	days := config.Days
+	if days == 0 {
+		days = defaultCertificateDays
+	}
+	threshold := time.Duration(days) * 24 * time.Hour
+
+	return func(ctx context.Context) error {
+		for _, file := range config.File {
+			cert, err := readCertificate(file)
+			if err != nil {
+				return errors.New("failed to read certificate ", file).Base(err)
+			}
+			switch left := time.Until(cert.NotAfter); {
+			case left <= 0:
+				errors.LogWarning(ctx, "certificate ", file, " of ", cert.Subject.CommonName, " expired at ", cert.NotAfter)
+			case left < threshold:
+				errors.LogWarning(ctx, "certificate ", file, " of ", cert.Subject.CommonName, " expires at ", cert.NotAfter, ", renew it")
+			default:
+				errors.LogDebug(ctx, "certificate ", file, " of ", cert.Subject.CommonName, " is valid until ", cert.NotAfter)
+			}
+		}
+		return nil
+	}, nil
+}
+
+// readCertificate returns the first certificate in a PEM file, which is the leaf of a chain.
+func readCertificate(file string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no certificate found")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+func (s *Scheduler) newCacheFlush() (action, error) {
+	var flusher dns.CacheFlusher
+	if err := core.RequireFeatures(s.ctx, func(c dns.Client) error {
+		f, ok := c.(dns.CacheFlusher)
+		if !ok {
+			return errors.New("DNS has no cache to flush")
+		}
+		flusher = f
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return func(context.Context) error {
+		flusher.FlushCache()
+		return nil
+	}, nil
+}
+
+func newHook(config *Hook) (action, error) {
+	if config.Command == "" {
+		return nil, errors.New("no command to run")
+	}
+	timeout := time.Duration(config.Timeout) * time.Second
+	if timeout == 0 {
+		timeout = defaultHookTimeout
+	}
+
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		var output bytes.Buffer
+		cmd := exec.CommandContext(ctx, config.Command, config.Args...)
+		cmd.Stdout = &limitedBuffer{Buffer: &output}
+		cmd.Stderr = cmd.Stdout
+		err := cmd.Run()
+		if err != nil {
+			return errors.New("command ", config.Command, " failed: ", strings.TrimSpace(output.String())).Base(err)
+		}
+		if output.Len() > 0 {
+			errors.LogInfo(ctx, "command ", config.Command, ": ", strings.TrimSpace(output.String()))
+		}
+		return nil
+	}, nil
+}
+
+// limitedBuffer keeps the first maxHookOutput bytes written to it.
+type limitedBuffer struct {
+	*bytes.Buffer
+}
+
+// Write implements io.Writer.
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := maxHookOutput - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,122 @@
+// Package scheduler runs maintenance tasks on cron schedules, so that routers need no cron of
+// their own.
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/cron"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/serial"
+)
+
+// action is what a task does when it runs.
+type action func(ctx context.Context) error
+
+type job struct {
+	name     string
+	schedule *cron.Schedule
+	run      action
+}
+
+// Scheduler is a feature running tasks on their schedules. A task doesn't run again before its
+// last run is over, skipping the times it missed.
+type Scheduler struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	jobs   []*job
+}
+
+// New creates a new Scheduler.
+func New(ctx context.Context, config *Config) (*Scheduler, error) {
+	s := new(Scheduler)
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	for i, task := range config.Task {
+		name := task.Name
+		if name == "" {
+			name = serial.Concat("#", i)
+		}
+		schedule, err := cron.Parse(task.Schedule)
+		if err != nil {
+			return nil, errors.New("invalid schedule of task ", name).Base(err)
+		}
+		run, err := s.newAction(task)
+		if err != nil {
+			return nil, errors.New("invalid task ", name).Base(err)
+		}
+		s.jobs = append(s.jobs, &job{
+			name:     name,
+			schedule: schedule,
+			run:      run,
+		})
+	}
+	return s, nil
+}
+
+func (s *Scheduler) newAction(task *Task) (action, error) {
+	switch a := task.Action.(type) {
+	case *Task_GeodataUpdate:
+		return s.newGeodataUpdate(a.GeodataUpdate)
+	case *Task_StatsSnapshot:
+		return s.newStatsSnapshot(a.StatsSnapshot)
+	case *Task_CertificateCheck:
+		return newCertificateCheck(a.CertificateCheck)
+	case *Task_CacheFlush:
+		return s.newCacheFlush()
+	case *Task_Hook:
+		return newHook(a.Hook)
+	default:
+		return nil, errors.New("no action")
+	}
+}
+
+// Type implements common.HasType.
+func (*Scheduler) Type() interface{} {
+	return (*Scheduler)(nil)
+}
+
+// Start implements common.Runnable.
+func (s *Scheduler) Start() error {
+	for _, j := range s.jobs {
+		go s.loop(j)
+	}
+	return nil
+}
+
+// Close implements common.Closable.
+func (s *Scheduler) Close() error {
+	s.cancel()
+	return nil
+}
+
+func (s *Scheduler) loop(j *job) {
+	for {
+		next := j.schedule.Next(time.Now())
+		if next.IsZero() {
+			errors.LogWarning(s.ctx, "task ", j.name, " is never due")
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		start := time.Now()
+		if err := j.run(s.ctx); err != nil {
+			errors.LogWarningInner(s.ctx, err, "task ", j.name, " failed")
+		} else {
+			errors.LogInfo(s.ctx, "task ", j.name, " done in ", time.Since(start).Round(time.Millisecond))
+		}
+	}
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		return New(ctx, config.(*Config))
+	}))
+}
@@ -0,0 +1,341 @@
+// Package shaper limits the traffic of inbounds, users and outbounds, with rates their
+// connections share and monthly quotas which throttle them, or reject their new connections,
+// once used up.
+package shaper
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	appstats "github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/storage"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+const (
+	// checkInterval is how often the usage is checked against the quotas, to throttle the open
+	// connections of those used up.
+	checkInterval = 10 * time.Second
+	// saveInterval is how often the usage is saved.
+	saveInterval = time.Minute
+)
+
+// subject is the inbound, user or outbound a limit applies to.
+type subject struct {
+	// key names the subject in the usage kept, and prefixes the names of its stats counters.
+	key   string
+	name  string
+	limit *Limit
+	// class is the rate limit of its connections, or nil if they are never limited.
+	class *routing.BandwidthClass
+	// usage counts the traffic of the month, or is nil without a quota.
+	usage     stats.Counter
+	exhausted bool
+}
+
+func newSubject(limit *Limit) (*subject, error) {
+	sub := &subject{limit: limit}
+	set := 0
+	if tag := limit.InboundTag; tag != "" {
+		sub.key, sub.name = "inbound>>>"+tag, "inbound "+tag
+		set++
+	}
+	if email := limit.UserEmail; email != "" {
+		sub.key, sub.name = "user>>>"+email, "user "+email
+		set++
+	}
+	if tag := limit.OutboundTag; tag != "" {
+		sub.key, sub.name = "outbound>>>"+tag, "outbound "+tag
+		set++
+	}
+	if set != 1 {
+		return nil, errors.New("a limit must be of exactly one inbound, user or outbound")
+	}
+	if limit.Quota > 0 && limit.QuotaAction == Limit_Throttle && limit.ThrottleRate == 0 {
+		return nil, errors.New("no throttle rate of the quota of ", sub.name)
+	}
+	if limit.Rate > 0 || (limit.Quota > 0 && limit.QuotaAction == Limit_Throttle) {
+		sub.class = routing.NewBandwidthClass(sub.key, int64(limit.Rate))
+	}
+	return sub, nil
+}
+
+// exceeded tells whether the quota of the subject is used up.
+func (s *subject) exceeded() bool {
+	return s.usage != nil && s.usage.Value() >= int64(s.limit.Quota)
+}
+
+// Shaper is a routing.TrafficShaper. The usage of the quota of a subject is the stats counter
+// "[key]>>>quota>>>usage", such as "user>>>[email]>>>quota>>>usage", which the stats API may
+// query, or reset.
+type Shaper struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	inbounds  map[string]*subject
+	users     map[string]*subject
+	outbounds map[string]*subject
+	subjects  []*subject
+
+	usageFile string
+	resetDay  int
+
+	access sync.Mutex
+	// period is the start of the month the usage is of.
+	period time.Time
+	saved  time.Time
+}
+
+// usageRecord is the usage as it is kept.
+type usageRecord struct {
+	Period time.Time        `json:"period"`
+	Usage  map[string]int64 `json:"usage"`
+}
+
+// New creates a new Shaper.
+func New(ctx context.Context, config *Config) (*Shaper, error) {
+	s, err := newShaper(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.RequireFeatures(ctx, func(sm stats.Manager) error {
+		s.registerCounters(sm)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func newShaper(ctx context.Context, config *Config) (*Shaper, error) {
+	s := &Shaper{
+		inbounds:  make(map[string]*subject),
+		users:     make(map[string]*subject),
+		outbounds: make(map[string]*subject),
+		usageFile: config.UsageFile,
+		resetDay:  int(config.ResetDay),
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	if s.resetDay < 1 || s.resetDay > 28 {
+		s.resetDay = 1
+	}
+	for _, limit := range config.Limit {
+		sub, err := newSubject(limit)
+		if err != nil {
+			return nil, err
+		}
+		subjects, id := s.inbounds, limit.InboundTag
+		if limit.UserEmail != "" {
+			subjects, id = s.users, limit.UserEmail
+		} else if limit.OutboundTag != "" {
+			subjects, id = s.outbounds, limit.OutboundTag
+		}
+		if _, found := subjects[id]; found {
+			return nil, errors.New("duplicate limit of ", sub.name)
+		}
+		subjects[id] = sub
+		s.subjects = append(s.subjects, sub)
+	}
+	return s, nil
+}
+
+// registerCounters registers the usage counters of the quotas, or keeps them apart if the
+// stats aren't enabled, and sets them to the usage kept.
+func (s *Shaper) registerCounters(sm stats.Manager) {
+	for _, sub := range s.subjects {
+		if sub.limit.Quota == 0 {
+			continue
+		}
+		if c, err := stats.GetOrRegisterCounter(sm, sub.key+">>>quota>>>usage"); err == nil {
+			sub.usage = c
+		} else {
+			sub.usage = new(appstats.Counter)
+		}
+	}
+	s.load()
+}
+
+// Type implements common.HasType.
+func (*Shaper) Type() interface{} {
+	return routing.TrafficShaperType()
+}
+
+// Start implements common.Runnable.
+func (s *Shaper) Start() error {
+	s.refresh(time.Now())
+	go s.loop()
+	return nil
+}
+
+// Close implements common.Closable. It saves the usage counted since the last save.
+func (s *Shaper) Close() error {
+	s.cancel()
+	s.access.Lock()
+	s.saved = time.Time{}
+	s.access.Unlock()
+	s.refresh(time.Now())
+	return nil
+}
+
+func (s *Shaper) loop() {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.refresh(now)
+		}
+	}
+}
+
+// Shape implements routing.TrafficShaper.
+func (s *Shaper) Shape(ctx context.Context, outboundTag string) (*routing.Shaping, error) {
+	var subjects []*subject
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		if sub := s.inbounds[inbound.Tag]; sub != nil && inbound.Tag != "" {
+			subjects = append(subjects, sub)
+		}
+		if inbound.User != nil && inbound.User.Email != "" {
+			if sub := s.users[inbound.User.Email]; sub != nil {
+				subjects = append(subjects, sub)
+			}
+		}
+	}
+	if sub := s.outbounds[outboundTag]; sub != nil && outboundTag != "" {
+		subjects = append(subjects, sub)
+	}
+	if len(subjects) == 0 {
+		return nil, nil
+	}
+	shaping := new(routing.Shaping)
+	for _, sub := range subjects {
+		if sub.limit.QuotaAction == Limit_Reject && sub.exceeded() {
+			return nil, errors.New("quota of ", sub.name, " used up")
+		}
+		if sub.class != nil {
+			shaping.Classes = append(shaping.Classes, sub.class)
+		}
+		if sub.usage != nil {
+			shaping.Counters = append(shaping.Counters, sub.usage)
+		}
+	}
+	return shaping, nil
+}
+
+// refresh resets the usage when a new month starts, throttles the connections of the quotas used
+// up, and saves the usage every saveInterval.
+func (s *Shaper) refresh(now time.Time) {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	if period := quotaPeriod(now, s.resetDay); !period.Equal(s.period) {
+		if !s.period.IsZero() {
+			errors.LogInfo(s.ctx, "quota usage reset for the month from ", period.Format(time.DateOnly))
+			for _, sub := range s.subjects {
+				if sub.usage != nil {
+					sub.usage.Set(0)
+				}
+			}
+		}
+		s.period = period
+	}
+	for _, sub := range s.subjects {
+		exhausted := sub.exceeded()
+		if exhausted == sub.exhausted {
+			continue
+		}
+		sub.exhausted = exhausted
+		if !exhausted {
+			errors.LogInfo(s.ctx, "quota of ", sub.name, " available again")
+			if sub.class != nil {
+				sub.class.SetRate(int64(sub.limit.Rate))
+			}
+			continue
+		}
+		switch sub.limit.QuotaAction {
+		case Limit_Throttle:
+			errors.LogWarning(s.ctx, "quota of ", sub.name, " used up, throttling it")
+			sub.class.SetRate(int64(sub.limit.ThrottleRate))
+		case Limit_Reject:
+			errors.LogWarning(s.ctx, "quota of ", sub.name, " used up, rejecting its new connections")
+		}
+	}
+	if now.Sub(s.saved) >= saveInterval {
+		s.save()
+		s.saved = now
+	}
+}
+
+// quotaPeriod returns the start of the month, reset on day, now is in.
+func quotaPeriod(now time.Time, day int) time.Time {
+	year, month, today := now.Date()
+	if today < day {
+		month--
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+}
+
+// usageEntry returns where the usage is kept: in the usage file if set, or in the state.
+func (s *Shaper) usageEntry() storage.Entry {
+	return storage.Entry{Path: s.usageFile, Bucket: "shaper", Key: "usage"}
+}
+
+// load sets the usage counters to the usage kept, if it's of the current month.
+func (s *Shaper) load() {
+	b, err := s.usageEntry().Load()
+	if err != nil {
+		if err != storage.ErrNotFound {
+			errors.LogWarningInner(s.ctx, err, "failed to read quota usage")
+		}
+		return
+	}
+	var u usageRecord
+	if err := json.Unmarshal(b, &u); err != nil {
+		errors.LogWarningInner(s.ctx, err, "failed to parse quota usage")
+		return
+	}
+	s.access.Lock()
+	defer s.access.Unlock()
+	if !u.Period.Equal(quotaPeriod(time.Now(), s.resetDay)) {
+		return
+	}
+	s.period = u.Period
+	for _, sub := range s.subjects {
+		if value, found := u.Usage[sub.key]; found && sub.usage != nil {
+			sub.usage.Set(value)
+		}
+	}
+}
+
+// save keeps the usage, if there is somewhere to.
+func (s *Shaper) save() {
+	u := usageRecord{Period: s.period, Usage: make(map[string]int64)}
+	for _, sub := range s.subjects {
+		if sub.usage != nil {
+			u.Usage[sub.key] = sub.usage.Value()
+		}
+	}
+	b, err := json.Marshal(&u)
+	if err != nil {
+		errors.LogWarningInner(s.ctx, err, "failed to encode quota usage")
+		return
+	}
+	if err := s.usageEntry().Save(b); err != nil {
+		errors.LogWarningInner(s.ctx, err, "failed to save quota usage")
+	}
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		return New(ctx, config.(*Config))
+	}))
+}
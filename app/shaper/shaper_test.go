@@ -0,0 +1,100 @@
+package shaper
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+)
+
+func TestShaper(t *testing.T) {
+	config := &Config{
+		Limit: []*Limit{
+			{InboundTag: "in", Rate: 500},
+			{UserEmail: "a@example.com", Rate: 1000, Quota: 100, ThrottleRate: 10},
+			{OutboundTag: "out", Quota: 100, QuotaAction: Limit_Reject},
+		},
+		UsageFile: filepath.Join(t.TempDir(), "usage.json"),
+	}
+	manager, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+	s, err := newShaper(context.Background(), config)
+	common.Must(err)
+	s.registerCounters(manager)
+	now := time.Now()
+	s.refresh(now)
+
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+		Tag:  "in",
+		User: &protocol.MemoryUser{Email: "a@example.com"},
+	})
+	shaping, err := s.Shape(ctx, "out")
+	common.Must(err)
+	if len(shaping.Classes) != 2 || len(shaping.Counters) != 2 {
+		t.Fatalf("got %d classes and %d counters, want 2 and 2", len(shaping.Classes), len(shaping.Counters))
+	}
+	if shaping, _ := s.Shape(context.Background(), "direct"); shaping != nil {
+		t.Error("unlimited connection shaped")
+	}
+
+	user := manager.GetCounter("user>>>a@example.com>>>quota>>>usage")
+	out := manager.GetCounter("outbound>>>out>>>quota>>>usage")
+	if user == nil || out == nil {
+		t.Fatal("no usage counters")
+	}
+	user.Add(100)
+	s.refresh(now)
+	if rate := s.users["a@example.com"].class.Rate(); rate != 10 {
+		t.Errorf("rate of user with used up quota: got %d, want 10", rate)
+	}
+	if _, err := s.Shape(ctx, "direct"); err != nil {
+		t.Error("throttled user rejected: ", err)
+	}
+	out.Add(100)
+	if _, err := s.Shape(ctx, "out"); err == nil {
+		t.Error("connection to outbound with used up quota not rejected")
+	}
+
+	common.Must(s.Close())
+	manager, err = stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+	s, err = newShaper(context.Background(), config)
+	common.Must(err)
+	s.registerCounters(manager)
+	s.refresh(now)
+	if v := manager.GetCounter("user>>>a@example.com>>>quota>>>usage").Value(); v != 100 {
+		t.Errorf("usage after restart: got %d, want 100", v)
+	}
+	if rate := s.users["a@example.com"].class.Rate(); rate != 10 {
+		t.Errorf("rate of user with used up quota after restart: got %d, want 10", rate)
+	}
+
+	s.refresh(now.AddDate(0, 1, 0))
+	if v := manager.GetCounter("user>>>a@example.com>>>quota>>>usage").Value(); v != 0 {
+		t.Errorf("usage in the next month: got %d, want 0", v)
+	}
+	if rate := s.users["a@example.com"].class.Rate(); rate != 1000 {
+		t.Errorf("rate of user in the next month: got %d, want 1000", rate)
+	}
+	if _, err := s.Shape(ctx, "out"); err != nil {
+		t.Error("connection rejected in the next month: ", err)
+	}
+}
+
+func TestNewShaperErrors(t *testing.T) {
+	for _, limits := range [][]*Limit{
+		{{Rate: 100}},
+		{{InboundTag: "in", UserEmail: "a@example.com", Rate: 100}},
+		{{UserEmail: "a@example.com", Quota: 100}},
+		{{OutboundTag: "out", Rate: 100}, {OutboundTag: "out", Rate: 200}},
+	} {
+		if _, err := newShaper(context.Background(), &Config{Limit: limits}); err == nil {
+			t.Errorf("no error for limits %v", limits)
+		}
+	}
+}
@@ -110,6 +110,37 @@ func (s *statsServer) QueryStats(ctx context.Context, request *QueryStatsRequest
 	return response, nil
 }
 
+func (s *statsServer) QueryStatsSeries(ctx context.Context, request *QueryStatsSeriesRequest) (*QueryStatsSeriesResponse, error) {
+	matcher, err := strmatcher.Substr.New(request.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	manager, ok := s.stats.(*stats.Manager)
+	if !ok {
+		return nil, errors.New("QueryStatsSeries only works its own stats.Manager.")
+	}
+
+	response := &QueryStatsSeriesResponse{}
+	manager.VisitSeries(func(name string, series *stats.Series) bool {
+		if matcher.Match(name) {
+			snapshot := series.Snapshot()
+			stat := &StatSeries{
+				Name:     name,
+				Interval: int64(snapshot.Interval / time.Second),
+				Values:   snapshot.Values,
+			}
+			if !snapshot.Start.IsZero() {
+				stat.Start = snapshot.Start.Unix()
+			}
+			response.Series = append(response.Series, stat)
+		}
+		return true
+	})
+
+	return response, nil
+}
+
 func (s *statsServer) GetSysStats(ctx context.Context, request *SysStatsRequest) (*SysStatsResponse, error) {
 	var rtm runtime.MemStats
 	runtime.ReadMemStats(&rtm)
@@ -3,6 +3,7 @@ package command_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -89,3 +90,33 @@ func TestQueryStats(t *testing.T) {
 		t.Error(r)
 	}
 }
+
+func TestQueryStatsSeries(t *testing.T) {
+	m, err := stats.NewManager(context.Background(), &stats.Config{SeriesLength: 60})
+	common.Must(err)
+
+	sc1, err := m.RegisterCounter("test_counter")
+	common.Must(err)
+	_, err = m.RegisterCounter("other_counter")
+	common.Must(err)
+
+	now := time.Now()
+	m.GetSeries("test_counter").Sample(now)
+	sc1.Set(5)
+	m.GetSeries("test_counter").Sample(now.Add(time.Minute))
+
+	s := NewStatsServer(m)
+	resp, err := s.QueryStatsSeries(context.Background(), &QueryStatsSeriesRequest{
+		Pattern: "test_",
+	})
+	common.Must(err)
+
+	if r := cmp.Diff(resp.Series, []*StatSeries{{
+		Name:     "test_counter",
+		Start:    now.Unix(),
+		Interval: 60,
+		Values:   []int64{5},
+	}}, cmpopts.IgnoreUnexported(StatSeries{})); r != "" {
+		t.Error(r)
+	}
+}
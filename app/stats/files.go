@@ -0,0 +1,84 @@
+package stats
+
+import (
+	"context"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/task"
+)
+
+const (
+	// OpenFilesCounter and FileLimitCounter are the counters of the open files of the process and
+	// of their limit.
+	OpenFilesCounter = "system>>>files>>>open"
+	FileLimitCounter = "system>>>files>>>limit"
+
+	filesInterval        = 10 * time.Second
+	defaultFileWarnRatio = 0.8
+	fileWarnHysteresis   = 0.9
+)
+
+// fileMonitor keeps the counters of the open files up to date, and warns when they come close to
+// the limit, beyond which new connections fail with "too many open files".
+type fileMonitor struct {
+	open   *Counter
+	limit  *Counter
+	ratio  float64
+	warned bool
+	task   *task.Periodic
+}
+
+// setupFiles raises the limit of open files to the one of config, and starts keeping track of them
+// in m.
+func setupFiles(ctx context.Context, m *Manager, config *Config) *fileMonitor {
+	if config.FileLimit > 0 {
+		limit, err := raiseFileLimit(config.FileLimit)
+		if err != nil {
+			errors.LogWarningInner(ctx, err, "failed to raise the limit of open files to ", config.FileLimit)
+		}
+		if limit > 0 {
+			errors.LogInfo(ctx, "limit of open files: ", limit)
+		}
+	}
+
+	f := &fileMonitor{
+		open:  new(Counter),
+		limit: new(Counter),
+		ratio: float64(config.FileWarnRatio),
+	}
+	if f.ratio <= 0 {
+		f.ratio = defaultFileWarnRatio
+	}
+	m.counters[OpenFilesCounter] = f.open
+	m.counters[FileLimitCounter] = f.limit
+	f.task = &task.Periodic{
+		Interval: filesInterval,
+		Execute:  f.update,
+	}
+	return f
+}
+
+func (f *fileMonitor) update() error {
+	limit, err := fileLimit()
+	if err != nil {
+		return nil
+	}
+	open, err := openFiles()
+	if err != nil {
+		return nil
+	}
+	f.open.Set(int64(open))
+	f.limit.Set(int64(limit))
+
+	threshold := f.ratio * float64(limit)
+	switch {
+	case !f.warned && float64(open) >= threshold:
+		f.warned = true
+		errors.LogWarning(context.Background(), open, " files open, close to the limit of ", limit)
+	case f.warned && float64(open) < threshold*fileWarnHysteresis:
+		f.warned = false
+		errors.LogInfo(context.Background(), open, " files open, back below the limit of ", limit)
+	}
+	return nil
+}
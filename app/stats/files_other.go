@@ -0,0 +1,20 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package stats
+
+import (
+	"github.com/xtls/xray-core/common/errors"
+)
+
+func raiseFileLimit(target uint64) (uint64, error) {
+	return 0, errors.New("limit of open files not supported on this platform")
+}
+
+func fileLimit() (uint64, error) {
+	return 0, errors.New("limit of open files not supported on this platform")
+}
+
+func openFiles() (int, error) {
+	return 0, errors.New("open files not supported on this platform")
+}
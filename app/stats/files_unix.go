@@ -0,0 +1,56 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package stats
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// raiseFileLimit raises the soft limit of open files to target, and the hard one too if it's
+// lower and the process is privileged enough. It returns the limit in effect afterwards.
+func raiseFileLimit(target uint64) (uint64, error) {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return 0, err
+	}
+	if rlimit.Cur >= target {
+		return rlimit.Cur, nil
+	}
+	want := syscall.Rlimit{Cur: target, Max: max(rlimit.Max, target)}
+	err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &want)
+	if err == nil {
+		return target, nil
+	}
+	if rlimit.Cur >= rlimit.Max {
+		return rlimit.Cur, err
+	}
+	// Up to the hard limit, which needs no privilege.
+	want = syscall.Rlimit{Cur: rlimit.Max, Max: rlimit.Max}
+	if syscall.Setrlimit(syscall.RLIMIT_NOFILE, &want) != nil {
+		return rlimit.Cur, err
+	}
+	return rlimit.Max, err
+}
+
+func fileLimit() (uint64, error) {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return 0, err
+	}
+	return rlimit.Cur, nil
+}
+
+func openFiles() (int, error) {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		entries, err := os.ReadDir(dir)
+		if err == nil {
+			// Without the one of the directory being read.
+			return len(entries) - 1, nil
+		}
+	}
+	return 0, errors.New("failed to list open files")
+}
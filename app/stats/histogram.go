@@ -0,0 +1,56 @@
+package stats
+
+import (
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/features/stats"
+)
+
+// histogramBuckets is the number of bounded buckets of Histogram.
+const histogramBuckets = 16
+
+// histogramBounds are the upper bounds of the buckets of Histogram, doubling from 1ms to about 33s.
+var histogramBounds = func() []time.Duration {
+	bounds := make([]time.Duration, histogramBuckets)
+	for i := range bounds {
+		bounds[i] = time.Millisecond << i
+	}
+	return bounds
+}()
+
+// Histogram is an implementation of stats.Histogram.
+type Histogram struct {
+	access sync.Mutex
+	counts [histogramBuckets + 1]int64
+	count  int64
+	sum    time.Duration
+}
+
+// Observe implements stats.Histogram.
+func (h *Histogram) Observe(d time.Duration) {
+	i := 0
+	for i < len(histogramBounds) && d > histogramBounds[i] {
+		i++
+	}
+
+	h.access.Lock()
+	defer h.access.Unlock()
+
+	h.counts[i]++
+	h.count++
+	h.sum += d
+}
+
+// Snapshot implements stats.Histogram.
+func (h *Histogram) Snapshot() stats.HistogramSnapshot {
+	h.access.Lock()
+	defer h.access.Unlock()
+
+	return stats.HistogramSnapshot{
+		Bounds: histogramBounds,
+		Counts: append([]int64(nil), h.counts[:]...),
+		Count:  h.count,
+		Sum:    h.sum,
+	}
+}
@@ -0,0 +1,47 @@
+package stats_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+func TestStatsHistogram(t *testing.T) {
+	raw, err := common.CreateObject(context.Background(), &Config{})
+	common.Must(err)
+
+	m := raw.(stats.Manager)
+	h, err := m.RegisterHistogram("test.histogram")
+	common.Must(err)
+
+	h.Observe(500 * time.Microsecond)
+	h.Observe(3 * time.Millisecond)
+	h.Observe(time.Minute)
+
+	s := h.Snapshot()
+	if s.Count != 3 {
+		t.Fatal("unexpected count: ", s.Count)
+	}
+	if s.Sum != time.Minute+3500*time.Microsecond {
+		t.Fatal("unexpected sum: ", s.Sum)
+	}
+	if len(s.Counts) != len(s.Bounds)+1 {
+		t.Fatal("unexpected number of buckets: ", len(s.Counts))
+	}
+	// Buckets are 1ms, 2ms, 4ms, ..., and one above the last bound.
+	if s.Counts[0] != 1 || s.Counts[2] != 1 || s.Counts[len(s.Counts)-1] != 1 {
+		t.Fatal("unexpected buckets: ", s.Counts)
+	}
+
+	if m.GetHistogram("test.histogram") != h {
+		t.Fatal("histogram not found")
+	}
+	common.Must(m.UnregisterHistogram("test.histogram"))
+	if m.GetHistogram("test.histogram") != nil {
+		t.Fatal("histogram not removed")
+	}
+}
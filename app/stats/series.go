@@ -0,0 +1,85 @@
+package stats
+
+import (
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/features/stats"
+)
+
+const defaultSeriesInterval = time.Minute
+
+// SeriesSnapshot is the state of a Series at some point.
+type SeriesSnapshot struct {
+	// Start is the start of the first interval.
+	Start time.Time
+	// Interval is the length of each interval.
+	Interval time.Duration
+	// Values are the increases of the counter in each interval, oldest first.
+	Values []int64
+}
+
+// Series keeps the history of a counter as its increase in each of the last intervals, in a ring
+// of fixed length. Only completed intervals are kept.
+type Series struct {
+	access   sync.Mutex
+	counter  stats.Counter
+	interval time.Duration
+	values   []int64
+	// next is the index in values of the next interval, and filled the number of intervals kept.
+	next   int
+	filled int
+	// last is the value of the counter at end, the end of the last interval.
+	last    int64
+	end     time.Time
+	sampled bool
+}
+
+// NewSeries creates a Series of counter, of length intervals of interval each. length must not be 0.
+func NewSeries(counter stats.Counter, length int, interval time.Duration) *Series {
+	return &Series{
+		counter:  counter,
+		interval: interval,
+		values:   make([]int64, length),
+	}
+}
+
+// Sample ends the current interval at now. The first sample only marks the start of the first
+// interval. A counter below its last value was reset, and its value is taken as the increase.
+func (s *Series) Sample(now time.Time) {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	value := s.counter.Value()
+	if s.sampled {
+		delta := value - s.last
+		if delta < 0 {
+			delta = value
+		}
+		s.values[s.next] = delta
+		s.next = (s.next + 1) % len(s.values)
+		if s.filled < len(s.values) {
+			s.filled++
+		}
+	}
+	s.sampled = true
+	s.last = value
+	s.end = now
+}
+
+// Snapshot returns the intervals kept.
+func (s *Series) Snapshot() SeriesSnapshot {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	values := make([]int64, 0, s.filled)
+	first := (s.next - s.filled + len(s.values)) % len(s.values)
+	for i := 0; i < s.filled; i++ {
+		values = append(values, s.values[(first+i)%len(s.values)])
+	}
+	return SeriesSnapshot{
+		Start:    s.end.Add(-time.Duration(s.filled) * s.interval),
+		Interval: s.interval,
+		Values:   values,
+	}
+}
@@ -0,0 +1,37 @@
+package stats_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/xtls/xray-core/app/stats"
+)
+
+func TestSeries(t *testing.T) {
+	c := new(Counter)
+	s := NewSeries(c, 3, time.Minute)
+	start := time.Unix(1700000000, 0)
+
+	if snapshot := s.Snapshot(); len(snapshot.Values) != 0 {
+		t.Fatal("unexpected values before first sample: ", snapshot.Values)
+	}
+
+	s.Sample(start)
+	for i, value := range []int64{10, 30, 30, 5, 12} {
+		c.Set(value)
+		s.Sample(start.Add(time.Duration(i+1) * time.Minute))
+	}
+
+	snapshot := s.Snapshot()
+	// The counter was reset to 5 in the 4th interval; only the last 3 intervals are kept.
+	if want := []int64{0, 5, 7}; !reflect.DeepEqual(snapshot.Values, want) {
+		t.Error("values: ", snapshot.Values, ", want ", want)
+	}
+	if want := start.Add(2 * time.Minute); !snapshot.Start.Equal(want) {
+		t.Error("start: ", snapshot.Start, ", want ", want)
+	}
+	if snapshot.Interval != time.Minute {
+		t.Error("interval: ", snapshot.Interval)
+	}
+}
@@ -3,27 +3,53 @@ package stats
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/task"
 	"github.com/xtls/xray-core/features/stats"
 )
 
 // Manager is an implementation of stats.Manager.
 type Manager struct {
-	access    sync.RWMutex
-	counters  map[string]*Counter
-	onlineMap map[string]*OnlineMap
-	channels  map[string]*Channel
-	running   bool
+	access     sync.RWMutex
+	counters   map[string]*Counter
+	onlineMap  map[string]*OnlineMap
+	histograms map[string]*Histogram
+	channels   map[string]*Channel
+	files      *fileMonitor
+	running    bool
+
+	// series are the histories of counters, if seriesLength is not 0. They have their own lock
+	// for being sampled while access is held.
+	seriesAccess   sync.RWMutex
+	series         map[string]*Series
+	seriesLength   int
+	seriesInterval time.Duration
+	seriesTask     *task.Periodic
 }
 
 // NewManager creates an instance of Statistics Manager.
 func NewManager(ctx context.Context, config *Config) (*Manager, error) {
 	m := &Manager{
-		counters:  make(map[string]*Counter),
-		onlineMap: make(map[string]*OnlineMap),
-		channels:  make(map[string]*Channel),
+		counters:   make(map[string]*Counter),
+		onlineMap:  make(map[string]*OnlineMap),
+		histograms: make(map[string]*Histogram),
+		channels:   make(map[string]*Channel),
+		series:     make(map[string]*Series),
+	}
+	m.files = setupFiles(ctx, m, config)
+	if config.SeriesLength > 0 {
+		m.seriesLength = int(config.SeriesLength)
+		m.seriesInterval = time.Duration(config.SeriesInterval) * time.Second
+		if m.seriesInterval <= 0 {
+			m.seriesInterval = defaultSeriesInterval
+		}
+		m.seriesTask = &task.Periodic{
+			Interval: m.seriesInterval,
+			Execute:  m.sampleSeries,
+		}
 	}
 
 	return m, nil
@@ -45,6 +71,11 @@ func (m *Manager) RegisterCounter(name string) (stats.Counter, error) {
 	errors.LogDebug(context.Background(), "create new counter ", name)
 	c := new(Counter)
 	m.counters[name] = c
+	if m.seriesLength > 0 {
+		m.seriesAccess.Lock()
+		m.series[name] = NewSeries(c, m.seriesLength, m.seriesInterval)
+		m.seriesAccess.Unlock()
+	}
 	return c, nil
 }
 
@@ -56,6 +87,9 @@ func (m *Manager) UnregisterCounter(name string) error {
 	if _, found := m.counters[name]; found {
 		errors.LogDebug(context.Background(), "remove counter ", name)
 		delete(m.counters, name)
+		m.seriesAccess.Lock()
+		delete(m.series, name)
+		m.seriesAccess.Unlock()
 	}
 	return nil
 }
@@ -83,6 +117,37 @@ func (m *Manager) VisitCounters(visitor func(string, stats.Counter) bool) {
 	}
 }
 
+// GetSeries returns the history of a counter, or nil if the counter has none.
+func (m *Manager) GetSeries(name string) *Series {
+	m.seriesAccess.RLock()
+	defer m.seriesAccess.RUnlock()
+
+	return m.series[name]
+}
+
+// VisitSeries calls visitor function on the histories of all managed counters.
+func (m *Manager) VisitSeries(visitor func(string, *Series) bool) {
+	m.seriesAccess.RLock()
+	defer m.seriesAccess.RUnlock()
+
+	for name, s := range m.series {
+		if !visitor(name, s) {
+			break
+		}
+	}
+}
+
+func (m *Manager) sampleSeries() error {
+	m.seriesAccess.RLock()
+	defer m.seriesAccess.RUnlock()
+
+	now := time.Now()
+	for _, s := range m.series {
+		s.Sample(now)
+	}
+	return nil
+}
+
 // RegisterOnlineMap implements stats.Manager.
 func (m *Manager) RegisterOnlineMap(name string) (stats.OnlineMap, error) {
 	m.access.Lock()
@@ -120,6 +185,55 @@ func (m *Manager) GetOnlineMap(name string) stats.OnlineMap {
 	return nil
 }
 
+// RegisterHistogram implements stats.Manager.
+func (m *Manager) RegisterHistogram(name string) (stats.Histogram, error) {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	if _, found := m.histograms[name]; found {
+		return nil, errors.New("Histogram ", name, " already registered.")
+	}
+	errors.LogDebug(context.Background(), "create new histogram ", name)
+	h := new(Histogram)
+	m.histograms[name] = h
+	return h, nil
+}
+
+// UnregisterHistogram implements stats.Manager.
+func (m *Manager) UnregisterHistogram(name string) error {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	if _, found := m.histograms[name]; found {
+		errors.LogDebug(context.Background(), "remove histogram ", name)
+		delete(m.histograms, name)
+	}
+	return nil
+}
+
+// GetHistogram implements stats.Manager.
+func (m *Manager) GetHistogram(name string) stats.Histogram {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	if h, found := m.histograms[name]; found {
+		return h
+	}
+	return nil
+}
+
+// VisitHistograms calls visitor function on all managed histograms.
+func (m *Manager) VisitHistograms(visitor func(string, stats.Histogram) bool) {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	for name, h := range m.histograms {
+		if !visitor(name, h) {
+			break
+		}
+	}
+}
+
 // RegisterChannel implements stats.Manager.
 func (m *Manager) RegisterChannel(name string) (stats.Channel, error) {
 	m.access.Lock()
@@ -167,6 +281,14 @@ func (m *Manager) Start() error {
 	defer m.access.Unlock()
 	m.running = true
 	errs := []error{}
+	if err := m.files.task.Start(); err != nil {
+		errs = append(errs, err)
+	}
+	if m.seriesTask != nil {
+		if err := m.seriesTask.Start(); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	for _, channel := range m.channels {
 		if err := channel.Start(); err != nil {
 			errs = append(errs, err)
@@ -184,6 +306,14 @@ func (m *Manager) Close() error {
 	defer m.access.Unlock()
 	m.running = false
 	errs := []error{}
+	if err := m.files.task.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if m.seriesTask != nil {
+		if err := m.seriesTask.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	for name, channel := range m.channels {
 		errors.LogDebug(context.Background(), "remove channel ", name)
 		delete(m.channels, name)
@@ -2,6 +2,7 @@ package stats_test
 
 import (
 	"context"
+	"runtime"
 	"testing"
 	"time"
 
@@ -84,3 +85,23 @@ func TestStatsChannelRunnable(t *testing.T) {
 		t.Fatalf("unexpected running channel: test.channel.%d", 3)
 	}
 }
+
+func TestOpenFiles(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("open files not supported on ", runtime.GOOS)
+	}
+
+	m, err := NewManager(context.Background(), &Config{})
+	common.Must(err)
+	common.Must(m.Start())
+	defer m.Close()
+
+	open := m.GetCounter(OpenFilesCounter)
+	limit := m.GetCounter(FileLimitCounter)
+	if open == nil || limit == nil {
+		t.Fatal("counters of open files not registered")
+	}
+	if open.Value() <= 0 || limit.Value() < open.Value() {
+		t.Error("unexpected open files: ", open.Value(), " of ", limit.Value())
+	}
+}
@@ -0,0 +1,219 @@
+package subscription
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/proxy/shadowsocks"
+	"github.com/xtls/xray-core/proxy/shadowsocks_2022"
+	"github.com/xtls/xray-core/proxy/trojan"
+	"github.com/xtls/xray-core/proxy/vless"
+	"github.com/xtls/xray-core/proxy/vmess"
+)
+
+// shadowsocksMethods are the names of the ciphers of Shadowsocks users in share links.
+var shadowsocksMethods = map[shadowsocks.CipherType]string{
+	shadowsocks.CipherType_AES_128_GCM:        "aes-128-gcm",
+	shadowsocks.CipherType_AES_256_GCM:        "aes-256-gcm",
+	shadowsocks.CipherType_CHACHA20_POLY1305:  "chacha20-ietf-poly1305",
+	shadowsocks.CipherType_XCHACHA20_POLY1305: "xchacha20-ietf-poly1305",
+	shadowsocks.CipherType_NONE:               "none",
+}
+
+// link is the share link of a user of an inbound, and the same as a Clash proxy, nil if Clash
+// can't describe it.
+type link struct {
+	url   string
+	clash map[string]interface{}
+}
+
+// newLink returns the link of the user with account on inbound.
+func newLink(inbound *Inbound, account protocol.Account) (*link, error) {
+	params, err := url.ParseQuery(inbound.Query)
+	if err != nil {
+		return nil, errors.New("invalid transport of inbound ", inbound.Tag).Base(err)
+	}
+	name := inbound.Name
+	if name == "" {
+		name = inbound.Tag
+	}
+	host := net.JoinHostPort(inbound.Address, strconv.Itoa(int(inbound.Port)))
+	clash := clashProxy(name, inbound, params)
+
+	switch account := account.ToProto().(type) {
+	case *vless.Account:
+		encryption := account.Encryption
+		if encryption == "" {
+			encryption = "none"
+		}
+		params.Set("encryption", encryption)
+		if account.Flow != "" {
+			params.Set("flow", account.Flow)
+		}
+		if clash != nil {
+			if encryption != "none" {
+				clash = nil
+			} else {
+				clash["type"] = "vless"
+				clash["uuid"] = account.Id
+				if account.Flow != "" {
+					clash["flow"] = account.Flow
+				}
+			}
+		}
+		u := &url.URL{Scheme: "vless", User: url.User(account.Id), Host: host, RawQuery: params.Encode(), Fragment: name}
+		return &link{url: u.String(), clash: clash}, nil
+	case *vmess.Account:
+		u, err := vmessLink(name, inbound, account.Id, params)
+		if err != nil {
+			return nil, err
+		}
+		if clash != nil {
+			clash["type"] = "vmess"
+			clash["uuid"] = account.Id
+			clash["alterId"] = 0
+			clash["cipher"] = "auto"
+		}
+		return &link{url: u, clash: clash}, nil
+	case *trojan.Account:
+		u := &url.URL{Scheme: "trojan", User: url.User(account.Password), Host: host, RawQuery: params.Encode(), Fragment: name}
+		if clash != nil {
+			clash["type"] = "trojan"
+			clash["password"] = account.Password
+		}
+		return &link{url: u.String(), clash: clash}, nil
+	case *shadowsocks.Account:
+		method, found := shadowsocksMethods[account.CipherType]
+		if !found {
+			return nil, errors.New("no share link for Shadowsocks cipher ", account.CipherType)
+		}
+		return shadowsocksLink(name, host, inbound, params, method, account.Password, clash)
+	case *shadowsocks_2022.Account:
+		password := account.Key
+		if inbound.ServerKey != "" {
+			password = inbound.ServerKey + ":" + password
+		}
+		return shadowsocksLink(name, host, inbound, params, inbound.Method, password, clash)
+	default:
+		return nil, errors.New("no share link for the users of inbound ", inbound.Tag)
+	}
+}
+
+func shadowsocksLink(name, host string, inbound *Inbound, params url.Values, method, password string, clash map[string]interface{}) (*link, error) {
+	if len(params) > 1 || params.Get("type") != "tcp" {
+		return nil, errors.New("ss links can't describe the transport of inbound ", inbound.Tag)
+	}
+	// SIP002 asks for the credential in base64 but for 2022 methods, whose keys are in base64 already.
+	user := url.User(base64.RawURLEncoding.EncodeToString([]byte(method + ":" + password)))
+	if strings.HasPrefix(method, "2022-") {
+		user = url.UserPassword(method, password)
+	}
+	u := &url.URL{Scheme: "ss", User: user, Host: host, Fragment: name}
+	if clash != nil {
+		clash["type"] = "ss"
+		clash["cipher"] = method
+		clash["password"] = password
+		delete(clash, "network")
+	}
+	return &link{url: u.String(), clash: clash}, nil
+}
+
+// vmessLink returns the link of a VMess user, in the JSON form of v2rayN.
+func vmessLink(name string, inbound *Inbound, id string, params url.Values) (string, error) {
+	if params.Get("security") == "reality" {
+		return "", errors.New("vmess links can't describe REALITY")
+	}
+	path := params.Get("path")
+	if params.Get("type") == "grpc" {
+		path = params.Get("serviceName")
+	}
+	b, err := json.Marshal(map[string]string{
+		"v":    "2",
+		"ps":   name,
+		"add":  inbound.Address,
+		"port": strconv.Itoa(int(inbound.Port)),
+		"id":   id,
+		"aid":  "0",
+		"scy":  "auto",
+		"net":  params.Get("type"),
+		"type": params.Get("headerType"),
+		"host": params.Get("host"),
+		"path": path,
+		"tls":  params.Get("security"),
+		"sni":  params.Get("sni"),
+		"alpn": params.Get("alpn"),
+		"fp":   params.Get("fp"),
+	})
+	if err != nil {
+		return "", err
+	}
+	return "vmess://" + base64.StdEncoding.EncodeToString(b), nil
+}
+
+// clashProxy returns the server and transport options of a Clash proxy for inbound, the ones of
+// the protocol left to the caller, or nil if Clash can't describe the transport.
+func clashProxy(name string, inbound *Inbound, params url.Values) map[string]interface{} {
+	proxy := map[string]interface{}{
+		"name":   name,
+		"server": inbound.Address,
+		"port":   inbound.Port,
+		"udp":    true,
+	}
+	if params.Get("headerType") != "" && params.Get("headerType") != "none" {
+		return nil
+	}
+	switch network := params.Get("type"); network {
+	case "", "tcp":
+		proxy["network"] = "tcp"
+	case "ws", "httpupgrade":
+		proxy["network"] = "ws"
+		opts := map[string]interface{}{}
+		if path := params.Get("path"); path != "" {
+			opts["path"] = path
+		}
+		if host := params.Get("host"); host != "" {
+			opts["headers"] = map[string]string{"Host": host}
+		}
+		if network == "httpupgrade" {
+			opts["v2ray-http-upgrade"] = true
+		}
+		proxy["ws-opts"] = opts
+	case "grpc":
+		proxy["network"] = "grpc"
+		proxy["grpc-opts"] = map[string]interface{}{"grpc-service-name": params.Get("serviceName")}
+	default:
+		return nil
+	}
+
+	switch params.Get("security") {
+	case "":
+	case "tls":
+		proxy["tls"] = true
+		if sni := params.Get("sni"); sni != "" {
+			proxy["servername"] = sni
+		}
+		if alpn := params.Get("alpn"); alpn != "" {
+			proxy["alpn"] = strings.Split(alpn, ",")
+		}
+		if fp := params.Get("fp"); fp != "" {
+			proxy["client-fingerprint"] = fp
+		}
+	case "reality":
+		proxy["tls"] = true
+		proxy["servername"] = params.Get("sni")
+		proxy["client-fingerprint"] = params.Get("fp")
+		proxy["reality-opts"] = map[string]interface{}{
+			"public-key": params.Get("pbk"),
+			"short-id":   params.Get("sid"),
+		}
+	default:
+		return nil
+	}
+	return proxy
+}
@@ -0,0 +1,269 @@
+// Package subscription serves the share links of the users of inbounds, so that operators can
+// hand out client configs without a panel. Each user subscribes at a URL of their own, whose
+// token is derived from their email and the secret of the server, and gets their links in the
+// base64 form clients subscribe to, or as a Clash config.
+package subscription
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/listen"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/proxy"
+)
+
+// Server is the subscription server.
+type Server struct {
+	config   *Config
+	ihm      inbound.Manager
+	stats    stats.Manager
+	dns      dns.Client
+	server   *http.Server
+	listener net.Listener
+}
+
+// NewServer creates a new Server based on the given config.
+func NewServer(ctx context.Context, config *Config) (*Server, error) {
+	if config.Secret == "" {
+		return nil, errors.New("subscription server without a secret")
+	}
+	s := &Server{
+		config: config,
+	}
+	common.Must(core.RequireFeatures(ctx, func(im inbound.Manager, sm stats.Manager, d dns.Client) {
+		s.ihm = im
+		s.stats = sm
+		s.dns = d
+	}))
+	return s, nil
+}
+
+// Type implements common.HasType.
+func (*Server) Type() interface{} {
+	return (*Server)(nil)
+}
+
+// Start implements common.Runnable.
+func (s *Server) Start() error {
+	listener, err := listen.TCP(context.Background(), s.config.Listen, s.dns)
+	if err != nil {
+		return errors.New("failed to listen on ", s.config.Listen).Base(err)
+	}
+	s.listener = listener
+	s.server = &http.Server{
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errors.LogInfo(context.Background(), "subscription server listening on ", listener.Addr())
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			errors.LogErrorInner(context.Background(), err, "failed to serve subscriptions")
+		}
+	}()
+	return nil
+}
+
+// Close implements common.Closable.
+func (s *Server) Close() error {
+	if s.server != nil {
+		return s.server.Close()
+	}
+	return nil
+}
+
+// Addr returns the address the server listens on, or nil before Start.
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Token returns the token of the user with email, under secret. Emails are matched regardless of
+// case, as elsewhere.
+func Token(secret, email string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.ToLower(email)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sub/{token}", s.serveSubscription)
+	mux.HandleFunc("GET /tokens", s.listTokens)
+	return mux
+}
+
+// user is a user of one of the inbounds of the config.
+type user struct {
+	inbound *Inbound
+	*protocol.MemoryUser
+}
+
+// users returns the users of the inbounds of the config, as they are now.
+func (s *Server) users(ctx context.Context) []user {
+	var users []user
+	for _, ib := range s.config.Inbounds {
+		handler, err := s.ihm.GetHandler(ctx, ib.Tag)
+		if err != nil {
+			errors.LogInfoInner(ctx, err, "no inbound ", ib.Tag, " to subscribe to")
+			continue
+		}
+		gi, ok := handler.(proxy.GetInbound)
+		if !ok {
+			continue
+		}
+		um, ok := gi.GetInbound().(proxy.UserManager)
+		if !ok {
+			errors.LogWarning(ctx, "inbound ", ib.Tag, " doesn't have users to subscribe")
+			continue
+		}
+		for _, u := range um.GetUsers(ctx) {
+			if u.Email != "" && u.Account != nil {
+				users = append(users, user{inbound: ib, MemoryUser: u})
+			}
+		}
+	}
+	return users
+}
+
+// serveSubscription writes the links of the user of the token, in the format of the format query
+// parameter: base64 as default, plain for the links as they are, or clash. Clash clients are
+// told apart by their User-Agent as well.
+func (s *Server) serveSubscription(w http.ResponseWriter, r *http.Request) {
+	token := []byte(r.PathValue("token"))
+	var email string
+	var links []*link
+	for _, u := range s.users(r.Context()) {
+		if subtle.ConstantTimeCompare([]byte(Token(s.config.Secret, u.Email)), token) != 1 {
+			continue
+		}
+		l, err := newLink(u.inbound, u.Account)
+		if err != nil {
+			errors.LogWarningInner(r.Context(), err, "no share link for ", u.Email)
+			continue
+		}
+		email = u.Email
+		links = append(links, l)
+	}
+	if len(links) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		agent := strings.ToLower(r.UserAgent())
+		if strings.Contains(agent, "clash") || strings.Contains(agent, "mihomo") || strings.Contains(agent, "stash") {
+			format = "clash"
+		}
+	}
+	var body []byte
+	switch format {
+	case "", "base64", "plain":
+		urls := make([]string, 0, len(links))
+		for _, l := range links {
+			urls = append(urls, l.url)
+		}
+		body = []byte(strings.Join(urls, "\n"))
+		if format != "plain" {
+			body = []byte(base64.StdEncoding.EncodeToString(body))
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	case "clash":
+		var err error
+		if body, err = clashConfig(links); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/yaml; charset=utf-8")
+	default:
+		http.Error(w, "unknown format "+format, http.StatusBadRequest)
+		return
+	}
+	if userinfo := s.userinfo(email); userinfo != "" {
+		w.Header().Set("Subscription-Userinfo", userinfo)
+	}
+	w.Write(body)
+}
+
+// userinfo returns the traffic of the user of email, in the Subscription-Userinfo header clients
+// show it from, or "" if it isn't counted.
+func (s *Server) userinfo(email string) string {
+	uplink := s.stats.GetCounter("user>>>" + email + ">>>traffic>>>uplink")
+	downlink := s.stats.GetCounter("user>>>" + email + ">>>traffic>>>downlink")
+	if uplink == nil && downlink == nil {
+		return ""
+	}
+	var upload, download int64
+	if uplink != nil {
+		upload = uplink.Value()
+	}
+	if downlink != nil {
+		download = downlink.Value()
+	}
+	return fmt.Sprintf("upload=%d; download=%d", upload, download)
+}
+
+// clashConfig returns a Clash config with the proxies of links, picked from a single group.
+func clashConfig(links []*link) ([]byte, error) {
+	var proxies []interface{}
+	var names []string
+	for _, l := range links {
+		if l.clash != nil {
+			proxies = append(proxies, l.clash)
+			names = append(names, l.clash["name"].(string))
+		}
+	}
+	if len(proxies) == 0 {
+		return nil, errors.New("Clash can't describe any of the links")
+	}
+	return yaml.Marshal(map[string]interface{}{
+		"proxies": proxies,
+		"proxy-groups": []interface{}{map[string]interface{}{
+			"name":    "Proxy",
+			"type":    "select",
+			"proxies": names,
+		}},
+		"rules": []string{"MATCH,Proxy"},
+	})
+}
+
+// listTokens writes the subscription URLs of all users, by lowercase email, to requests with the secret as
+// bearer token.
+func (s *Server) listTokens(w http.ResponseWriter, r *http.Request) {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.config.Secret)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	urls := map[string]string{}
+	for _, u := range s.users(r.Context()) {
+		urls[strings.ToLower(u.Email)] = strings.TrimSuffix(s.config.Url, "/") + "/sub/" + Token(s.config.Secret, u.Email)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(urls)
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, cfg interface{}) (interface{}, error) {
+		return NewServer(ctx, cfg.(*Config))
+	}))
+}
@@ -0,0 +1,145 @@
+package subscription_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/xtls/xray-core/app/subscription"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf/serial"
+	_ "github.com/xtls/xray-core/main/distro/all"
+	"github.com/xtls/xray-core/testing/servers/tcp"
+)
+
+const testConfig = `{
+	"subscriptionServer": {
+		"listen": "127.0.0.1:0",
+		"secret": "secret",
+		"url": "https://sub.example.com/",
+		"inbounds": [
+			{"tag": "reality", "name": "jp", "address": "jp.example.com"},
+			{"tag": "ss", "address": "203.0.113.1", "port": 443}
+		]
+	},
+	"inbounds": [
+		{
+			"tag": "reality",
+			"port": %d,
+			"listen": "127.0.0.1",
+			"protocol": "vless",
+			"settings": {
+				"clients": [{"id": "a06fe789-5ab1-480b-8124-ae4599801ff3", "email": "Alice@example.com", "flow": "xtls-rprx-vision"}],
+				"decryption": "none"
+			},
+			"streamSettings": {
+				"security": "reality",
+				"realitySettings": {
+					"target": "www.example.com:443",
+					"serverNames": ["www.example.com"],
+					"privateKey": "AAgPFh0kKzI5QEdOVVxjanF4f4aNlJuiqbC3vsXM01o",
+					"shortIds": ["6ba85179e30d4fc2"]
+				}
+			}
+		},
+		{
+			"tag": "ss",
+			"port": %d,
+			"listen": "127.0.0.1",
+			"protocol": "shadowsocks",
+			"settings": {
+				"method": "2022-blake3-aes-128-gcm",
+				"password": "c2FtcGxla2V5MTIzNDU2Nw==",
+				"clients": [
+					{"password": "dXNlcmtleTEyMzQ1Njc4OQ==", "email": "alice@example.com"},
+					{"password": "Ym9ia2V5MTIzNDU2Nzg5MA==", "email": "bob@example.com"}
+				]
+			}
+		}
+	]
+}`
+
+func startServer(t *testing.T) (string, net.Port) {
+	t.Helper()
+	port := tcp.PickPort()
+	config, err := serial.LoadJSONConfig(strings.NewReader(fmt.Sprintf(testConfig, port, tcp.PickPort())))
+	common.Must(err)
+	instance, err := core.New(config)
+	common.Must(err)
+	common.Must(instance.Start())
+	t.Cleanup(func() { instance.Close() })
+	server := instance.GetFeature((*subscription.Server)(nil)).(*subscription.Server)
+	return "http://" + server.Addr().String(), port
+}
+
+func get(t *testing.T, url string, header http.Header) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	common.Must(err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	common.Must(err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	common.Must(err)
+	return resp.StatusCode, string(body)
+}
+
+func TestSubscription(t *testing.T) {
+	base, port := startServer(t)
+	token := subscription.Token("secret", "alice@example.com")
+
+	status, body := get(t, base+"/sub/"+token, nil)
+	if status != http.StatusOK {
+		t.Fatal("unexpected status ", status, ": ", body)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(body)
+	common.Must(err)
+	links := strings.Split(string(decoded), "\n")
+	expected := []string{
+		"vless://a06fe789-5ab1-480b-8124-ae4599801ff3@jp.example.com:" + port.String() + "?encryption=none&flow=xtls-rprx-vision&fp=chrome&pbk=yP7Kgb4ZbN8sreq_E8SQPXYy3OSVWqaLbl2a3vVOJhY&security=reality&sid=6ba85179e30d4fc2&sni=www.example.com&type=tcp#jp",
+		"ss://2022-blake3-aes-128-gcm:c2FtcGxla2V5MTIzNDU2Nw==%3AdXNlcmtleTEyMzQ1Njc4OQ==@203.0.113.1:443#ss",
+	}
+	if len(links) != 2 {
+		t.Fatal("unexpected links ", links)
+	}
+	for i := range expected {
+		if links[i] != expected[i] {
+			t.Errorf("got %s, want %s", links[i], expected[i])
+		}
+	}
+
+	status, body = get(t, base+"/sub/"+token+"?format=clash", nil)
+	if status != http.StatusOK || !strings.Contains(body, "public-key: yP7Kgb4ZbN8sreq_E8SQPXYy3OSVWqaLbl2a3vVOJhY") ||
+		!strings.Contains(body, "cipher: 2022-blake3-aes-128-gcm") || !strings.Contains(body, "MATCH,Proxy") {
+		t.Error("unexpected Clash config: ", body)
+	}
+
+	if status, _ := get(t, base+"/sub/"+subscription.Token("other", "alice@example.com"), nil); status != http.StatusNotFound {
+		t.Error("unexpected status of unknown token ", status)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	base, _ := startServer(t)
+	if status, _ := get(t, base+"/tokens", nil); status != http.StatusUnauthorized {
+		t.Fatal("unexpected status without secret ", status)
+	}
+	status, body := get(t, base+"/tokens", http.Header{"Authorization": {"Bearer secret"}})
+	if status != http.StatusOK {
+		t.Fatal("unexpected status ", status)
+	}
+	var urls map[string]string
+	common.Must(json.Unmarshal([]byte(body), &urls))
+	if len(urls) != 2 || urls["bob@example.com"] != "https://sub.example.com/sub/"+subscription.Token("secret", "bob@example.com") {
+		t.Error("unexpected tokens ", urls)
+	}
+}
@@ -0,0 +1,151 @@
+//go:build linux
+
+package tuner
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+const (
+	cgroupRoot = "/sys/fs/cgroup"
+	selfCgroup = "/proc/self/cgroup"
+
+	// Memory limits of cgroup v1 at or above this are unlimited, rounded down to pages.
+	unlimitedMemory = 1 << 62
+)
+
+func readLimits() (limits, error) {
+	return readCgroupLimits(cgroupRoot, selfCgroup)
+}
+
+// readCgroupLimits reads the limits of the cgroup of the process in the hierarchy mounted at root,
+// the lowest of the limits of the cgroup and its ancestors.
+func readCgroupLimits(root, self string) (limits, error) {
+	paths, err := readCgroupPaths(self)
+	if err != nil {
+		return limits{}, err
+	}
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		dir := cgroupDir(root, paths[""])
+		return limits{
+			cpu:    walkCgroup(root, dir, readCPUMax),
+			memory: uint64(walkCgroup(root, dir, func(dir string) float64 { return readMemory(dir, "memory.max") })),
+		}, nil
+	}
+
+	var l limits
+	if cpuRoot := filepath.Join(root, "cpu"); exists(cpuRoot) {
+		l.cpu = walkCgroup(cpuRoot, cgroupDir(cpuRoot, paths["cpu"]), readCFSQuota)
+	}
+	if memoryRoot := filepath.Join(root, "memory"); exists(memoryRoot) {
+		l.memory = uint64(walkCgroup(memoryRoot, cgroupDir(memoryRoot, paths["memory"]), func(dir string) float64 {
+			return readMemory(dir, "memory.limit_in_bytes")
+		}))
+	}
+	return l, nil
+}
+
+// readCgroupPaths returns the paths of the cgroups of the process by controller, "" for the
+// unified hierarchy of cgroup v2.
+func readCgroupPaths(self string) (map[string]string, error) {
+	f, err := os.Open(self)
+	if err != nil {
+		return nil, errors.New("failed to read cgroups").Base(err)
+	}
+	defer f.Close()
+
+	paths := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[1] == "" {
+			paths[""] = fields[2]
+			continue
+		}
+		for _, controller := range strings.Split(fields[1], ",") {
+			paths[controller] = fields[2]
+		}
+	}
+	return paths, scanner.Err()
+}
+
+// cgroupDir returns the directory of the cgroup at path under root. Without a cgroup namespace
+// the path is of the host, which is not mounted in a container, where root is the cgroup instead.
+func cgroupDir(root, path string) string {
+	dir := filepath.Join(root, path)
+	if !exists(dir) {
+		return root
+	}
+	return dir
+}
+
+// walkCgroup returns the lowest positive limit read from dir up to root, 0 if there's none.
+func walkCgroup(root, dir string, read func(dir string) float64) float64 {
+	var lowest float64
+	for {
+		if limit := read(dir); limit > 0 && (lowest == 0 || limit < lowest) {
+			lowest = limit
+		}
+		if dir == root || len(dir) <= len(root) {
+			return lowest
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// readCPUMax reads the CPUs of cpu.max of cgroup v2, "$MAX $PERIOD".
+func readCPUMax(dir string) float64 {
+	fields := strings.Fields(readFile(filepath.Join(dir, "cpu.max")))
+	if len(fields) != 2 {
+		return 0
+	}
+	return cpus(fields[0], fields[1])
+}
+
+// readCFSQuota reads the CPUs of the CFS quota of cgroup v1.
+func readCFSQuota(dir string) float64 {
+	return cpus(readFile(filepath.Join(dir, "cpu.cfs_quota_us")), readFile(filepath.Join(dir, "cpu.cfs_period_us")))
+}
+
+func cpus(quota, period string) float64 {
+	q, err := strconv.ParseInt(quota, 10, 64)
+	if err != nil || q <= 0 {
+		return 0
+	}
+	p, err := strconv.ParseInt(period, 10, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+	return float64(q) / float64(p)
+}
+
+func readMemory(dir, name string) float64 {
+	limit, err := strconv.ParseUint(readFile(filepath.Join(dir, name)), 10, 64)
+	if err != nil || limit >= unlimitedMemory {
+		return 0
+	}
+	return float64(limit)
+}
+
+func readFile(name string) string {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
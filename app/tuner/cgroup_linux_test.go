@@ -0,0 +1,86 @@
+//go:build linux
+
+package tuner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		name = filepath.Join(root, name)
+		common.Must(os.MkdirAll(filepath.Dir(name), 0o755))
+		common.Must(os.WriteFile(name, []byte(content), 0o644))
+	}
+}
+
+func TestReadCgroupLimits(t *testing.T) {
+	cases := []struct {
+		name   string
+		self   string
+		files  map[string]string
+		limits limits
+	}{
+		{
+			name: "v2",
+			self: "0::/xray\n",
+			files: map[string]string{
+				"cgroup.controllers": "cpu memory",
+				"xray/cpu.max":       "150000 100000",
+				"xray/memory.max":    "536870912",
+			},
+			limits: limits{cpu: 1.5, memory: 512 << 20},
+		},
+		{
+			name: "v2 parent",
+			self: "0::/a/b\n",
+			files: map[string]string{
+				"cgroup.controllers": "cpu memory",
+				"a/cpu.max":          "200000 100000",
+				"a/memory.max":       "1073741824",
+				"a/b/cpu.max":        "max 100000",
+				"a/b/memory.max":     "2147483648",
+			},
+			limits: limits{cpu: 2, memory: 1 << 30},
+		},
+		{
+			name: "v2 namespace",
+			self: "0::/host/path\n",
+			files: map[string]string{
+				"cgroup.controllers": "cpu memory",
+				"cpu.max":            "max 100000",
+				"memory.max":         "max",
+			},
+		},
+		{
+			name: "v1",
+			self: "4:memory:/docker/x\n3:cpu,cpuacct:/docker/x\n",
+			files: map[string]string{
+				"cpu/docker/x/cpu.cfs_quota_us":         "50000",
+				"cpu/docker/x/cpu.cfs_period_us":        "100000",
+				"memory/docker/x/memory.limit_in_bytes": "9223372036854771712",
+				"memory/docker/memory.limit_in_bytes":   "268435456",
+			},
+			limits: limits{cpu: 0.5, memory: 256 << 20},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			root := filepath.Join(dir, "cgroup")
+			self := filepath.Join(dir, "self")
+			writeFiles(t, root, c.files)
+			common.Must(os.WriteFile(self, []byte(c.self), 0o644))
+
+			l, err := readCgroupLimits(root, self)
+			common.Must(err)
+			if l != c.limits {
+				t.Errorf("got %+v, want %+v", l, c.limits)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package tuner
+
+// readLimits returns no limits, as only the cgroups of Linux are read.
+func readLimits() (limits, error) {
+	return limits{}, nil
+}
@@ -0,0 +1,95 @@
+package tuner
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features"
+	"github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/routing"
+)
+
+const (
+	defaultIdleReclaimAfter = 300 * time.Second
+	maxReclaimCheckInterval = 30 * time.Second
+)
+
+// idlePeriod tracks the periods without connections, so that memory is reclaimed once in each,
+// when it has lasted long enough.
+type idlePeriod struct {
+	after     time.Duration
+	since     time.Time
+	reclaimed bool
+}
+
+// observe records the number of connections in progress at now, and tells whether to reclaim.
+func (p *idlePeriod) observe(now time.Time, connections int) bool {
+	if connections > 0 {
+		p.since = time.Time{}
+		p.reclaimed = false
+		return false
+	}
+	if p.since.IsZero() {
+		p.since = now
+	}
+	if p.reclaimed || now.Sub(p.since) < p.after {
+		return false
+	}
+	p.reclaimed = true
+	return true
+}
+
+// reclaimLoop returns memory to the OS after sustained idle periods, so that the process doesn't
+// stay at its peak after a large download. Idle is without connections through the tagged
+// inbounds, which are the ones counting them.
+func (t *Tuner) reclaimLoop() {
+	v := core.FromContext(t.ctx)
+	if v == nil {
+		return
+	}
+	reporter, ok := v.GetFeature(inbound.ManagerType()).(inbound.ConnectionReporter)
+	if !ok {
+		return
+	}
+	period := &idlePeriod{after: defaultIdleReclaimAfter}
+	if t.config.IdleReclaimAfter > 0 {
+		period.after = time.Duration(t.config.IdleReclaimAfter) * time.Second
+	}
+	ticker := time.NewTicker(min(period.after, maxReclaimCheckInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case now := <-ticker.C:
+			connections := 0
+			for _, c := range reporter.GetActiveConnections() {
+				connections += c.Connections
+			}
+			if period.observe(now, connections) {
+				t.reclaim(v, period.after)
+			}
+		}
+	}
+}
+
+// reclaim drops the caches of the features, and returns the freed memory to the OS. The buffer
+// pools are emptied by the collections, which drop the buffers pooled before the previous one.
+func (t *Tuner) reclaim(v *core.Instance, idle time.Duration) {
+	for _, featureType := range []interface{}{dns.ClientType(), outbound.ManagerType(), routing.RouterType()} {
+		if r, ok := v.GetFeature(featureType).(features.Reclaimer); ok {
+			r.Reclaim()
+		}
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	runtime.GC()
+	debug.FreeOSMemory()
+	runtime.ReadMemStats(&after)
+	errors.LogInfo(t.ctx, "idle for ", idle, ", returned ", int64(before.HeapSys-before.HeapReleased)-int64(after.HeapSys-after.HeapReleased), " bytes of memory to the OS")
+}
@@ -0,0 +1,30 @@
+package tuner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdlePeriod(t *testing.T) {
+	p := &idlePeriod{after: time.Minute}
+	start := time.Now()
+	steps := []struct {
+		elapsed     time.Duration
+		connections int
+		reclaim     bool
+	}{
+		{0, 0, false},
+		{30 * time.Second, 0, false},
+		{time.Minute, 0, true},
+		{2 * time.Minute, 0, false},
+		{3 * time.Minute, 2, false},
+		{3*time.Minute + 30*time.Second, 0, false},
+		{4 * time.Minute, 0, false},
+		{4*time.Minute + 30*time.Second, 0, true},
+	}
+	for _, s := range steps {
+		if reclaim := p.observe(start.Add(s.elapsed), s.connections); reclaim != s.reclaim {
+			t.Error("reclaim at ", s.elapsed, ": ", reclaim)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Package tuner sets GOMAXPROCS and the soft memory limit of the Go runtime to the CPU and memory
+// limits of the cgroup of the process, which the runtime doesn't see by itself. In a container
+// limited to a fraction of the CPUs of the host, the default GOMAXPROCS of all the CPUs makes the
+// process throttled most of the time, and without a memory limit the collector lets the heap grow
+// until the process is killed. Once the process has been idle for a while, it also returns memory
+// to the OS.
+package tuner
+
+import (
+	"context"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/protocol"
+)
+
+const (
+	defaultMemoryLimitPercent = 90
+	defaultCheckInterval      = 60 * time.Second
+)
+
+// limits are the CPU and memory limits of the process, 0 if unlimited.
+type limits struct {
+	cpu    float64
+	memory uint64
+}
+
+// Tuner is a feature keeping the settings of the Go runtime in line with the limits of the
+// process, checking the limits for changes periodically. GOMAXPROCS and GOMEMLIMIT in the
+// environment take precedence over the limits, but not over the config.
+type Tuner struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	config *Config
+
+	// The settings at start, restored on close and when the limits are lifted.
+	procs       int
+	memoryLimit int64
+	tuneProcs   bool
+	tuneMemory  bool
+}
+
+// New creates a new Tuner. The acceleration of AES is disabled here rather than on start, before
+// the accounts of users choose their ciphers.
+func New(ctx context.Context, config *Config) (*Tuner, error) {
+	if config.MemoryLimitPercent > 100 {
+		return nil, errors.New("memory limit percent above 100: ", config.MemoryLimitPercent)
+	}
+	if config.DisableAesHardware {
+		protocol.DisableAESHardware()
+	}
+	t := &Tuner{config: config}
+	t.ctx, t.cancel = context.WithCancel(ctx)
+	return t, nil
+}
+
+// Type implements common.HasType.
+func (*Tuner) Type() interface{} {
+	return (*Tuner)(nil)
+}
+
+// Start implements common.Runnable.
+func (t *Tuner) Start() error {
+	errors.LogInfo(t.ctx, "crypto acceleration: ", protocol.DetectCryptoAcceleration())
+	if !t.config.DisableIdleReclaim {
+		go t.reclaimLoop()
+	}
+	if t.config.Disabled {
+		return nil
+	}
+	t.procs = runtime.GOMAXPROCS(0)
+	t.memoryLimit = debug.SetMemoryLimit(-1)
+	t.tuneProcs = t.config.MaxProcs > 0 || os.Getenv("GOMAXPROCS") == ""
+	t.tuneMemory = t.config.MemoryLimit > 0 || os.Getenv("GOMEMLIMIT") == ""
+	t.apply()
+
+	// Settings of the config never change.
+	if (t.tuneProcs && t.config.MaxProcs == 0) || (t.tuneMemory && t.config.MemoryLimit == 0) {
+		go t.loop()
+	}
+	return nil
+}
+
+// Close implements common.Closable.
+func (t *Tuner) Close() error {
+	t.cancel()
+	if t.config.Disabled {
+		return nil
+	}
+	if t.tuneProcs {
+		runtime.GOMAXPROCS(t.procs)
+	}
+	if t.tuneMemory {
+		debug.SetMemoryLimit(t.memoryLimit)
+	}
+	return nil
+}
+
+func (t *Tuner) loop() {
+	interval := defaultCheckInterval
+	if t.config.CheckInterval > 0 {
+		interval = time.Duration(t.config.CheckInterval) * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+			t.apply()
+		}
+	}
+}
+
+// apply sets the runtime to the config, or to the current limits of the process.
+func (t *Tuner) apply() {
+	l, err := readLimits()
+	if err != nil {
+		errors.LogDebugInner(t.ctx, err, "failed to read the limits of the process")
+	}
+	if t.tuneProcs {
+		procs := t.targetProcs(l)
+		if previous := runtime.GOMAXPROCS(procs); previous != procs {
+			errors.LogInfo(t.ctx, "GOMAXPROCS set to ", procs, " from ", previous)
+		}
+	}
+	if t.tuneMemory {
+		limit := t.targetMemoryLimit(l)
+		if previous := debug.SetMemoryLimit(limit); previous != limit {
+			errors.LogInfo(t.ctx, "memory limit set to ", limit, " bytes from ", previous)
+		}
+	}
+}
+
+func (t *Tuner) targetProcs(l limits) int {
+	if t.config.MaxProcs > 0 {
+		return int(t.config.MaxProcs)
+	}
+	if l.cpu <= 0 {
+		return t.procs
+	}
+	return min(max(int(math.Ceil(l.cpu)), 1), runtime.NumCPU())
+}
+
+func (t *Tuner) targetMemoryLimit(l limits) int64 {
+	if t.config.MemoryLimit > 0 {
+		return int64(min(t.config.MemoryLimit, math.MaxInt64))
+	}
+	if l.memory == 0 {
+		return t.memoryLimit
+	}
+	percent := uint64(t.config.MemoryLimitPercent)
+	if percent == 0 {
+		percent = defaultMemoryLimitPercent
+	}
+	return int64(min(l.memory/100*percent, math.MaxInt64))
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		return New(ctx, config.(*Config))
+	}))
+}
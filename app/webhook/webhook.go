@@ -0,0 +1,334 @@
+// Package webhook posts the traffic of users and inbounds to a webhook in batches, for billing
+// systems which can't reach the stats API.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/storage"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+const (
+	defaultInterval    = time.Minute
+	defaultMaxAttempts = 5
+	// firstBackoff is the wait before the second attempt at posting a report, doubled at each
+	// further attempt.
+	firstBackoff = time.Second
+	postTimeout  = 30 * time.Second
+	// closeTimeout is how long the last report is given to be posted on close.
+	closeTimeout = 5 * time.Second
+
+	// SignatureHeader is the header of the signature of a report, "sha256=" followed by the hex
+	// of the HMAC-SHA256 of the timestamp, a dot and the body, keyed by the secret.
+	SignatureHeader = "X-Xray-Signature"
+	// TimestampHeader is the header of the Unix time a report is posted at.
+	TimestampHeader = "X-Xray-Timestamp"
+)
+
+// Usage is the traffic of a user or an inbound during a report.
+type Usage struct {
+	Name     string `json:"name"`
+	Uplink   int64  `json:"uplink"`
+	Downlink int64  `json:"downlink"`
+}
+
+// Report is the body of the requests to the webhook. A report keeps its ID while it's posted
+// again, so that the webhook counts it once.
+type Report struct {
+	ID       string   `json:"id"`
+	Start    int64    `json:"start"`
+	End      int64    `json:"end"`
+	Users    []*Usage `json:"users,omitempty"`
+	Inbounds []*Usage `json:"inbounds,omitempty"`
+}
+
+// visitor is the stats.Manager of app/stats, which lists its counters.
+type visitor interface {
+	VisitCounters(func(string, stats.Counter) bool)
+}
+
+// Webhook posts reports of the traffic counted by the stats counters of users and inbounds since
+// the previous one.
+type Webhook struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	url         string
+	secret      []byte
+	interval    time.Duration
+	maxAttempts int
+	client      *http.Client
+	stats       visitor
+
+	access sync.Mutex
+	// last is the value of each counter in the last report.
+	last  map[string]int64
+	start time.Time
+	// pending is the report not posted yet, posted before the traffic counted since.
+	pending *Report
+}
+
+// New creates a new Webhook.
+func New(ctx context.Context, config *Config) (*Webhook, error) {
+	if !strings.HasPrefix(config.Url, "https://") && !strings.HasPrefix(config.Url, "http://") {
+		return nil, errors.New("invalid URL of webhook: ", config.Url)
+	}
+	w := &Webhook{
+		url:         config.Url,
+		secret:      []byte(config.Secret),
+		interval:    time.Duration(config.Interval),
+		maxAttempts: int(config.MaxAttempts),
+		client:      &http.Client{Timeout: postTimeout},
+		last:        make(map[string]int64),
+		start:       time.Now(),
+	}
+	if w.interval <= 0 {
+		w.interval = defaultInterval
+	}
+	if w.maxAttempts <= 0 {
+		w.maxAttempts = defaultMaxAttempts
+	}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	if err := core.RequireFeatures(ctx, func(sm stats.Manager) error {
+		v, ok := sm.(visitor)
+		if !ok {
+			return errors.New("webhook needs the stats to be enabled")
+		}
+		w.stats = v
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Type implements common.HasType.
+func (*Webhook) Type() interface{} {
+	return (*Webhook)(nil)
+}
+
+// Start implements common.Runnable. It restores the report not posted before the last restart.
+func (w *Webhook) Start() error {
+	if b, err := pendingEntry().Load(); err == nil {
+		report := new(Report)
+		if err := json.Unmarshal(b, report); err == nil {
+			w.pending = report
+		}
+	}
+	go w.loop()
+	return nil
+}
+
+// Close implements common.Closable. It posts the traffic counted since the last report once, and
+// keeps it for the next start if that fails and there is a state directory.
+func (w *Webhook) Close() error {
+	w.cancel()
+	w.access.Lock()
+	defer w.access.Unlock()
+	if w.pending == nil {
+		w.pending = w.collect(time.Now())
+	}
+	entry := pendingEntry()
+	if w.pending != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+		defer cancel()
+		if body, err := json.Marshal(w.pending); err == nil && w.postOnce(ctx, body) == nil {
+			w.pending = nil
+		}
+	}
+	if w.pending == nil {
+		return entry.Delete()
+	}
+	b, err := json.Marshal(w.pending)
+	if err != nil {
+		return err
+	}
+	return entry.Save(b)
+}
+
+func pendingEntry() storage.Entry {
+	return storage.Entry{Bucket: "webhook", Key: "pending"}
+}
+
+func (w *Webhook) loop() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.report(now)
+		}
+	}
+}
+
+// report posts the pending report, if any, then the one of the traffic counted since.
+func (w *Webhook) report(now time.Time) {
+	w.access.Lock()
+	defer w.access.Unlock()
+	if w.pending != nil && !w.deliver() {
+		return
+	}
+	if w.pending = w.collect(now); w.pending != nil {
+		w.deliver()
+	}
+}
+
+// deliver posts the pending report, and tells whether it was posted.
+func (w *Webhook) deliver() bool {
+	if err := w.post(w.pending); err != nil {
+		errors.LogWarningInner(w.ctx, err, "failed to post traffic report to webhook, retrying in ", w.interval)
+		return false
+	}
+	w.pending = nil
+	return true
+}
+
+// collect returns the report of the traffic counted since the last one, or nil if there is none.
+func (w *Webhook) collect(now time.Time) *Report {
+	users := make(map[string]*Usage)
+	inbounds := make(map[string]*Usage)
+	w.stats.VisitCounters(func(name string, c stats.Counter) bool {
+		parts := strings.Split(name, ">>>")
+		if len(parts) != 4 || parts[2] != "traffic" {
+			return true
+		}
+		var usages map[string]*Usage
+		switch parts[0] {
+		case "user":
+			usages = users
+		case "inbound":
+			usages = inbounds
+		default:
+			return true
+		}
+		value := c.Value()
+		delta := value - w.last[name]
+		if delta < 0 {
+			// The counter was reset through the stats API.
+			delta = value
+		}
+		w.last[name] = value
+		if delta == 0 {
+			return true
+		}
+		u := usages[parts[1]]
+		if u == nil {
+			u = &Usage{Name: parts[1]}
+			usages[parts[1]] = u
+		}
+		switch parts[3] {
+		case "uplink":
+			u.Uplink += delta
+		case "downlink":
+			u.Downlink += delta
+		}
+		return true
+	})
+	if len(users) == 0 && len(inbounds) == 0 {
+		return nil
+	}
+	report := &Report{
+		ID:       newID(),
+		Start:    w.start.Unix(),
+		End:      now.Unix(),
+		Users:    sortUsages(users),
+		Inbounds: sortUsages(inbounds),
+	}
+	w.start = now
+	return report
+}
+
+func sortUsages(usages map[string]*Usage) []*Usage {
+	list := make([]*Usage, 0, len(usages))
+	for _, u := range usages {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func newID() string {
+	var b [16]byte
+	common.Must2(rand.Read(b[:]))
+	return hex.EncodeToString(b[:])
+}
+
+// post posts report, up to maxAttempts times, waiting longer after each failure.
+func (w *Webhook) post(report *Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	backoff := firstBackoff
+	for attempt := 1; ; attempt++ {
+		err = w.postOnce(w.ctx, body)
+		if err == nil || attempt == w.maxAttempts {
+			return err
+		}
+		errors.LogDebugInner(w.ctx, err, "failed to post traffic report to webhook, attempt ", attempt)
+		select {
+		case <-w.ctx.Done():
+			return w.ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (w *Webhook) postOnce(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	if len(w.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(w.secret, timestamp, body))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.New("webhook responded ", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the signature of a report posted at timestamp with body, as in SignatureHeader.
+// Webhooks check it with hmac.Equal, and reject old timestamps so that reports can't be replayed.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		return New(ctx, config.(*Config))
+	}))
+}
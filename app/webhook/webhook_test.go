@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+)
+
+func TestWebhookReport(t *testing.T) {
+	var (
+		access   sync.Mutex
+		reports  []*Report
+		failures = 1
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if got, want := r.Header.Get(SignatureHeader), Sign([]byte("secret"), r.Header.Get(TimestampHeader), body); got != want {
+			t.Error("signature: got ", got, ", want ", want)
+		}
+		access.Lock()
+		defer access.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		report := new(Report)
+		common.Must(json.Unmarshal(body, report))
+		reports = append(reports, report)
+	}))
+	defer server.Close()
+
+	manager, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+	up, _ := manager.RegisterCounter("user>>>a@example.com>>>traffic>>>uplink")
+	down, _ := manager.RegisterCounter("user>>>a@example.com>>>traffic>>>downlink")
+	in, _ := manager.RegisterCounter("inbound>>>in>>>traffic>>>downlink")
+	manager.RegisterCounter("outbound>>>out>>>traffic>>>downlink")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := &Webhook{
+		ctx:         ctx,
+		url:         server.URL,
+		secret:      []byte("secret"),
+		maxAttempts: 2,
+		client:      server.Client(),
+		stats:       manager,
+		last:        make(map[string]int64),
+		start:       time.Now(),
+	}
+
+	up.Add(10)
+	down.Add(20)
+	in.Add(20)
+	w.report(time.Now())
+	if len(reports) != 1 || w.pending != nil {
+		t.Fatalf("got %d reports, pending %v, want 1 report after a retry", len(reports), w.pending)
+	}
+	r := reports[0]
+	if len(r.Users) != 1 || *r.Users[0] != (Usage{Name: "a@example.com", Uplink: 10, Downlink: 20}) {
+		t.Error("users: ", r.Users)
+	}
+	if len(r.Inbounds) != 1 || *r.Inbounds[0] != (Usage{Name: "in", Downlink: 20}) {
+		t.Error("inbounds: ", r.Inbounds)
+	}
+
+	w.report(time.Now())
+	if len(reports) != 1 {
+		t.Error("report posted without traffic")
+	}
+
+	up.Set(0)
+	up.Add(5)
+	failures = 2
+	w.report(time.Now())
+	if w.pending == nil {
+		t.Fatal("no pending report after failures")
+	}
+	id := w.pending.ID
+	down.Add(1)
+	w.report(time.Now())
+	if len(reports) != 3 {
+		t.Fatalf("got %d reports, want 3", len(reports))
+	}
+	if reports[1].ID != id || reports[1].Users[0].Uplink != 5 || reports[2].Users[0].Downlink != 1 {
+		t.Error("reports after failures: ", reports[1].Users[0], reports[2].Users[0])
+	}
+}
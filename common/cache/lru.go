@@ -11,6 +11,8 @@ type Lru interface {
 	GetKeyFromValue(value interface{}) (key interface{}, ok bool)
 	PeekKeyFromValue(value interface{}) (key interface{}, ok bool) // Peek means check but NOT bring to top
 	Put(key, value interface{})
+	// Range calls f with the entries from the least to the most recently used, until f returns false
+	Range(f func(key, value interface{}) bool)
 }
 
 type lru struct {
@@ -19,6 +21,7 @@ type lru struct {
 	keyToElement     *sync.Map
 	valueToElement   *sync.Map
 	mu               *sync.Mutex
+	onEvict          func(key, value interface{})
 }
 
 type lruElement struct {
@@ -37,6 +40,14 @@ func NewLru(cap int) Lru {
 	}
 }
 
+// NewLruWithEvict initializes a lru cache which calls onEvict with the entries it drops to stay
+// within its capacity
+func NewLruWithEvict(cap int, onEvict func(key, value interface{})) Lru {
+	l := NewLru(cap).(*lru)
+	l.onEvict = onEvict
+	return l
+}
+
 func (l *lru) Get(key interface{}) (value interface{}, ok bool) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -83,7 +94,20 @@ func (l *lru) Put(key, value interface{}) {
 			l.doubleLinkedlist.Remove(toBeRemove)
 			l.keyToElement.Delete(toBeRemove.Value.(*lruElement).key)
 			l.valueToElement.Delete(toBeRemove.Value.(*lruElement).value)
+			if l.onEvict != nil {
+				l.onEvict(toBeRemove.Value.(*lruElement).key, toBeRemove.Value.(*lruElement).value)
+			}
 		}
 	}
 	l.mu.Unlock()
 }
+
+func (l *lru) Range(f func(key, value interface{}) bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for element := l.doubleLinkedlist.Back(); element != nil; element = element.Prev() {
+		if !f(element.Value.(*lruElement).key, element.Value.(*lruElement).value) {
+			return
+		}
+	}
+}
@@ -84,3 +84,21 @@ func TestPeekKeyFromValue(t *testing.T) {
 		t.Error("should get 2", v)
 	}
 }
+
+func TestLruEvict(t *testing.T) {
+	var evicted []interface{}
+	lru := NewLruWithEvict(2, func(key, value interface{}) {
+		evicted = append(evicted, key)
+	})
+	lru.Put(1, 1)
+	lru.Put(2, 2)
+	lru.Get(1)
+	lru.Put(3, 3)
+	if len(evicted) != 1 || evicted[0] != 2 {
+		t.Error("should evict 2", evicted)
+	}
+	lru.Put(3, 4)
+	if len(evicted) != 1 {
+		t.Error("should evict nothing on replace", evicted)
+	}
+}
@@ -0,0 +1,152 @@
+// Package crash contains panics of connection goroutines, so that a bug in one protocol handler
+// doesn't take down the whole server.
+//
+// A recovered panic is logged with its stack, appended as a line of JSON to the crash log set by
+// the "xray.crash.log" environment variable, and counted in the
+// "inbound>>>[tag]>>>panic>>>count" and "outbound>>>[tag]>>>panic>>>count" stats counters.
+package crash
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	c "github.com/xtls/xray-core/common/ctx"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/platform"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+// LogLocation is the environment variable of the crash log file.
+const LogLocation = "xray.crash.log"
+
+// Report describes a recovered panic and the connection it happened in.
+type Report struct {
+	Time      time.Time `json:"time"`
+	Component string    `json:"component"`
+	Panic     string    `json:"panic"`
+	Stack     string    `json:"stack"`
+	Session   uint32    `json:"session,omitempty"`
+	Inbound   string    `json:"inbound,omitempty"`
+	Source    string    `json:"source,omitempty"`
+	User      string    `json:"user,omitempty"`
+	Outbound  string    `json:"outbound,omitempty"`
+	Target    string    `json:"target,omitempty"`
+}
+
+var (
+	total   atomic.Int64
+	logLock sync.Mutex
+)
+
+func init() {
+	task.SetPanicHandler(func(ctx context.Context, v interface{}) error {
+		r := Handle(ctx, "task", v)
+		return errors.New("recovered from panic: ", r.Panic)
+	})
+}
+
+// Count returns the number of panics recovered since start.
+func Count() int64 {
+	return total.Load()
+}
+
+// Recover recovers a panic of the calling goroutine, and handles it with Handle. It must be
+// deferred directly, e.g. defer crash.Recover(ctx, "inbound").
+func Recover(ctx context.Context, component string) {
+	if v := recover(); v != nil {
+		Handle(ctx, component, v)
+	}
+}
+
+// Handle reports a recovered panic v, which happened in component for the connection in ctx.
+func Handle(ctx context.Context, component string, v interface{}) *Report {
+	total.Add(1)
+	r := newReport(ctx, component, v)
+	errors.LogError(ctx, "recovered from panic in ", component, ": ", r.Panic, "\n", r.Stack)
+	count(ctx, r)
+	if err := writeLog(r); err != nil {
+		errors.LogWarningInner(ctx, err, "failed to write crash log")
+	}
+	return r
+}
+
+func newReport(ctx context.Context, component string, v interface{}) *Report {
+	r := &Report{
+		Time:      time.Now(),
+		Component: component,
+		Panic:     fmt.Sprint(v),
+		Stack:     string(debug.Stack()),
+		Session:   uint32(c.IDFromContext(ctx)),
+	}
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		r.Inbound = inbound.Tag
+		if inbound.Source.IsValid() {
+			r.Source = inbound.Source.String()
+		}
+		if inbound.User != nil {
+			r.User = inbound.User.Email
+		}
+	}
+	if outbounds := session.OutboundsFromContext(ctx); len(outbounds) > 0 {
+		ob := outbounds[len(outbounds)-1]
+		r.Outbound = ob.Tag
+		if ob.Target.IsValid() {
+			r.Target = ob.Target.String()
+		}
+	}
+	return r
+}
+
+func count(ctx context.Context, r *Report) {
+	instance := core.FromContext(ctx)
+	if instance == nil {
+		return
+	}
+	manager, ok := instance.GetFeature(stats.ManagerType()).(stats.Manager)
+	if !ok {
+		return
+	}
+	var names []string
+	if r.Inbound != "" {
+		names = append(names, "inbound>>>"+r.Inbound+">>>panic>>>count")
+	}
+	if r.Outbound != "" && r.Component == "outbound" {
+		names = append(names, "outbound>>>"+r.Outbound+">>>panic>>>count")
+	}
+	for _, name := range names {
+		if counter, _ := stats.GetOrRegisterCounter(manager, name); counter != nil {
+			counter.Add(1)
+		}
+	}
+}
+
+func writeLog(r *Report) error {
+	path := platform.NewEnvFlag(LogLocation).GetValue(func() string { return "" })
+	if path == "" {
+		return nil
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	logLock.Lock()
+	defer logLock.Unlock()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
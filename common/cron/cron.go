@@ -0,0 +1,188 @@
+// Package cron parses cron expressions, and finds the times they stand for.
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny tell whether the day of month and week are "*". Days match when both
+	// fields do if either is, and when either does otherwise, as in Vixie cron.
+	domAny, dowAny bool
+	// every is the interval of "@every" schedules.
+	every time.Duration
+}
+
+type field struct {
+	min, max int
+	names    []string // names of the values from min on, if any
+}
+
+var (
+	minuteField = field{min: 0, max: 59}
+	hourField   = field{min: 0, max: 23}
+	domField    = field{min: 1, max: 31}
+	monthField  = field{min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	// Sunday is both 0 and 7.
+	dowField = field{min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}
+)
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a cron expression of five fields: minute, hour, day of month, month and day of
+// week. Fields are lists of values, ranges like "1-5" and "*", each of which may have a step
+// like "*/15". Months and days of week may be given by their first three letters. The macros
+// "@yearly", "@monthly", "@weekly", "@daily", "@hourly" and "@every <duration>" are accepted
+// too.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		every, err := time.ParseDuration(strings.TrimSpace(spec[len("@every "):]))
+		if err != nil {
+			return nil, errors.New("invalid interval in ", spec).Base(err)
+		}
+		if every < time.Second {
+			return nil, errors.New("interval shorter than a second: ", spec)
+		}
+		return &Schedule{every: every}, nil
+	}
+	if expanded, found := macros[strings.ToLower(spec)]; found {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, errors.New("expected 5 fields in cron expression: ", spec)
+	}
+	s := &Schedule{
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}
+	var err error
+	for i, f := range []struct {
+		bits  *uint64
+		field field
+	}{
+		{&s.minute, minuteField},
+		{&s.hour, hourField},
+		{&s.dom, domField},
+		{&s.month, monthField},
+		{&s.dow, dowField},
+	} {
+		if *f.bits, err = f.field.parse(fields[i]); err != nil {
+			return nil, errors.New("invalid cron expression: ", spec).Base(err)
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+func (f field) parse(s string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		step := 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, errors.New("invalid step in ", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		var from, to int
+		switch i := strings.IndexByte(part, '-'); {
+		case part == "*":
+			from, to = f.min, f.max
+		case i >= 0:
+			var err error
+			if from, err = f.value(part[:i]); err != nil {
+				return 0, err
+			}
+			if to, err = f.value(part[i+1:]); err != nil {
+				return 0, err
+			}
+			if to < from {
+				return 0, errors.New("invalid range ", part)
+			}
+		default:
+			var err error
+			if from, err = f.value(part); err != nil {
+				return 0, err
+			}
+			to = from
+			if step > 1 {
+				// "a/n" means from a to the end.
+				to = f.max
+			}
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f field) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, errors.New("value out of range [", f.min, ", ", f.max, "]: ", s)
+	}
+	return v, nil
+}
+
+// Next returns the first time after t the schedule stands for, or the zero time if there is
+// none within five years, like for "0 0 30 2 *".
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+	for t.Before(end) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,57 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xtls/xray-core/common/cron"
+)
+
+func TestNext(t *testing.T) {
+	from := time.Date(2024, time.January, 31, 10, 30, 15, 0, time.UTC) // a Wednesday
+	cases := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 31, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.January, 31, 10, 45, 0, 0, time.UTC)},
+		{"0 4 * * *", time.Date(2024, time.February, 1, 4, 0, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2024, time.February, 1, 10, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * sun", time.Date(2024, time.February, 4, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, time.February, 4, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * mon-fri", time.Date(2024, time.January, 31, 13, 0, 0, 0, time.UTC)},
+		// Either day field matches when both are restricted.
+		{"0 0 15 * fri", time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90m", from.Add(90 * time.Minute)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, c := range cases {
+		s, err := Parse(c.spec)
+		if err != nil {
+			t.Fatal(c.spec, ": ", err)
+		}
+		if next := s.Next(from); !next.Equal(c.next) {
+			t.Error(c.spec, ": expected ", c.next, ", got ", next)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* * 0 * *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"@every 1ms",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Error("expected error for ", spec)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package errors
+
+// Codes of Diagnostics.
+const (
+	CodeConfigRead     = "config_read"
+	CodeConfigSyntax   = "config_syntax"
+	CodeConfigType     = "config_type"
+	CodeConfigInvalid  = "config_invalid"
+	CodeServerInit     = "server_init"
+	CodeServerStart    = "server_start"
+	CodeGeodataMissing = "geodata_missing"
+	CodeTLSCertInvalid = "tls_cert_invalid"
+	CodeBindFailed     = "bind_failed"
+	CodeAPIPortBusy    = "api_port_busy"
+)
+
+// exitCodes are the exit codes of the process failing with the codes of Diagnostics, which stay
+// the same across versions for supervisors to base their restart and alert policies on. Errors
+// of configs exit with 23, which systemd units are set not to restart on, and other failures to
+// start with 255, as they always did; the rest have codes of their own.
+var exitCodes = map[string]int{
+	CodeConfigRead:     23,
+	CodeConfigSyntax:   23,
+	CodeConfigType:     23,
+	CodeConfigInvalid:  23,
+	CodeServerInit:     23,
+	CodeServerStart:    255,
+	CodeGeodataMissing: 24,
+	CodeTLSCertInvalid: 25,
+	CodeBindFailed:     26,
+	CodeAPIPortBusy:    27,
+}
+
+var suggestions = map[string]string{
+	CodeConfigRead:     "check that the config file exists and is readable",
+	CodeConfigSyntax:   "fix the JSON syntax at the given line and char, such as a missing comma or quote",
+	CodeConfigType:     "check the type of the value at the given path, such as a number given as a string",
+	CodeConfigInvalid:  "check the settings named in the message against the documentation",
+	CodeServerInit:     "check that the referenced files, such as certificates and geo data, exist",
+	CodeServerStart:    "check that the listening ports are free and allowed for this user",
+	CodeGeodataMissing: "download geoip.dat and geosite.dat next to the executable, or set xray.location.asset",
+	CodeTLSCertInvalid: "check that the certificate and key files exist and are readable",
+	CodeBindFailed:     "check that the ports of inbounds are free and allowed for this user",
+	CodeAPIPortBusy:    "check that the port of the API isn't taken by another process, such as another Xray",
+}
+
+// Diagnostic describes a startup failure in a machine-readable way, for programs wrapping
+// Xray. It's carried in the error chain, without changing the message of the error.
+type Diagnostic struct {
+	Code       string `json:"code"`
+	File       string `json:"file,omitempty"`
+	Path       string `json:"path,omitempty"`
+	Line       int    `json:"line,omitempty"`
+	Char       int    `json:"char,omitempty"`
+	Message    string `json:"message,omitempty"`
+	Suggestion string `json:"suggestion,omitempty"`
+	ExitCode   int    `json:"exitCode,omitempty"`
+
+	inner error
+}
+
+// Diagnose returns a Diagnostic of the given code for inner.
+func Diagnose(code string, inner error) *Diagnostic {
+	return &Diagnostic{
+		Code:       code,
+		Suggestion: suggestions[code],
+		ExitCode:   ExitCode(code),
+		inner:      inner,
+	}
+}
+
+// ExitCode returns the exit code of the process failing with a Diagnostic of code, 1 for unknown
+// codes.
+func ExitCode(code string) int {
+	if exit, found := exitCodes[code]; found {
+		return exit
+	}
+	return 1
+}
+
+// Error implements error.
+func (d *Diagnostic) Error() string {
+	if d.inner == nil {
+		return d.Code
+	}
+	return d.inner.Error()
+}
+
+// Unwrap implements hasInnerError.
+func (d *Diagnostic) Unwrap() error {
+	return d.inner
+}
+
+// DiagnosticOf returns the last Diagnostic in the chain of err, the most specific one, or nil.
+func DiagnosticOf(err error) *Diagnostic {
+	var diag *Diagnostic
+	for err != nil {
+		if d, ok := err.(*Diagnostic); ok {
+			diag = d
+		}
+		inner, ok := err.(hasInnerError)
+		if !ok {
+			break
+		}
+		err = inner.Unwrap()
+	}
+	return diag
+}
@@ -24,11 +24,12 @@ type hasSeverity interface {
 
 // Error is an error object with underlying error.
 type Error struct {
-	prefix   []interface{}
-	message  []interface{}
-	caller   string
-	inner    error
-	severity log.Severity
+	prefix    []interface{}
+	sessionID uint32
+	message   []interface{}
+	caller    string
+	inner     error
+	severity  log.Severity
 }
 
 // Error implements error.Error().
@@ -56,6 +57,11 @@ func (err *Error) Error() string {
 	return builder.String()
 }
 
+// SessionID returns the ID of the session the error was logged in, or 0 if none.
+func (err *Error) SessionID() uint32 {
+	return err.sessionID
+}
+
 // Unwrap implements hasInnerError.Unwrap()
 func (err *Error) Unwrap() error {
 	if err.inner == nil {
@@ -109,6 +115,12 @@ func (err *Error) AtError() *Error {
 	return err.atSeverity(log.Severity_Error)
 }
 
+// Caller returns the path of the package the error was created or logged in, relative to
+// the module, such as "app/dns".
+func (err *Error) Caller() string {
+	return err.caller
+}
+
 // String returns the string representation of this error.
 func (err *Error) String() string {
 	return err.Error()
@@ -190,6 +202,7 @@ func doLog(ctx context.Context, inner error, severity log.Severity, msg ...inter
 		id := uint32(c.IDFromContext(ctx))
 		if id > 0 {
 			err.prefix = append(err.prefix, id)
+			err.sessionID = id
 		}
 	}
 	log.Record(&log.GeneralMessage{
@@ -53,3 +53,23 @@ func TestErrorMessage(t *testing.T) {
 		}
 	}
 }
+
+func TestDiagnosticOf(t *testing.T) {
+	if DiagnosticOf(New("no diagnostic").Base(io.EOF)) != nil {
+		t.Error("expected no Diagnostic")
+	}
+
+	err := New("failed to create server").Base(Diagnose(CodeServerInit,
+		New("failed to load file").Base(Diagnose(CodeGeodataMissing, io.EOF))))
+	diag := DiagnosticOf(err)
+	if diag == nil || diag.Code != CodeGeodataMissing || diag.ExitCode != 24 {
+		t.Error("expected the innermost Diagnostic, got ", diag)
+	}
+	if !strings.HasSuffix(err.Error(), "failed to create server > common/errors_test: failed to load file > EOF") {
+		t.Error("unexpected message ", err.Error())
+	}
+
+	if ExitCode(CodeConfigSyntax) != 23 || ExitCode(CodeServerStart) != 255 || ExitCode("unknown") != 1 {
+		t.Error("unexpected exit codes")
+	}
+}
@@ -0,0 +1,269 @@
+// Package geodata downloads the geoip.dat and geosite.dat files routing and DNS rules refer to,
+// and keeps them up to date.
+package geodata
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/platform"
+)
+
+const (
+	// DefaultMirror serves the files of the Loyalsoldier/v2ray-rules-dat releases, with a
+	// .sha256sum file next to each.
+	DefaultMirror = "https://github.com/Loyalsoldier/v2ray-rules-dat/releases/latest/download/"
+	// DefaultTimeout is the time each file is given to download.
+	DefaultTimeout = 5 * time.Minute
+	// maxFileSize caps the size of downloaded files.
+	maxFileSize = 256 * 1024 * 1024
+)
+
+// DefaultFiles are the files updated if none are given.
+var DefaultFiles = []string{"geoip.dat", "geosite.dat"}
+
+// Updater replaces the files in the asset directory with newer ones from a mirror. Each file
+// must come with a "<file>.sha256sum" file, and with a "<file>.sig" file if PublicKey is set.
+type Updater struct {
+	// Mirror is the URL the file names are appended to. DefaultMirror if empty.
+	Mirror string
+	// Files are the names of the files. DefaultFiles if empty.
+	Files []string
+	// PublicKey, if set, is the ed25519 key the files must be signed with.
+	PublicKey ed25519.PublicKey
+	// Client downloads the files. http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// ParsePublicKey parses an ed25519 public key encoded in base64 or hex.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	s = strings.TrimSpace(s)
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		key, err = hex.DecodeString(s)
+	}
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid ed25519 public key: ", s)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+func (u *Updater) files() []string {
+	if len(u.Files) == 0 {
+		return DefaultFiles
+	}
+	return u.Files
+}
+
+func (u *Updater) url(name string) string {
+	mirror := u.Mirror
+	if mirror == "" {
+		mirror = DefaultMirror
+	}
+	if !strings.HasSuffix(mirror, "/") {
+		mirror += "/"
+	}
+	return mirror + name
+}
+
+// Path returns where the file of name is read from.
+func Path(name string) string {
+	return platform.GetAssetLocation(name)
+}
+
+// FileInfo describes a file in the asset directory. Geo data files carry no version, so they're
+// told apart by checksum and modification time.
+type FileInfo struct {
+	Name     string
+	Size     int64
+	Modified time.Time
+	SHA256   string
+}
+
+// Stat returns the FileInfo of the file of name.
+func Stat(name string) (*FileInfo, error) {
+	path := Path(name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return &FileInfo{
+		Name:     name,
+		Size:     info.Size(),
+		Modified: info.ModTime(),
+		SHA256:   hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// Missing returns the files that don't exist in the asset directory.
+func (u *Updater) Missing() []string {
+	var missing []string
+	for _, name := range u.files() {
+		if _, err := os.Stat(Path(name)); os.IsNotExist(err) {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// Update downloads the files that differ from the copies in the asset directory and swaps them
+// in, and returns the names of those replaced. Files failing to download or verify are left
+// as they are, and the first such error is returned.
+func (u *Updater) Update(ctx context.Context) ([]string, error) {
+	var updated []string
+	var firstErr error
+	for _, name := range u.files() {
+		changed, err := u.update(ctx, name)
+		if err != nil {
+			err = errors.New("failed to update ", name).Base(err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if changed {
+			updated = append(updated, name)
+		}
+	}
+	return updated, firstErr
+}
+
+func (u *Updater) update(ctx context.Context, name string) (bool, error) {
+	sumFile, err := u.fetch(ctx, name+".sha256sum", 1024)
+	if err != nil {
+		return false, err
+	}
+	fields := strings.Fields(string(sumFile))
+	if len(fields) == 0 {
+		return false, errors.New("empty checksum")
+	}
+	sum, err := hex.DecodeString(fields[0])
+	if err != nil || len(sum) != sha256.Size {
+		return false, errors.New("invalid checksum: ", fields[0])
+	}
+
+	path := Path(name)
+	if local, err := os.ReadFile(path); err == nil {
+		localSum := sha256.Sum256(local)
+		if bytes.Equal(localSum[:], sum) {
+			return false, nil
+		}
+	}
+
+	data, err := u.fetch(ctx, name, maxFileSize)
+	if err != nil {
+		return false, err
+	}
+	if dataSum := sha256.Sum256(data); !bytes.Equal(dataSum[:], sum) {
+		return false, errors.New("checksum mismatch, got ", hex.EncodeToString(dataSum[:]))
+	}
+	if u.PublicKey != nil {
+		sig, err := u.fetch(ctx, name+".sig", 1024)
+		if err != nil {
+			return false, errors.New("failed to get signature").Base(err)
+		}
+		if len(sig) != ed25519.SignatureSize {
+			if sig, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig))); err != nil {
+				return false, errors.New("invalid signature").Base(err)
+			}
+		}
+		if !ed25519.Verify(u.PublicKey, data, sig) {
+			return false, errors.New("invalid signature")
+		}
+	}
+	return true, replace(path, data)
+}
+
+func (u *Updater) fetch(ctx context.Context, name string, limit int64) ([]byte, error) {
+	return download(ctx, u.Client, u.url(name), limit)
+}
+
+// Download fetches the geo data file at url.
+func Download(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	return download(ctx, client, url, maxFileSize)
+}
+
+func download(ctx context.Context, client *http.Client, url string, limit int64) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected status ", resp.Status, " for ", url)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errors.New(url, " is larger than ", limit, " bytes")
+	}
+	return data, nil
+}
+
+// Install swaps data in as the file of name in the asset directory, and tells whether it differs
+// from the file there. name must be the name of a .dat file, not a path.
+func Install(name string, data []byte) (bool, error) {
+	if name != filepath.Base(name) || !strings.HasSuffix(name, ".dat") {
+		return false, errors.New("invalid geo data file name: ", name)
+	}
+	path := Path(name)
+	if local, err := os.ReadFile(path); err == nil && bytes.Equal(local, data) {
+		return false, nil
+	}
+	return true, replace(path, data)
+}
+
+func replace(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,109 @@
+package geodata_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xtls/xray-core/common/geodata"
+)
+
+func TestUpdater(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("xray.location.asset", dir)
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{"geoip.dat": []byte("geoip v1")}
+	serve := func(name string, data []byte) {
+		files[name] = data
+		sum := sha256.Sum256(data)
+		files[name+".sha256sum"] = []byte(hex.EncodeToString(sum[:]) + "  " + name + "\n")
+		files[name+".sig"] = ed25519.Sign(priv, data)
+	}
+	serve("geoip.dat", []byte("geoip v1"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path[1:]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	u := &geodata.Updater{
+		Mirror:    server.URL,
+		Files:     []string{"geoip.dat"},
+		PublicKey: pub,
+	}
+	if missing := u.Missing(); len(missing) != 1 {
+		t.Fatal("expected geoip.dat missing, got ", missing)
+	}
+	check := func(wantUpdated int, want string) {
+		t.Helper()
+		updated, err := u.Update(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(updated) != wantUpdated {
+			t.Error("expected ", wantUpdated, " updated, got ", updated)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "geoip.dat"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Error("expected ", want, ", got ", string(data))
+		}
+	}
+	check(1, "geoip v1")
+	check(0, "geoip v1")
+	serve("geoip.dat", []byte("geoip v2"))
+	check(1, "geoip v2")
+	info, err := geodata.Stat("geoip.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := sha256.Sum256([]byte("geoip v2")); info.Size != 8 || info.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Error("unexpected file info: ", info)
+	}
+
+	// Neither a file not matching its checksum nor one with a bad signature is swapped in.
+	serve("geoip.dat", []byte("geoip v3"))
+	files["geoip.dat"] = []byte("geoip v4")
+	if _, err := u.Update(context.Background()); err == nil {
+		t.Error("expected checksum mismatch")
+	}
+	serve("geoip.dat", []byte("geoip v3"))
+	files["geoip.dat.sig"] = ed25519.Sign(priv, []byte("geoip v2"))
+	if _, err := u.Update(context.Background()); err == nil {
+		t.Error("expected invalid signature")
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "geoip.dat"))
+	if string(data) != "geoip v2" {
+		t.Error("expected geoip v2 kept, got ", string(data))
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Error("expected no temporary files left, got ", len(entries), " files")
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(nil)
+	key, err := geodata.ParsePublicKey(hex.EncodeToString(pub))
+	if err != nil || !key.Equal(pub) {
+		t.Error("failed to parse hex key: ", err)
+	}
+	if _, err := geodata.ParsePublicKey("abc"); err == nil {
+		t.Error("expected invalid key")
+	}
+}
@@ -0,0 +1,194 @@
+// Package listen opens the listeners of local services, such as the API and metrics servers,
+// on addresses which may name a host or a network interface instead of an IP.
+package listen
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/features/dns"
+)
+
+// interfacePrefix marks addresses naming a network interface, like "iface:eth0:10085".
+const interfacePrefix = "iface:"
+
+// TCP listens on address, which is one of:
+//   - "ip:port", or ":port" for all addresses of both IP versions;
+//   - "host:port", listening on every IPv4 and IPv6 address host resolves to, through
+//     client if it's not nil and the system resolver otherwise;
+//   - "iface:name:port", listening on every address of the network interface.
+//
+// Listening on several addresses returns a single Listener accepting from all of them.
+func TCP(ctx context.Context, address string, client dns.Client) (net.Listener, error) {
+	ips, port, err := resolve(ctx, address, client)
+	if err != nil {
+		return nil, err
+	}
+	if ips == nil {
+		return net.Listen("tcp", address)
+	}
+
+	var listeners []net.Listener
+	for _, ip := range ips {
+		l, err := net.Listen("tcp", net.JoinHostPort(ip.String(), port))
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, errors.New("failed to listen on ", ip, " for ", address).Base(err)
+		}
+		listeners = append(listeners, l)
+	}
+	if len(listeners) == 1 {
+		return listeners[0], nil
+	}
+	return newMultiListener(listeners), nil
+}
+
+// resolve returns the IPs address stands for, or nil if it's a literal address.
+func resolve(ctx context.Context, address string, client dns.Client) ([]net.IP, string, error) {
+	if strings.HasPrefix(address, interfacePrefix) {
+		rest := address[len(interfacePrefix):]
+		i := strings.LastIndexByte(rest, ':')
+		if i < 0 {
+			return nil, "", errors.New("missing port in ", address)
+		}
+		ips, err := interfaceIPs(rest[:i])
+		return ips, rest[i+1:], err
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return nil, "", errors.New("invalid port in ", address)
+	}
+	if host == "" || net.ParseIP(host) != nil {
+		return nil, port, nil
+	}
+
+	var ips []net.IP
+	if client != nil {
+		ips, err = client.LookupIP(host, dns.IPOption{IPv4Enable: true, IPv6Enable: true})
+	}
+	if len(ips) == 0 {
+		var addrs []net.IPAddr
+		addrs, err = net.DefaultResolver.LookupIPAddr(ctx, host)
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, "", errors.New("failed to resolve ", host).Base(err)
+	}
+	return dedup(ips), port, nil
+}
+
+func interfaceIPs(name string) ([]net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, errors.New("unknown interface ", name).Base(err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, errors.New("failed to get addresses of interface ", name).Base(err)
+	}
+	var ips []net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		// Link-local IPv6 addresses need a zone, which isn't worth it for local services.
+		if !ok || (ipNet.IP.To4() == nil && ipNet.IP.IsLinkLocalUnicast()) {
+			continue
+		}
+		ips = append(ips, ipNet.IP)
+	}
+	if len(ips) == 0 {
+		return nil, errors.New("no address on interface ", name)
+	}
+	return ips, nil
+}
+
+func dedup(ips []net.IP) []net.IP {
+	seen := make(map[string]bool, len(ips))
+	var unique []net.IP
+	for _, ip := range ips {
+		if !seen[ip.String()] {
+			seen[ip.String()] = true
+			unique = append(unique, ip)
+		}
+	}
+	return unique
+}
+
+type accepted struct {
+	conn net.Conn
+	err  error
+}
+
+// multiListener accepts connections from several listeners.
+type multiListener struct {
+	listeners []net.Listener
+	conns     chan accepted
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newMultiListener(listeners []net.Listener) *multiListener {
+	m := &multiListener{
+		listeners: listeners,
+		conns:     make(chan accepted),
+		closed:    make(chan struct{}),
+	}
+	for _, l := range listeners {
+		go m.accept(l)
+	}
+	return m
+}
+
+func (m *multiListener) accept(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		select {
+		case m.conns <- accepted{conn: conn, err: err}:
+		case <-m.closed:
+			if conn != nil {
+				conn.Close()
+			}
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Accept implements net.Listener.
+func (m *multiListener) Accept() (net.Conn, error) {
+	select {
+	case a := <-m.conns:
+		return a.conn, a.err
+	case <-m.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close implements net.Listener.
+func (m *multiListener) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.closed)
+		for _, l := range m.listeners {
+			l.Close()
+		}
+	})
+	return nil
+}
+
+// Addr implements net.Listener. It returns the address of the first listener.
+func (m *multiListener) Addr() net.Addr {
+	return m.listeners[0].Addr()
+}
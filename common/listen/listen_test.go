@@ -0,0 +1,55 @@
+package listen_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/listen"
+)
+
+func TestTCPInterface(t *testing.T) {
+	ifaces, err := net.Interfaces()
+	common.Must(err)
+	name := ""
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			name = iface.Name
+		}
+	}
+	if name == "" {
+		t.Skip("no loopback interface")
+	}
+
+	l, err := listen.TCP(context.Background(), "iface:"+name+":0", nil)
+	common.Must(err)
+	defer l.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	common.Must(err)
+	defer conn.Close()
+
+	accepted, err := l.Accept()
+	common.Must(err)
+	accepted.Close()
+}
+
+func TestTCPHost(t *testing.T) {
+	l, err := listen.TCP(context.Background(), "localhost:0", nil)
+	common.Must(err)
+	defer l.Close()
+
+	if ip := l.Addr().(*net.TCPAddr).IP; !ip.IsLoopback() {
+		t.Error("listening on ", ip)
+	}
+}
+
+func TestTCPInvalid(t *testing.T) {
+	for _, address := range []string{"iface:lo", "localhost:http", "127.0.0.1"} {
+		if l, err := listen.TCP(context.Background(), address, nil); err == nil {
+			l.Close()
+			t.Error("listening on invalid address ", address)
+		}
+	}
+}
@@ -2,8 +2,12 @@ package log
 
 import (
 	"context"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	c "github.com/xtls/xray-core/common/ctx"
 	"github.com/xtls/xray-core/common/serial"
 )
 
@@ -18,6 +22,8 @@ type AccessStatus string
 const (
 	AccessAccepted = AccessStatus("accepted")
 	AccessRejected = AccessStatus("rejected")
+	// AccessClosed marks the records of connections once they're closed.
+	AccessClosed = AccessStatus("closed")
 )
 
 type AccessMessage struct {
@@ -27,10 +33,22 @@ type AccessMessage struct {
 	Reason interface{}
 	Email  string
 	Detour string
+	// Inbound and Outbound are the tags of the handlers the connection goes through.
+	Inbound  string
+	Outbound string
+	// Up and Down are the bytes sent and received through the connection, and Duration how long
+	// it lasted, in records of closed connections.
+	Up       int64
+	Down     int64
+	Duration time.Duration
+	// SessionID is the ID of the session of the connection, the one prefixing the other log
+	// lines about it, or 0 if unknown.
+	SessionID uint32
 }
 
 func (m *AccessMessage) String() string {
 	builder := strings.Builder{}
+	writeSessionID(&builder, m.SessionID)
 	builder.WriteString("from")
 	builder.WriteByte(' ')
 	builder.WriteString(serial.ToString(m.From))
@@ -55,10 +73,34 @@ func (m *AccessMessage) String() string {
 		builder.WriteString(m.Email)
 	}
 
+	if m.Status == AccessClosed {
+		builder.WriteString(" up ")
+		builder.WriteString(strconv.FormatInt(m.Up, 10))
+		builder.WriteString(" down ")
+		builder.WriteString(strconv.FormatInt(m.Down, 10))
+		builder.WriteString(" after ")
+		builder.WriteString(m.Duration.Round(time.Millisecond).String())
+	}
+
 	return builder.String()
 }
 
+// writeSessionID writes the session ID of a record the way errors are prefixed with it, for the
+// lines about a connection to be found by it.
+func writeSessionID(builder *strings.Builder, id uint32) {
+	if id != 0 {
+		builder.WriteByte('[')
+		builder.WriteString(strconv.FormatUint(uint64(id), 10))
+		builder.WriteString("] ")
+	}
+}
+
+// ContextWithAccessMessage returns ctx with accessMessage, recorded once the connection is routed.
+// accessMessage takes the ID of the session of ctx if it has none.
 func ContextWithAccessMessage(ctx context.Context, accessMessage *AccessMessage) context.Context {
+	if accessMessage.SessionID == 0 {
+		accessMessage.SessionID = uint32(c.IDFromContext(ctx))
+	}
 	return context.WithValue(ctx, accessMessageKey, accessMessage)
 }
 
@@ -68,3 +110,25 @@ func AccessMessageFromContext(ctx context.Context) *AccessMessage {
 	}
 	return nil
 }
+
+// RecordAccess records msg about the connection of ctx, with the ID of its session.
+func RecordAccess(ctx context.Context, msg *AccessMessage) {
+	if msg.SessionID == 0 {
+		msg.SessionID = uint32(c.IDFromContext(ctx))
+	}
+	Record(msg)
+}
+
+var closedAccesses atomic.Bool
+
+// RecordClosedAccesses sets whether accesses are recorded once more when their connections
+// close, with the traffic and duration of the connections.
+func RecordClosedAccesses(enabled bool) {
+	closedAccesses.Store(enabled)
+}
+
+// ClosedAccessesRecorded returns whether accesses are recorded once more when their connections
+// close.
+func ClosedAccessesRecorded() bool {
+	return closedAccesses.Load()
+}
@@ -13,12 +13,15 @@ type DNSLog struct {
 	Status  dnsStatus
 	Elapsed time.Duration
 	Error   error
+	// SessionID is the ID of the session the query was made for, or 0 if none.
+	SessionID uint32
 }
 
 func (l *DNSLog) String() string {
 	builder := &strings.Builder{}
 
-	// Server got answer: domain -> [ip1, ip2] 23ms
+	// [id] Server got answer: domain -> [ip1, ip2] 23ms
+	writeSessionID(builder, l.SessionID)
 	builder.WriteString(l.Server)
 	builder.WriteString(" ")
 	builder.WriteString(string(l.Status))
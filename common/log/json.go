@@ -0,0 +1,84 @@
+package log
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/common/serial"
+)
+
+// FormatJSON returns msg, recorded at t, as a line of JSON without line separator. Strings which
+// may hold addresses are passed through mask if it's not nil.
+func FormatJSON(msg Message, t time.Time, mask func(string) string) string {
+	if mask == nil {
+		mask = func(s string) string { return s }
+	}
+	record := map[string]interface{}{
+		"time": t.Format(time.RFC3339Nano),
+	}
+	switch msg := msg.(type) {
+	case *GeneralMessage:
+		record["type"] = "error"
+		record["level"] = strings.ToLower(msg.Severity.String())
+		record["message"] = mask(serial.ToString(msg.Content))
+		if c, ok := msg.Content.(hasSessionID); ok {
+			setSessionID(record, c.SessionID())
+		}
+	case *AccessMessage:
+		record["type"] = "access"
+		record["from"] = mask(serial.ToString(msg.From))
+		record["to"] = mask(serial.ToString(msg.To))
+		record["status"] = string(msg.Status)
+		setString(record, "inbound", msg.Inbound)
+		setString(record, "outbound", msg.Outbound)
+		setString(record, "reason", mask(serial.ToString(msg.Reason)))
+		setString(record, "email", msg.Email)
+		setSessionID(record, msg.SessionID)
+		if msg.Status == AccessClosed {
+			record["up"] = msg.Up
+			record["down"] = msg.Down
+			record["duration"] = msg.Duration.Seconds()
+		}
+	case *DNSLog:
+		record["type"] = "dns"
+		record["server"] = msg.Server
+		record["domain"] = msg.Domain
+		record["cached"] = msg.Status == DNSCacheHit
+		ips := make([]string, 0, len(msg.Result))
+		for _, ip := range msg.Result {
+			ips = append(ips, mask(ip.String()))
+		}
+		record["ips"] = ips
+		record["elapsed"] = msg.Elapsed.Seconds()
+		setSessionID(record, msg.SessionID)
+		if msg.Error != nil {
+			record["error"] = msg.Error.Error()
+		}
+	default:
+		record["message"] = mask(msg.String())
+	}
+	b, err := json.Marshal(record)
+	if err != nil {
+		return `{"message":"failed to format log message"}`
+	}
+	return string(b)
+}
+
+// hasSessionID is implemented by the contents of messages about a session, as errors logged in its
+// context.
+type hasSessionID interface {
+	SessionID() uint32
+}
+
+func setSessionID(record map[string]interface{}, id uint32) {
+	if id != 0 {
+		record["session"] = id
+	}
+}
+
+func setString(record map[string]interface{}, key, value string) {
+	if value != "" {
+		record[key] = value
+	}
+}
@@ -1,9 +1,13 @@
 package log_test
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	c "github.com/xtls/xray-core/common/ctx"
 	"github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/common/net"
 )
@@ -30,3 +34,56 @@ func TestLogRecord(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func TestFormatJSON(t *testing.T) {
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	msg := &log.AccessMessage{
+		From:     net.ParseAddress("1.2.3.4"),
+		To:       "tcp:example.com:443",
+		Status:   log.AccessClosed,
+		Inbound:  "socks",
+		Outbound: "direct",
+		Up:       10,
+		Down:     20,
+		Duration: 1500 * time.Millisecond,
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(log.FormatJSON(msg, tm, nil)), &record); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"time":     "2024-01-02T03:04:05Z",
+		"type":     "access",
+		"from":     "1.2.3.4",
+		"to":       "tcp:example.com:443",
+		"status":   "closed",
+		"inbound":  "socks",
+		"outbound": "direct",
+		"up":       float64(10),
+		"down":     float64(20),
+		"duration": 1.5,
+	}
+	if diff := cmp.Diff(expected, record); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAccessMessageSessionID(t *testing.T) {
+	msg := &log.AccessMessage{
+		From:   net.ParseAddress("1.2.3.4"),
+		To:     "tcp:example.com:443",
+		Status: log.AccessAccepted,
+	}
+	log.ContextWithAccessMessage(c.ContextWithID(context.Background(), 42), msg)
+	if diff := cmp.Diff("[42] from 1.2.3.4 accepted tcp:example.com:443", msg.String()); diff != "" {
+		t.Error(diff)
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(log.FormatJSON(msg, time.Now(), nil)), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["session"] != float64(42) {
+		t.Error("unexpected session of ", record)
+	}
+}
@@ -2,6 +2,8 @@ package log_test
 
 import (
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -37,3 +39,31 @@ func TestFileLogger(t *testing.T) {
 		t.Fatal("Expect log text contains 'Test Log', but actually: ", string(b))
 	}
 }
+
+func TestRotatingFileLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+
+	creator, err := CreateRotatingFileLogWriter(path, FileOptions{MaxSize: 32, MaxBackups: 2, Plain: true})
+	common.Must(err)
+
+	for i := 0; i < 4; i++ {
+		w := creator()
+		common.Must(w.Write(strings.Repeat(strconv.Itoa(i), 20)))
+		common.Must(w.Close())
+	}
+
+	for file, expected := range map[string]string{
+		path:        "33333333333333333333\n",
+		path + ".1": "22222222222222222222\n",
+		path + ".2": "11111111111111111111\n",
+	} {
+		b, err := os.ReadFile(file)
+		common.Must(err)
+		if string(b) != expected {
+			t.Error("unexpected content of ", file, ": ", string(b))
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("expected oldest backup removed")
+	}
+}
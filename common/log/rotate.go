@@ -0,0 +1,169 @@
+package log
+
+import (
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FileOptions are how a log file is written and rotated. Rotated files are renamed to the path
+// of the log file with ".1" appended, the previous ones shifting to ".2" and so on.
+type FileOptions struct {
+	// MaxSize is the size in bytes the file is rotated at, or 0 to not rotate by size.
+	MaxSize int64
+	// MaxAge is how long the file is written to before it's rotated, or 0 to not rotate by time.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files kept, or 0 to keep all of them.
+	MaxBackups int
+	// Plain writes lines as they are, for formats carrying their own timestamps.
+	Plain bool
+}
+
+// rotatingFile is a log file shared by the Writers a WriterCreator creates one after the other.
+type rotatingFile struct {
+	sync.Mutex
+	path    string
+	options FileOptions
+	file    *os.File
+	size    int64
+	opened  time.Time
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	// The file may have been written since long before, so its age counts from the first write.
+	f.opened = info.ModTime()
+	if f.size == 0 {
+		f.opened = time.Now()
+	}
+	return nil
+}
+
+func (f *rotatingFile) due(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.options.MaxSize > 0 && f.size+int64(n) > f.options.MaxSize {
+		return true
+	}
+	return f.options.MaxAge > 0 && time.Since(f.opened) >= f.options.MaxAge
+}
+
+// backup returns the path of the i-th rotated file.
+func (f *rotatingFile) backup(i int) string {
+	return f.path + "." + strconv.Itoa(i)
+}
+
+// rotate closes the file, shifts the rotated files and renames the file to the first of them.
+func (f *rotatingFile) rotate() error {
+	f.file.Close()
+	f.file = nil
+
+	last := f.options.MaxBackups
+	if last == 0 {
+		for last = 1; ; last++ {
+			if _, err := os.Stat(f.backup(last)); os.IsNotExist(err) {
+				break
+			}
+		}
+	}
+	os.Remove(f.backup(last))
+	for i := last - 1; i >= 1; i-- {
+		os.Rename(f.backup(i), f.backup(i+1))
+	}
+	if err := os.Rename(f.path, f.backup(1)); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.due(len(p)) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) close() error {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+type rotatingLogWriter struct {
+	file   *rotatingFile
+	logger *log.Logger
+}
+
+func (w *rotatingLogWriter) Write(s string) error {
+	w.logger.Print(s)
+	return nil
+}
+
+func (w *rotatingLogWriter) Close() error {
+	return w.file.close()
+}
+
+func newLogger(w io.Writer, plain bool) *log.Logger {
+	if plain {
+		return log.New(w, "", 0)
+	}
+	return log.New(w, "", log.Ldate|log.Ltime|log.Lmicroseconds)
+}
+
+// CreateRotatingFileLogWriter returns a LogWriterCreator that creates LogWriter for the given
+// file, rotating it as options tell.
+func CreateRotatingFileLogWriter(path string, options FileOptions) (WriterCreator, error) {
+	f := &rotatingFile{path: path, options: options}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	f.close()
+	return func() Writer {
+		return &rotatingLogWriter{
+			file:   f,
+			logger: newLogger(f, options.Plain),
+		}
+	}, nil
+}
+
+// CreatePlainStdoutLogWriter returns a LogWriterCreator that creates LogWriter for stdout, writing
+// lines as they are, for formats carrying their own timestamps.
+func CreatePlainStdoutLogWriter() WriterCreator {
+	return func() Writer {
+		return &consoleLogWriter{
+			logger: newLogger(os.Stdout, true),
+		}
+	}
+}
@@ -124,6 +124,19 @@ func (p *IncrementalWorkerPicker) PickAvailable() (*ClientWorker, error) {
 	return worker, err
 }
 
+// CloseAll closes the workers, so that new sessions go over new connections. Sessions of the
+// workers are migrated if the strategy does so, or closed.
+func (p *IncrementalWorkerPicker) CloseAll() {
+	p.access.Lock()
+	workers := p.workers
+	p.workers = nil
+	p.access.Unlock()
+
+	for _, w := range workers {
+		common.Must(w.done.Close())
+	}
+}
+
 type ClientWorkerFactory interface {
 	Create() (*ClientWorker, error)
 }
@@ -167,6 +180,9 @@ func (f *DialingWorkerFactory) Create() (*ClientWorker, error) {
 type ClientStrategy struct {
 	MaxConcurrency uint32
 	MaxConnection  uint32
+	// Migrate, if set, takes over the streams of a failed connection which haven't got any
+	// response yet.
+	Migrate MigrateFunc
 }
 
 type ClientWorker struct {
@@ -216,6 +232,7 @@ func (m *ClientWorker) monitor() {
 	for {
 		select {
 		case <-m.done.Wait():
+			m.migrateSessions()
 			m.sessionManager.Close()
 			common.Close(m.link.Writer)
 			common.Interrupt(m.link.Reader)
@@ -300,6 +317,13 @@ func (m *ClientWorker) Dispatch(ctx context.Context, link *transport.Link) bool
 	}
 	s.input = link.Reader
 	s.output = link.Writer
+	if m.strategy.Migrate != nil {
+		outbounds := session.OutboundsFromContext(ctx)
+		if outbounds[len(outbounds)-1].Target.Network == net.Network_TCP {
+			s.input = newReplayReader(link.Reader)
+			s.ctx = ctx
+		}
+	}
 	go fetchInput(ctx, s, m.link.Writer)
 	return true
 }
@@ -332,6 +356,10 @@ func (m *ClientWorker) handleStatusKeep(meta *FrameMetadata, reader *buf.Buffere
 		return buf.Copy(NewStreamReader(reader), buf.Discard)
 	}
 
+	if r, ok := s.input.(*replayReader); ok {
+		r.stop()
+	}
+
 	rr := s.NewReader(reader, &meta.Target)
 	err := buf.Copy(rr, s.output)
 	if err != nil && buf.IsWriteError(err) {
@@ -7,6 +7,7 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/mux"
 	"github.com/xtls/xray-core/common/net"
@@ -114,3 +115,93 @@ func TestClientWorkerClose(t *testing.T) {
 
 	common.Must(w2.Close())
 }
+
+func TestClientWorkerMigrate(t *testing.T) {
+	upReader, upWriter := pipe.New(pipe.WithoutSizeLimit())
+	downReader, downWriter := pipe.New(pipe.WithoutSizeLimit())
+
+	migrated := make(chan *transport.Link, 1)
+	worker, err := mux.NewClientWorker(transport.Link{
+		Reader: downReader,
+		Writer: upWriter,
+	}, mux.ClientStrategy{
+		MaxConcurrency: 4,
+		MaxConnection:  4,
+		Migrate: func(ctx context.Context, link *transport.Link) {
+			migrated <- link
+		},
+	})
+	common.Must(err)
+	go buf.Copy(upReader, buf.Discard)
+
+	inputReader, inputWriter := pipe.New(pipe.WithoutSizeLimit())
+	_, outputWriter := pipe.New(pipe.WithoutSizeLimit())
+	ctx := session.ContextWithOutbounds(context.Background(), []*session.Outbound{{
+		Target: net.TCPDestination(net.DomainAddress("www.example.com"), 80),
+	}})
+	if !worker.Dispatch(ctx, &transport.Link{Reader: inputReader, Writer: outputWriter}) {
+		t.Fatal("failed to dispatch")
+	}
+	common.Must(inputWriter.WriteMultiBuffer(buf.MergeBytes(nil, []byte("hello"))))
+	time.Sleep(time.Millisecond * 200)
+
+	common.Must(downWriter.Close())
+
+	var link *transport.Link
+	select {
+	case link = <-migrated:
+	case <-time.After(time.Second * 2):
+		t.Fatal("stream not migrated")
+	}
+
+	common.Must(inputWriter.WriteMultiBuffer(buf.MergeBytes(nil, []byte("world"))))
+	common.Must(inputWriter.Close())
+
+	var data []byte
+	for {
+		mb, err := link.Reader.ReadMultiBuffer()
+		for _, b := range mb {
+			data = append(data, b.Bytes()...)
+		}
+		buf.ReleaseMulti(mb)
+		if err != nil {
+			break
+		}
+	}
+	if string(data) != "helloworld" {
+		t.Error("migrated data: ", string(data))
+	}
+}
+
+func TestIncrementalPickerCloseAll(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	newWorker := func() *mux.ClientWorker {
+		r, w := pipe.New(pipe.WithoutSizeLimit())
+		worker, err := mux.NewClientWorker(transport.Link{Reader: r, Writer: w}, mux.ClientStrategy{
+			MaxConcurrency: 4,
+			MaxConnection:  4,
+		})
+		common.Must(err)
+		return worker
+	}
+	worker1, worker2 := newWorker(), newWorker()
+	factory := mocks.NewMuxClientWorkerFactory(mockCtl)
+	gomock.InOrder(
+		factory.EXPECT().Create().Return(worker1, nil),
+		factory.EXPECT().Create().Return(worker2, nil),
+	)
+	picker := &mux.IncrementalWorkerPicker{Factory: factory}
+
+	if w, err := picker.PickAvailable(); err != nil || w != worker1 {
+		t.Fatal("unexpected worker: ", w, err)
+	}
+	picker.CloseAll()
+	if !worker1.Closed() {
+		t.Error("worker not closed")
+	}
+	if w, err := picker.PickAvailable(); err != nil || w != worker2 {
+		t.Error("closed worker picked: ", w, err)
+	}
+}
@@ -0,0 +1,192 @@
+package mux
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/transport"
+)
+
+// maxReplaySize is the most uplink data kept for a session to be migrated. Sessions which
+// send more before any response arrives are not migrated.
+const maxReplaySize = 64 * 1024
+
+// MigrateFunc re-dispatches the link of a session whose Mux connection failed before the
+// session got any response. ctx is the context the session was dispatched with.
+type MigrateFunc func(ctx context.Context, link *transport.Link)
+
+// replayReader records what a session reads from its link until the first response, so that
+// the session can be replayed on another connection.
+type replayReader struct {
+	reader buf.Reader
+
+	read       sync.Mutex // held while reading from reader
+	access     sync.Mutex
+	recorded   buf.MultiBuffer
+	stopped    bool
+	detached   bool
+	pending    buf.MultiBuffer // read after detaching
+	pendingErr error
+}
+
+func newReplayReader(reader buf.Reader) *replayReader {
+	return &replayReader{reader: reader}
+}
+
+func (r *replayReader) readWith(read func() (buf.MultiBuffer, error)) (buf.MultiBuffer, error) {
+	r.read.Lock()
+	defer r.read.Unlock()
+
+	r.access.Lock()
+	detached := r.detached
+	r.access.Unlock()
+	if detached {
+		return nil, io.EOF
+	}
+
+	mb, err := read()
+
+	r.access.Lock()
+	defer r.access.Unlock()
+	if r.detached {
+		// The session moved on while this read was blocked. Hand the data over.
+		r.pending, r.pendingErr = mb, err
+		return nil, io.EOF
+	}
+	if !r.stopped && !mb.IsEmpty() {
+		if r.recorded.Len()+mb.Len() > maxReplaySize {
+			r.recorded = buf.ReleaseMulti(r.recorded)
+			r.stopped = true
+		} else {
+			for _, b := range mb {
+				r.recorded = buf.MergeBytes(r.recorded, b.Bytes())
+			}
+		}
+	}
+	return mb, err
+}
+
+// ReadMultiBuffer implements buf.Reader.
+func (r *replayReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	return r.readWith(r.reader.ReadMultiBuffer)
+}
+
+// ReadMultiBufferTimeout implements buf.TimeoutReader.
+func (r *replayReader) ReadMultiBufferTimeout(timeout time.Duration) (buf.MultiBuffer, error) {
+	tr, ok := r.reader.(buf.TimeoutReader)
+	if !ok {
+		return nil, buf.ErrNotTimeoutReader
+	}
+	return r.readWith(func() (buf.MultiBuffer, error) {
+		return tr.ReadMultiBufferTimeout(timeout)
+	})
+}
+
+// Interrupt implements common.Interruptible.
+func (r *replayReader) Interrupt() {
+	common.Interrupt(r.reader)
+}
+
+// stop drops the recorded data, as the session got a response and can't be replayed any more.
+func (r *replayReader) stop() {
+	r.access.Lock()
+	defer r.access.Unlock()
+
+	if !r.stopped {
+		r.recorded = buf.ReleaseMulti(r.recorded)
+		r.stopped = true
+	}
+}
+
+// detach returns a reader which replays the recorded data, then continues with the rest of
+// the link. Reads through r end from now on. It returns false if the session can't be
+// replayed.
+func (r *replayReader) detach() (buf.Reader, bool) {
+	r.access.Lock()
+	defer r.access.Unlock()
+
+	if r.stopped || r.detached {
+		return nil, false
+	}
+	r.detached = true
+	replay := r.recorded
+	r.recorded = nil
+	return &resumedReader{source: r, replay: replay}, true
+}
+
+type resumedReader struct {
+	source *replayReader
+	replay buf.MultiBuffer
+}
+
+// ReadMultiBuffer implements buf.Reader.
+func (r *resumedReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	if !r.replay.IsEmpty() {
+		mb := r.replay
+		r.replay = nil
+		return mb, nil
+	}
+
+	// Wait for a read of the failed session, which may still be blocked.
+	s := r.source
+	s.read.Lock()
+	defer s.read.Unlock()
+
+	s.access.Lock()
+	mb, err := s.pending, s.pendingErr
+	s.pending, s.pendingErr = nil, nil
+	s.access.Unlock()
+	if !mb.IsEmpty() || err != nil {
+		return mb, err
+	}
+	return s.reader.ReadMultiBuffer()
+}
+
+// Interrupt implements common.Interruptible.
+func (r *resumedReader) Interrupt() {
+	common.Interrupt(r.source.reader)
+}
+
+// migrateSessions hands the sessions which haven't got any response to the MigrateFunc of
+// the strategy, instead of closing them with the failed connection.
+func (m *ClientWorker) migrateSessions() {
+	if m.strategy.Migrate == nil {
+		return
+	}
+
+	type migration struct {
+		ctx  context.Context
+		link *transport.Link
+	}
+	var migrations []migration
+
+	sm := m.sessionManager
+	sm.Lock()
+	for id, s := range sm.sessions {
+		r, ok := s.input.(*replayReader)
+		if !ok || s.closed || s.XUDP != nil {
+			continue
+		}
+		reader, ok := r.detach()
+		if !ok {
+			continue
+		}
+		s.closed = true
+		delete(sm.sessions, id)
+		migrations = append(migrations, migration{
+			ctx:  s.ctx,
+			link: &transport.Link{Reader: reader, Writer: s.output},
+		})
+	}
+	sm.Unlock()
+
+	for _, mg := range migrations {
+		errors.LogInfo(mg.ctx, "migrating stream from failed mux connection")
+		go m.strategy.Migrate(mg.ctx, mg.link)
+	}
+}
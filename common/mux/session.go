@@ -11,6 +11,7 @@ import (
 	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/platform"
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/transport/pipe"
 )
@@ -155,6 +156,7 @@ type Session struct {
 	transferType protocol.TransferType
 	closed       bool
 	XUDP         *XUDP
+	ctx          context.Context // set on client sessions which may migrate
 }
 
 // Close closes all resources associated with this session.
@@ -180,7 +182,7 @@ func (s *Session) Close(locked bool) error {
 		s.input.(*pipe.Reader).Recover()
 		XUDPManager.Lock()
 		if s.XUDP.Status == Active {
-			s.XUDP.Expire = time.Now().Add(time.Minute)
+			s.XUDP.Expire = time.Now().Add(xudpExpire)
 			s.XUDP.Status = Expiring
 			errors.LogDebug(context.Background(), "XUDP put ", s.XUDP.GlobalID)
 		}
@@ -216,6 +218,16 @@ func (x *XUDP) Interrupt() {
 	common.Close(x.Mux.output)
 }
 
+// xudpExpire is how long the XUDP sessions detached from their Mux connection wait for their
+// source to come back on another one, keeping its UDP mappings. It's set in seconds by the
+// xray.xudp.expire environment variable.
+var xudpExpire = func() time.Duration {
+	if seconds := platform.NewEnvFlag(platform.XUDPExpire).GetValueAsInt(60); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Minute
+}()
+
 var XUDPManager struct {
 	sync.Mutex
 	Map map[[8]byte]*XUDP
@@ -225,7 +237,7 @@ func init() {
 	XUDPManager.Map = make(map[[8]byte]*XUDP)
 	go func() {
 		for {
-			time.Sleep(time.Minute)
+			time.Sleep(min(xudpExpire, time.Minute))
 			now := time.Now()
 			XUDPManager.Lock()
 			for id, x := range XUDPManager.Map {
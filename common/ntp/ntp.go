@@ -0,0 +1,153 @@
+// Package ntp measures the offset of the local clock from an NTP server, so that protocols
+// authenticating by time, as VMess and Shadowsocks 2022, work on devices with drifting clocks
+// without changing the system time.
+package ntp
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/task"
+)
+
+const (
+	// DefaultInterval is the interval between syncs of a Clock unless set.
+	DefaultInterval = time.Hour
+	queryTimeout    = 5 * time.Second
+	// Seconds from the NTP epoch, 1900, to the Unix epoch.
+	epochOffset = 2208988800
+)
+
+// Query asks server, a host with an optional port, for the time, and returns the offset of the
+// local clock from it, to be added to the local time.
+func Query(ctx context.Context, server string) (time.Duration, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return 0, errors.New("failed to dial NTP server ", server).Base(err)
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	request := make([]byte, 48)
+	request[0] = 0x23 // version 4, client mode
+	sent := time.Now()
+	putTimestamp(request[40:], sent)
+	if _, err := conn.Write(request); err != nil {
+		return 0, errors.New("failed to query NTP server ", server).Base(err)
+	}
+	response := make([]byte, 48)
+	for {
+		n, err := conn.Read(response)
+		if err != nil {
+			return 0, errors.New("no answer from NTP server ", server).Base(err)
+		}
+		// Answers of other requests are skipped.
+		if n == 48 && binary.BigEndian.Uint64(response[24:]) == binary.BigEndian.Uint64(request[40:]) {
+			break
+		}
+	}
+	received := time.Now()
+	if mode := response[0] & 0x07; mode != 4 {
+		return 0, errors.New("unexpected mode ", mode, " of NTP server ", server)
+	}
+	if stratum := response[1]; stratum == 0 || stratum > 15 {
+		return 0, errors.New("NTP server ", server, " is unsynchronized or refused the query")
+	}
+	serverReceived := timestamp(response[32:])
+	serverSent := timestamp(response[40:])
+	return (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2, nil
+}
+
+func putTimestamp(b []byte, t time.Time) {
+	seconds := uint64(t.Unix() + epochOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	binary.BigEndian.PutUint64(b, seconds<<32|fraction)
+}
+
+func timestamp(b []byte) time.Time {
+	v := binary.BigEndian.Uint64(b)
+	seconds := int64(v>>32) - epochOffset
+	nanoseconds := int64((v & 0xffffffff) * uint64(time.Second) >> 32)
+	return time.Unix(seconds, nanoseconds)
+}
+
+// Clock is the local clock corrected by the offset from an NTP server, which it syncs with
+// periodically once started. Until the first sync, and on a nil Clock, it's the local clock.
+type Clock struct {
+	server string
+	offset atomic.Int64
+	task   *task.Periodic
+}
+
+// NewClock returns a Clock syncing with server every interval, DefaultInterval if 0.
+func NewClock(server string, interval time.Duration) *Clock {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	c := &Clock{server: server}
+	c.task = &task.Periodic{
+		Interval: interval,
+		Execute:  c.sync,
+	}
+	return c
+}
+
+func (c *Clock) sync() error {
+	offset, err := Query(context.Background(), c.server)
+	if err != nil {
+		errors.LogWarningInner(context.Background(), err, "failed to sync the clock, its offset stays ", c.Offset())
+		return nil
+	}
+	c.offset.Store(int64(offset))
+	errors.LogInfo(context.Background(), "the local clock is off by ", offset, " from NTP server ", c.server)
+	return nil
+}
+
+// Now returns the corrected time.
+func (c *Clock) Now() time.Time {
+	return time.Now().Add(c.Offset())
+}
+
+// Offset returns the offset of the local clock from the NTP server, to be added to it.
+func (c *Clock) Offset() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return time.Duration(c.offset.Load())
+}
+
+// Start implements common.Runnable. The first sync runs in background, not to hold back the
+// start.
+func (c *Clock) Start() error {
+	go c.task.Start()
+	return nil
+}
+
+// Close implements common.Closable.
+func (c *Clock) Close() error {
+	return c.task.Close()
+}
+
+type clockKey struct{}
+
+// ContextWithClock returns a context carrying clock, for the proxies created with it.
+func ContextWithClock(ctx context.Context, clock *Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, clock)
+}
+
+// ClockFromContext returns the Clock of ctx, or nil for the local clock.
+func ClockFromContext(ctx context.Context) *Clock {
+	clock, _ := ctx.Value(clockKey{}).(*Clock)
+	return clock
+}
@@ -0,0 +1,74 @@
+package ntp_test
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	. "github.com/xtls/xray-core/common/ntp"
+)
+
+// serve answers NTP queries with the local time shifted by offset.
+func serve(t *testing.T, offset time.Duration) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	common.Must(err)
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		b := make([]byte, 48)
+		for {
+			n, addr, err := conn.ReadFrom(b)
+			if err != nil {
+				return
+			}
+			if n != 48 {
+				continue
+			}
+			now := time.Now().Add(offset)
+			response := make([]byte, 48)
+			response[0] = 0x24 // version 4, server mode
+			response[1] = 2
+			copy(response[24:32], b[40:48])
+			putTimestamp(response[32:], now)
+			putTimestamp(response[40:], now)
+			conn.WriteTo(response, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func putTimestamp(b []byte, t time.Time) {
+	seconds := uint64(t.Unix() + 2208988800)
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	binary.BigEndian.PutUint64(b, seconds<<32|fraction)
+}
+
+func TestQuery(t *testing.T) {
+	offset, err := Query(context.Background(), serve(t, -90*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset > -89*time.Second || offset < -91*time.Second {
+		t.Error("unexpected offset ", offset)
+	}
+}
+
+func TestClock(t *testing.T) {
+	var clock *Clock
+	if clock.Offset() != 0 {
+		t.Error("a nil clock is off")
+	}
+
+	clock = NewClock(serve(t, time.Hour), 0)
+	common.Must(clock.Start())
+	defer clock.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for clock.Offset() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if d := clock.Now().Sub(time.Now()); d < 59*time.Minute || d > 61*time.Minute {
+		t.Error("unexpected time ", clock.Now())
+	}
+}
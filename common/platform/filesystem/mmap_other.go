@@ -0,0 +1,12 @@
+//go:build !unix
+
+package filesystem
+
+// MapAsset reads the asset file. It's mapped into memory on unix.
+func MapAsset(file string) (data []byte, unmap func() error, err error) {
+	data, err = ReadAsset(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"os"
+
+	"github.com/xtls/xray-core/common/platform"
+	"golang.org/x/sys/unix"
+)
+
+// MapAsset maps the asset file into memory, so that only the parts in use are read. The data
+// must not be used after calling unmap.
+func MapAsset(file string) (data []byte, unmap func() error, err error) {
+	f, err := os.Open(platform.GetAssetLocation(file))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Size() == 0 || int64(int(info.Size())) != info.Size() {
+		// Empty files can't be mapped, and huge ones don't fit.
+		data, err := ReadAsset(file)
+		return data, func() error { return nil }, err
+	}
+	data, err = unix.Mmap(int(f.Fd()), 0, int(info.Size()), unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		data, err := ReadAsset(file)
+		return data, func() error { return nil }, err
+	}
+	return data, func() error { return unix.Munmap(data) }, nil
+}
@@ -13,16 +13,21 @@ const (
 	ConfdirLocation = "xray.location.confdir"
 	ToolLocation    = "xray.location.tool"
 	AssetLocation   = "xray.location.asset"
+	CacheLocation   = "xray.location.cache"
+	StateLocation   = "xray.location.state"
+	StateBackend    = "xray.state.backend"
 
 	UseReadV         = "xray.buf.readv"
 	UseFreedomSplice = "xray.buf.splice"
 	UseVmessPadding  = "xray.vmess.padding"
 	UseCone          = "xray.cone.disabled"
+	UseAESHardware   = "xray.crypto.aes.hardware"
 
 	BufferSize           = "xray.ray.buffer.size"
 	BrowserDialerAddress = "xray.browser.dialer"
 	XUDPLog              = "xray.xudp.show"
 	XUDPBaseKey          = "xray.xudp.basekey"
+	XUDPExpire           = "xray.xudp.expire"
 )
 
 type EnvFlag struct {
@@ -99,3 +104,15 @@ func GetConfDirPath() string {
 	configPath := NewEnvFlag(ConfdirLocation).GetValue(func() string { return "" })
 	return configPath
 }
+
+// GetCacheDirectory reads "xray.location.cache", the directory parsed geo data and downloaded
+// blocklists are cached in. An empty result, the default, means there is no cache.
+func GetCacheDirectory() string {
+	return NewEnvFlag(CacheLocation).GetValue(func() string { return "" })
+}
+
+// GetStateDirectory reads "xray.location.state", the directory runtime state is kept in across
+// restarts. An empty result means no state is kept but in the files configured for it.
+func GetStateDirectory() string {
+	return NewEnvFlag(StateLocation).GetValue(func() string { return "" })
+}
@@ -0,0 +1,119 @@
+// Package power tells when the system goes to sleep and wakes up, so that keepalives and probes
+// are held while it sleeps, and the connections which died meanwhile are replaced on wake.
+//
+// On macOS built with cgo, the power notifications of the system announce both. Elsewhere, waking
+// up is detected by the wall clock running ahead of the monotonic one, which stops while the
+// system sleeps, so only Wake events are sent, a few seconds late.
+package power
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType is the type of a power Event.
+type EventType int
+
+const (
+	// Sleep is sent before the system sleeps, which waits a few seconds for the subscribers.
+	Sleep EventType = iota
+	// Wake is sent after the system wakes up.
+	Wake
+)
+
+// Event is a change of the power state of the system.
+type Event struct {
+	Type EventType
+	// Slept is how long the system slept, in Wake events.
+	Slept time.Duration
+}
+
+const (
+	clockCheckInterval = 5 * time.Second
+	// minClockSleep is the least gap between the clocks taken for a sleep, which steps of the
+	// wall clock by NTP stay below.
+	minClockSleep = 30 * time.Second
+)
+
+var (
+	access      sync.Mutex
+	subscribers = make(map[*func(Event)]struct{})
+	stopWatch   func()
+	asleep      atomic.Bool
+)
+
+// Subscribe calls f with the power events until the returned function is called. The system is
+// watched while there are subscribers.
+func Subscribe(f func(Event)) (unsubscribe func()) {
+	access.Lock()
+	defer access.Unlock()
+
+	key := &f
+	subscribers[key] = struct{}{}
+	if stopWatch == nil {
+		stopWatch = watch()
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			access.Lock()
+			defer access.Unlock()
+
+			delete(subscribers, key)
+			if len(subscribers) == 0 && stopWatch != nil {
+				stopWatch()
+				stopWatch = nil
+			}
+		})
+	}
+}
+
+// Asleep tells whether the system is going to sleep, between a Sleep event and the next Wake one.
+func Asleep() bool {
+	return asleep.Load()
+}
+
+// publish calls the subscribers with e, in turn.
+func publish(e Event) {
+	asleep.Store(e.Type == Sleep)
+	access.Lock()
+	fs := make([]func(Event), 0, len(subscribers))
+	for f := range subscribers {
+		fs = append(fs, *f)
+	}
+	access.Unlock()
+	for _, f := range fs {
+		f(e)
+	}
+}
+
+// watchClock sends a Wake event each time the wall clock gets ahead of the monotonic one, until
+// the returned function is called.
+func watchClock() (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(clockCheckInterval)
+		defer ticker.Stop()
+		last := time.Now()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				now := time.Now()
+				if slept := sleptBetween(last, now); slept >= minClockSleep {
+					publish(Event{Type: Wake, Slept: slept})
+				}
+				last = now
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// sleptBetween returns how much further the wall clock went than the monotonic one between last
+// and now.
+func sleptBetween(last, now time.Time) time.Duration {
+	return now.Round(0).Sub(last.Round(0)) - now.Sub(last)
+}
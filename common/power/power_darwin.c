@@ -0,0 +1,43 @@
+//go:build darwin && cgo
+
+#include <CoreFoundation/CoreFoundation.h>
+#include <IOKit/IOMessage.h>
+#include <IOKit/pwr_mgt/IOPMLib.h>
+
+extern void xrayPowerEvent(int event, long argument);
+
+static io_connect_t rootPort = MACH_PORT_NULL;
+
+static void xrayPowerCallback(void *refcon, io_service_t service, natural_t type, void *argument) {
+	switch (type) {
+	case kIOMessageCanSystemSleep:
+		// Idle sleep isn't held back.
+		IOAllowPowerChange(rootPort, (long)argument);
+		break;
+	case kIOMessageSystemWillSleep:
+		xrayPowerEvent(0, (long)argument);
+		break;
+	case kIOMessageSystemHasPoweredOn:
+		xrayPowerEvent(1, 0);
+		break;
+	}
+}
+
+int xrayRegisterPower(void) {
+	IONotificationPortRef port;
+	io_object_t notifier;
+	rootPort = IORegisterForSystemPower(NULL, &port, xrayPowerCallback, &notifier);
+	if (rootPort == MACH_PORT_NULL) {
+		return -1;
+	}
+	CFRunLoopAddSource(CFRunLoopGetCurrent(), IONotificationPortGetRunLoopSource(port), kCFRunLoopCommonModes);
+	return 0;
+}
+
+void xrayRunPower(void) {
+	CFRunLoopRun();
+}
+
+void xrayAllowPowerChange(long argument) {
+	IOAllowPowerChange(rootPort, argument);
+}
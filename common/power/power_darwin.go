@@ -0,0 +1,80 @@
+//go:build darwin && cgo
+
+package power
+
+/*
+#cgo LDFLAGS: -framework IOKit -framework CoreFoundation
+
+int xrayRegisterPower(void);
+void xrayRunPower(void);
+void xrayAllowPowerChange(long argument);
+*/
+import "C"
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// sleepTimeout is how long the system is held from sleeping for the subscribers.
+const sleepTimeout = 5 * time.Second
+
+var (
+	registerOnce sync.Once
+	registered   bool
+	sleptAt      time.Time
+)
+
+//export xrayPowerEvent
+func xrayPowerEvent(event C.int, argument C.long) {
+	switch event {
+	case 0:
+		sleptAt = time.Now()
+		done := make(chan struct{})
+		go func() {
+			publish(Event{Type: Sleep})
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(sleepTimeout):
+		}
+		C.xrayAllowPowerChange(argument)
+	case 1:
+		var slept time.Duration
+		if !sleptAt.IsZero() {
+			slept = time.Now().Round(0).Sub(sleptAt.Round(0))
+		}
+		go publish(Event{Type: Wake, Slept: slept})
+	}
+}
+
+// register registers for the power notifications, which are delivered to the run loop of a
+// thread of their own for the life of the process.
+func register() {
+	result := make(chan C.int)
+	go func() {
+		runtime.LockOSThread()
+		r := C.xrayRegisterPower()
+		result <- r
+		if r == 0 {
+			C.xrayRunPower()
+		}
+	}()
+	registered = <-result == 0
+	if !registered {
+		errors.LogWarning(context.Background(), "failed to register for power notifications, waking up is detected by the clock")
+	}
+}
+
+func watch() func() {
+	registerOnce.Do(register)
+	if registered {
+		return func() {}
+	}
+	return watchClock()
+}
@@ -0,0 +1,44 @@
+package power
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	var events []Event
+	unsubscribe := Subscribe(func(e Event) {
+		events = append(events, e)
+	})
+
+	publish(Event{Type: Sleep})
+	if !Asleep() {
+		t.Error("not asleep after a Sleep event")
+	}
+	publish(Event{Type: Wake, Slept: time.Hour})
+	if Asleep() {
+		t.Error("asleep after a Wake event")
+	}
+	if len(events) != 2 || events[0].Type != Sleep || events[1].Type != Wake || events[1].Slept != time.Hour {
+		t.Error("unexpected events: ", events)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	publish(Event{Type: Wake})
+	if len(events) != 2 {
+		t.Error("event after unsubscribing: ", events)
+	}
+	access.Lock()
+	defer access.Unlock()
+	if stopWatch != nil {
+		t.Error("still watching without subscribers")
+	}
+}
+
+func TestSleptBetween(t *testing.T) {
+	last := time.Now()
+	if slept := sleptBetween(last, last.Add(time.Minute)); slept != 0 {
+		t.Error("slept ", slept, " while the clocks agree")
+	}
+}
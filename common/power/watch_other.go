@@ -0,0 +1,7 @@
+//go:build !darwin || !cgo
+
+package power
+
+func watch() func() {
+	return watchClock()
+}
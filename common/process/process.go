@@ -0,0 +1,96 @@
+// Package process finds the local process owning a connection, so that traffic can be routed by
+// the application it comes from.
+//
+// It is supported on Linux (procfs), macOS (lsof) and Windows (the extended TCP and UDP tables of
+// the IP helper API).
+package process
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+// Info identifies a process.
+type Info struct {
+	PID int
+	// Name is the name of the executable, without the ".exe" suffix on Windows.
+	Name string
+	// Path is the full path of the executable, or empty if it can't be read.
+	Path string
+}
+
+// ErrNotFound is returned when no local process owns a socket bound to the address.
+var ErrNotFound = errors.New("no local process owns the address")
+
+// cacheTTL is how long an owner found stays cached. Connections from the same address are often
+// routed by several rules in a row, while the address is only reused well after it's released.
+const cacheTTL = 2 * time.Second
+
+// maxCacheSize bounds the owners cached, expired ones being dropped when it's reached.
+const maxCacheSize = 1024
+
+type cacheKey struct {
+	network net.Network
+	source  string
+}
+
+type cacheEntry struct {
+	info    *Info
+	err     error
+	expires time.Time
+}
+
+var (
+	cacheAccess sync.Mutex
+	cache       = make(map[cacheKey]cacheEntry)
+)
+
+// FindOwner returns the local process with a socket of network bound to source, the address a
+// connection comes from.
+func FindOwner(network net.Network, source net.Destination) (*Info, error) {
+	if !source.Address.Family().IsIP() {
+		return nil, ErrNotFound
+	}
+	key := cacheKey{network: network, source: source.NetAddr()}
+	now := time.Now()
+	cacheAccess.Lock()
+	entry, found := cache[key]
+	cacheAccess.Unlock()
+	if found && now.Before(entry.expires) {
+		return entry.info, entry.err
+	}
+
+	info, err := findOwner(network, source)
+	if err == nil && info.Name == "" {
+		info.Name = nameOf(info.Path)
+	}
+
+	cacheAccess.Lock()
+	if len(cache) >= maxCacheSize {
+		for k, e := range cache {
+			if !now.Before(e.expires) {
+				delete(cache, k)
+			}
+		}
+		if len(cache) >= maxCacheSize {
+			cache = make(map[cacheKey]cacheEntry)
+		}
+	}
+	cache[key] = cacheEntry{info: info, err: err, expires: now.Add(cacheTTL)}
+	cacheAccess.Unlock()
+	return info, err
+}
+
+// nameOf returns the name of the executable at path.
+func nameOf(path string) string {
+	name := filepath.Base(strings.ReplaceAll(path, `\`, "/"))
+	if ext := filepath.Ext(name); strings.EqualFold(ext, ".exe") {
+		name = name[:len(name)-len(ext)]
+	}
+	return name
+}
@@ -0,0 +1,56 @@
+//go:build darwin
+// +build darwin
+
+package process
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/xtls/xray-core/common/net"
+)
+
+// findOwner looks the socket up with lsof, and the path of the executable with ps.
+func findOwner(network net.Network, source net.Destination) (*Info, error) {
+	host := source.Address.IP().String()
+	if source.Address.Family().IsIPv6() {
+		host = "[" + host + "]"
+	}
+	protocol := "TCP"
+	if network == net.Network_UDP {
+		protocol = "UDP"
+	}
+	// lsof lists the sockets with the address at either end, those of this process included when
+	// the connection was accepted by it.
+	out, err := exec.Command("lsof", "-nP", "+c", "0", "-Fpc", "-i"+protocol+"@"+host+":"+source.Port.String()).Output()
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	var owners []*Info
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.HasPrefix(line, "p"):
+			if pid, err := strconv.Atoi(line[1:]); err == nil {
+				owners = append(owners, &Info{PID: pid})
+			}
+		case strings.HasPrefix(line, "c") && len(owners) > 0:
+			owners[len(owners)-1].Name = line[1:]
+		}
+	}
+	if len(owners) == 0 {
+		return nil, ErrNotFound
+	}
+	info := owners[0]
+	for _, owner := range owners {
+		if owner.PID != os.Getpid() {
+			info = owner
+			break
+		}
+	}
+	if path, err := exec.Command("ps", "-o", "comm=", "-p", strconv.Itoa(info.PID)).Output(); err == nil {
+		info.Path = strings.TrimSpace(string(path))
+	}
+	return info, nil
+}
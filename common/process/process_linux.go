@@ -0,0 +1,102 @@
+//go:build linux
+// +build linux
+
+package process
+
+import (
+	"bufio"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xtls/xray-core/common/net"
+)
+
+// findOwner looks the socket up in the tables of procfs, then its inode in the file descriptors
+// of the processes.
+func findOwner(network net.Network, source net.Destination) (*Info, error) {
+	tables := []string{"/proc/net/tcp", "/proc/net/tcp6"}
+	if network == net.Network_UDP {
+		tables = []string{"/proc/net/udp", "/proc/net/udp6"}
+	}
+	inode := ""
+	for _, table := range tables {
+		if inode = findInode(table, network, source); inode != "" {
+			break
+		}
+	}
+	if inode == "" {
+		return nil, ErrNotFound
+	}
+
+	link := "socket:[" + inode + "]"
+	fds, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	for _, fd := range fds {
+		if target, err := os.Readlink(fd); err != nil || target != link {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.Split(fd, "/")[2])
+		if err != nil {
+			continue
+		}
+		info := &Info{PID: pid}
+		info.Path, _ = os.Readlink(filepath.Join("/proc", strconv.Itoa(pid), "exe"))
+		if info.Path == "" {
+			// The executable of processes of other users can't be read, but the name can.
+			comm, _ := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "comm"))
+			info.Name = strings.TrimSpace(string(comm))
+		}
+		return info, nil
+	}
+	return nil, ErrNotFound
+}
+
+// findInode returns the inode of the socket bound to source in table, like /proc/net/tcp. UDP
+// sockets bound to the unspecified address match any address of the port.
+func findInode(table string, network net.Network, source net.Destination) string {
+	f, err := os.Open(table)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		ip, port, ok := parseAddress(fields[1])
+		if !ok || port != source.Port {
+			continue
+		}
+		if ip.Equal(source.Address.IP()) || (network == net.Network_UDP && ip.IsUnspecified()) {
+			return fields[9]
+		}
+	}
+	return ""
+}
+
+// parseAddress parses an address of procfs, like "0100007F:1F90", whose IP is written as 32-bit
+// words in host byte order.
+func parseAddress(s string) (net.IP, net.Port, bool) {
+	host, port, found := strings.Cut(s, ":")
+	if !found {
+		return nil, 0, false
+	}
+	ip, err := hex.DecodeString(host)
+	if err != nil || (len(ip) != 4 && len(ip) != 16) {
+		return nil, 0, false
+	}
+	for i := 0; i < len(ip); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = ip[i+3], ip[i+2], ip[i+1], ip[i]
+	}
+	p, err := strconv.ParseUint(port, 16, 16)
+	if err != nil {
+		return nil, 0, false
+	}
+	return net.IP(ip), net.Port(p), true
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package process
+
+import (
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+func findOwner(net.Network, net.Destination) (*Info, error) {
+	return nil, errors.New("finding the process of a connection is not supported on this platform")
+}
@@ -0,0 +1,43 @@
+package process_test
+
+import (
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	. "github.com/xtls/xray-core/common/process"
+)
+
+func TestFindOwner(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux", "darwin", "windows":
+	default:
+		t.Skip("not supported on ", runtime.GOOS)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	info, err := FindOwner(net.Network_TCP, net.DestinationFromAddr(conn.LocalAddr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.PID != os.Getpid() {
+		t.Error("expected this process ", os.Getpid(), ", but got ", info.PID)
+	}
+	if info.Name == "" {
+		t.Error("expected the name of the executable")
+	}
+
+	if _, err := FindOwner(net.Network_TCP, net.TCPDestination(net.LocalHostIP, 1)); err == nil {
+		t.Error("expected no owner of an unbound address")
+	}
+}
@@ -0,0 +1,121 @@
+//go:build windows
+// +build windows
+
+package process
+
+import (
+	"encoding/binary"
+	"unsafe"
+
+	"github.com/xtls/xray-core/common/net"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	iphlpapi            = windows.NewLazySystemDLL("iphlpapi.dll")
+	getExtendedTcpTable = iphlpapi.NewProc("GetExtendedTcpTable")
+	getExtendedUdpTable = iphlpapi.NewProc("GetExtendedUdpTable")
+)
+
+const (
+	tcpTableOwnerPIDAll = 5
+	udpTableOwnerPID    = 1
+)
+
+// rowLayout is where the local address, local port and owning PID are in a row of a table, and its
+// size.
+type rowLayout struct {
+	size, addr, port, pid int
+}
+
+var (
+	tcp4Row = rowLayout{size: 24, addr: 4, port: 8, pid: 20}  // MIB_TCPROW_OWNER_PID
+	tcp6Row = rowLayout{size: 56, addr: 0, port: 20, pid: 52} // MIB_TCP6ROW_OWNER_PID
+	udp4Row = rowLayout{size: 12, addr: 0, port: 4, pid: 8}   // MIB_UDPROW_OWNER_PID
+	udp6Row = rowLayout{size: 28, addr: 0, port: 20, pid: 24} // MIB_UDP6ROW_OWNER_PID
+)
+
+// findOwner looks the socket up in the extended TCP or UDP tables, which give its owning PID.
+func findOwner(network net.Network, source net.Destination) (*Info, error) {
+	proc, class := getExtendedTcpTable, uintptr(tcpTableOwnerPIDAll)
+	rows := [2]rowLayout{tcp4Row, tcp6Row}
+	if network == net.Network_UDP {
+		proc, class = getExtendedUdpTable, udpTableOwnerPID
+		rows = [2]rowLayout{udp4Row, udp6Row}
+	}
+	// Dual-stack sockets are in the IPv6 table, with IPv4-mapped addresses.
+	for i, family := range []uintptr{windows.AF_INET, windows.AF_INET6} {
+		table, err := getTable(proc, family, class)
+		if err != nil {
+			return nil, err
+		}
+		if pid, found := findPID(table, rows[i], network, source); found {
+			return infoOf(pid), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func getTable(proc *windows.LazyProc, family, class uintptr) ([]byte, error) {
+	var size uint32
+	for {
+		var table []byte
+		var p uintptr
+		if size > 0 {
+			table = make([]byte, size)
+			p = uintptr(unsafe.Pointer(&table[0]))
+		}
+		r, _, _ := proc.Call(p, uintptr(unsafe.Pointer(&size)), 0, family, class, 0)
+		switch windows.Errno(r) {
+		case 0:
+			return table, nil
+		case windows.ERROR_INSUFFICIENT_BUFFER:
+			// The table may grow between calls.
+			continue
+		default:
+			return nil, windows.Errno(r)
+		}
+	}
+}
+
+// findPID returns the owner of the row bound to source in table, which starts with the number of
+// rows. UDP sockets bound to the unspecified address match any address of the port.
+func findPID(table []byte, row rowLayout, network net.Network, source net.Destination) (uint32, bool) {
+	if len(table) < 4 {
+		return 0, false
+	}
+	n := int(binary.LittleEndian.Uint32(table))
+	addrLen := 4
+	if row == tcp6Row || row == udp6Row {
+		addrLen = 16
+	}
+	for i := 0; i < n; i++ {
+		r := table[4+i*row.size:]
+		if len(r) < row.size {
+			break
+		}
+		if net.Port(binary.BigEndian.Uint16(r[row.port:])) != source.Port {
+			continue
+		}
+		ip := net.IP(r[row.addr : row.addr+addrLen])
+		if ip.Equal(source.Address.IP()) || (network == net.Network_UDP && ip.IsUnspecified()) {
+			return binary.LittleEndian.Uint32(r[row.pid:]), true
+		}
+	}
+	return 0, false
+}
+
+func infoOf(pid uint32) *Info {
+	info := &Info{PID: int(pid)}
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return info
+	}
+	defer windows.CloseHandle(h)
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(h, 0, &buf[0], &size); err == nil {
+		info.Path = windows.UTF16ToString(buf[:size])
+	}
+	return info
+}
@@ -0,0 +1,132 @@
+package protocol
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/platform"
+	"golang.org/x/sys/cpu"
+)
+
+var (
+	hasGCMAsmAMD64 = cpu.X86.HasAES && cpu.X86.HasPCLMULQDQ
+	hasGCMAsmARM64 = cpu.ARM64.HasAES && cpu.ARM64.HasPMULL
+	// Keep in sync with crypto/aes/cipher_s390x.go.
+	hasGCMAsmS390X = cpu.S390X.HasAES && cpu.S390X.HasAESCBC && cpu.S390X.HasAESCTR &&
+		(cpu.S390X.HasGHASH || cpu.S390X.HasAESGCM)
+
+	hasAESGCMHardwareSupport = runtime.GOARCH == "amd64" && hasGCMAsmAMD64 ||
+		runtime.GOARCH == "arm64" && hasGCMAsmARM64 ||
+		runtime.GOARCH == "s390x" && hasGCMAsmS390X
+
+	aesHardwareDisabled atomic.Bool
+	aesSoftwareWarning  sync.Once
+)
+
+func init() {
+	if platform.NewEnvFlag(platform.UseAESHardware).GetValue(func() string { return "" }) == "off" {
+		aesHardwareDisabled.Store(true)
+	}
+}
+
+// HasAESGCMHardwareSupport returns whether the CPU accelerates AES-GCM, so that it's preferred
+// over ChaCha20-Poly1305 where ciphers are chosen automatically. It returns false if the
+// acceleration is disabled.
+func HasAESGCMHardwareSupport() bool {
+	return hasAESGCMHardwareSupport && !aesHardwareDisabled.Load()
+}
+
+// DisableAESHardware makes ciphers chosen automatically ChaCha20-Poly1305, as on CPUs without
+// AES instructions. It must be called before the accounts of users are created, as they choose
+// their ciphers then. AES ciphers set explicitly keep running on the instructions, unless they
+// are turned off in the Go runtime by GODEBUG=cpu.aes=off.
+func DisableAESHardware() {
+	aesHardwareDisabled.Store(true)
+}
+
+// WarnAESInSoftware logs once if method, a cipher set explicitly, is AES-GCM and the CPU doesn't
+// accelerate it, as ChaCha20-Poly1305 is several times faster then.
+func WarnAESInSoftware(ctx context.Context, method string) {
+	if hasAESGCMHardwareSupport || !strings.Contains(strings.ToLower(method), "aes") {
+		return
+	}
+	aesSoftwareWarning.Do(func() {
+		errors.LogWarning(ctx, "cipher ", method, " runs in software on this CPU, consider chacha20-poly1305 instead")
+	})
+}
+
+// CryptoAcceleration describes how the CPU accelerates the AEAD ciphers of the proxies.
+type CryptoAcceleration struct {
+	// AES is the instructions accelerating AES-GCM, none if it runs in software.
+	AES []string
+	// ChaCha is the instructions accelerating ChaCha20-Poly1305, none if it runs in portable code.
+	ChaCha []string
+	// Disabled is whether the acceleration of AES-GCM is disabled by DisableAESHardware.
+	Disabled bool
+}
+
+// DetectCryptoAcceleration returns the acceleration of the AEAD ciphers on this CPU.
+func DetectCryptoAcceleration() *CryptoAcceleration {
+	a := &CryptoAcceleration{Disabled: aesHardwareDisabled.Load()}
+	switch runtime.GOARCH {
+	case "amd64":
+		if hasGCMAsmAMD64 {
+			a.AES = []string{"AES-NI", "PCLMULQDQ"}
+		}
+		// As golang.org/x/crypto/chacha20poly1305 picks its assembly.
+		if cpu.X86.HasAVX2 && cpu.X86.HasBMI2 {
+			a.ChaCha = []string{"AVX2", "BMI2"}
+		} else if cpu.X86.HasSSSE3 {
+			a.ChaCha = []string{"SSSE3"}
+		}
+	case "arm64":
+		if hasGCMAsmARM64 {
+			a.AES = []string{"AES", "PMULL"}
+		}
+		a.ChaCha = []string{"NEON"}
+	case "s390x":
+		if hasGCMAsmS390X {
+			a.AES = []string{"CPACF"}
+		}
+		if cpu.S390X.HasVX {
+			a.ChaCha = []string{"VX"}
+		}
+	case "ppc64le":
+		a.ChaCha = []string{"VSX"}
+	}
+	return a
+}
+
+// PreferredCipher returns the AEAD cipher preferred on this CPU, "aes-128-gcm" or
+// "chacha20-poly1305".
+func (a *CryptoAcceleration) PreferredCipher() string {
+	if len(a.AES) > 0 && !a.Disabled {
+		return "aes-128-gcm"
+	}
+	return "chacha20-poly1305"
+}
+
+func (a *CryptoAcceleration) String() string {
+	var b strings.Builder
+	b.WriteString("AES-GCM ")
+	switch {
+	case len(a.AES) == 0:
+		b.WriteString("in software")
+	case a.Disabled:
+		b.WriteString("by " + strings.Join(a.AES, ", ") + ", not preferred")
+	default:
+		b.WriteString("by " + strings.Join(a.AES, ", "))
+	}
+	b.WriteString("; ChaCha20-Poly1305 ")
+	if len(a.ChaCha) == 0 {
+		b.WriteString("in portable code")
+	} else {
+		b.WriteString("by " + strings.Join(a.ChaCha, ", "))
+	}
+	b.WriteString("; preferring " + a.PreferredCipher())
+	return b.String()
+}
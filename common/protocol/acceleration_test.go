@@ -0,0 +1,33 @@
+package protocol_test
+
+import (
+	"testing"
+
+	. "github.com/xtls/xray-core/common/protocol"
+)
+
+func TestCryptoAccelerationPreferredCipher(t *testing.T) {
+	cases := []struct {
+		acceleration *CryptoAcceleration
+		cipher       string
+	}{
+		{&CryptoAcceleration{AES: []string{"AES-NI", "PCLMULQDQ"}}, "aes-128-gcm"},
+		{&CryptoAcceleration{AES: []string{"AES-NI", "PCLMULQDQ"}, Disabled: true}, "chacha20-poly1305"},
+		{&CryptoAcceleration{ChaCha: []string{"NEON"}}, "chacha20-poly1305"},
+	}
+	for _, c := range cases {
+		if cipher := c.acceleration.PreferredCipher(); cipher != c.cipher {
+			t.Errorf("%v prefers %s, want %s", c.acceleration, cipher, c.cipher)
+		}
+	}
+}
+
+func TestDisableAESHardware(t *testing.T) {
+	DisableAESHardware()
+	if HasAESGCMHardwareSupport() {
+		t.Error("AES-GCM acceleration is still preferred")
+	}
+	if cipher := DetectCryptoAcceleration().PreferredCipher(); cipher != "chacha20-poly1305" {
+		t.Error("preferred cipher is ", cipher)
+	}
+}
@@ -1,10 +1,12 @@
 package http
 
 import (
+	"bytes"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/common/net"
 )
 
@@ -22,6 +24,76 @@ func ParseXForwardedFor(header http.Header) []net.Address {
 	return addrs
 }
 
+// ForwardedHeaders are the headers which tell servers behind proxies about their clients.
+var ForwardedHeaders = []string{"X-Forwarded-For", "X-Real-IP", "X-Forwarded-Proto"}
+
+// SetForwardedHeaders sets headers, which are some of ForwardedHeaders, in the HTTP/1.x request
+// at the start of data, replacing those the client sent. An empty client leaves out the headers
+// of its IP. It returns data as is and false if data doesn't start with a complete request head.
+func SetForwardedHeaders(data []byte, headers []string, client, proto string) ([]byte, bool) {
+	end := bytes.Index(data, []byte("\r\n\r\n"))
+	if end < 0 {
+		return data, false
+	}
+	lineEnd := bytes.Index(data, []byte("\r\n"))
+	if !bytes.HasSuffix(data[:lineEnd], []byte(" HTTP/1.1")) && !bytes.HasSuffix(data[:lineEnd], []byte(" HTTP/1.0")) {
+		return data, false
+	}
+
+	isSet := func(line []byte) bool {
+		name, _, _ := bytes.Cut(line, []byte(":"))
+		for _, h := range headers {
+			if strings.EqualFold(string(bytes.TrimSpace(name)), h) {
+				return true
+			}
+		}
+		return false
+	}
+
+	result := make([]byte, 0, len(data)+128)
+	result = append(result, data[:lineEnd+2]...)
+	dropping := false
+	for _, line := range bytes.SplitAfter(data[lineEnd+2:end+2], []byte("\r\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' { // not a continuation of the last line
+			dropping = isSet(line)
+		}
+		if !dropping {
+			result = append(result, line...)
+		}
+	}
+	for _, h := range headers {
+		value := ""
+		switch http.CanonicalHeaderKey(h) {
+		case "X-Forwarded-For", "X-Real-Ip":
+			value = client
+		case "X-Forwarded-Proto":
+			value = proto
+		}
+		if value != "" {
+			result = append(result, h+": "+value+"\r\n"...)
+		}
+	}
+	result = append(result, "\r\n"...)
+	return append(result, data[end+4:]...), true
+}
+
+// ForwardRequest writes the first payload of reader to writer, with headers set as
+// SetForwardedHeaders does. It returns false if the headers couldn't be set.
+func ForwardRequest(reader buf.Reader, writer buf.Writer, headers []string, client, proto string) (bool, error) {
+	mb, err := reader.ReadMultiBuffer()
+	if err != nil {
+		return false, err
+	}
+	data := make([]byte, mb.Len())
+	mb.Copy(data)
+	buf.ReleaseMulti(mb)
+	data, ok := SetForwardedHeaders(data, headers, client, proto)
+	return ok, writer.WriteMultiBuffer(buf.MergeBytes(nil, data))
+}
+
 // RemoveHopByHopHeaders removes hop by hop headers in http header list.
 func RemoveHopByHopHeaders(header http.Header) {
 	// Strip hop-by-hop header based on RFC:
@@ -115,3 +115,24 @@ func TestParseHost(t *testing.T) {
 		}
 	}
 }
+
+func TestSetForwardedHeaders(t *testing.T) {
+	request := "GET / HTTP/1.1\r\nHost: example.com\r\nX-Forwarded-For: 1.1.1.1,\r\n 2.2.2.2\r\nx-real-ip: 3.3.3.3\r\n\r\nbody"
+	result, ok := SetForwardedHeaders([]byte(request), ForwardedHeaders, "192.0.2.1", "https")
+	if !ok {
+		t.Fatal("expected the headers to be set")
+	}
+	expected := "GET / HTTP/1.1\r\nHost: example.com\r\nX-Forwarded-For: 192.0.2.1\r\nX-Real-IP: 192.0.2.1\r\nX-Forwarded-Proto: https\r\n\r\nbody"
+	if r := cmp.Diff(string(result), expected); r != "" {
+		t.Error(r)
+	}
+
+	for _, data := range []string{
+		"GET / HTTP/1.1\r\nHost: example.com\r\n",
+		"PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n",
+	} {
+		if result, ok := SetForwardedHeaders([]byte(data), ForwardedHeaders, "192.0.2.1", "https"); ok || string(result) != data {
+			t.Error("expected ", data, " to be left as is")
+		}
+	}
+}
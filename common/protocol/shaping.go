@@ -0,0 +1,75 @@
+package protocol
+
+import (
+	"io"
+	"time"
+
+	"github.com/xtls/xray-core/common/dice"
+	"github.com/xtls/xray-core/common/net"
+)
+
+// ShapingWriter splits the first writes to a connection into segments of random lengths, sent
+// after random intervals, so that the lengths of the packets of the handshake of a protocol don't
+// give it away.
+type ShapingWriter struct {
+	io.Writer
+	shaping *HandshakeShaping
+	count   uint32
+}
+
+// NewShapingWriter returns w shaped by shaping, or w itself if shaping splits no writes.
+func NewShapingWriter(w io.Writer, shaping *HandshakeShaping) io.Writer {
+	if shaping.GetPackets() == 0 {
+		return w
+	}
+	return &ShapingWriter{Writer: w, shaping: shaping}
+}
+
+func between(from, to uint32) int {
+	if to <= from {
+		return int(from)
+	}
+	return int(from) + dice.Roll(int(to-from)+1)
+}
+
+// Write implements io.Writer.
+func (w *ShapingWriter) Write(b []byte) (int, error) {
+	if w.count >= w.shaping.Packets {
+		return w.Writer.Write(b)
+	}
+	w.count++
+	written := 0
+	for written < len(b) {
+		if written > 0 {
+			time.Sleep(time.Duration(between(w.shaping.IntervalMin, w.shaping.IntervalMax)) * time.Millisecond)
+		}
+		end := written + max(between(w.shaping.LengthMin, w.shaping.LengthMax), 1)
+		if end > len(b) {
+			end = len(b)
+		}
+		n, err := w.Writer.Write(b[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+type shapedConn struct {
+	net.Conn
+	writer io.Writer
+}
+
+func (c *shapedConn) Write(b []byte) (int, error) {
+	return c.writer.Write(b)
+}
+
+// ShapeConn returns conn with its writes shaped by shaping, or conn itself if shaping splits no
+// writes.
+func ShapeConn(conn net.Conn, shaping *HandshakeShaping) net.Conn {
+	if shaping.GetPackets() == 0 {
+		return conn
+	}
+	return &shapedConn{Conn: conn, writer: NewShapingWriter(conn, shaping)}
+}
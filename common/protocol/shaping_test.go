@@ -0,0 +1,48 @@
+package protocol_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/xtls/xray-core/common/protocol"
+)
+
+type recordingWriter struct {
+	bytes.Buffer
+	writes []int
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.writes = append(w.writes, len(b))
+	return w.Buffer.Write(b)
+}
+
+func TestShapingWriter(t *testing.T) {
+	var plain bytes.Buffer
+	if NewShapingWriter(&plain, nil) != &plain || NewShapingWriter(&plain, &HandshakeShaping{PadHeader: true}) != &plain {
+		t.Error("expected writes without segments unshaped")
+	}
+
+	w := &recordingWriter{}
+	shaped := NewShapingWriter(w, &HandshakeShaping{Packets: 1, LengthMin: 3, LengthMax: 5})
+	payload := []byte("0123456789abcdef")
+	if n, err := shaped.Write(payload); n != len(payload) || err != nil {
+		t.Fatal(n, err)
+	}
+	if len(w.writes) < 4 {
+		t.Error("expected the first write split, got ", w.writes)
+	}
+	for _, n := range w.writes[:len(w.writes)-1] {
+		if n < 3 || n > 5 {
+			t.Error("unexpected segment length ", n)
+		}
+	}
+	segments := len(w.writes)
+	shaped.Write(payload)
+	if len(w.writes) != segments+1 {
+		t.Error("expected later writes unsplit, got ", w.writes[segments:])
+	}
+	if w.String() != string(payload)+string(payload) {
+		t.Error("unexpected content ", w.String())
+	}
+}
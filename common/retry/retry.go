@@ -1,6 +1,7 @@
 package retry // import "github.com/xtls/xray-core/common/retry"
 
 import (
+	"context"
 	"time"
 
 	"github.com/xtls/xray-core/common/errors"
@@ -12,6 +13,8 @@ var ErrRetryFailed = errors.New("all retry attempts failed")
 type Strategy interface {
 	// On performs a retry on a specific function, until it doesn't return any error.
 	On(func() error) error
+	// OnContext is On, which stops retrying once ctx is done.
+	OnContext(context.Context, func() error) error
 }
 
 type retryer struct {
@@ -21,6 +24,11 @@ type retryer struct {
 
 // On implements Strategy.On.
 func (r *retryer) On(method func() error) error {
+	return r.OnContext(context.Background(), method)
+}
+
+// OnContext implements Strategy.OnContext.
+func (r *retryer) OnContext(ctx context.Context, method func() error) error {
 	attempt := 0
 	accumulatedError := make([]error, 0, r.totalAttempt)
 	for attempt < r.totalAttempt {
@@ -33,7 +41,13 @@ func (r *retryer) On(method func() error) error {
 			accumulatedError = append(accumulatedError, err)
 		}
 		delay := r.nextDelay()
-		time.Sleep(time.Duration(delay) * time.Millisecond)
+		timer := time.NewTimer(time.Duration(delay) * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.New(accumulatedError).Base(ctx.Err())
+		case <-timer.C:
+		}
 		attempt++
 	}
 	return errors.New(accumulatedError).Base(ErrRetryFailed)
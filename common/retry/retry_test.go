@@ -1,6 +1,7 @@
 package retry_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -94,3 +95,25 @@ func TestExponentialBackoff(t *testing.T) {
 		t.Error("duration: ", v)
 	}
 }
+
+func TestRetryCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	startTime := time.Now()
+	called := 0
+	err := Timed(10, 100000).OnContext(ctx, func() error {
+		called++
+		cancel()
+		return errorTestOnly
+	})
+	duration := time.Since(startTime)
+
+	if errors.Cause(err) != context.Canceled {
+		t.Error("cause: ", err)
+	}
+	if called != 1 {
+		t.Error("called: ", called)
+	}
+	if duration > time.Second {
+		t.Error("duration: ", duration)
+	}
+}
@@ -25,6 +25,9 @@ const (
 	handlerSessionKey         ctx.SessionKey = 10
 	mitmAlpn11Key             ctx.SessionKey = 11
 	mitmServerNameKey         ctx.SessionKey = 12
+	bindKey                   ctx.SessionKey = 13
+	localBypassKey            ctx.SessionKey = 14
+	connectKey                ctx.SessionKey = 15
 )
 
 func ContextWithInbound(ctx context.Context, inbound *Inbound) context.Context {
@@ -186,3 +189,44 @@ func MitmServerNameFromContext(ctx context.Context) string {
 	}
 	return ""
 }
+
+// ContextWithBind marks the request in ctx as a SOCKS BIND request.
+func ContextWithBind(ctx context.Context, bind *Bind) context.Context {
+	return context.WithValue(ctx, bindKey, bind)
+}
+
+// BindFromContext returns the BIND request in ctx, or nil for ordinary requests.
+func BindFromContext(ctx context.Context) *Bind {
+	if bind, ok := ctx.Value(bindKey).(*Bind); ok {
+		return bind
+	}
+	return nil
+}
+
+// ContextWithConnect makes the outbound report to connect whether it connected the request in ctx.
+func ContextWithConnect(ctx context.Context, connect *Connect) context.Context {
+	return context.WithValue(ctx, connectKey, connect)
+}
+
+// ConnectFromContext returns the Connect the request in ctx is reported to, or nil if no inbound
+// waits for it.
+func ConnectFromContext(ctx context.Context) *Connect {
+	if connect, ok := ctx.Value(connectKey).(*Connect); ok {
+		return connect
+	}
+	return nil
+}
+
+// ContextWithLocalBypass marks the connections of ctx as allowed to reach the services of this
+// host directly, bypassing routing and outbounds.
+func ContextWithLocalBypass(ctx context.Context, bypass bool) context.Context {
+	return context.WithValue(ctx, localBypassKey, bypass)
+}
+
+// LocalBypassFromContext tells whether the connection of ctx may reach local services directly.
+func LocalBypassFromContext(ctx context.Context) bool {
+	if val, ok := ctx.Value(localBypassKey).(bool); ok {
+		return val
+	}
+	return false
+}
@@ -5,7 +5,10 @@ import (
 	"context"
 	"math/rand"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/xtls/xray-core/common/buf"
 	c "github.com/xtls/xray-core/common/ctx"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
@@ -71,6 +74,75 @@ type Outbound struct {
 	// CanSpliceCopy is a property for this connection
 	// 1 = can, 2 = after processing protocol info should be able to, 3 = cannot
 	CanSpliceCopy int
+	// Shaped tells that the dispatcher limits, counts or mirrors the traffic on the link, which
+	// splicing would bypass.
+	Shaped bool
+	// uplinkHandoff holds the *Handoff offered by the outbound, read by the inbound as it copies
+	// the request.
+	uplinkHandoff atomic.Value
+}
+
+// OfferUplinkHandoff is called by the outbound, once its connection is up, to let the inbound
+// splice the rest of the request into it.
+func (o *Outbound) OfferUplinkHandoff(h *Handoff) {
+	o.uplinkHandoff.Store(h)
+}
+
+// UplinkHandoff returns the handoff offered by the outbound, or nil.
+func (o *Outbound) UplinkHandoff() *Handoff {
+	h, _ := o.uplinkHandoff.Load().(*Handoff)
+	return h
+}
+
+// Handoff hands the copying of a request over from the link between the inbound and outbound
+// to the inbound, which copies the rest right into the connection of the outbound.
+type Handoff struct {
+	conn      net.Conn
+	reader    buf.Reader
+	handedOff atomic.Bool
+	drained   chan struct{}
+}
+
+// NewHandoff returns a handoff of the rest of the request into conn, the connection of the
+// outbound, which read the request from reader of its link.
+func NewHandoff(conn net.Conn, reader buf.Reader) *Handoff {
+	return &Handoff{conn: conn, reader: reader, drained: make(chan struct{})}
+}
+
+// Conn returns the connection of the outbound the rest of the request goes into.
+func (h *Handoff) Conn() net.Conn {
+	return h.conn
+}
+
+// Reader returns the reader of the link the outbound read the request from, through which the
+// dispatcher counts the request.
+func (h *Handoff) Reader() buf.Reader {
+	return h.reader
+}
+
+// HandOff is called by the inbound before it closes the link to copy the rest itself.
+func (h *Handoff) HandOff() {
+	h.handedOff.Store(true)
+}
+
+// HandedOff tells the outbound whether the inbound copies the rest of the request.
+func (h *Handoff) HandedOff() bool {
+	return h.handedOff.Load()
+}
+
+// Drain is called by the outbound once it has written all the request it read from the link.
+func (h *Handoff) Drain() {
+	close(h.drained)
+}
+
+// Drained is closed once the outbound has written all the request it read from the link.
+func (h *Handoff) Drained() <-chan struct{} {
+	return h.drained
+}
+
+// DomainExcluder decides whether a sniffed domain must not override the destination.
+type DomainExcluder interface {
+	Excluded(protocol string, domain string) bool
 }
 
 // SniffingRequest controls the behavior of content sniffing.
@@ -80,6 +152,54 @@ type SniffingRequest struct {
 	Enabled                        bool
 	MetadataOnly                   bool
 	RouteOnly                      bool
+	// DomainExcluder, if set, is consulted in addition to ExcludeForDomain.
+	DomainExcluder DomainExcluder
+	// Timeout is the longest wait for content to sniff, and MaxBytes the most bytes waited for,
+	// 0 for the defaults.
+	Timeout  time.Duration
+	MaxBytes int32
+}
+
+// Bind is a SOCKS BIND request. Instead of dialing the target, the outbound accepts one
+// incoming connection, expected from the target, and relays it.
+type Bind struct {
+	// Listening is called with the address the outbound accepts the connection on.
+	Listening func(net.Destination) error
+	// Accepted is called with the address of the accepted peer, before relaying starts.
+	Accepted func(net.Destination) error
+}
+
+// Connect is the outcome of connecting a request, which the outbound reports to an inbound
+// waiting on it before replying to the client, such as a SOCKS server replying to CONNECT.
+type Connect struct {
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+// NewConnect returns a Connect not reported yet.
+func NewConnect() *Connect {
+	return &Connect{done: make(chan struct{})}
+}
+
+// Report is called with nil once the outbound is connected, or with the error it failed with.
+// Only the first report counts.
+func (c *Connect) Report(err error) {
+	c.once.Do(func() {
+		c.err = err
+		close(c.done)
+	})
+}
+
+// Wait returns the reported error once the outcome is reported, or the error of ctx if it's
+// done first.
+func (c *Connect) Wait(ctx context.Context) error {
+	select {
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // Content is the metadata of the connection content.
@@ -93,6 +213,10 @@ type Content struct {
 
 	SkipDNSResolve bool
 
+	// OwnTransport marks the packets of the transports of this instance looping back through its
+	// inbounds. They're opaque, and not sniffed.
+	OwnTransport bool
+
 	mu sync.Mutex
 
 	isLocked bool
@@ -3,6 +3,7 @@ package signal
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/xtls/xray-core/common"
@@ -18,9 +19,13 @@ type ActivityTimer struct {
 	updated   chan struct{}
 	checkTask *task.Periodic
 	onTimeout func()
+	// lastUpdate is the Unix time in nanoseconds of the last activity.
+	lastUpdate atomic.Int64
+	timeout    atomic.Int64
 }
 
 func (t *ActivityTimer) Update() {
+	t.lastUpdate.Store(time.Now().UnixNano())
 	select {
 	case t.updated <- struct{}{}:
 	default:
@@ -40,6 +45,7 @@ func (t *ActivityTimer) finish() {
 	t.Lock()
 	defer t.Unlock()
 
+	t.timeout.Store(0)
 	if t.onTimeout != nil {
 		t.onTimeout()
 		t.onTimeout = nil
@@ -50,7 +56,18 @@ func (t *ActivityTimer) finish() {
 	}
 }
 
+// Idle returns the time since the last activity.
+func (t *ActivityTimer) Idle() time.Duration {
+	return time.Since(time.Unix(0, t.lastUpdate.Load()))
+}
+
+// Timeout returns the current inactivity timeout, or 0 once it has expired.
+func (t *ActivityTimer) Timeout() time.Duration {
+	return time.Duration(t.timeout.Load())
+}
+
 func (t *ActivityTimer) SetTimeout(timeout time.Duration) {
+	t.timeout.Store(int64(timeout))
 	if timeout == 0 {
 		t.finish()
 		return
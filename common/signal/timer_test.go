@@ -58,3 +58,23 @@ func TestActivityTimerZeroTimeout(t *testing.T) {
 	}
 	runtime.KeepAlive(timer)
 }
+
+func TestActivityTimerIdle(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	timer := CancelAfterInactivity(ctx, cancel, time.Second*10)
+	if timer.Timeout() != time.Second*10 {
+		t.Error("unexpected timeout: ", timer.Timeout())
+	}
+	time.Sleep(time.Millisecond * 200)
+	if idle := timer.Idle(); idle < time.Millisecond*200 {
+		t.Error("expected idle for at least 200ms, but got ", idle)
+	}
+	timer.Update()
+	if idle := timer.Idle(); idle >= time.Millisecond*200 {
+		t.Error("expected idle reset by update, but got ", idle)
+	}
+	timer.SetTimeout(0)
+	if timer.Timeout() != 0 {
+		t.Error("expected no timeout once expired, but got ", timer.Timeout())
+	}
+}
@@ -0,0 +1,195 @@
+// Package site serves a static website in process, to be used as a fallback
+// destination by inbounds that support fallbacks.
+package site
+
+import (
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// indexTemplate is the name of the optional template for the index page under the site root.
+const indexTemplate = "index.tmpl"
+
+const defaultIndex = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Host}}</title>
+</head>
+<body>
+<h1>Welcome to {{.Host}}</h1>
+<p>This site is under construction. Please check back later.</p>
+<hr>
+<p>&copy; {{.Year}} {{.Host}}</p>
+</body>
+</html>
+`
+
+var (
+	access  sync.Mutex
+	servers = make(map[string]*pipeListener)
+)
+
+// Dial returns a connection to the site serving the files under root. The site is
+// started on first use and shared by all later connections to the same root.
+func Dial(root string) (net.Conn, error) {
+	access.Lock()
+	l, found := servers[root]
+	if !found {
+		h, err := newHandler(root)
+		if err != nil {
+			access.Unlock()
+			return nil, err
+		}
+		l = newPipeListener()
+		server := &http.Server{
+			Handler:           h,
+			ReadHeaderTimeout: 30 * time.Second,
+		}
+		go server.Serve(l)
+		servers[root] = l
+	}
+	access.Unlock()
+
+	client, conn := net.Pipe()
+	if err := l.push(conn); err != nil {
+		client.Close()
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+type handler struct {
+	root  string
+	files http.Handler
+	index *template.Template
+}
+
+func newHandler(root string) (*handler, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, errors.New("failed to open site root ", root).Base(err)
+	}
+	if !info.IsDir() {
+		return nil, errors.New("site root is not a directory: ", root)
+	}
+
+	index, err := template.ParseFiles(filepath.Join(root, indexTemplate))
+	if os.IsNotExist(err) {
+		index, err = template.New(indexTemplate).Parse(defaultIndex)
+	}
+	if err != nil {
+		return nil, errors.New("failed to parse index template").Base(err)
+	}
+
+	return &handler{
+		root:  root,
+		files: http.FileServer(http.Dir(root)),
+		index: index,
+	}, nil
+}
+
+// ServeHTTP implements http.Handler. Directories are never listed: a directory is served
+// by its index.html, or by the index template for the site root, or not at all.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := path.Clean("/" + r.URL.Path)
+	if path.Base(name) == indexTemplate {
+		http.NotFound(w, r)
+		return
+	}
+	full := filepath.Join(h.root, filepath.FromSlash(name))
+	info, err := os.Stat(full)
+	if err != nil || !info.IsDir() {
+		h.files.ServeHTTP(w, r)
+		return
+	}
+
+	if f, err := os.Open(filepath.Join(full, "index.html")); err == nil {
+		defer f.Close()
+		if fi, err := f.Stat(); err == nil && !fi.IsDir() {
+			http.ServeContent(w, r, "index.html", fi.ModTime(), f)
+			return
+		}
+	}
+	if name != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return
+	}
+	h.index.Execute(w, struct {
+		Host string
+		Year int
+	}{
+		Host: r.Host,
+		Year: time.Now().Year(),
+	})
+}
+
+// pipeListener is a net.Listener accepting the server ends of pipes created by Dial.
+type pipeListener struct {
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+func (l *pipeListener) push(conn net.Conn) error {
+	select {
+	case l.conns <- conn:
+		return nil
+	case <-l.done:
+		return io.ErrClosedPipe
+	}
+}
+
+// Accept implements net.Listener.
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, io.ErrClosedPipe
+	}
+}
+
+// Close implements net.Listener.
+func (l *pipeListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+// Addr implements net.Listener.
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
@@ -0,0 +1,63 @@
+package site_test
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	. "github.com/xtls/xray-core/common/site"
+)
+
+func get(t *testing.T, root string, path string) (int, string) {
+	conn, err := Dial(root)
+	common.Must(err)
+	defer conn.Close()
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com"+path, nil)
+	common.Must(err)
+	go req.Write(conn)
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	common.Must(err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	common.Must(err)
+	return resp.StatusCode, string(body)
+}
+
+func TestSite(t *testing.T) {
+	root := t.TempDir()
+	common.Must(os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello"), 0o644))
+	common.Must(os.Mkdir(filepath.Join(root, "sub"), 0o755))
+	common.Must(os.WriteFile(filepath.Join(root, "sub", "b.txt"), []byte("world"), 0o644))
+
+	if code, body := get(t, root, "/"); code != http.StatusOK || !strings.Contains(body, "example.com") {
+		t.Error("unexpected index: ", code, " ", body)
+	}
+	if code, body := get(t, root, "/a.txt"); code != http.StatusOK || body != "hello" {
+		t.Error("unexpected file: ", code, " ", body)
+	}
+	if code, _ := get(t, root, "/sub/"); code != http.StatusNotFound {
+		t.Error("directory listed: ", code)
+	}
+	if code, _ := get(t, root, "/missing"); code != http.StatusNotFound {
+		t.Error("unexpected status: ", code)
+	}
+}
+
+func TestSiteIndexTemplate(t *testing.T) {
+	root := t.TempDir()
+	common.Must(os.WriteFile(filepath.Join(root, "index.tmpl"), []byte("custom {{.Host}}"), 0o644))
+
+	if code, body := get(t, root, "/"); code != http.StatusOK || body != "custom example.com" {
+		t.Error("unexpected index: ", code, " ", body)
+	}
+	if code, _ := get(t, root, "/index.tmpl"); code != http.StatusNotFound {
+		t.Error("template served: ", code)
+	}
+}
@@ -0,0 +1,149 @@
+// Package status summarizes what a running instance is set up with, for the startup banner and
+// for programs wrapping Xray.
+package status
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/common/geodata"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
+)
+
+// Summary is the status of an instance.
+type Summary struct {
+	Version   string     `json:"version"`
+	Inbounds  []Inbound  `json:"inbounds"`
+	Outbounds []Outbound `json:"outbounds"`
+	Balancers []string   `json:"balancers,omitempty"`
+	// Rules is the number of routing rules.
+	Rules int `json:"rules"`
+	// DNS are the name servers queried.
+	DNS     []string  `json:"dns"`
+	Geodata []GeoFile `json:"geodata"`
+	Time    time.Time `json:"time"`
+}
+
+// Inbound is an address an inbound listens on.
+type Inbound struct {
+	Tag     string `json:"tag"`
+	Network string `json:"network"`
+	Address string `json:"address"`
+	Port    uint16 `json:"port"`
+}
+
+// Outbound is an outbound and its protocol.
+type Outbound struct {
+	Tag      string `json:"tag"`
+	Protocol string `json:"protocol"`
+}
+
+// GeoFile is a geo data file rules may refer to.
+type GeoFile struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	SHA256   string    `json:"sha256"`
+}
+
+// OutboundProtocol returns the protocol of handler, like "freedom" or "vless", or "unknown" if
+// it's not a handler of proxyman.
+func OutboundProtocol(handler outbound.Handler) string {
+	getter, ok := handler.(interface{ GetOutbound() proxy.Outbound })
+	if !ok {
+		return "unknown"
+	}
+	t := reflect.TypeOf(getter.GetOutbound())
+	if t == nil {
+		return "unknown"
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	pkg := t.PkgPath()
+	return pkg[strings.LastIndex(pkg, "/")+1:]
+}
+
+// Collect returns the Summary of instance. Parts the features of instance don't tell are left
+// empty.
+func Collect(instance *core.Instance) *Summary {
+	s := &Summary{
+		Version: core.Version(),
+		Time:    time.Now(),
+	}
+	if ihm, ok := instance.GetFeature(inbound.ManagerType()).(inbound.AddressReporter); ok {
+		for _, a := range ihm.GetListenAddresses("") {
+			s.Inbounds = append(s.Inbounds, Inbound{
+				Tag:     a.Tag,
+				Network: a.Address.Network.SystemString(),
+				Address: a.Address.Address.String(),
+				Port:    uint16(a.Address.Port),
+			})
+		}
+	}
+	if ohm, ok := instance.GetFeature(outbound.ManagerType()).(outbound.Manager); ok {
+		if selector, ok := ohm.(outbound.HandlerSelector); ok {
+			for _, tag := range selector.Select([]string{""}) {
+				if handler := ohm.GetHandler(tag); handler != nil {
+					s.Outbounds = append(s.Outbounds, Outbound{Tag: tag, Protocol: OutboundProtocol(handler)})
+				}
+			}
+		}
+	}
+	router := instance.GetFeature(routing.RouterType())
+	if counter, ok := router.(routing.RuleCounter); ok {
+		s.Rules = counter.RuleCount()
+	}
+	if lister, ok := router.(routing.BalancerLister); ok {
+		s.Balancers = lister.ListBalancers()
+	}
+	if lister, ok := instance.GetFeature(dns.ClientType()).(dns.NameServerLister); ok {
+		s.DNS = lister.GetNameServers()
+	}
+	for _, name := range geodata.DefaultFiles {
+		if info, err := geodata.Stat(name); err == nil {
+			s.Geodata = append(s.Geodata, GeoFile{
+				Name:     info.Name,
+				Size:     info.Size,
+				Modified: info.Modified,
+				SHA256:   info.SHA256,
+			})
+		}
+	}
+	return s
+}
+
+// String returns the summary as the lines of the startup banner.
+func (s *Summary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Xray %s is running\n", s.Version)
+	inbounds := append([]Inbound(nil), s.Inbounds...)
+	sort.SliceStable(inbounds, func(i, j int) bool {
+		return inbounds[i].Tag < inbounds[j].Tag
+	})
+	for _, in := range inbounds {
+		fmt.Fprintf(&b, "  inbound  %-16s %s %s:%d\n", in.Tag, in.Network, in.Address, in.Port)
+	}
+	for _, out := range s.Outbounds {
+		fmt.Fprintf(&b, "  outbound %-16s %s\n", out.Tag, out.Protocol)
+	}
+	for _, tag := range s.Balancers {
+		fmt.Fprintf(&b, "  balancer %s\n", tag)
+	}
+	fmt.Fprintf(&b, "  routing  %d rules\n", s.Rules)
+	if len(s.DNS) > 0 {
+		fmt.Fprintf(&b, "  dns      %s\n", strings.Join(s.DNS, ", "))
+	}
+	for _, f := range s.Geodata {
+		fmt.Fprintf(&b, "  geodata  %-16s %s, sha256 %.12s\n", f.Name, f.Modified.Format("2006-01-02 15:04"), f.SHA256)
+	}
+	return b.String()
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// dirStore is a Store keeping each bucket in a directory, and each value in a file named after
+// its key.
+type dirStore struct {
+	dir string
+}
+
+// OpenDir opens the store of files in dir, the default backend.
+func OpenDir(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errors.New("failed to create state directory ", dir).Base(err)
+	}
+	return &dirStore{dir: dir}, nil
+}
+
+// path returns the file of key in bucket. Buckets and keys are escaped, so that the files stay
+// in the state directory whatever they are.
+func (s *dirStore) path(bucket, key string) string {
+	return filepath.Join(s.dir, escapeKey(bucket), escapeKey(key))
+}
+
+func escapeKey(key string) string {
+	key = url.PathEscape(key)
+	if strings.HasPrefix(key, ".") {
+		// Hidden files are those being written.
+		key = "%2E" + key[1:]
+	}
+	return key
+}
+
+// Get implements Store.
+func (s *dirStore) Get(bucket, key string) ([]byte, error) {
+	b, err := os.ReadFile(s.path(bucket, key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return b, err
+}
+
+// Put implements Store.
+func (s *dirStore) Put(bucket, key string, value []byte) error {
+	return WriteFile(s.path(bucket, key), value, 0o600)
+}
+
+// Delete implements Store.
+func (s *dirStore) Delete(bucket, key string) error {
+	if err := os.Remove(s.path(bucket, key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Keys implements Store.
+func (s *dirStore) Keys(bucket string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, escapeKey(bucket)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if key, err := url.PathUnescape(entry.Name()); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
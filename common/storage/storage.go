@@ -0,0 +1,146 @@
+// Package storage keeps runtime state across restarts, such as the usage of quotas, fake IPs,
+// balancer overrides and subscription caches, in a store under the state directory.
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/platform"
+)
+
+// ErrNotFound is returned for values not in a store.
+var ErrNotFound = errors.New("not found")
+
+// Store keeps values by keys within buckets, which group the state of a feature.
+type Store interface {
+	// Get returns the value of key in bucket, or ErrNotFound.
+	Get(bucket, key string) ([]byte, error)
+	// Put sets the value of key in bucket, replacing the previous one at once.
+	Put(bucket, key string, value []byte) error
+	// Delete removes key from bucket, if it's there.
+	Delete(bucket, key string) error
+	// Keys returns the keys in bucket.
+	Keys(bucket string) ([]string, error)
+}
+
+// Backend opens a store at location, such as a directory or a database file.
+type Backend func(location string) (Store, error)
+
+var (
+	backendAccess sync.Mutex
+	backends      = map[string]Backend{"file": OpenDir}
+
+	defaultAccess   sync.Mutex
+	defaultLocation string
+	defaultStore    Store
+)
+
+// RegisterBackend makes a backend, such as a database, available by name. The backend of the
+// default store is chosen by "xray.state.backend", "file" if unset.
+func RegisterBackend(name string, backend Backend) {
+	backendAccess.Lock()
+	defer backendAccess.Unlock()
+	backends[name] = backend
+}
+
+// Open opens the store of backend at location.
+func Open(backend, location string) (Store, error) {
+	backendAccess.Lock()
+	b, found := backends[backend]
+	backendAccess.Unlock()
+	if !found {
+		return nil, errors.New("unknown storage backend ", backend)
+	}
+	return b(location)
+}
+
+// Default returns the store in the state directory, or nil if there is none.
+func Default() Store {
+	dir := platform.GetStateDirectory()
+	if dir == "" {
+		return nil
+	}
+	backend := platform.NewEnvFlag(platform.StateBackend).GetValue(func() string { return "file" })
+	location := backend + ":" + dir
+
+	defaultAccess.Lock()
+	defer defaultAccess.Unlock()
+	if defaultLocation != location {
+		store, err := Open(backend, dir)
+		if err != nil {
+			errors.LogWarningInner(context.Background(), err, "failed to open the state in ", dir)
+			return nil
+		}
+		defaultLocation, defaultStore = location, store
+	}
+	return defaultStore
+}
+
+// Entry is where a value is kept: in a file of its own if Path is set, such as one configured
+// for it, or as Key in Bucket of the default store otherwise.
+type Entry struct {
+	Path   string
+	Bucket string
+	Key    string
+}
+
+// Load returns the value, or ErrNotFound if there is none, or nowhere to keep it.
+func (e Entry) Load() ([]byte, error) {
+	if e.Path != "" {
+		b, err := os.ReadFile(e.Path)
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return b, err
+	}
+	if store := Default(); store != nil {
+		return store.Get(e.Bucket, e.Key)
+	}
+	return nil, ErrNotFound
+}
+
+// Save replaces the value at once. It does nothing if there is nowhere to keep it.
+func (e Entry) Save(value []byte) error {
+	if e.Path != "" {
+		return WriteFile(e.Path, value, 0o600)
+	}
+	if store := Default(); store != nil {
+		return store.Put(e.Bucket, e.Key, value)
+	}
+	return nil
+}
+
+// Delete removes the value.
+func (e Entry) Delete() error {
+	if e.Path != "" {
+		if err := os.Remove(e.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if store := Default(); store != nil {
+		return store.Delete(e.Bucket, e.Key)
+	}
+	return nil
+}
+
+// WriteFile writes data to the file named path, creating its directory if needed, and replaces
+// the file at once, so that it's never found half written.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/platform"
+	"github.com/xtls/xray-core/common/storage"
+)
+
+func TestDirStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := storage.OpenDir(dir)
+	common.Must(err)
+
+	if _, err := store.Get("quota", "usage"); err != storage.ErrNotFound {
+		t.Fatal("expected ErrNotFound, got ", err)
+	}
+	for _, key := range []string{"usage", "../escape", ".hidden", "a/b"} {
+		common.Must(store.Put("quota", key, []byte(key)))
+	}
+	if b, err := store.Get("quota", "../escape"); err != nil || string(b) != "../escape" {
+		t.Error("got ", string(b), err)
+	}
+	keys, err := store.Keys("quota")
+	common.Must(err)
+	sort.Strings(keys)
+	if got, want := strings.Join(keys, " "), "../escape .hidden a/b usage"; got != want {
+		t.Error("keys: got ", got, ", want ", want)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(dir), "escape")); len(matches) != 0 {
+		t.Error("value written out of the state directory")
+	}
+
+	common.Must(store.Delete("quota", "usage"))
+	common.Must(store.Delete("quota", "usage"))
+	if _, err := store.Get("quota", "usage"); err != storage.ErrNotFound {
+		t.Error("expected ErrNotFound after delete, got ", err)
+	}
+	if keys, err := store.Keys("none"); err != nil || len(keys) != 0 {
+		t.Error("keys of missing bucket: ", keys, err)
+	}
+}
+
+func TestEntry(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(platform.StateLocation, "")
+	entry := storage.Entry{Bucket: "balancer", Key: "b"}
+	common.Must(entry.Save([]byte("x")))
+	if _, err := entry.Load(); err != storage.ErrNotFound {
+		t.Error("value kept without a state directory")
+	}
+
+	t.Setenv(platform.StateLocation, dir)
+	common.Must(entry.Save([]byte("x")))
+	if b, err := entry.Load(); err != nil || string(b) != "x" {
+		t.Error("got ", string(b), err)
+	}
+	common.Must(entry.Delete())
+	if _, err := entry.Load(); err != storage.ErrNotFound {
+		t.Error("expected ErrNotFound after delete, got ", err)
+	}
+
+	file := storage.Entry{Path: filepath.Join(dir, "sub", "usage.json"), Bucket: "quota", Key: "usage"}
+	common.Must(file.Save([]byte("y")))
+	if b, err := file.Load(); err != nil || string(b) != "y" {
+		t.Error("got ", string(b), err)
+	}
+	if _, err := (storage.Entry{Bucket: "quota", Key: "usage"}).Load(); err != storage.ErrNotFound {
+		t.Error("value of a file of its own kept in the store")
+	}
+}
@@ -2,6 +2,7 @@ package strmatcher
 
 import (
 	"container/list"
+	"unsafe"
 )
 
 const validCharCount = 53
@@ -28,6 +29,11 @@ type ACAutomaton struct {
 	count  int
 }
 
+// MemorySize estimates the memory used by ac, in bytes.
+func (ac *ACAutomaton) MemorySize() int {
+	return len(ac.trie)*int(unsafe.Sizeof([validCharCount]Edge{})) + 8*len(ac.fail) + 2*len(ac.exists)
+}
+
 func newNode() [validCharCount]Edge {
 	var s [validCharCount]Edge
 	for i := range s {
@@ -148,6 +148,19 @@ func (g *MphMatcherGroup) Build() {
 	}
 }
 
+// MemorySize estimates the memory used by g after Build, in bytes.
+func (g *MphMatcherGroup) MemorySize() int {
+	const stringHeader, regexSize = 16, 1024
+	size := 4*len(g.level0) + 4*len(g.level1) + stringHeader*len(g.rules)
+	for _, rule := range g.rules {
+		size += len(rule)
+	}
+	if g.ac != nil {
+		size += g.ac.MemorySize()
+	}
+	return size + regexSize*len(g.otherMatchers)
+}
+
 func nextPow2(v int) int {
 	if v <= 1 {
 		return 1
@@ -0,0 +1,233 @@
+package sysproxy
+
+import (
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+func run(name string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.New(name, " ", strings.Join(args, " "), ": ", strings.TrimSpace(stderr.String())).Base(err)
+	}
+	return string(out), nil
+}
+
+// parseNetworksetup parses the output of networksetup -getsocksfirewallproxy and -getwebproxy:
+//
+//	Enabled: Yes
+//	Server: 127.0.0.1
+//	Port: 1080
+func parseNetworksetup(out string) (enabled bool, host string, port uint16) {
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Enabled":
+			enabled = value == "Yes"
+		case "Server":
+			host = value
+		case "Port":
+			if p, err := strconv.ParseUint(value, 10, 16); err == nil {
+				port = uint16(p)
+			}
+		}
+	}
+	return
+}
+
+// parseNetworksetupAutoProxy parses the output of networksetup -getautoproxyurl:
+//
+//	URL: http://127.0.0.1:19801/proxy.pac
+//	Enabled: Yes
+func parseNetworksetupAutoProxy(out string) (enabled bool, url string) {
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Enabled":
+			enabled = value == "Yes"
+		case "URL":
+			if value != "(null)" {
+				url = value
+			}
+		}
+	}
+	return
+}
+
+// parseNetworksetupBypass parses the output of networksetup -getproxybypassdomains.
+func parseNetworksetupBypass(out string) []string {
+	var domains []string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "There aren't any") {
+			continue
+		}
+		domains = append(domains, line)
+	}
+	return domains
+}
+
+// parseNetworksetupDNS parses the output of networksetup -getdnsservers, which lists a server a
+// line, or tells there are none.
+func parseNetworksetupDNS(out string) []string {
+	var servers []string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "There aren't any") {
+			continue
+		}
+		servers = append(servers, line)
+	}
+	return servers
+}
+
+// parseRouteInterface parses the interface of the output of route -n get default:
+//
+//	   route to: default
+//	destination: default
+//	    gateway: 192.168.1.1
+//	  interface: en0
+func parseRouteInterface(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if key, value, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(key) == "interface" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// parseNetworkServiceOrder parses the output of networksetup -listnetworkserviceorder into the
+// services by their devices:
+//
+//	(1) Wi-Fi
+//	(Hardware Port: Wi-Fi, Device: en0)
+func parseNetworkServiceOrder(out string) map[string]string {
+	services := make(map[string]string)
+	service := ""
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "(Hardware Port:") {
+			_, device, ok := strings.Cut(line, "Device:")
+			device = strings.TrimSpace(strings.TrimSuffix(device, ")"))
+			if ok && device != "" && service != "" {
+				services[device] = service
+			}
+			service = ""
+			continue
+		}
+		if _, name, ok := strings.Cut(line, ") "); ok && strings.HasPrefix(line, "(") {
+			service = name
+		}
+	}
+	return services
+}
+
+// parseGSettingsString parses a string printed by gsettings get, e.g. 'manual'.
+func parseGSettingsString(out string) string {
+	return strings.Trim(strings.TrimSpace(out), "'")
+}
+
+// parseGSettingsList parses a string list printed by gsettings get, e.g. ['localhost', '127.0.0.0/8'].
+func parseGSettingsList(out string) []string {
+	out = strings.TrimSpace(out)
+	out = strings.TrimPrefix(out, "@as ")
+	out = strings.TrimSuffix(strings.TrimPrefix(out, "["), "]")
+	var list []string
+	for _, item := range strings.Split(out, ",") {
+		if item = parseGSettingsString(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// formatGSettingsList formats a string list for gsettings set.
+func formatGSettingsList(list []string) string {
+	quoted := make([]string, len(list))
+	for i, item := range list {
+		quoted[i] = "'" + strings.ReplaceAll(item, "'", "") + "'"
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// parseWindowsProxyServer parses the ProxyServer value of Internet Settings, which is either
+// host:port for all protocols or a list like http=host:port;socks=host:port.
+func parseWindowsProxyServer(value string) (t Type, host string, port uint16) {
+	t = HTTP
+	server := value
+	if strings.Contains(value, "=") {
+		server = ""
+		for _, entry := range strings.Split(value, ";") {
+			scheme, address, _ := strings.Cut(entry, "=")
+			switch strings.ToLower(strings.TrimSpace(scheme)) {
+			case "socks":
+				t, server = SOCKS, address
+			case "http":
+				if server == "" {
+					server = address
+				}
+			}
+			if t == SOCKS {
+				break
+			}
+		}
+	}
+	host, p, ok := cutLast(strings.TrimSpace(server), ":")
+	if !ok {
+		return t, host, 0
+	}
+	if n, err := strconv.ParseUint(p, 10, 16); err == nil {
+		port = uint16(n)
+	}
+	return
+}
+
+// parseKDEProxy parses a proxy of kioslaverc, written by KDE as "socks://host port", or as
+// "socks://host:port" by other tools.
+func parseKDEProxy(value string) (host string, port uint16) {
+	value = strings.TrimSpace(value)
+	if _, rest, ok := strings.Cut(value, "://"); ok {
+		value = rest
+	}
+	host, p, ok := strings.Cut(value, " ")
+	if !ok {
+		host, p, _ = cutLast(value, ":")
+	}
+	if n, err := strconv.ParseUint(strings.TrimSpace(p), 10, 16); err == nil {
+		port = uint16(n)
+	}
+	return strings.TrimSuffix(host, "/"), port
+}
+
+// parseKDEList parses a comma-separated list of kioslaverc.
+func parseKDEList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
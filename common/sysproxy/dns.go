@@ -0,0 +1,89 @@
+package sysproxy
+
+import (
+	"sync"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// ErrDNSUnsupported is returned on systems without a known way to set the system DNS.
+var ErrDNSUnsupported = errors.New("system DNS is not supported on this system")
+
+// dnsBackend reads and writes the DNS servers of a single system.
+type dnsBackend interface {
+	getDNS() ([]string, error)
+	// setDNS sets the DNS servers, or returns to those the system gets by DHCP if servers is empty.
+	setDNS(servers []string) error
+}
+
+// DNS drives the DNS servers of the system. Enable takes a snapshot of the servers the first time,
+// which Disable restores.
+type DNS struct {
+	sync.Mutex
+	backend  dnsBackend
+	state    State
+	snapshot *[]string
+}
+
+// NewDNS returns a DNS. device is the network service to configure on macOS, e.g. "Wi-Fi".
+func NewDNS(device string) *DNS {
+	return &DNS{backend: newDNSBackend(device)}
+}
+
+// DNSSupported returns whether the system DNS can be changed on this system.
+func DNSSupported() bool {
+	return newDNSBackend("") != nil
+}
+
+// State returns the current state.
+func (d *DNS) State() State {
+	d.Lock()
+	defer d.Unlock()
+	return d.state
+}
+
+// Enable sets the DNS servers of the system to servers.
+func (d *DNS) Enable(servers []string) error {
+	if d.backend == nil {
+		return ErrDNSUnsupported
+	}
+	d.Lock()
+	defer d.Unlock()
+	if d.snapshot == nil {
+		current, err := d.backend.getDNS()
+		if err != nil {
+			return errors.New("failed to read system DNS").Base(err)
+		}
+		d.snapshot = &current
+	}
+	if err := d.backend.setDNS(servers); err != nil {
+		d.state = Failed
+		return errors.New("failed to set system DNS").Base(err)
+	}
+	d.state = Enabled
+	return nil
+}
+
+// Disable restores the DNS servers from before Enable. Disable does nothing if the DNS is
+// Disabled.
+func (d *DNS) Disable() error {
+	if d.backend == nil {
+		return ErrDNSUnsupported
+	}
+	d.Lock()
+	defer d.Unlock()
+	if d.state == Disabled {
+		return nil
+	}
+	var restore []string
+	if d.snapshot != nil {
+		restore = *d.snapshot
+	}
+	if err := d.backend.setDNS(restore); err != nil {
+		d.state = Failed
+		return errors.New("failed to restore system DNS").Base(err)
+	}
+	d.snapshot = nil
+	d.state = Disabled
+	return nil
+}
@@ -0,0 +1,48 @@
+//go:build darwin
+// +build darwin
+
+package sysproxy
+
+import (
+	"github.com/xtls/xray-core/common/errors"
+)
+
+func newDNSBackend(device string) dnsBackend {
+	return &networksetup{device: device}
+}
+
+func (n *networksetup) getDNS() ([]string, error) {
+	out, err := run("networksetup", "-getdnsservers", n.device)
+	if err != nil {
+		return nil, err
+	}
+	return parseNetworksetupDNS(out), nil
+}
+
+func (n *networksetup) setDNS(servers []string) error {
+	if len(servers) == 0 {
+		servers = []string{"Empty"}
+	}
+	_, err := run("networksetup", append([]string{"-setdnsservers", n.device}, servers...)...)
+	return err
+}
+
+// ActiveService returns the network service of the default route, e.g. "Wi-Fi".
+func ActiveService() (string, error) {
+	out, err := run("route", "-n", "get", "default")
+	if err != nil {
+		return "", err
+	}
+	device := parseRouteInterface(out)
+	if device == "" {
+		return "", errors.New("no default route")
+	}
+	out, err = run("networksetup", "-listnetworkserviceorder")
+	if err != nil {
+		return "", err
+	}
+	if service := parseNetworkServiceOrder(out)[device]; service != "" {
+		return service, nil
+	}
+	return "", errors.New("no network service for device ", device)
+}
@@ -0,0 +1,13 @@
+//go:build !darwin
+// +build !darwin
+
+package sysproxy
+
+func newDNSBackend(string) dnsBackend {
+	return nil
+}
+
+// ActiveService returns the network service of the default route, which is only known on macOS.
+func ActiveService() (string, error) {
+	return "", ErrDNSUnsupported
+}
@@ -0,0 +1,249 @@
+// Package sysproxy reads and changes the proxy settings of the operating system.
+//
+// It is supported on macOS (networksetup), Windows (Internet Settings in registry and WinINet),
+// and Linux desktops using GNOME settings (gsettings) or KDE Plasma (kioslaverc).
+package sysproxy
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// Type is the kind of proxy set to the system.
+type Type int
+
+const (
+	SOCKS Type = iota
+	HTTP
+	// PAC is automatic proxy configuration with the script at PACURL.
+	PAC
+)
+
+func (t Type) String() string {
+	switch t {
+	case HTTP:
+		return "http"
+	case PAC:
+		return "pac"
+	}
+	return "socks"
+}
+
+// Settings are the system proxy settings.
+type Settings struct {
+	Enabled bool
+	Type    Type
+	Host    string
+	Port    uint16
+	// PACURL is the URL of the proxy auto-config script, for type PAC.
+	PACURL string
+	// Bypass lists hosts connected directly, in the syntax of the system.
+	Bypass []string
+}
+
+// Address returns Host:Port.
+func (s Settings) Address() string {
+	return s.Host + ":" + strconv.Itoa(int(s.Port))
+}
+
+// State is the state of a Proxy.
+type State int
+
+const (
+	// Disabled means the Proxy has not changed system settings, or has restored them.
+	Disabled State = iota
+	// Enabled means the Proxy has applied its settings.
+	Enabled
+	// Overridden means the system settings were changed by someone else while Enabled.
+	Overridden
+	// Failed means the last Enable or Disable failed; system settings are unknown.
+	Failed
+)
+
+func (s State) String() string {
+	switch s {
+	case Disabled:
+		return "disabled"
+	case Enabled:
+		return "enabled"
+	case Overridden:
+		return "overridden"
+	case Failed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrUnsupported is returned on systems without a known way to set the system proxy.
+var ErrUnsupported = errors.New("system proxy is not supported on this system")
+
+// backend reads and writes the settings of a single system.
+type backend interface {
+	get() (Settings, error)
+	set(Settings) error
+}
+
+// Proxy drives the system proxy. Enable takes a snapshot of the system settings the first time,
+// which Disable restores, so that settings made by the user before are kept.
+type Proxy struct {
+	sync.Mutex
+	backend  backend
+	state    State
+	applied  Settings
+	snapshot *Settings
+}
+
+// New returns a Proxy. device is the network service to configure on macOS, e.g. "Wi-Fi",
+// and is ignored on other systems.
+func New(device string) *Proxy {
+	return &Proxy{backend: newBackend(device)}
+}
+
+// Supported returns whether the system proxy can be changed on this system.
+func Supported() bool {
+	return newBackend("") != nil
+}
+
+// State returns the current state.
+func (p *Proxy) State() State {
+	p.Lock()
+	defer p.Unlock()
+	return p.state
+}
+
+// Detect returns the current system proxy settings.
+func (p *Proxy) Detect() (Settings, error) {
+	if p.backend == nil {
+		return Settings{}, ErrUnsupported
+	}
+	return p.backend.get()
+}
+
+// Snapshot returns the current system proxy settings, to be passed to Restore later.
+func (p *Proxy) Snapshot() (Settings, error) {
+	return p.Detect()
+}
+
+// Restore applies settings returned by Snapshot.
+func (p *Proxy) Restore(s Settings) error {
+	if p.backend == nil {
+		return ErrUnsupported
+	}
+	p.Lock()
+	defer p.Unlock()
+	if err := p.backend.set(s); err != nil {
+		p.state = Failed
+		return errors.New("failed to restore system proxy").Base(err)
+	}
+	p.state = Disabled
+	p.snapshot = nil
+	return nil
+}
+
+// Enable sets the system proxy to s. s.Enabled is implied.
+func (p *Proxy) Enable(s Settings) error {
+	if p.backend == nil {
+		return ErrUnsupported
+	}
+	s.Enabled = true
+	p.Lock()
+	defer p.Unlock()
+	if p.snapshot == nil {
+		current, err := p.backend.get()
+		if err != nil {
+			return errors.New("failed to read system proxy").Base(err)
+		}
+		p.snapshot = &current
+	}
+	if err := p.backend.set(s); err != nil {
+		p.state = Failed
+		return errors.New("failed to enable system proxy").Base(err)
+	}
+	p.applied = s
+	p.state = Enabled
+	return nil
+}
+
+// Disable restores the settings from before Enable. If there is nothing to restore,
+// the system proxy is turned off. Disable does nothing if the Proxy is Disabled; use Restore
+// to change system settings regardless.
+func (p *Proxy) Disable() error {
+	if p.backend == nil {
+		return ErrUnsupported
+	}
+	p.Lock()
+	defer p.Unlock()
+	if p.state == Disabled {
+		return nil
+	}
+	restore := Settings{Type: p.applied.Type}
+	if p.snapshot != nil {
+		restore = *p.snapshot
+	}
+	if err := p.backend.set(restore); err != nil {
+		p.state = Failed
+		return errors.New("failed to disable system proxy").Base(err)
+	}
+	p.snapshot = nil
+	p.state = Disabled
+	return nil
+}
+
+// Watch polls the system settings every interval until ctx is done, and calls onChange when they
+// differ from the last seen. While Enabled, a change moves the Proxy to Overridden, and back to
+// Enabled if the applied settings come back.
+func (p *Proxy) Watch(ctx context.Context, interval time.Duration, onChange func(Settings, State)) error {
+	if p.backend == nil {
+		return ErrUnsupported
+	}
+	last, err := p.backend.get()
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		current, err := p.backend.get()
+		if err != nil {
+			errors.LogInfoInner(ctx, err, "failed to read system proxy")
+			continue
+		}
+		if reflect.DeepEqual(current, last) {
+			continue
+		}
+		last = current
+		onChange(current, p.observe(current))
+	}
+}
+
+func (p *Proxy) observe(current Settings) State {
+	p.Lock()
+	defer p.Unlock()
+	switch p.state {
+	case Enabled, Overridden:
+		if sameProxy(current, p.applied) {
+			p.state = Enabled
+		} else {
+			p.state = Overridden
+		}
+	}
+	return p.state
+}
+
+func sameProxy(a, b Settings) bool {
+	if a.Enabled && b.Enabled && a.Type == PAC && b.Type == PAC {
+		return a.PACURL == b.PACURL
+	}
+	return a.Enabled == b.Enabled && a.Type == b.Type && a.Host == b.Host && a.Port == b.Port
+}
@@ -0,0 +1,95 @@
+//go:build darwin
+// +build darwin
+
+package sysproxy
+
+import (
+	"strconv"
+)
+
+type networksetup struct {
+	device string
+}
+
+func newBackend(device string) backend {
+	return &networksetup{device: device}
+}
+
+func (n *networksetup) get() (Settings, error) {
+	var s Settings
+	out, err := run("networksetup", "-getsocksfirewallproxy", n.device)
+	if err != nil {
+		return s, err
+	}
+	s.Enabled, s.Host, s.Port = parseNetworksetup(out)
+	if !s.Enabled {
+		out, err := run("networksetup", "-getwebproxy", n.device)
+		if err != nil {
+			return s, err
+		}
+		if enabled, host, port := parseNetworksetup(out); enabled {
+			s = Settings{Enabled: true, Type: HTTP, Host: host, Port: port}
+		}
+	}
+	if !s.Enabled {
+		out, err := run("networksetup", "-getautoproxyurl", n.device)
+		if err != nil {
+			return s, err
+		}
+		if enabled, url := parseNetworksetupAutoProxy(out); enabled {
+			s = Settings{Enabled: true, Type: PAC, PACURL: url}
+		}
+	}
+	out, err = run("networksetup", "-getproxybypassdomains", n.device)
+	if err != nil {
+		return s, err
+	}
+	s.Bypass = parseNetworksetupBypass(out)
+	return s, nil
+}
+
+func (n *networksetup) set(s Settings) error {
+	state := func(enabled bool) string {
+		if enabled {
+			return "on"
+		}
+		return "off"
+	}
+	socks := s.Enabled && s.Type == SOCKS
+	web := s.Enabled && s.Type == HTTP
+	auto := s.Enabled && s.Type == PAC
+	if socks {
+		if _, err := run("networksetup", "-setsocksfirewallproxy", n.device, s.Host, strconv.Itoa(int(s.Port))); err != nil {
+			return err
+		}
+	}
+	if web {
+		for _, option := range []string{"-setwebproxy", "-setsecurewebproxy"} {
+			if _, err := run("networksetup", option, n.device, s.Host, strconv.Itoa(int(s.Port))); err != nil {
+				return err
+			}
+		}
+	}
+	if auto {
+		if _, err := run("networksetup", "-setautoproxyurl", n.device, s.PACURL); err != nil {
+			return err
+		}
+	}
+	if _, err := run("networksetup", "-setautoproxystate", n.device, state(auto)); err != nil {
+		return err
+	}
+	if _, err := run("networksetup", "-setsocksfirewallproxystate", n.device, state(socks)); err != nil {
+		return err
+	}
+	for _, option := range []string{"-setwebproxystate", "-setsecurewebproxystate"} {
+		if _, err := run("networksetup", option, n.device, state(web)); err != nil {
+			return err
+		}
+	}
+	bypass := s.Bypass
+	if len(bypass) == 0 {
+		bypass = []string{"Empty"}
+	}
+	_, err := run("networksetup", append([]string{"-setproxybypassdomains", n.device}, bypass...)...)
+	return err
+}
@@ -0,0 +1,142 @@
+//go:build linux && !android
+// +build linux,!android
+
+package sysproxy
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	kdeFile  = "kioslaverc"
+	kdeGroup = "Proxy Settings"
+)
+
+// kde drives the proxy settings of KDE Plasma, kept in kioslaverc.
+type kde struct {
+	read  string
+	write string
+}
+
+func isKDE() bool {
+	return strings.Contains(strings.ToUpper(os.Getenv("XDG_CURRENT_DESKTOP")), "KDE")
+}
+
+func newKDE() backend {
+	for _, version := range []string{"6", "5"} {
+		read, err := exec.LookPath("kreadconfig" + version)
+		if err != nil {
+			continue
+		}
+		write, err := exec.LookPath("kwriteconfig" + version)
+		if err != nil {
+			continue
+		}
+		return kde{read: read, write: write}
+	}
+	return nil
+}
+
+func (k kde) readKey(key string) (string, error) {
+	out, err := run(k.read, "--file", kdeFile, "--group", kdeGroup, "--key", key)
+	return strings.TrimSpace(out), err
+}
+
+func (k kde) writeKey(key, value string) error {
+	_, err := run(k.write, "--file", kdeFile, "--group", kdeGroup, "--key", key, value)
+	return err
+}
+
+func (k kde) get() (Settings, error) {
+	var s Settings
+	mode, err := k.readKey("ProxyType")
+	if err != nil {
+		return s, err
+	}
+	bypass, err := k.readKey("NoProxyFor")
+	if err != nil {
+		return s, err
+	}
+	s.Bypass = parseKDEList(bypass)
+	// 2 is a proxy auto-config script.
+	if mode == "2" {
+		url, err := k.readKey("Proxy Config Script")
+		if err != nil {
+			return s, err
+		}
+		s.Enabled, s.Type, s.PACURL = true, PAC, url
+		return s, nil
+	}
+	// 1 is manually configured proxies.
+	if mode != "1" {
+		return s, nil
+	}
+	for _, t := range []Type{SOCKS, HTTP} {
+		value, err := k.readKey(t.String() + "Proxy")
+		if err != nil {
+			return s, err
+		}
+		host, port := parseKDEProxy(value)
+		if host == "" {
+			continue
+		}
+		s.Enabled, s.Type, s.Host, s.Port = true, t, host, port
+		break
+	}
+	return s, nil
+}
+
+func (k kde) set(s Settings) error {
+	if !s.Enabled {
+		if err := k.writeKey("ProxyType", "0"); err != nil {
+			return err
+		}
+		k.notify()
+		return nil
+	}
+	if s.Type == PAC {
+		if err := k.writeKey("Proxy Config Script", s.PACURL); err != nil {
+			return err
+		}
+		if err := k.writeKey("ProxyType", "2"); err != nil {
+			return err
+		}
+		k.notify()
+		return nil
+	}
+	keys := map[Type][]string{
+		SOCKS: {"socksProxy"},
+		HTTP:  {"httpProxy", "httpsProxy"},
+	}
+	for t, names := range keys {
+		for _, name := range names {
+			value := ""
+			if t == s.Type {
+				value = formatKDEProxy(t, s.Host, s.Port)
+			}
+			if err := k.writeKey(name, value); err != nil {
+				return err
+			}
+		}
+	}
+	if err := k.writeKey("NoProxyFor", strings.Join(s.Bypass, ",")); err != nil {
+		return err
+	}
+	if err := k.writeKey("ProxyType", "1"); err != nil {
+		return err
+	}
+	k.notify()
+	return nil
+}
+
+// notify tells running KDE applications to reload the proxy settings.
+func (kde) notify() {
+	run("dbus-send", "--type=signal", "/KIO/Scheduler", "org.kde.KIO.Scheduler.reparseSlaveConfiguration", "string:")
+}
+
+func formatKDEProxy(t Type, host string, port uint16) string {
+	return t.String() + "://" + host + " " + strconv.Itoa(int(port))
+}
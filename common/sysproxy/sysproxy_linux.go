@@ -0,0 +1,128 @@
+//go:build linux && !android
+// +build linux,!android
+
+package sysproxy
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+type gsettings struct{}
+
+// newBackend returns the backends of the desktop environments found. On KDE Plasma, both KDE
+// and GNOME settings are changed, as GTK applications read the latter.
+func newBackend(string) backend {
+	var backends backends
+	if isKDE() {
+		if k := newKDE(); k != nil {
+			backends = append(backends, k)
+		}
+	}
+	if _, err := exec.LookPath("gsettings"); err == nil {
+		backends = append(backends, gsettings{})
+	}
+	switch len(backends) {
+	case 0:
+		return nil
+	case 1:
+		return backends[0]
+	default:
+		return backends
+	}
+}
+
+// backends reads settings from the first backend, and writes them to all.
+type backends []backend
+
+func (b backends) get() (Settings, error) {
+	return b[0].get()
+}
+
+func (b backends) set(s Settings) error {
+	for _, backend := range b {
+		if err := backend.set(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (gsettings) get() (Settings, error) {
+	var s Settings
+	mode, err := run("gsettings", "get", "org.gnome.system.proxy", "mode")
+	if err != nil {
+		return s, err
+	}
+	ignore, err := run("gsettings", "get", "org.gnome.system.proxy", "ignore-hosts")
+	if err != nil {
+		return s, err
+	}
+	s.Bypass = parseGSettingsList(ignore)
+	if parseGSettingsString(mode) == "auto" {
+		url, err := run("gsettings", "get", "org.gnome.system.proxy", "autoconfig-url")
+		if err != nil {
+			return s, err
+		}
+		s.Enabled, s.Type, s.PACURL = true, PAC, parseGSettingsString(url)
+		return s, nil
+	}
+	if parseGSettingsString(mode) != "manual" {
+		return s, nil
+	}
+	for _, t := range []Type{SOCKS, HTTP} {
+		host, err := run("gsettings", "get", "org.gnome.system.proxy."+t.String(), "host")
+		if err != nil {
+			return s, err
+		}
+		if host = parseGSettingsString(host); host == "" {
+			continue
+		}
+		port, err := run("gsettings", "get", "org.gnome.system.proxy."+t.String(), "port")
+		if err != nil {
+			return s, err
+		}
+		p, _ := strconv.ParseUint(strings.TrimSpace(port), 10, 16)
+		s.Enabled, s.Type, s.Host, s.Port = true, t, host, uint16(p)
+		break
+	}
+	return s, nil
+}
+
+func (gsettings) set(s Settings) error {
+	if !s.Enabled {
+		_, err := run("gsettings", "set", "org.gnome.system.proxy", "mode", "none")
+		return err
+	}
+	if s.Type == PAC {
+		if _, err := run("gsettings", "set", "org.gnome.system.proxy", "autoconfig-url", s.PACURL); err != nil {
+			return err
+		}
+		_, err := run("gsettings", "set", "org.gnome.system.proxy", "mode", "auto")
+		return err
+	}
+	schemas := map[Type][]string{
+		SOCKS: {"socks"},
+		HTTP:  {"http", "https"},
+	}
+	for t, names := range schemas {
+		for _, name := range names {
+			host, port := "", "0"
+			if t == s.Type {
+				host, port = s.Host, strconv.Itoa(int(s.Port))
+			}
+			if _, err := run("gsettings", "set", "org.gnome.system.proxy."+name, "host", host); err != nil {
+				return err
+			}
+			if _, err := run("gsettings", "set", "org.gnome.system.proxy."+name, "port", port); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := run("gsettings", "set", "org.gnome.system.proxy", "ignore-hosts", formatGSettingsList(s.Bypass)); err != nil {
+		return err
+	}
+	_, err := run("gsettings", "set", "org.gnome.system.proxy", "mode", "manual")
+	return err
+}
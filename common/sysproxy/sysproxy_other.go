@@ -0,0 +1,10 @@
+//go:build !darwin && !windows && (!linux || android)
+// +build !darwin
+// +build !windows
+// +build !linux android
+
+package sysproxy
+
+func newBackend(string) backend {
+	return nil
+}
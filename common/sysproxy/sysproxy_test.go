@@ -0,0 +1,185 @@
+package sysproxy
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type fakeBackend struct {
+	settings Settings
+}
+
+func (f *fakeBackend) get() (Settings, error) {
+	return f.settings, nil
+}
+
+func (f *fakeBackend) set(s Settings) error {
+	f.settings = s
+	return nil
+}
+
+func TestProxyEnableDisable(t *testing.T) {
+	previous := Settings{Enabled: true, Type: HTTP, Host: "10.0.0.1", Port: 3128}
+	fake := &fakeBackend{settings: previous}
+	p := &Proxy{backend: fake}
+
+	if err := p.Enable(Settings{Type: SOCKS, Host: "127.0.0.1", Port: 10808}); err != nil {
+		t.Fatal(err)
+	}
+	if p.State() != Enabled || fake.settings.Host != "127.0.0.1" || !fake.settings.Enabled {
+		t.Error("unexpected state after enable: ", p.State(), fake.settings)
+	}
+	if p.observe(previous) != Overridden {
+		t.Error("expected overridden state")
+	}
+	if p.observe(fake.settings) != Enabled {
+		t.Error("expected enabled state")
+	}
+
+	if err := p.Disable(); err != nil {
+		t.Fatal(err)
+	}
+	if p.State() != Disabled {
+		t.Error("unexpected state after disable: ", p.State())
+	}
+	if r := cmp.Diff(fake.settings, previous); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestParseNetworksetup(t *testing.T) {
+	enabled, host, port := parseNetworksetup("Enabled: Yes\nServer: 127.0.0.1\nPort: 19800\nAuthenticated Proxy Enabled: 0\n")
+	if !enabled || host != "127.0.0.1" || port != 19800 {
+		t.Error("unexpected result: ", enabled, host, port)
+	}
+}
+
+func TestParseNetworksetupAutoProxy(t *testing.T) {
+	enabled, url := parseNetworksetupAutoProxy("URL: http://127.0.0.1:19801/proxy.pac\nEnabled: Yes\n")
+	if !enabled || url != "http://127.0.0.1:19801/proxy.pac" {
+		t.Error("unexpected result: ", enabled, url)
+	}
+	enabled, url = parseNetworksetupAutoProxy("URL: (null)\nEnabled: No\n")
+	if enabled || url != "" {
+		t.Error("unexpected result: ", enabled, url)
+	}
+}
+
+func TestParseGSettingsList(t *testing.T) {
+	list := parseGSettingsList("['localhost', '127.0.0.0/8', '::1']\n")
+	if r := cmp.Diff(list, []string{"localhost", "127.0.0.0/8", "::1"}); r != "" {
+		t.Error(r)
+	}
+	if list := parseGSettingsList("@as []"); len(list) != 0 {
+		t.Error("expected empty list, but got ", list)
+	}
+	if s := formatGSettingsList([]string{"localhost", "::1"}); s != "['localhost', '::1']" {
+		t.Error("unexpected format: ", s)
+	}
+}
+
+func TestParseWindowsProxyServer(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		t     Type
+		host  string
+		port  uint16
+	}{
+		{"127.0.0.1:8080", HTTP, "127.0.0.1", 8080},
+		{"http=127.0.0.1:8080;socks=127.0.0.1:1080", SOCKS, "127.0.0.1", 1080},
+		{"http=proxy:3128;https=proxy:3128", HTTP, "proxy", 3128},
+	} {
+		typ, host, port := parseWindowsProxyServer(tc.input)
+		if typ != tc.t || host != tc.host || port != tc.port {
+			t.Error(tc.input, ": unexpected result: ", typ, host, port)
+		}
+	}
+}
+
+func TestParseKDEProxy(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		host  string
+		port  uint16
+	}{
+		{"socks://127.0.0.1 1080", "127.0.0.1", 1080},
+		{"http://proxy:3128", "proxy", 3128},
+		{"", "", 0},
+	} {
+		host, port := parseKDEProxy(tc.input)
+		if host != tc.host || port != tc.port {
+			t.Error(tc.input, ": unexpected result: ", host, port)
+		}
+	}
+	if r := cmp.Diff(parseKDEList("localhost, 127.0.0.1,"), []string{"localhost", "127.0.0.1"}); r != "" {
+		t.Error(r)
+	}
+}
+
+type fakeDNSBackend struct {
+	servers []string
+}
+
+func (f *fakeDNSBackend) getDNS() ([]string, error) {
+	return f.servers, nil
+}
+
+func (f *fakeDNSBackend) setDNS(servers []string) error {
+	f.servers = servers
+	return nil
+}
+
+func TestDNSEnableDisable(t *testing.T) {
+	fake := &fakeDNSBackend{servers: []string{"192.168.1.1"}}
+	d := &DNS{backend: fake}
+
+	if err := d.Enable([]string{"127.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Enable([]string{"127.0.0.2"}); err != nil {
+		t.Fatal(err)
+	}
+	if d.State() != Enabled {
+		t.Error("unexpected state after enable: ", d.State())
+	}
+	if err := d.Disable(); err != nil {
+		t.Fatal(err)
+	}
+	if r := cmp.Diff(fake.servers, []string{"192.168.1.1"}); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestParseNetworksetupDNS(t *testing.T) {
+	if servers := parseNetworksetupDNS("There aren't any DNS Servers set on Wi-Fi.\n"); len(servers) != 0 {
+		t.Error("unexpected servers: ", servers)
+	}
+	if r := cmp.Diff(parseNetworksetupDNS("1.1.1.1\n8.8.8.8\n"), []string{"1.1.1.1", "8.8.8.8"}); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestParseNetworkServiceOrder(t *testing.T) {
+	out := `An asterisk (*) denotes that a network service is disabled.
+(1) USB 10/100/1000 LAN
+(Hardware Port: USB 10/100/1000 LAN, Device: en7)
+
+(2) Wi-Fi
+(Hardware Port: Wi-Fi, Device: en0)
+
+(*) Thunderbolt Bridge
+(Hardware Port: Thunderbolt Bridge, Device: bridge0)
+`
+	want := map[string]string{
+		"en7":     "USB 10/100/1000 LAN",
+		"en0":     "Wi-Fi",
+		"bridge0": "Thunderbolt Bridge",
+	}
+	if r := cmp.Diff(parseNetworkServiceOrder(out), want); r != "" {
+		t.Error(r)
+	}
+	if device := parseRouteInterface("   route to: default\n  interface: en0\n"); device != "en0" {
+		t.Error("unexpected interface: ", device)
+	}
+}
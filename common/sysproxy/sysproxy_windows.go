@@ -0,0 +1,104 @@
+//go:build windows
+// +build windows
+
+package sysproxy
+
+import (
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const internetSettingsKey = `Software\Microsoft\Windows\CurrentVersion\Internet Settings`
+
+const (
+	internetOptionRefresh         = 37
+	internetOptionSettingsChanged = 39
+)
+
+var internetSetOption = windows.NewLazySystemDLL("wininet.dll").NewProc("InternetSetOptionW")
+
+type internetSettings struct{}
+
+func newBackend(string) backend {
+	return internetSettings{}
+}
+
+func (internetSettings) get() (Settings, error) {
+	var s Settings
+	key, err := registry.OpenKey(registry.CURRENT_USER, internetSettingsKey, registry.QUERY_VALUE)
+	if err != nil {
+		return s, err
+	}
+	defer key.Close()
+
+	enabled, _, err := key.GetIntegerValue("ProxyEnable")
+	if err != nil && err != registry.ErrNotExist {
+		return s, err
+	}
+	server, _, err := key.GetStringValue("ProxyServer")
+	if err != nil && err != registry.ErrNotExist {
+		return s, err
+	}
+	override, _, err := key.GetStringValue("ProxyOverride")
+	if err != nil && err != registry.ErrNotExist {
+		return s, err
+	}
+	autoConfigURL, _, err := key.GetStringValue("AutoConfigURL")
+	if err != nil && err != registry.ErrNotExist {
+		return s, err
+	}
+
+	s.Enabled = enabled == 1
+	if server != "" {
+		s.Type, s.Host, s.Port = parseWindowsProxyServer(server)
+	}
+	if override != "" {
+		s.Bypass = strings.Split(override, ";")
+	}
+	if !s.Enabled && autoConfigURL != "" {
+		s.Enabled, s.Type, s.PACURL = true, PAC, autoConfigURL
+	}
+	return s, nil
+}
+
+func (internetSettings) set(s Settings) error {
+	key, err := registry.OpenKey(registry.CURRENT_USER, internetSettingsKey, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+
+	if s.Enabled && s.Type == PAC {
+		if err := key.SetStringValue("AutoConfigURL", s.PACURL); err != nil {
+			return err
+		}
+	} else if err := key.DeleteValue("AutoConfigURL"); err != nil && err != registry.ErrNotExist {
+		return err
+	}
+	if s.Host != "" {
+		server := s.Address()
+		if s.Type == SOCKS {
+			server = "socks=" + server
+		}
+		if err := key.SetStringValue("ProxyServer", server); err != nil {
+			return err
+		}
+	}
+	if err := key.SetStringValue("ProxyOverride", strings.Join(s.Bypass, ";")); err != nil {
+		return err
+	}
+	var enabled uint32
+	if s.Enabled && s.Type != PAC {
+		enabled = 1
+	}
+	if err := key.SetDWordValue("ProxyEnable", enabled); err != nil {
+		return err
+	}
+
+	// Running programs only pick up the new settings after being notified.
+	internetSetOption.Call(0, internetOptionSettingsChanged, 0, 0)
+	internetSetOption.Call(0, internetOptionRefresh, 0, 0)
+	return nil
+}
@@ -2,6 +2,7 @@ package task
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/xtls/xray-core/common/signal/semaphore"
 )
@@ -16,8 +17,45 @@ func OnSuccess(f func() error, g func() error) func() error {
 	}
 }
 
+// PanicHandler is called with the value of a panic in a task, which is recovered. The returned
+// error is the result of the task.
+type PanicHandler func(ctx context.Context, v interface{}) error
+
+var panicHandler atomic.Pointer[PanicHandler]
+
+// SetPanicHandler sets the handler of the panics in the tasks run by Run. Without one, panics
+// crash the process.
+func SetPanicHandler(h PanicHandler) {
+	if h == nil {
+		panicHandler.Store(nil)
+		return
+	}
+	panicHandler.Store(&h)
+}
+
+func runTask(ctx context.Context, handler PanicHandler, f func() error) (err error) {
+	if handler != nil {
+		defer func() {
+			if v := recover(); v != nil {
+				err = handler(ctx, v)
+			}
+		}()
+	}
+	return f()
+}
+
 // Run executes a list of tasks in parallel, returns the first error encountered or nil if all tasks pass.
 func Run(ctx context.Context, tasks ...func() error) error {
+	var handler PanicHandler
+	if h := panicHandler.Load(); h != nil {
+		handler = *h
+	}
+	return RunWithPanicHandler(ctx, handler, tasks...)
+}
+
+// RunWithPanicHandler is Run, with the panics in tasks handled by handler rather than the one set
+// by SetPanicHandler.
+func RunWithPanicHandler(ctx context.Context, handler PanicHandler, tasks ...func() error) error {
 	n := len(tasks)
 	s := semaphore.New(n)
 	done := make(chan error, 1)
@@ -25,7 +63,7 @@ func Run(ctx context.Context, tasks ...func() error) error {
 	for _, task := range tasks {
 		<-s.Wait()
 		go func(f func() error) {
-			err := f()
+			err := runTask(ctx, handler, f)
 			if err == nil {
 				s.Signal()
 				return
@@ -3,6 +3,7 @@ package task_test
 import (
 	"context"
 	"errors"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -46,6 +47,25 @@ func TestExecuteParallelContextCancel(t *testing.T) {
 	}
 }
 
+func TestForEach(t *testing.T) {
+	results := make([]int, 100)
+	err := ForEach(len(results), func(i int) error {
+		results[i] = i * i
+		if i == 42 || i == 77 {
+			return errors.New("failed at " + strconv.Itoa(i))
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "failed at 42" {
+		t.Error("expected the error of the lowest index, but got ", err)
+	}
+	for i, r := range results {
+		if r != i*i {
+			t.Error("index ", i, " not processed")
+		}
+	}
+}
+
 func BenchmarkExecuteOne(b *testing.B) {
 	noop := func() error {
 		return nil
@@ -63,3 +83,15 @@ func BenchmarkExecuteTwo(b *testing.B) {
 		common.Must(Run(context.Background(), noop, noop))
 	}
 }
+
+func TestExecuteParallelPanic(t *testing.T) {
+	handler := func(ctx context.Context, v interface{}) error {
+		return errors.New("panic: " + v.(string))
+	}
+	err := RunWithPanicHandler(context.Background(), handler, func() error {
+		panic("test")
+	})
+	if r := cmp.Diff(err.Error(), "panic: test"); r != "" {
+		t.Error(r)
+	}
+}
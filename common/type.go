@@ -31,3 +31,13 @@ func CreateObject(ctx context.Context, config interface{}) (interface{}, error)
 	}
 	return creator(ctx, config)
 }
+
+// RegisteredConfigs returns the types of the configs registered through RegisterConfig, that is of
+// the features compiled in.
+func RegisteredConfigs() []reflect.Type {
+	types := make([]reflect.Type, 0, len(typeCreatorRegistry))
+	for t := range typeCreatorRegistry {
+		types = append(types, t)
+	}
+	return types
+}
@@ -47,13 +47,25 @@ func init() {
 	}()
 }
 
+type baseKeyKey struct{}
+
+// ContextWithBaseKey returns a context in which GetGlobalID derives global IDs with key instead
+// of BaseKey.
+func ContextWithBaseKey(ctx context.Context, key []byte) context.Context {
+	return context.WithValue(ctx, baseKeyKey{}, key)
+}
+
 func GetGlobalID(ctx context.Context) (globalID [8]byte) {
 	if cone := ctx.Value("cone"); cone == nil || !cone.(bool) { // cone is nil only in some unit tests
 		return
 	}
 	if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.Source.Network == net.Network_UDP &&
 		(inbound.Name == "dokodemo-door" || inbound.Name == "socks" || inbound.Name == "shadowsocks") {
-		h := blake3.New(8, BaseKey)
+		key := BaseKey
+		if k, ok := ctx.Value(baseKeyKey{}).([]byte); ok {
+			key = k
+		}
+		h := blake3.New(8, key)
 		h.Write([]byte(inbound.Source.String()))
 		copy(globalID[:], h.Sum(nil))
 		if Show {
@@ -1,11 +1,14 @@
 package xudp
 
 import (
+	"bytes"
+	"context"
 	"testing"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
 )
 
 func TestXudpReadWrite(t *testing.T) {
@@ -34,3 +37,21 @@ func TestXudpReadWrite(t *testing.T) {
 		t.Error("failed to parse xudp buffer")
 	}
 }
+
+func TestGetGlobalIDBaseKey(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "cone", true)
+	source, _ := net.ParseDestination("udp:192.168.1.2:5000")
+	ctx = session.ContextWithInbound(ctx, &session.Inbound{Source: source, Name: "socks"})
+
+	key := bytes.Repeat([]byte{1}, 32)
+	id := GetGlobalID(ContextWithBaseKey(ctx, key))
+	if id == [8]byte{} {
+		t.Fatal("expected a global ID")
+	}
+	if GetGlobalID(ContextWithBaseKey(ctx, key)) != id {
+		t.Error("expected the same global ID for the same source and key")
+	}
+	if GetGlobalID(ContextWithBaseKey(ctx, bytes.Repeat([]byte{2}, 32))) == id {
+		t.Error("expected another global ID for another key")
+	}
+}
@@ -0,0 +1,203 @@
+package core
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/features"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/outbound"
+)
+
+// State is the lifecycle state of an Instance.
+type State int32
+
+const (
+	// StateCreated is the state of an Instance that has not been started yet.
+	StateCreated State = iota
+	// StateStarting is reported while features are being started.
+	StateStarting
+	// StateRunning is reported once all features have started.
+	StateRunning
+	// StateStopping is reported while features are being closed.
+	StateStopping
+	// StateStopped is reported once all features are closed.
+	StateStopped
+	// StateFailed is reported when a feature fails to start.
+	StateFailed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateCreated:
+		return "created"
+	case StateStarting:
+		return "starting"
+	case StateRunning:
+		return "running"
+	case StateStopping:
+		return "stopping"
+	case StateStopped:
+		return "stopped"
+	case StateFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// StateCallback is invoked on every state change of an Instance. err is non-nil only for StateFailed
+// and for StateStopped when some features failed to close.
+// Callbacks run synchronously on the goroutine changing the state and must not call Start or Close.
+type StateCallback func(state State, err error)
+
+// Option configures an Instance created by NewWithOptions.
+type Option func(*instanceOptions)
+
+type instanceOptions struct {
+	stateCallbacks []StateCallback
+	features       []features.Feature
+	inbounds       []inbound.Handler
+	outbounds      []outbound.Handler
+}
+
+// WithStateCallback registers a callback for state changes of the Instance.
+func WithStateCallback(callback StateCallback) Option {
+	return func(o *instanceOptions) {
+		o.stateCallbacks = append(o.stateCallbacks, callback)
+	}
+}
+
+// WithFeature registers a feature implemented in Go, such as a custom dns.Client. It takes precedence
+// over a feature of the same type created from config.
+func WithFeature(feature features.Feature) Option {
+	return func(o *instanceOptions) {
+		o.features = append(o.features, feature)
+	}
+}
+
+// WithInboundHandler adds an inbound handler implemented in Go, after the inbounds from config.
+func WithInboundHandler(handler inbound.Handler) Option {
+	return func(o *instanceOptions) {
+		o.inbounds = append(o.inbounds, handler)
+	}
+}
+
+// WithOutboundHandler adds an outbound handler implemented in Go, after the outbounds from config.
+// It becomes the default outbound only if config has no outbounds.
+func WithOutboundHandler(handler outbound.Handler) Option {
+	return func(o *instanceOptions) {
+		o.outbounds = append(o.outbounds, handler)
+	}
+}
+
+// NewWithOptions returns a new Xray instance based on given configuration and options.
+// The instance is not started at this point. ctx is used as the parent of the instance context,
+// same as NewWithContext.
+//
+// xray:api:beta
+func NewWithOptions(ctx context.Context, config *Config, opts ...Option) (*Instance, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	server := &Instance{ctx: ctx}
+	for _, opt := range opts {
+		opt(&server.options)
+	}
+
+	done, err := initInstanceWithConfig(config, server)
+	if done {
+		return nil, err
+	}
+
+	if len(server.options.inbounds) > 0 {
+		inboundManager, ok := server.GetFeature(inbound.ManagerType()).(inbound.Manager)
+		if !ok {
+			return nil, errors.New("inbound.Manager is not registered in Xray core")
+		}
+		for _, handler := range server.options.inbounds {
+			if err := inboundManager.AddHandler(server.ctx, handler); err != nil {
+				return nil, errors.New("failed to add inbound handler ", handler.Tag()).Base(err)
+			}
+		}
+	}
+	if len(server.options.outbounds) > 0 {
+		outboundManager, ok := server.GetFeature(outbound.ManagerType()).(outbound.Manager)
+		if !ok {
+			return nil, errors.New("outbound.Manager is not registered in Xray core")
+		}
+		for _, handler := range server.options.outbounds {
+			if err := outboundManager.AddHandler(server.ctx, handler); err != nil {
+				return nil, errors.New("failed to add outbound handler ", handler.Tag()).Base(err)
+			}
+		}
+	}
+
+	return server, nil
+}
+
+// State returns the current lifecycle state of the instance.
+func (s *Instance) State() State {
+	return State(atomic.LoadInt32(&s.state))
+}
+
+func (s *Instance) setState(state State, err error) {
+	atomic.StoreInt32(&s.state, int32(state))
+	for _, callback := range s.options.stateCallbacks {
+		callback(state, err)
+	}
+}
+
+// StartContext starts the instance like Start, but gives up waiting when ctx is done.
+// If ctx is done before Start returns, the instance is closed as soon as Start completes.
+//
+// xray:api:beta
+func (s *Instance) StartContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Start()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		go func() {
+			if <-done == nil {
+				s.Close()
+			}
+		}()
+		return ctx.Err()
+	}
+}
+
+// StopContext closes the instance like Close, but gives up waiting when ctx is done.
+// Closing continues in background in that case.
+//
+// xray:api:beta
+func (s *Instance) StopContext(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Close()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run starts the instance and blocks until ctx is done, then closes it.
+//
+// xray:api:beta
+func (s *Instance) Run(ctx context.Context) error {
+	if err := s.StartContext(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return s.Close()
+}
@@ -0,0 +1,284 @@
+package core
+
+import (
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/routing"
+	"google.golang.org/protobuf/proto"
+)
+
+// ReloadResult lists what Reload changed in a running Instance.
+type ReloadResult struct {
+	AddedInbounds    []string
+	RemovedInbounds  []string
+	ChangedInbounds  []string
+	AddedOutbounds   []string
+	RemovedOutbounds []string
+	ChangedOutbounds []string
+	RoutingChanged   bool
+	// RestartRequired lists the changes Reload could not apply, e.g. of untagged handlers or of apps other than routing.
+	RestartRequired []string
+	// MigratedInbounds lists the changed inbounds whose listeners and connections in progress were
+	// kept, as they listen the same way as before.
+	MigratedInbounds []string
+}
+
+// Changed returns whether Reload applied any change.
+func (r *ReloadResult) Changed() bool {
+	return len(r.AddedInbounds)+len(r.RemovedInbounds)+len(r.ChangedInbounds)+
+		len(r.AddedOutbounds)+len(r.RemovedOutbounds)+len(r.ChangedOutbounds) > 0 || r.RoutingChanged
+}
+
+// Reload applies config to the Instance without restarting it. Inbounds and outbounds are matched
+// by tag: new ones are added, missing ones removed, and changed ones replaced, while connections of
+// unchanged handlers are kept, as are those of changed inbounds still listening the same way if the
+// inbound manager is an inbound.Replacer. Routing rules are replaced when the routing config
+// changed. Other changes are reported in RestartRequired and not applied.
+//
+// When Reload returns an error, the Instance may run with part of the new config.
+func (s *Instance) Reload(config *Config) (*ReloadResult, error) {
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+
+	if s.config == nil {
+		return nil, errors.New("instance was not created from a config")
+	}
+	result := &ReloadResult{}
+
+	s.checkApps(config, result)
+	if err := s.reloadOutbounds(config.Outbound, result); err != nil {
+		return result, err
+	}
+	if err := s.reloadRouting(config, result); err != nil {
+		return result, err
+	}
+	if err := s.reloadInbounds(config.Inbound, result); err != nil {
+		return result, err
+	}
+
+	s.config = config
+	return result, nil
+}
+
+// Config returns the config the Instance was created or last reloaded with, nil if it wasn't
+// created from one.
+func (s *Instance) Config() *Config {
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+	return s.config
+}
+
+// SetReloadSource sets how ReloadFromSource reloads the Instance, as only the program running it
+// knows where its config comes from.
+func (s *Instance) SetReloadSource(reload func() (*ReloadResult, error)) {
+	s.sourceLock.Lock()
+	defer s.sourceLock.Unlock()
+	s.reloadSource = reload
+}
+
+// ReloadFromSource reloads the Instance with the config its source has now, e.g. after the geo
+// data files its rules refer to changed.
+func (s *Instance) ReloadFromSource() (*ReloadResult, error) {
+	s.sourceLock.Lock()
+	reload := s.reloadSource
+	s.sourceLock.Unlock()
+	if reload == nil {
+		return nil, errors.New("instance has no config source to reload from")
+	}
+	return reload()
+}
+
+// checkApps lists the apps that changed and can't be reloaded.
+func (s *Instance) checkApps(config *Config, result *ReloadResult) {
+	previous := appsByType(s.config.App)
+	current := appsByType(config.App)
+	for t, app := range current {
+		if old, found := previous[t]; !found || !proto.Equal(old, app) {
+			if _, ok := s.apps[t].(routing.Router); ok {
+				continue
+			}
+			result.RestartRequired = append(result.RestartRequired, t)
+		}
+	}
+	for t := range previous {
+		if _, found := current[t]; !found {
+			result.RestartRequired = append(result.RestartRequired, t)
+		}
+	}
+}
+
+func (s *Instance) reloadRouting(config *Config, result *ReloadResult) error {
+	previous := appsByType(s.config.App)
+	for t, app := range appsByType(config.App) {
+		router, ok := s.apps[t].(routing.Router)
+		if !ok || proto.Equal(previous[t], app) {
+			continue
+		}
+		if err := router.AddRule(app, false); err != nil {
+			return errors.New("failed to reload routing").Base(err)
+		}
+		result.RoutingChanged = true
+	}
+	return nil
+}
+
+func (s *Instance) reloadInbounds(configs []*InboundHandlerConfig, result *ReloadResult) error {
+	manager := s.GetFeature(inbound.ManagerType()).(inbound.Manager)
+
+	previous := make(map[string]*InboundHandlerConfig)
+	var previousUntagged, currentUntagged []proto.Message
+	for _, c := range s.config.Inbound {
+		if c.Tag == "" {
+			previousUntagged = append(previousUntagged, c)
+			continue
+		}
+		previous[c.Tag] = c
+	}
+
+	var added, changed []*InboundHandlerConfig
+	current := make(map[string]bool)
+	for _, c := range configs {
+		if c.Tag == "" {
+			currentUntagged = append(currentUntagged, c)
+			continue
+		}
+		current[c.Tag] = true
+		old, found := previous[c.Tag]
+		switch {
+		case !found:
+			result.AddedInbounds = append(result.AddedInbounds, c.Tag)
+			added = append(added, c)
+		case !proto.Equal(old, c):
+			result.ChangedInbounds = append(result.ChangedInbounds, c.Tag)
+			changed = append(changed, c)
+		}
+	}
+	if !sameMessages(previousUntagged, currentUntagged) {
+		result.RestartRequired = append(result.RestartRequired, "untagged inbounds")
+	}
+
+	for tag := range previous {
+		if !current[tag] {
+			result.RemovedInbounds = append(result.RemovedInbounds, tag)
+		}
+	}
+	for _, tag := range result.RemovedInbounds {
+		if err := manager.RemoveHandler(s.ctx, tag); err != nil {
+			return errors.New("failed to remove inbound ", tag).Base(err)
+		}
+	}
+	if replacer, ok := manager.(inbound.Replacer); ok {
+		for _, c := range changed {
+			handler, err := createInboundHandler(s, c)
+			if err != nil {
+				return errors.New("failed to create inbound ", c.Tag).Base(err)
+			}
+			migrated, err := replacer.ReplaceHandler(s.ctx, handler)
+			if migrated {
+				result.MigratedInbounds = append(result.MigratedInbounds, c.Tag)
+			}
+			if err != nil {
+				return errors.New("failed to replace inbound ", c.Tag).Base(err)
+			}
+		}
+	} else {
+		for _, c := range changed {
+			if err := manager.RemoveHandler(s.ctx, c.Tag); err != nil {
+				return errors.New("failed to remove inbound ", c.Tag).Base(err)
+			}
+		}
+		added = append(added, changed...)
+	}
+	for _, c := range added {
+		if err := AddInboundHandler(s, c); err != nil {
+			return errors.New("failed to add inbound ", c.Tag).Base(err)
+		}
+	}
+	return nil
+}
+
+func (s *Instance) reloadOutbounds(configs []*OutboundHandlerConfig, result *ReloadResult) error {
+	manager := s.GetFeature(outbound.ManagerType()).(outbound.Manager)
+
+	previous := make(map[string]*OutboundHandlerConfig)
+	var previousUntagged, currentUntagged []proto.Message
+	for _, c := range s.config.Outbound {
+		if c.Tag == "" {
+			previousUntagged = append(previousUntagged, c)
+			continue
+		}
+		previous[c.Tag] = c
+	}
+
+	var added []*OutboundHandlerConfig
+	current := make(map[string]bool)
+	for _, c := range configs {
+		if c.Tag == "" {
+			currentUntagged = append(currentUntagged, c)
+			continue
+		}
+		current[c.Tag] = true
+		old, found := previous[c.Tag]
+		switch {
+		case !found:
+			result.AddedOutbounds = append(result.AddedOutbounds, c.Tag)
+		case !proto.Equal(old, c):
+			result.ChangedOutbounds = append(result.ChangedOutbounds, c.Tag)
+		default:
+			continue
+		}
+		added = append(added, c)
+	}
+	if !sameMessages(previousUntagged, currentUntagged) {
+		result.RestartRequired = append(result.RestartRequired, "untagged outbounds")
+	}
+	// The first outbound is the default one, which the manager can't change.
+	if len(configs) > 0 && len(s.config.Outbound) > 0 && configs[0].Tag != s.config.Outbound[0].Tag {
+		result.RestartRequired = append(result.RestartRequired, "default outbound")
+	}
+
+	for tag := range previous {
+		if !current[tag] {
+			result.RemovedOutbounds = append(result.RemovedOutbounds, tag)
+		}
+	}
+	for _, tag := range append(result.RemovedOutbounds, result.ChangedOutbounds...) {
+		handler := manager.GetHandler(tag)
+		if err := manager.RemoveHandler(s.ctx, tag); err != nil {
+			return errors.New("failed to remove outbound ", tag).Base(err)
+		}
+		if handler != nil {
+			if err := handler.Close(); err != nil {
+				errors.LogWarningInner(s.ctx, err, "failed to close outbound ", tag)
+			}
+		}
+	}
+	for _, c := range added {
+		if err := AddOutboundHandler(s, c); err != nil {
+			return errors.New("failed to add outbound ", c.Tag).Base(err)
+		}
+	}
+	return nil
+}
+
+func appsByType(apps []*serial.TypedMessage) map[string]*serial.TypedMessage {
+	m := make(map[string]*serial.TypedMessage, len(apps))
+	for _, app := range apps {
+		m[app.Type] = app
+	}
+	return m
+}
+
+func sameMessages(a, b []proto.Message) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !proto.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -86,26 +86,41 @@ type Instance struct {
 	pendingOptionalResolutions []resolution
 	running                    bool
 	resolveLock                sync.Mutex
+	state                      int32
+	options                    instanceOptions
+	reloadLock                 sync.Mutex
+	config                     *Config
+	apps                       map[string]features.Feature
+	sourceLock                 sync.Mutex
+	reloadSource               func() (*ReloadResult, error)
 
 	ctx context.Context
 }
 
 func AddInboundHandler(server *Instance, config *InboundHandlerConfig) error {
 	inboundManager := server.GetFeature(inbound.ManagerType()).(inbound.Manager)
-	rawHandler, err := CreateObject(server, config)
+	handler, err := createInboundHandler(server, config)
 	if err != nil {
 		return err
 	}
-	handler, ok := rawHandler.(inbound.Handler)
-	if !ok {
-		return errors.New("not an InboundHandler")
-	}
 	if err := inboundManager.AddHandler(server.ctx, handler); err != nil {
 		return err
 	}
 	return nil
 }
 
+func createInboundHandler(server *Instance, config *InboundHandlerConfig) (inbound.Handler, error) {
+	rawHandler, err := CreateObject(server, config)
+	if err != nil {
+		return nil, err
+	}
+	handler, ok := rawHandler.(inbound.Handler)
+	if !ok {
+		return nil, errors.New("not an InboundHandler")
+	}
+	return handler, nil
+}
+
 func addInboundHandlers(server *Instance, configs []*InboundHandlerConfig) error {
 	for _, inboundConfig := range configs {
 		if err := AddInboundHandler(server, inboundConfig); err != nil {
@@ -171,20 +186,21 @@ func New(config *Config) (*Instance, error) {
 }
 
 func NewWithContext(ctx context.Context, config *Config) (*Instance, error) {
-	server := &Instance{ctx: ctx}
-
-	done, err := initInstanceWithConfig(config, server)
-	if done {
-		return nil, err
-	}
-
-	return server, nil
+	return NewWithOptions(ctx, config)
 }
 
 func initInstanceWithConfig(config *Config, server *Instance) (bool, error) {
 	server.ctx = context.WithValue(server.ctx, "cone",
 		platform.NewEnvFlag(platform.UseCone).GetValue(func() string { return "" }) != "true")
 
+	for _, feature := range server.options.features {
+		if err := server.AddFeature(feature); err != nil {
+			return true, err
+		}
+	}
+
+	server.config = config
+	server.apps = make(map[string]features.Feature)
 	for _, appSettings := range config.App {
 		settings, err := appSettings.GetInstance()
 		if err != nil {
@@ -198,6 +214,7 @@ func initInstanceWithConfig(config *Config, server *Instance) (bool, error) {
 			if err := server.AddFeature(feature); err != nil {
 				return true, err
 			}
+			server.apps[appSettings.Type] = feature
 		}
 	}
 
@@ -255,6 +272,7 @@ func (s *Instance) Close() error {
 	defer s.statusLock.Unlock()
 
 	s.running = false
+	s.setState(StateStopping, nil)
 
 	var errs []interface{}
 	for _, f := range s.features {
@@ -263,9 +281,12 @@ func (s *Instance) Close() error {
 		}
 	}
 	if len(errs) > 0 {
-		return errors.New("failed to close all features").Base(errors.New(serial.Concat(errs...)))
+		err := errors.New("failed to close all features").Base(errors.New(serial.Concat(errs...)))
+		s.setState(StateStopped, err)
+		return err
 	}
 
+	s.setState(StateStopped, nil)
 	return nil
 }
 
@@ -381,11 +402,14 @@ func (s *Instance) Start() error {
 	defer s.statusLock.Unlock()
 
 	s.running = true
+	s.setState(StateStarting, nil)
 	for _, f := range s.features {
 		if err := f.Start(); err != nil {
+			s.setState(StateFailed, err)
 			return err
 		}
 	}
+	s.setState(StateRunning, nil)
 
 	errors.LogWarning(s.ctx, "Xray ", Version(), " started")
 
@@ -1,8 +1,11 @@
 package core_test
 
 import (
+	"context"
+	"io"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/xtls/xray-core/app/dispatcher"
 	"github.com/xtls/xray-core/app/proxyman"
 	"github.com/xtls/xray-core/common"
@@ -13,8 +16,10 @@ import (
 	. "github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/features/dns/localdns"
+	outboundfeature "github.com/xtls/xray-core/features/outbound"
 	_ "github.com/xtls/xray-core/main/distro/all"
 	"github.com/xtls/xray-core/proxy/dokodemo"
+	"github.com/xtls/xray-core/proxy/freedom"
 	"github.com/xtls/xray-core/proxy/vmess"
 	"github.com/xtls/xray-core/proxy/vmess/outbound"
 	"github.com/xtls/xray-core/testing/servers/tcp"
@@ -88,3 +93,166 @@ func TestXrayClose(t *testing.T) {
 	common.Must(err)
 	server.Close()
 }
+
+func TestXrayStateCallback(t *testing.T) {
+	config := &Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+		},
+	}
+
+	var states []State
+	server, err := NewWithOptions(context.Background(), config, WithStateCallback(func(state State, err error) {
+		states = append(states, state)
+	}))
+	common.Must(err)
+	if server.State() != StateCreated {
+		t.Error("expected created state, but got ", server.State())
+	}
+
+	common.Must(server.StartContext(context.Background()))
+	common.Must(server.StopContext(context.Background()))
+
+	expected := []State{StateStarting, StateRunning, StateStopping, StateStopped}
+	if len(states) != len(expected) {
+		t.Fatal("unexpected states: ", states)
+	}
+	for i := range expected {
+		if states[i] != expected[i] {
+			t.Error("expected ", expected[i], " at ", i, ", but got ", states[i])
+		}
+	}
+}
+
+func TestXrayReload(t *testing.T) {
+	apps := []*serial.TypedMessage{
+		serial.ToTypedMessage(&dispatcher.Config{}),
+		serial.ToTypedMessage(&proxyman.InboundConfig{}),
+		serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+	}
+	freedomOutbound := func(tag string, domainStrategy freedom.Config_DomainStrategy) *OutboundHandlerConfig {
+		return &OutboundHandlerConfig{
+			Tag:           tag,
+			ProxySettings: serial.ToTypedMessage(&freedom.Config{DomainStrategy: domainStrategy}),
+		}
+	}
+
+	server, err := New(&Config{
+		App: apps,
+		Outbound: []*OutboundHandlerConfig{
+			freedomOutbound("direct", freedom.Config_AS_IS),
+			freedomOutbound("changed", freedom.Config_AS_IS),
+			freedomOutbound("removed", freedom.Config_AS_IS),
+		},
+	})
+	common.Must(err)
+	common.Must(server.Start())
+	defer server.Close()
+
+	result, err := server.Reload(&Config{
+		App: apps,
+		Outbound: []*OutboundHandlerConfig{
+			freedomOutbound("direct", freedom.Config_AS_IS),
+			freedomOutbound("changed", freedom.Config_USE_IP),
+			freedomOutbound("added", freedom.Config_AS_IS),
+		},
+	})
+	common.Must(err)
+
+	if r := cmp.Diff(result, &ReloadResult{
+		AddedOutbounds:   []string{"added"},
+		RemovedOutbounds: []string{"removed"},
+		ChangedOutbounds: []string{"changed"},
+	}); r != "" {
+		t.Error(r)
+	}
+
+	ohm := server.GetFeature(outboundfeature.ManagerType()).(outboundfeature.Manager)
+	for tag, exists := range map[string]bool{"direct": true, "changed": true, "added": true, "removed": false} {
+		if (ohm.GetHandler(tag) != nil) != exists {
+			t.Error("outbound ", tag, " expected to exist: ", exists)
+		}
+	}
+}
+
+func TestXrayReloadMigratesInbound(t *testing.T) {
+	echo := tcp.Server{MsgProcessor: func(b []byte) []byte { return b }}
+	dest, err := echo.Start()
+	common.Must(err)
+	defer echo.Close()
+
+	port := tcp.PickPort()
+	config := func(port net.Port, level uint32) *Config {
+		return &Config{
+			App: []*serial.TypedMessage{
+				serial.ToTypedMessage(&dispatcher.Config{}),
+				serial.ToTypedMessage(&proxyman.InboundConfig{}),
+				serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+			},
+			Inbound: []*InboundHandlerConfig{{
+				Tag: "in",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(port)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:   net.NewIPOrDomain(dest.Address),
+					Port:      uint32(dest.Port),
+					Networks:  []net.Network{net.Network_TCP},
+					UserLevel: level,
+				}),
+			}},
+			Outbound: []*OutboundHandlerConfig{{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			}},
+		}
+	}
+	server, err := New(config(port, 0))
+	common.Must(err)
+	common.Must(server.Start())
+	defer server.Close()
+
+	roundTrip := func(conn net.Conn, msg string) {
+		t.Helper()
+		common.Must2(conn.Write([]byte(msg)))
+		b := make([]byte, len(msg))
+		common.Must2(io.ReadFull(conn, b))
+		if string(b) != msg {
+			t.Error("unexpected echo: ", string(b))
+		}
+	}
+	dial := func(port net.Port) net.Conn {
+		t.Helper()
+		conn, err := net.DialTCP("tcp", nil, &net.TCPAddr{IP: []byte{127, 0, 0, 1}, Port: int(port)})
+		common.Must(err)
+		return conn
+	}
+	conn := dial(port)
+	defer conn.Close()
+	roundTrip(conn, "before")
+
+	result, err := server.Reload(config(port, 1))
+	common.Must(err)
+	if r := cmp.Diff(result, &ReloadResult{
+		ChangedInbounds:  []string{"in"},
+		MigratedInbounds: []string{"in"},
+	}); r != "" {
+		t.Error(r)
+	}
+	roundTrip(conn, "after")
+	newConn := dial(port)
+	defer newConn.Close()
+	roundTrip(newConn, "new")
+
+	newPort := tcp.PickPort()
+	result, err = server.Reload(config(newPort, 1))
+	common.Must(err)
+	if len(result.MigratedInbounds) != 0 {
+		t.Error("expected inbound listening on another port not migrated")
+	}
+	movedConn := dial(newPort)
+	defer movedConn.Close()
+	roundTrip(movedConn, "moved")
+}
@@ -0,0 +1,102 @@
+// Package extension is the public registry for Go programs embedding Xray.
+//
+// It lets external code plug custom proxies, transports and features (such as a DNS client)
+// into Xray without depending on internal registration calls. All Register functions write into
+// global registries and must be called before any Instance is created, typically from init().
+//
+// Configs of registered proxies and features are referenced from core.Config as
+// serial.TypedMessage, exactly like built-in ones.
+package extension
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/features"
+	"github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"google.golang.org/protobuf/proto"
+)
+
+// InboundCreator creates an inbound proxy from its config.
+type InboundCreator[T proto.Message] func(ctx context.Context, config T) (proxy.Inbound, error)
+
+// OutboundCreator creates an outbound proxy from its config.
+type OutboundCreator[T proto.Message] func(ctx context.Context, config T) (proxy.Outbound, error)
+
+// FeatureCreator creates a feature from its config.
+type FeatureCreator[T proto.Message] func(ctx context.Context, config T) (features.Feature, error)
+
+// DNSClientCreator creates a DNS client from its config.
+type DNSClientCreator[T proto.Message] func(ctx context.Context, config T) (dns.Client, error)
+
+// DialFunc dials a connection of a custom transport.
+type DialFunc = func(ctx context.Context, dest net.Destination, settings *internet.MemoryStreamConfig) (stat.Connection, error)
+
+func register[T proto.Message, R any](creator func(context.Context, T) (R, error)) error {
+	var zero T
+	if reflect.TypeOf(zero) == nil {
+		return errors.New("config type must be a concrete proto message")
+	}
+	return common.RegisterConfig(zero, func(ctx context.Context, config interface{}) (interface{}, error) {
+		return creator(ctx, config.(T))
+	})
+}
+
+// RegisterInbound registers an inbound proxy for config type T, usually a pointer to a generated
+// proto message. The config is used as ProxySettings of core.InboundHandlerConfig.
+func RegisterInbound[T proto.Message](creator InboundCreator[T]) error {
+	return register[T, proxy.Inbound](creator)
+}
+
+// RegisterOutbound registers an outbound proxy for config type T. The config is used as
+// ProxySettings of core.OutboundHandlerConfig.
+func RegisterOutbound[T proto.Message](creator OutboundCreator[T]) error {
+	return register[T, proxy.Outbound](creator)
+}
+
+// RegisterFeature registers a feature for config type T. The config is used in core.Config.App.
+func RegisterFeature[T proto.Message](creator FeatureCreator[T]) error {
+	return register[T, features.Feature](creator)
+}
+
+// RegisterDNSClient registers a DNS client for config type T. The config is used in core.Config.App
+// in place of the built-in DNS app.
+func RegisterDNSClient[T proto.Message](creator DNSClientCreator[T]) error {
+	return register[T, dns.Client](creator)
+}
+
+// RegisterTransport registers a transport protocol by name. config creates an empty settings message
+// for the protocol, used as TransportConfig.Settings. dial or listen may be nil for transports that
+// only work in one direction.
+func RegisterTransport(name string, config func() proto.Message, dial DialFunc, listen internet.ListenFunc) error {
+	if config != nil {
+		if err := internet.RegisterProtocolConfigCreator(name, func() interface{} {
+			return config()
+		}); err != nil {
+			return err
+		}
+	}
+	if dial != nil {
+		if err := internet.RegisterTransportDialer(name, dial); err != nil {
+			return err
+		}
+	}
+	if listen != nil {
+		if err := internet.RegisterTransportListener(name, listen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnDowngrade registers a hook called when a client connects to an inbound with weaker options
+// than configured, such as VLESS without Vision for a Vision account.
+func OnDowngrade(hook func(ctx context.Context, d *proxy.Downgrade)) {
+	proxy.RegisterDowngradeHook(hook)
+}
@@ -0,0 +1,37 @@
+package extension_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	. "github.com/xtls/xray-core/extension"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/internet"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type testOutbound struct{}
+
+func (testOutbound) Process(context.Context, *transport.Link, internet.Dialer) error {
+	return nil
+}
+
+func TestRegisterOutbound(t *testing.T) {
+	common.Must(RegisterOutbound(func(ctx context.Context, config *emptypb.Empty) (proxy.Outbound, error) {
+		return testOutbound{}, nil
+	}))
+
+	obj, err := common.CreateObject(context.Background(), &emptypb.Empty{})
+	common.Must(err)
+	if _, ok := obj.(proxy.Outbound); !ok {
+		t.Error("expected proxy.Outbound, but got ", obj)
+	}
+
+	if err := RegisterOutbound(func(ctx context.Context, config *emptypb.Empty) (proxy.Outbound, error) {
+		return testOutbound{}, nil
+	}); err == nil {
+		t.Error("expected error on duplicate registration")
+	}
+}
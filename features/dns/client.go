@@ -1,6 +1,8 @@
 package dns
 
 import (
+	"context"
+
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/serial"
@@ -24,6 +26,97 @@ type Client interface {
 	LookupIP(domain string, option IPOption) ([]net.IP, error)
 }
 
+// CacheFlusher is a Client whose cached answers can be dropped.
+type CacheFlusher interface {
+	// FlushCache drops all cached answers.
+	FlushCache()
+}
+
+// CacheStats are the queries of a name server answered from its cache, and those sent to it.
+type CacheStats struct {
+	Server string
+	// Partition is the tag of the outbound whose lookups the cache answers, empty for the cache
+	// shared by the other lookups.
+	Partition string
+	Hits      int64
+	Misses    int64
+}
+
+// CacheStatsReporter is a Client counting the queries its name servers answer from their caches.
+type CacheStatsReporter interface {
+	// GetCacheStats returns the cache hits and misses of each name server with a cache.
+	GetCacheStats() []CacheStats
+}
+
+// NameServerLister is a Client which tells the name servers it queries.
+type NameServerLister interface {
+	// GetNameServers returns the names of the name servers, in the order they're configured.
+	GetNameServers() []string
+}
+
+// TaggedLookup resolves domains at selected name servers only.
+type TaggedLookup interface {
+	// LookupIPWithTag returns IP addresses for the given domain from the name servers with the given tag,
+	// bypassing static hosts and cache.
+	LookupIPWithTag(domain string, tag string, option IPOption) ([]net.IP, error)
+}
+
+// InternalResolver is a Client resolving the domains the proxy itself dials, such as the servers of
+// outbounds, which are otherwise resolved by the system resolver.
+type InternalResolver interface {
+	// ResolvesInternal returns whether domain, dialed by the proxy itself, is resolved by the Client.
+	ResolvesInternal(domain string) bool
+}
+
+// SessionLookup is a Client which resolves domains on behalf of a session, logging the lookups
+// with the ID of the session.
+type SessionLookup interface {
+	// LookupIPForSession is LookupIP on behalf of the session of ctx.
+	LookupIPForSession(ctx context.Context, domain string, option IPOption) ([]net.IP, error)
+}
+
+type partitionKey struct{}
+
+// ContextWithPartition returns ctx for the lookups of the outbound tagged tag, for the destinations
+// of the connections it dials, which are answered apart from other lookups if the Client partitions
+// its cache.
+func ContextWithPartition(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, partitionKey{}, tag)
+}
+
+// PartitionFromContext returns the tag of the outbound whose lookup ctx is for, if any.
+func PartitionFromContext(ctx context.Context) string {
+	tag, _ := ctx.Value(partitionKey{}).(string)
+	return tag
+}
+
+// LookupIPForSession resolves domain with client on behalf of the session of ctx, if client
+// supports it.
+func LookupIPForSession(ctx context.Context, client Client, domain string, option IPOption) ([]net.IP, error) {
+	if l, ok := client.(SessionLookup); ok {
+		return l.LookupIPForSession(ctx, domain, option)
+	}
+	return client.LookupIP(domain, option)
+}
+
+// TTLLookup is a Client which tells how long its answers remain cached, so that answers handed out
+// to others expire along with the ones it routes by.
+type TTLLookup interface {
+	// LookupIPWithTTL is LookupIPForSession, which also returns the seconds the IPs remain valid
+	// for, 0 if unknown.
+	LookupIPWithTTL(ctx context.Context, domain string, option IPOption) ([]net.IP, uint32, error)
+}
+
+// LookupIPWithTTL resolves domain with client on behalf of the session of ctx, with the seconds
+// the IPs remain valid for if client tells.
+func LookupIPWithTTL(ctx context.Context, client Client, domain string, option IPOption) ([]net.IP, uint32, error) {
+	if l, ok := client.(TTLLookup); ok {
+		return l.LookupIPWithTTL(ctx, domain, option)
+	}
+	ips, err := LookupIPForSession(ctx, client, domain, option)
+	return ips, 0, err
+}
+
 type HostsLookup interface {
 	LookupHosts(domain string) *net.Address
 }
@@ -13,6 +13,36 @@ type Observatory interface {
 	GetObservation(ctx context.Context) (proto.Message, error)
 }
 
+// ObservatoryGroup is an Observatory made of several observatories, each probing its own
+// outbounds, which balancers select by tag.
+type ObservatoryGroup interface {
+	Observatory
+
+	// GetObservatory returns the observatory with the given tag, or nil if there is none.
+	GetObservatory(tag string) Observatory
+}
+
+// Kinds of congestion signals reported to a CongestionObserver.
+const (
+	// CongestionRetransmission is the fraction of the segments a connection retransmitted lately.
+	CongestionRetransmission = "retransmission"
+	// CongestionRTTInflation is the ratio of the round trip time of a connection to its lowest one.
+	CongestionRTTInflation = "rtt-inflation"
+	// CongestionThroughput is the ratio of the throughput of a transfer to the usual one of its
+	// outbound.
+	CongestionThroughput = "throughput"
+)
+
+// CongestionObserver is an Observatory which is also fed the congestion signals of the
+// connections of outbounds, so that balancers move off outbounds degrading mid-session without
+// waiting for probes to fail.
+type CongestionObserver interface {
+	// ObservesCongestion returns whether the signals of the outbound are of use.
+	ObservesCongestion(outbound string) bool
+	// ObserveCongestion reports a signal of the given kind of a connection of the outbound.
+	ObserveCongestion(outbound string, kind string, value float64)
+}
+
 func ObservatoryType() interface{} {
 	return (*Observatory)(nil)
 }
@@ -10,3 +10,10 @@ type Feature interface {
 	common.HasType
 	common.Runnable
 }
+
+// Reclaimer is implemented by features keeping caches which can be dropped, to return memory
+// while the process is idle.
+type Reclaimer interface {
+	// Reclaim drops the caches, which fill again as they are used.
+	Reclaim()
+}
@@ -2,8 +2,10 @@ package inbound
 
 import (
 	"context"
+	"time"
 
 	"github.com/xtls/xray-core/common"
+	c "github.com/xtls/xray-core/common/ctx"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/features"
 )
@@ -34,9 +36,119 @@ type Manager interface {
 	RemoveHandler(ctx context.Context, tag string) error
 }
 
+// HandlerFailure describes an InboundHandler that failed to start.
+type HandlerFailure struct {
+	Tag string
+	Err error
+}
+
+// FailureReporter is implemented by Managers that keep running when some of their handlers fail to start.
+type FailureReporter interface {
+	// GetFailedHandlers returns the handlers that failed to start and were taken out of service.
+	GetFailedHandlers() []HandlerFailure
+}
+
+// UDPSession is an entry in the UDP session (NAT) table of an inbound.
+type UDPSession struct {
+	Tag         string
+	Source      net.Destination
+	Destination net.Destination
+	Uplink      int64
+	Downlink    int64
+	// TTL is the time left before the session expires if it stays idle.
+	TTL time.Duration
+}
+
+// UDPSessionTable is implemented by Managers whose handlers keep per-client UDP sessions.
+type UDPSessionTable interface {
+	// GetUDPSessions returns the active UDP sessions of the handler with the given tag, or of all handlers if tag is empty.
+	GetUDPSessions(tag string) []UDPSession
+	// FlushUDPSessions closes the matching sessions and returns how many were removed.
+	// A nil source matches all sessions of the handler.
+	FlushUDPSessions(tag string, source net.Address) int
+}
+
 // ManagerType returns the type of Manager interface. Can be used for implementing common.HasType.
 //
 // xray:api:stable
 func ManagerType() interface{} {
 	return (*Manager)(nil)
 }
+
+// ListenAddress is an address an inbound listens on.
+type ListenAddress struct {
+	Tag     string
+	Address net.Destination
+}
+
+// AddressReporter is implemented by Managers which know the addresses their handlers listen on,
+// including the ports allocated at startup for those configured with port 0.
+type AddressReporter interface {
+	// GetListenAddresses returns the addresses of the handler with the given tag, or of all handlers if tag is empty.
+	GetListenAddresses(tag string) []ListenAddress
+}
+
+// ConnectionCount is the number of connections in progress through a handler.
+type ConnectionCount struct {
+	Tag         string
+	Connections int
+}
+
+// ConnectionReporter is implemented by Managers which count the connections in progress through
+// their handlers. UDP sessions count as connections.
+type ConnectionReporter interface {
+	// GetActiveConnections returns the connections in progress through each tagged handler.
+	GetActiveConnections() []ConnectionCount
+}
+
+// Drainer is implemented by Managers whose handlers can stop taking new connections, leaving those
+// in progress, before shutting down.
+type Drainer interface {
+	// StopAccepting makes all handlers refuse new connections and UDP sessions.
+	StopAccepting()
+}
+
+// Pauser is implemented by Managers whose handlers can refuse new connections for a while, keeping
+// those in progress and their listeners, until resumed.
+type Pauser interface {
+	// SetPaused makes all handlers, and those added later, refuse new connections and UDP sessions,
+	// or take them again.
+	SetPaused(paused bool)
+	// Paused tells whether the handlers refuse new connections.
+	Paused() bool
+}
+
+// IdleConnection is a connection in progress through a handler which has had no activity for a
+// while.
+type IdleConnection struct {
+	Tag         string
+	Source      net.Destination
+	Destination net.Destination
+	User        string
+	// Age is the time since the connection was accepted.
+	Age time.Duration
+	// Idle is the time since the last activity.
+	Idle time.Duration
+	// Limit is the idle time after which the connection is swept.
+	Limit time.Duration
+	// Session is the ID of the session of the connection, prefixing the log lines about it.
+	Session c.ID
+}
+
+// IdleConnectionLister is implemented by Managers which close connections left idle beyond the
+// policy of their users, and can list them beforehand.
+type IdleConnectionLister interface {
+	// GetIdleConnections returns the connections of the handler with the given tag, or of all
+	// handlers if tag is empty, which have been idle for at least idle.
+	GetIdleConnections(tag string, idle time.Duration) []IdleConnection
+}
+
+// Replacer is implemented by Managers which can replace a handler without closing the connections
+// in progress through it.
+type Replacer interface {
+	// ReplaceHandler replaces the handler with the tag of handler. If the new handler listens the
+	// same way as the old one, it takes over the listeners of the old one instead of binding them
+	// again, and the connections in progress are kept until they end. It returns whether the
+	// listeners were taken over.
+	ReplaceHandler(ctx context.Context, handler Handler) (bool, error)
+}
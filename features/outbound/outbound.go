@@ -2,6 +2,7 @@ package outbound
 
 import (
 	"context"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/features"
@@ -43,3 +44,79 @@ type Manager interface {
 func ManagerType() interface{} {
 	return (*Manager)(nil)
 }
+
+// ConnectionCount is the number of connections in progress through a handler.
+type ConnectionCount struct {
+	Tag         string
+	Connections int
+}
+
+// ConnectionReporter is implemented by Managers which count the connections in progress through
+// their handlers.
+type ConnectionReporter interface {
+	// GetActiveConnections returns the connections in progress through each tagged handler.
+	GetActiveConnections() []ConnectionCount
+}
+
+// MaintenanceManager is implemented by Managers which allow taking outbounds administratively
+// down. Balancers skip outbounds in maintenance regardless of their health.
+type MaintenanceManager interface {
+	// SetMaintenance puts the outbound with the given tag in or out of maintenance.
+	SetMaintenance(tag string, maintenance bool) error
+	// InMaintenance returns true if the outbound with the given tag is in maintenance.
+	InMaintenance(tag string) bool
+	// GetMaintenance returns the tags of the outbounds in maintenance.
+	GetMaintenance() []string
+	// Suspend keeps the outbound with the given tag in maintenance for the given duration.
+	// It's used when an outbound is found failing before health checks notice.
+	Suspend(tag string, duration time.Duration)
+}
+
+// HopStatus is the health of an outbound by the connections it handled, which tells the failing
+// hop of a chain of outbounds apart from the others.
+type HopStatus struct {
+	Successes       uint64
+	Failures        uint64
+	LastError       string
+	LastErrorTime   time.Time
+	LastSuccessTime time.Time
+}
+
+// ChainedHandler is implemented by Handlers which may send their connections through another
+// outbound, by proxySettings or dialerProxy.
+type ChainedHandler interface {
+	Handler
+	// NextHop returns the tag of the outbound the connections go through next, or "" if they're
+	// dialed directly.
+	NextHop() string
+	// HopStatus returns the health of the handler.
+	HopStatus() HopStatus
+}
+
+// StandbyManager is implemented by Managers which fail traffic over from outbounds to their warm
+// standbys.
+type StandbyManager interface {
+	// ActiveFor returns the tag of the outbound serving the traffic routed to tag: one of its
+	// standbys if it failed, or tag itself.
+	ActiveFor(tag string) string
+}
+
+// DrainingHandler is an outbound being drained: removed for new connections, but kept until its
+// connections finish.
+type DrainingHandler struct {
+	Tag string
+	// Connections still going through the outbound.
+	Connections int
+	// Deadline is when the outbound is closed, whether its connections finished or not.
+	Deadline time.Time
+}
+
+// DrainManager is implemented by Managers which remove outbounds without breaking the connections
+// going through them.
+type DrainManager interface {
+	// DrainHandler removes the handler with the given tag like RemoveHandler, and closes it once
+	// its connections finish, or after timeout. Another handler may be added with the tag meanwhile.
+	DrainHandler(ctx context.Context, tag string, timeout time.Duration) error
+	// GetDrainingHandlers returns the handlers being drained.
+	GetDrainingHandlers() []DrainingHandler
+}
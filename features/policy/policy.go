@@ -19,6 +19,13 @@ type Timeout struct {
 	UplinkOnly time.Duration
 	// Timeout for an downlink only connection, i.e., the uplink of the connection has been closed.
 	DownlinkOnly time.Duration
+	// Timeout for establishing an outbound connection, including the handshakes of its transport.
+	// 16s by default, which TCP connections always had. 0 leaves it unbounded.
+	Dial time.Duration
+	// Timeout for a DNS query, including the wait for a DoH server or one reached through an
+	// outbound. 4s by default, which queries always had, so slow servers need a longer one. A
+	// query without one still gives up its requests after the default.
+	Resolve time.Duration
 }
 
 // Stats contains settings for stats counters.
@@ -35,6 +42,9 @@ type Stats struct {
 type Buffer struct {
 	// Size of buffer per connection, in bytes. -1 for unlimited buffer.
 	PerConnection int32
+	// Whether to grow the buffer of a connection to the bandwidth-delay product of its outbound.
+	// PerConnection is the minimum then.
+	Auto bool
 }
 
 // SystemStats contains stat policy settings on system level.
@@ -47,6 +57,16 @@ type SystemStats struct {
 	OutboundUplink bool
 	// Whether or not to enable stat counter for downlink traffic in outbound handlers.
 	OutboundDownlink bool
+	// Whether or not to enable stat histograms for the handshake stages of outbound handlers.
+	OutboundHandshake bool
+	// Whether or not to enable stat counter for uplink traffic by the registered domain of destinations.
+	DomainUplink bool
+	// Whether or not to enable stat counter for downlink traffic by the registered domain of destinations.
+	DomainDownlink bool
+	// Most domains counted at a time. The counters of the least recently seen domains are removed beyond it.
+	DomainLimit int
+	// Whether or not to enable stat counters for the connections and traffic of routing rules with tags.
+	Rule bool
 }
 
 // System contains policy settings at system level.
@@ -121,6 +141,8 @@ func SessionDefault() Session {
 			ConnectionIdle: time.Second * 300,
 			UplinkOnly:     time.Second * 1,
 			DownlinkOnly:   time.Second * 1,
+			Dial:           time.Second * 16,
+			Resolve:        time.Second * 4,
 		},
 		Stats: Stats{
 			UserUplink:   false,
@@ -0,0 +1,84 @@
+package policy
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Stage is a step in handling a connection, bounded by one of the Timeouts.
+type Stage int
+
+const (
+	// StageDial is establishing an outbound connection.
+	StageDial Stage = iota
+	// StageResolve is a DNS query.
+	StageResolve
+)
+
+func (t Timeout) of(stage Stage) time.Duration {
+	switch stage {
+	case StageDial:
+		return t.Dial
+	case StageResolve:
+		return t.Resolve
+	default:
+		return 0
+	}
+}
+
+const timeoutsKey policyKey = 1
+
+// ContextWithTimeouts returns a context whose stages are bounded by t.
+func ContextWithTimeouts(ctx context.Context, t Timeout) context.Context {
+	return context.WithValue(ctx, timeoutsKey, t)
+}
+
+// TimeoutsFromContext returns the Timeouts in ctx, or the default ones.
+func TimeoutsFromContext(ctx context.Context) Timeout {
+	if t, ok := ctx.Value(timeoutsKey).(Timeout); ok {
+		return t
+	}
+	return SessionDefault().Timeouts
+}
+
+// WithStageTimeout returns a context which is canceled if stage takes longer than its timeout
+// in ctx, and a function to call once the stage is done. This is the one place where stage
+// timeouts are enforced.
+//
+// Unlike context.WithTimeout, the context is neither canceled nor has a deadline once the
+// stage is done, so that it can be kept by what the stage created, like a connection.
+func WithStageTimeout(ctx context.Context, stage Stage) (context.Context, func()) {
+	timeout := TimeoutsFromContext(ctx).of(stage)
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	parent, cancel := context.WithCancelCause(ctx)
+	c := &stageContext{
+		Context:  parent,
+		deadline: time.Now().Add(timeout),
+	}
+	timer := time.AfterFunc(timeout, func() {
+		cancel(context.DeadlineExceeded)
+	})
+	return c, func() {
+		if timer.Stop() {
+			c.done.Store(true)
+		}
+	}
+}
+
+type stageContext struct {
+	context.Context
+	deadline time.Time
+	done     atomic.Bool
+}
+
+// Deadline implements context.Context.
+func (c *stageContext) Deadline() (time.Time, bool) {
+	deadline, ok := c.Context.Deadline()
+	if c.done.Load() || (ok && deadline.Before(c.deadline)) {
+		return deadline, ok
+	}
+	return c.deadline, true
+}
@@ -0,0 +1,75 @@
+package policy_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/xtls/xray-core/features/policy"
+)
+
+func TestWithStageTimeout(t *testing.T) {
+	ctx := ContextWithTimeouts(context.Background(), Timeout{Dial: 50 * time.Millisecond})
+
+	start := time.Now()
+	stageCtx, done := WithStageTimeout(ctx, StageDial)
+	defer done()
+	if deadline, ok := stageCtx.Deadline(); !ok || deadline.Before(start.Add(50*time.Millisecond)) || deadline.After(time.Now().Add(50*time.Millisecond)) {
+		t.Error("unexpected deadline: ", deadline, ok)
+	}
+	select {
+	case <-stageCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("stage not canceled")
+	}
+	if err := context.Cause(stageCtx); err != context.DeadlineExceeded {
+		t.Error("cause: ", err)
+	}
+}
+
+func TestWithStageTimeoutDone(t *testing.T) {
+	ctx := ContextWithTimeouts(context.Background(), Timeout{Dial: 50 * time.Millisecond})
+
+	stageCtx, done := WithStageTimeout(ctx, StageDial)
+	done()
+	if _, ok := stageCtx.Deadline(); ok {
+		t.Error("deadline kept after the stage is done")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := stageCtx.Err(); err != nil {
+		t.Error("canceled after the stage is done: ", err)
+	}
+}
+
+func TestWithStageTimeoutParentDeadline(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	parentDeadline, _ := parent.Deadline()
+	ctx := ContextWithTimeouts(parent, Timeout{Resolve: time.Hour})
+
+	stageCtx, done := WithStageTimeout(ctx, StageResolve)
+	defer done()
+	if deadline, ok := stageCtx.Deadline(); !ok || !deadline.Equal(parentDeadline) {
+		t.Error("unexpected deadline: ", deadline, ok)
+	}
+}
+
+func TestWithStageTimeoutUnbounded(t *testing.T) {
+	ctx := ContextWithTimeouts(context.Background(), Timeout{})
+
+	stageCtx, done := WithStageTimeout(ctx, StageDial)
+	defer done()
+	if stageCtx != ctx {
+		t.Error("unbounded stage got a new context")
+	}
+}
+
+func TestTimeoutsFromContext(t *testing.T) {
+	if got, want := TimeoutsFromContext(context.Background()), SessionDefault().Timeouts; got != want {
+		t.Error("default timeouts: ", got, ", want ", want)
+	}
+	want := Timeout{Dial: time.Second, Resolve: 2 * time.Second}
+	if got := TimeoutsFromContext(ContextWithTimeouts(context.Background(), want)); got != want {
+		t.Error("timeouts: ", got, ", want ", want)
+	}
+}
@@ -8,3 +8,12 @@ type BalancerOverrider interface {
 type BalancerPrincipleTarget interface {
 	GetPrincipleTarget(tag string) ([]string, error)
 }
+
+type BalancerLister interface {
+	ListBalancers() []string
+}
+
+// BalancerCandidates is implemented by Routers which tell the outbounds a balancer picks from.
+type BalancerCandidates interface {
+	GetCandidates(tag string) ([]string, error)
+}
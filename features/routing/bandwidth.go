@@ -0,0 +1,34 @@
+package routing
+
+import "sync/atomic"
+
+// BandwidthClass is a named class of connections whose traffic shares an aggregate rate limit,
+// such as "bulk" for backups, so that they don't starve other connections over the same route.
+type BandwidthClass struct {
+	Name string
+	rate atomic.Int64
+}
+
+// NewBandwidthClass returns a class limited to rate bytes per second in each direction.
+func NewBandwidthClass(name string, rate int64) *BandwidthClass {
+	c := &BandwidthClass{Name: name}
+	c.rate.Store(rate)
+	return c
+}
+
+// Rate returns the most bytes per second the connections of the class transfer together in
+// each direction, or 0 for no limit.
+func (c *BandwidthClass) Rate() int64 {
+	return c.rate.Load()
+}
+
+// SetRate changes the limit of the class, which applies to its open connections as well.
+func (c *BandwidthClass) SetRate(rate int64) {
+	c.rate.Store(rate)
+}
+
+// ClassifiedRoute is implemented by Routes whose connections belong to a bandwidth class.
+type ClassifiedRoute interface {
+	// GetBandwidthClass returns the bandwidth class of the route, or nil.
+	GetBandwidthClass() *BandwidthClass
+}
@@ -17,6 +17,12 @@ type Context interface {
 	// GetSourcePort returns the source port of the connection.
 	GetSourcePort() net.Port
 
+	// GetLocalIPs returns the local IPs the inbound accepted the connection on.
+	GetLocalIPs() []net.IP
+
+	// GetLocalPort returns the local port the inbound accepted the connection on.
+	GetLocalPort() net.Port
+
 	// GetTargetIPs returns the target IP of the connection or resolved IPs of target domain.
 	GetTargetIPs() []net.IP
 
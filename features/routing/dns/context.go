@@ -3,10 +3,12 @@ package dns
 import (
 	"context"
 
+	c "github.com/xtls/xray-core/common/ctx"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/features/routing"
+	routing_session "github.com/xtls/xray-core/features/routing/session"
 )
 
 // ResolvableContext is an implementation of routing.Context, with domain resolving capability.
@@ -23,7 +25,11 @@ func (ctx *ResolvableContext) GetTargetIPs() []net.IP {
 	}
 
 	if domain := ctx.GetTargetDomain(); len(domain) != 0 {
-		ips, err := ctx.dnsClient.LookupIP(domain, dns.IPOption{
+		lookupCtx := context.Background()
+		if s, ok := ctx.Context.(*routing_session.Context); ok {
+			lookupCtx = c.ContextWithID(lookupCtx, s.ID)
+		}
+		ips, err := dns.LookupIPForSession(lookupCtx, ctx.dnsClient, domain, dns.IPOption{
 			IPv4Enable: true,
 			IPv6Enable: true,
 			FakeEnable: false,
@@ -32,7 +38,7 @@ func (ctx *ResolvableContext) GetTargetIPs() []net.IP {
 			ctx.resolvedIPs = ips
 			return ips
 		}
-		errors.LogInfoInner(context.Background(), err, "resolve ip for ", domain)
+		errors.LogInfoInner(lookupCtx, err, "resolve ip for ", domain)
 	}
 
 	if ips := ctx.Context.GetTargetIPs(); len(ips) != 0 {
@@ -47,3 +53,9 @@ func (ctx *ResolvableContext) GetTargetIPs() []net.IP {
 func ContextWithDNSClient(ctx routing.Context, client dns.Client) routing.Context {
 	return &ResolvableContext{Context: ctx, dnsClient: client}
 }
+
+// ContextWithResolvedIPs creates a new routing context whose GetTargetIPs() returns ips for the
+// target domain.
+func ContextWithResolvedIPs(ctx routing.Context, ips []net.IP) routing.Context {
+	return &ResolvableContext{Context: ctx, resolvedIPs: ips}
+}
@@ -0,0 +1,24 @@
+package routing
+
+// Mirror tells where the traffic of a connection is copied to, for analysis. The copy never
+// slows the connection down: data the mirror can't take in time is dropped.
+type Mirror struct {
+	// OutboundTag is the outbound the traffic is copied through, to the destination of the
+	// connection.
+	OutboundTag string
+	// Address is a local socket the traffic is copied to instead, "host:port" for TCP or a
+	// path for a Unix domain socket.
+	Address string
+	// SampleRate is the share of connections which are mirrored. 0 mirrors all of them.
+	SampleRate float32
+	// MaxBytes is the most bytes copied per direction of a connection. 0 for no limit.
+	MaxBytes int64
+	// Downlink copies the responses as well, on a stream of their own.
+	Downlink bool
+}
+
+// MirroredRoute is implemented by Routes whose traffic is mirrored.
+type MirroredRoute interface {
+	// GetMirror returns the mirror of the route, or nil.
+	GetMirror() *Mirror
+}
@@ -42,6 +42,12 @@ func RouterType() interface{} {
 	return (*Router)(nil)
 }
 
+// RuleCounterName returns the name of the counter of the rule tagged tag, such as
+// "rule>>>[tag]>>>connections" for name "connections".
+func RuleCounterName(tag, name string) string {
+	return "rule>>>" + tag + ">>>" + name
+}
+
 // DefaultRouter is an implementation of Router, which always returns ErrNoClue for routing decisions.
 type DefaultRouter struct{}
 
@@ -0,0 +1,20 @@
+package routing
+
+// RuleGroupInfo describes a named group of routing rules.
+type RuleGroupInfo struct {
+	Name    string
+	Enabled bool
+	Rules   int
+}
+
+// RuleGroupManager is implemented by Routers that allow toggling rule groups at runtime.
+type RuleGroupManager interface {
+	SetRuleGroupEnabled(group string, enabled bool) error
+	GetRuleGroups() []RuleGroupInfo
+}
+
+// RuleCounter is implemented by Routers which tell how many rules they have.
+type RuleCounter interface {
+	// RuleCount returns the number of routing rules, including those of disabled groups.
+	RuleCount() int
+}
@@ -3,6 +3,7 @@ package session
 import (
 	"context"
 
+	c "github.com/xtls/xray-core/common/ctx"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/features/routing"
@@ -13,6 +14,8 @@ type Context struct {
 	Inbound  *session.Inbound
 	Outbound *session.Outbound
 	Content  *session.Content
+	// ID is the ID of the session, for the logs of routing it.
+	ID c.ID
 }
 
 // GetInboundTag implements routing.Context.
@@ -44,6 +47,31 @@ func (ctx *Context) GetSourcePort() net.Port {
 	return ctx.Inbound.Source.Port
 }
 
+// GetLocalIPs implements routing.Context.
+func (ctx *Context) GetLocalIPs() []net.IP {
+	if ctx.Inbound == nil {
+		return nil
+	}
+	// Inbounds listening on all addresses accept on many.
+	if gateway := ctx.Inbound.Gateway; gateway.IsValid() && gateway.Address.Family().IsIP() && !gateway.Address.IP().IsUnspecified() {
+		return []net.IP{gateway.Address.IP()}
+	}
+	if ctx.Inbound.Conn != nil {
+		if local := net.DestinationFromAddr(ctx.Inbound.Conn.LocalAddr()); local.Address != nil && local.Address.Family().IsIP() {
+			return []net.IP{local.Address.IP()}
+		}
+	}
+	return nil
+}
+
+// GetLocalPort implements routing.Context.
+func (ctx *Context) GetLocalPort() net.Port {
+	if ctx.Inbound == nil || !ctx.Inbound.Gateway.IsValid() {
+		return 0
+	}
+	return ctx.Inbound.Gateway.Port
+}
+
 // GetTargetIPs implements routing.Context.
 func (ctx *Context) GetTargetIPs() []net.IP {
 	if ctx.Outbound == nil || !ctx.Outbound.Target.IsValid() {
@@ -130,5 +158,6 @@ func AsRoutingContext(ctx context.Context) routing.Context {
 		Inbound:  session.InboundFromContext(ctx),
 		Outbound: ob,
 		Content:  session.ContentFromContext(ctx),
+		ID:       c.IDFromContext(ctx),
 	}
 }
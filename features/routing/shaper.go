@@ -0,0 +1,31 @@
+package routing
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/features"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+// TrafficShaper limits the traffic of connections by their inbound, user and outbound, with
+// rates and monthly quotas.
+type TrafficShaper interface {
+	features.Feature
+
+	// Shape returns how the traffic of a connection routed to outboundTag is limited, or nil if
+	// it isn't. It returns an error if a used up quota rejects the connection.
+	Shape(ctx context.Context, outboundTag string) (*Shaping, error)
+}
+
+// Shaping is how the traffic of a connection is limited.
+type Shaping struct {
+	// Classes are the rate limits the traffic counts against.
+	Classes []*BandwidthClass
+	// Counters count the traffic in both directions against quotas.
+	Counters []stats.Counter
+}
+
+// TrafficShaperType returns the type of TrafficShaper interface. Can be used to implement common.HasType.
+func TrafficShaperType() interface{} {
+	return (*TrafficShaper)(nil)
+}
@@ -0,0 +1,27 @@
+package routing
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/features"
+)
+
+// ConnectionTracker follows protocols which announce related connections, such as FTP, so
+// the related connections take the outbound of the connection which announced them.
+type ConnectionTracker interface {
+	features.Feature
+
+	// Track returns the writer for the downlink of a connection to destination which is
+	// routed to outboundTag. The returned writer may inspect and rewrite the content.
+	Track(ctx context.Context, destination net.Destination, outboundTag string, writer buf.Writer) buf.Writer
+	// OutboundFor returns the outbound tag a connection to destination must take, or "" if
+	// it isn't related to a tracked connection.
+	OutboundFor(ctx context.Context, destination net.Destination) string
+}
+
+// ConnectionTrackerType returns the type of ConnectionTracker interface. Can be used to implement common.HasType.
+func ConnectionTrackerType() interface{} {
+	return (*ConnectionTracker)(nil)
+}
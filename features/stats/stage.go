@@ -0,0 +1,62 @@
+package stats
+
+import (
+	"context"
+	"time"
+)
+
+// Stage is a step of establishing an outbound connection, which is timed on its own.
+type Stage string
+
+const (
+	// StageConnect is the connection of the transport to the server, such as TCP connect.
+	StageConnect Stage = "connect"
+	// StageTLS is the TLS or REALITY handshake.
+	StageTLS Stage = "tls"
+	// StageProtocol is the handshake of the proxy protocol, up to its response.
+	StageProtocol Stage = "protocol"
+	// StageFirstByte is the time from dispatching a request to the first byte of its response.
+	StageFirstByte Stage = "firstByte"
+)
+
+// Stages are all the stages, in the order they happen.
+var Stages = []Stage{StageConnect, StageTLS, StageProtocol, StageFirstByte}
+
+// StageRecorder records how long a stage took.
+type StageRecorder func(Stage, time.Duration)
+
+type stageKey int
+
+const (
+	stageRecorderKey stageKey = iota
+	hopRecorderKey
+)
+
+// HopRecorder records that a stage of the outbound with the given tag, a hop of a chain, is
+// over, as it happens.
+type HopRecorder func(tag string, stage Stage)
+
+// ContextWithHopRecorder returns a context in which the stages of each outbound the connection
+// goes through are recorded by r, so that the hops of chains can be timed apart.
+func ContextWithHopRecorder(ctx context.Context, r HopRecorder) context.Context {
+	return context.WithValue(ctx, hopRecorderKey, r)
+}
+
+// HopRecorderFromContext returns the HopRecorder of ctx, or nil.
+func HopRecorderFromContext(ctx context.Context) HopRecorder {
+	r, _ := ctx.Value(hopRecorderKey).(HopRecorder)
+	return r
+}
+
+// ContextWithStageRecorder returns a context in which the stages of connections are recorded by r.
+func ContextWithStageRecorder(ctx context.Context, r StageRecorder) context.Context {
+	return context.WithValue(ctx, stageRecorderKey, r)
+}
+
+// RecordStage records that stage, which began at start, is over. It does nothing if the
+// context has no StageRecorder.
+func RecordStage(ctx context.Context, stage Stage, start time.Time) {
+	if r, ok := ctx.Value(stageRecorderKey).(StageRecorder); ok {
+		r(stage, time.Since(start))
+	}
+}
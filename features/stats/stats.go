@@ -35,6 +35,27 @@ type OnlineMap interface {
 	IpTimeMap() map[string]time.Time
 }
 
+// Histogram is the interface for stats histograms of durations.
+//
+// xray:api:beta
+type Histogram interface {
+	// Observe adds a duration to the histogram.
+	Observe(time.Duration)
+	// Snapshot returns the current state of the histogram.
+	Snapshot() HistogramSnapshot
+}
+
+// HistogramSnapshot is the state of a Histogram at some point.
+type HistogramSnapshot struct {
+	// Upper bounds of the buckets, in increasing order.
+	Bounds []time.Duration
+	// Number of durations in each bucket, with one more bucket for those above the last bound.
+	Counts []int64
+	// Number and sum of all durations.
+	Count int64
+	Sum   time.Duration
+}
+
 // Channel is the interface for stats channel.
 //
 // xray:api:stable
@@ -92,6 +113,13 @@ type Manager interface {
 	// GetOnlineMap returns a onlinemap by its identifier.
 	GetOnlineMap(string) OnlineMap
 
+	// RegisterHistogram registers a new histogram to the manager. The identifier string must not be empty, and unique among other histograms.
+	RegisterHistogram(string) (Histogram, error)
+	// UnregisterHistogram unregisters a histogram from the manager by its identifier.
+	UnregisterHistogram(string) error
+	// GetHistogram returns a histogram by its identifier.
+	GetHistogram(string) Histogram
+
 	// RegisterChannel registers a new channel to the manager. The identifier string must not be empty, and unique among other channels.
 	RegisterChannel(string) (Channel, error)
 	// UnregisterChannel unregisters a channel from the manager by its identifier.
@@ -120,6 +148,16 @@ func GetOrRegisterOnlineMap(m Manager, name string) (OnlineMap, error) {
 	return m.RegisterOnlineMap(name)
 }
 
+// GetOrRegisterHistogram tries to get the Histogram first. If not exist, it then tries to create a new histogram.
+func GetOrRegisterHistogram(m Manager, name string) (Histogram, error) {
+	histogram := m.GetHistogram(name)
+	if histogram != nil {
+		return histogram, nil
+	}
+
+	return m.RegisterHistogram(name)
+}
+
 // GetOrRegisterChannel tries to get the StatChannel first. If not exist, it then tries to create a new channel.
 func GetOrRegisterChannel(m Manager, name string) (Channel, error) {
 	channel := m.GetChannel(name)
@@ -175,6 +213,21 @@ func (NoopManager) GetOnlineMap(string) OnlineMap {
 	return nil
 }
 
+// RegisterHistogram implements Manager.
+func (NoopManager) RegisterHistogram(string) (Histogram, error) {
+	return nil, errors.New("not implemented")
+}
+
+// UnregisterHistogram implements Manager.
+func (NoopManager) UnregisterHistogram(string) error {
+	return nil
+}
+
+// GetHistogram implements Manager.
+func (NoopManager) GetHistogram(string) Histogram {
+	return nil
+}
+
 // RegisterChannel implements Manager.
 func (NoopManager) RegisterChannel(string) (Channel, error) {
 	return nil, errors.New("not implemented")
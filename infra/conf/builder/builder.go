@@ -0,0 +1,132 @@
+// Package builder provides typed Go builders for Xray configs.
+//
+// Builders produce the same JSON a user would write by hand, and Build runs it through the regular
+// JSON loader, so a config built here is validated exactly like a config file.
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf/serial"
+)
+
+// Config is the root of a config under construction.
+type Config struct {
+	log       *logSettings
+	inbounds  []*Inbound
+	outbounds []*Outbound
+	rules     []*Rule
+	strategy  string
+}
+
+type logSettings struct {
+	LogLevel string `json:"loglevel,omitempty"`
+	Access   string `json:"access,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Rule is a routing rule. Conditions that are empty are omitted.
+type Rule struct {
+	Domain      []string `json:"domain,omitempty"`
+	IP          []string `json:"ip,omitempty"`
+	Port        string   `json:"port,omitempty"`
+	Network     string   `json:"network,omitempty"`
+	InboundTag  []string `json:"inboundTag,omitempty"`
+	Protocol    []string `json:"protocol,omitempty"`
+	OutboundTag string   `json:"outboundTag,omitempty"`
+	BalancerTag string   `json:"balancerTag,omitempty"`
+}
+
+// NewConfig returns an empty config.
+func NewConfig() *Config {
+	return &Config{}
+}
+
+// WithLogLevel sets the log level, e.g. "warning" or "debug".
+func (c *Config) WithLogLevel(level string) *Config {
+	if c.log == nil {
+		c.log = &logSettings{}
+	}
+	c.log.LogLevel = level
+	return c
+}
+
+// WithLogFiles sets the access and error log files.
+func (c *Config) WithLogFiles(access, errorLog string) *Config {
+	if c.log == nil {
+		c.log = &logSettings{}
+	}
+	c.log.Access = access
+	c.log.Error = errorLog
+	return c
+}
+
+// AddInbound appends an inbound.
+func (c *Config) AddInbound(in *Inbound) *Config {
+	c.inbounds = append(c.inbounds, in)
+	return c
+}
+
+// AddOutbound appends an outbound. The first outbound is the default one.
+func (c *Config) AddOutbound(out *Outbound) *Config {
+	c.outbounds = append(c.outbounds, out)
+	return c
+}
+
+// AddRule appends a routing rule.
+func (c *Config) AddRule(rule *Rule) *Config {
+	c.rules = append(c.rules, rule)
+	return c
+}
+
+// WithDomainStrategy sets the routing domain strategy, e.g. "IPIfNonMatch".
+func (c *Config) WithDomainStrategy(strategy string) *Config {
+	c.strategy = strategy
+	return c
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c *Config) MarshalJSON() ([]byte, error) {
+	type routing struct {
+		DomainStrategy string  `json:"domainStrategy,omitempty"`
+		Rules          []*Rule `json:"rules,omitempty"`
+	}
+	root := struct {
+		Log       *logSettings `json:"log,omitempty"`
+		Routing   *routing     `json:"routing,omitempty"`
+		Inbounds  []*Inbound   `json:"inbounds,omitempty"`
+		Outbounds []*Outbound  `json:"outbounds,omitempty"`
+	}{
+		Log:       c.log,
+		Inbounds:  c.inbounds,
+		Outbounds: c.outbounds,
+	}
+	if len(c.rules) > 0 || c.strategy != "" {
+		root.Routing = &routing{
+			DomainStrategy: c.strategy,
+			Rules:          c.rules,
+		}
+	}
+	return json.Marshal(root)
+}
+
+// JSON returns the config as indented JSON, ready to be written to a config file.
+func (c *Config) JSON() ([]byte, error) {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, errors.New("failed to marshal config").Base(err)
+	}
+	return b, nil
+}
+
+// Build validates the config and converts it to protobuf, ready to be passed to core.New.
+func (c *Config) Build() (*core.Config, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, errors.New("failed to marshal config").Base(err)
+	}
+	return serial.LoadJSONConfig(bytes.NewReader(b))
+}
@@ -0,0 +1,49 @@
+package builder_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	. "github.com/xtls/xray-core/infra/conf/builder"
+)
+
+func TestConfigBuild(t *testing.T) {
+	config := NewConfig().
+		WithLogLevel("warning").
+		AddInbound(NewSOCKSInbound("127.0.0.1", 10808).WithTag("socks").WithSniffing(true, "http", "tls")).
+		AddOutbound(NewVLESSOutbound("example.com", 443, "27848739-7e62-4138-9fd3-098a63964b6b").
+			WithTag("proxy").
+			WithFlow("xtls-rprx-vision").
+			WithReality(&Reality{
+				ServerName:  "www.example.com",
+				PublicKey:   "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+				ShortID:     "0123abcd",
+				Fingerprint: "chrome",
+			})).
+		AddOutbound(NewFreedomOutbound().WithTag("direct")).
+		AddRule(&Rule{Domain: []string{"domain:example.org"}, OutboundTag: "direct"})
+
+	pb, err := config.Build()
+	common.Must(err)
+	if len(pb.Inbound) != 1 || len(pb.Outbound) != 2 {
+		t.Fatal("unexpected handlers: ", len(pb.Inbound), " ", len(pb.Outbound))
+	}
+	if pb.Outbound[0].Tag != "proxy" {
+		t.Error("expected proxy as default outbound, but got ", pb.Outbound[0].Tag)
+	}
+
+	b, err := config.JSON()
+	common.Must(err)
+	if !bytes.Contains(b, []byte(`"realitySettings"`)) {
+		t.Error("expected realitySettings in ", string(b))
+	}
+}
+
+func TestConfigBuildInvalid(t *testing.T) {
+	config := NewConfig().AddOutbound(NewVLESSOutbound("example.com", 443, "27848739-7e62-4138-9fd3-098a63964b6b").
+		WithReality(&Reality{PublicKey: "invalid"}))
+	if _, err := config.Build(); err == nil {
+		t.Error("expected error for invalid REALITY public key")
+	}
+}
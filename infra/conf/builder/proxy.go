@@ -0,0 +1,266 @@
+package builder
+
+// Outbound is an outbound under construction.
+type Outbound struct {
+	Protocol string          `json:"protocol"`
+	Tag      string          `json:"tag,omitempty"`
+	Settings interface{}     `json:"settings,omitempty"`
+	Stream   *streamSettings `json:"streamSettings,omitempty"`
+	Mux      *mux            `json:"mux,omitempty"`
+}
+
+// Inbound is an inbound under construction.
+type Inbound struct {
+	Protocol string          `json:"protocol"`
+	Tag      string          `json:"tag,omitempty"`
+	Listen   string          `json:"listen,omitempty"`
+	Port     uint16          `json:"port"`
+	Settings interface{}     `json:"settings,omitempty"`
+	Stream   *streamSettings `json:"streamSettings,omitempty"`
+	Sniffing *sniffing       `json:"sniffing,omitempty"`
+}
+
+type mux struct {
+	Enabled     bool  `json:"enabled"`
+	Concurrency int16 `json:"concurrency,omitempty"`
+}
+
+type sniffing struct {
+	Enabled      bool     `json:"enabled"`
+	DestOverride []string `json:"destOverride,omitempty"`
+	RouteOnly    bool     `json:"routeOnly,omitempty"`
+}
+
+type user struct {
+	ID         string `json:"id,omitempty"`
+	Password   string `json:"password,omitempty"`
+	Encryption string `json:"encryption,omitempty"`
+	Security   string `json:"security,omitempty"`
+	Flow       string `json:"flow,omitempty"`
+	Email      string `json:"email,omitempty"`
+}
+
+type vnext struct {
+	Address string  `json:"address"`
+	Port    uint16  `json:"port"`
+	Users   []*user `json:"users"`
+}
+
+type server struct {
+	Address  string `json:"address"`
+	Port     uint16 `json:"port"`
+	Method   string `json:"method,omitempty"`
+	Password string `json:"password"`
+	Flow     string `json:"flow,omitempty"`
+}
+
+type vnextSettings struct {
+	Vnext []*vnext `json:"vnext"`
+}
+
+type serverSettings struct {
+	Servers []*server `json:"servers"`
+}
+
+// NewVLESSOutbound returns a VLESS outbound to the given server and user id.
+func NewVLESSOutbound(address string, port uint16, id string) *Outbound {
+	return &Outbound{
+		Protocol: "vless",
+		Settings: &vnextSettings{Vnext: []*vnext{{
+			Address: address,
+			Port:    port,
+			Users:   []*user{{ID: id, Encryption: "none"}},
+		}}},
+	}
+}
+
+// NewVMessOutbound returns a VMess outbound to the given server and user id.
+func NewVMessOutbound(address string, port uint16, id string) *Outbound {
+	return &Outbound{
+		Protocol: "vmess",
+		Settings: &vnextSettings{Vnext: []*vnext{{
+			Address: address,
+			Port:    port,
+			Users:   []*user{{ID: id, Security: "auto"}},
+		}}},
+	}
+}
+
+// NewTrojanOutbound returns a Trojan outbound to the given server.
+func NewTrojanOutbound(address string, port uint16, password string) *Outbound {
+	return &Outbound{
+		Protocol: "trojan",
+		Settings: &serverSettings{Servers: []*server{{
+			Address:  address,
+			Port:     port,
+			Password: password,
+		}}},
+	}
+}
+
+// NewShadowsocksOutbound returns a Shadowsocks outbound to the given server.
+func NewShadowsocksOutbound(address string, port uint16, method, password string) *Outbound {
+	return &Outbound{
+		Protocol: "shadowsocks",
+		Settings: &serverSettings{Servers: []*server{{
+			Address:  address,
+			Port:     port,
+			Method:   method,
+			Password: password,
+		}}},
+	}
+}
+
+// NewFreedomOutbound returns an outbound that connects directly.
+func NewFreedomOutbound() *Outbound {
+	return &Outbound{Protocol: "freedom"}
+}
+
+// NewBlackholeOutbound returns an outbound that drops all traffic.
+func NewBlackholeOutbound() *Outbound {
+	return &Outbound{Protocol: "blackhole"}
+}
+
+// WithTag sets the outbound tag.
+func (o *Outbound) WithTag(tag string) *Outbound {
+	o.Tag = tag
+	return o
+}
+
+// WithFlow sets the flow of the VLESS or Trojan user, e.g. "xtls-rprx-vision".
+func (o *Outbound) WithFlow(flow string) *Outbound {
+	switch s := o.Settings.(type) {
+	case *vnextSettings:
+		for _, v := range s.Vnext {
+			for _, u := range v.Users {
+				u.Flow = flow
+			}
+		}
+	case *serverSettings:
+		for _, v := range s.Servers {
+			v.Flow = flow
+		}
+	}
+	return o
+}
+
+// WithTLS enables TLS security.
+func (o *Outbound) WithTLS(tls *TLS) *Outbound {
+	o.Stream = o.Stream.withTLS(tls)
+	return o
+}
+
+// WithReality enables REALITY security.
+func (o *Outbound) WithReality(reality *Reality) *Outbound {
+	o.Stream = o.Stream.withReality(reality)
+	return o
+}
+
+// WithTransport sets the transport protocol.
+func (o *Outbound) WithTransport(transport Transport) *Outbound {
+	o.Stream = o.Stream.withTransport(transport)
+	return o
+}
+
+// WithMux enables mux with the given concurrency. Zero keeps the default concurrency.
+func (o *Outbound) WithMux(concurrency int16) *Outbound {
+	o.Mux = &mux{Enabled: true, Concurrency: concurrency}
+	return o
+}
+
+type socksSettings struct {
+	Auth string `json:"auth"`
+	UDP  bool   `json:"udp"`
+}
+
+type clientSettings struct {
+	Clients    []*user `json:"clients"`
+	Decryption string  `json:"decryption,omitempty"`
+}
+
+// NewSOCKSInbound returns a SOCKS inbound without authentication, with UDP enabled.
+func NewSOCKSInbound(listen string, port uint16) *Inbound {
+	return &Inbound{
+		Protocol: "socks",
+		Listen:   listen,
+		Port:     port,
+		Settings: &socksSettings{Auth: "noauth", UDP: true},
+	}
+}
+
+// NewHTTPInbound returns an HTTP proxy inbound.
+func NewHTTPInbound(listen string, port uint16) *Inbound {
+	return &Inbound{
+		Protocol: "http",
+		Listen:   listen,
+		Port:     port,
+	}
+}
+
+// NewVLESSInbound returns a VLESS inbound accepting the given user ids.
+func NewVLESSInbound(listen string, port uint16, ids ...string) *Inbound {
+	settings := &clientSettings{Decryption: "none"}
+	for _, id := range ids {
+		settings.Clients = append(settings.Clients, &user{ID: id})
+	}
+	return &Inbound{
+		Protocol: "vless",
+		Listen:   listen,
+		Port:     port,
+		Settings: settings,
+	}
+}
+
+// NewTrojanInbound returns a Trojan inbound accepting the given passwords.
+func NewTrojanInbound(listen string, port uint16, passwords ...string) *Inbound {
+	settings := &clientSettings{}
+	for _, password := range passwords {
+		settings.Clients = append(settings.Clients, &user{Password: password})
+	}
+	return &Inbound{
+		Protocol: "trojan",
+		Listen:   listen,
+		Port:     port,
+		Settings: settings,
+	}
+}
+
+// WithTag sets the inbound tag.
+func (i *Inbound) WithTag(tag string) *Inbound {
+	i.Tag = tag
+	return i
+}
+
+// WithFlow sets the flow of all VLESS or Trojan clients, e.g. "xtls-rprx-vision".
+func (i *Inbound) WithFlow(flow string) *Inbound {
+	if s, ok := i.Settings.(*clientSettings); ok {
+		for _, u := range s.Clients {
+			u.Flow = flow
+		}
+	}
+	return i
+}
+
+// WithTLS enables TLS security.
+func (i *Inbound) WithTLS(tls *TLS) *Inbound {
+	i.Stream = i.Stream.withTLS(tls)
+	return i
+}
+
+// WithReality enables REALITY security.
+func (i *Inbound) WithReality(reality *Reality) *Inbound {
+	i.Stream = i.Stream.withReality(reality)
+	return i
+}
+
+// WithTransport sets the transport protocol.
+func (i *Inbound) WithTransport(transport Transport) *Inbound {
+	i.Stream = i.Stream.withTransport(transport)
+	return i
+}
+
+// WithSniffing enables sniffing with the given protocols, e.g. "http", "tls", "quic".
+func (i *Inbound) WithSniffing(routeOnly bool, destOverride ...string) *Inbound {
+	i.Sniffing = &sniffing{Enabled: true, DestOverride: destOverride, RouteOnly: routeOnly}
+	return i
+}
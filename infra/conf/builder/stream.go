@@ -0,0 +1,119 @@
+package builder
+
+// TLS is the client or server side of TLS security.
+type TLS struct {
+	ServerName    string   `json:"serverName,omitempty"`
+	ALPN          []string `json:"alpn,omitempty"`
+	Fingerprint   string   `json:"fingerprint,omitempty"`
+	AllowInsecure bool     `json:"allowInsecure,omitempty"`
+	// Certificates are used on the server side.
+	Certificates []*Certificate `json:"certificates,omitempty"`
+}
+
+// Certificate is a TLS certificate loaded from files.
+type Certificate struct {
+	CertificateFile string `json:"certificateFile"`
+	KeyFile         string `json:"keyFile"`
+}
+
+// Reality is the client or server side of REALITY security.
+// Client side uses ServerName, PublicKey, ShortID, Fingerprint and SpiderX;
+// server side uses Target, ServerNames, PrivateKey and ShortIDs.
+type Reality struct {
+	ServerName  string `json:"serverName,omitempty"`
+	PublicKey   string `json:"publicKey,omitempty"`
+	ShortID     string `json:"shortId,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	SpiderX     string `json:"spiderX,omitempty"`
+
+	Target      string   `json:"target,omitempty"`
+	ServerNames []string `json:"serverNames,omitempty"`
+	PrivateKey  string   `json:"privateKey,omitempty"`
+	ShortIDs    []string `json:"shortIds,omitempty"`
+}
+
+// Transport is a transport protocol for stream settings. Implementations are
+// RAW, WebSocket, GRPC and XHTTP.
+type Transport interface {
+	apply(*streamSettings)
+}
+
+// RAW is the plain TCP transport.
+type RAW struct{}
+
+// WebSocket is the WebSocket transport.
+type WebSocket struct {
+	Path string `json:"path,omitempty"`
+	Host string `json:"host,omitempty"`
+}
+
+// GRPC is the gRPC transport.
+type GRPC struct {
+	ServiceName string `json:"serviceName,omitempty"`
+	MultiMode   bool   `json:"multiMode,omitempty"`
+}
+
+// XHTTP is the XHTTP transport.
+type XHTTP struct {
+	Path string `json:"path,omitempty"`
+	Host string `json:"host,omitempty"`
+	Mode string `json:"mode,omitempty"`
+}
+
+func (RAW) apply(s *streamSettings) {
+	s.Network = "raw"
+}
+
+func (t WebSocket) apply(s *streamSettings) {
+	s.Network = "ws"
+	s.WS = &t
+}
+
+func (t GRPC) apply(s *streamSettings) {
+	s.Network = "grpc"
+	s.GRPC = &t
+}
+
+func (t XHTTP) apply(s *streamSettings) {
+	s.Network = "xhttp"
+	s.XHTTP = &t
+}
+
+type streamSettings struct {
+	Network  string     `json:"network,omitempty"`
+	Security string     `json:"security,omitempty"`
+	TLS      *TLS       `json:"tlsSettings,omitempty"`
+	Reality  *Reality   `json:"realitySettings,omitempty"`
+	WS       *WebSocket `json:"wsSettings,omitempty"`
+	GRPC     *GRPC      `json:"grpcSettings,omitempty"`
+	XHTTP    *XHTTP     `json:"xhttpSettings,omitempty"`
+}
+
+func (s *streamSettings) withTLS(tls *TLS) *streamSettings {
+	if s == nil {
+		s = &streamSettings{}
+	}
+	s.Security = "tls"
+	s.TLS = tls
+	s.Reality = nil
+	return s
+}
+
+func (s *streamSettings) withReality(reality *Reality) *streamSettings {
+	if s == nil {
+		s = &streamSettings{}
+	}
+	s.Security = "reality"
+	s.Reality = reality
+	s.TLS = nil
+	return s
+}
+
+func (s *streamSettings) withTransport(transport Transport) *streamSettings {
+	if s == nil {
+		s = &streamSettings{}
+	}
+	s.WS, s.GRPC, s.XHTTP = nil, nil, nil
+	transport.apply(s)
+	return s
+}
@@ -267,6 +267,57 @@ func (v *User) Build() *protocol.User {
 	}
 }
 
+// ReplayConfig is the JSON form of protocol.ReplayPolicy.
+type ReplayConfig struct {
+	Action      string `json:"action"`
+	BanDuration uint32 `json:"banDuration"`
+}
+
+func (c *ReplayConfig) Build() (*protocol.ReplayPolicy, error) {
+	policy := &protocol.ReplayPolicy{
+		BanDuration: c.BanDuration,
+	}
+	switch strings.ToLower(c.Action) {
+	case "", "fallback":
+		policy.Action = protocol.ReplayPolicy_Fallback
+	case "drop":
+		policy.Action = protocol.ReplayPolicy_Drop
+	case "ban":
+		policy.Action = protocol.ReplayPolicy_Ban
+	default:
+		return nil, errors.New("unknown replay action: ", c.Action)
+	}
+	return policy, nil
+}
+
+// HandshakeShapingConfig is the JSON form of protocol.HandshakeShaping.
+type HandshakeShapingConfig struct {
+	Packets   uint32      `json:"packets"`
+	Length    *Int32Range `json:"length"`
+	Interval  *Int32Range `json:"interval"`
+	PadHeader bool        `json:"padHeader"`
+}
+
+func (c *HandshakeShapingConfig) Build() (*protocol.HandshakeShaping, error) {
+	shaping := &protocol.HandshakeShaping{
+		Packets:   c.Packets,
+		PadHeader: c.PadHeader,
+	}
+	if c.Packets > 0 {
+		if c.Length == nil || c.Length.From <= 0 {
+			return nil, errors.New("handshake segments need a positive length")
+		}
+		shaping.LengthMin, shaping.LengthMax = uint32(c.Length.From), uint32(c.Length.To)
+		if c.Interval != nil {
+			if c.Interval.From < 0 {
+				return nil, errors.New("invalid handshake segment interval ", c.Interval)
+			}
+			shaping.IntervalMin, shaping.IntervalMax = uint32(c.Interval.From), uint32(c.Interval.To)
+		}
+	}
+	return shaping, nil
+}
+
 // Int32Range deserializes from "1-2" or 1, so can deserialize from both int and number.
 // Negative integers can be passed as sentinel values, but do not parse as ranges.
 // Value will be exchanged if From > To, use .Left and .Right to get original value if need.
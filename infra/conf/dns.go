@@ -3,6 +3,7 @@ package conf
 import (
 	"encoding/json"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/xtls/xray-core/app/dns"
@@ -19,6 +20,9 @@ type NameServerConfig struct {
 	Domains       []string   `json:"domains"`
 	ExpectIPs     StringList `json:"expectIps"`
 	QueryStrategy string     `json:"queryStrategy"`
+	Tag           string     `json:"tag"`
+	OutboundTag   string     `json:"outboundTag"`
+	Bootstrap     StringList `json:"bootstrap"`
 }
 
 func (c *NameServerConfig) UnmarshalJSON(data []byte) error {
@@ -36,6 +40,9 @@ func (c *NameServerConfig) UnmarshalJSON(data []byte) error {
 		Domains       []string   `json:"domains"`
 		ExpectIPs     StringList `json:"expectIps"`
 		QueryStrategy string     `json:"queryStrategy"`
+		Tag           string     `json:"tag"`
+		OutboundTag   string     `json:"outboundTag"`
+		Bootstrap     StringList `json:"bootstrap"`
 	}
 	if err := json.Unmarshal(data, &advanced); err == nil {
 		c.Address = advanced.Address
@@ -45,6 +52,9 @@ func (c *NameServerConfig) UnmarshalJSON(data []byte) error {
 		c.Domains = advanced.Domains
 		c.ExpectIPs = advanced.ExpectIPs
 		c.QueryStrategy = advanced.QueryStrategy
+		c.Tag = advanced.Tag
+		c.OutboundTag = advanced.OutboundTag
+		c.Bootstrap = advanced.Bootstrap
 		return nil
 	}
 
@@ -70,16 +80,31 @@ func (c *NameServerConfig) Build() (*dns.NameServer, error) {
 	if c.Address == nil {
 		return nil, errors.New("NameServer address is not specified.")
 	}
+	if c.OutboundTag != "" && c.isLocal() {
+		return nil, errors.New("name server ", c.Address.String(), " is queried without outbounds, outboundTag is not supported")
+	}
 
 	var domains []*dns.NameServer_PriorityDomain
 	var originalRules []*dns.NameServer_OriginalRule
 
+	var excludedDomains []*dns.NameServer_PriorityDomain
+
 	for _, rule := range c.Domains {
-		parsedDomain, err := parseDomainRule(rule)
+		excluded, domain := parseDomainException(rule)
+		parsedDomain, err := parseDomainRule(domain)
 		if err != nil {
 			return nil, errors.New("invalid domain rule: ", rule).Base(err)
 		}
 
+		if excluded {
+			for _, pd := range parsedDomain {
+				excludedDomains = append(excludedDomains, &dns.NameServer_PriorityDomain{
+					Type:   toDomainMatchingType(pd.Type),
+					Domain: pd.Value,
+				})
+			}
+			continue
+		}
 		for _, pd := range parsedDomain {
 			domains = append(domains, &dns.NameServer_PriorityDomain{
 				Type:   toDomainMatchingType(pd.Type),
@@ -105,6 +130,19 @@ func (c *NameServerConfig) Build() (*dns.NameServer, error) {
 		myClientIP = []byte(c.ClientIP.IP())
 	}
 
+	// The bootstrap is either IPs, or the tag of another name server.
+	var bootstrapIPs [][]byte
+	var bootstrapTag string
+	for _, item := range c.Bootstrap {
+		if address := net.ParseAddress(item); address.Family().IsIP() {
+			bootstrapIPs = append(bootstrapIPs, []byte(address.IP()))
+		} else if len(c.Bootstrap) == 1 {
+			bootstrapTag = item
+		} else {
+			return nil, errors.New("bootstrap of name server ", c.Address.String(), " is neither IPs nor a name server tag: ", item)
+		}
+	}
+
 	return &dns.NameServer{
 		Address: &net.Endpoint{
 			Network: net.Network_UDP,
@@ -117,9 +155,24 @@ func (c *NameServerConfig) Build() (*dns.NameServer, error) {
 		Geoip:             geoipList,
 		OriginalRules:     originalRules,
 		QueryStrategy:     resolveQueryStrategy(c.QueryStrategy),
+		ExcludedDomain:    excludedDomains,
+		Tag:               c.Tag,
+		OutboundTag:       c.OutboundTag,
+		BootstrapIp:       bootstrapIPs,
+		BootstrapTag:      bootstrapTag,
 	}, nil
 }
 
+// isLocal tells whether the name server is queried by the system or directly, not through
+// outbounds.
+func (c *NameServerConfig) isLocal() bool {
+	if !c.Address.Family().IsDomain() {
+		return false
+	}
+	address := strings.ToLower(c.Address.Domain())
+	return address == "localhost" || address == "fakedns" || strings.Contains(address, "+local://")
+}
+
 var typeMap = map[router.Domain_Type]dns.DomainMatchingType{
 	router.Domain_Full:   dns.DomainMatchingType_Full,
 	router.Domain_Domain: dns.DomainMatchingType_Subdomain,
@@ -137,6 +190,106 @@ type DNSConfig struct {
 	DisableCache           bool                `json:"disableCache"`
 	DisableFallback        bool                `json:"disableFallback"`
 	DisableFallbackIfMatch bool                `json:"disableFallbackIfMatch"`
+	DisableFailover        bool                `json:"disableFailover"`
+	BogusIPs               StringList          `json:"bogusIps"`
+	FilterBogons           bool                `json:"filterBogons"`
+	ResolveInternal        bool                `json:"resolveInternal"`
+	Regions                []*DNSRegionConfig  `json:"regions"`
+	Bootstrap              *NameServerConfig   `json:"bootstrap"`
+	PartitionCache         bool                `json:"partitionCache"`
+}
+
+// DNSRegionConfig sends the queries for the domains of a region, like geosite:cn, to the name
+// servers of the region, with the client IP of the region. Answers out of the IPs expected of
+// the region are dropped and the domain is queried again with the servers, which the region's
+// servers are never used as a fallback for.
+type DNSRegionConfig struct {
+	Name        string              `json:"name"`
+	Domains     []string            `json:"domains"`
+	ExpectIPs   StringList          `json:"expectIps"`
+	ClientIP    *Address            `json:"clientIp"`
+	OutboundTag string              `json:"outboundTag"`
+	Servers     []*NameServerConfig `json:"servers"`
+}
+
+// nameServers returns the name servers of the region, set to the domains, client IP, expected
+// IPs and outbound of the region unless they set their own.
+func (r *DNSRegionConfig) nameServers() ([]*NameServerConfig, error) {
+	if len(r.Domains) == 0 {
+		return nil, errors.New("no domains")
+	}
+	if len(r.Servers) == 0 {
+		return nil, errors.New("no servers")
+	}
+	servers := make([]*NameServerConfig, 0, len(r.Servers))
+	for _, server := range r.Servers {
+		if len(server.Domains) > 0 {
+			return nil, errors.New("name server ", server.Address, " sets domains, which are those of the region")
+		}
+		ns := *server
+		ns.Domains = r.Domains
+		ns.SkipFallback = true
+		if len(ns.ExpectIPs) == 0 {
+			ns.ExpectIPs = r.ExpectIPs
+		}
+		if ns.ClientIP == nil {
+			ns.ClientIP = r.ClientIP
+		}
+		if ns.OutboundTag == "" {
+			ns.OutboundTag = r.OutboundTag
+		}
+		servers = append(servers, &ns)
+	}
+	return servers, nil
+}
+
+// outboundTags returns the outbound tags the name servers are set to.
+func (c *DNSConfig) outboundTags() []string {
+	var tags []string
+	for _, server := range c.Servers {
+		if server.OutboundTag != "" {
+			tags = append(tags, server.OutboundTag)
+		}
+	}
+	for _, region := range c.Regions {
+		if region.OutboundTag != "" {
+			tags = append(tags, region.OutboundTag)
+		}
+		for _, server := range region.Servers {
+			if server.OutboundTag != "" {
+				tags = append(tags, server.OutboundTag)
+			}
+		}
+	}
+	if c.Bootstrap != nil && c.Bootstrap.OutboundTag != "" {
+		tags = append(tags, c.Bootstrap.OutboundTag)
+	}
+	return tags
+}
+
+// bootstrapDomains returns the domains of the servers of outbounds, which the bootstrap name
+// server is queried for unless it's given domains of its own.
+func bootstrapDomains(outbounds []OutboundDetourConfig) []*dns.NameServer_PriorityDomain {
+	var domains []*dns.NameServer_PriorityDomain
+	found := make(map[string]bool)
+	for i := range outbounds {
+		for _, server := range outbounds[i].ServerAddresses() {
+			host, _, err := net.SplitHostPort(server)
+			if err != nil || host == "" || net.ParseAddress(host).Family().IsIP() {
+				continue
+			}
+			host = strings.ToLower(host)
+			if found[host] {
+				continue
+			}
+			found[host] = true
+			domains = append(domains, &dns.NameServer_PriorityDomain{
+				Type:   dns.DomainMatchingType_Full,
+				Domain: host,
+			})
+		}
+	}
+	return domains
 }
 
 type HostAddress struct {
@@ -316,9 +469,21 @@ func (c *DNSConfig) Build() (*dns.Config, error) {
 		DisableCache:           c.DisableCache,
 		DisableFallback:        c.DisableFallback,
 		DisableFallbackIfMatch: c.DisableFallbackIfMatch,
+		DisableFailover:        c.DisableFailover,
+		FilterBogons:           c.FilterBogons,
+		ResolveInternal:        c.ResolveInternal,
+		PartitionCache:         c.PartitionCache,
 		QueryStrategy:          resolveQueryStrategy(c.QueryStrategy),
 	}
 
+	if len(c.BogusIPs) > 0 {
+		bogusIPs, err := ToCidrList(c.BogusIPs)
+		if err != nil {
+			return nil, errors.New("invalid bogus IP rule: ", c.BogusIPs).Base(err)
+		}
+		config.BogusIp = bogusIPs
+	}
+
 	if c.ClientIP != nil {
 		if !c.ClientIP.Family().IsIP() {
 			return nil, errors.New("not an IP address:", c.ClientIP.String())
@@ -326,6 +491,33 @@ func (c *DNSConfig) Build() (*dns.Config, error) {
 		config.ClientIp = []byte(c.ClientIP.IP())
 	}
 
+	for i, region := range c.Regions {
+		name := region.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		servers, err := region.nameServers()
+		if err != nil {
+			return nil, errors.New("invalid DNS region ", name).Base(err)
+		}
+		if len(region.ExpectIPs) > 0 {
+			// Answers out of the region are queried again with the servers.
+			if len(c.Servers) == 0 {
+				return nil, errors.New("DNS region ", name, " expects IPs, but there are no servers to fall back to")
+			}
+			if c.DisableFallback || c.DisableFallbackIfMatch {
+				return nil, errors.New("DNS region ", name, " expects IPs, which needs fallback to be enabled")
+			}
+		}
+		for _, server := range servers {
+			ns, err := server.Build()
+			if err != nil {
+				return nil, errors.New("failed to build nameserver of DNS region ", name).Base(err)
+			}
+			config.NameServer = append(config.NameServer, ns)
+		}
+	}
+
 	for _, server := range c.Servers {
 		ns, err := server.Build()
 		if err != nil {
@@ -334,6 +526,17 @@ func (c *DNSConfig) Build() (*dns.Config, error) {
 		config.NameServer = append(config.NameServer, ns)
 	}
 
+	if c.Bootstrap != nil {
+		if len(c.Bootstrap.Bootstrap) > 0 {
+			return nil, errors.New("bootstrap nameserver can't have a bootstrap of its own")
+		}
+		ns, err := c.Bootstrap.Build()
+		if err != nil {
+			return nil, errors.New("failed to build bootstrap nameserver").Base(err)
+		}
+		config.Bootstrap = ns
+	}
+
 	if c.Hosts != nil {
 		staticHosts, err := c.Hosts.Build()
 		if err != nil {
@@ -38,3 +38,32 @@ func (c *DNSOutboundConfig) Build() (proto.Message, error) {
 	config.BlockTypes = c.BlockTypes
 	return config, nil
 }
+
+// DNSInboundConfig is the config of the DNS inbound, which answers A and AAAA queries with the DNS
+// app, and forwards other queries to the server at address if it's set.
+type DNSInboundConfig struct {
+	Network   Network  `json:"network"`
+	Address   *Address `json:"address"`
+	Port      uint16   `json:"port"`
+	UserLevel uint32   `json:"userLevel"`
+	CacheSize uint32   `json:"cacheSize"`
+	FakeIP    bool     `json:"fakeIP"`
+}
+
+func (c *DNSInboundConfig) Build() (proto.Message, error) {
+	config := &dns.ServerConfig{
+		UserLevel: c.UserLevel,
+		CacheSize: c.CacheSize,
+		FakeIp:    c.FakeIP,
+	}
+	if c.Address != nil {
+		config.Server = &net.Endpoint{
+			Network: c.Network.Build(),
+			Address: c.Address.Build(),
+			Port:    uint32(c.Port),
+		}
+	} else if c.Network != "" || c.Port != 0 {
+		return nil, errors.New(`"network" and "port" of DNS inbound are for its "address"`)
+	}
+	return config, nil
+}
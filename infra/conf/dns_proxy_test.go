@@ -32,3 +32,36 @@ func TestDnsProxyConfig(t *testing.T) {
 		},
 	})
 }
+
+func TestDnsInboundConfig(t *testing.T) {
+	creator := func() Buildable {
+		return new(DNSInboundConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"fakeIP": true
+			}`,
+			Parser: loadJSON(creator),
+			Output: &dns.ServerConfig{
+				FakeIp: true,
+			},
+		},
+		{
+			Input: `{
+				"address": "1.1.1.1",
+				"network": "tcp",
+				"cacheSize": 100
+			}`,
+			Parser: loadJSON(creator),
+			Output: &dns.ServerConfig{
+				Server: &net.Endpoint{
+					Network: net.Network_TCP,
+					Address: net.NewIPOrDomain(net.IPAddress([]byte{1, 1, 1, 1})),
+				},
+				CacheSize: 100,
+			},
+		},
+	})
+}
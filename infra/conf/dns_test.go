@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/xtls/xray-core/app/dns"
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/net"
 	. "github.com/xtls/xray-core/infra/conf"
 	"google.golang.org/protobuf/proto"
@@ -103,5 +105,187 @@ func TestDNSConfigParsing(t *testing.T) {
 				DisableFallback: true,
 			},
 		},
+		{
+			Input: `{
+				"servers": [{"address": "8.8.8.8", "port": 53}],
+				"bogusIps": ["1.2.3.4"],
+				"filterBogons": true,
+				"resolveInternal": true
+			}`,
+			Parser: parserCreator(),
+			Output: &dns.Config{
+				NameServer: []*dns.NameServer{
+					{
+						Address: &net.Endpoint{
+							Network: net.Network_UDP,
+							Address: &net.IPOrDomain{
+								Address: &net.IPOrDomain_Ip{
+									Ip: []byte{8, 8, 8, 8},
+								},
+							},
+							Port: 53,
+						},
+					},
+				},
+				BogusIp: []*router.GeoIP{
+					{
+						Cidr: []*router.CIDR{
+							{
+								Ip:     []byte{1, 2, 3, 4},
+								Prefix: 32,
+							},
+						},
+					},
+				},
+				FilterBogons:    true,
+				ResolveInternal: true,
+			},
+		},
+		{
+			Input: `{
+				"regions": [{
+					"name": "cn",
+					"domains": ["domain:example.cn"],
+					"expectIps": ["10.0.0.0/8"],
+					"clientIp": "10.0.0.1",
+					"servers": ["8.8.8.8"]
+				}],
+				"servers": [{"address": "https://1.1.1.1/dns-query", "outboundTag": "proxy"}]
+			}`,
+			Parser: parserCreator(),
+			Output: &dns.Config{
+				NameServer: []*dns.NameServer{
+					{
+						Address: &net.Endpoint{
+							Network: net.Network_UDP,
+							Address: &net.IPOrDomain{
+								Address: &net.IPOrDomain_Ip{
+									Ip: []byte{8, 8, 8, 8},
+								},
+							},
+						},
+						ClientIp:     []byte{10, 0, 0, 1},
+						SkipFallback: true,
+						PrioritizedDomain: []*dns.NameServer_PriorityDomain{
+							{
+								Type:   dns.DomainMatchingType_Subdomain,
+								Domain: "example.cn",
+							},
+						},
+						OriginalRules: []*dns.NameServer_OriginalRule{
+							{
+								Rule: "domain:example.cn",
+								Size: 1,
+							},
+						},
+						Geoip: []*router.GeoIP{
+							{
+								Cidr: []*router.CIDR{
+									{
+										Ip:     []byte{10, 0, 0, 0},
+										Prefix: 8,
+									},
+								},
+							},
+						},
+					},
+					{
+						Address: &net.Endpoint{
+							Network: net.Network_UDP,
+							Address: &net.IPOrDomain{
+								Address: &net.IPOrDomain_Domain{
+									Domain: "https://1.1.1.1/dns-query",
+								},
+							},
+						},
+						OutboundTag: "proxy",
+					},
+				},
+			},
+		},
 	})
 }
+
+func TestDNSRegionErrors(t *testing.T) {
+	for _, input := range []string{
+		// Nothing to fall back to for answers out of the region.
+		`{"regions": [{"domains": ["domain:example.cn"], "expectIps": ["10.0.0.0/8"], "servers": ["8.8.8.8"]}]}`,
+		`{"regions": [{"domains": ["domain:example.cn"], "expectIps": ["10.0.0.0/8"], "servers": ["8.8.8.8"]}],
+		  "servers": ["1.1.1.1"], "disableFallback": true}`,
+		`{"regions": [{"domains": ["domain:example.cn"]}]}`,
+		`{"regions": [{"servers": ["8.8.8.8"]}]}`,
+		`{"regions": [{"domains": ["domain:example.cn"], "servers": [{"address": "8.8.8.8", "domains": ["domain:example.com"]}]}]}`,
+		`{"servers": [{"address": "https+local://1.1.1.1/dns-query", "outboundTag": "proxy"}]}`,
+	} {
+		config := new(DNSConfig)
+		if err := json.Unmarshal([]byte(input), config); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := config.Build(); err == nil {
+			t.Error("expected error for ", input)
+		}
+	}
+}
+
+func TestDNSBootstrap(t *testing.T) {
+	config := new(Config)
+	common.Must(json.Unmarshal([]byte(`{
+		"dns": {
+			"servers": ["https://1.1.1.1/dns-query"],
+			"bootstrap": {"address": "localhost"}
+		},
+		"outbounds": [{
+			"protocol": "vless",
+			"settings": {"vnext": [{"address": "Proxy.Example.com", "port": 443, "users": [{"id": "27848739-7e62-4138-9fd3-098a63964b6b", "encryption": "none"}]}]}
+		}, {
+			"protocol": "trojan",
+			"settings": {"servers": [{"address": "1.2.3.4", "port": 443, "password": "secret"}]}
+		}, {
+			"protocol": "freedom"
+		}]
+	}`), config))
+	built, err := config.Build()
+	common.Must(err)
+
+	var dnsConfig *dns.Config
+	for _, app := range built.App {
+		if instance, err := app.GetInstance(); err == nil {
+			if c, ok := instance.(*dns.Config); ok {
+				dnsConfig = c
+			}
+		}
+	}
+	if dnsConfig == nil || dnsConfig.Bootstrap == nil {
+		t.Fatal("no bootstrap name server")
+	}
+	// Server IPs need no resolving.
+	domains := dnsConfig.Bootstrap.PrioritizedDomain
+	expected := &dns.NameServer_PriorityDomain{Type: dns.DomainMatchingType_Full, Domain: "proxy.example.com"}
+	if len(domains) != 1 || !proto.Equal(domains[0], expected) {
+		t.Error("unexpected bootstrap domains: ", domains)
+	}
+}
+
+func TestNameServerBootstrap(t *testing.T) {
+	config := new(DNSConfig)
+	common.Must(json.Unmarshal([]byte(`{
+		"servers": [
+			{"address": "https://dns.google/dns-query", "bootstrap": ["8.8.8.8", "2001:4860:4860::8888"]},
+			{"address": "tcp+local://dns.example.com", "bootstrap": "local"},
+			{"address": "223.5.5.5", "tag": "local"}
+		]
+	}`), config))
+	built, err := config.Build()
+	common.Must(err)
+	if ns := built.NameServer[0]; len(ns.BootstrapIp) != 2 || net.IP(ns.BootstrapIp[0]).String() != "8.8.8.8" || ns.BootstrapTag != "" {
+		t.Error("unexpected bootstrap IPs ", ns.BootstrapIp, " and tag ", ns.BootstrapTag)
+	}
+	if ns := built.NameServer[1]; len(ns.BootstrapIp) != 0 || ns.BootstrapTag != "local" {
+		t.Error("unexpected bootstrap IPs ", ns.BootstrapIp, " and tag ", ns.BootstrapTag)
+	}
+
+	common.Must(json.Unmarshal([]byte(`{"servers": [{"address": "https://dns.google/dns-query", "bootstrap": ["8.8.8.8", "local"]}]}`), config))
+	if _, err := config.Build(); err == nil {
+		t.Error("expected error for bootstrap of both IPs and a tag")
+	}
+}
@@ -0,0 +1,97 @@
+//go:build !xray_client && !xray_minimal_router
+
+package conf
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/proxy/fallback"
+	"google.golang.org/protobuf/proto"
+)
+
+func init() {
+	common.Must(inboundConfigLoader.cache.RegisterCreator("fallback", func() interface{} { return new(FallbackInboundConfig) }))
+}
+
+// FallbackInboundConfig configures an inbound that only relays connections to its fallbacks.
+// Fallbacks take the same fields as those of VLESS.
+type FallbackInboundConfig struct {
+	Fallbacks []*VLessInboundFallback `json:"fallbacks"`
+	UserLevel uint32                  `json:"userLevel"`
+}
+
+// Build implements Buildable
+func (c *FallbackInboundConfig) Build() (proto.Message, error) {
+	config := &fallback.Config{
+		UserLevel: c.UserLevel,
+	}
+	for _, fb := range c.Fallbacks {
+		var i uint16
+		var s string
+		if err := json.Unmarshal(fb.Dest, &i); err == nil {
+			s = strconv.Itoa(int(i))
+		} else {
+			_ = json.Unmarshal(fb.Dest, &s)
+		}
+		forwardHeaders, err := buildForwardHeaders(fb.ForwardHeaders)
+		if err != nil {
+			return nil, errors.New(`fallback settings: invalid "forwardHeaders"`).Base(err)
+		}
+		config.Fallbacks = append(config.Fallbacks, &fallback.Fallback{
+			Name: strings.ToLower(fb.Name),
+			Alpn: strings.ToLower(fb.Alpn),
+			Path: fb.Path,
+			Type: fb.Type,
+			Dest: s,
+			Xver: fb.Xver,
+
+			ForwardHeaders: forwardHeaders,
+		})
+	}
+	if len(config.Fallbacks) == 0 {
+		return nil, errors.New(`fallback settings: no "fallbacks" specified`)
+	}
+	for _, fb := range config.Fallbacks {
+		if fb.Path != "" && fb.Path[0] != '/' {
+			return nil, errors.New(`fallback settings: "path" must be empty or start with "/"`)
+		}
+		if fb.Type == "" && fb.Dest != "" {
+			if strings.HasPrefix(fb.Dest, builtinSitePrefix) {
+				fb.Type = "site"
+				fb.Dest = fb.Dest[len(builtinSitePrefix):]
+			} else if filepath.IsAbs(fb.Dest) || fb.Dest[0] == '@' {
+				fb.Type = "unix"
+				if strings.HasPrefix(fb.Dest, "@@") && (runtime.GOOS == "linux" || runtime.GOOS == "android") {
+					fullAddr := make([]byte, len(syscall.RawSockaddrUnix{}.Path)) // may need padding to work with haproxy
+					copy(fullAddr, fb.Dest[1:])
+					fb.Dest = string(fullAddr)
+				}
+			} else {
+				if _, err := strconv.Atoi(fb.Dest); err == nil {
+					fb.Dest = "127.0.0.1:" + fb.Dest
+				}
+				if _, _, err := net.SplitHostPort(fb.Dest); err == nil {
+					fb.Type = "tcp"
+				}
+			}
+		}
+		if fb.Type == "" {
+			return nil, errors.New(`fallback settings: please fill in a valid value for every "dest"`)
+		}
+		if fb.Type == "site" && fb.Xver != 0 {
+			return nil, errors.New(`fallback settings: "xver" is not supported by the built-in site`)
+		}
+		if fb.Xver > 2 {
+			return nil, errors.New(`fallback settings: invalid PROXY protocol version, "xver" only accepts 0, 1, 2`)
+		}
+	}
+	return config, nil
+}
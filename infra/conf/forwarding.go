@@ -0,0 +1,117 @@
+package conf
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+// ForwardingConfig forwards a local port to a destination, through an outbound if one is given.
+// It's expanded into a dokodemo-door inbound, and a routing rule from it to the outbound ahead of
+// the other rules.
+type ForwardingConfig struct {
+	// Listen is a port, or an address and a port. The address defaults to 127.0.0.1.
+	Listen      json.RawMessage `json:"listen"`
+	Destination string          `json:"destination"`
+	Network     *NetworkList    `json:"network"`
+	OutboundTag string          `json:"outboundTag"`
+	// Tag is the tag of the inbound, "forwarding-" and the index of the forwarding by default.
+	Tag string `json:"tag"`
+}
+
+// expand returns the inbound and the routing rule, if any, of the i-th forwarding.
+func (c *ForwardingConfig) expand(i int) (*InboundDetourConfig, json.RawMessage, error) {
+	host := "127.0.0.1"
+	var port uint16
+	if err := json.Unmarshal(c.Listen, &port); err != nil {
+		var listen string
+		if err := json.Unmarshal(c.Listen, &listen); err != nil {
+			return nil, nil, errors.New(`forwarding: invalid "listen"`).Base(err)
+		}
+		if p, err := strconv.ParseUint(listen, 10, 16); err == nil {
+			port = uint16(p)
+		} else {
+			h, p, err := net.SplitHostPort(listen)
+			if err != nil {
+				return nil, nil, errors.New(`forwarding: invalid "listen" `, listen).Base(err)
+			}
+			if h != "" {
+				host = h
+			}
+			pp, err := net.PortFromString(p)
+			if err != nil {
+				return nil, nil, errors.New(`forwarding: invalid "listen" `, listen).Base(err)
+			}
+			port = uint16(pp)
+		}
+	}
+	if port == 0 {
+		return nil, nil, errors.New(`forwarding: no port in "listen"`)
+	}
+
+	destHost, destPort, err := net.SplitHostPort(c.Destination)
+	if err != nil {
+		return nil, nil, errors.New(`forwarding: invalid "destination" `, c.Destination).Base(err)
+	}
+	dp, err := net.PortFromString(destPort)
+	if err != nil {
+		return nil, nil, errors.New(`forwarding: invalid "destination" `, c.Destination).Base(err)
+	}
+	settings := map[string]interface{}{
+		"address": destHost,
+		"port":    dp,
+	}
+	if c.Network != nil {
+		settings["network"] = c.Network
+	}
+	rawSettings, err := json.Marshal(settings)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw := json.RawMessage(rawSettings)
+
+	tag := c.Tag
+	if tag == "" {
+		tag = "forwarding-" + strconv.Itoa(i)
+	}
+	inbound := &InboundDetourConfig{
+		Protocol: "dokodemo-door",
+		PortList: &PortList{Range: []PortRange{{From: uint32(port), To: uint32(port)}}},
+		ListenOn: &Address{Address: net.ParseAddress(host)},
+		Settings: &raw,
+		Tag:      tag,
+	}
+	if c.OutboundTag == "" {
+		return inbound, nil, nil
+	}
+	rule, err := json.Marshal(map[string]interface{}{
+		"inboundTag":  []string{tag},
+		"outboundTag": c.OutboundTag,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return inbound, rule, nil
+}
+
+// buildForwarding expands the forwarding into inbounds, and routing rules to their outbounds.
+func buildForwarding(forwarding []*ForwardingConfig, outbounds []OutboundDetourConfig) ([]InboundDetourConfig, []json.RawMessage, error) {
+	var inbounds []InboundDetourConfig
+	var rules []json.RawMessage
+	for i, f := range forwarding {
+		inbound, rule, err := f.expand(i)
+		if err != nil {
+			return nil, nil, err
+		}
+		if f.OutboundTag != "" && !hasOutbound(outbounds, f.OutboundTag) {
+			return nil, nil, errors.New("outbound ", f.OutboundTag, " of forwarding ", inbound.Tag, " not found")
+		}
+		inbounds = append(inbounds, *inbound)
+		if rule != nil {
+			rules = append(rules, rule)
+		}
+	}
+	return inbounds, rules, nil
+}
@@ -21,6 +21,7 @@ type FreedomConfig struct {
 	Noise          *Noise    `json:"noise"`
 	Noises         []*Noise  `json:"noises"`
 	ProxyProtocol  uint32    `json:"proxyProtocol"`
+	DisableSplice  bool      `json:"disableSplice"`
 }
 
 type Fragment struct {
@@ -125,6 +126,7 @@ func (c *FreedomConfig) Build() (proto.Message, error) {
 	}
 
 	config.UserLevel = c.UserLevel
+	config.DisableSplice = c.DisableSplice
 	if len(c.Redirect) > 0 {
 		host, portStr, err := net.SplitHostPort(c.Redirect)
 		if err != nil {
@@ -0,0 +1,26 @@
+package conf
+
+import (
+	"github.com/xtls/xray-core/app/ftp"
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// FTPConfig is the JSON config of the FTP helper.
+type FTPConfig struct {
+	Ports          []uint32 `json:"ports"`
+	RewritePassive bool     `json:"rewritePassive"`
+	Timeout        uint32   `json:"timeout"`
+}
+
+func (c *FTPConfig) Build() (*ftp.Config, error) {
+	for _, port := range c.Ports {
+		if port == 0 || port > 65535 {
+			return nil, errors.New("invalid FTP port: ", port)
+		}
+	}
+	return &ftp.Config{
+		Ports:          c.Ports,
+		RewritePassive: c.RewritePassive,
+		Timeout:        c.Timeout,
+	}, nil
+}
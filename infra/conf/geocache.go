@@ -0,0 +1,244 @@
+package conf
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/platform"
+	"github.com/xtls/xray-core/common/platform/filesystem"
+)
+
+// geoCacheHeader identifies the dat file a geoCache was taken from.
+type geoCacheHeader struct {
+	SHA256  string `json:"sha256"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"modTime"`
+}
+
+// geoCache keeps the lists taken from a dat file, so that later starts neither parse nor read
+// the dat file. Its file is valid for the hash of the dat file, which is only computed again
+// when the size or modification time of the dat file changed. It's only used when
+// "xray.location.cache" sets a cache directory.
+//
+// The file holds the header as a line of JSON, followed by records of a varint-prefixed key
+// and a varint-prefixed marshaled list.
+type geoCache struct {
+	sync.Mutex
+	path    string
+	header  geoCacheHeader
+	entries map[string][]byte
+	file    *os.File // opened on the first new entry
+	broken  bool     // stop writing after a failure
+}
+
+var (
+	geoCacheAccess sync.Mutex
+	geoCaches      = make(map[string]*geoCache) // by path of the dat file
+)
+
+// geoCacheFor returns the cache of the dat file, or nil if there is none.
+func geoCacheFor(file string) *geoCache {
+	dir := platform.GetCacheDirectory()
+	if dir == "" {
+		return nil
+	}
+	datPath := platform.GetAssetLocation(file)
+	info, err := os.Stat(datPath)
+	if err != nil {
+		return nil
+	}
+
+	geoCacheAccess.Lock()
+	defer geoCacheAccess.Unlock()
+
+	if c := geoCaches[datPath]; c != nil {
+		if c.header.Size == info.Size() && c.header.ModTime == info.ModTime().UnixNano() {
+			return c
+		}
+		c.close()
+	}
+	c, err := openGeoCache(dir, file, datPath, info)
+	if err != nil {
+		errors.LogDebugInner(context.Background(), err, "cache of ", file, " is off")
+		return nil
+	}
+	geoCaches[datPath] = c
+	return c
+}
+
+func openGeoCache(dir, file, datPath string, info os.FileInfo) (*geoCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	id := sha256.Sum256([]byte(datPath))
+	c := &geoCache{
+		path: filepath.Join(dir, fmt.Sprintf("%s-%x.cache", filepath.Base(datPath), id[:4])),
+		header: geoCacheHeader{
+			Size:    info.Size(),
+			ModTime: info.ModTime().UnixNano(),
+		},
+	}
+
+	header, entries, complete := readGeoCache(c.path)
+	if header.Size == c.header.Size && header.ModTime == c.header.ModTime && header.SHA256 != "" {
+		c.header.SHA256 = header.SHA256
+		c.entries = entries
+		if complete {
+			return c, nil
+		}
+		return c, c.rewrite()
+	}
+
+	sum, err := hashAsset(file)
+	if err != nil {
+		return nil, err
+	}
+	c.header.SHA256 = sum
+	if header.SHA256 == sum {
+		// Touched but unchanged.
+		c.entries = entries
+	} else {
+		c.entries = make(map[string][]byte)
+	}
+	return c, c.rewrite()
+}
+
+func hashAsset(file string) (string, error) {
+	data, unmap, err := filesystem.MapAsset(file)
+	if err != nil {
+		return "", err
+	}
+	defer unmap()
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// readGeoCache reads what's valid in the cache file. complete is false if the file ends in
+// a partial record.
+func readGeoCache(path string) (header geoCacheHeader, entries map[string][]byte, complete bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	line, err := r.ReadBytes('\n')
+	if err != nil || json.Unmarshal(line, &header) != nil {
+		return geoCacheHeader{}, nil, false
+	}
+	entries = make(map[string][]byte)
+	for {
+		key, err := readGeoCacheField(r)
+		if err == io.EOF {
+			return header, entries, true
+		}
+		if err != nil {
+			return header, entries, false
+		}
+		data, err := readGeoCacheField(r)
+		if err != nil {
+			return header, entries, false
+		}
+		entries[string(key)] = data
+	}
+}
+
+func readGeoCacheField(r *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > 1<<30 {
+		return nil, errors.New("record too large")
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return b, nil
+}
+
+func appendGeoCacheRecord(b []byte, key string, data []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(len(key)))
+	b = append(b, key...)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// rewrite replaces the cache file with the header and entries of c.
+func (c *geoCache) rewrite() error {
+	b, err := json.Marshal(c.header)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	for key, data := range c.entries {
+		b = appendGeoCacheRecord(b, key, data)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// get returns the list cached for key.
+func (c *geoCache) get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.Lock()
+	defer c.Unlock()
+
+	data, ok := c.entries[key]
+	return data, ok
+}
+
+// put caches the marshaled list for key.
+func (c *geoCache) put(key string, data []byte) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+
+	if _, ok := c.entries[key]; ok || c.broken {
+		return
+	}
+	c.entries[key] = data
+	if c.file == nil {
+		f, err := os.OpenFile(c.path, os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			errors.LogDebugInner(context.Background(), err, "failed to open ", c.path)
+			c.broken = true
+			return
+		}
+		c.file = f
+	}
+	if _, err := c.file.Write(appendGeoCacheRecord(nil, key, data)); err != nil {
+		errors.LogDebugInner(context.Background(), err, "failed to write ", c.path)
+		c.broken = true
+	}
+}
+
+func (c *geoCache) close() {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.file != nil {
+		c.file.Close()
+		c.file = nil
+	}
+	c.broken = true
+}
@@ -1,3 +1,5 @@
+//go:build !xray_minimal_router
+
 package conf
 
 import (
@@ -5,17 +7,7 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
-type GRPCConfig struct {
-	Authority           string `json:"authority"`
-	ServiceName         string `json:"serviceName"`
-	MultiMode           bool   `json:"multiMode"`
-	IdleTimeout         int32  `json:"idle_timeout"`
-	HealthCheckTimeout  int32  `json:"health_check_timeout"`
-	PermitWithoutStream bool   `json:"permit_without_stream"`
-	InitialWindowsSize  int32  `json:"initial_windows_size"`
-	UserAgent           string `json:"user_agent"`
-}
-
+// Build implements Buildable.
 func (g *GRPCConfig) Build() (proto.Message, error) {
 	if g.IdleTimeout <= 0 {
 		g.IdleTimeout = 0
@@ -0,0 +1,43 @@
+package conf
+
+import (
+	"net"
+
+	"github.com/xtls/xray-core/app/httpapi"
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// HTTPAPIConfig is the config of the REST and WebSocket API for external GUIs.
+type HTTPAPIConfig struct {
+	Listen       string   `json:"listen"`
+	Secret       string   `json:"secret"`
+	AllowOrigins []string `json:"allowOrigins"`
+}
+
+// isLoopback returns whether address is only reachable from this host.
+func isLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (c *HTTPAPIConfig) Build() (*httpapi.Config, error) {
+	listen := c.Listen
+	if listen == "" {
+		listen = httpapi.DefaultListen
+	}
+	if !isLoopback(listen) && c.Secret == "" {
+		return nil, errors.New("HTTP API listening on ", listen, " beyond this host must have a secret")
+	}
+	return &httpapi.Config{
+		Listen:       listen,
+		Secret:       c.Secret,
+		AllowOrigins: c.AllowOrigins,
+	}, nil
+}
@@ -0,0 +1,116 @@
+//go:build !xray_minimal_router
+
+package conf
+
+import (
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/transport/internet/kcp"
+	"google.golang.org/protobuf/proto"
+)
+
+// Build implements Buildable.
+func (c *KCPConfig) Build() (proto.Message, error) {
+	config := new(kcp.Config)
+
+	switch strings.ToLower(c.Carrier) {
+	case "":
+	case "icmp":
+		config.Carrier = "icmp"
+	case "dns":
+		if c.CarrierDomain == "" {
+			return nil, errors.New("mKCP DNS carrier without carrierDomain").AtError()
+		}
+		config.Carrier = "dns"
+		config.CarrierDomain = c.CarrierDomain
+	default:
+		return nil, errors.New("unknown mKCP carrier: ", c.Carrier).AtError()
+	}
+	if config.Carrier != "" {
+		if c.HopPorts != nil || c.Rebinding || c.AutoMtu {
+			return nil, errors.New("mKCP carriers don't hop, rebind or search for the MTU").AtError()
+		}
+		config.CarrierRate = c.CarrierRate
+	}
+
+	if c.Mtu != nil {
+		mtu := *c.Mtu
+		if config.Carrier == "dns" {
+			// Names of queries carry much less than UDP.
+			if limit := kcp.DNSCarrierMTU(c.CarrierDomain); mtu < 64 || mtu > limit {
+				return nil, errors.New("invalid mKCP MTU size of the DNS carrier, at most ", limit, ": ", mtu).AtError()
+			}
+		} else if mtu < 576 || mtu > 1460 {
+			return nil, errors.New("invalid mKCP MTU size: ", mtu).AtError()
+		}
+		config.Mtu = &kcp.MTU{Value: mtu}
+	} else if config.Carrier == "dns" {
+		config.Mtu = &kcp.MTU{Value: kcp.DNSCarrierMTU(c.CarrierDomain)}
+	}
+	if c.Tti != nil {
+		tti := *c.Tti
+		if tti < 10 || tti > 100 {
+			return nil, errors.New("invalid mKCP TTI: ", tti).AtError()
+		}
+		config.Tti = &kcp.TTI{Value: tti}
+	}
+	if c.UpCap != nil {
+		config.UplinkCapacity = &kcp.UplinkCapacity{Value: *c.UpCap}
+	}
+	if c.DownCap != nil {
+		config.DownlinkCapacity = &kcp.DownlinkCapacity{Value: *c.DownCap}
+	}
+	if c.Congestion != nil {
+		config.Congestion = *c.Congestion
+	}
+	if c.ReadBufferSize != nil {
+		size := *c.ReadBufferSize
+		if size > 0 {
+			config.ReadBuffer = &kcp.ReadBuffer{Size: size * 1024 * 1024}
+		} else {
+			config.ReadBuffer = &kcp.ReadBuffer{Size: 512 * 1024}
+		}
+	}
+	if c.WriteBufferSize != nil {
+		size := *c.WriteBufferSize
+		if size > 0 {
+			config.WriteBuffer = &kcp.WriteBuffer{Size: size * 1024 * 1024}
+		} else {
+			config.WriteBuffer = &kcp.WriteBuffer{Size: 512 * 1024}
+		}
+	}
+	if len(c.HeaderConfig) > 0 {
+		headerConfig, _, err := kcpHeaderLoader.Load(c.HeaderConfig)
+		if err != nil {
+			return nil, errors.New("invalid mKCP header config.").Base(err).AtError()
+		}
+		ts, err := headerConfig.(Buildable).Build()
+		if err != nil {
+			return nil, errors.New("invalid mKCP header config").Base(err).AtError()
+		}
+		config.HeaderConfig = serial.ToTypedMessage(ts)
+	}
+
+	if c.Seed != nil {
+		config.Seed = &kcp.EncryptionSeed{Seed: *c.Seed}
+	}
+
+	if c.HopPorts != nil && len(c.HopPorts.Range) > 0 {
+		config.HopPorts = c.HopPorts.Build()
+		config.HopInterval = c.HopInterval
+	} else if c.HopInterval > 0 {
+		return nil, errors.New("mKCP hopInterval without hopPorts").AtError()
+	}
+
+	// Connections are closed after 30 seconds without packets.
+	if c.KeepaliveInterval > 15 {
+		return nil, errors.New("invalid mKCP keepaliveInterval: ", c.KeepaliveInterval).AtError()
+	}
+	config.KeepaliveInterval = c.KeepaliveInterval
+	config.Rebinding = c.Rebinding
+	config.AutoMtu = c.AutoMtu
+
+	return config, nil
+}
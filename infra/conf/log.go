@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"github.com/xtls/xray-core/app/log"
+	"github.com/xtls/xray-core/common/errors"
 	clog "github.com/xtls/xray-core/common/log"
 )
 
@@ -16,50 +17,111 @@ func DefaultLogConfig() *log.Config {
 }
 
 type LogConfig struct {
-	AccessLog   string `json:"access"`
-	ErrorLog    string `json:"error"`
-	LogLevel    string `json:"loglevel"`
-	DNSLog      bool   `json:"dnsLog"`
-	MaskAddress string `json:"maskAddress"`
+	AccessLog   string            `json:"access"`
+	ErrorLog    string            `json:"error"`
+	LogLevel    string            `json:"loglevel"`
+	DNSLog      bool              `json:"dnsLog"`
+	MaskAddress string            `json:"maskAddress"`
+	Levels      map[string]string `json:"levels"`
+	Format      string            `json:"format"`
+	MaxSize     uint32            `json:"maxSize"`
+	MaxBackups  uint32            `json:"maxBackups"`
+	Rotate      string            `json:"rotate"`
+	// MemorySize is the number of entries kept by the logs set to "memory", which the API reads
+	// instead of them being written.
+	MemorySize uint32 `json:"memorySize"`
 }
 
-func (v *LogConfig) Build() *log.Config {
+// rotateIntervals are the seconds log files are written to before they're rotated, by the
+// names of the intervals.
+var rotateIntervals = map[string]uint32{
+	"":       0,
+	"hourly": 3600,
+	"daily":  24 * 3600,
+	"weekly": 7 * 24 * 3600,
+}
+
+// parseLogLevel returns the severity of a log level name. "none" disables logging, and
+// unknown names mean "warning".
+func parseLogLevel(level string) clog.Severity {
+	switch level {
+	case "debug":
+		return clog.Severity_Debug
+	case "info":
+		return clog.Severity_Info
+	case "error":
+		return clog.Severity_Error
+	case "none":
+		return clog.Severity_Unknown
+	default:
+		return clog.Severity_Warning
+	}
+}
+
+func (v *LogConfig) Build() (*log.Config, error) {
 	if v == nil {
-		return nil
+		return nil, nil
 	}
 	config := &log.Config{
 		ErrorLogType:  log.LogType_Console,
 		AccessLogType: log.LogType_Console,
 		EnableDnsLog:  v.DNSLog,
+		MaxSize:       v.MaxSize,
+		MaxBackups:    v.MaxBackups,
+		MemorySize:    v.MemorySize,
 	}
 
+	switch strings.ToLower(v.Format) {
+	case "", "console", "text":
+		config.Format = log.LogFormat_Text
+	case "json":
+		config.Format = log.LogFormat_JSON
+	default:
+		return nil, errors.New("unknown log format: ", v.Format)
+	}
+	interval, found := rotateIntervals[strings.ToLower(v.Rotate)]
+	if !found {
+		return nil, errors.New("unknown log rotation: ", v.Rotate, ", expected hourly, daily or weekly")
+	}
+	config.RotateInterval = interval
+
 	if v.AccessLog == "none" {
 		config.AccessLogType = log.LogType_None
+	} else if v.AccessLog == "memory" {
+		config.AccessLogType = log.LogType_Memory
 	} else if len(v.AccessLog) > 0 {
 		config.AccessLogPath = v.AccessLog
 		config.AccessLogType = log.LogType_File
 	}
 	if v.ErrorLog == "none" {
 		config.ErrorLogType = log.LogType_None
+	} else if v.ErrorLog == "memory" {
+		config.ErrorLogType = log.LogType_Memory
 	} else if len(v.ErrorLog) > 0 {
 		config.ErrorLogPath = v.ErrorLog
 		config.ErrorLogType = log.LogType_File
 	}
 
 	level := strings.ToLower(v.LogLevel)
-	switch level {
-	case "debug":
-		config.ErrorLogLevel = clog.Severity_Debug
-	case "info":
-		config.ErrorLogLevel = clog.Severity_Info
-	case "error":
-		config.ErrorLogLevel = clog.Severity_Error
-	case "none":
-		config.ErrorLogType = log.LogType_None
+	config.ErrorLogLevel = parseLogLevel(level)
+	moduleLogging := false
+	if len(v.Levels) > 0 {
+		config.ModuleLevels = make(map[string]clog.Severity, len(v.Levels))
+		for module, l := range v.Levels {
+			severity := parseLogLevel(strings.ToLower(l))
+			config.ModuleLevels[module] = severity
+			if severity != clog.Severity_Unknown {
+				moduleLogging = true
+			}
+		}
+	}
+	if level == "none" {
 		config.AccessLogType = log.LogType_None
-	default:
-		config.ErrorLogLevel = clog.Severity_Warning
+		// Modules with a level of their own are still logged.
+		if !moduleLogging {
+			config.ErrorLogType = log.LogType_None
+		}
 	}
 	config.MaskAddress = v.MaskAddress
-	return config
+	return config, nil
 }
@@ -0,0 +1,35 @@
+package conf_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/xtls/xray-core/app/log"
+	"github.com/xtls/xray-core/common"
+	. "github.com/xtls/xray-core/infra/conf"
+)
+
+func TestLogConfig_Format(t *testing.T) {
+	build := func(s string) (*log.Config, error) {
+		config := new(LogConfig)
+		common.Must(json.Unmarshal([]byte(s), config))
+		return config.Build()
+	}
+
+	config, err := build(`{"access": "/var/log/xray/access.log", "format": "json", "maxSize": 10, "maxBackups": 3, "rotate": "daily"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Format != log.LogFormat_JSON || config.AccessLogType != log.LogType_File {
+		t.Error("unexpected config: ", config)
+	}
+	if config.MaxSize != 10 || config.MaxBackups != 3 || config.RotateInterval != 24*3600 {
+		t.Error("unexpected rotation: ", config)
+	}
+	if _, err := build(`{"format": "xml"}`); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if _, err := build(`{"rotate": "monthly"}`); err == nil {
+		t.Error("expected an error for an unknown rotation")
+	}
+}
@@ -0,0 +1,159 @@
+package conf
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// checkRoutingLoops finds the outbounds which dial an inbound of the same config, while all the
+// traffic of the inbound is routed back to them. Outbounds chained through inbounds routed
+// elsewhere are fine, and the loops depending on the traffic are caught when dialing.
+func checkRoutingLoops(config *core.Config, routerConfig *router.Config) error {
+	for i, outbound := range config.Outbound {
+		if dialsThroughProxy(outbound) {
+			continue
+		}
+		settings, err := outbound.ProxySettings.GetInstance()
+		if err != nil {
+			continue
+		}
+		var endpoints []*protocol.ServerEndpoint
+		findServerEndpoints(settings.ProtoReflect(), &endpoints)
+		for _, endpoint := range endpoints {
+			for _, inbound := range config.Inbound {
+				if !listensOn(inbound, endpoint) {
+					continue
+				}
+				target, found := catchAllOutbound(routerConfig, inbound.Tag, config.Outbound)
+				if found && target == i {
+					return errors.New("routing loop detected: outbound [", outbound.Tag, "] dials inbound [", inbound.Tag, "] at ",
+						endpoint.Address.AsAddress(), ":", endpoint.Port, ", whose traffic is routed back to it")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func dialsThroughProxy(outbound *core.OutboundHandlerConfig) bool {
+	if outbound.SenderSettings == nil {
+		return false
+	}
+	settings, err := outbound.SenderSettings.GetInstance()
+	if err != nil {
+		return false
+	}
+	sender, ok := settings.(*proxyman.SenderConfig)
+	if !ok {
+		return false
+	}
+	return sender.ProxySettings.GetTag() != "" || sender.StreamSettings.GetSocketSettings().GetDialerProxy() != ""
+}
+
+// findServerEndpoints collects the servers of the settings of an outbound, wherever they are.
+func findServerEndpoints(m protoreflect.Message, endpoints *[]*protocol.ServerEndpoint) {
+	if endpoint, ok := m.Interface().(*protocol.ServerEndpoint); ok {
+		*endpoints = append(*endpoints, endpoint)
+		return
+	}
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsMap() || fd.Message() == nil:
+		case fd.IsList():
+			for i := 0; i < v.List().Len(); i++ {
+				findServerEndpoints(v.List().Get(i).Message(), endpoints)
+			}
+		default:
+			findServerEndpoints(v.Message(), endpoints)
+		}
+		return true
+	})
+}
+
+// listensOn tells whether inbound accepts the connections dialed to endpoint.
+func listensOn(inbound *core.InboundHandlerConfig, endpoint *protocol.ServerEndpoint) bool {
+	if inbound.ReceiverSettings == nil || endpoint.Address == nil {
+		return false
+	}
+	settings, err := inbound.ReceiverSettings.GetInstance()
+	if err != nil {
+		return false
+	}
+	receiver, ok := settings.(*proxyman.ReceiverConfig)
+	if !ok || receiver.PortList == nil || !net.PortListFromProto(receiver.PortList).Contains(net.Port(endpoint.Port)) {
+		return false
+	}
+
+	address := endpoint.Address.AsAddress()
+	if address.Family().IsDomain() {
+		if address.Domain() != "localhost" {
+			return false
+		}
+		address = net.LocalHostIP
+	}
+	ip := address.IP()
+	if ip.IsUnspecified() {
+		// Dialing the unspecified address reaches the loopback one.
+		ip = net.LocalHostIP.IP()
+	}
+	listen := net.AnyIP
+	if receiver.Listen != nil {
+		listen = receiver.Listen.AsAddress()
+	}
+	if listen == net.AnyIP || listen == net.AnyIPv6 {
+		return ip.IsLoopback()
+	}
+	return listen.Family().IsIP() && listen.IP().Equal(ip)
+}
+
+// catchAllOutbound returns the index of the outbound all the traffic of the inbound tagged tag is
+// routed to, if it's known without looking at the traffic.
+func catchAllOutbound(routerConfig *router.Config, tag string, outbounds []*core.OutboundHandlerConfig) (int, bool) {
+	rules := slices.Clone(routerConfig.GetRule())
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority > rules[j].Priority
+	})
+	for _, rule := range rules {
+		if len(rule.InboundTag) > 0 && !slices.Contains(rule.InboundTag, tag) {
+			continue
+		}
+		if rule.Group != "" || !onlyInboundTag(rule) {
+			// It may not apply to all the traffic, or be disabled.
+			continue
+		}
+		target, ok := rule.TargetTag.(*router.RoutingRule_Tag)
+		if !ok {
+			return 0, false
+		}
+		for i, outbound := range outbounds {
+			if outbound.Tag == target.Tag {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	return 0, len(outbounds) > 0
+}
+
+// onlyInboundTag tells whether the rule has no conditions other than its inbound tags.
+func onlyInboundTag(rule *router.RoutingRule) bool {
+	conditions := proto.Clone(rule).(*router.RoutingRule)
+	conditions.TargetTag = nil
+	conditions.RuleTag = ""
+	conditions.InboundTag = nil
+	conditions.DomainMatcher = ""
+	conditions.Priority = 0
+	conditions.Group = ""
+	conditions.Mirror = nil
+	conditions.BandwidthClass = ""
+	return proto.Size(conditions) == 0
+}
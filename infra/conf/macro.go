@@ -0,0 +1,115 @@
+package conf
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// domainMacros are the built-in lists "macro:name" stands for in the domains of rules, which need
+// no geosite file.
+var domainMacros = map[string][]string{
+	"localhost": {"full:localhost", "domain:localhost"},
+	"private": {
+		"macro:localhost",
+		// Hostnames without a dot are those of the local network.
+		"dotless:",
+		"domain:local",
+		"domain:lan",
+		"domain:home.arpa",
+		"domain:internal",
+		"domain:localdomain",
+	},
+}
+
+// ipMacros are the built-in lists "macro:name" stands for in the IPs of rules, which need no geoip
+// file.
+var ipMacros = map[string][]string{
+	"localhost": {"127.0.0.0/8", "::1/128"},
+	"lan": {
+		"macro:localhost",
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.168.0.0/16",
+		"224.0.0.0/4",
+		"255.255.255.255/32",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	},
+}
+
+func init() {
+	domainMacros["lan"] = domainMacros["private"]
+	ipMacros["private"] = ipMacros["lan"]
+}
+
+// expandMacro returns the entries of the macro named by entry, like "macro:lan", in macros, or entry
+// itself if it names no macro.
+func expandMacro(macros map[string][]string, entry string) ([]string, error) {
+	name, found := strings.CutPrefix(entry, "macro:")
+	if !found {
+		return []string{entry}, nil
+	}
+	entries, found := macros[strings.ToLower(name)]
+	if !found {
+		return nil, errors.New("unknown macro: ", name)
+	}
+	var expanded []string
+	for _, e := range entries {
+		list, err := expandMacro(macros, e)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, list...)
+	}
+	return expanded, nil
+}
+
+// defaultRules are the rules "name" in DefaultRules stands for. Their targets are written as
+// "direct" and "block".
+var defaultRules = map[string][]string{
+	"bypass-lan": {
+		`{"domain": ["macro:private"], "outboundTag": "direct"}`,
+		`{"ip": ["macro:lan"], "outboundTag": "direct"}`,
+	},
+	"bypass-localhost": {
+		`{"domain": ["macro:localhost"], "outboundTag": "direct"}`,
+		`{"ip": ["macro:localhost"], "outboundTag": "direct"}`,
+	},
+	"block-ads": {
+		`{"domain": ["geosite:category-ads-all"], "outboundTag": "block"}`,
+	},
+}
+
+// DefaultRules returns the standard routing rules named by names, in order: "bypass-lan" and
+// "bypass-localhost" send local traffic to the outbound tagged directTag, and "block-ads" sends
+// ad domains to blockTag.
+func DefaultRules(names []string, directTag, blockTag string) ([]*router.RoutingRule, error) {
+	var rules []*router.RoutingRule
+	for _, name := range names {
+		raws, found := defaultRules[name]
+		if !found {
+			return nil, errors.New("unknown default rules: ", name)
+		}
+		for _, raw := range raws {
+			rule, err := parseFieldRule(json.RawMessage(raw))
+			if err != nil {
+				return nil, errors.New("failed to build default rules: ", name).Base(err)
+			}
+			switch rule.GetTag() {
+			case "direct":
+				rule.TargetTag = &router.RoutingRule_Tag{Tag: directTag}
+			case "block":
+				rule.TargetTag = &router.RoutingRule_Tag{Tag: blockTag}
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
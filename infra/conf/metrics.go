@@ -8,6 +8,12 @@ import (
 type MetricsConfig struct {
 	Tag    string `json:"tag"`
 	Listen string `json:"listen"`
+	// Allow is the IPs and CIDRs of the clients allowed to reach the metrics.
+	Allow    StringList `json:"allow"`
+	Username string     `json:"username"`
+	Password string     `json:"password"`
+	// InboundTag is the inbounds the metrics may be reached through by the tag.
+	InboundTag StringList `json:"inboundTag"`
 }
 
 func (c *MetricsConfig) Build() (*metrics.Config, error) {
@@ -19,8 +25,16 @@ func (c *MetricsConfig) Build() (*metrics.Config, error) {
 		c.Tag = "Metrics"
 	}
 
+	if c.Password != "" && c.Username == "" {
+		return nil, errors.New("metrics: password without username")
+	}
+
 	return &metrics.Config{
-		Tag:    c.Tag,
-		Listen: c.Listen,
+		Tag:          c.Tag,
+		Listen:       c.Listen,
+		AllowSources: c.Allow,
+		Username:     c.Username,
+		Password:     c.Password,
+		InboundTags:  c.InboundTag,
 	}, nil
 }
@@ -1,34 +1,177 @@
 package conf
 
 import (
+	"net/http"
+	"strings"
+
 	"google.golang.org/protobuf/proto"
 
 	"github.com/xtls/xray-core/app/observatory"
 	"github.com/xtls/xray-core/app/observatory/burst"
+	"github.com/xtls/xray-core/app/observatory/group"
+	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/infra/conf/cfgcommon/duration"
 )
 
 type ObservatoryConfig struct {
-	SubjectSelector   []string          `json:"subjectSelector"`
-	ProbeURL          string            `json:"probeURL"`
-	ProbeInterval     duration.Duration `json:"probeInterval"`
-	EnableConcurrency bool              `json:"enableConcurrency"`
+	SubjectSelector   []string           `json:"subjectSelector"`
+	ProbeURL          string             `json:"probeURL"`
+	ProbeInterval     duration.Duration  `json:"probeInterval"`
+	EnableConcurrency bool               `json:"enableConcurrency"`
+	EgressCheck       *EgressCheckConfig `json:"egressCheck"`
+	Tag               string             `json:"tag"`
+	ProbeMethod       string             `json:"probeMethod"`
+	Congestion        *CongestionConfig  `json:"congestion"`
+	ProbeHops         bool               `json:"probeHops"`
 }
 
 func (o *ObservatoryConfig) Build() (proto.Message, error) {
-	return &observatory.Config{SubjectSelector: o.SubjectSelector, ProbeUrl: o.ProbeURL, ProbeInterval: int64(o.ProbeInterval), EnableConcurrency: o.EnableConcurrency}, nil
+	config := &observatory.Config{SubjectSelector: o.SubjectSelector, ProbeUrl: o.ProbeURL, ProbeInterval: int64(o.ProbeInterval), EnableConcurrency: o.EnableConcurrency, Tag: o.Tag, ProbeHops: o.ProbeHops}
+	switch method := strings.ToUpper(o.ProbeMethod); method {
+	case "":
+	case http.MethodGet, http.MethodHead:
+		config.ProbeMethod = method
+	default:
+		return nil, errors.New("unsupported probeMethod: ", o.ProbeMethod)
+	}
+	if o.EgressCheck != nil {
+		egressCheck, err := o.EgressCheck.Build()
+		if err != nil {
+			return nil, errors.New("invalid egressCheck").Base(err)
+		}
+		config.EgressCheck = egressCheck
+	}
+	if o.Congestion != nil {
+		congestion, err := o.Congestion.Build()
+		if err != nil {
+			return nil, errors.New("invalid congestion").Base(err)
+		}
+		config.Congestion = congestion
+	}
+	return config, nil
+}
+
+// CongestionConfig makes outbounds not alive for a while when their connections retransmit too
+// much, have their round trip times inflate, or transfer much slower than usual. Thresholds of
+// 0 are off.
+type CongestionConfig struct {
+	MaxRetransmission float32           `json:"maxRetransmission"`
+	MaxRTTInflation   float32           `json:"maxRttInflation"`
+	MinThroughput     float32           `json:"minThroughput"`
+	Hold              duration.Duration `json:"hold"`
+}
+
+func (c *CongestionConfig) Build() (*observatory.CongestionConfig, error) {
+	if c.MaxRetransmission < 0 || c.MaxRetransmission > 1 {
+		return nil, errors.New("maxRetransmission must be between 0 and 1")
+	}
+	if c.MaxRTTInflation != 0 && c.MaxRTTInflation <= 1 {
+		return nil, errors.New("maxRttInflation must be above 1")
+	}
+	if c.MinThroughput < 0 || c.MinThroughput >= 1 {
+		return nil, errors.New("minThroughput must be between 0 and 1")
+	}
+	if c.Hold < 0 {
+		return nil, errors.New("negative hold")
+	}
+	return &observatory.CongestionConfig{
+		MaxRetransmission: c.MaxRetransmission,
+		MaxRttInflation:   c.MaxRTTInflation,
+		MinThroughput:     c.MinThroughput,
+		Hold:              int64(c.Hold),
+	}, nil
+}
+
+// EgressCheckConfig expects the egress IPs of outbounds in networks given like the "ip" of
+// routing rules, such as "geoip:de" or "ext:asn.dat:as13335".
+type EgressCheckConfig struct {
+	URL      string            `json:"url"`
+	Expect   StringList        `json:"expect"`
+	Interval duration.Duration `json:"interval"`
+}
+
+func (c *EgressCheckConfig) Build() (*observatory.EgressCheck, error) {
+	if len(c.Expect) == 0 {
+		return nil, errors.New("no expected network")
+	}
+	geoips, err := ToCidrList(c.Expect)
+	if err != nil {
+		return nil, err
+	}
+	config := &observatory.EgressCheck{
+		Url:      c.URL,
+		Interval: int64(c.Interval),
+	}
+	for _, geoip := range geoips {
+		if geoip.ReverseMatch {
+			return nil, errors.New("reversed networks are not supported: ", geoip.CountryCode)
+		}
+		for _, cidr := range geoip.Cidr {
+			config.Expected = append(config.Expected, &observatory.EgressCheck_Network{
+				Ip:     cidr.Ip,
+				Prefix: cidr.Prefix,
+			})
+		}
+	}
+	return config, nil
 }
 
 type BurstObservatoryConfig struct {
 	SubjectSelector []string `json:"subjectSelector"`
 	// health check settings
 	HealthCheck *healthCheckSettings `json:"pingConfig,omitempty"`
+	Tag         string               `json:"tag"`
+	Congestion  *CongestionConfig    `json:"congestion"`
 }
 
 func (b BurstObservatoryConfig) Build() (proto.Message, error) {
 	if result, err := b.HealthCheck.Build(); err == nil {
-		return &burst.Config{SubjectSelector: b.SubjectSelector, PingConfig: result.(*burst.HealthPingConfig)}, nil
+		config := &burst.Config{SubjectSelector: b.SubjectSelector, PingConfig: result.(*burst.HealthPingConfig), Tag: b.Tag}
+		if b.Congestion != nil {
+			if config.Congestion, err = b.Congestion.Build(); err != nil {
+				return nil, errors.New("invalid congestion").Base(err)
+			}
+		}
+		return config, nil
 	} else {
 		return nil, err
 	}
 }
+
+// buildObservatoryGroup builds the observatories and burst observatories into a group, which
+// balancers select them from by "observatoryTag".
+func buildObservatoryGroup(observatories []*ObservatoryConfig, burstObservatories []*BurstObservatoryConfig) (*group.Config, error) {
+	config := &group.Config{}
+	tags := make(map[string]bool)
+	checkTag := func(tag string) error {
+		if tag == "" {
+			return nil
+		}
+		if tags[tag] {
+			return errors.New("duplicate observatory tag ", tag)
+		}
+		tags[tag] = true
+		return nil
+	}
+	for _, o := range observatories {
+		if err := checkTag(o.Tag); err != nil {
+			return nil, err
+		}
+		c, err := o.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Observatory = append(config.Observatory, c.(*observatory.Config))
+	}
+	for _, b := range burstObservatories {
+		if err := checkTag(b.Tag); err != nil {
+			return nil, err
+		}
+		c, err := b.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.BurstObservatory = append(config.BurstObservatory, c.(*burst.Config))
+	}
+	return config, nil
+}
@@ -2,6 +2,7 @@ package conf
 
 import (
 	"github.com/xtls/xray-core/app/policy"
+	feature_policy "github.com/xtls/xray-core/features/policy"
 )
 
 type Policy struct {
@@ -9,10 +10,13 @@ type Policy struct {
 	ConnectionIdle    *uint32 `json:"connIdle"`
 	UplinkOnly        *uint32 `json:"uplinkOnly"`
 	DownlinkOnly      *uint32 `json:"downlinkOnly"`
+	Dial              *uint32 `json:"dial"`    // 16 seconds by default
+	Resolve           *uint32 `json:"resolve"` // 4 seconds by default
 	StatsUserUplink   bool    `json:"statsUserUplink"`
 	StatsUserDownlink bool    `json:"statsUserDownlink"`
 	StatsUserOnline   bool    `json:"statsUserOnline"`
 	BufferSize        *int32  `json:"bufferSize"`
+	AutoBuffer        bool    `json:"autoBuffer"`
 }
 
 func (t *Policy) Build() (*policy.Policy, error) {
@@ -29,6 +33,12 @@ func (t *Policy) Build() (*policy.Policy, error) {
 	if t.DownlinkOnly != nil {
 		config.DownlinkOnly = &policy.Second{Value: *t.DownlinkOnly}
 	}
+	if t.Dial != nil {
+		config.Dial = &policy.Second{Value: *t.Dial}
+	}
+	if t.Resolve != nil {
+		config.Resolve = &policy.Second{Value: *t.Resolve}
+	}
 
 	p := &policy.Policy{
 		Timeout: config,
@@ -48,24 +58,42 @@ func (t *Policy) Build() (*policy.Policy, error) {
 			Connection: bs,
 		}
 	}
+	if t.AutoBuffer {
+		if p.Buffer == nil {
+			p.Buffer = &policy.Policy_Buffer{
+				Connection: feature_policy.SessionDefault().Buffer.PerConnection,
+			}
+		}
+		p.Buffer.Auto = true
+	}
 
 	return p, nil
 }
 
 type SystemPolicy struct {
-	StatsInboundUplink    bool `json:"statsInboundUplink"`
-	StatsInboundDownlink  bool `json:"statsInboundDownlink"`
-	StatsOutboundUplink   bool `json:"statsOutboundUplink"`
-	StatsOutboundDownlink bool `json:"statsOutboundDownlink"`
+	StatsInboundUplink     bool   `json:"statsInboundUplink"`
+	StatsInboundDownlink   bool   `json:"statsInboundDownlink"`
+	StatsOutboundUplink    bool   `json:"statsOutboundUplink"`
+	StatsOutboundDownlink  bool   `json:"statsOutboundDownlink"`
+	StatsOutboundHandshake bool   `json:"statsOutboundHandshake"`
+	StatsDomainUplink      bool   `json:"statsDomainUplink"`
+	StatsDomainDownlink    bool   `json:"statsDomainDownlink"`
+	StatsDomainLimit       uint32 `json:"statsDomainLimit"`
+	StatsRule              bool   `json:"statsRule"`
 }
 
 func (p *SystemPolicy) Build() (*policy.SystemPolicy, error) {
 	return &policy.SystemPolicy{
 		Stats: &policy.SystemPolicy_Stats{
-			InboundUplink:    p.StatsInboundUplink,
-			InboundDownlink:  p.StatsInboundDownlink,
-			OutboundUplink:   p.StatsOutboundUplink,
-			OutboundDownlink: p.StatsOutboundDownlink,
+			InboundUplink:     p.StatsInboundUplink,
+			InboundDownlink:   p.StatsInboundDownlink,
+			OutboundUplink:    p.StatsOutboundUplink,
+			OutboundDownlink:  p.StatsOutboundDownlink,
+			OutboundHandshake: p.StatsOutboundHandshake,
+			DomainUplink:      p.StatsDomainUplink,
+			DomainDownlink:    p.StatsDomainDownlink,
+			DomainLimit:       p.StatsDomainLimit,
+			Rule:              p.StatsRule,
 		},
 	}, nil
 }
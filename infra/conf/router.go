@@ -2,15 +2,17 @@ package conf
 
 import (
 	"encoding/json"
-	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/xtls/xray-core/app/router"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/platform/filesystem"
 	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/infra/conf/cfgcommon/duration"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -21,10 +23,11 @@ type StrategyConfig struct {
 }
 
 type BalancingRule struct {
-	Tag         string         `json:"tag"`
-	Selectors   StringList     `json:"selector"`
-	Strategy    StrategyConfig `json:"strategy"`
-	FallbackTag string         `json:"fallbackTag"`
+	Tag            string         `json:"tag"`
+	Selectors      StringList     `json:"selector"`
+	Strategy       StrategyConfig `json:"strategy"`
+	FallbackTag    string         `json:"fallbackTag"`
+	ObservatoryTag string         `json:"observatoryTag"`
 }
 
 // Build builds the balancing rule
@@ -40,7 +43,7 @@ func (r *BalancingRule) Build() (*router.BalancingRule, error) {
 	switch r.Strategy.Type {
 	case "":
 		r.Strategy.Type = strategyRandom
-	case strategyRandom, strategyLeastLoad, strategyLeastPing, strategyRoundRobin:
+	case strategyRandom, strategyLeastLoad, strategyLeastPing, strategyRoundRobin, strategySelector, strategyQuota:
 	default:
 		return nil, errors.New("unknown balancing strategy: " + r.Strategy.Type)
 	}
@@ -67,17 +70,168 @@ func (r *BalancingRule) Build() (*router.BalancingRule, error) {
 		FallbackTag:      r.FallbackTag,
 		OutboundSelector: r.Selectors,
 		Tag:              r.Tag,
+		ObservatoryTag:   r.ObservatoryTag,
 	}, nil
 }
 
 type RouterConfig struct {
-	RuleList       []json.RawMessage `json:"rules"`
-	DomainStrategy *string           `json:"domainStrategy"`
-	Balancers      []*BalancingRule  `json:"balancers"`
+	RuleList       []json.RawMessage  `json:"rules"`
+	DomainStrategy *string            `json:"domainStrategy"`
+	Balancers      []*BalancingRule   `json:"balancers"`
+	RuleGroups     []*RuleGroupConfig `json:"ruleGroups"`
+	Blocklists     []*BlocklistConfig `json:"blocklists"`
+
+	BandwidthClasses []*BandwidthClassConfig `json:"bandwidthClasses"`
+	PoisonCheck      *PoisonCheckConfig      `json:"poisonCheck"`
 
 	DomainMatcher string `json:"domainMatcher"`
 }
 
+// BlocklistConfig is an IP list downloaded from a URL, which rules refer to as "blocklist:name"
+// in "ip" and "source".
+type BlocklistConfig struct {
+	Name    string            `json:"name"`
+	URL     string            `json:"url"`
+	Refresh duration.Duration `json:"refresh"`
+}
+
+// Build implements Buildable.
+func (c *BlocklistConfig) Build() (*router.Blocklist, error) {
+	if c.Name == "" {
+		return nil, errors.New("empty blocklist name")
+	}
+	if c.URL == "" {
+		return nil, errors.New("no URL of blocklist ", c.Name)
+	}
+	if c.Refresh < 0 || (c.Refresh > 0 && time.Duration(c.Refresh) < time.Minute) {
+		return nil, errors.New("refresh of blocklist ", c.Name, " is less than a minute")
+	}
+	return &router.Blocklist{
+		Name:    c.Name,
+		Url:     c.URL,
+		Refresh: uint32(time.Duration(c.Refresh) / time.Second),
+	}, nil
+}
+
+// BandwidthClassConfig is a class of connections sharing an aggregate rate limit, which rules
+// assign connections to with "bandwidthClass".
+type BandwidthClassConfig struct {
+	Name string `json:"name"`
+	Rate string `json:"rate"`
+}
+
+// Build implements Buildable.
+func (c *BandwidthClassConfig) Build() (*router.BandwidthClass, error) {
+	if c.Name == "" {
+		return nil, errors.New("empty bandwidth class name")
+	}
+	rate, err := parseRate(c.Rate)
+	if err != nil {
+		return nil, errors.New("invalid rate of bandwidth class ", c.Name).Base(err)
+	}
+	return &router.BandwidthClass{
+		Name: c.Name,
+		Rate: rate,
+	}, nil
+}
+
+// PoisonCheckConfig re-resolves a domain at the name servers tagged DNSTag, when its locally
+// resolved IPs route it to one of DirectOutbounds while the domain itself is routed elsewhere.
+type PoisonCheckConfig struct {
+	DNSTag          string     `json:"dnsTag"`
+	DirectOutbounds StringList `json:"directOutbounds"`
+}
+
+// Build implements Buildable.
+func (c *PoisonCheckConfig) Build() (*router.PoisonCheck, error) {
+	if c.DNSTag == "" {
+		return nil, errors.New("no dnsTag of poison check")
+	}
+	direct := []string(c.DirectOutbounds)
+	if len(direct) == 0 {
+		direct = []string{"direct"}
+	}
+	return &router.PoisonCheck{
+		DnsTag:         c.DNSTag,
+		DirectOutbound: direct,
+	}, nil
+}
+
+// parseRate parses a rate such as "50mbps" in bits per second, and returns it in bytes per
+// second. An empty rate or "0" is no limit.
+func parseRate(s string) (uint64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "0" {
+		return 0, nil
+	}
+	var multiplier float64
+	for _, unit := range []struct {
+		suffix     string
+		multiplier float64
+	}{{"gbps", 1e9}, {"mbps", 1e6}, {"kbps", 1e3}, {"bps", 1}} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	if multiplier == 0 {
+		return 0, errors.New("unknown unit of rate ", s, ", expecting bps, kbps, mbps or gbps")
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if value < 0 {
+		return 0, errors.New("negative rate ", s)
+	}
+	return uint64(value * multiplier / 8), nil
+}
+
+type RuleGroupConfig struct {
+	Name    string `json:"name"`
+	Enabled *bool  `json:"enabled"`
+}
+
+// Build implements Buildable.
+func (c *RuleGroupConfig) Build() (*router.RuleGroup, error) {
+	if c.Name == "" {
+		return nil, errors.New("empty rule group name")
+	}
+	return &router.RuleGroup{
+		Name:     c.Name,
+		Disabled: c.Enabled != nil && !*c.Enabled,
+	}, nil
+}
+
+type MirrorConfig struct {
+	OutboundTag string   `json:"outboundTag"`
+	Address     string   `json:"address"`
+	SampleRate  *float32 `json:"sampleRate"`
+	MaxBytes    uint64   `json:"maxBytes"`
+	Downlink    bool     `json:"downlink"`
+}
+
+// Build implements Buildable.
+func (c *MirrorConfig) Build() (*router.MirrorConfig, error) {
+	if (c.OutboundTag == "") == (c.Address == "") {
+		return nil, errors.New("exactly one of outboundTag and address must be set in mirror")
+	}
+	config := &router.MirrorConfig{
+		OutboundTag: c.OutboundTag,
+		Address:     c.Address,
+		MaxBytes:    c.MaxBytes,
+		Downlink:    c.Downlink,
+	}
+	if c.SampleRate != nil {
+		if *c.SampleRate <= 0 || *c.SampleRate > 1 {
+			return nil, errors.New("mirror sampleRate must be in (0, 1]: ", *c.SampleRate)
+		}
+		config.SampleRate = *c.SampleRate
+	}
+	return config, nil
+}
+
 func (c *RouterConfig) getDomainStrategy() router.Config_DomainStrategy {
 	ds := ""
 	if c.DomainStrategy != nil {
@@ -105,18 +259,25 @@ func (c *RouterConfig) Build() (*router.Config, error) {
 		rawRuleList = c.RuleList
 	}
 
-	for _, rawRule := range rawRuleList {
-		rule, err := ParseRule(rawRule)
+	// Rules referring to large geo lists take a while each, so they're parsed in parallel.
+	rules := make([]*router.RoutingRule, len(rawRuleList))
+	err := task.ForEach(len(rawRuleList), func(i int) error {
+		rule, err := ParseRule(rawRuleList[i])
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		if rule.DomainMatcher == "" {
 			rule.DomainMatcher = c.DomainMatcher
 		}
 
-		config.Rule = append(config.Rule, rule)
+		rules[i] = rule
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	config.Rule = append(config.Rule, rules...)
 	for _, rawBalancer := range c.Balancers {
 		balancer, err := rawBalancer.Build()
 		if err != nil {
@@ -124,6 +285,34 @@ func (c *RouterConfig) Build() (*router.Config, error) {
 		}
 		config.BalancingRule = append(config.BalancingRule, balancer)
 	}
+	for _, rawGroup := range c.RuleGroups {
+		group, err := rawGroup.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.RuleGroup = append(config.RuleGroup, group)
+	}
+	for _, rawBlocklist := range c.Blocklists {
+		blocklist, err := rawBlocklist.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Blocklist = append(config.Blocklist, blocklist)
+	}
+	for _, rawClass := range c.BandwidthClasses {
+		class, err := rawClass.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.BandwidthClass = append(config.BandwidthClass, class)
+	}
+	if c.PoisonCheck != nil {
+		check, err := c.PoisonCheck.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.PoisonCheck = check
+	}
 	return config, nil
 }
 
@@ -132,8 +321,18 @@ type RouterRule struct {
 	Type        string `json:"type"`
 	OutboundTag string `json:"outboundTag"`
 	BalancerTag string `json:"balancerTag"`
+	Priority    int32  `json:"priority"`
+	Group       string `json:"group"`
 
-	DomainMatcher string `json:"domainMatcher"`
+	DomainMatcher string        `json:"domainMatcher"`
+	Mirror        *MirrorConfig `json:"mirror"`
+
+	BandwidthClass string `json:"bandwidthClass"`
+
+	// LatencyBudget is in milliseconds, which the best alive outbound of the rule must be observed
+	// below, or connections go to LatencyFallbackTag, an outbound or a balancer.
+	LatencyBudget      uint32 `json:"latencyBudget"`
+	LatencyFallbackTag string `json:"latencyFallbackTag"`
 }
 
 func ParseIP(s string) (*router.CIDR, error) {
@@ -194,65 +393,58 @@ var (
 	SiteCache = make(map[string]*router.GeoSite)
 )
 
-func loadFile(file string) ([]byte, error) {
-	if FileCache[file] == nil {
-		bs, err := filesystem.ReadAsset(file)
-		if err != nil {
-			return nil, errors.New("failed to open file: ", file).Base(err)
-		}
-		if len(bs) == 0 {
-			return nil, errors.New("empty file: ", file)
-		}
-		// Do not cache file, may save RAM when there
-		// are many files, but consume CPU each time.
-		return bs, nil
-		FileCache[file] = bs
+// loadEntry returns a copy of the entry of code in the dat file, or nil if there is none.
+func loadEntry(file, code string) ([]byte, error) {
+	data, unmap, err := filesystem.MapAsset(file)
+	if err != nil {
+		return nil, errors.New("failed to open file: ", file).Base(errors.Diagnose(errors.CodeGeodataMissing, err))
+	}
+	defer unmap()
+	if len(data) == 0 {
+		return nil, errors.New("empty file: ", file)
 	}
-	return FileCache[file], nil
+	entry := find(data, []byte(code))
+	if entry == nil {
+		return nil, nil
+	}
+	return append([]byte(nil), entry...), nil
 }
 
 func loadIP(file, code string) ([]*router.CIDR, error) {
-	index := file + ":" + code
-	if IPCache[index] == nil {
-		bs, err := loadFile(file)
-		if err != nil {
-			return nil, errors.New("failed to load file: ", file).Base(err)
-		}
-		bs = find(bs, []byte(code))
-		if bs == nil {
-			return nil, errors.New("code not found in ", file, ": ", code)
-		}
-		var geoip router.GeoIP
-		if err := proto.Unmarshal(bs, &geoip); err != nil {
-			return nil, errors.New("error unmarshal IP in ", file, ": ", code).Base(err)
-		}
-		defer runtime.GC()     // or debug.FreeOSMemory()
-		return geoip.Cidr, nil // do not cache geoip
-		IPCache[index] = &geoip
+	cache := geoCacheFor(file)
+	key := "ip:" + code
+	var geoip router.GeoIP
+	if bs, ok := cache.get(key); ok && proto.Unmarshal(bs, &geoip) == nil {
+		return geoip.Cidr, nil
 	}
-	return IPCache[index].Cidr, nil
+
+	bs, err := loadEntry(file, code)
+	if err != nil {
+		return nil, errors.New("failed to load file: ", file).Base(err)
+	}
+	if bs == nil {
+		return nil, errors.New("code not found in ", file, ": ", code)
+	}
+	if err := proto.Unmarshal(bs, &geoip); err != nil {
+		return nil, errors.New("error unmarshal IP in ", file, ": ", code).Base(err)
+	}
+	cache.put(key, bs)
+	return geoip.Cidr, nil // do not cache geoip in memory
 }
 
 func loadSite(file, code string) ([]*router.Domain, error) {
-	index := file + ":" + code
-	if SiteCache[index] == nil {
-		bs, err := loadFile(file)
-		if err != nil {
-			return nil, errors.New("failed to load file: ", file).Base(err)
-		}
-		bs = find(bs, []byte(code))
-		if bs == nil {
-			return nil, errors.New("list not found in ", file, ": ", code)
-		}
-		var geosite router.GeoSite
-		if err := proto.Unmarshal(bs, &geosite); err != nil {
-			return nil, errors.New("error unmarshal Site in ", file, ": ", code).Base(err)
-		}
-		defer runtime.GC()         // or debug.FreeOSMemory()
-		return geosite.Domain, nil // do not cache geosite
-		SiteCache[index] = &geosite
+	bs, err := loadEntry(file, code)
+	if err != nil {
+		return nil, errors.New("failed to load file: ", file).Base(err)
+	}
+	if bs == nil {
+		return nil, errors.New("list not found in ", file, ": ", code)
 	}
-	return SiteCache[index].Domain, nil
+	var geosite router.GeoSite
+	if err := proto.Unmarshal(bs, &geosite); err != nil {
+		return nil, errors.New("error unmarshal Site in ", file, ": ", code).Base(err)
+	}
+	return geosite.Domain, nil // do not cache geosite in memory
 }
 
 func DecodeVarint(buf []byte) (x uint64, n int) {
@@ -354,26 +546,62 @@ func loadGeositeWithAttr(file string, siteWithAttr string) ([]*router.Domain, er
 	}
 	country := strings.ToUpper(parts[0])
 	attrs := parseAttrs(parts[1:])
+
+	cache := geoCacheFor(file)
+	key := "site:" + country + "@" + strings.ToLower(strings.Join(parts[1:], "@"))
+	var cached router.GeoSite
+	if bs, ok := cache.get(key); ok && proto.Unmarshal(bs, &cached) == nil {
+		return cached.Domain, nil
+	}
+
 	domains, err := loadSite(file, country)
 	if err != nil {
 		return nil, err
 	}
 
-	if attrs.IsEmpty() {
-		return domains, nil
+	if !attrs.IsEmpty() {
+		filteredDomains := make([]*router.Domain, 0, len(domains))
+		for _, domain := range domains {
+			if attrs.Match(domain) {
+				filteredDomains = append(filteredDomains, domain)
+			}
+		}
+		domains = filteredDomains
 	}
 
-	filteredDomains := make([]*router.Domain, 0, len(domains))
-	for _, domain := range domains {
-		if attrs.Match(domain) {
-			filteredDomains = append(filteredDomains, domain)
+	if cache != nil {
+		if bs, err := proto.Marshal(&router.GeoSite{Domain: domains}); err == nil {
+			cache.put(key, bs)
 		}
 	}
+	return domains, nil
+}
 
-	return filteredDomains, nil
+// parseDomainException tells whether domain is an exception to the other domains of its rule,
+// like "!domain:example.com", and returns it without the mark.
+func parseDomainException(domain string) (bool, string) {
+	if strings.HasPrefix(domain, "!") {
+		return true, domain[1:]
+	}
+	return false, domain
 }
 
 func parseDomainRule(domain string) ([]*router.Domain, error) {
+	if strings.HasPrefix(domain, "macro:") {
+		entries, err := expandMacro(domainMacros, domain)
+		if err != nil {
+			return nil, err
+		}
+		var domains []*router.Domain
+		for _, entry := range entries {
+			rules, err := parseDomainRule(entry)
+			if err != nil {
+				return nil, err
+			}
+			domains = append(domains, rules...)
+		}
+		return domains, nil
+	}
 	if strings.HasPrefix(domain, "geosite:") {
 		country := strings.ToUpper(domain[8:])
 		domains, err := loadGeositeWithAttr("geosite.dat", country)
@@ -447,7 +675,16 @@ func ToCidrList(ips StringList) ([]*router.GeoIP, error) {
 	var geoipList []*router.GeoIP
 	var customCidrs []*router.CIDR
 
+	var expanded StringList
 	for _, ip := range ips {
+		entries, err := expandMacro(ipMacros, ip)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, entries...)
+	}
+
+	for _, ip := range expanded {
 		if strings.HasPrefix(ip, "geoip:") {
 			country := ip[6:]
 			isReverseMatch := false
@@ -528,29 +765,57 @@ func ToCidrList(ips StringList) ([]*router.GeoIP, error) {
 	return geoipList, nil
 }
 
-func parseFieldRule(msg json.RawMessage) (*router.RoutingRule, error) {
-	type RawFieldRule struct {
-		RouterRule
-		Domain     *StringList       `json:"domain"`
-		Domains    *StringList       `json:"domains"`
-		IP         *StringList       `json:"ip"`
-		Port       *PortList         `json:"port"`
-		Network    *NetworkList      `json:"network"`
-		SourceIP   *StringList       `json:"source"`
-		SourcePort *PortList         `json:"sourcePort"`
-		User       *StringList       `json:"user"`
-		InboundTag *StringList       `json:"inboundTag"`
-		Protocols  *StringList       `json:"protocol"`
-		Attributes map[string]string `json:"attrs"`
+// splitBlocklists separates "blocklist:name" entries from the other IPs of a list. As the
+// conditions of a rule must all match, blocklists can't be mixed with other IPs.
+func splitBlocklists(list StringList) (StringList, []string, error) {
+	var ips StringList
+	var blocklists []string
+	for _, entry := range list {
+		if name, found := strings.CutPrefix(entry, "blocklist:"); found {
+			if name == "" {
+				return nil, nil, errors.New("empty blocklist name")
+			}
+			blocklists = append(blocklists, name)
+		} else {
+			ips = append(ips, entry)
+		}
 	}
+	if len(blocklists) > 0 && len(ips) > 0 {
+		return nil, nil, errors.New("blocklists can't be mixed with other IPs in a rule")
+	}
+	return ips, blocklists, nil
+}
+
+// RawFieldRule is a routing rule of the "field" type, the only one there is.
+type RawFieldRule struct {
+	RouterRule
+	Domain     *StringList       `json:"domain"`
+	Domains    *StringList       `json:"domains"`
+	IP         *StringList       `json:"ip"`
+	Port       *PortList         `json:"port"`
+	Network    *NetworkList      `json:"network"`
+	SourceIP   *StringList       `json:"source"`
+	SourcePort *PortList         `json:"sourcePort"`
+	LocalIP    *StringList       `json:"inboundIP"`
+	LocalPort  *PortList         `json:"inboundPort"`
+	User       *StringList       `json:"user"`
+	InboundTag *StringList       `json:"inboundTag"`
+	Protocols  *StringList       `json:"protocol"`
+	Attributes map[string]string `json:"attrs"`
+	Process    *StringList       `json:"process"`
+}
+
+func parseFieldRule(msg json.RawMessage) (*router.RoutingRule, error) {
 	rawFieldRule := new(RawFieldRule)
 	err := json.Unmarshal(msg, rawFieldRule)
 	if err != nil {
 		return nil, err
 	}
 
-	rule := new(router.RoutingRule)
-	rule.RuleTag = rawFieldRule.RuleTag
+	rule, err := rawFieldRule.Build()
+	if err != nil {
+		return nil, err
+	}
 	switch {
 	case len(rawFieldRule.OutboundTag) > 0:
 		rule.TargetTag = &router.RoutingRule_Tag{
@@ -563,37 +828,73 @@ func parseFieldRule(msg json.RawMessage) (*router.RoutingRule, error) {
 	default:
 		return nil, errors.New("neither outboundTag nor balancerTag is specified in routing rule")
 	}
+	return rule, nil
+}
+
+// Build builds the conditions of the rule, and the rest of it but its target. The conditions of
+// rules without targets pick traffic elsewhere, as the exclusions of Mux.
+func (rawFieldRule *RawFieldRule) Build() (*router.RoutingRule, error) {
+	rule := new(router.RoutingRule)
+	rule.RuleTag = rawFieldRule.RuleTag
+	rule.Priority = rawFieldRule.Priority
+	rule.Group = rawFieldRule.Group
 
 	if rawFieldRule.DomainMatcher != "" {
 		rule.DomainMatcher = rawFieldRule.DomainMatcher
 	}
 
-	if rawFieldRule.Domain != nil {
-		for _, domain := range *rawFieldRule.Domain {
-			rules, err := parseDomainRule(domain)
-			if err != nil {
-				return nil, errors.New("failed to parse domain rule: ", domain).Base(err)
-			}
-			rule.Domain = append(rule.Domain, rules...)
+	if rawFieldRule.Mirror != nil {
+		mirror, err := rawFieldRule.Mirror.Build()
+		if err != nil {
+			return nil, err
+		}
+		rule.Mirror = mirror
+	}
+
+	rule.BandwidthClass = rawFieldRule.BandwidthClass
+
+	if rawFieldRule.LatencyBudget > 0 {
+		if rawFieldRule.LatencyFallbackTag == "" {
+			return nil, errors.New("latencyBudget needs a latencyFallbackTag")
 		}
+		rule.LatencyBudget = rawFieldRule.LatencyBudget
+		rule.LatencyFallbackTag = rawFieldRule.LatencyFallbackTag
 	}
 
-	if rawFieldRule.Domains != nil {
-		for _, domain := range *rawFieldRule.Domains {
+	for _, list := range []*StringList{rawFieldRule.Domain, rawFieldRule.Domains} {
+		if list == nil {
+			continue
+		}
+		for _, domain := range *list {
+			excluded, domain := parseDomainException(domain)
 			rules, err := parseDomainRule(domain)
 			if err != nil {
 				return nil, errors.New("failed to parse domain rule: ", domain).Base(err)
 			}
-			rule.Domain = append(rule.Domain, rules...)
+			if excluded {
+				rule.ExcludedDomain = append(rule.ExcludedDomain, rules...)
+			} else {
+				rule.Domain = append(rule.Domain, rules...)
+			}
 		}
 	}
+	if len(rule.ExcludedDomain) > 0 && len(rule.Domain) == 0 {
+		return nil, errors.New("domain exceptions need other domains in the same rule to except from")
+	}
 
 	if rawFieldRule.IP != nil {
-		geoipList, err := ToCidrList(*rawFieldRule.IP)
+		ips, blocklists, err := splitBlocklists(*rawFieldRule.IP)
 		if err != nil {
 			return nil, err
 		}
-		rule.Geoip = geoipList
+		rule.Blocklist = blocklists
+		if len(ips) > 0 {
+			geoipList, err := ToCidrList(ips)
+			if err != nil {
+				return nil, err
+			}
+			rule.Geoip = geoipList
+		}
 	}
 
 	if rawFieldRule.Port != nil {
@@ -605,17 +906,36 @@ func parseFieldRule(msg json.RawMessage) (*router.RoutingRule, error) {
 	}
 
 	if rawFieldRule.SourceIP != nil {
-		geoipList, err := ToCidrList(*rawFieldRule.SourceIP)
+		ips, blocklists, err := splitBlocklists(*rawFieldRule.SourceIP)
 		if err != nil {
 			return nil, err
 		}
-		rule.SourceGeoip = geoipList
+		rule.SourceBlocklist = blocklists
+		if len(ips) > 0 {
+			geoipList, err := ToCidrList(ips)
+			if err != nil {
+				return nil, err
+			}
+			rule.SourceGeoip = geoipList
+		}
 	}
 
 	if rawFieldRule.SourcePort != nil {
 		rule.SourcePortList = rawFieldRule.SourcePort.Build()
 	}
 
+	if rawFieldRule.LocalIP != nil {
+		geoipList, err := ToCidrList(*rawFieldRule.LocalIP)
+		if err != nil {
+			return nil, err
+		}
+		rule.LocalGeoip = geoipList
+	}
+
+	if rawFieldRule.LocalPort != nil {
+		rule.LocalPortList = rawFieldRule.LocalPort.Build()
+	}
+
 	if rawFieldRule.User != nil {
 		for _, s := range *rawFieldRule.User {
 			rule.UserEmail = append(rule.UserEmail, s)
@@ -638,6 +958,10 @@ func parseFieldRule(msg json.RawMessage) (*router.RoutingRule, error) {
 		rule.Attributes = rawFieldRule.Attributes
 	}
 
+	if rawFieldRule.Process != nil {
+		rule.Process = *rawFieldRule.Process
+	}
+
 	return rule, nil
 }
 
@@ -1,10 +1,14 @@
 package conf
 
 import (
+	"sort"
+
 	"google.golang.org/protobuf/proto"
 
 	"github.com/xtls/xray-core/app/observatory/burst"
 	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/units"
 	"github.com/xtls/xray-core/infra/conf/cfgcommon/duration"
 )
 
@@ -13,6 +17,8 @@ const (
 	strategyLeastPing  string = "leastping"
 	strategyRoundRobin string = "roundrobin"
 	strategyLeastLoad  string = "leastload"
+	strategySelector   string = "selector"
+	strategyQuota      string = "quota"
 )
 
 var (
@@ -21,6 +27,8 @@ var (
 		strategyLeastPing:  func() interface{} { return new(strategyEmptyConfig) },
 		strategyRoundRobin: func() interface{} { return new(strategyEmptyConfig) },
 		strategyLeastLoad:  func() interface{} { return new(strategyLeastLoadConfig) },
+		strategySelector:   func() interface{} { return new(strategySelectorConfig) },
+		strategyQuota:      func() interface{} { return new(strategyQuotaConfig) },
 	}, "type", "settings")
 )
 
@@ -42,6 +50,73 @@ type strategyLeastLoadConfig struct {
 	MaxRTT duration.Duration `json:"maxRTT,omitempty"`
 	// acceptable failure rate
 	Tolerance float64 `json:"tolerance,omitempty"`
+	// weights of the average RTT, RTT deviation and failure rate in the cost
+	RTTWeight     float64 `json:"rttWeight,omitempty"`
+	JitterWeight  float64 `json:"jitterWeight,omitempty"`
+	FailureWeight float64 `json:"failureWeight,omitempty"`
+}
+
+type strategySelectorConfig struct {
+	// how much faster another outbound must be to take over from the selected one
+	Tolerance duration.Duration `json:"tolerance,omitempty"`
+	// how long an outbound that failed is not selected after it is alive again
+	HoldDown duration.Duration `json:"holdDown,omitempty"`
+}
+
+// Build implements Buildable.
+func (v *strategySelectorConfig) Build() (proto.Message, error) {
+	if v.Tolerance < 0 || v.HoldDown < 0 {
+		return nil, errors.New("selector tolerance and holdDown must not be negative")
+	}
+	return &router.StrategySelectorConfig{
+		Tolerance: int64(v.Tolerance),
+		HoldDown:  int64(v.HoldDown),
+	}, nil
+}
+
+type strategyQuotaConfig struct {
+	// monthly caps of outbounds by tag, such as "100GB"
+	Quotas map[string]string `json:"quotas"`
+	// file keeping the usage of the month across restarts
+	UsageFile string `json:"usageFile,omitempty"`
+	// day of month the usage is reset on, 1 to 28
+	ResetDay uint32 `json:"resetDay,omitempty"`
+	// fraction of its cap below which an outbound takes no more bulk traffic
+	Reserve float64 `json:"reserve,omitempty"`
+	// tags of the routing rules of bulk traffic
+	BulkRules StringList `json:"bulkRules,omitempty"`
+}
+
+// Build implements Buildable.
+func (v *strategyQuotaConfig) Build() (proto.Message, error) {
+	if v.ResetDay > 28 {
+		return nil, errors.New("quota resetDay must be from 1 to 28")
+	}
+	if v.Reserve < 0 || v.Reserve >= 1 {
+		return nil, errors.New("quota reserve must be from 0 to 1")
+	}
+	config := &router.StrategyQuotaConfig{
+		UsageFile:    v.UsageFile,
+		ResetDay:     v.ResetDay,
+		Reserve:      float32(v.Reserve),
+		BulkRuleTags: v.BulkRules,
+	}
+	tags := make([]string, 0, len(v.Quotas))
+	for tag := range v.Quotas {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		var limit units.ByteSize
+		if err := limit.Parse(v.Quotas[tag]); err != nil {
+			return nil, errors.New("invalid quota of outbound ", tag).Base(err)
+		}
+		config.Quotas = append(config.Quotas, &router.OutboundQuota{
+			OutboundTag: tag,
+			MonthlyCap:  uint64(limit),
+		})
+	}
+	return config, nil
 }
 
 // healthCheckSettings holds settings for health Checker
@@ -82,6 +157,12 @@ func (v *strategyLeastLoadConfig) Build() (proto.Message, error) {
 	if config.MaxRTT < 0 {
 		config.MaxRTT = 0
 	}
+	if v.RTTWeight < 0 || v.JitterWeight < 0 || v.FailureWeight < 0 {
+		return nil, errors.New("leastLoad weights must not be negative")
+	}
+	config.RttWeight = float32(v.RTTWeight)
+	config.JitterWeight = float32(v.JitterWeight)
+	config.FailureWeight = float32(v.FailureWeight)
 	config.Baselines = make([]int64, 0)
 	for _, b := range v.Baselines {
 		if b <= 0 {
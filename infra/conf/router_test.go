@@ -74,6 +74,55 @@ func TestToCidrList(t *testing.T) {
 	}
 }
 
+func TestGeoCache(t *testing.T) {
+	assetDir := t.TempDir()
+	cacheDir := t.TempDir()
+	t.Setenv("xray.location.asset", assetDir)
+	t.Setenv("xray.location.cache", cacheDir)
+
+	datPath := filepath.Join(assetDir, "geositetestcache.dat")
+	writeDat := func(domain string, modTime time.Time) {
+		list := &router.GeoSiteList{Entry: []*router.GeoSite{{
+			CountryCode: "TEST",
+			Domain: []*router.Domain{
+				{Type: router.Domain_Domain, Value: domain, Attribute: []*router.Domain_Attribute{
+					{Key: "cn", TypedValue: &router.Domain_Attribute_BoolValue{BoolValue: true}},
+				}},
+				{Type: router.Domain_Domain, Value: "other.com"},
+			},
+		}}}
+		common.Must(os.WriteFile(datPath, common.Must2(proto.Marshal(list)).([]byte), 0o644))
+		common.Must(os.Chtimes(datPath, modTime, modTime))
+	}
+	build := func() []*router.Domain {
+		config := new(RouterConfig)
+		common.Must(json.Unmarshal([]byte(`{"rules": [{
+			"domain": ["ext:geositetestcache.dat:test@cn"],
+			"outboundTag": "direct"
+		}]}`), config))
+		built, err := config.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return built.Rule[0].Domain
+	}
+
+	now := time.Now()
+	writeDat("example.com", now)
+	if domains := build(); len(domains) != 1 || domains[0].Value != "example.com" {
+		t.Fatal("unexpected domains: ", domains)
+	}
+	if files, _ := filepath.Glob(filepath.Join(cacheDir, "*.cache")); len(files) != 1 {
+		t.Fatal("expected a cache file, but got ", files)
+	}
+
+	// A changed dat file invalidates the cache.
+	writeDat("example.org", now.Add(time.Second))
+	if domains := build(); len(domains) != 1 || domains[0].Value != "example.org" {
+		t.Fatal("unexpected domains after changing the dat file: ", domains)
+	}
+}
+
 func TestRouterConfig(t *testing.T) {
 	createParser := func() func(string) (proto.Message, error) {
 		return func(s string) (proto.Message, error) {
@@ -144,10 +193,27 @@ func TestRouterConfig(t *testing.T) {
 								"baselines": ["400ms", "600ms"],
 								"expected": 6,
 								"maxRTT": "1000ms",
-								"tolerance": 0.5
+								"tolerance": 0.5,
+								"rttWeight": 1,
+								"jitterWeight": 0.5,
+								"failureWeight": 2
 							}
 						},
 						"fallbackTag": "fall"
+					},
+					{
+						"tag": "b3",
+						"selector": ["test"],
+						"strategy": {
+							"type": "quota",
+							"settings": {
+								"quotas": {"b": "1GB", "a": "500MB"},
+								"usageFile": "usage.json",
+								"resetDay": 15,
+								"reserve": 0.25,
+								"bulkRules": ["downloads"]
+							}
+						}
 					}
 				]
 			}`,
@@ -177,12 +243,30 @@ func TestRouterConfig(t *testing.T) {
 								int64(time.Duration(400) * time.Millisecond),
 								int64(time.Duration(600) * time.Millisecond),
 							},
-							Expected:  6,
-							MaxRTT:    int64(time.Duration(1000) * time.Millisecond),
-							Tolerance: 0.5,
+							Expected:      6,
+							MaxRTT:        int64(time.Duration(1000) * time.Millisecond),
+							Tolerance:     0.5,
+							RttWeight:     1,
+							JitterWeight:  0.5,
+							FailureWeight: 2,
 						}),
 						FallbackTag: "fall",
 					},
+					{
+						Tag:              "b3",
+						OutboundSelector: []string{"test"},
+						Strategy:         "quota",
+						StrategySettings: serial.ToTypedMessage(&router.StrategyQuotaConfig{
+							Quotas: []*router.OutboundQuota{
+								{OutboundTag: "a", MonthlyCap: 500 << 20},
+								{OutboundTag: "b", MonthlyCap: 1 << 30},
+							},
+							UsageFile:    "usage.json",
+							ResetDay:     15,
+							Reserve:      0.25,
+							BulkRuleTags: []string{"downloads"},
+						}),
+					},
 				},
 				Rule: []*router.RoutingRule{
 					{
@@ -307,5 +391,152 @@ func TestRouterConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: `{
+				"rules": [
+					{
+						"type": "field",
+						"port": 80,
+						"outboundTag": "direct",
+						"mirror": {
+							"address": "127.0.0.1:9000",
+							"sampleRate": 0.5,
+							"maxBytes": 65536,
+							"downlink": true
+						}
+					}
+				]
+			}`,
+			Parser: createParser(),
+			Output: &router.Config{
+				DomainStrategy: router.Config_AsIs,
+				Rule: []*router.RoutingRule{
+					{
+						PortList: &net.PortList{
+							Range: []*net.PortRange{{From: 80, To: 80}},
+						},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "direct",
+						},
+						Mirror: &router.MirrorConfig{
+							Address:    "127.0.0.1:9000",
+							SampleRate: 0.5,
+							MaxBytes:   65536,
+							Downlink:   true,
+						},
+					},
+				},
+			},
+		},
+		{
+			Input: `{
+				"bandwidthClasses": [
+					{"name": "bulk", "rate": "50mbps"},
+					{"name": "interactive"}
+				],
+				"rules": [
+					{
+						"type": "field",
+						"port": 22,
+						"outboundTag": "direct",
+						"bandwidthClass": "bulk"
+					}
+				]
+			}`,
+			Parser: createParser(),
+			Output: &router.Config{
+				DomainStrategy: router.Config_AsIs,
+				Rule: []*router.RoutingRule{
+					{
+						PortList: &net.PortList{
+							Range: []*net.PortRange{{From: 22, To: 22}},
+						},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "direct",
+						},
+						BandwidthClass: "bulk",
+					},
+				},
+				BandwidthClass: []*router.BandwidthClass{
+					{Name: "bulk", Rate: 6250000},
+					{Name: "interactive"},
+				},
+			},
+		},
+		{
+			Input: `{
+				"rules": [
+					{
+						"type": "field",
+						"process": ["firefox", "slack"],
+						"outboundTag": "direct"
+					}
+				]
+			}`,
+			Parser: createParser(),
+			Output: &router.Config{
+				DomainStrategy: router.Config_AsIs,
+				Rule: []*router.RoutingRule{
+					{
+						Process: []string{"firefox", "slack"},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "direct",
+						},
+					},
+				},
+			},
+		},
 	})
 }
+
+func TestRouterMacros(t *testing.T) {
+	config := new(RouterConfig)
+	common.Must(json.Unmarshal([]byte(`{"rules": [
+		{"domain": ["macro:localhost", "example.com"], "outboundTag": "direct"},
+		{"ip": ["macro:localhost", "10.0.0.1"], "outboundTag": "direct"}
+	]}`), config))
+	built, err := config.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*router.RoutingRule{
+		{
+			TargetTag: &router.RoutingRule_Tag{Tag: "direct"},
+			Domain: []*router.Domain{
+				{Type: router.Domain_Full, Value: "localhost"},
+				{Type: router.Domain_Domain, Value: "localhost"},
+				{Type: router.Domain_Plain, Value: "example.com"},
+			},
+		},
+		{
+			TargetTag: &router.RoutingRule_Tag{Tag: "direct"},
+			Geoip: []*router.GeoIP{{Cidr: []*router.CIDR{
+				{Ip: []byte{127, 0, 0, 0}, Prefix: 8},
+				{Ip: net.ParseAddress("::1").IP(), Prefix: 128},
+				{Ip: []byte{10, 0, 0, 1}, Prefix: 32},
+			}}},
+		},
+	}
+	for i, rule := range expected {
+		if !proto.Equal(built.Rule[i], rule) {
+			t.Errorf("rule %d: expected %v, but got %v", i, rule, built.Rule[i])
+		}
+	}
+
+	if _, err := ToCidrList(StringList{"macro:unknown"}); err == nil {
+		t.Error("expected an error for an unknown macro")
+	}
+
+	rules, err := DefaultRules([]string{"bypass-lan", "bypass-localhost"}, "out", "block")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 4 {
+		t.Fatal("expected 4 default rules, but got ", len(rules))
+	}
+	for _, rule := range rules {
+		if rule.GetTag() != "out" {
+			t.Error("expected default rules to target out, but got ", rule.GetTag())
+		}
+	}
+}
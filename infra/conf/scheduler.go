@@ -0,0 +1,121 @@
+package conf
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/xtls/xray-core/app/scheduler"
+	"github.com/xtls/xray-core/common/cron"
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// SchedulerConfig is the JSON config of the scheduler.
+type SchedulerConfig struct {
+	Tasks []*SchedulerTaskConfig `json:"tasks"`
+}
+
+// SchedulerTaskConfig is a task of the scheduler, whose settings depend on its action.
+type SchedulerTaskConfig struct {
+	Name     string          `json:"name"`
+	Schedule string          `json:"schedule"`
+	Action   string          `json:"action"`
+	Settings json.RawMessage `json:"settings"`
+}
+
+type GeodataFileConfig struct {
+	URL  string `json:"url"`
+	Name string `json:"name"`
+}
+
+type GeodataUpdateConfig struct {
+	Files       []*GeodataFileConfig `json:"files"`
+	OutboundTag string               `json:"outboundTag"`
+}
+
+type StatsSnapshotConfig struct {
+	Path  string `json:"path"`
+	Reset bool   `json:"reset"`
+}
+
+type CertificateCheckConfig struct {
+	Files []string `json:"files"`
+	Days  uint32   `json:"days"`
+}
+
+type HookConfig struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Timeout uint32   `json:"timeout"`
+}
+
+func (c *SchedulerConfig) Build() (*scheduler.Config, error) {
+	config := new(scheduler.Config)
+	for _, t := range c.Tasks {
+		task, err := t.Build()
+		if err != nil {
+			return nil, errors.New("invalid task ", t.Name).Base(err)
+		}
+		config.Task = append(config.Task, task)
+	}
+	return config, nil
+}
+
+func (c *SchedulerTaskConfig) Build() (*scheduler.Task, error) {
+	if _, err := cron.Parse(c.Schedule); err != nil {
+		return nil, err
+	}
+	task := &scheduler.Task{
+		Name:     c.Name,
+		Schedule: c.Schedule,
+	}
+	settings := c.Settings
+	if len(settings) == 0 {
+		settings = json.RawMessage("{}")
+	}
+
+	switch strings.ToLower(c.Action) {
+	case "geodataupdate":
+		s := new(GeodataUpdateConfig)
+		if err := json.Unmarshal(settings, s); err != nil {
+			return nil, errors.New("invalid settings of geodataUpdate").Base(err)
+		}
+		update := &scheduler.GeodataUpdate{OutboundTag: s.OutboundTag}
+		for _, f := range s.Files {
+			update.File = append(update.File, &scheduler.GeodataUpdate_File{Url: f.URL, Name: f.Name})
+		}
+		task.Action = &scheduler.Task_GeodataUpdate{GeodataUpdate: update}
+	case "statssnapshot":
+		s := new(StatsSnapshotConfig)
+		if err := json.Unmarshal(settings, s); err != nil {
+			return nil, errors.New("invalid settings of statsSnapshot").Base(err)
+		}
+		task.Action = &scheduler.Task_StatsSnapshot{StatsSnapshot: &scheduler.StatsSnapshot{
+			Path:   s.Path,
+			Reset_: s.Reset,
+		}}
+	case "certificatecheck":
+		s := new(CertificateCheckConfig)
+		if err := json.Unmarshal(settings, s); err != nil {
+			return nil, errors.New("invalid settings of certificateCheck").Base(err)
+		}
+		task.Action = &scheduler.Task_CertificateCheck{CertificateCheck: &scheduler.CertificateCheck{
+			File: s.Files,
+			Days: s.Days,
+		}}
+	case "cacheflush":
+		task.Action = &scheduler.Task_CacheFlush{CacheFlush: &scheduler.CacheFlush{}}
+	case "hook":
+		s := new(HookConfig)
+		if err := json.Unmarshal(settings, s); err != nil {
+			return nil, errors.New("invalid settings of hook").Base(err)
+		}
+		task.Action = &scheduler.Task_Hook{Hook: &scheduler.Hook{
+			Command: s.Command,
+			Args:    s.Args,
+			Timeout: s.Timeout,
+		}}
+	default:
+		return nil, errors.New("unknown action: ", c.Action)
+	}
+	return task, nil
+}
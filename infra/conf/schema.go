@@ -0,0 +1,392 @@
+package conf
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema is a JSON Schema (draft 2020-12), in the subset generated for configs.
+type Schema struct {
+	Schema      string             `json:"$schema,omitempty"`
+	Ref         string             `json:"$ref,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Type        []string           `json:"-"`
+	Enum        []string           `json:"enum,omitempty"`
+	Const       string             `json:"const,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	// Closed objects have no properties but those listed. Values is the schema of the values of
+	// the other properties, for maps.
+	Closed bool               `json:"-"`
+	Values *Schema            `json:"-"`
+	Items  *Schema            `json:"items,omitempty"`
+	AnyOf  []*Schema          `json:"anyOf,omitempty"`
+	AllOf  []*Schema          `json:"allOf,omitempty"`
+	If     *Schema            `json:"if,omitempty"`
+	Then   *Schema            `json:"then,omitempty"`
+	Defs   map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	type schema Schema
+	v := struct {
+		*schema
+		Type                 interface{} `json:"type,omitempty"`
+		AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+	}{schema: (*schema)(s)}
+	switch len(s.Type) {
+	case 0:
+	case 1:
+		v.Type = s.Type[0]
+	default:
+		v.Type = s.Type
+	}
+	if s.Values != nil {
+		v.AdditionalProperties = s.Values
+	} else if s.Closed {
+		v.AdditionalProperties = false
+	}
+	return json.Marshal(v)
+}
+
+// SchemaError is a value of a config not matching the schema.
+type SchemaError struct {
+	// Pointer is the JSON pointer to the value, like "/inbounds/0/port".
+	Pointer string
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	return e.Pointer + ": " + e.Message
+}
+
+var (
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	rawMessageType  = reflect.TypeOf(json.RawMessage(nil))
+)
+
+func typed(types ...string) *Schema {
+	return &Schema{Type: types}
+}
+
+func stringOrList() *Schema {
+	return &Schema{Type: []string{"string", "array"}, Items: typed("string")}
+}
+
+// schemaGenerator collects the definitions of the struct types met.
+type schemaGenerator struct {
+	defs  map[string]*Schema
+	names map[reflect.Type]string
+	// special are the schemas of the types decoded by UnmarshalJSON.
+	special map[reflect.Type]func() *Schema
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	g := &schemaGenerator{
+		defs:  make(map[string]*Schema),
+		names: make(map[reflect.Type]string),
+	}
+	g.special = map[reflect.Type]func() *Schema{
+		reflect.TypeOf(StringList(nil)):  stringOrList,
+		reflect.TypeOf(NetworkList(nil)): stringOrList,
+		reflect.TypeOf(Address{}):        func() *Schema { return typed("string") },
+		reflect.TypeOf(PortRange{}):      func() *Schema { return typed("integer", "string") },
+		reflect.TypeOf(PortList{}):       func() *Schema { return typed("integer", "string") },
+		reflect.TypeOf(Int32Range{}):     func() *Schema { return typed("integer", "string") },
+		reflect.TypeOf(HostAddress{}):    stringOrList,
+		reflect.TypeOf(HostsWrapper{}): func() *Schema {
+			return &Schema{Type: []string{"object"}, Values: stringOrList()}
+		},
+		reflect.TypeOf(NameServerConfig{}): func() *Schema {
+			return &Schema{AnyOf: []*Schema{typed("string"), g.structSchema(reflect.TypeOf(NameServerConfig{}))}}
+		},
+		reflect.TypeOf(FakeDNSConfig{}): func() *Schema {
+			pool := g.of(reflect.TypeOf(FakeDNSPoolElementConfig{}))
+			return &Schema{AnyOf: []*Schema{pool, {Type: []string{"array"}, Items: pool}}}
+		},
+	}
+	return g
+}
+
+// of returns the schema of values of type t, referring to the definitions of structs.
+func (g *schemaGenerator) of(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if special, found := g.special[t]; found {
+		return special()
+	}
+	if t == rawMessageType || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return &Schema{}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return typed("string")
+	case reflect.Bool:
+		return typed("boolean")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return typed("integer")
+	case reflect.Float32, reflect.Float64:
+		return typed("number")
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return typed("string")
+		}
+		return &Schema{Type: []string{"array"}, Items: g.of(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: []string{"object"}, Values: g.of(t.Elem())}
+	case reflect.Struct:
+		return &Schema{Ref: "#/$defs/" + g.define(t)}
+	default:
+		return &Schema{}
+	}
+}
+
+// define adds the definition of the struct type t, and returns its name.
+func (g *schemaGenerator) define(t reflect.Type) string {
+	if name, found := g.names[t]; found {
+		return name
+	}
+	name := t.Name()
+	if name == "" {
+		name = "struct"
+	}
+	for i := 2; g.defs[name] != nil; i++ {
+		name = t.Name() + strconv.Itoa(i)
+	}
+	g.names[t] = name
+	g.defs[name] = &Schema{}
+	*g.defs[name] = *g.structSchema(t)
+	return name
+}
+
+// structSchema returns the schema of the fields of the struct type t, as encoding/json decodes them.
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: []string{"object"}, Properties: make(map[string]*Schema), Closed: true}
+	g.addFields(s, t)
+	return s
+}
+
+func (g *schemaGenerator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(s, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = g.of(f.Type)
+	}
+}
+
+// protocols sets the schema of the settings of def, by the protocol picked with loader.
+func (g *schemaGenerator) protocols(def string, loader *JSONConfigLoader) {
+	s := g.defs[def]
+	names := make([]string, 0, len(loader.cache))
+	for name := range loader.cache {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	s.Properties[loader.idKey] = &Schema{Type: []string{"string"}, Enum: names}
+	for _, name := range names {
+		s.AllOf = append(s.AllOf, &Schema{
+			If: &Schema{
+				Properties: map[string]*Schema{loader.idKey: {Const: name}},
+				Required:   []string{loader.idKey},
+			},
+			Then: &Schema{
+				Properties: map[string]*Schema{loader.configKey: g.of(reflect.TypeOf(loader.cache[name]()))},
+			},
+		})
+	}
+}
+
+// ConfigSchema returns the schema of configs in JSON, for editors to complete and check them. The
+// settings of inbounds and outbounds are described for each protocol.
+func ConfigSchema() *Schema {
+	g := newSchemaGenerator()
+	root := g.define(reflect.TypeOf(Config{}))
+	g.defs["RouterConfig"].Properties["rules"] = &Schema{
+		Type:  []string{"array"},
+		Items: g.of(reflect.TypeOf(RawFieldRule{})),
+	}
+	g.protocols(g.define(reflect.TypeOf(InboundDetourConfig{})), inboundConfigLoader)
+	g.protocols(g.define(reflect.TypeOf(OutboundDetourConfig{})), outboundConfigLoader)
+	return &Schema{
+		Schema: "https://json-schema.org/draft/2020-12/schema",
+		Ref:    "#/$defs/" + root,
+		Defs:   g.defs,
+	}
+}
+
+// Validate returns the errors of the JSON value v, as decoded by encoding/json, against s. Names
+// of properties and strings of enums are matched regardless of case, as Xray decodes them.
+func (s *Schema) Validate(v interface{}) []SchemaError {
+	var errs []SchemaError
+	s.validate(s, v, "", &errs)
+	return errs
+}
+
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func (s *Schema) validate(root *Schema, v interface{}, pointer string, errs *[]SchemaError) {
+	fail := func(msg ...string) {
+		*errs = append(*errs, SchemaError{Pointer: pointer, Message: strings.Join(msg, "")})
+	}
+	if s.Ref != "" {
+		if def := root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")]; def != nil {
+			def.validate(root, v, pointer, errs)
+		}
+	}
+	// null stands for the default value of any type.
+	if v == nil {
+		return
+	}
+	if len(s.Type) > 0 {
+		t := jsonType(v)
+		matched := false
+		for _, want := range s.Type {
+			if want == t || want == "number" && t == "integer" {
+				matched = true
+			}
+		}
+		if !matched {
+			fail("expected ", strings.Join(s.Type, " or "), ", got ", t)
+			return
+		}
+	}
+	if str, ok := v.(string); ok {
+		if s.Const != "" && !strings.EqualFold(str, s.Const) {
+			fail("expected ", strconv.Quote(s.Const))
+		}
+		if len(s.Enum) > 0 {
+			found := false
+			for _, e := range s.Enum {
+				found = found || strings.EqualFold(str, e)
+			}
+			if !found {
+				fail("unknown value ", strconv.Quote(str), ", expected one of ", strings.Join(s.Enum, ", "))
+			}
+		}
+	}
+	if obj, ok := v.(map[string]interface{}); ok {
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			p := pointer + "/" + escapePointer(key)
+			if property := s.property(key); property != nil {
+				property.validate(root, obj[key], p, errs)
+			} else if s.Values != nil {
+				s.Values.validate(root, obj[key], p, errs)
+			} else if s.Closed {
+				*errs = append(*errs, SchemaError{Pointer: p, Message: "unknown property " + strconv.Quote(key)})
+			}
+		}
+		for _, key := range s.Required {
+			if _, found := obj[key]; !found {
+				fail("missing property ", strconv.Quote(key))
+			}
+		}
+	}
+	if list, ok := v.([]interface{}); ok && s.Items != nil {
+		for i, item := range list {
+			s.Items.validate(root, item, pointer+"/"+strconv.Itoa(i), errs)
+		}
+	}
+	if len(s.AnyOf) > 0 {
+		// Without a match, the errors of the closest alternative are reported: one of the type
+		// of v, with the fewest errors.
+		var closest []SchemaError
+		closestScore := -1
+		for _, alternative := range s.AnyOf {
+			var altErrs []SchemaError
+			alternative.validate(root, v, pointer, &altErrs)
+			if len(altErrs) == 0 {
+				closest = nil
+				break
+			}
+			score := len(altErrs)
+			for _, e := range altErrs {
+				if e.Pointer == pointer {
+					score += 1 << 16
+				}
+			}
+			if closestScore < 0 || score < closestScore {
+				closest, closestScore = altErrs, score
+			}
+		}
+		*errs = append(*errs, closest...)
+	}
+	for _, sub := range s.AllOf {
+		sub.validate(root, v, pointer, errs)
+	}
+	if s.If != nil && s.Then != nil {
+		var ifErrs []SchemaError
+		s.If.validate(root, v, pointer, &ifErrs)
+		if len(ifErrs) == 0 {
+			s.Then.validate(root, v, pointer, errs)
+		}
+	}
+}
+
+// property returns the schema of the property name, matched as encoding/json does: exactly, or
+// else regardless of case.
+func (s *Schema) property(name string) *Schema {
+	if p, found := s.Properties[name]; found {
+		return p
+	}
+	for key, p := range s.Properties {
+		if strings.EqualFold(key, name) {
+			return p
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+//go:build !xray_minimal_router
+
+package conf_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	. "github.com/xtls/xray-core/infra/conf"
+)
+
+func TestConfigSchemaValidate(t *testing.T) {
+	schema := ConfigSchema()
+	if _, err := json.Marshal(schema); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		input string
+		want  []string
+	}{
+		{
+			input: `{
+				"log": {"loglevel": "warning"},
+				"inbounds": [{"protocol": "SOCKS", "port": "1080-1081", "listen": "127.0.0.1", "settings": {"udp": true}}],
+				"outbounds": [{"protocol": "freedom", "settings": {"domainStrategy": "UseIP"}}],
+				"routing": {"rules": [{"domain": "a.com,b.com", "outboundTag": "direct"}]},
+				"dns": {"servers": ["1.1.1.1", {"address": "8.8.8.8", "domains": ["geosite:google"]}], "hosts": {"a.com": ["1.1.1.1"]}}
+			}`,
+		},
+		{
+			input: `{
+				"log": {"loglevel": 3},
+				"inbounds": [{"protocol": "socks", "port": true, "settings": {"udpp": true}}],
+				"outbounds": [{"protocol": "vpn"}],
+				"routing": {"rules": [{"domian": ["a.com"], "outboundTag": "direct"}]},
+				"dns": {"servers": [{"adress": "8.8.8.8"}]}
+			}`,
+			want: []string{
+				`/dns/servers/0/adress: unknown property "adress"`,
+				"/inbounds/0/port: expected integer or string, got boolean",
+				`/inbounds/0/settings/udpp: unknown property "udpp"`,
+				"/log/loglevel: expected string, got integer",
+				`/outbounds/0/protocol: unknown value "vpn", expected one of blackhole, dns, freedom, http, loopback, shadowsocks, socks, trojan, vless, vmess, wireguard`,
+				`/routing/rules/0/domian: unknown property "domian"`,
+			},
+		},
+	}
+	for _, c := range cases {
+		var v interface{}
+		if err := json.Unmarshal([]byte(c.input), &v); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range schema.Validate(v) {
+			got = append(got, e.Error())
+		}
+		if r := cmp.Diff(got, c.want); r != "" {
+			t.Error(r)
+		}
+	}
+}
@@ -29,10 +29,15 @@ func mergeConfigs(files []*core.ConfigSource) (*conf.Config, error) {
 		errors.LogInfo(context.Background(), "Reading config: ", file)
 		r, err := confloader.LoadConfig(file.Name)
 		if err != nil {
-			return nil, errors.New("failed to read config: ", file).Base(err)
+			diag := errors.Diagnose(errors.CodeConfigRead, err)
+			diag.File = file.Name
+			return nil, errors.New("failed to read config: ", file).Base(diag)
 		}
 		c, err := ReaderDecoderByFormat[file.Format](r)
 		if err != nil {
+			if diag := errors.DiagnosticOf(err); diag != nil {
+				diag.File = file.Name
+			}
 			return nil, errors.New("failed to decode config: ", file).Base(err)
 		}
 		if i == 0 {
@@ -44,6 +49,11 @@ func mergeConfigs(files []*core.ConfigSource) (*conf.Config, error) {
 	return cf, nil
 }
 
+// DecodeConfigFromFiles decodes and merges the given files, without building them.
+func DecodeConfigFromFiles(files []*core.ConfigSource) (*conf.Config, error) {
+	return mergeConfigs(files)
+}
+
 func BuildConfig(files []*core.ConfigSource) (*core.Config, error) {
 	config, err := mergeConfigs(files)
 	if err != nil {
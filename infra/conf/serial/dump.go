@@ -0,0 +1,132 @@
+package serial
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pelletier/go-toml"
+	"github.com/xtls/xray-core/common/errors"
+	creflect "github.com/xtls/xray-core/common/reflect"
+)
+
+// Redacted replaces the secrets of a redacted config.
+const Redacted = "<redacted>"
+
+// secretKeys are the keys of the secrets of a config, in lower case: UUIDs of users, passwords,
+// private keys and keys shared with the peers.
+var secretKeys = map[string]bool{
+	"id":           true,
+	"pass":         true,
+	"password":     true,
+	"secret":       true,
+	"secretkey":    true,
+	"privatekey":   true,
+	"presharedkey": true,
+	"key":          true,
+	"seed":         true,
+	"shortid":      true,
+	"shortids":     true,
+}
+
+var uuidPattern = regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// EncodeMergedConfig encodes config, the JSON of a merged config, in format, "json", "yaml" or
+// "toml", with its keys sorted. If redact, the secrets of the config are masked, as are UUIDs
+// anywhere, so that it can be shared.
+func EncodeMergedConfig(config string, format string, redact bool) (string, error) {
+	var tree interface{}
+	decoder := json.NewDecoder(strings.NewReader(config))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tree); err != nil {
+		return "", errors.New("failed to decode merged config").Base(err)
+	}
+	if redact {
+		tree = redactValue(tree, false)
+	}
+
+	switch strings.ToLower(format) {
+	case "", "json":
+		b, err := creflect.JSONMarshalWithoutEscape(tree)
+		if err != nil {
+			return "", errors.New("failed to encode config to json").Base(err)
+		}
+		return string(b), nil
+	case "yaml", "yml":
+		b, err := json.Marshal(tree)
+		if err != nil {
+			return "", errors.New("failed to encode config to json").Base(err)
+		}
+		y, err := yaml.JSONToYAML(b)
+		if err != nil {
+			return "", errors.New("failed to encode config to yaml").Base(err)
+		}
+		return string(y), nil
+	case "toml":
+		m, ok := tomlValue(tree).(map[string]interface{})
+		if !ok {
+			return "", errors.New("config is not an object")
+		}
+		t, err := toml.TreeFromMap(m)
+		if err != nil {
+			return "", errors.New("failed to encode config to toml").Base(err)
+		}
+		return t.ToTomlString()
+	default:
+		return "", errors.New("unknown format: ", format)
+	}
+}
+
+// redactValue masks the secrets of v, all of its strings if secret.
+func redactValue(v interface{}, secret bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = redactValue(value, secret || secretKeys[strings.ToLower(key)])
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactValue(value, secret)
+		}
+		return v
+	case string:
+		if secret {
+			return Redacted
+		}
+		return uuidPattern.ReplaceAllString(v, Redacted)
+	default:
+		return v
+	}
+}
+
+// tomlValue converts v to the values go-toml encodes: without nulls, which TOML has no notation
+// for, and with integers apart from floats.
+func tomlValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if value == nil {
+				delete(v, key)
+				continue
+			}
+			v[key] = tomlValue(value)
+		}
+	case []interface{}:
+		values := v[:0]
+		for _, value := range v {
+			if value != nil {
+				values = append(values, tomlValue(value))
+			}
+		}
+		return values
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
@@ -0,0 +1,53 @@
+package serial_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/infra/conf/serial"
+)
+
+func TestEncodeMergedConfig(t *testing.T) {
+	config := `{
+		"inbounds": [{
+			"port": 443,
+			"protocol": "vless",
+			"tag": "in-b831381d-6324-4d53-ad4f-8cda48b30811",
+			"settings": {"clients": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811", "email": "user@example.com"}]},
+			"streamSettings": {"security": "reality", "realitySettings": {"privateKey": "aGVsbG8", "shortIds": ["abcd"]}}
+		}],
+		"outbounds": [{"protocol": "shadowsocks", "settings": {"servers": [{"address": "1.2.3.4", "port": 8388, "password": "secret"}]}}]
+	}`
+
+	for _, format := range []string{"json", "yaml", "toml"} {
+		out, err := serial.EncodeMergedConfig(config, format, true)
+		common.Must(err)
+		for _, secret := range []string{"b831381d", "aGVsbG8", "abcd", "secret"} {
+			if strings.Contains(out, secret) {
+				t.Error(format, ": ", secret, " not redacted")
+			}
+		}
+		if !strings.Contains(out, "user@example.com") {
+			t.Error(format, ": email redacted")
+		}
+
+		decode := map[string]func(r *strings.Reader) error{
+			"json": func(r *strings.Reader) error { _, err := serial.DecodeJSONConfig(r); return err },
+			"yaml": func(r *strings.Reader) error { _, err := serial.DecodeYAMLConfig(r); return err },
+			"toml": func(r *strings.Reader) error { _, err := serial.DecodeTOMLConfig(r); return err },
+		}[format]
+		if err := decode(strings.NewReader(out)); err != nil {
+			t.Error(format, ": output not loadable: ", err)
+		}
+	}
+
+	out, err := serial.EncodeMergedConfig(config, "toml", false)
+	common.Must(err)
+	if !strings.Contains(out, "port = 443\n") || !strings.Contains(out, `password = "secret"`) {
+		t.Error("unexpected toml: ", out)
+	}
+	if _, err := serial.EncodeMergedConfig(config, "xml", false); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
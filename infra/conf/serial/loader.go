@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"strconv"
+	"strings"
 
 	"github.com/ghodss/yaml"
 	"github.com/pelletier/go-toml"
@@ -40,6 +42,70 @@ func findOffset(b []byte, o int) *offset {
 	return &offset{line: line, char: char}
 }
 
+type jsonFrame struct {
+	array bool
+	index int    // of the current element, in arrays
+	key   string // of the current member, in objects
+	value bool   // whether key was read without its value yet, in objects
+}
+
+// jsonPathAt returns the path of the value at offset o of the JSON document b, such as
+// "inbounds[0].settings". The document may be invalid after o.
+func jsonPathAt(b []byte, o int) string {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	var stack []*jsonFrame
+	startValue := func() {
+		if n := len(stack); n > 0 && stack[n-1].array {
+			stack[n-1].index++
+		}
+	}
+	endValue := func() {
+		if n := len(stack); n > 0 && !stack[n-1].array {
+			stack[n-1].value = false
+		}
+	}
+	for decoder.InputOffset() < int64(o) {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if n := len(stack); n > 0 && !stack[n-1].array && !stack[n-1].value {
+			if key, ok := token.(string); ok {
+				stack[n-1].key = key
+				stack[n-1].value = true
+				continue
+			}
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			startValue()
+			stack = append(stack, &jsonFrame{array: token == json.Delim('['), index: -1})
+		case json.Delim('}'), json.Delim(']'):
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			endValue()
+		default:
+			startValue()
+			endValue()
+		}
+	}
+
+	var path strings.Builder
+	for _, f := range stack {
+		switch {
+		case f.array && f.index >= 0:
+			path.WriteString("[" + strconv.Itoa(f.index) + "]")
+		case !f.array && f.key != "":
+			if path.Len() > 0 {
+				path.WriteByte('.')
+			}
+			path.WriteString(f.key)
+		}
+	}
+	return path.String()
+}
+
 // DecodeJSONConfig reads from reader and decode the config into *conf.Config
 // syntax error could be detected.
 func DecodeJSONConfig(reader io.Reader) (*conf.Config, error) {
@@ -53,17 +119,25 @@ func DecodeJSONConfig(reader io.Reader) (*conf.Config, error) {
 
 	if err := decoder.Decode(jsonConfig); err != nil {
 		var pos *offset
+		var diag *errors.Diagnostic
 		cause := errors.Cause(err)
 		switch tErr := cause.(type) {
 		case *json.SyntaxError:
 			pos = findOffset(jsonContent.Bytes(), int(tErr.Offset))
+			diag = errors.Diagnose(errors.CodeConfigSyntax, err)
+			diag.Path = jsonPathAt(jsonContent.Bytes(), int(tErr.Offset))
 		case *json.UnmarshalTypeError:
 			pos = findOffset(jsonContent.Bytes(), int(tErr.Offset))
+			diag = errors.Diagnose(errors.CodeConfigType, err)
+			diag.Path = jsonPathAt(jsonContent.Bytes(), int(tErr.Offset))
+		default:
+			diag = errors.Diagnose(errors.CodeConfigInvalid, err)
 		}
 		if pos != nil {
-			return nil, errors.New("failed to read config file at line ", pos.line, " char ", pos.char).Base(err)
+			diag.Line, diag.Char = pos.line, pos.char
+			return nil, errors.New("failed to read config file at line ", pos.line, " char ", pos.char).Base(diag)
 		}
-		return nil, errors.New("failed to read config file").Base(err)
+		return nil, errors.New("failed to read config file").Base(diag)
 	}
 
 	return jsonConfig, nil
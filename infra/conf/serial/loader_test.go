@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/infra/conf/serial"
 )
 
@@ -61,3 +62,23 @@ func TestLoaderError(t *testing.T) {
 		}
 	}
 }
+
+func TestLoaderDiagnostic(t *testing.T) {
+	input := `{
+		"inbounds": [{
+			"port": 1,
+			"protocol": "test"
+		}, {
+			"port": 2,
+			"tag": 5
+		}]
+	}`
+	_, err := serial.DecodeJSONConfig(bytes.NewReader([]byte(input)))
+	diag := errors.DiagnosticOf(err)
+	if diag == nil {
+		t.Fatal("no diagnostic in ", err)
+	}
+	if diag.Code != errors.CodeConfigType || diag.Path != "inbounds[1].tag" || diag.Line != 7 {
+		t.Error("unexpected diagnostic: ", diag.Code, " ", diag.Path, " line ", diag.Line)
+	}
+}
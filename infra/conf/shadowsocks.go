@@ -47,16 +47,35 @@ type ShadowsocksServerConfig struct {
 	Users       []*ShadowsocksUserConfig `json:"clients"`
 	NetworkList *NetworkList             `json:"network"`
 	IVCheck     bool                     `json:"ivCheck"`
+	Replay      *ReplayConfig            `json:"replay"`
+	// Largest accepted clock skew of Shadowsocks 2022 clients, in seconds.
+	TimestampTolerance uint32 `json:"timestampTolerance"`
 }
 
 func (v *ShadowsocksServerConfig) Build() (proto.Message, error) {
 	if C.Contains(shadowaead_2022.List, v.Cipher) {
+		if v.Replay != nil {
+			return nil, errors.New("replay is not supported by Shadowsocks 2022, which has its own replay protection")
+		}
 		return buildShadowsocks2022(v)
 	}
+	if v.TimestampTolerance != 0 {
+		return nil, errors.New("timestampTolerance is only supported by Shadowsocks 2022")
+	}
 
 	config := new(shadowsocks.ServerConfig)
 	config.Network = v.NetworkList.Build()
 
+	if v.Replay != nil {
+		replay, err := v.Replay.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Replay = replay
+		// Replays are only detected with the IV check.
+		v.IVCheck = true
+	}
+
 	if v.Users != nil {
 		for _, user := range v.Users {
 			account := &shadowsocks.Account{
@@ -106,6 +125,7 @@ func buildShadowsocks2022(v *ShadowsocksServerConfig) (proto.Message, error) {
 		config.Key = v.Password
 		config.Network = v.NetworkList.Build()
 		config.Email = v.Email
+		config.TimestampTolerance = v.TimestampTolerance
 		return config, nil
 	}
 
@@ -121,6 +141,7 @@ func buildShadowsocks2022(v *ShadowsocksServerConfig) (proto.Message, error) {
 		config.Method = v.Cipher
 		config.Key = v.Password
 		config.Network = v.NetworkList.Build()
+		config.TimestampTolerance = v.TimestampTolerance
 
 		for _, user := range v.Users {
 			if user.Cipher != "" {
@@ -138,6 +159,9 @@ func buildShadowsocks2022(v *ShadowsocksServerConfig) (proto.Message, error) {
 		return config, nil
 	}
 
+	if v.TimestampTolerance != 0 {
+		return nil, errors.New("shadowsocks 2022 (relay): timestampTolerance is not supported")
+	}
 	config := new(shadowsocks_2022.RelayServerConfig)
 	config.Method = v.Cipher
 	config.Key = v.Password
@@ -172,7 +196,8 @@ type ShadowsocksServerTarget struct {
 }
 
 type ShadowsocksClientConfig struct {
-	Servers []*ShadowsocksServerTarget `json:"servers"`
+	Servers          []*ShadowsocksServerTarget `json:"servers"`
+	HandshakeShaping *HandshakeShapingConfig    `json:"handshakeShaping"`
 }
 
 func (v *ShadowsocksClientConfig) Build() (proto.Message, error) {
@@ -200,10 +225,21 @@ func (v *ShadowsocksClientConfig) Build() (proto.Message, error) {
 			config.Key = server.Password
 			config.UdpOverTcp = server.UoT
 			config.UdpOverTcpVersion = uint32(server.UoTVersion)
+			if v.HandshakeShaping != nil {
+				shaping, err := v.HandshakeShaping.Build()
+				if err != nil {
+					return nil, err
+				}
+				config.HandshakeShaping = shaping
+			}
 			return config, nil
 		}
 	}
 
+	if v.HandshakeShaping != nil {
+		return nil, errors.New("handshake shaping is only supported by Shadowsocks 2022")
+	}
+
 	config := new(shadowsocks.ClientConfig)
 	serverSpecs := make([]*protocol.ServerEndpoint, len(v.Servers))
 	for idx, server := range v.Servers {
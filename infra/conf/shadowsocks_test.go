@@ -8,6 +8,7 @@ import (
 	"github.com/xtls/xray-core/common/serial"
 	. "github.com/xtls/xray-core/infra/conf"
 	"github.com/xtls/xray-core/proxy/shadowsocks"
+	"github.com/xtls/xray-core/proxy/shadowsocks_2022"
 )
 
 func TestShadowsocksServerConfigParsing(t *testing.T) {
@@ -32,5 +33,66 @@ func TestShadowsocksServerConfigParsing(t *testing.T) {
 				Network: []net.Network{net.Network_TCP},
 			},
 		},
+		{
+			Input: `{
+				"method": "2022-blake3-aes-128-gcm",
+				"password": "AAAAAAAAAAAAAAAAAAAAAA==",
+				"timestampTolerance": 300
+			}`,
+			Parser: loadJSON(creator),
+			Output: &shadowsocks_2022.ServerConfig{
+				Method:             "2022-blake3-aes-128-gcm",
+				Key:                "AAAAAAAAAAAAAAAAAAAAAA==",
+				Network:            []net.Network{net.Network_TCP},
+				TimestampTolerance: 300,
+			},
+		},
 	})
 }
+
+func TestShadowsocksClientConfigParsing(t *testing.T) {
+	creator := func() Buildable {
+		return new(ShadowsocksClientConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"servers": [{
+					"address": "127.0.0.1",
+					"port": 1234,
+					"method": "2022-blake3-aes-128-gcm",
+					"password": "AAAAAAAAAAAAAAAAAAAAAA=="
+				}],
+				"handshakeShaping": {
+					"packets": 2,
+					"length": "20-60",
+					"interval": 5,
+					"padHeader": true
+				}
+			}`,
+			Parser: loadJSON(creator),
+			Output: &shadowsocks_2022.ClientConfig{
+				Address: net.NewIPOrDomain(net.LocalHostIP),
+				Port:    1234,
+				Method:  "2022-blake3-aes-128-gcm",
+				Key:     "AAAAAAAAAAAAAAAAAAAAAA==",
+				HandshakeShaping: &protocol.HandshakeShaping{
+					Packets:     2,
+					LengthMin:   20,
+					LengthMax:   60,
+					IntervalMin: 5,
+					IntervalMax: 5,
+					PadHeader:   true,
+				},
+			},
+		},
+	})
+
+	if _, err := loadJSON(creator)(`{
+		"servers": [{"address": "127.0.0.1", "port": 1234, "method": "2022-blake3-aes-128-gcm", "password": "AAAAAAAAAAAAAAAAAAAAAA=="}],
+		"handshakeShaping": {"packets": 2}
+	}`); err == nil {
+		t.Error("expected error of handshake segments without length")
+	}
+}
@@ -0,0 +1,90 @@
+package conf
+
+import (
+	"strings"
+
+	"github.com/xtls/xray-core/app/shaper"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/units"
+)
+
+// ShaperConfig is the JSON config of the traffic shaper.
+type ShaperConfig struct {
+	Limits    []*ShaperLimitConfig `json:"limits"`
+	UsageFile string               `json:"usageFile"`
+	ResetDay  uint32               `json:"resetDay"`
+}
+
+// ShaperLimitConfig limits the connections of one of an inbound, a user or an outbound, with a
+// rate such as "10mbps" and a monthly quota such as "100GB".
+type ShaperLimitConfig struct {
+	InboundTag  string `json:"inboundTag"`
+	User        string `json:"user"`
+	OutboundTag string `json:"outboundTag"`
+	Rate        string `json:"rate"`
+	Quota       string `json:"quota"`
+	// "throttle" to throttleRate, the default, or "reject" new connections once the quota is used up
+	QuotaAction  string `json:"quotaAction"`
+	ThrottleRate string `json:"throttleRate"`
+}
+
+func (c *ShaperConfig) Build() (*shaper.Config, error) {
+	if c.ResetDay > 28 {
+		return nil, errors.New("shaper resetDay must be from 1 to 28")
+	}
+	config := &shaper.Config{
+		UsageFile: c.UsageFile,
+		ResetDay:  c.ResetDay,
+	}
+	for i, l := range c.Limits {
+		limit, err := l.Build()
+		if err != nil {
+			return nil, errors.New("invalid limit ", i, " of shaper").Base(err)
+		}
+		config.Limit = append(config.Limit, limit)
+	}
+	return config, nil
+}
+
+func (c *ShaperLimitConfig) Build() (*shaper.Limit, error) {
+	limit := &shaper.Limit{
+		InboundTag:  c.InboundTag,
+		UserEmail:   c.User,
+		OutboundTag: c.OutboundTag,
+	}
+	set := 0
+	for _, s := range []string{c.InboundTag, c.User, c.OutboundTag} {
+		if s != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, errors.New("exactly one of inboundTag, user and outboundTag must be set")
+	}
+	var err error
+	if limit.Rate, err = parseRate(c.Rate); err != nil {
+		return nil, errors.New("invalid rate").Base(err)
+	}
+	if c.Quota != "" {
+		var quota units.ByteSize
+		if err := quota.Parse(c.Quota); err != nil {
+			return nil, errors.New("invalid quota").Base(err)
+		}
+		limit.Quota = uint64(quota)
+	}
+	switch strings.ToLower(c.QuotaAction) {
+	case "", "throttle":
+		limit.QuotaAction = shaper.Limit_Throttle
+		if limit.ThrottleRate, err = parseRate(c.ThrottleRate); err != nil {
+			return nil, errors.New("invalid throttleRate").Base(err)
+		}
+		if limit.Quota > 0 && limit.ThrottleRate == 0 {
+			return nil, errors.New("no throttleRate to throttle to once the quota is used up")
+		}
+	case "reject":
+		limit.QuotaAction = shaper.Limit_Reject
+	default:
+		return nil, errors.New("unknown quotaAction ", c.QuotaAction, ", expecting throttle or reject")
+	}
+	return limit, nil
+}
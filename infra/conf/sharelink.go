@@ -0,0 +1,318 @@
+package conf
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// parseShadowsocksLink parses ss:// links, in the form of SIP002,
+// ss://userinfo@address:port#name where userinfo is "method:password" either encoded in
+// base64 or percent-encoded, and in the legacy form, ss://base64(method:password@address:port)#name.
+func parseShadowsocksLink(link string) (*OutboundDetourConfig, string, error) {
+	body, fragment, _ := strings.Cut(link[len("ss://"):], "#")
+	name, err := url.PathUnescape(fragment)
+	if err != nil {
+		name = fragment
+	}
+	if !strings.Contains(body, "@") {
+		decoded, err := decodeBase64(body)
+		if err != nil {
+			return nil, "", errors.New("invalid ss link").Base(err)
+		}
+		userinfo, hostport, found := strings.Cut(string(decoded), "@")
+		if !found {
+			return nil, "", errors.New("no server in ss link")
+		}
+		method, password, _ := strings.Cut(userinfo, ":")
+		body = url.UserPassword(method, password).String() + "@" + hostport
+	}
+	u, err := url.Parse("ss://" + body)
+	if err != nil {
+		return nil, "", errors.New("invalid ss link").Base(err)
+	}
+	if u.User == nil {
+		return nil, "", errors.New("no credential in ss link")
+	}
+	method := u.User.Username()
+	password, found := u.User.Password()
+	if !found {
+		decoded, err := decodeBase64(method)
+		if err != nil {
+			return nil, "", errors.New("invalid credential of ss link").Base(err)
+		}
+		method, password, _ = strings.Cut(string(decoded), ":")
+	}
+	port, err := strconv.ParseUint(u.Port(), 10, 16)
+	if err != nil {
+		return nil, "", errors.New("invalid port of ss link").Base(err)
+	}
+	if plugin := u.Query().Get("plugin"); plugin != "" {
+		return nil, "", errors.New("unsupported plugin of ss link: ", plugin)
+	}
+	settings := map[string]interface{}{
+		"servers": []interface{}{map[string]interface{}{
+			"address":  u.Hostname(),
+			"port":     port,
+			"method":   method,
+			"password": password,
+		}},
+	}
+	ob, err := newShareLinkOutbound("shadowsocks", settings, url.Values{})
+	return ob, name, err
+}
+
+// shareLinkServer is a server in the settings of the outbounds share links can hold, which have
+// it under "vnext" for VMess and VLESS, and "servers" for Trojan and Shadowsocks.
+type shareLinkServer struct {
+	Address string `json:"address"`
+	Port    uint16 `json:"port"`
+	Users   []struct {
+		ID         string `json:"id"`
+		AlterID    uint16 `json:"alterId"`
+		Security   string `json:"security"`
+		Encryption string `json:"encryption"`
+		Flow       string `json:"flow"`
+	} `json:"users"`
+	Password string `json:"password"`
+	Method   string `json:"method"`
+}
+
+// ShareLink returns the share link of the outbound, named after its tag. Only VMess, VLESS,
+// Trojan and Shadowsocks outbounds with a single server, and transports share links can
+// describe, have one.
+func ShareLink(ob *OutboundDetourConfig) (string, error) {
+	if ob.Settings == nil {
+		return "", errors.New("outbound ", ob.Tag, " has no settings")
+	}
+	var settings struct {
+		Vnext   []shareLinkServer `json:"vnext"`
+		Servers []shareLinkServer `json:"servers"`
+	}
+	if err := json.Unmarshal(*ob.Settings, &settings); err != nil {
+		return "", errors.New("invalid settings of outbound ", ob.Tag).Base(err)
+	}
+	servers := settings.Servers
+	protocol := strings.ToLower(ob.Protocol)
+	if protocol == "vmess" || protocol == "vless" {
+		servers = settings.Vnext
+	}
+	if len(servers) != 1 {
+		return "", errors.New("outbound ", ob.Tag, " has ", len(servers), " servers, but a share link holds one")
+	}
+	server := servers[0]
+	if (protocol == "vmess" || protocol == "vless") && len(server.Users) != 1 {
+		return "", errors.New("outbound ", ob.Tag, " has ", len(server.Users), " users, but a share link holds one")
+	}
+
+	params, err := shareLinkParams(ob.StreamSetting)
+	if err != nil {
+		return "", errors.New("failed to describe transport of outbound ", ob.Tag).Base(err)
+	}
+	host := net.JoinHostPort(server.Address, strconv.Itoa(int(server.Port)))
+	switch protocol {
+	case "vmess":
+		return vmessShareLink(ob.Tag, server, params)
+	case "vless":
+		user := server.Users[0]
+		encryption := user.Encryption
+		if encryption == "" {
+			encryption = "none"
+		}
+		params.Set("encryption", encryption)
+		setParam(params, "flow", user.Flow)
+		u := &url.URL{Scheme: "vless", User: url.User(user.ID), Host: host, RawQuery: params.Encode(), Fragment: ob.Tag}
+		return u.String(), nil
+	case "trojan":
+		u := &url.URL{Scheme: "trojan", User: url.User(server.Password), Host: host, RawQuery: params.Encode(), Fragment: ob.Tag}
+		return u.String(), nil
+	case "shadowsocks":
+		if len(params) > 1 || params.Get("type") != "tcp" {
+			return "", errors.New("ss links can't describe the transport of outbound ", ob.Tag)
+		}
+		// SIP002 asks for the credential in base64 but for 2022 methods, whose keys are in base64 already.
+		user := url.User(base64.RawURLEncoding.EncodeToString([]byte(server.Method + ":" + server.Password)))
+		if strings.HasPrefix(server.Method, "2022-") {
+			user = url.UserPassword(server.Method, server.Password)
+		}
+		u := &url.URL{Scheme: "ss", User: user, Host: host, Fragment: ob.Tag}
+		return u.String(), nil
+	default:
+		return "", errors.New("no share link for protocol ", ob.Protocol, " of outbound ", ob.Tag)
+	}
+}
+
+func vmessShareLink(name string, server shareLinkServer, params url.Values) (string, error) {
+	user := server.Users[0]
+	path := params.Get("path")
+	if params.Get("type") == "grpc" {
+		path = params.Get("serviceName")
+	}
+	v := &vmessLink{
+		Version:  "2",
+		Name:     name,
+		Address:  server.Address,
+		Port:     json.RawMessage(strconv.Quote(strconv.Itoa(int(server.Port)))),
+		ID:       user.ID,
+		AlterID:  json.RawMessage(strconv.Quote(strconv.Itoa(int(user.AlterID)))),
+		Security: user.Security,
+		Network:  params.Get("type"),
+		Type:     params.Get("headerType"),
+		Host:     params.Get("host"),
+		Path:     path,
+		TLS:      params.Get("security"),
+		SNI:      params.Get("sni"),
+		ALPN:     params.Get("alpn"),
+		FP:       params.Get("fp"),
+	}
+	if v.TLS == "reality" {
+		return "", errors.New("vmess links can't describe REALITY")
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return "vmess://" + base64.StdEncoding.EncodeToString(b), nil
+}
+
+func setParam(params url.Values, key, value string) {
+	if value != "" {
+		params.Set(key, value)
+	}
+}
+
+// shareLinkParams returns the transport parameters of a share link for stream, the inverse of
+// newShareLinkOutbound.
+func shareLinkParams(stream *StreamConfig) (url.Values, error) {
+	params := url.Values{}
+	if stream == nil {
+		stream = &StreamConfig{}
+	}
+	network := "tcp"
+	if stream.Network != nil && *stream.Network != "" {
+		network = strings.ToLower(string(*stream.Network))
+	}
+	switch network {
+	case "tcp", "raw":
+		params.Set("type", "tcp")
+		tcp := stream.RAWSettings
+		if tcp == nil {
+			tcp = stream.TCPSettings
+		}
+		if tcp != nil && len(tcp.HeaderConfig) > 0 {
+			var header struct {
+				Type    string `json:"type"`
+				Request struct {
+					Path    []string            `json:"path"`
+					Headers map[string][]string `json:"headers"`
+				} `json:"request"`
+			}
+			if err := json.Unmarshal(tcp.HeaderConfig, &header); err != nil {
+				return nil, errors.New("invalid TCP header").Base(err)
+			}
+			if header.Type == "http" {
+				params.Set("headerType", "http")
+				setParam(params, "path", strings.Join(header.Request.Path, ","))
+				setParam(params, "host", strings.Join(header.Request.Headers["Host"], ","))
+			}
+		}
+	case "ws":
+		params.Set("type", "ws")
+		if ws := stream.WSSettings; ws != nil {
+			setParam(params, "path", ws.Path)
+			setParam(params, "host", hostOf(ws.Host, ws.Headers))
+		}
+	case "httpupgrade":
+		params.Set("type", "httpupgrade")
+		if hu := stream.HTTPUPGRADESettings; hu != nil {
+			setParam(params, "path", hu.Path)
+			setParam(params, "host", hostOf(hu.Host, hu.Headers))
+		}
+	case "xhttp", "splithttp":
+		params.Set("type", "xhttp")
+		xhttp := stream.XHTTPSettings
+		if xhttp == nil {
+			xhttp = stream.SplitHTTPSettings
+		}
+		if xhttp != nil {
+			setParam(params, "path", xhttp.Path)
+			setParam(params, "host", hostOf(xhttp.Host, xhttp.Headers))
+			setParam(params, "mode", xhttp.Mode)
+		}
+	case "grpc":
+		params.Set("type", "grpc")
+		if grpc := stream.GRPCSettings; grpc != nil {
+			setParam(params, "serviceName", grpc.ServiceName)
+			setParam(params, "authority", grpc.Authority)
+			if grpc.MultiMode {
+				params.Set("mode", "multi")
+			}
+		}
+	case "kcp", "mkcp":
+		params.Set("type", "kcp")
+		if kcp := stream.KCPSettings; kcp != nil {
+			if len(kcp.HeaderConfig) > 0 {
+				var header struct {
+					Type string `json:"type"`
+				}
+				if err := json.Unmarshal(kcp.HeaderConfig, &header); err != nil {
+					return nil, errors.New("invalid mKCP header").Base(err)
+				}
+				setParam(params, "headerType", header.Type)
+			}
+			if kcp.Seed != nil {
+				setParam(params, "seed", *kcp.Seed)
+			}
+		}
+	default:
+		return nil, errors.New("share links can't describe network ", network)
+	}
+
+	switch security := strings.ToLower(stream.Security); security {
+	case "", "none":
+	case "tls":
+		params.Set("security", "tls")
+		if tls := stream.TLSSettings; tls != nil {
+			setParam(params, "sni", tls.ServerName)
+			setParam(params, "fp", tls.Fingerprint)
+			if tls.ALPN != nil {
+				setParam(params, "alpn", strings.Join(*tls.ALPN, ","))
+			}
+			if tls.Insecure {
+				params.Set("allowInsecure", "1")
+			}
+		}
+	case "reality":
+		params.Set("security", "reality")
+		if reality := stream.REALITYSettings; reality != nil {
+			setParam(params, "sni", reality.ServerName)
+			setParam(params, "fp", reality.Fingerprint)
+			setParam(params, "pbk", reality.PublicKey)
+			setParam(params, "sid", reality.ShortId)
+			if reality.SpiderX != "/" {
+				setParam(params, "spx", reality.SpiderX)
+			}
+		}
+	default:
+		return nil, errors.New("share links can't describe security ", security)
+	}
+	return params, nil
+}
+
+// hostOf returns host, or the Host header if host is empty.
+func hostOf(host string, headers map[string]string) string {
+	if host != "" {
+		return host
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, "host") {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+//go:build !xray_minimal_router
+
+package conf_test
+
+import (
+	"net/url"
+	"testing"
+
+	. "github.com/xtls/xray-core/infra/conf"
+)
+
+func TestShareLinkRoundTrip(t *testing.T) {
+	links := []string{
+		"vless://a06fe789-5ab1-480b-8124-ae4599801ff3@example.com:443?encryption=none&flow=xtls-rprx-vision&fp=chrome&pbk=Z84J2IelR9ch3k8VtlVhhs5ycBUlXA7wHBWcBrjqnAw&security=reality&sid=6ba85179e30d4fc2&sni=www.example.com&type=tcp#jp",
+		"vless://a06fe789-5ab1-480b-8124-ae4599801ff3@[2001:db8::1]:8443?encryption=none&host=cdn.example.com&mode=packet-up&path=%2Fx&security=tls&sni=cdn.example.com&type=xhttp#v6",
+		"trojan://password@example.com:443?alpn=h2%2Chttp%2F1.1&security=tls&serviceName=g&type=grpc#tr",
+		"ss://YWVzLTI1Ni1nY206cGFzc3dvcmQ@example.com:8388#ss",
+		"ss://2022-blake3-aes-128-gcm:c2FtcGxla2V5MTIzNDU2Nw==@example.com:8388#ss2022",
+	}
+	for _, link := range links {
+		ob, name, err := ParseShareLink(link)
+		if err != nil {
+			t.Fatal(link, ": ", err)
+		}
+		ob.Tag = name
+		got, err := ShareLink(ob)
+		if err != nil {
+			t.Fatal(link, ": ", err)
+		}
+		if got != link {
+			t.Errorf("got %s, want %s", got, link)
+		}
+	}
+}
+
+func TestShareLinkVMess(t *testing.T) {
+	ob, _, err := ParseShareLink("vmess://eyJ2IjoiMiIsInBzIjoiYyIsImFkZCI6ImV4YW1wbGUuY29tIiwicG9ydCI6IjQ0MyIsImlkIjoiYTA2ZmU3ODktNWFiMS00ODBiLTgxMjQtYWU0NTk5ODAxZmYzIiwiYWlkIjoiMCIsIm5ldCI6IndzIiwicGF0aCI6Ii93cyIsInRscyI6InRscyJ9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ob.Tag = "c"
+	link, err := ShareLink(ob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, name, err := ParseShareLink(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "c" || string(*again.Settings) != string(*ob.Settings) || *again.StreamSetting.Network != "ws" ||
+		again.StreamSetting.WSSettings.Path != "/ws" || again.StreamSetting.Security != "tls" {
+		t.Error("unexpected outbound of ", link)
+	}
+}
+
+func TestParseShadowsocksLink(t *testing.T) {
+	for _, link := range []string{
+		"ss://YWVzLTI1Ni1nY206cGFzc3dvcmQ@example.com:8388#a%20b",
+		// The legacy form, with the server in base64 as well.
+		"ss://YWVzLTI1Ni1nY206cGFzc3dvcmRAZXhhbXBsZS5jb206ODM4OA==#a%20b",
+	} {
+		ob, name, err := ParseShareLink(link)
+		if err != nil {
+			t.Fatal(link, ": ", err)
+		}
+		if name != "a b" || ob.Protocol != "shadowsocks" {
+			t.Error("unexpected outbound of ", link, ": ", name, " ", ob.Protocol)
+		}
+		if servers := ob.ServerAddresses(); len(servers) != 1 || servers[0] != "example.com:8388" {
+			t.Error("unexpected servers of ", link, ": ", servers)
+		}
+	}
+	if _, _, err := ParseShareLink("ss://YWVzLTI1Ni1nY206cGFzc3dvcmQ@example.com:8388?plugin=" + url.QueryEscape("obfs-local;obfs=http") + "#p"); err == nil {
+		t.Error("expected an error for a plugin")
+	}
+}
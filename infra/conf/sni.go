@@ -0,0 +1,85 @@
+//go:build !xray_client && !xray_minimal_router
+
+package conf
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/proxy/sni"
+	"google.golang.org/protobuf/proto"
+)
+
+func init() {
+	common.Must(inboundConfigLoader.cache.RegisterCreator("sni", func() interface{} { return new(SNIProxyConfig) }))
+}
+
+// SNIBackendConfig is a backend of the SNI proxy, serving some server names. Dest is a port, an
+// address or a Unix socket, as the dest of fallbacks.
+type SNIBackendConfig struct {
+	ServerNames StringList      `json:"serverNames"`
+	Dest        json.RawMessage `json:"dest"`
+	Xver        uint64          `json:"xver"`
+}
+
+// SNIProxyConfig configures an inbound that proxies TLS by SNI without terminating it.
+type SNIProxyConfig struct {
+	Backends  []*SNIBackendConfig `json:"backends"`
+	Port      uint16              `json:"port"`
+	UserLevel uint32              `json:"userLevel"`
+}
+
+// Build implements Buildable
+func (c *SNIProxyConfig) Build() (proto.Message, error) {
+	config := &sni.Config{
+		Port:      uint32(c.Port),
+		UserLevel: c.UserLevel,
+	}
+	for _, b := range c.Backends {
+		if len(b.ServerNames) == 0 {
+			return nil, errors.New(`sni settings: no "serverNames" of a backend`)
+		}
+		backend := &sni.Backend{Xver: b.Xver}
+		for _, name := range b.ServerNames {
+			name = strings.ToLower(name)
+			if name != "*" && (name == "" || strings.Contains(strings.TrimPrefix(name, "*."), "*")) {
+				return nil, errors.New(`sni settings: invalid server name "`, name, `"`)
+			}
+			backend.ServerNames = append(backend.ServerNames, name)
+		}
+		if len(b.Dest) > 0 {
+			var port uint16
+			if err := json.Unmarshal(b.Dest, &port); err == nil {
+				backend.Dest = "127.0.0.1:" + strconv.Itoa(int(port))
+			} else if err := json.Unmarshal(b.Dest, &backend.Dest); err != nil {
+				return nil, errors.New(`sni settings: invalid "dest"`).Base(err)
+			}
+		}
+		switch {
+		case backend.Dest == "":
+		case filepath.IsAbs(backend.Dest) || backend.Dest[0] == '@':
+			backend.Type = "unix"
+		default:
+			if _, err := strconv.Atoi(backend.Dest); err == nil {
+				backend.Dest = "127.0.0.1:" + backend.Dest
+			}
+			if _, _, err := net.SplitHostPort(backend.Dest); err != nil {
+				return nil, errors.New(`sni settings: invalid "dest" `, backend.Dest).Base(err)
+			}
+			backend.Type = "tcp"
+		}
+		if backend.Xver > 2 {
+			return nil, errors.New(`sni settings: invalid PROXY protocol version, "xver" only accepts 0, 1, 2`)
+		}
+		if backend.Dest == "" && backend.Xver != 0 {
+			return nil, errors.New(`sni settings: "xver" needs a "dest"`)
+		}
+		config.Backends = append(config.Backends, backend)
+	}
+	return config, nil
+}
@@ -0,0 +1,50 @@
+//go:build !xray_client && !xray_minimal_router
+
+package conf_test
+
+import (
+	"testing"
+
+	. "github.com/xtls/xray-core/infra/conf"
+	"github.com/xtls/xray-core/proxy/sni"
+)
+
+func TestSNIProxyConfig(t *testing.T) {
+	creator := func() Buildable {
+		return new(SNIProxyConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"backends": [
+					{"serverNames": ["Example.com", "*.example.com"], "dest": 8443, "xver": 1},
+					{"serverNames": ["*.example.org"], "dest": "/run/example.sock"},
+					{"serverNames": ["*"]}
+				],
+				"port": 8443,
+				"userLevel": 1
+			}`,
+			Parser: loadJSON(creator),
+			Output: &sni.Config{
+				Backends: []*sni.Backend{
+					{ServerNames: []string{"example.com", "*.example.com"}, Dest: "127.0.0.1:8443", Type: "tcp", Xver: 1},
+					{ServerNames: []string{"*.example.org"}, Dest: "/run/example.sock", Type: "unix"},
+					{ServerNames: []string{"*"}},
+				},
+				Port:      8443,
+				UserLevel: 1,
+			},
+		},
+	})
+
+	for _, input := range []string{
+		`{"backends": [{"serverNames": ["www.*.com"], "dest": 443}]}`,
+		`{"backends": [{"serverNames": ["*"], "xver": 1}]}`,
+		`{"backends": [{"dest": 443}]}`,
+	} {
+		if _, err := loadJSON(creator)(input); err == nil {
+			t.Errorf("expected an error for %s", input)
+		}
+	}
+}
@@ -33,6 +33,7 @@ type SocksServerConfig struct {
 	UDP        bool            `json:"udp"`
 	Host       *Address        `json:"ip"`
 	UserLevel  uint32          `json:"userLevel"`
+	Bind       bool            `json:"bind"`
 }
 
 func (v *SocksServerConfig) Build() (proto.Message, error) {
@@ -55,6 +56,7 @@ func (v *SocksServerConfig) Build() (proto.Message, error) {
 	}
 
 	config.UdpEnabled = v.UDP
+	config.BindEnabled = v.Bind
 	if v.Host != nil {
 		config.Address = v.Host.Build()
 	}
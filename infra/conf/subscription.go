@@ -0,0 +1,419 @@
+package conf
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/storage"
+	"github.com/xtls/xray-core/infra/conf/cfgcommon/duration"
+)
+
+const (
+	defaultSubscriptionTag      = "subscription"
+	defaultSubscriptionInterval = 12 * time.Hour
+	// subscriptionSizeLimit is the largest subscription accepted.
+	subscriptionSizeLimit = 16 << 20
+)
+
+// SubscriptionConfig is a list of share links (vmess://, vless://, trojan:// and ss://, optionally
+// encoded in base64) fetched from a provider. Each link becomes an outbound tagged
+// "<tag>-<name>". The last fetched copy is kept in Cache, or in the state, so that the outbounds
+// are there even when the provider can't be reached.
+type SubscriptionConfig struct {
+	URL      string            `json:"url"`
+	Tag      string            `json:"tag"`
+	Interval duration.Duration `json:"interval"`
+	Cache    string            `json:"cache"`
+}
+
+// TagPrefix returns the prefix of the tags of the outbounds of the subscription.
+func (c *SubscriptionConfig) TagPrefix() string {
+	if c.Tag == "" {
+		return defaultSubscriptionTag
+	}
+	return c.Tag
+}
+
+// RefreshInterval returns how often the subscription is fetched.
+func (c *SubscriptionConfig) RefreshInterval() time.Duration {
+	if c.Interval <= 0 {
+		return defaultSubscriptionInterval
+	}
+	return time.Duration(c.Interval)
+}
+
+// cache returns where the last fetched copy is kept: in Cache if set, or in the state, or else
+// in a file named after the URL in the user cache directory.
+func (c *SubscriptionConfig) cache() storage.Entry {
+	sum := sha256.Sum256([]byte(c.URL))
+	name := hex.EncodeToString(sum[:8])
+	entry := storage.Entry{Path: c.Cache, Bucket: "subscription", Key: name}
+	if entry.Path == "" && storage.Default() == nil {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			dir = os.TempDir()
+		}
+		entry.Path = filepath.Join(dir, "xray", "subscription-"+name+".txt")
+	}
+	return entry
+}
+
+// Fetch downloads the subscription and replaces the cached copy with it. It returns whether
+// the content changed. A response without any valid share link leaves the cache untouched.
+func (c *SubscriptionConfig) Fetch(ctx context.Context, client *http.Client) (bool, error) {
+	if c.URL == "" {
+		return false, errors.New("subscription url is empty")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, errors.New("failed to fetch subscription ", c.URL).Base(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.New("failed to fetch subscription ", c.URL, ": ", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, subscriptionSizeLimit+1))
+	if err != nil {
+		return false, errors.New("failed to read subscription ", c.URL).Base(err)
+	}
+	if len(data) > subscriptionSizeLimit {
+		return false, errors.New("subscription ", c.URL, " is too large")
+	}
+	if len(ParseShareLinks(data, c.TagPrefix())) == 0 {
+		return false, errors.New("subscription ", c.URL, " has no valid share link")
+	}
+
+	cache := c.cache()
+	if cached, err := cache.Load(); err == nil && bytes.Equal(cached, data) {
+		return false, nil
+	}
+	if err := cache.Save(data); err != nil {
+		return false, errors.New("failed to write subscription cache").Base(err)
+	}
+	return true, nil
+}
+
+// Outbounds returns the outbounds of the cached copy of the subscription.
+func (c *SubscriptionConfig) Outbounds() ([]OutboundDetourConfig, error) {
+	data, err := c.cache().Load()
+	if err != nil {
+		return nil, errors.New("subscription ", c.URL, " is not fetched yet").Base(err)
+	}
+	return ParseShareLinks(data, c.TagPrefix()), nil
+}
+
+// ParseShareLinks converts the share links in data, one per line and optionally encoded in
+// base64 as a whole, into outbounds tagged "<tagPrefix>-<name>". Invalid and unsupported links
+// are skipped with a warning.
+func ParseShareLinks(data []byte, tagPrefix string) []OutboundDetourConfig {
+	text := strings.TrimSpace(string(data))
+	if !strings.Contains(text, "://") {
+		if decoded, err := decodeBase64(text); err == nil {
+			text = string(decoded)
+		}
+	}
+
+	var outbounds []OutboundDetourConfig
+	tags := make(map[string]int)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		ob, name, err := ParseShareLink(line)
+		if err != nil {
+			errors.LogWarningInner(context.Background(), err, "skipped share link of subscription ", tagPrefix)
+			continue
+		}
+		if name == "" {
+			name = strconv.Itoa(len(outbounds) + 1)
+		}
+		tag := tagPrefix + "-" + name
+		if n := tags[tag]; n > 0 {
+			tags[tag] = n + 1
+			tag += "-" + strconv.Itoa(n+1)
+		} else {
+			tags[tag] = 1
+		}
+		ob.Tag = tag
+		outbounds = append(outbounds, *ob)
+	}
+	return outbounds
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.Join(strings.Fields(s), "")
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := encoding.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	return nil, errors.New("invalid base64")
+}
+
+// ParseShareLink returns the outbound of a share link (vmess://, vless://, trojan:// or ss://),
+// and the name of the server in it.
+func ParseShareLink(link string) (*OutboundDetourConfig, string, error) {
+	scheme, _, found := strings.Cut(link, "://")
+	if !found {
+		return nil, "", errors.New("not a share link: ", link)
+	}
+	switch strings.ToLower(scheme) {
+	case "vmess":
+		return parseVMessLink(link)
+	case "vless", "trojan":
+		return parseURLLink(link)
+	case "ss":
+		return parseShadowsocksLink(link)
+	default:
+		return nil, "", errors.New("unsupported share link: ", scheme)
+	}
+}
+
+// vmessLink is the JSON in vmess:// links, in the format of v2rayN.
+type vmessLink struct {
+	Version  string          `json:"v,omitempty"`
+	Name     string          `json:"ps"`
+	Address  string          `json:"add"`
+	Port     json.RawMessage `json:"port"`
+	ID       string          `json:"id"`
+	AlterID  json.RawMessage `json:"aid"`
+	Security string          `json:"scy"`
+	Network  string          `json:"net"`
+	Type     string          `json:"type"`
+	Host     string          `json:"host"`
+	Path     string          `json:"path"`
+	TLS      string          `json:"tls"`
+	SNI      string          `json:"sni"`
+	ALPN     string          `json:"alpn"`
+	FP       string          `json:"fp"`
+}
+
+func parseVMessLink(link string) (*OutboundDetourConfig, string, error) {
+	data, err := decodeBase64(link[len("vmess://"):])
+	if err != nil {
+		return nil, "", errors.New("invalid vmess link").Base(err)
+	}
+	v := new(vmessLink)
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, "", errors.New("invalid vmess link").Base(err)
+	}
+	port, err := jsonNumber(v.Port)
+	if err != nil {
+		return nil, "", errors.New("invalid port of vmess link").Base(err)
+	}
+	alterID, _ := jsonNumber(v.AlterID)
+	security := v.Security
+	if security == "" {
+		security = "auto"
+	}
+	settings := map[string]interface{}{
+		"vnext": []interface{}{map[string]interface{}{
+			"address": v.Address,
+			"port":    port,
+			"users": []interface{}{map[string]interface{}{
+				"id":       v.ID,
+				"alterId":  alterID,
+				"security": security,
+			}},
+		}},
+	}
+	params := url.Values{}
+	params.Set("type", v.Network)
+	params.Set("headerType", v.Type)
+	params.Set("host", v.Host)
+	params.Set("path", v.Path)
+	params.Set("serviceName", v.Path)
+	params.Set("security", v.TLS)
+	params.Set("sni", v.SNI)
+	params.Set("alpn", v.ALPN)
+	params.Set("fp", v.FP)
+	ob, err := newShareLinkOutbound("vmess", settings, params)
+	return ob, v.Name, err
+}
+
+// parseURLLink parses vless:// and trojan:// links, in the form of
+// scheme://credential@address:port?params#name.
+func parseURLLink(link string) (*OutboundDetourConfig, string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, "", errors.New("invalid share link").Base(err)
+	}
+	protocol := strings.ToLower(u.Scheme)
+	if u.User == nil || u.User.Username() == "" {
+		return nil, "", errors.New("no credential in ", protocol, " link")
+	}
+	credential := u.User.Username()
+	port, err := strconv.ParseUint(u.Port(), 10, 16)
+	if err != nil {
+		return nil, "", errors.New("invalid port of ", protocol, " link").Base(err)
+	}
+	params := u.Query()
+
+	var settings map[string]interface{}
+	switch protocol {
+	case "vless":
+		encryption := params.Get("encryption")
+		if encryption == "" {
+			encryption = "none"
+		}
+		settings = map[string]interface{}{
+			"vnext": []interface{}{map[string]interface{}{
+				"address": u.Hostname(),
+				"port":    port,
+				"users": []interface{}{map[string]interface{}{
+					"id":         credential,
+					"encryption": encryption,
+					"flow":       params.Get("flow"),
+				}},
+			}},
+		}
+	case "trojan":
+		if params.Get("security") == "" {
+			// Trojan runs over TLS unless told otherwise.
+			params.Set("security", "tls")
+		}
+		settings = map[string]interface{}{
+			"servers": []interface{}{map[string]interface{}{
+				"address":  u.Hostname(),
+				"port":     port,
+				"password": credential,
+			}},
+		}
+	}
+	ob, err := newShareLinkOutbound(protocol, settings, params)
+	return ob, u.Fragment, err
+}
+
+// newShareLinkOutbound builds an outbound from the settings of a protocol and the transport
+// parameters of a share link, as named in the share link standard of Xray.
+func newShareLinkOutbound(protocol string, settings map[string]interface{}, params url.Values) (*OutboundDetourConfig, error) {
+	network := params.Get("type")
+	if network == "" {
+		network = "tcp"
+	}
+	stream := map[string]interface{}{
+		"network": network,
+	}
+	switch network {
+	case "tcp", "raw":
+		if params.Get("headerType") == "http" {
+			request := map[string]interface{}{}
+			if path := params.Get("path"); path != "" {
+				request["path"] = strings.Split(path, ",")
+			}
+			if host := params.Get("host"); host != "" {
+				request["headers"] = map[string]interface{}{"Host": strings.Split(host, ",")}
+			}
+			stream["tcpSettings"] = map[string]interface{}{
+				"header": map[string]interface{}{"type": "http", "request": request},
+			}
+		}
+	case "ws":
+		stream["wsSettings"] = map[string]interface{}{"path": params.Get("path"), "host": params.Get("host")}
+	case "httpupgrade":
+		stream["httpupgradeSettings"] = map[string]interface{}{"path": params.Get("path"), "host": params.Get("host")}
+	case "xhttp", "splithttp":
+		xhttp := map[string]interface{}{"path": params.Get("path"), "host": params.Get("host")}
+		if mode := params.Get("mode"); mode != "" {
+			xhttp["mode"] = mode
+		}
+		stream["xhttpSettings"] = xhttp
+		stream["network"] = "xhttp"
+	case "grpc":
+		stream["grpcSettings"] = map[string]interface{}{
+			"serviceName": params.Get("serviceName"),
+			"authority":   params.Get("authority"),
+			"multiMode":   params.Get("mode") == "multi",
+		}
+	case "kcp", "mkcp":
+		stream["network"] = "kcp"
+		kcp := map[string]interface{}{}
+		if headerType := params.Get("headerType"); headerType != "" {
+			kcp["header"] = map[string]interface{}{"type": headerType}
+		}
+		if seed := params.Get("seed"); seed != "" {
+			kcp["seed"] = seed
+		}
+		stream["kcpSettings"] = kcp
+	default:
+		return nil, errors.New("unsupported network of share link: ", network)
+	}
+
+	switch security := params.Get("security"); security {
+	case "", "none":
+	case "tls":
+		tls := map[string]interface{}{
+			"serverName":  params.Get("sni"),
+			"fingerprint": params.Get("fp"),
+		}
+		if alpn := params.Get("alpn"); alpn != "" {
+			tls["alpn"] = strings.Split(alpn, ",")
+		}
+		if params.Get("allowInsecure") == "1" {
+			tls["allowInsecure"] = true
+		}
+		stream["security"] = "tls"
+		stream["tlsSettings"] = tls
+	case "reality":
+		stream["security"] = "reality"
+		stream["realitySettings"] = map[string]interface{}{
+			"serverName":  params.Get("sni"),
+			"fingerprint": params.Get("fp"),
+			"publicKey":   params.Get("pbk"),
+			"shortId":     params.Get("sid"),
+			"spiderX":     params.Get("spx"),
+		}
+	default:
+		return nil, errors.New("unsupported security of share link: ", security)
+	}
+
+	rawSettings, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	rawStream, err := json.Marshal(stream)
+	if err != nil {
+		return nil, err
+	}
+	ob := &OutboundDetourConfig{
+		Protocol: protocol,
+		Settings: (*json.RawMessage)(&rawSettings),
+	}
+	if err := json.Unmarshal(rawStream, &ob.StreamSetting); err != nil {
+		return nil, errors.New("invalid transport of share link").Base(err)
+	}
+	if _, err := ob.Build(); err != nil {
+		return nil, errors.New("invalid share link").Base(err)
+	}
+	return ob, nil
+}
+
+// jsonNumber reads a number which may be given as a JSON string.
+func jsonNumber(raw json.RawMessage) (uint64, error) {
+	if len(raw) == 0 {
+		return 0, nil
+	}
+	s := strings.Trim(string(raw), `"`)
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
@@ -0,0 +1,173 @@
+package conf
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/xtls/xray-core/app/subscription"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"golang.org/x/crypto/curve25519"
+)
+
+// SubscriptionServerConfig is the config of the server handing out the share links of the users
+// of inbounds.
+type SubscriptionServerConfig struct {
+	Listen   string                       `json:"listen"`
+	Secret   string                       `json:"secret"`
+	URL      string                       `json:"url"`
+	Inbounds []*SubscriptionInboundConfig `json:"inbounds"`
+}
+
+// SubscriptionInboundConfig is an inbound whose users get share links, as clients reach it.
+// Clients reach it at address and port, the first port of the inbound by default, with its
+// streamSettings turned into the ones of clients: the public key of REALITY is derived from the
+// private one, for example. StreamSettings replaces them, for inbounds behind a CDN or a
+// reverse proxy.
+type SubscriptionInboundConfig struct {
+	Tag            string        `json:"tag"`
+	Name           string        `json:"name"`
+	Address        string        `json:"address"`
+	Port           uint16        `json:"port"`
+	StreamSettings *StreamConfig `json:"streamSettings"`
+}
+
+// Build builds the config of the subscription server, with the inbounds of the config.
+func (c *SubscriptionServerConfig) Build(inbounds []InboundDetourConfig) (*subscription.Config, error) {
+	if c.Listen == "" {
+		return nil, errors.New("subscription server without listen address")
+	}
+	if c.Secret == "" {
+		return nil, errors.New("subscription server without secret")
+	}
+	config := &subscription.Config{
+		Listen: c.Listen,
+		Secret: c.Secret,
+		Url:    c.URL,
+	}
+	for _, ic := range c.Inbounds {
+		var found *InboundDetourConfig
+		for i := range inbounds {
+			if inbounds[i].Tag == ic.Tag {
+				found = &inbounds[i]
+				break
+			}
+		}
+		if found == nil {
+			return nil, errors.New("subscription of unknown inbound ", ic.Tag)
+		}
+		inbound, err := ic.build(found)
+		if err != nil {
+			return nil, errors.New("failed to build subscription of inbound ", ic.Tag).Base(err)
+		}
+		config.Inbounds = append(config.Inbounds, inbound)
+	}
+	return config, nil
+}
+
+func (c *SubscriptionInboundConfig) build(ib *InboundDetourConfig) (*subscription.Inbound, error) {
+	if c.Address == "" {
+		return nil, errors.New("no address")
+	}
+	inbound := &subscription.Inbound{
+		Tag:      c.Tag,
+		Name:     c.Name,
+		Protocol: strings.ToLower(ib.Protocol),
+		Address:  c.Address,
+		Port:     uint32(c.Port),
+	}
+	if inbound.Port == 0 {
+		if ib.PortList == nil || len(ib.PortList.Range) == 0 {
+			return nil, errors.New("no port")
+		}
+		inbound.Port = ib.PortList.Range[0].From
+	}
+	switch inbound.Protocol {
+	case "vless", "vmess", "trojan":
+	case "shadowsocks":
+		if ib.Settings != nil {
+			settings := new(ShadowsocksServerConfig)
+			if err := json.Unmarshal(*ib.Settings, settings); err != nil {
+				return nil, errors.New("invalid settings").Base(err)
+			}
+			if strings.HasPrefix(settings.Cipher, "2022-") {
+				inbound.Method = settings.Cipher
+				inbound.ServerKey = settings.Password
+			}
+		}
+	default:
+		return nil, errors.New("no share links for protocol ", ib.Protocol)
+	}
+
+	stream := c.StreamSettings
+	if stream == nil {
+		var err error
+		if stream, err = clientStream(ib.StreamSetting, c.Address); err != nil {
+			return nil, err
+		}
+	}
+	params, err := shareLinkParams(stream)
+	if err != nil {
+		return nil, err
+	}
+	inbound.Query = params.Encode()
+	return inbound, nil
+}
+
+// clientStream returns the streamSettings of clients of an inbound with stream, reaching it at
+// address.
+func clientStream(stream *StreamConfig, address string) (*StreamConfig, error) {
+	if stream == nil {
+		return nil, nil
+	}
+	client := *stream
+	switch strings.ToLower(stream.Security) {
+	case "tls":
+		tls := &TLSConfig{}
+		if server := stream.TLSSettings; server != nil {
+			tls.ServerName = server.ServerName
+			tls.ALPN = server.ALPN
+			tls.Fingerprint = server.Fingerprint
+		}
+		if tls.ServerName == "" && net.ParseAddress(address).Family().IsDomain() {
+			tls.ServerName = address
+		}
+		client.TLSSettings = tls
+	case "reality":
+		server := stream.REALITYSettings
+		if server == nil {
+			return nil, errors.New("no REALITY settings")
+		}
+		privateKey, shortIds := server.PrivateKey, server.ShortIds
+		if privateKey == "" && len(server.Keys) > 0 {
+			// The last key is taken to be the newest one.
+			key := server.Keys[len(server.Keys)-1]
+			privateKey, shortIds = key.PrivateKey, key.ShortIds
+		}
+		key, err := base64.RawURLEncoding.DecodeString(privateKey)
+		if err != nil || len(key) != curve25519.ScalarSize {
+			return nil, errors.New(`invalid REALITY "privateKey"`)
+		}
+		publicKey, err := curve25519.X25519(key, curve25519.Basepoint)
+		if err != nil {
+			return nil, errors.New("invalid REALITY private key").Base(err)
+		}
+		reality := &REALITYConfig{
+			Fingerprint: server.Fingerprint,
+			PublicKey:   base64.RawURLEncoding.EncodeToString(publicKey),
+			SpiderX:     server.SpiderX,
+		}
+		if reality.Fingerprint == "" {
+			reality.Fingerprint = "chrome"
+		}
+		if len(server.ServerNames) > 0 {
+			reality.ServerName = server.ServerNames[0]
+		}
+		if len(shortIds) > 0 {
+			reality.ShortId = shortIds[0]
+		}
+		client.REALITYSettings = reality
+	}
+	return &client, nil
+}
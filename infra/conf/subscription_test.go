@@ -0,0 +1,35 @@
+//go:build !xray_minimal_router
+
+package conf_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	. "github.com/xtls/xray-core/infra/conf"
+)
+
+func TestParseShareLinks(t *testing.T) {
+	vmess := base64.StdEncoding.EncodeToString([]byte(`{"v":"2","ps":"c","add":"example.com","port":"443","id":"a06fe789-5ab1-480b-8124-ae4599801ff3","aid":"0","net":"ws","path":"/ws","tls":"tls"}`))
+	links := strings.Join([]string{
+		"vless://a06fe789-5ab1-480b-8124-ae4599801ff3@example.com:443?security=tls&sni=example.com&type=grpc&serviceName=g#a",
+		"trojan://password@example.com:443#b",
+		"vmess://" + vmess,
+		"trojan://password@example.com:443#b",
+		"ss://unsupported",
+		"not a link",
+	}, "\n")
+
+	for _, data := range []string{links, base64.StdEncoding.EncodeToString([]byte(links))} {
+		outbounds := ParseShareLinks([]byte(data), "sub")
+		var got []string
+		for _, ob := range outbounds {
+			got = append(got, ob.Tag+":"+ob.Protocol)
+		}
+		want := "sub-a:vless sub-b:trojan sub-c:vmess sub-b-2:trojan"
+		if strings.Join(got, " ") != want {
+			t.Errorf("got %v, want %s", got, want)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
@@ -17,9 +18,7 @@ import (
 	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/httpupgrade"
-	"github.com/xtls/xray-core/transport/internet/kcp"
 	"github.com/xtls/xray-core/transport/internet/reality"
-	"github.com/xtls/xray-core/transport/internet/splithttp"
 	"github.com/xtls/xray-core/transport/internet/tcp"
 	"github.com/xtls/xray-core/transport/internet/tls"
 	"github.com/xtls/xray-core/transport/internet/websocket"
@@ -53,68 +52,25 @@ type KCPConfig struct {
 	WriteBufferSize *uint32         `json:"writeBufferSize"`
 	HeaderConfig    json.RawMessage `json:"header"`
 	Seed            *string         `json:"seed"`
-}
-
-// Build implements Buildable.
-func (c *KCPConfig) Build() (proto.Message, error) {
-	config := new(kcp.Config)
-
-	if c.Mtu != nil {
-		mtu := *c.Mtu
-		if mtu < 576 || mtu > 1460 {
-			return nil, errors.New("invalid mKCP MTU size: ", mtu).AtError()
-		}
-		config.Mtu = &kcp.MTU{Value: mtu}
-	}
-	if c.Tti != nil {
-		tti := *c.Tti
-		if tti < 10 || tti > 100 {
-			return nil, errors.New("invalid mKCP TTI: ", tti).AtError()
-		}
-		config.Tti = &kcp.TTI{Value: tti}
-	}
-	if c.UpCap != nil {
-		config.UplinkCapacity = &kcp.UplinkCapacity{Value: *c.UpCap}
-	}
-	if c.DownCap != nil {
-		config.DownlinkCapacity = &kcp.DownlinkCapacity{Value: *c.DownCap}
-	}
-	if c.Congestion != nil {
-		config.Congestion = *c.Congestion
-	}
-	if c.ReadBufferSize != nil {
-		size := *c.ReadBufferSize
-		if size > 0 {
-			config.ReadBuffer = &kcp.ReadBuffer{Size: size * 1024 * 1024}
-		} else {
-			config.ReadBuffer = &kcp.ReadBuffer{Size: 512 * 1024}
-		}
-	}
-	if c.WriteBufferSize != nil {
-		size := *c.WriteBufferSize
-		if size > 0 {
-			config.WriteBuffer = &kcp.WriteBuffer{Size: size * 1024 * 1024}
-		} else {
-			config.WriteBuffer = &kcp.WriteBuffer{Size: 512 * 1024}
-		}
-	}
-	if len(c.HeaderConfig) > 0 {
-		headerConfig, _, err := kcpHeaderLoader.Load(c.HeaderConfig)
-		if err != nil {
-			return nil, errors.New("invalid mKCP header config.").Base(err).AtError()
-		}
-		ts, err := headerConfig.(Buildable).Build()
-		if err != nil {
-			return nil, errors.New("invalid mKCP header config").Base(err).AtError()
-		}
-		config.HeaderConfig = serial.ToTypedMessage(ts)
-	}
-
-	if c.Seed != nil {
-		config.Seed = &kcp.EncryptionSeed{Seed: *c.Seed}
-	}
-
-	return config, nil
+	// HopPorts, like "20000-30000", are the ports of the server a client hops among every
+	// hopInterval seconds. A server with hopPorts accepts connections hopping among the ports of
+	// its inbound.
+	HopPorts    *PortList `json:"hopPorts"`
+	HopInterval uint32    `json:"hopInterval"`
+	// KeepaliveInterval is the seconds between the pings of an idle connection. With rebinding, a
+	// client moves to a new socket when its packets go unanswered, and a server accepts connections
+	// moving to another port of their client, so that they survive a NAT rebinding.
+	KeepaliveInterval uint32 `json:"keepaliveInterval"`
+	Rebinding         bool   `json:"rebinding"`
+	// AutoMtu searches for the largest packets getting through the path, with mtu as the upper
+	// bound.
+	AutoMtu bool `json:"autoMtu"`
+	// Carrier, experimental, carries the packets in pings with "icmp", or with "dns" in TXT queries
+	// of carrierDomain sent to the address of the server, usually a resolver, at carrierRate
+	// requests per second at most.
+	Carrier       string `json:"carrier"`
+	CarrierDomain string `json:"carrierDomain"`
+	CarrierRate   uint32 `json:"carrierRate"`
 }
 
 type TCPConfig struct {
@@ -148,6 +104,8 @@ type WebSocketConfig struct {
 	Headers             map[string]string `json:"headers"`
 	AcceptProxyProtocol bool              `json:"acceptProxyProtocol"`
 	HeartbeatPeriod     uint32            `json:"heartbeatPeriod"`
+	Compression         bool              `json:"compression"`
+	AutoFrameSize       bool              `json:"autoFrameSize"`
 }
 
 // Build implements Buildable.
@@ -180,6 +138,8 @@ func (c *WebSocketConfig) Build() (proto.Message, error) {
 		AcceptProxyProtocol: c.AcceptProxyProtocol,
 		Ed:                  ed,
 		HeartbeatPeriod:     c.HeartbeatPeriod,
+		Compression:         c.Compression,
+		AutoFrameSize:       c.AutoFrameSize,
 	}
 	return config, nil
 }
@@ -234,7 +194,14 @@ type SplitHTTPConfig struct {
 	ScStreamUpServerSecs Int32Range        `json:"scStreamUpServerSecs"`
 	Xmux                 XmuxConfig        `json:"xmux"`
 	DownloadSettings     *StreamConfig     `json:"downloadSettings"`
-	Extra                json.RawMessage   `json:"extra"`
+	Camouflage           string            `json:"camouflage"`
+	AffinityHeader       string            `json:"affinityHeader"`
+	AffinityCookie       string            `json:"affinityCookie"`
+	PinAddress           bool              `json:"pinAddress"`
+	// ScAutoMaxEachPostBytes searches for the largest posts getting through the path, with
+	// scMaxEachPostBytes as the upper bound.
+	ScAutoMaxEachPostBytes bool            `json:"scAutoMaxEachPostBytes"`
+	Extra                  json.RawMessage `json:"extra"`
 }
 
 type XmuxConfig struct {
@@ -246,90 +213,15 @@ type XmuxConfig struct {
 	HKeepAlivePeriod int64      `json:"hKeepAlivePeriod"`
 }
 
-func newRangeConfig(input Int32Range) *splithttp.RangeConfig {
-	return &splithttp.RangeConfig{
-		From: input.From,
-		To:   input.To,
-	}
-}
-
-// Build implements Buildable.
-func (c *SplitHTTPConfig) Build() (proto.Message, error) {
-	if c.Extra != nil {
-		var extra SplitHTTPConfig
-		if err := json.Unmarshal(c.Extra, &extra); err != nil {
-			return nil, errors.New(`Failed to unmarshal "extra".`).Base(err)
-		}
-		extra.Host = c.Host
-		extra.Path = c.Path
-		extra.Mode = c.Mode
-		c = &extra
-	}
-
-	switch c.Mode {
-	case "":
-		c.Mode = "auto"
-	case "auto", "packet-up", "stream-up", "stream-one":
-	default:
-		return nil, errors.New("unsupported mode: " + c.Mode)
-	}
-
-	// Priority (client): host > serverName > address
-	for k := range c.Headers {
-		if strings.ToLower(k) == "host" {
-			return nil, errors.New(`"headers" can't contain "host"`)
-		}
-	}
-
-	if c.XPaddingBytes != (Int32Range{}) && (c.XPaddingBytes.From <= 0 || c.XPaddingBytes.To <= 0) {
-		return nil, errors.New("xPaddingBytes cannot be disabled")
-	}
-
-	if c.Xmux.MaxConnections.To > 0 && c.Xmux.MaxConcurrency.To > 0 {
-		return nil, errors.New("maxConnections cannot be specified together with maxConcurrency")
-	}
-	if c.Xmux == (XmuxConfig{}) {
-		c.Xmux.MaxConcurrency.From = 16
-		c.Xmux.MaxConcurrency.To = 32
-		c.Xmux.HMaxRequestTimes.From = 600
-		c.Xmux.HMaxRequestTimes.To = 900
-		c.Xmux.HMaxReusableSecs.From = 1800
-		c.Xmux.HMaxReusableSecs.To = 3000
-	}
-
-	config := &splithttp.Config{
-		Host:                 c.Host,
-		Path:                 c.Path,
-		Mode:                 c.Mode,
-		Headers:              c.Headers,
-		XPaddingBytes:        newRangeConfig(c.XPaddingBytes),
-		NoGRPCHeader:         c.NoGRPCHeader,
-		NoSSEHeader:          c.NoSSEHeader,
-		ScMaxEachPostBytes:   newRangeConfig(c.ScMaxEachPostBytes),
-		ScMinPostsIntervalMs: newRangeConfig(c.ScMinPostsIntervalMs),
-		ScMaxBufferedPosts:   c.ScMaxBufferedPosts,
-		ScStreamUpServerSecs: newRangeConfig(c.ScStreamUpServerSecs),
-		Xmux: &splithttp.XmuxConfig{
-			MaxConcurrency:   newRangeConfig(c.Xmux.MaxConcurrency),
-			MaxConnections:   newRangeConfig(c.Xmux.MaxConnections),
-			CMaxReuseTimes:   newRangeConfig(c.Xmux.CMaxReuseTimes),
-			HMaxRequestTimes: newRangeConfig(c.Xmux.HMaxRequestTimes),
-			HMaxReusableSecs: newRangeConfig(c.Xmux.HMaxReusableSecs),
-			HKeepAlivePeriod: c.Xmux.HKeepAlivePeriod,
-		},
-	}
-
-	if c.DownloadSettings != nil {
-		if c.Mode == "stream-one" {
-			return nil, errors.New(`Can not use "downloadSettings" in "stream-one" mode.`)
-		}
-		var err error
-		if config.DownloadSettings, err = c.DownloadSettings.Build(); err != nil {
-			return nil, errors.New(`Failed to build "downloadSettings".`).Base(err)
-		}
-	}
-
-	return config, nil
+type GRPCConfig struct {
+	Authority           string `json:"authority"`
+	ServiceName         string `json:"serviceName"`
+	MultiMode           bool   `json:"multiMode"`
+	IdleTimeout         int32  `json:"idle_timeout"`
+	HealthCheckTimeout  int32  `json:"health_check_timeout"`
+	PermitWithoutStream bool   `json:"permit_without_stream"`
+	InitialWindowsSize  int32  `json:"initial_windows_size"`
+	UserAgent           string `json:"user_agent"`
 }
 
 func readFileOrString(f string, s []string) ([]byte, error) {
@@ -359,7 +251,7 @@ func (c *TLSCertConfig) Build() (*tls.Certificate, error) {
 
 	cert, err := readFileOrString(c.CertFile, c.CertStr)
 	if err != nil {
-		return nil, errors.New("failed to parse certificate").Base(err)
+		return nil, errors.New("failed to parse certificate").Base(errors.Diagnose(errors.CodeTLSCertInvalid, err))
 	}
 	certificate.Certificate = cert
 	certificate.CertificatePath = c.CertFile
@@ -367,7 +259,7 @@ func (c *TLSCertConfig) Build() (*tls.Certificate, error) {
 	if len(c.KeyFile) > 0 || len(c.KeyStr) > 0 {
 		key, err := readFileOrString(c.KeyFile, c.KeyStr)
 		if err != nil {
-			return nil, errors.New("failed to parse key").Base(err)
+			return nil, errors.New("failed to parse key").Base(errors.Diagnose(errors.CodeTLSCertInvalid, err))
 		}
 		certificate.Key = key
 		certificate.KeyPath = c.KeyFile
@@ -440,13 +332,42 @@ func (c *TLSConfig) Build() (proto.Message, error) {
 			}
 		}
 	}
+	for _, p := range config.NextProtocol {
+		if len(p) == 0 || len(p) > 255 {
+			return nil, errors.New(`invalid protocol in "alpn": `, p)
+		}
+	}
 	if c.CurvePreferences != nil && len(*c.CurvePreferences) > 0 {
+		for _, name := range *c.CurvePreferences {
+			if !tls.IsCurveName(name) {
+				return nil, errors.New(`unknown curve in "curvePreferences": `, name)
+			}
+		}
 		config.CurvePreferences = []string(*c.CurvePreferences)
 	}
 	config.EnableSessionResumption = c.EnableSessionResumption
 	config.DisableSystemRoot = c.DisableSystemRoot
+	minVersion, err := tls.ParseVersion(c.MinVersion)
+	if err != nil {
+		return nil, errors.New(`invalid "minVersion"`).Base(err)
+	}
+	maxVersion, err := tls.ParseVersion(c.MaxVersion)
+	if err != nil {
+		return nil, errors.New(`invalid "maxVersion"`).Base(err)
+	}
+	if minVersion != 0 && maxVersion != 0 && minVersion > maxVersion {
+		return nil, errors.New(`"minVersion" `, c.MinVersion, ` above "maxVersion" `, c.MaxVersion)
+	}
 	config.MinVersion = c.MinVersion
 	config.MaxVersion = c.MaxVersion
+	if c.CipherSuites != "" {
+		if _, err := tls.ParseCipherSuites(c.CipherSuites); err != nil {
+			return nil, errors.New(`invalid "cipherSuites"`).Base(err)
+		}
+		if c.MinVersion == "1.3" {
+			return nil, errors.New(`"cipherSuites" don't apply to TLS 1.3, whose suites can't be chosen`)
+		}
+	}
 	config.CipherSuites = c.CipherSuites
 	config.Fingerprint = strings.ToLower(c.Fingerprint)
 	if config.Fingerprint != "unsafe" && tls.GetFingerprint(config.Fingerprint) == nil {
@@ -499,6 +420,7 @@ type REALITYConfig struct {
 	MaxClientVer string          `json:"maxClientVer"`
 	MaxTimeDiff  uint64          `json:"maxTimeDiff"`
 	ShortIds     []string        `json:"shortIds"`
+	Keys         []*REALITYKey   `json:"keys"`
 
 	Fingerprint string `json:"fingerprint"`
 	ServerName  string `json:"serverName"`
@@ -507,6 +429,57 @@ type REALITYConfig struct {
 	SpiderX     string `json:"spiderX"`
 }
 
+// REALITYKey is a private key a REALITY server accepts besides "privateKey", with its short IDs,
+// from "notBefore" until "notAfter" in RFC 3339, for rotating keys without breaking the clients
+// not updated yet.
+type REALITYKey struct {
+	PrivateKey string   `json:"privateKey"`
+	ShortIds   []string `json:"shortIds"`
+	NotBefore  string   `json:"notBefore"`
+	NotAfter   string   `json:"notAfter"`
+}
+
+// Build builds the key, which accepts the short IDs of the server if it has none.
+func (c *REALITYKey) Build(i int) (*reality.Key, error) {
+	key := new(reality.Key)
+	var err error
+	if key.PrivateKey, err = base64.RawURLEncoding.DecodeString(c.PrivateKey); err != nil || len(key.PrivateKey) != 32 {
+		return nil, errors.New(`invalid "keys[`, i, `].privateKey": `, c.PrivateKey)
+	}
+	if key.ShortIds, err = parseShortIds(c.ShortIds); err != nil {
+		return nil, errors.New(`invalid "keys[`, i, `].shortIds"`).Base(err)
+	}
+	if c.NotBefore != "" {
+		t, err := time.Parse(time.RFC3339, c.NotBefore)
+		if err != nil {
+			return nil, errors.New(`invalid "keys[`, i, `].notBefore": `, c.NotBefore).Base(err)
+		}
+		key.NotBefore = t.Unix()
+	}
+	if c.NotAfter != "" {
+		t, err := time.Parse(time.RFC3339, c.NotAfter)
+		if err != nil {
+			return nil, errors.New(`invalid "keys[`, i, `].notAfter": `, c.NotAfter).Base(err)
+		}
+		key.NotAfter = t.Unix()
+	}
+	if key.NotBefore != 0 && key.NotAfter != 0 && key.NotAfter <= key.NotBefore {
+		return nil, errors.New(`"keys[`, i, `].notAfter" is not after "notBefore"`)
+	}
+	return key, nil
+}
+
+func parseShortIds(shortIds []string) ([][]byte, error) {
+	ids := make([][]byte, len(shortIds))
+	for i, s := range shortIds {
+		ids[i] = make([]byte, 8)
+		if _, err := hex.Decode(ids[i], []byte(s)); err != nil {
+			return nil, errors.New(`invalid "shortIds[`, i, `]": `, s)
+		}
+	}
+	return ids, nil
+}
+
 func (c *REALITYConfig) Build() (proto.Message, error) {
 	config := new(reality.Config)
 	config.MasterKeyLog = c.MasterKeyLog
@@ -550,11 +523,13 @@ func (c *REALITYConfig) Build() (proto.Message, error) {
 		if len(c.ServerNames) == 0 {
 			return nil, errors.New(`empty "serverNames"`)
 		}
-		if c.PrivateKey == "" {
+		if c.PrivateKey == "" && len(c.Keys) == 0 {
 			return nil, errors.New(`empty "privateKey"`)
 		}
-		if config.PrivateKey, err = base64.RawURLEncoding.DecodeString(c.PrivateKey); err != nil || len(config.PrivateKey) != 32 {
-			return nil, errors.New(`invalid "privateKey": `, c.PrivateKey)
+		if c.PrivateKey != "" {
+			if config.PrivateKey, err = base64.RawURLEncoding.DecodeString(c.PrivateKey); err != nil || len(config.PrivateKey) != 32 {
+				return nil, errors.New(`invalid "privateKey": `, c.PrivateKey)
+			}
 		}
 		if c.MinClientVer != "" {
 			config.MinClientVer = make([]byte, 3)
@@ -584,15 +559,21 @@ func (c *REALITYConfig) Build() (proto.Message, error) {
 				}
 			}
 		}
-		if len(c.ShortIds) == 0 {
-			return nil, errors.New(`empty "shortIds"`)
+		if config.ShortIds, err = parseShortIds(c.ShortIds); err != nil {
+			return nil, err
 		}
-		config.ShortIds = make([][]byte, len(c.ShortIds))
-		for i, s := range c.ShortIds {
-			config.ShortIds[i] = make([]byte, 8)
-			if _, err = hex.Decode(config.ShortIds[i], []byte(s)); err != nil {
-				return nil, errors.New(`invalid "shortIds[`, i, `]": `, s)
+		for i, k := range c.Keys {
+			key, err := k.Build(i)
+			if err != nil {
+				return nil, err
 			}
+			if len(key.ShortIds) == 0 && len(config.ShortIds) == 0 {
+				return nil, errors.New(`empty "shortIds" of "keys[`, i, `]"`)
+			}
+			config.Keys = append(config.Keys, key)
+		}
+		if len(config.PrivateKey) > 0 && len(config.ShortIds) == 0 {
+			return nil, errors.New(`empty "shortIds"`)
 		}
 		config.Dest = s
 		config.Type = c.Type
@@ -619,6 +600,9 @@ func (c *REALITYConfig) Build() (proto.Message, error) {
 		if len(c.ShortIds) != 0 {
 			return nil, errors.New(`non-empty "shortIds", please use "shortId" instead`)
 		}
+		if len(c.Keys) != 0 {
+			return nil, errors.New(`non-empty "keys", please use "publicKey" and "shortId" instead`)
+		}
 		config.ShortId = make([]byte, 8)
 		if _, err = hex.Decode(config.ShortId, []byte(c.ShortId)); err != nil {
 			return nil, errors.New(`invalid "shortId": `, c.ShortId)
@@ -705,12 +689,20 @@ type SocketConfig struct {
 	TCPCongestion        string                 `json:"tcpCongestion"`
 	TCPWindowClamp       int32                  `json:"tcpWindowClamp"`
 	TCPMaxSeg            int32                  `json:"tcpMaxSeg"`
+	TCPMss               int32                  `json:"tcpMss"`
+	MTU                  int32                  `json:"mtu"`
 	Penetrate            bool                   `json:"penetrate"`
 	TCPUserTimeout       int32                  `json:"tcpUserTimeout"`
 	V6only               bool                   `json:"v6only"`
 	Interface            string                 `json:"interface"`
 	TcpMptcp             bool                   `json:"tcpMptcp"`
 	CustomSockopt        []*CustomSockoptConfig `json:"customSockopt"`
+	LocalPortRange       *PortRange             `json:"localPortRange"`
+	HandshakeTimeout     uint32                 `json:"handshakeTimeout"`
+	MaxHandshakes        uint32                 `json:"maxHandshakes"`
+	TrustedProxies       []string               `json:"trustedProxies"`
+	PeerUids             []uint32               `json:"peerUids"`
+	FileMode             string                 `json:"fileMode"`
 }
 
 // Build implements Buildable.
@@ -780,6 +772,40 @@ func (c *SocketConfig) Build() (*internet.SocketConfig, error) {
 		customSockopts = append(customSockopts, customSockopt)
 	}
 
+	maxSeg := c.TCPMaxSeg
+	if c.TCPMss != 0 {
+		if maxSeg != 0 && maxSeg != c.TCPMss {
+			return nil, errors.New("tcpMss: conflicts with tcpMaxSeg")
+		}
+		maxSeg = c.TCPMss
+	}
+	if maxSeg < 0 || maxSeg > 65535 {
+		return nil, errors.New("tcpMss: invalid value ", maxSeg)
+	}
+	if c.MTU != 0 && (c.MTU < 576 || c.MTU > 65535) {
+		return nil, errors.New("mtu: must be between 576 and 65535, got ", c.MTU)
+	}
+
+	var localPortRange *net.PortRange
+	if c.LocalPortRange != nil {
+		if c.LocalPortRange.From == 0 {
+			return nil, errors.New("localPortRange: port 0 is not allowed")
+		}
+		localPortRange = c.LocalPortRange.Build()
+	}
+
+	if _, err := internet.NewProxyTrust(&internet.SocketConfig{TrustedProxies: c.TrustedProxies}); err != nil {
+		return nil, errors.New("trustedProxies: invalid").Base(err)
+	}
+
+	var fileMode uint64
+	if c.FileMode != "" {
+		var err error
+		if fileMode, err = strconv.ParseUint(c.FileMode, 8, 32); err != nil || fileMode == 0 || fileMode > 0o777 {
+			return nil, errors.New("fileMode: invalid permission ", c.FileMode)
+		}
+	}
+
 	return &internet.SocketConfig{
 		Mark:                 c.Mark,
 		Tfo:                  tfo,
@@ -791,13 +817,20 @@ func (c *SocketConfig) Build() (*internet.SocketConfig, error) {
 		TcpKeepAliveIdle:     c.TCPKeepAliveIdle,
 		TcpCongestion:        c.TCPCongestion,
 		TcpWindowClamp:       c.TCPWindowClamp,
-		TcpMaxSeg:            c.TCPMaxSeg,
+		TcpMaxSeg:            maxSeg,
+		Mtu:                  c.MTU,
 		Penetrate:            c.Penetrate,
 		TcpUserTimeout:       c.TCPUserTimeout,
 		V6Only:               c.V6only,
 		Interface:            c.Interface,
 		TcpMptcp:             c.TcpMptcp,
 		CustomSockopt:        customSockopts,
+		LocalPortRange:       localPortRange,
+		HandshakeTimeout:     c.HandshakeTimeout,
+		MaxHandshakes:        c.MaxHandshakes,
+		TrustedProxies:       c.TrustedProxies,
+		PeerUids:             c.PeerUids,
+		FileMode:             uint32(fileMode),
 	}, nil
 }
 
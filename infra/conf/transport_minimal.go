@@ -0,0 +1,25 @@
+//go:build xray_minimal_router
+
+package conf
+
+import (
+	"github.com/xtls/xray-core/common/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+// The minimal-router profile leaves mKCP, XHTTP and gRPC out, failing the configs using them.
+
+// Build implements Buildable.
+func (c *KCPConfig) Build() (proto.Message, error) {
+	return nil, errors.New("mKCP is not compiled into this build").AtError()
+}
+
+// Build implements Buildable.
+func (c *SplitHTTPConfig) Build() (proto.Message, error) {
+	return nil, errors.New("XHTTP is not compiled into this build").AtError()
+}
+
+// Build implements Buildable.
+func (g *GRPCConfig) Build() (proto.Message, error) {
+	return nil, errors.New("gRPC is not compiled into this build").AtError()
+}
@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
 	. "github.com/xtls/xray-core/infra/conf"
 	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/reality"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -148,4 +151,104 @@ func TestSocketConfig(t *testing.T) {
 	if expectedOutput.ParseTFOValue() != -1 {
 		t.Fatalf("unexpected parsed TFO value, which should be -1")
 	}
+
+	// test "tcpMss" as an alias of "tcpMaxSeg", along with "mtu"
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"tcpMss": 1380,
+				"mtu": 1420
+			}`,
+			Parser: createParser(),
+			Output: &internet.SocketConfig{
+				TcpMaxSeg: 1380,
+				Mtu:       1420,
+			},
+		},
+	})
+	if _, err := createParser()(`{"tcpMss": 1380, "tcpMaxSeg": 1400}`); err == nil {
+		t.Error("expected error for conflicting tcpMss and tcpMaxSeg")
+	}
+	if _, err := createParser()(`{"mtu": 100}`); err == nil {
+		t.Error("expected error for too small mtu")
+	}
+
+	// test "localPortRange"
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"localPortRange": "30000-31000"
+			}`,
+			Parser: createParser(),
+			Output: &internet.SocketConfig{
+				LocalPortRange: &net.PortRange{From: 30000, To: 31000},
+			},
+		},
+	})
+	if _, err := createParser()(`{"localPortRange": "0-100"}`); err == nil {
+		t.Error("expected error for local port 0")
+	}
+}
+
+func TestTLSConfigValidation(t *testing.T) {
+	build := func(s string) error {
+		config := new(TLSConfig)
+		if err := json.Unmarshal([]byte(s), config); err != nil {
+			return err
+		}
+		_, err := config.Build()
+		return err
+	}
+	for _, s := range []string{
+		`{"minVersion": "1.2", "maxVersion": "1.3", "cipherSuites": "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256:TLS_RSA_WITH_RC4_128_SHA"}`,
+		`{"curvePreferences": ["X25519", "CurveP256"], "alpn": ["h2", "http/1.1"]}`,
+	} {
+		if err := build(s); err != nil {
+			t.Error("unexpected error for ", s, ": ", err)
+		}
+	}
+	for _, s := range []string{
+		`{"minVersion": "1.4"}`,
+		`{"minVersion": "1.3", "maxVersion": "1.2"}`,
+		`{"cipherSuites": "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256:TLS_UNKNOWN"}`,
+		`{"minVersion": "1.3", "cipherSuites": "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}`,
+		`{"curvePreferences": ["X448"]}`,
+		`{"alpn": ["h2", ""]}`,
+	} {
+		if err := build(s); err == nil {
+			t.Error("expected an error for ", s)
+		}
+	}
+}
+
+func TestREALITYConfigKeys(t *testing.T) {
+	build := func(s string) (*reality.Config, error) {
+		config := new(REALITYConfig)
+		if err := json.Unmarshal([]byte(s), config); err != nil {
+			return nil, err
+		}
+		c, err := config.Build()
+		if err != nil {
+			return nil, err
+		}
+		return c.(*reality.Config), nil
+	}
+	c, err := build(`{"target": 443, "serverNames": ["example.com"], "shortIds": ["0102030405060708"], "keys": [
+		{"privateKey": "SGfWgt-vTfpcYJvcAsr9TOEQM-ayJ-CxTAGwB7ubvWs", "notAfter": "2026-01-02T00:00:00Z"},
+		{"privateKey": "uMwG6cZzQsAfOuNszIEKzLYfQNwQPRDHn7elNhpx7X8", "shortIds": ["a1"], "notBefore": "2026-01-01T00:00:00Z"}]}`)
+	common.Must(err)
+	if len(c.PrivateKey) != 0 || len(c.Keys) != 2 || c.Keys[0].NotAfter != 1767312000 || c.Keys[1].NotBefore != 1767225600 ||
+		len(c.Keys[0].ShortIds) != 0 || len(c.Keys[1].ShortIds) != 1 {
+		t.Error("keys: ", c.Keys)
+	}
+	for _, s := range []string{
+		`{"target": 443, "serverNames": ["example.com"], "keys": [{"privateKey": "SGfWgt-vTfpcYJvcAsr9TOEQM-ayJ-CxTAGwB7ubvWs"}]}`,
+		`{"target": 443, "serverNames": ["example.com"], "shortIds": [""], "keys": [{"privateKey": "SGfWgt"}]}`,
+		`{"target": 443, "serverNames": ["example.com"], "shortIds": [""], "keys": [{"privateKey": "SGfWgt-vTfpcYJvcAsr9TOEQM-ayJ-CxTAGwB7ubvWs", "notBefore": "2026-01-02T00:00:00Z", "notAfter": "2026-01-01T00:00:00Z"}]}`,
+		`{"target": 443, "serverNames": ["example.com"], "shortIds": [""], "keys": [{"privateKey": "SGfWgt-vTfpcYJvcAsr9TOEQM-ayJ-CxTAGwB7ubvWs", "notAfter": "tomorrow"}]}`,
+	} {
+		if _, err := build(s); err == nil {
+			t.Error("expected an error for ", s)
+		}
+	}
 }
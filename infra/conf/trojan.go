@@ -28,7 +28,8 @@ type TrojanServerTarget struct {
 
 // TrojanClientConfig is configuration of trojan servers
 type TrojanClientConfig struct {
-	Servers []*TrojanServerTarget `json:"servers"`
+	Servers          []*TrojanServerTarget   `json:"servers"`
+	HandshakeShaping *HandshakeShapingConfig `json:"handshakeShaping"`
 }
 
 // Build implements Buildable
@@ -70,6 +71,17 @@ func (c *TrojanClientConfig) Build() (proto.Message, error) {
 		}
 	}
 
+	if c.HandshakeShaping != nil {
+		if c.HandshakeShaping.PadHeader {
+			return nil, errors.New("Trojan has no padding, its handshake can only be segmented")
+		}
+		shaping, err := c.HandshakeShaping.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.HandshakeShaping = shaping
+	}
+
 	return config, nil
 }
 
@@ -81,6 +93,8 @@ type TrojanInboundFallback struct {
 	Type string          `json:"type"`
 	Dest json.RawMessage `json:"dest"`
 	Xver uint64          `json:"xver"`
+
+	ForwardHeaders StringList `json:"forwardHeaders"`
 }
 
 // TrojanUserConfig is user configuration
@@ -125,6 +139,10 @@ func (c *TrojanServerConfig) Build() (proto.Message, error) {
 		} else {
 			_ = json.Unmarshal(fb.Dest, &s)
 		}
+		forwardHeaders, err := buildForwardHeaders(fb.ForwardHeaders)
+		if err != nil {
+			return nil, errors.New(`Trojan fallbacks: invalid "forwardHeaders"`).Base(err)
+		}
 		config.Fallbacks = append(config.Fallbacks, &trojan.Fallback{
 			Name: fb.Name,
 			Alpn: fb.Alpn,
@@ -132,6 +150,8 @@ func (c *TrojanServerConfig) Build() (proto.Message, error) {
 			Type: fb.Type,
 			Dest: s,
 			Xver: fb.Xver,
+
+			ForwardHeaders: forwardHeaders,
 		})
 	}
 	for _, fb := range config.Fallbacks {
@@ -146,6 +166,9 @@ func (c *TrojanServerConfig) Build() (proto.Message, error) {
 		if fb.Type == "" && fb.Dest != "" {
 			if fb.Dest == "serve-ws-none" {
 				fb.Type = "serve"
+			} else if strings.HasPrefix(fb.Dest, builtinSitePrefix) {
+				fb.Type = "site"
+				fb.Dest = fb.Dest[len(builtinSitePrefix):]
 			} else if filepath.IsAbs(fb.Dest) || fb.Dest[0] == '@' {
 				fb.Type = "unix"
 				if strings.HasPrefix(fb.Dest, "@@") && (runtime.GOOS == "linux" || runtime.GOOS == "android") {
@@ -165,6 +188,9 @@ func (c *TrojanServerConfig) Build() (proto.Message, error) {
 		if fb.Type == "" {
 			return nil, errors.New(`Trojan fallbacks: please fill in a valid value for every "dest"`)
 		}
+		if fb.Type == "site" && fb.Xver != 0 {
+			return nil, errors.New(`Trojan fallbacks: "xver" is not supported by the built-in site`)
+		}
 		if fb.Xver > 2 {
 			return nil, errors.New(`Trojan fallbacks: invalid PROXY protocol version, "xver" only accepts 0, 1, 2`)
 		}
@@ -0,0 +1,41 @@
+//go:build !xray_minimal_router
+
+package conf
+
+import (
+	"net/netip"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/proxy/tun"
+	"google.golang.org/protobuf/proto"
+)
+
+func init() {
+	common.Must(inboundConfigLoader.cache.RegisterCreator("tun", func() interface{} { return new(TunConfig) }))
+}
+
+type TunConfig struct {
+	Name      string   `json:"name"`
+	MTU       uint32   `json:"mtu"`
+	Address   []string `json:"address"`
+	AutoRoute bool     `json:"autoRoute"`
+	Route     []string `json:"route"`
+	UserLevel uint32   `json:"userLevel"`
+}
+
+func (c *TunConfig) Build() (proto.Message, error) {
+	for _, cidr := range append(append([]string(nil), c.Address...), c.Route...) {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return nil, errors.New("invalid CIDR in tun settings: ", cidr).Base(err)
+		}
+	}
+	return &tun.Config{
+		Name:      c.Name,
+		Mtu:       c.MTU,
+		Address:   c.Address,
+		AutoRoute: c.AutoRoute,
+		Route:     c.Route,
+		UserLevel: c.UserLevel,
+	}, nil
+}
@@ -0,0 +1,44 @@
+package conf
+
+import (
+	"github.com/xtls/xray-core/app/tuner"
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// RuntimeConfig is the JSON config of the settings of the Go runtime. GOMAXPROCS and the soft
+// memory limit follow the CPU and memory limits of the cgroup of the process unless set here.
+type RuntimeConfig struct {
+	Disabled           bool   `json:"disabled"`
+	MaxProcs           uint32 `json:"maxProcs"`
+	MemoryLimit        uint64 `json:"memoryLimit"`
+	MemoryLimitPercent uint32 `json:"memoryLimitPercent"`
+	// CheckInterval is the seconds between checks of the limits for changes.
+	CheckInterval uint32 `json:"checkInterval"`
+	// DisableAESHardware prefers ChaCha20-Poly1305 where ciphers are chosen automatically, as on
+	// CPUs without AES instructions.
+	DisableAESHardware bool `json:"disableAesHardware"`
+	// IdleReclaimAfter is the seconds without connections after which caches are dropped and
+	// memory is returned to the OS.
+	IdleReclaimAfter   uint32 `json:"idleReclaimAfter"`
+	DisableIdleReclaim bool   `json:"disableIdleReclaim"`
+}
+
+// Build implements Buildable.
+func (c *RuntimeConfig) Build() (*tuner.Config, error) {
+	if c == nil {
+		return &tuner.Config{}, nil
+	}
+	if c.MemoryLimitPercent > 100 {
+		return nil, errors.New("memoryLimitPercent must be at most 100: ", c.MemoryLimitPercent)
+	}
+	return &tuner.Config{
+		Disabled:           c.Disabled,
+		MaxProcs:           c.MaxProcs,
+		MemoryLimit:        c.MemoryLimit,
+		MemoryLimitPercent: c.MemoryLimitPercent,
+		CheckInterval:      c.CheckInterval,
+		DisableAesHardware: c.DisableAESHardware,
+		IdleReclaimAfter:   c.IdleReclaimAfter,
+		DisableIdleReclaim: c.DisableIdleReclaim,
+	}, nil
+}
@@ -0,0 +1,89 @@
+//go:build !xray_client && !xray_minimal_router
+
+package conf_test
+
+import (
+	"testing"
+
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	. "github.com/xtls/xray-core/infra/conf"
+	"github.com/xtls/xray-core/proxy/vless"
+	"github.com/xtls/xray-core/proxy/vless/inbound"
+)
+
+func TestVLessInbound(t *testing.T) {
+	creator := func() Buildable {
+		return new(VLessInboundConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"clients": [
+					{
+						"id": "27848739-7e62-4138-9fd3-098a63964b6b",
+						"flow": "xtls-rprx-vision",
+						"level": 0,
+						"email": "love@example.com"
+					}
+				],
+				"decryption": "none",
+				"fallbacks": [
+					{
+						"dest": 80,
+						"forwardHeaders": ["x-forwarded-for", "X-Forwarded-Proto"]
+					},
+					{
+						"alpn": "h2",
+						"dest": "@/dev/shm/domain.socket",
+						"xver": 2
+					},
+					{
+						"path": "/innerws",
+						"dest": "serve-ws-none"
+					}
+				]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &inbound.Config{
+				Clients: []*protocol.User{
+					{
+						Account: serial.ToTypedMessage(&vless.Account{
+							Id:   "27848739-7e62-4138-9fd3-098a63964b6b",
+							Flow: "xtls-rprx-vision",
+						}),
+						Level: 0,
+						Email: "love@example.com",
+					},
+				},
+				Decryption: "none",
+				Fallbacks: []*inbound.Fallback{
+					{
+						Alpn: "",
+						Path: "",
+						Type: "tcp",
+						Dest: "127.0.0.1:80",
+						Xver: 0,
+
+						ForwardHeaders: []string{"X-Forwarded-For", "X-Forwarded-Proto"},
+					},
+					{
+						Alpn: "h2",
+						Path: "",
+						Type: "unix",
+						Dest: "@/dev/shm/domain.socket",
+						Xver: 2,
+					},
+					{
+						Alpn: "",
+						Path: "/innerws",
+						Type: "serve",
+						Dest: "serve-ws-none",
+						Xver: 0,
+					},
+				},
+			},
+		},
+	})
+}
@@ -1,23 +1,30 @@
+//go:build !xray_minimal_router
+
 package conf
 
 import (
 	"encoding/json"
 	"strings"
 
+	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/common/uuid"
 	"github.com/xtls/xray-core/proxy/vmess"
-	"github.com/xtls/xray-core/proxy/vmess/inbound"
 	"github.com/xtls/xray-core/proxy/vmess/outbound"
 	"google.golang.org/protobuf/proto"
 )
 
+func init() {
+	common.Must(outboundConfigLoader.cache.RegisterCreator("vmess", func() interface{} { return new(VMessOutboundConfig) }))
+}
+
 type VMessAccount struct {
 	ID          string `json:"id"`
 	Security    string `json:"security"`
 	Experiments string `json:"experiments"`
+	AlterIds    uint16 `json:"alterId"`
 }
 
 // Build implements Buildable
@@ -43,73 +50,10 @@ func (a *VMessAccount) Build() *vmess.Account {
 			Type: st,
 		},
 		TestsEnabled: a.Experiments,
+		AlterId:      uint32(a.AlterIds),
 	}
 }
 
-type VMessDetourConfig struct {
-	ToTag string `json:"to"`
-}
-
-// Build implements Buildable
-func (c *VMessDetourConfig) Build() *inbound.DetourConfig {
-	return &inbound.DetourConfig{
-		To: c.ToTag,
-	}
-}
-
-type VMessDefaultConfig struct {
-	Level byte `json:"level"`
-}
-
-// Build implements Buildable
-func (c *VMessDefaultConfig) Build() *inbound.DefaultConfig {
-	config := new(inbound.DefaultConfig)
-	config.Level = uint32(c.Level)
-	return config
-}
-
-type VMessInboundConfig struct {
-	Users        []json.RawMessage   `json:"clients"`
-	Defaults     *VMessDefaultConfig `json:"default"`
-	DetourConfig *VMessDetourConfig  `json:"detour"`
-}
-
-// Build implements Buildable
-func (c *VMessInboundConfig) Build() (proto.Message, error) {
-	config := &inbound.Config{}
-
-	if c.Defaults != nil {
-		config.Default = c.Defaults.Build()
-	}
-
-	if c.DetourConfig != nil {
-		config.Detour = c.DetourConfig.Build()
-	}
-
-	config.User = make([]*protocol.User, len(c.Users))
-	for idx, rawData := range c.Users {
-		user := new(protocol.User)
-		if err := json.Unmarshal(rawData, user); err != nil {
-			return nil, errors.New("invalid VMess user").Base(err)
-		}
-		account := new(VMessAccount)
-		if err := json.Unmarshal(rawData, account); err != nil {
-			return nil, errors.New("invalid VMess user").Base(err)
-		}
-
-		u, err := uuid.ParseString(account.ID)
-		if err != nil {
-			return nil, err
-		}
-		account.ID = u.String()
-
-		user.Account = serial.ToTypedMessage(account.Build())
-		config.User[idx] = user
-	}
-
-	return config, nil
-}
-
 type VMessOutboundTarget struct {
 	Address *Address          `json:"address"`
 	Port    uint16            `json:"port"`
@@ -154,6 +98,7 @@ func (c *VMessOutboundConfig) Build() (proto.Message, error) {
 				return nil, err
 			}
 			account.ID = u.String()
+			account.AlterIds = 0 // only inbounds accept legacy clients
 
 			user.Account = serial.ToTypedMessage(account.Build())
 			spec.User = append(spec.User, user)
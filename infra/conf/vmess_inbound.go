@@ -0,0 +1,98 @@
+//go:build !xray_client && !xray_minimal_router
+
+package conf
+
+import (
+	"encoding/json"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/common/uuid"
+	"github.com/xtls/xray-core/proxy/vmess/inbound"
+	"google.golang.org/protobuf/proto"
+)
+
+func init() {
+	common.Must(inboundConfigLoader.cache.RegisterCreator("vmess", func() interface{} { return new(VMessInboundConfig) }))
+}
+
+type VMessDetourConfig struct {
+	ToTag string `json:"to"`
+}
+
+// Build implements Buildable
+func (c *VMessDetourConfig) Build() *inbound.DetourConfig {
+	return &inbound.DetourConfig{
+		To: c.ToTag,
+	}
+}
+
+type VMessDefaultConfig struct {
+	Level byte `json:"level"`
+}
+
+// Build implements Buildable
+func (c *VMessDefaultConfig) Build() *inbound.DefaultConfig {
+	config := new(inbound.DefaultConfig)
+	config.Level = uint32(c.Level)
+	return config
+}
+
+type VMessInboundConfig struct {
+	Users        []json.RawMessage   `json:"clients"`
+	Defaults     *VMessDefaultConfig `json:"default"`
+	DetourConfig *VMessDetourConfig  `json:"detour"`
+	Replay       *ReplayConfig       `json:"replay"`
+	LegacyCompat bool                `json:"legacyCompat"`
+}
+
+// Build implements Buildable
+func (c *VMessInboundConfig) Build() (proto.Message, error) {
+	config := &inbound.Config{
+		LegacyCompat: c.LegacyCompat,
+	}
+
+	if c.Defaults != nil {
+		config.Default = c.Defaults.Build()
+	}
+
+	if c.DetourConfig != nil {
+		config.Detour = c.DetourConfig.Build()
+	}
+
+	if c.Replay != nil {
+		replay, err := c.Replay.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Replay = replay
+	}
+
+	config.User = make([]*protocol.User, len(c.Users))
+	for idx, rawData := range c.Users {
+		user := new(protocol.User)
+		if err := json.Unmarshal(rawData, user); err != nil {
+			return nil, errors.New("invalid VMess user").Base(err)
+		}
+		account := new(VMessAccount)
+		if err := json.Unmarshal(rawData, account); err != nil {
+			return nil, errors.New("invalid VMess user").Base(err)
+		}
+
+		u, err := uuid.ParseString(account.ID)
+		if err != nil {
+			return nil, err
+		}
+		account.ID = u.String()
+		if account.AlterIds > 0 && !c.LegacyCompat {
+			return nil, errors.New(`VMess clients with "alterId" need "legacyCompat"`)
+		}
+
+		user.Account = serial.ToTypedMessage(account.Build())
+		config.User[idx] = user
+	}
+
+	return config, nil
+}
@@ -0,0 +1,105 @@
+//go:build !xray_client && !xray_minimal_router
+
+package conf_test
+
+import (
+	"testing"
+
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	. "github.com/xtls/xray-core/infra/conf"
+	"github.com/xtls/xray-core/proxy/vmess"
+	"github.com/xtls/xray-core/proxy/vmess/inbound"
+)
+
+func TestVMessInbound(t *testing.T) {
+	creator := func() Buildable {
+		return new(VMessInboundConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"clients": [
+					{
+						"id": "27848739-7e62-4138-9fd3-098a63964b6b",
+						"level": 0,
+						"email": "love@example.com",
+						"security": "aes-128-gcm"
+					}
+				],
+				"default": {
+					"level": 0
+				},
+				"detour": {
+					"to": "tag_to_detour"
+				},
+				"disableInsecureEncryption": true
+			}`,
+			Parser: loadJSON(creator),
+			Output: &inbound.Config{
+				User: []*protocol.User{
+					{
+						Level: 0,
+						Email: "love@example.com",
+						Account: serial.ToTypedMessage(&vmess.Account{
+							Id: "27848739-7e62-4138-9fd3-098a63964b6b",
+							SecuritySettings: &protocol.SecurityConfig{
+								Type: protocol.SecurityType_AES128_GCM,
+							},
+						}),
+					},
+				},
+				Default: &inbound.DefaultConfig{
+					Level: 0,
+				},
+				Detour: &inbound.DetourConfig{
+					To: "tag_to_detour",
+				},
+			},
+		},
+		{
+			Input: `{
+				"clients": [],
+				"replay": {
+					"action": "ban",
+					"banDuration": 3600
+				}
+			}`,
+			Parser: loadJSON(creator),
+			Output: &inbound.Config{
+				User: []*protocol.User{},
+				Replay: &protocol.ReplayPolicy{
+					Action:      protocol.ReplayPolicy_Ban,
+					BanDuration: 3600,
+				},
+			},
+		},
+		{
+			Input: `{
+				"clients": [
+					{
+						"id": "27848739-7e62-4138-9fd3-098a63964b6b",
+						"alterId": 64
+					}
+				],
+				"legacyCompat": true
+			}`,
+			Parser: loadJSON(creator),
+			Output: &inbound.Config{
+				User: []*protocol.User{
+					{
+						Account: serial.ToTypedMessage(&vmess.Account{
+							Id: "27848739-7e62-4138-9fd3-098a63964b6b",
+							SecuritySettings: &protocol.SecurityConfig{
+								Type: protocol.SecurityType_AUTO,
+							},
+							AlterId: 64,
+						}),
+					},
+				},
+				LegacyCompat: true,
+			},
+		},
+	})
+}
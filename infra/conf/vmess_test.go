@@ -1,3 +1,5 @@
+//go:build !xray_minimal_router
+
 package conf_test
 
 import (
@@ -8,7 +10,6 @@ import (
 	"github.com/xtls/xray-core/common/serial"
 	. "github.com/xtls/xray-core/infra/conf"
 	"github.com/xtls/xray-core/proxy/vmess"
-	"github.com/xtls/xray-core/proxy/vmess/inbound"
 	"github.com/xtls/xray-core/proxy/vmess/outbound"
 )
 
@@ -60,52 +61,3 @@ func TestVMessOutbound(t *testing.T) {
 		},
 	})
 }
-
-func TestVMessInbound(t *testing.T) {
-	creator := func() Buildable {
-		return new(VMessInboundConfig)
-	}
-
-	runMultiTestCase(t, []TestCase{
-		{
-			Input: `{
-				"clients": [
-					{
-						"id": "27848739-7e62-4138-9fd3-098a63964b6b",
-						"level": 0,
-						"email": "love@example.com",
-						"security": "aes-128-gcm"
-					}
-				],
-				"default": {
-					"level": 0
-				},
-				"detour": {
-					"to": "tag_to_detour"
-				},
-				"disableInsecureEncryption": true
-			}`,
-			Parser: loadJSON(creator),
-			Output: &inbound.Config{
-				User: []*protocol.User{
-					{
-						Level: 0,
-						Email: "love@example.com",
-						Account: serial.ToTypedMessage(&vmess.Account{
-							Id: "27848739-7e62-4138-9fd3-098a63964b6b",
-							SecuritySettings: &protocol.SecurityConfig{
-								Type: protocol.SecurityType_AES128_GCM,
-							},
-						}),
-					},
-				},
-				Default: &inbound.DefaultConfig{
-					Level: 0,
-				},
-				Detour: &inbound.DetourConfig{
-					To: "tag_to_detour",
-				},
-			},
-		},
-	})
-}
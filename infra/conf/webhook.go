@@ -0,0 +1,34 @@
+package conf
+
+import (
+	"net/url"
+
+	"github.com/xtls/xray-core/app/webhook"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/infra/conf/cfgcommon/duration"
+)
+
+// WebhookConfig posts the traffic of users and inbounds to URL every interval, signed with
+// secret. It needs the "stats" section, and "statsUserUplink" and the like in the policy.
+type WebhookConfig struct {
+	URL         string            `json:"url"`
+	Secret      string            `json:"secret"`
+	Interval    duration.Duration `json:"interval"`
+	MaxAttempts uint32            `json:"maxAttempts"`
+}
+
+func (c *WebhookConfig) Build() (*webhook.Config, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, errors.New("invalid webhook url: ", c.URL)
+	}
+	if c.Interval < 0 {
+		return nil, errors.New("negative webhook interval")
+	}
+	return &webhook.Config{
+		Url:         c.URL,
+		Secret:      c.Secret,
+		Interval:    int64(c.Interval),
+		MaxAttempts: c.MaxAttempts,
+	}, nil
+}
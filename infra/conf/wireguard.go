@@ -1,3 +1,5 @@
+//go:build !xray_minimal_router
+
 package conf
 
 import (
@@ -5,11 +7,17 @@ import (
 	"encoding/hex"
 	"strings"
 
+	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/proxy/wireguard"
 	"google.golang.org/protobuf/proto"
 )
 
+func init() {
+	common.Must(inboundConfigLoader.cache.RegisterCreator("wireguard", func() interface{} { return &WireGuardConfig{IsClient: false} }))
+	common.Must(outboundConfigLoader.cache.RegisterCreator("wireguard", func() interface{} { return &WireGuardConfig{IsClient: true} }))
+}
+
 type WireGuardPeerConfig struct {
 	PublicKey    string   `json:"publicKey"`
 	PreSharedKey string   `json:"preSharedKey"`
@@ -1,3 +1,5 @@
+//go:build !xray_minimal_router
+
 package conf_test
 
 import (
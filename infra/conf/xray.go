@@ -2,34 +2,39 @@ package conf
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
+	gonet "net"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/xtls/xray-core/app/dispatcher"
 	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/app/router"
 	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/serial"
 	core "github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf/cfgcommon/duration"
 	"github.com/xtls/xray-core/transport/internet"
 )
 
 var (
 	inboundConfigLoader = NewJSONConfigLoader(ConfigCreatorCache{
+		"dns":           func() interface{} { return new(DNSInboundConfig) },
 		"dokodemo-door": func() interface{} { return new(DokodemoConfig) },
 		"http":          func() interface{} { return new(HTTPServerConfig) },
 		"shadowsocks":   func() interface{} { return new(ShadowsocksServerConfig) },
 		"mixed":         func() interface{} { return new(SocksServerConfig) },
 		"socks":         func() interface{} { return new(SocksServerConfig) },
-		"vless":         func() interface{} { return new(VLessInboundConfig) },
-		"vmess":         func() interface{} { return new(VMessInboundConfig) },
 		"trojan":        func() interface{} { return new(TrojanServerConfig) },
-		"wireguard":     func() interface{} { return &WireGuardConfig{IsClient: false} },
 	}, "protocol", "settings")
 
 	outboundConfigLoader = NewJSONConfigLoader(ConfigCreatorCache{
@@ -40,58 +45,128 @@ var (
 		"shadowsocks": func() interface{} { return new(ShadowsocksClientConfig) },
 		"socks":       func() interface{} { return new(SocksClientConfig) },
 		"vless":       func() interface{} { return new(VLessOutboundConfig) },
-		"vmess":       func() interface{} { return new(VMessOutboundConfig) },
 		"trojan":      func() interface{} { return new(TrojanClientConfig) },
 		"dns":         func() interface{} { return new(DNSOutboundConfig) },
-		"wireguard":   func() interface{} { return &WireGuardConfig{IsClient: true} },
 	}, "protocol", "settings")
 
 	ctllog = log.New(os.Stderr, "xctl> ", 0)
 )
 
 type SniffingConfig struct {
-	Enabled         bool        `json:"enabled"`
-	DestOverride    *StringList `json:"destOverride"`
-	DomainsExcluded *StringList `json:"domainsExcluded"`
-	MetadataOnly    bool        `json:"metadataOnly"`
-	RouteOnly       bool        `json:"routeOnly"`
+	Enabled                 bool                   `json:"enabled"`
+	DestOverride            *StringList            `json:"destOverride"`
+	DomainsExcluded         *StringList            `json:"domainsExcluded"`
+	ProtocolDomainsExcluded map[string]*StringList `json:"protocolDomainsExcluded"`
+	MetadataOnly            bool                   `json:"metadataOnly"`
+	RouteOnly               bool                   `json:"routeOnly"`
+	// Timeout is the longest wait for the client to send enough to sniff, and MaxBytes the most
+	// bytes waited for, at most 8192. Sniffing stops earlier once the protocol is identified or
+	// ruled out.
+	Timeout  duration.Duration `json:"timeout"`
+	MaxBytes uint32            `json:"maxBytes"`
+}
+
+func parseSniffingProtocol(protocol string) (string, error) {
+	switch strings.ToLower(protocol) {
+	case "http":
+		return "http", nil
+	case "tls", "https", "ssl":
+		return "tls", nil
+	case "quic":
+		return "quic", nil
+	case "fakedns":
+		return "fakedns", nil
+	case "fakedns+others":
+		return "fakedns+others", nil
+	default:
+		return "", errors.New("unknown protocol: ", protocol)
+	}
+}
+
+// parseExcludedDomains expands geosite and external site lists into
+// prefixed domain rules. Other entries are kept as they are.
+func parseExcludedDomains(list *StringList) ([]string, error) {
+	if list == nil {
+		return nil, nil
+	}
+	var d []string
+	for _, domain := range *list {
+		if !strings.HasPrefix(domain, "geosite:") && !strings.HasPrefix(domain, "ext:") && !strings.HasPrefix(domain, "ext-domain:") {
+			d = append(d, strings.ToLower(domain))
+			continue
+		}
+		rules, err := parseDomainRule(domain)
+		if err != nil {
+			return nil, errors.New("failed to parse excluded domain: ", domain).Base(err)
+		}
+		for _, rule := range rules {
+			switch rule.Type {
+			case router.Domain_Plain:
+				d = append(d, "keyword:"+rule.Value)
+			case router.Domain_Regex:
+				d = append(d, "regexp:"+rule.Value)
+			case router.Domain_Domain:
+				d = append(d, "domain:"+rule.Value)
+			case router.Domain_Full:
+				d = append(d, "full:"+rule.Value)
+			}
+		}
+	}
+	return d, nil
 }
 
 // Build implements Buildable.
 func (c *SniffingConfig) Build() (*proxyman.SniffingConfig, error) {
+	if c.Timeout < 0 {
+		return nil, errors.New("sniffing timeout must not be negative")
+	}
+	if c.MaxBytes > buf.Size {
+		return nil, errors.New("sniffing maxBytes must be at most ", buf.Size)
+	}
 	var p []string
 	if c.DestOverride != nil {
 		for _, protocol := range *c.DestOverride {
-			switch strings.ToLower(protocol) {
-			case "http":
-				p = append(p, "http")
-			case "tls", "https", "ssl":
-				p = append(p, "tls")
-			case "quic":
-				p = append(p, "quic")
-			case "fakedns":
-				p = append(p, "fakedns")
-			case "fakedns+others":
-				p = append(p, "fakedns+others")
-			default:
-				return nil, errors.New("unknown protocol: ", protocol)
+			name, err := parseSniffingProtocol(protocol)
+			if err != nil {
+				return nil, err
 			}
+			p = append(p, name)
 		}
 	}
 
-	var d []string
-	if c.DomainsExcluded != nil {
-		for _, domain := range *c.DomainsExcluded {
-			d = append(d, strings.ToLower(domain))
+	d, err := parseExcludedDomains(c.DomainsExcluded)
+	if err != nil {
+		return nil, err
+	}
+
+	var exclusions []*proxyman.SniffingExclusion
+	for protocol, list := range c.ProtocolDomainsExcluded {
+		name, err := parseSniffingProtocol(protocol)
+		if err != nil {
+			return nil, err
+		}
+		domains, err := parseExcludedDomains(list)
+		if err != nil {
+			return nil, err
 		}
+		exclusions = append(exclusions, &proxyman.SniffingExclusion{
+			Protocol: name,
+			Domains:  domains,
+		})
 	}
+	sort.Slice(exclusions, func(i, j int) bool {
+		return exclusions[i].Protocol < exclusions[j].Protocol
+	})
 
 	return &proxyman.SniffingConfig{
-		Enabled:             c.Enabled,
-		DestinationOverride: p,
-		DomainsExcluded:     d,
-		MetadataOnly:        c.MetadataOnly,
-		RouteOnly:           c.RouteOnly,
+		Enabled:                 c.Enabled,
+		DestinationOverride:     p,
+		DomainsExcluded:         d,
+		ProtocolDomainsExcluded: exclusions,
+		MetadataOnly:            c.MetadataOnly,
+		RouteOnly:               c.RouteOnly,
+		Timeout:                 int64(c.Timeout),
+		MaxBytes:                c.MaxBytes,
 	}, nil
 }
 
@@ -100,6 +175,13 @@ type MuxConfig struct {
 	Concurrency     int16  `json:"concurrency"`
 	XudpConcurrency int16  `json:"xudpConcurrency"`
 	XudpProxyUDP443 string `json:"xudpProxyUDP443"`
+	Migrate         bool   `json:"migrate"`
+	// XudpKey is the key XUDP global IDs are derived with, 32 bytes in base64, like the
+	// xray.xudp.basekey environment variable which it overrides for the outbound.
+	XudpKey string `json:"xudpKey"`
+	// Exclude are routing rules, without targets, of the traffic which goes without Mux, as
+	// speed tests or QUIC.
+	Exclude []*RawFieldRule `json:"exclude"`
 }
 
 // Build creates MultiplexingConfig, Concurrency < 0 completely disables mux.
@@ -111,11 +193,83 @@ func (m *MuxConfig) Build() (*proxyman.MultiplexingConfig, error) {
 	default:
 		return nil, errors.New(`unknown "xudpProxyUDP443": `, m.XudpProxyUDP443)
 	}
-	return &proxyman.MultiplexingConfig{
+	config := &proxyman.MultiplexingConfig{
 		Enabled:         m.Enabled,
 		Concurrency:     int32(m.Concurrency),
 		XudpConcurrency: int32(m.XudpConcurrency),
 		XudpProxyUDP443: m.XudpProxyUDP443,
+		Migrate:         m.Migrate,
+	}
+	if m.XudpKey != "" {
+		key, err := base64.RawURLEncoding.DecodeString(strings.NewReplacer("+", "-", "/", "_", "=", "").Replace(m.XudpKey))
+		if err != nil || len(key) != 32 {
+			return nil, errors.New(`invalid "xudpKey", expected 32 bytes in base64: `, m.XudpKey)
+		}
+		config.XudpKey = key
+	}
+	for _, rule := range m.Exclude {
+		exclusion, err := rule.Build()
+		if err != nil {
+			return nil, errors.New(`invalid "exclude" of mux`).Base(err)
+		}
+		config.Exclude = append(config.Exclude, exclusion)
+	}
+	return config, nil
+}
+
+type RetryConfig struct {
+	Attempts      uint32      `json:"attempts"`
+	Backoff       uint32      `json:"backoff"`
+	RetryOn       *StringList `json:"retryOn"`
+	RotateAddress bool        `json:"rotateAddress"`
+}
+
+// Build implements Buildable.
+func (c *RetryConfig) Build() (*proxyman.RetryConfig, error) {
+	config := &proxyman.RetryConfig{
+		Attempts:      c.Attempts,
+		Backoff:       c.Backoff,
+		RotateAddress: c.RotateAddress,
+	}
+	if c.RetryOn != nil {
+		for _, kind := range *c.RetryOn {
+			kind = strings.ToLower(kind)
+			switch kind {
+			case "timeout", "reset", "refused":
+				config.RetryOn = append(config.RetryOn, kind)
+			default:
+				return nil, errors.New(`unknown "retryOn": `, kind)
+			}
+		}
+	}
+	return config, nil
+}
+
+// StandbyConfig makes an outbound a warm standby of the outbound tagged For.
+type StandbyConfig struct {
+	For      string            `json:"for"`
+	ProbeURL string            `json:"probeURL"`
+	Interval duration.Duration `json:"interval"`
+	Timeout  duration.Duration `json:"timeout"`
+}
+
+// Build builds the standby config of the outbound with the given tag.
+func (c *StandbyConfig) Build(tag string) (*proxyman.StandbyConfig, error) {
+	if tag == "" {
+		return nil, errors.New("standby outbound must have a tag")
+	}
+	if c.For == "" || c.For == tag {
+		return nil, errors.New(`"for" of standby outbound `, tag, " must be the tag of another outbound")
+	}
+	if c.Interval < 0 || c.Timeout < 0 {
+		return nil, errors.New("interval and timeout of standby outbound ", tag, " must not be negative")
+	}
+	return &proxyman.StandbyConfig{
+		Tag:           tag,
+		Primary:       c.For,
+		ProbeUrl:      c.ProbeURL,
+		ProbeInterval: int64(c.Interval),
+		ProbeTimeout:  int64(c.Timeout),
 	}, nil
 }
 
@@ -162,13 +316,20 @@ type InboundDetourConfig struct {
 	Allocation     *InboundDetourAllocationConfig `json:"allocate"`
 	StreamSetting  *StreamConfig                  `json:"streamSettings"`
 	SniffingConfig *SniffingConfig                `json:"sniffing"`
+	// MPTCP accepts Multipath TCP connections, same as "tcpMptcp" in the sockopt.
+	MPTCP bool `json:"mptcp"`
+	// LocalBypass dials the services of this host, on its addresses or localhost, directly
+	// instead of through routing and outbounds.
+	LocalBypass bool `json:"localBypass"`
 }
 
 // Build implements Buildable.
 func (c *InboundDetourConfig) Build() (*core.InboundHandlerConfig, error) {
 	receiverSettings := &proxyman.ReceiverConfig{}
 
-	if c.ListenOn == nil {
+	if c.Protocol == "tun" {
+		// A TUN inbound takes the traffic of its device, listening on no port
+	} else if c.ListenOn == nil {
 		// Listen on anyip, must set PortList
 		if c.PortList == nil {
 			return nil, errors.New("Listen on AnyIP but no Port(s) set in InboundDetour.")
@@ -227,6 +388,9 @@ func (c *InboundDetourConfig) Build() (*core.InboundHandlerConfig, error) {
 		}
 		receiverSettings.StreamSettings = ss
 	}
+	if c.MPTCP {
+		receiverSettings.StreamSettings = enableMPTCP(receiverSettings.StreamSettings)
+	}
 	if c.SniffingConfig != nil {
 		s, err := c.SniffingConfig.Build()
 		if err != nil {
@@ -234,6 +398,7 @@ func (c *InboundDetourConfig) Build() (*core.InboundHandlerConfig, error) {
 		}
 		receiverSettings.SniffingSettings = s
 	}
+	receiverSettings.LocalBypass = c.LocalBypass
 
 	settings := []byte("{}")
 	if c.Settings != nil {
@@ -266,6 +431,75 @@ type OutboundDetourConfig struct {
 	StreamSetting *StreamConfig    `json:"streamSettings"`
 	ProxySettings *ProxyConfig     `json:"proxySettings"`
 	MuxSettings   *MuxConfig       `json:"mux"`
+	RetrySettings *RetryConfig     `json:"retry"`
+	Standby       *StandbyConfig   `json:"standby"`
+	// MPTCP dials Multipath TCP connections, same as "tcpMptcp" in the sockopt.
+	MPTCP bool `json:"mptcp"`
+	// Trace logs every step of the dials at debug level, for a few dials at a time.
+	Trace bool `json:"trace"`
+	// NTP corrects the clock of time-sensitive protocols, over the global "ntp".
+	NTP *NTPConfig `json:"ntp"`
+}
+
+// NTPConfig is an NTP server the clock of VMess and Shadowsocks 2022 outbounds is corrected by,
+// without changing the system time.
+type NTPConfig struct {
+	// Server is a host with an optional port, 123 by default.
+	Server string `json:"server"`
+	// Interval is the interval between syncs in seconds, an hour by default.
+	Interval uint32 `json:"interval"`
+}
+
+// enableMPTCP turns on Multipath TCP in ss, created if nil. Connections fall back to TCP where
+// the kernel or the peer doesn't support it.
+func enableMPTCP(ss *internet.StreamConfig) *internet.StreamConfig {
+	if ss == nil {
+		ss = new(internet.StreamConfig)
+	}
+	if ss.SocketSettings == nil {
+		ss.SocketSettings = new(internet.SocketConfig)
+	}
+	ss.SocketSettings.TcpMptcp = true
+	return ss
+}
+
+// serverEndpoints are the server addresses in the settings of outbounds, under "vnext" for VMess
+// and VLESS, and "servers" for other protocols.
+type serverEndpoints struct {
+	Vnext []struct {
+		Address string `json:"address"`
+		Port    uint16 `json:"port"`
+	} `json:"vnext"`
+	Servers []struct {
+		Address string `json:"address"`
+		Port    uint16 `json:"port"`
+	} `json:"servers"`
+}
+
+// ServerAddresses returns the host:port of the servers in the settings of the outbound.
+func (c *OutboundDetourConfig) ServerAddresses() []string {
+	if c.Settings == nil {
+		return nil
+	}
+	var endpoints serverEndpoints
+	if err := json.Unmarshal(*c.Settings, &endpoints); err != nil {
+		return nil
+	}
+	var servers []string
+	for _, v := range endpoints.Vnext {
+		servers = append(servers, gonet.JoinHostPort(v.Address, strconv.Itoa(int(v.Port))))
+	}
+	for _, s := range endpoints.Servers {
+		if s.Address != "" && s.Port != 0 {
+			servers = append(servers, gonet.JoinHostPort(s.Address, strconv.Itoa(int(s.Port))))
+		}
+	}
+	return servers
+}
+
+// DialedThroughOutbound returns whether the outbound connects to its servers through another outbound.
+func (c *OutboundDetourConfig) DialedThroughOutbound() bool {
+	return c.ProxySettings != nil || (c.StreamSetting != nil && c.StreamSetting.SocketSettings != nil && c.StreamSetting.SocketSettings.DialerProxy != "")
 }
 
 func (c *OutboundDetourConfig) checkChainProxyConfig() error {
@@ -305,6 +539,9 @@ func (c *OutboundDetourConfig) Build() (*core.OutboundHandlerConfig, error) {
 		}
 		senderSettings.StreamSettings = ss
 	}
+	if c.MPTCP {
+		senderSettings.StreamSettings = enableMPTCP(senderSettings.StreamSettings)
+	}
 
 	if c.ProxySettings != nil {
 		ps, err := c.ProxySettings.Build()
@@ -334,6 +571,22 @@ func (c *OutboundDetourConfig) Build() (*core.OutboundHandlerConfig, error) {
 		senderSettings.MultiplexSettings = ms
 	}
 
+	if c.RetrySettings != nil {
+		rs, err := c.RetrySettings.Build()
+		if err != nil {
+			return nil, errors.New("failed to build retry config.").Base(err)
+		}
+		senderSettings.RetrySettings = rs
+	}
+	senderSettings.Trace = c.Trace
+	if c.NTP != nil {
+		if c.NTP.Server == "" {
+			return nil, errors.New("no server in the NTP settings of outbound ", c.Tag)
+		}
+		senderSettings.NtpServer = c.NTP.Server
+		senderSettings.NtpInterval = c.NTP.Interval
+	}
+
 	settings := []byte("{}")
 	if c.Settings != nil {
 		settings = ([]byte)(*c.Settings)
@@ -354,11 +607,25 @@ func (c *OutboundDetourConfig) Build() (*core.OutboundHandlerConfig, error) {
 	}, nil
 }
 
-type StatsConfig struct{}
+type StatsConfig struct {
+	FileLimit     uint64  `json:"fileLimit"`
+	FileWarnRatio float32 `json:"fileWarnRatio"`
+	// SeriesLength and SeriesInterval, in seconds, set the history kept of each counter.
+	SeriesLength   uint32 `json:"seriesLength"`
+	SeriesInterval uint32 `json:"seriesInterval"`
+}
 
 // Build implements Buildable.
 func (c *StatsConfig) Build() (*stats.Config, error) {
-	return &stats.Config{}, nil
+	if c.FileWarnRatio < 0 || c.FileWarnRatio > 1 {
+		return nil, errors.New("fileWarnRatio must be between 0 and 1: ", c.FileWarnRatio)
+	}
+	return &stats.Config{
+		FileLimit:      c.FileLimit,
+		FileWarnRatio:  c.FileWarnRatio,
+		SeriesLength:   c.SeriesLength,
+		SeriesInterval: c.SeriesInterval,
+	}, nil
 }
 
 type Config struct {
@@ -374,11 +641,33 @@ type Config struct {
 	Policy           *PolicyConfig           `json:"policy"`
 	API              *APIConfig              `json:"api"`
 	Metrics          *MetricsConfig          `json:"metrics"`
+	HTTPAPI          *HTTPAPIConfig          `json:"httpApi"`
 	Stats            *StatsConfig            `json:"stats"`
 	Reverse          *ReverseConfig          `json:"reverse"`
 	FakeDNS          *FakeDNSConfig          `json:"fakeDns"`
 	Observatory      *ObservatoryConfig      `json:"observatory"`
 	BurstObservatory *BurstObservatoryConfig `json:"burstObservatory"`
+	FTP              *FTPConfig              `json:"ftp"`
+	Scheduler        *SchedulerConfig        `json:"scheduler"`
+	Shaper           *ShaperConfig           `json:"shaper"`
+	Webhook          *WebhookConfig          `json:"webhook"`
+	Runtime          *RuntimeConfig          `json:"runtime"`
+
+	SubscriptionServer *SubscriptionServerConfig `json:"subscriptionServer"`
+
+	// Several observatories, instead of or together with Observatory and BurstObservatory.
+	Observatories      []*ObservatoryConfig      `json:"observatories"`
+	BurstObservatories []*BurstObservatoryConfig `json:"burstObservatories"`
+
+	Subscriptions []*SubscriptionConfig `json:"subscriptions"`
+
+	// Forwarding of local ports, expanded into inbounds and routing rules.
+	Forwarding []*ForwardingConfig `json:"forwarding"`
+
+	// NTP corrects the clock of time-sensitive protocols of the outbounds without their own.
+	NTP *NTPConfig `json:"ntp"`
+
+	TolerateInboundErrors bool `json:"tolerateInboundErrors"`
 }
 
 func (c *Config) findInboundTag(tag string) int {
@@ -428,6 +717,9 @@ func (c *Config) Override(o *Config, fn string) {
 	if o.Metrics != nil {
 		c.Metrics = o.Metrics
 	}
+	if o.HTTPAPI != nil {
+		c.HTTPAPI = o.HTTPAPI
+	}
 	if o.Stats != nil {
 		c.Stats = o.Stats
 	}
@@ -447,6 +739,54 @@ func (c *Config) Override(o *Config, fn string) {
 		c.BurstObservatory = o.BurstObservatory
 	}
 
+	if len(o.Observatories) > 0 {
+		c.Observatories = o.Observatories
+	}
+
+	if len(o.BurstObservatories) > 0 {
+		c.BurstObservatories = o.BurstObservatories
+	}
+
+	if o.FTP != nil {
+		c.FTP = o.FTP
+	}
+
+	if o.Scheduler != nil {
+		c.Scheduler = o.Scheduler
+	}
+
+	if o.Shaper != nil {
+		c.Shaper = o.Shaper
+	}
+
+	if o.Webhook != nil {
+		c.Webhook = o.Webhook
+	}
+
+	if o.Runtime != nil {
+		c.Runtime = o.Runtime
+	}
+
+	if o.NTP != nil {
+		c.NTP = o.NTP
+	}
+
+	if o.SubscriptionServer != nil {
+		c.SubscriptionServer = o.SubscriptionServer
+	}
+
+	if o.TolerateInboundErrors {
+		c.TolerateInboundErrors = true
+	}
+
+	if len(o.Subscriptions) > 0 {
+		c.Subscriptions = append(c.Subscriptions, o.Subscriptions...)
+	}
+
+	if len(o.Forwarding) > 0 {
+		c.Forwarding = append(c.Forwarding, o.Forwarding...)
+	}
+
 	// update the Inbound in slice if the only one in override config has same tag
 	if len(o.InboundConfigs) > 0 {
 		for i := range o.InboundConfigs {
@@ -485,17 +825,93 @@ func (c *Config) Override(o *Config, fn string) {
 	}
 }
 
+// withSubscriptions returns the outbounds with those of the cached copies of subscriptions
+// appended. Subscription outbounds whose tags are taken are skipped.
+func (c *Config) withSubscriptions() []OutboundDetourConfig {
+	outbounds := append([]OutboundDetourConfig(nil), c.OutboundConfigs...)
+	if len(c.Subscriptions) == 0 {
+		return outbounds
+	}
+	tags := make(map[string]bool, len(outbounds))
+	for _, ob := range outbounds {
+		tags[ob.Tag] = true
+	}
+	for _, sub := range c.Subscriptions {
+		obs, err := sub.Outbounds()
+		if err != nil {
+			errors.LogWarningInner(context.Background(), err, "subscription ", sub.URL, " has no outbounds")
+			continue
+		}
+		for _, ob := range obs {
+			if tags[ob.Tag] {
+				errors.LogWarning(context.Background(), "skipped outbound ", ob.Tag, " of subscription ", sub.URL, ": tag is taken")
+				continue
+			}
+			tags[ob.Tag] = true
+			outbounds = append(outbounds, ob)
+		}
+	}
+	return outbounds
+}
+
+// buildOutboundManager collects the standby settings of outbounds.
+func buildOutboundManager(outbounds []OutboundDetourConfig) (*proxyman.OutboundConfig, error) {
+	config := &proxyman.OutboundConfig{}
+	tags := make(map[string]bool, len(outbounds))
+	for _, ob := range outbounds {
+		tags[ob.Tag] = true
+	}
+	for _, ob := range outbounds {
+		if ob.Standby == nil {
+			continue
+		}
+		standby, err := ob.Standby.Build(ob.Tag)
+		if err != nil {
+			return nil, err
+		}
+		if !tags[standby.Primary] {
+			return nil, errors.New("outbound ", standby.Primary, " of standby ", ob.Tag, " not found")
+		}
+		config.Standby = append(config.Standby, standby)
+	}
+	return config, nil
+}
+
+func hasOutbound(outbounds []OutboundDetourConfig, tag string) bool {
+	for _, ob := range outbounds {
+		if ob.Tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
 // Build implements Buildable.
 func (c *Config) Build() (*core.Config, error) {
 	if err := PostProcessConfigureFile(c); err != nil {
 		return nil, err
 	}
 
+	outbounds := c.withSubscriptions()
+	outboundManager, err := buildOutboundManager(outbounds)
+	if err != nil {
+		return nil, err
+	}
+	if c.NTP != nil {
+		if c.NTP.Server == "" {
+			return nil, errors.New("no server in the NTP settings")
+		}
+		outboundManager.NtpServer = c.NTP.Server
+		outboundManager.NtpInterval = c.NTP.Interval
+	}
+
 	config := &core.Config{
 		App: []*serial.TypedMessage{
 			serial.ToTypedMessage(&dispatcher.Config{}),
-			serial.ToTypedMessage(&proxyman.InboundConfig{}),
-			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{
+				TolerateErrors: c.TolerateInboundErrors,
+			}),
+			serial.ToTypedMessage(outboundManager),
 		},
 	}
 
@@ -513,6 +929,13 @@ func (c *Config) Build() (*core.Config, error) {
 		}
 		config.App = append(config.App, serial.ToTypedMessage(metricsConf))
 	}
+	if c.HTTPAPI != nil {
+		httpAPIConf, err := c.HTTPAPI.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.App = append(config.App, serial.ToTypedMessage(httpAPIConf))
+	}
 	if c.Stats != nil {
 		statsConf, err := c.Stats.Build()
 		if err != nil {
@@ -523,7 +946,11 @@ func (c *Config) Build() (*core.Config, error) {
 
 	var logConfMsg *serial.TypedMessage
 	if c.LogConfig != nil {
-		logConfMsg = serial.ToTypedMessage(c.LogConfig.Build())
+		logConf, err := c.LogConfig.Build()
+		if err != nil {
+			return nil, errors.New("failed to build log config").Base(err)
+		}
+		logConfMsg = serial.ToTypedMessage(logConf)
 	} else {
 		logConfMsg = serial.ToTypedMessage(DefaultLogConfig())
 	}
@@ -531,8 +958,25 @@ func (c *Config) Build() (*core.Config, error) {
 	// so that other modules could print log during initiating
 	config.App = append([]*serial.TypedMessage{logConfMsg}, config.App...)
 
-	if c.RouterConfig != nil {
-		routerConfig, err := c.RouterConfig.Build()
+	forwardInbounds, forwardRules, err := buildForwarding(c.Forwarding, outbounds)
+	if err != nil {
+		return nil, err
+	}
+	rawRouterConfig := c.RouterConfig
+	if len(forwardRules) > 0 {
+		// The rules of forwarding go first, not to be shadowed by broader rules.
+		rc := RouterConfig{}
+		if rawRouterConfig != nil {
+			rc = *rawRouterConfig
+		}
+		rc.RuleList = append(forwardRules, rc.RuleList...)
+		rawRouterConfig = &rc
+	}
+
+	var routerConfig *router.Config
+	if rawRouterConfig != nil {
+		var err error
+		routerConfig, err = rawRouterConfig.Build()
 		if err != nil {
 			return nil, err
 		}
@@ -544,6 +988,14 @@ func (c *Config) Build() (*core.Config, error) {
 		if err != nil {
 			return nil, errors.New("failed to parse DNS config").Base(err)
 		}
+		for _, tag := range c.DNSConfig.outboundTags() {
+			if !hasOutbound(outbounds, tag) {
+				return nil, errors.New("outbound ", tag, " of DNS servers not found")
+			}
+		}
+		if dnsApp.Bootstrap != nil && len(dnsApp.Bootstrap.PrioritizedDomain) == 0 {
+			dnsApp.Bootstrap.PrioritizedDomain = bootstrapDomains(outbounds)
+		}
 		config.App = append(config.App, serial.ToTypedMessage(dnsApp))
 	}
 
@@ -571,27 +1023,91 @@ func (c *Config) Build() (*core.Config, error) {
 		config.App = append([]*serial.TypedMessage{serial.ToTypedMessage(r)}, config.App...)
 	}
 
-	if c.Observatory != nil {
-		r, err := c.Observatory.Build()
+	if len(c.Observatories) > 0 || len(c.BurstObservatories) > 0 {
+		observatories := c.Observatories
+		if c.Observatory != nil {
+			observatories = append([]*ObservatoryConfig{c.Observatory}, observatories...)
+		}
+		burstObservatories := c.BurstObservatories
+		if c.BurstObservatory != nil {
+			burstObservatories = append([]*BurstObservatoryConfig{c.BurstObservatory}, burstObservatories...)
+		}
+		r, err := buildObservatoryGroup(observatories, burstObservatories)
+		if err != nil {
+			return nil, err
+		}
+		config.App = append(config.App, serial.ToTypedMessage(r))
+	} else {
+		if c.Observatory != nil {
+			r, err := c.Observatory.Build()
+			if err != nil {
+				return nil, err
+			}
+			config.App = append(config.App, serial.ToTypedMessage(r))
+		}
+
+		if c.BurstObservatory != nil {
+			r, err := c.BurstObservatory.Build()
+			if err != nil {
+				return nil, err
+			}
+			config.App = append(config.App, serial.ToTypedMessage(r))
+		}
+	}
+
+	if c.FTP != nil {
+		r, err := c.FTP.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.App = append(config.App, serial.ToTypedMessage(r))
+	}
+
+	if c.Scheduler != nil {
+		r, err := c.Scheduler.Build()
 		if err != nil {
 			return nil, err
 		}
 		config.App = append(config.App, serial.ToTypedMessage(r))
 	}
 
-	if c.BurstObservatory != nil {
-		r, err := c.BurstObservatory.Build()
+	if c.Shaper != nil {
+		r, err := c.Shaper.Build()
 		if err != nil {
 			return nil, err
 		}
 		config.App = append(config.App, serial.ToTypedMessage(r))
 	}
 
+	if c.Webhook != nil {
+		r, err := c.Webhook.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.App = append(config.App, serial.ToTypedMessage(r))
+	}
+
+	if c.SubscriptionServer != nil {
+		r, err := c.SubscriptionServer.Build(c.InboundConfigs)
+		if err != nil {
+			return nil, errors.New("failed to build subscription server").Base(err)
+		}
+		config.App = append(config.App, serial.ToTypedMessage(r))
+	}
+
+	// The runtime is tuned to the limits of the process without a config too.
+	runtimeConfig, err := c.Runtime.Build()
+	if err != nil {
+		return nil, errors.New("failed to build runtime config").Base(err)
+	}
+	config.App = append(config.App, serial.ToTypedMessage(runtimeConfig))
+
 	var inbounds []InboundDetourConfig
 
 	if len(c.InboundConfigs) > 0 {
 		inbounds = append(inbounds, c.InboundConfigs...)
 	}
+	inbounds = append(inbounds, forwardInbounds...)
 
 	if len(c.Transport) > 0 {
 		return nil, errors.PrintRemovedFeatureError("Global transport config", "streamSettings in inbounds and outbounds")
@@ -605,12 +1121,6 @@ func (c *Config) Build() (*core.Config, error) {
 		config.Inbound = append(config.Inbound, ic)
 	}
 
-	var outbounds []OutboundDetourConfig
-
-	if len(c.OutboundConfigs) > 0 {
-		outbounds = append(outbounds, c.OutboundConfigs...)
-	}
-
 	for _, rawOutboundConfig := range outbounds {
 		oc, err := rawOutboundConfig.Build()
 		if err != nil {
@@ -619,6 +1129,10 @@ func (c *Config) Build() (*core.Config, error) {
 		config.Outbound = append(config.Outbound, oc)
 	}
 
+	if err := checkRoutingLoops(config, routerConfig); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
@@ -0,0 +1,204 @@
+//go:build !xray_client && !xray_minimal_router
+
+package conf_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/app/log"
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/app/tuner"
+	"github.com/xtls/xray-core/common"
+	clog "github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	core "github.com/xtls/xray-core/core"
+	. "github.com/xtls/xray-core/infra/conf"
+	"github.com/xtls/xray-core/proxy/vmess"
+	"github.com/xtls/xray-core/proxy/vmess/inbound"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/tls"
+	"github.com/xtls/xray-core/transport/internet/websocket"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestXrayConfig(t *testing.T) {
+	createParser := func() func(string) (proto.Message, error) {
+		return func(s string) (proto.Message, error) {
+			config := new(Config)
+			if err := json.Unmarshal([]byte(s), config); err != nil {
+				return nil, err
+			}
+			return config.Build()
+		}
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"log": {
+					"access": "/var/log/xray/access.log",
+					"loglevel": "error",
+					"error": "/var/log/xray/error.log"
+				},
+				"inbounds": [{
+					"streamSettings": {
+						"network": "ws",
+						"wsSettings": {
+							"host": "example.domain",
+							"path": ""
+						},
+						"tlsSettings": {
+							"alpn": "h2"
+						},
+						"security": "tls"
+					},
+					"protocol": "vmess",
+					"port": "443-500",
+					"allocate": {
+						"strategy": "random",
+						"concurrency": 3
+					},
+					"settings": {
+						"clients": [
+							{
+								"security": "aes-128-gcm",
+								"id": "0cdf8a45-303d-4fed-9780-29aa7f54175e"
+							}
+						]
+					}
+				}],
+				"routing": {
+					"rules": [
+						{
+							"ip": [
+								"10.0.0.0/8"
+							],
+							"type": "field",
+							"outboundTag": "blocked"
+						}
+					]
+				}
+			}`,
+			Parser: createParser(),
+			Output: &core.Config{
+				App: []*serial.TypedMessage{
+					serial.ToTypedMessage(&log.Config{
+						ErrorLogType:  log.LogType_File,
+						ErrorLogPath:  "/var/log/xray/error.log",
+						ErrorLogLevel: clog.Severity_Error,
+						AccessLogType: log.LogType_File,
+						AccessLogPath: "/var/log/xray/access.log",
+					}),
+					serial.ToTypedMessage(&dispatcher.Config{}),
+					serial.ToTypedMessage(&proxyman.InboundConfig{}),
+					serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+					serial.ToTypedMessage(&router.Config{
+						DomainStrategy: router.Config_AsIs,
+						Rule: []*router.RoutingRule{
+							{
+								Geoip: []*router.GeoIP{
+									{
+										Cidr: []*router.CIDR{
+											{
+												Ip:     []byte{10, 0, 0, 0},
+												Prefix: 8,
+											},
+										},
+									},
+								},
+								TargetTag: &router.RoutingRule_Tag{
+									Tag: "blocked",
+								},
+							},
+						},
+					}),
+					serial.ToTypedMessage(&tuner.Config{}),
+				},
+				Inbound: []*core.InboundHandlerConfig{
+					{
+						ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+							PortList: &net.PortList{Range: []*net.PortRange{{
+								From: 443,
+								To:   500,
+							}}},
+							AllocationStrategy: &proxyman.AllocationStrategy{
+								Type: proxyman.AllocationStrategy_Random,
+								Concurrency: &proxyman.AllocationStrategy_AllocationStrategyConcurrency{
+									Value: 3,
+								},
+							},
+							StreamSettings: &internet.StreamConfig{
+								ProtocolName: "websocket",
+								TransportSettings: []*internet.TransportConfig{
+									{
+										ProtocolName: "websocket",
+										Settings: serial.ToTypedMessage(&websocket.Config{
+											Host: "example.domain",
+										}),
+									},
+								},
+								SecurityType: "xray.transport.internet.tls.Config",
+								SecuritySettings: []*serial.TypedMessage{
+									serial.ToTypedMessage(&tls.Config{
+										NextProtocol: []string{"h2"},
+									}),
+								},
+							},
+						}),
+						ProxySettings: serial.ToTypedMessage(&inbound.Config{
+							User: []*protocol.User{
+								{
+									Level: 0,
+									Account: serial.ToTypedMessage(&vmess.Account{
+										Id: "0cdf8a45-303d-4fed-9780-29aa7f54175e",
+										SecuritySettings: &protocol.SecurityConfig{
+											Type: protocol.SecurityType_AES128_GCM,
+										},
+									}),
+								},
+							},
+						}),
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestConfig_NTP(t *testing.T) {
+	config := new(Config)
+	common.Must(json.Unmarshal([]byte(`{
+		"ntp": {"server": "time.example.com"},
+		"outbounds": [
+			{"protocol": "freedom"},
+			{"protocol": "vmess", "tag": "vmess", "ntp": {"server": "10.0.0.1:123", "interval": 600},
+				"settings": {"vnext": [{"address": "example.com", "port": 443, "users": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811"}]}]}}
+		]
+	}`), config))
+	c, err := config.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, app := range c.App {
+		m, _ := app.GetInstance()
+		if o, ok := m.(*proxyman.OutboundConfig); ok && (o.NtpServer != "time.example.com" || o.NtpInterval != 0) {
+			t.Error("unexpected global NTP: ", o)
+		}
+	}
+	sender, err := c.Outbound[1].SenderSettings.GetInstance()
+	common.Must(err)
+	if s := sender.(*proxyman.SenderConfig); s.NtpServer != "10.0.0.1:123" || s.NtpInterval != 600 {
+		t.Error("unexpected NTP of outbound: ", s)
+	}
+
+	config = new(Config)
+	common.Must(json.Unmarshal([]byte(`{"ntp": {"interval": 60}}`), config))
+	if _, err := config.Build(); err == nil {
+		t.Error("expected an error for NTP without a server")
+	}
+}
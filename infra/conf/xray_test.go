@@ -1,173 +1,24 @@
 package conf_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
-	"github.com/xtls/xray-core/app/dispatcher"
-	"github.com/xtls/xray-core/app/log"
+	"github.com/xtls/xray-core/app/httpapi"
 	"github.com/xtls/xray-core/app/proxyman"
 	"github.com/xtls/xray-core/app/router"
 	"github.com/xtls/xray-core/common"
-	clog "github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/common/net"
-	"github.com/xtls/xray-core/common/protocol"
-	"github.com/xtls/xray-core/common/serial"
 	core "github.com/xtls/xray-core/core"
 	. "github.com/xtls/xray-core/infra/conf"
-	"github.com/xtls/xray-core/proxy/vmess"
-	"github.com/xtls/xray-core/proxy/vmess/inbound"
-	"github.com/xtls/xray-core/transport/internet"
-	"github.com/xtls/xray-core/transport/internet/tls"
-	"github.com/xtls/xray-core/transport/internet/websocket"
+	"github.com/xtls/xray-core/proxy/dokodemo"
 	"google.golang.org/protobuf/proto"
 )
 
-func TestXrayConfig(t *testing.T) {
-	createParser := func() func(string) (proto.Message, error) {
-		return func(s string) (proto.Message, error) {
-			config := new(Config)
-			if err := json.Unmarshal([]byte(s), config); err != nil {
-				return nil, err
-			}
-			return config.Build()
-		}
-	}
-
-	runMultiTestCase(t, []TestCase{
-		{
-			Input: `{
-				"log": {
-					"access": "/var/log/xray/access.log",
-					"loglevel": "error",
-					"error": "/var/log/xray/error.log"
-				},
-				"inbounds": [{
-					"streamSettings": {
-						"network": "ws",
-						"wsSettings": {
-							"host": "example.domain",
-							"path": ""
-						},
-						"tlsSettings": {
-							"alpn": "h2"
-						},
-						"security": "tls"
-					},
-					"protocol": "vmess",
-					"port": "443-500",
-					"allocate": {
-						"strategy": "random",
-						"concurrency": 3
-					},
-					"settings": {
-						"clients": [
-							{
-								"security": "aes-128-gcm",
-								"id": "0cdf8a45-303d-4fed-9780-29aa7f54175e"
-							}
-						]
-					}
-				}],
-				"routing": {
-					"rules": [
-						{
-							"ip": [
-								"10.0.0.0/8"
-							],
-							"type": "field",
-							"outboundTag": "blocked"
-						}
-					]
-				}
-			}`,
-			Parser: createParser(),
-			Output: &core.Config{
-				App: []*serial.TypedMessage{
-					serial.ToTypedMessage(&log.Config{
-						ErrorLogType:  log.LogType_File,
-						ErrorLogPath:  "/var/log/xray/error.log",
-						ErrorLogLevel: clog.Severity_Error,
-						AccessLogType: log.LogType_File,
-						AccessLogPath: "/var/log/xray/access.log",
-					}),
-					serial.ToTypedMessage(&dispatcher.Config{}),
-					serial.ToTypedMessage(&proxyman.InboundConfig{}),
-					serial.ToTypedMessage(&proxyman.OutboundConfig{}),
-					serial.ToTypedMessage(&router.Config{
-						DomainStrategy: router.Config_AsIs,
-						Rule: []*router.RoutingRule{
-							{
-								Geoip: []*router.GeoIP{
-									{
-										Cidr: []*router.CIDR{
-											{
-												Ip:     []byte{10, 0, 0, 0},
-												Prefix: 8,
-											},
-										},
-									},
-								},
-								TargetTag: &router.RoutingRule_Tag{
-									Tag: "blocked",
-								},
-							},
-						},
-					}),
-				},
-				Inbound: []*core.InboundHandlerConfig{
-					{
-						ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
-							PortList: &net.PortList{Range: []*net.PortRange{{
-								From: 443,
-								To:   500,
-							}}},
-							AllocationStrategy: &proxyman.AllocationStrategy{
-								Type: proxyman.AllocationStrategy_Random,
-								Concurrency: &proxyman.AllocationStrategy_AllocationStrategyConcurrency{
-									Value: 3,
-								},
-							},
-							StreamSettings: &internet.StreamConfig{
-								ProtocolName: "websocket",
-								TransportSettings: []*internet.TransportConfig{
-									{
-										ProtocolName: "websocket",
-										Settings: serial.ToTypedMessage(&websocket.Config{
-											Host: "example.domain",
-										}),
-									},
-								},
-								SecurityType: "xray.transport.internet.tls.Config",
-								SecuritySettings: []*serial.TypedMessage{
-									serial.ToTypedMessage(&tls.Config{
-										NextProtocol: []string{"h2"},
-									}),
-								},
-							},
-						}),
-						ProxySettings: serial.ToTypedMessage(&inbound.Config{
-							User: []*protocol.User{
-								{
-									Level: 0,
-									Account: serial.ToTypedMessage(&vmess.Account{
-										Id: "0cdf8a45-303d-4fed-9780-29aa7f54175e",
-										SecuritySettings: &protocol.SecurityConfig{
-											Type: protocol.SecurityType_AES128_GCM,
-										},
-									}),
-								},
-							},
-						}),
-					},
-				},
-			},
-		},
-	})
-}
-
 func TestMuxConfig_Build(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -198,6 +49,20 @@ func TestMuxConfig_Build(t *testing.T) {
 			XudpConcurrency: 0,
 			XudpProxyUDP443: "reject",
 		}},
+		{"xudp key", `{"xudpKey": "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE="}`, &proxyman.MultiplexingConfig{
+			XudpProxyUDP443: "reject",
+			XudpKey:         bytes.Repeat([]byte{1}, 32),
+		}},
+		{"short xudp key", `{"xudpKey": "AQEB"}`, nil},
+		{"exclude", `{"enabled": true, "exclude": [{"network": "udp"}, {"domain": ["domain:speedtest.net"]}]}`, &proxyman.MultiplexingConfig{
+			Enabled:         true,
+			XudpProxyUDP443: "reject",
+			Exclude: []*router.RoutingRule{
+				{Networks: []net.Network{net.Network_UDP}},
+				{Domain: []*router.Domain{{Type: router.Domain_Domain, Value: "speedtest.net"}}},
+			},
+		}},
+		{"invalid exclude", `{"exclude": [{"ip": ["not an ip"]}]}`, nil},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -210,6 +75,94 @@ func TestMuxConfig_Build(t *testing.T) {
 	}
 }
 
+func TestStandbyConfig_Build(t *testing.T) {
+	tests := []struct {
+		name   string
+		tag    string
+		fields string
+		want   *proxyman.StandbyConfig
+	}{
+		{"default", "backup", `{"for": "main"}`, &proxyman.StandbyConfig{
+			Tag:     "backup",
+			Primary: "main",
+		}},
+		{"probe", "backup", `{"for": "main", "probeURL": "https://example.com", "interval": "5s", "timeout": "2s"}`, &proxyman.StandbyConfig{
+			Tag:           "backup",
+			Primary:       "main",
+			ProbeUrl:      "https://example.com",
+			ProbeInterval: int64(5 * time.Second),
+			ProbeTimeout:  int64(2 * time.Second),
+		}},
+		{"no tag", "", `{"for": "main"}`, nil},
+		{"self", "main", `{"for": "main"}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &StandbyConfig{}
+			common.Must(json.Unmarshal([]byte(tt.fields), c))
+			got, err := c.Build(tt.tag)
+			if tt.want == nil {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("StandbyConfig.Build() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSniffingConfig_Build(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields string
+		want   *proxyman.SniffingConfig
+	}{
+		{"limits", `{"enabled": true, "timeout": "300ms", "maxBytes": 4096}`, &proxyman.SniffingConfig{
+			Enabled:  true,
+			Timeout:  int64(300 * time.Millisecond),
+			MaxBytes: 4096,
+		}},
+		{"buffer size", `{"enabled": true, "maxBytes": 8192}`, &proxyman.SniffingConfig{
+			Enabled:  true,
+			MaxBytes: 8192,
+		}},
+		{"maxBytes above buffer size", `{"enabled": true, "maxBytes": 8193}`, nil},
+		{"negative timeout", `{"enabled": true, "timeout": "-1s"}`, nil},
+		{"exclusions", `{"enabled": true, "domainsExcluded": ["Example.COM", "domain:cdn.net"],
+			"protocolDomainsExcluded": {"quic": ["keyword:game"], "https": ["full:pinned.example.net"]}}`, &proxyman.SniffingConfig{
+			Enabled:         true,
+			DomainsExcluded: []string{"example.com", "domain:cdn.net"},
+			ProtocolDomainsExcluded: []*proxyman.SniffingExclusion{
+				{Protocol: "quic", Domains: []string{"keyword:game"}},
+				{Protocol: "tls", Domains: []string{"full:pinned.example.net"}},
+			},
+		}},
+		{"exclusions of unknown protocol", `{"enabled": true, "protocolDomainsExcluded": {"ftp": ["example.com"]}}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &SniffingConfig{}
+			common.Must(json.Unmarshal([]byte(tt.fields), c))
+			got, err := c.Build()
+			if tt.want == nil {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("SniffingConfig.Build() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestConfig_Override(t *testing.T) {
 	tests := []struct {
 		name string
@@ -299,3 +252,186 @@ func TestConfig_Override(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_RoutingLoop(t *testing.T) {
+	build := func(routing string) error {
+		config := new(Config)
+		common.Must(json.Unmarshal([]byte(`{
+			"inbounds": [{
+				"tag": "in",
+				"listen": "127.0.0.1",
+				"port": 1080,
+				"protocol": "socks"
+			}],
+			"outbounds": [{
+				"tag": "out",
+				"protocol": "socks",
+				"settings": {
+					"servers": [{"address": "127.0.0.1", "port": 1080}]
+				}
+			}, {
+				"tag": "direct",
+				"protocol": "freedom"
+			}],
+			"routing": `+routing+`
+		}`), config))
+		_, err := config.Build()
+		return err
+	}
+
+	if err := build(`{}`); err == nil {
+		t.Error("expected a routing loop through the default outbound")
+	}
+	if err := build(`{"rules": [{"inboundTag": ["in"], "outboundTag": "out"}]}`); err == nil {
+		t.Error("expected a routing loop through a rule")
+	}
+	if err := build(`{"rules": [{"inboundTag": ["in"], "outboundTag": "direct"}]}`); err != nil {
+		t.Error("unexpected error: ", err)
+	}
+	if err := build(`{"rules": [{"inboundTag": ["in"], "port": 443, "outboundTag": "direct"}]}`); err == nil {
+		t.Error("expected a routing loop for the rest of the traffic")
+	}
+}
+
+func TestConfig_DNSOutboundTag(t *testing.T) {
+	build := func(dns string) error {
+		config := new(Config)
+		common.Must(json.Unmarshal([]byte(`{
+			"outbounds": [{
+				"tag": "direct",
+				"protocol": "freedom"
+			}],
+			"dns": `+dns+`
+		}`), config))
+		_, err := config.Build()
+		return err
+	}
+
+	if err := build(`{"servers": [{"address": "1.1.1.1", "outboundTag": "direct"}]}`); err != nil {
+		t.Error("unexpected error: ", err)
+	}
+	if err := build(`{"servers": [{"address": "1.1.1.1", "outboundTag": "proxy"}]}`); err == nil {
+		t.Error("expected an unknown outbound")
+	}
+	if err := build(`{"regions": [{"domains": ["domain:example.cn"], "outboundTag": "proxy", "servers": ["8.8.8.8"]}]}`); err == nil {
+		t.Error("expected an unknown outbound of a region")
+	}
+}
+
+func TestConfig_HTTPAPI(t *testing.T) {
+	build := func(api string) (*httpapi.Config, error) {
+		config := new(Config)
+		common.Must(json.Unmarshal([]byte(`{"httpApi": `+api+`}`), config))
+		c, err := config.Build()
+		if err != nil {
+			return nil, err
+		}
+		for _, app := range c.App {
+			if m, err := app.GetInstance(); err == nil {
+				if api, ok := m.(*httpapi.Config); ok {
+					return api, nil
+				}
+			}
+		}
+		t.Fatal("no HTTP API in config")
+		return nil, nil
+	}
+
+	api, err := build(`{"secret": "s", "allowOrigins": ["*"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api.Listen != "127.0.0.1:9090" || api.Secret != "s" || len(api.AllowOrigins) != 1 {
+		t.Error("unexpected config: ", api)
+	}
+	if _, err := build(`{"listen": "[::1]:9090"}`); err != nil {
+		t.Error("unexpected error: ", err)
+	}
+	if _, err := build(`{"listen": "0.0.0.0:9090"}`); err == nil {
+		t.Error("expected an error for a public address without secret")
+	}
+	if _, err := build(`{"listen": "0.0.0.0:9090", "secret": "s"}`); err != nil {
+		t.Error("unexpected error: ", err)
+	}
+}
+
+func TestConfig_MPTCP(t *testing.T) {
+	config := new(Config)
+	common.Must(json.Unmarshal([]byte(`{
+		"inbounds": [{
+			"port": 1080,
+			"protocol": "socks",
+			"mptcp": true
+		}],
+		"outbounds": [{
+			"protocol": "freedom",
+			"mptcp": true,
+			"streamSettings": {"sockopt": {"mark": 255}}
+		}]
+	}`), config))
+	c, err := config.Build()
+	common.Must(err)
+
+	receiver, err := c.Inbound[0].ReceiverSettings.GetInstance()
+	common.Must(err)
+	if !receiver.(*proxyman.ReceiverConfig).StreamSettings.GetSocketSettings().GetTcpMptcp() {
+		t.Error("MPTCP not enabled on inbound")
+	}
+	sender, err := c.Outbound[0].SenderSettings.GetInstance()
+	common.Must(err)
+	sockopt := sender.(*proxyman.SenderConfig).StreamSettings.GetSocketSettings()
+	if !sockopt.GetTcpMptcp() || sockopt.GetMark() != 255 {
+		t.Error("unexpected sockopt of outbound: ", sockopt)
+	}
+}
+
+func TestConfig_Forwarding(t *testing.T) {
+	build := func(forwarding string) (*core.Config, error) {
+		config := new(Config)
+		common.Must(json.Unmarshal([]byte(`{
+			"outbounds": [{"protocol": "freedom"}, {"tag": "proxy", "protocol": "blackhole"}],
+			"routing": {"rules": [{"network": "tcp,udp", "outboundTag": "direct"}]},
+			"forwarding": `+forwarding+`
+		}`), config))
+		return config.Build()
+	}
+
+	c, err := build(`[
+		{"listen": 2222, "destination": "example.com:22", "outboundTag": "proxy"},
+		{"listen": "0.0.0.0:5353", "destination": "1.1.1.1:53", "network": "udp", "tag": "dns"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Inbound) != 2 || c.Inbound[0].Tag != "forwarding-0" || c.Inbound[1].Tag != "dns" {
+		t.Fatal("unexpected inbounds: ", c.Inbound)
+	}
+	receiver, err := c.Inbound[0].ReceiverSettings.GetInstance()
+	common.Must(err)
+	if r := receiver.(*proxyman.ReceiverConfig); r.Listen.AsAddress() != net.LocalHostIP || r.PortList.Range[0].From != 2222 {
+		t.Error("unexpected receiver: ", r)
+	}
+	proxy, err := c.Inbound[1].ProxySettings.GetInstance()
+	common.Must(err)
+	if d := proxy.(*dokodemo.Config); d.Port != 53 || d.Address.AsAddress().String() != "1.1.1.1" || len(d.Networks) != 1 || d.Networks[0] != net.Network_UDP {
+		t.Error("unexpected settings: ", d)
+	}
+	for _, app := range c.App {
+		m, _ := app.GetInstance()
+		if r, ok := m.(*router.Config); ok {
+			if len(r.Rule) != 2 || r.Rule[0].InboundTag[0] != "forwarding-0" || r.Rule[0].GetTag() != "proxy" {
+				t.Error("unexpected rules: ", r.Rule)
+			}
+		}
+	}
+
+	for _, forwarding := range []string{
+		`[{"listen": 2222, "destination": "example.com:22", "outboundTag": "unknown"}]`,
+		`[{"listen": 2222, "destination": "example.com"}]`,
+		`[{"listen": "localhost", "destination": "example.com:22"}]`,
+	} {
+		if _, err := build(forwarding); err == nil {
+			t.Error("expected an error for ", forwarding)
+		}
+	}
+}
@@ -12,6 +12,7 @@ var CmdAPI = &base.Command{
 `,
 	Commands: []*base.Command{
 		cmdRestartLogger,
+		cmdFollowLog,
 		cmdGetStats,
 		cmdQueryStats,
 		cmdSysStats,
@@ -20,11 +21,25 @@ var CmdAPI = &base.Command{
 		cmdAddInbounds,
 		cmdAddOutbounds,
 		cmdRemoveInbounds,
+		cmdFailedInbounds,
+		cmdInboundAddresses,
+		cmdUDPSessions,
+		cmdFlushUDPSessions,
+		cmdIdleConnections,
+		cmdFeatures,
 		cmdRemoveOutbounds,
+		cmdDrainingOutbounds,
+		cmdOutboundMaintenance,
+		cmdSetOutboundMaintenance,
+		cmdOutboundChain,
 		cmdInboundUser,
 		cmdInboundUserCount,
 		cmdAddRules,
 		cmdRemoveRules,
+		cmdRuleGroups,
+		cmdSetRuleGroup,
+		cmdRuleStats,
+		cmdUpdateGeodata,
 		cmdSourceIpBlock,
 		cmdOnlineStats,
 		cmdOnlineStatsIpList,
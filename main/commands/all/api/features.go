@@ -0,0 +1,47 @@
+package api
+
+import (
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdFeatures = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api features [--server=127.0.0.1:8080]",
+	Short:       "List features compiled in and configured",
+	Long: `
+List the protocols, transports, security types and apps compiled into a
+running Xray, and those its config uses. Protocols and apps are named by
+the types of their configs, as in "xray.proxy.vless.inbound.Config is not
+registered" errors of builds without them. Handlers added through the API
+are not listed as configured.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080
+`,
+	Run: executeFeatures,
+}
+
+func executeFeatures(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	resp, err := client.ListFeatures(ctx, &handlerService.ListFeaturesRequest{})
+	if err != nil {
+		base.Fatalf("failed to list features: %s", err)
+	}
+	showJSONResponse(resp)
+}
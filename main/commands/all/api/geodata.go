@@ -0,0 +1,84 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+
+	routerService "github.com/xtls/xray-core/app/router/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdUpdateGeodata = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api geodata [--server=127.0.0.1:8080] [-file=geoip.dat] <file.dat | -url=url>",
+	Short:       "Replace geo data of a running Xray",
+	Long: `
+Replace a geo data file, like geoip.dat or geosite.dat, of a running Xray
+with a local file, or with the file Xray downloads from a URL, then reload
+its config so that routing rules match with the new lists.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3, which
+		reloading large lists may take longer than.
+
+	-file
+		Name of the geo data file to replace. Default the name of the
+		local file, or of the file in the URL
+
+	-url
+		URL Xray downloads the file from, instead of a local file
+
+	-sha256
+		Hex SHA-256 checksum the new file must have
+
+Example:
+
+	{{.Exec}} {{.LongName}} -t 60 geosite.dat
+	{{.Exec}} {{.LongName}} -t 60 -file=geoip.dat -url=https://example.com/geoip.dat
+`,
+	Run: executeUpdateGeodata,
+}
+
+func executeUpdateGeodata(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	var file, url, sum string
+	cmd.Flag.StringVar(&file, "file", "", "")
+	cmd.Flag.StringVar(&url, "url", "", "")
+	cmd.Flag.StringVar(&sum, "sha256", "", "")
+	cmd.Flag.Parse(args)
+
+	request := &routerService.UpdateGeodataRequest{Url: url, Sha256: sum}
+	switch {
+	case url != "" && cmd.Flag.NArg() == 0:
+		if file == "" {
+			file = filepath.Base(url)
+		}
+	case url == "" && cmd.Flag.NArg() == 1:
+		data, err := os.ReadFile(cmd.Flag.Arg(0))
+		if err != nil {
+			base.Fatalf("failed to read %s: %s", cmd.Flag.Arg(0), err)
+		}
+		request.Data = data
+		if file == "" {
+			file = filepath.Base(cmd.Flag.Arg(0))
+		}
+	default:
+		base.Fatalf("specify either a local file or -url")
+	}
+	request.File = file
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := routerService.NewRoutingServiceClient(conn)
+	resp, err := client.UpdateGeodata(ctx, request)
+	if err != nil {
+		base.Fatalf("failed to update geo data: %s", err)
+	}
+	showJSONResponse(resp)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdIdleConnections = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api lsidle [--server=127.0.0.1:8080] [-tag=tag] [-idle=seconds]",
+	Short:       "List idle connections of inbounds",
+	Long: `
+List the connections in progress through inbounds which have had no
+activity for a while, with the seconds since they were accepted, since
+their last activity, and of inactivity after which they are closed.
+Connections idle beyond their limit are closed by a periodic sweep, which
+logs each of them. The session of each connection is the ID prefixing the
+log lines about it, such as "[1234567890]", for finding them with -idle=0.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-tag
+		Inbound tag. Lists the connections of all inbounds if not set.
+
+	-idle
+		Only list connections idle for at least this many seconds.
+		Default 60
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag="tag name" -idle=300
+`,
+	Run: executeIdleConnections,
+}
+
+func executeIdleConnections(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	var tag string
+	var idle int64
+	cmd.Flag.StringVar(&tag, "tag", "", "")
+	cmd.Flag.Int64Var(&idle, "idle", 60, "")
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	resp, err := client.ListIdleConnections(ctx, &handlerService.ListIdleConnectionsRequest{Tag: tag, MinIdle: idle})
+	if err != nil {
+		base.Fatalf("failed to list idle connections: %s", err)
+	}
+	showJSONResponse(resp)
+}
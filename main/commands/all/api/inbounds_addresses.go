@@ -0,0 +1,49 @@
+package api
+
+import (
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdInboundAddresses = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api lsia [--server=127.0.0.1:8080] [-tag=tag]",
+	Short:       "List addresses of inbounds",
+	Long: `
+List the addresses and ports inbounds listen on, including the ports
+allocated at startup for inbounds configured with port 0.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-tag
+		Inbound tag. Lists the addresses of all inbounds if not set.
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag="tag name"
+`,
+	Run: executeInboundAddresses,
+}
+
+func executeInboundAddresses(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	var tag string
+	cmd.Flag.StringVar(&tag, "tag", "", "")
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	resp, err := client.ListInboundAddresses(ctx, &handlerService.ListInboundAddressesRequest{Tag: tag})
+	if err != nil {
+		base.Fatalf("failed to list inbound addresses: %s", err)
+	}
+	showJSONResponse(resp)
+}
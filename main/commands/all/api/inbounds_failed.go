@@ -0,0 +1,44 @@
+package api
+
+import (
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdFailedInbounds = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api lsfi [--server=127.0.0.1:8080]",
+	Short:       "List inbounds failed to start",
+	Long: `
+List inbounds which failed to start and were skipped, when Xray runs
+with "tolerateInboundErrors" or the -partial flag.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080
+`,
+	Run: executeFailedInbounds,
+}
+
+func executeFailedInbounds(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	resp, err := client.ListFailedInbounds(ctx, &handlerService.ListFailedInboundsRequest{})
+	if err != nil {
+		base.Fatalf("failed to list failed inbounds: %s", err)
+	}
+	showJSONResponse(resp)
+}
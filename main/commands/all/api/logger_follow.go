@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	logService "github.com/xtls/xray-core/app/log/command"
+	clog "github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdFollowLog = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api followlog [--server=127.0.0.1:8080] [-level warning] [-type access,error,dns] [-filter '']",
+	Short:       "Follow the log",
+	Long: `
+Print the entries Xray logs from now on, until interrupted. Entries are
+streamed whether the logger writes them to its files or not.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for connecting to the API. Default 3
+
+	-json
+		Print the entries as JSON, with their types, levels and times.
+
+	-level <level>
+		Least severe level of error log entries to print: error, warning,
+		info or debug. Default debug
+
+	-type <types>
+		Comma-separated types of entries to print: access, error and dns.
+		Default all of them
+
+	-filter <substring>
+		Only print entries containing the substring.
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -level warning -type error
+`,
+	Run: executeFollowLog,
+}
+
+func executeFollowLog(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	level := cmd.Flag.String("level", "debug", "")
+	types := cmd.Flag.String("type", "", "")
+	filter := cmd.Flag.String("filter", "", "")
+	cmd.Flag.Parse(args)
+
+	r := &logService.FollowLogRequest{
+		Filter: *filter,
+	}
+	switch strings.ToLower(*level) {
+	case "error":
+		r.Level = clog.Severity_Error
+	case "warning":
+		r.Level = clog.Severity_Warning
+	case "info":
+		r.Level = clog.Severity_Info
+	case "debug", "":
+		r.Level = clog.Severity_Debug
+	default:
+		base.Fatalf("unknown log level: %s", *level)
+	}
+	if *types != "" {
+		for _, t := range strings.Split(*types, ",") {
+			switch strings.ToLower(strings.TrimSpace(t)) {
+			case "access":
+				r.Type = append(r.Type, logService.LogType_Access)
+			case "error":
+				r.Type = append(r.Type, logService.LogType_Error)
+			case "dns":
+				r.Type = append(r.Type, logService.LogType_DNS)
+			default:
+				base.Fatalf("unknown log type: %s", t)
+			}
+		}
+	}
+
+	conn, _, close := dialAPIServer()
+	defer close()
+
+	// The timeout is for connecting only, the stream lasts until interrupted.
+	client := logService.NewLoggerServiceClient(conn)
+	stream, err := client.FollowLog(context.Background(), r)
+	if err != nil {
+		base.Fatalf("failed to follow log: %s", err)
+	}
+	for {
+		entry, err := stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			base.Fatalf("failed to follow log: %s", err)
+		}
+		if apiJSON {
+			showJSONResponse(entry)
+			continue
+		}
+		if entry.Dropped > 0 {
+			fmt.Printf("(%d entries dropped)\n", entry.Dropped)
+		}
+		fmt.Println(entry.Message)
+	}
+}
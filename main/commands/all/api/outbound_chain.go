@@ -0,0 +1,58 @@
+package api
+
+import (
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdOutboundChain = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api obchain [--server=127.0.0.1:8080] -tag=tag",
+	Short:       "Show the chain of an outbound",
+	Long: `
+Show the outbounds an outbound sends its connections through, by
+"proxySettings" or "dialerProxy", with the health of each hop.
+
+Failures of a hop show in the hops before it too, so the last failing
+hop of the chain is the one to look at.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-tag
+		Tag of the outbound
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag=chained
+`,
+	Run: executeOutboundChain,
+}
+
+func executeOutboundChain(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	var tag string
+	cmd.Flag.StringVar(&tag, "tag", "", "")
+	cmd.Flag.Parse(args)
+
+	if tag == "" {
+		base.Fatalf("no outbound tag specified")
+	}
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	resp, err := client.GetOutboundChain(ctx, &handlerService.GetOutboundChainRequest{
+		Tag: tag,
+	})
+	if err != nil {
+		base.Fatalf("failed to get outbound chain: %s", err)
+	}
+	showJSONResponse(resp)
+}
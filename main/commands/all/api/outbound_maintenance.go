@@ -0,0 +1,100 @@
+package api
+
+import (
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdOutboundMaintenance = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api lsmaint [--server=127.0.0.1:8080]",
+	Short:       "List outbounds in maintenance",
+	Long: `
+List outbounds which are administratively down.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080
+`,
+	Run: executeOutboundMaintenance,
+}
+
+func executeOutboundMaintenance(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	resp, err := client.ListOutboundMaintenance(ctx, &handlerService.ListOutboundMaintenanceRequest{})
+	if err != nil {
+		base.Fatalf("failed to list outbounds in maintenance: %s", err)
+	}
+	showJSONResponse(resp)
+}
+
+var cmdSetOutboundMaintenance = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api maint [--server=127.0.0.1:8080] -tag=tag [-down=false]",
+	Short:       "Put an outbound in or out of maintenance",
+	Long: `
+Mark an outbound as administratively down or up. Balancers skip outbounds
+in maintenance regardless of health checks, and observatory results show
+them as not alive.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-tag
+		Tag of the outbound
+
+	-down
+		Whether the outbound is in maintenance. Default true
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag=proxy-1
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag=proxy-1 -down=false
+`,
+	Run: executeSetOutboundMaintenance,
+}
+
+func executeSetOutboundMaintenance(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	var tag string
+	var down bool
+	cmd.Flag.StringVar(&tag, "tag", "", "")
+	cmd.Flag.BoolVar(&down, "down", true, "")
+	cmd.Flag.Parse(args)
+
+	if tag == "" {
+		base.Fatalf("no outbound tag specified")
+	}
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	resp, err := client.SetOutboundMaintenance(ctx, &handlerService.SetOutboundMaintenanceRequest{
+		Tag:         tag,
+		Maintenance: down,
+	})
+	if err != nil {
+		base.Fatalf("failed to set outbound maintenance: %s", err)
+	}
+	showJSONResponse(resp)
+}
@@ -10,7 +10,7 @@ import (
 
 var cmdRemoveOutbounds = &base.Command{
 	CustomFlags: true,
-	UsageLine:   "{{.Exec}} api rmo [--server=127.0.0.1:8080] <json_file|tag> [json_file] [tag]...",
+	UsageLine:   "{{.Exec}} api rmo [--server=127.0.0.1:8080] [-drain=seconds] <json_file|tag> [json_file] [tag]...",
 	Short:       "Remove outbounds",
 	Long: `
 Remove outbounds from Xray.
@@ -23,15 +23,24 @@ Arguments:
 	-t, -timeout <seconds>
 		Timeout in seconds for calling API. Default 3
 
+	-drain <seconds>
+		Drain the outbounds instead of removing them at once: they're no
+		longer used for new connections, and closed once their connections
+		finish, or after the given seconds. Outbounds with the same tags
+		can be added meanwhile, to replace them without breaking transfers.
+
 Example:
 
 	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 c1.json "tag name"
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -drain=300 "tag name"
 `,
 	Run: executeRemoveOutbounds,
 }
 
 func executeRemoveOutbounds(cmd *base.Command, args []string) {
 	setSharedFlags(cmd)
+	var drain uint
+	cmd.Flag.UintVar(&drain, "drain", 0, "")
 	cmd.Flag.Parse(args)
 	unnamedArgs := cmd.Flag.Args()
 	if len(unnamedArgs) == 0 {
@@ -67,7 +76,8 @@ func executeRemoveOutbounds(cmd *base.Command, args []string) {
 	for _, tag := range tags {
 		fmt.Println("removing:", tag)
 		r := &handlerService.RemoveOutboundRequest{
-			Tag: tag,
+			Tag:          tag,
+			DrainTimeout: uint32(drain),
 		}
 		resp, err := client.RemoveOutbound(ctx, r)
 		if err != nil {
@@ -76,3 +86,41 @@ func executeRemoveOutbounds(cmd *base.Command, args []string) {
 		showJSONResponse(resp)
 	}
 }
+
+var cmdDrainingOutbounds = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api lsdrain [--server=127.0.0.1:8080]",
+	Short:       "List draining outbounds",
+	Long: `
+List outbounds removed with -drain which still have connections going
+through them, with the time they're closed at regardless.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080
+`,
+	Run: executeDrainingOutbounds,
+}
+
+func executeDrainingOutbounds(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	resp, err := client.ListDrainingOutbounds(ctx, &handlerService.ListDrainingOutboundsRequest{})
+	if err != nil {
+		base.Fatalf("failed to list draining outbounds: %s", err)
+	}
+	showJSONResponse(resp)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	routerService "github.com/xtls/xray-core/app/router/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdRuleGroups = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api lsrg [--server=127.0.0.1:8080]",
+	Short:       "List routing rule groups",
+	Long: `
+List routing rule groups, whether they are enabled and how many rules
+they contain.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080
+`,
+	Run: executeRuleGroups,
+}
+
+func executeRuleGroups(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := routerService.NewRoutingServiceClient(conn)
+	resp, err := client.ListRuleGroups(ctx, &routerService.ListRuleGroupsRequest{})
+	if err != nil {
+		base.Fatalf("failed to list rule groups: %s", err)
+	}
+	showJSONResponse(resp)
+}
+
+var cmdSetRuleGroup = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api setrg [--server=127.0.0.1:8080] -group=name [-enabled=false]",
+	Short:       "Enable or disable a routing rule group",
+	Long: `
+Enable or disable all routing rules of a group. Rules of a disabled group
+are skipped until the group is enabled again.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-group
+		Name of the rule group
+
+	-enabled
+		Whether the group is enabled. Default true
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -group=ads -enabled=false
+`,
+	Run: executeSetRuleGroup,
+}
+
+func executeSetRuleGroup(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	var group string
+	var enabled bool
+	cmd.Flag.StringVar(&group, "group", "", "")
+	cmd.Flag.BoolVar(&enabled, "enabled", true, "")
+	cmd.Flag.Parse(args)
+
+	if group == "" {
+		base.Fatalf("no rule group specified")
+	}
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := routerService.NewRoutingServiceClient(conn)
+	resp, err := client.SetRuleGroup(ctx, &routerService.SetRuleGroupRequest{
+		Group:   group,
+		Enabled: enabled,
+	})
+	if err != nil {
+		base.Fatalf("failed to set rule group: %s", err)
+	}
+	showJSONResponse(resp)
+}
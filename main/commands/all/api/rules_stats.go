@@ -0,0 +1,102 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	statsService "github.com/xtls/xray-core/app/stats/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdRuleStats = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api rulestats [--server=127.0.0.1:8080]",
+	Short:       "Show the connections and traffic of routing rules",
+	Long: `
+Show a table of the routing rules with tags, with the connections each
+routed and their uplink and downlink bytes, the busiest rules first.
+Rules which never matched are listed last, with no connections. Rules
+are counted with "statsRule" in the system policy. The counters are
+"rule>>>[ruleTag]>>>connections" and "rule>>>[ruleTag]>>>traffic>>>uplink"
+or "...>>>downlink", which "api statsquery" can also reset.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080
+`,
+	Run: executeRuleStats,
+}
+
+type ruleStats struct {
+	tag                           string
+	connections, uplink, downlink int64
+}
+
+func executeRuleStats(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := statsService.NewStatsServiceClient(conn)
+	resp, err := client.QueryStats(ctx, &statsService.QueryStatsRequest{Pattern: "rule>>>"})
+	if err != nil {
+		base.Fatalf("failed to query stats: %s", err)
+	}
+
+	rules := make(map[string]*ruleStats)
+	for _, stat := range resp.Stat {
+		rest, found := strings.CutPrefix(stat.Name, "rule>>>")
+		if !found {
+			continue
+		}
+		tag, name, _ := strings.Cut(rest, ">>>")
+		r := rules[tag]
+		if r == nil {
+			r = &ruleStats{tag: tag}
+		}
+		switch name {
+		case "connections":
+			r.connections = stat.Value
+		case "traffic>>>uplink":
+			r.uplink = stat.Value
+		case "traffic>>>downlink":
+			r.downlink = stat.Value
+		default:
+			// Such as the memory of the matchers of the rule.
+			continue
+		}
+		rules[tag] = r
+	}
+	if len(rules) == 0 {
+		fmt.Println("No routing rules are counted, set statsRule in the system policy and tag the rules.")
+		return
+	}
+
+	sorted := make([]*ruleStats, 0, len(rules))
+	width := len("RULE")
+	for _, r := range rules {
+		sorted = append(sorted, r)
+		width = max(width, len(r.tag))
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].connections != sorted[j].connections {
+			return sorted[i].connections > sorted[j].connections
+		}
+		return sorted[i].tag < sorted[j].tag
+	})
+	fmt.Printf("%-*s %12s %16s %16s\n", width, "RULE", "CONNECTIONS", "UPLINK", "DOWNLINK")
+	for _, r := range sorted {
+		fmt.Printf("%-*s %12d %16d %16d\n", width, r.tag, r.connections, r.uplink, r.downlink)
+	}
+}
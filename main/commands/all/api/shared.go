@@ -13,8 +13,7 @@ import (
 	"strings"
 	"time"
 
-	"google.golang.org/grpc/credentials/insecure"
-
+	"github.com/xtls/xray-core/app/commander/apiclient"
 	"github.com/xtls/xray-core/common/buf"
 	creflect "github.com/xtls/xray-core/common/reflect"
 	"github.com/xtls/xray-core/main/commands/base"
@@ -39,14 +38,16 @@ func setSharedFlags(cmd *base.Command) {
 }
 
 func dialAPIServer() (conn *grpc.ClientConn, ctx context.Context, close func()) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(apiTimeout)*time.Second)
-	conn, err := grpc.DialContext(ctx, apiServerAddrPtr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	timeout := time.Duration(apiTimeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	client, err := apiclient.Dial(ctx, apiServerAddrPtr, apiclient.WithTimeout(timeout))
 	if err != nil {
 		base.Fatalf("failed to dial %s", apiServerAddrPtr)
 	}
+	conn = client.Conn()
 	close = func() {
 		cancel()
-		conn.Close()
+		client.Close()
 	}
 	return
 }
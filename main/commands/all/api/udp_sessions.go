@@ -0,0 +1,99 @@
+package api
+
+import (
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdUDPSessions = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api udpsessions [--server=127.0.0.1:8080] [-tag=tag]",
+	Short:       "List UDP sessions of inbounds",
+	Long: `
+List the active UDP sessions (the NAT table) of inbounds, with the
+traffic of each session and the seconds left before it expires when idle.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-tag
+		Inbound tag. Lists the sessions of all inbounds if not set.
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag="tag name"
+`,
+	Run: executeUDPSessions,
+}
+
+func executeUDPSessions(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	var tag string
+	cmd.Flag.StringVar(&tag, "tag", "", "")
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	resp, err := client.ListUDPSessions(ctx, &handlerService.ListUDPSessionsRequest{Tag: tag})
+	if err != nil {
+		base.Fatalf("failed to list UDP sessions: %s", err)
+	}
+	showJSONResponse(resp)
+}
+
+var cmdFlushUDPSessions = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api udpflush [--server=127.0.0.1:8080] [-tag=tag] [-source=ip]",
+	Short:       "Flush UDP sessions of inbounds",
+	Long: `
+Close UDP sessions of inbounds, so that the next packet from the client
+starts a new session.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-tag
+		Inbound tag. Flushes the sessions of all inbounds if not set.
+
+	-source
+		Only flush the sessions of this client address.
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag="tag name" -source=192.168.1.2
+`,
+	Run: executeFlushUDPSessions,
+}
+
+func executeFlushUDPSessions(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	var tag, source string
+	cmd.Flag.StringVar(&tag, "tag", "", "")
+	cmd.Flag.StringVar(&source, "source", "", "")
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	resp, err := client.FlushUDPSessions(ctx, &handlerService.FlushUDPSessionsRequest{
+		Tag:    tag,
+		Source: source,
+	})
+	if err != nil {
+		base.Fatalf("failed to flush UDP sessions: %s", err)
+	}
+	showJSONResponse(resp)
+}
@@ -0,0 +1,399 @@
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pelletier/go-toml"
+	"github.com/xtls/xray-core/common/cmdarg"
+	"github.com/xtls/xray-core/common/platform"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf"
+	json_reader "github.com/xtls/xray-core/infra/conf/json"
+	"github.com/xtls/xray-core/infra/conf/serial"
+	"github.com/xtls/xray-core/main/commands/base"
+	"github.com/xtls/xray-core/main/confloader"
+)
+
+// CmdCheck is the check command
+var CmdCheck = &base.Command{
+	UsageLine: "{{.Exec}} check [-c config.json] [-schema]",
+	Short:     "Validate config and report all problems",
+	Long: `
+Validate config files against the schema of Xray configs, and report all
+errors at once, each with its file, line and JSON pointer, like:
+
+	config.json:12:7: error: /inbounds/0/port: expected integer or string, got boolean
+
+Each file is checked against the schema on its own, so that partial
+files of a confdir are accepted. Then the merged config is built, each
+inbound, outbound and section apart, and checked for common mistakes,
+reported as warnings: outbounds no rule, balancer or other outbound
+uses, inbounds listening on the same port, routing rules no traffic
+reaches because an earlier rule matches all of it, and rules sending
+to unknown outbounds or balancers.
+
+Lines are those of JSON files; values of YAML and TOML files are
+located by their JSON pointers only.
+
+Exits with code 1 if there are errors.
+
+Arguments:
+
+	-c, -config
+		Config file. Multiple assign is accepted. Defaults to config.json
+		in working directory, or the config from environment.
+
+	-schema
+		Print the JSON schema of configs instead, for editors to complete
+		and check them, e.g. with "$schema" in the config or the
+		json.schemas setting of VS Code.
+`,
+}
+
+func init() {
+	CmdCheck.Run = executeCheck // break init loop
+}
+
+var (
+	configFiles cmdarg.Arg
+	printSchema = CmdCheck.Flag.Bool("schema", false, "")
+
+	_ = func() bool {
+		CmdCheck.Flag.Var(&configFiles, "config", "")
+		CmdCheck.Flag.Var(&configFiles, "c", "")
+		return true
+	}()
+)
+
+// issue is a problem found in the config.
+type issue struct {
+	warning bool
+	file    string
+	line    int
+	column  int
+	pointer string
+	message string
+}
+
+func (i *issue) String() string {
+	var b strings.Builder
+	if i.file != "" {
+		b.WriteString(i.file)
+		if i.line > 0 {
+			b.WriteString(":" + strconv.Itoa(i.line) + ":" + strconv.Itoa(i.column))
+		}
+		b.WriteString(": ")
+	}
+	if i.warning {
+		b.WriteString("warning: ")
+	} else {
+		b.WriteString("error: ")
+	}
+	if i.pointer != "" {
+		b.WriteString(i.pointer + ": ")
+	}
+	b.WriteString(i.message)
+	return b.String()
+}
+
+// configFile is a config file decoded as JSON.
+type configFile struct {
+	name  string
+	value interface{}
+	// offsets are the positions of the values of JSON files by their pointers, or nil for files
+	// in other formats.
+	offsets map[string]position
+}
+
+type position struct {
+	line   int
+	column int
+}
+
+type checker struct {
+	issues []*issue
+	files  []*configFile
+}
+
+func (c *checker) add(warning bool, file *configFile, pointer, message string) {
+	i := &issue{warning: warning, pointer: pointer, message: message}
+	if file != nil {
+		i.file = file.name
+		i.line, i.column = file.locate(pointer)
+	}
+	c.issues = append(c.issues, i)
+}
+
+func (c *checker) errorf(file *configFile, pointer, format string, a ...interface{}) {
+	c.add(false, file, pointer, fmt.Sprintf(format, a...))
+}
+
+func (c *checker) warnf(pointer, format string, a ...interface{}) {
+	c.add(true, c.merged(), pointer, fmt.Sprintf(format, a...))
+}
+
+// merged returns the file pointers into the merged config locate values in, which is the config
+// file if there is a single one.
+func (c *checker) merged() *configFile {
+	if len(c.files) == 1 {
+		return c.files[0]
+	}
+	return nil
+}
+
+func executeCheck(cmd *base.Command, args []string) {
+	if *printSchema {
+		b, err := json.MarshalIndent(conf.ConfigSchema(), "", "  ")
+		if err != nil {
+			base.Fatalf("failed to marshal schema: %s", err)
+		}
+		fmt.Println(string(b))
+		return
+	}
+
+	files := getConfigFiles()
+	if len(files) == 0 {
+		base.Fatalf("no config file found, pass it with -c")
+	}
+	c := &checker{}
+	schema := conf.ConfigSchema()
+	var sources []*core.ConfigSource
+	for _, name := range files {
+		format := core.GetFormatByExtension(strings.TrimPrefix(filepath.Ext(name), "."))
+		if format == "" {
+			format = "json"
+		}
+		if format == "protobuf" {
+			base.Fatalf("%s: only JSON, YAML and TOML configs can be checked", name)
+		}
+		sources = append(sources, &core.ConfigSource{Name: name, Format: format})
+		file, err := decodeFile(name, format)
+		if err != nil {
+			c.errorf(&configFile{name: name}, "", "%s", err)
+			continue
+		}
+		c.files = append(c.files, file)
+		for _, e := range schema.Validate(file.value) {
+			c.errorf(file, e.Pointer, "%s", e.Message)
+		}
+	}
+
+	if len(c.files) == len(files) {
+		c.build(sources)
+	}
+
+	errs := 0
+	for _, i := range c.issues {
+		fmt.Println(i)
+		if !i.warning {
+			errs++
+		}
+	}
+	fmt.Printf("%d errors, %d warnings\n", errs, len(c.issues)-errs)
+	if errs > 0 {
+		os.Exit(1)
+	}
+}
+
+// build builds each part of the merged config apart, so that all of their errors are reported,
+// and checks it for common mistakes.
+func (c *checker) build(sources []*core.ConfigSource) {
+	config, err := serial.DecodeConfigFromFiles(sources)
+	if err != nil {
+		// Values failing to decode are those of the wrong types, reported already.
+		if len(c.issues) == 0 {
+			c.errorf(nil, "", "%s", err)
+		}
+		return
+	}
+	failed := false
+	for i := range config.InboundConfigs {
+		if _, err := config.InboundConfigs[i].Build(); err != nil {
+			c.errorf(c.merged(), "/inbounds/"+strconv.Itoa(i), "%s", err)
+			failed = true
+		}
+	}
+	for i := range config.OutboundConfigs {
+		if _, err := config.OutboundConfigs[i].Build(); err != nil {
+			c.errorf(c.merged(), "/outbounds/"+strconv.Itoa(i), "%s", err)
+			failed = true
+		}
+	}
+	if config.RouterConfig != nil {
+		if _, err := config.RouterConfig.Build(); err != nil {
+			c.errorf(c.merged(), "/routing", "%s", err)
+			failed = true
+		}
+	}
+	if config.DNSConfig != nil {
+		if _, err := config.DNSConfig.Build(); err != nil {
+			c.errorf(c.merged(), "/dns", "%s", err)
+			failed = true
+		}
+	}
+	if !failed {
+		// The parts built above are built again from a fresh copy, as building may change them.
+		if fresh, err := serial.DecodeConfigFromFiles(sources); err == nil {
+			if _, err := fresh.Build(); err != nil {
+				c.errorf(nil, "", "%s", err)
+			}
+		}
+	}
+
+	c.checkOutboundsUsed(config)
+	c.checkListenPorts(config)
+	c.checkRules(config)
+}
+
+func getConfigFiles() cmdarg.Arg {
+	if len(configFiles) > 0 {
+		return configFiles
+	}
+	if workingDir, err := os.Getwd(); err == nil {
+		configFile := filepath.Join(workingDir, "config.json")
+		if _, err := os.Stat(configFile); err == nil {
+			return cmdarg.Arg{configFile}
+		}
+	}
+	if configFile := platform.GetConfigurationPath(); configFile != "" {
+		return cmdarg.Arg{configFile}
+	}
+	return nil
+}
+
+// decodeFile reads the config file name in format, and decodes it as JSON.
+func decodeFile(name, format string) (*configFile, error) {
+	r, err := confloader.LoadConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	file := &configFile{name: name}
+	var data []byte
+	switch format {
+	case "json":
+		// Comments are dropped, keeping line breaks.
+		if data, err = io.ReadAll(&json_reader.Reader{Reader: r}); err != nil {
+			return nil, err
+		}
+	case "yaml":
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = yaml.YAMLToJSON(b); err != nil {
+			return nil, err
+		}
+	case "toml":
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{})
+		if err := toml.Unmarshal(b, &m); err != nil {
+			return nil, err
+		}
+		if data, err = json.Marshal(m); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(data, &file.value); err != nil {
+		if e, ok := err.(*json.SyntaxError); ok && format == "json" {
+			p := positionAt(data, int(e.Offset))
+			return nil, fmt.Errorf("line %d column %d: %s", p.line, p.column, err)
+		}
+		return nil, err
+	}
+	if format == "json" {
+		file.offsets = pointerPositions(data)
+	}
+	return file, nil
+}
+
+// locate returns the line and column of the value at pointer, or of its closest parent that is
+// in the file, or zeros if they aren't known.
+func (f *configFile) locate(pointer string) (int, int) {
+	if f.offsets == nil {
+		return 0, 0
+	}
+	for {
+		if p, found := f.offsets[pointer]; found {
+			return p.line, p.column
+		}
+		i := strings.LastIndex(pointer, "/")
+		if i < 0 {
+			return 0, 0
+		}
+		pointer = pointer[:i]
+	}
+}
+
+// positionAt returns the line and column, both counted from 1, of offset in data.
+func positionAt(data []byte, offset int) position {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	return position{line: line, column: offset - bytes.LastIndexByte(before, '\n')}
+}
+
+// pointerPositions returns the positions of the values in the JSON document data by their
+// pointers. Members of objects are at their keys.
+func pointerPositions(data []byte) map[string]position {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	positions := make(map[string]position)
+	next := func() int {
+		o := int(decoder.InputOffset())
+		for o < len(data) && strings.IndexByte(" \t\r\n,:", data[o]) >= 0 {
+			o++
+		}
+		return o
+	}
+	var walk func(pointer string, start int) bool
+	walk = func(pointer string, start int) bool {
+		if _, found := positions[pointer]; !found {
+			positions[pointer] = positionAt(data, start)
+		}
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		switch token {
+		case json.Delim('{'):
+			for decoder.More() {
+				start := next()
+				key, err := decoder.Token()
+				if err != nil {
+					return false
+				}
+				s, _ := key.(string)
+				if !walk(pointer+"/"+escapePointer(s), start) {
+					return false
+				}
+			}
+			_, err = decoder.Token()
+		case json.Delim('['):
+			for i := 0; decoder.More(); i++ {
+				if !walk(pointer+"/"+strconv.Itoa(i), next()) {
+					return false
+				}
+			}
+			_, err = decoder.Token()
+		}
+		return err == nil
+	}
+	walk("", next())
+	return positions
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
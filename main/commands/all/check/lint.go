@@ -0,0 +1,216 @@
+package check
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/xtls/xray-core/infra/conf"
+)
+
+// checkOutboundsUsed warns about tagged outbounds nothing can send traffic to. The first outbound
+// is the default one, and others are used if their tags are mentioned anywhere in the config
+// files, as by rules, balancers or the proxy settings of other outbounds, or if a balancer
+// selects them by prefix.
+func (c *checker) checkOutboundsUsed(config *conf.Config) {
+	mentioned := make(map[string]bool)
+	for _, file := range c.files {
+		collectStrings(file.value, "", mentioned)
+	}
+	var selectors []string
+	if config.RouterConfig != nil {
+		for _, b := range config.RouterConfig.Balancers {
+			selectors = append(selectors, b.Selectors...)
+		}
+	}
+	for i, ob := range config.OutboundConfigs {
+		if i == 0 || ob.Tag == "" || mentioned[ob.Tag] {
+			continue
+		}
+		selected := false
+		for _, selector := range selectors {
+			selected = selected || strings.HasPrefix(ob.Tag, selector)
+		}
+		if !selected {
+			c.warnf("/outbounds/"+strconv.Itoa(i), "outbound %q is never used: no rule, balancer or outbound refers to it", ob.Tag)
+		}
+	}
+}
+
+// collectStrings adds the strings in v, and their comma separated items, to found, but for
+// the tags of outbounds themselves.
+func collectStrings(v interface{}, pointer string, found map[string]bool) {
+	switch v := v.(type) {
+	case string:
+		if strings.HasPrefix(pointer, "/outbounds/") && strings.HasSuffix(pointer, "/tag") && strings.Count(pointer, "/") == 3 {
+			return
+		}
+		found[v] = true
+		for _, item := range strings.Split(v, ",") {
+			found[strings.TrimSpace(item)] = true
+		}
+	case []interface{}:
+		for i, item := range v {
+			collectStrings(item, pointer+"/"+strconv.Itoa(i), found)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			collectStrings(item, pointer+"/"+strings.ToLower(key), found)
+		}
+	}
+}
+
+// checkListenPorts warns about inbounds listening on the same port of the same address, of which
+// only the first can start.
+func (c *checker) checkListenPorts(config *conf.Config) {
+	type listener struct {
+		index   int
+		tag     string
+		address string
+		ports   *conf.PortList
+	}
+	var listeners []listener
+	for i, ib := range config.InboundConfigs {
+		if ib.PortList == nil || ib.Protocol == "tun" {
+			continue
+		}
+		if ib.Allocation != nil && strings.EqualFold(ib.Allocation.Strategy, "random") {
+			continue
+		}
+		address := ""
+		if ib.ListenOn != nil {
+			if ib.ListenOn.Family().IsDomain() {
+				// Unix sockets, or addresses resolved at start.
+				continue
+			}
+			if !ib.ListenOn.IP().IsUnspecified() {
+				address = ib.ListenOn.String()
+			}
+		}
+		l := listener{index: i, tag: ib.Tag, address: address, ports: ib.PortList}
+		for _, other := range listeners {
+			if other.address != "" && l.address != "" && other.address != l.address {
+				continue
+			}
+			if port, found := overlappingPort(other.ports, l.ports); found {
+				c.warnf("/inbounds/"+strconv.Itoa(i)+"/port", "inbound %s listens on port %d as inbound %s does, and fails to start",
+					inboundName(l.index, l.tag), port, inboundName(other.index, other.tag))
+				break
+			}
+		}
+		listeners = append(listeners, l)
+	}
+}
+
+func inboundName(i int, tag string) string {
+	if tag != "" {
+		return strconv.Quote(tag)
+	}
+	return "#" + strconv.Itoa(i)
+}
+
+// overlappingPort returns the first port in both a and b, but 0, which is a random port.
+func overlappingPort(a, b *conf.PortList) (uint32, bool) {
+	for _, x := range a.Range {
+		for _, y := range b.Range {
+			from, to := max(x.From, y.From, 1), min(x.To, y.To)
+			if from <= to {
+				return from, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// checkRules warns about routing rules no traffic reaches, as an earlier rule matches all of it:
+// one with the same conditions, or one without conditions. Rules are skipped if priorities
+// reorder them. It also warns about rules sending to outbounds or balancers the config lacks.
+func (c *checker) checkRules(config *conf.Config) {
+	if config.RouterConfig == nil {
+		return
+	}
+	targets := make(map[string]bool)
+	for _, ob := range config.OutboundConfigs {
+		targets[ob.Tag] = true
+	}
+	if config.Reverse != nil {
+		for _, b := range config.Reverse.Bridges {
+			targets[b.Tag] = true
+		}
+		for _, p := range config.Reverse.Portals {
+			targets[p.Tag] = true
+		}
+	}
+	balancers := make(map[string]bool)
+	for _, b := range config.RouterConfig.Balancers {
+		balancers[b.Tag] = true
+	}
+
+	rules := make([]*conf.RawFieldRule, len(config.RouterConfig.RuleList))
+	for i, raw := range config.RouterConfig.RuleList {
+		rule := new(conf.RawFieldRule)
+		if err := json.Unmarshal(raw, rule); err != nil {
+			return
+		}
+		if rule.Priority != 0 {
+			return
+		}
+		rules[i] = rule
+	}
+	none := ruleConditions(&conf.RawFieldRule{})
+	seen := make(map[string]int)
+	catchAll := -1
+	for i, rule := range rules {
+		pointer := "/routing/rules/" + strconv.Itoa(i)
+		// Subscriptions add outbounds at start.
+		if tag := rule.OutboundTag; tag != "" && !targets[tag] && !strings.HasPrefix(tag, "subscription-") {
+			c.warnf(pointer+"/outboundTag", "rule sends traffic to outbound %q the config lacks", tag)
+		}
+		if tag := rule.BalancerTag; tag != "" && rule.OutboundTag == "" && !balancers[tag] {
+			c.warnf(pointer+"/balancerTag", "rule sends traffic to balancer %q the config lacks", tag)
+		}
+		if catchAll >= 0 {
+			c.warnf(pointer, "rule is never reached: rule %d before it matches all traffic", catchAll)
+			continue
+		}
+		conditions := ruleConditions(rule)
+		if j, found := seen[conditions]; found {
+			c.warnf(pointer, "rule is never reached: rule %d before it has the same conditions", j)
+			continue
+		}
+		// Rules of groups may be disabled, and let traffic through to later rules.
+		if rule.Group != "" {
+			continue
+		}
+		seen[conditions] = i
+		if conditions == none {
+			catchAll = i
+		}
+	}
+}
+
+// ruleConditions returns what rule matches, as a string equal for rules matching the same traffic
+// by the same conditions.
+func ruleConditions(rule *conf.RawFieldRule) string {
+	r := *rule
+	r.RouterRule = conf.RouterRule{}
+	if r.Network != nil {
+		tcp, udp, others := false, false, false
+		for _, n := range *r.Network {
+			switch strings.ToLower(strings.TrimSpace(string(n))) {
+			case "tcp":
+				tcp = true
+			case "udp":
+				udp = true
+			default:
+				others = true
+			}
+		}
+		// Proxied traffic is either.
+		if tcp && udp && !others {
+			r.Network = nil
+		}
+	}
+	b, _ := json.Marshal(&r)
+	return string(b)
+}
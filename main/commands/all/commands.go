@@ -2,7 +2,14 @@ package all
 
 import (
 	"github.com/xtls/xray-core/main/commands/all/api"
+	"github.com/xtls/xray-core/main/commands/all/check"
 	"github.com/xtls/xray-core/main/commands/all/convert"
+	"github.com/xtls/xray-core/main/commands/all/doctor"
+	"github.com/xtls/xray-core/main/commands/all/geodata"
+	"github.com/xtls/xray-core/main/commands/all/log"
+	"github.com/xtls/xray-core/main/commands/all/migrate"
+	"github.com/xtls/xray-core/main/commands/all/ping"
+	"github.com/xtls/xray-core/main/commands/all/scenario"
 	"github.com/xtls/xray-core/main/commands/all/tls"
 	"github.com/xtls/xray-core/main/commands/base"
 )
@@ -11,10 +18,18 @@ func init() {
 	base.RootCommand.Commands = append(
 		base.RootCommand.Commands,
 		api.CmdAPI,
+		check.CmdCheck,
 		convert.CmdConvert,
+		doctor.CmdDoctor,
+		geodata.CmdGeodata,
+		log.CmdLog,
+		migrate.CmdMigrate,
+		ping.CmdPing,
+		scenario.CmdScenario,
 		tls.CmdTLS,
 		cmdUUID,
 		cmdX25519,
+		cmdRealityRotate,
 		cmdWG,
 	)
 }
@@ -0,0 +1,200 @@
+package convert
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf"
+	"github.com/xtls/xray-core/infra/conf/serial"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdLinkToJSON = &base.Command{
+	UsageLine: "{{.Exec}} convert link2json [-tag tag] [link]...",
+	Short:     "Convert share links to outbounds",
+	Long: `
+Convert vmess://, vless://, trojan:// and ss:// share links, or those
+read from stdin one per line, to a config with their outbounds, which
+can be put into the confdir or merged with other configs.
+
+The outbounds are tagged after the names in the links.
+
+Arguments:
+
+	-tag
+		Tag of the outbound, numbered if there are several links.
+
+Example:
+
+	{{.Exec}} {{.LongName}} "vless://uuid@example.com:443?security=reality&...#jp"
+`,
+}
+
+var cmdJSONToLink = &base.Command{
+	UsageLine: "{{.Exec}} convert json2link [-tag tag] [config file]...",
+	Short:     "Convert outbounds to share links",
+	Long: `
+Print the share links of the VMess, VLESS, Trojan and Shadowsocks
+outbounds of the config, which defaults to config.json in the working
+directory. Outbounds share links can't describe are skipped.
+
+Arguments:
+
+	-tag
+		Only the outbound with the tag.
+
+Example:
+
+	{{.Exec}} {{.LongName}} -tag proxy config.json
+`,
+}
+
+func init() {
+	cmdLinkToJSON.Run = executeLinkToJSON // break init loop
+	cmdJSONToLink.Run = executeJSONToLink
+}
+
+var (
+	linkTag = cmdLinkToJSON.Flag.String("tag", "", "")
+	jsonTag = cmdJSONToLink.Flag.String("tag", "", "")
+)
+
+func executeLinkToJSON(cmd *base.Command, args []string) {
+	links := args
+	if len(links) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				links = append(links, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			base.Fatalf("failed to read links: %s", err)
+		}
+	}
+	if len(links) == 0 {
+		base.Fatalf("no share link given")
+	}
+
+	var outbounds []*conf.OutboundDetourConfig
+	tags := make(map[string]int)
+	for i, link := range links {
+		ob, name, err := conf.ParseShareLink(link)
+		if err != nil {
+			base.Fatalf("%s", err)
+		}
+		tag := name
+		if *linkTag != "" {
+			tag = *linkTag
+			if len(links) > 1 {
+				tag += "-" + strconv.Itoa(i+1)
+			}
+		}
+		if tag == "" {
+			tag = "proxy"
+		}
+		if n := tags[tag]; n > 0 {
+			tags[tag] = n + 1
+			tag += "-" + strconv.Itoa(n+1)
+		} else {
+			tags[tag] = 1
+		}
+		ob.Tag = tag
+		outbounds = append(outbounds, ob)
+	}
+	b, err := json.Marshal(map[string]interface{}{"outbounds": outbounds})
+	if err != nil {
+		base.Fatalf("%s", err)
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		base.Fatalf("%s", err)
+	}
+	if b, err = json.MarshalIndent(prune(v), "", "  "); err != nil {
+		base.Fatalf("%s", err)
+	}
+	fmt.Println(string(b))
+}
+
+// prune removes the unset fields of the decoded JSON v, leaving those the links set.
+func prune(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if e = prune(e); e == nil {
+				delete(v, k)
+			} else {
+				v[k] = e
+			}
+		}
+		if len(v) == 0 {
+			return nil
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		for i, e := range v {
+			v[i] = prune(e)
+		}
+	case string:
+		if v == "" {
+			return nil
+		}
+	case bool:
+		if !v {
+			return nil
+		}
+	case float64:
+		if v == 0 {
+			return nil
+		}
+	}
+	return v
+}
+
+func executeJSONToLink(cmd *base.Command, args []string) {
+	files := args
+	if len(files) == 0 {
+		files = []string{"config.json"}
+	}
+	var sources []*core.ConfigSource
+	for _, file := range files {
+		format := core.GetFormatByExtension(strings.TrimPrefix(filepath.Ext(file), "."))
+		if format == "" || format == "protobuf" {
+			base.Fatalf("%s: only JSON, YAML and TOML configs can be converted", file)
+		}
+		sources = append(sources, &core.ConfigSource{Name: file, Format: format})
+	}
+	config, err := serial.DecodeConfigFromFiles(sources)
+	if err != nil {
+		base.Fatalf("%s", err)
+	}
+
+	found := false
+	for i := range config.OutboundConfigs {
+		ob := &config.OutboundConfigs[i]
+		if *jsonTag != "" && ob.Tag != *jsonTag {
+			continue
+		}
+		found = true
+		link, err := conf.ShareLink(ob)
+		if err != nil {
+			if *jsonTag != "" {
+				base.Fatalf("%s", err)
+			}
+			fmt.Fprintln(os.Stderr, "Skipped:", err)
+			continue
+		}
+		fmt.Println(link)
+	}
+	if !found && *jsonTag != "" {
+		base.Fatalf("no outbound tagged %s", *jsonTag)
+	}
+}
@@ -0,0 +1,450 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/platform"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/sysproxy"
+	"github.com/xtls/xray-core/infra/conf"
+)
+
+const (
+	geodataMaxAge     = 30 * 24 * time.Hour
+	certWarnBefore    = 14 * 24 * time.Hour
+	clockSkewWarn     = 30 * time.Second
+	clockSkewFail     = 90 * time.Second
+	dnsProbeDomain    = "www.example.com"
+	suggestUpdateGeo  = "download the latest geoip.dat and geosite.dat from https://github.com/Loyalsoldier/v2ray-rules-dat"
+	suggestPortInUse  = "stop the program using the port (possibly another Xray), or change the inbound port"
+	suggestPortDenied = "ports below 1024 need root, or CAP_NET_BIND_SERVICE on Linux"
+)
+
+func handlerName(i int, tag string) string {
+	if tag != "" {
+		return tag
+	}
+	return "#" + strconv.Itoa(i)
+}
+
+func listenAddress(ib *conf.InboundDetourConfig) (string, bool) {
+	if ib.ListenOn == nil {
+		return "0.0.0.0", true
+	}
+	addr := ib.ListenOn.Address
+	if addr.Family().IsDomain() {
+		// Unix domain sockets
+		return "", false
+	}
+	return addr.String(), true
+}
+
+func checkPorts(r *report, config *conf.Config) {
+	const check = "port"
+	for i := range config.InboundConfigs {
+		ib := &config.InboundConfigs[i]
+		name := handlerName(i, ib.Tag)
+		host, ok := listenAddress(ib)
+		if !ok || ib.PortList == nil || len(ib.PortList.Range) == 0 {
+			continue
+		}
+		port := ib.PortList.Range[0].From
+		if port == 0 {
+			r.add(check, name, StatusOK, "ephemeral port", "")
+			continue
+		}
+		address := net.JoinHostPort(host, strconv.Itoa(int(port)))
+		l, err := net.Listen("tcp", address)
+		if err != nil {
+			suggestion := suggestPortInUse
+			if port < 1024 && strings.Contains(err.Error(), "permission denied") {
+				suggestion = suggestPortDenied
+			}
+			r.add(check, name, StatusFail, address+" is not available: "+err.Error(), suggestion)
+			continue
+		}
+		l.Close()
+		r.add(check, name, StatusOK, address+" is available", "")
+	}
+}
+
+func streamSettings(config *conf.Config) map[string]*conf.StreamConfig {
+	settings := make(map[string]*conf.StreamConfig)
+	for i, ib := range config.InboundConfigs {
+		if ib.StreamSetting != nil {
+			settings["inbound "+handlerName(i, ib.Tag)] = ib.StreamSetting
+		}
+	}
+	for i, ob := range config.OutboundConfigs {
+		if ob.StreamSetting != nil {
+			settings["outbound "+handlerName(i, ob.Tag)] = ob.StreamSetting
+		}
+	}
+	return settings
+}
+
+func checkCertificates(r *report, config *conf.Config) {
+	const check = "certificate"
+	for name, ss := range streamSettings(config) {
+		if ss.TLSSettings == nil {
+			continue
+		}
+		for _, c := range ss.TLSSettings.Certs {
+			subject := name
+			var data []byte
+			if c.CertFile != "" {
+				subject += " " + c.CertFile
+				b, err := os.ReadFile(c.CertFile)
+				if err != nil {
+					r.add(check, subject, StatusFail, err.Error(), "check the certificateFile path and its permissions")
+					continue
+				}
+				data = b
+			} else {
+				data = []byte(strings.Join(c.CertStr, "\n"))
+			}
+			block, _ := pem.Decode(data)
+			if block == nil {
+				r.add(check, subject, StatusFail, "no PEM certificate found", "the certificate must be in PEM format")
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				r.add(check, subject, StatusFail, err.Error(), "the certificate must be in PEM format")
+				continue
+			}
+			left := time.Until(cert.NotAfter)
+			detail := "expires on " + cert.NotAfter.Format(time.DateOnly)
+			switch {
+			case left <= 0:
+				r.add(check, subject, StatusFail, "expired on "+cert.NotAfter.Format(time.DateOnly), "renew the certificate")
+			case left < certWarnBefore:
+				r.add(check, subject, StatusWarn, detail, "renew the certificate soon")
+			default:
+				r.add(check, subject, StatusOK, detail, "")
+			}
+		}
+	}
+}
+
+// usesGeodata reports whether rules or DNS of the config refer to the given kind of geodata, "geoip" or "geosite".
+func usesGeodata(config *conf.Config, kind string) bool {
+	if config == nil {
+		return false
+	}
+	prefix := []byte(kind + ":")
+	if config.RouterConfig != nil {
+		for _, rule := range config.RouterConfig.RuleList {
+			if bytes.Contains(rule, prefix) {
+				return true
+			}
+		}
+	}
+	if config.DNSConfig != nil {
+		for _, server := range config.DNSConfig.Servers {
+			for _, d := range append(server.Domains, server.ExpectIPs...) {
+				if strings.HasPrefix(d, kind+":") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func checkGeodata(r *report, config *conf.Config) {
+	const check = "geodata"
+	for _, kind := range []string{"geoip", "geosite"} {
+		file := platform.GetAssetLocation(kind + ".dat")
+		info, err := os.Stat(file)
+		if err != nil {
+			if usesGeodata(config, kind) {
+				r.add(check, file, StatusFail, "missing, but used by config", suggestUpdateGeo)
+			} else {
+				r.add(check, file, StatusWarn, "missing", suggestUpdateGeo)
+			}
+			continue
+		}
+		age := time.Since(info.ModTime())
+		detail := fmt.Sprintf("updated %d days ago", int(age.Hours()/24))
+		if age > geodataMaxAge {
+			r.add(check, file, StatusWarn, detail, suggestUpdateGeo)
+		} else {
+			r.add(check, file, StatusOK, detail, "")
+		}
+	}
+}
+
+// probe runs f for each subject concurrently, and adds the results in order.
+func probe(r *report, check string, subjects []string, f func(string) (Status, string, string)) {
+	results := make([]Result, len(subjects))
+	var wg sync.WaitGroup
+	for i, subject := range subjects {
+		wg.Add(1)
+		go func(i int, subject string) {
+			defer wg.Done()
+			status, detail, suggestion := f(subject)
+			results[i] = Result{Check: check, Subject: subject, Status: status, Detail: detail, Suggestion: suggestion}
+		}(i, subject)
+	}
+	wg.Wait()
+	r.results = append(r.results, results...)
+}
+
+func dialTCP(address string, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return 0, err
+	}
+	conn.Close()
+	return time.Since(start), nil
+}
+
+func checkDNS(r *report, config *conf.Config, timeout time.Duration) {
+	if config.DNSConfig == nil || len(config.DNSConfig.Servers) == 0 {
+		r.add("dns", "", StatusSkip, "no DNS servers in config, system resolver is used", "")
+		return
+	}
+	var servers []string
+	ports := make(map[string]uint16)
+	for _, s := range config.DNSConfig.Servers {
+		if s.Address == nil {
+			continue
+		}
+		server := s.Address.String()
+		servers = append(servers, server)
+		ports[server] = s.Port
+	}
+	probe(r, "dns", servers, func(server string) (Status, string, string) {
+		const suggestion = "check that the DNS server is reachable from this network, or use another one"
+		switch {
+		case server == "localhost":
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if _, err := net.DefaultResolver.LookupHost(ctx, dnsProbeDomain); err != nil {
+				return StatusFail, err.Error(), "check the DNS settings of the system"
+			}
+			return StatusOK, "system resolver works", ""
+		case server == "fakedns":
+			return StatusSkip, "fake DNS", ""
+		case strings.Contains(server, "://"):
+			u, err := url.Parse(server)
+			if err != nil {
+				return StatusFail, err.Error(), "fix the server address"
+			}
+			scheme := strings.TrimSuffix(u.Scheme, "+local")
+			port := u.Port()
+			switch scheme {
+			case "https", "h2c":
+				if port == "" {
+					port = "443"
+				}
+			case "tcp":
+				if port == "" {
+					port = "53"
+				}
+			default:
+				return StatusSkip, scheme + " servers are not checked", ""
+			}
+			rtt, err := dialTCP(net.JoinHostPort(u.Hostname(), port), timeout)
+			if err != nil {
+				return StatusFail, err.Error(), suggestion
+			}
+			return StatusOK, "reachable in " + rtt.Round(time.Millisecond).String(), ""
+		default:
+			port := ports[server]
+			if port == 0 {
+				port = 53
+			}
+			address := net.JoinHostPort(server, strconv.Itoa(int(port)))
+			resolver := &net.Resolver{
+				PreferGo: true,
+				Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, network, address)
+				},
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			start := time.Now()
+			if _, err := resolver.LookupHost(ctx, dnsProbeDomain); err != nil {
+				return StatusFail, err.Error(), suggestion
+			}
+			return StatusOK, "answered in " + time.Since(start).Round(time.Millisecond).String(), ""
+		}
+	})
+}
+
+func checkOutbounds(r *report, config *conf.Config, timeout time.Duration) {
+	var subjects []string
+	for i := range config.OutboundConfigs {
+		ob := &config.OutboundConfigs[i]
+		name := handlerName(i, ob.Tag)
+		if ob.DialedThroughOutbound() {
+			r.add("outbound", name, StatusSkip, "dialed through another outbound", "")
+			continue
+		}
+		if ob.StreamSetting != nil && ob.StreamSetting.Network != nil {
+			if network := strings.ToLower(string(*ob.StreamSetting.Network)); network == "kcp" || network == "mkcp" {
+				r.add("outbound", name, StatusSkip, "UDP based transport is not checked", "")
+				continue
+			}
+		}
+		for _, server := range ob.ServerAddresses() {
+			subjects = append(subjects, name+" "+server)
+		}
+	}
+	probe(r, "outbound", subjects, func(subject string) (Status, string, string) {
+		_, address, _ := strings.Cut(subject, " ")
+		rtt, err := dialTCP(address, timeout)
+		if err != nil {
+			return StatusFail, err.Error(), "check the server address and port, and that the server is running and not blocked"
+		}
+		return StatusOK, "TCP connect in " + rtt.Round(time.Millisecond).String(), ""
+	})
+}
+
+func checkSysProxy(r *report, config *conf.Config, device string) {
+	const check = "sysproxy"
+	settings, err := sysproxy.New(device).Detect()
+	if err != nil {
+		r.add(check, "", StatusSkip, err.Error(), "")
+		return
+	}
+	if !settings.Enabled {
+		r.add(check, "", StatusOK, "disabled", "")
+		return
+	}
+	detail := settings.Type.String() + " proxy at " + settings.Address()
+	if config != nil {
+		found := false
+		for _, ib := range config.InboundConfigs {
+			if ib.PortList == nil {
+				continue
+			}
+			for _, pr := range ib.PortList.Range {
+				if uint32(settings.Port) >= pr.From && uint32(settings.Port) <= pr.To {
+					found = true
+				}
+			}
+		}
+		if !found {
+			r.add(check, "", StatusWarn, detail+", which is not an inbound of this config", "point the system proxy to an inbound port, or disable it")
+			return
+		}
+	}
+	r.add(check, "", StatusOK, detail, "")
+}
+
+// cipherSettings are the settings of Shadowsocks and VMess handlers that set ciphers.
+type cipherSettings struct {
+	Method  string `json:"method"`
+	Clients []struct {
+		Method string `json:"method"`
+	} `json:"clients"`
+	Servers []struct {
+		Method string `json:"method"`
+	} `json:"servers"`
+	Vnext []struct {
+		Users []struct {
+			Security string `json:"security"`
+		} `json:"users"`
+	} `json:"vnext"`
+}
+
+// aesCiphers returns the AES ciphers set in the settings of a handler.
+func aesCiphers(proto string, settings *json.RawMessage) []string {
+	if settings == nil || (proto != "shadowsocks" && proto != "vmess") {
+		return nil
+	}
+	var s cipherSettings
+	if json.Unmarshal(*settings, &s) != nil {
+		return nil
+	}
+	methods := []string{s.Method}
+	for _, c := range s.Clients {
+		methods = append(methods, c.Method)
+	}
+	for _, c := range s.Servers {
+		methods = append(methods, c.Method)
+	}
+	for _, v := range s.Vnext {
+		for _, u := range v.Users {
+			methods = append(methods, u.Security)
+		}
+	}
+	var ciphers []string
+	for _, m := range methods {
+		if m = strings.ToLower(m); strings.Contains(m, "aes") && !slices.Contains(ciphers, m) {
+			ciphers = append(ciphers, m)
+		}
+	}
+	return ciphers
+}
+
+func checkCrypto(r *report, config *conf.Config) {
+	const check = "crypto"
+	acceleration := protocol.DetectCryptoAcceleration()
+	r.add(check, "", StatusOK, acceleration.String(), "")
+	if config == nil || len(acceleration.AES) > 0 {
+		return
+	}
+	const suggestion = "AES runs in software on this CPU, use chacha20-poly1305 (or 2022-blake3-chacha20-poly1305) instead"
+	for i, ib := range config.InboundConfigs {
+		if ciphers := aesCiphers(ib.Protocol, ib.Settings); len(ciphers) > 0 {
+			r.add(check, "inbound "+handlerName(i, ib.Tag), StatusWarn, "uses "+strings.Join(ciphers, ", "), suggestion)
+		}
+	}
+	for i, ob := range config.OutboundConfigs {
+		if ciphers := aesCiphers(ob.Protocol, ob.Settings); len(ciphers) > 0 {
+			r.add(check, "outbound "+handlerName(i, ob.Tag), StatusWarn, "uses "+strings.Join(ciphers, ", "), suggestion)
+		}
+	}
+}
+
+func checkClock(r *report, timeURL string, timeout time.Duration) {
+	const check = "clock"
+	client := &http.Client{Timeout: timeout}
+	start := time.Now()
+	resp, err := client.Head(timeURL)
+	if err != nil {
+		r.add(check, "", StatusSkip, "failed to get time from "+timeURL+": "+err.Error(), "")
+		return
+	}
+	resp.Body.Close()
+	remote, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		r.add(check, "", StatusSkip, "no valid Date header from "+timeURL, "")
+		return
+	}
+	rtt := time.Since(start)
+	skew := start.Add(rtt / 2).Sub(remote)
+	if skew < 0 {
+		skew = -skew
+	}
+	detail := "local clock is off by " + skew.Round(time.Second).String()
+	const suggestion = "enable time synchronization (NTP) of the system; VMess and REALITY reject clients with skewed clocks"
+	switch {
+	case skew > clockSkewFail:
+		r.add(check, "", StatusFail, detail, suggestion)
+	case skew > clockSkewWarn:
+		r.add(check, "", StatusWarn, detail, suggestion)
+	default:
+		r.add(check, "", StatusOK, detail, "")
+	}
+}
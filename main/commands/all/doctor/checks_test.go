@@ -0,0 +1,107 @@
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/protocol/tls/cert"
+	"github.com/xtls/xray-core/infra/conf"
+)
+
+// statuses returns the status of each subject checked in r.
+func statuses(r *report) map[string]Status {
+	s := make(map[string]Status)
+	for _, res := range r.results {
+		s[res.Subject] = res.Status
+	}
+	return s
+}
+
+func parseConfig(t *testing.T, s string) *conf.Config {
+	t.Helper()
+	config := new(conf.Config)
+	common.Must(json.Unmarshal([]byte(s), config))
+	return config
+}
+
+func TestCheckPorts(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer busy.Close()
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	free.Close()
+
+	r := new(report)
+	checkPorts(r, parseConfig(t, fmt.Sprintf(`{"inbounds": [
+		{"tag": "busy", "listen": "127.0.0.1", "port": %d, "protocol": "socks"},
+		{"tag": "free", "listen": "127.0.0.1", "port": %d, "protocol": "socks"},
+		{"listen": "/run/xray.sock", "protocol": "socks"}
+	]}`, busy.Addr().(*net.TCPAddr).Port, free.Addr().(*net.TCPAddr).Port)))
+	if r := cmp.Diff(statuses(r), map[string]Status{"busy": StatusFail, "free": StatusOK}); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestCheckCertificates(t *testing.T) {
+	pemOf := func(notAfter time.Time) string {
+		c := cert.MustGenerate(nil, cert.NotBefore(time.Now().Add(-time.Hour)), cert.NotAfter(notAfter))
+		certPEM, _ := c.ToPEM()
+		lines, _ := json.Marshal(strings.Split(strings.TrimSpace(string(certPEM)), "\n"))
+		return string(lines)
+	}
+	r := new(report)
+	checkCertificates(r, parseConfig(t, fmt.Sprintf(`{"inbounds": [
+		{"tag": "valid", "protocol": "vless", "streamSettings": {"security": "tls", "tlsSettings": {"certificates": [{"certificate": %s}]}}},
+		{"tag": "expiring", "protocol": "vless", "streamSettings": {"security": "tls", "tlsSettings": {"certificates": [{"certificate": %s}]}}},
+		{"tag": "expired", "protocol": "vless", "streamSettings": {"security": "tls", "tlsSettings": {"certificates": [{"certificate": %s}]}}},
+		{"tag": "garbage", "protocol": "vless", "streamSettings": {"security": "tls", "tlsSettings": {"certificates": [{"certificate": ["not a certificate"]}]}}}
+	]}`, pemOf(time.Now().Add(365*24*time.Hour)), pemOf(time.Now().Add(7*24*time.Hour)), pemOf(time.Now().Add(-time.Minute)))))
+	want := map[string]Status{
+		"inbound valid":    StatusOK,
+		"inbound expiring": StatusWarn,
+		"inbound expired":  StatusFail,
+		"inbound garbage":  StatusFail,
+	}
+	if r := cmp.Diff(statuses(r), want); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestCheckClock(t *testing.T) {
+	var skew time.Duration
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(skew).UTC().Format(http.TimeFormat))
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		skew time.Duration
+		want Status
+	}{
+		{0, StatusOK},
+		{-time.Minute, StatusWarn},
+		{5 * time.Minute, StatusFail},
+	} {
+		skew = tt.skew
+		r := new(report)
+		checkClock(r, server.URL, 5*time.Second)
+		if len(r.results) != 1 || r.results[0].Status != tt.want {
+			t.Errorf("clock off by %v: %v, want %v", tt.skew, r.results, tt.want)
+		}
+	}
+
+	r := new(report)
+	checkClock(r, "http://127.0.0.1:1", time.Second)
+	if len(r.results) != 1 || r.results[0].Status != StatusSkip {
+		t.Error("expected the check skipped without a time server, got ", r.results)
+	}
+}
@@ -0,0 +1,198 @@
+package doctor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/common/cmdarg"
+	"github.com/xtls/xray-core/common/platform"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf"
+	"github.com/xtls/xray-core/infra/conf/serial"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+// CmdDoctor is the doctor command
+var CmdDoctor = &base.Command{
+	UsageLine: "{{.Exec}} doctor [-c config.json] [-timeout 5s]",
+	Short:     "Check config and environment for common problems",
+	Long: `
+Check config and environment for common problems, and print a report
+with suggestions to fix them.
+
+Checks: config validity, inbound port availability, geodata presence and
+age, DNS upstream reachability, outbound server reachability, system proxy
+state, clock skew, expiry of TLS certificates in the config, and the
+acceleration of AES-GCM and ChaCha20-Poly1305 by the CPU, with the AES
+ciphers of the config that run in software.
+
+Exits with code 1 if any check fails.
+
+Arguments:
+
+	-c, -config
+		Config file. Multiple assign is accepted. Defaults to config.json
+		in working directory, or the config from environment.
+
+	-timeout
+		Timeout of each network check. Default 5s.
+
+	-time-url
+		URL whose Date header is compared to the local clock.
+		Default "https://www.cloudflare.com".
+
+	-sysproxy-device
+		Device whose system proxy is checked (only for macOS). Default "Wi-Fi".
+`,
+}
+
+func init() {
+	CmdDoctor.Run = executeDoctor // break init loop
+}
+
+var (
+	configFiles    cmdarg.Arg
+	timeout        = CmdDoctor.Flag.Duration("timeout", 5*time.Second, "")
+	timeURL        = CmdDoctor.Flag.String("time-url", "https://www.cloudflare.com", "")
+	sysProxyDevice = CmdDoctor.Flag.String("sysproxy-device", "Wi-Fi", "")
+
+	_ = func() bool {
+		CmdDoctor.Flag.Var(&configFiles, "config", "")
+		CmdDoctor.Flag.Var(&configFiles, "c", "")
+		return true
+	}()
+)
+
+// Status is the outcome of a check.
+type Status int
+
+const (
+	StatusOK Status = iota
+	StatusSkip
+	StatusWarn
+	StatusFail
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusOK:
+		return "OK"
+	case StatusSkip:
+		return "SKIP"
+	case StatusWarn:
+		return "WARN"
+	default:
+		return "FAIL"
+	}
+}
+
+// Result is the outcome of a single check on a single subject.
+type Result struct {
+	Check      string
+	Subject    string
+	Status     Status
+	Detail     string
+	Suggestion string
+}
+
+type report struct {
+	results []Result
+}
+
+func (r *report) add(check, subject string, status Status, detail, suggestion string) {
+	r.results = append(r.results, Result{
+		Check:      check,
+		Subject:    subject,
+		Status:     status,
+		Detail:     detail,
+		Suggestion: suggestion,
+	})
+}
+
+func (r *report) print() (failed bool) {
+	counts := make(map[Status]int)
+	for _, res := range r.results {
+		counts[res.Status]++
+		subject := res.Check
+		if res.Subject != "" {
+			subject += " " + res.Subject
+		}
+		fmt.Printf("[%-4s] %s: %s\n", res.Status, subject, res.Detail)
+		if res.Suggestion != "" && res.Status >= StatusWarn {
+			fmt.Printf("       -> %s\n", res.Suggestion)
+		}
+	}
+	fmt.Println("-------------------")
+	fmt.Printf("%d ok, %d warnings, %d failures, %d skipped\n", counts[StatusOK], counts[StatusWarn], counts[StatusFail], counts[StatusSkip])
+	return counts[StatusFail] > 0
+}
+
+func executeDoctor(cmd *base.Command, args []string) {
+	r := &report{}
+
+	config := checkConfig(r, getConfigFiles())
+	if config != nil {
+		checkPorts(r, config)
+		checkCertificates(r, config)
+	}
+	checkGeodata(r, config)
+	if config != nil {
+		checkDNS(r, config, *timeout)
+		checkOutbounds(r, config, *timeout)
+	}
+	checkSysProxy(r, config, *sysProxyDevice)
+	checkClock(r, *timeURL, *timeout)
+	checkCrypto(r, config)
+
+	if r.print() {
+		os.Exit(1)
+	}
+}
+
+func getConfigFiles() cmdarg.Arg {
+	if len(configFiles) > 0 {
+		return configFiles
+	}
+	if workingDir, err := os.Getwd(); err == nil {
+		configFile := filepath.Join(workingDir, "config.json")
+		if _, err := os.Stat(configFile); err == nil {
+			return cmdarg.Arg{configFile}
+		}
+	}
+	if configFile := platform.GetConfigurationPath(); configFile != "" {
+		return cmdarg.Arg{configFile}
+	}
+	return nil
+}
+
+// checkConfig decodes and builds the config, and returns the decoded config if it is valid.
+func checkConfig(r *report, files cmdarg.Arg) *conf.Config {
+	const check = "config"
+	if len(files) == 0 {
+		r.add(check, "", StatusFail, "no config file found", "pass the config with -c, or put config.json in working directory")
+		return nil
+	}
+	var sources []*core.ConfigSource
+	for _, file := range files {
+		format := core.GetFormatByExtension(strings.TrimPrefix(filepath.Ext(file), "."))
+		if format == "" || format == "protobuf" {
+			r.add(check, file, StatusSkip, "only JSON, YAML and TOML configs can be checked", "")
+			return nil
+		}
+		sources = append(sources, &core.ConfigSource{Name: file, Format: format})
+	}
+	config, err := serial.DecodeConfigFromFiles(sources)
+	if err != nil {
+		r.add(check, strings.Join(files, ", "), StatusFail, err.Error(), "fix the syntax error at the reported position")
+		return nil
+	}
+	if _, err := config.Build(); err != nil {
+		r.add(check, strings.Join(files, ", "), StatusFail, err.Error(), "fix the reported setting, see https://xtls.github.io/config/")
+		return nil
+	}
+	r.add(check, strings.Join(files, ", "), StatusOK, "valid", "")
+	return config
+}
@@ -0,0 +1,17 @@
+package geodata
+
+import (
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+// CmdGeodata holds all geodata sub commands
+var CmdGeodata = &base.Command{
+	UsageLine: "{{.Exec}} geodata",
+	Short:     "Geo data tools",
+	Long: `{{.Exec}} {{.LongName}} manages and inspects the geoip.dat and geosite.dat files.
+`,
+	Commands: []*base.Command{
+		cmdUpdate,
+		cmdInspect,
+	},
+}
@@ -0,0 +1,305 @@
+package geodata
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/geodata"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/main/commands/base"
+	"google.golang.org/protobuf/proto"
+)
+
+var cmdInspect = &base.Command{
+	UsageLine: "{{.Exec}} geodata inspect [-type geoip|geosite] [-category name] [-match domain|ip] <file>",
+	Short:     "Verify a geo data file and list its categories",
+	Long: `
+Verify a geoip.dat or geosite.dat file, and list its categories with the
+number of entries in each, as referred to by "geoip:name" and
+"geosite:name" in routing and DNS rules.
+
+The file is checked the way Xray loads it: every CIDR and domain must be
+valid, and every regular expression must compile. If "<file>.sha256sum"
+exists next to the file, the checksum of the file must match it. Files
+not found are looked up in the asset directory.
+
+Exits with code 1 if the file is invalid, or if -match matches nothing.
+
+Arguments:
+
+	-type
+		Type of the file, geoip or geosite. Defaults to the one in the
+		file name.
+
+	-category
+		Only the category of this name. Its entries are listed as well.
+
+	-match
+		Domain or IP to look up. The categories it's in are listed, each
+		with the entries matching it.
+
+Example:
+
+	{{.Exec}} {{.LongName}} geosite.dat
+	{{.Exec}} {{.LongName}} -category cn geoip.dat
+	{{.Exec}} {{.LongName}} -match www.google.com geosite.dat
+`,
+}
+
+func init() {
+	cmdInspect.Run = executeInspect // break init loop
+}
+
+var (
+	inspectType     = cmdInspect.Flag.String("type", "", "")
+	inspectCategory = cmdInspect.Flag.String("category", "", "")
+	inspectMatch    = cmdInspect.Flag.String("match", "", "")
+)
+
+// domainPrefixes are the prefixes of the domains of each type in rules.
+var domainPrefixes = map[router.Domain_Type]string{
+	router.Domain_Plain:  "keyword:",
+	router.Domain_Regex:  "regexp:",
+	router.Domain_Domain: "domain:",
+	router.Domain_Full:   "full:",
+}
+
+// category is a list of a geo data file, with the entries of either type.
+type category struct {
+	name    string
+	ip      bool
+	cidrs   []*router.CIDR
+	domains []*router.Domain
+}
+
+func (c *category) size() int {
+	return len(c.cidrs) + len(c.domains)
+}
+
+// inspector collects the problems found in a file.
+type inspector struct {
+	errs int
+}
+
+func (i *inspector) errorf(format string, a ...interface{}) {
+	fmt.Printf("error: "+format+"\n", a...)
+	i.errs++
+}
+
+func executeInspect(cmd *base.Command, args []string) {
+	if len(args) != 1 {
+		base.Fatalf("expected a single geo data file")
+	}
+	path := args[0]
+	if _, err := os.Stat(path); os.IsNotExist(err) && filepath.Base(path) == path {
+		path = geodata.Path(path)
+	}
+	kind := *inspectType
+	if kind == "" {
+		name := strings.ToLower(filepath.Base(path))
+		switch {
+		case strings.Contains(name, "geoip"):
+			kind = "geoip"
+		case strings.Contains(name, "geosite"):
+			kind = "geosite"
+		default:
+			base.Fatalf("unknown type of %s, set it with -type", path)
+		}
+	}
+	if kind != "geoip" && kind != "geosite" {
+		base.Fatalf("unknown type %s, expected geoip or geosite", kind)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		base.Fatalf("%s", err)
+	}
+
+	i := &inspector{}
+	sum := sha256.Sum256(data)
+	fmt.Printf("%s: %d bytes, sha256 %s\n", path, len(data), hex.EncodeToString(sum[:]))
+	i.checkSum(path, sum[:])
+
+	categories, err := decodeCategories(kind, data)
+	if err != nil {
+		i.errorf("%s is not a valid %s file: %s", path, kind, err)
+		base.SetExitStatus(1)
+		base.Exit()
+	}
+	entries := 0
+	seen := make(map[string]bool, len(categories))
+	for _, c := range categories {
+		entries += c.size()
+		if seen[c.name] {
+			fmt.Printf("warning: category %s appears more than once, only the first is used\n", c.name)
+		}
+		seen[c.name] = true
+	}
+	fmt.Printf("%d categories, %d entries\n", len(categories), entries)
+
+	if name := strings.ToLower(strings.TrimPrefix(*inspectCategory, kind+":")); name != "" {
+		var found []*category
+		for _, c := range categories {
+			if c.name == name {
+				found = append(found, c)
+			}
+		}
+		if len(found) == 0 {
+			base.Fatalf("no category %s in %s", name, path)
+		}
+		categories = found[:1]
+	}
+
+	// The categories are verified by building their matchers.
+	var match net.Address
+	if *inspectMatch != "" {
+		match = net.ParseAddress(*inspectMatch)
+		if kind == "geoip" && !match.Family().IsIP() {
+			base.Fatalf("%s is not an IP", *inspectMatch)
+		}
+		if kind == "geosite" && !match.Family().IsDomain() {
+			base.Fatalf("%s is not a domain", *inspectMatch)
+		}
+	}
+	matched := 0
+	for _, c := range categories {
+		if i.inspect(c, match) {
+			matched++
+		}
+	}
+
+	if match == nil {
+		if len(categories) == 1 {
+			listEntries(categories[0])
+		} else {
+			sort.Slice(categories, func(a, b int) bool {
+				return categories[a].name < categories[b].name
+			})
+			for _, c := range categories {
+				fmt.Printf("%-32s %d\n", c.name, c.size())
+			}
+		}
+	} else if matched == 0 {
+		fmt.Printf("%s is in no category\n", *inspectMatch)
+		base.SetExitStatus(1)
+	}
+
+	if i.errs > 0 {
+		fmt.Printf("%d errors\n", i.errs)
+		base.SetExitStatus(1)
+	}
+}
+
+// checkSum compares sum with the one in "<path>.sha256sum", if that file exists.
+func (i *inspector) checkSum(path string, sum []byte) {
+	sumFile, err := os.ReadFile(path + ".sha256sum")
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		i.errorf("%s", err)
+		return
+	}
+	fields := strings.Fields(string(sumFile))
+	if len(fields) == 0 {
+		i.errorf("%s.sha256sum is empty", path)
+		return
+	}
+	if expected, err := hex.DecodeString(fields[0]); err != nil || !bytes.Equal(expected, sum) {
+		i.errorf("checksum mismatch, %s.sha256sum has %s", path, fields[0])
+		return
+	}
+	fmt.Printf("checksum matches %s.sha256sum\n", path)
+}
+
+// decodeCategories decodes the categories of a file of kind, geoip or geosite.
+func decodeCategories(kind string, data []byte) ([]*category, error) {
+	var categories []*category
+	if kind == "geoip" {
+		list := new(router.GeoIPList)
+		if err := proto.Unmarshal(data, list); err != nil {
+			return nil, err
+		}
+		for _, entry := range list.Entry {
+			categories = append(categories, &category{name: strings.ToLower(entry.CountryCode), ip: true, cidrs: entry.Cidr})
+		}
+	} else {
+		list := new(router.GeoSiteList)
+		if err := proto.Unmarshal(data, list); err != nil {
+			return nil, err
+		}
+		for _, entry := range list.Entry {
+			categories = append(categories, &category{name: strings.ToLower(entry.CountryCode), domains: entry.Domain})
+		}
+	}
+	if len(categories) == 0 {
+		return nil, errors.New("no categories")
+	}
+	return categories, nil
+}
+
+// inspect verifies the entries of c, and returns whether match, if set, is in c. The entries
+// matching it are printed.
+func (i *inspector) inspect(c *category, match net.Address) bool {
+	if c.ip {
+		m := new(router.GeoIPMatcher)
+		if err := m.Init(c.cidrs); err != nil {
+			i.errorf("category %s: %s", c.name, err)
+			return false
+		}
+		if match == nil || !m.Match(match.IP()) {
+			return false
+		}
+		fmt.Println(c.name)
+		for _, cidr := range c.cidrs {
+			network := &net.IPNet{IP: cidr.Ip, Mask: net.CIDRMask(int(cidr.Prefix), len(cidr.Ip)*8)}
+			if network.Contains(match.IP()) {
+				fmt.Println("\t" + network.String())
+			}
+		}
+		return true
+	}
+
+	m, err := router.NewDomainMatcher(c.domains)
+	if err != nil {
+		i.errorf("category %s: %s", c.name, err)
+		return false
+	}
+	if match == nil || !m.ApplyDomain(match.Domain()) {
+		return false
+	}
+	fmt.Println(c.name)
+	for _, d := range c.domains {
+		if dm, err := router.NewDomainMatcher([]*router.Domain{d}); err == nil && dm.ApplyDomain(match.Domain()) {
+			fmt.Println("\t" + formatDomain(d))
+		}
+	}
+	return true
+}
+
+// listEntries prints the entries of c, as they'd be written in rules.
+func listEntries(c *category) {
+	fmt.Printf("%s: %d entries\n", c.name, c.size())
+	for _, cidr := range c.cidrs {
+		fmt.Printf("\t%s/%d\n", net.IP(cidr.Ip), cidr.Prefix)
+	}
+	for _, d := range c.domains {
+		fmt.Println("\t" + formatDomain(d))
+	}
+}
+
+// formatDomain returns d as written in rules, followed by its attributes.
+func formatDomain(d *router.Domain) string {
+	s := domainPrefixes[d.Type] + d.Value
+	for _, attr := range d.Attribute {
+		s += " @" + attr.Key
+	}
+	return s
+}
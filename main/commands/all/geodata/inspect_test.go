@@ -0,0 +1,116 @@
+package geodata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestInspectGeoSite(t *testing.T) {
+	data, err := proto.Marshal(&router.GeoSiteList{Entry: []*router.GeoSite{
+		{CountryCode: "GOOGLE", Domain: []*router.Domain{
+			{Type: router.Domain_Domain, Value: "google.com"},
+			{Type: router.Domain_Full, Value: "www.google.cn", Attribute: []*router.Domain_Attribute{{Key: "cn"}}},
+		}},
+		{CountryCode: "games", Domain: []*router.Domain{
+			{Type: router.Domain_Plain, Value: "steam"},
+		}},
+		{CountryCode: "broken", Domain: []*router.Domain{
+			{Type: router.Domain_Regex, Value: "("},
+		}},
+	}})
+	common.Must(err)
+	categories, err := decodeCategories("geosite", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(categories) != 3 || categories[0].name != "google" || categories[0].size() != 2 {
+		t.Fatal("unexpected categories: ", categories)
+	}
+
+	i := &inspector{}
+	tests := []struct {
+		category *category
+		match    string
+		want     bool
+	}{
+		{categories[0], "mail.google.com", true},
+		{categories[0], "www.google.cn", true},
+		{categories[0], "google.cn", false},
+		{categories[1], "store.steampowered.com", true},
+		{categories[1], "mail.google.com", false},
+	}
+	for _, tt := range tests {
+		if got := i.inspect(tt.category, net.ParseAddress(tt.match)); got != tt.want {
+			t.Errorf("%s in %s: %v, want %v", tt.match, tt.category.name, got, tt.want)
+		}
+	}
+	if i.errs != 0 {
+		t.Error("unexpected errors in valid categories: ", i.errs)
+	}
+	i.inspect(categories[2], nil)
+	if i.errs != 1 {
+		t.Error("expected an invalid regexp to be an error")
+	}
+
+	if s := formatDomain(categories[0].domains[1]); s != "full:www.google.cn @cn" {
+		t.Error("unexpected formatted domain: ", s)
+	}
+}
+
+func TestInspectGeoIP(t *testing.T) {
+	data, err := proto.Marshal(&router.GeoIPList{Entry: []*router.GeoIP{
+		{CountryCode: "PRIVATE", Cidr: []*router.CIDR{
+			{Ip: net.ParseIP("10.0.0.0").To4(), Prefix: 8},
+			{Ip: net.ParseIP("fc00::"), Prefix: 7},
+		}},
+	}})
+	common.Must(err)
+	categories, err := decodeCategories("geoip", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := &inspector{}
+	if !i.inspect(categories[0], net.ParseAddress("10.1.2.3")) || !i.inspect(categories[0], net.ParseAddress("fd00::1")) {
+		t.Error("expected private IPs in private")
+	}
+	if i.inspect(categories[0], net.ParseAddress("8.8.8.8")) {
+		t.Error("expected 8.8.8.8 not in private")
+	}
+
+	if _, err := decodeCategories("geoip", []byte("not a geo data file")); err == nil {
+		t.Error("expected an invalid file to fail")
+	}
+	if _, err := decodeCategories("geosite", nil); err == nil {
+		t.Error("expected a file without categories to fail")
+	}
+}
+
+func TestCheckSum(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "geosite.dat")
+	sum := sha256.Sum256([]byte("data"))
+
+	i := &inspector{}
+	i.checkSum(path, sum[:])
+	if i.errs != 0 {
+		t.Error("expected files without a checksum file to pass")
+	}
+	common.Must(os.WriteFile(path+".sha256sum", []byte(hex.EncodeToString(sum[:])+"  geosite.dat\n"), 0o644))
+	i.checkSum(path, sum[:])
+	if i.errs != 0 {
+		t.Error("expected the checksum to match")
+	}
+	other := sha256.Sum256([]byte("other"))
+	i.checkSum(path, other[:])
+	if i.errs != 1 {
+		t.Error("expected a checksum mismatch")
+	}
+}
@@ -0,0 +1,69 @@
+package geodata
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/xtls/xray-core/common/geodata"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdUpdate = &base.Command{
+	UsageLine: "{{.Exec}} geodata update [-mirror url] [-pubkey key] [file]...",
+	Short:     "Download the latest geo data files",
+	Long: `
+Download the latest geoip.dat and geosite.dat, or the files given, into
+the asset directory (set by XRAY_LOCATION_ASSET, or the directory of
+the executable). Files already up to date are not downloaded.
+
+Each file is verified against the SHA-256 checksum in "<file>.sha256sum"
+of the mirror before it replaces the current one, which is kept if
+anything fails.
+
+Arguments:
+
+	-mirror
+		URL the file names are appended to. Defaults to the latest
+		release of Loyalsoldier/v2ray-rules-dat.
+
+	-pubkey
+		Ed25519 public key, in base64 or hex, the files must be signed
+		with. The signature is read from "<file>.sig".
+
+A running Xray picks up the new files on reload (SIGHUP), or by itself
+with "xray run -geodata-update".
+`,
+}
+
+func init() {
+	cmdUpdate.Run = executeUpdate // break init loop
+}
+
+var (
+	updateMirror = cmdUpdate.Flag.String("mirror", geodata.DefaultMirror, "")
+	updatePubKey = cmdUpdate.Flag.String("pubkey", "", "")
+)
+
+func executeUpdate(cmd *base.Command, args []string) {
+	u := &geodata.Updater{
+		Mirror: *updateMirror,
+		Files:  args,
+	}
+	if *updatePubKey != "" {
+		key, err := geodata.ParsePublicKey(*updatePubKey)
+		if err != nil {
+			base.Fatalf("%s", err)
+		}
+		u.PublicKey = key
+	}
+	updated, err := u.Update(context.Background())
+	if len(updated) > 0 {
+		fmt.Println("Updated", strings.Join(updated, ", "))
+	} else if err == nil {
+		fmt.Println("Already up to date.")
+	}
+	if err != nil {
+		base.Fatalf("%s", err)
+	}
+}
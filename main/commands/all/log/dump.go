@@ -0,0 +1,134 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/app/commander/apiclient"
+	logService "github.com/xtls/xray-core/app/log/command"
+	clog "github.com/xtls/xray-core/common/log"
+	creflect "github.com/xtls/xray-core/common/reflect"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdDump = &base.Command{
+	UsageLine: "{{.Exec}} log dump [-s 127.0.0.1:8080] [-n entries] [-level warning] [-type access,error,dns] [-filter '']",
+	Short:     "Print the entries kept by the memory log",
+	Long: `
+Print the entries Xray kept in memory, oldest first, for the access and
+error logs set to "memory" in the "log" object of the config. Such logs
+are not written to disk, and keep their last "memorySize" entries, 1000
+by default. The API must be enabled, with "LoggerService".
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling the API. Default 3
+
+	-n <entries>
+		Number of the last entries to print, all of them if 0. Default 0
+
+	-level <level>
+		Least severe level of error log entries to print: error, warning,
+		info or debug. Default debug
+
+	-type <types>
+		Comma-separated types of entries to print: access, error and dns.
+		Default all of them
+
+	-filter <substring>
+		Only print entries containing the substring.
+
+	-json
+		Print the entries as JSON, with their types, levels and times.
+
+Example:
+
+	{{.Exec}} {{.LongName}} -s 127.0.0.1:8080 -n 100 -level warning
+`,
+}
+
+func init() {
+	cmdDump.Run = executeDump // break init loop
+}
+
+var (
+	dumpServer  string
+	dumpTimeout int
+	dumpLimit   = cmdDump.Flag.Uint("n", 0, "")
+	dumpLevel   = cmdDump.Flag.String("level", "debug", "")
+	dumpTypes   = cmdDump.Flag.String("type", "", "")
+	dumpFilter  = cmdDump.Flag.String("filter", "", "")
+	dumpJSON    = cmdDump.Flag.Bool("json", false, "")
+
+	_ = func() bool {
+		cmdDump.Flag.StringVar(&dumpServer, "s", "127.0.0.1:8080", "")
+		cmdDump.Flag.StringVar(&dumpServer, "server", "127.0.0.1:8080", "")
+		cmdDump.Flag.IntVar(&dumpTimeout, "t", 3, "")
+		cmdDump.Flag.IntVar(&dumpTimeout, "timeout", 3, "")
+		return true
+	}()
+)
+
+func executeDump(cmd *base.Command, args []string) {
+	r := &logService.DumpLogRequest{
+		Filter: *dumpFilter,
+		Limit:  uint32(*dumpLimit),
+	}
+	switch strings.ToLower(*dumpLevel) {
+	case "error":
+		r.Level = clog.Severity_Error
+	case "warning":
+		r.Level = clog.Severity_Warning
+	case "info":
+		r.Level = clog.Severity_Info
+	case "debug", "":
+		r.Level = clog.Severity_Debug
+	default:
+		base.Fatalf("unknown log level: %s", *dumpLevel)
+	}
+	if *dumpTypes != "" {
+		for _, t := range strings.Split(*dumpTypes, ",") {
+			switch strings.ToLower(strings.TrimSpace(t)) {
+			case "access":
+				r.Type = append(r.Type, logService.LogType_Access)
+			case "error":
+				r.Type = append(r.Type, logService.LogType_Error)
+			case "dns":
+				r.Type = append(r.Type, logService.LogType_DNS)
+			default:
+				base.Fatalf("unknown log type: %s", t)
+			}
+		}
+	}
+
+	timeout := time.Duration(dumpTimeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	client, err := apiclient.Dial(ctx, dumpServer, apiclient.WithTimeout(timeout))
+	if err != nil {
+		base.Fatalf("%s", err)
+	}
+	defer client.Close()
+
+	resp, err := logService.NewLoggerServiceClient(client.Conn()).DumpLog(ctx, r)
+	if err != nil {
+		base.Fatalf("failed to dump log: %s", err)
+	}
+	for _, entry := range resp.Entries {
+		if *dumpJSON {
+			j, ok := creflect.MarshalToJson(entry, false)
+			if !ok {
+				base.Fatalf("failed to encode entry")
+			}
+			fmt.Print(j)
+			continue
+		}
+		fmt.Println(time.UnixMilli(entry.Time).Format("2006/01/02 15:04:05.000000"), entry.Message)
+	}
+}
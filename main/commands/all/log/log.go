@@ -0,0 +1,18 @@
+package log
+
+import (
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+// CmdLog holds all log sub commands
+var CmdLog = &base.Command{
+	UsageLine: "{{.Exec}} log",
+	Short:     "Log tools",
+	Long: `{{.Exec}} {{.LongName}} reads the access and error logs Xray writes, or keeps in
+memory.
+`,
+	Commands: []*base.Command{
+		cmdTail,
+		cmdDump,
+	},
+}
@@ -0,0 +1,275 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/common/cmdarg"
+	"github.com/xtls/xray-core/common/platform"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf/serial"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdTail = &base.Command{
+	UsageLine: "{{.Exec}} log tail [-n lines] [-f] [-c config.json] [-type access|error] [file]...",
+	Short:     "Print the last lines of the logs",
+	Long: `
+Print the last lines of the given log files, or of the access and error
+logs the config writes to. Rotated files are not read.
+
+Arguments:
+
+	-n
+		Number of lines to print of each file. Default 10.
+
+	-f
+		Keep printing lines as they are written, following the files
+		across rotations.
+
+	-c, -config
+		Config file to read the log paths from. Defaults to config.json
+		in the working directory.
+
+	-type
+		Only the "access" or the "error" log of the config.
+
+Example:
+
+	{{.Exec}} {{.LongName}} -f -type access -c config.json
+`,
+}
+
+func init() {
+	cmdTail.Run = executeTail // break init loop
+}
+
+var (
+	configFiles cmdarg.Arg
+	tailLines   = cmdTail.Flag.Int("n", 10, "")
+	tailFollow  = cmdTail.Flag.Bool("f", false, "")
+	tailType    = cmdTail.Flag.String("type", "", "")
+
+	_ = func() bool {
+		cmdTail.Flag.Var(&configFiles, "config", "")
+		cmdTail.Flag.Var(&configFiles, "c", "")
+		return true
+	}()
+)
+
+const pollInterval = 500 * time.Millisecond
+
+func executeTail(cmd *base.Command, args []string) {
+	files := args
+	if len(files) == 0 {
+		var err error
+		if files, err = configuredLogs(*tailType); err != nil {
+			base.Fatalf("%s", err)
+		}
+	}
+	followers := make([]*follower, 0, len(files))
+	for _, file := range files {
+		f := &follower{path: file}
+		if len(files) > 1 {
+			f.prefix = filepath.Base(file) + ": "
+		}
+		if err := f.open(); err != nil {
+			base.Fatalf("%s", err)
+		}
+		if err := f.tail(*tailLines); err != nil {
+			base.Fatalf("failed to read %s: %s", file, err)
+		}
+		followers = append(followers, f)
+	}
+	if !*tailFollow {
+		return
+	}
+	for {
+		time.Sleep(pollInterval)
+		for _, f := range followers {
+			if err := f.follow(); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to read %s: %s\n", f.path, err)
+			}
+		}
+	}
+}
+
+// configuredLogs returns the paths of the log files the config writes to, the access or the
+// error log only if logType tells so.
+func configuredLogs(logType string) ([]string, error) {
+	files := configFiles
+	if len(files) == 0 {
+		if workingDir, err := os.Getwd(); err == nil {
+			if configFile := filepath.Join(workingDir, "config.json"); fileExists(configFile) {
+				files = cmdarg.Arg{configFile}
+			}
+		}
+	}
+	if len(files) == 0 {
+		if configFile := platform.GetConfigurationPath(); configFile != "" {
+			files = cmdarg.Arg{configFile}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no log file given, and no config file found")
+	}
+	var sources []*core.ConfigSource
+	for _, file := range files {
+		format := core.GetFormatByExtension(strings.TrimPrefix(filepath.Ext(file), "."))
+		if format == "" || format == "protobuf" {
+			return nil, fmt.Errorf("log paths can only be read from JSON, YAML and TOML configs")
+		}
+		sources = append(sources, &core.ConfigSource{Name: file, Format: format})
+	}
+	config, err := serial.DecodeConfigFromFiles(sources)
+	if err != nil {
+		return nil, err
+	}
+	if config.LogConfig == nil {
+		return nil, fmt.Errorf("the config doesn't write logs to files")
+	}
+	var logs []string
+	add := func(t, path string) {
+		if (logType == "" || logType == t) && path != "" && path != "none" {
+			logs = append(logs, path)
+		}
+	}
+	switch logType {
+	case "", "access", "error":
+	default:
+		return nil, fmt.Errorf("unknown log type %q", logType)
+	}
+	add("access", config.LogConfig.AccessLog)
+	add("error", config.LogConfig.ErrorLog)
+	if len(logs) == 0 {
+		return nil, fmt.Errorf("the config doesn't write logs to files")
+	}
+	return logs, nil
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// follower prints the lines written to a log file.
+type follower struct {
+	path   string
+	prefix string
+	file   *os.File
+	reader *bufio.Reader
+	offset int64
+	// partial is a line read before it's fully written.
+	partial []byte
+}
+
+func (f *follower) open() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	if f.file != nil {
+		f.file.Close()
+	}
+	f.file = file
+	f.reader = bufio.NewReader(file)
+	f.offset = 0
+	f.partial = nil
+	return nil
+}
+
+// tail prints the last n lines of the file, and leaves it at its end.
+func (f *follower) tail(n int) error {
+	info, err := f.file.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	start := size
+	if n <= 0 {
+		if _, err := f.file.Seek(size, io.SeekStart); err != nil {
+			return err
+		}
+		f.reader.Reset(f.file)
+		f.offset = size
+		return nil
+	}
+	block := make([]byte, 4096)
+	lines := 0
+	for start > 0 && lines <= n {
+		read := int64(len(block))
+		if read > start {
+			read = start
+		}
+		start -= read
+		if _, err := f.file.ReadAt(block[:read], start); err != nil {
+			return err
+		}
+		for i := read - 1; i >= 0; i-- {
+			// The line break ending the file doesn't begin a line.
+			if block[i] != '\n' || start+i == size-1 {
+				continue
+			}
+			if lines++; lines > n {
+				start += i + 1
+				break
+			}
+		}
+	}
+	if _, err := f.file.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	f.reader.Reset(f.file)
+	f.offset = start
+	return f.print()
+}
+
+// print prints the complete lines written since the last call.
+func (f *follower) print() error {
+	for {
+		line, err := f.reader.ReadBytes('\n')
+		f.offset += int64(len(line))
+		if err == io.EOF {
+			f.partial = append(f.partial, line...)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(f.partial) > 0 {
+			line = append(f.partial, line...)
+			f.partial = nil
+		}
+		fmt.Printf("%s%s\n", f.prefix, bytes.TrimRight(line, "\r\n"))
+	}
+}
+
+// follow prints the lines written since the last call, reopening the file when it has been
+// rotated or truncated.
+func (f *follower) follow() error {
+	if err := f.print(); err != nil {
+		return err
+	}
+	info, err := os.Stat(f.path)
+	if err != nil {
+		// The file is being rotated and not created yet.
+		return nil
+	}
+	current, err := f.file.Stat()
+	if err != nil {
+		return err
+	}
+	if os.SameFile(info, current) && info.Size() >= f.offset {
+		return nil
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	return f.print()
+}
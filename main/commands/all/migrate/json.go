@@ -0,0 +1,186 @@
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// object is a JSON object keeping the order of its members, so that migrated configs read as
+// their originals do.
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newObject() *object {
+	return &object{values: make(map[string]interface{})}
+}
+
+func (o *object) get(key string) (interface{}, bool) {
+	v, found := o.values[key]
+	return v, found
+}
+
+// set sets the member key, appending it if it's new.
+func (o *object) set(key string, value interface{}) {
+	if _, found := o.values[key]; !found {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *object) del(key string) {
+	if _, found := o.values[key]; !found {
+		return
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// rename renames the member from to to in place. It does nothing and returns false if there is
+// no member from, or there is a member to already.
+func (o *object) rename(from, to string) bool {
+	v, found := o.values[from]
+	if !found {
+		return false
+	}
+	if _, found := o.values[to]; found {
+		return false
+	}
+	delete(o.values, from)
+	o.values[to] = v
+	for i, k := range o.keys {
+		if k == from {
+			o.keys[i] = to
+		}
+	}
+	return true
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o *object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if err := marshal(&b, k); err != nil {
+			return nil, err
+		}
+		b.WriteByte(':')
+		if err := marshal(&b, o.values[k]); err != nil {
+			return nil, err
+		}
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// marshal appends v to b, without escaping HTML characters as json.Marshal does.
+func marshal(b *bytes.Buffer, v interface{}) error {
+	encoder := json.NewEncoder(b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	b.Truncate(b.Len() - 1) // the newline after v
+	return nil
+}
+
+// decode decodes a JSON document, with objects as *object and numbers as json.Number.
+func decode(r io.Reader) (interface{}, error) {
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	return decodeValue(d)
+}
+
+func decodeValue(d *json.Decoder) (interface{}, error) {
+	token, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		o := newObject()
+		for d.More() {
+			key, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(d)
+			if err != nil {
+				return nil, err
+			}
+			o.set(key.(string), value)
+		}
+		_, err = d.Token()
+		return o, err
+	case json.Delim('['):
+		a := []interface{}{}
+		for d.More() {
+			value, err := decodeValue(d)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, value)
+		}
+		_, err = d.Token()
+		return a, err
+	}
+	return token, nil
+}
+
+// encode writes v indented, without escaping HTML characters.
+func encode(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// clone returns a deep copy of v.
+func clone(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *object:
+		o := newObject()
+		for _, k := range v.keys {
+			o.set(k, clone(v.values[k]))
+		}
+		return o
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, item := range v {
+			a[i] = clone(item)
+		}
+		return a
+	}
+	return v
+}
+
+// objectAt returns the member key of o if it's an object, or nil.
+func objectAt(o *object, key string) *object {
+	v, _ := o.get(key)
+	member, _ := v.(*object)
+	return member
+}
+
+// arrayAt returns the member key of o if it's an array, or nil.
+func arrayAt(o *object, key string) []interface{} {
+	v, _ := o.get(key)
+	member, _ := v.([]interface{})
+	return member
+}
+
+// stringAt returns the member key of o if it's a string, or "".
+func stringAt(o *object, key string) string {
+	v, _ := o.get(key)
+	member, _ := v.(string)
+	return member
+}
@@ -0,0 +1,41 @@
+package migrate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	const in = `{"z": 1, "a": {"y": 1.50, "b": [true, null, "<&>"]}, "m": 12345678901234567890}`
+	v, err := decode(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := encode(&b, v); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := compact(t, b.String()), compact(t, in); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestObjectRename(t *testing.T) {
+	o := newObject()
+	o.set("a", 1)
+	o.set("b", 2)
+	o.set("c", 3)
+	if !o.rename("b", "d") {
+		t.Fatal("rename failed")
+	}
+	if o.rename("a", "c") {
+		t.Error("renamed over an existing member")
+	}
+	if o.rename("x", "y") {
+		t.Error("renamed a missing member")
+	}
+	if got := strings.Join(o.keys, ","); got != "a,d,c" {
+		t.Errorf("keys are %s, want a,d,c", got)
+	}
+}
@@ -0,0 +1,125 @@
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xtls/xray-core/core"
+	json_reader "github.com/xtls/xray-core/infra/conf/json"
+	"github.com/xtls/xray-core/infra/conf/serial"
+	"github.com/xtls/xray-core/main/commands/base"
+	"github.com/xtls/xray-core/main/confloader"
+)
+
+// CmdMigrate is the migrate command
+var CmdMigrate = &base.Command{
+	UsageLine: "{{.Exec}} migrate -c old.json [-o new.json]",
+	Short:     "Rewrite an old config to current syntax",
+	Long: `
+Rewrite a JSON config of an older version of Xray or V2Ray to current
+syntax, and report each change made, and each one left to do by hand.
+
+It migrates:
+
+	- The "inbound", "outbound", "inboundDetour" and "outboundDetour" of
+	  V2Ray, to "inbounds" and "outbounds".
+	- The global "transport", to the streamSettings of each proxy.
+	- The "settings" of "routing", to "routing" itself.
+	- The "domainOverride" of inbounds, to "sniffing".
+	- The HTTP (H2) and QUIC transports, to XHTTP stream-one. Their
+	  servers must be migrated too.
+	- Legacy XTLS, to TLS with the flow xtls-rprx-vision.
+	- "serverNameToVerify", to "verifyPeerCertInNames".
+	- The "Host" in "headers" of WebSocket, to "host".
+	- The TCP and SplitHTTP names of transports, to RAW and XHTTP.
+	- The "alterId" of VMess users. Inbounds with legacy clients get
+	  "legacyCompat".
+	- VLESS users without "encryption", and the flows of Trojan.
+	- The "noise" of freedom, to "noises".
+
+Comments are dropped, while the order of settings is kept. The report
+goes to stderr, and the migrated config to stdout or -o.
+
+Arguments:
+
+	-c, -config
+		The config file to migrate, in JSON.
+
+	-o
+		The file to write the migrated config to. Defaults to stdout.
+
+Examples:
+
+	{{.Exec}} {{.LongName}} -c old.json -o config.json
+`,
+}
+
+func init() {
+	CmdMigrate.Run = executeMigrate // break init loop
+}
+
+var (
+	configFile string
+	outputFile = CmdMigrate.Flag.String("o", "", "")
+
+	_ = func() bool {
+		CmdMigrate.Flag.StringVar(&configFile, "config", "", "")
+		CmdMigrate.Flag.StringVar(&configFile, "c", "", "")
+		return true
+	}()
+)
+
+func executeMigrate(cmd *base.Command, args []string) {
+	if configFile == "" {
+		base.Fatalf("no config file, pass it with -c")
+	}
+	if format := core.GetFormatByExtension(strings.TrimPrefix(filepath.Ext(configFile), ".")); format != "" && format != "json" {
+		base.Fatalf("%s: only JSON configs can be migrated", configFile)
+	}
+	r, err := confloader.LoadConfig(configFile)
+	if err != nil {
+		base.Fatalf("failed to load %s: %s", configFile, err)
+	}
+	v, err := decode(&json_reader.Reader{Reader: r})
+	if err != nil {
+		base.Fatalf("failed to decode %s: %s", configFile, err)
+	}
+	config, ok := v.(*object)
+	if !ok {
+		base.Fatalf("%s: the config is not a JSON object", configFile)
+	}
+
+	m := &migrator{}
+	m.migrate(config)
+
+	var b bytes.Buffer
+	if err := encode(&b, config); err != nil {
+		base.Fatalf("failed to encode the migrated config: %s", err)
+	}
+	for _, c := range m.changes {
+		fmt.Fprintln(os.Stderr, c)
+	}
+	manual := 0
+	for _, c := range m.changes {
+		if c.manual {
+			manual++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d changes, %d left to do by hand\n", len(m.changes)-manual, manual)
+	if migrated, err := serial.DecodeJSONConfig(bytes.NewReader(b.Bytes())); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: the migrated config fails to decode: %s\n", err)
+	} else if _, err := migrated.Build(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: the migrated config fails to build: %s\n", err)
+	}
+
+	if *outputFile == "" {
+		os.Stdout.Write(b.Bytes())
+		return
+	}
+	if err := os.WriteFile(*outputFile, b.Bytes(), 0o644); err != nil {
+		base.Fatalf("failed to write %s: %s", *outputFile, err)
+	}
+}
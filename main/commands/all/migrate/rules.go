@@ -0,0 +1,382 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// change is a change made to the config, or one left to do by hand.
+type change struct {
+	pointer string
+	message string
+	manual  bool
+}
+
+func (c change) String() string {
+	if c.manual {
+		return "manual: " + c.pointer + ": " + c.message
+	}
+	return c.pointer + ": " + c.message
+}
+
+type migrator struct {
+	changes []change
+}
+
+func (m *migrator) changed(pointer, format string, a ...interface{}) {
+	m.changes = append(m.changes, change{pointer: pointer, message: fmt.Sprintf(format, a...)})
+}
+
+func (m *migrator) manual(pointer, format string, a ...interface{}) {
+	m.changes = append(m.changes, change{pointer: pointer, message: fmt.Sprintf(format, a...), manual: true})
+}
+
+// transportSettings are the keys of the settings of transports in streamSettings, by network.
+var transportSettings = map[string]string{
+	"tcp":          "tcpSettings",
+	"raw":          "rawSettings",
+	"kcp":          "kcpSettings",
+	"mkcp":         "kcpSettings",
+	"ws":           "wsSettings",
+	"websocket":    "wsSettings",
+	"http":         "httpSettings",
+	"h2":           "httpSettings",
+	"quic":         "quicSettings",
+	"domainsocket": "dsSettings",
+	"ds":           "dsSettings",
+	"grpc":         "grpcSettings",
+	"httpupgrade":  "httpupgradeSettings",
+	"splithttp":    "splithttpSettings",
+	"xhttp":        "xhttpSettings",
+}
+
+// transportProxies are the protocols the global transport applied to in effect.
+var transportProxies = map[string]bool{
+	"vmess":       true,
+	"vless":       true,
+	"trojan":      true,
+	"shadowsocks": true,
+}
+
+func (m *migrator) migrate(config *object) {
+	m.migrateLayout(config)
+	m.migrateTransport(config)
+	m.migrateRouting(config)
+	for i, v := range arrayAt(config, "inbounds") {
+		if ib, ok := v.(*object); ok {
+			m.migrateHandler("/inbounds/"+strconv.Itoa(i), ib, true)
+		}
+	}
+	for i, v := range arrayAt(config, "outbounds") {
+		if ob, ok := v.(*object); ok {
+			m.migrateHandler("/outbounds/"+strconv.Itoa(i), ob, false)
+		}
+	}
+}
+
+// migrateLayout moves the handlers of V2Ray configs, a main one and detours, to the lists of
+// handlers, in place of them. The main outbound stays the first, which is the default one.
+func (m *migrator) migrateLayout(config *object) {
+	for _, kind := range []string{"inbound", "outbound"} {
+		existing := arrayAt(config, kind+"s")
+		var handlers []interface{}
+		placed := false
+		for _, key := range []string{kind, kind + "Detour"} {
+			v, found := config.get(key)
+			if !found {
+				continue
+			}
+			switch v := v.(type) {
+			case *object:
+				handlers = append(handlers, v)
+			case []interface{}:
+				handlers = append(handlers, v...)
+			}
+			if !placed {
+				placed = config.rename(key, kind+"s")
+			}
+			config.del(key)
+			m.changed("/"+key, "moved to %q", kind+"s")
+		}
+		if len(handlers) > 0 {
+			config.set(kind+"s", append(handlers, existing...))
+		} else if placed {
+			config.del(kind + "s")
+		}
+	}
+}
+
+// migrateTransport copies the settings of the removed global transport to the streamSettings of
+// the proxies using the transport they're of.
+func (m *migrator) migrateTransport(config *object) {
+	transport := objectAt(config, "transport")
+	if _, found := config.get("transport"); !found {
+		return
+	}
+	config.del("transport")
+	if transport == nil || len(transport.keys) == 0 {
+		m.changed("/transport", "removed, as it's no longer supported")
+		return
+	}
+	for _, kind := range []string{"inbounds", "outbounds"} {
+		for _, v := range arrayAt(config, kind) {
+			handler, ok := v.(*object)
+			if !ok || !transportProxies[stringAt(handler, "protocol")] {
+				continue
+			}
+			stream := objectAt(handler, "streamSettings")
+			network := "tcp"
+			if stream != nil && stringAt(stream, "network") != "" {
+				network = strings.ToLower(stringAt(stream, "network"))
+			}
+			key := transportSettings[network]
+			settings, found := transport.get(key)
+			if !found && key == "rawSettings" {
+				settings, found = transport.get("tcpSettings")
+			}
+			if !found {
+				continue
+			}
+			if stream == nil {
+				stream = newObject()
+				handler.set("streamSettings", stream)
+			}
+			if _, found := stream.get(key); !found {
+				stream.set(key, clone(settings))
+			}
+		}
+	}
+	m.changed("/transport", "removed, its settings are copied to the streamSettings of the proxies using them")
+}
+
+// migrateRouting moves the "settings" of V2Ray routing to routing itself.
+func (m *migrator) migrateRouting(config *object) {
+	routing := objectAt(config, "routing")
+	if routing == nil {
+		return
+	}
+	if _, found := routing.get("strategy"); found {
+		routing.del("strategy")
+		m.changed("/routing/strategy", "removed, rules are the only strategy")
+	}
+	settings := objectAt(routing, "settings")
+	if settings == nil {
+		return
+	}
+	routing.del("settings")
+	for _, k := range settings.keys {
+		if _, found := routing.get(k); !found {
+			routing.set(k, settings.values[k])
+		}
+	}
+	m.changed("/routing/settings", "moved to \"routing\"")
+}
+
+func (m *migrator) migrateHandler(p string, handler *object, inbound bool) {
+	if v, found := handler.get("domainOverride"); found {
+		handler.del("domainOverride")
+		if _, found := handler.get("sniffing"); !found {
+			sniffing := newObject()
+			sniffing.set("enabled", true)
+			sniffing.set("destOverride", v)
+			handler.set("sniffing", sniffing)
+		}
+		m.changed(p+"/domainOverride", "moved to \"sniffing\"")
+	}
+	if stream := objectAt(handler, "streamSettings"); stream != nil {
+		m.migrateStream(p+"/streamSettings", stream)
+	}
+	if settings := objectAt(handler, "settings"); settings != nil {
+		m.migrateSettings(p+"/settings", strings.ToLower(stringAt(handler, "protocol")), settings, inbound)
+	}
+}
+
+func (m *migrator) migrateStream(p string, stream *object) {
+	network := strings.ToLower(stringAt(stream, "network"))
+	switch network {
+	case "tcp":
+		stream.set("network", "raw")
+		m.changed(p+"/network", "TCP is renamed to RAW")
+	case "splithttp":
+		stream.set("network", "xhttp")
+		m.changed(p+"/network", "SplitHTTP is renamed to XHTTP")
+	case "http", "h2", "h3":
+		stream.set("network", "xhttp")
+		xhttp := newObject()
+		if http := objectAt(stream, "httpSettings"); http != nil {
+			switch host := http.values["host"].(type) {
+			case string:
+				xhttp.set("host", host)
+			case []interface{}:
+				if len(host) > 0 {
+					xhttp.set("host", host[0])
+				}
+				if len(host) > 1 {
+					m.manual(p+"/httpSettings/host", "XHTTP takes a single host, %v is kept", host[0])
+				}
+			}
+			if path, found := http.get("path"); found {
+				xhttp.set("path", path)
+			}
+		}
+		xhttp.set("mode", "stream-one")
+		stream.del("httpSettings")
+		if _, found := stream.get("xhttpSettings"); !found {
+			stream.set("xhttpSettings", xhttp)
+		}
+		m.changed(p+"/network", "HTTP transport is removed, changed to XHTTP stream-one, which the server needs too")
+	case "quic":
+		stream.set("network", "xhttp")
+		stream.del("quicSettings")
+		if _, found := stream.get("xhttpSettings"); !found {
+			xhttp := newObject()
+			xhttp.set("mode", "stream-one")
+			stream.set("xhttpSettings", xhttp)
+		}
+		m.changed(p+"/network", "QUIC transport is removed, changed to XHTTP stream-one, which the server needs too")
+		m.manual(p+"/tlsSettings", "XHTTP runs over QUIC with TLS and the ALPN h3 only")
+	case "domainsocket", "ds":
+		m.manual(p+"/network", "DomainSocket transport is removed, listen on or dial the path of the socket with RAW instead")
+	}
+	if stream.rename("tcpSettings", "rawSettings") {
+		m.changed(p+"/tcpSettings", "renamed to \"rawSettings\"")
+	}
+	if stream.rename("splithttpSettings", "xhttpSettings") {
+		m.changed(p+"/splithttpSettings", "renamed to \"xhttpSettings\"")
+	}
+
+	if strings.EqualFold(stringAt(stream, "security"), "xtls") {
+		stream.set("security", "tls")
+		stream.rename("xtlsSettings", "tlsSettings")
+		m.changed(p+"/security", "legacy XTLS is removed, changed to TLS with the flow xtls-rprx-vision")
+	}
+	if tls := objectAt(stream, "tlsSettings"); tls != nil {
+		if name := stringAt(tls, "serverNameToVerify"); name != "" {
+			tls.del("serverNameToVerify")
+			if _, found := tls.get("verifyPeerCertInNames"); !found {
+				tls.set("verifyPeerCertInNames", []interface{}{name})
+			}
+			m.changed(p+"/tlsSettings/serverNameToVerify", "moved to \"verifyPeerCertInNames\"")
+		}
+	}
+	if ws := objectAt(stream, "wsSettings"); ws != nil {
+		if headers := objectAt(ws, "headers"); headers != nil {
+			for _, k := range headers.keys {
+				if !strings.EqualFold(k, "host") {
+					continue
+				}
+				if _, found := ws.get("host"); !found {
+					ws.set("host", headers.values[k])
+				}
+				headers.del(k)
+				if len(headers.keys) == 0 {
+					ws.del("headers")
+				}
+				m.changed(p+"/wsSettings/headers/"+k, "moved to \"host\"")
+				break
+			}
+		}
+	}
+}
+
+func (m *migrator) migrateSettings(p, protocol string, settings *object, inbound bool) {
+	switch protocol {
+	case "vless":
+		if inbound {
+			m.migrateUsers(p+"/clients", arrayAt(settings, "clients"), func(up string, user *object) {
+				m.migrateVLESSFlow(up, user, false)
+			})
+			return
+		}
+		for i, v := range arrayAt(settings, "vnext") {
+			if server, ok := v.(*object); ok {
+				m.migrateUsers(p+"/vnext/"+strconv.Itoa(i)+"/users", arrayAt(server, "users"), func(up string, user *object) {
+					m.migrateVLESSFlow(up, user, true)
+					if _, found := user.get("encryption"); !found {
+						user.set("encryption", "none")
+						m.changed(up, "added \"encryption\": \"none\", which VLESS users need")
+					}
+				})
+			}
+		}
+	case "vmess":
+		if inbound {
+			legacy := false
+			m.migrateUsers(p+"/clients", arrayAt(settings, "clients"), func(up string, user *object) {
+				if alterID, found := user.get("alterId"); found {
+					if n, ok := alterID.(json.Number); ok && n.String() != "0" {
+						legacy = true
+						return
+					}
+					user.del("alterId")
+					m.changed(up+"/alterId", "removed, as 0 is the only value of AEAD clients")
+				}
+			})
+			if legacy {
+				if _, found := settings.get("legacyCompat"); !found {
+					settings.set("legacyCompat", true)
+					m.changed(p+"/legacyCompat", "added for the clients with \"alterId\"")
+				}
+				m.manual(p+"/clients", "clients with \"alterId\" authenticate with MD5, upgrade them to AEAD and remove \"alterId\"")
+			}
+			if _, found := settings.get("disableInsecureEncryption"); found {
+				settings.del("disableInsecureEncryption")
+				m.changed(p+"/disableInsecureEncryption", "removed, as it's no longer supported")
+			}
+			return
+		}
+		for i, v := range arrayAt(settings, "vnext") {
+			if server, ok := v.(*object); ok {
+				m.migrateUsers(p+"/vnext/"+strconv.Itoa(i)+"/users", arrayAt(server, "users"), func(up string, user *object) {
+					if _, found := user.get("alterId"); found {
+						user.del("alterId")
+						m.changed(up+"/alterId", "removed, as outbounds always use AEAD")
+					}
+				})
+			}
+		}
+	case "trojan":
+		for _, key := range []string{"clients", "servers"} {
+			m.migrateUsers(p+"/"+key, arrayAt(settings, key), func(up string, user *object) {
+				if _, found := user.get("flow"); found {
+					user.del("flow")
+					m.changed(up+"/flow", "removed, as Trojan has no flows")
+				}
+			})
+		}
+	case "freedom":
+		if noise, found := settings.get("noise"); found {
+			settings.del("noise")
+			if _, found := settings.get("noises"); !found {
+				settings.set("noises", []interface{}{noise})
+			}
+			m.changed(p+"/noise", "moved to \"noises\"")
+		}
+	}
+}
+
+// migrateUsers calls f with each user, and the pointer to it.
+func (m *migrator) migrateUsers(p string, users []interface{}, f func(string, *object)) {
+	for i, v := range users {
+		if user, ok := v.(*object); ok {
+			f(p+"/"+strconv.Itoa(i), user)
+		}
+	}
+}
+
+// migrateVLESSFlow changes the flows of legacy XTLS to xtls-rprx-vision, keeping -udp443 of
+// outbounds.
+func (m *migrator) migrateVLESSFlow(p string, user *object, outbound bool) {
+	flow := stringAt(user, "flow")
+	if !strings.HasPrefix(flow, "xtls-rprx-") || strings.HasPrefix(flow, "xtls-rprx-vision") {
+		return
+	}
+	vision := "xtls-rprx-vision"
+	if outbound && strings.HasSuffix(flow, "-udp443") {
+		vision += "-udp443"
+	}
+	user.set("flow", vision)
+	m.changed(p+"/flow", "legacy XTLS flow %s is removed, changed to %s", flow, vision)
+}
@@ -0,0 +1,139 @@
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// migrateJSON migrates the config in, returning it compacted along with the changes made.
+func migrateJSON(t *testing.T, in string) (string, []change) {
+	t.Helper()
+	v, err := decode(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, ok := v.(*object)
+	if !ok {
+		t.Fatal("not an object: ", in)
+	}
+	m := &migrator{}
+	m.migrate(config)
+	out, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), m.changes
+}
+
+func compact(t *testing.T, s string) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := json.Compact(&b, []byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "raw",
+			in: `{"outbounds": [{"protocol": "freedom", "streamSettings": {
+				"network": "tcp", "tcpSettings": {"header": {"type": "none"}}}}]}`,
+			want: `{"outbounds": [{"protocol": "freedom", "streamSettings": {
+				"network": "raw", "rawSettings": {"header": {"type": "none"}}}}]}`,
+		},
+		{
+			name: "xhttp",
+			in: `{"outbounds": [{"protocol": "freedom", "streamSettings": {
+				"network": "splithttp", "splithttpSettings": {"path": "/x"}}}]}`,
+			want: `{"outbounds": [{"protocol": "freedom", "streamSettings": {
+				"network": "xhttp", "xhttpSettings": {"path": "/x"}}}]}`,
+		},
+		{
+			name: "verifyPeerCertInNames",
+			in: `{"outbounds": [{"protocol": "freedom", "streamSettings": {
+				"security": "tls", "tlsSettings": {"serverNameToVerify": "example.com", "alpn": ["h2"]}}}]}`,
+			want: `{"outbounds": [{"protocol": "freedom", "streamSettings": {
+				"security": "tls", "tlsSettings": {"alpn": ["h2"], "verifyPeerCertInNames": ["example.com"]}}}]}`,
+		},
+		{
+			name: "noises",
+			in: `{"outbounds": [{"protocol": "freedom", "settings": {
+				"noise": {"type": "rand", "packet": "10-20", "delay": "10-16"}}}]}`,
+			want: `{"outbounds": [{"protocol": "freedom", "settings": {
+				"noises": [{"type": "rand", "packet": "10-20", "delay": "10-16"}]}}]}`,
+		},
+		{
+			name: "legacyCompat",
+			in: `{"inbounds": [{"protocol": "vmess", "settings": {"clients": [
+				{"id": "27848739-7e62-4138-9fd3-098a63964b6b", "alterId": 64},
+				{"id": "11c2a696-0366-4524-b8f0-9a9c21512b02", "alterId": 0}]}}]}`,
+			want: `{"inbounds": [{"protocol": "vmess", "settings": {"clients": [
+				{"id": "27848739-7e62-4138-9fd3-098a63964b6b", "alterId": 64},
+				{"id": "11c2a696-0366-4524-b8f0-9a9c21512b02"}], "legacyCompat": true}}]}`,
+		},
+		{
+			name: "inbound into inbounds",
+			in: `{"log": {}, "inbound": {"protocol": "socks", "port": 1080},
+				"inbounds": [{"protocol": "http", "port": 8080}]}`,
+			want: `{"log": {}, "inbounds": [{"protocol": "socks", "port": 1080},
+				{"protocol": "http", "port": 8080}]}`,
+		},
+		{
+			name: "inbound and detours",
+			in: `{"inbound": {"protocol": "socks", "port": 1080},
+				"inboundDetour": [{"protocol": "http", "port": 8080}]}`,
+			want: `{"inbounds": [{"protocol": "socks", "port": 1080},
+				{"protocol": "http", "port": 8080}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changes := migrateJSON(t, tt.in)
+			if want := compact(t, tt.want); got != want {
+				t.Errorf("migrated to\n%s\nwant\n%s", got, want)
+			}
+			if len(changes) == 0 {
+				t.Error("no changes reported")
+			}
+
+			again, changes := migrateJSON(t, got)
+			if again != got {
+				t.Errorf("migrating again changed\n%s\nto\n%s", got, again)
+			}
+			for _, c := range changes {
+				if !c.manual {
+					t.Error("migrating again made a change: ", c)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateCurrent(t *testing.T) {
+	const current = `{
+		"log": {"loglevel": "warning"},
+		"inbounds": [{"protocol": "vless", "port": 443, "settings": {
+			"clients": [{"id": "27848739-7e62-4138-9fd3-098a63964b6b", "flow": "xtls-rprx-vision"}],
+			"decryption": "none"},
+			"streamSettings": {"network": "raw", "security": "tls",
+				"tlsSettings": {"verifyPeerCertInNames": ["example.com"]}}}],
+		"outbounds": [{"protocol": "freedom", "settings": {"noises": [{"type": "rand", "packet": "10"}]},
+			"streamSettings": {"network": "xhttp", "xhttpSettings": {"path": "/x"}}}],
+		"routing": {"rules": [{"outboundTag": "direct", "network": "tcp"}]}
+	}`
+	got, changes := migrateJSON(t, current)
+	if want := compact(t, current); got != want {
+		t.Errorf("migrated to\n%s\nwant\n%s", got, want)
+	}
+	if len(changes) != 0 {
+		t.Error("unexpected changes: ", changes)
+	}
+}
@@ -0,0 +1,303 @@
+package ping
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/app/dns"
+	"github.com/xtls/xray-core/app/policy"
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common/cmdarg"
+	clog "github.com/xtls/xray-core/common/log"
+	xnet "github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/platform"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf"
+	confserial "github.com/xtls/xray-core/infra/conf/serial"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+// CmdPing is the ping command
+var CmdPing = &base.Command{
+	UsageLine: "{{.Exec}} ping [-c config.json] [-url url] [-count 3] [-timeout 5s] [tag]...",
+	Short:     "Test the latency of outbounds",
+	Long: `
+Test the latency of the outbounds of a config, fastest first, with:
+
+	TCP: the TCP handshake with the server of the outbound, directly.
+	HEAD: an HTTPS HEAD request to a test URL through the outbound,
+	including its handshakes.
+
+All tagged outbounds are tested unless tags are given. Inbounds of the
+config are not started.
+
+Arguments:
+
+	-c, -config
+		Config file. Multiple assign is accepted. Defaults to config.json
+		in working directory, or the config from environment.
+
+	-url
+		Test URL. Default "https://www.google.com/generate_204".
+
+	-count
+		Number of tests of each outbound. Default 3.
+
+	-timeout
+		Timeout of each test. Default 5s.
+`,
+}
+
+func init() {
+	CmdPing.Run = executePing // break init loop
+}
+
+var (
+	configFiles cmdarg.Arg
+	testURL     = CmdPing.Flag.String("url", "https://www.google.com/generate_204", "")
+	count       = CmdPing.Flag.Int("count", 3, "")
+	timeout     = CmdPing.Flag.Duration("timeout", 5*time.Second, "")
+
+	_ = func() bool {
+		CmdPing.Flag.Var(&configFiles, "config", "")
+		CmdPing.Flag.Var(&configFiles, "c", "")
+		return true
+	}()
+)
+
+// result is the outcome of the tests of an outbound.
+type result struct {
+	tag string
+	tcp latency
+	// head are the latencies of the HEAD requests, to sort the outbounds by.
+	head latency
+}
+
+// latency is the best and average durations of successful tests, out of total tests.
+type latency struct {
+	min, sum  time.Duration
+	ok, total int
+	err       error
+}
+
+func (l *latency) add(d time.Duration, err error) {
+	l.total++
+	if err != nil {
+		l.err = err
+		return
+	}
+	if l.ok == 0 || d < l.min {
+		l.min = d
+	}
+	l.sum += d
+	l.ok++
+}
+
+func (l latency) String() string {
+	switch {
+	case l.total == 0:
+		return "-"
+	case l.ok == 0:
+		return "failed"
+	}
+	s := fmt.Sprintf("%v avg, %v min", (l.sum / time.Duration(l.ok)).Round(time.Millisecond), l.min.Round(time.Millisecond))
+	if l.ok < l.total {
+		s += fmt.Sprintf(", %d/%d failed", l.total-l.ok, l.total)
+	}
+	return s
+}
+
+func executePing(cmd *base.Command, args []string) {
+	config, err := loadConfig(getConfigFiles())
+	if err != nil {
+		base.Fatalf("Failed to load config: %s", err)
+	}
+	pb, err := config.Build()
+	if err != nil {
+		base.Fatalf("Failed to build config: %s", err)
+	}
+	clog.ReplaceWithSeverityLogger(clog.Severity_Error)
+	instance, err := core.New(outboundsOnly(pb))
+	if err != nil {
+		base.Fatalf("Failed to create server: %s", err)
+	}
+	if err := instance.Start(); err != nil {
+		base.Fatalf("Failed to start server: %s", err)
+	}
+	defer instance.Close()
+
+	outbounds := selectOutbounds(config, CmdPing.Flag.Args())
+	if len(outbounds) == 0 {
+		base.Fatalf("No outbound to test")
+	}
+	fmt.Println("Testing", len(outbounds), "outbounds with", *testURL)
+
+	results := make([]*result, len(outbounds))
+	var wg sync.WaitGroup
+	for i, ob := range outbounds {
+		wg.Add(1)
+		go func(i int, ob *conf.OutboundDetourConfig) {
+			defer wg.Done()
+			r := &result{tag: ob.Tag}
+			servers := ob.ServerAddresses()
+			for n := 0; n < *count; n++ {
+				if len(servers) > 0 && !ob.DialedThroughOutbound() {
+					r.tcp.add(dialTCP(servers[0], *timeout))
+				}
+				r.head.add(headThrough(instance, ob.Tag, *testURL, *timeout))
+			}
+			results[i] = r
+		}(i, ob)
+	}
+	wg.Wait()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i].head, results[j].head
+		if (a.ok > 0) != (b.ok > 0) {
+			return a.ok > 0
+		}
+		return a.ok > 0 && a.sum/time.Duration(a.ok) < b.sum/time.Duration(b.ok)
+	})
+	fmt.Println("-------------------")
+	for _, r := range results {
+		fmt.Printf("%s\n  TCP:  %s\n  HEAD: %s\n", r.tag, r.tcp, r.head)
+		if r.head.ok < r.head.total && r.head.err != nil {
+			fmt.Printf("        %s\n", r.head.err)
+		}
+	}
+	fmt.Println("-------------------")
+	if results[0].head.ok > 0 {
+		fmt.Println("Fastest:", results[0].tag)
+	} else {
+		fmt.Println("All outbounds failed")
+		os.Exit(1)
+	}
+}
+
+func getConfigFiles() cmdarg.Arg {
+	if len(configFiles) > 0 {
+		return configFiles
+	}
+	if workingDir, err := os.Getwd(); err == nil {
+		configFile := filepath.Join(workingDir, "config.json")
+		if _, err := os.Stat(configFile); err == nil {
+			return cmdarg.Arg{configFile}
+		}
+	}
+	if configFile := platform.GetConfigurationPath(); configFile != "" {
+		return cmdarg.Arg{configFile}
+	}
+	return nil
+}
+
+func loadConfig(files cmdarg.Arg) (*conf.Config, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no config file found")
+	}
+	var sources []*core.ConfigSource
+	for _, file := range files {
+		format := core.GetFormatByExtension(strings.TrimPrefix(filepath.Ext(file), "."))
+		if format == "" || format == "protobuf" {
+			return nil, fmt.Errorf("only JSON, YAML and TOML configs are supported: %s", file)
+		}
+		sources = append(sources, &core.ConfigSource{Name: file, Format: format})
+	}
+	return confserial.DecodeConfigFromFiles(sources)
+}
+
+// outboundsOnly removes the inbounds of c, and the apps other than those outbounds need, such as
+// the API and metrics which listen on ports, and observatories which would probe as well.
+func outboundsOnly(c *core.Config) *core.Config {
+	keep := map[string]bool{
+		serial.GetMessageType(&dispatcher.Config{}):       true,
+		serial.GetMessageType(&proxyman.InboundConfig{}):  true,
+		serial.GetMessageType(&proxyman.OutboundConfig{}): true,
+		serial.GetMessageType(&dns.Config{}):              true,
+		serial.GetMessageType(&policy.Config{}):           true,
+	}
+	apps := c.App[:0]
+	for _, app := range c.App {
+		if keep[app.Type] {
+			apps = append(apps, app)
+		}
+	}
+	c.App = apps
+	c.Inbound = nil
+	return c
+}
+
+// selectOutbounds returns the outbounds tagged tags, or all tagged ones but those which can't
+// relay the test.
+func selectOutbounds(config *conf.Config, tags []string) []*conf.OutboundDetourConfig {
+	wanted := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		wanted[tag] = true
+	}
+	var outbounds []*conf.OutboundDetourConfig
+	for i := range config.OutboundConfigs {
+		ob := &config.OutboundConfigs[i]
+		if ob.Tag == "" {
+			continue
+		}
+		if len(tags) > 0 {
+			if !wanted[ob.Tag] {
+				continue
+			}
+		} else {
+			switch strings.ToLower(ob.Protocol) {
+			case "blackhole", "block", "dns", "loopback":
+				continue
+			}
+		}
+		outbounds = append(outbounds, ob)
+	}
+	return outbounds
+}
+
+func dialTCP(address string, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return 0, err
+	}
+	conn.Close()
+	return time.Since(start), nil
+}
+
+// headThrough sends a HEAD request to url through the outbound tagged tag, on a new connection.
+func headThrough(instance *core.Instance, tag string, url string, timeout time.Duration) (time.Duration, error) {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dest, err := xnet.ParseDestination(network + ":" + addr)
+				if err != nil {
+					return nil, err
+				}
+				return core.Dial(session.SetForcedOutboundTagToContext(ctx, tag), instance, dest)
+			},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout: timeout,
+	}
+	start := time.Now()
+	response, err := client.Head(url)
+	if err != nil {
+		return 0, err
+	}
+	response.Body.Close()
+	return time.Since(start), nil
+}
@@ -0,0 +1,117 @@
+package all
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/xtls/xray-core/main/commands/base"
+	"golang.org/x/crypto/curve25519"
+)
+
+var cmdRealityRotate = &base.Command{
+	UsageLine: `{{.Exec}} reality-rotate [-i "current private key"] [-overlap 168h] [-short-ids 1]`,
+	Short:     `Generate a new REALITY key to rotate to`,
+	Long: `
+Generate a new key pair and short IDs for a REALITY server, with the entries of
+"keys" in its realitySettings and the values of the clients.
+
+The server accepts the new key at once, and the current key until the overlap
+ends, so that the clients keep connecting while they are updated.
+
+Arguments:
+
+	-i <private key>
+		The current private key of the server (base64.RawURLEncoding), to
+		print its entry expiring at the end of the overlap.
+
+	-overlap <duration>
+		How long the current key is still accepted. Default 168h.
+
+	-short-ids <n>
+		The number of short IDs of the new key. Default 1.
+
+Example:
+
+	{{.Exec}} {{.LongName}} -i "current private key" -overlap 72h
+`,
+}
+
+func init() {
+	cmdRealityRotate.Run = executeRealityRotate // break init loop
+}
+
+var (
+	rotateCurrentKey = cmdRealityRotate.Flag.String("i", "", "")
+	rotateOverlap    = cmdRealityRotate.Flag.Duration("overlap", 7*24*time.Hour, "")
+	rotateShortIds   = cmdRealityRotate.Flag.Int("short-ids", 1, "")
+)
+
+type realityKey struct {
+	PrivateKey string   `json:"privateKey"`
+	ShortIds   []string `json:"shortIds,omitempty"`
+	NotBefore  string   `json:"notBefore,omitempty"`
+	NotAfter   string   `json:"notAfter,omitempty"`
+}
+
+func executeRealityRotate(cmd *base.Command, args []string) {
+	if *rotateShortIds < 1 {
+		base.Fatalf("invalid number of short IDs: %d", *rotateShortIds)
+	}
+	if *rotateCurrentKey != "" {
+		if key, err := base64.RawURLEncoding.DecodeString(*rotateCurrentKey); err != nil || len(key) != curve25519.ScalarSize {
+			base.Fatalf("invalid current private key: %s", *rotateCurrentKey)
+		}
+	}
+	privateKey := make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(privateKey); err != nil {
+		base.Fatalf("failed to generate private key: %s", err)
+	}
+	// Clamped as in Curve25519Genkey.
+	privateKey[0] &= 248
+	privateKey[31] &= 127
+	privateKey[31] |= 64
+	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+	if err != nil {
+		base.Fatalf("failed to compute public key: %s", err)
+	}
+	shortIds := make([]string, *rotateShortIds)
+	for i := range shortIds {
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			base.Fatalf("failed to generate short ID: %s", err)
+		}
+		shortIds[i] = hex.EncodeToString(id)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	fmt.Println(`Add the new key to "keys" in the realitySettings of the server:`)
+	printJSON(&realityKey{
+		PrivateKey: base64.RawURLEncoding.EncodeToString(privateKey),
+		ShortIds:   shortIds,
+		NotBefore:  now.Format(time.RFC3339),
+	})
+	if *rotateCurrentKey != "" {
+		fmt.Println(`Move the current "privateKey" into "keys" as this entry, which expires at the end of the overlap:`)
+		printJSON(&realityKey{
+			PrivateKey: *rotateCurrentKey,
+			NotAfter:   now.Add(*rotateOverlap).Format(time.RFC3339),
+		})
+	}
+	fmt.Println(`Set in the realitySettings of the clients:`)
+	printJSON(map[string]string{
+		"publicKey": base64.RawURLEncoding.EncodeToString(publicKey),
+		"shortId":   shortIds[0],
+	})
+}
+
+func printJSON(v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		base.Fatalf("failed to encode: %s", err)
+	}
+	fmt.Println(string(b))
+}
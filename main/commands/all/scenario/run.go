@@ -0,0 +1,258 @@
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/app/dns"
+	"github.com/xtls/xray-core/app/policy"
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/routing"
+	routing_session "github.com/xtls/xray-core/features/routing/session"
+	feature_stats "github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/infra/conf"
+	confserial "github.com/xtls/xray-core/infra/conf/serial"
+)
+
+// Result is the outcome of a check of a scenario, failed if Err is set.
+type Result struct {
+	Check string
+	Err   error
+}
+
+// Run starts the config of the scenario, makes its requests and checks the counters, and
+// returns the results of the checks. An error is returned if the config failed to start.
+func (s *Scenario) Run(timeout time.Duration) ([]Result, error) {
+	config, err := s.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	inbounds := make(map[string]bool, len(config.InboundConfigs))
+	for _, in := range config.InboundConfigs {
+		inbounds[in.Tag] = true
+	}
+	pb, err := config.Build()
+	if err != nil {
+		return nil, errors.New("failed to build config").Base(err)
+	}
+	instance, err := core.New(withoutListeners(pb))
+	if err != nil {
+		return nil, errors.New("failed to create server").Base(err)
+	}
+	if err := instance.Start(); err != nil {
+		return nil, errors.New("failed to start server").Base(err)
+	}
+	defer instance.Close()
+
+	var results []Result
+	for _, r := range s.Requests {
+		check := "request " + r.String()
+		if r.Inbound != "" && !inbounds[r.Inbound] {
+			results = append(results, Result{Check: check, Err: errors.New("no inbound tagged ", r.Inbound)})
+			continue
+		}
+		results = append(results, Result{Check: check, Err: r.run(instance, timeout)})
+	}
+
+	var sm feature_stats.Manager
+	if len(s.Expect.Counters) > 0 {
+		sm, _ = instance.GetFeature(feature_stats.ManagerType()).(feature_stats.Manager)
+	}
+	names := make([]string, 0, len(s.Expect.Counters))
+	for name := range s.Expect.Counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		want := s.Expect.Counters[name]
+		check := "counter " + name + " " + want.String()
+		var value int64
+		if sm != nil {
+			if counter := sm.GetCounter(name); counter != nil {
+				value = counter.Value()
+			}
+		}
+		result := Result{Check: check}
+		if !want.Match(value) {
+			result.Err = errors.New("counter is ", value)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (s *Scenario) loadConfig() (*conf.Config, error) {
+	if len(s.Xray) > 0 {
+		config, err := confserial.DecodeJSONConfig(bytes.NewReader(s.Xray))
+		if err != nil {
+			return nil, errors.New("failed to load config").Base(err)
+		}
+		return config, nil
+	}
+	sources := make([]*core.ConfigSource, 0, len(s.Config))
+	for _, file := range s.Config {
+		sources = append(sources, &core.ConfigSource{Name: file, Format: core.GetFormatByExtension(strings.TrimPrefix(filepath.Ext(file), "."))})
+	}
+	config, err := confserial.DecodeConfigFromFiles(sources)
+	if err != nil {
+		return nil, errors.New("failed to load config").Base(err)
+	}
+	return config, nil
+}
+
+// withoutListeners removes the inbounds of c, and the apps other than those routing and
+// counting requests need, such as the API and metrics which listen on ports.
+func withoutListeners(c *core.Config) *core.Config {
+	keep := map[string]bool{
+		serial.GetMessageType(&dispatcher.Config{}):       true,
+		serial.GetMessageType(&proxyman.InboundConfig{}):  true,
+		serial.GetMessageType(&proxyman.OutboundConfig{}): true,
+		serial.GetMessageType(&dns.Config{}):              true,
+		serial.GetMessageType(&policy.Config{}):           true,
+		serial.GetMessageType(&router.Config{}):           true,
+		serial.GetMessageType(&stats.Config{}):            true,
+	}
+	apps := c.App[:0]
+	for _, app := range c.App {
+		if keep[app.Type] {
+			apps = append(apps, app)
+		}
+	}
+	c.App = apps
+	c.Inbound = nil
+	return c
+}
+
+// context returns the context of the request as an inbound would have dispatched it.
+func (r *Request) context(dest net.Destination) context.Context {
+	in := &session.Inbound{Tag: r.Inbound}
+	if r.Source != "" {
+		in.Source = net.DestinationFromAddr(&net.TCPAddr{IP: net.ParseAddress(r.Source).IP()})
+		if dest.Network == net.Network_UDP {
+			in.Source.Network = net.Network_UDP
+		}
+	}
+	if r.User != "" {
+		in.User = &protocol.MemoryUser{Email: r.User, Level: r.Level}
+	}
+	ctx := session.ContextWithInbound(context.Background(), in)
+	ctx = session.ContextWithOutbounds(ctx, []*session.Outbound{{Target: dest, OriginalTarget: dest}})
+	return session.ContextWithContent(ctx, &session.Content{Protocol: r.Protocol})
+}
+
+// run makes the request Count times, and returns the first failed expectation.
+func (r *Request) run(instance *core.Instance, timeout time.Duration) error {
+	dest, err := net.ParseDestination(r.network() + ":" + r.Destination)
+	if err != nil {
+		return err
+	}
+	rt, ok := instance.GetFeature(routing.RouterType()).(routing.Router)
+	if !ok {
+		return errors.New("no router")
+	}
+	om := instance.GetFeature(outbound.ManagerType()).(outbound.Manager)
+	for i := 0; i < r.Count; i++ {
+		if err := r.route(rt, om, dest); err != nil {
+			return err
+		}
+		if r.Payload != "" {
+			if err := r.send(instance, dest, timeout); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Request) route(rt routing.Router, om outbound.Manager, dest net.Destination) error {
+	var outbound, rule string
+	route, err := rt.PickRoute(routing_session.AsRoutingContext(r.context(dest)))
+	if err == nil {
+		outbound, rule = route.GetOutboundTag(), route.GetRuleTag()
+	} else if errors.Cause(err) != common.ErrNoClue {
+		return errors.New("failed to route").Base(err)
+	} else if handler := om.GetDefaultHandler(); handler != nil {
+		// Requests no rule matches go to the first outbound.
+		outbound = handler.Tag()
+	}
+	if r.Expect.Outbound != "" && outbound != r.Expect.Outbound {
+		return errors.New("routed to ", outbound, " by rule [", rule, "], expected ", r.Expect.Outbound)
+	}
+	if r.Expect.Rule != "" && rule != r.Expect.Rule {
+		return errors.New("routed by rule [", rule, "], expected [", r.Expect.Rule, "]")
+	}
+	return nil
+}
+
+// send sends the payload through the outbound routed to, and reads the response until it's
+// closed, it contains the expected response or the timeout.
+func (r *Request) send(instance *core.Instance, dest net.Destination, timeout time.Duration) error {
+	conn, err := core.Dial(r.context(dest), instance, dest)
+	if err != nil {
+		return errors.New("failed to dial").Base(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(r.Payload)); err != nil {
+		return errors.New("failed to send payload").Base(err)
+	}
+
+	chunks := make(chan []byte)
+	go func() {
+		defer close(chunks)
+		for {
+			b := make([]byte, 8192)
+			n, err := conn.Read(b)
+			if n > 0 {
+				chunks <- b[:n]
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	var response []byte
+read:
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				break read
+			}
+			response = append(response, chunk...)
+			if r.Expect.Response != "" && bytes.Contains(response, []byte(r.Expect.Response)) {
+				break read
+			}
+		case <-deadline.C:
+			break read
+		}
+	}
+	conn.Close()
+	go func() {
+		for range chunks {
+		}
+	}()
+	if r.Expect.Response != "" && !bytes.Contains(response, []byte(r.Expect.Response)) {
+		if len(response) == 0 {
+			return errors.New("no response, expected ", r.Expect.Response)
+		}
+		return errors.New("response does not contain ", r.Expect.Response)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package scenario
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	clog "github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+// CmdScenario is the scenario command
+var CmdScenario = &base.Command{
+	UsageLine: "{{.Exec}} scenario [-timeout 5s] [-v] <scenario.yaml>...",
+	Short:     "Check the behavior of a config with scenarios",
+	Long: `
+Run a config in process, send requests through it and check where they
+are routed and what the counters of the stats are, as described by
+scenario files in YAML or JSON. It exits with status 1 if any check
+fails, to test rule sets for regressions.
+
+A scenario looks like:
+
+	name: ads are blocked
+	config: [config.json]    # relative to the scenario file
+	requests:
+	  - name: ads
+	    inbound: socks-in    # the request enters as if accepted by it
+	    destination: ads.example.com:443
+	    network: tcp         # or udp, default tcp
+	    source: 192.168.1.2  # optional, and so are the following
+	    user: alice@example.com
+	    level: 0
+	    protocol: tls        # as if sniffed
+	    count: 3
+	    expect:
+	      outbound: block
+	      rule: ads          # ruleTag of the rule routing it
+	  - destination: example.com:80
+	    payload: "HEAD / HTTP/1.1\r\nHost: example.com\r\n\r\n"
+	    expect:
+	      outbound: direct
+	      response: HTTP/1.1
+	expect:
+	  counters:
+	    outbound>>>direct>>>traffic>>>uplink: "> 0"
+	    outbound>>>block>>>traffic>>>downlink: 0
+
+Instead of config, xray holds the config itself. Inbounds are not
+started, so no ports are taken. Requests are only routed, unless they
+have a payload: it is then sent through the outbound routed to, and the
+response read until the outbound closes it, the expected response is
+seen or the timeout. Counters can be compared with =, !=, >, >=, < and
+<=, and count as 0 until registered.
+
+Arguments:
+
+	-timeout
+		Time to wait for each response. Default 5s.
+
+	-v
+		Print the checks passing too.
+`,
+}
+
+func init() {
+	CmdScenario.Run = executeScenario // break init loop
+}
+
+var (
+	responseTimeout = CmdScenario.Flag.Duration("timeout", 5*time.Second, "")
+	verbose         = CmdScenario.Flag.Bool("v", false, "")
+)
+
+func executeScenario(cmd *base.Command, args []string) {
+	if len(args) == 0 {
+		base.Fatalf("No scenario file given")
+	}
+	clog.ReplaceWithSeverityLogger(clog.Severity_Error)
+
+	var passed, failed int
+	for _, file := range args {
+		s, err := Load(file)
+		if err != nil {
+			base.Fatalf("%s", err)
+		}
+		fmt.Println("Scenario:", s.Name)
+		results, err := s.Run(*responseTimeout)
+		if err != nil {
+			fmt.Println("  FAIL ", err)
+			failed++
+			continue
+		}
+		for _, r := range results {
+			switch {
+			case r.Err != nil:
+				fmt.Printf("  FAIL  %s: %s\n", r.Check, r.Err)
+				failed++
+			default:
+				if *verbose {
+					fmt.Printf("  PASS  %s\n", r.Check)
+				}
+				passed++
+			}
+		}
+	}
+	fmt.Printf("%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,154 @@
+package scenario
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// Scenario is a config to run and the requests to check it with.
+type Scenario struct {
+	Name string `json:"name"`
+	// Config are the config files, relative to the scenario file.
+	Config []string `json:"config"`
+	// Xray is the config itself, instead of Config.
+	Xray     json.RawMessage `json:"xray"`
+	Requests []*Request      `json:"requests"`
+	Expect   struct {
+		// Counters are checked after all requests were made.
+		Counters map[string]*Comparison `json:"counters"`
+	} `json:"expect"`
+}
+
+// Request is sent Count times as if accepted by the inbound tagged Inbound.
+type Request struct {
+	Name        string `json:"name"`
+	Inbound     string `json:"inbound"`
+	Network     string `json:"network"`
+	Destination string `json:"destination"`
+	Source      string `json:"source"`
+	User        string `json:"user"`
+	Level       uint32 `json:"level"`
+	// Protocol is the protocol the request is taken as sniffed as, like "http" or "tls".
+	Protocol string `json:"protocol"`
+	// Payload, if set, is sent through the outbound the request is routed to. Otherwise the
+	// request is only routed.
+	Payload string `json:"payload"`
+	Count   int    `json:"count"`
+	Expect  struct {
+		Outbound string `json:"outbound"`
+		Rule     string `json:"rule"`
+		// Response is a part the response to Payload must contain.
+		Response string `json:"response"`
+	} `json:"expect"`
+}
+
+func (r *Request) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.network() + ":" + r.Destination
+}
+
+func (r *Request) network() string {
+	if r.Network == "" {
+		return "tcp"
+	}
+	return strings.ToLower(r.Network)
+}
+
+// Comparison is a number, or a number following one of =, !=, >, >=, < and <=.
+type Comparison struct {
+	op    string
+	value int64
+}
+
+func (c *Comparison) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		s = string(b)
+	}
+	s = strings.TrimSpace(s)
+	c.op = "="
+	for _, op := range []string{"!=", ">=", "<=", "=", ">", "<"} {
+		if strings.HasPrefix(s, op) {
+			c.op = op
+			s = strings.TrimSpace(s[len(op):])
+			break
+		}
+	}
+	value, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return errors.New("invalid comparison: ", string(b))
+	}
+	c.value = value
+	return nil
+}
+
+// Match returns whether v compares to the number as required.
+func (c *Comparison) Match(v int64) bool {
+	switch c.op {
+	case "!=":
+		return v != c.value
+	case ">":
+		return v > c.value
+	case ">=":
+		return v >= c.value
+	case "<":
+		return v < c.value
+	case "<=":
+		return v <= c.value
+	default:
+		return v == c.value
+	}
+}
+
+func (c *Comparison) String() string {
+	return c.op + " " + strconv.FormatInt(c.value, 10)
+}
+
+// Load reads a scenario in YAML or JSON, with the paths of its config files made relative to
+// the working directory.
+func Load(file string) (*Scenario, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	s := new(Scenario)
+	if err := yaml.Unmarshal(data, s); err != nil {
+		return nil, errors.New("failed to parse scenario ", file).Base(err)
+	}
+	if len(s.Config) == 0 && len(s.Xray) == 0 {
+		return nil, errors.New("scenario ", file, " has neither config nor xray")
+	}
+	if len(s.Config) > 0 && len(s.Xray) > 0 {
+		return nil, errors.New("scenario ", file, " has both config and xray")
+	}
+	for i, config := range s.Config {
+		if !filepath.IsAbs(config) {
+			s.Config[i] = filepath.Join(filepath.Dir(file), config)
+		}
+	}
+	if s.Name == "" {
+		s.Name = filepath.Base(file)
+	}
+	for i, r := range s.Requests {
+		if r.Destination == "" {
+			return nil, errors.New("request ", i, " of scenario ", file, " has no destination")
+		}
+		switch r.network() {
+		case "tcp", "udp":
+		default:
+			return nil, errors.New("request ", r, " of scenario ", file, " has unknown network ", r.Network)
+		}
+		if r.Count <= 0 {
+			r.Count = 1
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf"
+	"github.com/xtls/xray-core/proxy/blackhole"
+	"github.com/xtls/xray-core/proxy/freedom"
+	"google.golang.org/protobuf/proto"
+)
+
+// defaultRuleNames returns the standard rules given with -defaults.
+func defaultRuleNames() []string {
+	var names []string
+	for _, name := range strings.Split(*defaults, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// addDefaultRules puts the standard rules given with -defaults before the routing rules of c. They
+// go to the first freedom and blackhole outbounds, or to ones added as "direct" and "block".
+func addDefaultRules(c *core.Config) error {
+	names := defaultRuleNames()
+	if len(names) == 0 {
+		return nil
+	}
+	directTag, err := outboundTagOf(c, &freedom.Config{}, "direct")
+	if err != nil {
+		return err
+	}
+	blockTag := ""
+	for _, name := range names {
+		if name == "block-ads" {
+			if blockTag, err = outboundTagOf(c, &blackhole.Config{}, "block"); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	rules, err := conf.DefaultRules(names, directTag, blockTag)
+	if err != nil {
+		return err
+	}
+
+	routing := &router.Config{}
+	index := -1
+	for i, app := range c.App {
+		if app.Type != serial.GetMessageType(routing) {
+			continue
+		}
+		instance, err := app.GetInstance()
+		if err != nil {
+			return err
+		}
+		routing, index = instance.(*router.Config), i
+	}
+	routing.Rule = append(rules, routing.Rule...)
+	if index < 0 {
+		c.App = append(c.App, serial.ToTypedMessage(routing))
+	} else {
+		c.App[index] = serial.ToTypedMessage(routing)
+	}
+
+	used := map[string]bool{}
+	for _, rule := range rules {
+		used[rule.GetTag()] = true
+	}
+	addOutbound := func(tag string, settings *serial.TypedMessage) {
+		for _, ob := range c.Outbound {
+			if ob.Tag == tag {
+				return
+			}
+		}
+		if used[tag] {
+			c.Outbound = append(c.Outbound, &core.OutboundHandlerConfig{Tag: tag, ProxySettings: settings})
+		}
+	}
+	addOutbound(directTag, serial.ToTypedMessage(&freedom.Config{}))
+	addOutbound(blockTag, serial.ToTypedMessage(&blackhole.Config{}))
+	return nil
+}
+
+// outboundTagOf returns the tag of the first tagged outbound of c with settings of the type of
+// settings, or tag if there is none, failing if tag is taken by another outbound.
+func outboundTagOf(c *core.Config, settings proto.Message, tag string) (string, error) {
+	messageType := serial.GetMessageType(settings)
+	for _, ob := range c.Outbound {
+		if ob.Tag != "" && ob.ProxySettings != nil && ob.ProxySettings.Type == messageType {
+			return ob.Tag, nil
+		}
+	}
+	for _, ob := range c.Outbound {
+		if ob.Tag == tag {
+			return "", errors.New("outbound tag ", tag, " of the default rules is taken")
+		}
+	}
+	return tag, nil
+}
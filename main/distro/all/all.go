@@ -1,3 +1,15 @@
+// Package all registers the features of Xray, so that importing it builds the full Xray.
+//
+// Build tags pick a profile, leaving out the protocols and transports it doesn't need, to shrink
+// the binary for embedded devices:
+//
+//   - server-full, the default, has everything.
+//   - client, with the xray_client tag, leaves out the inbounds only servers use: VLESS, VMess,
+//     fallback and SNI.
+//   - minimal-router, with the xray_minimal_router tag, is a client for routers. It also leaves
+//     out VMess, WireGuard, TUN, mKCP, gRPC and XHTTP.
+//
+// "xray version -features" prints the profile and the features compiled in.
 package all
 
 import (
@@ -20,18 +32,26 @@ import (
 	// Other optional features.
 	_ "github.com/xtls/xray-core/app/dns"
 	_ "github.com/xtls/xray-core/app/dns/fakedns"
+	_ "github.com/xtls/xray-core/app/ftp"
+	_ "github.com/xtls/xray-core/app/httpapi"
 	_ "github.com/xtls/xray-core/app/log"
 	_ "github.com/xtls/xray-core/app/metrics"
 	_ "github.com/xtls/xray-core/app/policy"
 	_ "github.com/xtls/xray-core/app/reverse"
 	_ "github.com/xtls/xray-core/app/router"
+	_ "github.com/xtls/xray-core/app/scheduler"
+	_ "github.com/xtls/xray-core/app/shaper"
 	_ "github.com/xtls/xray-core/app/stats"
+	_ "github.com/xtls/xray-core/app/subscription"
+	_ "github.com/xtls/xray-core/app/tuner"
+	_ "github.com/xtls/xray-core/app/webhook"
 
 	// Fix dependency cycle caused by core import in internet package
 	_ "github.com/xtls/xray-core/transport/internet/tagged/taggedimpl"
 
 	// Developer preview features
 	_ "github.com/xtls/xray-core/app/observatory"
+	_ "github.com/xtls/xray-core/app/observatory/group"
 
 	// Inbound and outbound proxies.
 	_ "github.com/xtls/xray-core/proxy/blackhole"
@@ -43,18 +63,11 @@ import (
 	_ "github.com/xtls/xray-core/proxy/shadowsocks"
 	_ "github.com/xtls/xray-core/proxy/socks"
 	_ "github.com/xtls/xray-core/proxy/trojan"
-	_ "github.com/xtls/xray-core/proxy/vless/inbound"
 	_ "github.com/xtls/xray-core/proxy/vless/outbound"
-	_ "github.com/xtls/xray-core/proxy/vmess/inbound"
-	_ "github.com/xtls/xray-core/proxy/vmess/outbound"
-	_ "github.com/xtls/xray-core/proxy/wireguard"
 
 	// Transports
-	_ "github.com/xtls/xray-core/transport/internet/grpc"
 	_ "github.com/xtls/xray-core/transport/internet/httpupgrade"
-	_ "github.com/xtls/xray-core/transport/internet/kcp"
 	_ "github.com/xtls/xray-core/transport/internet/reality"
-	_ "github.com/xtls/xray-core/transport/internet/splithttp"
 	_ "github.com/xtls/xray-core/transport/internet/tcp"
 	_ "github.com/xtls/xray-core/transport/internet/tls"
 	_ "github.com/xtls/xray-core/transport/internet/udp"
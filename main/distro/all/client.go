@@ -0,0 +1,6 @@
+//go:build xray_client && !xray_minimal_router
+
+package all
+
+// Profile is the build profile, picking the features compiled in.
+const Profile = "client"
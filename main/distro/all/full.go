@@ -0,0 +1,13 @@
+//go:build !xray_minimal_router
+
+package all
+
+import (
+	// Proxies and transports left out of the minimal-router profile.
+	_ "github.com/xtls/xray-core/proxy/tun"
+	_ "github.com/xtls/xray-core/proxy/vmess/outbound"
+	_ "github.com/xtls/xray-core/proxy/wireguard"
+	_ "github.com/xtls/xray-core/transport/internet/grpc"
+	_ "github.com/xtls/xray-core/transport/internet/kcp"
+	_ "github.com/xtls/xray-core/transport/internet/splithttp"
+)
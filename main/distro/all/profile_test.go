@@ -0,0 +1,112 @@
+package all_test
+
+import (
+	"os"
+	"os/exec"
+	"slices"
+	"testing"
+
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	. "github.com/xtls/xray-core/main/distro/all"
+)
+
+// profileFeatures are the protocols and transports each profile has, and leaves out.
+var profileFeatures = map[string]struct {
+	protocols, transports               []string
+	withoutProtocols, withoutTransports []string
+}{
+	"server-full": {
+		protocols: []string{
+			"xray.proxy.fallback.Config",
+			"xray.proxy.sni.Config",
+			"xray.proxy.tun.Config",
+			"xray.proxy.vless.inbound.Config",
+			"xray.proxy.vless.outbound.Config",
+			"xray.proxy.vmess.inbound.Config",
+			"xray.proxy.vmess.outbound.Config",
+			"xray.proxy.wireguard.DeviceConfig",
+		},
+		transports: []string{"grpc", "mkcp", "splithttp", "tcp", "websocket"},
+	},
+	"client": {
+		protocols: []string{
+			"xray.proxy.tun.Config",
+			"xray.proxy.vless.outbound.Config",
+			"xray.proxy.vmess.outbound.Config",
+			"xray.proxy.wireguard.DeviceConfig",
+		},
+		transports: []string{"grpc", "mkcp", "splithttp", "tcp", "websocket"},
+		withoutProtocols: []string{
+			"xray.proxy.fallback.Config",
+			"xray.proxy.sni.Config",
+			"xray.proxy.vless.inbound.Config",
+			"xray.proxy.vmess.inbound.Config",
+		},
+	},
+	"minimal-router": {
+		protocols:  []string{"xray.proxy.vless.outbound.Config", "xray.proxy.socks.ClientConfig"},
+		transports: []string{"tcp", "websocket"},
+		withoutProtocols: []string{
+			"xray.proxy.fallback.Config",
+			"xray.proxy.sni.Config",
+			"xray.proxy.tun.Config",
+			"xray.proxy.vless.inbound.Config",
+			"xray.proxy.vmess.inbound.Config",
+			"xray.proxy.vmess.outbound.Config",
+			"xray.proxy.wireguard.DeviceConfig",
+		},
+		withoutTransports: []string{"grpc", "mkcp", "splithttp"},
+	},
+}
+
+func TestCompiledFeatures(t *testing.T) {
+	want, found := profileFeatures[Profile]
+	if !found {
+		t.Fatal("unknown profile ", Profile)
+	}
+	f := handlerService.CompiledFeatures()
+	for _, p := range want.protocols {
+		if !slices.Contains(f.Protocols, p) {
+			t.Error(Profile, " doesn't have protocol ", p)
+		}
+	}
+	for _, p := range want.withoutProtocols {
+		if slices.Contains(f.Protocols, p) {
+			t.Error(Profile, " has protocol ", p)
+		}
+	}
+	for _, tr := range want.transports {
+		if !slices.Contains(f.Transports, tr) {
+			t.Error(Profile, " doesn't have transport ", tr)
+		}
+	}
+	for _, tr := range want.withoutTransports {
+		if slices.Contains(f.Transports, tr) {
+			t.Error(Profile, " has transport ", tr)
+		}
+	}
+	if !slices.Equal(f.Security, []string{"reality", "tls"}) {
+		t.Error(Profile, " has security types ", f.Security)
+	}
+}
+
+// TestProfiles builds each profile and runs TestCompiledFeatures in it.
+func TestProfiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds every profile")
+	}
+	for profile, tags := range map[string]string{
+		"server-full":    "",
+		"client":         "xray_client",
+		"minimal-router": "xray_minimal_router",
+	} {
+		t.Run(profile, func(t *testing.T) {
+			cmd := exec.Command("go", "test", "-count=1", "-tags", tags, "-run", "^TestCompiledFeatures$", "-v", ".")
+			cmd.Env = os.Environ()
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("%v\n%s", err, out)
+			}
+		})
+	}
+}
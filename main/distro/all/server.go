@@ -0,0 +1,14 @@
+//go:build !xray_client && !xray_minimal_router
+
+package all
+
+import (
+	// Inbounds only servers use.
+	_ "github.com/xtls/xray-core/proxy/fallback"
+	_ "github.com/xtls/xray-core/proxy/sni"
+	_ "github.com/xtls/xray-core/proxy/vless/inbound"
+	_ "github.com/xtls/xray-core/proxy/vmess/inbound"
+)
+
+// Profile is the build profile, picking the features compiled in.
+const Profile = "server-full"
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/common/geodata"
+)
+
+// geodataUpdater returns the updater set by the -geodata flags, or nil if updating is off.
+func geodataUpdater() *geodata.Updater {
+	if *geodataInterval <= 0 {
+		return nil
+	}
+	u := &geodata.Updater{Mirror: *geodataMirror}
+	if *geodataPubKey != "" {
+		key, err := geodata.ParsePublicKey(*geodataPubKey)
+		if err != nil {
+			log.Println("Geo data is not updated:", err)
+			return nil
+		}
+		u.PublicKey = key
+	}
+	return u
+}
+
+// fetchMissingGeodata downloads the geo data files that don't exist yet, as the config may
+// not load without them. Files that exist are updated once the server is running.
+func fetchMissingGeodata(u *geodata.Updater) {
+	missing := u.Missing()
+	if len(missing) == 0 {
+		return
+	}
+	updated, err := (&geodata.Updater{
+		Mirror:    u.Mirror,
+		Files:     missing,
+		PublicKey: u.PublicKey,
+		Client:    u.Client,
+	}).Update(context.Background())
+	if len(updated) > 0 {
+		log.Println("Downloaded", strings.Join(updated, ", "))
+	}
+	if err != nil {
+		log.Println("Failed to download geo data:", err)
+	}
+}
+
+// refreshGeodata updates the geo data files now and every interval until end is closed, and
+// reloads the config when any of them changed, so that rules match with the new lists.
+func (r *reloader) refreshGeodata(u *geodata.Updater, interval time.Duration, end <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		updated, err := u.Update(context.Background())
+		if err != nil {
+			log.Println("Failed to update geo data, keep using the current files:", err)
+		}
+		if len(updated) > 0 {
+			log.Println("Updated", strings.Join(updated, ", "))
+			r.reload()
+		}
+
+		select {
+		case <-end:
+			return
+		case <-ticker.C:
+		}
+	}
+}
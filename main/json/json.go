@@ -25,10 +25,15 @@ func init() {
 					errors.LogInfo(context.Background(), "Reading config: ", arg)
 					r, err := confloader.LoadConfig(arg)
 					if err != nil {
-						return nil, errors.New("failed to read config: ", arg).Base(err)
+						diag := errors.Diagnose(errors.CodeConfigRead, err)
+						diag.File = arg
+						return nil, errors.New("failed to read config: ", arg).Base(diag)
 					}
 					c, err := serial.DecodeJSONConfig(r)
 					if err != nil {
+						if diag := errors.DiagnosticOf(err); diag != nil {
+							diag.File = arg
+						}
 						return nil, errors.New("failed to decode config: ", arg).Base(err)
 					}
 					if i == 0 {
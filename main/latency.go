@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/getlantern/systray"
+	"github.com/xtls/xray-core/app/observatory"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/extension"
+	"github.com/xtls/xray-core/features/routing"
+)
+
+// latencyTooltipInterval is how often the tray tooltip is refreshed.
+const latencyTooltipInterval = 10 * time.Second
+
+// updateLatencyTooltip keeps the tray tooltip showing the outbounds selected by the balancers and
+// the latencies measured by the observatory. current returns the running server, which changes when
+// switching profiles.
+func updateLatencyTooltip(current func() core.Server) {
+	for {
+		if instance, ok := current().(*core.Instance); ok {
+			if tooltip := latencyTooltip(instance); tooltip != "" {
+				systray.SetTooltip(tooltip)
+			}
+		}
+		time.Sleep(latencyTooltipInterval)
+	}
+}
+
+// latencyTooltip returns a line per balancer with its selection, then a line per observed outbound
+// with its latency, or "" if the server has neither.
+func latencyTooltip(instance *core.Instance) string {
+	var lines []string
+	router := instance.GetFeature(routing.RouterType())
+	if lister, ok := router.(routing.BalancerLister); ok {
+		if pt, ok := router.(routing.BalancerPrincipleTarget); ok {
+			for _, tag := range lister.ListBalancers() {
+				if targets, err := pt.GetPrincipleTarget(tag); err == nil && len(targets) > 0 && targets[0] != "" {
+					lines = append(lines, fmt.Sprintf("%s → %s", tag, strings.Join(targets, ", ")))
+				}
+			}
+		}
+	}
+	if ob, ok := instance.GetFeature(extension.ObservatoryType()).(extension.Observatory); ok {
+		if report, err := ob.GetObservation(context.Background()); err == nil {
+			if result, ok := report.(*observatory.ObservationResult); ok {
+				for _, status := range result.Status {
+					if status.Alive {
+						lines = append(lines, fmt.Sprintf("%s: %d ms", status.OutboundTag, status.Delay))
+					} else {
+						lines = append(lines, fmt.Sprintf("%s: down", status.OutboundTag))
+					}
+				}
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/proxy/freedom"
+)
+
+// pacPath is the path the PAC script is served at.
+const pacPath = "/proxy.pac"
+
+var (
+	// pacScript is the PAC script of the running config.
+	pacScript     atomic.Pointer[[]byte]
+	pacServerOnce sync.Once
+	pacServerErr  error
+)
+
+// pacProxy returns the proxy the PAC script routes through, the system proxy port.
+func pacProxy() string {
+	address := net.JoinHostPort("127.0.0.1", *sysProxyPort)
+	return "SOCKS5 " + address + "; SOCKS " + address
+}
+
+// updatePAC generates the PAC script from the routing rules of c, in PAC mode.
+func updatePAC(c *core.Config) {
+	if *sysProxyMode != "pac" {
+		return
+	}
+	script, err := buildPAC(c)
+	if err != nil {
+		log.Println("Failed to generate PAC script:", err)
+		return
+	}
+	pacScript.Store(&script)
+}
+
+// buildPAC returns the PAC script of c. Hosts routed to freedom outbounds are connected directly,
+// as are hosts matching no rule if the first outbound, the default one, is a freedom outbound.
+func buildPAC(c *core.Config) ([]byte, error) {
+	routing := &router.Config{}
+	for _, app := range c.App {
+		if app.Type != serial.GetMessageType(routing) {
+			continue
+		}
+		instance, err := app.GetInstance()
+		if err != nil {
+			return nil, err
+		}
+		routing = instance.(*router.Config)
+	}
+	isDirect := func(ob *core.OutboundHandlerConfig) bool {
+		return ob.ProxySettings != nil && ob.ProxySettings.Type == serial.GetMessageType(&freedom.Config{})
+	}
+	var directTags []string
+	for _, ob := range c.Outbound {
+		if isDirect(ob) && ob.Tag != "" {
+			directTags = append(directTags, ob.Tag)
+		}
+	}
+	defaultDirect := len(c.Outbound) > 0 && isDirect(c.Outbound[0])
+	return routing.PAC(pacProxy(), directTags, defaultDirect)
+}
+
+// startPACServer serves the PAC script at the PAC port of localhost, once, and returns its URL.
+func startPACServer() (string, error) {
+	address := net.JoinHostPort("127.0.0.1", *sysProxyPACPort)
+	pacServerOnce.Do(func() {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			pacServerErr = err
+			return
+		}
+		go http.Serve(listener, http.HandlerFunc(servePAC))
+	})
+	return "http://" + address + pacPath, pacServerErr
+}
+
+func servePAC(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != pacPath {
+		http.NotFound(w, r)
+		return
+	}
+	var script []byte
+	if p := pacScript.Load(); p != nil {
+		script = *p
+	} else {
+		// Until a config is loaded, everything goes through the proxy.
+		script, _ = (&router.Config{}).PAC(pacProxy(), nil, false)
+	}
+	w.Header().Set("Content-Type", "application/x-ns-proxy-autoconfig")
+	w.Write(script)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/getlantern/systray"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/sysproxy"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/inbound"
+)
+
+// paused tells whether the proxy is paused, which servers started by switching profiles are too.
+var paused atomic.Bool
+
+// pauseInbounds makes the inbounds of server refuse new connections while paused, or take them.
+func pauseInbounds(server core.Server) error {
+	instance, ok := server.(*core.Instance)
+	if !ok {
+		return errors.New("server can't be paused")
+	}
+	pauser, ok := instance.GetFeature(inbound.ManagerType()).(inbound.Pauser)
+	if !ok {
+		return errors.New("inbounds can't be paused")
+	}
+	pauser.SetPaused(paused.Load())
+	return nil
+}
+
+// setPaused pauses or resumes server. While paused, its inbounds refuse new connections, those in
+// progress going on. With -pause-mode=direct, the system proxy is disabled too, so that programs
+// connect directly; otherwise it keeps pointing at the paused inbounds, and nothing gets through.
+func setPaused(server core.Server, pause bool) error {
+	if *pauseMode != "block" && *pauseMode != "direct" {
+		return errors.New("invalid pause mode: ", *pauseMode)
+	}
+	paused.Store(pause)
+	if err := pauseInbounds(server); err != nil {
+		return err
+	}
+	if *pauseMode == "direct" && sysproxy.Supported() {
+		if pause {
+			disableSysProxy()
+		} else {
+			enableSysProxy()
+		}
+	}
+	if pause {
+		log.Println("Paused, new connections are refused")
+	} else {
+		log.Println("Resumed")
+	}
+	return nil
+}
+
+// pauseControl lets the HTTP API pause and resume the running server.
+type pauseControl struct {
+	current func() core.Server
+}
+
+func (pauseControl) Paused() bool {
+	return paused.Load()
+}
+
+func (p pauseControl) SetPaused(pause bool) error {
+	return setPaused(p.current(), pause)
+}
+
+// addPauseMenu adds a checkbox pausing the server current returns. It follows pausing through the
+// API as well.
+func addPauseMenu(current func() core.Server) {
+	item := systray.AddMenuItemCheckbox("Pause", "Refuse new connections, taking this device off the proxy", paused.Load())
+	update := func() {
+		if pause := paused.Load(); pause != item.Checked() {
+			if pause {
+				item.Check()
+			} else {
+				item.Uncheck()
+			}
+		}
+	}
+	go func() {
+		for range item.ClickedCh {
+			if err := setPaused(current(), !item.Checked()); err != nil {
+				log.Println("Failed to pause:", err)
+			}
+			update()
+		}
+	}()
+	go func() {
+		for range time.Tick(time.Second) {
+			update()
+		}
+	}()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/getlantern/systray"
+	"github.com/xtls/xray-core/common/cmdarg"
+	"github.com/xtls/xray-core/common/platform"
+)
+
+const (
+	// DefaultProfileDirLocation is the dir of profiles when no confdir is set.
+	DefaultProfileDirLocation = "~/.xray/profiles"
+	// LastProfileLocation is the file keeping the path of the last used profile.
+	LastProfileLocation = "~/.xray/last_profile"
+)
+
+// expandHome replaces the leading ~ of location with the home directory.
+func expandHome(location string) string {
+	if !strings.HasPrefix(location, "~") {
+		return location
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, location[1:])
+}
+
+// profileDir returns the dir of profiles: the confdir if any, or DefaultProfileDirLocation.
+func profileDir() string {
+	if dirExists(configDir) {
+		return configDir
+	}
+	if envConfDir := platform.GetConfDirPath(); dirExists(envConfDir) {
+		return envConfDir
+	}
+	return expandHome(DefaultProfileDirLocation)
+}
+
+// listProfiles returns the config files in the dir of profiles, in order of name.
+func listProfiles() []string {
+	dir := profileDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var profiles []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if matched, _ := regexp.MatchString(getRegepxByFormat(), entry.Name()); matched {
+			profiles = append(profiles, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return profiles
+}
+
+// lastProfile returns the profile used last, unless config files are given in command line, which
+// take precedence, or the profile is gone.
+func lastProfile(args cmdarg.Arg) string {
+	if len(args) > 0 {
+		return ""
+	}
+	b, err := os.ReadFile(expandHome(LastProfileLocation))
+	if err != nil {
+		return ""
+	}
+	profile := strings.TrimSpace(string(b))
+	if !fileExists(profile) {
+		return ""
+	}
+	return profile
+}
+
+// saveLastProfile remembers profile to be used at next start.
+func saveLastProfile(profile string) {
+	file := expandHome(LastProfileLocation)
+	if file == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		log.Println("Failed to save last profile:", err)
+		return
+	}
+	if err := os.WriteFile(file, []byte(profile+"\n"), 0o644); err != nil {
+		log.Println("Failed to save last profile:", err)
+	}
+}
+
+// profileFiles returns the config files of profile, or, without a profile, the ones found from the
+// config files given in command line.
+func profileFiles(args cmdarg.Arg, profile string) cmdarg.Arg {
+	if profile != "" {
+		return cmdarg.Arg{profile}
+	}
+	return resolveConfigFiles(args)
+}
+
+// addProfileMenu adds a checkbox per profile, restarting the server with the profile clicked.
+func addProfileMenu(r *reloader) {
+	profiles := listProfiles()
+	if r == nil || len(profiles) == 0 {
+		return
+	}
+
+	menu := systray.AddMenuItem("Profiles", "Switch the active config")
+	items := make(map[string]*systray.MenuItem, len(profiles))
+	active := r.activeProfile()
+	for _, profile := range profiles {
+		items[profile] = menu.AddSubMenuItemCheckbox(filepath.Base(profile), profile, profile == active)
+	}
+	for profile, item := range items {
+		go func(profile string, item *systray.MenuItem) {
+			for range item.ClickedCh {
+				if r.activeProfile() == profile {
+					item.Check()
+					continue
+				}
+				log.Println("Switching to profile", profile)
+				if err := r.switchProfile(profile); err != nil {
+					log.Println("Failed to switch profile:", err)
+				} else {
+					saveLastProfile(profile)
+				}
+				active := r.activeProfile()
+				for p, i := range items {
+					if p == active {
+						i.Check()
+					} else {
+						i.Uncheck()
+					}
+				}
+			}
+		}(profile, item)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/xtls/xray-core/common/cmdarg"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/platform"
+	"github.com/xtls/xray-core/core"
+)
+
+// watchInterval is how often config files are checked for changes.
+const watchInterval = 2 * time.Second
+
+// reloader rebuilds the config and applies it to a running server, on SIGHUP and, if watching,
+// when a config file or the confdir changes. It also restarts the server when switching profiles.
+type reloader struct {
+	sync.Mutex
+	server *core.Instance
+	// args are the config files given in command line, before confdir is added.
+	args cmdarg.Arg
+	// profile is the config file in use instead of args, if not empty.
+	profile string
+}
+
+func newReloader(server core.Server, args cmdarg.Arg, profile string) *reloader {
+	instance, ok := server.(*core.Instance)
+	if !ok {
+		return nil
+	}
+	r := &reloader{
+		server:  instance,
+		args:    append(cmdarg.Arg(nil), args...),
+		profile: profile,
+	}
+	instance.SetReloadSource(r.reloadConfig)
+	return r
+}
+
+// current returns the running server.
+func (r *reloader) current() *core.Instance {
+	r.Lock()
+	defer r.Unlock()
+	return r.server
+}
+
+// activeProfile returns the profile in use, or "" if the config files given in command line are.
+func (r *reloader) activeProfile() string {
+	r.Lock()
+	defer r.Unlock()
+	return r.profile
+}
+
+// switchProfile restarts the server with the config of profile. If the new server fails to start,
+// the previous config is brought back.
+func (r *reloader) switchProfile(profile string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	c, err := loadConfig(profileFiles(r.args, profile))
+	if err != nil {
+		return err
+	}
+	if err := r.server.Close(); err != nil {
+		log.Println("Failed to close server:", err)
+	}
+	server, err := startServer(c)
+	if err != nil {
+		if previous, perr := loadConfig(r.files()); perr == nil {
+			if server, perr := startServer(previous); perr == nil {
+				server.SetReloadSource(r.reloadConfig)
+				r.server = server
+			}
+		}
+		return errors.New("failed to start profile ", profile).Base(err)
+	}
+	server.SetReloadSource(r.reloadConfig)
+	r.server = server
+	r.profile = profile
+	updatePAC(c)
+	return nil
+}
+
+// run reloads on SIGHUP and file changes until end is closed.
+func (r *reloader) run(watch bool, end <-chan struct{}) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	last := r.fingerprint()
+	if watch {
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-end:
+			return
+		case <-hup:
+			log.Println("Received SIGHUP, reloading config")
+		case <-tick:
+			current := r.fingerprint()
+			if current == last {
+				continue
+			}
+			log.Println("Config files changed, reloading config")
+		}
+		last = r.fingerprint()
+		r.reload()
+	}
+}
+
+// files returns the config files as startXray resolves them, with the current content of confdir.
+func (r *reloader) files() cmdarg.Arg {
+	return profileFiles(r.args, r.profile)
+}
+
+// fingerprint describes the size and modification time of the config files and confdir,
+// so that a change of any of them, or a file added to confdir, changes it.
+func (r *reloader) fingerprint() string {
+	r.Lock()
+	defer r.Unlock()
+	files := r.files()
+	var parts []string
+	for _, dir := range []string{configDir, platform.GetConfDirPath()} {
+		if dirExists(dir) {
+			files = append(files, dir)
+		}
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			// stdin and remote configs can only be reloaded with SIGHUP.
+			continue
+		}
+		parts = append(parts, file+"|"+strconv.FormatInt(info.Size(), 10)+"|"+strconv.FormatInt(info.ModTime().UnixNano(), 10))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "\n")
+}
+
+func (r *reloader) reload() {
+	if _, err := r.reloadConfig(); err != nil {
+		log.Println("Failed to reload config:", err)
+	}
+}
+
+// reloadConfig reloads the server with the current config files, as the API does after replacing
+// geo data, and logs what changed.
+func (r *reloader) reloadConfig() (*core.ReloadResult, error) {
+	r.Lock()
+	defer r.Unlock()
+	c, err := loadConfig(r.files())
+	if err != nil {
+		return nil, errors.New("failed to load config, keep running with the current one").Base(err)
+	}
+
+	result, err := r.server.Reload(c)
+	if err != nil {
+		return result, err
+	}
+	updatePAC(c)
+	for _, change := range []struct {
+		what string
+		tags []string
+	}{
+		{"Added inbounds", result.AddedInbounds},
+		{"Removed inbounds", result.RemovedInbounds},
+		{"Replaced inbounds", result.ChangedInbounds},
+		{"Kept listeners and connections of inbounds", result.MigratedInbounds},
+		{"Added outbounds", result.AddedOutbounds},
+		{"Removed outbounds", result.RemovedOutbounds},
+		{"Replaced outbounds", result.ChangedOutbounds},
+	} {
+		if len(change.tags) > 0 {
+			log.Println(change.what+":", strings.Join(change.tags, ", "))
+		}
+	}
+	if result.RoutingChanged {
+		log.Println("Reloaded routing")
+	}
+	if len(result.RestartRequired) > 0 {
+		log.Println("Changes not applied until restart:", strings.Join(result.RestartRequired, ", "))
+	}
+	if !result.Changed() && len(result.RestartRequired) == 0 {
+		log.Println("Config reloaded, nothing changed")
+	}
+	return result, nil
+}
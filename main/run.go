@@ -1,27 +1,36 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/getlantern/systray"
 	"github.com/getlantern/systray/example/icon"
+	"github.com/xtls/xray-core/app/httpapi"
+	"github.com/xtls/xray-core/app/proxyman"
 	"github.com/xtls/xray-core/common/cmdarg"
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/geodata"
 	clog "github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/common/platform"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/common/status"
+	"github.com/xtls/xray-core/common/sysproxy"
 	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/outbound"
+	confserial "github.com/xtls/xray-core/infra/conf/serial"
 	"github.com/xtls/xray-core/main/commands/base"
 )
 
@@ -40,13 +49,119 @@ The -format=json flag sets the format of config files.
 Default "auto".
 
 The -test flag tells Xray to test config files only, 
-without launching the server.
-
-The -dump flag tells Xray to print the merged config.
-
-The -sysproxy-port=port flag enables system proxy at specified port (only for macOS)
-
-The -sysproxy-device=device flag enables system proxy at specified device (only for macOS)
+without launching the server. "xray check" reports all problems
+of config files at once instead.
+
+The -dump flag tells Xray to print the merged config. The
+-dump-format=yaml|toml|json flag sets its format, default "json", and
+the -redact flag masks its UUIDs, passwords and private keys, so that it
+can be shared in bug reports.
+
+When Xray fails to start, a line of JSON describing the failure (code,
+config file, JSON path and a suggestion) is written to stderr.
+
+The -partial flag tells Xray to keep running when some inbounds fail
+to start (e.g. port already in use), same as "tolerateInboundErrors".
+
+On SIGHUP, and when a config file or the confdir changes, Xray reloads
+the config: changed inbounds, outbounds and routing are applied without
+restarting, keeping other connections and the system proxy. An inbound
+whose listen address, port and transport stay the same keeps its
+listener and connections. The -watch=false flag turns off watching
+files.
+
+The -subscribe=url flag adds the servers of a subscription (a list of
+vmess://, vless://, trojan:// and ss:// share links, optionally in
+base64) as outbounds tagged "subscription-<name>". Multiple assign is
+accepted. Subscriptions can also be set in the "subscriptions" section
+of the config. They are refreshed every 12 hours by default, and the
+last fetched copy is kept so that Xray starts without network.
+
+The tray menu lists the config files of the confdir, or of
+~/.xray/profiles, as profiles. Clicking one restarts Xray with it
+alone, and it is used again at next start unless -config is given.
+
+The -sysproxy-port=port flag enables system proxy at specified port (macOS,
+Windows, and Linux with GNOME or KDE Plasma)
+
+The -sysproxy-device=device flag enables system proxy at specified device
+(only for macOS)
+
+The -sysproxy-mode=pac flag sets the system proxy to a proxy auto-config
+script instead of sending all traffic to the proxy. The script is served
+at http://127.0.0.1:port/proxy.pac, the port set by -sysproxy-pac-port,
+and routes hosts as the routing rules do: hosts routed to freedom
+outbounds are connected directly. Only rules matching domains, or IPs
+when the host is an IPv4 address, are applied. The default mode, global,
+sends all traffic to the proxy.
+
+The -tun flag adds a TUN inbound tagged "tun", routing all traffic of the
+device, UDP and programs ignoring the system proxy included, to Xray. The
+device and its routes are removed on exit. It needs root, or
+Administrator and wintun.dll on Windows.
+
+A "dns" inbound, listening for example on 127.0.0.1:53, lets the device
+use Xray as its resolver: A and AAAA queries are answered by the "dns"
+section, whose servers, such as https:// and quic:// ones, are queried
+through the proxy and picked by their "domains", geosite included.
+Other queries go to the server in "address" of its settings. Answers
+are cached, up to "cacheSize" of them, and "fakeIP": true answers with
+fake IPs of the fakedns server, for use together with -tun. The -sysdns
+flag sets the DNS of the active network service to the inbound on UDP
+port 53 once started, and restores it on exit (only for macOS).
+
+The -defaults=rules flag puts standard routing rules, comma separated,
+before those of the config: bypass-lan sends private domains and LAN
+IPs directly, bypass-localhost does so for localhost, and block-ads
+blocks the domains of geosite:category-ads-all. They go to the first
+freedom and blackhole outbounds, or to ones added as "direct" and
+"block". -defaults=bypass-lan,bypass-localhost,block-ads is a safe
+start with the system proxy.
+
+Rules accept built-in lists, needing no geo files: "macro:private" and
+"macro:localhost" in domains, "macro:lan" and "macro:localhost" in IPs.
+
+On SIGINT, SIGTERM or Quit, the system proxy is disabled first. The
+-drain=duration flag, like -drain=10s, then makes inbounds refuse new
+connections, and gives those in progress up to the duration to finish
+before closing. The -kill-switch flag keeps the system proxy until Xray
+is closed instead, and blocks the freedom outbounds while draining, so
+that no traffic leaves unproxied while shutting down.
+
+The Pause item of the tray menu, and PUT /pause of the "httpApi" section
+with {"paused": true}, pause the proxy without closing Xray: inbounds
+refuse new connections until resumed, letting those in progress finish.
+By default the system proxy keeps pointing at them, taking the device off
+the network. The -pause-mode=direct flag disables the system proxy while
+paused instead, so that programs connect directly.
+
+The -geodata-update=interval flag, like -geodata-update=24h, keeps
+geoip.dat and geosite.dat up to date: missing files are downloaded
+before the config is loaded, and newer files replace the current ones
+every interval, after which the config is reloaded so that routing
+rules use them. Files are downloaded from -geodata-mirror=url, the
+latest release of Loyalsoldier/v2ray-rules-dat by default, and checked
+against the "<file>.sha256sum" next to them, and against the signature
+in "<file>.sig" if -geodata-pubkey=key sets an Ed25519 public key.
+"xray geodata update" updates them once, and "xray api geodata"
+replaces them in a running Xray through the routing API.
+
+Once started, Xray prints a summary of the inbounds listening, the
+outbounds and their protocols, the number of routing rules, the DNS
+servers and the geo data files. The -status-json flag prints it as a
+line of JSON instead, for programs wrapping Xray. The "httpApi" section
+of the config serves it at /status too.
+
+The -statedir=dir flag sets a dir runtime state is kept in across
+restarts, same as the "xray.location.state" environment variable: the
+usage of quotas, fake IPs of fakedns, balancer overrides set through the
+API and cached subscriptions. Settings naming a file of their own, such
+as "usageFile", keep using it. Nothing but subscriptions is kept
+without a state dir.
+
+The "xray.location.cache" environment variable sets a dir the lists
+taken from geo data files and downloaded blocklists are cached in, so
+that later starts are faster. Nothing is cached without it.
 	`,
 }
 
@@ -58,13 +173,33 @@ func init() {
 }
 
 var (
-	configFiles    cmdarg.Arg // "Config file for Xray.", the option is customed type, parse in main
-	configDir      string
-	dump           = cmdRun.Flag.Bool("dump", false, "Dump merged config only, without launching Xray server.")
-	test           = cmdRun.Flag.Bool("test", false, "Test config file only, without launching Xray server.")
-	format         = cmdRun.Flag.String("format", "auto", "Format of input file.")
-	sysProxyPort   = cmdRun.Flag.String("sysproxy-port", "19800", "Enable system proxy at specified port (only for macOS)")
-	sysProxyDevice = cmdRun.Flag.String("sysproxy-device", "Wi-Fi", "Enable system proxy at specified device (only for macOS)")
+	configFiles     cmdarg.Arg // "Config file for Xray.", the option is customed type, parse in main
+	configDir       string
+	dump            = cmdRun.Flag.Bool("dump", false, "Dump merged config only, without launching Xray server.")
+	dumpFormat      = cmdRun.Flag.String("dump-format", "json", "Format of the dumped config: json, yaml or toml.")
+	redact          = cmdRun.Flag.Bool("redact", false, "Mask UUIDs, passwords and private keys in the dumped config.")
+	test            = cmdRun.Flag.Bool("test", false, "Test config file only, without launching Xray server.")
+	format          = cmdRun.Flag.String("format", "auto", "Format of input file.")
+	partial         = cmdRun.Flag.Bool("partial", false, "Keep running when some inbounds fail to start.")
+	watch           = cmdRun.Flag.Bool("watch", true, "Reload config when config files change.")
+	sysProxyPort    = cmdRun.Flag.String("sysproxy-port", "19800", "Enable system proxy at specified port")
+	sysProxyDevice  = cmdRun.Flag.String("sysproxy-device", "Wi-Fi", "Enable system proxy at specified device (only for macOS)")
+	sysProxyMode    = cmdRun.Flag.String("sysproxy-mode", "global", "System proxy mode: global or pac")
+	sysProxyPACPort = cmdRun.Flag.String("sysproxy-pac-port", "19801", "Port the PAC script is served at in pac mode")
+	tunMode         = cmdRun.Flag.Bool("tun", false, "Proxy all traffic of the device through a TUN inbound.")
+	defaults        = cmdRun.Flag.String("defaults", "", "Standard routing rules to add: bypass-lan, bypass-localhost, block-ads")
+	drainPeriod     = cmdRun.Flag.Duration("drain", 0, "Time connections in progress are given to finish on exit.")
+	killSwitch      = cmdRun.Flag.Bool("kill-switch", false, "Keep the system proxy and block direct outbounds until closed on exit.")
+	pauseMode       = cmdRun.Flag.String("pause-mode", "block", "What pausing does to the system proxy: block or direct")
+	geodataInterval = cmdRun.Flag.Duration("geodata-update", 0, "Interval geoip.dat and geosite.dat are updated at.")
+	statusJSON      = cmdRun.Flag.Bool("status-json", false, "Print the startup summary as a line of JSON.")
+	geodataMirror   = cmdRun.Flag.String("geodata-mirror", geodata.DefaultMirror, "URL geo data files are downloaded from.")
+	geodataPubKey   = cmdRun.Flag.String("geodata-pubkey", "", "Ed25519 public key geo data files must be signed with.")
+	sysDNSEnabled   = cmdRun.Flag.Bool("sysdns", false, "Set the system DNS to the inbound on UDP port 53 (only for macOS)")
+	stateDir        = cmdRun.Flag.String("statedir", "", "A dir runtime state is kept in across restarts")
+	sysProxy        *sysproxy.Proxy
+	// quit is closed by the Quit item of the tray menu.
+	quit = make(chan struct{})
 
 	/* We have to do this here because Golang's Test will also need to parse flag, before
 	 * main func in this file is run.
@@ -73,15 +208,24 @@ var (
 		cmdRun.Flag.Var(&configFiles, "config", "Config path for Xray.")
 		cmdRun.Flag.Var(&configFiles, "c", "Short alias of -config")
 		cmdRun.Flag.StringVar(&configDir, "confdir", "", "A dir with multiple json config")
+		cmdRun.Flag.Var(&subscribeURLs, "subscribe", "Subscription URL of outbounds.")
 
 		return true
 	}()
 )
 
 func executeRun(cmd *base.Command, args []string) {
-	if runtime.GOOS == "darwin" {
-		enableSysProxy(*sysProxyDevice, *sysProxyPort)
-		defer disableSysProxy(*sysProxyDevice)
+	if *stateDir != "" {
+		os.Setenv(platform.StateLocation, *stateDir)
+	}
+	sysProxy = sysproxy.New(*sysProxyDevice)
+	if sysproxy.Supported() {
+		enableSysProxy()
+		defer sysProxyOff.Do(disableSysProxy)
+		httpapi.RegisterSystemProxy(systemProxyControl{})
+	}
+	if *sysDNSEnabled && sysproxy.DNSSupported() {
+		defer sysDNSOff.Do(disableSysDNS)
 	}
 
 	if *dump {
@@ -91,11 +235,21 @@ func executeRun(cmd *base.Command, args []string) {
 	}
 
 	printVersion()
-	server, err := startXray()
+	cmdFiles := append(cmdarg.Arg(nil), configFiles...)
+	profile := ""
+	if !*test {
+		profile = lastProfile(cmdFiles)
+		fetchSubscriptions(subscriptions(profileFiles(cmdFiles, profile)))
+	}
+	geodataUpdates := geodataUpdater()
+	if geodataUpdates != nil && !*test {
+		fetchMissingGeodata(geodataUpdates)
+	}
+	server, err := startXray(profile)
 	if err != nil {
 		fmt.Println("Failed to start:", err)
-		// Configuration error. Exit with a special value to prevent systemd from restarting.
-		os.Exit(23)
+		// Configuration errors exit with 23, to prevent systemd from restarting.
+		os.Exit(printDiagnostic(err, errors.CodeConfigInvalid).ExitCode)
 	}
 
 	if *test {
@@ -105,9 +259,20 @@ func executeRun(cmd *base.Command, args []string) {
 
 	if err := server.Start(); err != nil {
 		fmt.Println("Failed to start:", err)
-		os.Exit(-1)
+		os.Exit(printDiagnostic(err, errors.CodeServerStart).ExitCode)
 	}
-	defer server.Close()
+	printStatus(server)
+	if *sysDNSEnabled && sysproxy.DNSSupported() {
+		enableSysDNS(server)
+	}
+	r := newReloader(server, cmdFiles, profile)
+	defer func() {
+		// The server is restarted when switching profiles.
+		if r != nil {
+			server = r.current()
+		}
+		server.Close()
+	}()
 
 	/*
 		conf.FileCache = nil
@@ -124,17 +289,67 @@ func executeRun(cmd *base.Command, args []string) {
 	go func() error {
 		osSignals := make(chan os.Signal, 1)
 		signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
-		<-osSignals
+		select {
+		case <-osSignals:
+		case <-quit:
+		}
 		close(end)
 		return nil
 	}()
+	httpapi.RegisterPause(pauseControl{current: func() core.Server {
+		if r != nil {
+			return r.current()
+		}
+		return server
+	}})
+	if r != nil {
+		go r.run(*watch, end)
+		go r.refreshSubscriptions(end)
+		if geodataUpdates != nil {
+			go r.refreshGeodata(geodataUpdates, *geodataInterval, end)
+		}
+	}
 	go func() error {
 		runtime.LockOSThread()
-		systray.Run(onReady, onExit)
+		systray.Run(func() { onReady(server, r) }, onExit)
 		return nil
 	}()
 
 	<-end
+	if r != nil {
+		server = r.current()
+	}
+	shutdown(server)
+}
+
+// printStatus prints the summary of server, as a line of JSON with -status-json.
+func printStatus(server core.Server) {
+	instance, ok := server.(*core.Instance)
+	if !ok {
+		return
+	}
+	summary := status.Collect(instance)
+	if !*statusJSON {
+		fmt.Print(summary)
+		return
+	}
+	if b, err := json.Marshal(summary); err == nil {
+		fmt.Println(string(b))
+	}
+}
+
+// printDiagnostic writes the Diagnostic of err to stderr as a line of JSON, for programs
+// wrapping Xray, and returns it. code is used for errors without a Diagnostic.
+func printDiagnostic(err error, code string) *errors.Diagnostic {
+	diag := errors.DiagnosticOf(err)
+	if diag == nil {
+		diag = errors.Diagnose(code, err)
+	}
+	diag.Message = err.Error()
+	if b, err := json.Marshal(diag); err == nil {
+		fmt.Fprintln(os.Stderr, string(b))
+	}
+	return diag
 }
 
 func dumpConfig() int {
@@ -143,6 +358,13 @@ func dumpConfig() int {
 		fmt.Println(err)
 		time.Sleep(1 * time.Second)
 		return 23
+	} else if *redact || !strings.EqualFold(*dumpFormat, "json") {
+		encoded, err := confserial.EncodeMergedConfig(config, *dumpFormat, *redact)
+		if err != nil {
+			fmt.Println(err)
+			return 23
+		}
+		fmt.Print(encoded)
 	} else {
 		fmt.Print(config)
 	}
@@ -261,46 +483,128 @@ func getConfigFormat() string {
 	return f
 }
 
-func startXray() (core.Server, error) {
-	configFiles := getConfigFilePath(true)
+// resolveConfigFiles returns the config files getConfigFilePath finds with args as the config
+// files given in command line, leaving configFiles as args.
+func resolveConfigFiles(args cmdarg.Arg) cmdarg.Arg {
+	configFiles = append(cmdarg.Arg(nil), args...)
+	files := getConfigFilePath(false)
+	configFiles = append(cmdarg.Arg(nil), args...)
+	return files
+}
 
+// loadConfig loads the config files, with the changes made by command line flags.
+func loadConfig(files cmdarg.Arg) (*core.Config, error) {
 	// config, err := core.LoadConfig(getConfigFormat(), configFiles[0], configFiles)
 
-	c, err := core.LoadConfig(getConfigFormat(), configFiles)
+	c, err := core.LoadConfig(getConfigFormat(), files)
 	if err != nil {
-		return nil, errors.New("failed to load config files: [", configFiles.String(), "]").Base(err)
+		return nil, errors.New("failed to load config files: [", files.String(), "]").Base(err)
+	}
+
+	if *partial {
+		if err := tolerateInboundErrors(c); err != nil {
+			return nil, errors.New("failed to tolerate inbound errors").Base(err)
+		}
+	}
+	addSubscriptionOutbounds(c)
+	if *tunMode {
+		if err := addTunInbound(c); err != nil {
+			return nil, err
+		}
+	}
+	if err := addDefaultRules(c); err != nil {
+		return nil, errors.New("failed to add default rules").Base(err)
+	}
+	return c, nil
+}
+
+// startXray creates the server with the config of profile, or, without a profile, of the config
+// files found from command line.
+func startXray(profile string) (core.Server, error) {
+	var configFiles cmdarg.Arg
+	if profile != "" {
+		log.Println("Using profile:", profile)
+		configFiles = cmdarg.Arg{profile}
+	} else {
+		configFiles = getConfigFilePath(true)
+	}
+
+	c, err := loadConfig(configFiles)
+	if err != nil {
+		return nil, err
 	}
 
 	server, err := core.New(c)
 	if err != nil {
-		return nil, errors.New("failed to create server").Base(err)
+		return nil, errors.New("failed to create server").Base(errors.Diagnose(errors.CodeServerInit, err))
 	}
+	updatePAC(c)
 
 	return server, nil
 }
 
-func background(quite *systray.MenuItem, swithSysProxyState *systray.MenuItem) {
-	sysProxyState := 1
+// startServer creates and starts a server with c.
+func startServer(c *core.Config) (*core.Instance, error) {
+	server, err := core.New(c)
+	if err != nil {
+		return nil, errors.New("failed to create server").Base(err)
+	}
+	if err := server.Start(); err != nil {
+		server.Close()
+		return nil, errors.New("failed to start server").Base(err)
+	}
+	if paused.Load() {
+		if err := pauseInbounds(server); err != nil {
+			log.Println("Failed to pause:", err)
+		}
+	}
+	return server, nil
+}
 
+// tolerateInboundErrors makes the inbound manager skip the inbounds failing to start, instead of aborting.
+func tolerateInboundErrors(c *core.Config) error {
+	inbound := &proxyman.InboundConfig{}
+	index := -1
+	for i, app := range c.App {
+		if app.Type != serial.GetMessageType(inbound) {
+			continue
+		}
+		instance, err := app.GetInstance()
+		if err != nil {
+			return err
+		}
+		inbound, index = instance.(*proxyman.InboundConfig), i
+	}
+	inbound.TolerateErrors = true
+	if index < 0 {
+		c.App = append(c.App, serial.ToTypedMessage(inbound))
+	} else {
+		c.App[index] = serial.ToTypedMessage(inbound)
+	}
+	return nil
+}
+
+func background(quite *systray.MenuItem, swithSysProxyState *systray.MenuItem) {
 	for {
 		select {
 		case <-quite.ClickedCh:
-			os.Exit(0)
+			// Exit through the end of executeRun, removing the system proxy and closing the server.
+			close(quit)
+			return
 
 		case <-swithSysProxyState.ClickedCh:
 			{
-				if sysProxyState == 1 {
-					disableSysProxy(*sysProxyDevice)
+				// The HTTP API may have switched the system proxy too, so go by its state.
+				if sysProxy.State() == sysproxy.Enabled {
+					disableSysProxy()
 
 					systray.SetIcon([]byte{1})
 					swithSysProxyState.SetTitle("Enable")
-					sysProxyState = 0
 				} else {
-					enableSysProxy(*sysProxyDevice, *sysProxyPort)
+					enableSysProxy()
 
 					systray.SetIcon(icon.Data)
 					swithSysProxyState.SetTitle("Disable")
-					sysProxyState = 1
 				}
 			}
 		}
@@ -308,37 +612,157 @@ func background(quite *systray.MenuItem, swithSysProxyState *systray.MenuItem) {
 	}
 }
 
-func onReady() {
+func onReady(server core.Server, r *reloader) {
 	systray.SetTitle("xray")
 	systray.SetIcon(icon.Data)
 	enableSysProxy := systray.AddMenuItem("Disable", "Disable/Enable system proxy")
+	addProfileMenu(r)
+	current := func() core.Server {
+		if r != nil {
+			return r.current()
+		}
+		return server
+	}
+	addMaintenanceMenu(current)
+	addPauseMenu(current)
+	go updateLatencyTooltip(current)
 	quite := systray.AddMenuItem("Quit", "Quit the whole app")
 
 	go background(quite, enableSysProxy)
 }
 
+// addMaintenanceMenu adds a checkbox per tagged outbound, to put it in or out of maintenance.
+// The checkboxes follow changes made through the API as well. current returns the running server,
+// which changes when switching profiles; the checkboxes are the outbounds of the first one.
+func addMaintenanceMenu(current func() core.Server) {
+	maintenanceManager := func() outbound.MaintenanceManager {
+		instance, ok := current().(*core.Instance)
+		if !ok {
+			return nil
+		}
+		mm, _ := instance.GetFeature(outbound.ManagerType()).(outbound.MaintenanceManager)
+		return mm
+	}
+	instance, ok := current().(*core.Instance)
+	if !ok {
+		return
+	}
+	hs, ok := instance.GetFeature(outbound.ManagerType()).(outbound.HandlerSelector)
+	if !ok || maintenanceManager() == nil {
+		return
+	}
+	tags := hs.Select([]string{""})
+	if len(tags) == 0 {
+		return
+	}
+
+	// Outbounds suspended for failing aren't shown, as they come back by themselves.
+	inMaintenance := func() map[string]bool {
+		maintenance := make(map[string]bool)
+		if mm := maintenanceManager(); mm != nil {
+			for _, tag := range mm.GetMaintenance() {
+				maintenance[tag] = true
+			}
+		}
+		return maintenance
+	}
+
+	menu := systray.AddMenuItem("Maintenance", "Take outbounds administratively down")
+	items := make(map[string]*systray.MenuItem, len(tags))
+	maintenance := inMaintenance()
+	for _, tag := range tags {
+		item := menu.AddSubMenuItemCheckbox(tag, "Skip "+tag+" in balancers", maintenance[tag])
+		items[tag] = item
+		go func(tag string, item *systray.MenuItem) {
+			for range item.ClickedCh {
+				down := !item.Checked()
+				mm := maintenanceManager()
+				if mm == nil {
+					continue
+				}
+				if err := mm.SetMaintenance(tag, down); err != nil {
+					log.Println("failed to set maintenance of", tag, err)
+					continue
+				}
+				if down {
+					item.Check()
+				} else {
+					item.Uncheck()
+				}
+			}
+		}(tag, item)
+	}
+
+	go func() {
+		for range time.Tick(5 * time.Second) {
+			maintenance := inMaintenance()
+			for tag, item := range items {
+				if down := maintenance[tag]; down != item.Checked() {
+					if down {
+						item.Check()
+					} else {
+						item.Uncheck()
+					}
+				}
+			}
+		}
+	}()
+}
+
 func onExit() {
 	// clean up here
 }
 
-func enableSysProxy(device string, port string) {
-	enableCmd := exec.Command("networksetup", "-setsocksfirewallproxy", device, "127.0.0.1", port)
-	if err := enableCmd.Run(); err != nil {
-		fmt.Println("Failed to set SOCKS proxy:", err)
+func enableSysProxy() {
+	port, err := strconv.ParseUint(*sysProxyPort, 10, 16)
+	if err != nil {
+		fmt.Println("Invalid system proxy port:", *sysProxyPort)
+		return
 	}
-
-	stateCmd := exec.Command("networksetup", "-setsocksfirewallproxystate", device, "on")
-	if err := stateCmd.Run(); err != nil {
-		fmt.Println("Failed to enable SOCKS proxy:", err)
+	settings := sysproxy.Settings{Type: sysproxy.SOCKS, Host: "127.0.0.1", Port: uint16(port)}
+	switch *sysProxyMode {
+	case "global":
+	case "pac":
+		url, err := startPACServer()
+		if err != nil {
+			fmt.Println("Failed to serve PAC script:", err)
+			return
+		}
+		settings = sysproxy.Settings{Type: sysproxy.PAC, PACURL: url}
+	default:
+		fmt.Println("Invalid system proxy mode:", *sysProxyMode)
+		return
+	}
+	if err := sysProxy.Enable(settings); err != nil {
+		fmt.Println("Failed to enable system proxy:", err)
+		return
 	}
+	log.Println("Enabled system proxy for device", *sysProxyDevice, "at port", *sysProxyPort)
+}
 
-	log.Println("Enabled system proxy for device", device, "at port", port)
+// systemProxyControl lets the HTTP API turn the system proxy on and off.
+type systemProxyControl struct{}
+
+func (systemProxyControl) Enabled() bool {
+	return sysProxy.State() == sysproxy.Enabled
+}
+
+func (systemProxyControl) SetEnabled(enabled bool) error {
+	if enabled {
+		enableSysProxy()
+	} else {
+		disableSysProxy()
+	}
+	if state := sysProxy.State(); (state == sysproxy.Enabled) != enabled {
+		return errors.New("failed to switch system proxy, it's ", state)
+	}
+	return nil
 }
 
-func disableSysProxy(device string) {
-	disableCmd := exec.Command("networksetup", "-setsocksfirewallproxystate", device, "off")
-	if err := disableCmd.Run(); err != nil {
-		fmt.Println("Failed to disable SOCKS proxy:", err)
+func disableSysProxy() {
+	if err := sysProxy.Disable(); err != nil {
+		fmt.Println("Failed to disable system proxy:", err)
+		return
 	}
-	log.Println("Disabled system proxy for device", device)
+	log.Println("Disabled system proxy for device", *sysProxyDevice)
 }
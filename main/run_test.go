@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+)
+
+func TestTolerateInboundErrors(t *testing.T) {
+	inboundType := serial.GetMessageType(&proxyman.InboundConfig{})
+	for _, c := range []*core.Config{
+		{App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+		}},
+		{App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&dispatcher.Config{}),
+		}},
+	} {
+		apps := len(c.App)
+		common.Must(tolerateInboundErrors(c))
+
+		var inbounds []*proxyman.InboundConfig
+		for _, app := range c.App {
+			if app.Type == inboundType {
+				instance, err := app.GetInstance()
+				common.Must(err)
+				inbounds = append(inbounds, instance.(*proxyman.InboundConfig))
+			}
+		}
+		if len(inbounds) != 1 || !inbounds[0].TolerateErrors {
+			t.Error("expected a single inbound config tolerating errors, got ", inbounds)
+		}
+		if c.App[0].Type != serial.GetMessageType(&dispatcher.Config{}) || len(c.App) < apps || len(c.App) > apps+1 {
+			t.Error("expected the other apps kept, got ", c.App)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/common/sysproxy"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/proxy/blackhole"
+	"github.com/xtls/xray-core/proxy/freedom"
+)
+
+// drainCheckInterval is how often the connections left are counted while draining.
+const drainCheckInterval = 200 * time.Millisecond
+
+// sysProxyOff disables the system proxy once on exit, which happens before draining unless in
+// kill-switch mode.
+var sysProxyOff sync.Once
+
+// shutdown prepares server to be closed. Without the kill switch, the system proxy is disabled
+// and the system DNS restored first, so that programs don't connect to a closing port. Then, with
+// -drain, inbounds stop taking new connections, and those in progress are given the drain period
+// to finish. In kill-switch mode, the system proxy and DNS stay until the server is closed, and
+// direct outbounds are blocked while draining, so that no traffic leaves unproxied.
+func shutdown(server core.Server) {
+	if !*killSwitch && sysproxy.Supported() {
+		sysProxyOff.Do(disableSysProxy)
+	}
+	if !*killSwitch {
+		sysDNSOff.Do(disableSysDNS)
+	}
+	instance, ok := server.(*core.Instance)
+	if !ok || *drainPeriod <= 0 {
+		return
+	}
+	if *killSwitch {
+		blockDirectOutbounds(instance)
+	}
+	drainInbounds(instance, *drainPeriod)
+}
+
+// drainInbounds stops the inbounds of instance from taking new connections, and waits for those in
+// progress to finish for up to timeout.
+func drainInbounds(instance *core.Instance, timeout time.Duration) {
+	im, _ := instance.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	drainer, ok := im.(inbound.Drainer)
+	if !ok {
+		return
+	}
+	drainer.StopAccepting()
+	reporter, ok := im.(inbound.ConnectionReporter)
+	if !ok {
+		return
+	}
+	active := func() int {
+		n := 0
+		for _, c := range reporter.GetActiveConnections() {
+			n += c.Connections
+		}
+		return n
+	}
+	left := active()
+	if left == 0 {
+		return
+	}
+	log.Println("Draining", left, "connections for up to", timeout)
+	deadline := time.Now().Add(timeout)
+	for left > 0 && time.Now().Before(deadline) {
+		time.Sleep(drainCheckInterval)
+		left = active()
+	}
+	if left > 0 {
+		log.Println("Drain period over,", left, "connections interrupted")
+	}
+}
+
+// blockDirectOutbounds replaces the tagged freedom outbounds of instance with blackholes of the
+// same tags. Their connections in progress are drained as those of the inbounds.
+func blockDirectOutbounds(instance *core.Instance) {
+	om, _ := instance.GetFeature(outbound.ManagerType()).(outbound.Manager)
+	selector, ok := om.(outbound.HandlerSelector)
+	if !ok {
+		return
+	}
+	drainManager, ok := om.(outbound.DrainManager)
+	if !ok {
+		return
+	}
+	for _, tag := range selector.Select([]string{""}) {
+		h, ok := om.GetHandler(tag).(interface{ GetOutbound() proxy.Outbound })
+		if !ok {
+			continue
+		}
+		if _, ok := h.GetOutbound().(*freedom.Handler); !ok {
+			continue
+		}
+		if err := drainManager.DrainHandler(context.Background(), tag, *drainPeriod); err != nil {
+			log.Println("Failed to block direct outbound", tag, ":", err)
+			continue
+		}
+		if err := core.AddOutboundHandler(instance, &core.OutboundHandlerConfig{
+			Tag:           tag,
+			ProxySettings: serial.ToTypedMessage(&blackhole.Config{}),
+		}); err != nil {
+			log.Println("Failed to block direct outbound", tag, ":", err)
+			continue
+		}
+		log.Println("Blocked direct outbound", tag)
+	}
+}
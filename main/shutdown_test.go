@@ -0,0 +1,156 @@
+package main
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/proxy/blackhole"
+	"github.com/xtls/xray-core/proxy/dokodemo"
+	"github.com/xtls/xray-core/proxy/freedom"
+	"github.com/xtls/xray-core/testing/servers/tcp"
+)
+
+// startRelay starts an instance relaying its inbound to an echo server through a freedom outbound
+// tagged "direct", and returns it with the address of the inbound.
+func startRelay(t *testing.T) (*core.Instance, string) {
+	t.Helper()
+	echo := &tcp.Server{MsgProcessor: func(b []byte) []byte { return b }}
+	dest, err := echo.Start()
+	common.Must(err)
+	t.Cleanup(func() { echo.Close() })
+
+	port := tcp.PickPort()
+	instance, err := core.New(&core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+		},
+		Inbound: []*core.InboundHandlerConfig{{
+			Tag: "in",
+			ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+				PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(port)}},
+				Listen:   net.NewIPOrDomain(net.LocalHostIP),
+			}),
+			ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+				Address:  net.NewIPOrDomain(dest.Address),
+				Port:     uint32(dest.Port),
+				Networks: []net.Network{net.Network_TCP},
+			}),
+		}},
+		Outbound: []*core.OutboundHandlerConfig{{
+			Tag:           "direct",
+			ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+		}},
+	})
+	common.Must(err)
+	common.Must(instance.Start())
+	t.Cleanup(func() { instance.Close() })
+	return instance, net.TCPDestination(net.LocalHostIP, port).NetAddr()
+}
+
+// echoes tells whether a message sent on conn comes back.
+func echoes(conn net.Conn) bool {
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		return false
+	}
+	b := make([]byte, 4)
+	_, err := io.ReadFull(conn, b)
+	return err == nil && string(b) == "ping"
+}
+
+// relaying connects to addr, and tells whether the connection is relayed.
+func relaying(addr string) bool {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	return echoes(conn)
+}
+
+func TestDrainInbounds(t *testing.T) {
+	instance, addr := startRelay(t)
+	inFlight, err := net.Dial("tcp", addr)
+	common.Must(err)
+	defer inFlight.Close()
+	if !echoes(inFlight) {
+		t.Fatal("the inbound doesn't relay")
+	}
+
+	time.AfterFunc(300*time.Millisecond, func() {
+		if !echoes(inFlight) {
+			t.Error("the connection in progress was broken while draining")
+		}
+		inFlight.Close()
+	})
+	start := time.Now()
+	drainInbounds(instance, 5*time.Second)
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond || elapsed > 2*time.Second {
+		t.Error("drained in ", elapsed, ", want once the connection in progress finished")
+	}
+	if relaying(addr) {
+		t.Error("a new connection was taken after draining")
+	}
+}
+
+func TestDrainInboundsTimeout(t *testing.T) {
+	instance, addr := startRelay(t)
+	stuck, err := net.Dial("tcp", addr)
+	common.Must(err)
+	defer stuck.Close()
+	if !echoes(stuck) {
+		t.Fatal("the inbound doesn't relay")
+	}
+
+	start := time.Now()
+	drainInbounds(instance, 500*time.Millisecond)
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond || elapsed > 500*time.Millisecond+2*drainCheckInterval {
+		t.Error("drained in ", elapsed, ", want the drain period")
+	}
+}
+
+func TestBlockDirectOutbounds(t *testing.T) {
+	defer func(period time.Duration) { *drainPeriod = period }(*drainPeriod)
+	*drainPeriod = 500 * time.Millisecond
+
+	instance, addr := startRelay(t)
+	inFlight, err := net.Dial("tcp", addr)
+	common.Must(err)
+	defer inFlight.Close()
+	if !echoes(inFlight) {
+		t.Fatal("the inbound doesn't relay")
+	}
+
+	blockDirectOutbounds(instance)
+	om := instance.GetFeature(outbound.ManagerType()).(outbound.Manager)
+	if _, ok := om.GetHandler("direct").(interface{ GetOutbound() proxy.Outbound }).GetOutbound().(*blackhole.Handler); !ok {
+		t.Fatal("the direct outbound isn't blocked")
+	}
+	if relaying(addr) {
+		t.Error("a new connection went out directly")
+	}
+	// The direct connection in progress is drained, and broken only once the drain period is over.
+	if !echoes(inFlight) {
+		t.Error("the direct connection in progress was broken before the drain period was over")
+	}
+	for deadline := time.Now().Add(5 * time.Second); len(om.(outbound.DrainManager).GetDrainingHandlers()) > 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("the direct outbound was not drained")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if echoes(inFlight) {
+		t.Error("the direct connection outlived the drain period")
+	}
+}
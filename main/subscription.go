@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/common/cmdarg"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf"
+	"github.com/xtls/xray-core/infra/conf/serial"
+)
+
+const (
+	// subscriptionCheckInterval is how often subscriptions are checked for being due to refresh.
+	subscriptionCheckInterval = time.Minute
+	subscriptionFetchTimeout  = 30 * time.Second
+)
+
+// subscribeURLs are the subscriptions given with -subscribe.
+var subscribeURLs cmdarg.Arg
+
+// flagSubscriptions returns the subscriptions given with -subscribe.
+func flagSubscriptions() []*conf.SubscriptionConfig {
+	subs := make([]*conf.SubscriptionConfig, 0, len(subscribeURLs))
+	for _, u := range subscribeURLs {
+		subs = append(subs, &conf.SubscriptionConfig{URL: u})
+	}
+	return subs
+}
+
+// subscriptions returns the subscriptions given with -subscribe and in the config files.
+// Only JSON, YAML and TOML config files are read.
+func subscriptions(files cmdarg.Arg) []*conf.SubscriptionConfig {
+	subs := flagSubscriptions()
+	sources := make([]*core.ConfigSource, 0, len(files))
+	for _, file := range files {
+		format := getConfigFormat()
+		if format == "auto" {
+			format = core.GetFormatByExtension(strings.TrimPrefix(filepath.Ext(file), "."))
+		}
+		if format == "" || format == "protobuf" {
+			return subs
+		}
+		sources = append(sources, &core.ConfigSource{Name: file, Format: format})
+	}
+	if len(sources) == 0 {
+		return subs
+	}
+	config, err := serial.DecodeConfigFromFiles(sources)
+	if err != nil {
+		// The error is reported when the config is loaded.
+		return subs
+	}
+	return append(subs, config.Subscriptions...)
+}
+
+// fetchSubscriptions fetches subscriptions, and returns whether any of them changed. A
+// subscription failing to fetch keeps its last fetched copy.
+func fetchSubscriptions(subs []*conf.SubscriptionConfig) bool {
+	client := &http.Client{Timeout: subscriptionFetchTimeout}
+	changed := false
+	for _, sub := range subs {
+		updated, err := sub.Fetch(context.Background(), client)
+		if err != nil {
+			log.Println("Failed to fetch subscription, using the last fetched copy:", err)
+			continue
+		}
+		if updated {
+			log.Println("Updated subscription", sub.URL)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// addSubscriptionOutbounds adds the outbounds of subscriptions given with -subscribe to c.
+// Subscriptions in the config files are added when the config is built.
+func addSubscriptionOutbounds(c *core.Config) {
+	if len(subscribeURLs) == 0 {
+		return
+	}
+	tags := make(map[string]bool, len(c.Outbound))
+	for _, ob := range c.Outbound {
+		tags[ob.Tag] = true
+	}
+	for _, sub := range flagSubscriptions() {
+		outbounds, err := sub.Outbounds()
+		if err != nil {
+			log.Println("Subscription has no outbounds:", err)
+			continue
+		}
+		for _, ob := range outbounds {
+			if tags[ob.Tag] {
+				log.Println("Skipped outbound", ob.Tag, "of subscription", sub.URL, ": tag is taken")
+				continue
+			}
+			oc, err := ob.Build()
+			if err != nil {
+				log.Println("Skipped outbound", ob.Tag, "of subscription", sub.URL, ":", err)
+				continue
+			}
+			tags[ob.Tag] = true
+			c.Outbound = append(c.Outbound, oc)
+		}
+	}
+}
+
+// refreshSubscriptions fetches each subscription on its interval until end is closed, and
+// reloads the config when any of them changed.
+func (r *reloader) refreshSubscriptions(end <-chan struct{}) {
+	fetched := make(map[string]time.Time)
+	started := time.Now()
+	ticker := time.NewTicker(subscriptionCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-end:
+			return
+		case <-ticker.C:
+		}
+
+		r.Lock()
+		subs := subscriptions(r.files())
+		r.Unlock()
+
+		var due []*conf.SubscriptionConfig
+		now := time.Now()
+		for _, sub := range subs {
+			last, found := fetched[sub.URL]
+			if !found {
+				// Subscriptions were fetched at startup.
+				last = started
+			}
+			if now.Sub(last) >= sub.RefreshInterval() {
+				due = append(due, sub)
+				fetched[sub.URL] = now
+			}
+		}
+		if len(due) > 0 && fetchSubscriptions(due) {
+			r.reload()
+		}
+	}
+}
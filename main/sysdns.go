@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/sysproxy"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/inbound"
+)
+
+var (
+	sysDNS *sysproxy.DNS
+	// sysDNSDevice is the network service whose DNS servers are set.
+	sysDNSDevice string
+	// sysDNSOff restores the system DNS once on exit, along with the system proxy.
+	sysDNSOff sync.Once
+)
+
+// dnsInboundAddresses returns the addresses the inbounds of instance listening on UDP port 53
+// take queries at, the loopback address for those listening on all addresses.
+func dnsInboundAddresses(instance *core.Instance) []string {
+	reporter, ok := instance.GetFeature(inbound.ManagerType()).(inbound.AddressReporter)
+	if !ok {
+		return nil
+	}
+	var addresses []string
+	for _, a := range reporter.GetListenAddresses("") {
+		if a.Address.Network != net.Network_UDP || a.Address.Port != 53 || !a.Address.Address.Family().IsIP() {
+			continue
+		}
+		address := a.Address.Address
+		if address.IP().IsUnspecified() {
+			address = net.LocalHostIP
+			if a.Address.Address.Family().IsIPv6() {
+				address = net.LocalHostIPv6
+			}
+		}
+		addresses = append(addresses, address.String())
+	}
+	return addresses
+}
+
+// enableSysDNS sets the DNS servers of the active network service to the DNS inbounds of server.
+func enableSysDNS(server core.Server) {
+	instance, ok := server.(*core.Instance)
+	if !ok {
+		return
+	}
+	addresses := dnsInboundAddresses(instance)
+	if len(addresses) == 0 {
+		fmt.Println("Failed to set system DNS: no inbound listens on UDP port 53")
+		return
+	}
+	sysDNSDevice = *sysProxyDevice
+	if service, err := sysproxy.ActiveService(); err == nil {
+		sysDNSDevice = service
+	}
+	sysDNS = sysproxy.NewDNS(sysDNSDevice)
+	if err := sysDNS.Enable(addresses); err != nil {
+		fmt.Println("Failed to set system DNS:", err)
+		return
+	}
+	log.Println("Set system DNS of device", sysDNSDevice, "to", addresses)
+}
+
+func disableSysDNS() {
+	if sysDNS == nil || sysDNS.State() == sysproxy.Disabled {
+		return
+	}
+	if err := sysDNS.Disable(); err != nil {
+		fmt.Println("Failed to restore system DNS:", err)
+		return
+	}
+	log.Println("Restored system DNS of device", sysDNSDevice)
+}
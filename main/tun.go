@@ -0,0 +1,27 @@
+//go:build !xray_minimal_router
+
+package main
+
+import (
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/proxy/tun"
+)
+
+// addTunInbound adds a TUN inbound routing all traffic of the device. Destinations are sniffed for
+// routing only, the connections going to the IPs the system resolved.
+func addTunInbound(c *core.Config) error {
+	c.Inbound = append(c.Inbound, &core.InboundHandlerConfig{
+		Tag: "tun",
+		ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+			SniffingSettings: &proxyman.SniffingConfig{
+				Enabled:             true,
+				DestinationOverride: []string{"http", "tls", "quic"},
+				RouteOnly:           true,
+			},
+		}),
+		ProxySettings: serial.ToTypedMessage(&tun.Config{AutoRoute: true}),
+	})
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build xray_minimal_router
+
+package main
+
+import (
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/core"
+)
+
+// addTunInbound fails, as the minimal-router profile leaves TUN out.
+func addTunInbound(c *core.Config) error {
+	return errors.New("TUN is not compiled into this build")
+}
@@ -2,21 +2,37 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/main/commands/base"
+	"github.com/xtls/xray-core/main/distro/all"
 )
 
 var cmdVersion = &base.Command{
-	UsageLine: "{{.Exec}} version",
+	UsageLine: "{{.Exec}} version [-features]",
 	Short:     "Show current version of Xray",
 	Long: `Version prints the build information for Xray executables.
+
+The -features flag also prints the build profile, and the protocols,
+transports, security types and apps compiled in. Protocols and apps are
+named by the types of their configs, as in "xray.proxy.vless.inbound.Config
+is not registered" errors of builds without them.
 	`,
-	Run: executeVersion,
 }
 
+func init() {
+	cmdVersion.Run = executeVersion // break init loop
+}
+
+var versionFeatures = cmdVersion.Flag.Bool("features", false, "Print the build profile and the features compiled in.")
+
 func executeVersion(cmd *base.Command, args []string) {
 	printVersion()
+	if *versionFeatures {
+		printFeatures()
+	}
 }
 
 func printVersion() {
@@ -25,3 +41,13 @@ func printVersion() {
 		fmt.Println(s)
 	}
 }
+
+// printFeatures prints the build profile and the features compiled in, a list per line.
+func printFeatures() {
+	f := handlerService.CompiledFeatures()
+	fmt.Println("Profile:", all.Profile)
+	fmt.Println("Protocols:", strings.Join(f.Protocols, " "))
+	fmt.Println("Transports:", strings.Join(f.Transports, " "))
+	fmt.Println("Security:", strings.Join(f.Security, " "))
+	fmt.Println("Apps:", strings.Join(f.Apps, " "))
+}
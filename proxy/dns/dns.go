@@ -160,7 +160,7 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, d internet.
 	}
 
 	if session.TimeoutOnlyFromContext(ctx) {
-		ctx, _ = context.WithCancel(context.Background())
+		ctx = context.Background()
 	}
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -192,7 +192,7 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, d internet.
 					}
 				}
 				if isIPQuery {
-					go h.handleIPQuery(id, qType, domain, writer)
+					go h.handleIPQuery(ctx, id, qType, domain, writer)
 				}
 				if isIPQuery || h.nonIPQuery == "drop" {
 					b.Release()
@@ -232,25 +232,15 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, d internet.
 	return nil
 }
 
-func (h *Handler) handleIPQuery(id uint16, qType dnsmessage.Type, domain string, writer dns_proto.MessageWriter) {
-	var ips []net.IP
-	var err error
-
-	var ttl uint32 = 600
-
-	switch qType {
-	case dnsmessage.TypeA:
-		ips, err = h.client.LookupIP(domain, dns.IPOption{
-			IPv4Enable: true,
-			IPv6Enable: false,
-			FakeEnable: true,
-		})
-	case dnsmessage.TypeAAAA:
-		ips, err = h.client.LookupIP(domain, dns.IPOption{
-			IPv4Enable: false,
-			IPv6Enable: true,
-			FakeEnable: true,
-		})
+func (h *Handler) handleIPQuery(ctx context.Context, id uint16, qType dnsmessage.Type, domain string, writer dns_proto.MessageWriter) {
+	// The TTL of the answer of the DNS app, so that clients cache it no longer than the app.
+	ips, ttl, err := dns.LookupIPWithTTL(ctx, h.client, domain, dns.IPOption{
+		IPv4Enable: qType == dnsmessage.TypeA,
+		IPv6Enable: qType == dnsmessage.TypeAAAA,
+		FakeEnable: true,
+	})
+	if ttl == 0 {
+		ttl = 600
 	}
 
 	rcode := dns.RCodeFromError(err)
@@ -263,17 +253,19 @@ func (h *Handler) handleIPQuery(id uint16, qType dnsmessage.Type, domain string,
 		ttl = 1
 	}
 
-	switch qType {
-	case dnsmessage.TypeA:
-		for i, ip := range ips {
-			ips[i] = ip.To4()
-		}
-	case dnsmessage.TypeAAAA:
-		for i, ip := range ips {
-			ips[i] = ip.To16()
-		}
+	b, err := packIPAnswer(id, qType, domain, ips, rcode, ttl)
+	if err != nil {
+		errors.LogInfoInner(context.Background(), err, "pack message")
+		return
+	}
+	if err := writer.WriteMessage(b); err != nil {
+		errors.LogInfoInner(context.Background(), err, "write IP answer")
 	}
+}
 
+// packIPAnswer returns the answer with ips, valid for ttl seconds, to the A or AAAA query with id
+// for domain.
+func packIPAnswer(id uint16, qType dnsmessage.Type, domain string, ips []net.IP, rcode uint16, ttl uint32) (*buf.Buffer, error) {
 	b := buf.New()
 	rawBytes := b.Extend(buf.Size)
 	builder := dnsmessage.NewBuilder(rawBytes[:0], dnsmessage.Header{
@@ -295,6 +287,12 @@ func (h *Handler) handleIPQuery(id uint16, qType dnsmessage.Type, domain string,
 
 	rHeader := dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(domain), Class: dnsmessage.ClassINET, TTL: ttl}
 	for _, ip := range ips {
+		// ips may be shared with the cache of the DNS app, so they're converted on copies.
+		if qType == dnsmessage.TypeA {
+			ip = ip.To4()
+		} else {
+			ip = ip.To16()
+		}
 		if len(ip) == net.IPv4len {
 			var r dnsmessage.AResource
 			copy(r.A[:], ip)
@@ -307,15 +305,11 @@ func (h *Handler) handleIPQuery(id uint16, qType dnsmessage.Type, domain string,
 	}
 	msgBytes, err := builder.Finish()
 	if err != nil {
-		errors.LogInfoInner(context.Background(), err, "pack message")
 		b.Release()
-		return
+		return nil, err
 	}
 	b.Resize(0, int32(len(msgBytes)))
-
-	if err := writer.WriteMessage(b); err != nil {
-		errors.LogInfoInner(context.Background(), err, "write IP answer")
-	}
+	return b, nil
 }
 
 type outboundConn struct {
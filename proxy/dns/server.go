@@ -0,0 +1,380 @@
+package dns
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/cache"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	dns_proto "github.com/xtls/xray-core/common/protocol/dns"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func init() {
+	common.Must(common.RegisterConfig((*ServerConfig)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		s := new(Server)
+		if err := core.RequireFeatures(ctx, func(dnsClient dns.Client, policyManager policy.Manager) error {
+			core.OptionalFeatures(ctx, func(fdns dns.FakeDNSEngine) {
+				s.fdns = fdns
+			})
+			return s.Init(config.(*ServerConfig), dnsClient, policyManager)
+		}); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}))
+}
+
+// defaultCacheSize is the number of answers the Server keeps if its config doesn't tell.
+const defaultCacheSize = 4096
+
+// Server is the DNS inbound. It answers A and AAAA queries with the DNS app, whose name servers,
+// selected by the domains they serve, are queried through routing, and forwards other queries to
+// the server of its config. Only the answers of the latter are cached by the Server itself.
+type Server struct {
+	config        *ServerConfig
+	client        dns.Client
+	fdns          dns.FakeDNSEngine
+	policyManager policy.Manager
+	server        net.Destination
+	cache         cache.Lru
+}
+
+func (s *Server) Init(config *ServerConfig, dnsClient dns.Client, policyManager policy.Manager) error {
+	s.config = config
+	s.client = dnsClient
+	s.policyManager = policyManager
+	if config.Server != nil {
+		s.server = config.Server.AsDestination()
+		if s.server.Network == net.Network_Unknown {
+			s.server.Network = net.Network_UDP
+		}
+		if s.server.Port == 0 {
+			s.server.Port = 53
+		}
+	}
+	size := int(config.CacheSize)
+	if size == 0 {
+		size = defaultCacheSize
+	}
+	s.cache = cache.NewLru(size)
+	return nil
+}
+
+// Network implements proxy.Inbound.
+func (s *Server) Network() []net.Network {
+	return []net.Network{net.Network_TCP, net.Network_UDP}
+}
+
+// Process implements proxy.Inbound.
+func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Connection, dispatcher routing.Dispatcher) error {
+	inbound := session.InboundFromContext(ctx)
+	inbound.Name = "dns"
+	inbound.User = &protocol.MemoryUser{
+		Level: s.config.UserLevel,
+	}
+
+	var reader dns_proto.MessageReader
+	var writer dns_proto.MessageWriter
+	if network == net.Network_TCP {
+		reader = dns_proto.NewTCPReader(buf.NewReader(conn))
+		writer = &dns_proto.TCPWriter{Writer: buf.NewWriter(conn)}
+	} else {
+		reader = &dns_proto.UDPReader{Reader: buf.NewPacketReader(conn)}
+		writer = &dns_proto.UDPWriter{Writer: &buf.SequentialWriter{Writer: conn}}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	timer := signal.CancelAfterInactivity(ctx, cancel, s.policyManager.ForLevel(s.config.UserLevel).Timeouts.ConnectionIdle)
+	// Reading blocks until the client closes, so idle connections are closed on their side.
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	c := &serverConn{
+		server:     s,
+		ctx:        ctx,
+		dispatcher: dispatcher,
+		writer:     writer,
+		timer:      timer,
+	}
+	defer c.close()
+
+	for {
+		b, err := reader.ReadMessage()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.New("failed to read query").Base(err)
+		}
+		timer.Update()
+		go c.handle(b)
+	}
+}
+
+// cacheKey is the question answers are cached by.
+type cacheKey struct {
+	name  string
+	qType dnsmessage.Type
+	class dnsmessage.Class
+}
+
+func questionKey(q dnsmessage.Question) cacheKey {
+	return cacheKey{name: strings.ToLower(q.Name.String()), qType: q.Type, class: q.Class}
+}
+
+// cachedAnswer is an answer in the cache of the Server, which expires with the shortest TTL of
+// its records.
+type cachedAnswer struct {
+	msg    dnsmessage.Message
+	stored time.Time
+	expire time.Time
+}
+
+// cached returns the cached answer to q with the given id, its TTLs reduced by the time it was
+// cached for.
+func (s *Server) cached(id uint16, q dnsmessage.Question) *buf.Buffer {
+	v, found := s.cache.Get(questionKey(q))
+	if !found {
+		return nil
+	}
+	answer := v.(*cachedAnswer)
+	now := time.Now()
+	if !now.Before(answer.expire) {
+		return nil
+	}
+	elapsed := uint32(now.Sub(answer.stored) / time.Second)
+	msg := answer.msg
+	msg.ID = id
+	msg.Answers = append([]dnsmessage.Resource(nil), msg.Answers...)
+	for i := range msg.Answers {
+		msg.Answers[i].Header.TTL -= elapsed
+	}
+	b, err := dns_proto.PackMessage(&msg)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// store caches the answer in b if it's a successful one with records.
+func (s *Server) store(b []byte) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(b); err != nil || msg.RCode != dnsmessage.RCodeSuccess ||
+		len(msg.Questions) != 1 || len(msg.Answers) == 0 {
+		return
+	}
+	ttl := msg.Answers[0].Header.TTL
+	for _, r := range msg.Answers[1:] {
+		ttl = min(ttl, r.Header.TTL)
+	}
+	if ttl == 0 {
+		return
+	}
+	now := time.Now()
+	s.cache.Put(questionKey(msg.Questions[0]), &cachedAnswer{
+		msg:    msg,
+		stored: now,
+		expire: now.Add(time.Duration(ttl) * time.Second),
+	})
+}
+
+// isFake returns whether ips were answered by fakedns.
+func (s *Server) isFake(ips []net.IP) bool {
+	fkr0, ok := s.fdns.(dns.FakeDNSEngineRev0)
+	return ok && len(ips) > 0 && fkr0.IsIPInIPPool(net.IPAddress(ips[0]))
+}
+
+// serverConn is a connection of a client to the Server, which may send queries before the
+// previous ones are answered.
+type serverConn struct {
+	server     *Server
+	ctx        context.Context
+	dispatcher routing.Dispatcher
+	timer      signal.ActivityUpdater
+
+	writeAccess sync.Mutex
+	writer      dns_proto.MessageWriter
+
+	linkAccess sync.Mutex
+	upstream   dns_proto.MessageWriter
+	link       *transport.Link
+}
+
+func (c *serverConn) write(b *buf.Buffer) {
+	c.writeAccess.Lock()
+	defer c.writeAccess.Unlock()
+
+	c.timer.Update()
+	if err := c.writer.WriteMessage(b); err != nil {
+		errors.LogInfoInner(c.ctx, err, "failed to write answer")
+	}
+}
+
+func (c *serverConn) handle(b *buf.Buffer) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(b.Bytes())
+	if err != nil {
+		errors.LogInfoInner(c.ctx, err, "invalid query")
+		b.Release()
+		return
+	}
+	q, err := parser.Question()
+	if err != nil {
+		errors.LogInfoInner(c.ctx, err, "invalid question")
+		b.Release()
+		return
+	}
+
+	if answer := c.server.cached(header.ID, q); answer != nil {
+		b.Release()
+		c.write(answer)
+		return
+	}
+	if q.Class == dnsmessage.ClassINET && (q.Type == dnsmessage.TypeA || q.Type == dnsmessage.TypeAAAA) {
+		b.Release()
+		c.answerIP(header.ID, q)
+		return
+	}
+	c.forward(header, q, b)
+}
+
+func (c *serverConn) answerIP(id uint16, q dnsmessage.Question) {
+	s := c.server
+	domain := q.Name.String()
+	ips, ttl, err := dns.LookupIPWithTTL(c.ctx, s.client, domain, dns.IPOption{
+		IPv4Enable: q.Type == dnsmessage.TypeA,
+		IPv6Enable: q.Type == dnsmessage.TypeAAAA,
+		FakeEnable: s.config.FakeIp,
+	})
+	rcode := dns.RCodeFromError(err)
+	if rcode == 0 && len(ips) == 0 && !errors.AllEqual(dns.ErrEmptyResponse, errors.Cause(err)) {
+		// Failures without an rcode of their own are reported as such.
+		errors.LogInfoInner(c.ctx, err, "failed to look up ", domain)
+		rcode = uint16(dnsmessage.RCodeServerFailure)
+	}
+
+	switch {
+	case s.isFake(ips):
+		ttl = 1
+	case ttl == 0:
+		ttl = 600
+	}
+	// Answers of the DNS app aren't cached here, but in the app, so that clients get the IPs the
+	// connections to domain are routed by, for as long as they are.
+	b, err := packIPAnswer(id, q.Type, domain, ips, rcode, ttl)
+	if err != nil {
+		errors.LogInfoInner(c.ctx, err, "failed to pack answer for ", domain)
+		return
+	}
+	c.write(b)
+}
+
+// forward sends the query in b to the upstream server, or answers it with NOTIMP if there is none.
+func (c *serverConn) forward(header dnsmessage.Header, q dnsmessage.Question, b *buf.Buffer) {
+	if c.server.server.Address == nil {
+		b.Release()
+		c.refuse(header, q)
+		return
+	}
+	upstream, err := c.dial()
+	if err != nil {
+		b.Release()
+		errors.LogInfoInner(c.ctx, err, "failed to forward query")
+		return
+	}
+	if err := upstream.WriteMessage(b); err != nil {
+		errors.LogInfoInner(c.ctx, err, "failed to forward query")
+	}
+}
+
+func (c *serverConn) refuse(header dnsmessage.Header, q dnsmessage.Question) {
+	msg := &dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 header.ID,
+			Response:           true,
+			RecursionDesired:   header.RecursionDesired,
+			RecursionAvailable: true,
+			RCode:              dnsmessage.RCodeNotImplemented,
+		},
+		Questions: []dnsmessage.Question{q},
+	}
+	b, err := dns_proto.PackMessage(msg)
+	if err != nil {
+		errors.LogInfoInner(c.ctx, err, "failed to pack answer")
+		return
+	}
+	c.write(b)
+}
+
+// dial returns the writer of the link to the upstream server, dispatching it on first use. The
+// answers read from the link are cached and passed to the client.
+func (c *serverConn) dial() (dns_proto.MessageWriter, error) {
+	c.linkAccess.Lock()
+	defer c.linkAccess.Unlock()
+
+	if c.upstream != nil {
+		return c.upstream, nil
+	}
+	dest := c.server.server
+	link, err := c.dispatcher.Dispatch(c.ctx, dest)
+	if err != nil {
+		return nil, errors.New("failed to dispatch to ", dest).Base(err)
+	}
+
+	var reader dns_proto.MessageReader
+	if dest.Network == net.Network_TCP {
+		reader = dns_proto.NewTCPReader(link.Reader)
+		c.upstream = &dns_proto.TCPWriter{Writer: link.Writer}
+	} else {
+		reader = &dns_proto.UDPReader{Reader: link.Reader}
+		c.upstream = &dns_proto.UDPWriter{Writer: link.Writer}
+	}
+	c.link = link
+
+	go func() {
+		for {
+			b, err := reader.ReadMessage()
+			if err != nil {
+				if err != io.EOF && c.ctx.Err() == nil {
+					errors.LogInfoInner(c.ctx, err, "failed to read answer from ", dest)
+				}
+				return
+			}
+			c.server.store(b.Bytes())
+			c.write(b)
+		}
+	}()
+	return c.upstream, nil
+}
+
+func (c *serverConn) close() {
+	c.linkAccess.Lock()
+	defer c.linkAccess.Unlock()
+
+	if c.link != nil {
+		common.Close(c.link.Writer)
+		common.Interrupt(c.link.Reader)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/tls"
 )
@@ -135,13 +136,13 @@ func (d *DokodemoDoor) Process(ctx context.Context, network net.Network, conn st
 			}
 		}()
 
-		var reader buf.Reader
 		if dest.Network == net.Network_UDP {
-			reader = buf.NewPacketReader(conn)
-		} else {
-			reader = buf.NewReader(conn)
+			if err := buf.Copy(buf.NewPacketReader(conn), link.Writer, buf.UpdateActivity(timer)); err != nil {
+				return errors.New("failed to transport request").Base(err)
+			}
+			return nil
 		}
-		if err := buf.Copy(reader, link.Writer, buf.UpdateActivity(timer)); err != nil {
+		if err := proxy.CopyRequest(ctx, conn, buf.NewReader(conn), link.Writer, timer); err != nil {
 			return errors.New("failed to transport request").Base(err)
 		}
 
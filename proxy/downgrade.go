@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"context"
+	gotls "crypto/tls"
+	"sync"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"github.com/xtls/xray-core/transport/internet/tls"
+)
+
+// Kinds of Downgrade.
+const (
+	// DowngradeFlow is a VLESS client connecting without Vision to an account configured with it.
+	DowngradeFlow = "flow"
+	// DowngradeTLSVersion is a client negotiating a TLS version below 1.3.
+	DowngradeTLSVersion = "tls-version"
+)
+
+// Downgrade describes a client connecting with weaker options than configured.
+type Downgrade struct {
+	Kind     string
+	Inbound  string
+	Source   string
+	User     string
+	Expected string
+	Actual   string
+}
+
+var (
+	downgradeHooksLock sync.RWMutex
+	downgradeHooks     []func(context.Context, *Downgrade)
+)
+
+// RegisterDowngradeHook registers a function called on every downgrade reported by any inbound.
+// Hooks run synchronously on the connection goroutine and should return quickly.
+func RegisterDowngradeHook(hook func(context.Context, *Downgrade)) {
+	downgradeHooksLock.Lock()
+	defer downgradeHooksLock.Unlock()
+	downgradeHooks = append(downgradeHooks, hook)
+}
+
+// DowngradeAlert reports downgrades of an inbound. Downgrades are logged, passed to the hooks
+// registered with RegisterDowngradeHook, and counted in the "inbound>>>[tag]>>>downgrade>>>count"
+// stats counter.
+type DowngradeAlert struct {
+	stats stats.Manager
+}
+
+// NewDowngradeAlert creates a DowngradeAlert. statsManager may be nil.
+func NewDowngradeAlert(statsManager stats.Manager) *DowngradeAlert {
+	return &DowngradeAlert{stats: statsManager}
+}
+
+// Report records a downgrade of the given kind for the connection in ctx.
+func (a *DowngradeAlert) Report(ctx context.Context, kind string, expected string, actual string) {
+	d := &Downgrade{
+		Kind:     kind,
+		Expected: expected,
+		Actual:   actual,
+	}
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		d.Inbound = inbound.Tag
+		if inbound.Source.IsValid() {
+			d.Source = inbound.Source.Address.String()
+		}
+		if inbound.User != nil {
+			d.User = inbound.User.Email
+		}
+	}
+	errors.LogWarning(ctx, "downgraded ", kind, " from ", d.Source, " user ", d.User, " on inbound ", d.Inbound, ": expected ", expected, ", actual ", actual)
+
+	if a.stats != nil && d.Inbound != "" {
+		if c, _ := stats.GetOrRegisterCounter(a.stats, "inbound>>>"+d.Inbound+">>>downgrade>>>count"); c != nil {
+			c.Add(1)
+		}
+	}
+
+	downgradeHooksLock.RLock()
+	hooks := downgradeHooks
+	downgradeHooksLock.RUnlock()
+	for _, hook := range hooks {
+		hook(ctx, d)
+	}
+}
+
+// CheckTLS reports a DowngradeTLSVersion if conn is a TLS connection negotiated below TLS 1.3.
+// conn must not be wrapped by stat.CounterConnection.
+func (a *DowngradeAlert) CheckTLS(ctx context.Context, conn stat.Connection) {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return
+	}
+	if version := tlsConn.ConnectionState().Version; version < gotls.VersionTLS13 {
+		a.Report(ctx, DowngradeTLSVersion, gotls.VersionName(gotls.VersionTLS13), gotls.VersionName(version))
+	}
+}
@@ -0,0 +1,120 @@
+package proxy_test
+
+import (
+	"context"
+	gotls "crypto/tls"
+	gonet "net"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/protocol/tls/cert"
+	"github.com/xtls/xray-core/common/session"
+	. "github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"github.com/xtls/xray-core/transport/internet/tls"
+)
+
+// recordDowngrades returns the downgrades reported on the inbound tagged tag from now on.
+func recordDowngrades(tag string) func() []Downgrade {
+	var access sync.Mutex
+	var downgrades []Downgrade
+	RegisterDowngradeHook(func(ctx context.Context, d *Downgrade) {
+		if d.Inbound == tag {
+			access.Lock()
+			downgrades = append(downgrades, *d)
+			access.Unlock()
+		}
+	})
+	return func() []Downgrade {
+		access.Lock()
+		defer access.Unlock()
+		return append([]Downgrade(nil), downgrades...)
+	}
+}
+
+func downgradeContext(tag string) context.Context {
+	return session.ContextWithInbound(context.Background(), &session.Inbound{
+		Tag:    tag,
+		Source: net.TCPDestination(net.ParseAddress("192.0.2.1"), 40000),
+		User:   &protocol.MemoryUser{Email: "love@example.com"},
+	})
+}
+
+func TestDowngradeAlertReport(t *testing.T) {
+	m, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+	downgrades := recordDowngrades("vless-report")
+
+	alert := NewDowngradeAlert(m)
+	alert.Report(downgradeContext("vless-report"), DowngradeFlow, "xtls-rprx-vision", "none")
+	alert.Report(downgradeContext("vless-report"), DowngradeFlow, "xtls-rprx-vision", "none")
+
+	if c := m.GetCounter("inbound>>>vless-report>>>downgrade>>>count"); c == nil || c.Value() != 2 {
+		t.Error("expected 2 downgrades counted, got ", c)
+	}
+	want := Downgrade{
+		Kind:     DowngradeFlow,
+		Inbound:  "vless-report",
+		Source:   "192.0.2.1",
+		User:     "love@example.com",
+		Expected: "xtls-rprx-vision",
+		Actual:   "none",
+	}
+	if r := cmp.Diff(downgrades(), []Downgrade{want, want}); r != "" {
+		t.Error(r)
+	}
+
+	// Without a stats manager, downgrades are still passed to the hooks.
+	NewDowngradeAlert(nil).Report(downgradeContext("vless-report"), DowngradeFlow, "xtls-rprx-vision", "none")
+	if len(downgrades()) != 3 {
+		t.Error("expected the hook called without stats")
+	}
+}
+
+// tlsConnection returns the server side of a TLS connection negotiated with a client supporting
+// up to maxVersion.
+func tlsConnection(t *testing.T, maxVersion uint16) stat.Connection {
+	t.Helper()
+	certificate, err := cert.Generate(nil, cert.CommonName("example.com"), cert.DNSNames("example.com"))
+	common.Must(err)
+	certPEM, keyPEM := certificate.ToPEM()
+	pair, err := gotls.X509KeyPair(certPEM, keyPEM)
+	common.Must(err)
+
+	client, server := gonet.Pipe()
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	conn := tls.Server(server, &gotls.Config{Certificates: []gotls.Certificate{pair}})
+	go gotls.Client(client, &gotls.Config{InsecureSkipVerify: true, MaxVersion: maxVersion}).Handshake()
+	common.Must(conn.(*tls.Conn).HandshakeContext(context.Background()))
+	return conn
+}
+
+func TestDowngradeAlertCheckTLS(t *testing.T) {
+	downgrades := recordDowngrades("trojan-tls")
+	alert := NewDowngradeAlert(nil)
+
+	alert.CheckTLS(downgradeContext("trojan-tls"), tlsConnection(t, gotls.VersionTLS13))
+	if d := downgrades(); len(d) != 0 {
+		t.Error("expected TLS 1.3 not to be a downgrade, got ", d)
+	}
+
+	alert.CheckTLS(downgradeContext("trojan-tls"), tlsConnection(t, gotls.VersionTLS12))
+	if d := downgrades(); len(d) != 1 || d[0].Kind != DowngradeTLSVersion || d[0].Expected != "TLS 1.3" || d[0].Actual != "TLS 1.2" {
+		t.Error("expected a TLS 1.2 downgrade, got ", d)
+	}
+
+	client, server := gonet.Pipe()
+	defer client.Close()
+	alert.CheckTLS(downgradeContext("trojan-tls"), server)
+	if d := downgrades(); len(d) != 1 {
+		t.Error("expected connections without TLS to be left alone, got ", d)
+	}
+}
@@ -0,0 +1,280 @@
+package fallback
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	http_proto "github.com/xtls/xray-core/common/protocol/http"
+	"github.com/xtls/xray-core/common/retry"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/common/site"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/transport/internet/reality"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"github.com/xtls/xray-core/transport/internet/tls"
+)
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		h := new(Handler)
+		err := core.RequireFeatures(ctx, func(pm policy.Manager) error {
+			return h.Init(config.(*Config), pm)
+		})
+		return h, err
+	}))
+}
+
+// Handler is an inbound handler which doesn't speak any proxy protocol. It relays each
+// connection to one of its fallbacks, chosen by SNI, ALPN and HTTP path, so that several
+// transports can share the certificate and port of one inbound.
+type Handler struct {
+	policyManager policy.Manager
+	config        *Config
+}
+
+// Init initializes the Handler with necessary parameters.
+func (h *Handler) Init(config *Config, pm policy.Manager) error {
+	if len(config.Fallbacks) == 0 {
+		return errors.New("no fallback specified")
+	}
+	h.config = config
+	h.policyManager = pm
+	return nil
+}
+
+// Network implements proxy.Inbound.
+func (*Handler) Network() []net.Network {
+	return []net.Network{net.Network_TCP, net.Network_UNIX}
+}
+
+// pick returns the most specific fallback matching the connection. Name takes precedence
+// over ALPN, and ALPN over path, as in the fallbacks of VLESS and Trojan.
+func (h *Handler) pick(name, alpn, path string) *Fallback {
+	var best *Fallback
+	bestScore := -1
+	for _, fb := range h.config.Fallbacks {
+		if fb.Name != "" && !strings.Contains(name, fb.Name) {
+			continue
+		}
+		if fb.Alpn != "" && fb.Alpn != alpn {
+			continue
+		}
+		if fb.Path != "" && fb.Path != path {
+			continue
+		}
+		score := len(fb.Name) * 4
+		if fb.Alpn != "" {
+			score += 2
+		}
+		if fb.Path != "" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = fb, score
+		}
+	}
+	return best
+}
+
+// requestPath returns the path of the HTTP/1.x request line in first, if any.
+func requestPath(first []byte) string {
+	if len(first) < 18 || first[4] == '*' { // h2c preface
+		return ""
+	}
+	for i := 4; i <= 8; i++ {
+		if first[i] != '/' || first[i-1] != ' ' {
+			continue
+		}
+		search := len(first)
+		if search > 64 {
+			search = 64
+		}
+		for j := i + 1; j < search; j++ {
+			switch first[j] {
+			case '\r', '\n':
+				return ""
+			case '?', ' ':
+				return string(first[i:j])
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// Process implements proxy.Inbound.
+func (h *Handler) Process(ctx context.Context, network net.Network, connection stat.Connection, dispatcher routing.Dispatcher) error {
+	iConn := connection
+	if statConn, ok := iConn.(*stat.CounterConnection); ok {
+		iConn = statConn.Connection
+	}
+
+	sessionPolicy := h.policyManager.ForLevel(h.config.UserLevel)
+	if err := connection.SetReadDeadline(time.Now().Add(sessionPolicy.Timeouts.Handshake)); err != nil {
+		return errors.New("unable to set read deadline").Base(err).AtWarning()
+	}
+	first := buf.New()
+	if _, err := first.ReadFrom(connection); err != nil && first.IsEmpty() {
+		first.Release()
+		return errors.New("failed to read first payload").Base(err).AtInfo()
+	}
+	if err := connection.SetReadDeadline(time.Time{}); err != nil {
+		errors.LogWarningInner(ctx, err, "unable to set back read deadline")
+	}
+
+	name := ""
+	alpn := ""
+	if tlsConn, ok := iConn.(*tls.Conn); ok {
+		cs := tlsConn.ConnectionState()
+		name = cs.ServerName
+		alpn = cs.NegotiatedProtocol
+	} else if realityConn, ok := iConn.(*reality.Conn); ok {
+		cs := realityConn.ConnectionState()
+		name = cs.ServerName
+		alpn = cs.NegotiatedProtocol
+	}
+	name = strings.ToLower(name)
+	alpn = strings.ToLower(alpn)
+	path := requestPath(first.Bytes())
+
+	fb := h.pick(name, alpn, path)
+	if fb == nil {
+		first.Release()
+		return errors.New("no fallback for name=", name, " alpn=", alpn, " path=", path).AtWarning()
+	}
+	errors.LogInfo(ctx, "fallback to ", fb.Dest, " for name=", name, " alpn=", alpn, " path=", path)
+
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		inbound.Name = "fallback"
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
+	ctx = policy.ContextWithBufferPolicy(ctx, sessionPolicy.Buffer)
+
+	var conn net.Conn
+	if err := retry.ExponentialBackoff(5, 100).On(func() error {
+		var err error
+		if fb.Type == "site" {
+			conn, err = site.Dial(fb.Dest)
+		} else {
+			var dialer net.Dialer
+			conn, err = dialer.DialContext(ctx, fb.Type, fb.Dest)
+		}
+		return err
+	}); err != nil {
+		first.Release()
+		return errors.New("failed to dial to " + fb.Dest).Base(err).AtWarning()
+	}
+	defer conn.Close()
+
+	reader := &buf.BufferedReader{
+		Reader: buf.NewReader(connection),
+		Buffer: buf.MultiBuffer{first},
+	}
+	serverReader := buf.NewReader(conn)
+	serverWriter := buf.NewWriter(conn)
+
+	postRequest := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.DownlinkOnly)
+		if fb.Xver != 0 {
+			if err := serverWriter.WriteMultiBuffer(buf.MultiBuffer{ProxyHeader(fb.Xver, connection)}); err != nil {
+				return errors.New("failed to set PROXY protocol v", fb.Xver).Base(err).AtWarning()
+			}
+		}
+		if len(fb.ForwardHeaders) > 0 {
+			client, _, _ := net.SplitHostPort(connection.RemoteAddr().String())
+			proto := "http"
+			switch iConn.(type) {
+			case *tls.Conn, *reality.Conn:
+				proto = "https"
+			}
+			ok, err := http_proto.ForwardRequest(reader, serverWriter, fb.ForwardHeaders, client, proto)
+			if err != nil {
+				return errors.New("failed to fallback request payload").Base(err).AtInfo()
+			}
+			if !ok {
+				errors.LogInfo(ctx, "headers not forwarded to ", fb.Dest, " as the first request isn't complete HTTP/1.x")
+			}
+		}
+		if err := buf.Copy(reader, serverWriter, buf.UpdateActivity(timer)); err != nil {
+			return errors.New("failed to fallback request payload").Base(err).AtInfo()
+		}
+		return nil
+	}
+
+	writer := buf.NewWriter(connection)
+
+	getResponse := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
+		if err := buf.Copy(serverReader, writer, buf.UpdateActivity(timer)); err != nil {
+			return errors.New("failed to deliver response payload").Base(err).AtInfo()
+		}
+		return nil
+	}
+
+	if err := task.Run(ctx, task.OnSuccess(postRequest, task.Close(serverWriter)), task.OnSuccess(getResponse, task.Close(writer))); err != nil {
+		common.Interrupt(serverReader)
+		common.Interrupt(serverWriter)
+		return errors.New("fallback ends").Base(err).AtInfo()
+	}
+	return nil
+}
+
+// ProxyHeader builds a PROXY protocol header of the given version for connection.
+func ProxyHeader(version uint64, connection net.Conn) *buf.Buffer {
+	ipType := 4
+	remoteAddr, remotePort, err := net.SplitHostPort(connection.RemoteAddr().String())
+	if err != nil {
+		ipType = 0
+	}
+	localAddr, localPort, err := net.SplitHostPort(connection.LocalAddr().String())
+	if err != nil {
+		ipType = 0
+	}
+	if ipType == 4 && strings.Contains(remoteAddr, ":") {
+		ipType = 6
+	}
+
+	pro := buf.New()
+	switch version {
+	case 1:
+		switch ipType {
+		case 0:
+			pro.Write([]byte("PROXY UNKNOWN\r\n"))
+		case 4:
+			pro.Write([]byte("PROXY TCP4 " + remoteAddr + " " + localAddr + " " + remotePort + " " + localPort + "\r\n"))
+		default:
+			pro.Write([]byte("PROXY TCP6 " + remoteAddr + " " + localAddr + " " + remotePort + " " + localPort + "\r\n"))
+		}
+	case 2:
+		pro.Write([]byte("\x0D\x0A\x0D\x0A\x00\x0D\x0A\x51\x55\x49\x54\x0A")) // signature
+		switch ipType {
+		case 0:
+			pro.Write([]byte("\x20\x00\x00\x00")) // v2 + LOCAL + UNSPEC + UNSPEC + 0 bytes
+			return pro
+		case 4:
+			pro.Write([]byte("\x21\x11\x00\x0C")) // v2 + PROXY + AF_INET + STREAM + 12 bytes
+			pro.Write(net.ParseIP(remoteAddr).To4())
+			pro.Write(net.ParseIP(localAddr).To4())
+		default:
+			pro.Write([]byte("\x21\x21\x00\x24")) // v2 + PROXY + AF_INET6 + STREAM + 36 bytes
+			pro.Write(net.ParseIP(remoteAddr).To16())
+			pro.Write(net.ParseIP(localAddr).To16())
+		}
+		p1, _ := strconv.ParseUint(remotePort, 10, 16)
+		p2, _ := strconv.ParseUint(localPort, 10, 16)
+		pro.Write([]byte{byte(p1 >> 8), byte(p1), byte(p2 >> 8), byte(p2)})
+	}
+	return pro
+}
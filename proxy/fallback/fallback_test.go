@@ -0,0 +1,46 @@
+package fallback
+
+import (
+	"testing"
+)
+
+func TestRequestPath(t *testing.T) {
+	cases := map[string]string{
+		"GET /ws HTTP/1.1\r\nHost: example.com\r\n":    "/ws",
+		"GET /ws?ed=2048 HTTP/1.1\r\nHost: a\r\n":      "/ws",
+		"POST /xhttp/abc HTTP/1.1\r\nHost: a\r\n":      "/xhttp/abc",
+		"PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n":             "",
+		"\x16\x03\x01\x02\x00\x01\x00\x01\xfc\x03\x03": "",
+	}
+	for input, expected := range cases {
+		if path := requestPath([]byte(input)); path != expected {
+			t.Errorf("requestPath(%q) = %q, expected %q", input, path, expected)
+		}
+	}
+}
+
+func TestPick(t *testing.T) {
+	h := &Handler{config: &Config{
+		Fallbacks: []*Fallback{
+			{Dest: "site"},
+			{Path: "/ws", Dest: "ws"},
+			{Alpn: "h2", Dest: "grpc"},
+			{Name: "example.com", Dest: "example"},
+		},
+	}}
+	cases := []struct {
+		name, alpn, path string
+		dest             string
+	}{
+		{"", "http/1.1", "/ws", "ws"},
+		{"", "http/1.1", "/other", "site"},
+		{"", "h2", "", "grpc"},
+		{"www.example.com", "h2", "/ws", "example"},
+	}
+	for _, c := range cases {
+		fb := h.pick(c.name, c.alpn, c.path)
+		if fb == nil || fb.Dest != c.dest {
+			t.Errorf("pick(%q, %q, %q) = %v, expected %s", c.name, c.alpn, c.path, fb, c.dest)
+		}
+	}
+}
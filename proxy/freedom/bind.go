@@ -0,0 +1,75 @@
+package freedom
+
+import (
+	"context"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+// SupportsBind implements proxy.Binder.
+func (h *Handler) SupportsBind() bool {
+	return true
+}
+
+// accept serves a BIND request. It listens on the local address used to reach destination,
+// and waits for the connection from it.
+func (h *Handler) accept(ctx context.Context, bind *session.Bind, destination net.Destination, dialer internet.Dialer) (stat.Connection, error) {
+	var expected net.IP
+	if destination.Address.Family().IsIP() && !destination.Address.IP().IsUnspecified() {
+		expected = destination.Address.IP()
+	}
+
+	local := &net.TCPAddr{}
+	if src := dialer.Address(); src != nil && src.Family().IsIP() {
+		local.IP = src.IP()
+	} else if expected != nil {
+		// Connecting a UDP socket picks the route without sending anything.
+		if probe, err := net.Dial("udp", (&net.UDPAddr{IP: expected, Port: 9}).String()); err == nil {
+			local.IP = probe.LocalAddr().(*net.UDPAddr).IP
+			probe.Close()
+		}
+	}
+
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, "tcp", local.String())
+	if err != nil {
+		return nil, errors.New("failed to listen for BIND").Base(err)
+	}
+	defer ln.Close()
+	stop := context.AfterFunc(ctx, func() {
+		ln.Close()
+	})
+	defer stop()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	if err := bind.Listening(net.TCPDestination(net.IPAddress(addr.IP), net.Port(addr.Port))); err != nil {
+		return nil, err
+	}
+	errors.LogInfo(ctx, "waiting on ", addr, " for BIND connection from ", destination.Address)
+
+	if err := ln.(*net.TCPListener).SetDeadline(time.Now().Add(h.policy().Timeouts.ConnectionIdle)); err != nil {
+		return nil, err
+	}
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return nil, errors.New("failed to accept BIND connection").Base(err)
+		}
+		peer := net.DestinationFromAddr(conn.RemoteAddr())
+		if expected != nil && !expected.Equal(peer.Address.IP()) {
+			errors.LogWarning(ctx, "rejected BIND connection from unexpected ", peer)
+			conn.Close()
+			continue
+		}
+		if err := bind.Accepted(peer); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
@@ -71,13 +71,16 @@ func (h *Handler) policy() policy.Session {
 }
 
 func (h *Handler) resolveIP(ctx context.Context, domain string, localAddr net.Address) net.Address {
-	ips, err := h.dns.LookupIP(domain, dns.IPOption{
+	if outbounds := session.OutboundsFromContext(ctx); len(outbounds) > 0 {
+		ctx = dns.ContextWithPartition(ctx, outbounds[len(outbounds)-1].Tag)
+	}
+	ips, err := dns.LookupIPForSession(ctx, h.dns, domain, dns.IPOption{
 		IPv4Enable: (localAddr == nil || localAddr.Family().IsIPv4()) && h.config.preferIP4(),
 		IPv6Enable: (localAddr == nil || localAddr.Family().IsIPv6()) && h.config.preferIP6(),
 	})
 	{ // Resolve fallback
 		if (len(ips) == 0 || err != nil) && h.config.hasFallback() && localAddr == nil {
-			ips, err = h.dns.LookupIP(domain, dns.IPOption{
+			ips, err = dns.LookupIPForSession(ctx, h.dns, domain, dns.IPOption{
 				IPv4Enable: h.config.fallbackIP4(),
 				IPv6Enable: h.config.fallbackIP6(),
 			})
@@ -101,6 +104,11 @@ func isValidAddress(addr *net.IPOrDomain) bool {
 	return a != net.AnyIP
 }
 
+// ConnectsFirst implements proxy.Connector.
+func (h *Handler) ConnectsFirst() bool {
+	return true
+}
+
 // Process implements proxy.Outbound.
 func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	outbounds := session.OutboundsFromContext(ctx)
@@ -130,47 +138,62 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 	output := link.Writer
 
 	var conn stat.Connection
-	err := retry.ExponentialBackoff(5, 100).On(func() error {
-		dialDest := destination
-		if h.config.hasStrategy() && dialDest.Address.Family().IsDomain() {
-			ip := h.resolveIP(ctx, dialDest.Address.Domain(), dialer.Address())
-			if ip != nil {
-				dialDest = net.Destination{
-					Network: dialDest.Network,
-					Address: ip,
-					Port:    dialDest.Port,
+	var err error
+	if bind := session.BindFromContext(ctx); bind != nil {
+		conn, err = h.accept(ctx, bind, destination, dialer)
+	} else {
+		err = retry.ExponentialBackoff(5, 100).On(func() error {
+			dialDest := destination
+			if h.config.hasStrategy() && dialDest.Address.Family().IsDomain() {
+				ip := h.resolveIP(ctx, dialDest.Address.Domain(), dialer.Address())
+				if ip != nil {
+					dialDest = net.Destination{
+						Network: dialDest.Network,
+						Address: ip,
+						Port:    dialDest.Port,
+					}
+					errors.LogInfo(ctx, "dialing to ", dialDest)
+				} else if h.config.forceIP() {
+					return dns.ErrEmptyResponse
 				}
-				errors.LogInfo(ctx, "dialing to ", dialDest)
-			} else if h.config.forceIP() {
-				return dns.ErrEmptyResponse
 			}
-		}
 
-		rawConn, err := dialer.Dial(ctx, dialDest)
-		if err != nil {
-			return err
-		}
-
-		if h.config.ProxyProtocol > 0 && h.config.ProxyProtocol <= 2 {
-			version := byte(h.config.ProxyProtocol)
-			srcAddr := inbound.Source.RawNetAddr()
-			dstAddr := rawConn.RemoteAddr()
-			header := proxyproto.HeaderProxyFromAddrs(version, srcAddr, dstAddr)
-			if _, err = header.WriteTo(rawConn); err != nil {
-				rawConn.Close()
+			rawConn, err := dialer.Dial(ctx, dialDest)
+			if err != nil {
 				return err
 			}
-		}
 
-		conn = rawConn
-		return nil
-	})
+			if h.config.ProxyProtocol > 0 && h.config.ProxyProtocol <= 2 {
+				version := byte(h.config.ProxyProtocol)
+				srcAddr := inbound.Source.RawNetAddr()
+				dstAddr := rawConn.RemoteAddr()
+				header := proxyproto.HeaderProxyFromAddrs(version, srcAddr, dstAddr)
+				if _, err = header.WriteTo(rawConn); err != nil {
+					rawConn.Close()
+					return err
+				}
+			}
+
+			conn = rawConn
+			return nil
+		})
+	}
 	if err != nil {
 		return errors.New("failed to open connection to ", destination).Base(err)
 	}
 	defer conn.Close()
 	errors.LogInfo(ctx, "connection opened to ", destination, ", local endpoint ", conn.LocalAddr(), ", remote endpoint ", conn.RemoteAddr())
 
+	splice := useSplice && !h.config.DisableSplice
+	// The inbound may splice the rest of the request into conn, if nothing is done to it on its way:
+	// freedom isn't the last hop of a chain, and the link is neither limited, counted nor mirrored.
+	var handoff *session.Handoff
+	if splice && destination.Network == net.Network_TCP && h.config.Fragment == nil && !isTLSConn(conn) &&
+		len(outbounds) == 1 && !ob.Shaped {
+		handoff = session.NewHandoff(conn, input)
+		ob.OfferUplinkHandoff(handoff)
+	}
+
 	var newCtx context.Context
 	var newCancel context.CancelFunc
 	if session.TimeoutOnlyFromContext(ctx) {
@@ -187,7 +210,18 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 	}, plcy.Timeouts.ConnectionIdle)
 
 	requestDone := func() error {
-		defer timer.SetTimeout(plcy.Timeouts.DownlinkOnly)
+		defer func() {
+			if handoff == nil {
+				timer.SetTimeout(plcy.Timeouts.DownlinkOnly)
+				return
+			}
+			handoff.Drain()
+			if handoff.HandedOff() {
+				timer.SetTimeout(8 * time.Hour) // the inbound copies the rest of the request
+			} else {
+				timer.SetTimeout(plcy.Timeouts.DownlinkOnly)
+			}
+		}()
 
 		var writer buf.Writer
 		if destination.Network == net.Network_TCP {
@@ -226,7 +260,7 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 		if destination.Network == net.Network_TCP {
 			var writeConn net.Conn
 			var inTimer *signal.ActivityTimer
-			if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.Conn != nil && useSplice {
+			if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.Conn != nil && splice {
 				writeConn = inbound.Conn
 				inTimer = inbound.Timer
 			}
@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+// CopyRequest copies the request of an inbound from reader, which reads conn, to writer of its
+// link. On Linux, once the outbound offers an uplink handoff, and conn and the connection of the
+// outbound are plain TCP connections, it stops writing to the link and, when the outbound has
+// written what it read from the link, splices the rest of the request from conn into the
+// connection of the outbound in the kernel.
+func CopyRequest(ctx context.Context, conn net.Conn, reader buf.Reader, writer buf.Writer, timer *signal.ActivityTimer) error {
+	for {
+		if s := newUplinkSplice(ctx, conn, reader); s != nil {
+			return s.copy(ctx, writer, timer)
+		}
+		buffer, err := reader.ReadMultiBuffer()
+		if !buffer.IsEmpty() {
+			timer.Update()
+			if werr := writer.WriteMultiBuffer(buffer); werr != nil {
+				return werr
+			}
+		}
+		if err != nil {
+			if errors.Cause(err) == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// uplinkSplice is the splice of the rest of a request, from the connection of the inbound into
+// that of the outbound.
+type uplinkSplice struct {
+	handoff      *session.Handoff
+	in           *net.TCPConn
+	out          *net.TCPConn
+	readCounter  stats.Counter
+	writeCounter stats.Counter
+}
+
+// newUplinkSplice returns the splice of the rest of the request from conn, or nil if the request
+// can't be spliced yet, or at all.
+func newUplinkSplice(ctx context.Context, conn net.Conn, reader buf.Reader) *uplinkSplice {
+	if runtime.GOOS != "linux" && runtime.GOOS != "android" {
+		return nil
+	}
+	// Bytes read ahead are written to the link first.
+	if r, ok := reader.(*buf.BufferedReader); ok && r.BufferedBytes() > 0 {
+		return nil
+	}
+	// Outbounds offer handoffs only when they are the only one, and the link isn't shaped. The
+	// offer is the only field of the outbound read here, as it's written by another goroutine.
+	outbounds := session.OutboundsFromContext(ctx)
+	if len(outbounds) != 1 {
+		return nil
+	}
+	handoff := outbounds[0].UplinkHandoff()
+	if handoff == nil {
+		return nil
+	}
+	if inbound := session.InboundFromContext(ctx); inbound == nil || inbound.CanSpliceCopy != 1 {
+		return nil
+	}
+	in, readCounter, _ := plainTCPConn(conn)
+	out, _, writeCounter := plainTCPConn(handoff.Conn())
+	if in == nil || out == nil {
+		return nil
+	}
+	return &uplinkSplice{
+		handoff:      handoff,
+		in:           in,
+		out:          out,
+		readCounter:  readCounter,
+		writeCounter: writeCounter,
+	}
+}
+
+// plainTCPConn returns conn as a TCP connection, with its counters if it counts traffic, or nil
+// if it's another kind of connection, such as a TLS one.
+func plainTCPConn(conn net.Conn) (*net.TCPConn, stats.Counter, stats.Counter) {
+	var readCounter, writeCounter stats.Counter
+	if statConn, ok := conn.(*stat.CounterConnection); ok {
+		conn = statConn.Connection
+		readCounter = statConn.ReadCounter
+		writeCounter = statConn.WriteCounter
+	}
+	tc, _ := conn.(*net.TCPConn)
+	return tc, readCounter, writeCounter
+}
+
+func (s *uplinkSplice) copy(ctx context.Context, writer buf.Writer, timer *signal.ActivityTimer) error {
+	errors.LogInfo(ctx, "CopyRequest splice")
+	// The request is counted for the user on the link of the inbound, and for the routing rule
+	// and the domain on that of the outbound.
+	statCounters := append(sizeStatCountersOf(writer), sizeStatReaderCountersOf(s.handoff.Reader())...)
+	s.handoff.HandOff()
+	common.Close(writer)
+	select {
+	case <-s.handoff.Drained():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	timer.SetTimeout(8 * time.Hour) // prevent leak, just in case
+	w, err := s.out.ReadFrom(s.in)
+	if s.readCounter != nil {
+		s.readCounter.Add(w) // inbound stats
+	}
+	if s.writeCounter != nil {
+		s.writeCounter.Add(w) // outbound stats
+	}
+	for _, c := range statCounters {
+		c.Add(w) // user, rule and domain stats
+	}
+	if err != nil && errors.Cause(err) != io.EOF {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"github.com/xtls/xray-core/transport/internet/tls"
+)
+
+// Causes of failed handshakes counted by HandshakeStats.
+const (
+	// HandshakeTLS is a connection which didn't complete its TLS or REALITY handshake.
+	HandshakeTLS = "tls"
+	// HandshakeAuth is a request of an unknown user, or with wrong credentials.
+	HandshakeAuth = "auth"
+	// HandshakeProtocol is a request which isn't valid in the protocol of the inbound.
+	HandshakeProtocol = "protocol"
+	// HandshakeFallback is a connection handed to a fallback, whatever failed in its request.
+	HandshakeFallback = "fallback"
+)
+
+// HandshakeStats counts the failed handshakes of inbounds by cause, in the
+// "inbound>>>[tag]>>>handshake>>>[cause]" stats counters. Active probing mostly shows as TLS
+// failures and fallbacks, while misconfigured clients mostly fail auth.
+type HandshakeStats struct {
+	stats stats.Manager
+}
+
+// NewHandshakeStats creates a HandshakeStats. statsManager may be nil, in which case nothing is
+// counted.
+func NewHandshakeStats(statsManager stats.Manager) *HandshakeStats {
+	return &HandshakeStats{stats: statsManager}
+}
+
+// Report counts a handshake of the inbound in ctx failed for cause. Connections whose TLS
+// handshake failed are left to the inbound handler, which counts them as HandshakeTLS.
+func (s *HandshakeStats) Report(ctx context.Context, cause string) {
+	inbound := session.InboundFromContext(ctx)
+	if inbound == nil {
+		return
+	}
+	conn := inbound.Conn
+	if statConn, ok := conn.(*stat.CounterConnection); ok {
+		conn = statConn.Connection
+	}
+	if tlsConn, ok := conn.(*tls.Conn); ok && !tlsConn.ConnectionState().HandshakeComplete {
+		return
+	}
+	s.Count(inbound.Tag, cause)
+}
+
+// Count counts a handshake of the inbound tagged tag failed for cause.
+func (s *HandshakeStats) Count(tag string, cause string) {
+	if s == nil || s.stats == nil || tag == "" {
+		return
+	}
+	if c, _ := stats.GetOrRegisterCounter(s.stats, "inbound>>>"+tag+">>>handshake>>>"+cause); c != nil {
+		c.Add(1)
+	}
+}
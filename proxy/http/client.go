@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"sync"
 	"text/template"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
@@ -23,6 +24,8 @@ import (
 	"github.com/xtls/xray-core/common/task"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/stat"
@@ -31,6 +34,7 @@ import (
 )
 
 type Client struct {
+	serverList    *protocol.ServerList
 	serverPicker  protocol.ServerPicker
 	policyManager policy.Manager
 	header        []*Header
@@ -62,12 +66,18 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 
 	v := core.MustFromContext(ctx)
 	return &Client{
+		serverList:    serverList,
 		serverPicker:  protocol.NewRoundRobinServerPicker(serverList),
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
 		header:        config.Header,
 	}, nil
 }
 
+// Init implements proxy.Initializer.
+func (c *Client) Init(ctx context.Context, dialer internet.Dialer) error {
+	return proxy.PrepareServers(ctx, dialer, c.serverList)
+}
+
 // Process implements proxy.Outbound.Process. We first create a socket tunnel via HTTP CONNECT method, then redirect all inbound traffic to that tunnel.
 func (c *Client) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	outbounds := session.OutboundsFromContext(ctx)
@@ -229,6 +239,7 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 	connectHTTP1 := func(rawConn net.Conn) (net.Conn, error) {
 		req.Header.Set("Proxy-Connection", "Keep-Alive")
 
+		start := time.Now()
 		err := req.Write(rawConn)
 		if err != nil {
 			rawConn.Close()
@@ -246,6 +257,7 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 			rawConn.Close()
 			return nil, errors.New("Proxy responded with non 200 code: " + resp.Status)
 		}
+		stats.RecordStage(ctx, stats.StageProtocol, start)
 		return rawConn, nil
 	}
 
@@ -262,6 +274,7 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 			wg.Done()
 		}()
 
+		start := time.Now()
 		resp, err := h2clientConn.RoundTrip(req)
 		if err != nil {
 			rawConn.Close()
@@ -278,6 +291,7 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 			rawConn.Close()
 			return nil, errors.New("Proxy responded with non 200 code: " + resp.Status)
 		}
+		stats.RecordStage(ctx, stats.StageProtocol, start)
 		return newHTTP2Conn(rawConn, pw, resp.Body), nil
 	}
 
@@ -23,6 +23,7 @@ import (
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport/internet/stat"
 )
 
@@ -203,7 +204,7 @@ func (s *Server) handleConnect(ctx context.Context, _ *http.Request, reader *buf
 	requestDone := func() error {
 		defer timer.SetTimeout(plcy.Timeouts.DownlinkOnly)
 
-		return buf.Copy(buf.NewReader(conn), link.Writer, buf.UpdateActivity(timer))
+		return proxy.CopyRequest(ctx, conn, buf.NewReader(conn), link.Writer, timer)
 	}
 
 	responseDone := func() error {
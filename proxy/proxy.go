@@ -17,6 +17,7 @@ import (
 
 	"github.com/pires/go-proxyproto"
 	"github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
@@ -65,12 +66,68 @@ type Inbound interface {
 	Process(context.Context, net.Network, stat.Connection, routing.Dispatcher) error
 }
 
+// An Acceptor is an Inbound which accepts connections by itself instead of on listening ports,
+// e.g. from a TUN device. Its connections are passed to Process like those accepted on ports.
+type Acceptor interface {
+	Inbound
+	common.Closable
+
+	// Accept starts accepting connections, calling handle with each of them and its original
+	// destination, until the Acceptor is closed.
+	Accept(handle func(conn stat.Connection, dest net.Destination)) error
+}
+
 // An Outbound process outbound connections.
 type Outbound interface {
 	// Process processes the given connection. The given dialer may be used to dial a system outbound connection.
 	Process(context.Context, *transport.Link, internet.Dialer) error
 }
 
+// A Binder is an Outbound which can serve SOCKS BIND requests, see session.Bind.
+type Binder interface {
+	// SupportsBind returns true if BIND requests can be served with current settings.
+	SupportsBind() bool
+}
+
+// A Connector is an Outbound which connects each request through the dialer passed to Process
+// before relaying it, so that inbounds may wait for the connection, see session.Connect.
+type Connector interface {
+	// ConnectsFirst returns true if requests are connected before they are relayed with current settings.
+	ConnectsFirst() bool
+}
+
+// An Initializer is an Outbound with expensive setup work, such as resolving server addresses or performing handshakes,
+// which can be done in background before the first connection arrives.
+type Initializer interface {
+	// Init prepares the outbound for use. It may be called again if it returns an error.
+	Init(context.Context, internet.Dialer) error
+}
+
+// A Preparer is a Dialer which can prepare ahead for dialing a destination, resolving its
+// address and loading the certificates of its transport.
+type Preparer interface {
+	Prepare(context.Context, net.Destination) error
+}
+
+// PrepareServers prepares dialer for dialing each of servers, if it's a Preparer. It's the Init
+// of the Outbounds whose expensive setup is only that.
+func PrepareServers(ctx context.Context, dialer internet.Dialer, servers *protocol.ServerList) error {
+	preparer, ok := dialer.(Preparer)
+	if !ok {
+		return nil
+	}
+	for i := uint32(0); i < servers.Size(); i++ {
+		server := servers.GetServer(i)
+		if server == nil {
+			break
+		}
+		if err := preparer.Prepare(ctx, server.Destination()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // UserManager is the interface for Inbounds and Outbounds that can manage their users.
 type UserManager interface {
 	// AddUser adds a new user.
@@ -554,6 +611,38 @@ func UnwrapRawConn(conn net.Conn) (net.Conn, stats.Counter, stats.Counter) {
 	return conn, readCounter, writerCounter
 }
 
+// sizeStatCountersOf returns the counters of the SizeStatWriters writer writes through, such as
+// those of the user, the routing rule and the domain, which the dispatcher and the outbound
+// handler wrap in one another.
+func sizeStatCountersOf(writer buf.Writer) []stats.Counter {
+	var counters []stats.Counter
+	for {
+		if w, ok := writer.(*dispatcher.SizeStatWriter); ok {
+			counters = append(counters, w.Counter)
+		}
+		w, ok := writer.(interface{ Unwrap() buf.Writer })
+		if !ok {
+			return counters
+		}
+		writer = w.Unwrap()
+	}
+}
+
+// sizeStatReaderCountersOf returns the counters of the SizeStatReaders reader reads through.
+func sizeStatReaderCountersOf(reader buf.Reader) []stats.Counter {
+	var counters []stats.Counter
+	for {
+		if r, ok := reader.(*dispatcher.SizeStatReader); ok {
+			counters = append(counters, r.Counter)
+		}
+		r, ok := reader.(interface{ Unwrap() buf.Reader })
+		if !ok {
+			return counters
+		}
+		reader = r.Unwrap()
+	}
+}
+
 // CopyRawConnIfExist use the most efficient copy method.
 // - If caller don't want to turn on splice, do not pass in both reader conn and writer conn
 // - writer are from *transport.Link
@@ -577,7 +666,7 @@ func CopyRawConnIfExist(ctx context.Context, readerConn net.Conn, writerConn net
 		return readV(ctx, reader, writer, timer, readCounter)
 	}
 	for _, ob := range outbounds {
-		if ob.CanSpliceCopy == 3 {
+		if ob.CanSpliceCopy == 3 || ob.Shaped {
 			return readV(ctx, reader, writer, timer, readCounter)
 		}
 	}
@@ -593,7 +682,7 @@ func CopyRawConnIfExist(ctx context.Context, readerConn net.Conn, writerConn net
 		}
 		if splice {
 			errors.LogInfo(ctx, "CopyRawConn splice")
-			statWriter, _ := writer.(*dispatcher.SizeStatWriter)
+			statCounters := sizeStatCountersOf(writer)
 			//runtime.Gosched() // necessary
 			time.Sleep(time.Millisecond)    // without this, there will be a rare ssl error for freedom splice
 			timer.SetTimeout(8 * time.Hour) // prevent leak, just in case
@@ -607,8 +696,8 @@ func CopyRawConnIfExist(ctx context.Context, readerConn net.Conn, writerConn net
 			if writeCounter != nil {
 				writeCounter.Add(w) // inbound stats
 			}
-			if statWriter != nil {
-				statWriter.Counter.Add(w) // user stats
+			for _, c := range statCounters {
+				c.Add(w) // user, rule and domain stats
 			}
 			if err != nil && errors.Cause(err) != io.EOF {
 				return err
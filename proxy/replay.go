@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+const defaultReplayBanDuration = 10 * time.Minute
+
+// ReplayGuard applies the replay policy of an inbound. Replayed handshakes are logged and
+// counted in the "inbound>>>[tag]>>>replay>>>count" stats counter, so active probing can be
+// studied through the stats API.
+type ReplayGuard struct {
+	action      protocol.ReplayPolicy_Action
+	banDuration time.Duration
+	stats       stats.Manager
+
+	access sync.Mutex
+	banned map[string]time.Time
+}
+
+// NewReplayGuard creates a ReplayGuard for the given policy. policy may be nil, in which
+// case replays are handled like other invalid requests. statsManager may be nil as well.
+func NewReplayGuard(policy *protocol.ReplayPolicy, statsManager stats.Manager) *ReplayGuard {
+	g := &ReplayGuard{
+		action:      policy.GetAction(),
+		banDuration: time.Duration(policy.GetBanDuration()) * time.Second,
+		stats:       statsManager,
+		banned:      make(map[string]time.Time),
+	}
+	if g.banDuration == 0 {
+		g.banDuration = defaultReplayBanDuration
+	}
+	return g
+}
+
+// Action returns the configured response to replays.
+func (g *ReplayGuard) Action() protocol.ReplayPolicy_Action {
+	return g.action
+}
+
+func sourceOf(ctx context.Context) string {
+	if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.Source.IsValid() {
+		return inbound.Source.Address.String()
+	}
+	return ""
+}
+
+// Banned returns true if the source of the connection in ctx is currently banned.
+func (g *ReplayGuard) Banned(ctx context.Context) bool {
+	source := sourceOf(ctx)
+	if source == "" {
+		return false
+	}
+	g.access.Lock()
+	defer g.access.Unlock()
+	until, found := g.banned[source]
+	if !found {
+		return false
+	}
+	if time.Now().After(until) {
+		delete(g.banned, source)
+		return false
+	}
+	return true
+}
+
+// Report records a replayed handshake from the source of the connection in ctx, and
+// returns the action the inbound should take.
+func (g *ReplayGuard) Report(ctx context.Context, reason error) protocol.ReplayPolicy_Action {
+	source := sourceOf(ctx)
+	tag := ""
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		tag = inbound.Tag
+	}
+	errors.LogWarningInner(ctx, reason, "replayed handshake from ", source, " on inbound ", tag, ", action: ", g.action)
+
+	if g.stats != nil && tag != "" {
+		if c, _ := stats.GetOrRegisterCounter(g.stats, "inbound>>>"+tag+">>>replay>>>count"); c != nil {
+			c.Add(1)
+		}
+	}
+
+	if g.action == protocol.ReplayPolicy_Ban && source != "" {
+		now := time.Now()
+		g.access.Lock()
+		for s, until := range g.banned {
+			if now.After(until) {
+				delete(g.banned, s)
+			}
+		}
+		g.banned[source] = now.Add(g.banDuration)
+		g.access.Unlock()
+	}
+	return g.action
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/xtls/xray-core/common/task"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/stat"
@@ -22,6 +23,7 @@ import (
 
 // Client is a inbound handler for Shadowsocks protocol
 type Client struct {
+	serverList    *protocol.ServerList
 	serverPicker  protocol.ServerPicker
 	policyManager policy.Manager
 }
@@ -34,6 +36,9 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 		if err != nil {
 			return nil, errors.New("failed to parse server spec").Base(err)
 		}
+		if account, ok := s.PickUser().Account.(*MemoryAccount); ok {
+			protocol.WarnAESInSoftware(ctx, account.CipherType.String())
+		}
 		serverList.AddServer(s)
 	}
 	if serverList.Size() == 0 {
@@ -42,12 +47,23 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 
 	v := core.MustFromContext(ctx)
 	client := &Client{
+		serverList:    serverList,
 		serverPicker:  protocol.NewRoundRobinServerPicker(serverList),
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
 	}
 	return client, nil
 }
 
+// Init implements proxy.Initializer.
+func (c *Client) Init(ctx context.Context, dialer internet.Dialer) error {
+	return proxy.PrepareServers(ctx, dialer, c.serverList)
+}
+
+// ConnectsFirst implements proxy.Connector.
+func (c *Client) ConnectsFirst() bool {
+	return true
+}
+
 // Process implements OutboundHandler.Process().
 func (c *Client) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	outbounds := session.OutboundsFromContext(ctx)
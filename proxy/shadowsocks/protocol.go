@@ -79,6 +79,9 @@ func ReadTCPSession(validator *Validator, reader io.Reader) (*protocol.RequestHe
 		drainer.AcknowledgeReceive(int(buffer.Len()))
 		return nil, nil, drain.WithError(drainer, reader, errors.New("failed to match an user").Base(err))
 	case ErrIVNotUnique:
+		if validator.skipReplayDrain {
+			return nil, nil, errors.New("failed iv check").Base(err)
+		}
 		drainer.AcknowledgeReceive(int(buffer.Len()))
 		return nil, nil, drain.WithError(drainer, reader, errors.New("failed iv check").Base(err))
 	default:
@@ -2,6 +2,8 @@ package shadowsocks
 
 import (
 	"context"
+	goerrors "errors"
+	"io"
 	"time"
 
 	"github.com/xtls/xray-core/common"
@@ -17,6 +19,8 @@ import (
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/udp"
 )
@@ -26,6 +30,8 @@ type Server struct {
 	validator     *Validator
 	policyManager policy.Manager
 	cone          bool
+	replayGuard   *proxy.ReplayGuard
+	handshakes    *proxy.HandshakeStats
 }
 
 // NewServer create a new Shadowsocks server.
@@ -36,6 +42,9 @@ func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
 		if err != nil {
 			return nil, errors.New("failed to get shadowsocks user").Base(err).AtError()
 		}
+		if account, ok := u.Account.(*MemoryAccount); ok {
+			protocol.WarnAESInSoftware(ctx, account.CipherType.String())
+		}
 
 		if err := validator.Add(u); err != nil {
 			return nil, errors.New("failed to add user").Base(err).AtError()
@@ -43,11 +52,17 @@ func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
 	}
 
 	v := core.MustFromContext(ctx)
+	statsManager, _ := v.GetFeature(stats.ManagerType()).(stats.Manager)
 	s := &Server{
 		config:        config,
 		validator:     validator,
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
 		cone:          ctx.Value("cone").(bool),
+		replayGuard:   proxy.NewReplayGuard(config.Replay, statsManager),
+		handshakes:    proxy.NewHandshakeStats(statsManager),
+	}
+	if s.replayGuard.Action() != protocol.ReplayPolicy_Fallback {
+		validator.DisableReplayDrain()
 	}
 
 	return s, nil
@@ -91,6 +106,10 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Con
 	inbound.Name = "shadowsocks"
 	inbound.CanSpliceCopy = 3
 
+	if s.replayGuard.Banned(ctx) {
+		return errors.New("rejected banned source ", inbound.Source).AtInfo()
+	}
+
 	switch network {
 	case net.Network_TCP:
 		return s.handleConnection(ctx, conn, dispatcher)
@@ -155,9 +174,12 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn stat.Connection, dis
 			}
 
 			if err != nil {
+				if goerrors.Is(err, ErrIVNotUnique) {
+					s.replayGuard.Report(ctx, err)
+				}
 				if inbound.Source.IsValid() {
 					errors.LogInfoInner(ctx, err, "dropping invalid UDP packet from: ", inbound.Source)
-					log.Record(&log.AccessMessage{
+					log.RecordAccess(ctx, &log.AccessMessage{
 						From:   inbound.Source,
 						To:     "",
 						Status: log.AccessRejected,
@@ -205,7 +227,16 @@ func (s *Server) handleConnection(ctx context.Context, conn stat.Connection, dis
 	bufferedReader := buf.BufferedReader{Reader: buf.NewReader(conn)}
 	request, bodyReader, err := ReadTCPSession(s.validator, &bufferedReader)
 	if err != nil {
-		log.Record(&log.AccessMessage{
+		switch {
+		case goerrors.Is(err, ErrIVNotUnique):
+			s.replayGuard.Report(ctx, err)
+		case goerrors.Is(err, ErrNotFound):
+			// Without the key of a user, the request can't be told apart from garbage.
+			s.handshakes.Report(ctx, proxy.HandshakeAuth)
+		case errors.Cause(err) != io.EOF:
+			s.handshakes.Report(ctx, proxy.HandshakeProtocol)
+		}
+		log.RecordAccess(ctx, &log.AccessMessage{
 			From:   conn.RemoteAddr(),
 			To:     "",
 			Status: log.AccessRejected,
@@ -234,6 +265,7 @@ func (s *Server) handleConnection(ctx context.Context, conn stat.Connection, dis
 	sessionPolicy = s.policyManager.ForLevel(request.User.Level)
 	ctx, cancel := context.WithCancel(ctx)
 	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
+	inbound.Timer = timer
 
 	ctx = policy.ContextWithBufferPolicy(ctx, sessionPolicy.Buffer)
 	link, err := dispatcher.Dispatch(ctx, dest)
@@ -20,10 +20,18 @@ type Validator struct {
 
 	behaviorSeed  uint64
 	behaviorFused bool
+
+	skipReplayDrain bool
 }
 
 var ErrNotFound = errors.New("Not Found")
 
+// DisableReplayDrain makes ReadTCPSession return replayed requests at once, instead of
+// draining them like other invalid requests.
+func (v *Validator) DisableReplayDrain() {
+	v.skipReplayDrain = true
+}
+
 // Add a Shadowsocks user.
 func (v *Validator) Add(u *protocol.MemoryUser) error {
 	v.Lock()
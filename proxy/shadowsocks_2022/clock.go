@@ -0,0 +1,256 @@
+package shadowsocks_2022
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	goerrors "errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/antireplay"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/stats"
+	"golang.org/x/crypto/chacha20poly1305"
+	"lukechampine.com/blake3"
+)
+
+const (
+	// protocolSkew is the clock skew accepted by the protocol, and by the service of sing-shadowsocks.
+	protocolSkew = 30 * time.Second
+	// requestHeaderLength is the length of the fixed header of TCP requests: type, timestamp
+	// and length of the variable header.
+	requestHeaderLength = 1 + 8 + 2
+	headerTypeClient    = 0
+	// rejectionLogInterval is the least time between two log messages about rejections of a kind.
+	rejectionLogInterval = 10 * time.Second
+)
+
+var errReplay = errors.New("salt of the request was used before")
+
+// clockSkewError is returned for requests whose timestamp is off by more than the tolerance.
+type clockSkewError struct {
+	skew      time.Duration
+	tolerance time.Duration
+}
+
+// Error implements error.
+func (e *clockSkewError) Error() string {
+	direction := "ahead of"
+	skew := e.skew
+	if skew < 0 {
+		direction = "behind"
+		skew = -skew
+	}
+	return "client clock is " + skew.String() + " " + direction + " the server, more than the tolerance of " + e.tolerance.String()
+}
+
+// clockGuard checks the timestamp of TCP requests before the service of sing-shadowsocks reads
+// them, which only accepts the skew of the protocol. Requests beyond it but within the
+// tolerance get the time of the server in their header, which is encrypted again. Their salts
+// are remembered for the whole tolerance then, as the service forgets them after a minute.
+type clockGuard struct {
+	method    string
+	psk       []byte
+	tolerance time.Duration
+	salts     *antireplay.ReplayFilter
+	// userPSK returns the PSK of the user whose hash is in an identity header, for multi-user
+	// inbounds.
+	userPSK func(hash []byte) []byte
+}
+
+func newClockGuard(method string, psk []byte, tolerance uint32) *clockGuard {
+	g := &clockGuard{
+		method:    method,
+		psk:       psk,
+		tolerance: time.Duration(tolerance) * time.Second,
+	}
+	if g.tolerance == 0 {
+		g.tolerance = protocolSkew
+	}
+	if g.tolerance > protocolSkew {
+		g.salts = antireplay.NewReplayFilter(int64(2 * g.tolerance / time.Second))
+	}
+	return g
+}
+
+func (g *clockGuard) newAEAD(key []byte) (cipher.AEAD, error) {
+	if g.method == "2022-blake3-chacha20-poly1305" {
+		return chacha20poly1305.New(key)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func deriveKey(context string, psk, salt []byte) []byte {
+	material := make([]byte, 0, len(psk)+len(salt))
+	material = append(append(material, psk...), salt...)
+	key := make([]byte, len(psk))
+	blake3.DeriveKey(key, context, material)
+	return key
+}
+
+// check reads the header of a TCP request from conn, and returns the connection for the
+// service to read the request from. Requests it can't decrypt are left to the service.
+func (g *clockGuard) check(conn net.Conn) (net.Conn, error) {
+	saltLength := len(g.psk)
+	identityLength := 0
+	if g.userPSK != nil {
+		identityLength = aes.BlockSize
+	}
+	header := make([]byte, saltLength+identityLength+requestHeaderLength+chacha20poly1305.Overhead)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, errors.New("failed to read request header").Base(err)
+	}
+	guarded := &headerConn{Conn: conn, header: header}
+
+	salt := header[:saltLength]
+	psk := g.psk
+	if g.userPSK != nil {
+		block, err := aes.NewCipher(deriveKey("shadowsocks 2022 identity subkey", g.psk, salt))
+		if err != nil {
+			return guarded, nil
+		}
+		hash := make([]byte, aes.BlockSize)
+		block.Decrypt(hash, header[saltLength:saltLength+identityLength])
+		if psk = g.userPSK(hash); psk == nil {
+			return guarded, nil
+		}
+	}
+	aead, err := g.newAEAD(deriveKey("shadowsocks 2022 session subkey", psk, salt))
+	if err != nil {
+		return guarded, nil
+	}
+	nonce := make([]byte, aead.NonceSize())
+	sealed := header[saltLength+identityLength:]
+	fixed, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil || fixed[0] != headerTypeClient {
+		return guarded, nil
+	}
+
+	now := time.Now().Unix()
+	skew := time.Duration(int64(binary.BigEndian.Uint64(fixed[1:9]))-now) * time.Second
+	if skew > g.tolerance || skew < -g.tolerance {
+		return nil, &clockSkewError{skew: skew, tolerance: g.tolerance}
+	}
+	if g.salts != nil && !g.salts.Check(salt) {
+		return nil, errReplay
+	}
+	if skew > protocolSkew || skew < -protocolSkew {
+		binary.BigEndian.PutUint64(fixed[1:9], uint64(now))
+		aead.Seal(sealed[:0], nonce, fixed, nil)
+	}
+	return guarded, nil
+}
+
+// headerConn is a connection whose header was read already.
+type headerConn struct {
+	net.Conn
+	header []byte
+}
+
+// Read implements net.Conn.
+func (c *headerConn) Read(b []byte) (int, error) {
+	if len(c.header) > 0 {
+		n := copy(b, c.header)
+		c.header = c.header[n:]
+		return n, nil
+	}
+	return c.Conn.Read(b)
+}
+
+// rejections reports requests rejected for clock skew or replay, which would look like any
+// other invalid request otherwise. They are counted in the "inbound>>>[tag]>>>clockskew>>>count"
+// and "inbound>>>[tag]>>>replay>>>count" stats counters, and logged at most once every
+// rejectionLogInterval for each kind.
+type rejections struct {
+	stats stats.Manager
+
+	access     sync.Mutex
+	logged     map[string]time.Time
+	suppressed map[string]int
+}
+
+func newRejections(statsManager stats.Manager) *rejections {
+	return &rejections{
+		stats:      statsManager,
+		logged:     make(map[string]time.Time),
+		suppressed: make(map[string]int),
+	}
+}
+
+// rejectionKind returns the kind of rejection err is, or "" if it's not one. Rejections by the
+// service are told by their messages.
+func rejectionKind(err error) string {
+	var skew *clockSkewError
+	switch {
+	case goerrors.As(err, &skew):
+		return "clockskew"
+	case goerrors.Is(err, errReplay):
+		return "replay"
+	}
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "bad timestamp"):
+		return "clockskew"
+	case strings.Contains(message, "not unique"):
+		return "replay"
+	}
+	return ""
+}
+
+// report reports err if it's a rejection, and returns it.
+func (r *rejections) report(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	kind := rejectionKind(err)
+	if kind == "" {
+		return err
+	}
+
+	source, tag := "", ""
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		if inbound.Source.IsValid() {
+			source = inbound.Source.Address.String()
+		}
+		tag = inbound.Tag
+	}
+	if r.stats != nil && tag != "" {
+		if c, _ := stats.GetOrRegisterCounter(r.stats, "inbound>>>"+tag+">>>"+kind+">>>count"); c != nil {
+			c.Add(1)
+		}
+	}
+
+	r.access.Lock()
+	now := time.Now()
+	if now.Sub(r.logged[kind]) < rejectionLogInterval {
+		r.suppressed[kind]++
+		r.access.Unlock()
+		return err
+	}
+	suppressed := r.suppressed[kind]
+	r.suppressed[kind] = 0
+	r.logged[kind] = now
+	r.access.Unlock()
+
+	var msg []interface{}
+	if kind == "clockskew" {
+		msg = []interface{}{"rejected request from ", source, " on inbound [", tag, "] for clock skew, check the clock of the client or raise timestampTolerance"}
+	} else {
+		msg = []interface{}{"rejected replayed request from ", source, " on inbound [", tag, "], which may be active probing"}
+	}
+	if suppressed > 0 {
+		msg = append(msg, " (", suppressed, " more since the last message)")
+	}
+	errors.LogWarningInner(ctx, err, msg...)
+	return err
+}
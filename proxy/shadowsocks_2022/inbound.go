@@ -2,6 +2,7 @@ package shadowsocks_2022
 
 import (
 	"context"
+	"encoding/base64"
 
 	shadowsocks "github.com/sagernet/sing-shadowsocks"
 	"github.com/sagernet/sing-shadowsocks/shadowaead_2022"
@@ -19,7 +20,9 @@ import (
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/singbridge"
+	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/transport/internet/stat"
 )
 
@@ -30,10 +33,12 @@ func init() {
 }
 
 type Inbound struct {
-	networks []net.Network
-	service  shadowsocks.Service
-	email    string
-	level    int
+	networks   []net.Network
+	service    shadowsocks.Service
+	email      string
+	level      int
+	guard      *clockGuard
+	rejections *rejections
 }
 
 func NewServer(ctx context.Context, config *ServerConfig) (*Inbound, error) {
@@ -52,11 +57,19 @@ func NewServer(ctx context.Context, config *ServerConfig) (*Inbound, error) {
 	if !C.Contains(shadowaead_2022.List, config.Method) {
 		return nil, errors.New("unsupported method ", config.Method)
 	}
+	protocol.WarnAESInSoftware(ctx, config.Method)
 	service, err := shadowaead_2022.NewServiceWithPassword(config.Method, config.Key, 500, inbound, nil)
 	if err != nil {
 		return nil, errors.New("create service").Base(err)
 	}
+	psk, err := base64.StdEncoding.DecodeString(config.Key)
+	if err != nil {
+		return nil, errors.New("parse config").Base(err)
+	}
+	statsManager, _ := core.MustFromContext(ctx).GetFeature(stats.ManagerType()).(stats.Manager)
 	inbound.service = service
+	inbound.guard = newClockGuard(config.Method, psk, config.TimestampTolerance)
+	inbound.rejections = newRejections(statsManager)
 	return inbound, nil
 }
 
@@ -77,7 +90,11 @@ func (i *Inbound) Process(ctx context.Context, network net.Network, connection s
 	ctx = session.ContextWithDispatcher(ctx, dispatcher)
 
 	if network == net.Network_TCP {
-		return singbridge.ReturnError(i.service.NewConnection(ctx, connection, metadata))
+		conn, err := i.guard.check(connection)
+		if err != nil {
+			return i.rejections.report(ctx, err)
+		}
+		return i.rejections.report(ctx, singbridge.ReturnError(i.service.NewConnection(ctx, conn, metadata)))
 	} else {
 		reader := buf.NewReader(connection)
 		pc := &natPacketConn{connection}
@@ -94,7 +111,7 @@ func (i *Inbound) Process(ctx context.Context, network net.Network, connection s
 				if err != nil {
 					packet.Release()
 					buf.ReleaseMulti(mb)
-					return err
+					return i.rejections.report(ctx, err)
 				}
 			}
 		}
@@ -153,6 +170,10 @@ func (i *Inbound) NewError(ctx context.Context, err error) {
 	if E.IsClosed(err) {
 		return
 	}
+	if rejectionKind(err) != "" {
+		i.rejections.report(ctx, err)
+		return
+	}
 	errors.LogWarning(ctx, err.Error())
 }
 
@@ -2,6 +2,7 @@ package shadowsocks_2022
 
 import (
 	"context"
+	"crypto/aes"
 	"encoding/base64"
 	"strconv"
 	"strings"
@@ -24,8 +25,11 @@ import (
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/singbridge"
 	"github.com/xtls/xray-core/common/uuid"
+	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/transport/internet/stat"
+	"lukechampine.com/blake3"
 )
 
 func init() {
@@ -36,9 +40,12 @@ func init() {
 
 type MultiUserInbound struct {
 	sync.Mutex
-	networks []net.Network
-	users    []*protocol.MemoryUser
-	service  *shadowaead_2022.MultiService[int]
+	networks   []net.Network
+	users      []*protocol.MemoryUser
+	service    *shadowaead_2022.MultiService[int]
+	userPSKs   map[[aes.BlockSize]byte][]byte // by hash, for the clock guard
+	guard      *clockGuard
+	rejections *rejections
 }
 
 func NewMultiServer(ctx context.Context, config *MultiUserServerConfig) (*MultiUserInbound, error) {
@@ -73,6 +80,7 @@ func NewMultiServer(ctx context.Context, config *MultiUserServerConfig) (*MultiU
 	if err != nil {
 		return nil, errors.New("parse config").Base(err)
 	}
+	protocol.WarnAESInSoftware(ctx, config.Method)
 	service, err := shadowaead_2022.NewMultiService[int](config.Method, psk, 500, inbound, nil)
 	if err != nil {
 		return nil, errors.New("create service").Base(err)
@@ -85,10 +93,35 @@ func NewMultiServer(ctx context.Context, config *MultiUserServerConfig) (*MultiU
 		return nil, errors.New("create service").Base(err)
 	}
 
+	statsManager, _ := core.MustFromContext(ctx).GetFeature(stats.ManagerType()).(stats.Manager)
 	inbound.service = service
+	inbound.hashUsers()
+	inbound.guard = newClockGuard(config.Method, psk, config.TimestampTolerance)
+	inbound.guard.userPSK = inbound.userPSK
+	inbound.rejections = newRejections(statsManager)
 	return inbound, nil
 }
 
+// hashUsers indexes the PSKs of users by the hash in their identity headers.
+func (i *MultiUserInbound) hashUsers() {
+	i.userPSKs = make(map[[aes.BlockSize]byte][]byte, len(i.users))
+	for _, u := range i.users {
+		psk, err := base64.StdEncoding.DecodeString(u.Account.(*MemoryAccount).Key)
+		if err != nil {
+			continue
+		}
+		hash := blake3.Sum512(psk)
+		i.userPSKs[[aes.BlockSize]byte(hash[:aes.BlockSize])] = psk
+	}
+}
+
+func (i *MultiUserInbound) userPSK(hash []byte) []byte {
+	i.Lock()
+	defer i.Unlock()
+
+	return i.userPSKs[[aes.BlockSize]byte(hash)]
+}
+
 // AddUser implements proxy.UserManager.AddUser().
 func (i *MultiUserInbound) AddUser(ctx context.Context, u *protocol.MemoryUser) error {
 	i.Lock()
@@ -109,6 +142,7 @@ func (i *MultiUserInbound) AddUser(ctx context.Context, u *protocol.MemoryUser)
 		C.MapIndexed(i.users, func(index int, it *protocol.MemoryUser) int { return index }),
 		C.Map(i.users, func(it *protocol.MemoryUser) string { return it.Account.(*MemoryAccount).Key }),
 	)
+	i.hashUsers()
 
 	return nil
 }
@@ -146,6 +180,7 @@ func (i *MultiUserInbound) RemoveUser(ctx context.Context, email string) error {
 		C.MapIndexed(i.users, func(index int, it *protocol.MemoryUser) int { return index }),
 		C.Map(i.users, func(it *protocol.MemoryUser) string { return it.Account.(*MemoryAccount).Key }),
 	)
+	i.hashUsers()
 
 	return nil
 }
@@ -200,7 +235,11 @@ func (i *MultiUserInbound) Process(ctx context.Context, network net.Network, con
 	ctx = session.ContextWithDispatcher(ctx, dispatcher)
 
 	if network == net.Network_TCP {
-		return singbridge.ReturnError(i.service.NewConnection(ctx, connection, metadata))
+		conn, err := i.guard.check(connection)
+		if err != nil {
+			return i.rejections.report(ctx, err)
+		}
+		return i.rejections.report(ctx, singbridge.ReturnError(i.service.NewConnection(ctx, conn, metadata)))
 	} else {
 		reader := buf.NewReader(connection)
 		pc := &natPacketConn{connection}
@@ -217,7 +256,7 @@ func (i *MultiUserInbound) Process(ctx context.Context, network net.Network, con
 				if err != nil {
 					packet.Release()
 					buf.ReleaseMulti(mb)
-					return err
+					return i.rejections.report(ctx, err)
 				}
 			}
 		}
@@ -279,5 +318,9 @@ func (i *MultiUserInbound) NewError(ctx context.Context, err error) {
 	if E.IsClosed(err) {
 		return
 	}
+	if rejectionKind(err) != "" {
+		i.rejections.report(ctx, err)
+		return
+	}
 	errors.LogWarning(ctx, err.Error())
 }
@@ -53,6 +53,7 @@ func NewRelayServer(ctx context.Context, config *RelayServerConfig) (*RelayInbou
 	if !C.Contains(shadowaead_2022.List, config.Method) || !strings.Contains(config.Method, "aes") {
 		return nil, errors.New("unsupported method ", config.Method)
 	}
+	protocol.WarnAESInSoftware(ctx, config.Method)
 	service, err := shadowaead_2022.NewRelayServiceWithPassword[int](config.Method, config.Key, 500, inbound)
 	if err != nil {
 		return nil, errors.New("create service").Base(err)
@@ -15,6 +15,8 @@ import (
 	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/ntp"
+	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/singbridge"
 	"github.com/xtls/xray-core/transport"
@@ -32,6 +34,7 @@ type Outbound struct {
 	server    net.Destination
 	method    shadowsocks.Method
 	uotClient *uot.Client
+	shaping   *protocol.HandshakeShaping
 }
 
 func NewClient(ctx context.Context, config *ClientConfig) (*Outbound, error) {
@@ -42,16 +45,22 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Outbound, error) {
 			Port:    net.Port(config.Port),
 			Network: net.Network_TCP,
 		},
+		shaping: config.HandshakeShaping,
 	}
 	if C.Contains(shadowaead_2022.List, config.Method) {
 		if config.Key == "" {
 			return nil, errors.New("missing psk")
 		}
-		method, err := shadowaead_2022.NewWithPassword(config.Method, config.Key, nil)
+		var timeFunc func() time.Time
+		if clock := ntp.ClockFromContext(ctx); clock != nil {
+			timeFunc = clock.Now
+		}
+		method, err := shadowaead_2022.NewWithPassword(config.Method, config.Key, timeFunc)
 		if err != nil {
 			return nil, errors.New("create method").Base(err)
 		}
 		o.method = method
+		protocol.WarnAESInSoftware(ctx, config.Method)
 	} else {
 		return nil, errors.New("unknown method ", config.Method)
 	}
@@ -61,6 +70,11 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Outbound, error) {
 	return o, nil
 }
 
+// ConnectsFirst implements proxy.Connector.
+func (o *Outbound) ConnectsFirst() bool {
+	return true
+}
+
 func (o *Outbound) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	var inboundConn net.Conn
 	inbound := session.InboundFromContext(ctx)
@@ -90,14 +104,24 @@ func (o *Outbound) Process(ctx context.Context, link *transport.Link, dialer int
 	if err != nil {
 		return errors.New("failed to connect to server").Base(err)
 	}
+	if serverDestination.Network == net.Network_TCP {
+		connection = protocol.ShapeConn(connection, o.shaping)
+	}
 
 	if session.TimeoutOnlyFromContext(ctx) {
-		ctx, _ = context.WithCancel(context.Background())
+		ctx = context.Background()
 	}
 
 	if network == net.Network_TCP {
 		serverConn := o.method.DialEarlyConn(connection, singbridge.ToSocksaddr(destination))
 		var handshake bool
+		if o.shaping.GetPadHeader() {
+			// The request header is padded randomly when sent without payload.
+			if _, err = serverConn.Write(nil); err != nil {
+				return errors.New("client handshake").Base(err)
+			}
+			handshake = true
+		}
 		if timeoutReader, isTimeoutReader := link.Reader.(buf.TimeoutReader); isTimeoutReader {
 			mb, err := timeoutReader.ReadMultiBufferTimeout(time.Millisecond * 100)
 			if err != nil && err != buf.ErrNotTimeoutReader && err != buf.ErrReadTimeout {
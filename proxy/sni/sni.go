@@ -0,0 +1,208 @@
+package sni
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/protocol/tls"
+	"github.com/xtls/xray-core/common/retry"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy/fallback"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		h := new(Handler)
+		err := core.RequireFeatures(ctx, func(pm policy.Manager) error {
+			return h.Init(config.(*Config), pm)
+		})
+		return h, err
+	}))
+}
+
+// Handler is an inbound handler which proxies TLS by SNI without terminating it. It peeks the
+// server name in the ClientHello of each connection, and relays the raw TLS stream to the
+// backend serving the name, or dispatches it to the outbounds, to the name, so that several TLS
+// servers can share one port.
+type Handler struct {
+	policyManager policy.Manager
+	config        *Config
+	port          net.Port
+}
+
+// Init initializes the Handler with necessary parameters.
+func (h *Handler) Init(config *Config, pm policy.Manager) error {
+	h.config = config
+	h.policyManager = pm
+	h.port = net.Port(config.Port)
+	if h.port == 0 {
+		h.port = 443
+	}
+	return nil
+}
+
+// Network implements proxy.Inbound.
+func (*Handler) Network() []net.Network {
+	return []net.Network{net.Network_TCP, net.Network_UNIX}
+}
+
+// pick returns the backend serving name. Exact names take precedence over wildcards, and longer
+// wildcards over shorter ones.
+func (h *Handler) pick(name string) *Backend {
+	var best *Backend
+	bestLen := -1
+	for _, b := range h.config.Backends {
+		for _, pattern := range b.ServerNames {
+			switch {
+			case pattern == name:
+				return b
+			case pattern == "*":
+				if bestLen < 0 {
+					best, bestLen = b, 0
+				}
+			case strings.HasPrefix(pattern, "*.") && strings.HasSuffix(name, pattern[1:]):
+				if len(pattern) > bestLen {
+					best, bestLen = b, len(pattern)
+				}
+			}
+		}
+	}
+	return best
+}
+
+// readClientHello reads from reader until the first payload holds a whole ClientHello, and
+// returns the payload and the server name in it.
+func readClientHello(reader buf.Reader) (buf.MultiBuffer, string, error) {
+	var mb buf.MultiBuffer
+	hello := buf.New()
+	defer hello.Release()
+	for {
+		more, err := reader.ReadMultiBuffer()
+		mb = append(mb, more...)
+		if err != nil {
+			buf.ReleaseMulti(mb)
+			return nil, "", err
+		}
+		for _, b := range more {
+			hello.Write(b.Bytes())
+		}
+		header, err := tls.SniffTLS(hello.Bytes())
+		switch {
+		case err == nil:
+			return mb, strings.ToLower(header.Domain()), nil
+		case err != common.ErrNoClue:
+			buf.ReleaseMulti(mb)
+			return nil, "", errors.New("not a TLS ClientHello with a server name").Base(err)
+		case hello.IsFull():
+			buf.ReleaseMulti(mb)
+			return nil, "", errors.New("ClientHello too large")
+		}
+	}
+}
+
+// Process implements proxy.Inbound.
+func (h *Handler) Process(ctx context.Context, network net.Network, connection stat.Connection, dispatcher routing.Dispatcher) error {
+	sessionPolicy := h.policyManager.ForLevel(h.config.UserLevel)
+	if err := connection.SetReadDeadline(time.Now().Add(sessionPolicy.Timeouts.Handshake)); err != nil {
+		return errors.New("unable to set read deadline").Base(err).AtWarning()
+	}
+	reader := buf.NewReader(connection)
+	first, name, err := readClientHello(reader)
+	if err != nil {
+		return errors.New("failed to peek server name").Base(err).AtInfo()
+	}
+	if err := connection.SetReadDeadline(time.Time{}); err != nil {
+		errors.LogWarningInner(ctx, err, "unable to set back read deadline")
+	}
+	reader = &buf.BufferedReader{Reader: reader, Buffer: first}
+
+	inbound := session.InboundFromContext(ctx)
+	inbound.Name = "sni"
+	inbound.User = &protocol.MemoryUser{
+		Level: h.config.UserLevel,
+	}
+	if content := session.ContentFromContext(ctx); content != nil {
+		content.Protocol = "tls"
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
+	inbound.Timer = timer
+	ctx = policy.ContextWithBufferPolicy(ctx, sessionPolicy.Buffer)
+
+	var serverReader buf.Reader
+	var serverWriter buf.Writer
+	if backend := h.pick(name); backend != nil && backend.Dest != "" {
+		errors.LogInfo(ctx, "relaying ", name, " to ", backend.Dest)
+		var conn net.Conn
+		if err := retry.ExponentialBackoff(5, 100).On(func() error {
+			var dialer net.Dialer
+			var err error
+			conn, err = dialer.DialContext(ctx, backend.Type, backend.Dest)
+			return err
+		}); err != nil {
+			buf.ReleaseMulti(first)
+			return errors.New("failed to dial to " + backend.Dest).Base(err).AtWarning()
+		}
+		defer conn.Close()
+		serverReader = buf.NewReader(conn)
+		serverWriter = buf.NewWriter(conn)
+		if backend.Xver != 0 {
+			if err := serverWriter.WriteMultiBuffer(buf.MultiBuffer{fallback.ProxyHeader(backend.Xver, connection)}); err != nil {
+				buf.ReleaseMulti(first)
+				return errors.New("failed to set PROXY protocol v", backend.Xver).Base(err).AtWarning()
+			}
+		}
+	} else {
+		dest := net.TCPDestination(net.DomainAddress(name), h.port)
+		ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
+			From:   connection.RemoteAddr(),
+			To:     dest,
+			Status: log.AccessAccepted,
+			Reason: "",
+		})
+		link, err := dispatcher.Dispatch(ctx, dest)
+		if err != nil {
+			buf.ReleaseMulti(first)
+			return errors.New("failed to dispatch request").Base(err)
+		}
+		serverReader, serverWriter = link.Reader, link.Writer
+	}
+
+	requestDone := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.DownlinkOnly)
+		if err := buf.Copy(reader, serverWriter, buf.UpdateActivity(timer)); err != nil {
+			return errors.New("failed to transport request").Base(err)
+		}
+		return nil
+	}
+
+	writer := buf.NewWriter(connection)
+	responseDone := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
+		if err := buf.Copy(serverReader, writer, buf.UpdateActivity(timer)); err != nil {
+			return errors.New("failed to transport response").Base(err)
+		}
+		return nil
+	}
+
+	if err := task.Run(ctx, task.OnSuccess(requestDone, task.Close(serverWriter)), responseDone); err != nil {
+		common.Interrupt(serverReader)
+		common.Interrupt(serverWriter)
+		return errors.New("connection ends").Base(err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package sni
+
+import (
+	gotls "crypto/tls"
+	"net"
+	"testing"
+
+	"github.com/xtls/xray-core/common/buf"
+)
+
+func TestPick(t *testing.T) {
+	h := &Handler{config: &Config{
+		Backends: []*Backend{
+			{ServerNames: []string{"*"}, Dest: "any"},
+			{ServerNames: []string{"*.example.com"}, Dest: "example"},
+			{ServerNames: []string{"*.cdn.example.com", "example.org"}, Dest: "cdn"},
+			{ServerNames: []string{"www.cdn.example.com"}, Dest: "www"},
+		},
+	}}
+	cases := map[string]string{
+		"example.org":         "cdn",
+		"img.cdn.example.com": "cdn",
+		"www.cdn.example.com": "www",
+		"mail.example.com":    "example",
+		"example.com":         "any",
+		"example.net":         "any",
+	}
+	for name, dest := range cases {
+		if b := h.pick(name); b == nil || b.Dest != dest {
+			t.Errorf("pick(%q) = %v, expected %s", name, b, dest)
+		}
+	}
+	h.config.Backends = h.config.Backends[1:]
+	if b := h.pick("example.net"); b != nil {
+		t.Errorf("pick(%q) = %v, expected none", "example.net", b)
+	}
+}
+
+// chunkReader reads a connection in small pieces, as a ClientHello may arrive.
+type chunkReader struct {
+	conn net.Conn
+}
+
+func (r *chunkReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	b := buf.New()
+	_, err := b.ReadFrom(&limitedConn{r.conn})
+	if err != nil {
+		b.Release()
+		return nil, err
+	}
+	return buf.MultiBuffer{b}, nil
+}
+
+type limitedConn struct {
+	net.Conn
+}
+
+func (c *limitedConn) Read(b []byte) (int, error) {
+	return c.Conn.Read(b[:min(len(b), 100)])
+}
+
+func TestReadClientHello(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		gotls.Client(client, &gotls.Config{ServerName: "Www.Example.com"}).Handshake()
+		client.Close()
+	}()
+
+	mb, name, err := readClientHello(&chunkReader{server})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer buf.ReleaseMulti(mb)
+	if name != "www.example.com" {
+		t.Errorf("server name %q, expected www.example.com", name)
+	}
+	if len(mb) < 2 || mb[0].Byte(0) != 0x16 {
+		t.Errorf("ClientHello read in %d pieces", len(mb))
+	}
+}
@@ -15,6 +15,8 @@ import (
 	"github.com/xtls/xray-core/common/task"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/stat"
@@ -22,6 +24,7 @@ import (
 
 // Client is a Socks5 client.
 type Client struct {
+	serverList    *protocol.ServerList
 	serverPicker  protocol.ServerPicker
 	policyManager policy.Manager
 }
@@ -42,6 +45,7 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 
 	v := core.MustFromContext(ctx)
 	c := &Client{
+		serverList:    serverList,
 		serverPicker:  protocol.NewRoundRobinServerPicker(serverList),
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
 	}
@@ -49,6 +53,16 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 	return c, nil
 }
 
+// Init implements proxy.Initializer.
+func (c *Client) Init(ctx context.Context, dialer internet.Dialer) error {
+	return proxy.PrepareServers(ctx, dialer, c.serverList)
+}
+
+// ConnectsFirst implements proxy.Connector.
+func (c *Client) ConnectsFirst() bool {
+	return true
+}
+
 // Process implements proxy.Outbound.Process.
 func (c *Client) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	outbounds := session.OutboundsFromContext(ctx)
@@ -110,10 +124,18 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 	if err := conn.SetDeadline(time.Now().Add(p.Timeouts.Handshake)); err != nil {
 		errors.LogInfoInner(ctx, err, "failed to set deadline for handshake")
 	}
-	udpRequest, err := ClientHandshake(request, conn, conn)
+	var udpRequest *protocol.RequestHeader
+	var err error
+	start := time.Now()
+	if bind := session.BindFromContext(ctx); bind != nil {
+		err = c.bindHandshake(bind, request, conn, dest, p)
+	} else {
+		udpRequest, err = ClientHandshake(request, conn, conn)
+	}
 	if err != nil {
 		return errors.New("failed to establish connection to server").AtWarning().Base(err)
 	}
+	stats.RecordStage(ctx, stats.StageProtocol, start)
 	if udpRequest != nil {
 		if udpRequest.Address == net.AnyIP || udpRequest.Address == net.AnyIPv6 {
 			udpRequest.Address = dest.Address
@@ -181,6 +203,35 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 	return nil
 }
 
+// SupportsBind implements proxy.Binder.
+func (c *Client) SupportsBind() bool {
+	return true
+}
+
+// bindHandshake passes a BIND request on to the server, and reports its replies to bind.
+func (c *Client) bindHandshake(bind *session.Bind, request *protocol.RequestHeader, conn stat.Connection, server net.Destination, p policy.Session) error {
+	addr, err := ClientBindHandshake(request, conn, conn)
+	if err != nil {
+		return err
+	}
+	if addr.Address == net.AnyIP || addr.Address == net.AnyIPv6 {
+		addr.Address = server.Address
+	}
+	if err := bind.Listening(addr); err != nil {
+		return err
+	}
+
+	// The peer may connect long after the first reply.
+	if err := conn.SetDeadline(time.Now().Add(p.Timeouts.ConnectionIdle)); err != nil {
+		return err
+	}
+	peer, err := ReadBindReply(conn)
+	if err != nil {
+		return errors.New("failed to wait for BIND connection").Base(err)
+	}
+	return bind.Accepted(peer)
+}
+
 func init() {
 	common.Must(common.RegisterConfig((*ClientConfig)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
 		return NewClient(ctx, config.(*ClientConfig))
@@ -29,8 +29,9 @@ const (
 	authPassword         = 0x02
 	authNoMatchingMethod = 0xFF
 
-	statusSuccess       = 0x00
-	statusCmdNotSupport = 0x07
+	statusSuccess        = 0x00
+	statusGeneralFailure = 0x01
+	statusCmdNotSupport  = 0x07
 )
 
 var addrParser = protocol.NewAddressParser(
@@ -44,6 +45,42 @@ type ServerSession struct {
 	address      net.Address
 	port         net.Port
 	localAddress net.Address
+	// bind is set for BIND requests, whose replies are sent as the outbound proceeds.
+	bind bool
+	// deferReply leaves the replies to CONNECT requests to Reply, so that servers can tell
+	// clients whether the outbound connected to their destinations.
+	deferReply bool
+	granted    func(io.Writer) error
+	failed     func(io.Writer) error
+}
+
+// reply sends the reply granting a CONNECT request with granted, or leaves it to Reply, along
+// with failed in case the request fails.
+func (s *ServerSession) reply(writer io.Writer, granted, failed func(io.Writer) error) error {
+	if s.deferReply {
+		s.granted, s.failed = granted, failed
+		return nil
+	}
+	return granted(writer)
+}
+
+// ReplyDeferred tells whether the reply to the CONNECT request of the handshake is left to Reply.
+func (s *ServerSession) ReplyDeferred() bool {
+	return s.granted != nil
+}
+
+// Reply sends the deferred reply to the CONNECT request of the handshake, if any: a failure if
+// err isn't nil.
+func (s *ServerSession) Reply(writer io.Writer, err error) error {
+	write := s.granted
+	if err != nil {
+		write = s.failed
+	}
+	s.granted, s.failed = nil, nil
+	if write == nil {
+		return nil
+	}
+	return write(writer)
 }
 
 func (s *ServerSession) handshake4(cmd byte, reader io.Reader, writer io.Writer) (*protocol.RequestHeader, error) {
@@ -85,7 +122,11 @@ func (s *ServerSession) handshake4(cmd byte, reader io.Reader, writer io.Writer)
 			Port:    port,
 			Version: socks4Version,
 		}
-		if err := writeSocks4Response(writer, socks4RequestGranted, net.AnyIP, net.Port(0)); err != nil {
+		if err := s.reply(writer, func(w io.Writer) error {
+			return writeSocks4Response(w, socks4RequestGranted, net.AnyIP, net.Port(0))
+		}, func(w io.Writer) error {
+			return writeSocks4Response(w, socks4RequestRejected, net.AnyIP, net.Port(0))
+		}); err != nil {
 			return nil, err
 		}
 		return request, nil
@@ -172,8 +213,12 @@ func (s *ServerSession) handshake5(nMethod byte, reader io.Reader, writer io.Wri
 		}
 		request.Command = protocol.RequestCommandUDP
 	case cmdTCPBind:
-		writeSocks5Response(writer, statusCmdNotSupport, net.AnyIP, net.Port(0))
-		return nil, errors.New("TCP bind is not supported.")
+		if !s.config.BindEnabled {
+			writeSocks5Response(writer, statusCmdNotSupport, net.AnyIP, net.Port(0))
+			return nil, errors.New("TCP bind is not enabled.")
+		}
+		request.Command = protocol.RequestCommandTCP
+		s.bind = true
 	default:
 		writeSocks5Response(writer, statusCmdNotSupport, net.AnyIP, net.Port(0))
 		return nil, errors.New("unknown command ", cmd)
@@ -188,6 +233,10 @@ func (s *ServerSession) handshake5(nMethod byte, reader io.Reader, writer io.Wri
 	request.Address = addr
 	request.Port = port
 
+	if s.bind {
+		return request, nil
+	}
+
 	responseAddress := s.address
 	responsePort := s.port
 	//nolint:gocritic // Use if else chain for clarity
@@ -200,7 +249,17 @@ func (s *ServerSession) handshake5(nMethod byte, reader io.Reader, writer io.Wri
 			responseAddress = s.localAddress
 		}
 	}
-	if err := writeSocks5Response(writer, statusSuccess, responseAddress, responsePort); err != nil {
+	write := func(w io.Writer) error {
+		return writeSocks5Response(w, statusSuccess, responseAddress, responsePort)
+	}
+	if request.Command == protocol.RequestCommandTCP {
+		err = s.reply(writer, write, func(w io.Writer) error {
+			return writeSocks5Response(w, statusGeneralFailure, net.AnyIP, net.Port(0))
+		})
+	} else {
+		err = write(writer)
+	}
+	if err != nil {
 		return nil, err
 	}
 
@@ -413,7 +472,8 @@ func (w *UDPWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
 	return nil
 }
 
-func ClientHandshake(request *protocol.RequestHeader, reader io.Reader, writer io.Writer) (*protocol.RequestHeader, error) {
+// clientAuthenticate sends the greeting of a Socks 5 client and authenticates as request.User.
+func clientAuthenticate(request *protocol.RequestHeader, reader io.Reader, writer io.Writer) error {
 	authByte := byte(authNotRequired)
 	if request.User != nil {
 		authByte = byte(authPassword)
@@ -424,19 +484,19 @@ func ClientHandshake(request *protocol.RequestHeader, reader io.Reader, writer i
 
 	common.Must2(b.Write([]byte{socks5Version, 0x01, authByte}))
 	if err := buf.WriteAllBytes(writer, b.Bytes(), nil); err != nil {
-		return nil, err
+		return err
 	}
 
 	b.Clear()
 	if _, err := b.ReadFullFrom(reader, 2); err != nil {
-		return nil, err
+		return err
 	}
 
 	if b.Byte(0) != socks5Version {
-		return nil, errors.New("unexpected server version: ", b.Byte(0)).AtWarning()
+		return errors.New("unexpected server version: ", b.Byte(0)).AtWarning()
 	}
 	if b.Byte(1) != authByte {
-		return nil, errors.New("auth method not supported.").AtWarning()
+		return errors.New("auth method not supported.").AtWarning()
 	}
 
 	if authByte == authPassword {
@@ -448,19 +508,28 @@ func ClientHandshake(request *protocol.RequestHeader, reader io.Reader, writer i
 		common.Must(b.WriteByte(byte(len(account.Password))))
 		common.Must2(b.WriteString(account.Password))
 		if err := buf.WriteAllBytes(writer, b.Bytes(), nil); err != nil {
-			return nil, err
+			return err
 		}
 
 		b.Clear()
 		if _, err := b.ReadFullFrom(reader, 2); err != nil {
-			return nil, err
+			return err
 		}
 		if b.Byte(1) != 0x00 {
-			return nil, errors.New("server rejects account: ", b.Byte(1))
+			return errors.New("server rejects account: ", b.Byte(1))
 		}
 	}
 
-	b.Clear()
+	return nil
+}
+
+func ClientHandshake(request *protocol.RequestHeader, reader io.Reader, writer io.Writer) (*protocol.RequestHeader, error) {
+	if err := clientAuthenticate(request, reader, writer); err != nil {
+		return nil, err
+	}
+
+	b := buf.New()
+	defer b.Release()
 
 	command := byte(cmdTCPConnect)
 	if request.Command == protocol.RequestCommandUDP {
@@ -508,3 +577,45 @@ func ClientHandshake(request *protocol.RequestHeader, reader io.Reader, writer i
 
 	return nil, nil
 }
+
+// ClientBindHandshake sends a BIND request for the peer in request, and returns the address
+// the server listens on. The server sends another reply when the peer connects, which can
+// be read with ReadBindReply.
+func ClientBindHandshake(request *protocol.RequestHeader, reader io.Reader, writer io.Writer) (net.Destination, error) {
+	if err := clientAuthenticate(request, reader, writer); err != nil {
+		return net.Destination{}, err
+	}
+
+	b := buf.New()
+	defer b.Release()
+
+	common.Must2(b.Write([]byte{socks5Version, cmdTCPBind, 0x00 /* reserved */}))
+	if err := addrParser.WriteAddressPort(b, request.Address, request.Port); err != nil {
+		return net.Destination{}, err
+	}
+	if err := buf.WriteAllBytes(writer, b.Bytes(), nil); err != nil {
+		return net.Destination{}, err
+	}
+
+	return ReadBindReply(reader)
+}
+
+// ReadBindReply reads a reply to a BIND request.
+func ReadBindReply(reader io.Reader) (net.Destination, error) {
+	b := buf.New()
+	defer b.Release()
+
+	if _, err := b.ReadFullFrom(reader, 3); err != nil {
+		return net.Destination{}, err
+	}
+	if resp := b.Byte(1); resp != statusSuccess {
+		return net.Destination{}, errors.New("server rejects bind: ", resp)
+	}
+
+	b.Clear()
+	address, port, err := addrParser.ReadAddressPort(b, reader)
+	if err != nil {
+		return net.Destination{}, err
+	}
+	return net.TCPDestination(address, port), nil
+}
@@ -121,3 +121,32 @@ func BenchmarkReadUsernamePassword(b *testing.B) {
 		buffer.Extend(int32(len(input)))
 	}
 }
+
+func TestClientBindHandshake(t *testing.T) {
+	request := &protocol.RequestHeader{
+		Address: net.ParseAddress("1.2.3.4"),
+		Port:    21,
+	}
+	reader := bytes.NewReader([]byte{
+		5, 0, // no auth
+		5, 0, 0, 1, 10, 0, 0, 1, 0x30, 0x39, // listening on 10.0.0.1:12345
+		5, 0, 0, 1, 1, 2, 3, 4, 0, 20, // accepted 1.2.3.4:20
+	})
+	writer := buf.New()
+	defer writer.Release()
+
+	addr, err := ClientBindHandshake(request, reader, writer)
+	common.Must(err)
+	if r := cmp.Diff(addr, net.TCPDestination(net.ParseAddress("10.0.0.1"), 12345)); r != "" {
+		t.Error(r)
+	}
+	if r := cmp.Diff(writer.Bytes(), []byte{5, 1, 0, 5, 2, 0, 1, 1, 2, 3, 4, 0, 21}); r != "" {
+		t.Error(r)
+	}
+
+	peer, err := ReadBindReply(reader)
+	common.Must(err)
+	if r := cmp.Diff(peer, net.TCPDestination(net.ParseAddress("1.2.3.4"), 20)); r != "" {
+		t.Error(r)
+	}
+}
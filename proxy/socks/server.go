@@ -4,6 +4,8 @@ import (
 	"context"
 	goerrors "errors"
 	"io"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/xtls/xray-core/common"
@@ -19,6 +21,7 @@ import (
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/proxy/http"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/udp"
@@ -114,6 +117,9 @@ func (s *Server) processTCP(ctx context.Context, conn stat.Connection, dispatche
 		address:      inbound.Gateway.Address,
 		port:         inbound.Gateway.Port,
 		localAddress: net.IPAddress(conn.LocalAddr().(*net.TCPAddr).IP),
+		// The reply waits for the outbound to connect, unless the first bytes of the client are
+		// sniffed first, which it only sends once replied to.
+		deferReply: !sniffed(ctx),
 	}
 
 	// Firstbyte is for forwarded conn from SOCKS inbound
@@ -126,7 +132,7 @@ func (s *Server) processTCP(ctx context.Context, conn stat.Connection, dispatche
 	request, err := svrSession.Handshake(reader, conn)
 	if err != nil {
 		if inbound.Source.IsValid() {
-			log.Record(&log.AccessMessage{
+			log.RecordAccess(ctx, &log.AccessMessage{
 				From:   inbound.Source,
 				To:     "",
 				Status: log.AccessRejected,
@@ -143,8 +149,18 @@ func (s *Server) processTCP(ctx context.Context, conn stat.Connection, dispatche
 		errors.LogInfoInner(ctx, err, "failed to clear deadline")
 	}
 
+	if svrSession.bind {
+		return s.processBind(ctx, reader, conn, request.Destination(), dispatcher, inbound)
+	}
+
 	if request.Command == protocol.RequestCommandTCP {
 		dest := request.Destination()
+		if version := UoTVersion(dest); version != 0 {
+			if err := svrSession.Reply(conn, nil); err != nil {
+				return errors.New("failed to write reply").Base(err)
+			}
+			return s.handleUoT(ctx, reader, conn, version, dispatcher, inbound)
+		}
 		errors.LogInfo(ctx, "TCP Connect request to ", dest)
 		if inbound.Source.IsValid() {
 			ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
@@ -155,7 +171,7 @@ func (s *Server) processTCP(ctx context.Context, conn stat.Connection, dispatche
 			})
 		}
 
-		return s.transport(ctx, reader, conn, dest, dispatcher, inbound)
+		return s.transport(ctx, reader, conn, dest, dispatcher, inbound, svrSession)
 	}
 
 	if request.Command == protocol.RequestCommandUDP {
@@ -168,13 +184,59 @@ func (s *Server) processTCP(ctx context.Context, conn stat.Connection, dispatche
 	return nil
 }
 
+// processBind serves a BIND request. The outbound accepts the connection from dest in place
+// of the proxy, and the two replies of RFC 1928 are sent as it listens and accepts the peer.
+func (s *Server) processBind(ctx context.Context, reader io.Reader, conn stat.Connection, dest net.Destination, dispatcher routing.Dispatcher, inbound *session.Inbound) error {
+	errors.LogInfo(ctx, "TCP Bind request for ", dest)
+	if inbound.Source.IsValid() {
+		ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
+			From:   inbound.Source,
+			To:     dest,
+			Status: log.AccessAccepted,
+			Reason: "bind",
+		})
+	}
+
+	var replied atomic.Bool
+	bind := &session.Bind{
+		Listening: func(addr net.Destination) error {
+			replied.Store(true)
+			if addr.Address == net.AnyIP || addr.Address == net.AnyIPv6 {
+				if s.config.Address != nil {
+					addr.Address = s.config.Address.AsAddress()
+				} else {
+					addr.Address = net.IPAddress(conn.LocalAddr().(*net.TCPAddr).IP)
+				}
+			}
+			return writeSocks5Response(conn, statusSuccess, addr.Address, addr.Port)
+		},
+		Accepted: func(peer net.Destination) error {
+			return writeSocks5Response(conn, statusSuccess, peer.Address, peer.Port)
+		},
+	}
+
+	err := s.transport(session.ContextWithBind(ctx, bind), reader, conn, dest, dispatcher, inbound, nil)
+	if err != nil && !replied.Load() {
+		writeSocks5Response(conn, statusGeneralFailure, net.AnyIP, net.Port(0))
+	}
+	return err
+}
+
 func (*Server) handleUDP(c io.Reader) error {
 	// The TCP connection closes after this method returns. We need to wait until
 	// the client closes it.
 	return common.Error2(io.Copy(buf.DiscardBytes, c))
 }
 
-func (s *Server) transport(ctx context.Context, reader io.Reader, writer io.Writer, dest net.Destination, dispatcher routing.Dispatcher, inbound *session.Inbound) error {
+// sniffed tells whether the content of the connection of ctx is sniffed to route it.
+func sniffed(ctx context.Context) bool {
+	content := session.ContentFromContext(ctx)
+	return content != nil && content.SniffingRequest.Enabled
+}
+
+// transport relays the connection to dest. The deferred reply of svrSession, if any, is sent once
+// the outbound has connected to dest, or failed to.
+func (s *Server) transport(ctx context.Context, reader io.Reader, writer io.Writer, dest net.Destination, dispatcher routing.Dispatcher, inbound *session.Inbound, svrSession *ServerSession) error {
 	ctx, cancel := context.WithCancel(ctx)
 	timer := signal.CancelAfterInactivity(ctx, cancel, s.policy().Timeouts.ConnectionIdle)
 
@@ -184,14 +246,38 @@ func (s *Server) transport(ctx context.Context, reader io.Reader, writer io.Writ
 
 	plcy := s.policy()
 	ctx = policy.ContextWithBufferPolicy(ctx, plcy.Buffer)
+	var connect *session.Connect
+	if svrSession != nil && svrSession.ReplyDeferred() {
+		connect = session.NewConnect()
+		ctx = session.ContextWithConnect(ctx, connect)
+	}
 	link, err := dispatcher.Dispatch(ctx, dest)
 	if err != nil {
+		if svrSession != nil {
+			svrSession.Reply(writer, err)
+		}
 		return err
 	}
+	if connect != nil {
+		if err := connect.Wait(ctx); err != nil {
+			svrSession.Reply(writer, err)
+			common.Interrupt(link.Reader)
+			common.Interrupt(link.Writer)
+			return errors.New("failed to connect to ", dest).Base(err)
+		}
+	}
+	if svrSession != nil {
+		if err := svrSession.Reply(writer, nil); err != nil {
+			common.Interrupt(link.Reader)
+			common.Interrupt(link.Writer)
+			return errors.New("failed to write reply").Base(err)
+		}
+	}
 
 	requestDone := func() error {
 		defer timer.SetTimeout(plcy.Timeouts.DownlinkOnly)
-		if err := buf.Copy(buf.NewReader(reader), link.Writer, buf.UpdateActivity(timer)); err != nil {
+		conn, _ := writer.(net.Conn)
+		if err := proxy.CopyRequest(ctx, conn, buf.NewReader(reader), link.Writer, timer); err != nil {
 			return errors.New("failed to transport all TCP request").Base(err)
 		}
 
@@ -220,6 +306,68 @@ func (s *Server) transport(ctx context.Context, reader io.Reader, writer io.Writ
 	return nil
 }
 
+// handleUoT relays UDP packets carried over the TCP connection, for clients which can't reach
+// the server over UDP.
+func (s *Server) handleUoT(ctx context.Context, reader io.Reader, conn stat.Connection, version int, dispatcher routing.Dispatcher, inbound *session.Inbound) error {
+	if !s.config.UdpEnabled {
+		return errors.New("UDP over TCP requested while UDP is not enabled")
+	}
+	uotReader := &UoTReader{Reader: reader}
+	if version == 2 {
+		dest, err := ReadUoTRequest(reader)
+		if err != nil {
+			return err
+		}
+		uotReader.Destination = dest
+	}
+	errors.LogInfo(ctx, "UDP over TCP request, version ", version)
+
+	var access sync.Mutex
+	uotWriter := &UoTWriter{Writer: conn, Connect: uotReader.Destination != nil}
+	udpServer := udp.NewDispatcher(dispatcher, func(ctx context.Context, packet *udp_proto.Packet) {
+		payload := packet.Payload
+		if payload.UDP == nil {
+			payload.UDP = &packet.Source
+		}
+		access.Lock()
+		defer access.Unlock()
+		if err := uotWriter.WriteMultiBuffer(buf.MultiBuffer{payload}); err != nil {
+			errors.LogInfoInner(ctx, err, "failed to write UDP over TCP response")
+		}
+	})
+	defer udpServer.RemoveRay()
+
+	var dest *net.Destination
+	for {
+		mpayload, err := uotReader.ReadMultiBuffer()
+		if err != nil {
+			if errors.Cause(err) == io.EOF {
+				return nil
+			}
+			return errors.New("failed to read UDP over TCP packet").Base(err)
+		}
+		for _, payload := range mpayload {
+			destination := *payload.UDP
+
+			currentPacketCtx := ctx
+			errors.LogDebug(ctx, "send packet to ", destination, " with ", payload.Len(), " bytes")
+			if inbound != nil && inbound.Source.IsValid() {
+				currentPacketCtx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
+					From:   inbound.Source,
+					To:     destination,
+					Status: log.AccessAccepted,
+					Reason: "",
+				})
+			}
+
+			if !s.cone || dest == nil {
+				dest = &destination
+			}
+			udpServer.Dispatch(currentPacketCtx, *dest, payload)
+		}
+	}
+}
+
 func (s *Server) handleUDPPayload(ctx context.Context, conn stat.Connection, dispatcher routing.Dispatcher) error {
 	if s.udpFilter != nil && !s.udpFilter.Check(conn.RemoteAddr()) {
 		errors.LogDebug(ctx, "Unauthorized UDP access from ", conn.RemoteAddr().String())
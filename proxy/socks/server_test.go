@@ -0,0 +1,129 @@
+package socks_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/routing"
+	. "github.com/xtls/xray-core/proxy/socks"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+const xrayKey core.XrayKey = 1
+
+var errDial = errors.New("connection refused")
+
+// connectDispatcher stands for an outbound which reports connecting to destinations with err,
+// after a delay, and closes their responses.
+type connectDispatcher struct {
+	routing.Dispatcher
+	err   error
+	delay time.Duration
+}
+
+func (d *connectDispatcher) Dispatch(ctx context.Context, dest net.Destination) (*transport.Link, error) {
+	uplinkReader, uplinkWriter := pipe.New()
+	downlinkReader, downlinkWriter := pipe.New()
+	go func() {
+		time.Sleep(d.delay)
+		if connect := session.ConnectFromContext(ctx); connect != nil {
+			connect.Report(d.err)
+		}
+		downlinkWriter.Close()
+		uplinkReader.Interrupt()
+	}()
+	return &transport.Link{Reader: downlinkReader, Writer: uplinkWriter}, nil
+}
+
+// connectThroughServer sends a SOCKS5 CONNECT request through a server relaying to d, with
+// sniffing enabled if sniff is set, and returns the reply code, the time it took, and the error
+// the server ended with.
+func connectThroughServer(t *testing.T, d *connectDispatcher, sniff bool) (byte, time.Duration, error) {
+	t.Helper()
+	v, err := core.New(&core.Config{})
+	common.Must(err)
+	ctx := context.WithValue(context.Background(), xrayKey, v)
+	ctx = context.WithValue(ctx, "cone", false)
+	server, err := NewServer(ctx, &ServerConfig{AuthType: AuthType_NO_AUTH})
+	common.Must(err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer l.Close()
+	client, err := net.Dial("tcp", l.Addr().String())
+	common.Must(err)
+	defer client.Close()
+	conn, err := l.Accept()
+	common.Must(err)
+	defer conn.Close()
+
+	ctx = session.ContextWithInbound(ctx, &session.Inbound{
+		Source:  net.DestinationFromAddr(client.LocalAddr()),
+		Gateway: net.DestinationFromAddr(l.Addr()),
+	})
+	content := new(session.Content)
+	content.SniffingRequest.Enabled = sniff
+	ctx = session.ContextWithContent(ctx, content)
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Process(ctx, net.Network_TCP, conn, d)
+	}()
+
+	start := time.Now()
+	common.Must2(client.Write([]byte{0x05, 0x01, 0x00}))
+	method := make([]byte, 2)
+	common.Must2(io.ReadFull(client, method))
+	common.Must2(client.Write([]byte{0x05, 0x01, 0x00, 0x01, 192, 0, 2, 1, 0x01, 0xbb}))
+	reply := make([]byte, 10)
+	common.Must2(io.ReadFull(client, reply))
+	elapsed := time.Since(start)
+	client.Close()
+
+	select {
+	case err := <-done:
+		return reply[1], elapsed, err
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't end")
+		return 0, 0, nil
+	}
+}
+
+func TestServerRepliesOnceConnected(t *testing.T) {
+	code, elapsed, _ := connectThroughServer(t, &connectDispatcher{delay: 100 * time.Millisecond}, false)
+	if code != 0x00 {
+		t.Error("reply ", code, ", want success")
+	}
+	if elapsed < 100*time.Millisecond {
+		t.Error("replied before the outbound connected, after ", elapsed)
+	}
+}
+
+func TestServerRepliesDialFailure(t *testing.T) {
+	code, _, err := connectThroughServer(t, &connectDispatcher{err: errDial}, false)
+	if code != 0x01 {
+		t.Error("reply ", code, ", want general failure")
+	}
+	if errors.Cause(err) != errDial {
+		t.Error("server ended with ", err, ", want ", errDial)
+	}
+}
+
+func TestServerRepliesBeforeSniffing(t *testing.T) {
+	// The first bytes of the client are sniffed before the outbound connects, and the client
+	// sends them only once replied to.
+	code, elapsed, _ := connectThroughServer(t, &connectDispatcher{delay: 300 * time.Millisecond}, true)
+	if code != 0x00 {
+		t.Error("reply ", code, ", want success")
+	}
+	if elapsed >= 300*time.Millisecond {
+		t.Error("reply waited for the outbound to connect")
+	}
+}
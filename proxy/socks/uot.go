@@ -0,0 +1,159 @@
+package socks
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+)
+
+// Destinations of CONNECT requests through which clients, such as sing-box, ask for UDP over
+// TCP, in version 1 and 2 of the extension.
+const (
+	uotMagicAddress   = "sp.udp-over-tcp.arpa"
+	uotV2MagicAddress = "sp.v2.udp-over-tcp.arpa"
+)
+
+var uotAddrParser = protocol.NewAddressParser(
+	protocol.AddressFamilyByte(0x00, net.AddressFamilyIPv4),
+	protocol.AddressFamilyByte(0x01, net.AddressFamilyIPv6),
+	protocol.AddressFamilyByte(0x02, net.AddressFamilyDomain),
+)
+
+// UoTVersion returns the version of UDP over TCP asked for by connecting to dest, or 0 if
+// dest is an ordinary destination.
+func UoTVersion(dest net.Destination) int {
+	if !dest.Address.Family().IsDomain() {
+		return 0
+	}
+	switch dest.Address.Domain() {
+	case uotMagicAddress:
+		return 1
+	case uotV2MagicAddress:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// ReadUoTRequest reads the request which starts a stream of UDP over TCP version 2. It returns
+// the destination of all packets in the stream, or nil if each packet has its own.
+func ReadUoTRequest(reader io.Reader) (*net.Destination, error) {
+	b := buf.New()
+	defer b.Release()
+
+	if _, err := b.ReadFullFrom(reader, 1); err != nil {
+		return nil, errors.New("failed to read UDP over TCP request").Base(err)
+	}
+	connect := b.Byte(0) != 0
+	addr, port, err := uotAddrParser.ReadAddressPort(b, reader)
+	if err != nil {
+		return nil, errors.New("failed to read UDP over TCP destination").Base(err)
+	}
+	if !connect {
+		return nil, nil
+	}
+	dest := net.UDPDestination(addr, port)
+	return &dest, nil
+}
+
+// WriteUoTRequest writes the request which starts a stream of UDP over TCP version 2.
+func WriteUoTRequest(writer io.Writer, dest net.Destination, connect bool) error {
+	b := buf.New()
+	defer b.Release()
+
+	if connect {
+		b.WriteByte(1)
+	} else {
+		b.WriteByte(0)
+	}
+	if err := uotAddrParser.WriteAddressPort(b, dest.Address, dest.Port); err != nil {
+		return err
+	}
+	_, err := writer.Write(b.Bytes())
+	return err
+}
+
+// UoTReader reads the packets of a UDP over TCP stream. Each packet is a length-prefixed
+// payload, after its destination unless the stream has one for all packets.
+type UoTReader struct {
+	Reader io.Reader
+	// Destination of all packets, if the stream has one.
+	Destination *net.Destination
+}
+
+// ReadMultiBuffer implements buf.Reader.
+func (r *UoTReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	header := buf.New()
+	defer header.Release()
+
+	var dest net.Destination
+	if r.Destination != nil {
+		dest = *r.Destination
+	} else {
+		addr, port, err := uotAddrParser.ReadAddressPort(header, r.Reader)
+		if err != nil {
+			return nil, err
+		}
+		dest = net.UDPDestination(addr, port)
+	}
+	if _, err := header.ReadFullFrom(r.Reader, 2); err != nil {
+		return nil, err
+	}
+	length := int32(binary.BigEndian.Uint16(header.BytesFrom(-2)))
+
+	payload := buf.New()
+	if length > buf.Size {
+		payload.Release()
+		payload = buf.NewWithSize(length)
+	}
+	if _, err := payload.ReadFullFrom(r.Reader, length); err != nil {
+		payload.Release()
+		return nil, err
+	}
+	payload.UDP = &dest
+	return buf.MultiBuffer{payload}, nil
+}
+
+// UoTWriter writes packets to a UDP over TCP stream. The source of each packet is written
+// along with it, unless Connect is set.
+type UoTWriter struct {
+	Writer  io.Writer
+	Connect bool
+	// Source of packets without one.
+	Source net.Destination
+}
+
+// WriteMultiBuffer implements buf.Writer.
+func (w *UoTWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	defer buf.ReleaseMulti(mb)
+
+	for _, b := range mb {
+		if b.Len() > 0xffff {
+			return errors.New("UDP packet too large: ", b.Len())
+		}
+		// An address with its port takes at most 259 bytes, for the longest domain.
+		packet := buf.NewWithSize(259 + 2 + b.Len())
+		if !w.Connect {
+			source := w.Source
+			if b.UDP != nil {
+				source = *b.UDP
+			}
+			if err := uotAddrParser.WriteAddressPort(packet, source.Address, source.Port); err != nil {
+				packet.Release()
+				return err
+			}
+		}
+		binary.BigEndian.PutUint16(packet.Extend(2), uint16(b.Len()))
+		packet.Write(b.Bytes())
+		_, err := w.Writer.Write(packet.Bytes())
+		packet.Release()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
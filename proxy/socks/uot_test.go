@@ -0,0 +1,79 @@
+package socks_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+	. "github.com/xtls/xray-core/proxy/socks"
+)
+
+func TestUoTVersion(t *testing.T) {
+	cases := map[string]int{
+		"sp.udp-over-tcp.arpa":    1,
+		"sp.v2.udp-over-tcp.arpa": 2,
+		"example.com":             0,
+		"1.1.1.1":                 0,
+	}
+	for addr, version := range cases {
+		if v := UoTVersion(net.TCPDestination(net.ParseAddress(addr), 443)); v != version {
+			t.Error("version of ", addr, ": ", v, ", want ", version)
+		}
+	}
+}
+
+func TestUoTPackets(t *testing.T) {
+	stream := new(bytes.Buffer)
+	dest := net.UDPDestination(net.DomainAddress("example.com"), 53)
+	common.Must(WriteUoTRequest(stream, dest, false))
+
+	writer := &UoTWriter{Writer: stream}
+	for _, d := range []net.Destination{dest, net.UDPDestination(net.IPAddress([]byte{8, 8, 8, 8}), 53)} {
+		payload := buf.New()
+		payload.WriteString("query")
+		payload.UDP = &d
+		common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{payload}))
+	}
+
+	connect, err := ReadUoTRequest(stream)
+	common.Must(err)
+	if connect != nil {
+		t.Fatal("unexpected destination of stream: ", connect)
+	}
+	reader := &UoTReader{Reader: stream}
+	for _, want := range []string{"udp:example.com:53", "udp:8.8.8.8:53"} {
+		mb, err := reader.ReadMultiBuffer()
+		common.Must(err)
+		if r := cmp.Diff(mb[0].String(), "query"); r != "" {
+			t.Error(r)
+		}
+		if got := mb[0].UDP.String(); got != want {
+			t.Error("destination ", got, ", want ", want)
+		}
+		buf.ReleaseMulti(mb)
+	}
+}
+
+func TestUoTConnect(t *testing.T) {
+	stream := new(bytes.Buffer)
+	dest := net.UDPDestination(net.IPAddress([]byte{1, 2, 3, 4}), 443)
+	common.Must(WriteUoTRequest(stream, dest, true))
+
+	payload := buf.New()
+	payload.Write(make([]byte, 1200))
+	common.Must((&UoTWriter{Writer: stream, Connect: true}).WriteMultiBuffer(buf.MultiBuffer{payload}))
+
+	connect, err := ReadUoTRequest(stream)
+	common.Must(err)
+	if connect == nil || *connect != dest {
+		t.Fatal("unexpected destination of stream: ", connect)
+	}
+	mb, err := (&UoTReader{Reader: stream, Destination: connect}).ReadMultiBuffer()
+	common.Must(err)
+	if mb.Len() != 1200 || *mb[0].UDP != dest {
+		t.Error("unexpected packet of ", mb.Len(), " bytes to ", mb[0].UDP)
+	}
+}
@@ -0,0 +1,370 @@
+package proxy_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/app/policy"
+	"github.com/xtls/xray-core/app/proxyman"
+	_ "github.com/xtls/xray-core/app/proxyman/outbound"
+	"github.com/xtls/xray-core/app/router"
+	app_stats "github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/transport"
+)
+
+const xrayKey core.XrayKey = 1
+
+// linkHandler is an outbound handing the connections dispatched to it over to the test.
+type linkHandler struct {
+	links chan *dispatchedLink
+}
+
+type dispatchedLink struct {
+	ctx  context.Context
+	link *transport.Link
+}
+
+func (*linkHandler) Start() error { return nil }
+func (*linkHandler) Close() error { return nil }
+func (*linkHandler) Tag() string  { return "out" }
+
+func (h *linkHandler) Dispatch(ctx context.Context, link *transport.Link) {
+	h.links <- &dispatchedLink{ctx: ctx, link: link}
+}
+
+// countedConnection is a connection dispatched with its traffic counted, with the context and
+// link of its inbound, and those its outbound got.
+type countedConnection struct {
+	stats    stats.Manager
+	ctx      context.Context
+	inbound  *transport.Link
+	outbound *dispatchedLink
+}
+
+// dispatchCounted dispatches a TCP connection of a user to www.example.com, routed by rule to a
+// linkHandler, with its traffic counted by user, routing rule and domain.
+func dispatchCounted(t *testing.T, rule *router.RoutingRule) *countedConnection {
+	t.Helper()
+	v, err := core.New(&core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+			serial.ToTypedMessage(&app_stats.Config{}),
+			serial.ToTypedMessage(&policy.Config{
+				Level: map[uint32]*policy.Policy{
+					0: {Stats: &policy.Policy_Stats{UserUplink: true, UserDownlink: true}},
+				},
+				System: &policy.SystemPolicy{Stats: &policy.SystemPolicy_Stats{
+					DomainUplink:   true,
+					DomainDownlink: true,
+					Rule:           true,
+				}},
+			}),
+			serial.ToTypedMessage(&router.Config{Rule: []*router.RoutingRule{rule}}),
+		},
+	})
+	common.Must(err)
+	handler := &linkHandler{links: make(chan *dispatchedLink, 1)}
+	common.Must(v.GetFeature(outbound.ManagerType()).(outbound.Manager).AddHandler(context.Background(), handler))
+
+	ctx := context.WithValue(context.Background(), xrayKey, v)
+	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
+		From:   "127.0.0.1:10000",
+		To:     "tcp:www.example.com:80",
+		Status: log.AccessAccepted,
+	})
+	ctx = session.ContextWithInbound(ctx, &session.Inbound{
+		Source:        net.TCPDestination(net.LocalHostIP, 10000),
+		User:          &protocol.MemoryUser{Email: "user"},
+		CanSpliceCopy: 1,
+	})
+	ctx = session.ContextWithOutbounds(ctx, []*session.Outbound{{}})
+	d := v.GetFeature(routing.DispatcherType()).(routing.Dispatcher)
+	link, err := d.Dispatch(ctx, net.TCPDestination(net.DomainAddress("www.example.com"), 80))
+	common.Must(err)
+
+	select {
+	case dispatched := <-handler.links:
+		return &countedConnection{
+			stats:    v.GetFeature(stats.ManagerType()).(stats.Manager),
+			ctx:      ctx,
+			inbound:  link,
+			outbound: dispatched,
+		}
+	case <-time.After(time.Second):
+		t.Fatal("connection not dispatched")
+		return nil
+	}
+}
+
+// counted returns the values of the counters of the connection with the given names.
+func (c *countedConnection) counted(names ...string) []int64 {
+	var values []int64
+	for _, name := range names {
+		values = append(values, c.stats.GetCounter(name).Value())
+	}
+	return values
+}
+
+// tcpPair returns both ends of a TCP connection.
+func tcpPair(t *testing.T) (*net.TCPConn, *net.TCPConn) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer l.Close()
+	client, err := net.Dial("tcp", l.Addr().String())
+	common.Must(err)
+	server, err := l.Accept()
+	common.Must(err)
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client.(*net.TCPConn), server.(*net.TCPConn)
+}
+
+func skipUnlessSplice(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "android" {
+		t.Skip("splice is only used on Linux")
+	}
+}
+
+// newTimer returns an activity timer which doesn't time out during a test.
+func newTimer(ctx context.Context) *signal.ActivityTimer {
+	return signal.CancelAfterInactivity(ctx, func() {}, time.Minute)
+}
+
+func assertCounted(t *testing.T, counted []int64, n int) {
+	t.Helper()
+	for _, v := range counted {
+		if v != int64(n) {
+			t.Errorf("counted %v, want %d each", counted, n)
+			return
+		}
+	}
+}
+
+// spliceResponse splices response from the connection of the outbound of c into that of its
+// inbound, failing the test if it isn't spliced.
+func spliceResponse(t *testing.T, c *countedConnection, response []byte) {
+	t.Helper()
+	for _, ob := range session.OutboundsFromContext(c.outbound.ctx) {
+		ob.CanSpliceCopy = 1
+	}
+	remote, server := tcpPair(t)
+	inboundConn, client := tcpPair(t)
+
+	common.Must2(server.Write(response))
+	common.Must(server.Close())
+	common.Must(proxy.CopyRawConnIfExist(c.outbound.ctx, remote, inboundConn, c.outbound.link.Writer, newTimer(c.ctx), nil))
+	common.Must(inboundConn.Close())
+
+	// Splicing copies the response right into the connection of the inbound.
+	if got, _ := io.ReadAll(client); !bytes.Equal(got, response) {
+		t.Fatalf("client got %q, want %q", got, response)
+	}
+}
+
+// handOffRequest splices request from the connection of the inbound of c into that of its
+// outbound, failing the test if it isn't spliced.
+func handOffRequest(t *testing.T, c *countedConnection, request []byte) {
+	t.Helper()
+	inboundConn, client := tcpPair(t)
+	remote, server := tcpPair(t)
+	handoff := session.NewHandoff(remote, c.outbound.link.Reader)
+	session.OutboundsFromContext(c.ctx)[0].OfferUplinkHandoff(handoff)
+	handoff.Drain()
+
+	common.Must2(client.Write(request))
+	common.Must(client.Close())
+	common.Must(proxy.CopyRequest(c.ctx, inboundConn, buf.NewReader(inboundConn), c.inbound.Writer, newTimer(c.ctx)))
+	common.Must(remote.Close())
+
+	if got, _ := io.ReadAll(server); !bytes.Equal(got, request) {
+		t.Fatalf("server got %q, want %q", got, request)
+	}
+}
+
+func TestSpliceCountsRule(t *testing.T) {
+	skipUnlessSplice(t)
+	c := dispatchCounted(t, &router.RoutingRule{
+		TargetTag: &router.RoutingRule_Tag{Tag: "out"},
+		RuleTag:   "rule",
+		Networks:  []net.Network{net.Network_TCP},
+	})
+
+	response := []byte("response")
+	spliceResponse(t, c, response)
+	assertCounted(t, c.counted(
+		"user>>>user>>>traffic>>>downlink",
+		routing.RuleCounterName("rule", "traffic>>>downlink"),
+		"domain>>>example.com>>>traffic>>>downlink",
+	), len(response))
+}
+
+func TestHandoffCountsRule(t *testing.T) {
+	skipUnlessSplice(t)
+	c := dispatchCounted(t, &router.RoutingRule{
+		TargetTag: &router.RoutingRule_Tag{Tag: "out"},
+		RuleTag:   "rule",
+		Networks:  []net.Network{net.Network_TCP},
+	})
+
+	request := []byte("request")
+	handOffRequest(t, c, request)
+	assertCounted(t, c.counted(
+		"user>>>user>>>traffic>>>uplink",
+		routing.RuleCounterName("rule", "traffic>>>uplink"),
+		"domain>>>example.com>>>traffic>>>uplink",
+	), len(request))
+}
+
+func TestSpliceCountsDomain(t *testing.T) {
+	skipUnlessSplice(t)
+	// Without a rule tag, the counter of the domain wraps that of the user alone.
+	c := dispatchCounted(t, &router.RoutingRule{
+		TargetTag: &router.RoutingRule_Tag{Tag: "out"},
+		Networks:  []net.Network{net.Network_TCP},
+	})
+
+	response := []byte("response")
+	spliceResponse(t, c, response)
+	assertCounted(t, c.counted(
+		"user>>>user>>>traffic>>>downlink",
+		"domain>>>example.com>>>traffic>>>downlink",
+	), len(response))
+}
+
+func TestHandoffCountsDomain(t *testing.T) {
+	skipUnlessSplice(t)
+	c := dispatchCounted(t, &router.RoutingRule{
+		TargetTag: &router.RoutingRule_Tag{Tag: "out"},
+		Networks:  []net.Network{net.Network_TCP},
+	})
+
+	request := []byte("request")
+	handOffRequest(t, c, request)
+	assertCounted(t, c.counted(
+		"user>>>user>>>traffic>>>uplink",
+		"domain>>>example.com>>>traffic>>>uplink",
+	), len(request))
+}
+
+func TestSpliceKeepsMirroredTraffic(t *testing.T) {
+	skipUnlessSplice(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer l.Close()
+	mirrored := make(chan []byte, 2)
+	go func() {
+		// One stream mirrors the uplink and another the downlink.
+		for i := 0; i < 2; i++ {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				b, _ := io.ReadAll(conn)
+				mirrored <- b
+			}()
+		}
+	}()
+	c := dispatchCounted(t, &router.RoutingRule{
+		TargetTag: &router.RoutingRule_Tag{Tag: "out"},
+		Networks:  []net.Network{net.Network_TCP},
+		Mirror:    &router.MirrorConfig{Address: l.Addr().String(), Downlink: true},
+	})
+	for _, ob := range session.OutboundsFromContext(c.outbound.ctx) {
+		ob.CanSpliceCopy = 1
+	}
+	remote, server := tcpPair(t)
+	inboundConn, _ := tcpPair(t)
+
+	response := []byte("response")
+	common.Must2(server.Write(response))
+	common.Must(server.Close())
+	common.Must(proxy.CopyRawConnIfExist(c.outbound.ctx, remote, inboundConn, c.outbound.link.Writer, newTimer(c.ctx), nil))
+	common.Must(common.Close(c.outbound.link.Writer))
+	common.Interrupt(c.outbound.link.Reader)
+
+	// The response is copied through the link, rather than spliced past it.
+	mb, err := c.inbound.Reader.ReadMultiBuffer()
+	common.Must(err)
+	if got := mb.String(); got != string(response) {
+		t.Errorf("link got %q, want %q", got, response)
+	}
+	var got [][]byte
+	for i := 0; i < 2; i++ {
+		select {
+		case b := <-mirrored:
+			if len(b) > 0 {
+				got = append(got, b)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("mirror streams not closed")
+		}
+	}
+	if len(got) != 1 || !bytes.Equal(got[0], response) {
+		t.Errorf("mirrored %q, want %q", got, response)
+	}
+	assertCounted(t, c.counted("user>>>user>>>traffic>>>downlink"), len(response))
+}
+
+// accessRecorder hands the accesses of closed connections over to the test.
+type accessRecorder struct {
+	closed chan *log.AccessMessage
+}
+
+func (r *accessRecorder) Handle(msg log.Message) {
+	if m, ok := msg.(*log.AccessMessage); ok && m.Status == log.AccessClosed {
+		r.closed <- m
+	}
+}
+
+func TestSpliceCountsClosedAccess(t *testing.T) {
+	skipUnlessSplice(t)
+	recorder := &accessRecorder{closed: make(chan *log.AccessMessage, 1)}
+	log.RegisterHandler(recorder)
+	log.RecordClosedAccesses(true)
+	defer log.RecordClosedAccesses(false)
+	c := dispatchCounted(t, &router.RoutingRule{
+		TargetTag: &router.RoutingRule_Tag{Tag: "out"},
+		Networks:  []net.Network{net.Network_TCP},
+	})
+
+	request := []byte("request")
+	handOffRequest(t, c, request)
+	response := []byte("a longer response")
+	spliceResponse(t, c, response)
+	common.Must(common.Close(c.outbound.link.Writer))
+
+	select {
+	case m := <-recorder.closed:
+		if m.Up != int64(len(request)) || m.Down != int64(len(response)) {
+			t.Errorf("closed access carried %d bytes up and %d down, want %d and %d", m.Up, m.Down, len(request), len(response))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("closed access not recorded")
+	}
+	assertCounted(t, c.counted("user>>>user>>>traffic>>>uplink"), len(request))
+	assertCounted(t, c.counted("user>>>user>>>traffic>>>downlink"), len(response))
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/xtls/xray-core/common/task"
 	core "github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/stat"
@@ -22,8 +23,10 @@ import (
 
 // Client is a inbound handler for trojan protocol
 type Client struct {
+	serverList    *protocol.ServerList
 	serverPicker  protocol.ServerPicker
 	policyManager policy.Manager
+	shaping       *protocol.HandshakeShaping
 }
 
 // NewClient create a new trojan client.
@@ -42,12 +45,24 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 
 	v := core.MustFromContext(ctx)
 	client := &Client{
+		serverList:    serverList,
 		serverPicker:  protocol.NewRoundRobinServerPicker(serverList),
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+		shaping:       config.HandshakeShaping,
 	}
 	return client, nil
 }
 
+// Init implements proxy.Initializer.
+func (c *Client) Init(ctx context.Context, dialer internet.Dialer) error {
+	return proxy.PrepareServers(ctx, dialer, c.serverList)
+}
+
+// ConnectsFirst implements proxy.Connector.
+func (c *Client) ConnectsFirst() bool {
+	return true
+}
+
 // Process implements OutboundHandler.Process().
 func (c *Client) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	outbounds := session.OutboundsFromContext(ctx)
@@ -104,7 +119,8 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 	postRequest := func() error {
 		defer timer.SetTimeout(sessionPolicy.Timeouts.DownlinkOnly)
 
-		bufferWriter := buf.NewBufferedWriter(buf.NewWriter(conn))
+		// Trojan has no padding, so its handshake is only shaped by segments.
+		bufferWriter := buf.NewBufferedWriter(buf.NewWriter(protocol.NewShapingWriter(conn, c.shaping)))
 
 		connWriter := &ConnWriter{
 			Writer:  bufferWriter,
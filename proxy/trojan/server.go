@@ -13,14 +13,18 @@ import (
 	"github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol"
+	http_proto "github.com/xtls/xray-core/common/protocol/http"
 	udp_proto "github.com/xtls/xray-core/common/protocol/udp"
 	"github.com/xtls/xray-core/common/retry"
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/common/site"
 	"github.com/xtls/xray-core/common/task"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport/internet/reality"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/tls"
@@ -39,6 +43,8 @@ type Server struct {
 	validator     *Validator
 	fallbacks     map[string]map[string]map[string]*Fallback // or nil
 	cone          bool
+	downgrade     *proxy.DowngradeAlert
+	handshakes    *proxy.HandshakeStats
 }
 
 // NewServer creates a new trojan inbound handler.
@@ -56,10 +62,13 @@ func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
 	}
 
 	v := core.MustFromContext(ctx)
+	statsManager, _ := v.GetFeature(stats.ManagerType()).(stats.Manager)
 	server := &Server{
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
 		validator:     validator,
 		cone:          ctx.Value("cone").(bool),
+		downgrade:     proxy.NewDowngradeAlert(statsManager),
+		handshakes:    proxy.NewHandshakeStats(statsManager),
 	}
 
 	if config.Fallbacks != nil {
@@ -177,10 +186,11 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Con
 	isfb := napfb != nil
 
 	shouldFallback := false
+	cause := proxy.HandshakeProtocol
 	if firstLen < 58 || first.Byte(56) != '\r' {
 		// invalid protocol
 		err = errors.New("not trojan protocol")
-		log.Record(&log.AccessMessage{
+		log.RecordAccess(ctx, &log.AccessMessage{
 			From:   conn.RemoteAddr(),
 			To:     "",
 			Status: log.AccessRejected,
@@ -193,7 +203,8 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Con
 		if user == nil {
 			// invalid user, let's fallback
 			err = errors.New("not a valid user")
-			log.Record(&log.AccessMessage{
+			cause = proxy.HandshakeAuth
+			log.RecordAccess(ctx, &log.AccessMessage{
 				From:   conn.RemoteAddr(),
 				To:     "",
 				Status: log.AccessRejected,
@@ -207,17 +218,19 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Con
 	if isfb && shouldFallback {
 		return s.fallback(ctx, err, sessionPolicy, conn, iConn, napfb, first, firstLen, bufferedReader)
 	} else if shouldFallback {
+		s.handshakes.Report(ctx, cause)
 		return errors.New("invalid protocol or invalid user")
 	}
 
 	clientReader := &ConnReader{Reader: bufferedReader}
 	if err := clientReader.ParseHeader(); err != nil {
-		log.Record(&log.AccessMessage{
+		log.RecordAccess(ctx, &log.AccessMessage{
 			From:   conn.RemoteAddr(),
 			To:     "",
 			Status: log.AccessRejected,
 			Reason: err,
 		})
+		s.handshakes.Report(ctx, proxy.HandshakeProtocol)
 		return errors.New("failed to create request from: ", conn.RemoteAddr()).Base(err)
 	}
 
@@ -231,6 +244,7 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Con
 	inbound.CanSpliceCopy = 3
 	inbound.User = user
 	sessionPolicy = s.policyManager.ForLevel(user.Level)
+	s.downgrade.CheckTLS(ctx, iConn)
 
 	if destination.Network == net.Network_UDP { // handle udp request
 		return s.handleUDPPayload(ctx, &PacketReader{Reader: clientReader}, &PacketWriter{Writer: conn}, dispatcher)
@@ -315,6 +329,9 @@ func (s *Server) handleConnection(ctx context.Context, sessionPolicy policy.Sess
 ) error {
 	ctx, cancel := context.WithCancel(ctx)
 	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		inbound.Timer = timer
+	}
 	ctx = policy.ContextWithBufferPolicy(ctx, sessionPolicy.Buffer)
 
 	link, err := dispatcher.Dispatch(ctx, destination)
@@ -354,6 +371,7 @@ func (s *Server) fallback(ctx context.Context, err error, sessionPolicy policy.S
 		errors.LogWarningInner(ctx, err, "unable to set back read deadline")
 	}
 	errors.LogInfoInner(ctx, err, "fallback starts")
+	s.handshakes.Report(ctx, proxy.HandshakeFallback)
 
 	name := ""
 	alpn := ""
@@ -437,12 +455,19 @@ func (s *Server) fallback(ctx context.Context, err error, sessionPolicy policy.S
 
 	ctx, cancel := context.WithCancel(ctx)
 	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		inbound.Timer = timer
+	}
 	ctx = policy.ContextWithBufferPolicy(ctx, sessionPolicy.Buffer)
 
 	var conn net.Conn
 	if err := retry.ExponentialBackoff(5, 100).On(func() error {
-		var dialer net.Dialer
-		conn, err = dialer.DialContext(ctx, fb.Type, fb.Dest)
+		if fb.Type == "site" {
+			conn, err = site.Dial(fb.Dest)
+		} else {
+			var dialer net.Dialer
+			conn, err = dialer.DialContext(ctx, fb.Type, fb.Dest)
+		}
 		if err != nil {
 			return err
 		}
@@ -511,6 +536,21 @@ func (s *Server) fallback(ctx context.Context, err error, sessionPolicy policy.S
 				return errors.New("failed to set PROXY protocol v", fb.Xver).Base(err).AtWarning()
 			}
 		}
+		if len(fb.ForwardHeaders) > 0 {
+			client, _, _ := net.SplitHostPort(connection.RemoteAddr().String())
+			proto := "http"
+			switch iConn.(type) {
+			case *tls.Conn, *reality.Conn:
+				proto = "https"
+			}
+			ok, err := http_proto.ForwardRequest(reader, serverWriter, fb.ForwardHeaders, client, proto)
+			if err != nil {
+				return errors.New("failed to fallback request payload").Base(err).AtInfo()
+			}
+			if !ok {
+				errors.LogInfo(ctx, "headers not forwarded to ", fb.Dest, " as the first request isn't complete HTTP/1.x")
+			}
+		}
 		if err := buf.Copy(reader, serverWriter, buf.UpdateActivity(timer)); err != nil {
 			return errors.New("failed to fallback request payload").Base(err).AtInfo()
 		}
@@ -0,0 +1,65 @@
+package tun
+
+import (
+	"net/netip"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+const defaultMTU = 1500
+
+var (
+	defaultAddress = []string{"172.19.0.1/30"}
+	// defaultRoutes take all traffic without replacing the default route, which is kept for the
+	// interface it goes through to be found, and restored by removing them.
+	defaultRoutes4 = []string{"0.0.0.0/1", "128.0.0.0/1"}
+	defaultRoutes6 = []string{"::/1", "8000::/1"}
+)
+
+func (c *Config) mtu() int {
+	if c.Mtu == 0 {
+		return defaultMTU
+	}
+	return int(c.Mtu)
+}
+
+// prefixes returns the addresses of the interface.
+func (c *Config) prefixes() ([]netip.Prefix, error) {
+	addresses := c.Address
+	if len(addresses) == 0 {
+		addresses = defaultAddress
+	}
+	return parsePrefixes(addresses)
+}
+
+// routes returns the networks routed through the interface given prefixes, the addresses of it.
+func (c *Config) routes(prefixes []netip.Prefix) ([]netip.Prefix, error) {
+	routes := c.Route
+	if len(routes) == 0 {
+		for _, p := range prefixes {
+			if p.Addr().Is4() {
+				routes = append(routes, defaultRoutes4...)
+				break
+			}
+		}
+		for _, p := range prefixes {
+			if p.Addr().Is6() {
+				routes = append(routes, defaultRoutes6...)
+				break
+			}
+		}
+	}
+	return parsePrefixes(routes)
+}
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		p, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, errors.New("invalid CIDR: ", cidr).Base(err)
+		}
+		prefixes = append(prefixes, p)
+	}
+	return prefixes, nil
+}
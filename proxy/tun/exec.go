@@ -0,0 +1,24 @@
+//go:build darwin || windows
+
+package tun
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// output runs the command name with args, and returns its output.
+func output(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return "", errors.New(name, " ", strings.Join(args, " "), ": ", strings.TrimSpace(string(out))).Base(err)
+	}
+	return string(out), nil
+}
+
+func run(name string, args ...string) error {
+	_, err := output(name, args...)
+	return err
+}
@@ -0,0 +1,74 @@
+package tun
+
+import (
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// defaultName lets the system pick the next utun device.
+const defaultName = "utun"
+
+// configure assigns prefixes to the interface name, and brings it up.
+func configure(name string, prefixes []netip.Prefix) error {
+	for _, p := range prefixes {
+		var err error
+		if p.Addr().Is4() {
+			mask := net.IP(net.CIDRMask(p.Bits(), 32)).String()
+			// A utun device is point to point, the stack taking the next address.
+			err = run("ifconfig", name, "inet", p.Addr().String(), p.Addr().Next().String(), "netmask", mask, "up")
+		} else {
+			err = run("ifconfig", name, "inet6", p.Addr().String(), "prefixlen", strconv.Itoa(p.Bits()), "alias")
+		}
+		if err != nil {
+			return errors.New("failed to add address ", p, " to ", name).Base(err)
+		}
+	}
+	return nil
+}
+
+func routeArgs(action, name string, p netip.Prefix) []string {
+	args := []string{"-n", action}
+	if p.Addr().Is6() {
+		args = append(args, "-inet6")
+	}
+	return append(args, "-net", p.Masked().String(), "-interface", name)
+}
+
+// addRoutes routes the networks of routes through the interface name, and returns the function
+// removing them.
+func addRoutes(name string, routes []netip.Prefix) (func() error, error) {
+	var added []netip.Prefix
+	remove := func() error {
+		var errs []error
+		for _, p := range added {
+			errs = append(errs, run("route", routeArgs("delete", name, p)...))
+		}
+		return errors.Combine(errs...)
+	}
+	for _, p := range routes {
+		if err := run("route", routeArgs("add", name, p)...); err != nil {
+			remove()
+			return nil, errors.New("failed to add route ", p).Base(err)
+		}
+		added = append(added, p)
+	}
+	return remove, nil
+}
+
+// defaultInterface returns the interface the IPv4 default route goes through.
+func defaultInterface() (string, error) {
+	out, err := output("route", "-n", "get", "default")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if name, found := strings.CutPrefix(strings.TrimSpace(line), "interface:"); found {
+			return strings.TrimSpace(name), nil
+		}
+	}
+	return "", errors.New("no default route")
+}
@@ -0,0 +1,79 @@
+package tun
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/vishvananda/netlink"
+	"github.com/xtls/xray-core/common/errors"
+)
+
+const defaultName = "xray0"
+
+func ipNet(p netip.Prefix) *net.IPNet {
+	return &net.IPNet{
+		IP:   p.Addr().AsSlice(),
+		Mask: net.CIDRMask(p.Bits(), p.Addr().BitLen()),
+	}
+}
+
+// configure assigns prefixes to the interface name, and brings it up.
+func configure(name string, prefixes []netip.Prefix) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return err
+	}
+	for _, p := range prefixes {
+		if err := netlink.AddrAdd(link, &netlink.Addr{IPNet: ipNet(p)}); err != nil {
+			return errors.New("failed to add address ", p, " to ", name).Base(err)
+		}
+	}
+	return netlink.LinkSetUp(link)
+}
+
+// addRoutes routes the networks of routes through the interface name, and returns the function
+// removing them.
+func addRoutes(name string, routes []netip.Prefix) (func() error, error) {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return nil, err
+	}
+	var added []*netlink.Route
+	remove := func() error {
+		var errs []error
+		for _, r := range added {
+			errs = append(errs, netlink.RouteDel(r))
+		}
+		return errors.Combine(errs...)
+	}
+	for _, p := range routes {
+		r := &netlink.Route{LinkIndex: link.Attrs().Index, Dst: ipNet(p.Masked())}
+		if err := netlink.RouteAdd(r); err != nil {
+			remove()
+			return nil, errors.New("failed to add route ", p).Base(err)
+		}
+		added = append(added, r)
+	}
+	return remove, nil
+}
+
+// defaultInterface returns the interface the IPv4 default route goes through.
+func defaultInterface() (string, error) {
+	routes, err := netlink.RouteList(nil, netlink.FAMILY_V4)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range routes {
+		if r.Dst != nil {
+			if ones, _ := r.Dst.Mask.Size(); ones != 0 {
+				continue
+			}
+		}
+		link, err := netlink.LinkByIndex(r.LinkIndex)
+		if err != nil {
+			return "", err
+		}
+		return link.Attrs().Name, nil
+	}
+	return "", errors.New("no default route")
+}
@@ -0,0 +1,23 @@
+//go:build !linux && !darwin && !windows
+
+package tun
+
+import (
+	"net/netip"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+const defaultName = "tun"
+
+func configure(name string, prefixes []netip.Prefix) error {
+	return errors.New("TUN is not supported on this platform")
+}
+
+func addRoutes(name string, routes []netip.Prefix) (func() error, error) {
+	return nil, errors.New("TUN is not supported on this platform")
+}
+
+func defaultInterface() (string, error) {
+	return "", errors.New("TUN is not supported on this platform")
+}
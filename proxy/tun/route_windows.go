@@ -0,0 +1,76 @@
+package tun
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+const defaultName = "xray"
+
+// configure assigns prefixes to the interface name.
+func configure(name string, prefixes []netip.Prefix) error {
+	for _, p := range prefixes {
+		var err error
+		if p.Addr().Is4() {
+			mask := net.IP(net.CIDRMask(p.Bits(), 32)).String()
+			err = run("netsh", "interface", "ipv4", "add", "address", "name="+name, "address="+p.Addr().String(), "mask="+mask)
+		} else {
+			err = run("netsh", "interface", "ipv6", "add", "address", "interface="+name, "address="+p.String())
+		}
+		if err != nil {
+			return errors.New("failed to add address ", p, " to ", name).Base(err)
+		}
+	}
+	return nil
+}
+
+func routeArgs(action, name string, p netip.Prefix) []string {
+	family := "ipv4"
+	if p.Addr().Is6() {
+		family = "ipv6"
+	}
+	args := []string{"interface", family, action, "route", "prefix=" + p.Masked().String(), "interface=" + name}
+	if action == "add" {
+		args = append(args, "metric=1")
+	}
+	return append(args, "store=active")
+}
+
+// addRoutes routes the networks of routes through the interface name, and returns the function
+// removing them.
+func addRoutes(name string, routes []netip.Prefix) (func() error, error) {
+	var added []netip.Prefix
+	remove := func() error {
+		var errs []error
+		for _, p := range added {
+			errs = append(errs, run("netsh", routeArgs("delete", name, p)...))
+		}
+		return errors.Combine(errs...)
+	}
+	for _, p := range routes {
+		if err := run("netsh", routeArgs("add", name, p)...); err != nil {
+			remove()
+			return nil, errors.New("failed to add route ", p).Base(err)
+		}
+		added = append(added, p)
+	}
+	return remove, nil
+}
+
+// defaultInterface returns the interface the IPv4 default route with the lowest metric goes
+// through.
+func defaultInterface() (string, error) {
+	out, err := output("powershell", "-NoProfile", "-Command",
+		"(Get-NetRoute -DestinationPrefix 0.0.0.0/0 | Sort-Object -Property RouteMetric | Select-Object -First 1).InterfaceAlias")
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(out)
+	if name == "" {
+		return "", errors.New("no default route")
+	}
+	return name, nil
+}
@@ -0,0 +1,258 @@
+// Package tun is an inbound taking the traffic of a TUN device, so that the whole system, UDP and
+// programs ignoring proxy settings included, can be proxied.
+package tun
+
+import (
+	"context"
+	goerrors "errors"
+	gonet "net"
+	"net/netip"
+	"os"
+	"sync"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy/wireguard/gvisortun"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"golang.zx2c4.com/wireguard/tun"
+)
+
+// packetOffset is the room left before packets read from and written to devices, which the
+// Linux device needs for its virtio header.
+const packetOffset = 16
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		h := new(Handler)
+		err := core.RequireFeatures(ctx, func(pm policy.Manager) error {
+			return h.Init(config.(*Config), pm)
+		})
+		return h, err
+	}))
+}
+
+// Handler is an inbound accepting the connections sent to a TUN device. The packets of the device
+// are handled by a userspace network stack, which hands over the TCP and UDP connections.
+type Handler struct {
+	policyManager policy.Manager
+	config        *Config
+	prefixes      []netip.Prefix
+	routes        []netip.Prefix
+
+	access       sync.Mutex
+	device       tun.Device
+	stack        tun.Device
+	removeRoutes func() error
+}
+
+// Init initializes the Handler with necessary parameters.
+func (h *Handler) Init(config *Config, pm policy.Manager) error {
+	prefixes, err := config.prefixes()
+	if err != nil {
+		return err
+	}
+	routes, err := config.routes(prefixes)
+	if err != nil {
+		return err
+	}
+	h.config = config
+	h.policyManager = pm
+	h.prefixes = prefixes
+	h.routes = routes
+	return nil
+}
+
+// Network implements proxy.Inbound. The Handler listens on no port.
+func (h *Handler) Network() []net.Network {
+	return []net.Network{}
+}
+
+// Accept implements proxy.Acceptor. It creates the device, and routes traffic through it with
+// auto_route.
+func (h *Handler) Accept(handle func(conn stat.Connection, dest net.Destination)) error {
+	h.access.Lock()
+	defer h.access.Unlock()
+
+	name := h.config.Name
+	if name == "" {
+		name = defaultName
+	}
+	device, err := tun.CreateTUN(name, h.config.mtu())
+	if err != nil {
+		return errors.New("failed to create TUN device ", name).Base(err)
+	}
+	if name, err = device.Name(); err != nil {
+		device.Close()
+		return errors.New("failed to get name of TUN device").Base(err)
+	}
+	if err := configure(name, h.prefixes); err != nil {
+		device.Close()
+		return errors.New("failed to configure TUN device ", name).Base(err)
+	}
+
+	// The stack takes the next address to that of the interface, sending packets to the system.
+	stackAddresses := make([]netip.Addr, 0, len(h.prefixes))
+	for _, p := range h.prefixes {
+		stackAddresses = append(stackAddresses, p.Addr().Next())
+	}
+	stack, _, s, err := gvisortun.CreateNetTUN(stackAddresses, h.config.mtu(), true)
+	if err != nil {
+		device.Close()
+		return errors.New("failed to create network stack").Base(err)
+	}
+	gvisortun.ForwardConnections(s, func(dest net.Destination, conn gonet.Conn) {
+		handle(conn, dest)
+	})
+	h.device, h.stack = device, stack
+	go h.copyPackets(stack, device)
+	go h.copyPackets(device, stack)
+
+	if h.config.AutoRoute {
+		// The connections of Xray itself keep to the interface the default route goes through.
+		outbound, err := defaultInterface()
+		if err != nil {
+			h.close()
+			return errors.New("failed to find default interface").Base(err)
+		}
+		if h.removeRoutes, err = addRoutes(name, h.routes); err != nil {
+			h.close()
+			return errors.New("failed to add routes through TUN device ", name).Base(err)
+		}
+		internet.BindToInterface(outbound)
+		errors.LogInfo(context.Background(), "routing ", h.routes, " through TUN device ", name, ", connections out through ", outbound)
+	}
+	errors.LogInfo(context.Background(), "TUN device ", name, " is up")
+	return nil
+}
+
+// copyPackets copies packets from src to dst until either is closed.
+func (h *Handler) copyPackets(dst, src tun.Device) {
+	batch := src.BatchSize()
+	bufs := make([][]byte, batch)
+	for i := range bufs {
+		bufs[i] = make([]byte, packetOffset+h.config.mtu())
+	}
+	sizes := make([]int, batch)
+	packets := make([][]byte, 0, batch)
+	for {
+		n, err := src.Read(bufs, sizes, packetOffset)
+		packets = packets[:0]
+		for i := 0; i < n; i++ {
+			packets = append(packets, bufs[i][:packetOffset+sizes[i]])
+		}
+		if len(packets) > 0 {
+			if _, err := dst.Write(packets, packetOffset); err != nil && goerrors.Is(err, os.ErrClosed) {
+				return
+			}
+		}
+		if err != nil {
+			if goerrors.Is(err, tun.ErrTooManySegments) {
+				continue
+			}
+			return
+		}
+	}
+}
+
+// Close implements common.Closable. It removes the routes, and closes the device.
+func (h *Handler) Close() error {
+	h.access.Lock()
+	defer h.access.Unlock()
+	return h.close()
+}
+
+func (h *Handler) close() error {
+	var errs []error
+	if h.removeRoutes != nil {
+		internet.BindToInterface("")
+		errs = append(errs, h.removeRoutes())
+		h.removeRoutes = nil
+	}
+	if h.stack != nil {
+		errs = append(errs, h.stack.Close())
+		h.stack = nil
+	}
+	if h.device != nil {
+		errs = append(errs, h.device.Close())
+		h.device = nil
+	}
+	return errors.Combine(errs...)
+}
+
+// Process implements proxy.Inbound.
+func (h *Handler) Process(ctx context.Context, network net.Network, conn stat.Connection, dispatcher routing.Dispatcher) error {
+	outbounds := session.OutboundsFromContext(ctx)
+	if len(outbounds) == 0 || !outbounds[0].Target.IsValid() {
+		return errors.New("unable to get destination")
+	}
+	dest := outbounds[0].Target
+
+	inbound := session.InboundFromContext(ctx)
+	inbound.Name = "tun"
+	inbound.User = &protocol.MemoryUser{
+		Level: h.config.UserLevel,
+	}
+
+	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
+		From:   conn.RemoteAddr(),
+		To:     dest,
+		Status: log.AccessAccepted,
+		Reason: "",
+	})
+	errors.LogInfo(ctx, "received request for ", conn.RemoteAddr())
+
+	plcy := h.policyManager.ForLevel(h.config.UserLevel)
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, plcy.Timeouts.ConnectionIdle)
+	inbound.Timer = timer
+
+	ctx = policy.ContextWithBufferPolicy(ctx, plcy.Buffer)
+	link, err := dispatcher.Dispatch(ctx, dest)
+	if err != nil {
+		return errors.New("failed to dispatch request").Base(err)
+	}
+
+	var reader buf.Reader
+	var writer buf.Writer
+	if network == net.Network_UDP {
+		reader = buf.NewPacketReader(conn)
+		writer = &buf.SequentialWriter{Writer: conn}
+	} else {
+		reader = buf.NewReader(conn)
+		writer = buf.NewWriter(conn)
+	}
+
+	requestDone := func() error {
+		defer timer.SetTimeout(plcy.Timeouts.DownlinkOnly)
+		if err := buf.Copy(reader, link.Writer, buf.UpdateActivity(timer)); err != nil {
+			return errors.New("failed to transport request").Base(err)
+		}
+		return nil
+	}
+
+	responseDone := func() error {
+		defer timer.SetTimeout(plcy.Timeouts.UplinkOnly)
+		if err := buf.Copy(link.Reader, writer, buf.UpdateActivity(timer)); err != nil {
+			return errors.New("failed to transport response").Base(err)
+		}
+		return nil
+	}
+
+	if err := task.Run(ctx, task.OnSuccess(requestDone, task.Close(link.Writer)), responseDone); err != nil {
+		common.Interrupt(link.Reader)
+		common.Interrupt(link.Writer)
+		return errors.New("connection ends").Base(err)
+	}
+	return nil
+}
@@ -20,6 +20,9 @@ const (
 	Version = byte(0)
 )
 
+// ErrInvalidUser is returned by DecodeRequestHeader for requests of unknown users.
+var ErrInvalidUser = errors.New("invalid request user id")
+
 var addrParser = protocol.NewAddressParser(
 	protocol.AddressFamilyByte(byte(protocol.AddressTypeIPv4), net.AddressFamilyIPv4),
 	protocol.AddressFamilyByte(byte(protocol.AddressTypeDomain), net.AddressFamilyDomain),
@@ -93,7 +96,7 @@ func DecodeRequestHeader(isfb bool, first *buf.Buffer, reader io.Reader, validat
 		}
 
 		if request.User = validator.Get(id); request.User == nil {
-			return nil, nil, isfb, errors.New("invalid request user id")
+			return nil, nil, isfb, ErrInvalidUser
 		}
 
 		if isfb {
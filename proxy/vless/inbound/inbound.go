@@ -3,6 +3,7 @@ package inbound
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	gotls "crypto/tls"
 	"io"
 	"reflect"
@@ -12,20 +13,24 @@ import (
 	"unsafe"
 
 	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/antireplay"
 	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol"
+	http_proto "github.com/xtls/xray-core/common/protocol/http"
 	"github.com/xtls/xray-core/common/retry"
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/common/site"
 	"github.com/xtls/xray-core/common/task"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/dns"
 	feature_inbound "github.com/xtls/xray-core/features/inbound"
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/proxy/vless"
 	"github.com/xtls/xray-core/proxy/vless/encoding"
@@ -61,6 +66,9 @@ func init() {
 	}))
 }
 
+// replayInterval is how long, in seconds, the first packets of requests are remembered.
+const replayInterval = 120
+
 // Handler is an inbound connection handler that handles messages in VLess protocol.
 type Handler struct {
 	inboundHandlerManager feature_inbound.Manager
@@ -69,16 +77,28 @@ type Handler struct {
 	dns                   dns.Client
 	fallbacks             map[string]map[string]map[string]*Fallback // or nil
 	// regexps               map[string]*regexp.Regexp       // or nil
+	replayGuard  *proxy.ReplayGuard
+	replayFilter *antireplay.ReplayFilter // or nil
+	downgrade    *proxy.DowngradeAlert
+	handshakes   *proxy.HandshakeStats
 }
 
 // New creates a new VLess inbound handler.
 func New(ctx context.Context, config *Config, dc dns.Client, validator vless.Validator) (*Handler, error) {
 	v := core.MustFromContext(ctx)
+	statsManager, _ := v.GetFeature(stats.ManagerType()).(stats.Manager)
 	handler := &Handler{
 		inboundHandlerManager: v.GetFeature(feature_inbound.ManagerType()).(feature_inbound.Manager),
 		policyManager:         v.GetFeature(policy.ManagerType()).(policy.Manager),
 		dns:                   dc,
 		validator:             validator,
+		downgrade:             proxy.NewDowngradeAlert(statsManager),
+		handshakes:            proxy.NewHandshakeStats(statsManager),
+	}
+
+	if config.Replay != nil {
+		handler.replayGuard = proxy.NewReplayGuard(config.Replay, statsManager)
+		handler.replayFilter = antireplay.NewReplayFilter(replayInterval)
 	}
 
 	if config.Fallbacks != nil {
@@ -199,6 +219,10 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 		iConn = statConn.Connection
 	}
 
+	if h.replayGuard != nil && h.replayGuard.Banned(ctx) {
+		return errors.New("rejected banned source ", connection.RemoteAddr()).AtInfo()
+	}
+
 	sessionPolicy := h.policyManager.ForLevel(0)
 	if err := connection.SetReadDeadline(time.Now().Add(sessionPolicy.Timeouts.Handshake)); err != nil {
 		return errors.New("unable to set read deadline").Base(err).AtWarning()
@@ -217,6 +241,7 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 	var request *protocol.RequestHeader
 	var requestAddons *encoding.Addons
 	var err error
+	replayed := false
 
 	napfb := h.fallbacks
 	isfb := napfb != nil
@@ -224,7 +249,27 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 	if isfb && firstLen < 18 {
 		err = errors.New("fallback directly")
 	} else {
+		var raw []byte
+		if h.replayFilter != nil {
+			raw = bytes.Clone(first.Bytes())
+		}
 		request, requestAddons, isfb, err = encoding.DecodeRequestHeader(isfb, first, reader, h.validator)
+		// Only requests carrying early data are checked, a bare header may well repeat.
+		if err == nil && raw != nil && reader.BufferedBytes() > 0 {
+			sum := sha256.Sum256(raw)
+			if !h.replayFilter.Check(sum[:]) {
+				err = errors.New("replayed request of ", request.User.Email)
+				replayed = true
+				isfb = napfb != nil && h.replayGuard.Report(ctx, err) == protocol.ReplayPolicy_Fallback
+				if isfb {
+					first = buf.FromBytes(raw)
+					reader = &buf.BufferedReader{
+						Reader: buf.NewReader(connection),
+						Buffer: buf.MultiBuffer{first},
+					}
+				}
+			}
+		}
 	}
 
 	if err != nil {
@@ -233,6 +278,7 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 				errors.LogWarningInner(ctx, err, "unable to set back read deadline")
 			}
 			errors.LogInfoInner(ctx, err, "fallback starts")
+			h.handshakes.Report(ctx, proxy.HandshakeFallback)
 
 			name := ""
 			alpn := ""
@@ -335,8 +381,12 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 
 			var conn net.Conn
 			if err := retry.ExponentialBackoff(5, 100).On(func() error {
-				var dialer net.Dialer
-				conn, err = dialer.DialContext(ctx, fb.Type, fb.Dest)
+				if fb.Type == "site" {
+					conn, err = site.Dial(fb.Dest)
+				} else {
+					var dialer net.Dialer
+					conn, err = dialer.DialContext(ctx, fb.Type, fb.Dest)
+				}
 				if err != nil {
 					return err
 				}
@@ -405,6 +455,21 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 						return errors.New("failed to set PROXY protocol v", fb.Xver).Base(err).AtWarning()
 					}
 				}
+				if len(fb.ForwardHeaders) > 0 {
+					client, _, _ := net.SplitHostPort(connection.RemoteAddr().String())
+					proto := "http"
+					switch iConn.(type) {
+					case *tls.Conn, *reality.Conn:
+						proto = "https"
+					}
+					ok, err := http_proto.ForwardRequest(reader, serverWriter, fb.ForwardHeaders, client, proto)
+					if err != nil {
+						return errors.New("failed to fallback request payload").Base(err).AtInfo()
+					}
+					if !ok {
+						errors.LogInfo(ctx, "headers not forwarded to ", fb.Dest, " as the first request isn't complete HTTP/1.x")
+					}
+				}
 				if err := buf.Copy(reader, serverWriter, buf.UpdateActivity(timer)); err != nil {
 					return errors.New("failed to fallback request payload").Base(err).AtInfo()
 				}
@@ -430,12 +495,18 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 		}
 
 		if errors.Cause(err) != io.EOF {
-			log.Record(&log.AccessMessage{
+			log.RecordAccess(ctx, &log.AccessMessage{
 				From:   connection.RemoteAddr(),
 				To:     "",
 				Status: log.AccessRejected,
 				Reason: err,
 			})
+			// Replays are counted by the replay guard.
+			if errors.Cause(err) == encoding.ErrInvalidUser {
+				h.handshakes.Report(ctx, proxy.HandshakeAuth)
+			} else if !replayed {
+				h.handshakes.Report(ctx, proxy.HandshakeProtocol)
+			}
 			err = errors.New("invalid request from ", connection.RemoteAddr()).Base(err).AtInfo()
 		}
 		return err
@@ -475,6 +546,7 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 				var p uintptr
 				if tlsConn, ok := iConn.(*tls.Conn); ok {
 					if tlsConn.ConnectionState().Version != gotls.VersionTLS13 {
+						h.downgrade.CheckTLS(ctx, iConn)
 						return errors.New(`failed to use `+requestAddons.Flow+`, found outer tls version `, tlsConn.ConnectionState().Version).AtWarning()
 					}
 					t = reflect.TypeOf(tlsConn.Conn).Elem()
@@ -496,11 +568,15 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 	case "":
 		inbound.CanSpliceCopy = 3
 		if account.Flow == vless.XRV && (request.Command == protocol.RequestCommandTCP || isMuxAndNotXUDP(request, first)) {
+			h.downgrade.Report(ctx, proxy.DowngradeFlow, vless.XRV, "none")
 			return errors.New("account " + account.ID.String() + " is rejected since the client flow is empty. Note that the pure TLS proxy has certain TLS in TLS characters.").AtWarning()
 		}
 	default:
 		return errors.New("unknown request flow " + requestAddons.Flow).AtWarning()
 	}
+	if requestAddons.Flow == "" {
+		h.downgrade.CheckTLS(ctx, iConn)
+	}
 
 	if request.Command != protocol.RequestCommandMux {
 		ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
@@ -21,6 +21,7 @@ import (
 	"github.com/xtls/xray-core/common/xudp"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/proxy/vless"
 	"github.com/xtls/xray-core/proxy/vless/encoding"
@@ -67,6 +68,16 @@ func New(ctx context.Context, config *Config) (*Handler, error) {
 	return handler, nil
 }
 
+// Init implements proxy.Initializer.
+func (h *Handler) Init(ctx context.Context, dialer internet.Dialer) error {
+	return proxy.PrepareServers(ctx, dialer, h.serverList)
+}
+
+// ConnectsFirst implements proxy.Connector.
+func (h *Handler) ConnectsFirst() bool {
+	return true
+}
+
 // Process implements proxy.Outbound.Process().
 func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	outbounds := session.OutboundsFromContext(ctx)
@@ -90,6 +101,8 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 		return errors.New("failed to find an available destination").Base(err).AtWarning()
 	}
 	defer conn.Close()
+	// The response header comes after the server connected to the target.
+	start := time.Now()
 
 	iConn := conn
 	if statConn, ok := iConn.(*stat.CounterConnection); ok {
@@ -257,6 +270,7 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 		if err != nil {
 			return errors.New("failed to decode response header").Base(err).AtInfo()
 		}
+		stats.RecordStage(ctx, stats.StageProtocol, start)
 
 		// default: serverReader := buf.NewReader(conn)
 		serverReader := encoding.DecodeBodyAddons(conn, request, responseAddons)
@@ -13,6 +13,8 @@ import (
 type MemoryAccount struct {
 	// ID is the main ID of the account.
 	ID *protocol.ID
+	// AlterIDs are the alternative IDs legacy clients authenticate with.
+	AlterIDs []*protocol.ID
 	// Security type of the account. Used for client connections.
 	Security protocol.SecurityType
 
@@ -41,6 +43,7 @@ func (a *MemoryAccount) ToProto() proto.Message {
 		Id:               a.ID.String(),
 		TestsEnabled:     test,
 		SecuritySettings: &protocol.SecurityConfig{Type: a.Security},
+		AlterId:          uint32(len(a.AlterIDs)),
 	}
 }
 
@@ -51,6 +54,9 @@ func (a *Account) AsAccount() (protocol.Account, error) {
 		return nil, errors.New("failed to parse ID").Base(err).AtError()
 	}
 	protoID := protocol.NewID(id)
+	if a.AlterId > 0xFFFF {
+		return nil, errors.New("too many alter IDs: ", a.AlterId)
+	}
 	var AuthenticatedLength, NoTerminationSignal bool
 	if strings.Contains(a.TestsEnabled, "AuthenticatedLength") {
 		AuthenticatedLength = true
@@ -60,6 +66,7 @@ func (a *Account) AsAccount() (protocol.Account, error) {
 	}
 	return &MemoryAccount{
 		ID:                            protoID,
+		AlterIDs:                      protocol.NewAlterIDs(protoID, uint16(a.AlterId)),
 		Security:                      a.SecuritySettings.GetSecurityType(),
 		AuthenticatedLengthExperiment: AuthenticatedLength,
 		NoTerminationSignal:           NoTerminationSignal,
@@ -13,7 +13,13 @@ import (
 )
 
 func SealVMessAEADHeader(key [16]byte, data []byte) []byte {
-	generatedAuthID := CreateAuthID(key[:], time.Now().Unix())
+	return SealVMessAEADHeaderAt(key, data, time.Now())
+}
+
+// SealVMessAEADHeaderAt seals the header with the auth ID of the time now, for clients whose
+// clock is corrected by NTP.
+func SealVMessAEADHeaderAt(key [16]byte, data []byte, now time.Time) []byte {
+	generatedAuthID := CreateAuthID(key[:], now.Unix())
 
 	connectionNonce := make([]byte, 8)
 	if _, err := io.ReadFull(rand.Reader, connectionNonce); err != nil {
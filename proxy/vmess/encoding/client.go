@@ -18,6 +18,7 @@ import (
 	"github.com/xtls/xray-core/common/dice"
 	"github.com/xtls/xray-core/common/drain"
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/ntp"
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/proxy/vmess"
 	vmessaead "github.com/xtls/xray-core/proxy/vmess/aead"
@@ -34,11 +35,13 @@ type ClientSession struct {
 	responseHeader  byte
 
 	readDrainer drain.Drainer
+	clock       *ntp.Clock
 }
 
 // NewClientSession creates a new ClientSession.
+// The auth ID of the header is of the time of the NTP clock of ctx, if any.
 func NewClientSession(ctx context.Context, behaviorSeed int64) *ClientSession {
-	session := &ClientSession{}
+	session := &ClientSession{clock: ntp.ClockFromContext(ctx)}
 
 	randomBytes := make([]byte, 33) // 16 + 16 + 1
 	common.Must2(rand.Read(randomBytes))
@@ -97,7 +100,7 @@ func (c *ClientSession) EncodeRequestHeader(header *protocol.RequestHeader, writ
 
 	var fixedLengthCmdKey [16]byte
 	copy(fixedLengthCmdKey[:], account.ID.CmdKey())
-	vmessout := vmessaead.SealVMessAEADHeader(fixedLengthCmdKey, buffer.Bytes())
+	vmessout := vmessaead.SealVMessAEADHeaderAt(fixedLengthCmdKey, buffer.Bytes(), c.clock.Now())
 	common.Must2(io.Copy(writer, bytes.NewReader(vmessout)))
 
 	return nil
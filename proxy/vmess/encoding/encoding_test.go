@@ -1,12 +1,19 @@
 package encoding_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"hash/fnv"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/crypto"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/uuid"
@@ -151,3 +158,87 @@ func TestMuxRequest(t *testing.T) {
 		t.Error(r)
 	}
 }
+
+func TestLegacyRequest(t *testing.T) {
+	user := &protocol.MemoryUser{
+		Level: 0,
+		Email: "test@example.com",
+	}
+	id := uuid.New()
+	account := &vmess.Account{
+		Id:      id.String(),
+		AlterId: 4,
+	}
+	user.Account = toAccount(account)
+	memoryAccount := user.Account.(*vmess.MemoryAccount)
+
+	var requestKey, requestIV [16]byte
+	common.Must2(rand.Read(requestKey[:]))
+	common.Must2(rand.Read(requestIV[:]))
+	header := buf.New()
+	defer header.Release()
+	common.Must(header.WriteByte(1))
+	common.Must2(header.Write(requestIV[:]))
+	common.Must2(header.Write(requestKey[:]))
+	common.Must2(header.Write([]byte{0x42, byte(protocol.RequestOptionChunkStream), byte(protocol.SecurityType_AES128_GCM), 0, byte(protocol.RequestCommandTCP)}))
+	common.Must2(header.Write([]byte{0x01, 0xBB, 0x02, 15}))
+	common.Must2(header.WriteString("www.example.com"))
+	fnv1a := fnv.New32a()
+	common.Must2(fnv1a.Write(header.Bytes()))
+	common.Must2(header.Write(fnv1a.Sum(nil)))
+
+	timestamp := time.Now().Unix() - 10
+	var timestampBytes [8]byte
+	binary.BigEndian.PutUint64(timestampBytes[:], uint64(timestamp))
+	headerIV := md5.Sum(bytes.Repeat(timestampBytes[:], 4))
+	auth := vmess.LegacyAuth(memoryAccount.AlterIDs[2], timestamp)
+	request := buf.New()
+	common.Must2(request.Write(auth[:]))
+	crypto.NewAesEncryptionStream(memoryAccount.ID.CmdKey(), headerIV[:]).XORKeyStream(request.Extend(header.Len()), header.Bytes())
+	request2 := buf.New()
+	common.Must2(request2.Write(request.Bytes()))
+
+	sessionHistory := NewSessionHistory()
+	defer common.Close(sessionHistory)
+
+	userValidator := vmess.NewTimedUserValidator()
+	common.Must(userValidator.EnableLegacy())
+	common.Must(userValidator.Add(user))
+	defer common.Close(userValidator)
+
+	server := NewServerSession(userValidator, sessionHistory)
+	actualRequest, err := server.DecodeRequestHeader(request, false)
+	common.Must(err)
+
+	expectedRequest := &protocol.RequestHeader{
+		Version:  1,
+		User:     user,
+		Command:  protocol.RequestCommandTCP,
+		Option:   protocol.RequestOptionChunkStream,
+		Address:  net.DomainAddress("www.example.com"),
+		Port:     net.Port(443),
+		Security: protocol.SecurityType_AES128_GCM,
+	}
+	if r := cmp.Diff(actualRequest, expectedRequest, cmp.AllowUnexported(protocol.ID{})); r != "" {
+		t.Error(r)
+	}
+	if !server.IsLegacy() {
+		t.Error("expected a legacy request")
+	}
+
+	response := buf.New()
+	defer response.Release()
+	server.EncodeResponseHeader(&protocol.ResponseHeader{}, response)
+	responseKey := md5.Sum(requestKey[:])
+	responseIV := md5.Sum(requestIV[:])
+	crypto.NewAesDecryptionStream(responseKey[:], responseIV[:]).XORKeyStream(response.Bytes(), response.Bytes())
+	if r := cmp.Diff(response.Bytes(), []byte{0x42, 0, 0, 0}); r != "" {
+		t.Error(r)
+	}
+
+	_, err = NewServerSession(userValidator, sessionHistory).DecodeRequestHeader(request2, false)
+	// anti replay attack
+	if err == nil {
+		t.Error("nil error")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/md5"
 	"crypto/sha256"
 	"encoding/binary"
 	"hash/fnv"
@@ -102,6 +103,8 @@ type ServerSession struct {
 	responseBodyIV  [16]byte
 	responseWriter  io.Writer
 	responseHeader  byte
+	skipReplayDrain bool
+	isLegacy        bool
 }
 
 // NewServerSession creates a new ServerSession, using the given UserValidator.
@@ -113,6 +116,28 @@ func NewServerSession(validator *vmess.TimedUserValidator, sessionHistory *Sessi
 	}
 }
 
+// DisableReplayDrain makes DecodeRequestHeader return replayed requests at once, instead of
+// draining them like other invalid requests.
+func (s *ServerSession) DisableReplayDrain() {
+	s.skipReplayDrain = true
+}
+
+// IsLegacy returns whether the request is a legacy one, authenticated by the MD5 of its timestamp.
+func (s *ServerSession) IsLegacy() bool {
+	return s.isLegacy
+}
+
+// legacyHeaderIV returns the IV of the header of a legacy request sent at timestamp.
+func legacyHeaderIV(timestamp int64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(timestamp))
+	md5hash := md5.New()
+	for i := 0; i < 4; i++ {
+		common.Must2(md5hash.Write(b[:]))
+	}
+	return md5hash.Sum(nil)
+}
+
 func parseSecurityType(b byte) protocol.SecurityType {
 	if _, f := protocol.SecurityType_name[int32(b)]; f {
 		st := protocol.SecurityType(b)
@@ -137,7 +162,7 @@ func (s *ServerSession) DecodeRequestHeader(reader io.Reader, isDrain bool) (*pr
 	drainConnection := func(e error) error {
 		// We read a deterministic generated length of data before closing the connection to offset padding read pattern
 		drainer.AcknowledgeReceive(int(buffer.Len()))
-		if isDrain {
+		if isDrain && !(s.skipReplayDrain && errors.Cause(e) == vmessaead.ErrReplay) {
 			return drain.WithError(drainer, reader, e)
 		}
 		return e
@@ -175,7 +200,13 @@ func (s *ServerSession) DecodeRequestHeader(reader io.Reader, isDrain bool) (*pr
 		}
 		decryptor = bytes.NewReader(aeadData)
 	default:
-		return nil, drainConnection(errors.New("invalid user").Base(errorAEAD))
+		var timestamp int64
+		if user, timestamp, s.isLegacy = s.userValidator.GetLegacy(buffer.Bytes()); !s.isLegacy {
+			return nil, drainConnection(errors.New("invalid user").Base(errorAEAD))
+		}
+		vmessAccount = user.Account.(*vmess.MemoryAccount)
+		aesStream := crypto.NewAesDecryptionStream(vmessAccount.ID.CmdKey(), legacyHeaderIV(timestamp))
+		decryptor = crypto.NewCryptionReader(aesStream, reader)
 	}
 
 	drainer.AcknowledgeReceive(int(buffer.Len()))
@@ -196,7 +227,10 @@ func (s *ServerSession) DecodeRequestHeader(reader io.Reader, isDrain bool) (*pr
 	sid.key = s.requestBodyKey
 	sid.nonce = s.requestBodyIV
 	if !s.sessionHistory.addIfNotExits(sid) {
-		return nil, errors.New("duplicated session id, possibly under replay attack, but this is a AEAD request")
+		if s.isLegacy {
+			return nil, drainConnection(errors.New("duplicated session id, possibly under replay attack").Base(vmessaead.ErrReplay))
+		}
+		return nil, errors.New("duplicated session id, possibly under replay attack, but this is a AEAD request").Base(vmessaead.ErrReplay)
 	}
 
 	s.responseHeader = buffer.Byte(33)             // 1 byte
@@ -234,6 +268,9 @@ func (s *ServerSession) DecodeRequestHeader(reader io.Reader, isDrain bool) (*pr
 	expectedHash := binary.BigEndian.Uint32(buffer.BytesFrom(-4))
 
 	if actualHash != expectedHash {
+		if s.isLegacy {
+			return nil, drainConnection(errors.New("invalid auth"))
+		}
 		return nil, errors.New("invalid auth, but this is a AEAD request")
 	}
 
@@ -328,6 +365,11 @@ func (s *ServerSession) DecodeRequestBody(request *protocol.RequestHeader, reade
 
 // EncodeResponseHeader writes encoded response header into the given writer.
 func (s *ServerSession) EncodeResponseHeader(header *protocol.ResponseHeader, writer io.Writer) {
+	if s.isLegacy {
+		s.encodeLegacyResponseHeader(header, writer)
+		return
+	}
+
 	var encryptionWriter io.Writer
 	BodyKey := sha256.Sum256(s.requestBodyKey[:])
 	copy(s.responseBodyKey[:], BodyKey[:16])
@@ -372,6 +414,22 @@ func (s *ServerSession) EncodeResponseHeader(header *protocol.ResponseHeader, wr
 	common.Must2(io.Copy(writer, bytes.NewReader(aeadEncryptedHeaderPayload)))
 }
 
+// encodeLegacyResponseHeader writes the response header of a legacy request, which is
+// encrypted like the body but without authentication.
+func (s *ServerSession) encodeLegacyResponseHeader(header *protocol.ResponseHeader, writer io.Writer) {
+	s.responseBodyKey = md5.Sum(s.requestBodyKey[:])
+	s.responseBodyIV = md5.Sum(s.requestBodyIV[:])
+
+	aesStream := crypto.NewAesEncryptionStream(s.responseBodyKey[:], s.responseBodyIV[:])
+	encryptionWriter := crypto.NewCryptionWriter(aesStream, writer)
+	s.responseWriter = encryptionWriter
+
+	common.Must2(encryptionWriter.Write([]byte{s.responseHeader, byte(header.Option)}))
+	if err := MarshalCommand(header.Command, encryptionWriter); err != nil {
+		common.Must2(encryptionWriter.Write([]byte{0x00, 0x00}))
+	}
+}
+
 // EncodeResponseBody returns a Writer that auto-encrypt content written by caller.
 func (s *ServerSession) EncodeResponseBody(request *protocol.RequestHeader, writer io.Writer) (buf.Writer, error) {
 	var sizeParser crypto.ChunkSizeEncoder = crypto.PlainChunkSizeParser{}
@@ -21,7 +21,10 @@ import (
 	feature_inbound "github.com/xtls/xray-core/features/inbound"
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/proxy/vmess"
+	"github.com/xtls/xray-core/proxy/vmess/aead"
 	"github.com/xtls/xray-core/proxy/vmess/encoding"
 	"github.com/xtls/xray-core/transport/internet/stat"
 )
@@ -100,6 +103,29 @@ func (v *userByEmail) Remove(email string) bool {
 	return true
 }
 
+// legacyWarnInterval is how often a warning is logged about each legacy client.
+const legacyWarnInterval = time.Hour
+
+// legacyClients keeps when the legacy clients were warned about, so that operators find them
+// in the logs without being flooded.
+type legacyClients struct {
+	sync.Mutex
+	warned map[string]time.Time
+}
+
+// shouldWarn returns whether client wasn't warned about within legacyWarnInterval.
+func (c *legacyClients) shouldWarn(client string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+	if last, found := c.warned[client]; found && now.Sub(last) < legacyWarnInterval {
+		return false
+	}
+	c.warned[client] = now
+	return true
+}
+
 // Handler is an inbound connection handler that handles messages in VMess protocol.
 type Handler struct {
 	policyManager         policy.Manager
@@ -108,11 +134,15 @@ type Handler struct {
 	usersByEmail          *userByEmail
 	detours               *DetourConfig
 	sessionHistory        *encoding.SessionHistory
+	replayGuard           *proxy.ReplayGuard
+	handshakes            *proxy.HandshakeStats
+	legacyClients         *legacyClients
 }
 
 // New creates a new VMess inbound handler.
 func New(ctx context.Context, config *Config) (*Handler, error) {
 	v := core.MustFromContext(ctx)
+	statsManager, _ := v.GetFeature(stats.ManagerType()).(stats.Manager)
 	handler := &Handler{
 		policyManager:         v.GetFeature(policy.ManagerType()).(policy.Manager),
 		inboundHandlerManager: v.GetFeature(feature_inbound.ManagerType()).(feature_inbound.Manager),
@@ -120,6 +150,16 @@ func New(ctx context.Context, config *Config) (*Handler, error) {
 		detours:               config.Detour,
 		usersByEmail:          newUserByEmail(config.GetDefaultValue()),
 		sessionHistory:        encoding.NewSessionHistory(),
+		replayGuard:           proxy.NewReplayGuard(config.Replay, statsManager),
+		handshakes:            proxy.NewHandshakeStats(statsManager),
+	}
+
+	if config.LegacyCompat {
+		if err := handler.clients.EnableLegacy(); err != nil {
+			return nil, errors.New("failed to enable legacy clients").Base(err)
+		}
+		handler.legacyClients = &legacyClients{warned: make(map[string]time.Time)}
+		errors.LogWarning(ctx, "VMess legacy compatibility is enabled. Legacy clients authenticated by MD5 are deprecated and insecure, migrate them to AEAD and turn it off")
 	}
 
 	for _, user := range config.User {
@@ -140,6 +180,7 @@ func New(ctx context.Context, config *Config) (*Handler, error) {
 func (h *Handler) Close() error {
 	return errors.Combine(
 		h.sessionHistory.Close(),
+		h.clients.Close(),
 		common.Close(h.usersByEmail))
 }
 
@@ -186,6 +227,18 @@ func (h *Handler) RemoveUser(ctx context.Context, email string) error {
 	return nil
 }
 
+// warnLegacy logs a deprecation warning about a legacy client, identified by its email or
+// the beginning of its ID.
+func (h *Handler) warnLegacy(ctx context.Context, user *protocol.MemoryUser, from net.Addr) {
+	client := user.Email
+	if client == "" {
+		client = "ID " + user.Account.(*vmess.MemoryAccount).ID.String()[:8] + "..."
+	}
+	if h.legacyClients.shouldWarn(client) {
+		errors.LogWarning(ctx, "deprecated legacy VMess client ", client, " connected from ", from, ", migrate it to AEAD with alterId 0")
+	}
+}
+
 func transferResponse(timer signal.ActivityUpdater, session *encoding.ServerSession, request *protocol.RequestHeader, response *protocol.ResponseHeader, input buf.Reader, output *buf.BufferedWriter) error {
 	session.EncodeResponseHeader(response, output)
 
@@ -226,6 +279,10 @@ func transferResponse(timer signal.ActivityUpdater, session *encoding.ServerSess
 
 // Process implements proxy.Inbound.Process().
 func (h *Handler) Process(ctx context.Context, network net.Network, connection stat.Connection, dispatcher routing.Dispatcher) error {
+	if h.replayGuard.Banned(ctx) {
+		return errors.New("rejected banned source ", connection.RemoteAddr()).AtInfo()
+	}
+
 	sessionPolicy := h.policyManager.ForLevel(0)
 	if err := connection.SetReadDeadline(time.Now().Add(sessionPolicy.Timeouts.Handshake)); err != nil {
 		return errors.New("unable to set read deadline").Base(err).AtWarning()
@@ -242,10 +299,22 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 
 	reader := &buf.BufferedReader{Reader: buf.NewReader(connection)}
 	svrSession := encoding.NewServerSession(h.clients, h.sessionHistory)
+	if h.replayGuard.Action() != protocol.ReplayPolicy_Fallback {
+		svrSession.DisableReplayDrain()
+	}
 	request, err := svrSession.DecodeRequestHeader(reader, isDrain)
 	if err != nil {
+		switch errors.Cause(err) {
+		case aead.ErrReplay:
+			h.replayGuard.Report(ctx, err)
+		case aead.ErrNotFound:
+			h.handshakes.Report(ctx, proxy.HandshakeAuth)
+		case io.EOF:
+		default:
+			h.handshakes.Report(ctx, proxy.HandshakeProtocol)
+		}
 		if errors.Cause(err) != io.EOF {
-			log.Record(&log.AccessMessage{
+			log.RecordAccess(ctx, &log.AccessMessage{
 				From:   connection.RemoteAddr(),
 				To:     "",
 				Status: log.AccessRejected,
@@ -268,6 +337,10 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 
 	errors.LogInfo(ctx, "received request for ", request.Destination())
 
+	if svrSession.IsLegacy() {
+		h.warnLegacy(ctx, request.User, connection.RemoteAddr())
+	}
+
 	if err := connection.SetReadDeadline(time.Time{}); err != nil {
 		errors.LogInfoInner(ctx, err, "unable to set back read deadline")
 	}
@@ -281,6 +354,7 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 
 	ctx, cancel := context.WithCancel(ctx)
 	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
+	inbound.Timer = timer
 
 	ctx = policy.ContextWithBufferPolicy(ctx, sessionPolicy.Buffer)
 	link, err := dispatcher.Dispatch(ctx, request.Destination())
@@ -0,0 +1,134 @@
+package vmess
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/task"
+)
+
+const (
+	// legacyWindow is how far, in seconds, the timestamps of legacy requests may be from now.
+	legacyWindow = 120
+	// legacyUpdate is how often the auths of new timestamps are computed.
+	legacyUpdate = 10 * time.Second
+)
+
+// LegacyAuth returns the auth of a legacy request sent at timestamp, which is the HMAC-MD5 of
+// the timestamp keyed by an ID of the user.
+func LegacyAuth(id *protocol.ID, timestamp int64) [16]byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(timestamp))
+	h := hmac.New(md5.New, id.Bytes())
+	h.Write(b[:])
+	var auth [16]byte
+	h.Sum(auth[:0])
+	return auth
+}
+
+type legacyEntry struct {
+	user      *protocol.MemoryUser
+	timestamp int64
+}
+
+// legacyIndex finds the users of legacy requests by their auths. As the auths depend on the
+// timestamps, those of all the timestamps within legacyWindow from now are kept, and updated
+// as time passes.
+type legacyIndex struct {
+	sync.RWMutex
+	users []*protocol.MemoryUser
+	auths map[[16]byte]legacyEntry
+	end   int64 // the auths are kept up to this timestamp
+	task  *task.Periodic
+}
+
+func newLegacyIndex() *legacyIndex {
+	idx := &legacyIndex{
+		auths: make(map[[16]byte]legacyEntry),
+		end:   time.Now().Unix() + legacyWindow,
+	}
+	idx.task = &task.Periodic{
+		Interval: legacyUpdate,
+		Execute:  idx.update,
+	}
+	return idx
+}
+
+// addAuths adds the auths of u for the timestamps from start to end.
+func (idx *legacyIndex) addAuths(u *protocol.MemoryUser, start, end int64) {
+	account := u.Account.(*MemoryAccount)
+	for _, id := range append([]*protocol.ID{account.ID}, account.AlterIDs...) {
+		for timestamp := start; timestamp <= end; timestamp++ {
+			idx.auths[LegacyAuth(id, timestamp)] = legacyEntry{
+				user:      u,
+				timestamp: timestamp,
+			}
+		}
+	}
+}
+
+func (idx *legacyIndex) add(u *protocol.MemoryUser) {
+	idx.Lock()
+	defer idx.Unlock()
+
+	idx.users = append(idx.users, u)
+	idx.addAuths(u, time.Now().Unix()-legacyWindow, idx.end)
+}
+
+func (idx *legacyIndex) remove(u *protocol.MemoryUser) {
+	idx.Lock()
+	defer idx.Unlock()
+
+	for i, user := range idx.users {
+		if user == u {
+			idx.users = append(idx.users[:i], idx.users[i+1:]...)
+			break
+		}
+	}
+	for auth, entry := range idx.auths {
+		if entry.user == u {
+			delete(idx.auths, auth)
+		}
+	}
+}
+
+func (idx *legacyIndex) update() error {
+	now := time.Now().Unix()
+
+	idx.Lock()
+	defer idx.Unlock()
+
+	for auth, entry := range idx.auths {
+		if entry.timestamp < now-legacyWindow {
+			delete(idx.auths, auth)
+		}
+	}
+	start := max(idx.end+1, now-legacyWindow)
+	for _, u := range idx.users {
+		idx.addAuths(u, start, now+legacyWindow)
+	}
+	idx.end = now + legacyWindow
+	return nil
+}
+
+// get returns the user and the timestamp of a legacy request by its auth.
+func (idx *legacyIndex) get(auth []byte) (*protocol.MemoryUser, int64, bool) {
+	var key [16]byte
+	copy(key[:], auth)
+
+	idx.RLock()
+	defer idx.RUnlock()
+
+	entry, found := idx.auths[key]
+	if !found {
+		return nil, 0, false
+	}
+	if delta := entry.timestamp - time.Now().Unix(); delta < -legacyWindow || delta > legacyWindow {
+		return nil, 0, false
+	}
+	return entry.user, entry.timestamp, true
+}
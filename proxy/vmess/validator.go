@@ -22,6 +22,7 @@ type TimedUserValidator struct {
 	behaviorFused bool
 
 	aeadDecoderHolder *aead.AuthIDDecoderHolder
+	legacy            *legacyIndex
 }
 
 // NewTimedUserValidator creates a new TimedUserValidator.
@@ -52,7 +53,45 @@ func (v *TimedUserValidator) Add(u *protocol.MemoryUser) error {
 	var cmdkeyfl [16]byte
 	copy(cmdkeyfl[:], account.ID.CmdKey())
 	v.aeadDecoderHolder.AddUser(cmdkeyfl, u)
+	if v.legacy != nil {
+		v.legacy.add(u)
+	}
+
+	return nil
+}
+
+// EnableLegacy makes the validator find the users of legacy requests, authenticated by the
+// MD5 of timestamps, as well. Call it before adding users.
+func (v *TimedUserValidator) EnableLegacy() error {
+	v.Lock()
+	defer v.Unlock()
+
+	if v.legacy == nil {
+		v.legacy = newLegacyIndex()
+	}
+	return v.legacy.task.Start()
+}
 
+// GetLegacy returns the user and the timestamp of a legacy request by its auth.
+func (v *TimedUserValidator) GetLegacy(auth []byte) (*protocol.MemoryUser, int64, bool) {
+	v.RLock()
+	legacy := v.legacy
+	v.RUnlock()
+
+	if legacy == nil {
+		return nil, 0, false
+	}
+	return legacy.get(auth)
+}
+
+// Close implements common.Closable.
+func (v *TimedUserValidator) Close() error {
+	v.Lock()
+	defer v.Unlock()
+
+	if v.legacy != nil {
+		return v.legacy.task.Close()
+	}
 	return nil
 }
 
@@ -96,6 +135,9 @@ func (v *TimedUserValidator) Remove(email string) bool {
 			var cmdkeyfl [16]byte
 			copy(cmdkeyfl[:], u.Account.(*MemoryAccount).ID.CmdKey())
 			v.aeadDecoderHolder.RemoveUser(cmdkeyfl)
+			if v.legacy != nil {
+				v.legacy.remove(u)
+			}
 			break
 		}
 	}
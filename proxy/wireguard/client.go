@@ -126,6 +126,11 @@ func (h *Handler) processWireGuard(ctx context.Context, dialer internet.Dialer)
 	return nil
 }
 
+// Init implements proxy.Initializer.
+func (h *Handler) Init(ctx context.Context, dialer internet.Dialer) error {
+	return h.processWireGuard(ctx, dialer)
+}
+
 // Process implements OutboundHandler.Dispatch().
 func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	outbounds := session.OutboundsFromContext(ctx)
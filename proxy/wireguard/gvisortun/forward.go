@@ -0,0 +1,71 @@
+package gvisortun
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	xnet "github.com/xtls/xray-core/common/net"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/adapters/gonet"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/tcp"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/udp"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// ForwardConnections captures the TCP and UDP connections arriving at s, which is in promiscuous
+// mode, and passes each of them to handler with its destination.
+func ForwardConnections(s *stack.Stack, handler func(dest xnet.Destination, conn net.Conn)) {
+	tcpForwarder := tcp.NewForwarder(s, 0, 65535, func(r *tcp.ForwarderRequest) {
+		go func(r *tcp.ForwarderRequest) {
+			var (
+				wq waiter.Queue
+				id = r.ID()
+			)
+
+			// Perform a TCP three-way handshake.
+			ep, err := r.CreateEndpoint(&wq)
+			if err != nil {
+				errors.LogError(context.Background(), err.String())
+				r.Complete(true)
+				return
+			}
+			r.Complete(false)
+			defer ep.Close()
+
+			// enable tcp keep-alive to prevent hanging connections
+			ep.SocketOptions().SetKeepAlive(true)
+
+			// local address is actually destination
+			handler(xnet.TCPDestination(xnet.IPAddress(id.LocalAddress.AsSlice()), xnet.Port(id.LocalPort)), gonet.NewTCPConn(&wq, ep))
+		}(r)
+	})
+	s.SetTransportProtocolHandler(tcp.ProtocolNumber, tcpForwarder.HandlePacket)
+
+	udpForwarder := udp.NewForwarder(s, func(r *udp.ForwarderRequest) {
+		go func(r *udp.ForwarderRequest) {
+			var (
+				wq waiter.Queue
+				id = r.ID()
+			)
+
+			ep, err := r.CreateEndpoint(&wq)
+			if err != nil {
+				errors.LogError(context.Background(), err.String())
+				return
+			}
+			defer ep.Close()
+
+			// prevents hanging connections and ensure timely release
+			ep.SocketOptions().SetLinger(tcpip.LingerOption{
+				Enabled: true,
+				Timeout: 15 * time.Second,
+			})
+
+			handler(xnet.UDPDestination(xnet.IPAddress(id.LocalAddress.AsSlice()), xnet.Port(id.LocalPort)), gonet.NewUDPConn(&wq, ep))
+		}(r)
+	})
+	s.SetTransportProtocolHandler(udp.ProtocolNumber, udpForwarder.HandlePacket)
+}
@@ -0,0 +1,212 @@
+// Package conformance sends malformed and edge case handshakes of VMess, VLESS, Trojan and
+// Shadowsocks to a running inbound, and checks that it rejects them, or relays them to its
+// fallback, without giving itself away.
+//
+// A Target describes the inbound. Inbounds with fallbacks are expected to relay to a server
+// started by StartFallbackServer, whose greeting tells the harness the fallback was reached.
+// If an echo server the inbound may reach is given, valid handshakes are checked to be accepted
+// too, so that a misconfigured target doesn't pass by rejecting everything.
+package conformance
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/testing/servers/tcp"
+)
+
+// FallbackMarker is the greeting of the fallback server.
+const FallbackMarker = "XRAY-CONFORMANCE-FALLBACK"
+
+// echoPayload follows valid handshakes, to be echoed back.
+const echoPayload = "xray-conformance-echo"
+
+// Target is an inbound under test.
+type Target struct {
+	// Protocol is one of "vless", "vmess", "trojan" and "shadowsocks".
+	Protocol string
+	Address  net.Destination
+	// ID is the UUID of a user of VLESS or VMess.
+	ID string
+	// Password is the password of a user of Trojan.
+	Password string
+	// Fallback is whether the inbound falls back to the fallback server.
+	Fallback bool
+	// Echo is an echo server the inbound may reach, for the probes of valid handshakes, which are
+	// skipped if it's not valid.
+	Echo net.Destination
+}
+
+// Expect is the behavior a probe expects of an inbound.
+type Expect int
+
+const (
+	// ExpectRejected expects the inbound to close the connection or keep silent, sending nothing.
+	ExpectRejected Expect = iota
+	// ExpectFallback expects the inbound to relay to its fallback if it has one, or to reject
+	// the connection otherwise.
+	ExpectFallback
+	// ExpectAccepted expects the inbound to proxy the connection to the echo server.
+	ExpectAccepted
+)
+
+func (e Expect) String() string {
+	switch e {
+	case ExpectRejected:
+		return "rejected"
+	case ExpectFallback:
+		return "fallback"
+	default:
+		return "accepted"
+	}
+}
+
+// Outcome is what an inbound did with a probe.
+type Outcome int
+
+const (
+	// OutcomeClosed is the connection closed or reset without any response.
+	OutcomeClosed Outcome = iota
+	// OutcomeSilent is no response until the timeout.
+	OutcomeSilent
+	// OutcomeFallback is the greeting of the fallback server.
+	OutcomeFallback
+	// OutcomeAccepted is the payload of the probe echoed.
+	OutcomeAccepted
+	// OutcomeResponded is any other response, telling the inbound apart.
+	OutcomeResponded
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeClosed:
+		return "closed"
+	case OutcomeSilent:
+		return "silent"
+	case OutcomeFallback:
+		return "fallback"
+	case OutcomeAccepted:
+		return "accepted"
+	default:
+		return "responded"
+	}
+}
+
+// Result is the outcome of a probe.
+type Result struct {
+	Probe   *Probe
+	Outcome Outcome
+	// Response is what the inbound sent, if anything.
+	Response []byte
+	// Err is set if the probe could not be sent.
+	Err error
+}
+
+// OK returns whether the outcome is what the probe expects of target.
+func (r *Result) OK(target *Target) bool {
+	if r.Err != nil {
+		return false
+	}
+	switch r.Probe.Expect {
+	case ExpectAccepted:
+		return r.Outcome == OutcomeAccepted
+	case ExpectFallback:
+		if target.Fallback {
+			return r.Outcome == OutcomeFallback
+		}
+	}
+	return r.Outcome == OutcomeClosed || r.Outcome == OutcomeSilent
+}
+
+// Run sends each of probes to target in its own connection, all at once, and waits at most
+// timeout for each response.
+func Run(target *Target, probes []*Probe, timeout time.Duration) []*Result {
+	results := make([]*Result, len(probes))
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = p.run(target, timeout)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func (p *Probe) run(target *Target, timeout time.Duration) *Result {
+	result := &Result{Probe: p}
+	payload, err := p.Build(target)
+	if err != nil {
+		result.Err = errors.New("failed to build probe ", p.Name).Base(err)
+		return result
+	}
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.Dial("tcp", target.Address.NetAddr())
+	if err != nil {
+		result.Err = errors.New("failed to dial ", target.Address).Base(err)
+		return result
+	}
+	defer conn.Close()
+	if _, err := conn.Write(payload); err != nil {
+		result.Err = errors.New("failed to send probe ", p.Name).Base(err)
+		return result
+	}
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	b := make([]byte, 4096)
+	for {
+		n, err := conn.Read(b)
+		result.Response = append(result.Response, b[:n]...)
+		switch {
+		case bytes.Contains(result.Response, []byte(FallbackMarker)):
+			result.Outcome = OutcomeFallback
+			return result
+		case p.Expect == ExpectAccepted && bytes.Contains(result.Response, []byte(echoPayload)):
+			result.Outcome = OutcomeAccepted
+			return result
+		case err == nil:
+			continue
+		case len(result.Response) > 0:
+			result.Outcome = OutcomeResponded
+		case isTimeout(err):
+			result.Outcome = OutcomeSilent
+		default:
+			// EOF, or the connection reset.
+			result.Outcome = OutcomeClosed
+		}
+		return result
+	}
+}
+
+func isTimeout(err error) bool {
+	if err == io.EOF {
+		return false
+	}
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+// StartFallbackServer starts a server greeting each connection with FallbackMarker, for the
+// fallbacks of inbounds under test.
+func StartFallbackServer() (*tcp.Server, net.Destination, error) {
+	server := &tcp.Server{
+		SendFirst:    []byte(FallbackMarker),
+		MsgProcessor: func(b []byte) []byte { return b },
+	}
+	dest, err := server.Start()
+	return server, dest, err
+}
+
+// StartEchoServer starts a server echoing what it receives, for Target.Echo.
+func StartEchoServer() (*tcp.Server, net.Destination, error) {
+	server := &tcp.Server{
+		MsgProcessor: func(b []byte) []byte { return b },
+	}
+	dest, err := server.Start()
+	return server, dest, err
+}
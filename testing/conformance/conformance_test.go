@@ -0,0 +1,80 @@
+package conformance_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf/serial"
+	_ "github.com/xtls/xray-core/main/distro/all"
+	. "github.com/xtls/xray-core/testing/conformance"
+	"github.com/xtls/xray-core/testing/servers/tcp"
+)
+
+const id = "b831381d-6324-4d53-ad4f-8cda48b30811"
+
+func TestConformance(t *testing.T) {
+	fallbackServer, fallback, err := StartFallbackServer()
+	common.Must(err)
+	defer fallbackServer.Close()
+	echoServer, echo, err := StartEchoServer()
+	common.Must(err)
+	defer echoServer.Close()
+
+	targets := []struct {
+		target   *Target
+		settings string
+	}{
+		{
+			&Target{Protocol: "vless", ID: id, Fallback: true, Echo: echo},
+			fmt.Sprintf(`{"clients": [{"id": %q}], "decryption": "none", "fallbacks": [{"dest": %d}]}`, id, fallback.Port),
+		},
+		{
+			&Target{Protocol: "vless", ID: id, Echo: echo},
+			fmt.Sprintf(`{"clients": [{"id": %q}], "decryption": "none"}`, id),
+		},
+		{
+			&Target{Protocol: "trojan", Password: "password", Fallback: true, Echo: echo},
+			fmt.Sprintf(`{"clients": [{"password": "password"}], "fallbacks": [{"dest": %d}]}`, fallback.Port),
+		},
+		{
+			&Target{Protocol: "vmess", ID: id},
+			fmt.Sprintf(`{"clients": [{"id": %q}]}`, id),
+		},
+		{
+			&Target{Protocol: "shadowsocks"},
+			`{"method": "aes-256-gcm", "password": "password"}`,
+		},
+	}
+
+	var inbounds []string
+	for _, c := range targets {
+		port := tcp.PickPort()
+		c.target.Address = net.TCPDestination(net.LocalHostIP, port)
+		inbounds = append(inbounds, fmt.Sprintf(`{"listen": "127.0.0.1", "port": %d, "protocol": %q, "settings": %s}`,
+			port, c.target.Protocol, c.settings))
+	}
+	config, err := serial.LoadJSONConfig(strings.NewReader(fmt.Sprintf(`{
+		"policy": {"levels": {"0": {"handshake": 1}}},
+		"inbounds": [%s],
+		"outbounds": [{"protocol": "freedom"}]
+	}`, strings.Join(inbounds, ","))))
+	common.Must(err)
+	server, err := core.New(config)
+	common.Must(err)
+	common.Must(server.Start())
+	defer server.Close()
+
+	for _, c := range targets {
+		for _, r := range Run(c.target, Probes(c.target), 3*time.Second) {
+			if !r.OK(c.target) {
+				t.Errorf("%s (fallback %v): %s expects %v, got %v %q (%v)", c.target.Protocol, c.target.Fallback,
+					r.Probe.Name, r.Probe.Expect, r.Outcome, r.Response, r.Err)
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package conformance
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/uuid"
+)
+
+// Probe is a handshake sent to an inbound, and the behavior expected of it.
+type Probe struct {
+	Name   string
+	Expect Expect
+	// Build returns the bytes sent to target.
+	Build func(target *Target) ([]byte, error)
+}
+
+// Probes returns the probes of the protocol of target: those any inbound must not tell apart
+// from other servers, and those specific to the protocol.
+func Probes(target *Target) []*Probe {
+	probes := []*Probe{
+		{"random-1", ExpectFallback, constant(random(1))},
+		{"random-64", ExpectFallback, constant(random(64))},
+		{"random-1024", ExpectFallback, constant(random(1024))},
+		{"zeros-512", ExpectFallback, constant(make([]byte, 512))},
+		{"http-get", ExpectFallback, constant([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))},
+		{"tls-record", ExpectFallback, constant(append([]byte{0x16, 0x03, 0x01, 0x02, 0x00}, random(512)...))},
+	}
+	switch target.Protocol {
+	case "vless":
+		probes = append(probes,
+			&Probe{"vless-unknown-user", ExpectFallback, func(t *Target) ([]byte, error) {
+				return vlessRequest(0, random(16), 1, 0x01, t.Echo), nil
+			}},
+			&Probe{"vless-bad-version", ExpectFallback, vlessProbe(1, 1, 0x01)},
+			&Probe{"vless-bad-command", ExpectRejected, vlessProbe(0, 0x7f, 0x01)},
+			&Probe{"vless-bad-address-type", ExpectRejected, vlessProbe(0, 1, 0x7f)},
+			&Probe{"vless-truncated", ExpectRejected, func(t *Target) ([]byte, error) {
+				b, err := vlessProbe(0, 1, 0x01)(t)
+				if err != nil {
+					return nil, err
+				}
+				return b[:19], nil
+			}},
+		)
+		if target.Echo.IsValid() {
+			probes = append(probes, &Probe{"vless-valid", ExpectAccepted, vlessProbe(0, 1, 0x01)})
+		}
+	case "trojan":
+		probes = append(probes,
+			&Probe{"trojan-wrong-password", ExpectFallback, func(t *Target) ([]byte, error) {
+				return trojanRequest(hex.EncodeToString(random(28)), "\r\n", 1, 0x01, t.Echo), nil
+			}},
+			&Probe{"trojan-no-crlf", ExpectFallback, trojanProbe("\n\n", 1, 0x01)},
+			&Probe{"trojan-bad-command", ExpectRejected, trojanProbe("\r\n", 0x7f, 0x01)},
+			&Probe{"trojan-bad-address-type", ExpectRejected, trojanProbe("\r\n", 1, 0x7f)},
+		)
+		if target.Echo.IsValid() {
+			probes = append(probes, &Probe{"trojan-valid", ExpectAccepted, trojanProbe("\r\n", 1, 0x01)})
+		}
+	case "vmess":
+		probes = append(probes,
+			&Probe{"vmess-random-auth-id", ExpectRejected, constant(random(16 + 18 + 8 + 64))},
+			&Probe{"vmess-legacy-auth", ExpectRejected, func(t *Target) ([]byte, error) {
+				id, err := uuid.ParseString(t.ID)
+				if err != nil {
+					return nil, err
+				}
+				// The MD5 HMAC of the time by the ID, as legacy clients without AEAD send.
+				mac := hmac.New(md5.New, id.Bytes())
+				binary.Write(mac, binary.BigEndian, time.Now().Unix())
+				return append(mac.Sum(nil), random(64)...), nil
+			}},
+		)
+	case "shadowsocks":
+		probes = append(probes,
+			&Probe{"shadowsocks-short-salt", ExpectRejected, constant(random(8))},
+			&Probe{"shadowsocks-random-chunk", ExpectRejected, constant(random(32 + 2 + 16 + 64 + 16))},
+		)
+	}
+	return probes
+}
+
+func random(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
+
+func constant(b []byte) func(*Target) ([]byte, error) {
+	return func(*Target) ([]byte, error) {
+		return b, nil
+	}
+}
+
+// vlessProbe returns a probe of a request of the user of target.
+func vlessProbe(version, command, addressType byte) func(*Target) ([]byte, error) {
+	return func(t *Target) ([]byte, error) {
+		id, err := uuid.ParseString(t.ID)
+		if err != nil {
+			return nil, err
+		}
+		return vlessRequest(version, id.Bytes(), command, addressType, t.Echo), nil
+	}
+}
+
+// vlessRequest returns a VLESS request to echo, or a local port if it's not valid, followed by
+// echoPayload.
+func vlessRequest(version byte, id []byte, command, addressType byte, echo net.Destination) []byte {
+	b := append([]byte{version}, id...)
+	b = append(b, 0, command)
+	b = binary.BigEndian.AppendUint16(b, uint16(echoPort(echo)))
+	b = append(b, addressType)
+	b = append(b, echoIP(echo)...)
+	return append(b, echoPayload...)
+}
+
+// trojanProbe returns a probe of a request with the password of target.
+func trojanProbe(crlf string, command, addressType byte) func(*Target) ([]byte, error) {
+	return func(t *Target) ([]byte, error) {
+		if t.Password == "" {
+			return nil, errors.New("no password")
+		}
+		hash := sha256.Sum224([]byte(t.Password))
+		return trojanRequest(hex.EncodeToString(hash[:]), crlf, command, addressType, t.Echo), nil
+	}
+}
+
+// trojanRequest returns a Trojan request to echo, or a local port if it's not valid, followed by
+// echoPayload.
+func trojanRequest(hash, crlf string, command, addressType byte, echo net.Destination) []byte {
+	b := append([]byte(hash), crlf...)
+	b = append(b, command, addressType)
+	b = append(b, echoIP(echo)...)
+	b = binary.BigEndian.AppendUint16(b, uint16(echoPort(echo)))
+	b = append(b, "\r\n"...)
+	return append(b, echoPayload...)
+}
+
+func echoIP(echo net.Destination) []byte {
+	if echo.IsValid() && echo.Address.Family().IsIPv4() {
+		return echo.Address.IP().To4()
+	}
+	return []byte{127, 0, 0, 1}
+}
+
+func echoPort(echo net.Destination) net.Port {
+	if echo.IsValid() {
+		return echo.Port
+	}
+	return 80
+}
@@ -1,6 +1,8 @@
 package internet
 
 import (
+	"sort"
+
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/serial"
 )
@@ -36,6 +38,16 @@ func RegisterProtocolConfigCreator(name string, creator ConfigCreator) error {
 	return nil
 }
 
+// RegisteredProtocols returns the names of the transport protocols compiled in, sorted.
+func RegisteredProtocols() []string {
+	names := make([]string, 0, len(globalTransportConfigCreatorCache))
+	for name := range globalTransportConfigCreatorCache {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Note: Each new transport needs to add init() func in transport/internet/xxx/config.go
 // Otherwise, it will cause #3244
 func CreateTransportConfig(name string) (interface{}, error) {
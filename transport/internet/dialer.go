@@ -2,6 +2,7 @@ package internet
 
 import (
 	"context"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/dice"
@@ -11,6 +12,7 @@ import (
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/transport"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/pipe"
@@ -82,18 +84,27 @@ var (
 	obm       outbound.Manager
 )
 
-func lookupIP(domain string, strategy DomainStrategy, localAddr net.Address) ([]net.IP, error) {
+func lookupIP(ctx context.Context, domain string, strategy DomainStrategy, localAddr net.Address) ([]net.IP, error) {
 	if dnsClient == nil {
 		return nil, nil
 	}
+	return lookupIPWith(ctx, dnsClient, domain, strategy, localAddr)
+}
+
+// LookupIPWithStrategy resolves domain with client, for the IP versions strategy asks for and
+// then those it falls back to. AS_IS resolves both.
+func LookupIPWithStrategy(ctx context.Context, client dns.Client, domain string, strategy DomainStrategy) ([]net.IP, error) {
+	return lookupIPWith(ctx, client, domain, strategy, nil)
+}
 
-	ips, err := dnsClient.LookupIP(domain, dns.IPOption{
+func lookupIPWith(ctx context.Context, client dns.Client, domain string, strategy DomainStrategy, localAddr net.Address) ([]net.IP, error) {
+	ips, err := dns.LookupIPForSession(ctx, client, domain, dns.IPOption{
 		IPv4Enable: (localAddr == nil || localAddr.Family().IsIPv4()) && strategy.preferIP4(),
 		IPv6Enable: (localAddr == nil || localAddr.Family().IsIPv6()) && strategy.preferIP6(),
 	})
 	{ // Resolve fallback
 		if (len(ips) == 0 || err != nil) && strategy.hasFallback() && localAddr == nil {
-			ips, err = dnsClient.LookupIP(domain, dns.IPOption{
+			ips, err = dns.LookupIPForSession(ctx, client, domain, dns.IPOption{
 				IPv4Enable: strategy.fallbackIP4(),
 				IPv6Enable: strategy.fallbackIP6(),
 			})
@@ -110,6 +121,31 @@ func canLookupIP(ctx context.Context, dst net.Destination, sockopt *SocketConfig
 	return sockopt.DomainStrategy.hasStrategy()
 }
 
+// resolveInternal replaces the domain of dest with one of its IPs, if the DNS resolves the domains
+// dialed without a domain strategy. Otherwise, or if it fails, the domain is left to the system
+// resolver.
+func resolveInternal(ctx context.Context, dest net.Destination, src net.Address) net.Destination {
+	if !dest.Address.Family().IsDomain() {
+		return dest
+	}
+	domain := dest.Address.Domain()
+	if r, ok := dnsClient.(dns.InternalResolver); !ok || !r.ResolvesInternal(domain) {
+		errors.LogDebug(ctx, "resolving ", domain, " with the system resolver")
+		TraceDial(ctx, "resolving ", domain, " with the system resolver")
+		return dest
+	}
+	ips, err := lookupIP(ctx, domain, DomainStrategy_USE_IP, src)
+	if err != nil || len(ips) == 0 {
+		errors.LogInfoInner(ctx, err, "failed to resolve ", domain, " with DNS, falling back to the system resolver")
+		TraceDial(ctx, "failed to resolve ", domain, " with DNS, falling back to the system resolver: ", err)
+		return dest
+	}
+	dest.Address = net.IPAddress(ips[dice.Roll(len(ips))])
+	errors.LogDebug(ctx, "resolved ", domain, " with DNS to ", dest.Address)
+	TraceDial(ctx, "resolved ", domain, " with DNS to ", ips, ", chose ", dest.Address)
+	return dest
+}
+
 func redirect(ctx context.Context, dst net.Destination, obt string) net.Conn {
 	errors.LogInfo(ctx, "redirecting request "+dst.String()+" to "+obt)
 	h := obm.GetHandler(obt)
@@ -142,6 +178,10 @@ func redirect(ctx context.Context, dst net.Destination, obt string) net.Conn {
 
 // DialSystem calls system dialer to create a network connection.
 func DialSystem(ctx context.Context, dest net.Destination, sockopt *SocketConfig) (net.Conn, error) {
+	dest, err := resolveInboundAddress(dest)
+	if err != nil {
+		return nil, err
+	}
 	var src net.Address
 	outbounds := session.OutboundsFromContext(ctx)
 	if len(outbounds) > 0 {
@@ -149,27 +189,55 @@ func DialSystem(ctx context.Context, dest net.Destination, sockopt *SocketConfig
 		src = ob.Gateway
 	}
 	if sockopt == nil {
-		return effectiveSystemDialer.Dial(ctx, src, dest, sockopt)
+		return dialSystem(ctx, src, resolveInternal(ctx, dest, src), sockopt)
 	}
 
 	if canLookupIP(ctx, dest, sockopt) {
-		ips, err := lookupIP(dest.Address.String(), sockopt.DomainStrategy, src)
+		errors.LogDebug(ctx, "resolving ", dest.Address, " with DNS, by domain strategy")
+		ips, err := lookupIP(ctx, dest.Address.String(), sockopt.DomainStrategy, src)
 		if err == nil && len(ips) > 0 {
+			domain := dest.Address
 			dest.Address = net.IPAddress(ips[dice.Roll(len(ips))])
 			errors.LogInfo(ctx, "replace destination with "+dest.String())
+			TraceDial(ctx, "resolved ", domain, " with DNS by domain strategy ", sockopt.DomainStrategy, " to ", ips, ", chose ", dest.Address)
 		} else if err != nil {
 			errors.LogWarningInner(ctx, err, "failed to resolve ip")
+			TraceDial(ctx, "failed to resolve ", dest.Address, " by domain strategy ", sockopt.DomainStrategy, ": ", err)
 		}
+	} else {
+		dest = resolveInternal(ctx, dest, src)
 	}
 
 	if obm != nil && len(sockopt.DialerProxy) > 0 {
 		nc := redirect(ctx, dest, sockopt.DialerProxy)
 		if nc != nil {
+			TraceDial(ctx, "dialing ", dest, " through dialer proxy ", sockopt.DialerProxy)
 			return nc, nil
 		}
 	}
 
-	return effectiveSystemDialer.Dial(ctx, src, dest, sockopt)
+	return dialSystem(ctx, src, dest, sockopt)
+}
+
+// dialSystem dials dest with the system dialer, and records the time TCP connect took. It fails
+// the connections which loop back into this instance too many times.
+func dialSystem(ctx context.Context, src net.Address, dest net.Destination, sockopt *SocketConfig) (net.Conn, error) {
+	TraceDial(ctx, "connecting to ", dest, " from ", src)
+	start := time.Now()
+	conn, err := effectiveSystemDialer.Dial(ctx, src, dest, sockopt)
+	if err != nil {
+		TraceDial(ctx, "failed to connect to ", dest, " after ", time.Since(start), ": ", err)
+		return nil, err
+	}
+	TraceDial(ctx, "connected ", conn.LocalAddr(), " -> ", conn.RemoteAddr(), " in ", time.Since(start))
+	if dest.Network == net.Network_TCP {
+		stats.RecordStage(ctx, stats.StageConnect, start)
+	}
+	if err := checkLoop(ctx, conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
 }
 
 func InitSystemDialer(dc dns.Client, om outbound.Manager) {
@@ -10,6 +10,7 @@ import (
 	c "github.com/xtls/xray-core/common/ctx"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/power"
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/grpc/encoding"
@@ -47,6 +48,22 @@ var (
 	globalDialerAccess sync.Mutex
 )
 
+// closeClients closes the cached connections before the system sleeps, which stops their
+// keepalives, and after it wakes up, as they died meanwhile. Streams dial new ones.
+func closeClients(e power.Event) {
+	globalDialerAccess.Lock()
+	clients := globalDialerMap
+	globalDialerMap = make(map[dialerConf]*grpc.ClientConn)
+	globalDialerAccess.Unlock()
+
+	for _, client := range clients {
+		client.Close()
+	}
+	if len(clients) > 0 {
+		errors.LogInfo(context.Background(), "closed ", len(clients), " gRPC connections as the system sleeps or wakes up")
+	}
+}
+
 func dialgRPC(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (net.Conn, error) {
 	grpcSettings := streamSettings.ProtocolSettings.(*Config)
 
@@ -79,6 +96,7 @@ func getGrpcClient(ctx context.Context, dest net.Destination, streamSettings *in
 
 	if globalDialerMap == nil {
 		globalDialerMap = make(map[dialerConf]*grpc.ClientConn)
+		power.Subscribe(closeClients)
 	}
 	tlsConfig := tls.ConfigFromStreamSettings(streamSettings)
 	realityConfig := reality.ConfigFromStreamSettings(streamSettings)
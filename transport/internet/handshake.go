@@ -0,0 +1,129 @@
+package internet
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+// HandshakeGuard bounds the time and the number of concurrent handshakes of inbound
+// connections, so that peers stalling mid-handshake can't exhaust the resources of servers.
+type HandshakeGuard struct {
+	timeout time.Duration
+	slots   chan struct{}
+
+	access  sync.Mutex
+	holding map[net.Conn]struct{} // connections of http.Server holding slots
+}
+
+// NewHandshakeGuard returns the guard configured in config, or nil if there's none.
+func NewHandshakeGuard(config *SocketConfig) *HandshakeGuard {
+	if config == nil || (config.HandshakeTimeout == 0 && config.MaxHandshakes == 0) {
+		return nil
+	}
+	g := &HandshakeGuard{
+		timeout: time.Duration(config.HandshakeTimeout) * time.Second,
+		holding: make(map[net.Conn]struct{}),
+	}
+	if config.MaxHandshakes > 0 {
+		g.slots = make(chan struct{}, config.MaxHandshakes)
+	}
+	return g
+}
+
+// Timeout returns the handshake timeout, or fallback if it isn't set.
+func (g *HandshakeGuard) Timeout(fallback time.Duration) time.Duration {
+	if g == nil || g.timeout == 0 {
+		return fallback
+	}
+	return g.timeout
+}
+
+func (g *HandshakeGuard) acquire() bool {
+	if g.slots == nil {
+		return true
+	}
+	select {
+	case g.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (g *HandshakeGuard) release() {
+	if g.slots != nil {
+		<-g.slots
+	}
+}
+
+// Run runs handshake on conn within the timeout. conn is closed if the handshake fails, or if
+// too many handshakes are in progress. A nil guard only runs handshake.
+func (g *HandshakeGuard) Run(conn net.Conn, handshake func() error) error {
+	if g == nil {
+		return handshake()
+	}
+	if !g.acquire() {
+		conn.Close()
+		return errors.New("too many handshakes in progress, dropped connection from ", conn.RemoteAddr())
+	}
+	defer g.release()
+
+	if g.timeout > 0 {
+		conn.SetDeadline(time.Now().Add(g.timeout))
+	}
+	if err := handshake(); err != nil {
+		conn.Close()
+		return err
+	}
+	if g.timeout > 0 {
+		conn.SetDeadline(time.Time{})
+	}
+	return nil
+}
+
+// ConnState is the ConnState hook of an http.Server, under which connections count as
+// handshaking until their first request is served or they are hijacked.
+func (g *HandshakeGuard) ConnState(conn net.Conn, state http.ConnState) {
+	if g == nil || g.slots == nil {
+		return
+	}
+	g.access.Lock()
+	defer g.access.Unlock()
+
+	switch state {
+	case http.StateNew:
+		if !g.acquire() {
+			errors.LogDebug(context.Background(), "too many handshakes in progress, dropped connection from ", conn.RemoteAddr())
+			conn.Close()
+			return
+		}
+		g.holding[conn] = struct{}{}
+	case http.StateIdle, http.StateHijacked, http.StateClosed:
+		if _, found := g.holding[conn]; found {
+			delete(g.holding, conn)
+			g.release()
+		}
+	}
+}
+
+type handshakeFailureKey struct{}
+
+// ContextWithHandshakeFailure returns a context for listeners to call onFailure with the
+// connections which fail the TLS or REALITY handshakes they run.
+func ContextWithHandshakeFailure(ctx context.Context, onFailure func(net.Conn, error)) context.Context {
+	return context.WithValue(ctx, handshakeFailureKey{}, onFailure)
+}
+
+// HandshakeFailureFromContext returns the function set by ContextWithHandshakeFailure, or one
+// doing nothing.
+func HandshakeFailureFromContext(ctx context.Context) func(net.Conn, error) {
+	if onFailure, ok := ctx.Value(handshakeFailureKey{}).(func(net.Conn, error)); ok {
+		return onFailure
+	}
+	return func(net.Conn, error) {}
+}
@@ -0,0 +1,56 @@
+package internet_test
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/xtls/xray-core/transport/internet"
+)
+
+func TestHandshakeGuard(t *testing.T) {
+	if NewHandshakeGuard(&SocketConfig{}) != nil {
+		t.Error("expected no guard")
+	}
+
+	guard := NewHandshakeGuard(&SocketConfig{
+		HandshakeTimeout: 1,
+		MaxHandshakes:    1,
+	})
+
+	// A peer sending nothing stalls the handshake until the timeout, and holds the only slot.
+	stalled, peer := net.Pipe()
+	defer peer.Close()
+	done := make(chan error)
+	started := make(chan struct{})
+	go func() {
+		done <- guard.Run(stalled, func() error {
+			close(started)
+			_, err := stalled.Read(make([]byte, 1))
+			return err
+		})
+	}()
+	<-started
+
+	second, secondPeer := net.Pipe()
+	if err := guard.Run(second, func() error { return nil }); err == nil {
+		t.Error("expected the second handshake to be dropped")
+	}
+	if _, err := secondPeer.Read(make([]byte, 1)); err != io.EOF {
+		t.Error("expected the dropped connection to be closed, got ", err)
+	}
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected the stalled handshake to time out")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("the stalled handshake didn't time out")
+	}
+
+	if err := guard.Run(second, func() error { return nil }); err != nil {
+		t.Error("expected a free slot after the timeout, got ", err)
+	}
+}
@@ -20,6 +20,8 @@ type server struct {
 	config         *Config
 	addConn        internet.ConnHandler
 	innnerListener net.Listener
+	guard          *internet.HandshakeGuard
+	trust          *internet.ProxyTrust
 }
 
 func (s *server) Close() error {
@@ -70,14 +72,7 @@ func (s *server) Handle(conn net.Conn) (stat.Connection, error) {
 		return nil, err
 	}
 
-	forwardedAddrs := http_proto.ParseXForwardedFor(req.Header)
-	remoteAddr := conn.RemoteAddr()
-	if len(forwardedAddrs) > 0 && forwardedAddrs[0].Family().IsIP() {
-		remoteAddr = &net.TCPAddr{
-			IP:   forwardedAddrs[0].IP(),
-			Port: int(0),
-		}
-	}
+	remoteAddr := s.trust.ClientAddr(conn.RemoteAddr(), http_proto.ParseXForwardedFor(req.Header))
 
 	return stat.Connection(newConnection(conn, remoteAddr)), nil
 }
@@ -88,12 +83,18 @@ func (s *server) keepAccepting() {
 		if err != nil {
 			return
 		}
-		handledConn, err := s.Handle(conn)
-		if err != nil {
-			errors.LogInfoInner(context.Background(), err, "failed to handle request")
-			continue
-		}
-		s.addConn(handledConn)
+		go func() {
+			var handledConn stat.Connection
+			if err := s.guard.Run(conn, func() (err error) {
+				handledConn, err = s.Handle(conn)
+				return err
+			}); err != nil {
+				conn.Close()
+				errors.LogInfoInner(context.Background(), err, "failed to handle request")
+				return
+			}
+			s.addConn(handledConn)
+		}()
 	}
 }
 
@@ -105,8 +106,11 @@ func ListenHTTPUpgrade(ctx context.Context, address net.Address, port net.Port,
 		}
 		streamSettings.SocketSettings.AcceptProxyProtocol = transportConfiguration.AcceptProxyProtocol || streamSettings.SocketSettings.AcceptProxyProtocol
 	}
+	trust, err := internet.NewProxyTrust(streamSettings.SocketSettings)
+	if err != nil {
+		return nil, err
+	}
 	var listener net.Listener
-	var err error
 	if port == net.Port(0) { // unix
 		listener, err = internet.ListenSystem(ctx, &net.UnixAddr{
 			Name: address.Domain(),
@@ -141,6 +145,8 @@ func ListenHTTPUpgrade(ctx context.Context, address net.Address, port net.Port,
 		config:         transportConfiguration,
 		addConn:        addConn,
 		innnerListener: listener,
+		guard:          internet.NewHandshakeGuard(streamSettings.SocketSettings),
+		trust:          trust,
 	}
 	go serverInstance.keepAccepting()
 	return serverInstance, nil
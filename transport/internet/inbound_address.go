@@ -0,0 +1,78 @@
+package internet
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+// InboundAddressPrefix prefixes the tag of an inbound in a domain address, as "inbound:socks",
+// which is dialed at the address and port that inbound listens on. It refers to inbounds
+// listening on ports allocated at startup.
+const InboundAddressPrefix = "inbound:"
+
+var inboundAddresses = struct {
+	sync.Mutex
+	byTag map[string][]net.Destination
+}{
+	byTag: make(map[string][]net.Destination),
+}
+
+// RegisterInboundAddress records that the inbound tagged tag listens on dest. The returned
+// function removes it.
+func RegisterInboundAddress(tag string, dest net.Destination) func() {
+	if tag == "" {
+		return func() {}
+	}
+	r := &inboundAddresses
+	r.Lock()
+	r.byTag[tag] = append(r.byTag[tag], dest)
+	r.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.Lock()
+			defer r.Unlock()
+			dests := r.byTag[tag]
+			for i, d := range dests {
+				if d == dest {
+					dests = append(dests[:i], dests[i+1:]...)
+					break
+				}
+			}
+			if len(dests) == 0 {
+				delete(r.byTag, tag)
+			} else {
+				r.byTag[tag] = dests
+			}
+		})
+	}
+}
+
+// resolveInboundAddress returns dest, or the address of the inbound it refers to if its address
+// is in the form of InboundAddressPrefix + tag.
+func resolveInboundAddress(dest net.Destination) (net.Destination, error) {
+	if !dest.Address.Family().IsDomain() || !strings.HasPrefix(dest.Address.Domain(), InboundAddressPrefix) {
+		return dest, nil
+	}
+	tag := strings.TrimPrefix(dest.Address.Domain(), InboundAddressPrefix)
+
+	r := &inboundAddresses
+	r.Lock()
+	defer r.Unlock()
+	for _, d := range r.byTag[tag] {
+		if d.Network != dest.Network {
+			continue
+		}
+		if d.Address == net.AnyIP {
+			d.Address = net.LocalHostIP
+		} else if d.Address == net.AnyIPv6 {
+			d.Address = net.LocalHostIPv6
+		}
+		return d, nil
+	}
+	return dest, errors.New("inbound [", tag, "] isn't listening on ", dest.Network)
+}
@@ -0,0 +1,31 @@
+package internet
+
+import (
+	"testing"
+
+	"github.com/xtls/xray-core/common/net"
+)
+
+func TestResolveInboundAddress(t *testing.T) {
+	unregister := RegisterInboundAddress("in", net.TCPDestination(net.AnyIP, 23457))
+
+	dest, err := resolveInboundAddress(net.TCPDestination(net.DomainAddress("inbound:in"), 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dest != net.TCPDestination(net.LocalHostIP, 23457) {
+		t.Error("unexpected destination: ", dest)
+	}
+	if _, err := resolveInboundAddress(net.UDPDestination(net.DomainAddress("inbound:in"), 0)); err == nil {
+		t.Error("expected no UDP address of the inbound")
+	}
+
+	unregister()
+	if _, err := resolveInboundAddress(net.TCPDestination(net.DomainAddress("inbound:in"), 0)); err == nil {
+		t.Error("expected no address after unregistering")
+	}
+	other := net.TCPDestination(net.DomainAddress("example.com"), 443)
+	if dest, err := resolveInboundAddress(other); err != nil || dest != other {
+		t.Error("unexpected destination: ", dest, err)
+	}
+}
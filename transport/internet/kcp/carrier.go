@@ -0,0 +1,367 @@
+package kcp
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol/udp"
+	"github.com/xtls/xray-core/common/signal/done"
+	"github.com/xtls/xray-core/transport/internet"
+)
+
+// Carriers are experimental covert channels carrying the packets of mKCP where only pings or DNS
+// get out, "icmp" in the payloads of echo requests and replies, and "dns" in the names of TXT
+// queries and their answers, through the resolvers of the network. Neither lets a server send by
+// itself: a client sends requests at the rate of its config, empty ones to poll when it has
+// nothing to send, and the server answers each with a packet waiting for the client, if any.
+const (
+	carrierICMP = "icmp"
+	carrierDNS  = "dns"
+
+	// carrierHold is how long a server holds a request waiting for a packet to answer with.
+	carrierHold = 200 * time.Millisecond
+	// carrierActive is how long since the last packet a client keeps polling at its rate, after
+	// which it polls every carrierIdlePoll.
+	carrierActive   = 2 * time.Second
+	carrierIdlePoll = time.Second
+	// carrierPeerTimeout is how long a server keeps the packets of a client sending no requests.
+	carrierPeerTimeout = time.Minute
+)
+
+// packetHub is where a listener receives and sends its packets, a UDP hub or a carrier.
+type packetHub interface {
+	Receive() <-chan *udp.Packet
+	WriteTo(payload []byte, dest net.Destination) (int, error)
+	Addr() net.Addr
+	Close() error
+}
+
+// carrierExchange is the client side of a carrier, sending requests and receiving answers.
+type carrierExchange interface {
+	// send sends a request carrying payload, which is empty to poll.
+	send(payload []byte) error
+	// receive returns the payload of the next answer, empty if it carries no packet.
+	receive() ([]byte, error)
+	Close() error
+	LocalAddr() net.Addr
+	RemoteAddr() net.Addr
+}
+
+// validateCarrier checks the carrier settings of config.
+func validateCarrier(config *Config) error {
+	switch config.Carrier {
+	case carrierICMP:
+	case carrierDNS:
+		if config.CarrierDomain == "" {
+			return errors.New("no domain of the DNS carrier")
+		}
+		if mtu := DNSCarrierMTU(config.CarrierDomain); config.GetMTUValue() > mtu {
+			return errors.New("MTU above ", mtu, " of the DNS carrier")
+		}
+	default:
+		return errors.New("unknown carrier ", config.Carrier)
+	}
+	if config.HopEnabled() || config.RebindingEnabled() || config.AutoMtu {
+		return errors.New("carriers don't hop, rebind or search for the MTU")
+	}
+	return nil
+}
+
+// newCarrierConn dials dest, the server, or for the DNS carrier a resolver, through the carrier
+// of config.
+func newCarrierConn(ctx context.Context, dest net.Destination, config *Config, sockopt *internet.SocketConfig) (net.Conn, error) {
+	if err := validateCarrier(config); err != nil {
+		return nil, err
+	}
+	var exchange carrierExchange
+	var err error
+	switch config.Carrier {
+	case carrierICMP:
+		exchange, err = dialICMPCarrier(dest)
+	case carrierDNS:
+		exchange, err = dialDNSCarrier(ctx, dest, config.CarrierDomain, sockopt)
+	}
+	if err != nil {
+		return nil, errors.New("failed to dial ", config.Carrier, " carrier").Base(err)
+	}
+	errors.LogWarning(ctx, "mKCP over the experimental ", config.Carrier, " carrier to ", dest)
+	c := &carrierConn{
+		exchange: exchange,
+		interval: config.GetCarrierInterval(),
+		outgoing: make(chan *buf.Buffer, 64),
+		incoming: make(chan *buf.Buffer, 1024),
+		done:     done.New(),
+	}
+	go c.sendLoop()
+	go c.receiveLoop()
+	return c, nil
+}
+
+// carrierConn is the client side of a carrier as a connection of packets, each read or write
+// being a packet.
+type carrierConn struct {
+	exchange carrierExchange
+	interval time.Duration
+	outgoing chan *buf.Buffer
+	incoming chan *buf.Buffer
+	done     *done.Instance
+
+	access   sync.Mutex
+	received time.Time
+}
+
+func (c *carrierConn) Read(b []byte) (int, error) {
+	select {
+	case payload := <-c.incoming:
+		n := copy(b, payload.Bytes())
+		payload.Release()
+		return n, nil
+	case <-c.done.Wait():
+		return 0, io.EOF
+	}
+}
+
+// Write queues a packet to send, dropping it if the queue is full, as mKCP sends it again.
+func (c *carrierConn) Write(b []byte) (int, error) {
+	if c.done.Done() {
+		return 0, io.ErrClosedPipe
+	}
+	payload := buf.New()
+	payload.Write(b)
+	select {
+	case c.outgoing <- payload:
+	default:
+		payload.Release()
+	}
+	return len(b), nil
+}
+
+func (c *carrierConn) sendLoop() {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	var sent time.Time
+	for {
+		select {
+		case <-c.done.Wait():
+			return
+		case now := <-ticker.C:
+			var err error
+			select {
+			case payload := <-c.outgoing:
+				err = c.exchange.send(payload.Bytes())
+				payload.Release()
+			default:
+				c.access.Lock()
+				active := now.Sub(c.received) < carrierActive
+				c.access.Unlock()
+				if !active && now.Sub(sent) < carrierIdlePoll {
+					continue
+				}
+				err = c.exchange.send(nil)
+			}
+			if err != nil {
+				errors.LogDebugInner(context.Background(), err, "failed to send through carrier")
+			}
+			sent = now
+		}
+	}
+}
+
+func (c *carrierConn) receiveLoop() {
+	for {
+		b, err := c.exchange.receive()
+		if err != nil {
+			if !c.done.Done() {
+				errors.LogInfoInner(context.Background(), err, "carrier closed")
+				c.Close()
+			}
+			return
+		}
+		if len(b) == 0 {
+			continue
+		}
+		c.access.Lock()
+		c.received = time.Now()
+		c.access.Unlock()
+		payload := buf.New()
+		payload.Write(b)
+		select {
+		case c.incoming <- payload:
+		default:
+			payload.Release()
+		}
+	}
+}
+
+func (c *carrierConn) Close() error {
+	if c.done.Done() {
+		return nil
+	}
+	c.done.Close()
+	return c.exchange.Close()
+}
+
+func (c *carrierConn) LocalAddr() net.Addr {
+	return c.exchange.LocalAddr()
+}
+
+func (c *carrierConn) RemoteAddr() net.Addr {
+	return c.exchange.RemoteAddr()
+}
+
+func (*carrierConn) SetDeadline(time.Time) error {
+	return nil
+}
+
+func (*carrierConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func (*carrierConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// listenCarrier listens for clients of the carrier of config on address and port, which only
+// the DNS carrier uses.
+func listenCarrier(ctx context.Context, address net.Address, port net.Port, config *Config, sockopt *internet.SocketConfig) (packetHub, error) {
+	if err := validateCarrier(config); err != nil {
+		return nil, err
+	}
+	h := &carrierHub{
+		ctx:   ctx,
+		cache: make(chan *udp.Packet, 1024),
+		peers: make(map[net.Destination]*carrierPeer),
+		done:  done.New(),
+	}
+	var err error
+	switch config.Carrier {
+	case carrierICMP:
+		err = listenICMPCarrier(h, address)
+	case carrierDNS:
+		err = listenDNSCarrier(h, address, port, config.CarrierDomain, sockopt)
+	}
+	if err != nil {
+		return nil, errors.New("failed to listen on ", config.Carrier, " carrier").Base(err)
+	}
+	errors.LogWarning(ctx, "mKCP listening on the experimental ", config.Carrier, " carrier")
+	go h.expire()
+	return h, nil
+}
+
+// carrierPeer is a client of a carrier, and the packets waiting for its requests.
+type carrierPeer struct {
+	queue chan *buf.Buffer
+	seen  time.Time
+}
+
+// carrierHub is the server side of a carrier, which its listener feeds with requests.
+type carrierHub struct {
+	ctx    context.Context
+	addr   net.Addr
+	closer io.Closer
+	cache  chan *udp.Packet
+	done   *done.Instance
+
+	access sync.Mutex
+	peers  map[net.Destination]*carrierPeer
+}
+
+// request handles a request of the client src carrying payload, calling answer once with a
+// packet for the client or an empty one.
+func (h *carrierHub) request(src net.Destination, payload []byte, answer func(payload []byte)) {
+	h.access.Lock()
+	if h.done.Done() {
+		h.access.Unlock()
+		return
+	}
+	peer := h.peers[src]
+	if peer == nil {
+		peer = &carrierPeer{queue: make(chan *buf.Buffer, 256)}
+		h.peers[src] = peer
+	}
+	peer.seen = time.Now()
+	if len(payload) > 0 {
+		b := buf.New()
+		b.Write(payload)
+		select {
+		case h.cache <- &udp.Packet{Payload: b, Source: src}:
+		default:
+			b.Release()
+		}
+	}
+	h.access.Unlock()
+
+	go func() {
+		timer := time.NewTimer(carrierHold)
+		defer timer.Stop()
+		select {
+		case b := <-peer.queue:
+			answer(b.Bytes())
+			b.Release()
+		case <-timer.C:
+			answer(nil)
+		case <-h.done.Wait():
+		}
+	}()
+}
+
+func (h *carrierHub) expire() {
+	ticker := time.NewTicker(carrierPeerTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.done.Wait():
+			return
+		case now := <-ticker.C:
+			h.access.Lock()
+			for src, peer := range h.peers {
+				if now.Sub(peer.seen) > carrierPeerTimeout {
+					delete(h.peers, src)
+				}
+			}
+			h.access.Unlock()
+		}
+	}
+}
+
+func (h *carrierHub) Receive() <-chan *udp.Packet {
+	return h.cache
+}
+
+// WriteTo queues payload for the next request of dest, dropping it if the queue is full.
+func (h *carrierHub) WriteTo(payload []byte, dest net.Destination) (int, error) {
+	h.access.Lock()
+	peer := h.peers[dest]
+	h.access.Unlock()
+	if peer == nil {
+		return 0, errors.New("unknown carrier client ", dest)
+	}
+	b := buf.New()
+	b.Write(payload)
+	select {
+	case peer.queue <- b:
+	default:
+		b.Release()
+	}
+	return len(payload), nil
+}
+
+func (h *carrierHub) Addr() net.Addr {
+	return h.addr
+}
+
+func (h *carrierHub) Close() error {
+	h.access.Lock()
+	defer h.access.Unlock()
+	if h.done.Done() {
+		return nil
+	}
+	h.done.Close()
+	close(h.cache)
+	return h.closer.Close()
+}
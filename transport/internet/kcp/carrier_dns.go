@@ -0,0 +1,237 @@
+package kcp
+
+import (
+	"context"
+	"encoding/base32"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/xtls/xray-core/common/dice"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Queries of the DNS carrier are for TXT records of names of the packet of the client in base32,
+// in labels of up to 63 characters, then a label of the session of the client and a nonce against
+// caches, in hex, then the domain of the carrier. Answers carry the packet for the client in the
+// strings of a TXT record. As names are much shorter than answers may be, the names limit the MTU.
+const (
+	dnsMaxName      = 253
+	dnsMaxLabel     = 63
+	dnsSessionLabel = 8
+	dnsMaxString    = 255
+)
+
+var dnsEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// DNSCarrierMTU returns the largest packets the DNS carrier of domain carries.
+func DNSCarrierMTU(domain string) uint32 {
+	available := dnsMaxName - len(strings.Trim(domain, ".")) - 1 - dnsSessionLabel - 1
+	// Each label of the packet is followed by a dot.
+	chars := 0
+	for next := chars + 1; next+(next+dnsMaxLabel-1)/dnsMaxLabel <= available; next++ {
+		chars = next
+	}
+	return uint32(chars * 5 / 8)
+}
+
+// encodeDNSName returns the name of the query of the DNS carrier of domain carrying payload.
+func encodeDNSName(payload []byte, session, nonce uint16, domain string) string {
+	encoded := strings.ToLower(dnsEncoding.EncodeToString(payload))
+	var name strings.Builder
+	for len(encoded) > 0 {
+		n := min(len(encoded), dnsMaxLabel)
+		name.WriteString(encoded[:n])
+		name.WriteByte('.')
+		encoded = encoded[n:]
+	}
+	fmt.Fprintf(&name, "%04x%04x.%s.", session, nonce, strings.Trim(domain, "."))
+	return name.String()
+}
+
+// decodeDNSName returns the session and the payload of the name of a query of the DNS carrier of
+// domain. Resolvers may change the case of names.
+func decodeDNSName(name, domain string) (uint16, []byte, bool) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	rest, found := strings.CutSuffix(name, "."+strings.ToLower(strings.Trim(domain, ".")))
+	if !found {
+		return 0, nil, false
+	}
+	labels := strings.Split(rest, ".")
+	last := labels[len(labels)-1]
+	if len(last) != dnsSessionLabel {
+		return 0, nil, false
+	}
+	session, err := strconv.ParseUint(last[:4], 16, 16)
+	if err != nil {
+		return 0, nil, false
+	}
+	payload, err := dnsEncoding.DecodeString(strings.ToUpper(strings.Join(labels[:len(labels)-1], "")))
+	if err != nil {
+		return 0, nil, false
+	}
+	return uint16(session), payload, true
+}
+
+// dnsExchange sends the queries of a session of the DNS carrier to a resolver, or to the server.
+type dnsExchange struct {
+	conn    net.Conn
+	domain  string
+	session uint16
+	nonce   atomic.Uint32
+}
+
+func dialDNSCarrier(ctx context.Context, dest net.Destination, domain string, sockopt *internet.SocketConfig) (*dnsExchange, error) {
+	conn, err := internet.DialSystem(ctx, dest, sockopt)
+	if err != nil {
+		return nil, err
+	}
+	return &dnsExchange{conn: conn, domain: domain, session: dice.RollUint16()}, nil
+}
+
+func (e *dnsExchange) send(payload []byte) error {
+	name, err := dnsmessage.NewName(encodeDNSName(payload, e.session, uint16(e.nonce.Add(1)), e.domain))
+	if err != nil {
+		return err
+	}
+	b, err := (&dnsmessage.Message{
+		Header: dnsmessage.Header{ID: dice.RollUint16(), RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  name,
+			Type:  dnsmessage.TypeTXT,
+			Class: dnsmessage.ClassINET,
+		}},
+	}).Pack()
+	if err != nil {
+		return err
+	}
+	_, err = e.conn.Write(b)
+	return err
+}
+
+func (e *dnsExchange) receive() ([]byte, error) {
+	b := make([]byte, 4096)
+	for {
+		n, err := e.conn.Read(b)
+		if err != nil {
+			return nil, err
+		}
+		var m dnsmessage.Message
+		if err := m.Unpack(b[:n]); err != nil || !m.Response {
+			continue
+		}
+		var payload []byte
+		for _, answer := range m.Answers {
+			if txt, ok := answer.Body.(*dnsmessage.TXTResource); ok {
+				for _, s := range txt.TXT {
+					payload = append(payload, s...)
+				}
+				break
+			}
+		}
+		return payload, nil
+	}
+}
+
+func (e *dnsExchange) Close() error {
+	return e.conn.Close()
+}
+
+func (e *dnsExchange) LocalAddr() net.Addr {
+	return e.conn.LocalAddr()
+}
+
+func (e *dnsExchange) RemoteAddr() net.Addr {
+	return e.conn.RemoteAddr()
+}
+
+// listenDNSCarrier answers the TXT queries of domain on address and port, as the authoritative
+// server of domain. Clients are told apart by their sessions, as their queries come through
+// resolvers of many addresses, and are named after the first resolver and the session.
+func listenDNSCarrier(h *carrierHub, address net.Address, port net.Port, domain string, sockopt *internet.SocketConfig) error {
+	conn, err := internet.ListenSystemPacket(h.ctx, &net.UDPAddr{IP: address.IP(), Port: int(port)}, sockopt)
+	if err != nil {
+		return err
+	}
+	h.addr, h.closer = conn.LocalAddr(), conn
+	sessions := make(map[uint16]net.Destination)
+	go func() {
+		b := make([]byte, 4096)
+		for {
+			n, addr, err := conn.ReadFrom(b)
+			if err != nil {
+				if !h.done.Done() {
+					errors.LogWarningInner(h.ctx, err, "DNS carrier stops")
+				}
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(b[:n]); err != nil || query.Response || len(query.Questions) != 1 {
+				continue
+			}
+			answer := func(payload []byte) {
+				response, err := answerDNSQuery(&query, payload)
+				if err == nil {
+					_, err = conn.WriteTo(response, addr)
+				}
+				if err != nil {
+					errors.LogDebugInner(context.Background(), err, "failed to answer ", addr)
+				}
+			}
+			// Such as the queries of resolvers minimizing the names they send.
+			q := query.Questions[0]
+			session, payload, ok := decodeDNSName(q.Name.String(), domain)
+			if q.Type != dnsmessage.TypeTXT || !ok {
+				answer(nil)
+				continue
+			}
+			src, found := sessions[session]
+			if !found {
+				udpAddr, ok := addr.(*net.UDPAddr)
+				if !ok {
+					continue
+				}
+				src = net.UDPDestination(net.IPAddress(udpAddr.IP), net.Port(session))
+				sessions[session] = src
+			}
+			h.request(src, payload, answer)
+		}
+	}()
+	return nil
+}
+
+// answerDNSQuery returns the response to query, with a TXT record of payload if it's not empty.
+func answerDNSQuery(query *dnsmessage.Message, payload []byte) ([]byte, error) {
+	response := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:               query.ID,
+			Response:         true,
+			Authoritative:    true,
+			RecursionDesired: query.RecursionDesired,
+			OpCode:           query.OpCode,
+			RCode:            dnsmessage.RCodeSuccess,
+		},
+		Questions: query.Questions,
+	}
+	if len(payload) > 0 {
+		var txt []string
+		for len(payload) > 0 {
+			n := min(len(payload), dnsMaxString)
+			txt = append(txt, string(payload[:n]))
+			payload = payload[n:]
+		}
+		response.Answers = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{
+				Name:  query.Questions[0].Name,
+				Type:  dnsmessage.TypeTXT,
+				Class: dnsmessage.ClassINET,
+			},
+			Body: &dnsmessage.TXTResource{TXT: txt},
+		}}
+	}
+	return response.Pack()
+}
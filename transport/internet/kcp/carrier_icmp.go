@@ -0,0 +1,186 @@
+package kcp
+
+import (
+	"context"
+	gonet "net"
+	"sync/atomic"
+
+	"github.com/xtls/xray-core/common/dice"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// The first bytes of the payloads of echo requests and replies of the ICMP carrier, telling them
+// from other pings, and from the replies of the system echoing the requests.
+const (
+	icmpRequestMarker = 0x6b
+	icmpReplyMarker   = 0x4b
+)
+
+// icmpFamily is the echo messages of ICMP or ICMPv6.
+type icmpFamily struct {
+	protocol int
+	request  icmp.Type
+	reply    icmp.Type
+	// Networks of unprivileged ping sockets and raw sockets, and the address of all interfaces.
+	ping, raw, any string
+}
+
+var (
+	icmpv4 = &icmpFamily{1, ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply, "udp4", "ip4:icmp", "0.0.0.0"}
+	icmpv6 = &icmpFamily{58, ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply, "udp6", "ip6:ipv6-icmp", "::"}
+)
+
+func familyOf(ip net.IP) *icmpFamily {
+	if ip.To4() != nil {
+		return icmpv4
+	}
+	return icmpv6
+}
+
+func (f *icmpFamily) marshal(typ icmp.Type, id, seq int, marker byte, payload []byte) ([]byte, error) {
+	data := make([]byte, 1+len(payload))
+	data[0] = marker
+	copy(data[1:], payload)
+	return (&icmp.Message{Type: typ, Body: &icmp.Echo{ID: id, Seq: seq, Data: data}}).Marshal(nil)
+}
+
+// parse returns the echo message of type typ with marker in b.
+func (f *icmpFamily) parse(b []byte, typ icmp.Type, marker byte) (*icmp.Echo, bool) {
+	m, err := icmp.ParseMessage(f.protocol, b)
+	if err != nil || m.Type != typ {
+		return nil, false
+	}
+	echo, ok := m.Body.(*icmp.Echo)
+	if !ok || len(echo.Data) == 0 || echo.Data[0] != marker {
+		return nil, false
+	}
+	return echo, true
+}
+
+// icmpExchange sends echo requests to a server from an unprivileged ping socket, whose echo ID the
+// system sets and filters replies by, or from a raw socket otherwise.
+type icmpExchange struct {
+	family *icmpFamily
+	conn   *icmp.PacketConn
+	dest   net.Addr
+	ip     net.IP
+	// Whether the socket is raw, receiving the replies of other pings too.
+	raw bool
+	id  int
+	seq atomic.Uint32
+}
+
+func dialICMPCarrier(dest net.Destination) (*icmpExchange, error) {
+	if !dest.Address.Family().IsIP() {
+		return nil, errors.New("the ICMP carrier needs the IP of the server, not ", dest.Address)
+	}
+	ip := dest.Address.IP()
+	e := &icmpExchange{family: familyOf(ip), ip: ip, id: int(dice.RollUint16())}
+	conn, err := icmp.ListenPacket(e.family.ping, "")
+	if err == nil {
+		e.dest = &net.UDPAddr{IP: ip}
+	} else {
+		conn, err = icmp.ListenPacket(e.family.raw, "")
+		if err != nil {
+			return nil, errors.New("neither ping nor raw sockets are permitted").Base(err)
+		}
+		e.dest = &gonet.IPAddr{IP: ip}
+		e.raw = true
+	}
+	e.conn = conn
+	return e, nil
+}
+
+func (e *icmpExchange) send(payload []byte) error {
+	b, err := e.family.marshal(e.family.request, e.id, int(uint16(e.seq.Add(1))), icmpRequestMarker, payload)
+	if err != nil {
+		return err
+	}
+	_, err = e.conn.WriteTo(b, e.dest)
+	return err
+}
+
+func (e *icmpExchange) receive() ([]byte, error) {
+	b := make([]byte, 2048)
+	for {
+		n, addr, err := e.conn.ReadFrom(b)
+		if err != nil {
+			return nil, err
+		}
+		echo, ok := e.family.parse(b[:n], e.family.reply, icmpReplyMarker)
+		if !ok {
+			continue
+		}
+		if e.raw {
+			if ipAddr, ok := addr.(*gonet.IPAddr); !ok || !ipAddr.IP.Equal(e.ip) || echo.ID != e.id {
+				continue
+			}
+		}
+		return echo.Data[1:], nil
+	}
+}
+
+func (e *icmpExchange) Close() error {
+	return e.conn.Close()
+}
+
+func (e *icmpExchange) LocalAddr() net.Addr {
+	return e.conn.LocalAddr()
+}
+
+func (e *icmpExchange) RemoteAddr() net.Addr {
+	return e.dest
+}
+
+// listenICMPCarrier answers the echo requests of clients to address from a raw socket. The system
+// keeps answering them too, unless its echo replies are disabled, by net.ipv4.icmp_echo_ignore_all
+// on Linux, but clients ignore its replies.
+func listenICMPCarrier(h *carrierHub, address net.Address) error {
+	family := icmpv4
+	listen := family.any
+	if address.Family().IsIP() {
+		family = familyOf(address.IP())
+		listen = address.IP().String()
+	}
+	conn, err := icmp.ListenPacket(family.raw, listen)
+	if err != nil {
+		return err
+	}
+	h.addr, h.closer = conn.LocalAddr(), conn
+	go func() {
+		b := make([]byte, 2048)
+		for {
+			n, addr, err := conn.ReadFrom(b)
+			if err != nil {
+				if !h.done.Done() {
+					errors.LogWarningInner(h.ctx, err, "ICMP carrier stops")
+				}
+				return
+			}
+			ipAddr, ok := addr.(*gonet.IPAddr)
+			if !ok {
+				continue
+			}
+			echo, ok := family.parse(b[:n], family.request, icmpRequestMarker)
+			if !ok {
+				continue
+			}
+			src := net.UDPDestination(net.IPAddress(ipAddr.IP), net.Port(echo.ID))
+			id, seq := echo.ID, echo.Seq
+			h.request(src, echo.Data[1:], func(payload []byte) {
+				reply, err := family.marshal(family.reply, id, seq, icmpReplyMarker, payload)
+				if err == nil {
+					_, err = conn.WriteTo(reply, ipAddr)
+				}
+				if err != nil {
+					errors.LogDebugInner(context.Background(), err, "failed to answer ", src)
+				}
+			})
+		}
+	}()
+	return nil
+}
@@ -2,6 +2,7 @@ package kcp
 
 import (
 	"crypto/cipher"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/transport/internet"
@@ -104,3 +105,44 @@ func init() {
 		return new(Config)
 	}))
 }
+
+// HopEnabled tells whether connections hop among ports.
+func (c *Config) HopEnabled() bool {
+	return c != nil && len(c.HopPorts.GetRange()) > 0
+}
+
+// GetHopIntervalValue returns the time between hops.
+func (c *Config) GetHopIntervalValue() time.Duration {
+	if c == nil || c.HopInterval == 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.HopInterval) * time.Second
+}
+
+// GetKeepaliveIntervalValue returns the time between the pings of an idle connection, in
+// milliseconds.
+func (c *Config) GetKeepaliveIntervalValue() uint32 {
+	if c == nil || c.KeepaliveInterval == 0 {
+		return 5000
+	}
+	return c.KeepaliveInterval * 1000
+}
+
+// RebindingEnabled tells whether connections move to another socket, or on a server, to another
+// port of their client, when their packets go unanswered.
+func (c *Config) RebindingEnabled() bool {
+	return c != nil && c.Rebinding
+}
+
+// CarrierEnabled tells whether something else than UDP carries the packets.
+func (c *Config) CarrierEnabled() bool {
+	return c != nil && c.Carrier != ""
+}
+
+// GetCarrierInterval returns the time between the requests of a client of a carrier.
+func (c *Config) GetCarrierInterval() time.Duration {
+	if c == nil || c.CarrierRate == 0 {
+		return time.Second / 20
+	}
+	return time.Second / time.Duration(c.CarrierRate)
+}
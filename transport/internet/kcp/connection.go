@@ -191,8 +191,9 @@ type Connection struct {
 	lastIncomingTime uint32
 	lastPingTime     uint32
 
-	mss       uint32
+	mss       atomic.Uint32
 	roundTrip *RoundTripInfo
+	mtuProbe  *mtuProbe
 
 	receivingWorker *ReceivingWorker
 	sendingWorker   *SendingWorker
@@ -215,13 +216,18 @@ func NewConnection(meta ConnMetadata, writer PacketWriter, closer io.Closer, con
 		dataOutput: signal.NewNotifier(),
 		Config:     config,
 		output:     NewRetryableWriter(NewSegmentWriter(writer)),
-		mss:        config.GetMTUValue() - uint32(writer.Overhead()) - DataSegmentOverhead,
 		roundTrip: &RoundTripInfo{
 			rto:    100,
 			minRtt: config.GetTTIValue(),
 		},
 	}
 
+	overhead := uint32(writer.Overhead()) + DataSegmentOverhead
+	conn.mss.Store(config.GetMTUValue() - overhead)
+	if config.AutoMtu {
+		conn.mtuProbe = newMTUProbe(conn, int32(overhead))
+	}
+
 	conn.receivingWorker = NewReceivingWorker(conn)
 	conn.sendingWorker = NewSendingWorker(conn)
 
@@ -239,7 +245,7 @@ func NewConnection(meta ConnMetadata, writer PacketWriter, closer io.Closer, con
 		isTerminating,
 		conn.updateTask)
 	conn.pingUpdater = NewUpdater(
-		5000, // 5 seconds
+		config.GetKeepaliveIntervalValue(),
 		func() bool { return !isTerminated() },
 		isTerminated,
 		conn.updateTask)
@@ -402,7 +408,7 @@ func (c *Connection) writeMultiBufferInternal(reader io.Reader) error {
 
 			if b == nil {
 				b = buf.New()
-				_, err := b.ReadFrom(io.LimitReader(reader, int64(c.mss)))
+				_, err := b.ReadFrom(io.LimitReader(reader, int64(c.mss.Load())))
 				if err != nil {
 					return nil
 				}
@@ -638,7 +644,7 @@ func (c *Connection) flush() {
 	c.receivingWorker.Flush(current)
 	c.sendingWorker.Flush(current)
 
-	if current-atomic.LoadUint32(&c.lastPingTime) >= 3000 {
+	if current-atomic.LoadUint32(&c.lastPingTime) >= min(3000, c.Config.GetKeepaliveIntervalValue()) {
 		c.Ping(current, CommandPing)
 	}
 }
@@ -49,13 +49,21 @@ func DialKCP(ctx context.Context, dest net.Destination, streamSettings *internet
 	dest.Network = net.Network_UDP
 	errors.LogInfo(ctx, "dialing mKCP to ", dest)
 
-	rawConn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+	kcpSettings := streamSettings.ProtocolSettings.(*Config)
+
+	var rawConn net.Conn
+	var err error
+	if kcpSettings.CarrierEnabled() {
+		rawConn, err = newCarrierConn(ctx, dest, kcpSettings, streamSettings.SocketSettings)
+	} else if kcpSettings.HopEnabled() || kcpSettings.RebindingEnabled() {
+		rawConn, err = newHopConn(ctx, dest, kcpSettings, streamSettings.SocketSettings)
+	} else {
+		rawConn, err = internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+	}
 	if err != nil {
 		return nil, errors.New("failed to dial to dest: ", err).AtWarning().Base(err)
 	}
 
-	kcpSettings := streamSettings.ProtocolSettings.(*Config)
-
 	header, err := kcpSettings.GetPackerHeader()
 	if err != nil {
 		return nil, errors.New("failed to create packet header").Base(err)
@@ -0,0 +1,231 @@
+package kcp
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/dice"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/signal/done"
+	"github.com/xtls/xray-core/transport/internet"
+)
+
+const (
+	// hopStallTimeout is how long packets may go unanswered before the connection hops, as the
+	// port is likely throttled.
+	hopStallTimeout = 5 * time.Second
+	// hopCheckInterval is how often the connection checks whether to hop.
+	hopCheckInterval = time.Second
+	// hopGracePeriod is how long the socket of the previous port keeps receiving, for the packets
+	// in flight.
+	hopGracePeriod = 5 * time.Second
+)
+
+// hopConn sends the packets of a connection from a new socket to another port of the server every
+// interval, and whenever they go unanswered for a while, so that no port carries enough traffic to
+// be throttled. It receives from the sockets of all ports still in use. Without ports, it only
+// moves to a new socket to the same port when the packets go unanswered, as after the NAT mapping
+// of the socket was lost.
+type hopConn struct {
+	ctx            context.Context
+	dest           net.Destination
+	ports          []*net.PortRange
+	interval       time.Duration
+	stallTimeout   time.Duration
+	socketSettings *internet.SocketConfig
+
+	access  sync.Mutex
+	current net.Conn
+	hopped  time.Time
+	// waiting is when the first packet was sent since the last one was received, zero if none.
+	waiting    time.Time
+	packets    chan *buf.Buffer
+	done       *done.Instance
+	localAddr  net.Addr
+	remoteAddr net.Addr
+}
+
+func newHopConn(ctx context.Context, dest net.Destination, config *Config, socketSettings *internet.SocketConfig) (*hopConn, error) {
+	c := &hopConn{
+		ctx:            ctx,
+		dest:           dest,
+		ports:          config.HopPorts.GetRange(),
+		stallTimeout:   hopStallTimeout,
+		socketSettings: socketSettings,
+		packets:        make(chan *buf.Buffer, 1024),
+		done:           done.New(),
+	}
+	if config.HopEnabled() {
+		c.interval = config.GetHopIntervalValue()
+	}
+	// Idle connections are answered by the pings of the server.
+	if keepalive := 2 * time.Duration(config.GetKeepaliveIntervalValue()) * time.Millisecond; keepalive > c.stallTimeout {
+		c.stallTimeout = keepalive
+	}
+	conn, err := c.dial(dest.Port)
+	if err != nil {
+		return nil, err
+	}
+	c.localAddr, c.remoteAddr = conn.LocalAddr(), conn.RemoteAddr()
+	c.current = conn
+	c.hopped = time.Now()
+	go c.receive(conn)
+	go c.monitor()
+	return c, nil
+}
+
+func (c *hopConn) dial(port net.Port) (net.Conn, error) {
+	dest := c.dest
+	dest.Port = port
+	return internet.DialSystem(c.ctx, dest, c.socketSettings)
+}
+
+// randomPort returns a port of the ranges, each port being as likely.
+func (c *hopConn) randomPort() net.Port {
+	if len(c.ports) == 0 {
+		return c.dest.Port
+	}
+	total := 0
+	for _, r := range c.ports {
+		total += int(r.To) - int(r.From) + 1
+	}
+	n := dice.Roll(total)
+	for _, r := range c.ports {
+		size := int(r.To) - int(r.From) + 1
+		if n < size {
+			return net.Port(int(r.From) + n)
+		}
+		n -= size
+	}
+	return c.dest.Port
+}
+
+func (c *hopConn) receive(conn net.Conn) {
+	for {
+		payload := buf.New()
+		if _, err := payload.ReadFrom(conn); err != nil {
+			payload.Release()
+			return
+		}
+		c.access.Lock()
+		c.waiting = time.Time{}
+		c.access.Unlock()
+		select {
+		case c.packets <- payload:
+		case <-c.done.Wait():
+			payload.Release()
+			return
+		default:
+			payload.Release()
+		}
+	}
+}
+
+func (c *hopConn) monitor() {
+	ticker := time.NewTicker(hopCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done.Wait():
+			return
+		case now := <-ticker.C:
+			c.access.Lock()
+			stalled := !c.waiting.IsZero() && now.Sub(c.waiting) > c.stallTimeout && now.Sub(c.hopped) > c.stallTimeout
+			due := c.interval > 0 && now.Sub(c.hopped) >= c.interval
+			c.access.Unlock()
+			if stalled && len(c.ports) == 0 {
+				errors.LogInfo(c.ctx, "mKCP packets to ", c.dest, " unanswered, sending from a new socket")
+			} else if stalled {
+				errors.LogInfo(c.ctx, "mKCP packets to ", c.dest.Address, " unanswered, hopping to another port")
+			}
+			if stalled || due {
+				c.hop(now)
+			}
+		}
+	}
+}
+
+// hop moves the connection to a new socket and a random port, and closes the previous socket
+// after hopGracePeriod.
+func (c *hopConn) hop(now time.Time) {
+	port := c.randomPort()
+	conn, err := c.dial(port)
+	if err != nil {
+		errors.LogInfoInner(c.ctx, err, "failed to hop to port ", port)
+		return
+	}
+	c.access.Lock()
+	if c.done.Done() {
+		c.access.Unlock()
+		conn.Close()
+		return
+	}
+	previous := c.current
+	c.current = conn
+	c.hopped = now
+	c.access.Unlock()
+	go c.receive(conn)
+	time.AfterFunc(hopGracePeriod, func() { previous.Close() })
+	errors.LogDebug(c.ctx, "mKCP to ", c.dest.Address, " hopped to port ", port, " from ", conn.LocalAddr())
+}
+
+// Read implements io.Reader, reading a packet from any of the sockets.
+func (c *hopConn) Read(b []byte) (int, error) {
+	select {
+	case payload := <-c.packets:
+		n := copy(b, payload.Bytes())
+		payload.Release()
+		return n, nil
+	case <-c.done.Wait():
+		return 0, io.EOF
+	}
+}
+
+// Write implements io.Writer, sending a packet from the socket of the current port.
+func (c *hopConn) Write(b []byte) (int, error) {
+	c.access.Lock()
+	conn := c.current
+	if c.waiting.IsZero() {
+		c.waiting = time.Now()
+	}
+	c.access.Unlock()
+	return conn.Write(b)
+}
+
+// Close implements io.Closer.
+func (c *hopConn) Close() error {
+	c.access.Lock()
+	defer c.access.Unlock()
+	if c.done.Done() {
+		return nil
+	}
+	c.done.Close()
+	return c.current.Close()
+}
+
+func (c *hopConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+func (c *hopConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// SetDeadline implements net.Conn. Timeouts are left to the mKCP connection.
+func (c *hopConn) SetDeadline(time.Time) error {
+	return nil
+}
+
+// SetReadDeadline implements net.Conn.
+func (c *hopConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline implements net.Conn.
+func (c *hopConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
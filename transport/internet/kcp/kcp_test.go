@@ -83,3 +83,193 @@ func TestDialAndListen(t *testing.T) {
 		t.Error("active connections: ", v)
 	}
 }
+
+func TestDialAndListenHopping(t *testing.T) {
+	serverConfig := &Config{
+		HopPorts: &net.PortList{Range: []*net.PortRange{{From: 1, To: 1}}},
+	}
+	echo := func(conn stat.Connection) {
+		go func(c stat.Connection) {
+			payload := make([]byte, 4096)
+			for {
+				nBytes, err := c.Read(payload)
+				if err != nil {
+					break
+				}
+				c.Write(payload[:nBytes])
+			}
+			c.Close()
+		}(conn)
+	}
+	var ports []*net.PortRange
+	var listeners []*Listener
+	for i := 0; i < 2; i++ {
+		listener, err := NewListener(context.Background(), net.LocalHostIP, net.Port(0), &internet.MemoryStreamConfig{
+			ProtocolName:     "mkcp",
+			ProtocolSettings: serverConfig,
+		}, echo)
+		common.Must(err)
+		defer listener.Close()
+		listeners = append(listeners, listener)
+		port := uint32(listener.Addr().(*net.UDPAddr).Port)
+		ports = append(ports, &net.PortRange{From: port, To: port})
+	}
+
+	clientConn, err := DialKCP(context.Background(), net.UDPDestination(net.LocalHostIP, net.Port(ports[0].From)), &internet.MemoryStreamConfig{
+		ProtocolName: "mkcp",
+		ProtocolSettings: &Config{
+			HopPorts:    &net.PortList{Range: ports},
+			HopInterval: 1,
+		},
+	})
+	common.Must(err)
+
+	for i := 0; i < 8; i++ {
+		sent := make([]byte, 1024)
+		rand.Read(sent)
+		common.Must2(clientConn.Write(sent))
+		received := make([]byte, len(sent))
+		common.Must2(io.ReadFull(clientConn, received))
+		if r := cmp.Diff(received, sent); r != "" {
+			t.Fatal("round ", i, ": ", r)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	clientConn.Close()
+
+	for i := 0; i < 60 && listeners[0].ActiveConnections() > 0; i++ {
+		time.Sleep(500 * time.Millisecond)
+	}
+	if v := listeners[0].ActiveConnections(); v != 0 {
+		t.Error("active connections: ", v)
+	}
+}
+
+// natRelay forwards the packets of a client to a server, from a new port after each rebind, as a
+// NAT does after its mapping is lost.
+type natRelay struct {
+	conn     *net.UDPConn
+	server   *net.UDPAddr
+	upstream chan *net.UDPConn
+}
+
+func newNATRelay(server *net.UDPAddr) *natRelay {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.LocalHostIP.IP()})
+	common.Must(err)
+	r := &natRelay{conn: conn, server: server, upstream: make(chan *net.UDPConn, 1)}
+	r.rebind()
+	go func() {
+		b := make([]byte, 2048)
+		var upstream *net.UDPConn
+		for {
+			n, client, err := conn.ReadFromUDP(b)
+			if err != nil {
+				return
+			}
+			select {
+			case u := <-r.upstream:
+				if upstream != nil {
+					upstream.Close()
+				}
+				upstream = u
+				go func() {
+					b := make([]byte, 2048)
+					for {
+						n, err := u.Read(b)
+						if err != nil {
+							return
+						}
+						conn.WriteToUDP(b[:n], client)
+					}
+				}()
+			default:
+			}
+			upstream.Write(b[:n])
+		}
+	}()
+	return r
+}
+
+func (r *natRelay) rebind() {
+	upstream, err := net.DialUDP("udp", nil, r.server)
+	common.Must(err)
+	r.upstream <- upstream
+}
+
+func TestDialAndListenRebinding(t *testing.T) {
+	listener, err := NewListener(context.Background(), net.LocalHostIP, net.Port(0), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: &Config{Rebinding: true},
+	}, func(conn stat.Connection) {
+		go func(c stat.Connection) {
+			common.Must2(io.Copy(c, c))
+			c.Close()
+		}(conn)
+	})
+	common.Must(err)
+	defer listener.Close()
+
+	relay := newNATRelay(listener.Addr().(*net.UDPAddr))
+	defer relay.conn.Close()
+	clientConn, err := DialKCP(context.Background(), net.UDPDestination(net.LocalHostIP, net.Port(relay.conn.LocalAddr().(*net.UDPAddr).Port)), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: &Config{Rebinding: true},
+	})
+	common.Must(err)
+	defer clientConn.Close()
+	common.Must(clientConn.SetDeadline(time.Now().Add(10 * time.Second)))
+
+	for i := 0; i < 3; i++ {
+		sent := make([]byte, 1024)
+		rand.Read(sent)
+		common.Must2(clientConn.Write(sent))
+		received := make([]byte, len(sent))
+		common.Must2(io.ReadFull(clientConn, received))
+		if r := cmp.Diff(received, sent); r != "" {
+			t.Fatal("round ", i, ": ", r)
+		}
+		relay.rebind()
+	}
+	if v := listener.ActiveConnections(); v != 1 {
+		t.Error("active connections: ", v)
+	}
+}
+
+func TestDialAndListenDNSCarrier(t *testing.T) {
+	const domain = "t.example.com"
+	config := &Config{
+		Mtu:           &MTU{Value: DNSCarrierMTU(domain)},
+		Carrier:       "dns",
+		CarrierDomain: domain,
+		CarrierRate:   1000,
+	}
+	listener, err := NewListener(context.Background(), net.LocalHostIP, net.Port(0), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: config,
+	}, func(conn stat.Connection) {
+		go func(c stat.Connection) {
+			defer c.Close()
+			common.Must2(io.Copy(c, c))
+		}(conn)
+	})
+	common.Must(err)
+	defer listener.Close()
+
+	port := net.Port(listener.Addr().(*net.UDPAddr).Port)
+	conn, err := DialKCP(context.Background(), net.UDPDestination(net.LocalHostIP, port), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: config,
+	})
+	common.Must(err)
+	defer conn.Close()
+
+	sent := make([]byte, 16*1024)
+	rand.Read(sent)
+	go conn.Write(sent)
+
+	received := make([]byte, len(sent))
+	common.Must2(io.ReadFull(conn, received))
+	if r := cmp.Diff(received, sent); r != "" {
+		t.Error(r)
+	}
+}
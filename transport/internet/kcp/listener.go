@@ -22,11 +22,58 @@ type ConnectionID struct {
 	Conv   uint16
 }
 
+// session is a connection accepted by a listener, and the writer of its packets.
+type session struct {
+	conn   *Connection
+	writer *Writer
+}
+
+// sessionTable is the connections of a listener, or with port hopping, of all the listeners of the
+// same settings, such as those of the ports of an inbound.
+type sessionTable struct {
+	sync.Mutex
+	sessions  map[ConnectionID]*session
+	listeners int
+}
+
+var hopTables = struct {
+	sync.Mutex
+	tables map[*Config]*sessionTable
+}{tables: make(map[*Config]*sessionTable)}
+
+// acquireSessionTable returns the table of the connections of a new listener of config.
+func acquireSessionTable(config *Config) *sessionTable {
+	if !config.HopEnabled() {
+		return &sessionTable{sessions: make(map[ConnectionID]*session), listeners: 1}
+	}
+	hopTables.Lock()
+	defer hopTables.Unlock()
+	t := hopTables.tables[config]
+	if t == nil {
+		t = &sessionTable{sessions: make(map[ConnectionID]*session)}
+		hopTables.tables[config] = t
+	}
+	t.listeners++
+	return t
+}
+
+// releaseSessionTable releases the table of a listener of config once closed.
+func releaseSessionTable(config *Config, t *sessionTable) {
+	if !config.HopEnabled() {
+		return
+	}
+	hopTables.Lock()
+	defer hopTables.Unlock()
+	if t.listeners--; t.listeners == 0 {
+		delete(hopTables.tables, config)
+	}
+}
+
 // Listener defines a server listening for connections
 type Listener struct {
 	sync.Mutex
-	sessions  map[ConnectionID]*Connection
-	hub       *udp.Hub
+	table     *sessionTable
+	hub       packetHub
 	tlsConfig *gotls.Config
 	config    *Config
 	reader    PacketReader
@@ -52,19 +99,24 @@ func NewListener(ctx context.Context, address net.Address, port net.Port, stream
 			Header:   header,
 			Security: security,
 		},
-		sessions: make(map[ConnectionID]*Connection),
-		config:   kcpSettings,
-		addConn:  addConn,
+		config:  kcpSettings,
+		addConn: addConn,
 	}
 
 	if config := tls.ConfigFromStreamSettings(streamSettings); config != nil {
 		l.tlsConfig = config.GetTLSConfig()
 	}
 
-	hub, err := udp.ListenUDP(ctx, address, port, streamSettings, udp.HubCapacity(1024))
+	var hub packetHub
+	if kcpSettings.CarrierEnabled() {
+		hub, err = listenCarrier(ctx, address, port, kcpSettings, streamSettings.SocketSettings)
+	} else {
+		hub, err = udp.ListenUDP(ctx, address, port, streamSettings, udp.HubCapacity(1024))
+	}
 	if err != nil {
 		return nil, err
 	}
+	l.table = acquireSessionTable(kcpSettings)
 	l.Lock()
 	l.hub = hub
 	l.Unlock()
@@ -99,13 +151,20 @@ func (l *Listener) OnReceive(payload *buf.Buffer, src net.Destination) {
 		Port:   src.Port,
 		Conv:   conv,
 	}
+	if l.config.HopEnabled() || l.config.RebindingEnabled() {
+		// The client sends from a new port to another one at each hop, and from another port
+		// after a NAT rebinding.
+		id.Port = 0
+	}
 
-	l.Lock()
-	defer l.Unlock()
+	l.table.Lock()
+	defer l.table.Unlock()
 
-	conn, found := l.sessions[id]
+	s, found := l.table.sessions[id]
 
-	if !found {
+	if found {
+		s.writer.update(l, src)
+	} else {
 		if cmd == CommandTerminate {
 			return
 		}
@@ -120,7 +179,7 @@ func (l *Listener) OnReceive(payload *buf.Buffer, src net.Destination) {
 			Port: int(src.Port),
 		}
 		localAddr := l.hub.Addr()
-		conn = NewConnection(ConnMetadata{
+		conn := NewConnection(ConnMetadata{
 			LocalAddr:    localAddr,
 			RemoteAddr:   remoteAddr,
 			Conversation: conv,
@@ -135,36 +194,40 @@ func (l *Listener) OnReceive(payload *buf.Buffer, src net.Destination) {
 		}
 
 		l.addConn(netConn)
-		l.sessions[id] = conn
+		s = &session{conn: conn, writer: writer}
+		l.table.sessions[id] = s
 	}
-	conn.Input(segments)
+	s.conn.Input(segments)
 }
 
 func (l *Listener) Remove(id ConnectionID) {
-	l.Lock()
-	delete(l.sessions, id)
-	l.Unlock()
+	l.table.Lock()
+	delete(l.table.sessions, id)
+	l.table.Unlock()
 }
 
 // Close stops listening on the UDP address. Already Accepted connections are not closed.
 func (l *Listener) Close() error {
 	l.hub.Close()
 
-	l.Lock()
-	defer l.Unlock()
+	l.table.Lock()
+	defer l.table.Unlock()
 
-	for _, conn := range l.sessions {
-		go conn.Terminate()
+	for _, s := range l.table.sessions {
+		if s.writer.owner() == l {
+			go s.conn.Terminate()
+		}
 	}
+	releaseSessionTable(l.config, l.table)
 
 	return nil
 }
 
 func (l *Listener) ActiveConnections() int {
-	l.Lock()
-	defer l.Unlock()
+	l.table.Lock()
+	defer l.table.Unlock()
 
-	return len(l.sessions)
+	return len(l.table.sessions)
 }
 
 // Addr returns the listener's network address, The Addr returned is shared by all invocations of Addr, so do not modify it.
@@ -173,18 +236,36 @@ func (l *Listener) Addr() net.Addr {
 }
 
 type Writer struct {
+	access   sync.Mutex
 	id       ConnectionID
 	dest     net.Destination
-	hub      *udp.Hub
+	hub      packetHub
 	listener *Listener
 }
 
+// update sends the next packets to dest from the port of listener, where the last packet of the
+// connection came from and to, as connections hop among ports.
+func (w *Writer) update(listener *Listener, dest net.Destination) {
+	w.access.Lock()
+	defer w.access.Unlock()
+	w.listener, w.hub, w.dest = listener, listener.hub, dest
+}
+
+func (w *Writer) owner() *Listener {
+	w.access.Lock()
+	defer w.access.Unlock()
+	return w.listener
+}
+
 func (w *Writer) Write(payload []byte) (int, error) {
-	return w.hub.WriteTo(payload, w.dest)
+	w.access.Lock()
+	hub, dest := w.hub, w.dest
+	w.access.Unlock()
+	return hub.WriteTo(payload, dest)
 }
 
 func (w *Writer) Close() error {
-	w.listener.Remove(w.id)
+	w.owner().Remove(w.id)
 	return nil
 }
 
@@ -0,0 +1,81 @@
+package kcp
+
+import (
+	"net"
+	"sync/atomic"
+
+	"github.com/xtls/xray-core/transport/internet/pmtu"
+)
+
+const (
+	// minMTU is the least MTU of mKCP.
+	minMTU = 576
+	// blackHoleTransmissions is the transmissions of a full-size segment after which its size is
+	// taken not to get through, if the peer is still heard from meanwhile.
+	blackHoleTransmissions = 4
+)
+
+// mtuProber searches for the largest packets getting through the paths of mKCP connections set
+// to AutoMtu.
+var mtuProber = pmtu.NewProber("mKCP", minMTU)
+
+// mtuProbe reports whether the full-size segments of a connection get through to mtuProber, and
+// lowers the segment size of the connection when they don't. Segments sent already keep their
+// size, so a connection on a path dropping them may still fail, but the next ones are sized to
+// get through.
+type mtuProbe struct {
+	conn      *Connection
+	path      string
+	overhead  int32 // of packets over segment payloads
+	confirmed int32 // size of packets last reported to get through
+}
+
+func newMTUProbe(conn *Connection, overhead int32) *mtuProbe {
+	path := conn.meta.RemoteAddr.String()
+	if host, _, err := net.SplitHostPort(path); err == nil {
+		// Hop ports share the path.
+		path = host
+	}
+	p := &mtuProbe{
+		conn:     conn,
+		path:     path,
+		overhead: overhead,
+	}
+	conn.mss.Store(uint32(mtuProber.Size(path, int32(conn.Config.GetMTUValue())) - overhead))
+	return p
+}
+
+// full returns whether seg is of the size of the segments of the connection, and the size of the
+// packets carrying it.
+func (p *mtuProbe) full(seg *DataSegment) (bool, int32) {
+	size := int32(seg.payload.Len())
+	return size == int32(p.conn.mss.Load()), size + p.overhead
+}
+
+// onAcknowledged is called when seg is acknowledged.
+func (p *mtuProbe) onAcknowledged(seg *DataSegment) {
+	if p == nil || seg.transmit != 1 {
+		return
+	}
+	if full, size := p.full(seg); full && size != p.confirmed {
+		p.confirmed = size
+		mtuProber.Succeeded(p.path, size)
+	}
+}
+
+// onTransmit is called when seg is sent, at current.
+func (p *mtuProbe) onTransmit(seg *DataSegment, current uint32) {
+	if p == nil || seg.transmit != blackHoleTransmissions {
+		return
+	}
+	// Nothing at all getting through isn't a matter of size.
+	if current-atomic.LoadUint32(&p.conn.lastIncomingTime) > p.conn.Config.GetKeepaliveIntervalValue()+1000 {
+		return
+	}
+	full, size := p.full(seg)
+	if !full {
+		return
+	}
+	mtuProber.Failed(p.path, size)
+	p.conn.mss.Store(uint32(mtuProber.Size(p.path, size) - p.overhead))
+}
@@ -47,6 +47,8 @@ type AckList struct {
 
 	flushCandidates []uint32
 	dirty           bool
+	// limit is the most numbers of a segment, which fits in the MTU.
+	limit int
 }
 
 func NewAckList(writer SegmentWriter) *AckList {
@@ -56,9 +58,14 @@ func NewAckList(writer SegmentWriter) *AckList {
 		numbers:         make([]uint32, 0, 128),
 		nextFlush:       make([]uint32, 0, 128),
 		flushCandidates: make([]uint32, 0, 128),
+		limit:           ackNumberLimit,
 	}
 }
 
+func (l *AckList) full(seg *AckSegment) bool {
+	return seg.IsFull() || len(seg.NumberList) >= l.limit
+}
+
 func (l *AckList) Add(number uint32, timestamp uint32) {
 	l.timestamps = append(l.timestamps, timestamp)
 	l.numbers = append(l.numbers, number)
@@ -106,7 +113,7 @@ func (l *AckList) Flush(current uint32, rto uint32) {
 		}
 		l.nextFlush[i] = current + timeout
 
-		if seg.IsFull() {
+		if l.full(seg) {
 			l.writer.Write(seg)
 			seg.Release()
 			seg = NewAckSegment()
@@ -116,7 +123,7 @@ func (l *AckList) Flush(current uint32, rto uint32) {
 
 	if l.dirty || !seg.IsEmpty() {
 		for _, number := range l.flushCandidates {
-			if seg.IsFull() {
+			if l.full(seg) {
 				break
 			}
 			seg.PutNumber(number)
@@ -145,6 +152,8 @@ func NewReceivingWorker(kcp *Connection) *ReceivingWorker {
 		windowSize: kcp.Config.GetReceivingInFlightSize(),
 	}
 	worker.acklist = NewAckList(worker)
+	// An ACK segment has a byte less of overhead than a data segment, and 4 bytes per number.
+	worker.acklist.limit = max(int(kcp.mss.Load()+DataSegmentOverhead-AckSegmentOverhead)/4, 1)
 	return worker
 }
 
@@ -180,6 +189,9 @@ func (w *ReceivingWorker) ProcessSegment(seg *DataSegment) {
 }
 
 func (w *ReceivingWorker) ReadMultiBuffer() buf.MultiBuffer {
+	w.Lock()
+	defer w.Unlock()
+
 	if w.leftOver != nil {
 		mb := w.leftOver
 		w.leftOver = nil
@@ -187,9 +199,6 @@ func (w *ReceivingWorker) ReadMultiBuffer() buf.MultiBuffer {
 	}
 
 	mb := make(buf.MultiBuffer, 0, 32)
-
-	w.Lock()
-	defer w.Unlock()
 	for {
 		seg := w.window.Remove(w.nextNumber)
 		if seg == nil {
@@ -210,7 +219,9 @@ func (w *ReceivingWorker) Read(b []byte) int {
 	}
 	mb, nBytes := buf.SplitBytes(mb, b)
 	if !mb.IsEmpty() {
+		w.Lock()
 		w.leftOver = mb
+		w.Unlock()
 	}
 	return nBytes
 }
@@ -37,6 +37,7 @@ type Segment interface {
 
 const (
 	DataSegmentOverhead = 18
+	AckSegmentOverhead  = 17
 )
 
 type DataSegment struct {
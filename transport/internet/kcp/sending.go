@@ -12,6 +12,7 @@ type SendingWindow struct {
 	totalInFlightSize uint32
 	writer            SegmentWriter
 	onPacketLoss      func(uint32)
+	probe             *mtuProbe
 }
 
 func NewSendingWindow(writer SegmentWriter, onPacketLoss func(uint32)) *SendingWindow {
@@ -117,6 +118,7 @@ func (sw *SendingWindow) Flush(current uint32, rto uint32, maxInFlightSize uint3
 
 		segment.Timestamp = current
 		segment.transmit++
+		sw.probe.onTransmit(segment, current)
 		sw.writer.Write(segment)
 		inFlightSize++
 		return inFlightSize < maxInFlightSize
@@ -141,6 +143,7 @@ func (sw *SendingWindow) Remove(number uint32) bool {
 			if sw.totalInFlightSize > 0 {
 				sw.totalInFlightSize--
 			}
+			sw.probe.onAcknowledged(seg)
 			seg.Release()
 			sw.cache.Remove(e)
 			return true
@@ -173,6 +176,7 @@ func NewSendingWorker(kcp *Connection) *SendingWorker {
 		windowSize:       kcp.Config.GetSendingBufferSize(),
 	}
 	worker.window = NewSendingWindow(worker, worker.OnPacketLoss)
+	worker.window.probe = kcp.mtuProbe
 	return worker
 }
 
@@ -191,6 +195,15 @@ func (w *SendingWorker) ProcessReceivingNext(nextNumber uint32) {
 }
 
 func (w *SendingWorker) ProcessReceivingNextWithoutLock(nextNumber uint32) {
+	if w.window.probe != nil {
+		w.window.Visit(func(seg *DataSegment) bool {
+			if seg.Number >= nextNumber {
+				return false
+			}
+			w.window.probe.onAcknowledged(seg)
+			return true
+		})
+	}
 	w.window.Clear(nextNumber)
 	w.FindFirstUnacknowledged()
 }
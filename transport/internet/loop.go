@@ -0,0 +1,194 @@
+package internet
+
+import (
+	"context"
+	gonet "net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+// maxLoopHops is how many times a connection may be dialed by this instance to its own inbounds,
+// as with outbounds chained through local inbounds, before it's taken as a routing loop.
+const maxLoopHops = 8
+
+// selfDialTimeout is how long the connections dialed to our own inbounds are remembered, for
+// them to dial further.
+const selfDialTimeout = time.Minute
+
+type loopSourceKey struct{}
+
+// ContextWithLoopSource returns a context for a connection accepted by an inbound from source,
+// which may have been dialed by this instance itself.
+func ContextWithLoopSource(ctx context.Context, source net.Destination) context.Context {
+	return context.WithValue(ctx, loopSourceKey{}, source)
+}
+
+// loopHops returns how many times the connection of ctx went through our own inbounds. It's
+// looked up when dialing rather than accepting, as the dialer may not have recorded the
+// connection yet by then.
+func loopHops(ctx context.Context) int {
+	source, ok := ctx.Value(loopSourceKey{}).(net.Destination)
+	if !ok {
+		return 0
+	}
+	r := &localReservations
+	r.Lock()
+	defer r.Unlock()
+	if dial, found := r.dials[source]; found && time.Since(dial.time) <= selfDialTimeout {
+		return dial.hops
+	}
+	return 0
+}
+
+// IsOwnTransport tells whether the UDP flow of ctx, accepted by an inbound, was dialed by a
+// transport of this instance, as the packets of mKCP or QUIC of an outbound chained through our
+// own inbounds. TCP connections are not told apart, as they may be accepted before the dialer
+// records them.
+func IsOwnTransport(ctx context.Context) bool {
+	source, ok := ctx.Value(loopSourceKey{}).(net.Destination)
+	return ok && source.Network == net.Network_UDP && loopHops(ctx) > 0
+}
+
+type reservedPort struct {
+	network net.Network
+	port    net.Port
+}
+
+type selfDial struct {
+	hops int
+	time time.Time
+}
+
+var localReservations = struct {
+	sync.Mutex
+	addresses map[reservedPort][]net.Address
+	dials     map[net.Destination]selfDial // by the local addresses of the connections
+	purged    time.Time
+}{
+	addresses: make(map[reservedPort][]net.Address),
+	dials:     make(map[net.Destination]selfDial),
+}
+
+// ReserveLocalAddress records that an inbound listens on dest, so that connections dialed to it
+// are known to loop back into this instance. The returned function releases it.
+func ReserveLocalAddress(dest net.Destination) func() {
+	if !dest.Address.Family().IsIP() {
+		return func() {}
+	}
+	key := reservedPort{network: dest.Network, port: dest.Port}
+	r := &localReservations
+	r.Lock()
+	r.addresses[key] = append(r.addresses[key], dest.Address)
+	r.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.Lock()
+			defer r.Unlock()
+			addresses := r.addresses[key]
+			for i, address := range addresses {
+				if address == dest.Address {
+					addresses = append(addresses[:i], addresses[i+1:]...)
+					break
+				}
+			}
+			if len(addresses) == 0 {
+				delete(r.addresses, key)
+			} else {
+				r.addresses[key] = addresses
+			}
+		})
+	}
+}
+
+// isReserved tells whether dest is the address of one of our own inbounds.
+func isReserved(dest net.Destination) bool {
+	if !dest.Address.Family().IsIP() {
+		return false
+	}
+	r := &localReservations
+	r.Lock()
+	addresses := r.addresses[reservedPort{network: dest.Network, port: dest.Port}]
+	r.Unlock()
+
+	for _, address := range addresses {
+		if address.IP().Equal(dest.Address.IP()) {
+			return true
+		}
+		if (address == net.AnyIP || address == net.AnyIPv6) && isLocalIP(dest.Address.IP()) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocalIP tells whether ip is an address of this host.
+func isLocalIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsUnspecified() {
+		return true
+	}
+	addrs, err := gonet.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLocalService tells whether dest is a service of this host other than our own inbounds: an
+// address of this host, or localhost.
+func IsLocalService(dest net.Destination) bool {
+	switch {
+	case dest.Address.Family().IsDomain():
+		if !strings.EqualFold(strings.TrimSuffix(dest.Address.Domain(), "."), "localhost") {
+			return false
+		}
+		dest.Address = net.LocalHostIP
+	case !isLocalIP(dest.Address.IP()):
+		return false
+	}
+	return !isReserved(dest)
+}
+
+// checkLoop fails conn, dialed with ctx, if it's dialed to our own inbounds too many times in a
+// row. Otherwise the inbound accepting it learns how many times it went through them already.
+func checkLoop(ctx context.Context, conn net.Conn) error {
+	remote := net.DestinationFromAddr(conn.RemoteAddr())
+	if !isReserved(remote) {
+		return nil
+	}
+	hops := loopHops(ctx) + 1
+	if hops > maxLoopHops {
+		return errors.New("loop detected: the connection was dialed to our own inbounds ", maxLoopHops, " times already, and again to ", remote)
+	}
+	errors.LogDebug(ctx, "dialing our own inbound at ", remote)
+
+	r := &localReservations
+	r.Lock()
+	defer r.Unlock()
+	now := time.Now()
+	if now.Sub(r.purged) > selfDialTimeout {
+		for local, dial := range r.dials {
+			if now.Sub(dial.time) > selfDialTimeout {
+				delete(r.dials, local)
+			}
+		}
+		r.purged = now
+	}
+	local := net.DestinationFromAddr(conn.LocalAddr())
+	if ip := local.Address; ip == net.AnyIP || ip == net.AnyIPv6 {
+		// Unconnected sockets, as of UDP, are seen from the address they send to.
+		local.Address = remote.Address
+	}
+	r.dials[local] = selfDial{hops: hops, time: now}
+	return nil
+}
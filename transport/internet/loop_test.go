@@ -0,0 +1,97 @@
+package internet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/common/net"
+)
+
+type loopConn struct {
+	net.Conn
+	local, remote net.Addr
+}
+
+func (c *loopConn) LocalAddr() net.Addr  { return c.local }
+func (c *loopConn) RemoteAddr() net.Addr { return c.remote }
+func (c *loopConn) Close() error         { return nil }
+
+func TestCheckLoop(t *testing.T) {
+	release := ReserveLocalAddress(net.TCPDestination(net.AnyIP, 23456))
+	defer release()
+
+	other := &loopConn{
+		local:  &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 40000},
+		remote: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 23457},
+	}
+	if err := checkLoop(context.Background(), other); err != nil {
+		t.Error(err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < maxLoopHops; i++ {
+		conn := &loopConn{
+			local:  &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 40000 + i},
+			remote: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 23456},
+		}
+		if err := checkLoop(ctx, conn); err != nil {
+			t.Fatal(err)
+		}
+		// The inbound accepts the connection, and dials again.
+		ctx = ContextWithLoopSource(context.Background(), net.DestinationFromAddr(conn.LocalAddr()))
+	}
+	conn := &loopConn{
+		local:  &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 50000},
+		remote: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 23456},
+	}
+	if err := checkLoop(ctx, conn); err == nil {
+		t.Error("expected a loop")
+	}
+
+	release()
+	if err := checkLoop(ctx, conn); err != nil {
+		t.Error("unexpected loop after release: ", err)
+	}
+}
+
+func TestIsLocalService(t *testing.T) {
+	release := ReserveLocalAddress(net.TCPDestination(net.AnyIP, 23458))
+	defer release()
+
+	for dest, local := range map[net.Destination]bool{
+		net.TCPDestination(net.LocalHostIP, 8080):                  true,
+		net.TCPDestination(net.LocalHostIPv6, 8080):                true,
+		net.TCPDestination(net.DomainAddress("LocalHost."), 8080):  true,
+		net.TCPDestination(net.LocalHostIP, 23458):                 false,
+		net.TCPDestination(net.DomainAddress("localhost"), 23458):  false,
+		net.TCPDestination(net.ParseAddress("8.8.8.8"), 8080):      false,
+		net.TCPDestination(net.DomainAddress("example.com"), 8080): false,
+	} {
+		if IsLocalService(dest) != local {
+			t.Error("unexpected local service of ", dest, ", expected ", local)
+		}
+	}
+}
+
+func TestIsOwnTransport(t *testing.T) {
+	release := ReserveLocalAddress(net.UDPDestination(net.AnyIP, 23459))
+	defer release()
+
+	conn := &loopConn{
+		local:  &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 40100},
+		remote: &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 23459},
+	}
+	if err := checkLoop(context.Background(), conn); err != nil {
+		t.Fatal(err)
+	}
+	if !IsOwnTransport(ContextWithLoopSource(context.Background(), net.DestinationFromAddr(conn.LocalAddr()))) {
+		t.Error("expected the packets of our own transport")
+	}
+	other := net.UDPDestination(net.LocalHostIP, 40101)
+	if IsOwnTransport(ContextWithLoopSource(context.Background(), other)) {
+		t.Error("unexpected own transport from ", other)
+	}
+	if IsOwnTransport(context.Background()) {
+		t.Error("unexpected own transport without source")
+	}
+}
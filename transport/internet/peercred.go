@@ -0,0 +1,35 @@
+package internet
+
+import (
+	"context"
+	"slices"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+// peerCredListener closes the connections of peers on unix sockets whose UIDs aren't allowed,
+// so that other users of the host can't inject traffic into inbounds.
+type peerCredListener struct {
+	net.Listener
+	uids []uint32
+}
+
+func (l *peerCredListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		uid, err := peerUID(conn)
+		if err == nil && slices.Contains(l.uids, uid) {
+			return conn, nil
+		}
+		conn.Close()
+		if err != nil {
+			errors.LogWarningInner(context.Background(), err, "failed to get the credentials of peer on ", l.Addr())
+		} else {
+			errors.LogWarning(context.Background(), "rejected peer of uid ", uid, " on ", l.Addr())
+		}
+	}
+}
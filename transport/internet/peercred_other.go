@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package internet
+
+import (
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+const peerCredSupported = false
+
+func peerUID(conn net.Conn) (uint32, error) {
+	return 0, errors.New("peer credentials are only checked on Linux")
+}
@@ -0,0 +1,140 @@
+// Package pmtu searches for the largest packets or chunks of a transport which get through a
+// network path, the way path MTU discovery does for IP packets. Censors dropping or throttling
+// the larger packets of obfuscated transports don't send the errors path MTU discovery relies
+// on, so transports report which sizes got through and which didn't, and size their framing by
+// the outcome.
+package pmtu
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+const (
+	// reprobeInterval is how long a failed size is remembered. Paths change, and larger sizes
+	// are tried again after it, as in RFC 1191.
+	reprobeInterval = 10 * time.Minute
+	// maxPaths caps the paths remembered, past which the ones unused for a reprobe interval
+	// are forgotten.
+	maxPaths = 1024
+)
+
+// path is what is known of the sizes which get through a network path.
+type path struct {
+	good     int32 // largest size which got through
+	bad      int32 // smallest size which didn't, 0 if none
+	failedAt time.Time
+	usedAt   time.Time
+}
+
+// Prober keeps the sizes which get through the paths of a transport, and picks the size to use
+// on each by binary search, between the largest size which got through and the smallest one
+// which didn't.
+type Prober struct {
+	name string
+	min  int32
+
+	access sync.Mutex
+	paths  map[string]*path
+	now    func() time.Time
+}
+
+// NewProber creates a Prober for the transport of the given name, which never goes below min.
+func NewProber(name string, min int32) *Prober {
+	return &Prober{
+		name:  name,
+		min:   min,
+		paths: make(map[string]*path),
+		now:   time.Now,
+	}
+}
+
+// Size returns the size to use on the path, at most max.
+func (p *Prober) Size(key string, max int32) int32 {
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	s := p.get(key)
+	size := max
+	if s.bad != 0 {
+		size = s.good + (s.bad-s.good)/2
+		if s.bad-s.good <= precision(s.bad) {
+			size = s.good
+		}
+	}
+	if size > max {
+		size = max
+	}
+	if size < p.min {
+		size = p.min
+	}
+	return size
+}
+
+// Succeeded records that size got through the path.
+func (p *Prober) Succeeded(key string, size int32) {
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	s := p.get(key)
+	if size > s.good {
+		s.good = size
+	}
+	if s.bad != 0 && size >= s.bad {
+		// The path changed.
+		s.bad = 0
+	}
+}
+
+// Failed records that size didn't get through the path.
+func (p *Prober) Failed(key string, size int32) {
+	if size <= p.min {
+		return
+	}
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	s := p.get(key)
+	if size <= s.good {
+		// The path changed.
+		s.good = p.min
+	}
+	if s.bad == 0 || size < s.bad {
+		s.bad = size
+		errors.LogInfo(context.Background(), p.name, " size ", size, " doesn't get through ", key, ", probing below it")
+	}
+	s.failedAt = p.now()
+}
+
+// get returns the path of key, forgetting failures older than the reprobe interval.
+func (p *Prober) get(key string) *path {
+	now := p.now()
+	s := p.paths[key]
+	if s == nil {
+		if len(p.paths) >= maxPaths {
+			for k, v := range p.paths {
+				if now.Sub(v.usedAt) > reprobeInterval {
+					delete(p.paths, k)
+				}
+			}
+		}
+		s = &path{good: p.min}
+		p.paths[key] = s
+	}
+	if s.bad != 0 && now.Sub(s.failedAt) > reprobeInterval {
+		s.bad = 0
+	}
+	s.usedAt = now
+	return s
+}
+
+// precision is the gap between sizes at which the search settles on the smaller one.
+func precision(size int32) int32 {
+	if size < 256 {
+		return 8
+	}
+	return size / 32
+}
@@ -0,0 +1,79 @@
+package pmtu
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProberSearch(t *testing.T) {
+	p := NewProber("test", 500)
+	const path = "1.2.3.4:443"
+	const limit = 1300 // largest size getting through
+
+	if size := p.Size(path, 1400); size != 1400 {
+		t.Fatal("expect max size on a new path, but got ", size)
+	}
+	size := int32(1400)
+	for i := 0; i < 16; i++ {
+		if size > limit {
+			p.Failed(path, size)
+		} else {
+			p.Succeeded(path, size)
+		}
+		size = p.Size(path, 1400)
+	}
+	if size > limit || size < limit-limit/32 {
+		t.Error("expect the search to settle just below ", limit, ", but got ", size)
+	}
+	if other := p.Size("5.6.7.8:443", 1400); other != 1400 {
+		t.Error("expect other paths not to be affected, but got ", other)
+	}
+	if small := p.Size(path, 800); small != 800 {
+		t.Error("expect size capped by max, but got ", small)
+	}
+}
+
+func TestProberMin(t *testing.T) {
+	p := NewProber("test", 500)
+	const path = "1.2.3.4:443"
+	for i := 0; i < 16; i++ {
+		p.Failed(path, p.Size(path, 1400))
+	}
+	if size := p.Size(path, 1400); size != 500 {
+		t.Error("expect min size, but got ", size)
+	}
+}
+
+func TestProberReprobe(t *testing.T) {
+	now := time.Now()
+	p := NewProber("test", 500)
+	p.now = func() time.Time { return now }
+	const path = "1.2.3.4:443"
+
+	p.Succeeded(path, 1000)
+	p.Failed(path, 1400)
+	if size := p.Size(path, 1400); size != 1200 {
+		t.Error("expect size between 1000 and 1400, but got ", size)
+	}
+
+	now = now.Add(reprobeInterval + time.Second)
+	if size := p.Size(path, 1400); size != 1400 {
+		t.Error("expect max size after the reprobe interval, but got ", size)
+	}
+}
+
+func TestProberPathChange(t *testing.T) {
+	p := NewProber("test", 500)
+	const path = "1.2.3.4:443"
+
+	p.Succeeded(path, 1000)
+	p.Failed(path, 1200)
+	p.Failed(path, 900)
+	if size := p.Size(path, 1400); size >= 900 {
+		t.Error("expect size below 900, but got ", size)
+	}
+	p.Succeeded(path, 1400)
+	if size := p.Size(path, 1400); size != 1400 {
+		t.Error("expect max size, but got ", size)
+	}
+}
@@ -0,0 +1,108 @@
+package internet
+
+import (
+	gonet "net"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+// ProxyTrust tells the peers trusted to forward the addresses of clients, by the PROXY protocol
+// or X-Forwarded-For, from the others, whose forwarded addresses are ignored so that clients
+// can't spoof theirs to routing, stats and bans. A nil ProxyTrust trusts all peers, as inbounds
+// without trusted proxies always did.
+type ProxyTrust struct {
+	nets []*net.IPNet
+}
+
+// NewProxyTrust returns the trust of the trusted proxies in config, IPs or CIDRs, or nil if
+// there are none.
+func NewProxyTrust(config *SocketConfig) (*ProxyTrust, error) {
+	if config == nil || len(config.TrustedProxies) == 0 {
+		return nil, nil
+	}
+	t := &ProxyTrust{}
+	for _, s := range config.TrustedProxies {
+		_, ipNet, err := gonet.ParseCIDR(s)
+		if err != nil {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, errors.New("invalid trusted proxy ", s).Base(err)
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			ipNet = &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)}
+		}
+		t.nets = append(t.nets, ipNet)
+	}
+	return t, nil
+}
+
+func (t *ProxyTrust) trustsIP(ip net.IP) bool {
+	for _, n := range t.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Trusts returns whether addr, of a peer, may forward the addresses of clients. Peers on unix
+// sockets are local, and always trusted.
+func (t *ProxyTrust) Trusts(addr net.Addr) bool {
+	if t == nil {
+		return true
+	}
+	switch addr := addr.(type) {
+	case *net.UnixAddr:
+		return true
+	case *net.TCPAddr:
+		return t.trustsIP(addr.IP)
+	case *net.UDPAddr:
+		return t.trustsIP(addr.IP)
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && t.trustsIP(ip)
+}
+
+// ProxyPolicy is the policy of PROXY protocol headers from upstream: required of trusted peers,
+// and ignored from the others.
+func (t *ProxyTrust) ProxyPolicy(upstream net.Addr) (proxyproto.Policy, error) {
+	if t.Trusts(upstream) {
+		return proxyproto.REQUIRE, nil
+	}
+	return proxyproto.IGNORE, nil
+}
+
+// ClientAddr returns the address of the client behind peer, given the addresses in the
+// X-Forwarded-For header of its request. Without trusted proxies it's the first forwarded
+// address, as before. With them, forwarded addresses count only from trusted peers, and the
+// client is the last one not of a trusted proxy, as clients may put anything in front.
+func (t *ProxyTrust) ClientAddr(peer net.Addr, forwarded []net.Address) net.Addr {
+	if t == nil {
+		if len(forwarded) > 0 && forwarded[0].Family().IsIP() {
+			return &net.TCPAddr{IP: forwarded[0].IP()}
+		}
+		return peer
+	}
+	if !t.Trusts(peer) {
+		return peer
+	}
+	client := peer
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		if !forwarded[i].Family().IsIP() {
+			break
+		}
+		client = &net.TCPAddr{IP: forwarded[i].IP()}
+		if !t.trustsIP(forwarded[i].IP()) {
+			break
+		}
+	}
+	return client
+}
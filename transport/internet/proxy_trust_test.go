@@ -0,0 +1,46 @@
+package internet_test
+
+import (
+	"net"
+	"testing"
+
+	xnet "github.com/xtls/xray-core/common/net"
+	. "github.com/xtls/xray-core/transport/internet"
+)
+
+func TestProxyTrust(t *testing.T) {
+	if trust, err := NewProxyTrust(&SocketConfig{}); trust != nil || err != nil {
+		t.Error("expected no trust, got ", trust, err)
+	}
+	if _, err := NewProxyTrust(&SocketConfig{TrustedProxies: []string{"10.0.0.0/33"}}); err == nil {
+		t.Error("expected error of invalid CIDR")
+	}
+
+	forwarded := []xnet.Address{xnet.ParseAddress("1.1.1.1"), xnet.ParseAddress("2.2.2.2"), xnet.ParseAddress("10.0.0.2")}
+	proxy := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 443}
+	other := &net.TCPAddr{IP: net.ParseIP("3.3.3.3"), Port: 443}
+
+	// Without trusted proxies, the first forwarded address is taken from anyone.
+	var none *ProxyTrust
+	if addr := none.ClientAddr(other, forwarded); addr.String() != "1.1.1.1:0" {
+		t.Error("unexpected client ", addr)
+	}
+
+	trust, err := NewProxyTrust(&SocketConfig{TrustedProxies: []string{"10.0.0.0/24", "::1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !trust.Trusts(proxy) || trust.Trusts(other) || !trust.Trusts(&net.TCPAddr{IP: net.ParseIP("::1")}) {
+		t.Error("unexpected trust")
+	}
+	if addr := trust.ClientAddr(other, forwarded); addr != other {
+		t.Error("expected forwarded addresses of untrusted peer to be ignored, got ", addr)
+	}
+	// 1.1.1.1 may be spoofed by the client at 2.2.2.2, in front of the trusted 10.0.0.2.
+	if addr := trust.ClientAddr(proxy, forwarded); addr.String() != "2.2.2.2:0" {
+		t.Error("unexpected client ", addr)
+	}
+	if addr := trust.ClientAddr(proxy, nil); addr != proxy {
+		t.Error("unexpected client ", addr)
+	}
+}
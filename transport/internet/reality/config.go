@@ -13,6 +13,10 @@ import (
 )
 
 func (c *Config) GetREALITYConfig() *reality.Config {
+	return c.newREALITYConfig(c.PrivateKey, c.ShortIds, KeyLogWriterFromConfig(c))
+}
+
+func (c *Config) newREALITYConfig(privateKey []byte, shortIds [][]byte, keyLog io.Writer) *reality.Config {
 	var dialer net.Dialer
 	config := &reality.Config{
 		DialContext: dialer.DialContext,
@@ -22,7 +26,7 @@ func (c *Config) GetREALITYConfig() *reality.Config {
 		Dest: c.Dest,
 		Xver: byte(c.Xver),
 
-		PrivateKey:   c.PrivateKey,
+		PrivateKey:   privateKey,
 		MinClientVer: c.MinClientVer,
 		MaxClientVer: c.MaxClientVer,
 		MaxTimeDiff:  time.Duration(c.MaxTimeDiff) * time.Millisecond,
@@ -30,14 +34,14 @@ func (c *Config) GetREALITYConfig() *reality.Config {
 		NextProtos:             nil, // should be nil
 		SessionTicketsDisabled: true,
 
-		KeyLogWriter: KeyLogWriterFromConfig(c),
+		KeyLogWriter: keyLog,
 	}
 	config.ServerNames = make(map[string]bool)
 	for _, serverName := range c.ServerNames {
 		config.ServerNames[serverName] = true
 	}
 	config.ShortIds = make(map[[8]byte]bool)
-	for _, shortId := range c.ShortIds {
+	for _, shortId := range shortIds {
 		config.ShortIds[*(*[8]byte)(shortId)] = true
 	}
 	return config
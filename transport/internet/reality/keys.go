@@ -0,0 +1,284 @@
+package reality
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"io"
+	"time"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/xtls/reality"
+	"github.com/xtls/xray-core/common/net"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	recordTypeHandshake    = 22
+	typeClientHello        = 1
+	extensionKeyShare      = 51
+	curveX25519            = 29
+	maxClientHelloRecord   = 16384 + 2048
+	realitySessionIDLength = 32
+	// sessionIDOffset is where the session ID is in a ClientHello message, after its type, length,
+	// version, random and the length of the session ID.
+	sessionIDOffset = 1 + 3 + 2 + 32 + 1
+)
+
+// key is the REALITY config of a key a server accepts, with its validity.
+type key struct {
+	config    *reality.Config
+	notBefore int64
+	notAfter  int64
+}
+
+func (k *key) valid(now int64) bool {
+	return (k.notBefore == 0 || now >= k.notBefore) && (k.notAfter == 0 || now <= k.notAfter)
+}
+
+// Keys is the REALITY configs of the keys a server accepts. While several keys are valid, as when
+// they are rotated, the ClientHello of each connection is read ahead to find the key its client
+// uses.
+type Keys struct {
+	keys []*key
+	// expired forwards every connection to the target, for when no key is valid.
+	expired *reality.Config
+}
+
+// GetREALITYKeys returns the REALITY configs of the private key and of the keys of c. A key without
+// short IDs accepts those of c.
+func (c *Config) GetREALITYKeys() *Keys {
+	keyLog := KeyLogWriterFromConfig(c)
+	k := new(Keys)
+	if len(c.PrivateKey) > 0 {
+		k.keys = append(k.keys, &key{config: c.newREALITYConfig(c.PrivateKey, c.ShortIds, keyLog)})
+	}
+	for _, rk := range c.Keys {
+		shortIds := rk.ShortIds
+		if len(shortIds) == 0 {
+			shortIds = c.ShortIds
+		}
+		k.keys = append(k.keys, &key{
+			config:    c.newREALITYConfig(rk.PrivateKey, shortIds, keyLog),
+			notBefore: rk.NotBefore,
+			notAfter:  rk.NotAfter,
+		})
+	}
+	privateKey := c.PrivateKey
+	if len(privateKey) == 0 && len(c.Keys) > 0 {
+		privateKey = c.Keys[0].PrivateKey
+	}
+	k.expired = c.newREALITYConfig(privateKey, nil, keyLog)
+	return k
+}
+
+// Server handshakes conn with REALITY, with the key its client uses.
+func (k *Keys) Server(conn net.Conn) (net.Conn, error) {
+	config, conn := k.pick(conn, time.Now().Unix())
+	return Server(conn, config)
+}
+
+// pick returns the config of the valid key the client of conn uses, or of the first one if none, and
+// the connection to handshake, which reads again what was read ahead.
+func (k *Keys) pick(conn net.Conn, now int64) (*reality.Config, net.Conn) {
+	var valid []*reality.Config
+	for _, key := range k.keys {
+		if key.valid(now) {
+			valid = append(valid, key.config)
+		}
+	}
+	switch len(valid) {
+	case 0:
+		return k.expired, conn
+	case 1:
+		return valid[0], conn
+	}
+	record, err := readRecord(conn)
+	conn = &replayConn{Conn: conn, buffered: record}
+	if err != nil {
+		return valid[0], conn
+	}
+	for _, config := range valid {
+		if authenticates(record, config.PrivateKey) {
+			return config, conn
+		}
+	}
+	return valid[0], conn
+}
+
+// readRecord reads the first TLS record of conn, or as much of it as was sent.
+func readRecord(conn net.Conn) ([]byte, error) {
+	header := make([]byte, 5)
+	if n, err := io.ReadFull(conn, header); err != nil {
+		return header[:n], err
+	}
+	length := int(header[3])<<8 | int(header[4])
+	if header[0] != recordTypeHandshake || length > maxClientHelloRecord {
+		return header, io.ErrUnexpectedEOF
+	}
+	record := make([]byte, 5+length)
+	copy(record, header)
+	n, err := io.ReadFull(conn, record[5:])
+	return record[:5+n], err
+}
+
+// authenticates tells whether the ClientHello in record carries a session ID sealed with the key
+// the client shares with privateKey, as REALITY clients send.
+func authenticates(record []byte, privateKey []byte) bool {
+	raw := record[5:]
+	s := cryptobyte.String(raw)
+	var msgType uint8
+	var body cryptobyte.String
+	if !s.ReadUint8(&msgType) || msgType != typeClientHello || !s.ReadUint24LengthPrefixed(&body) {
+		return false
+	}
+	raw = raw[:4+len(body)]
+	var random, sessionID, cipherSuites, compressionMethods, extensions []byte
+	if !body.Skip(2) || !body.ReadBytes(&random, 32) ||
+		!body.ReadUint8LengthPrefixed((*cryptobyte.String)(&sessionID)) || len(sessionID) != realitySessionIDLength ||
+		!body.ReadUint16LengthPrefixed((*cryptobyte.String)(&cipherSuites)) ||
+		!body.ReadUint8LengthPrefixed((*cryptobyte.String)(&compressionMethods)) ||
+		!body.ReadUint16LengthPrefixed((*cryptobyte.String)(&extensions)) {
+		return false
+	}
+	share := keyShareX25519(extensions)
+	if share == nil {
+		return false
+	}
+	authKey, err := curve25519.X25519(privateKey, share)
+	if err != nil {
+		return false
+	}
+	if _, err := hkdf.New(sha256.New, authKey, random[:20], []byte("REALITY")).Read(authKey); err != nil {
+		return false
+	}
+	suites := make([]uint16, 0, len(cipherSuites)/2)
+	for i := 0; i+1 < len(cipherSuites); i += 2 {
+		suites = append(suites, uint16(cipherSuites[i])<<8|uint16(cipherSuites[i+1]))
+	}
+	var aead cipher.AEAD
+	if aesgcmPreferred(suites) {
+		block, _ := aes.NewCipher(authKey)
+		aead, _ = cipher.NewGCM(block)
+	} else {
+		aead, _ = chacha20poly1305.New(authKey)
+	}
+	// The session ID is sealed with the ClientHello as additional data, with the session ID zeroed.
+	ciphertext := append([]byte(nil), sessionID...)
+	aad := append([]byte(nil), raw...)
+	clear(aad[sessionIDOffset : sessionIDOffset+realitySessionIDLength])
+	_, err = aead.Open(nil, random[20:], ciphertext, aad)
+	return err == nil
+}
+
+// keyShareX25519 returns the X25519 key share among extensions, as REALITY servers use it.
+func keyShareX25519(extensions []byte) []byte {
+	s := cryptobyte.String(extensions)
+	for !s.Empty() {
+		var typ uint16
+		var data cryptobyte.String
+		if !s.ReadUint16(&typ) || !s.ReadUint16LengthPrefixed(&data) {
+			return nil
+		}
+		if typ != extensionKeyShare {
+			continue
+		}
+		var shares cryptobyte.String
+		if !data.ReadUint16LengthPrefixed(&shares) {
+			return nil
+		}
+		for !shares.Empty() {
+			var group uint16
+			var share []byte
+			if !shares.ReadUint16(&group) || !shares.ReadUint16LengthPrefixed((*cryptobyte.String)(&share)) {
+				return nil
+			}
+			if group == curveX25519 && len(share) == 32 {
+				return share
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// replayConn reads the bytes read ahead from a connection before the rest of them.
+type replayConn struct {
+	net.Conn
+	buffered []byte
+}
+
+func (c *replayConn) Read(b []byte) (int, error) {
+	if len(c.buffered) > 0 {
+		n := copy(b, c.buffered)
+		c.buffered = c.buffered[n:]
+		return n, nil
+	}
+	return c.Conn.Read(b)
+}
+
+// CloseWrite is needed by REALITY to forward the connections of other clients to the target.
+func (c *replayConn) CloseWrite() error {
+	conn := c.Conn
+	if pc, ok := conn.(*proxyproto.Conn); ok {
+		conn = pc.Raw()
+	}
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return conn.Close()
+}
+
+// NetConn returns the connection under c once the bytes read ahead are read again, so that it can
+// be spliced.
+func (c *Conn) NetConn() net.Conn {
+	conn := c.Conn.NetConn()
+	if rc, ok := conn.(*replayConn); ok && len(rc.buffered) == 0 {
+		return rc.Conn
+	}
+	return conn
+}
+
+// NewListener returns a listener of the connections of inner handshaked with REALITY, as
+// reality.NewListener does, with the key each client uses.
+func NewListener(inner net.Listener, keys *Keys) net.Listener {
+	l := &listener{
+		Listener: inner,
+		conns:    make(chan net.Conn),
+	}
+	go func() {
+		for {
+			c, err := inner.Accept()
+			if err != nil {
+				l.err = err
+				close(l.conns)
+				return
+			}
+			go func() {
+				defer func() { recover() }()
+				config, c := keys.pick(c, time.Now().Unix())
+				if conn, err := reality.Server(context.Background(), c, config); err == nil {
+					l.conns <- conn
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+type listener struct {
+	net.Listener
+	conns chan net.Conn
+	err   error
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	if c, ok := <-l.conns; ok {
+		return c, nil
+	}
+	return nil, l.err
+}
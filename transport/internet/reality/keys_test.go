@@ -0,0 +1,65 @@
+package reality
+
+import (
+	"context"
+	"crypto/rand"
+	gonet "net"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"golang.org/x/crypto/curve25519"
+)
+
+func newKeyPair() ([]byte, []byte) {
+	privateKey := make([]byte, curve25519.ScalarSize)
+	common.Must2(rand.Read(privateKey))
+	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+	common.Must(err)
+	return privateKey, publicKey
+}
+
+func TestKeysPick(t *testing.T) {
+	oldKey, _ := newKeyPair()
+	newKey, newPublicKey := newKeyPair()
+	shortId := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	now := time.Now().Unix()
+	config := &Config{
+		Dest:        "127.0.0.1:443",
+		Type:        "tcp",
+		ServerNames: []string{"example.com"},
+		PrivateKey:  oldKey,
+		ShortIds:    [][]byte{shortId},
+		Keys: []*Key{
+			{PrivateKey: newKey, NotBefore: now - 60},
+			{PrivateKey: newKey, NotBefore: now + 3600},
+		},
+	}
+	keys := config.GetREALITYKeys()
+
+	client, server := gonet.Pipe()
+	defer client.Close()
+	defer server.Close()
+	go UClient(client, &Config{
+		Fingerprint: "chrome",
+		ServerName:  "example.com",
+		PublicKey:   newPublicKey,
+		ShortId:     shortId,
+	}, context.Background(), net.TCPDestination(net.DomainAddress("example.com"), 443))
+
+	picked, conn := keys.pick(server, now)
+	if picked != keys.keys[1].config {
+		t.Error("picked the config of another key than the client's")
+	}
+	// The ClientHello read ahead is read again by the handshake.
+	b := make([]byte, 5)
+	common.Must2(conn.Read(b))
+	if b[0] != recordTypeHandshake {
+		t.Error("record not replayed: ", b)
+	}
+
+	if picked, conn := keys.pick(server, now-120); picked != keys.keys[0].config || conn != server {
+		t.Error("read ahead with a single valid key")
+	}
+}
@@ -1,5 +1,7 @@
 package internet
 
+import "net"
+
 func isTCPSocket(network string) bool {
 	switch network {
 	case "tcp", "tcp4", "tcp6":
@@ -28,3 +30,22 @@ func (v *SocketConfig) ParseTFOValue() int {
 	}
 	return tfo
 }
+
+// maxSegment returns the MSS for TCP connections to address, or 0 to leave it to the system.
+// tcp_max_seg wins over the MSS derived from the MTU, which leaves room for the IPv6 header
+// unless address is an IPv4 one.
+func (v *SocketConfig) maxSegment(address string) int {
+	if v.TcpMaxSeg > 0 {
+		return int(v.TcpMaxSeg)
+	}
+	if v.Mtu <= 0 {
+		return 0
+	}
+	overhead := 60 // IPv6 and TCP headers
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		if ip := net.ParseIP(host); ip != nil && ip.To4() != nil {
+			overhead = 40
+		}
+	}
+	return int(v.Mtu) - overhead
+}
@@ -136,6 +136,12 @@ func applyOutboundSocketOptions(network string, address string, fd uintptr, conf
 				return errors.New("failed to unset SO_KEEPALIVE", err)
 			}
 		}
+
+		if mss := config.maxSegment(address); mss > 0 {
+			if err := unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_MAXSEG, mss); err != nil {
+				return errors.New("failed to set TCP_MAXSEG", err)
+			}
+		}
 	}
 
 	return nil
@@ -180,6 +186,12 @@ func applyInboundSocketOptions(network string, fd uintptr, config *SocketConfig)
 				return errors.New("failed to unset SO_KEEPALIVE", err)
 			}
 		}
+
+		if mss := config.maxSegment(""); mss > 0 {
+			if err := unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_MAXSEG, mss); err != nil {
+				return errors.New("failed to set TCP_MAXSEG", err)
+			}
+		}
 	}
 
 	return nil
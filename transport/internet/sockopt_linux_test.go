@@ -2,8 +2,11 @@ package internet_test
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/net"
@@ -40,3 +43,55 @@ func TestSockOptMark(t *testing.T) {
 	})
 	common.Must(err)
 }
+
+func TestUnixSocketPeerUids(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "xray.sock")
+	uid := uint32(os.Getuid())
+	l, err := ListenSystem(context.Background(), &net.UnixAddr{Name: path, Net: "unix"}, &SocketConfig{
+		PeerUids: []uint32{uid + 1},
+		FileMode: 0o600,
+	})
+	common.Must(err)
+	defer l.Close()
+
+	info, err := os.Stat(path)
+	common.Must(err)
+	if info.Mode().Perm() != 0o600 {
+		t.Error("unexpected file mode ", info.Mode().Perm())
+	}
+
+	accepted := make(chan struct{})
+	go func() {
+		if conn, err := l.Accept(); err == nil {
+			conn.Close()
+			close(accepted)
+		}
+	}()
+	conn, err := net.Dial("unix", path)
+	common.Must(err)
+	defer conn.Close()
+
+	// The peer of another UID is closed instead of accepted.
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("expected the connection to be closed")
+	}
+	select {
+	case <-accepted:
+		t.Error("accepted peer of uid ", uid)
+	default:
+	}
+
+	l2, err := ListenSystem(context.Background(), &net.UnixAddr{Name: path + "2", Net: "unix"}, &SocketConfig{PeerUids: []uint32{uid}})
+	common.Must(err)
+	defer l2.Close()
+	go func() {
+		if conn, err := net.Dial("unix", path+"2"); err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+	conn2, err := l2.Accept()
+	common.Must(err)
+	conn2.Close()
+}
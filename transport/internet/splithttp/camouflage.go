@@ -0,0 +1,179 @@
+package splithttp
+
+import (
+	"encoding/binary"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/http2"
+)
+
+// camouflageProfile makes the requests of the client look like the ones of a browser, to those
+// fingerprinting HTTP clients by their headers and HTTP/2 SETTINGS.
+//
+// Only what can be changed without breaking flow control is: the window sizes, the order of
+// pseudo-headers and priorities are the ones of Go, and so is the whole of HTTP/3.
+type camouflageProfile struct {
+	// headers are set on requests unless configured in "headers".
+	headers [][2]string
+	// headerTableSize and maxHeaderListSize are announced in SETTINGS.
+	headerTableSize   uint32
+	maxHeaderListSize uint32
+	// settings is the order of the SETTINGS of the browser. Others are left out if they're
+	// advisory or at their defaults, or sent last otherwise.
+	settings []http2.SettingID
+}
+
+var camouflageProfiles = map[string]*camouflageProfile{
+	"chrome": {
+		headers: [][2]string{
+			{"Sec-Ch-Ua", `"Google Chrome";v="131", "Chromium";v="131", "Not_A Brand";v="24"`},
+			{"Sec-Ch-Ua-Mobile", "?0"},
+			{"Sec-Ch-Ua-Platform", `"Windows"`},
+			{"User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36"},
+			{"Accept", "*/*"},
+			{"Sec-Fetch-Site", "same-origin"},
+			{"Sec-Fetch-Mode", "cors"},
+			{"Sec-Fetch-Dest", "empty"},
+			{"Accept-Language", "en-US,en;q=0.9"},
+		},
+		headerTableSize:   65536,
+		maxHeaderListSize: 262144,
+		settings: []http2.SettingID{
+			http2.SettingHeaderTableSize,
+			http2.SettingEnablePush,
+			http2.SettingInitialWindowSize,
+			http2.SettingMaxHeaderListSize,
+		},
+	},
+	"firefox": {
+		headers: [][2]string{
+			{"User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:133.0) Gecko/20100101 Firefox/133.0"},
+			{"Accept", "*/*"},
+			{"Accept-Language", "en-US,en;q=0.5"},
+			{"Sec-Fetch-Dest", "empty"},
+			{"Sec-Fetch-Mode", "cors"},
+			{"Sec-Fetch-Site", "same-origin"},
+		},
+		headerTableSize: 65536,
+		settings: []http2.SettingID{
+			http2.SettingHeaderTableSize,
+			http2.SettingInitialWindowSize,
+			http2.SettingMaxFrameSize,
+		},
+	},
+}
+
+// profile returns the camouflage profile of the config, or nil if there's none.
+func (c *Config) profile() *camouflageProfile {
+	return camouflageProfiles[c.Camouflage]
+}
+
+// IsValidCamouflage tells whether name is a known camouflage profile, or empty.
+func IsValidCamouflage(name string) bool {
+	_, found := camouflageProfiles[name]
+	return name == "" || found
+}
+
+func (p *camouflageProfile) setHeaders(header http.Header) {
+	for _, h := range p.headers {
+		if header.Get(h[0]) == "" {
+			header.Set(h[0], h[1])
+		}
+	}
+}
+
+// setupTransport sets the SETTINGS of the profile which the transport knows about.
+func (p *camouflageProfile) setupTransport(t *http2.Transport) {
+	if p.headerTableSize != 0 {
+		t.MaxDecoderHeaderTableSize = p.headerTableSize
+	}
+	if p.maxHeaderListSize != 0 {
+		t.MaxHeaderListSize = p.maxHeaderListSize
+	}
+}
+
+// wrapConn returns conn reordering the SETTINGS sent after the client preface as the profile.
+func (p *camouflageProfile) wrapConn(conn net.Conn) net.Conn {
+	return &settingsConn{Conn: conn, profile: p}
+}
+
+// settingsConn rewrites the first SETTINGS frame written to it, which follows the client preface.
+type settingsConn struct {
+	net.Conn
+	profile *camouflageProfile
+	pending []byte
+	done    bool
+}
+
+func (c *settingsConn) Write(b []byte) (int, error) {
+	if c.done {
+		return c.Conn.Write(b)
+	}
+	c.pending = append(c.pending, b...)
+	prefaceLen := len(http2.ClientPreface)
+	if len(c.pending) < prefaceLen+9 {
+		return len(b), nil
+	}
+	length := int(c.pending[prefaceLen])<<16 | int(c.pending[prefaceLen+1])<<8 | int(c.pending[prefaceLen+2])
+	end := prefaceLen + 9 + length
+	if len(c.pending) < end {
+		return len(b), nil
+	}
+	c.done = true
+	pending := c.pending
+	c.pending = nil
+	if string(pending[:prefaceLen]) == http2.ClientPreface && http2.FrameType(pending[prefaceLen+3]) == http2.FrameSettings {
+		settings := c.profile.reorder(pending[prefaceLen+9 : end])
+		frame := make([]byte, 0, len(pending)-length+len(settings))
+		frame = append(frame, pending[:prefaceLen+9]...)
+		frame[prefaceLen], frame[prefaceLen+1], frame[prefaceLen+2] = byte(len(settings)>>16), byte(len(settings)>>8), byte(len(settings))
+		frame = append(frame, settings...)
+		pending = append(frame, pending[end:]...)
+	}
+	if _, err := c.Conn.Write(pending); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// reorder returns the payload of a SETTINGS frame in the order of the profile.
+func (p *camouflageProfile) reorder(payload []byte) []byte {
+	values := make(map[http2.SettingID]uint32)
+	var ids []http2.SettingID
+	for i := 0; i+6 <= len(payload); i += 6 {
+		id := http2.SettingID(binary.BigEndian.Uint16(payload[i:]))
+		if _, found := values[id]; !found {
+			ids = append(ids, id)
+		}
+		values[id] = binary.BigEndian.Uint32(payload[i+2:])
+	}
+
+	reordered := make([]byte, 0, len(payload))
+	add := func(id http2.SettingID) {
+		reordered = binary.BigEndian.AppendUint16(reordered, uint16(id))
+		reordered = binary.BigEndian.AppendUint32(reordered, values[id])
+		delete(values, id)
+	}
+	for _, id := range p.settings {
+		if _, found := values[id]; found {
+			add(id)
+		}
+	}
+	for _, id := range ids {
+		value, found := values[id]
+		if !found {
+			continue
+		}
+		switch {
+		case id == http2.SettingMaxHeaderListSize:
+			// Advisory only.
+		case id == http2.SettingMaxFrameSize && value == 16384,
+			id == http2.SettingHeaderTableSize && value == 4096:
+			// The defaults.
+		default:
+			add(id)
+		}
+	}
+	return reordered
+}
@@ -49,8 +49,19 @@ func (c *Config) GetRequestHeader(rawURL string) http.Header {
 	for k, v := range c.Headers {
 		header.Add(k, v)
 	}
+	if p := c.profile(); p != nil {
+		p.setHeaders(header)
+	}
 
 	u, _ := url.Parse(rawURL)
+	if sessionId := c.sessionIdOf(u.Path); sessionId != "" {
+		if c.AffinityHeader != "" {
+			header.Set(c.AffinityHeader, sessionId)
+		}
+		if c.AffinityCookie != "" {
+			header.Add("Cookie", (&http.Cookie{Name: c.AffinityCookie, Value: sessionId}).String())
+		}
+	}
 	// https://www.rfc-editor.org/rfc/rfc7541.html#appendix-B
 	// h2's HPACK Header Compression feature employs a huffman encoding using a static table.
 	// 'X' is assigned an 8 bit code, so HPACK compression won't change actual padding length on the wire.
@@ -62,6 +73,12 @@ func (c *Config) GetRequestHeader(rawURL string) http.Header {
 	return header
 }
 
+// sessionIdOf returns the session ID in the path of a request, or "" for stream-one.
+func (c *Config) sessionIdOf(path string) string {
+	sessionId, _, _ := strings.Cut(strings.TrimPrefix(path, c.GetNormalizedPath()), "/")
+	return sessionId
+}
+
 func (c *Config) WriteResponseHeader(writer http.ResponseWriter) {
 	// CORS headers for the browser dialer
 	writer.Header().Set("Access-Control-Allow-Origin", "*")
@@ -1,6 +1,7 @@
 package splithttp_test
 
 import (
+	"strings"
 	"testing"
 
 	. "github.com/xtls/xray-core/transport/internet/splithttp"
@@ -16,3 +17,39 @@ func Test_GetNormalizedPath(t *testing.T) {
 		t.Error("Unexpected: ", path)
 	}
 }
+
+func Test_GetRequestHeaderWithCamouflage(t *testing.T) {
+	c := Config{
+		Headers:    map[string]string{"Accept-Language": "de"},
+		Camouflage: "firefox",
+	}
+
+	header := c.GetRequestHeader("https://example.com/")
+	if ua := header.Get("User-Agent"); !strings.Contains(ua, "Firefox/") {
+		t.Error("Unexpected User-Agent: ", ua)
+	}
+	if lang := header.Get("Accept-Language"); lang != "de" {
+		t.Error("Unexpected Accept-Language: ", lang)
+	}
+}
+
+func Test_GetRequestHeaderWithAffinity(t *testing.T) {
+	c := Config{
+		Path:           "/path",
+		AffinityHeader: "X-Session",
+		AffinityCookie: "session",
+	}
+
+	header := c.GetRequestHeader("https://example.com/path/abcd/3")
+	if v := header.Get("X-Session"); v != "abcd" {
+		t.Error("Unexpected affinity header: ", v)
+	}
+	if v := header.Get("Cookie"); v != "session=abcd" {
+		t.Error("Unexpected affinity cookie: ", v)
+	}
+
+	header = c.GetRequestHeader("https://example.com/path/")
+	if v := header.Get("X-Session"); v != "" {
+		t.Error("Unexpected affinity header for stream-one: ", v)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/xtls/xray-core/common/uuid"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/browser_dialer"
+	"github.com/xtls/xray-core/transport/internet/pmtu"
 	"github.com/xtls/xray-core/transport/internet/reality"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/tls"
@@ -40,6 +41,10 @@ const quicgoH3KeepAlivePeriod = 10 * time.Second
 // consistent with chrome
 const chromeH2KeepAlivePeriod = 45 * time.Second
 
+// postSizeProber searches for the largest posts getting through the paths of packet-up
+// uploads set to scAutoMaxEachPostBytes.
+var postSizeProber = pmtu.NewProber("XHTTP", 2*buf.Size)
+
 type dialerConf struct {
 	net.Destination
 	*internet.MemoryStreamConfig
@@ -75,8 +80,12 @@ func getHTTPClient(ctx context.Context, dest net.Destination, streamSettings *in
 			xmuxConfig = *transportConfig.Xmux
 		}
 
+		var pin *addressPin
+		if transportConfig.PinAddress && (streamSettings.SocketSettings == nil || streamSettings.SocketSettings.DialerProxy == "") {
+			pin = getAddressPin(dest)
+		}
 		xmuxManager = NewXmuxManager(xmuxConfig, func() XmuxConn {
-			return createHTTPClient(dest, streamSettings)
+			return createHTTPClient(dest, streamSettings, pin)
 		})
 		globalDialerMap[key] = xmuxManager
 	}
@@ -104,7 +113,7 @@ func decideHTTPVersion(tlsConfig *tls.Config, realityConfig *reality.Config) str
 	return "2"
 }
 
-func createHTTPClient(dest net.Destination, streamSettings *internet.MemoryStreamConfig) DialerClient {
+func createHTTPClient(dest net.Destination, streamSettings *internet.MemoryStreamConfig, pin *addressPin) DialerClient {
 	tlsConfig := tls.ConfigFromStreamSettings(streamSettings)
 	realityConfig := reality.ConfigFromStreamSettings(streamSettings)
 
@@ -122,10 +131,12 @@ func createHTTPClient(dest net.Destination, streamSettings *internet.MemoryStrea
 	transportConfig := streamSettings.ProtocolSettings.(*Config)
 
 	dialContext := func(ctxInner context.Context) (net.Conn, error) {
-		conn, err := internet.DialSystem(ctxInner, dest, streamSettings.SocketSettings)
+		conn, err := internet.DialSystem(ctxInner, pin.destination(dest), streamSettings.SocketSettings)
 		if err != nil {
+			pin.update(dest, nil, err)
 			return nil, err
 		}
+		pin.update(dest, conn.RemoteAddr(), nil)
 
 		if realityConfig != nil {
 			return reality.UClient(conn, realityConfig, ctxInner, dest)
@@ -135,6 +146,7 @@ func createHTTPClient(dest net.Destination, streamSettings *internet.MemoryStrea
 			if fingerprint := tls.GetFingerprint(tlsConfig.Fingerprint); fingerprint != nil {
 				conn = tls.UClient(conn, gotlsConfig, fingerprint)
 				if err := conn.(*tls.UConn).HandshakeContext(ctxInner); err != nil {
+					pin.update(dest, nil, err)
 					return nil, err
 				}
 			} else {
@@ -172,10 +184,12 @@ func createHTTPClient(dest net.Destination, streamSettings *internet.MemoryStrea
 			QUICConfig:      quicConfig,
 			TLSClientConfig: gotlsConfig,
 			Dial: func(ctx context.Context, addr string, tlsCfg *gotls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
-				conn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+				conn, err := internet.DialSystem(ctx, pin.destination(dest), streamSettings.SocketSettings)
 				if err != nil {
+					pin.update(dest, nil, err)
 					return nil, err
 				}
+				pin.update(dest, conn.RemoteAddr(), nil)
 
 				var udpConn net.PacketConn
 				var udpAddr *net.UDPAddr
@@ -205,7 +219,11 @@ func createHTTPClient(dest net.Destination, streamSettings *internet.MemoryStrea
 					}
 				}
 
-				return quic.DialEarly(ctx, udpConn, udpAddr, tlsCfg, cfg)
+				qconn, err := quic.DialEarly(ctx, udpConn, udpAddr, tlsCfg, cfg)
+				if err != nil {
+					pin.update(dest, nil, err)
+				}
+				return qconn, err
 			},
 		}
 	} else if httpVersion == "2" {
@@ -215,13 +233,22 @@ func createHTTPClient(dest net.Destination, streamSettings *internet.MemoryStrea
 		if keepAlivePeriod < 0 {
 			keepAlivePeriod = 0
 		}
-		transport = &http2.Transport{
+		profile := transportConfig.profile()
+		h2Transport := &http2.Transport{
 			DialTLSContext: func(ctxInner context.Context, network string, addr string, cfg *gotls.Config) (net.Conn, error) {
-				return dialContext(ctxInner)
+				conn, err := dialContext(ctxInner)
+				if err != nil || profile == nil {
+					return conn, err
+				}
+				return profile.wrapConn(conn), nil
 			},
 			IdleConnTimeout: connIdleTimeout,
 			ReadIdleTimeout: keepAlivePeriod,
 		}
+		if profile != nil {
+			profile.setupTransport(h2Transport)
+		}
+		transport = h2Transport
 	} else {
 		httpDialContext := func(ctxInner context.Context, network string, addr string) (net.Conn, error) {
 			return dialContext(ctxInner)
@@ -406,6 +433,11 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 	}
 
 	maxUploadSize := scMaxEachPostBytes.rand()
+	probePath := dest.NetAddr()
+	probing := transportConfiguration.ScAutoMaxEachPostBytes
+	if probing {
+		maxUploadSize = postSizeProber.Size(probePath, maxUploadSize)
+	}
 	// WithSizeLimit(0) will still allow single bytes to pass, and a lot of
 	// code relies on this behavior. Subtract 1 so that together with
 	// uploadWriter wrapper, exact size limits can be enforced
@@ -457,13 +489,21 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 			}
 
 			go func() {
+				size := chunk.Len()
 				err := httpClient.PostPacket(
 					ctx,
 					url.String(),
 					&buf.MultiBufferContainer{MultiBuffer: chunk},
-					int64(chunk.Len()),
+					int64(size),
 				)
 				wroteRequest.Close()
+				if probing {
+					if err == nil {
+						postSizeProber.Succeeded(probePath, size)
+					} else if ctx.Err() == nil {
+						postSizeProber.Failed(probePath, size)
+					}
+				}
 				if err != nil {
 					errors.LogInfoInner(ctx, err, "failed to send upload")
 					uploadPipeReader.Interrupt()
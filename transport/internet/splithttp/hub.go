@@ -14,7 +14,6 @@ import (
 
 	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/http3"
-	goreality "github.com/xtls/reality"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
@@ -36,6 +35,7 @@ type requestHandler struct {
 	sessionMu *sync.Mutex
 	sessions  sync.Map
 	localAddr net.Addr
+	trust     *internet.ProxyTrust
 }
 
 type httpSession struct {
@@ -45,20 +45,44 @@ type httpSession struct {
 	// after the client connects, this becomes "done" and the session lives as
 	// long as the GET request.
 	isFullyConnected *done.Instance
+	// uploaded is closed once the client uploads anything.
+	uploaded *done.Instance
 }
 
-func (h *requestHandler) maybeReapSession(isFullyConnected *done.Instance, sessionId string) {
+// sessionHalfTimeout is how long a half of a session waits for the other one. Past it, the
+// halves are taken as routed to different servers, as by CDNs routing them to different edges.
+const sessionHalfTimeout = 30 * time.Second
+
+func (h *requestHandler) maybeReapSession(s *httpSession, sessionId string) {
 	shouldReap := done.New()
 	go func() {
-		time.Sleep(30 * time.Second)
+		time.Sleep(sessionHalfTimeout)
 		shouldReap.Close()
 	}()
 
 	select {
-	case <-isFullyConnected.Wait():
+	case <-s.isFullyConnected.Wait():
 		return
 	case <-shouldReap.Wait():
 		h.sessions.Delete(sessionId)
+		if s.uploaded.Done() {
+			errors.LogWarning(context.Background(), "session ", sessionId, " uploaded without downloading, its halves may have reached different servers")
+		}
+	}
+}
+
+// watchUploads closes the download of a session if nothing is uploaded to it in time, for the
+// client to retry rather than waiting for uploads which reached another server.
+func (h *requestHandler) watchUploads(s *httpSession, sessionId string, downloadDone *done.Instance) {
+	timer := time.NewTimer(sessionHalfTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-s.uploaded.Wait():
+	case <-downloadDone.Wait():
+	case <-timer.C:
+		errors.LogWarning(context.Background(), "session ", sessionId, " downloading without uploads, its halves may have reached different servers")
+		downloadDone.Close()
 	}
 }
 
@@ -81,10 +105,11 @@ func (h *requestHandler) upsertSession(sessionId string) *httpSession {
 	s := &httpSession{
 		uploadQueue:      NewUploadQueue(h.ln.config.GetNormalizedScMaxBufferedPosts()),
 		isFullyConnected: done.New(),
+		uploaded:         done.New(),
 	}
 
 	h.sessions.Store(sessionId, s)
-	go h.maybeReapSession(s.isFullyConnected, sessionId)
+	go h.maybeReapSession(s, sessionId)
 	return s
 }
 
@@ -158,11 +183,13 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 			Port: remoteAddr.(*net.TCPAddr).Port,
 		}
 	}
-	if len(forwardedAddrs) > 0 && forwardedAddrs[0].Family().IsIP() {
-		remoteAddr = &net.TCPAddr{
-			IP:   forwardedAddrs[0].IP(),
-			Port: 0,
-		}
+	peer := remoteAddr
+	if _, ok := h.localAddr.(*net.UnixAddr); ok {
+		// Peers on unix sockets have no addresses of their own.
+		peer = h.localAddr
+	}
+	if client := h.trust.ClientAddr(peer, forwardedAddrs); client != peer {
+		remoteAddr = client
 	}
 
 	var currentSession *httpSession
@@ -184,6 +211,7 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 				return
 			}
 			uploadDone := done.New()
+			currentSession.uploaded.Close()
 			err = currentSession.uploadQueue.Push(Packet{
 				Reader: &httpRequestBodyReader{
 					requestReader: request.Body,
@@ -249,6 +277,7 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 			return
 		}
 
+		currentSession.uploaded.Close()
 		err = currentSession.uploadQueue.Push(Packet{
 			Payload: payload,
 			Seq:     seqInt,
@@ -304,6 +333,7 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 		}
 		if sessionId != "" { // if not stream-one
 			conn.reader = currentSession.uploadQueue
+			go h.watchUploads(currentSession, sessionId, downloadDone)
 		}
 
 		h.ln.addConn(stat.Connection(&conn))
@@ -383,6 +413,10 @@ func ListenXH(ctx context.Context, address net.Address, port net.Port, streamSet
 			streamSettings.SocketSettings = &internet.SocketConfig{}
 		}
 	}
+	trust, err := internet.NewProxyTrust(streamSettings.SocketSettings)
+	if err != nil {
+		return nil, err
+	}
 	handler := &requestHandler{
 		config:    l.config,
 		host:      l.config.Host,
@@ -390,11 +424,11 @@ func ListenXH(ctx context.Context, address net.Address, port net.Port, streamSet
 		ln:        l,
 		sessionMu: &sync.Mutex{},
 		sessions:  sync.Map{},
+		trust:     trust,
 	}
 	tlsConfig := getTLSConfig(streamSettings)
 	l.isH3 = len(tlsConfig.NextProtos) == 1 && tlsConfig.NextProtos[0] == "h3"
 
-	var err error
 	if port == net.Port(0) { // unix
 		l.listener, err = internet.ListenSystem(ctx, &net.UnixAddr{
 			Name: address.Domain(),
@@ -447,7 +481,7 @@ func ListenXH(ctx context.Context, address net.Address, port net.Port, streamSet
 			}
 		}
 		if config := reality.ConfigFromStreamSettings(streamSettings); config != nil {
-			l.listener = goreality.NewListener(l.listener, config.GetREALITYConfig())
+			l.listener = reality.NewListener(l.listener, config.GetREALITYKeys())
 		}
 
 		handler.localAddr = l.listener.Addr()
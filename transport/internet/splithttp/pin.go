@@ -0,0 +1,62 @@
+package splithttp
+
+import (
+	"sync"
+
+	"github.com/xtls/xray-core/common/net"
+)
+
+// addressPin keeps the connections to a domain on the IP of it connected to first, as CDNs may
+// route the requests of a session by the edge they reach, and a domain resolves to many.
+type addressPin struct {
+	sync.Mutex
+	address net.Address
+}
+
+var globalPinMap map[net.Destination]*addressPin
+
+// getAddressPin returns the pin of dest, shared by all the clients dialing it. It must be called
+// with globalDialerAccess held.
+func getAddressPin(dest net.Destination) *addressPin {
+	if globalPinMap == nil {
+		globalPinMap = make(map[net.Destination]*addressPin)
+	}
+	pin, found := globalPinMap[dest]
+	if !found {
+		pin = new(addressPin)
+		globalPinMap[dest] = pin
+	}
+	return pin
+}
+
+// destination returns dest with the pinned IP, if any.
+func (p *addressPin) destination(dest net.Destination) net.Destination {
+	if p == nil || !dest.Address.Family().IsDomain() {
+		return dest
+	}
+	p.Lock()
+	defer p.Unlock()
+	if p.address != nil {
+		dest.Address = p.address
+	}
+	return dest
+}
+
+// update pins the IP conn is connected to, or releases the pin if the dial failed with err.
+func (p *addressPin) update(dest net.Destination, remote net.Addr, err error) {
+	if p == nil || !dest.Address.Family().IsDomain() {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	if err != nil {
+		p.address = nil
+		return
+	}
+	if p.address != nil || remote == nil {
+		return
+	}
+	if address := net.DestinationFromAddr(remote).Address; address.Family().IsIP() && !address.IP().IsUnspecified() {
+		p.address = address
+	}
+}
@@ -2,15 +2,20 @@ package internet
 
 import (
 	"context"
+	goerrors "errors"
 	"math/rand"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/sagernet/sing/common/control"
+	"github.com/xtls/xray-core/common/dice"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/policy"
+	"google.golang.org/protobuf/proto"
 )
 
 var effectiveSystemDialer SystemDialer = &DefaultSystemDialer{}
@@ -26,21 +31,24 @@ type DefaultSystemDialer struct {
 	obm         outbound.Manager
 }
 
-func resolveSrcAddr(network net.Network, src net.Address) net.Addr {
-	if src == nil || src == net.AnyIP {
+func resolveSrcAddr(network net.Network, src net.Address, port int) net.Addr {
+	var ip net.IP
+	if src != nil && src != net.AnyIP {
+		ip = src.IP()
+	} else if port == 0 {
 		return nil
 	}
 
 	if network == net.Network_TCP {
 		return &net.TCPAddr{
-			IP:   src.IP(),
-			Port: 0,
+			IP:   ip,
+			Port: port,
 		}
 	}
 
 	return &net.UDPAddr{
-		IP:   src.IP(),
-		Port: 0,
+		IP:   ip,
+		Port: port,
 	}
 }
 
@@ -50,16 +58,22 @@ func hasBindAddr(sockopt *SocketConfig) bool {
 
 func (d *DefaultSystemDialer) Dial(ctx context.Context, src net.Address, dest net.Destination, sockopt *SocketConfig) (net.Conn, error) {
 	errors.LogDebug(ctx, "dialing to "+dest.String())
+	sockopt = withBoundInterface(sockopt, dest)
 
 	if dest.Network == net.Network_UDP && !hasBindAddr(sockopt) {
-		srcAddr := resolveSrcAddr(net.Network_UDP, src)
-		if srcAddr == nil {
-			srcAddr = &net.UDPAddr{
-				IP:   []byte{0, 0, 0, 0},
-				Port: 0,
+		var packetConn net.PacketConn
+		err := withLocalPortRange(sockopt, func(port int) error {
+			srcAddr := resolveSrcAddr(net.Network_UDP, src, port)
+			if srcAddr == nil {
+				srcAddr = &net.UDPAddr{
+					IP:   []byte{0, 0, 0, 0},
+					Port: 0,
+				}
 			}
-		}
-		packetConn, err := ListenSystemPacket(ctx, srcAddr, sockopt)
+			var err error
+			packetConn, err = ListenSystemPacket(ctx, srcAddr, sockopt)
+			return err
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -75,6 +89,9 @@ func (d *DefaultSystemDialer) Dial(ctx context.Context, src net.Address, dest ne
 			sys.Control(func(fd uintptr) {
 				if err := applyOutboundSocketOptions("udp", dest.NetAddr(), fd, sockopt); err != nil {
 					errors.LogInfo(ctx, err, "failed to apply socket options")
+					TraceDial(ctx, "failed to apply socket options to udp ", dest.NetAddr(), ": ", err)
+				} else {
+					TraceDial(ctx, "applied socket options to udp ", dest.NetAddr(), ": ", sockopt)
 				}
 			})
 		}
@@ -88,8 +105,8 @@ func (d *DefaultSystemDialer) Dial(ctx context.Context, src net.Address, dest ne
 		goStdKeepAlive = time.Duration(-1)
 	}
 	dialer := &net.Dialer{
-		Timeout:   time.Second * 16,
-		LocalAddr: resolveSrcAddr(dest.Network, src),
+		Timeout:   policy.TimeoutsFromContext(ctx).Dial,
+		LocalAddr: resolveSrcAddr(dest.Network, src, 0),
 		KeepAlive: goStdKeepAlive,
 	}
 
@@ -107,6 +124,9 @@ func (d *DefaultSystemDialer) Dial(ctx context.Context, src net.Address, dest ne
 				if sockopt != nil {
 					if err := applyOutboundSocketOptions(network, address, fd, sockopt); err != nil {
 						errors.LogInfoInner(ctx, err, "failed to apply socket options")
+						TraceDial(ctx, "failed to apply socket options to ", network, " ", address, ": ", err)
+					} else {
+						TraceDial(ctx, "applied socket options to ", network, " ", address, ": ", sockopt)
 					}
 					if dest.Network == net.Network_UDP && hasBindAddr(sockopt) {
 						if err := bindAddr(fd, sockopt.BindAddress, sockopt.BindPort); err != nil {
@@ -118,7 +138,51 @@ func (d *DefaultSystemDialer) Dial(ctx context.Context, src net.Address, dest ne
 		}
 	}
 
-	return dialer.DialContext(ctx, dest.Network.SystemString(), dest.NetAddr())
+	if hasBindAddr(sockopt) {
+		return dialer.DialContext(ctx, dest.Network.SystemString(), dest.NetAddr())
+	}
+
+	var conn net.Conn
+	err := withLocalPortRange(sockopt, func(port int) error {
+		if port > 0 {
+			dialer.LocalAddr = resolveSrcAddr(dest.Network, src, port)
+		}
+		var err error
+		conn, err = dialer.DialContext(ctx, dest.Network.SystemString(), dest.NetAddr())
+		return err
+	})
+	if err == nil && sockopt.GetTcpMptcp() {
+		logMPTCPFallback(ctx, conn, dest)
+	}
+	return conn, err
+}
+
+// logMPTCPFallback tells when a connection asked to use MPTCP fell back to TCP, as the kernel or
+// the peer doesn't support it.
+func logMPTCPFallback(ctx context.Context, conn net.Conn, dest net.Destination) {
+	if tc, ok := conn.(*net.TCPConn); ok {
+		if used, err := tc.MultipathTCP(); err == nil && !used {
+			errors.LogDebug(ctx, "MPTCP not available to ", dest, ", using TCP")
+		}
+	}
+}
+
+// withLocalPortRange calls bind with random ports from the local port range of sockopt,
+// until it gets one that is not in use. bind is called with port 0 if no range is set.
+func withLocalPortRange(sockopt *SocketConfig, bind func(port int) error) error {
+	r := sockopt.GetLocalPortRange()
+	if r == nil || r.From == 0 || r.To < r.From {
+		return bind(0)
+	}
+	var err error
+	for i := 0; i < 8; i++ {
+		port := int(r.From) + dice.Roll(int(r.To-r.From)+1)
+		err = bind(port)
+		if !goerrors.Is(err, syscall.EADDRINUSE) && !goerrors.Is(err, syscall.EADDRNOTAVAIL) {
+			return err
+		}
+	}
+	return errors.New("no free local port in range ", r.From, "-", r.To).Base(err)
 }
 
 func (d *DefaultSystemDialer) DestIpAddress() net.IP {
@@ -223,6 +287,31 @@ func RegisterDialerController(ctl control.Func) error {
 	return nil
 }
 
+var boundInterface atomic.Value // string
+
+// BindToInterface makes the system dialer bind the sockets it dials without an interface of their
+// own to the named interface, or to none if name is empty. It keeps the connections of Xray off a
+// TUN device taking the default routes.
+func BindToInterface(name string) {
+	boundInterface.Store(name)
+}
+
+func withBoundInterface(sockopt *SocketConfig, dest net.Destination) *SocketConfig {
+	name, _ := boundInterface.Load().(string)
+	if name == "" || (sockopt != nil && sockopt.Interface != "") {
+		return sockopt
+	}
+	if dest.Address != nil && dest.Address.Family().IsIP() && dest.Address.IP().IsLoopback() {
+		return sockopt
+	}
+	if sockopt == nil {
+		return &SocketConfig{Interface: name}
+	}
+	sockopt = proto.Clone(sockopt).(*SocketConfig)
+	sockopt.Interface = name
+	return sockopt
+}
+
 type FakePacketConn struct {
 	net.Conn
 }
@@ -0,0 +1,48 @@
+package internet_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/testing/servers/tcp"
+	. "github.com/xtls/xray-core/transport/internet"
+)
+
+func TestDialLocalPortRange(t *testing.T) {
+	tcpServer := tcp.Server{MsgProcessor: func(b []byte) []byte { return b }}
+	tcpDest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+	// Dialing UDP only binds the local port.
+	udpDest := net.UDPDestination(net.LocalHostIP, tcp.PickPort())
+
+	from := tcp.PickPort()
+	sockopt := &SocketConfig{LocalPortRange: &net.PortRange{From: uint32(from), To: uint32(from) + 19}}
+	for _, dest := range []net.Destination{tcpDest, udpDest} {
+		conn, err := DialSystem(context.Background(), dest, sockopt)
+		if err != nil {
+			t.Fatal("failed to dial ", dest, ": ", err)
+		}
+		port := net.DestinationFromAddr(conn.LocalAddr()).Port
+		conn.Close()
+		if port < from || port > from+19 {
+			t.Error("dialed ", dest, " from port ", port, ", want within ", from, "-", from+19)
+		}
+	}
+}
+
+func TestDialLocalPortRangeInUse(t *testing.T) {
+	dest := net.UDPDestination(net.LocalHostIP, tcp.PickPort())
+	busy, err := net.ListenUDP("udp", &net.UDPAddr{})
+	common.Must(err)
+	defer busy.Close()
+	port := uint32(busy.LocalAddr().(*net.UDPAddr).Port)
+
+	_, err = DialSystem(context.Background(), dest, &SocketConfig{LocalPortRange: &net.PortRange{From: port, To: port}})
+	if err == nil || !strings.Contains(err.Error(), "no free local port") {
+		t.Error("expected dialing from a port in use to fail, got ", err)
+	}
+}
@@ -100,6 +100,9 @@ func (dl *DefaultListener) Listen(ctx context.Context, addr net.Addr, sockopt *S
 		lc.Control = nil
 		network = addr.Network()
 		address = addr.Name
+		if sockopt != nil && len(sockopt.PeerUids) > 0 && !peerCredSupported {
+			return nil, errors.New("peerUids: not supported on ", runtime.GOOS)
+		}
 
 		if (runtime.GOOS == "linux" || runtime.GOOS == "android") && address[0] == '@' {
 			// linux abstract unix domain socket is lockfree
@@ -121,6 +124,9 @@ func (dl *DefaultListener) Listen(ctx context.Context, addr net.Addr, sockopt *S
 
 				mode := os.FileMode(perm)
 				filePerm = &mode
+			} else if sockopt != nil && sockopt.FileMode != 0 {
+				mode := os.FileMode(sockopt.FileMode)
+				filePerm = &mode
 			}
 			// normal unix domain socket needs lock
 			locker := &FileLocker{
@@ -150,10 +156,18 @@ func (dl *DefaultListener) Listen(ctx context.Context, addr net.Addr, sockopt *S
 		}
 	}
 
+	var trust *ProxyTrust
+	if sockopt != nil && sockopt.AcceptProxyProtocol {
+		if trust, err = NewProxyTrust(sockopt); err != nil {
+			return nil, err
+		}
+	}
 	l, err = callback(lc.Listen(ctx, network, address))
+	if _, ok := addr.(*net.UnixAddr); ok && err == nil && sockopt != nil && len(sockopt.PeerUids) > 0 {
+		l = &peerCredListener{Listener: l, uids: sockopt.PeerUids}
+	}
 	if err == nil && sockopt != nil && sockopt.AcceptProxyProtocol {
-		policyFunc := func(upstream net.Addr) (proxyproto.Policy, error) { return proxyproto.REQUIRE, nil }
-		l = &proxyproto.Listener{Listener: l, Policy: policyFunc}
+		l = &proxyproto.Listener{Listener: l, Policy: trust.ProxyPolicy}
 	}
 	return l, err
 }
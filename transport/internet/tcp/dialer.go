@@ -2,13 +2,16 @@ package tcp
 
 import (
 	"context"
+	gotls "crypto/tls"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/reality"
 	"github.com/xtls/xray-core/transport/internet/stat"
@@ -64,6 +67,9 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 				tlsConfig.NextProtos = []string{"h2", "http/1.1"}
 			}
 		}
+		internet.TraceDial(ctx, "TLS handshake with server name ", tlsConfig.ServerName, ", ALPN ", tlsConfig.NextProtos,
+			", fingerprint ", config.Fingerprint, ", versions ", versionName(tlsConfig.MinVersion), "-", versionName(tlsConfig.MaxVersion))
+		start := time.Now()
 		if fingerprint := tls.GetFingerprint(config.Fingerprint); fingerprint != nil {
 			conn = tls.UClient(conn, tlsConfig, fingerprint)
 			if len(tlsConfig.NextProtos) == 1 && tlsConfig.NextProtos[0] == "http/1.1" { // allow manually specify
@@ -76,20 +82,30 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 			err = conn.(*tls.Conn).HandshakeContext(ctx)
 		}
 		if err != nil {
+			internet.TraceDial(ctx, "TLS handshake failed: ", err)
 			if isFromMitmVerify {
 				return nil, errors.New("MITM freedom RAW TLS: failed to verify Domain Fronting certificate from " + mitmServerName).Base(err).AtWarning()
 			}
 			return nil, err
 		}
+		stats.RecordStage(ctx, stats.StageTLS, start)
 		negotiatedProtocol := conn.(tls.Interface).NegotiatedProtocol()
+		if internet.IsDialTraced(ctx) {
+			traceTLS(ctx, conn, negotiatedProtocol, time.Since(start))
+		}
 		if isFromMitmAlpn && !mitmAlpn11 && negotiatedProtocol != "h2" {
 			conn.Close()
 			return nil, errors.New("MITM freedom RAW TLS: unexpected Negotiated Protocol (" + negotiatedProtocol + ") with " + mitmServerName).AtWarning()
 		}
 	} else if config := reality.ConfigFromStreamSettings(streamSettings); config != nil {
+		internet.TraceDial(ctx, "REALITY handshake with server name ", config.ServerName, ", fingerprint ", config.Fingerprint)
+		start := time.Now()
 		if conn, err = reality.UClient(conn, config, ctx, dest); err != nil {
+			internet.TraceDial(ctx, "REALITY handshake failed: ", err)
 			return nil, err
 		}
+		stats.RecordStage(ctx, stats.StageTLS, start)
+		internet.TraceDial(ctx, "REALITY handshake done in ", time.Since(start))
 	}
 
 	tcpSettings := streamSettings.ProtocolSettings.(*Config)
@@ -107,6 +123,27 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 	return stat.Connection(conn), nil
 }
 
+// traceTLS traces the parameters the TLS handshake of conn negotiated.
+func traceTLS(ctx context.Context, conn net.Conn, alpn string, elapsed time.Duration) {
+	var version, suite uint16
+	switch c := conn.(type) {
+	case *tls.Conn:
+		state := c.ConnectionState()
+		version, suite = state.Version, state.CipherSuite
+	case *tls.UConn:
+		state := c.ConnectionState()
+		version, suite = state.Version, state.CipherSuite
+	}
+	internet.TraceDial(ctx, "TLS handshake done in ", elapsed, ": ", gotls.VersionName(version), ", ", gotls.CipherSuiteName(suite), ", ALPN ", alpn)
+}
+
+func versionName(version uint16) string {
+	if version == 0 {
+		return "default"
+	}
+	return gotls.VersionName(version)
+}
+
 func init() {
 	common.Must(internet.RegisterTransportDialer(protocolName, Dial))
 }
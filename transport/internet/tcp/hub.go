@@ -6,7 +6,6 @@ import (
 	"strings"
 	"time"
 
-	goreality "github.com/xtls/reality"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
@@ -18,12 +17,14 @@ import (
 
 // Listener is an internet.Listener that listens for TCP connections.
 type Listener struct {
-	listener      net.Listener
-	tlsConfig     *gotls.Config
-	realityConfig *goreality.Config
-	authConfig    internet.ConnectionAuthenticator
-	config        *Config
-	addConn       internet.ConnHandler
+	listener    net.Listener
+	tlsConfig   *gotls.Config
+	realityKeys *reality.Keys
+	authConfig  internet.ConnectionAuthenticator
+	config      *Config
+	addConn     internet.ConnHandler
+	guard       *internet.HandshakeGuard
+	onFailure   func(net.Conn, error)
 }
 
 // ListenTCP creates a new Listener based on configurations.
@@ -66,12 +67,14 @@ func ListenTCP(ctx context.Context, address net.Address, port net.Port, streamSe
 	}
 
 	l.listener = listener
+	l.guard = internet.NewHandshakeGuard(streamSettings.SocketSettings)
+	l.onFailure = internet.HandshakeFailureFromContext(ctx)
 
 	if config := tls.ConfigFromStreamSettings(streamSettings); config != nil {
 		l.tlsConfig = config.GetTLSConfig()
 	}
 	if config := reality.ConfigFromStreamSettings(streamSettings); config != nil {
-		l.realityConfig = config.GetREALITYConfig()
+		l.realityKeys = config.GetREALITYKeys()
 	}
 
 	if tcpSettings.HeaderSettings != nil {
@@ -106,9 +109,30 @@ func (v *Listener) keepAccepting() {
 		}
 		go func() {
 			if v.tlsConfig != nil {
-				conn = tls.Server(conn, v.tlsConfig)
-			} else if v.realityConfig != nil {
-				if conn, err = reality.Server(conn, v.realityConfig); err != nil {
+				tlsConn := tls.Server(conn, v.tlsConfig)
+				// Without a guard, the handshake is left to the first read.
+				if v.guard != nil {
+					if err := v.guard.Run(conn, func() error {
+						err := tlsConn.(*tls.Conn).Handshake()
+						if err != nil {
+							v.onFailure(conn, err)
+						}
+						return err
+					}); err != nil {
+						errors.LogInfoInner(context.Background(), err, "failed TLS handshake")
+						return
+					}
+				}
+				conn = tlsConn
+			} else if v.realityKeys != nil {
+				rawConn := conn
+				if err := v.guard.Run(rawConn, func() error {
+					conn, err = v.realityKeys.Server(rawConn)
+					if err != nil {
+						v.onFailure(rawConn, err)
+					}
+					return err
+				}); err != nil {
 					errors.LogInfo(context.Background(), err.Error())
 					return
 				}
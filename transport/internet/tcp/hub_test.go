@@ -0,0 +1,52 @@
+package tcp_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol/tls/cert"
+	"github.com/xtls/xray-core/testing/servers/tcp"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	. "github.com/xtls/xray-core/transport/internet/tcp"
+	"github.com/xtls/xray-core/transport/internet/tls"
+)
+
+func TestListenTCPHandshakeFailure(t *testing.T) {
+	failures := make(chan error, 1)
+	ctx := internet.ContextWithHandshakeFailure(context.Background(), func(conn net.Conn, err error) {
+		failures <- err
+	})
+	port := tcp.PickPort()
+	listener, err := ListenTCP(ctx, net.LocalHostIP, port, &internet.MemoryStreamConfig{
+		ProtocolName:     "tcp",
+		ProtocolSettings: &Config{},
+		SecurityType:     "tls",
+		SecuritySettings: &tls.Config{
+			Certificate: []*tls.Certificate{tls.ParseCertificate(cert.MustGenerate(nil))},
+		},
+		SocketSettings: &internet.SocketConfig{HandshakeTimeout: 2},
+	}, func(conn stat.Connection) {
+		conn.Close()
+		t.Error("expected the connection to be dropped")
+	})
+	common.Must(err)
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", net.TCPDestination(net.LocalHostIP, port).NetAddr())
+	common.Must(err)
+	defer conn.Close()
+	common.Must2(conn.Write([]byte("GET / HTTP/1.1\r\n\r\n")))
+
+	select {
+	case err := <-failures:
+		if err == nil {
+			t.Error("expected the error of the handshake")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the failed handshake wasn't reported")
+	}
+}
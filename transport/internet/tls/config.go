@@ -342,6 +342,23 @@ func (r *RandCarrier) Read(p []byte) (n int, err error) {
 }
 
 // GetTLSConfig converts this Config into tls.Config.
+// LoadCertificates loads the root certificates of c and checks its key pairs, so that the first
+// handshake doesn't wait for them.
+func (c *Config) LoadCertificates() error {
+	if _, err := c.getCertPool(); err != nil {
+		return err
+	}
+	for _, entry := range c.Certificate {
+		if entry.Usage != Certificate_ENCIPHERMENT {
+			continue
+		}
+		if _, err := tls.X509KeyPair(entry.Certificate, entry.Key); err != nil {
+			return errors.New("invalid X509 key pair").Base(err)
+		}
+	}
+	return nil
+}
+
 func (c *Config) GetTLSConfig(opts ...Option) *tls.Config {
 	root, err := c.getCertPool()
 	if err != nil {
@@ -402,38 +419,11 @@ func (c *Config) GetTLSConfig(opts ...Option) *tls.Config {
 		config.NextProtos = []string{"h2", "http/1.1"}
 	}
 
-	switch c.MinVersion {
-	case "1.0":
-		config.MinVersion = tls.VersionTLS10
-	case "1.1":
-		config.MinVersion = tls.VersionTLS11
-	case "1.2":
-		config.MinVersion = tls.VersionTLS12
-	case "1.3":
-		config.MinVersion = tls.VersionTLS13
-	}
-
-	switch c.MaxVersion {
-	case "1.0":
-		config.MaxVersion = tls.VersionTLS10
-	case "1.1":
-		config.MaxVersion = tls.VersionTLS11
-	case "1.2":
-		config.MaxVersion = tls.VersionTLS12
-	case "1.3":
-		config.MaxVersion = tls.VersionTLS13
-	}
+	config.MinVersion, _ = ParseVersion(c.MinVersion)
+	config.MaxVersion, _ = ParseVersion(c.MaxVersion)
 
 	if len(c.CipherSuites) > 0 {
-		id := make(map[string]uint16)
-		for _, s := range tls.CipherSuites() {
-			id[s.Name] = s.ID
-		}
-		for _, n := range strings.Split(c.CipherSuites, ":") {
-			if id[n] != 0 {
-				config.CipherSuites = append(config.CipherSuites, id[n])
-			}
-		}
+		config.CipherSuites, _ = ParseCipherSuites(c.CipherSuites)
 	}
 
 	if len(c.MasterKeyLog) > 0 && c.MasterKeyLog != "none" {
@@ -484,15 +474,21 @@ func ConfigFromStreamSettings(settings *internet.MemoryStreamConfig) *Config {
 	return config
 }
 
-func ParseCurveName(curveNames []string) []tls.CurveID {
-	curveMap := map[string]tls.CurveID{
-		"curvep256":             tls.CurveP256,
-		"curvep384":             tls.CurveP384,
-		"curvep521":             tls.CurveP521,
-		"x25519":                tls.X25519,
-		"x25519kyber768draft00": 0x6399,
-	}
+var curveMap = map[string]tls.CurveID{
+	"curvep256":             tls.CurveP256,
+	"curvep384":             tls.CurveP384,
+	"curvep521":             tls.CurveP521,
+	"x25519":                tls.X25519,
+	"x25519kyber768draft00": 0x6399,
+}
+
+// IsCurveName tells whether name, in any case, is a curve of ParseCurveName.
+func IsCurveName(name string) bool {
+	_, ok := curveMap[strings.ToLower(name)]
+	return ok
+}
 
+func ParseCurveName(curveNames []string) []tls.CurveID {
 	var curveIDs []tls.CurveID
 	for _, name := range curveNames {
 		if curveID, ok := curveMap[strings.ToLower(name)]; ok {
@@ -503,3 +499,45 @@ func ParseCurveName(curveNames []string) []tls.CurveID {
 	}
 	return curveIDs
 }
+
+// ParseVersion returns the TLS version named like "1.2", or 0 for an empty name, letting Go
+// choose.
+func ParseVersion(name string) (uint16, error) {
+	switch name {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, errors.New("unknown TLS version ", name, ", expecting 1.0, 1.1, 1.2 or 1.3")
+}
+
+// ParseCipherSuites returns the cipher suites named in names, separated by colons, like
+// "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256:TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", in their
+// order. Insecure ones, such as those with RC4, are accepted too. The error names the first
+// unknown suite, which is left out.
+func ParseCipherSuites(names string) ([]uint16, error) {
+	ids := make(map[string]uint16)
+	for _, s := range tls.CipherSuites() {
+		ids[s.Name] = s.ID
+	}
+	for _, s := range tls.InsecureCipherSuites() {
+		ids[s.Name] = s.ID
+	}
+	var suites []uint16
+	var err error
+	for _, name := range strings.Split(names, ":") {
+		if id, ok := ids[strings.TrimSpace(name)]; ok {
+			suites = append(suites, id)
+		} else if err == nil {
+			err = errors.New("unknown cipher suite ", name)
+		}
+	}
+	return suites, err
+}
@@ -0,0 +1,88 @@
+package internet
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+const (
+	// traceDials is how many dials of an outbound are traced every traceWindow at most, so that
+	// a busy outbound doesn't flood the log.
+	traceDials  = 10
+	traceWindow = 10 * time.Second
+)
+
+// DialTracer traces the dials of an outbound with "trace" on, logging each of their steps at
+// debug level.
+type DialTracer struct {
+	tag  string
+	next atomic.Uint32
+
+	access  sync.Mutex
+	window  time.Time
+	traced  int
+	skipped int
+}
+
+// NewDialTracer creates a DialTracer for the outbound of tag.
+func NewDialTracer(tag string) *DialTracer {
+	return &DialTracer{tag: tag}
+}
+
+type dialTrace struct {
+	tag   string
+	id    uint32
+	start time.Time
+}
+
+type dialTraceKey struct{}
+
+// Start returns ctx with the trace of a new dial to dest, or ctx itself if too many dials were
+// traced lately.
+func (t *DialTracer) Start(ctx context.Context, dest net.Destination) context.Context {
+	if t == nil {
+		return ctx
+	}
+	now := time.Now()
+	t.access.Lock()
+	if now.Sub(t.window) >= traceWindow {
+		if t.skipped > 0 {
+			errors.LogDebug(ctx, "[trace ", t.tag, "] skipped ", t.skipped, " dials over the limit of ", traceDials, " per ", traceWindow)
+		}
+		t.window, t.traced, t.skipped = now, 0, 0
+	}
+	if t.traced >= traceDials {
+		t.skipped++
+		t.access.Unlock()
+		return ctx
+	}
+	t.traced++
+	t.access.Unlock()
+
+	trace := &dialTrace{tag: t.tag, id: t.next.Add(1), start: now}
+	ctx = context.WithValue(ctx, dialTraceKey{}, trace)
+	TraceDial(ctx, "dialing ", dest)
+	return ctx
+}
+
+// TraceDial logs a step of the dial of ctx, with the time since it started, if the dial is
+// traced.
+func TraceDial(ctx context.Context, values ...interface{}) {
+	trace, ok := ctx.Value(dialTraceKey{}).(*dialTrace)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(trace.start).Round(time.Microsecond)
+	errors.LogDebug(ctx, append([]interface{}{"[trace ", trace.tag, " #", trace.id, " +", elapsed, "] "}, values...)...)
+}
+
+// IsDialTraced tells whether the dial of ctx is traced, for steps which are costly to describe.
+func IsDialTraced(ctx context.Context) bool {
+	_, ok := ctx.Value(dialTraceKey{}).(*dialTrace)
+	return ok
+}
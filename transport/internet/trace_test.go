@@ -0,0 +1,34 @@
+package internet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/common/net"
+)
+
+func TestDialTracerLimit(t *testing.T) {
+	tracer := NewDialTracer("out")
+	dest := net.TCPDestination(net.DomainAddress("example.com"), 443)
+	for i := 0; i < traceDials; i++ {
+		if !IsDialTraced(tracer.Start(context.Background(), dest)) {
+			t.Fatal("dial ", i, " not traced")
+		}
+	}
+	if IsDialTraced(tracer.Start(context.Background(), dest)) {
+		t.Error("dial over the limit traced")
+	}
+
+	tracer.window = tracer.window.Add(-traceWindow)
+	if !IsDialTraced(tracer.Start(context.Background(), dest)) {
+		t.Error("dial of the next window not traced")
+	}
+	if tracer.skipped != 0 || tracer.traced != 1 {
+		t.Error("window not reset: ", tracer.traced, " ", tracer.skipped)
+	}
+
+	var none *DialTracer
+	if IsDialTraced(none.Start(context.Background(), dest)) {
+		t.Error("dial traced without a tracer")
+	}
+}
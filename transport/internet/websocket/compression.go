@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+// Counters of the connections with permessage-deflate in use, to tell whether compression pays
+// off: the ratio of wire to payload bytes, and the time spent framing and (de)compressing
+// messages, in microseconds. The wire bytes include the overhead of TLS, if any.
+const (
+	compressionPayloadCounter = "transport>>>websocket>>>compression>>>payload"
+	compressionWireCounter    = "transport>>>websocket>>>compression>>>wire"
+	compressionTimeCounter    = "transport>>>websocket>>>compression>>>micros"
+)
+
+type compressionStats struct {
+	payload stats.Counter
+	wire    stats.Counter
+	micros  stats.Counter
+}
+
+// compressionStatsFromContext returns the counters of compression in the stats manager of the
+// instance of ctx, or nil if stats aren't enabled.
+func compressionStatsFromContext(ctx context.Context) *compressionStats {
+	v := core.FromContext(ctx)
+	if v == nil {
+		return nil
+	}
+	m, ok := v.GetFeature(stats.ManagerType()).(stats.Manager)
+	if !ok {
+		return nil
+	}
+	s := new(compressionStats)
+	s.payload, _ = stats.GetOrRegisterCounter(m, compressionPayloadCounter)
+	s.wire, _ = stats.GetOrRegisterCounter(m, compressionWireCounter)
+	s.micros, _ = stats.GetOrRegisterCounter(m, compressionTimeCounter)
+	if s.payload == nil || s.wire == nil || s.micros == nil {
+		return nil
+	}
+	return s
+}
+
+func (s *compressionStats) record(payload int, wire int64, d time.Duration) {
+	s.payload.Add(int64(payload))
+	s.wire.Add(wire)
+	s.micros.Add(int64(d / time.Microsecond))
+}
+
+// isCompressed tells whether permessage-deflate is among the extensions of header.
+func isCompressed(extensions string) bool {
+	return strings.Contains(extensions, "permessage-deflate")
+}
+
+// wireConn counts the bytes of the connection under a WebSocket, and the time its reads and
+// writes block, which is told apart from the time spent on the messages.
+type wireConn struct {
+	net.Conn
+	read         atomic.Int64
+	written      atomic.Int64
+	readBlocked  atomic.Int64
+	writeBlocked atomic.Int64
+}
+
+func (c *wireConn) Read(b []byte) (int, error) {
+	start := time.Now()
+	n, err := c.Conn.Read(b)
+	c.read.Add(int64(n))
+	c.readBlocked.Add(int64(time.Since(start)))
+	return n, err
+}
+
+func (c *wireConn) Write(b []byte) (int, error) {
+	start := time.Now()
+	n, err := c.Conn.Write(b)
+	c.written.Add(int64(n))
+	c.writeBlocked.Add(int64(time.Since(start)))
+	return n, err
+}
+
+// wireListener wraps the connections it accepts in wireConns.
+type wireListener struct {
+	net.Listener
+}
+
+func (l *wireListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &wireConn{Conn: conn}, nil
+}
+
+// findWireConn returns the wireConn under conn, which may be a TLS one.
+func findWireConn(conn net.Conn) *wireConn {
+	for {
+		switch c := conn.(type) {
+		case *wireConn:
+			return c
+		case interface{ NetConn() net.Conn }:
+			conn = c.NetConn()
+		default:
+			return nil
+		}
+	}
+}
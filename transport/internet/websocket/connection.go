@@ -20,6 +20,13 @@ type connection struct {
 	conn       *websocket.Conn
 	reader     io.Reader
 	remoteAddr net.Addr
+
+	// Set if permessage-deflate is in use and counted.
+	wire        *wireConn
+	compression *compressionStats
+
+	// Set if the frame size is probed.
+	probe *frameProbe
 }
 
 func NewConnection(conn *websocket.Conn, remoteAddr net.Addr, extraReader io.Reader, heartbeatPeriod uint32) *connection {
@@ -41,8 +48,35 @@ func NewConnection(conn *websocket.Conn, remoteAddr net.Addr, extraReader io.Rea
 	}
 }
 
+// withCompression counts the messages of the connection in s, as permessage-deflate is in use.
+// wire is the connection under it.
+func (c *connection) withCompression(wire *wireConn, s *compressionStats) *connection {
+	if wire != nil && s != nil {
+		c.wire = wire
+		c.compression = s
+	}
+	return c
+}
+
 // Read implements net.Conn.Read()
 func (c *connection) Read(b []byte) (int, error) {
+	if c.compression == nil {
+		return c.read(b)
+	}
+	start := time.Now()
+	read, blocked := c.wire.read.Load(), c.wire.readBlocked.Load()
+	n, err := c.read(b)
+	c.compression.record(n, c.wire.read.Load()-read, time.Since(start)-time.Duration(c.wire.readBlocked.Load()-blocked))
+	return n, err
+}
+
+// withFrameProbe splits the writes of the connection into frames sized by probe.
+func (c *connection) withFrameProbe(probe *frameProbe) *connection {
+	c.probe = probe
+	return c
+}
+
+func (c *connection) read(b []byte) (int, error) {
 	for {
 		reader, err := c.getReader()
 		if err != nil {
@@ -65,18 +99,44 @@ func (c *connection) getReader() (io.Reader, error) {
 
 	_, reader, err := c.conn.NextReader()
 	if err != nil {
+		c.probe.onError(err)
 		return nil, err
 	}
+	c.probe.onRead()
 	c.reader = reader
 	return reader, nil
 }
 
 // Write implements io.Writer.
 func (c *connection) Write(b []byte) (int, error) {
-	if err := c.conn.WriteMessage(websocket.BinaryMessage, b); err != nil {
-		return 0, err
+	if c.compression == nil {
+		return c.write(b)
+	}
+	start := time.Now()
+	written, blocked := c.wire.written.Load(), c.wire.writeBlocked.Load()
+	n, err := c.write(b)
+	c.compression.record(n, c.wire.written.Load()-written, time.Since(start)-time.Duration(c.wire.writeBlocked.Load()-blocked))
+	return n, err
+}
+
+func (c *connection) write(b []byte) (int, error) {
+	if c.probe == nil {
+		if err := c.conn.WriteMessage(websocket.BinaryMessage, b); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	n := 0
+	for n < len(b) {
+		frame := b[n:min(n+int(c.probe.size), len(b))]
+		if err := c.conn.WriteMessage(websocket.BinaryMessage, frame); err != nil {
+			c.probe.onError(err)
+			return n, err
+		}
+		c.probe.onWrite(int32(len(frame)))
+		n += len(frame)
 	}
-	return len(b), nil
+	return n, nil
 }
 
 func (c *connection) WriteMultiBuffer(mb buf.MultiBuffer) error {
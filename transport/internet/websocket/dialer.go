@@ -47,13 +47,28 @@ func init() {
 func dialWebSocket(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig, ed []byte) (net.Conn, error) {
 	wsSettings := streamSettings.ProtocolSettings.(*Config)
 
+	var compression *compressionStats
+	var wire *wireConn
+	if wsSettings.Compression {
+		compression = compressionStatsFromContext(ctx)
+	}
+	dialSystem := func() (net.Conn, error) {
+		conn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+		if err != nil || compression == nil {
+			return conn, err
+		}
+		wire = &wireConn{Conn: conn}
+		return wire, nil
+	}
+
 	dialer := &websocket.Dialer{
 		NetDial: func(network, addr string) (net.Conn, error) {
-			return internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+			return dialSystem()
 		},
-		ReadBufferSize:   4 * 1024,
-		WriteBufferSize:  4 * 1024,
-		HandshakeTimeout: time.Second * 8,
+		ReadBufferSize:    4 * 1024,
+		WriteBufferSize:   4 * 1024,
+		HandshakeTimeout:  time.Second * 8,
+		EnableCompression: wsSettings.Compression,
 	}
 
 	protocol := "ws"
@@ -66,7 +81,7 @@ func dialWebSocket(ctx context.Context, dest net.Destination, streamSettings *in
 		if fingerprint := tls.GetFingerprint(tConfig.Fingerprint); fingerprint != nil {
 			dialer.NetDialTLSContext = func(_ context.Context, _, addr string) (gonet.Conn, error) {
 				// Like the NetDial in the dialer
-				pconn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+				pconn, err := dialSystem()
 				if err != nil {
 					errors.LogErrorInner(ctx, err, "failed to dial to "+addr)
 					return nil, err
@@ -126,7 +141,14 @@ func dialWebSocket(ctx context.Context, dest net.Destination, streamSettings *in
 		return nil, errors.New("failed to dial to (", uri, "): ", reason).Base(err)
 	}
 
-	return NewConnection(conn, conn.RemoteAddr(), nil, wsSettings.HeartbeatPeriod), nil
+	wsConn := NewConnection(conn, conn.RemoteAddr(), nil, wsSettings.HeartbeatPeriod)
+	if isCompressed(resp.Header.Get("Sec-WebSocket-Extensions")) {
+		wsConn = wsConn.withCompression(wire, compression)
+	}
+	if wsSettings.AutoFrameSize {
+		wsConn = wsConn.withFrameProbe(newFrameProbe(dest.NetAddr()))
+	}
+	return wsConn, nil
 }
 
 type delayDialConn struct {
@@ -21,9 +21,11 @@ import (
 )
 
 type requestHandler struct {
-	host string
-	path string
-	ln   *Listener
+	host        string
+	path        string
+	ln          *Listener
+	upgrader    *websocket.Upgrader
+	compression *compressionStats
 }
 
 var replacer = strings.NewReplacer("+", "-", "/", "_", "=", "")
@@ -58,22 +60,19 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 		}
 	}
 
-	conn, err := upgrader.Upgrade(writer, request, responseHeader)
+	conn, err := h.upgrader.Upgrade(writer, request, responseHeader)
 	if err != nil {
 		errors.LogInfoInner(context.Background(), err, "failed to convert to WebSocket connection")
 		return
 	}
 
-	forwardedAddrs := http_proto.ParseXForwardedFor(request.Header)
-	remoteAddr := conn.RemoteAddr()
-	if len(forwardedAddrs) > 0 && forwardedAddrs[0].Family().IsIP() {
-		remoteAddr = &net.TCPAddr{
-			IP:   forwardedAddrs[0].IP(),
-			Port: int(0),
-		}
-	}
+	remoteAddr := h.ln.trust.ClientAddr(conn.RemoteAddr(), http_proto.ParseXForwardedFor(request.Header))
 
-	h.ln.addConn(NewConnection(conn, remoteAddr, extraReader, h.ln.config.HeartbeatPeriod))
+	wsConn := NewConnection(conn, remoteAddr, extraReader, h.ln.config.HeartbeatPeriod)
+	if h.upgrader.EnableCompression && isCompressed(request.Header.Get("Sec-WebSocket-Extensions")) {
+		wsConn = wsConn.withCompression(findWireConn(conn.UnderlyingConn()), h.compression)
+	}
+	h.ln.addConn(wsConn)
 }
 
 type Listener struct {
@@ -82,6 +81,7 @@ type Listener struct {
 	listener net.Listener
 	config   *Config
 	addConn  internet.ConnHandler
+	trust    *internet.ProxyTrust
 }
 
 func ListenWS(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, addConn internet.ConnHandler) (internet.Listener, error) {
@@ -96,8 +96,12 @@ func ListenWS(ctx context.Context, address net.Address, port net.Port, streamSet
 		}
 		streamSettings.SocketSettings.AcceptProxyProtocol = l.config.AcceptProxyProtocol || streamSettings.SocketSettings.AcceptProxyProtocol
 	}
+	trust, err := internet.NewProxyTrust(streamSettings.SocketSettings)
+	if err != nil {
+		return nil, err
+	}
+	l.trust = trust
 	var listener net.Listener
-	var err error
 	if port == net.Port(0) { // unix
 		listener, err = internet.ListenSystem(ctx, &net.UnixAddr{
 			Name: address.Domain(),
@@ -122,6 +126,21 @@ func ListenWS(ctx context.Context, address net.Address, port net.Port, streamSet
 		errors.LogWarning(ctx, "accepting PROXY protocol")
 	}
 
+	handler := &requestHandler{
+		host:     wsSettings.Host,
+		path:     wsSettings.GetNormalizedPath(),
+		ln:       l,
+		upgrader: upgrader,
+	}
+	if wsSettings.Compression {
+		u := *upgrader
+		u.EnableCompression = true
+		handler.upgrader = &u
+		if handler.compression = compressionStatsFromContext(ctx); handler.compression != nil {
+			listener = &wireListener{Listener: listener}
+		}
+	}
+
 	if config := v2tls.ConfigFromStreamSettings(streamSettings); config != nil {
 		if tlsConfig := config.GetTLSConfig(); tlsConfig != nil {
 			listener = tls.NewListener(listener, tlsConfig)
@@ -129,15 +148,13 @@ func ListenWS(ctx context.Context, address net.Address, port net.Port, streamSet
 	}
 
 	l.listener = listener
+	guard := internet.NewHandshakeGuard(streamSettings.SocketSettings)
 
 	l.server = http.Server{
-		Handler: &requestHandler{
-			host: wsSettings.Host,
-			path: wsSettings.GetNormalizedPath(),
-			ln:   l,
-		},
-		ReadHeaderTimeout: time.Second * 4,
+		Handler:           handler,
+		ReadHeaderTimeout: guard.Timeout(time.Second * 4),
 		MaxHeaderBytes:    8192,
+		ConnState:         guard.ConnState,
 	}
 
 	go func() {
@@ -0,0 +1,73 @@
+package websocket
+
+import (
+	"io"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/transport/internet/pmtu"
+)
+
+// minFrameSize is the least size of the frames of connections set to autoFrameSize.
+const minFrameSize = 512
+
+// frameSizeProber searches for the largest frames getting through the paths of WebSocket
+// connections set to autoFrameSize.
+var frameSizeProber = pmtu.NewProber("WebSocket", minFrameSize)
+
+// frameProbe sizes the frames of a connection by frameSizeProber, and reports whether they get
+// through: a message read after frames are written shows they did, while the connection failing
+// before shows they didn't.
+type frameProbe struct {
+	path string
+	size int32
+
+	access    sync.Mutex
+	written   int32 // largest frame written since the last message read
+	confirmed int32 // largest frame reported to get through
+}
+
+func newFrameProbe(path string) *frameProbe {
+	return &frameProbe{
+		path: path,
+		size: frameSizeProber.Size(path, buf.Size),
+	}
+}
+
+func (p *frameProbe) onWrite(size int32) {
+	if p == nil {
+		return
+	}
+	p.access.Lock()
+	defer p.access.Unlock()
+	if size > p.written {
+		p.written = size
+	}
+}
+
+func (p *frameProbe) onRead() {
+	if p == nil {
+		return
+	}
+	p.access.Lock()
+	defer p.access.Unlock()
+	if p.written > p.confirmed {
+		p.confirmed = p.written
+		frameSizeProber.Succeeded(p.path, p.written)
+	}
+	p.written = 0
+}
+
+func (p *frameProbe) onError(err error) {
+	if p == nil || errors.Cause(err) == io.EOF || websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		return
+	}
+	p.access.Lock()
+	defer p.access.Unlock()
+	if p.written > p.confirmed {
+		frameSizeProber.Failed(p.path, p.written)
+	}
+	p.written = 0
+}
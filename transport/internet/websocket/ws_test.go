@@ -1,7 +1,9 @@
 package websocket_test
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"runtime"
 	"testing"
 	"time"
@@ -151,3 +153,37 @@ func Test_listenWSAndDial_TLS(t *testing.T) {
 		t.Error("end: ", end, " start: ", start)
 	}
 }
+
+func Test_listenWSAndDialWithCompression(t *testing.T) {
+	listenPort := tcp.PickPort()
+	streamSettings := &internet.MemoryStreamConfig{
+		ProtocolName:     "websocket",
+		ProtocolSettings: &Config{Path: "ws", Compression: true},
+	}
+	payload := bytes.Repeat([]byte("compressible "), 1000)
+	listen, err := ListenWS(context.Background(), net.LocalHostIP, listenPort, streamSettings, func(conn stat.Connection) {
+		go func(c stat.Connection) {
+			defer c.Close()
+
+			b := make([]byte, len(payload))
+			c.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if _, err := io.ReadFull(c, b); err != nil {
+				return
+			}
+			common.Must2(c.Write(b))
+		}(conn)
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	conn, err := Dial(context.Background(), net.TCPDestination(net.DomainAddress("localhost"), listenPort), streamSettings)
+	common.Must(err)
+	defer conn.Close()
+	common.Must2(conn.Write(payload))
+
+	b := make([]byte, len(payload))
+	common.Must2(io.ReadFull(conn, b))
+	if !bytes.Equal(b, payload) {
+		t.Error("response mismatch")
+	}
+}
@@ -23,11 +23,16 @@ const (
 
 type pipeOption struct {
 	limit           int32 // maximum buffer size in bytes
+	sharedLimit     *Limit
 	discardOverflow bool
 }
 
 func (o *pipeOption) isFull(curSize int32) bool {
-	return o.limit >= 0 && curSize > o.limit
+	limit := o.limit
+	if o.sharedLimit != nil {
+		limit = o.sharedLimit.Get()
+	}
+	return limit >= 0 && curSize > limit
 }
 
 type pipe struct {
@@ -0,0 +1,46 @@
+package pipe
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Limit is a size limit shared by the Pipes of a connection. It can be changed after the
+// Pipes are created, once the connection is routed.
+type Limit struct {
+	value atomic.Int32
+}
+
+// NewLimit creates a Limit of size bytes. -1 means unlimited.
+func NewLimit(size int32) *Limit {
+	l := new(Limit)
+	l.value.Store(size)
+	return l
+}
+
+// Get returns the current limit.
+func (l *Limit) Get() int32 {
+	return l.value.Load()
+}
+
+// Set changes the limit. Pipes blocked on a full buffer notice it on their next read.
+func (l *Limit) Set(size int32) {
+	l.value.Store(size)
+}
+
+type limitKey int32
+
+const sharedLimitKey limitKey = 0
+
+// ContextWithLimit returns a context whose Pipes share the size limit l.
+func ContextWithLimit(ctx context.Context, l *Limit) context.Context {
+	return context.WithValue(ctx, sharedLimitKey, l)
+}
+
+// LimitFromContext returns the shared size limit in ctx, or nil.
+func LimitFromContext(ctx context.Context) *Limit {
+	if l, ok := ctx.Value(sharedLimitKey).(*Limit); ok {
+		return l
+	}
+	return nil
+}
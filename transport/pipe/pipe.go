@@ -25,6 +25,13 @@ func WithSizeLimit(limit int32) Option {
 	}
 }
 
+// WithLimit returns an Option for Pipe to have the size limit l, which may change later.
+func WithLimit(l *Limit) Option {
+	return func(opt *pipeOption) {
+		opt.sharedLimit = l
+	}
+}
+
 // DiscardOverflow returns an Option for Pipe to discard writes if full.
 func DiscardOverflow() Option {
 	return func(opt *pipeOption) {
@@ -36,6 +43,10 @@ func DiscardOverflow() Option {
 func OptionsFromContext(ctx context.Context) []Option {
 	var opt []Option
 
+	if l := LimitFromContext(ctx); l != nil {
+		return append(opt, WithLimit(l))
+	}
+
 	bp := policy.BufferPolicyFromContext(ctx)
 	if bp.PerConnection >= 0 {
 		opt = append(opt, WithSizeLimit(bp.PerConnection))
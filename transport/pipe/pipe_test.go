@@ -106,6 +106,34 @@ func TestPipeLimitZero(t *testing.T) {
 	}
 }
 
+func TestPipeSharedLimit(t *testing.T) {
+	limit := NewLimit(0)
+	pReader, pWriter := New(WithLimit(limit))
+	bb := buf.New()
+	common.Must2(bb.Write([]byte{'a', 'b'}))
+	common.Must(pWriter.WriteMultiBuffer(buf.MultiBuffer{bb}))
+
+	limit.Set(1024)
+	done := make(chan error, 1)
+	go func() {
+		b := buf.New()
+		b.Write([]byte{'c', 'd'})
+		done <- pWriter.WriteMultiBuffer(buf.MultiBuffer{b})
+	}()
+	select {
+	case err := <-done:
+		common.Must(err)
+	case <-time.After(time.Second):
+		t.Fatal("write blocked after the limit was raised")
+	}
+
+	mb, err := pReader.ReadMultiBuffer()
+	common.Must(err)
+	if r := cmp.Diff(mb.String(), "abcd"); r != "" {
+		t.Error(r)
+	}
+}
+
 func TestPipeWriteMultiThread(t *testing.T) {
 	pReader, pWriter := New(WithSizeLimit(0))
 